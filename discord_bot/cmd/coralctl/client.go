@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"coral-bot/discord_bot/internal/web"
+)
+
+// client calls the bot's HTTP API with the configured credentials
+type client struct {
+	baseURL    string
+	apiKey     string
+	token      string
+	httpClient *http.Client
+}
+
+// newClient creates a client for the API served at baseURL
+func newClient(baseURL, apiKey, token string) *client {
+	return &client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		token:      token,
+		httpClient: &http.Client{},
+	}
+}
+
+// do sends a request with an optional JSON body and returns the response body. Non-2xx
+// responses are returned as errors carrying the API's error message.
+func (c *client) do(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr web.ErrorResponse
+		if json.Unmarshal(respBody, &apiErr) == nil && apiErr.Code != "" {
+			return nil, fmt.Errorf("%s %s: %s (%d %s, request %s)", method, path, apiErr.Message, resp.StatusCode, apiErr.Code, apiErr.RequestID)
+		}
+		if apiErr.Error != "" {
+			return nil, fmt.Errorf("%s %s: %s (%d)", method, path, apiErr.Error, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	return respBody, nil
+}
+
+// printJSON sends a request and writes the indented JSON response, if any, to w
+func (c *client) printJSON(ctx context.Context, w io.Writer, method, path string, body interface{}) error {
+	respBody, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(respBody)) == 0 {
+		return nil
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, respBody, "", "  "); err != nil {
+		// Not JSON; show it as is
+		_, err = fmt.Fprintln(w, strings.TrimSpace(string(respBody)))
+		return err
+	}
+	indented.WriteByte('\n')
+	_, err = indented.WriteTo(w)
+	return err
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/web"
+)
+
+// testEventTitle marks synthetic events so they are recognisable in Discord
+const testEventTitle = "[TEST] Will coralctl deliver this event?"
+
+// testEventModelTypes maps each /discord/events path segment to the event type used by POST /discord/admin/test-event
+var testEventModelTypes = map[string]string{
+	"new-market":      models.EventNewMarket,
+	"market-update":   models.EventMarketUpdate,
+	"trading-start":   models.EventTradingStarted,
+	"trading-end":     models.EventTradingEnded,
+	"market-resolved": models.EventMarketResolved,
+	"market-buy":      models.EventMarketBuy,
+}
+
+// testEvents builds a synthetic payload for each /discord/events endpoint, keyed by its path segment
+var testEvents = map[string]func(marketID string, now time.Time) interface{}{
+	"new-market": func(marketID string, now time.Time) interface{} {
+		return web.NewMarketEventRequest{
+			MarketID:    marketID,
+			Title:       testEventTitle,
+			Description: "Synthetic market sent by coralctl to check event delivery.",
+			Creator:     "coralctl",
+			Category:    "test",
+			Outcomes:    []web.EventOutcome{{ID: "yes", Name: "Yes"}, {ID: "no", Name: "No"}},
+			StartTime:   now.Format(time.RFC3339),
+			EndTime:     now.Add(24 * time.Hour).Format(time.RFC3339),
+		}
+	},
+	"market-update": func(marketID string, now time.Time) interface{} {
+		return web.MarketUpdateEventRequest{
+			MarketID:       marketID,
+			Title:          testEventTitle,
+			Volume:         1250,
+			VolumeDeltaPct: 12.5,
+			TimeLeft:       "1 day",
+			EndTime:        now.Add(24 * time.Hour).Format(time.RFC3339),
+			Outcomes:       []web.EventOutcome{{ID: "yes", Name: "Yes", Pct: 62}, {ID: "no", Name: "No", Pct: 38}},
+		}
+	},
+	"trading-start": func(marketID string, now time.Time) interface{} {
+		return web.TradingStartEventRequest{
+			MarketID:      marketID,
+			Title:         testEventTitle,
+			Description:   "Synthetic market sent by coralctl to check event delivery.",
+			Duration:      "1 day",
+			OutcomesCount: 2,
+			Outcomes:      []string{"Yes", "No"},
+		}
+	},
+	"trading-end": func(marketID string, now time.Time) interface{} {
+		return web.TradingEndEventRequest{
+			MarketID:    marketID,
+			Title:       testEventTitle,
+			Description: "Synthetic market sent by coralctl to check event delivery.",
+			Outcomes:    []web.EventOutcome{{ID: "yes", Name: "Yes", Pct: 62}, {ID: "no", Name: "No", Pct: 38}},
+			FinalPool:   1250,
+		}
+	},
+	"market-resolved": func(marketID string, now time.Time) interface{} {
+		return web.MarketResolvedEventRequest{
+			MarketID:       marketID,
+			Title:          testEventTitle,
+			WinningOutcome: "Yes",
+			TotalPool:      1250,
+		}
+	},
+	"market-buy": func(marketID string, now time.Time) interface{} {
+		return web.MarketBuyEventRequest{
+			MarketID: marketID,
+			Title:    testEventTitle,
+			Amount:   100,
+			Outcome:  "Yes",
+			Buyer:    "coralctl",
+		}
+	},
+}
+
+// testEvent returns the synthetic payload for an event type
+func testEvent(eventType, marketID string, now time.Time) (interface{}, bool) {
+	build, ok := testEvents[eventType]
+	if !ok {
+		return nil, false
+	}
+	return build(marketID, now), true
+}
+
+// testEventTypes lists the supported event types in a stable order
+func testEventTypes() []string {
+	types := make([]string, 0, len(testEvents))
+	for eventType := range testEvents {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,424 @@
+// Command coralctl is an operator CLI for the bot's admin HTTP API.
+//
+// Usage:
+//
+//	coralctl [--url URL] [--api-key KEY | --token TOKEN] <command> [arguments]
+//
+// Commands:
+//
+//	health                                   show the bot's health and gateway state
+//	webhooks list                            list webhook registrations
+//	webhooks register --channel ID --webhook-url URL [--events a,b] [--frequency f] [--categories a,b] [--test-ping true|false]
+//	webhooks unregister ID                   unregister a webhook
+//	subscriptions [DISCORD_USER_ID]          dump every user's subscriptions, or one user's
+//	test-event TYPE [--market ID]            send a synthetic market event (new-market, market-update,
+//	                                         trading-start, trading-end, market-resolved, market-buy)
+//	test-event TYPE --channel ID             send a sample event to one channel only
+//	broadcast MESSAGE                        post an announcement to every feed channel
+//	channels export ID                       print a channel's settings as portable JSON
+//	channels import ID FILE                  apply exported settings to a channel (FILE - reads stdin)
+//	channels copy SOURCE_ID TARGET_ID        copy one channel's settings to another
+//	channels bulk FILE                       apply settings to many channels at once, all or none (FILE - reads stdin)
+//	keys list                                list API keys, including revoked keys
+//	keys create --name NAME --scopes a,b     create a scoped API key and print its secret
+//	keys rotate ID [--grace 24h]             issue the next key, the old one working for the grace period
+//	keys revoke ID [--grace 24h]             revoke an API key, now or after the grace period
+//	storage migrate --path FILE              copy subscriptions, channel configs and webhooks to a new data file
+//
+// The URL defaults to CORALCTL_URL or http://localhost:3000, and the credentials to CORAL_API_KEY
+// and CORAL_TOKEN, matching the variables the bot authenticates against.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/web"
+)
+
+// defaultURL is used when neither --url nor CORALCTL_URL is set
+const defaultURL = "http://localhost:3000"
+
+// commandTimeout bounds each command, including every request it makes
+const commandTimeout = 30 * time.Second
+
+// errUsage reports invalid arguments; the usage text has already been printed
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes a command line and returns the process exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("coralctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	baseURL := flags.String("url", envOr("CORALCTL_URL", defaultURL), "base URL of the bot's HTTP API")
+	apiKey := flags.String("api-key", os.Getenv("CORAL_API_KEY"), "API key sent as X-API-Key")
+	token := flags.String("token", os.Getenv("CORAL_TOKEN"), "bearer token sent as Authorization")
+	flags.Usage = func() { usage(stderr) }
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	api := newClient(*baseURL, *apiKey, *token)
+	command, commandArgs := flags.Arg(0), flags.Args()[1:]
+
+	var err error
+	switch command {
+	case "health":
+		err = api.printJSON(ctx, stdout, http.MethodGet, "/discord/health", nil)
+	case "webhooks":
+		err = runWebhooks(ctx, api, commandArgs, stdout, stderr)
+	case "subscriptions":
+		err = runSubscriptions(ctx, api, commandArgs, stdout, stderr)
+	case "test-event":
+		err = runTestEvent(ctx, api, commandArgs, stdout, stderr)
+	case "broadcast":
+		err = runBroadcast(ctx, api, commandArgs, stdout, stderr)
+	case "channels":
+		err = runChannels(ctx, api, commandArgs, os.Stdin, stdout, stderr)
+	case "keys":
+		err = runKeys(ctx, api, commandArgs, stdout, stderr)
+	case "storage":
+		err = runStorage(ctx, api, commandArgs, stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "coralctl: unknown command %q\n\n", command)
+		usage(stderr)
+		return 2
+	}
+
+	if errors.Is(err, errUsage) {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "coralctl: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// usage prints the command summary
+func usage(w io.Writer) {
+	fmt.Fprint(w, `Usage: coralctl [--url URL] [--api-key KEY | --token TOKEN] <command> [arguments]
+
+Commands:
+  health                                   show the bot's health and gateway state
+  webhooks list                            list webhook registrations
+  webhooks register --channel ID --webhook-url URL [--events a,b] [--frequency f] [--categories a,b] [--test-ping true|false]
+  webhooks unregister ID                   unregister a webhook
+  subscriptions [DISCORD_USER_ID]          dump every user's subscriptions, or one user's
+  test-event TYPE [--market ID]            send a synthetic market event through the full fan-out; TYPE is one of
+                                           `+strings.Join(testEventTypes(), ", ")+`
+  test-event TYPE --channel ID             send a sample event to one channel only
+  broadcast MESSAGE                        post an announcement to every feed channel
+  channels export ID                       print a channel's settings as portable JSON
+  channels import ID FILE                  apply exported settings to a channel (FILE - reads stdin)
+  channels copy SOURCE_ID TARGET_ID        copy one channel's settings to another
+  channels bulk FILE                       apply settings to many channels at once, all or none (FILE - reads stdin)
+  keys list                                list API keys, including revoked keys
+  keys create --name NAME --scopes a,b     create a scoped API key and print its secret
+  keys rotate ID [--grace 24h]             issue the next key, the old one working for the grace period
+  keys revoke ID [--grace 24h]             revoke an API key, now or after the grace period
+  storage migrate --path FILE              copy subscriptions, channel configs and webhooks to a new data file
+
+The URL defaults to CORALCTL_URL or `+defaultURL+`; credentials default to CORAL_API_KEY and CORAL_TOKEN.
+`)
+}
+
+// runWebhooks handles the webhooks subcommands
+func runWebhooks(ctx context.Context, api *client, args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "Usage: coralctl webhooks <list|register|unregister> [arguments]")
+		return errUsage
+	}
+
+	switch args[0] {
+	case "list":
+		return api.printJSON(ctx, stdout, http.MethodGet, "/discord/webhooks", nil)
+	case "register":
+		flags := flag.NewFlagSet("webhooks register", flag.ContinueOnError)
+		flags.SetOutput(stderr)
+		channelID := flags.String("channel", "", "Discord channel ID (required)")
+		webhookURL := flags.String("webhook-url", "", "Discord webhook URL (required)")
+		events := flags.String("events", "", "comma-separated event types, e.g. new_market,market_resolved (default all)")
+		frequency := flags.String("frequency", "", "low, medium or high")
+		categories := flags.String("categories", "", "comma-separated allowed categories (default all)")
+		testPing := flags.String("test-ping", "", "true or false, post a test message through the webhook first (default the bot's WEBHOOK_TEST_PING)")
+		if err := flags.Parse(args[1:]); err != nil {
+			return errUsage
+		}
+		var ping *bool
+		if *testPing != "" {
+			value, err := strconv.ParseBool(*testPing)
+			if err != nil {
+				fmt.Fprintln(stderr, "coralctl: --test-ping must be true or false")
+				return errUsage
+			}
+			ping = &value
+		}
+		if *channelID == "" || *webhookURL == "" {
+			fmt.Fprintln(stderr, "Usage: coralctl webhooks register --channel ID --webhook-url URL [--events a,b] [--frequency f] [--categories a,b] [--test-ping true|false]")
+			return errUsage
+		}
+		return api.printJSON(ctx, stdout, http.MethodPost, "/discord/webhooks/register", web.RegisterWebhookRequest{
+			ChannelID:         *channelID,
+			WebhookURL:        *webhookURL,
+			Events:            splitList(*events),
+			Frequency:         *frequency,
+			AllowedCategories: splitList(*categories),
+			TestPing:          ping,
+		})
+	case "unregister":
+		if len(args) != 2 {
+			fmt.Fprintln(stderr, "Usage: coralctl webhooks unregister ID")
+			return errUsage
+		}
+		if err := api.printJSON(ctx, stdout, http.MethodDelete, "/discord/webhooks/"+url.PathEscape(args[1]), nil); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Unregistered webhook %s\n", args[1])
+		return nil
+	default:
+		fmt.Fprintf(stderr, "coralctl: unknown webhooks command %q\n", args[0])
+		return errUsage
+	}
+}
+
+// runSubscriptions dumps all subscriptions, or a single user's
+func runSubscriptions(ctx context.Context, api *client, args []string, stdout, stderr io.Writer) error {
+	switch len(args) {
+	case 0:
+		return api.printJSON(ctx, stdout, http.MethodGet, "/discord/admin/subscriptions", nil)
+	case 1:
+		return api.printJSON(ctx, stdout, http.MethodGet, "/discord/subscriptions/"+url.PathEscape(args[0]), nil)
+	default:
+		fmt.Fprintln(stderr, "Usage: coralctl subscriptions [DISCORD_USER_ID]")
+		return errUsage
+	}
+}
+
+// runTestEvent sends a synthetic market event through the normal event endpoints, or to a single channel
+func runTestEvent(ctx context.Context, api *client, args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprintf(stderr, "Usage: coralctl test-event TYPE [--market ID | --channel ID]\nTYPE is one of %s\n", strings.Join(testEventTypes(), ", "))
+		return errUsage
+	}
+	eventType := args[0]
+	flags := flag.NewFlagSet("test-event", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	marketID := flags.String("market", "coralctl-test", "market ID used in the synthetic event")
+	channelID := flags.String("channel", "", "send a sample event to this channel only, ignoring its feed settings")
+	if err := flags.Parse(args[1:]); err != nil {
+		return errUsage
+	}
+
+	if *channelID != "" {
+		modelType, ok := testEventModelTypes[eventType]
+		if !ok {
+			fmt.Fprintf(stderr, "coralctl: unknown event type %q; use one of %s\n", eventType, strings.Join(testEventTypes(), ", "))
+			return errUsage
+		}
+		return api.printJSON(ctx, stdout, http.MethodPost, "/discord/admin/test-event", web.TestEventRequest{ChannelID: *channelID, EventType: modelType})
+	}
+
+	payload, ok := testEvent(eventType, *marketID, time.Now())
+	if !ok {
+		fmt.Fprintf(stderr, "coralctl: unknown event type %q; use one of %s\n", eventType, strings.Join(testEventTypes(), ", "))
+		return errUsage
+	}
+	return api.printJSON(ctx, stdout, http.MethodPost, "/discord/events/"+eventType, payload)
+}
+
+// runBroadcast posts an announcement; the remaining arguments are joined into the message
+func runBroadcast(ctx context.Context, api *client, args []string, stdout, stderr io.Writer) error {
+	message := strings.TrimSpace(strings.Join(args, " "))
+	if message == "" {
+		fmt.Fprintln(stderr, "Usage: coralctl broadcast MESSAGE")
+		return errUsage
+	}
+	return api.printJSON(ctx, stdout, http.MethodPost, "/discord/admin/broadcast", web.BroadcastRequest{Message: message})
+}
+
+// runChannels handles the channels subcommands
+func runChannels(ctx context.Context, api *client, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "Usage: coralctl channels <export|import|copy|bulk> [arguments]")
+		return errUsage
+	}
+
+	switch args[0] {
+	case "export":
+		if len(args) != 2 {
+			fmt.Fprintln(stderr, "Usage: coralctl channels export ID")
+			return errUsage
+		}
+		return api.printJSON(ctx, stdout, http.MethodGet, "/discord/channel/settings/"+url.PathEscape(args[1])+"/export", nil)
+	case "import":
+		if len(args) != 3 {
+			fmt.Fprintln(stderr, "Usage: coralctl channels import ID FILE")
+			return errUsage
+		}
+		input := stdin
+		if args[2] != "-" {
+			file, err := os.Open(args[2])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			input = file
+		}
+		var settings models.ChannelSettings
+		if err := json.NewDecoder(input).Decode(&settings); err != nil {
+			return fmt.Errorf("failed to read settings: %w", err)
+		}
+		return api.printJSON(ctx, stdout, http.MethodPut, "/discord/channel/settings/"+url.PathEscape(args[1]), settings)
+	case "copy":
+		if len(args) != 3 {
+			fmt.Fprintln(stderr, "Usage: coralctl channels copy SOURCE_ID TARGET_ID")
+			return errUsage
+		}
+		return api.printJSON(ctx, stdout, http.MethodPost, "/discord/channel/settings/copy", web.ChannelSettingsCopyRequest{
+			SourceChannelID: args[1],
+			TargetChannelID: args[2],
+		})
+	case "bulk":
+		if len(args) != 2 {
+			fmt.Fprintln(stderr, "Usage: coralctl channels bulk FILE")
+			return errUsage
+		}
+		input := stdin
+		if args[1] != "-" {
+			file, err := os.Open(args[1])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			input = file
+		}
+		var channels []web.BulkChannelSettings
+		if err := json.NewDecoder(input).Decode(&channels); err != nil {
+			return fmt.Errorf("failed to read channels: %w", err)
+		}
+		return api.printJSON(ctx, stdout, http.MethodPost, "/discord/channel/settings/bulk", channels)
+	default:
+		fmt.Fprintf(stderr, "coralctl: unknown channels command %q\n", args[0])
+		return errUsage
+	}
+}
+
+// runKeys handles the keys subcommands
+func runKeys(ctx context.Context, api *client, args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "Usage: coralctl keys <list|create|rotate|revoke> [arguments]")
+		return errUsage
+	}
+
+	switch args[0] {
+	case "list":
+		return api.printJSON(ctx, stdout, http.MethodGet, "/discord/admin/api-keys", nil)
+	case "create":
+		flags := flag.NewFlagSet("keys create", flag.ContinueOnError)
+		flags.SetOutput(stderr)
+		name := flags.String("name", "", "name of the key, e.g. the service that uses it (required)")
+		scopes := flags.String("scopes", "", "comma-separated scopes, e.g. events:write,admin:read (required)")
+		if err := flags.Parse(args[1:]); err != nil {
+			return errUsage
+		}
+		if *name == "" || *scopes == "" {
+			fmt.Fprintln(stderr, "Usage: coralctl keys create --name NAME --scopes a,b")
+			return errUsage
+		}
+		return api.printJSON(ctx, stdout, http.MethodPost, "/discord/admin/api-keys", web.CreateAPIKeyRequest{
+			Name:   *name,
+			Scopes: splitList(*scopes),
+		})
+	case "rotate", "revoke":
+		if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+			fmt.Fprintf(stderr, "Usage: coralctl keys %s ID [--grace 24h]\n", args[0])
+			return errUsage
+		}
+		id := args[1]
+		flags := flag.NewFlagSet("keys "+args[0], flag.ContinueOnError)
+		flags.SetOutput(stderr)
+		grace := flags.String("grace", "", "how long the old key keeps working, e.g. 1h, 24h or 7d")
+		if err := flags.Parse(args[2:]); err != nil {
+			return errUsage
+		}
+		path := "/discord/admin/api-keys/" + url.PathEscape(id)
+		if args[0] == "rotate" {
+			return api.printJSON(ctx, stdout, http.MethodPost, path+"/rotate", web.RotateAPIKeyRequest{Grace: *grace})
+		}
+		if *grace != "" {
+			path += "?grace=" + url.QueryEscape(*grace)
+		}
+		if err := api.printJSON(ctx, stdout, http.MethodDelete, path, nil); err != nil {
+			return err
+		}
+		if *grace != "" {
+			fmt.Fprintf(stdout, "API key %s stops working in %s\n", id, *grace)
+		} else {
+			fmt.Fprintf(stdout, "Revoked API key %s\n", id)
+		}
+		return nil
+	default:
+		fmt.Fprintf(stderr, "coralctl: unknown keys command %q\n", args[0])
+		return errUsage
+	}
+}
+
+// envOr returns an environment variable, or fallback when it is unset or empty
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// runStorage handles the storage subcommands
+func runStorage(ctx context.Context, api *client, args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] != "migrate" {
+		fmt.Fprintln(stderr, "Usage: coralctl storage migrate --path FILE")
+		return errUsage
+	}
+	flags := flag.NewFlagSet("storage migrate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("path", "", "data file to create on the bot's host, for STORAGE_DRIVER=file (required)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return errUsage
+	}
+	if *path == "" {
+		fmt.Fprintln(stderr, "Usage: coralctl storage migrate --path FILE")
+		return errUsage
+	}
+	return api.printJSON(ctx, stdout, http.MethodPost, "/discord/admin/storage/migrate", web.StorageMigrationRequest{Driver: "file", Path: *path})
+}
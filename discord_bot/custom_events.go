@@ -0,0 +1,19 @@
+package main
+
+import "coral-bot/discord_bot/internal/services"
+
+// registerCustomEvents registers the event types a deployment adds to the built-in ones. Forks add
+// their own events here, each with a decoder for its payload, a template for its message and a
+// routing policy, and the bot serves them at /discord/events/{name, with dashes for underscores} and accepts them in batches and
+// from the message bus. For example:
+//
+//	registry.Register(services.CustomEventType{
+//		Name:     "market_featured",
+//		Summary:  "Announce a featured market",
+//		Decode:   decodeMarketFeatured,
+//		Template: "⭐ **{{.Market.Title}}** is featured: {{.Fields.reason}}",
+//		Routing:  services.RouteToFeed,
+//	})
+func registerCustomEvents(registry *services.EventRegistry) error {
+	return nil
+}
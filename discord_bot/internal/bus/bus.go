@@ -0,0 +1,113 @@
+// Package bus connects the bot to a message bus, NATS JetStream or Kafka, so market events can be
+// consumed from a subject or topic instead of being posted to the HTTP endpoints, and the events the
+// bot processed can be published for other services. Kafka is reached through a Confluent REST Proxy
+// (v2 API), not the Kafka protocol.
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Message bus drivers
+const (
+	DriverNATS  = "nats"
+	DriverKafka = "kafka"
+)
+
+// DefaultGroup is the consumer group, or JetStream durable consumer, used when none is configured
+const DefaultGroup = "coral-discord-bot"
+
+// fetchWait is how long a fetch waits for messages before returning an empty batch
+const fetchWait = 5 * time.Second
+
+// Config selects and addresses a message bus
+type Config struct {
+	Driver     string // nats or kafka
+	URL        string // NATS server URL, or the URL of a Confluent REST Proxy (v2 API) in front of Kafka
+	Topic      string // NATS subject or Kafka topic
+	Group      string // Kafka consumer group or JetStream durable consumer name, empty for DefaultGroup
+	Stream     string // JetStream stream holding Topic, empty to look it up from the subject
+	FetchBatch int    // messages a NATS reader pulls at a time, 0 for DefaultFetchBatch
+}
+
+// Message is a message read from the bus
+type Message struct {
+	Topic     string
+	Partition int32 // always 0 for NATS
+	Offset    int64 // Kafka offset or JetStream stream sequence, increasing within a partition
+	Data      []byte
+
+	ack func() error // acknowledges a JetStream message
+}
+
+// Reader reads messages through a consumer group whose position is committed on the bus, so a
+// restarted bot resumes after the last committed message
+type Reader interface {
+	// Name identifies the consumer, for logs and stored offsets, e.g. kafka:markets/coral-discord-bot
+	Name() string
+	// Fetch waits briefly for messages and returns the next batch, which may be empty
+	Fetch(ctx context.Context) ([]*Message, error)
+	// Commit records on the bus that a message and every earlier one of its partition are processed
+	Commit(ctx context.Context, msg *Message) error
+	Close() error
+}
+
+// Envelope is the JSON body of a market event message
+type Envelope struct {
+	Type    string          `json:"type"`    // new_market, market_update, trading_started, trading_ended, market_resolved or market_buy
+	Payload json.RawMessage `json:"payload"` // the body the event type's HTTP endpoint accepts
+}
+
+// NewReader returns a reader for the configured bus. It connects on the first fetch and reconnects
+// after errors, so the bus does not have to be reachable when the bot starts.
+func NewReader(config Config) (Reader, error) {
+	if config.URL == "" || config.Topic == "" {
+		return nil, fmt.Errorf("a message bus URL and topic are required")
+	}
+	if _, err := url.Parse(config.URL); err != nil {
+		return nil, fmt.Errorf("invalid message bus URL: %w", err)
+	}
+	if config.Group == "" {
+		config.Group = DefaultGroup
+	}
+	switch config.Driver {
+	case DriverNATS:
+		return newNATSReader(config), nil
+	case DriverKafka:
+		return newKafkaReader(config), nil
+	default:
+		return nil, fmt.Errorf("unknown message bus driver %q, use nats or kafka", config.Driver)
+	}
+}
+
+// Writer publishes messages to a topic
+type Writer interface {
+	// Name identifies the destination, for logs, e.g. kafka:markets.processed
+	Name() string
+	// Publish publishes a message, returning once the bus has stored it. Kafka uses key to choose
+	// the partition, so messages with the same key stay in order.
+	Publish(ctx context.Context, key string, data []byte) error
+	Close() error
+}
+
+// NewWriter returns a writer to the configured bus's topic. Like a reader it connects on first use.
+func NewWriter(config Config) (Writer, error) {
+	if config.URL == "" || config.Topic == "" {
+		return nil, fmt.Errorf("a message bus URL and topic are required")
+	}
+	if _, err := url.Parse(config.URL); err != nil {
+		return nil, fmt.Errorf("invalid message bus URL: %w", err)
+	}
+	switch config.Driver {
+	case DriverNATS:
+		return newNATSWriter(config), nil
+	case DriverKafka:
+		return newKafkaWriter(config), nil
+	default:
+		return nil, fmt.Errorf("unknown message bus driver %q, use nats or kafka", config.Driver)
+	}
+}
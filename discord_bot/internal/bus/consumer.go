@@ -0,0 +1,121 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/tracing"
+	"coral-bot/discord_bot/internal/utils"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// retryDelay is how long the consumer waits after a failed fetch before trying again
+const retryDelay = 5 * time.Second
+
+// EventProcessor validates and delivers a market event given as its type and JSON payload
+type EventProcessor interface {
+	ProcessEventJSON(ctx context.Context, eventType string, payload json.RawMessage) (suppressed bool, err error)
+}
+
+// Consumer routes market event messages from a message bus into the event pipeline.
+//
+// Each message is processed, its offset saved in the repository, and then committed on the bus.
+// A restart resumes after the last committed message, so no event is lost. A message processed
+// but not yet committed when the bot stopped is redelivered and skipped, because its offset is
+// not past the saved one, so no event is announced twice.
+type Consumer struct {
+	reader    Reader
+	processor EventProcessor
+	repo      repository.SubscriptionRepository
+	logger    *utils.Logger
+}
+
+// NewConsumer creates a consumer reading from reader
+func NewConsumer(reader Reader, processor EventProcessor, repo repository.SubscriptionRepository, logger *utils.Logger) *Consumer {
+	return &Consumer{
+		reader:    reader,
+		processor: processor,
+		repo:      repo,
+		logger:    logger,
+	}
+}
+
+// Run consumes messages until ctx is cancelled, then closes the reader
+func (c *Consumer) Run(ctx context.Context) {
+	c.logger.Info(fmt.Sprintf("Consuming market events from %s", c.reader.Name()))
+	defer c.reader.Close()
+	for ctx.Err() == nil {
+		if _, err := c.ProcessMessages(ctx); err != nil && ctx.Err() == nil {
+			c.logger.Error(fmt.Sprintf("Failed to read market events from %s: %v", c.reader.Name(), err))
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryDelay):
+			}
+		}
+	}
+}
+
+// ProcessMessages fetches one batch of messages and handles them in order, returning how many were
+// delivered as events
+func (c *Consumer) ProcessMessages(ctx context.Context) (int, error) {
+	messages, err := c.reader.Fetch(ctx)
+	delivered := 0
+	for _, msg := range messages {
+		if c.handle(ctx, msg) {
+			delivered++
+		}
+	}
+	return delivered, err
+}
+
+// handle processes a message unless it was already processed, then commits it. Messages that are
+// not valid events are logged and committed too, so they cannot block the partition.
+func (c *Consumer) handle(ctx context.Context, msg *Message) (delivered bool) {
+	ctx, span := tracing.Start(ctx, "bus.message",
+		attribute.String("messaging.destination", msg.Topic),
+		attribute.Int("messaging.partition", int(msg.Partition)),
+		attribute.Int64("messaging.offset", msg.Offset),
+	)
+	defer span.End()
+
+	consumer := c.reader.Name()
+	saved, err := c.repo.GetBusOffset(ctx, consumer, msg.Partition)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to get offset of %s partition %d: %v", consumer, msg.Partition, err))
+		return false
+	}
+
+	if saved != nil && msg.Offset <= saved.Offset {
+		c.logger.Info(fmt.Sprintf("Skipping already processed message %d of %s partition %d", msg.Offset, msg.Topic, msg.Partition))
+	} else {
+		delivered = c.process(ctx, msg)
+		offset := &models.BusOffset{Consumer: consumer, Partition: msg.Partition, Offset: msg.Offset, UpdatedAt: time.Now()}
+		if err := c.repo.SaveBusOffset(ctx, offset); err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to save offset of %s partition %d: %v", consumer, msg.Partition, err))
+		}
+	}
+
+	if err := c.reader.Commit(ctx, msg); err != nil {
+		c.logger.Warning(fmt.Sprintf("Failed to commit message %d of %s partition %d, it will be redelivered and skipped: %v", msg.Offset, msg.Topic, msg.Partition, err))
+	}
+	return delivered
+}
+
+// process decodes a message and delivers its event, logging invalid messages
+func (c *Consumer) process(ctx context.Context, msg *Message) bool {
+	var envelope Envelope
+	if err := json.Unmarshal(msg.Data, &envelope); err != nil {
+		c.logger.Warning(fmt.Sprintf("Skipping malformed message %d of %s partition %d: %v", msg.Offset, msg.Topic, msg.Partition, err))
+		return false
+	}
+	if _, err := c.processor.ProcessEventJSON(ctx, envelope.Type, envelope.Payload); err != nil {
+		c.logger.Warning(fmt.Sprintf("Skipping invalid %s event in message %d of %s partition %d: %v", envelope.Type, msg.Offset, msg.Topic, msg.Partition, err))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,268 @@
+package bus
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kafka REST Proxy v2 content types
+const (
+	kafkaJSONContentType   = "application/vnd.kafka.v2+json"
+	kafkaBinaryContentType = "application/vnd.kafka.binary.v2+json"
+)
+
+// kafkaInstanceNotFound is the proxy's error code for an unknown consumer instance
+const kafkaInstanceNotFound = 40403
+
+// errConsumerInstanceGone is returned when the proxy no longer knows the consumer instance, which
+// happens after it has been idle for longer than the proxy's instance timeout
+var errConsumerInstanceGone = errors.New("consumer instance not found")
+
+// kafkaReader reads a topic through a consumer group of a Kafka REST Proxy (v2 API). The group's
+// offsets are committed in Kafka, so a restarted bot, or another replica joining the group, resumes
+// after the last committed message of each partition.
+type kafkaReader struct {
+	kafkaProxy
+	config Config
+
+	mutex       sync.Mutex
+	instanceURI string // base URI of the consumer instance, empty until created
+}
+
+// newKafkaReader creates a Kafka REST Proxy reader
+func newKafkaReader(config Config) *kafkaReader {
+	return &kafkaReader{
+		kafkaProxy: kafkaProxy{url: config.URL, client: &http.Client{Timeout: fetchWait + 30*time.Second}},
+		config:     config,
+	}
+}
+
+// Name identifies the reader's consumer group
+func (r *kafkaReader) Name() string {
+	return fmt.Sprintf("%s:%s/%s", DriverKafka, r.config.Topic, r.config.Group)
+}
+
+// kafkaRecord is a record returned by the proxy in the binary embedded format
+type kafkaRecord struct {
+	Topic     string `json:"topic"`
+	Value     []byte `json:"value"` // base64 in JSON
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+}
+
+// kafkaProxyError is the body of the proxy's error responses
+type kafkaProxyError struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+// kafkaProxy sends requests to a Kafka REST Proxy
+type kafkaProxy struct {
+	url    string // proxy URL, possibly with basic auth credentials
+	client *http.Client
+}
+
+// do sends a request to the proxy, decoding a JSON response into result when it is not nil
+func (p kafkaProxy) do(ctx context.Context, method, target, accept string, body, result interface{}) error {
+	return p.send(ctx, method, target, kafkaJSONContentType, accept, body, result)
+}
+
+// send sends a request with a body of the given content type to the proxy, decoding a JSON response
+// into result when it is not nil
+func (p kafkaProxy) send(ctx context.Context, method, target, contentType, accept string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, _ := json.Marshal(body)
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", accept)
+	if parsed, err := url.Parse(p.url); err == nil && parsed.User != nil {
+		password, _ := parsed.User.Password()
+		req.SetBasicAuth(parsed.User.Username(), password)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var proxyErr kafkaProxyError
+		json.NewDecoder(resp.Body).Decode(&proxyErr)
+		if proxyErr.ErrorCode == kafkaInstanceNotFound {
+			return errConsumerInstanceGone
+		}
+		return fmt.Errorf("Kafka REST Proxy returned %d: %s", resp.StatusCode, proxyErr.Message)
+	}
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// currentInstance returns the base URI of the consumer instance, or an empty string
+func (r *kafkaReader) currentInstance() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.instanceURI
+}
+
+// instance returns the consumer instance, creating it and subscribing it to the topic when needed.
+// Offsets are only committed after a message is processed, never automatically, and a group without
+// committed offsets starts from the earliest message.
+func (r *kafkaReader) instance(ctx context.Context) (string, error) {
+	if uri := r.currentInstance(); uri != "" {
+		return uri, nil
+	}
+
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	var created struct {
+		InstanceID string `json:"instance_id"`
+		BaseURI    string `json:"base_uri"`
+	}
+	err := r.do(ctx, http.MethodPost, strings.TrimRight(r.config.URL, "/")+"/consumers/"+url.PathEscape(r.config.Group), kafkaJSONContentType, map[string]string{
+		"name":               "coral-discord-bot-" + hex.EncodeToString(suffix),
+		"format":             "binary",
+		"auto.offset.reset":  "earliest",
+		"auto.commit.enable": "false",
+	}, &created)
+	if err != nil {
+		return "", fmt.Errorf("failed to create consumer in group %s: %w", r.config.Group, err)
+	}
+	if err := r.do(ctx, http.MethodPost, created.BaseURI+"/subscription", kafkaJSONContentType, map[string][]string{"topics": {r.config.Topic}}, nil); err != nil {
+		r.do(ctx, http.MethodDelete, created.BaseURI, kafkaJSONContentType, nil, nil)
+		return "", fmt.Errorf("failed to subscribe to %s: %w", r.config.Topic, err)
+	}
+
+	r.mutex.Lock()
+	r.instanceURI = created.BaseURI
+	r.mutex.Unlock()
+	return created.BaseURI, nil
+}
+
+// forgetInstance drops an expired consumer instance so the next call creates a new one
+func (r *kafkaReader) forgetInstance() {
+	r.mutex.Lock()
+	r.instanceURI = ""
+	r.mutex.Unlock()
+}
+
+// Fetch returns the records the proxy has buffered, waiting up to fetchWait for new ones
+func (r *kafkaReader) Fetch(ctx context.Context) ([]*Message, error) {
+	uri, err := r.instance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var records []kafkaRecord
+	err = r.do(ctx, http.MethodGet, fmt.Sprintf("%s/records?timeout=%d", uri, fetchWait.Milliseconds()), kafkaBinaryContentType, nil, &records)
+	if errors.Is(err, errConsumerInstanceGone) {
+		r.forgetInstance()
+	}
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]*Message, 0, len(records))
+	for _, record := range records {
+		messages = append(messages, &Message{Topic: record.Topic, Partition: record.Partition, Offset: record.Offset, Data: record.Value})
+	}
+	return messages, nil
+}
+
+// Commit commits a record's offset for the group. The proxy stores the position after the record,
+// where the group resumes.
+func (r *kafkaReader) Commit(ctx context.Context, msg *Message) error {
+	uri, err := r.instance(ctx)
+	if err != nil {
+		return err
+	}
+	offsets := map[string]interface{}{
+		"offsets": []map[string]interface{}{{"topic": msg.Topic, "partition": msg.Partition, "offset": msg.Offset}},
+	}
+	err = r.do(ctx, http.MethodPost, uri+"/offsets", kafkaJSONContentType, offsets, nil)
+	if errors.Is(err, errConsumerInstanceGone) {
+		r.forgetInstance()
+	}
+	return err
+}
+
+// Close deletes the consumer instance so the group rebalances its partitions right away
+func (r *kafkaReader) Close() error {
+	uri := r.currentInstance()
+	if uri == "" {
+		return nil
+	}
+	r.forgetInstance()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return r.do(ctx, http.MethodDelete, uri, kafkaJSONContentType, nil, nil)
+}
+
+// kafkaWriter produces records to a topic through a Kafka REST Proxy (v2 API)
+type kafkaWriter struct {
+	kafkaProxy
+	config Config
+}
+
+// newKafkaWriter creates a Kafka REST Proxy writer
+func newKafkaWriter(config Config) *kafkaWriter {
+	return &kafkaWriter{
+		kafkaProxy: kafkaProxy{url: config.URL, client: &http.Client{Timeout: 30 * time.Second}},
+		config:     config,
+	}
+}
+
+// Name identifies the writer's topic
+func (w *kafkaWriter) Name() string {
+	return fmt.Sprintf("%s:%s", DriverKafka, w.config.Topic)
+}
+
+// Publish produces a record keyed by key, so records with the same key land on the same partition
+func (w *kafkaWriter) Publish(ctx context.Context, key string, data []byte) error {
+	record := map[string]interface{}{"value": data} // base64 in JSON
+	if key != "" {
+		record["key"] = []byte(key)
+	}
+	var produced struct {
+		Offsets []struct {
+			Partition int32  `json:"partition"`
+			Offset    int64  `json:"offset"`
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	target := strings.TrimRight(w.config.URL, "/") + "/topics/" + url.PathEscape(w.config.Topic)
+	body := map[string]interface{}{"records": []interface{}{record}}
+	if err := w.send(ctx, http.MethodPost, target, kafkaBinaryContentType, kafkaJSONContentType, body, &produced); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", w.config.Topic, err)
+	}
+	for _, offset := range produced.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("failed to publish to %s: %s", w.config.Topic, offset.Error)
+		}
+	}
+	return nil
+}
+
+// Close does nothing, the proxy keeps no producer state for the writer
+func (w *kafkaWriter) Close() error {
+	return nil
+}
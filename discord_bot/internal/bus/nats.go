@@ -0,0 +1,211 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// DefaultFetchBatch is the number of messages a NATS reader pulls at a time when none is configured
+const DefaultFetchBatch = 10
+
+// natsClientName identifies the bot's connections in the server's monitoring
+const natsClientName = "coral-discord-bot"
+
+// natsReader reads a subject through a durable JetStream pull consumer, which keeps the position of
+// the last acknowledged message on the server
+type natsReader struct {
+	config Config
+
+	mutex    sync.Mutex
+	conn     *nats.Conn
+	consumer jetstream.Consumer
+}
+
+// newNATSReader creates a JetStream reader
+func newNATSReader(config Config) *natsReader {
+	if config.FetchBatch <= 0 {
+		config.FetchBatch = DefaultFetchBatch
+	}
+	return &natsReader{config: config}
+}
+
+// Name identifies the reader's durable consumer
+func (r *natsReader) Name() string {
+	return fmt.Sprintf("%s:%s/%s", DriverNATS, r.config.Topic, r.config.Group)
+}
+
+// connectNATS connects to a NATS server given as nats://[user:pass@]host[:port], nats://token@host or
+// tls://host. The client reconnects by itself after short outages; a connection it gave up on is
+// closed and replaced on next use.
+func connectNATS(rawURL string) (*nats.Conn, error) {
+	conn, err := nats.Connect(rawURL, nats.Name(natsClientName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	return conn, nil
+}
+
+// connection returns the durable consumer, connecting and creating it when needed
+func (r *natsReader) connection(ctx context.Context) (jetstream.Consumer, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.conn != nil && !r.conn.IsClosed() {
+		return r.consumer, nil
+	}
+
+	conn, err := connectNATS(r.config.URL)
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	stream := r.config.Stream
+	if stream == "" {
+		if stream, err = js.StreamNameBySubject(ctx, r.config.Topic); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to look up the stream of %s: %w", r.config.Topic, err)
+		}
+	}
+
+	// At most a batch is unacknowledged, and Consumer acknowledges each in turn, so messages arrive
+	// in stream order and the stream sequence works as an offset. A redelivered message was
+	// processed already and is skipped by its offset.
+	consumer, err := js.CreateOrUpdateConsumer(ctx, stream, jetstream.ConsumerConfig{
+		Durable:       r.config.Group,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		DeliverPolicy: jetstream.DeliverAllPolicy,
+		FilterSubject: r.config.Topic,
+		MaxAckPending: r.config.FetchBatch,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create consumer %s: %w", r.config.Group, err)
+	}
+	r.conn, r.consumer = conn, consumer
+	return consumer, nil
+}
+
+// Fetch pulls the next batch of messages, waiting up to fetchWait for the first one
+func (r *natsReader) Fetch(ctx context.Context) ([]*Message, error) {
+	consumer, err := r.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	batch, err := consumer.Fetch(r.config.FetchBatch, jetstream.FetchMaxWait(fetchWait))
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*Message
+	for {
+		select {
+		case msg, ok := <-batch.Messages():
+			if !ok {
+				// A pull that expires before the batch is full is not an error
+				if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) && !errors.Is(err, jetstream.ErrNoMessages) {
+					return messages, err
+				}
+				return messages, nil
+			}
+			metadata, err := msg.Metadata()
+			if err != nil {
+				return messages, err
+			}
+			messages = append(messages, &Message{Topic: msg.Subject(), Offset: int64(metadata.Sequence.Stream), Data: msg.Data(), ack: msg.Ack})
+		case <-ctx.Done():
+			return messages, ctx.Err()
+		}
+	}
+}
+
+// Commit acknowledges a message. JetStream tracks acknowledgements per message, which for an ordered
+// single-partition reader amounts to committing its position.
+func (r *natsReader) Commit(ctx context.Context, msg *Message) error {
+	if msg.ack == nil {
+		return fmt.Errorf("message %d was not read from NATS", msg.Offset)
+	}
+	return msg.ack()
+}
+
+// Close closes the connection
+func (r *natsReader) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn, r.consumer = nil, nil
+	}
+	return nil
+}
+
+// natsWriter publishes to a subject captured by a JetStream stream, waiting for the stream to
+// acknowledge each message
+type natsWriter struct {
+	config Config
+
+	mutex sync.Mutex
+	conn  *nats.Conn
+	js    jetstream.JetStream
+}
+
+// newNATSWriter creates a JetStream writer
+func newNATSWriter(config Config) *natsWriter {
+	return &natsWriter{config: config}
+}
+
+// Name identifies the writer's subject
+func (w *natsWriter) Name() string {
+	return fmt.Sprintf("%s:%s", DriverNATS, w.config.Topic)
+}
+
+// connection returns the JetStream context of the open connection, connecting when needed
+func (w *natsWriter) connection() (jetstream.JetStream, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.conn != nil && !w.conn.IsClosed() {
+		return w.js, nil
+	}
+	conn, err := connectNATS(w.config.URL)
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	w.conn, w.js = conn, js
+	return js, nil
+}
+
+// Publish publishes a message and waits for the stream's acknowledgement. NATS has no partitions, so
+// key is ignored and messages keep the order they were published in.
+func (w *natsWriter) Publish(ctx context.Context, key string, data []byte) error {
+	js, err := w.connection()
+	if err != nil {
+		return err
+	}
+	if _, err := js.Publish(ctx, w.config.Topic, data); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", w.config.Topic, err)
+	}
+	return nil
+}
+
+// Close closes the connection
+func (w *natsWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn, w.js = nil, nil
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package charts
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// Default chart dimensions in pixels
+const (
+	DefaultWidth  = 640
+	DefaultHeight = 320
+)
+
+const (
+	chartMargin    = 16
+	lineThickness  = 3
+	gridLineSteps  = 4 // horizontal grid lines every 25%
+	minChartPoints = 2
+)
+
+// palette holds the line colors used for outcomes, in outcome order
+var palette = []color.RGBA{
+	{R: 0x34, G: 0x98, B: 0xdb, A: 0xff}, // blue
+	{R: 0xe7, G: 0x4c, B: 0x3c, A: 0xff}, // red
+	{R: 0x2e, G: 0xcc, B: 0x71, A: 0xff}, // green
+	{R: 0xf1, G: 0xc4, B: 0x0f, A: 0xff}, // yellow
+	{R: 0x9b, G: 0x59, B: 0xb6, A: 0xff}, // purple
+	{R: 0xe6, G: 0x7e, B: 0x22, A: 0xff}, // orange
+}
+
+// paletteEmoji are the Discord emoji matching palette, used to build a text legend
+var paletteEmoji = []string{"🟦", "🟥", "🟩", "🟨", "🟪", "🟧"}
+
+var (
+	backgroundColor = color.RGBA{R: 0x2f, G: 0x31, B: 0x36, A: 0xff}
+	gridColor       = color.RGBA{R: 0x4f, G: 0x54, B: 0x5c, A: 0xff}
+)
+
+// ErrNotEnoughHistory is returned when fewer than two snapshots are available to plot
+var ErrNotEnoughHistory = fmt.Errorf("at least %d snapshots are required to render a chart", minChartPoints)
+
+// RenderProbabilityChart draws the probability of each outcome over time as a PNG image.
+// Outcomes are taken from the most recent snapshot and colored in order using the palette.
+func RenderProbabilityChart(history []*models.MarketSnapshot, width, height int) ([]byte, error) {
+	if len(history) < minChartPoints {
+		return nil, ErrNotEnoughHistory
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: backgroundColor}, image.Point{}, draw.Src)
+
+	plotWidth := width - 2*chartMargin
+	plotHeight := height - 2*chartMargin
+	for step := 0; step <= gridLineSteps; step++ {
+		y := chartMargin + plotHeight*step/gridLineSteps
+		for x := chartMargin; x <= chartMargin+plotWidth; x++ {
+			img.SetRGBA(x, y, gridColor)
+		}
+	}
+
+	start := history[0].Timestamp
+	span := history[len(history)-1].Timestamp.Sub(start)
+	xFor := func(index int) int {
+		if span <= 0 {
+			return chartMargin + plotWidth*index/(len(history)-1)
+		}
+		elapsed := history[index].Timestamp.Sub(start)
+		return chartMargin + int(float64(plotWidth)*float64(elapsed)/float64(span))
+	}
+	yFor := func(percentage float64) int {
+		if percentage < 0 {
+			percentage = 0
+		}
+		if percentage > 100 {
+			percentage = 100
+		}
+		return chartMargin + int(float64(plotHeight)*(100-percentage)/100)
+	}
+
+	outcomes := history[len(history)-1].Outcomes
+	for i, outcome := range outcomes {
+		lineColor := palette[i%len(palette)]
+		havePrevious := false
+		var prevX, prevY int
+		for index, snapshot := range history {
+			percentage, ok := snapshot.Percentage(outcome)
+			if !ok {
+				havePrevious = false
+				continue
+			}
+			x, y := xFor(index), yFor(percentage)
+			if havePrevious {
+				drawLine(img, prevX, prevY, x, y, lineColor)
+			}
+			prevX, prevY, havePrevious = x, y, true
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode chart: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Legend builds a text legend mapping outcomes to the colors used by RenderProbabilityChart
+func Legend(outcomes []string) string {
+	entries := make([]string, 0, len(outcomes))
+	for i, outcome := range outcomes {
+		entries = append(entries, paletteEmoji[i%len(paletteEmoji)]+" "+outcome)
+	}
+	return strings.Join(entries, "  ")
+}
+
+// drawLine draws a thick line between two points using Bresenham's algorithm
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx := abs(x1 - x0)
+	dy := -abs(y1 - y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		plot(img, x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+// plot paints a square of lineThickness pixels centred on a point
+func plot(img *image.RGBA, x, y int, c color.RGBA) {
+	half := lineThickness / 2
+	for px := x - half; px <= x+half; px++ {
+		for py := y - half; py <= y+half; py++ {
+			if image.Pt(px, py).In(img.Bounds()) {
+				img.SetRGBA(px, py, c)
+			}
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
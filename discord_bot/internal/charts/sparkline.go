@@ -0,0 +1,38 @@
+package charts
+
+// sparkBlocks are the characters used by Sparkline, from lowest to highest
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders probabilities (0-100) as a row of block characters,
+// downsampling evenly when there are more than maxPoints values
+func Sparkline(percentages []float64, maxPoints int) string {
+	if len(percentages) == 0 || maxPoints <= 0 {
+		return ""
+	}
+
+	sampled := percentages
+	if len(percentages) > maxPoints {
+		if maxPoints == 1 {
+			sampled = percentages[len(percentages)-1:]
+		} else {
+			// Keep the first and last values and spread the rest evenly between them
+			sampled = make([]float64, maxPoints)
+			for i := range sampled {
+				sampled[i] = percentages[i*(len(percentages)-1)/(maxPoints-1)]
+			}
+		}
+	}
+
+	line := make([]rune, len(sampled))
+	for i, percentage := range sampled {
+		if percentage < 0 {
+			percentage = 0
+		}
+		if percentage > 100 {
+			percentage = 100
+		}
+		index := int(percentage / 100 * float64(len(sparkBlocks)-1))
+		line[i] = sparkBlocks[index]
+	}
+	return string(line)
+}
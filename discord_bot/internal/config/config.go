@@ -3,16 +3,132 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // Config holds the application configuration
 type Config struct {
-	DiscordBotToken string
-	CoralBackendURL string
+	DiscordBotToken   string
+	DiscordPublicKey  string // application public key, enables the HTTP interactions endpoint
+	GatewayEnabled    bool   // connect to the Discord gateway; without it commands only arrive over HTTP
+	PrefixCommands    bool   // also run commands sent as messages, which needs the message content intent
+	CommandPrefix     string // prefix of message commands
+	CoralBackendURL   string
+	MinBuyAmount      float64 // buys below this amount are never forwarded
+	WhaleBuyAmount    float64 // buys at or above this amount are formatted as whale buys
+	TLSCertFile       string
+	TLSKeyFile        string
+	TrustedProxies    []string // IPs or CIDR ranges whose X-Forwarded-For header is trusted
+	IngestAllowlist   []string // IPs or CIDR ranges allowed to post events, any when empty
+	BotOwnerIDs       []string // Discord user IDs allowed to run bot owner commands such as admin_user_data
+	CommandGuildID    string   // guild slash commands are registered in, for development; empty registers them globally
+	UnregisterOnExit  bool     // remove the commands from CommandGuildID on shutdown
+	RateLimit         int      // requests per minute per client IP, 0 disables rate limiting
+	TracingEnabled    bool     // export spans over OTLP, enabled by setting an OTLP endpoint
+	GatewayBufferSize int      // outbound messages held while the Discord gateway is disconnected, 0 uses the default
+	FanoutWorkers     int      // messages of event fan-outs sent concurrently, 0 uses the default
+	FanoutQueueSize   int      // messages of each priority a fan-out worker holds before event processing waits, 0 uses the default
+	MaxUserMarkets    int      // markets one user may subscribe to, 0 is unlimited
+	MaxUserCreators   int      // creators one user may subscribe to, 0 is unlimited
+	MaxUserOutcomes   int      // market outcomes one user may subscribe to, 0 is unlimited
+	MaxGuildChannels  int      // channels one guild may configure, 0 is unlimited
+	PresenceEnabled   bool     // show live market stats as the bot's status
+	PresenceTemplate  string   // text/template for the status, empty uses the default
+	PresenceInterval  time.Duration
+	BoardInterval     time.Duration // how often pinned market boards are refreshed besides after events
+	DigestTime        string        // local time of day digests are posted at, HH:MM
+	DigestTimezone    string        // IANA time zone for DigestTime, empty for UTC
+	DigestWeekday     string        // day weekly digests are posted on, empty for Monday
+	CalendarEnabled   bool          // offer the backend's events calendar and post themed digests before events
+	CalendarLead      time.Duration // how long before an event its themed digest is posted, 0 uses the default
+	GRPCPort          string        // port of the gRPC ingest API, empty disables it
+	GRPCReflection    bool          // register the gRPC reflection service, for tools such as grpcurl
+	BusDriver         string        // message bus market events are consumed from and published to, nats or kafka, empty disables it
+	BusURL            string        // NATS server URL, or Confluent REST Proxy (v2 API) URL for Kafka, which is not reached directly
+	BusTopic          string        // NATS subject or Kafka topic carrying market events
+	BusGroup          string        // Kafka consumer group or JetStream durable consumer, empty for the default
+	BusStream         string        // JetStream stream holding BusTopic, empty to look it up
+	BusFetchBatch     int           // messages pulled from NATS at a time, 0 for the default
+	BusPublishTopic   string        // NATS subject or Kafka topic processed events are published to, empty disables publishing
+	StorageDriver     string        // where subscriptions and settings are kept, memory or file, empty for memory
+	StoragePath       string        // JSON file of the file storage driver
+	StorageInterval   time.Duration // how often the file storage driver writes changes to StoragePath
+	AnalyticsMaxAge   time.Duration // how long analytics events are kept before they are pruned
+	StorageCache      bool          // cache the subscription and channel config lists read for every event
+	StorageCacheTTL   time.Duration // how long cached lists are kept, 0 until the bot changes them
+	UpdateHigh        time.Duration // interval of market updates at the high frequency, 0 uses the default
+	UpdateMedium      time.Duration // interval at the medium frequency, 0 uses the default
+	UpdateLow         time.Duration // interval at the low frequency, 0 uses the default
+	UpdateClosingSoon time.Duration // window before a market closes in which it updates every UpdateClosing, 0 uses the default
+	UpdateClosing     time.Duration // interval of updates of markets about to close, 0 uses the default
+	UpdateCoalesce    time.Duration // window in which a market's updates are coalesced into the latest, 0 sends every update
+	LinkDomains       []string      // domains links in messages can point to, empty uses services.DefaultLinkDomains
+	LinkUTM           bool          // add UTM parameters naming Discord and the channel to market links
+	LinkUTMCampaign   string        // utm_campaign of market links, empty for none
+	LinkShortenerURL  string        // shortener service market links are routed through, empty posts them in full
+	LinkShortenerKey  string        // bearer token of the shortener service, empty for none
+	SMTPHost          string        // mail server of email notifications, empty turns them off
+	SMTPPort          int           // port of the mail server, 0 uses services.DefaultSMTPPort
+	SMTPUsername      string        // mail server login, empty sends without authenticating
+	SMTPPassword      string        // mail server password
+	SMTPFrom          string        // sender address of email notifications
+	PushEnabled       bool          // push notifications to linked Coral accounts through the backend's POST /notify
+	PushToken         string        // bearer token of the backend's notification API, empty for none
+	TradingEnabled    bool          // let linked accounts buy outcomes from buttons under markets, in guilds that turn it on
+	TradingToken      string        // bearer token of the backend's order API, empty for none
+	GameEnabled       bool          // let guilds that turn it on bet play money on market alerts
+	GameBalance       float64       // play money members start the game with, 0 uses the default
+	ShadowMode        bool          // log and report every message instead of sending it
+	WebhookTestPing   bool          // test webhooks with a message when they are registered
+	MaintenanceMode   bool          // start in maintenance, holding outbound notifications until it ends
+	MaintenanceNotice string        // reply to commands during maintenance, empty for the default one
+	MaintenanceBuffer int           // outbound messages held during maintenance, 0 uses the default
+	HTTPLogSampleRate float64       // share of requests to the web server logged with their bodies, 0 for none
+	HTTPLogEndpoints  []string      // endpoints whose requests are all logged, a trailing * matching a prefix
+	HTTPLogCapacity   int           // logged requests kept, 0 uses the default
+	HTTPLogBodyBytes  int           // longest logged request or response body, 0 uses the default
+	CoralAPIKey       string        // X-API-Key granting every scope of the web API, empty for none
+	CoralToken        string        // bearer token granting every scope of the web API, empty for none
+	CoralAPIKeyNext   string        // X-API-Key accepted alongside CoralAPIKey while it is rotated, empty for none
+	CoralTokenNext    string        // bearer token accepted alongside CoralToken while it is rotated, empty for none
+
+	// Secrets come from SecretsProvider and are read again every SecretsRefresh, so rotated values
+	// apply without a restart
+	SecretsProvider SecretsProvider
+	SecretsRefresh  time.Duration // 0 never reads them again
 }
 
+// DefaultPresenceInterval is how often the bot's status is refreshed when PRESENCE_INTERVAL is not set
+const DefaultPresenceInterval = 5 * time.Minute
+
+// DefaultBoardInterval is how often market boards are refreshed when BOARD_INTERVAL is not set
+const DefaultBoardInterval = 5 * time.Minute
+
+// DefaultStoragePath is the data file of the file storage driver when STORAGE_PATH is not set
+const DefaultStoragePath = "coral-bot-data.json"
+
+// DefaultCommandPrefix starts message commands when COMMAND_PREFIX is not set
+const DefaultCommandPrefix = "!coral"
+
+// DefaultStorageInterval is how often the file storage driver saves when STORAGE_FLUSH_INTERVAL is not set
+const DefaultStorageInterval = 30 * time.Second
+
+// DefaultWhaleBuyAmount is the buy amount that triggers a whale alert when WHALE_BUY_AMOUNT is not set
+const DefaultWhaleBuyAmount = 10000.0
+
+// Subscription limits used when MAX_USER_MARKETS, MAX_USER_CREATORS, MAX_USER_OUTCOMES and
+// MAX_GUILD_CHANNELS are not set
+const (
+	DefaultMaxUserMarkets   = 100
+	DefaultMaxUserCreators  = 50
+	DefaultMaxUserOutcomes  = 100
+	DefaultMaxGuildChannels = 50
+)
+
 // LoadConfig loads configuration from environment variables
 func LoadConfig() *Config {
 	// Load .env file if it exists
@@ -22,14 +138,192 @@ func LoadConfig() *Config {
 	}
 
 	config := &Config{
-		DiscordBotToken: os.Getenv("DISCORD_BOT_TOKEN"),
-		CoralBackendURL: os.Getenv("CORAL_BACKEND_URL"),
+		DiscordBotToken:   os.Getenv("DISCORD_BOT_TOKEN"),
+		DiscordPublicKey:  os.Getenv("DISCORD_PUBLIC_KEY"),
+		GatewayEnabled:    getEnvBool("DISCORD_GATEWAY", true),
+		PrefixCommands:    getEnvBool("PREFIX_COMMANDS", false),
+		CommandPrefix:     os.Getenv("COMMAND_PREFIX"),
+		CoralBackendURL:   os.Getenv("CORAL_BACKEND_URL"),
+		MinBuyAmount:      getEnvFloat("MIN_BUY_AMOUNT", 0),
+		WhaleBuyAmount:    getEnvFloat("WHALE_BUY_AMOUNT", DefaultWhaleBuyAmount),
+		TLSCertFile:       os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:        os.Getenv("TLS_KEY_FILE"),
+		TrustedProxies:    getEnvList("TRUSTED_PROXIES"),
+		IngestAllowlist:   getEnvList("INGEST_ALLOWED_IPS"),
+		BotOwnerIDs:       getEnvList("BOT_OWNER_IDS"),
+		CommandGuildID:    os.Getenv("COMMAND_GUILD_ID"),
+		UnregisterOnExit:  getEnvBool("UNREGISTER_COMMANDS_ON_EXIT", false),
+		RateLimit:         getEnvInt("RATE_LIMIT_PER_MINUTE", 0),
+		TracingEnabled:    os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "",
+		GatewayBufferSize: getEnvInt("GATEWAY_BUFFER_SIZE", 0),
+		FanoutWorkers:     getEnvInt("FANOUT_WORKERS", 0),
+		FanoutQueueSize:   getEnvInt("FANOUT_QUEUE_SIZE", 0),
+		MaxUserMarkets:    getEnvInt("MAX_USER_MARKETS", DefaultMaxUserMarkets),
+		MaxUserCreators:   getEnvInt("MAX_USER_CREATORS", DefaultMaxUserCreators),
+		MaxUserOutcomes:   getEnvInt("MAX_USER_OUTCOMES", DefaultMaxUserOutcomes),
+		MaxGuildChannels:  getEnvInt("MAX_GUILD_CHANNELS", DefaultMaxGuildChannels),
+		PresenceEnabled:   getEnvBool("PRESENCE_ENABLED", true),
+		PresenceTemplate:  os.Getenv("PRESENCE_TEMPLATE"),
+		PresenceInterval:  getEnvDuration("PRESENCE_INTERVAL", DefaultPresenceInterval),
+		BoardInterval:     getEnvDuration("BOARD_INTERVAL", DefaultBoardInterval),
+		DigestTime:        os.Getenv("DIGEST_TIME"),
+		DigestTimezone:    os.Getenv("DIGEST_TIMEZONE"),
+		DigestWeekday:     os.Getenv("DIGEST_WEEKDAY"),
+		CalendarEnabled:   getEnvBool("CALENDAR_ENABLED", false),
+		CalendarLead:      getEnvDuration("CALENDAR_DIGEST_LEAD", 0),
+		GRPCPort:          os.Getenv("GRPC_PORT"),
+		GRPCReflection:    getEnvBool("GRPC_REFLECTION", false),
+		BusDriver:         os.Getenv("BUS_DRIVER"),
+		BusURL:            os.Getenv("BUS_URL"),
+		BusTopic:          os.Getenv("BUS_TOPIC"),
+		BusGroup:          os.Getenv("BUS_GROUP"),
+		BusStream:         os.Getenv("BUS_STREAM"),
+		BusFetchBatch:     getEnvInt("BUS_FETCH_BATCH", 0),
+		BusPublishTopic:   os.Getenv("BUS_PUBLISH_TOPIC"),
+		StorageDriver:     os.Getenv("STORAGE_DRIVER"),
+		StoragePath:       os.Getenv("STORAGE_PATH"),
+		StorageInterval:   getEnvDuration("STORAGE_FLUSH_INTERVAL", DefaultStorageInterval),
+		AnalyticsMaxAge:   getEnvDuration("ANALYTICS_RETENTION", 0),
+		StorageCache:      getEnvBool("STORAGE_CACHE", true),
+		StorageCacheTTL:   getEnvDuration("STORAGE_CACHE_TTL", 0),
+		UpdateHigh:        getEnvDuration("UPDATE_INTERVAL_HIGH", 0),
+		UpdateMedium:      getEnvDuration("UPDATE_INTERVAL_MEDIUM", 0),
+		UpdateLow:         getEnvDuration("UPDATE_INTERVAL_LOW", 0),
+		UpdateClosingSoon: getEnvDuration("UPDATE_CLOSING_SOON_WINDOW", 0),
+		UpdateClosing:     getEnvDuration("UPDATE_CLOSING_SOON_INTERVAL", 0),
+		UpdateCoalesce:    getEnvDuration("UPDATE_COALESCE_WINDOW", 0),
+		LinkDomains:       getEnvList("LINK_ALLOWED_DOMAINS"),
+		LinkUTM:           getEnvBool("LINK_UTM", false),
+		LinkUTMCampaign:   os.Getenv("LINK_UTM_CAMPAIGN"),
+		LinkShortenerURL:  os.Getenv("LINK_SHORTENER_URL"),
+		LinkShortenerKey:  os.Getenv("LINK_SHORTENER_TOKEN"),
+		SMTPHost:          os.Getenv("SMTP_HOST"),
+		SMTPPort:          getEnvInt("SMTP_PORT", 0),
+		SMTPUsername:      os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:      os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:          os.Getenv("SMTP_FROM"),
+		PushEnabled:       getEnvBool("PUSH_ENABLED", false),
+		PushToken:         os.Getenv("PUSH_API_TOKEN"),
+		TradingEnabled:    getEnvBool("TRADING_ENABLED", false),
+		TradingToken:      os.Getenv("TRADING_API_TOKEN"),
+		GameEnabled:       getEnvBool("GAME_ENABLED", false),
+		GameBalance:       getEnvFloat("GAME_STARTING_BALANCE", 0),
+		ShadowMode:        getEnvBool("SHADOW_MODE", false),
+		WebhookTestPing:   getEnvBool("WEBHOOK_TEST_PING", false),
+		MaintenanceMode:   getEnvBool("MAINTENANCE_MODE", false),
+		MaintenanceNotice: os.Getenv("MAINTENANCE_NOTICE"),
+		MaintenanceBuffer: getEnvInt("MAINTENANCE_BUFFER_SIZE", 0),
+		HTTPLogSampleRate: getEnvFloat("HTTP_LOG_SAMPLE_RATE", 0),
+		HTTPLogEndpoints:  getEnvList("HTTP_LOG_ENDPOINTS"),
+		HTTPLogCapacity:   getEnvInt("HTTP_LOG_CAPACITY", 0),
+		HTTPLogBodyBytes:  getEnvInt("HTTP_LOG_MAX_BODY_BYTES", 0),
+		CoralAPIKey:       os.Getenv("CORAL_API_KEY"),
+		CoralToken:        os.Getenv("CORAL_TOKEN"),
+		CoralAPIKeyNext:   os.Getenv("CORAL_API_KEY_NEXT"),
+		CoralTokenNext:    os.Getenv("CORAL_TOKEN_NEXT"),
+	}
+	if config.StoragePath == "" {
+		config.StoragePath = DefaultStoragePath
+	}
+	if strings.TrimSpace(config.CommandPrefix) == "" {
+		config.CommandPrefix = DefaultCommandPrefix
+	}
+
+	// Secrets the provider holds replace the environment's
+	provider, err := NewSecretsProvider(loadSecretsConfig())
+	if err != nil {
+		log.Fatalf("Invalid secrets provider: %v", err)
+	}
+	secrets, err := readSecrets(provider)
+	if err != nil {
+		log.Fatalf("Failed to read secrets from %s: %v", provider.Name(), err)
+	}
+	fromEnv := config.applySecrets(secrets)
+	config.SecretsProvider = provider
+	if provider.Name() != SecretsEnv {
+		config.SecretsRefresh = getEnvDuration("SECRETS_REFRESH_INTERVAL", DefaultSecretsRefresh)
+		if len(fromEnv) > 0 {
+			log.Printf("Warning: %s not found in %s, read from the environment instead", strings.Join(fromEnv, ", "), provider.Name())
+		}
 	}
 
 	// Validate required configuration
 	if config.DiscordBotToken == "" {
 		log.Fatal("DISCORD_BOT_TOKEN is required")
 	}
+	if !config.GatewayEnabled && config.DiscordPublicKey == "" {
+		log.Fatal("DISCORD_PUBLIC_KEY is required when DISCORD_GATEWAY is off, commands would never arrive")
+	}
+	if config.StorageDriver != "" && config.StorageDriver != "memory" && config.StorageDriver != "file" {
+		log.Fatalf("STORAGE_DRIVER must be memory or file, got %q", config.StorageDriver)
+	}
 
 	return config
 }
+
+// getEnvFloat reads a float from the environment, falling back to a default when unset or invalid
+func getEnvFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Warning: invalid %s %q, using %v", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvInt reads an integer from the environment, falling back to a default when unset or invalid
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid %s %q, using %v", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvBool reads a boolean from the environment, falling back to a default when unset or invalid
+func getEnvBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: invalid %s %q, using %v", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvDuration reads a positive duration such as 5m from the environment, falling back to a default when unset or invalid
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		log.Printf("Warning: invalid %s %q, using %v", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvList reads a comma-separated list from the environment
+func getEnvList(key string) []string {
+	values := []string{}
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
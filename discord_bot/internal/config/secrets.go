@@ -0,0 +1,204 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Secrets providers
+const (
+	SecretsEnv   = "env"   // the environment and .env, for development
+	SecretsFile  = "file"  // one file per secret in a directory, such as a mounted Kubernetes or Docker secret
+	SecretsVault = "vault" // a HashiCorp Vault KV version 2 secret
+	SecretsAWS   = "aws"   // an AWS Secrets Manager secret holding a JSON object
+)
+
+// secretsTimeout bounds each read of the secrets provider
+const secretsTimeout = 15 * time.Second
+
+// DefaultSecretsRefresh is how often secrets are read again to pick up rotated values, when they come
+// from a provider other than the environment
+const DefaultSecretsRefresh = 5 * time.Minute
+
+// SecretsProvider reads the bot's secrets, such as DISCORD_BOT_TOKEN, keyed by their environment
+// variable name. Secrets it does not hold fall back to the environment.
+type SecretsProvider interface {
+	// Name identifies the provider in logs, e.g. vault:secret/coral-bot
+	Name() string
+	// Secrets returns the current value of every secret the provider holds
+	Secrets(ctx context.Context) (map[string]string, error)
+}
+
+// SecretsConfig selects and addresses a secrets provider
+type SecretsConfig struct {
+	Provider string // env, file, vault or aws
+
+	Dir string // directory of secret files, for file
+
+	VaultAddr      string // Vault server URL
+	VaultToken     string // Vault token, read from VaultTokenFile when empty
+	VaultTokenFile string // file holding the Vault token, such as one written by the Vault agent
+	VaultNamespace string // Vault Enterprise namespace, empty for none
+	VaultPath      string // KV version 2 mount and secret path, e.g. secret/coral-bot
+
+	AWSRegion          string
+	AWSSecretID        string // name or ARN of the secret
+	AWSEndpoint        string // Secrets Manager endpoint, empty for the region's public one
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+	AWSSessionToken    string // for temporary credentials, empty otherwise
+}
+
+// NewSecretsProvider returns the configured secrets provider
+func NewSecretsProvider(config SecretsConfig) (SecretsProvider, error) {
+	switch config.Provider {
+	case "", SecretsEnv:
+		return envSecrets{}, nil
+	case SecretsFile:
+		if config.Dir == "" {
+			return nil, fmt.Errorf("SECRETS_DIR is required for file secrets")
+		}
+		return fileSecrets{dir: config.Dir}, nil
+	case SecretsVault:
+		return newVaultSecrets(config)
+	case SecretsAWS:
+		return newAWSSecrets(config)
+	default:
+		return nil, fmt.Errorf("SECRETS_PROVIDER must be env, file, vault or aws, got %q", config.Provider)
+	}
+}
+
+// loadSecretsConfig reads the secrets provider's settings from the environment. The provider's own
+// credentials cannot come from it, so they are the only secrets left in the environment.
+func loadSecretsConfig() SecretsConfig {
+	return SecretsConfig{
+		Provider:           os.Getenv("SECRETS_PROVIDER"),
+		Dir:                os.Getenv("SECRETS_DIR"),
+		VaultAddr:          os.Getenv("VAULT_ADDR"),
+		VaultToken:         os.Getenv("VAULT_TOKEN"),
+		VaultTokenFile:     os.Getenv("VAULT_TOKEN_FILE"),
+		VaultNamespace:     os.Getenv("VAULT_NAMESPACE"),
+		VaultPath:          os.Getenv("VAULT_SECRET_PATH"),
+		AWSRegion:          os.Getenv("AWS_REGION"),
+		AWSSecretID:        os.Getenv("AWS_SECRET_ID"),
+		AWSEndpoint:        os.Getenv("AWS_SECRETS_ENDPOINT"),
+		AWSAccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		AWSSecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		AWSSessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// secretFields maps the name of each secret to the setting it fills
+func (config *Config) secretFields() map[string]*string {
+	return map[string]*string{
+		"DISCORD_BOT_TOKEN":    &config.DiscordBotToken,
+		"CORAL_API_KEY":        &config.CoralAPIKey,
+		"CORAL_TOKEN":          &config.CoralToken,
+		"CORAL_API_KEY_NEXT":   &config.CoralAPIKeyNext,
+		"CORAL_TOKEN_NEXT":     &config.CoralTokenNext,
+		"LINK_SHORTENER_TOKEN": &config.LinkShortenerKey,
+		"SMTP_PASSWORD":        &config.SMTPPassword,
+		"PUSH_API_TOKEN":       &config.PushToken,
+		"TRADING_API_TOKEN":    &config.TradingToken,
+	}
+}
+
+// SecretNames returns the names of the settings read from the secrets provider, sorted
+func SecretNames() []string {
+	names := []string{}
+	for name := range (&Config{}).secretFields() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SecretValues returns the current value of every secret setting, keyed by name
+func (config *Config) SecretValues() map[string]string {
+	values := make(map[string]string)
+	for name, field := range config.secretFields() {
+		values[name] = *field
+	}
+	return values
+}
+
+// applySecrets fills the secret settings from the provider's values, and reports the names that are
+// still read from the environment
+func (config *Config) applySecrets(secrets map[string]string) []string {
+	fromEnv := []string{}
+	for name, field := range config.secretFields() {
+		if value := secrets[name]; value != "" {
+			*field = value
+		} else if *field != "" {
+			fromEnv = append(fromEnv, name)
+		}
+	}
+	sort.Strings(fromEnv)
+	return fromEnv
+}
+
+// readSecrets reads the secrets from a provider within secretsTimeout
+func readSecrets(provider SecretsProvider) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretsTimeout)
+	defer cancel()
+	return provider.Secrets(ctx)
+}
+
+// envSecrets reads the secrets from the environment
+type envSecrets struct{}
+
+// Name identifies the provider
+func (envSecrets) Name() string {
+	return SecretsEnv
+}
+
+// Secrets returns the secret settings set in the environment
+func (envSecrets) Secrets(ctx context.Context) (map[string]string, error) {
+	secrets := make(map[string]string)
+	for _, name := range SecretNames() {
+		if value := os.Getenv(name); value != "" {
+			secrets[name] = value
+		}
+	}
+	return secrets, nil
+}
+
+// fileSecrets reads one secret per file from a directory, named after the secret
+type fileSecrets struct {
+	dir string
+}
+
+// Name identifies the provider
+func (provider fileSecrets) Name() string {
+	return SecretsFile + ":" + provider.dir
+}
+
+// Secrets reads every file in the directory. Hidden entries, such as the ..data link of a
+// Kubernetes secret volume, and directories are skipped; a trailing newline is trimmed.
+func (provider fileSecrets) Secrets(ctx context.Context) (map[string]string, error) {
+	entries, err := os.ReadDir(provider.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+	secrets := make(map[string]string)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(provider.dir, entry.Name())
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret %s: %w", entry.Name(), err)
+		}
+		secrets[entry.Name()] = strings.TrimRight(string(value), "\r\n")
+	}
+	return secrets, nil
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// awsSecretsService is the name of Secrets Manager in request signatures
+const awsSecretsService = "secretsmanager"
+
+// awsSecrets reads the secrets from an AWS Secrets Manager secret whose value is a JSON object of
+// secret names to values
+type awsSecrets struct {
+	region       string
+	secretID     string
+	endpoint     string
+	accessKeyID  string
+	secretKey    string
+	sessionToken string
+	client       *http.Client
+	now          func() time.Time
+}
+
+// newAWSSecrets checks the AWS settings
+func newAWSSecrets(config SecretsConfig) (*awsSecrets, error) {
+	if config.AWSRegion == "" || config.AWSSecretID == "" {
+		return nil, fmt.Errorf("AWS_REGION and AWS_SECRET_ID are required for aws secrets")
+	}
+	if config.AWSAccessKeyID == "" || config.AWSSecretAccessKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for aws secrets")
+	}
+	endpoint := config.AWSEndpoint
+	if endpoint == "" {
+		endpoint = "https://" + awsSecretsService + "." + config.AWSRegion + ".amazonaws.com"
+	}
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid AWS_SECRETS_ENDPOINT: %w", err)
+	}
+	return &awsSecrets{
+		region:       config.AWSRegion,
+		secretID:     config.AWSSecretID,
+		endpoint:     strings.TrimRight(endpoint, "/") + "/",
+		accessKeyID:  config.AWSAccessKeyID,
+		secretKey:    config.AWSSecretAccessKey,
+		sessionToken: config.AWSSessionToken,
+		client:       &http.Client{Timeout: secretsTimeout},
+		now:          time.Now,
+	}, nil
+}
+
+// Name identifies the provider
+func (provider *awsSecrets) Name() string {
+	return SecretsAWS + ":" + provider.secretID
+}
+
+// Secrets reads the current version of the secret
+func (provider *awsSecrets) Secrets(ctx context.Context) (map[string]string, error) {
+	payload, _ := json.Marshal(map[string]string{"SecretId": provider.secretID})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Secrets Manager request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	if provider.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", provider.sessionToken)
+	}
+	provider.sign(req, payload, provider.now().UTC())
+
+	resp, err := provider.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Secrets Manager: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("secrets manager returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var body struct {
+		SecretString *string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode Secrets Manager response: %w", err)
+	}
+	if body.SecretString == nil {
+		return nil, fmt.Errorf("secret %s has no string value", provider.secretID)
+	}
+	secrets := make(map[string]string)
+	if err := json.Unmarshal([]byte(*body.SecretString), &secrets); err != nil {
+		return nil, fmt.Errorf("secret %s must be a JSON object of string values: %w", provider.secretID, err)
+	}
+	return secrets, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to a request, signing its Host header,
+// body and every X-Amz and Content-Type header already set
+func (provider *awsSecrets) sign(req *http.Request, payload []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(payload)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + provider.region + "/" + awsSecretsService + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+provider.secretKey), date)
+	key = hmacSHA256(key, provider.region)
+	key = hmacSHA256(key, awsSecretsService)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		provider.accessKeyID, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data under key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
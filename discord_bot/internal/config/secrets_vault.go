@@ -0,0 +1,119 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultVaultSecretPath is the Vault secret read unless VAULT_SECRET_PATH is set
+const DefaultVaultSecretPath = "secret/coral-bot"
+
+// vaultSecrets reads the secrets from the fields of a Vault KV version 2 secret
+type vaultSecrets struct {
+	addr      string
+	token     string
+	tokenFile string
+	namespace string
+	mount     string
+	path      string
+	client    *http.Client
+}
+
+// newVaultSecrets checks the Vault settings
+func newVaultSecrets(config SecretsConfig) (*vaultSecrets, error) {
+	if config.VaultAddr == "" {
+		return nil, fmt.Errorf("VAULT_ADDR is required for vault secrets")
+	}
+	if _, err := url.Parse(config.VaultAddr); err != nil {
+		return nil, fmt.Errorf("invalid VAULT_ADDR: %w", err)
+	}
+	if config.VaultToken == "" && config.VaultTokenFile == "" {
+		return nil, fmt.Errorf("VAULT_TOKEN or VAULT_TOKEN_FILE is required for vault secrets")
+	}
+	secretPath := config.VaultPath
+	if secretPath == "" {
+		secretPath = DefaultVaultSecretPath
+	}
+	mount, path, ok := strings.Cut(strings.Trim(secretPath, "/"), "/")
+	if !ok || mount == "" || path == "" {
+		return nil, fmt.Errorf("VAULT_SECRET_PATH must be a mount and a path, e.g. secret/coral-bot")
+	}
+	return &vaultSecrets{
+		addr:      strings.TrimRight(config.VaultAddr, "/"),
+		token:     config.VaultToken,
+		tokenFile: config.VaultTokenFile,
+		namespace: config.VaultNamespace,
+		mount:     mount,
+		path:      path,
+		client:    &http.Client{Timeout: secretsTimeout},
+	}, nil
+}
+
+// Name identifies the provider
+func (provider *vaultSecrets) Name() string {
+	return SecretsVault + ":" + provider.mount + "/" + provider.path
+}
+
+// vaultToken returns the Vault token. A token file is read on every call, so a token renewed by the
+// Vault agent is picked up.
+func (provider *vaultSecrets) vaultToken() (string, error) {
+	if provider.token != "" {
+		return provider.token, nil
+	}
+	token, err := os.ReadFile(provider.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the Vault token: %w", err)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// Secrets reads the latest version of the secret. Every field must be a string.
+func (provider *vaultSecrets) Secrets(ctx context.Context) (map[string]string, error) {
+	token, err := provider.vaultToken()
+	if err != nil {
+		return nil, err
+	}
+	endpoint := provider.addr + "/v1/" + provider.mount + "/data/" + provider.path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if provider.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", provider.namespace)
+	}
+
+	resp, err := provider.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Vault: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("vault returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode Vault secret: %w", err)
+	}
+	secrets := make(map[string]string, len(body.Data.Data))
+	for name, value := range body.Data.Data {
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("vault secret field %s is not a string", name)
+		}
+		secrets[name] = text
+	}
+	return secrets, nil
+}
@@ -0,0 +1,76 @@
+package fixtures
+
+import (
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// ReferenceTime is a fixed instant for tests that need fully deterministic fixtures
+var ReferenceTime = time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// Market returns an active two-outcome market that closes a day after now
+func Market(marketID string, now time.Time) *models.Market {
+	return &models.Market{
+		ID:          marketID,
+		Title:       "Test Market",
+		Description: "This is a test market",
+		Outcomes:    []string{"Yes", "No"},
+		Percentages: []float64{50.0, 50.0},
+		Category:    "Test",
+		Creator:     "Test Creator",
+		Volume:      1000.0,
+		StartTime:   now,
+		EndTime:     now.Add(24 * time.Hour),
+		Status:      "active",
+		Link:        "https://coral.markets/market/" + marketID,
+	}
+}
+
+// Categories returns the canonical category names the backend knows, including the fixture markets' category
+func Categories() []string {
+	return []string{"Crypto", "Economics", "Politics", "Sports", "Test"}
+}
+
+// Markets returns two active markets with different creators and outcome counts
+func Markets(now time.Time) []*models.Market {
+	second := Market("2", now)
+	second.Title = "Test Market 2"
+	second.Description = "This is another test market"
+	second.Outcomes = []string{"Option A", "Option B", "Option C"}
+	second.Percentages = []float64{33.3, 33.3, 33.4}
+	second.Creator = "Another Creator"
+	second.Volume = 2500.0
+	second.StartTime = now.Add(-1 * time.Hour)
+	second.EndTime = now.Add(12 * time.Hour)
+
+	first := Market("1", now)
+	first.Title = "Test Market 1"
+	return []*models.Market{first, second}
+}
+
+// MarketHistory returns three snapshots of a two-outcome market over the day before now, oldest first
+func MarketHistory(marketID string, now time.Time) []*models.MarketSnapshot {
+	return []*models.MarketSnapshot{
+		{MarketID: marketID, Outcomes: []string{"Yes", "No"}, Percentages: []float64{50.0, 50.0}, Timestamp: now.Add(-24 * time.Hour)},
+		{MarketID: marketID, Outcomes: []string{"Yes", "No"}, Percentages: []float64{56.0, 44.0}, Timestamp: now.Add(-12 * time.Hour)},
+		{MarketID: marketID, Outcomes: []string{"Yes", "No"}, Percentages: []float64{48.0, 52.0}, Timestamp: now.Add(-1 * time.Hour)},
+	}
+}
+
+// CalendarEvents returns two upcoming events, a match in two days tagged to both fixture markets and an
+// election in a week tagged to none
+func CalendarEvents(now time.Time) []*models.CalendarEvent {
+	return []*models.CalendarEvent{
+		{ID: "ev1", Name: "Sunday's match", Kind: "sports", StartTime: now.Add(48 * time.Hour), MarketIDs: []string{"1", "2"}},
+		{ID: "ev2", Name: "General election", Kind: "election", StartTime: now.Add(7 * 24 * time.Hour)},
+	}
+}
+
+// Positions returns positions in the fixture markets, one in profit and one at a loss
+func Positions() []*models.Position {
+	return []*models.Position{
+		{MarketID: "1", MarketTitle: "Test Market", Outcome: "Yes", Shares: 40, Cost: 16, Value: 20, Status: "active", Link: "https://coral.markets/market/1"},
+		{MarketID: "2", MarketTitle: "Test Market 2", Outcome: "Option B", Shares: 25, Cost: 10, Value: 7.5, Status: "active", Link: "https://coral.markets/market/2"},
+	}
+}
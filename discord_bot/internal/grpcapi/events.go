@@ -0,0 +1,148 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"coral-bot/discord_bot/internal/grpcapi/ingestpb"
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/web"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// eventIngestServer implements the EventIngest service on top of the webhook handler's event pipeline
+type eventIngestServer struct {
+	ingestpb.UnimplementedEventIngestServer
+	handler *web.WebhookHandler
+}
+
+// PublishEvent validates and delivers a single event
+func (s *eventIngestServer) PublishEvent(ctx context.Context, event *ingestpb.MarketEvent) (*ingestpb.PublishEventResponse, error) {
+	_, suppressed, err := s.publish(ctx, event)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &ingestpb.PublishEventResponse{Accepted: true, Suppressed: suppressed}, nil
+}
+
+// PublishEvents delivers the events of a batch in order, reporting each one's outcome
+func (s *eventIngestServer) PublishEvents(ctx context.Context, request *ingestpb.PublishEventsRequest) (*ingestpb.PublishEventsResponse, error) {
+	if len(request.GetEvents()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "events required")
+	}
+	if len(request.GetEvents()) > web.MaxBatchEvents {
+		return nil, status.Errorf(codes.InvalidArgument, "a batch holds at most %d events", web.MaxBatchEvents)
+	}
+
+	response := &ingestpb.PublishEventsResponse{Results: make([]*ingestpb.EventResult, 0, len(request.GetEvents()))}
+	for i, event := range request.GetEvents() {
+		eventType, suppressed, err := s.publish(ctx, event)
+		result := &ingestpb.EventResult{Index: int32(i), Type: eventType}
+		if err != nil {
+			result.Error = err.Error()
+			response.Failed++
+		} else {
+			result.Accepted, result.Suppressed = true, suppressed
+			response.Accepted++
+		}
+		response.Results = append(response.Results, result)
+	}
+	return response, nil
+}
+
+// publish converts an event to its HTTP payload and processes it, returning the event type
+func (s *eventIngestServer) publish(ctx context.Context, event *ingestpb.MarketEvent) (eventType string, suppressed bool, err error) {
+	eventType, payload, err := eventPayload(event)
+	if err != nil {
+		return eventType, false, err
+	}
+	suppressed, err = s.handler.ProcessEvent(ctx, payload)
+	return eventType, suppressed, err
+}
+
+// eventPayload converts a MarketEvent to the request type of the matching HTTP endpoint
+func eventPayload(event *ingestpb.MarketEvent) (string, interface{}, error) {
+	switch e := event.GetEvent().(type) {
+	case *ingestpb.MarketEvent_NewMarket:
+		return models.EventNewMarket, &web.NewMarketEventRequest{
+			MarketID:    e.NewMarket.GetMarketId(),
+			Title:       e.NewMarket.GetTitle(),
+			Description: e.NewMarket.GetDescription(),
+			Creator:     e.NewMarket.GetCreator(),
+			Category:    e.NewMarket.GetCategory(),
+			Outcomes:    eventOutcomes(e.NewMarket.GetOutcomes()),
+			StartTime:   e.NewMarket.GetStartTime(),
+			EndTime:     e.NewMarket.GetEndTime(),
+			Volume:      e.NewMarket.GetVolume(),
+			Link:        e.NewMarket.GetLink(),
+		}, nil
+	case *ingestpb.MarketEvent_MarketUpdate:
+		return models.EventMarketUpdate, &web.MarketUpdateEventRequest{
+			MarketID:       e.MarketUpdate.GetMarketId(),
+			Title:          e.MarketUpdate.GetTitle(),
+			Volume:         e.MarketUpdate.GetVolume(),
+			VolumeDeltaPct: e.MarketUpdate.GetVolumeDeltaPct(),
+			TimeLeft:       e.MarketUpdate.GetTimeLeft(),
+			EndTime:        e.MarketUpdate.GetEndTime(),
+			Link:           e.MarketUpdate.GetLink(),
+			Outcomes:       eventOutcomes(e.MarketUpdate.GetOutcomes()),
+		}, nil
+	case *ingestpb.MarketEvent_TradingStarted:
+		return models.EventTradingStarted, &web.TradingStartEventRequest{
+			MarketID:      e.TradingStarted.GetMarketId(),
+			Title:         e.TradingStarted.GetTitle(),
+			Description:   e.TradingStarted.GetDescription(),
+			Duration:      e.TradingStarted.GetDuration(),
+			OutcomesCount: int(e.TradingStarted.GetOutcomesCount()),
+			Outcomes:      e.TradingStarted.GetOutcomes(),
+			Link:          e.TradingStarted.GetLink(),
+		}, nil
+	case *ingestpb.MarketEvent_TradingEnded:
+		return models.EventTradingEnded, &web.TradingEndEventRequest{
+			MarketID:    e.TradingEnded.GetMarketId(),
+			Title:       e.TradingEnded.GetTitle(),
+			Description: e.TradingEnded.GetDescription(),
+			Outcomes:    eventOutcomes(e.TradingEnded.GetOutcomes()),
+			FinalPool:   e.TradingEnded.GetFinalPool(),
+			Link:        e.TradingEnded.GetLink(),
+		}, nil
+	case *ingestpb.MarketEvent_MarketResolved:
+		resolvedAt, err := web.ParseEventTime("resolved_at", e.MarketResolved.GetResolvedAt())
+		if err != nil {
+			return models.EventMarketResolved, nil, err
+		}
+		return models.EventMarketResolved, &web.MarketResolvedEventRequest{
+			MarketID:       e.MarketResolved.GetMarketId(),
+			Title:          e.MarketResolved.GetTitle(),
+			WinningOutcome: e.MarketResolved.GetWinningOutcome(),
+			TotalPool:      e.MarketResolved.GetTotalPool(),
+			Link:           e.MarketResolved.GetLink(),
+			ResolvedAt:     resolvedAt,
+		}, nil
+	case *ingestpb.MarketEvent_MarketBuy:
+		return models.EventMarketBuy, &web.MarketBuyEventRequest{
+			MarketID: e.MarketBuy.GetMarketId(),
+			Title:    e.MarketBuy.GetTitle(),
+			Amount:   e.MarketBuy.GetAmount(),
+			Outcome:  e.MarketBuy.GetOutcome(),
+			Buyer:    e.MarketBuy.GetBuyer(),
+			Link:     e.MarketBuy.GetLink(),
+		}, nil
+	case nil:
+		return "", nil, errors.New("event required")
+	default:
+		return "", nil, fmt.Errorf("unsupported event %T", e)
+	}
+}
+
+// eventOutcomes converts outcomes to their HTTP payload form
+func eventOutcomes(outcomes []*ingestpb.Outcome) []web.EventOutcome {
+	converted := make([]web.EventOutcome, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		converted = append(converted, web.EventOutcome{ID: outcome.GetId(), Name: outcome.GetName(), Pct: outcome.GetPct()})
+	}
+	return converted
+}
@@ -0,0 +1,169 @@
+// Package grpcapi serves the gRPC ingest API, an alternative to the HTTP event and subscription
+// endpoints for gRPC-first backends. Calls are authenticated with the same credentials and scopes as
+// the REST API and feed the same services.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"coral-bot/discord_bot/internal/grpcapi/ingestpb"
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+	"coral-bot/discord_bot/internal/tracing"
+	"coral-bot/discord_bot/internal/utils"
+	"coral-bot/discord_bot/internal/web"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// methodScopes maps each RPC to the API key scope it requires
+var methodScopes = map[string]string{
+	ingestpb.EventIngest_PublishEvent_FullMethodName:         models.ScopeEventsWrite,
+	ingestpb.EventIngest_PublishEvents_FullMethodName:        models.ScopeEventsWrite,
+	ingestpb.Subscriptions_SubscribeMarket_FullMethodName:    models.ScopeSubscriptionsWrite,
+	ingestpb.Subscriptions_UnsubscribeMarket_FullMethodName:  models.ScopeSubscriptionsWrite,
+	ingestpb.Subscriptions_SubscribeCreator_FullMethodName:   models.ScopeSubscriptionsWrite,
+	ingestpb.Subscriptions_UnsubscribeCreator_FullMethodName: models.ScopeSubscriptionsWrite,
+	ingestpb.Subscriptions_SubscribeOutcome_FullMethodName:   models.ScopeSubscriptionsWrite,
+	ingestpb.Subscriptions_UnsubscribeOutcome_FullMethodName: models.ScopeSubscriptionsWrite,
+	ingestpb.Subscriptions_GetSubscriptions_FullMethodName:   models.ScopeSubscriptionsRead,
+}
+
+// Server is the gRPC ingest server
+type Server struct {
+	handler             *web.WebhookHandler
+	subscriptionService services.SubscriptionService
+	logger              *utils.Logger
+	reflection          bool
+
+	mutex  sync.Mutex
+	server *grpc.Server // created when serving starts
+}
+
+// NewServer creates a gRPC server that delivers events through the webhook handler and manages
+// subscriptions through the subscription service
+func NewServer(handler *web.WebhookHandler, subscriptionService services.SubscriptionService, logger *utils.Logger) *Server {
+	return &Server{
+		handler:             handler,
+		subscriptionService: subscriptionService,
+		logger:              logger,
+	}
+}
+
+// SetReflection registers the gRPC reflection service, for tools such as grpcurl
+func (s *Server) SetReflection(enabled bool) {
+	s.reflection = enabled
+}
+
+// Start listens on the port and serves until Stop is called, with TLS when a certificate and key are given
+func (s *Server) Start(port, certFile, keyFile string) {
+	var options []grpc.ServerOption
+	switch {
+	case certFile != "" && keyFile != "":
+		creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("Failed to start gRPC server: %v", err))
+			return
+		}
+		options = append(options, grpc.Creds(creds))
+		s.logger.Info(fmt.Sprintf("Starting gRPC server with TLS on port %s", port))
+	case certFile != "" || keyFile != "":
+		s.logger.Error("Failed to start gRPC server: both TLS_CERT_FILE and TLS_KEY_FILE must be set to enable TLS")
+		return
+	default:
+		s.logger.Info(fmt.Sprintf("Starting gRPC server on port %s", port))
+	}
+
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to start gRPC server: %v", err))
+		return
+	}
+	if err := s.serve(listener, options...); err != nil {
+		s.logger.Error(fmt.Sprintf("gRPC server stopped: %v", err))
+	}
+}
+
+// Serve serves plain gRPC on an existing listener until Stop is called
+func (s *Server) Serve(listener net.Listener) error {
+	return s.serve(listener)
+}
+
+// serve registers both services behind the tracing and auth interceptors and serves the listener
+func (s *Server) serve(listener net.Listener, options ...grpc.ServerOption) error {
+	options = append(options, grpc.ChainUnaryInterceptor(traceInterceptor, authInterceptor(s.handler)))
+	server := grpc.NewServer(options...)
+	ingestpb.RegisterEventIngestServer(server, &eventIngestServer{handler: s.handler})
+	ingestpb.RegisterSubscriptionsServer(server, &subscriptionsServer{subscriptionService: s.subscriptionService})
+	if s.reflection {
+		reflection.Register(server)
+	}
+
+	s.mutex.Lock()
+	if s.server != nil {
+		s.mutex.Unlock()
+		return errors.New("gRPC server already started")
+	}
+	s.server = server
+	s.mutex.Unlock()
+	return server.Serve(listener)
+}
+
+// Stop stops accepting calls and waits for the ones in flight to finish
+func (s *Server) Stop() {
+	s.mutex.Lock()
+	server := s.server
+	s.mutex.Unlock()
+	if server != nil {
+		server.GracefulStop()
+	}
+}
+
+// traceInterceptor records a span for each call
+func traceInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	ctx, span := tracing.Start(ctx, "grpc"+strings.ReplaceAll(info.FullMethod, "/", "."), attribute.String("rpc.method", info.FullMethod))
+	defer func() { tracing.End(span, err) }()
+	return handler(ctx, req)
+}
+
+// authInterceptor checks the x-api-key or authorization metadata of each call against the scope of
+// its method, answering Unauthenticated or PermissionDenied like the REST API's 401 and 403
+func authInterceptor(handler *web.WebhookHandler) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		scope, ok := methodScopes[info.FullMethod]
+		if !ok {
+			return nil, status.Error(codes.Unimplemented, "unknown method")
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		err := handler.Authorize(ctx, firstValue(md, "x-api-key"), firstValue(md, "authorization"), scope)
+		switch {
+		case err == nil:
+			return next(ctx, req)
+		case errors.Is(err, web.ErrUnauthenticated):
+			return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+		case errors.Is(err, web.ErrForbidden):
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		default:
+			return nil, status.Error(codes.Internal, "Failed to check credentials")
+		}
+	}
+}
+
+// firstValue returns the first value of a metadata key, or an empty string
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
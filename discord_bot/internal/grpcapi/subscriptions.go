@@ -0,0 +1,117 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"coral-bot/discord_bot/internal/grpcapi/ingestpb"
+	"coral-bot/discord_bot/internal/services"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// subscriptionsServer implements the Subscriptions service on top of the subscription service
+type subscriptionsServer struct {
+	ingestpb.UnimplementedSubscriptionsServer
+	subscriptionService services.SubscriptionService
+}
+
+// subscribeError converts a failed subscription to a gRPC status, ResourceExhausted when the user
+// reached a subscription limit
+func subscribeError(err error) error {
+	var limitErr *services.LimitExceededError
+	if errors.As(err, &limitErr) {
+		return status.Error(codes.ResourceExhausted, limitErr.Error())
+	}
+	return status.Error(codes.Internal, "Failed to subscribe")
+}
+
+// SubscribeMarket subscribes a user to a market's DMs
+func (s *subscriptionsServer) SubscribeMarket(ctx context.Context, request *ingestpb.MarketSubscriptionRequest) (*ingestpb.SubscriptionStatus, error) {
+	if request.GetDiscordUserId() == "" || request.GetMarketId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "discord_user_id and market_id are required")
+	}
+	if err := s.subscriptionService.SubscribeToMarket(ctx, request.GetDiscordUserId(), request.GetMarketId()); err != nil {
+		return nil, subscribeError(err)
+	}
+	return &ingestpb.SubscriptionStatus{Subscribed: true}, nil
+}
+
+// UnsubscribeMarket unsubscribes a user from a market's DMs
+func (s *subscriptionsServer) UnsubscribeMarket(ctx context.Context, request *ingestpb.MarketSubscriptionRequest) (*ingestpb.SubscriptionStatus, error) {
+	if request.GetDiscordUserId() == "" || request.GetMarketId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "discord_user_id and market_id are required")
+	}
+	if err := s.subscriptionService.UnsubscribeFromMarket(ctx, request.GetDiscordUserId(), request.GetMarketId()); err != nil {
+		return nil, status.Error(codes.Internal, "Failed to unsubscribe")
+	}
+	return &ingestpb.SubscriptionStatus{Subscribed: false}, nil
+}
+
+// SubscribeCreator subscribes a user to DMs about a creator's markets
+func (s *subscriptionsServer) SubscribeCreator(ctx context.Context, request *ingestpb.CreatorSubscriptionRequest) (*ingestpb.SubscriptionStatus, error) {
+	if request.GetDiscordUserId() == "" || request.GetCreatorId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "discord_user_id and creator_id are required")
+	}
+	if err := s.subscriptionService.SubscribeToCreator(ctx, request.GetDiscordUserId(), request.GetCreatorId()); err != nil {
+		return nil, subscribeError(err)
+	}
+	return &ingestpb.SubscriptionStatus{Subscribed: true}, nil
+}
+
+// UnsubscribeCreator unsubscribes a user from DMs about a creator's markets
+func (s *subscriptionsServer) UnsubscribeCreator(ctx context.Context, request *ingestpb.CreatorSubscriptionRequest) (*ingestpb.SubscriptionStatus, error) {
+	if request.GetDiscordUserId() == "" || request.GetCreatorId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "discord_user_id and creator_id are required")
+	}
+	if err := s.subscriptionService.UnsubscribeFromCreator(ctx, request.GetDiscordUserId(), request.GetCreatorId()); err != nil {
+		return nil, status.Error(codes.Internal, "Failed to unsubscribe")
+	}
+	return &ingestpb.SubscriptionStatus{Subscribed: false}, nil
+}
+
+// SubscribeOutcome subscribes a user to moves of one outcome of a market
+func (s *subscriptionsServer) SubscribeOutcome(ctx context.Context, request *ingestpb.OutcomeSubscriptionRequest) (*ingestpb.SubscriptionStatus, error) {
+	if request.GetDiscordUserId() == "" || request.GetMarketId() == "" || request.GetOutcome() == "" {
+		return nil, status.Error(codes.InvalidArgument, "discord_user_id, market_id and outcome are required")
+	}
+	if err := s.subscriptionService.SubscribeToOutcome(ctx, request.GetDiscordUserId(), request.GetMarketId(), request.GetOutcome(), request.GetMinChange()); err != nil {
+		return nil, subscribeError(err)
+	}
+	return &ingestpb.SubscriptionStatus{Subscribed: true}, nil
+}
+
+// UnsubscribeOutcome unsubscribes a user from an outcome of a market
+func (s *subscriptionsServer) UnsubscribeOutcome(ctx context.Context, request *ingestpb.OutcomeSubscriptionRequest) (*ingestpb.SubscriptionStatus, error) {
+	if request.GetDiscordUserId() == "" || request.GetMarketId() == "" || request.GetOutcome() == "" {
+		return nil, status.Error(codes.InvalidArgument, "discord_user_id, market_id and outcome are required")
+	}
+	if err := s.subscriptionService.UnsubscribeFromOutcome(ctx, request.GetDiscordUserId(), request.GetMarketId(), request.GetOutcome()); err != nil {
+		return nil, status.Error(codes.Internal, "Failed to unsubscribe")
+	}
+	return &ingestpb.SubscriptionStatus{Subscribed: false}, nil
+}
+
+// GetSubscriptions lists a user's market, creator and outcome subscriptions
+func (s *subscriptionsServer) GetSubscriptions(ctx context.Context, request *ingestpb.GetSubscriptionsRequest) (*ingestpb.UserSubscriptions, error) {
+	if request.GetDiscordUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "discord_user_id required")
+	}
+	subscription, err := s.subscriptionService.GetUserSubscriptions(ctx, request.GetDiscordUserId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to get subscriptions")
+	}
+	response := &ingestpb.UserSubscriptions{
+		Markets:  subscription.SubscribedMarkets,
+		Creators: subscription.SubscribedCreators,
+	}
+	for _, outcome := range subscription.SubscribedOutcomes {
+		response.Outcomes = append(response.Outcomes, &ingestpb.OutcomeSubscription{
+			MarketId:  outcome.MarketID,
+			Outcome:   outcome.Outcome,
+			MinChange: outcome.MinChange,
+		})
+	}
+	return response, nil
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// accountsDisabledMessage answers the account commands when no backend is configured
+const accountsDisabledMessage = "Coral account linking is not enabled on this bot"
+
+// pushDisabledMessage answers push_notifications when the push bridge is not configured
+const pushDisabledMessage = "Push notifications are not enabled on this bot"
+
+// handleLinkAccount handles the link_account command, giving the user a code to enter on Coral or
+// unlinking their account. Replies are private since the code links whoever enters it.
+func (h *CommandHandler) handleLinkAccount(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string, unlink bool) {
+	if h.accounts == nil {
+		h.respondPrivately(session, interaction, accountsDisabledMessage, nil)
+		return
+	}
+
+	if unlink {
+		unlinked, err := h.accounts.UnlinkAccount(ctx, userID)
+		if err != nil {
+			h.respondFailure(ctx, session, interaction, "Failed to unlink your Coral account", fmt.Sprintf("Failed to unlink the Coral account of user %s: %v", userID, err))
+			return
+		}
+		if !unlinked {
+			h.respondPrivately(session, interaction, "You have no Coral account linked", nil)
+			return
+		}
+		h.respondPrivately(session, interaction, "Your Coral account is unlinked", nil)
+		return
+	}
+
+	code, err := h.accounts.CreateLinkCode(ctx, userID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to create a link code", fmt.Sprintf("Failed to create a link code for user %s: %v", userID, err))
+		return
+	}
+	h.respondPrivately(session, interaction, fmt.Sprintf("🔗 Enter the code `%s` in your Coral Markets account settings within %d minutes to link your account, then see your positions with `/my_positions`. Do not share it: it links whoever enters it.", code, int(services.AccountCodeTTL.Minutes())), nil)
+}
+
+// handlePushNotifications handles the push_notifications command
+func (h *CommandHandler) handlePushNotifications(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, mode string) {
+	if h.accounts == nil || !h.pushEnabled {
+		h.respondPrivately(session, interaction, pushDisabledMessage, nil)
+		return
+	}
+
+	err := h.accounts.SetPushMode(ctx, userID, mode)
+	if errors.Is(err, services.ErrAccountNotLinked) {
+		h.respondPrivately(session, interaction, "Link your Coral account first with `/link_account`", nil)
+		return
+	}
+	if errors.Is(err, services.ErrInvalidChannelSettings) {
+		h.respondPrivately(session, interaction, "Invalid mode, use always, fallback or off", nil)
+		return
+	}
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update your push notifications", fmt.Sprintf("Failed to set the push mode of user %s: %v", userID, err))
+		return
+	}
+	h.respondPrivately(session, interaction, fmt.Sprintf("📱 Push notifications updated: %s", describePushMode(mode)), nil)
+}
+
+// handleMyPositions handles the my_positions command, listing what the user's linked Coral account
+// holds. Replies are private since positions are personal.
+func (h *CommandHandler) handleMyPositions(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string) {
+	if h.accounts == nil {
+		h.respondPrivately(session, interaction, accountsDisabledMessage, nil)
+		return
+	}
+
+	accountID, err := h.accounts.LinkedAccount(ctx, userID)
+	if errors.Is(err, services.ErrAccountNotLinked) {
+		h.respondPrivately(session, interaction, "Link your Coral account first with `/link_account`", nil)
+		return
+	}
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to get your Coral account", fmt.Sprintf("Failed to get the Coral account of user %s: %v", userID, err))
+		return
+	}
+	positions, err := h.marketService.FetchPositions(ctx, accountID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to fetch your positions from Coral Markets", fmt.Sprintf("Failed to fetch positions of account %s: %v", accountID, err))
+		return
+	}
+	if len(positions) == 0 {
+		h.respondPrivately(session, interaction, "You have no open positions on Coral Markets", nil)
+		return
+	}
+
+	embed := h.marketService.CreatePositionsEmbed(positions)
+	for _, field := range embed.Fields {
+		field.Value = h.linkDecorator.Decorate(ctx, field.Value, interaction.ChannelID)
+	}
+	err = session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to respond to interaction: %v", err))
+	}
+}
+
+// describePushMode explains when notifications are pushed to a linked Coral account
+func describePushMode(mode string) string {
+	switch mode {
+	case models.PushAlways:
+		return "pushed instead of DMed"
+	case models.PushFallback:
+		return "pushed when a DM cannot be delivered"
+	}
+	return "never pushed"
+}
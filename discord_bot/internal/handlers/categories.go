@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxAutocompleteChoices is the number of choices Discord shows for an autocompleted option
+const maxAutocompleteChoices = 25
+
+// maxChoiceLength is the longest name and value Discord accepts for an option choice
+const maxChoiceLength = 100
+
+// SetCategoryCatalog sets the backend's category list, used to complete and check the categories of
+// channel_feed_categories and the channel setup form
+func (h *CommandHandler) SetCategoryCatalog(categories *services.CategoryCatalog) {
+	h.categories = categories
+}
+
+// resolveCategories replaces each category with the backend's spelling of it. It returns a message
+// naming the unknown categories, with suggestions, when some are not known. Without a category
+// list, or while the backend cannot be reached, the categories are kept as typed.
+func (h *CommandHandler) resolveCategories(ctx context.Context, categories []string) ([]string, string) {
+	if h.categories == nil || len(categories) == 0 {
+		return categories, ""
+	}
+	resolved, unknown, err := h.categories.Resolve(ctx, categories)
+	if err != nil {
+		h.logger.Warning(fmt.Sprintf("Not checking categories, the category list is unavailable: %v", err))
+		return categories, ""
+	}
+	if len(unknown) == 0 {
+		return resolved, ""
+	}
+
+	var problems []string
+	for _, category := range unknown {
+		problem := fmt.Sprintf("%q is not a market category", category.Name)
+		if len(category.Suggestions) > 0 {
+			problem += fmt.Sprintf(" (did you mean %s?)", strings.Join(category.Suggestions, ", "))
+		}
+		problems = append(problems, problem)
+	}
+	return nil, strings.Join(problems, "; ")
+}
+
+// handleAutocomplete suggests values for the option being typed
+func (h *CommandHandler) handleAutocomplete(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	ctx, cancel := context.WithTimeout(context.Background(), interactionTimeout)
+	defer cancel()
+
+	command := interaction.ApplicationCommandData()
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, option := range command.Options {
+		if option.Focused && command.Name == "channel_feed_categories" && option.Name == "categories" {
+			choices = h.categoryChoices(ctx, option.StringValue())
+		}
+	}
+
+	err := session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	})
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to respond to autocomplete for %s: %v", command.Name, err))
+	}
+}
+
+// categoryChoices completes the last entry of a comma-separated category list, keeping the entries
+// before it and leaving out categories already listed
+func (h *CommandHandler) categoryChoices(ctx context.Context, typed string) []*discordgo.ApplicationCommandOptionChoice {
+	if h.categories == nil {
+		return nil
+	}
+	entries := parseCategories(typed)
+	partial := ""
+	if len(entries) > 0 && !strings.HasSuffix(strings.TrimSpace(typed), ",") {
+		partial, entries = entries[len(entries)-1], entries[:len(entries)-1]
+	}
+	listed := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		listed[models.NormalizeCategory(entry)] = true
+	}
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, category := range h.categories.Complete(ctx, partial, maxAutocompleteChoices+len(entries)) {
+		if listed[models.NormalizeCategory(category)] || len(choices) == maxAutocompleteChoices {
+			continue
+		}
+		value := strings.Join(append(append([]string{}, entries...), category), ", ")
+		if len(value) > maxChoiceLength {
+			continue
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: value, Value: value})
+	}
+	return choices
+}
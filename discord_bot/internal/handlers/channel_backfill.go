@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"coral-bot/discord_bot/internal/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// minBackfillHours is the lowest value of the channel_backfill hours option
+var minBackfillHours = 1.0
+
+// SetChannelBackfillService sets the service posting the digests of the channel_backfill command
+func (h *CommandHandler) SetChannelBackfillService(backfill services.ChannelBackfillService) {
+	h.backfill = backfill
+}
+
+// handleChannelBackfill handles the channel_backfill command, posting the markets created in the last
+// hours that the channel has not announced yet
+func (h *CommandHandler) handleChannelBackfill(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID string, hours int) {
+	if h.backfill == nil {
+		h.respondToInteraction(session, interaction, "Backfills are not enabled on this bot")
+		return
+	}
+
+	listed, err := h.backfill.Backfill(ctx, channelID, hours)
+	switch {
+	case errors.Is(err, services.ErrInvalidBackfillWindow):
+		h.respondToInteraction(session, interaction, fmt.Sprintf("Invalid window: %s", strings.TrimPrefix(err.Error(), services.ErrInvalidBackfillWindow.Error()+": ")))
+		return
+	case errors.Is(err, services.ErrBackfillTooSoon):
+		h.respondToInteraction(session, interaction, fmt.Sprintf("This channel was backfilled less than %d minutes ago, try again later", int(services.BackfillCooldown.Minutes())))
+		return
+	case err != nil:
+		h.respondFailure(ctx, session, interaction, "Failed to backfill this channel", fmt.Sprintf("Failed to backfill channel %s: %v", channelID, err))
+		return
+	case listed == 0:
+		h.respondToInteraction(session, interaction, fmt.Sprintf("No markets from the last %d hours to catch up on: none match this channel's settings, or they were already posted here", hours))
+		return
+	}
+	h.respondToInteraction(session, interaction, fmt.Sprintf("Posted %d %s from the last %d hours", listed, pluralize(listed, "market"), hours))
+}
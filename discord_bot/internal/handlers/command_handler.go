@@ -1,37 +1,139 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"coral-bot/discord_bot/internal/models"
 	"coral-bot/discord_bot/internal/services"
+	"coral-bot/discord_bot/internal/tracing"
 	"coral-bot/discord_bot/internal/utils"
 
 	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// interactionTimeout bounds the service calls made while handling a slash command
+const interactionTimeout = 2500 * time.Millisecond
+
 // CommandHandler handles Discord slash commands
 type CommandHandler struct {
 	marketService       services.MarketService
 	subscriptionService services.SubscriptionService
+	reminderService     services.ReminderService
+	analyticsService    services.AnalyticsService
+	testEventSender     TestEventSender                 // nil until the web server is wired in
+	userDataService     services.UserDataService        // nil disables admin_user_data
+	deadLetters         services.DeadLetterService      // nil disables admin_dead_letters
+	maintenance         *services.MaintenanceMode       // nil disables admin_maintenance and never answers with a maintenance notice
+	boards              services.MarketBoardService     // nil when market boards are not kept
+	calendar            services.CalendarService        // nil when the calendar integration is off
+	email               services.EmailService           // nil when email notifications are off
+	accounts            services.AccountService         // nil when Coral accounts cannot be linked
+	pushEnabled         bool                            // linked accounts can get notifications pushed
+	trading             services.TradingService         // nil when trading from Discord is off
+	game                services.GameService            // nil when the prediction game is off
+	stats               services.CommunityStatsService  // nil when community stats are not posted
+	backfill            services.ChannelBackfillService // nil when channels cannot be backfilled
+	categories          *services.CategoryCatalog       // nil accepts any category name
+	linkDecorator       *services.LinkDecorator         // nil shows links as the backend sent them
+	owners              map[string]bool                 // Discord user IDs allowed to run bot owner commands
+	commandGuildID      string                          // guild the commands are registered in, empty for global commands
+	syncMutex           sync.Mutex                      // held while the commands are synced with Discord
+	commandPrefix       string                          // prefix of message commands, empty when they are off
 	logger              *utils.Logger
 }
 
+// TestEventSender delivers a synthetic market event to a single channel
+type TestEventSender interface {
+	SendTestEvent(ctx context.Context, eventType, channelID string) error
+}
+
 // NewCommandHandler creates a new command handler
 func NewCommandHandler(
 	marketService services.MarketService,
 	subscriptionService services.SubscriptionService,
+	reminderService services.ReminderService,
+	analyticsService services.AnalyticsService,
 	logger *utils.Logger,
 ) *CommandHandler {
 	return &CommandHandler{
 		marketService:       marketService,
 		subscriptionService: subscriptionService,
+		reminderService:     reminderService,
+		analyticsService:    analyticsService,
 		logger:              logger,
 	}
 }
 
-// RegisterCommands registers all slash commands with Discord
-func (h *CommandHandler) RegisterCommands(session *discordgo.Session) error {
+// SetTestEventSender sets the sender used by the test_announcement command
+func (h *CommandHandler) SetTestEventSender(sender TestEventSender) {
+	h.testEventSender = sender
+}
+
+// SetUserDataService sets the service used by the admin_user_data command
+func (h *CommandHandler) SetUserDataService(userDataService services.UserDataService) {
+	h.userDataService = userDataService
+}
+
+// SetDeadLetterService sets the dead-letter store inspected by the admin_dead_letters command
+func (h *CommandHandler) SetDeadLetterService(deadLetters services.DeadLetterService) {
+	h.deadLetters = deadLetters
+}
+
+// SetMarketBoardService sets the service keeping the pinned market boards of the channel_board command
+func (h *CommandHandler) SetMarketBoardService(boards services.MarketBoardService) {
+	h.boards = boards
+}
+
+// SetCalendarService sets the service behind events_calendar and channel_calendar
+func (h *CommandHandler) SetCalendarService(calendar services.CalendarService) {
+	h.calendar = calendar
+}
+
+// SetEmailService sets the service behind link_email, verify_email and email_notifications
+func (h *CommandHandler) SetEmailService(email services.EmailService) {
+	h.email = email
+}
+
+// SetAccountService sets the service behind link_account, my_positions and push_notifications
+func (h *CommandHandler) SetAccountService(accounts services.AccountService) {
+	h.accounts = accounts
+}
+
+// SetPushEnabled turns on push_notifications, for bots that push notifications to linked accounts
+func (h *CommandHandler) SetPushEnabled(enabled bool) {
+	h.pushEnabled = enabled
+}
+
+// SetLinkDecorator sets the decorator adding click tracking to the links of market replies
+func (h *CommandHandler) SetLinkDecorator(decorator *services.LinkDecorator) {
+	h.linkDecorator = decorator
+}
+
+// SetOwners sets the Discord user IDs allowed to run bot owner commands
+func (h *CommandHandler) SetOwners(userIDs []string) {
+	h.owners = make(map[string]bool, len(userIDs))
+	for _, userID := range userIDs {
+		h.owners[userID] = true
+	}
+}
+
+// minClosingSoonHours is the lowest value of the channel_closing_soon hours option, which turns the feed off
+var minClosingSoonHours = 0.0
+
+// manageGuildPermission restricts server-wide setup commands to members who can manage the guild
+var manageGuildPermission int64 = discordgo.PermissionManageServer
+
+// Commands returns the slash commands the bot offers
+func (h *CommandHandler) Commands() []*discordgo.ApplicationCommand {
 	commands := []*discordgo.ApplicationCommand{
 		{
 			Name:        "subscribe_market",
@@ -81,6 +183,48 @@ func (h *CommandHandler) RegisterCommands(session *discordgo.Session) error {
 				},
 			},
 		},
+		{
+			Name:        "subscribe_outcome",
+			Description: "Get notified when a market outcome moves significantly or wins",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "market_id",
+					Description: "The ID of the market",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "outcome",
+					Description: "The outcome to follow (e.g. Yes)",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionNumber,
+					Name:        "min_change",
+					Description: "Minimum probability move in percentage points (default 5)",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "unsubscribe_outcome",
+			Description: "Stop following a market outcome",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "market_id",
+					Description: "The ID of the market",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "outcome",
+					Description: "The outcome to stop following",
+					Required:    true,
+				},
+			},
+		},
 		{
 			Name:        "list_subscriptions",
 			Description: "List all your current subscriptions",
@@ -97,6 +241,304 @@ func (h *CommandHandler) RegisterCommands(session *discordgo.Session) error {
 				},
 			},
 		},
+		{
+			Name:        "leaderboard",
+			Description: "Show the most-followed markets and creators",
+		},
+		{
+			Name:        "events_calendar",
+			Description: "Browse upcoming events like elections and matches, and the markets about them",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "event",
+					Description: "The ID or name of an event, to list its markets",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "history",
+			Description: "Show how a market's odds moved recently",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "market_id",
+					Description: "The ID of the market",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "period",
+					Description: "How far back to look (default day)",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "day", Value: "day"},
+						{Name: "week", Value: "week"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "remind_me",
+			Description: "Get a DM reminder before a market closes",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "market_id",
+					Description: "The ID of the market to be reminded about",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "duration",
+					Description: "How long before the close to remind you (e.g. 24h, 1h, 30m)",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "min_buy",
+			Description: "Only notify me about buys of at least this amount",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionNumber,
+					Name:        "amount",
+					Description: "Minimum buy amount in dollars (0 for all buys)",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "set_timezone",
+			Description: "Show close and resolution times in a timezone of your choice",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "timezone",
+					Description: "An IANA timezone such as Europe/Berlin or America/New_York, or off for your Discord locale",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "scope",
+					Description: "Set it for yourself or for messages posted in this channel (default me)",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "me", Value: "me"},
+						{Name: "channel", Value: "channel"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "snooze",
+			Description: "Pause your notification DMs for a while",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "duration",
+					Description: "How long to pause, e.g. 2h, 90m or 3d (up to 30d), or off to resume now",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "activity",
+			Description: "Get comments and other activity on the markets you subscribed to",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "setting",
+					Description: "Turn activity notifications on or off",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "on", Value: "on"},
+						{Name: "off", Value: "off"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "link_email",
+			Description: "Link an email address to get digests and resolution notifications by email",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "address",
+					Description: "The address to send a verification code to, or off to unlink it",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "verify_email",
+			Description: "Finish linking your email address with the code it was sent",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "code",
+					Description: "The 6-digit code from the verification email",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "email_notifications",
+			Description: "Choose what the bot emails to your linked address",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "digest",
+					Description: "Email a roundup of the markets daily or weekly",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "off", Value: "off"},
+						{Name: "daily", Value: "daily"},
+						{Name: "weekly", Value: "weekly"},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "resolutions",
+					Description: "Email market resolutions and cancellations instead of DMing them (default off)",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "on", Value: "on"},
+						{Name: "off", Value: "off"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "link_account",
+			Description: "Link your Coral account to see your positions and get notifications on your devices",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "action",
+					Description: "Get a code to enter on Coral, or unlink your account (default link)",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "link", Value: "link"},
+						{Name: "unlink", Value: "unlink"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "my_positions",
+			Description: "Show the stake, value and P&L of your linked Coral account's positions",
+		},
+		{
+			Name:        "push_notifications",
+			Description: "Choose when notifications are pushed to your linked Coral account",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "mode",
+					Description: "Push instead of DMing, only when a DM cannot be delivered, or never",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "instead of DMs", Value: "always"},
+						{Name: "when DMs fail", Value: "fallback"},
+						{Name: "off", Value: "off"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "watchlist",
+			Description: "Organize markets into named watchlists with their own notification settings",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "create",
+					Description: "Create a watchlist",
+					Options:     []*discordgo.ApplicationCommandOption{watchlistNameOption()},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "add",
+					Description: "Add a market to a watchlist",
+					Options:     []*discordgo.ApplicationCommandOption{watchlistNameOption(), watchlistMarketOption()},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "remove",
+					Description: "Remove a market from a watchlist",
+					Options:     []*discordgo.ApplicationCommandOption{watchlistNameOption(), watchlistMarketOption()},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "show",
+					Description: "Show a watchlist's markets and notification settings",
+					Options:     []*discordgo.ApplicationCommandOption{watchlistNameOption()},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "list",
+					Description: "List your watchlists",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "settings",
+					Description: "Change which of a watchlist's events notify you",
+					Options: []*discordgo.ApplicationCommandOption{
+						watchlistNameOption(),
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "notify",
+							Description: "Which events to DM you about",
+							Required:    false,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "all events", Value: models.WatchlistNotifyAll},
+								{Name: "resolutions and trading starts only", Value: models.WatchlistNotifyMajor},
+								{Name: "off", Value: models.WatchlistNotifyOff},
+							},
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionNumber,
+							Name:        "min_buy",
+							Description: "Minimum buy amount in dollars (0 for all buys)",
+							Required:    false,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "delete",
+					Description: "Delete a watchlist",
+					Options:     []*discordgo.ApplicationCommandOption{watchlistNameOption()},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "share",
+					Description: "Get a code others can use to import a copy of a watchlist",
+					Options:     []*discordgo.ApplicationCommandOption{watchlistNameOption()},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "import",
+					Description: "Copy a shared watchlist into your watchlists",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "code",
+							Description: "The share code, e.g. K7QX-2M9P",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "name",
+							Description: "A name for your copy (default: the shared watchlist's name)",
+							Required:    false,
+							MaxLength:   services.MaxWatchlistNameLength,
+						},
+					},
+				},
+			},
+		},
 		{
 			Name:        "help",
 			Description: "Display help information",
@@ -122,13 +564,18 @@ func (h *CommandHandler) RegisterCommands(session *discordgo.Session) error {
 			Description: "Set allowed categories for market feed in this channel",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "categories",
-					Description: "Comma-separated list of allowed categories",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "categories",
+					Description:  "Comma-separated list of allowed categories, leave out to edit the list in a form",
+					Required:     false,
+					Autocomplete: true,
 				},
 			},
 		},
+		{
+			Name:        "channel_setup",
+			Description: "Set this channel's categories, frequency, minimum volume and minimum buy in one form",
+		},
 		{
 			Name:        "channel_feed_frequency",
 			Description: "Set the frequency of market updates in this channel",
@@ -136,306 +583,2096 @@ func (h *CommandHandler) RegisterCommands(session *discordgo.Session) error {
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
 					Name:        "level",
-					Description: "low, medium, or high",
+					Description: "low, medium, high, or custom",
 					Required:    true,
 					Choices: []*discordgo.ApplicationCommandOptionChoice{
 						{Name: "low", Value: "low"},
 						{Name: "medium", Value: "medium"},
 						{Name: "high", Value: "high"},
+						{Name: "custom", Value: models.FrequencyCustom},
 					},
 				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "interval",
+					Description: "How often the custom level updates, like 45m or 2h",
+					Required:    false,
+				},
 			},
 		},
 		{
-			Name:        "channel_settings",
-			Description: "Display current channel settings",
+			Name:        "channel_feed_ping",
+			Description: "Mention a role in this channel's alerts of some events, or nobody without a role",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionRole,
+					Name:        "role",
+					Description: "The role to mention, leave out to stop mentioning",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "events",
+					Description: "Comma-separated events, like new_market,market_resolved (the default)",
+					Required:    false,
+				},
+			},
 		},
-	}
-
-	h.logger.Info("Registering commands...")
-	registeredCommands := make([]*discordgo.ApplicationCommand, len(commands))
-	for i, cmd := range commands {
-		rc, err := session.ApplicationCommandCreate(session.State.User.ID, "", cmd)
-		if err != nil {
-			h.logger.Error(fmt.Sprintf("Cannot create '%s' command: %v", cmd.Name, err))
-			return err
-		}
-		registeredCommands[i] = rc
-	}
-
-	h.logger.Info(fmt.Sprintf("Successfully registered %d commands", len(registeredCommands)))
-	return nil
-}
-
-// HandleInteraction handles incoming slash command interactions
-func (h *CommandHandler) HandleInteraction(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
-	if interaction.Type != discordgo.InteractionApplicationCommand {
-		return
-	}
-
-	command := interaction.ApplicationCommandData()
-	userID := interaction.Member.User.ID
-
-	h.logger.Info(fmt.Sprintf("Handling command: %s from user: %s", command.Name, userID))
-
-	switch command.Name {
-	case "subscribe_market":
-		h.handleSubscribeMarket(session, interaction, userID, command.Options[0].StringValue())
-	case "unsubscribe_market":
-		h.handleUnsubscribeMarket(session, interaction, userID, command.Options[0].StringValue())
-	case "subscribe_creator":
-		h.handleSubscribeCreator(session, interaction, userID, command.Options[0].StringValue())
-	case "unsubscribe_creator":
-		h.handleUnsubscribeCreator(session, interaction, userID, command.Options[0].StringValue())
-	case "list_subscriptions":
-		h.handleListSubscriptions(session, interaction, userID)
-	case "market":
-		h.handleGetMarket(session, interaction, command.Options[0].StringValue())
-	case "help":
-		h.handleHelp(session, interaction)
-	case "channel_feed_new_markets":
-		h.handleChannelFeedNewMarkets(session, interaction, interaction.ChannelID, command.Options[0].StringValue())
-	case "channel_feed_categories":
-		h.handleChannelFeedCategories(session, interaction, interaction.ChannelID, command.Options[0].StringValue())
+		{
+			Name:        "channel_subscribe_market",
+			Description: "Post updates for a specific market in this channel",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "market_id",
+					Description: "The ID of the market to follow in this channel",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "channel_unsubscribe_market",
+			Description: "Stop posting updates for a specific market in this channel",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "market_id",
+					Description: "The ID of the market to stop following in this channel",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "channel_remind",
+			Description: "Post a reminder in this channel before a market closes",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "market_id",
+					Description: "The ID of the market to remind the channel about",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "duration",
+					Description: "How long before the close to post the reminder (e.g. 24h, 1h, 30m)",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "channel_min_buy",
+			Description: "Only post buys of at least this amount in this channel",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionNumber,
+					Name:        "amount",
+					Description: "Minimum buy amount in dollars (0 for all buys)",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "channel_settings",
+			Description: "Display current channel settings",
+		},
+		{
+			Name:        "channel_closing_soon",
+			Description: "Announce markets in this channel when they enter their final hours",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "hours",
+					Description: fmt.Sprintf("How many hours before the close to announce a market (0 to turn off, default %d)", models.DefaultClosingSoonHours),
+					Required:    true,
+					MinValue:    &minClosingSoonHours,
+					MaxValue:    models.MaxClosingSoonHours,
+				},
+			},
+		},
+		{
+			Name:        "channel_backfill",
+			Description: "Post the markets created recently that this channel has not announced yet",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "hours",
+					Description: fmt.Sprintf("How many hours back to look, up to %d", services.MaxBackfillHours),
+					Required:    true,
+					MinValue:    &minBackfillHours,
+					MaxValue:    services.MaxBackfillHours,
+				},
+			},
+		},
+		{
+			Name:        "channel_digest",
+			Description: "Post a daily or weekly market roundup in this channel",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "schedule",
+					Description: "How often to post the roundup",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "daily", Value: models.DigestDaily},
+						{Name: "weekly", Value: models.DigestWeekly},
+						{Name: "off", Value: "off"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "channel_crosspost",
+			Description: "Publish alerts in this announcement channel to the servers following it",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "setting",
+					Description: "on or off",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "on", Value: "on"},
+						{Name: "off", Value: "off"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "channel_board",
+			Description: "Keep a pinned board of the top active markets in this channel",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "setting",
+					Description: "on or off",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "on", Value: "on"},
+						{Name: "off", Value: "off"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "channel_liquidity",
+			Description: "Post liquidity added to or removed from markets in this channel",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "setting",
+					Description: "on or off",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "on", Value: "on"},
+						{Name: "off", Value: "off"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "channel_shadow_mode",
+			Description: "Log this channel's alerts instead of posting them, to check its setup",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "setting",
+					Description: "on or off",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "on", Value: "on"},
+						{Name: "off", Value: "off"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "channel_calendar",
+			Description: "Post a digest of the markets about each upcoming event, like an election or a match",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "setting",
+					Description: "on or off",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "on", Value: "on"},
+						{Name: "off", Value: "off"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "channel_quiet_hours",
+			Description: "Hold market events during quiet hours and post a summary when they end",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "hours",
+					Description: "Quiet window in the channel's timezone, such as 23:00-08:00, or off",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:                     "channel_settings_copy",
+			Description:              "Copy another channel's settings to this channel",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         "source_channel",
+					Description:  "The channel to copy settings from",
+					Required:     true,
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews},
+				},
+			},
+		},
+		{
+			Name:                     "channel_audit",
+			Description:              "Show recent changes to this channel's settings and webhooks",
+			DefaultMemberPermissions: &manageGuildPermission,
+		},
+		{
+			Name:                     "setup",
+			Description:              "Set the default announcements channel for this server",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         "channel",
+					Description:  "The channel to post market announcements in",
+					Required:     true,
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews},
+				},
+			},
+		},
+		{
+			Name:                     "test_announcement",
+			Description:              "Post a sample market event to check that announcements reach a channel",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "event",
+					Description: "The kind of event to send (default: new market)",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "new market", Value: models.EventNewMarket},
+						{Name: "market update", Value: models.EventMarketUpdate},
+						{Name: "trading started", Value: models.EventTradingStarted},
+						{Name: "trading ended", Value: models.EventTradingEnded},
+						{Name: "market resolved", Value: models.EventMarketResolved},
+						{Name: "market buy", Value: models.EventMarketBuy},
+					},
+				},
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         "channel",
+					Description:  "The channel to post in (default: this channel)",
+					Required:     false,
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews},
+				},
+			},
+		},
+		{
+			Name:                     "route_category",
+			Description:              "Announce this server's new markets of a category in one channel only",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "category",
+					Description: "The market category, e.g. politics",
+					Required:    true,
+				},
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         "channel",
+					Description:  "The channel new markets of the category are announced in",
+					Required:     true,
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews},
+				},
+			},
+		},
+		{
+			Name:                     "unroute_category",
+			Description:              "Announce a category's new markets in every feed channel again",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "category",
+					Description: "The market category",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:                     "trading_buttons",
+			Description:              "Show buy buttons under this server's market alerts for members with a linked Coral account",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "setting",
+					Description: "Turn buy buttons on or off",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "on", Value: "on"},
+						{Name: "off", Value: "off"},
+					},
+				},
+			},
+		},
+		{
+			Name:                     "game_mode",
+			Description:              "Let this server's members bet play money on market alerts",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "setting",
+					Description: "Turn the prediction game on or off",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "on", Value: "on"},
+						{Name: "off", Value: "off"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "game_leaderboard",
+			Description: "Show the standings of this server's prediction game",
+		},
+		{
+			Name:                     "community_stats",
+			Description:              "Post this server's community stats in this channel every week",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "setting",
+					Description: "Turn the weekly stats on or off, or preview this week's",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "on", Value: "on"},
+						{Name: "off", Value: "off"},
+						{Name: "preview", Value: "preview"},
+					},
+				},
+			},
+		},
+		{
+			Name:                     "category_routes",
+			Description:              "List where this server's new markets are announced by category",
+			DefaultMemberPermissions: &manageGuildPermission,
+		},
+		{
+			Name:                     "routing_rules",
+			Description:              "Decide which market alerts this server's channels post with ordered rules",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "add",
+					Description: "Add a rule; the first rule matching an event decides what happens to it",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "action",
+							Description: "What happens to the events the rule matches",
+							Required:    true,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "send, whatever the channel's filters", Value: models.RuleActionSend},
+								{Name: "suppress", Value: models.RuleActionSuppress},
+								{Name: "ping a role", Value: models.RuleActionPing},
+								{Name: "route to another channel", Value: models.RuleActionRoute},
+							},
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "scope",
+							Description: "The channels the rule applies to (default: this channel)",
+							Required:    false,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "this channel", Value: "channel"},
+								{Name: "every channel of the server", Value: "server"},
+							},
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "event_types",
+							Description: "Comma-separated events to match, e.g. new_market, market_resolved",
+							Required:    false,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "categories",
+							Description: "Comma-separated market categories to match",
+							Required:    false,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "creators",
+							Description: "Comma-separated market creators to match",
+							Required:    false,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "keywords",
+							Description: "Comma-separated words to match in market titles",
+							Required:    false,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionNumber,
+							Name:        "min_volume",
+							Description: "Only match markets with at least this volume",
+							Required:    false,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionNumber,
+							Name:        "max_volume",
+							Description: "Only match markets with at most this volume",
+							Required:    false,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionRole,
+							Name:        "role",
+							Description: "The role ping rules mention",
+							Required:    false,
+						},
+						{
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							Name:         "target",
+							Description:  "The channel route rules post in",
+							Required:     false,
+							ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews},
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "position",
+							Description: "Where the rule goes in the list (default: last)",
+							Required:    false,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "remove",
+					Description: "Remove a rule",
+					Options:     []*discordgo.ApplicationCommandOption{routingRulePositionOption("position", "The rule's position in /routing_rules list")},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "move",
+					Description: "Move a rule up or down the list",
+					Options: []*discordgo.ApplicationCommandOption{
+						routingRulePositionOption("position", "The rule's position in /routing_rules list"),
+						routingRulePositionOption("new_position", "The position to move it to"),
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "list",
+					Description: "List this server's rules in the order they are evaluated",
+				},
+			},
+		},
+		{
+			Name:        "admin_user_data",
+			Description: "Export or delete everything the bot stores about a user (bot owners only)",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "user_id",
+					Description: "The Discord user ID",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "delete",
+					Description: "Delete the data after exporting it (default: false)",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "admin_maintenance",
+			Description: "Pause notifications and answer commands with a notice, or end maintenance (bot owners only)",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "action",
+					Description: "What to do (default: status)",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "status", Value: "status"},
+						{Name: "on", Value: "on"},
+						{Name: "off", Value: "off"},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "notice",
+					Description: "The reply to commands during maintenance",
+					Required:    false,
+					MaxLength:   services.MaxMaintenanceNoticeLength,
+				},
+			},
+		},
+		{
+			Name:        "admin_resync_commands",
+			Description: "Sync the bot's slash commands with Discord again (bot owners only)",
+		},
+		{
+			Name:        "admin_dead_letters",
+			Description: "List, retry or discard messages that failed to send (bot owners only)",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "action",
+					Description: "What to do (default: list)",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "list", Value: "list"},
+						{Name: "retry", Value: "retry"},
+						{Name: "discard", Value: "discard"},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "id",
+					Description: "The dead letter ID, required to retry or discard",
+					Required:    false,
+				},
+			},
+		},
+	}
+
+	// User commands work in DMs; channel and server admin commands only make sense inside a guild
+	for _, cmd := range commands {
+		allowInDM := !guildOnlyCommand(cmd.Name)
+		cmd.DMPermission = &allowInDM
+	}
+	return commands
+}
+
+// HandleInteraction handles incoming slash command interactions
+func (h *CommandHandler) HandleInteraction(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	switch interaction.Type {
+	case discordgo.InteractionApplicationCommand, discordgo.InteractionModalSubmit, discordgo.InteractionMessageComponent:
+		if notice, ok := h.maintenanceNotice(interactionName(interaction)); ok {
+			h.respondPrivately(session, interaction, notice, nil)
+			return
+		}
+	}
+
+	switch interaction.Type {
+	case discordgo.InteractionApplicationCommand:
+	case discordgo.InteractionModalSubmit:
+		h.handleModalSubmit(session, interaction)
+		return
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		h.handleAutocomplete(session, interaction)
+		return
+	case discordgo.InteractionMessageComponent:
+		h.handleComponent(session, interaction)
+		return
+	default:
+		return
+	}
+
+	command := interaction.ApplicationCommandData()
+	userID := interactionUserID(interaction)
+	if userID == "" {
+		h.logger.Warning(fmt.Sprintf("Ignoring command %s without a user", command.Name))
+		return
+	}
+
+	// Discord expects a response within three seconds, so bound the work and leave time to reply
+	ctx, cancel := context.WithTimeout(context.Background(), interactionTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "discord.command."+command.Name, attribute.String("discord.user_id", userID))
+	defer span.End()
+	defer h.recoverInteraction(ctx, span, session, interaction, command.Name, userID)
+
+	h.logger.Info(fmt.Sprintf("Handling command: %s from user: %s", command.Name, userID))
+	h.analyticsService.RecordCommand(ctx, command.Name, userID)
+
+	if interaction.GuildID == "" && guildOnlyCommand(command.Name) {
+		h.respondPrivately(session, interaction, "This command can only be used in a server channel", nil)
+		return
+	}
+
+	switch command.Name {
+	case "subscribe_market":
+		h.handleSubscribeMarket(ctx, session, interaction, userID, command.Options[0].StringValue())
+	case "unsubscribe_market":
+		h.handleUnsubscribeMarket(ctx, session, interaction, userID, command.Options[0].StringValue())
+	case "subscribe_creator":
+		h.handleSubscribeCreator(ctx, session, interaction, userID, command.Options[0].StringValue())
+	case "unsubscribe_creator":
+		h.handleUnsubscribeCreator(ctx, session, interaction, userID, command.Options[0].StringValue())
+	case "subscribe_outcome":
+		minChange := 0.0
+		if option := findOption(command.Options, "min_change"); option != nil {
+			minChange = option.FloatValue()
+		}
+		h.handleSubscribeOutcome(ctx, session, interaction, userID, command.Options[0].StringValue(), command.Options[1].StringValue(), minChange)
+	case "unsubscribe_outcome":
+		h.handleUnsubscribeOutcome(ctx, session, interaction, userID, command.Options[0].StringValue(), command.Options[1].StringValue())
+	case "list_subscriptions":
+		h.handleListSubscriptions(ctx, session, interaction, userID)
+	case "market":
+		h.handleGetMarket(ctx, session, interaction, command.Options[0].StringValue())
+	case "leaderboard":
+		h.handleLeaderboard(ctx, session, interaction)
+	case "events_calendar":
+		query := ""
+		if option := findOption(command.Options, "event"); option != nil {
+			query = option.StringValue()
+		}
+		h.handleEventsCalendar(ctx, session, interaction, query)
+	case "history":
+		period := "day"
+		if option := findOption(command.Options, "period"); option != nil {
+			period = option.StringValue()
+		}
+		h.handleHistory(ctx, session, interaction, command.Options[0].StringValue(), period)
+	case "remind_me":
+		h.handleRemindMe(ctx, session, interaction, userID, command.Options[0].StringValue(), command.Options[1].StringValue())
+	case "min_buy":
+		h.handleMinBuy(ctx, session, interaction, userID, command.Options[0].FloatValue())
+	case "set_timezone":
+		scope := "me"
+		if option := findOption(command.Options, "scope"); option != nil {
+			scope = option.StringValue()
+		}
+		h.handleSetTimezone(ctx, session, interaction, userID, command.Options[0].StringValue(), scope)
+	case "snooze":
+		h.handleSnooze(ctx, session, interaction, userID, command.Options[0].StringValue())
+	case "activity":
+		h.handleActivity(ctx, session, interaction, userID, command.Options[0].StringValue() == "on")
+	case "link_email":
+		h.handleLinkEmail(ctx, session, interaction, userID, command.Options[0].StringValue())
+	case "verify_email":
+		h.handleVerifyEmail(ctx, session, interaction, userID, command.Options[0].StringValue())
+	case "email_notifications":
+		resolutions := false
+		if option := findOption(command.Options, "resolutions"); option != nil {
+			resolutions = option.StringValue() == "on"
+		}
+		h.handleEmailNotifications(ctx, session, interaction, userID, command.Options[0].StringValue(), resolutions)
+	case "link_account":
+		unlink := false
+		if option := findOption(command.Options, "action"); option != nil {
+			unlink = option.StringValue() == "unlink"
+		}
+		h.handleLinkAccount(ctx, session, interaction, userID, unlink)
+	case "my_positions":
+		h.handleMyPositions(ctx, session, interaction, userID)
+	case "push_notifications":
+		h.handlePushNotifications(ctx, session, interaction, userID, command.Options[0].StringValue())
+	case "watchlist":
+		h.handleWatchlist(ctx, session, interaction, userID, command.Options[0])
+	case "help":
+		h.handleHelp(session, interaction)
+	case "channel_feed_new_markets":
+		h.handleChannelFeedNewMarkets(ctx, session, interaction, interaction.ChannelID, command.Options[0].StringValue())
+	case "channel_feed_categories":
+		if option := findOption(command.Options, "categories"); option != nil {
+			h.handleChannelFeedCategories(ctx, session, interaction, interaction.ChannelID, option.StringValue())
+		} else {
+			h.openCategoriesModal(ctx, session, interaction, interaction.ChannelID)
+		}
+	case "channel_setup":
+		h.openChannelSetupModal(ctx, session, interaction, interaction.ChannelID)
 	case "channel_feed_frequency":
-		h.handleChannelFeedFrequency(session, interaction, interaction.ChannelID, command.Options[0].StringValue())
+		interval := ""
+		if option := findOption(command.Options, "interval"); option != nil {
+			interval = option.StringValue()
+		}
+		h.handleChannelFeedFrequency(ctx, session, interaction, interaction.ChannelID, command.Options[0].StringValue(), interval)
+	case "channel_feed_ping":
+		roleID, events := "", ""
+		if option := findOption(command.Options, "role"); option != nil {
+			roleID = option.RoleValue(nil, "").ID
+		}
+		if option := findOption(command.Options, "events"); option != nil {
+			events = option.StringValue()
+		}
+		h.handleChannelFeedPing(ctx, session, interaction, interaction.ChannelID, roleID, events)
+	case "channel_subscribe_market":
+		h.handleChannelSubscribeMarket(ctx, session, interaction, interaction.ChannelID, command.Options[0].StringValue())
+	case "channel_unsubscribe_market":
+		h.handleChannelUnsubscribeMarket(ctx, session, interaction, interaction.ChannelID, command.Options[0].StringValue())
+	case "channel_remind":
+		h.handleChannelRemind(ctx, session, interaction, interaction.ChannelID, command.Options[0].StringValue(), command.Options[1].StringValue())
+	case "channel_min_buy":
+		h.handleChannelMinBuy(ctx, session, interaction, interaction.ChannelID, command.Options[0].FloatValue())
 	case "channel_settings":
-		h.handleChannelSettings(session, interaction, interaction.ChannelID)
+		h.handleChannelSettings(ctx, session, interaction, interaction.ChannelID)
+	case "channel_closing_soon":
+		h.handleChannelClosingSoon(ctx, session, interaction, interaction.ChannelID, int(command.Options[0].IntValue()))
+	case "channel_backfill":
+		h.handleChannelBackfill(ctx, session, interaction, interaction.ChannelID, int(command.Options[0].IntValue()))
+	case "channel_digest":
+		h.handleChannelDigest(ctx, session, interaction, interaction.ChannelID, command.Options[0].StringValue())
+	case "channel_crosspost":
+		h.handleChannelCrosspost(ctx, session, interaction, interaction.ChannelID, command.Options[0].StringValue())
+	case "channel_board":
+		h.handleChannelBoard(ctx, session, interaction, interaction.ChannelID, command.Options[0].StringValue())
+	case "channel_liquidity":
+		h.handleChannelLiquidity(ctx, session, interaction, interaction.ChannelID, command.Options[0].StringValue())
+	case "channel_shadow_mode":
+		h.handleChannelShadowMode(ctx, session, interaction, interaction.ChannelID, command.Options[0].StringValue())
+	case "channel_calendar":
+		h.handleChannelCalendar(ctx, session, interaction, interaction.ChannelID, command.Options[0].StringValue())
+	case "channel_quiet_hours":
+		h.handleChannelQuietHours(ctx, session, interaction, interaction.ChannelID, command.Options[0].StringValue())
+	case "channel_settings_copy":
+		h.handleChannelSettingsCopy(ctx, session, interaction, command.Options[0].ChannelValue(nil).ID, interaction.ChannelID)
+	case "channel_audit":
+		h.handleChannelAudit(ctx, session, interaction, interaction.ChannelID)
+	case "setup":
+		h.handleSetup(ctx, session, interaction, userID, command.Options[0].ChannelValue(nil).ID)
+	case "test_announcement":
+		eventType := models.EventNewMarket
+		if option := findOption(command.Options, "event"); option != nil {
+			eventType = option.StringValue()
+		}
+		channelID := interaction.ChannelID
+		if option := findOption(command.Options, "channel"); option != nil {
+			channelID = option.ChannelValue(nil).ID
+		}
+		h.handleTestAnnouncement(ctx, session, interaction, eventType, channelID)
+	case "route_category":
+		h.handleRouteCategory(ctx, session, interaction, userID, command.Options[0].StringValue(), command.Options[1].ChannelValue(nil).ID)
+	case "unroute_category":
+		h.handleUnrouteCategory(ctx, session, interaction, userID, command.Options[0].StringValue())
+	case "category_routes":
+		h.handleCategoryRoutes(ctx, session, interaction)
+	case "routing_rules":
+		h.handleRoutingRules(ctx, session, interaction, userID, command.Options[0])
+	case "trading_buttons":
+		h.handleTradingButtons(ctx, session, interaction, userID, command.Options[0].StringValue() == "on")
+	case "game_mode":
+		h.handleGameMode(ctx, session, interaction, userID, command.Options[0].StringValue() == "on")
+	case "game_leaderboard":
+		h.handleGameLeaderboard(ctx, session, interaction, userID)
+	case "community_stats":
+		h.handleCommunityStats(ctx, session, interaction, userID, command.Options[0].StringValue())
+	case "admin_user_data":
+		purge := false
+		if option := findOption(command.Options, "delete"); option != nil {
+			purge = option.BoolValue()
+		}
+		h.handleAdminUserData(ctx, session, interaction, userID, strings.TrimSpace(command.Options[0].StringValue()), purge)
+	case "admin_dead_letters":
+		action, id := "list", ""
+		if option := findOption(command.Options, "action"); option != nil {
+			action = option.StringValue()
+		}
+		if option := findOption(command.Options, "id"); option != nil {
+			id = strings.TrimSpace(option.StringValue())
+		}
+		h.handleAdminDeadLetters(ctx, session, interaction, userID, action, id)
+	case "admin_resync_commands":
+		h.handleAdminResyncCommands(session, interaction, userID)
+	case "admin_maintenance":
+		action, notice := "status", ""
+		if option := findOption(command.Options, "action"); option != nil {
+			action = option.StringValue()
+		}
+		if option := findOption(command.Options, "notice"); option != nil {
+			notice = strings.TrimSpace(option.StringValue())
+		}
+		h.handleAdminMaintenance(session, interaction, userID, action, notice)
 	default:
 		h.respondToInteraction(session, interaction, "Unknown command")
 	}
-}
+}
+
+// interactionUserID returns the invoking user, which is on Member in guilds and on User in DMs
+func interactionUserID(interaction *discordgo.InteractionCreate) string {
+	if interaction.Member != nil && interaction.Member.User != nil {
+		return interaction.Member.User.ID
+	}
+	if interaction.User != nil {
+		return interaction.User.ID
+	}
+	return ""
+}
+
+// findOption returns the named option of a command, or nil when an optional option was omitted
+func findOption(options []*discordgo.ApplicationCommandInteractionDataOption, name string) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, option := range options {
+		if option.Name == name {
+			return option
+		}
+	}
+	return nil
+}
+
+// guildOnlyCommand reports whether a command configures a channel or server and cannot run in DMs
+func guildOnlyCommand(name string) bool {
+	switch name {
+	case "setup", "test_announcement", "route_category", "unroute_category", "category_routes", "routing_rules", "trading_buttons", "game_mode", "game_leaderboard", "community_stats":
+		return true
+	}
+	return strings.HasPrefix(name, "channel_")
+}
+
+// handleSubscribeMarket handles the subscribe_market command
+func (h *CommandHandler) handleSubscribeMarket(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, marketID string) {
+	if !h.confirmMarket(ctx, session, interaction, "subscribe_market", marketID, "") {
+		return
+	}
+
+	err := h.subscriptionService.SubscribeToMarket(ctx, userID, marketID)
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to subscribe to market", fmt.Sprintf("Failed to subscribe user %s to market %s: %v", userID, marketID, err))
+		return
+	}
+
+	response := fmt.Sprintf("You have been subscribed to market `%s`", marketID)
+	h.respondToInteraction(session, interaction, response)
+}
+
+// handleUnsubscribeMarket handles the unsubscribe_market command
+func (h *CommandHandler) handleUnsubscribeMarket(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, marketID string) {
+	err := h.subscriptionService.UnsubscribeFromMarket(ctx, userID, marketID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to unsubscribe from market", fmt.Sprintf("Failed to unsubscribe user %s from market %s: %v", userID, marketID, err))
+		return
+	}
+
+	response := fmt.Sprintf("You have been unsubscribed from market `%s`", marketID)
+	h.respondToInteraction(session, interaction, response)
+}
+
+// handleSubscribeCreator handles the subscribe_creator command
+func (h *CommandHandler) handleSubscribeCreator(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, creator string) {
+	err := h.subscriptionService.SubscribeToCreator(ctx, userID, creator)
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to subscribe to creator", fmt.Sprintf("Failed to subscribe user %s to creator %s: %v", userID, creator, err))
+		return
+	}
+
+	response := fmt.Sprintf("You have been subscribed to creator `%s`", creator)
+	h.respondToInteraction(session, interaction, response)
+}
+
+// handleUnsubscribeCreator handles the unsubscribe_creator command
+func (h *CommandHandler) handleUnsubscribeCreator(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, creator string) {
+	err := h.subscriptionService.UnsubscribeFromCreator(ctx, userID, creator)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to unsubscribe from creator", fmt.Sprintf("Failed to unsubscribe user %s from creator %s: %v", userID, creator, err))
+		return
+	}
+
+	response := fmt.Sprintf("You have been unsubscribed from creator `%s`", creator)
+	h.respondToInteraction(session, interaction, response)
+}
+
+// handleSubscribeOutcome handles the subscribe_outcome command
+func (h *CommandHandler) handleSubscribeOutcome(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, marketID, outcome string, minChange float64) {
+	err := h.subscriptionService.SubscribeToOutcome(ctx, userID, marketID, outcome, minChange)
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to subscribe to outcome", fmt.Sprintf("Failed to subscribe user %s to outcome %s of market %s: %v", userID, outcome, marketID, err))
+		return
+	}
+
+	if minChange <= 0 {
+		minChange = services.DefaultOutcomeMinChange
+	}
+	response := fmt.Sprintf("You will be notified when `%s` on market `%s` moves by %.1f points or wins", outcome, marketID, minChange)
+	h.respondToInteraction(session, interaction, response)
+}
+
+// handleUnsubscribeOutcome handles the unsubscribe_outcome command
+func (h *CommandHandler) handleUnsubscribeOutcome(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, marketID, outcome string) {
+	err := h.subscriptionService.UnsubscribeFromOutcome(ctx, userID, marketID, outcome)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to unsubscribe from outcome", fmt.Sprintf("Failed to unsubscribe user %s from outcome %s of market %s: %v", userID, outcome, marketID, err))
+		return
+	}
+
+	response := fmt.Sprintf("You have been unsubscribed from `%s` on market `%s`", outcome, marketID)
+	h.respondToInteraction(session, interaction, response)
+}
+
+// handleMinBuy handles the min_buy command
+func (h *CommandHandler) handleMinBuy(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string, amount float64) {
+	if amount < 0 {
+		h.respondToInteraction(session, interaction, "The minimum buy amount cannot be negative")
+		return
+	}
+
+	err := h.subscriptionService.SetMinBuyAmount(ctx, userID, amount)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update minimum buy amount", fmt.Sprintf("Failed to set minimum buy amount for user %s: %v", userID, err))
+		return
+	}
+
+	response := fmt.Sprintf("You will only be notified about buys of at least $%.2f", amount)
+	if amount == 0 {
+		response = "You will be notified about all buys"
+	}
+	h.respondToInteraction(session, interaction, response)
+}
+
+// handleListSubscriptions handles the list_subscriptions command
+func (h *CommandHandler) handleListSubscriptions(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string) {
+	subscription, err := h.subscriptionService.GetUserSubscriptions(ctx, userID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve subscriptions", fmt.Sprintf("Failed to get subscriptions for user %s: %v", userID, err))
+		return
+	}
+
+	if len(subscription.SubscribedMarkets) == 0 && len(subscription.SubscribedCreators) == 0 && len(subscription.SubscribedOutcomes) == 0 {
+		h.respondToInteraction(session, interaction, "You have no subscriptions")
+		return
+	}
+
+	var response strings.Builder
+	response.WriteString("**Your Subscriptions:**\n\n")
+
+	if len(subscription.SubscribedMarkets) > 0 {
+		response.WriteString("**Markets:**\n")
+		for _, marketID := range subscription.SubscribedMarkets {
+			response.WriteString(fmt.Sprintf("- `%s`\n", marketID))
+		}
+		response.WriteString("\n")
+	}
+
+	if len(subscription.SubscribedCreators) > 0 {
+		response.WriteString("**Creators:**\n")
+		for _, creator := range subscription.SubscribedCreators {
+			response.WriteString(fmt.Sprintf("- `%s`\n", creator))
+		}
+		response.WriteString("\n")
+	}
+
+	if len(subscription.SubscribedOutcomes) > 0 {
+		response.WriteString("**Outcomes:**\n")
+		for _, outcome := range subscription.SubscribedOutcomes {
+			response.WriteString(fmt.Sprintf("- `%s` on `%s` (±%.1f points)\n", outcome.Outcome, outcome.MarketID, outcome.MinChange))
+		}
+		response.WriteString("\n")
+	}
+
+	if subscription.MinBuyAmount > 0 {
+		response.WriteString(fmt.Sprintf("**Minimum Buy:** $%.2f\n", subscription.MinBuyAmount))
+	}
+	if subscription.Timezone != "" {
+		response.WriteString(fmt.Sprintf("**Timezone:** %s\n", subscription.Timezone))
+	}
+	if subscription.Snoozed(time.Now()) {
+		response.WriteString(fmt.Sprintf("**Snoozed until:** %s\n", services.DiscordTimestamp(subscription.SnoozedUntil, services.TimestampRelative)))
+	}
+	if subscription.Email.Verified() {
+		response.WriteString(fmt.Sprintf("**Email:** %s\n", describeEmailSettings(subscription.Email)))
+	}
+	if subscription.Account.Linked() {
+		response.WriteString(fmt.Sprintf("**Coral account:** %s\n", describePushMode(subscription.PushMode())))
+	}
+
+	h.respondToInteraction(session, interaction, response.String())
+}
+
+// handleGetMarket handles the market command
+func (h *CommandHandler) handleGetMarket(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, marketID string) {
+	market, err := h.marketService.FetchMarket(ctx, marketID)
+	if errors.Is(err, services.ErrBackendNotFound) {
+		h.respondUnknownMarket(ctx, session, interaction, "market", marketID, "")
+		return
+	}
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve market information", fmt.Sprintf("Failed to fetch market %s: %v", marketID, err))
+		return
+	}
+
+	announcement := h.marketService.CreateMarketAnnouncement(market)
+	h.respondLocalized(ctx, session, interaction, announcement, h.marketButtons(ctx, interaction.GuildID, market)...)
+}
+
+// respondLocalized responds with a message whose absolute times are written out in the timezone the
+// user chose with set_timezone, or left as Discord timestamps for their locale, and whose links are
+// decorated for the channel, with any components under it
+func (h *CommandHandler) respondLocalized(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, content string, components ...discordgo.MessageComponent) {
+	content = h.linkDecorator.Decorate(ctx, content, interaction.ChannelID)
+	if subscription, err := h.subscriptionService.GetUserSubscriptions(ctx, interactionUserID(interaction)); err == nil {
+		if loc, err := services.LoadTimezone(subscription.Timezone); err == nil {
+			content = services.LocalizeTimestamps(content, loc)
+		}
+	}
+	h.respondToInteraction(session, interaction, content, components...)
+}
+
+// handleSetTimezone handles the set_timezone command
+func (h *CommandHandler) handleSetTimezone(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, zone, scope string) {
+	zone = strings.TrimSpace(zone)
+	if strings.EqualFold(zone, "off") {
+		zone = ""
+	}
+	if _, err := services.LoadTimezone(zone); err != nil {
+		h.respondToInteraction(session, interaction, fmt.Sprintf("`%s` is not a timezone I know, use an IANA name such as `Europe/Berlin` or `America/New_York`", zone))
+		return
+	}
+
+	if scope == "channel" {
+		if interaction.GuildID == "" {
+			h.respondToInteraction(session, interaction, "A channel timezone can only be set in a server channel")
+			return
+		}
+		if interaction.Member == nil || interaction.Member.Permissions&discordgo.PermissionManageServer == 0 {
+			h.respondToInteraction(session, interaction, "You need the Manage Server permission to set this channel's timezone")
+			return
+		}
+		if err := h.subscriptionService.SetChannelTimezone(ctx, interaction.ChannelID, interaction.GuildID, zone, userID); err != nil {
+			h.respondChangeFailure(ctx, session, interaction, err, "Failed to update channel settings", fmt.Sprintf("Failed to set timezone for channel %s: %v", interaction.ChannelID, err))
+			return
+		}
+		if zone == "" {
+			h.respondToInteraction(session, interaction, "Times posted in this channel will be shown in each reader's Discord locale")
+			return
+		}
+		h.respondToInteraction(session, interaction, fmt.Sprintf("Times posted in this channel will be shown in `%s`", zone))
+		return
+	}
+
+	if err := h.subscriptionService.SetUserTimezone(ctx, userID, zone); err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update your timezone", fmt.Sprintf("Failed to set timezone for user %s: %v", userID, err))
+		return
+	}
+	if zone == "" {
+		h.respondToInteraction(session, interaction, "Times in your messages will be shown in your Discord locale")
+		return
+	}
+	h.respondToInteraction(session, interaction, fmt.Sprintf("Times in your messages will be shown in `%s`", zone))
+}
+
+// handleSnooze handles the snooze command
+func (h *CommandHandler) handleSnooze(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, duration string) {
+	duration = strings.TrimSpace(duration)
+	if strings.EqualFold(duration, "off") {
+		resumed, err := h.subscriptionService.ResumeNotifications(ctx, userID)
+		if err != nil {
+			h.respondFailure(ctx, session, interaction, "Failed to resume notifications", fmt.Sprintf("Failed to end the snooze of user %s: %v", userID, err))
+			return
+		}
+		if !resumed {
+			h.respondToInteraction(session, interaction, "Your notifications are not snoozed")
+			return
+		}
+		h.respondToInteraction(session, interaction, "🔔 Your notifications are back on")
+		return
+	}
+
+	length, err := parseSnoozeDuration(duration)
+	if err != nil {
+		h.respondToInteraction(session, interaction, "Invalid duration, use a value like `2h`, `90m` or `3d`, or `off` to resume now")
+		return
+	}
+	until, err := h.subscriptionService.SnoozeNotifications(ctx, userID, length)
+	if errors.Is(err, services.ErrInvalidSnooze) {
+		h.respondToInteraction(session, interaction, fmt.Sprintf("You can snooze for up to %d days", int(services.MaxSnooze.Hours()/24)))
+		return
+	}
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to snooze notifications", fmt.Sprintf("Failed to snooze user %s for %s: %v", userID, length, err))
+		return
+	}
+
+	response := fmt.Sprintf("🔕 Notifications snoozed until %s (%s). Use `/snooze off` to resume early.", services.DiscordTimestamp(until, services.TimestampLongDateTime), services.DiscordTimestamp(until, services.TimestampRelative))
+	h.respondLocalized(ctx, session, interaction, response)
+}
+
+// handleActivity handles the activity command
+func (h *CommandHandler) handleActivity(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string, enabled bool) {
+	if err := h.subscriptionService.SetMarketActivity(ctx, userID, enabled); err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update your activity setting", fmt.Sprintf("Failed to set activity for user %s: %v", userID, err))
+		return
+	}
+	response := "💬 You will get comments and other activity on the markets you subscribed to with `/subscribe_market`"
+	if !enabled {
+		response = "You will no longer get market activity"
+	}
+	h.respondToInteraction(session, interaction, response)
+}
+
+// parseSnoozeDuration parses a Go duration such as 90m or 2h30m, or a whole number of days such as 3d
+func parseSnoozeDuration(value string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(value, "d"); found {
+		count, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// handleLeaderboard handles the leaderboard command
+func (h *CommandHandler) handleLeaderboard(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	leaderboard, err := h.subscriptionService.GetLeaderboard(ctx, services.DefaultLeaderboardSize)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve leaderboard", fmt.Sprintf("Failed to get leaderboard: %v", err))
+		return
+	}
+
+	if len(leaderboard.Markets) == 0 && len(leaderboard.Creators) == 0 {
+		h.respondToInteraction(session, interaction, "Nobody is following any markets or creators yet")
+		return
+	}
+
+	var response strings.Builder
+	response.WriteString("🏆 **LEADERBOARD** 🏆\n\n")
+
+	if len(leaderboard.Markets) > 0 {
+		response.WriteString("**Most-Followed Markets:**\n")
+		for i, entry := range leaderboard.Markets {
+			response.WriteString(fmt.Sprintf("%d. `%s` - %d %s\n", i+1, entry.ID, entry.Subscribers, pluralize(entry.Subscribers, "subscriber")))
+		}
+		response.WriteString("\n")
+	}
+
+	if len(leaderboard.Creators) > 0 {
+		response.WriteString("**Most-Followed Creators:**\n")
+		for i, entry := range leaderboard.Creators {
+			response.WriteString(fmt.Sprintf("%d. `%s` - %d %s\n", i+1, entry.ID, entry.Subscribers, pluralize(entry.Subscribers, "subscriber")))
+		}
+	}
+
+	h.respondToInteraction(session, interaction, response.String())
+}
+
+// pluralize appends an s to a noun unless the count is one
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return noun
+	}
+	return noun + "s"
+}
+
+// historyPeriods maps the history command periods to their lookback windows
+var historyPeriods = map[string]time.Duration{
+	"day":  24 * time.Hour,
+	"week": 7 * 24 * time.Hour,
+}
+
+// handleHistory handles the history command
+func (h *CommandHandler) handleHistory(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, marketID, period string) {
+	window, ok := historyPeriods[period]
+	if !ok {
+		period, window = "day", historyPeriods["day"]
+	}
+
+	history, err := h.subscriptionService.GetMarketHistory(ctx, marketID, time.Now().Add(-window))
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve market history", fmt.Sprintf("Failed to get history for market %s: %v", marketID, err))
+		return
+	}
+
+	market, err := h.marketService.FetchMarket(ctx, marketID)
+	if errors.Is(err, services.ErrBackendNotFound) && len(history) == 0 {
+		h.respondUnknownMarket(ctx, session, interaction, "history", marketID, period)
+		return
+	}
+	if err != nil {
+		h.logger.Warning(fmt.Sprintf("Failed to fetch market %s for history: %v", marketID, err))
+		market = &models.Market{ID: marketID}
+	}
+
+	h.respondLocalized(ctx, session, interaction, h.marketService.CreateMarketHistoryMessage(market, history, period))
+}
+
+// handleRemindMe handles the remind_me command
+func (h *CommandHandler) handleRemindMe(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, marketID, duration string) {
+	before, err := time.ParseDuration(duration)
+	if err != nil || before <= 0 {
+		h.respondToInteraction(session, interaction, "Invalid duration, use a value like `24h`, `1h` or `30m`")
+		return
+	}
+
+	reminder, err := h.reminderService.CreateUserReminder(ctx, userID, marketID, before)
+	if errors.Is(err, services.ErrBackendNotFound) {
+		h.respondUnknownMarket(ctx, session, interaction, "remind_me", marketID, duration)
+		return
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to create reminder for user %s on market %s: %v", userID, marketID, err))
+		h.respondToInteraction(session, interaction, fmt.Sprintf("Failed to create reminder: %v", err))
+		return
+	}
+
+	response := fmt.Sprintf("I'll DM you %s before market `%s` closes, %s", before, reminder.MarketID, services.DiscordTimestamp(reminder.RemindAt, services.TimestampRelative))
+	h.respondToInteraction(session, interaction, response)
+}
+
+// handleHelp handles the help command
+func (h *CommandHandler) handleHelp(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	helpText := "**Coral Markets Bot Help**\n\n" +
+		"**User Commands:**\n" +
+		"- `/subscribe_market <market_id>` - Subscribe to notifications for a specific market\n" +
+		"- `/unsubscribe_market <market_id>` - Unsubscribe from notifications for a specific market\n" +
+		"- `/subscribe_creator <creator>` - Subscribe to notifications for a specific creator\n" +
+		"- `/unsubscribe_creator <creator>` - Unsubscribe from notifications for a specific creator\n" +
+		"- `/subscribe_outcome <market_id> <outcome> [min_change]` - Get notified when an outcome moves significantly or wins\n" +
+		"- `/unsubscribe_outcome <market_id> <outcome>` - Stop following a market outcome\n" +
+		"- `/list_subscriptions` - List all your current subscriptions\n" +
+		"- `/market <market_id>` - Get information about a specific market\n" +
+		"- `/leaderboard` - Show the most-followed markets and creators\n" +
+		"- `/history <market_id> [day/week]` - Show how a market's odds moved recently\n" +
+		"- `/events_calendar [event]` - Browse upcoming events like elections and matches, and the markets about them\n" +
+		"- `/remind_me <market_id> <duration>` - Get a DM reminder before a market closes\n" +
+		"- `/min_buy <amount>` - Only get buy notifications at or above an amount\n" +
+		"- `/set_timezone <timezone> [me/channel]` - Show close and resolution times in an IANA timezone, or `off` for your Discord locale\n" +
+		"- `/snooze <duration>` - Pause your notification DMs, e.g. `8h` or `3d`, or `off` to resume now\n" +
+		"- `/activity on/off` - Also get comments on the markets you subscribed to\n" +
+		"- `/link_email <address/off>` / `/verify_email <code>` - Link an email address with a code sent to it, or unlink it\n" +
+		"- `/email_notifications <daily/weekly/off> [resolutions]` - Get a market digest by email, and resolutions by email instead of DM\n" +
+		"- `/link_account [unlink]` - Link your Coral account with a code entered on Coral, or unlink it\n" +
+		"- `/my_positions` - Show the stake, value and P&L of your linked Coral account's positions\n" +
+		"- `/push_notifications <always/fallback/off>` - Push notifications to your Coral account instead of DMs, or only when DMs fail\n" +
+		"- `/game_leaderboard` - Show the standings of this server's prediction game\n" +
+		"- `/watchlist create/add/remove/show/list/settings/delete` - Group markets into named watchlists, e.g. `/watchlist add crypto <market_id>`\n" +
+		"- `/watchlist share <name>` / `/watchlist import <code> [name]` - Share a watchlist with a code, or copy one someone shared\n" +
+		"- `/help` - Display this help message\n\n" +
+		"**Channel Admin Commands:**\n" +
+		"- `/channel_feed_new_markets <on/off>` - Enable or disable new market announcements\n" +
+		"- `/channel_feed_categories [categories]` - Set allowed categories (comma-separated), or edit them in a form\n" +
+		"- `/channel_setup` - Set categories, frequency, minimum volume and minimum buy in one form\n" +
+		"- `/channel_feed_frequency <low/medium/high/custom> [interval]` - Set update frequency, or a custom interval like `custom 45m`\n" +
+		"- `/channel_feed_ping [role] [events]` - Mention a role in alerts of some events, like `new_market,market_resolved`, or nobody without a role\n" +
+		"- `/channel_subscribe_market <market_id>` - Post updates for a specific market in this channel\n" +
+		"- `/channel_unsubscribe_market <market_id>` - Stop posting updates for a specific market in this channel\n" +
+		"- `/channel_remind <market_id> <duration>` - Post a reminder in this channel before a market closes\n" +
+		"- `/channel_min_buy <amount>` - Only post buys at or above an amount in this channel\n" +
+		"- `/channel_settings` - Display current channel settings\n" +
+		"- `/channel_closing_soon <hours>` - Announce markets in this channel when they enter their final hours (0 to turn off)\n" +
+		"- `/channel_backfill <hours>` - Post the markets created in the last hours that this channel has not announced yet\n" +
+		"- `/channel_digest <daily/weekly/off>` - Post a scheduled market roundup in this channel\n" +
+		"- `/channel_crosspost <on/off>` - Publish alerts in this announcement channel to the servers following it\n" +
+		"- `/channel_board <on/off>` - Keep a pinned board of the top active markets in this channel\n" +
+		"- `/channel_liquidity <on/off>` - Post liquidity added to or removed from markets in this channel\n" +
+		"- `/channel_shadow_mode <on/off>` - Log this channel's alerts instead of posting them, shown in delivery reports as shadowed\n" +
+		"- `/channel_calendar <on/off>` - Post a digest of the markets about each upcoming event before it starts\n" +
+		"- `/channel_quiet_hours <HH:MM-HH:MM/off>` - Hold market events during quiet hours and post a summary when they end\n" +
+		"- `/channel_settings_copy <source_channel>` - Copy another channel's settings to this channel\n" +
+		"- `/channel_audit` - Show recent changes to this channel's settings and webhooks\n" +
+		"- `/setup <channel>` - Set the default announcements channel for this server\n" +
+		"- `/test_announcement [event] [channel]` - Post a sample market event to check your setup\n" +
+		"- `/route_category <category> <channel>` - Announce new markets of a category in one channel only\n" +
+		"- `/unroute_category <category>` - Announce a category's new markets in every feed channel again\n" +
+		"- `/category_routes` - List this server's category routes\n" +
+		"- `/routing_rules <add/remove/move/list>` - Send, suppress, ping a role for or route alerts matching ordered rules\n" +
+		"- `/trading_buttons <on/off>` - Show buy buttons under market alerts for members with a linked Coral account\n" +
+		"- `/game_mode <on/off>` - Let members bet play money on market alerts, with a leaderboard\n" +
+		"- `/community_stats <on/off/preview>` - Post this server's alerts, followed markets, top predictors and resolutions here every week\n\n" +
+		"You'll receive notifications for markets and creators you're subscribed to based on your preferences. " +
+		"User commands also work in a direct message with the bot."
+
+	h.respondToInteraction(session, interaction, helpText)
+}
+
+// handleChannelFeedNewMarkets handles the channel_feed_new_markets command
+func (h *CommandHandler) handleChannelFeedNewMarkets(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, setting string) {
+	config, err := h.subscriptionService.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update channel settings", fmt.Sprintf("Failed to get channel config for %s: %v", channelID, err))
+		return
+	}
+
+	enabled := setting == "on"
+	if enabled && !h.checkFeedPermissions(ctx, session, interaction, channelID) {
+		return
+	}
+	config.FeedEnabled = enabled
+	config.GuildID = interaction.GuildID
+
+	err = h.subscriptionService.UpdateChannelConfig(ctx, config, interactionUserID(interaction))
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to update channel settings", fmt.Sprintf("Failed to update channel config for %s: %v", channelID, err))
+		return
+	}
+
+	response := fmt.Sprintf("New market announcements have been turned %s for this channel", setting)
+	h.respondToInteraction(session, interaction, response)
+}
+
+// checkFeedPermissions verifies that the bot can post feed alerts in a channel, replying with the
+// permissions it is missing when it cannot. Interactions carry the bot's permissions in their own
+// channel, other channels are checked through the gateway state.
+func (h *CommandHandler) checkFeedPermissions(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID string) bool {
+	var err error
+	if channelID == interaction.ChannelID && interaction.AppPermissions != 0 {
+		err = services.MissingFeedPermissions(interaction.AppPermissions)
+	} else {
+		err = services.CheckFeedPermissions(ctx, session, channelID)
+	}
+
+	var missing *services.MissingPermissionsError
+	switch {
+	case errors.As(err, &missing):
+		h.respondToInteraction(session, interaction, fmt.Sprintf("I can't post alerts in this channel. Give me the %s permissions here, then try again", strings.Join(missing.Missing, ", ")))
+		return false
+	case err != nil:
+		h.respondFailure(ctx, session, interaction, "Failed to check this channel", fmt.Sprintf("Failed to check feed permissions in channel %s: %v", channelID, err))
+		return false
+	}
+	return true
+}
+
+// handleChannelFeedCategories handles the channel_feed_categories command
+func (h *CommandHandler) handleChannelFeedCategories(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, categories string) {
+	config, err := h.subscriptionService.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update channel settings", fmt.Sprintf("Failed to get channel config for %s: %v", channelID, err))
+		return
+	}
+
+	categoryList, problem := h.resolveCategories(ctx, parseCategories(categories))
+	if problem != "" {
+		h.respondPrivately(session, interaction, "Nothing was saved: "+problem, nil)
+		return
+	}
+	config.AllowedCategories = categoryList
+	config.GuildID = interaction.GuildID
+
+	err = h.subscriptionService.UpdateChannelConfig(ctx, config, interactionUserID(interaction))
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to update channel settings", fmt.Sprintf("Failed to update channel config for %s: %v", channelID, err))
+		return
+	}
+
+	response := fmt.Sprintf("Allowed categories have been set to: %s", strings.Join(categoryList, ", "))
+	if len(categoryList) == 0 {
+		response = "This channel will receive markets in all categories"
+	}
+	h.respondToInteraction(session, interaction, response)
+}
+
+// handleChannelFeedFrequency handles the channel_feed_frequency command
+func (h *CommandHandler) handleChannelFeedFrequency(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, level, interval string) {
+	frequency, customInterval, err := services.ParseFrequency(level, interval)
+	if err != nil {
+		h.respondToInteraction(session, interaction, fmt.Sprintf("The custom level needs an interval between %s and %s, like `/channel_feed_frequency custom 45m`",
+			models.FormatInterval(models.MinCustomInterval), models.FormatInterval(models.MaxCustomInterval)))
+		return
+	}
+
+	config, err := h.subscriptionService.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update channel settings", fmt.Sprintf("Failed to get channel config for %s: %v", channelID, err))
+		return
+	}
+
+	config.FrequencyMode = frequency
+	config.CustomInterval = customInterval
+	config.GuildID = interaction.GuildID
+
+	err = h.subscriptionService.UpdateChannelConfig(ctx, config, interactionUserID(interaction))
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to update channel settings", fmt.Sprintf("Failed to update channel config for %s: %v", channelID, err))
+		return
+	}
+
+	response := fmt.Sprintf("Update frequency has been set to: %s", config.Frequency())
+	h.respondToInteraction(session, interaction, response)
+}
+
+// handleChannelSubscribeMarket handles the channel_subscribe_market command
+func (h *CommandHandler) handleChannelSubscribeMarket(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, marketID string) {
+	if !h.confirmMarket(ctx, session, interaction, "channel_subscribe_market", marketID, "") {
+		return
+	}
+
+	err := h.subscriptionService.SubscribeChannelToMarket(ctx, channelID, marketID, interactionUserID(interaction))
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to subscribe channel to market", fmt.Sprintf("Failed to subscribe channel %s to market %s: %v", channelID, marketID, err))
+		return
+	}
+
+	response := fmt.Sprintf("This channel will now receive updates for market `%s`", marketID)
+	h.respondToInteraction(session, interaction, response)
+}
+
+// handleChannelUnsubscribeMarket handles the channel_unsubscribe_market command
+func (h *CommandHandler) handleChannelUnsubscribeMarket(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, marketID string) {
+	err := h.subscriptionService.UnsubscribeChannelFromMarket(ctx, channelID, marketID, interactionUserID(interaction))
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to unsubscribe channel from market", fmt.Sprintf("Failed to unsubscribe channel %s from market %s: %v", channelID, marketID, err))
+		return
+	}
+
+	response := fmt.Sprintf("This channel will no longer receive updates for market `%s`", marketID)
+	h.respondToInteraction(session, interaction, response)
+}
+
+// handleChannelRemind handles the channel_remind command
+func (h *CommandHandler) handleChannelRemind(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, marketID, duration string) {
+	before, err := time.ParseDuration(duration)
+	if err != nil || before <= 0 {
+		h.respondToInteraction(session, interaction, "Invalid duration, use a value like `24h`, `1h` or `30m`")
+		return
+	}
+
+	reminder, err := h.reminderService.CreateChannelReminder(ctx, channelID, marketID, before)
+	if errors.Is(err, services.ErrBackendNotFound) {
+		h.respondUnknownMarket(ctx, session, interaction, "channel_remind", marketID, duration)
+		return
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to create reminder for channel %s on market %s: %v", channelID, marketID, err))
+		h.respondToInteraction(session, interaction, fmt.Sprintf("Failed to create reminder: %v", err))
+		return
+	}
+
+	response := fmt.Sprintf("A reminder will be posted in this channel %s before market `%s` closes, %s", before, reminder.MarketID, services.DiscordTimestamp(reminder.RemindAt, services.TimestampRelative))
+	h.respondToInteraction(session, interaction, response)
+}
+
+// handleChannelMinBuy handles the channel_min_buy command
+func (h *CommandHandler) handleChannelMinBuy(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID string, amount float64) {
+	if amount < 0 {
+		h.respondToInteraction(session, interaction, "The minimum buy amount cannot be negative")
+		return
+	}
+
+	config, err := h.subscriptionService.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update channel settings", fmt.Sprintf("Failed to get channel config for %s: %v", channelID, err))
+		return
+	}
 
-// handleSubscribeMarket handles the subscribe_market command
-func (h *CommandHandler) handleSubscribeMarket(session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, marketID string) {
-	err := h.subscriptionService.SubscribeToMarket(userID, marketID)
+	config.MinBuyAmount = amount
+	config.GuildID = interaction.GuildID
+
+	err = h.subscriptionService.UpdateChannelConfig(ctx, config, interactionUserID(interaction))
 	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to subscribe user %s to market %s: %v", userID, marketID, err))
-		h.respondToInteraction(session, interaction, "Failed to subscribe to market")
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to update channel settings", fmt.Sprintf("Failed to update channel config for %s: %v", channelID, err))
 		return
 	}
 
-	response := fmt.Sprintf("You have been subscribed to market `%s`", marketID)
+	response := fmt.Sprintf("This channel will only receive buys of at least $%.2f", amount)
+	if amount == 0 {
+		response = "This channel will receive all buys"
+	}
 	h.respondToInteraction(session, interaction, response)
 }
 
-// handleUnsubscribeMarket handles the unsubscribe_market command
-func (h *CommandHandler) handleUnsubscribeMarket(session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, marketID string) {
-	err := h.subscriptionService.UnsubscribeFromMarket(userID, marketID)
+// handleChannelSettings handles the channel_settings command
+func (h *CommandHandler) handleChannelSettings(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID string) {
+	config, err := h.subscriptionService.GetChannelConfig(ctx, channelID)
 	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to unsubscribe user %s from market %s: %v", userID, marketID, err))
-		h.respondToInteraction(session, interaction, "Failed to unsubscribe from market")
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve channel settings", fmt.Sprintf("Failed to get channel config for %s: %v", channelID, err))
 		return
 	}
 
-	response := fmt.Sprintf("You have been unsubscribed from market `%s`", marketID)
-	h.respondToInteraction(session, interaction, response)
+	settings := fmt.Sprintf("**Channel Settings**\n\n"+
+		"New Market Announcements: %s\n"+
+		"Allowed Categories: %s\n"+
+		"Update Frequency: %s\n"+
+		"Followed Markets: %s\n"+
+		"Minimum Volume: $%.2f\n"+
+		"Minimum Buy: $%.2f\n"+
+		"Closing Soon Notices: %s\n"+
+		"Market Digest: %s\n"+
+		"Timezone: %s\n"+
+		"Crossposting: %s\n"+
+		"Market Board: %s\n"+
+		"Liquidity Alerts: %s\n"+
+		"Event Digests: %s\n"+
+		"Role Ping: %s\n"+
+		"Quiet Hours: %s\n"+
+		"Shadow Mode: %s\n"+
+		"Last Update: %s",
+		map[bool]string{true: "Enabled", false: "Disabled"}[config.FeedEnabled],
+		func() string {
+			if len(config.AllowedCategories) == 0 {
+				return "All categories"
+			}
+			return strings.Join(config.AllowedCategories, ", ")
+		}(),
+		config.Frequency(),
+		func() string {
+			if len(config.SubscribedMarkets) == 0 {
+				return "None"
+			}
+			return strings.Join(config.SubscribedMarkets, ", ")
+		}(),
+		config.MinVolume,
+		config.MinBuyAmount,
+		func() string {
+			if config.ClosingSoonHours == 0 {
+				return "Off"
+			}
+			return fmt.Sprintf("%dh before close", config.ClosingSoonHours)
+		}(),
+		func() string {
+			if config.DigestMode == models.DigestOff {
+				return "Off"
+			}
+			return config.DigestMode
+		}(),
+		func() string {
+			if config.Timezone == "" {
+				return "Each reader's locale"
+			}
+			return config.Timezone
+		}(),
+		map[bool]string{true: "On", false: "Off"}[config.Crosspost],
+		map[bool]string{true: "On", false: "Off"}[config.Board],
+		map[bool]string{true: "On", false: "Off"}[config.LiquidityAlerts],
+		map[bool]string{true: "On", false: "Off"}[config.CalendarDigests],
+		func() string {
+			if config.PingRoleID == "" {
+				return "Off"
+			}
+			return fmt.Sprintf("<@&%s> for %s", config.PingRoleID, strings.Join(config.PingEvents, ", "))
+		}(),
+		func() string {
+			if !config.HasQuietHours() {
+				return "Off"
+			}
+			return config.QuietHoursStart + "–" + config.QuietHoursEnd
+		}(),
+		map[bool]string{true: "On, alerts are logged instead of posted", false: "Off"}[config.ShadowMode],
+		func() string {
+			if config.LastUpdateTimestamp.IsZero() {
+				return "Never"
+			}
+			return services.DiscordTimestamp(config.LastUpdateTimestamp, services.TimestampShortDateTime)
+		}(),
+	)
+
+	h.respondLocalized(ctx, session, interaction, settings)
 }
 
-// handleSubscribeCreator handles the subscribe_creator command
-func (h *CommandHandler) handleSubscribeCreator(session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, creator string) {
-	err := h.subscriptionService.SubscribeToCreator(userID, creator)
+// handleChannelClosingSoon handles the channel_closing_soon command
+func (h *CommandHandler) handleChannelClosingSoon(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID string, hours int) {
+	if hours < 0 || hours > models.MaxClosingSoonHours {
+		h.respondToInteraction(session, interaction, fmt.Sprintf("Choose between 0 and %d hours", models.MaxClosingSoonHours))
+		return
+	}
+
+	err := h.subscriptionService.SetChannelClosingSoon(ctx, channelID, interaction.GuildID, hours, interactionUserID(interaction))
 	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to subscribe user %s to creator %s: %v", userID, creator, err))
-		h.respondToInteraction(session, interaction, "Failed to subscribe to creator")
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to update channel settings", fmt.Sprintf("Failed to set closing-soon window for channel %s: %v", channelID, err))
 		return
 	}
 
-	response := fmt.Sprintf("You have been subscribed to creator `%s`", creator)
-	h.respondToInteraction(session, interaction, response)
+	if hours == 0 {
+		h.respondToInteraction(session, interaction, "This channel will no longer announce markets closing soon")
+		return
+	}
+	h.respondToInteraction(session, interaction, fmt.Sprintf("Markets will be announced in this channel %d hours before they close", hours))
 }
 
-// handleUnsubscribeCreator handles the unsubscribe_creator command
-func (h *CommandHandler) handleUnsubscribeCreator(session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, creator string) {
-	err := h.subscriptionService.UnsubscribeFromCreator(userID, creator)
+// handleChannelDigest handles the channel_digest command
+func (h *CommandHandler) handleChannelDigest(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, schedule string) {
+	mode := schedule
+	if mode == "off" {
+		mode = models.DigestOff
+	}
+
+	err := h.subscriptionService.SetChannelDigest(ctx, channelID, interaction.GuildID, mode, interactionUserID(interaction))
 	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to unsubscribe user %s from creator %s: %v", userID, creator, err))
-		h.respondToInteraction(session, interaction, "Failed to unsubscribe from creator")
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to update channel settings", fmt.Sprintf("Failed to set digest for channel %s: %v", channelID, err))
 		return
 	}
 
-	response := fmt.Sprintf("You have been unsubscribed from creator `%s`", creator)
-	h.respondToInteraction(session, interaction, response)
+	if mode == models.DigestOff {
+		h.respondToInteraction(session, interaction, "This channel will no longer receive market roundups")
+		return
+	}
+	h.respondToInteraction(session, interaction, fmt.Sprintf("This channel will receive a %s market roundup, starting at the next scheduled time", mode))
 }
 
-// handleListSubscriptions handles the list_subscriptions command
-func (h *CommandHandler) handleListSubscriptions(session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string) {
-	subscription, err := h.subscriptionService.GetUserSubscriptions(userID)
+// handleChannelCrosspost handles the channel_crosspost command. Crossposting is only turned on in
+// announcement channels where the bot can send messages.
+func (h *CommandHandler) handleChannelCrosspost(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, setting string) {
+	enabled := setting == "on"
+	if enabled {
+		err := services.CheckCrosspost(ctx, session, channelID)
+		switch {
+		case errors.Is(err, services.ErrNotAnnouncementChannel):
+			h.respondToInteraction(session, interaction, "Crossposting only works in announcement channels. Change this channel's type to Announcement in its settings first")
+			return
+		case errors.Is(err, services.ErrMissingCrosspostPermission):
+			h.respondToInteraction(session, interaction, "I need the View Channel and Send Messages permissions in this channel to publish alerts")
+			return
+		case err != nil:
+			h.respondFailure(ctx, session, interaction, "Failed to check this channel", fmt.Sprintf("Failed to check crossposting in channel %s: %v", channelID, err))
+			return
+		}
+	}
+
+	err := h.subscriptionService.SetChannelCrosspost(ctx, channelID, interaction.GuildID, enabled, interactionUserID(interaction))
 	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to get subscriptions for user %s: %v", userID, err))
-		h.respondToInteraction(session, interaction, "Failed to retrieve subscriptions")
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to update channel settings", fmt.Sprintf("Failed to set crossposting for channel %s: %v", channelID, err))
 		return
 	}
 
-	if len(subscription.SubscribedMarkets) == 0 && len(subscription.SubscribedCreators) == 0 {
-		h.respondToInteraction(session, interaction, "You have no subscriptions")
+	if !enabled {
+		h.respondToInteraction(session, interaction, "Alerts in this channel will no longer be published to following servers")
 		return
 	}
+	h.respondToInteraction(session, interaction, "Alerts in this channel will be published to the servers following it")
+}
 
-	var response strings.Builder
-	response.WriteString("**Your Subscriptions:**\n\n")
+// handleChannelLiquidity handles the channel_liquidity command
+func (h *CommandHandler) handleChannelLiquidity(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, setting string) {
+	enabled := setting == "on"
+	err := h.subscriptionService.SetChannelLiquidityAlerts(ctx, channelID, interaction.GuildID, enabled, interactionUserID(interaction))
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to update channel settings", fmt.Sprintf("Failed to set liquidity alerts for channel %s: %v", channelID, err))
+		return
+	}
 
-	if len(subscription.SubscribedMarkets) > 0 {
-		response.WriteString("**Markets:**\n")
-		for _, marketID := range subscription.SubscribedMarkets {
-			response.WriteString(fmt.Sprintf("- `%s`\n", marketID))
-		}
-		response.WriteString("\n")
+	if !enabled {
+		h.respondToInteraction(session, interaction, "This channel will no longer post liquidity changes")
+		return
 	}
+	h.respondToInteraction(session, interaction, "Liquidity added to or removed from markets will be posted in this channel, with the odds shift it caused")
+}
 
-	if len(subscription.SubscribedCreators) > 0 {
-		response.WriteString("**Creators:**\n")
-		for _, creator := range subscription.SubscribedCreators {
-			response.WriteString(fmt.Sprintf("- `%s`\n", creator))
-		}
+// handleChannelShadowMode handles the channel_shadow_mode command
+func (h *CommandHandler) handleChannelShadowMode(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, setting string) {
+	enabled := setting == "on"
+	err := h.subscriptionService.SetChannelShadowMode(ctx, channelID, interaction.GuildID, enabled, interactionUserID(interaction))
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to update channel settings", fmt.Sprintf("Failed to set shadow mode for channel %s: %v", channelID, err))
+		return
 	}
 
-	h.respondToInteraction(session, interaction, response.String())
+	if !enabled {
+		h.respondToInteraction(session, interaction, "Alerts will be posted in this channel again")
+		return
+	}
+	h.respondToInteraction(session, interaction, "Alerts for this channel will be logged and shown as shadowed in delivery reports instead of posted. Turn it off with `/channel_shadow_mode off`")
 }
 
-// handleGetMarket handles the market command
-func (h *CommandHandler) handleGetMarket(session *discordgo.Session, interaction *discordgo.InteractionCreate, marketID string) {
-	market, err := h.marketService.FetchMarket(marketID)
+// handleChannelBoard handles the channel_board command
+func (h *CommandHandler) handleChannelBoard(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, setting string) {
+	if h.boards == nil {
+		h.respondToInteraction(session, interaction, "Market boards are not enabled on this bot")
+		return
+	}
+
+	enabled := setting == "on"
+	err := h.subscriptionService.SetChannelBoard(ctx, channelID, interaction.GuildID, enabled, interactionUserID(interaction))
 	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to fetch market %s: %v", marketID, err))
-		h.respondToInteraction(session, interaction, "Failed to retrieve market information")
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to update channel settings", fmt.Sprintf("Failed to set market board for channel %s: %v", channelID, err))
 		return
 	}
+	h.boards.MarketsChanged()
 
-	announcement := h.marketService.CreateMarketAnnouncement(market)
-	h.respondToInteraction(session, interaction, announcement)
+	if !enabled {
+		h.respondToInteraction(session, interaction, "The market board will be removed from this channel shortly")
+		return
+	}
+	h.respondToInteraction(session, interaction, "A market board will be posted and pinned in this channel shortly. Pinning needs the Manage Messages permission")
 }
 
-// handleHelp handles the help command
-func (h *CommandHandler) handleHelp(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
-	helpText := "**Coral Markets Bot Help**\n\n" +
-		"**User Commands:**\n" +
-		"- `/subscribe_market <market_id>` - Subscribe to notifications for a specific market\n" +
-		"- `/unsubscribe_market <market_id>` - Unsubscribe from notifications for a specific market\n" +
-		"- `/subscribe_creator <creator>` - Subscribe to notifications for a specific creator\n" +
-		"- `/unsubscribe_creator <creator>` - Unsubscribe from notifications for a specific creator\n" +
-		"- `/list_subscriptions` - List all your current subscriptions\n" +
-		"- `/market <market_id>` - Get information about a specific market\n" +
-		"- `/help` - Display this help message\n\n" +
-		"**Channel Admin Commands:**\n" +
-		"- `/channel_feed_new_markets <on/off>` - Enable or disable new market announcements\n" +
-		"- `/channel_feed_categories <categories>` - Set allowed categories (comma-separated)\n" +
-		"- `/channel_feed_frequency <low/medium/high>` - Set update frequency\n" +
-		"- `/channel_settings` - Display current channel settings\n\n" +
-		"You'll receive notifications for markets and creators you're subscribed to based on your preferences."
+// handleChannelQuietHours handles the channel_quiet_hours command
+func (h *CommandHandler) handleChannelQuietHours(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, hours string) {
+	start, end := "", ""
+	if hours = strings.TrimSpace(hours); !strings.EqualFold(hours, "off") {
+		var err error
+		if start, end, err = services.ParseQuietHours(hours); err != nil {
+			h.respondToInteraction(session, interaction, "Write quiet hours as two different times, such as 23:00-08:00, or off")
+			return
+		}
+	}
 
-	h.respondToInteraction(session, interaction, helpText)
+	err := h.subscriptionService.SetChannelQuietHours(ctx, channelID, interaction.GuildID, start, end, interactionUserID(interaction))
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to update channel settings", fmt.Sprintf("Failed to set quiet hours for channel %s: %v", channelID, err))
+		return
+	}
+
+	if start == "" {
+		h.respondToInteraction(session, interaction, "Quiet hours are off. Events held so far will be posted shortly")
+		return
+	}
+	zone := "UTC"
+	if config, err := h.subscriptionService.GetChannelConfig(ctx, channelID); err == nil && config.Timezone != "" {
+		zone = config.Timezone
+	}
+	h.respondToInteraction(session, interaction, fmt.Sprintf("Market events will be held from %s to %s (%s) and posted as a summary when quiet hours end", start, end, zone))
 }
 
-// handleChannelFeedNewMarkets handles the channel_feed_new_markets command
-func (h *CommandHandler) handleChannelFeedNewMarkets(session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, setting string) {
-	config, err := h.subscriptionService.GetChannelConfig(channelID)
+// handleChannelSettingsCopy handles the channel_settings_copy command
+func (h *CommandHandler) handleChannelSettingsCopy(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, sourceChannelID, targetChannelID string) {
+	if sourceChannelID == targetChannelID {
+		h.respondToInteraction(session, interaction, "Pick a different channel to copy settings from")
+		return
+	}
+
+	// Only copy from channels the bot knows belong to this server
+	source, err := h.subscriptionService.GetChannelConfig(ctx, sourceChannelID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to copy channel settings", fmt.Sprintf("Failed to get channel config for %s: %v", sourceChannelID, err))
+		return
+	}
+	if source.GuildID != "" && source.GuildID != interaction.GuildID {
+		h.respondToInteraction(session, interaction, "Settings can only be copied from a channel in this server")
+		return
+	}
+
+	config, err := h.subscriptionService.CopyChannelSettings(ctx, sourceChannelID, targetChannelID, interaction.GuildID, interactionUserID(interaction))
 	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to get channel config for %s: %v", channelID, err))
-		h.respondToInteraction(session, interaction, "Failed to update channel settings")
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to copy channel settings", fmt.Sprintf("Failed to copy channel settings from %s to %s: %v", sourceChannelID, targetChannelID, err))
 		return
 	}
 
-	enabled := setting == "on"
-	config.FeedEnabled = enabled
+	h.respondToInteraction(session, interaction, fmt.Sprintf("Copied settings from <#%s>: %d followed markets, %s update frequency, minimum buy $%.2f", sourceChannelID, len(config.SubscribedMarkets), config.FrequencyMode, config.MinBuyAmount))
+}
 
-	err = h.subscriptionService.UpdateChannelConfig(config)
+// channelAuditSize is the number of audit entries shown by the channel_audit command
+const channelAuditSize = 10
+
+// handleChannelAudit handles the channel_audit command
+func (h *CommandHandler) handleChannelAudit(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID string) {
+	entries, err := h.subscriptionService.GetAuditLog(ctx, models.AuditFilter{ChannelID: channelID, Limit: channelAuditSize})
 	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to update channel config for %s: %v", channelID, err))
-		h.respondToInteraction(session, interaction, "Failed to update channel settings")
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve audit log", fmt.Sprintf("Failed to get audit log for channel %s: %v", channelID, err))
 		return
 	}
 
-	response := fmt.Sprintf("New market announcements have been turned %s for this channel", setting)
-	h.respondToInteraction(session, interaction, response)
+	if len(entries) == 0 {
+		h.respondToInteraction(session, interaction, "No changes have been recorded for this channel")
+		return
+	}
+
+	var response strings.Builder
+	response.WriteString("**Recent Channel Changes**\n\n")
+	for _, entry := range entries {
+		actor := entry.Actor
+		if actor != "" && actor != "api" {
+			actor = fmt.Sprintf("<@%s>", actor)
+		}
+
+		response.WriteString(fmt.Sprintf("- %s %s %sd %s `%s`",
+			services.DiscordTimestamp(entry.Timestamp, services.TimestampShortDateTime),
+			actor,
+			entry.Action,
+			strings.ReplaceAll(entry.ResourceType, "_", " "),
+			entry.ResourceID,
+		))
+		if len(entry.Changes) > 0 && entry.Action == models.AuditActionUpdate {
+			response.WriteString(fmt.Sprintf(" (%s)", strings.Join(entry.Changes, ", ")))
+		}
+		response.WriteString("\n")
+	}
+
+	h.respondLocalized(ctx, session, interaction, response.String())
 }
 
-// handleChannelFeedCategories handles the channel_feed_categories command
-func (h *CommandHandler) handleChannelFeedCategories(session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, categories string) {
-	config, err := h.subscriptionService.GetChannelConfig(channelID)
+// handleSetup handles the setup command
+func (h *CommandHandler) handleSetup(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, channelID string) {
+	if interaction.GuildID == "" {
+		h.respondToInteraction(session, interaction, "This command can only be used in a server")
+		return
+	}
+
+	config, err := h.subscriptionService.GetGuildConfig(ctx, interaction.GuildID)
 	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to get channel config for %s: %v", channelID, err))
-		h.respondToInteraction(session, interaction, "Failed to update channel settings")
+		h.respondFailure(ctx, session, interaction, "Failed to update server settings", fmt.Sprintf("Failed to get guild config for %s: %v", interaction.GuildID, err))
 		return
 	}
+	if config == nil {
+		config = &models.GuildConfig{GuildID: interaction.GuildID}
+	}
+	config.DefaultChannelID = channelID
+	config.ConfiguredBy = userID
 
-	categoryList := strings.Split(categories, ",")
-	for i, category := range categoryList {
-		categoryList[i] = strings.TrimSpace(category)
+	err = h.subscriptionService.UpdateGuildConfig(ctx, config)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update server settings", fmt.Sprintf("Failed to update guild config for %s: %v", interaction.GuildID, err))
+		return
 	}
 
-	config.AllowedCategories = categoryList
+	response := fmt.Sprintf("Market announcements for this server will be posted in <#%s>. "+
+		"Use the `/channel_feed_*` commands in any channel to customize where announcements go.", channelID)
+	h.respondToInteraction(session, interaction, response)
+}
 
-	err = h.subscriptionService.UpdateChannelConfig(config)
+// handleRouteCategory handles the route_category command
+func (h *CommandHandler) handleRouteCategory(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, category, channelID string) {
+	route, err := h.subscriptionService.SetCategoryRoute(ctx, interaction.GuildID, category, channelID, userID)
+	if errors.Is(err, services.ErrInvalidCategoryRoute) {
+		h.respondToInteraction(session, interaction, "Please provide a category, e.g. `politics`")
+		return
+	}
 	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to update channel config for %s: %v", channelID, err))
-		h.respondToInteraction(session, interaction, "Failed to update channel settings")
+		h.respondFailure(ctx, session, interaction, "Failed to update category routing", fmt.Sprintf("Failed to route category %s in guild %s: %v", category, interaction.GuildID, err))
 		return
 	}
 
-	response := fmt.Sprintf("Allowed categories have been set to: %s", strings.Join(categoryList, ", "))
+	response := fmt.Sprintf("New `%s` markets in this server will only be announced in <#%s>", route.Category, route.ChannelID)
 	h.respondToInteraction(session, interaction, response)
 }
 
-// handleChannelFeedFrequency handles the channel_feed_frequency command
-func (h *CommandHandler) handleChannelFeedFrequency(session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, frequency string) {
-	config, err := h.subscriptionService.GetChannelConfig(channelID)
+// handleUnrouteCategory handles the unroute_category command
+func (h *CommandHandler) handleUnrouteCategory(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, category string) {
+	removed, err := h.subscriptionService.RemoveCategoryRoute(ctx, interaction.GuildID, category, userID)
 	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to get channel config for %s: %v", channelID, err))
-		h.respondToInteraction(session, interaction, "Failed to update channel settings")
+		h.respondFailure(ctx, session, interaction, "Failed to update category routing", fmt.Sprintf("Failed to unroute category %s in guild %s: %v", category, interaction.GuildID, err))
 		return
 	}
 
-	config.FrequencyMode = frequency
+	category = models.NormalizeCategory(category)
+	if !removed {
+		h.respondToInteraction(session, interaction, fmt.Sprintf("`%s` markets are not routed in this server", category))
+		return
+	}
+	h.respondToInteraction(session, interaction, fmt.Sprintf("New `%s` markets will be announced in every feed channel again", category))
+}
 
-	err = h.subscriptionService.UpdateChannelConfig(config)
+// handleCategoryRoutes handles the category_routes command
+func (h *CommandHandler) handleCategoryRoutes(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	routes, err := h.subscriptionService.GetCategoryRoutes(ctx, interaction.GuildID)
 	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to update channel config for %s: %v", channelID, err))
-		h.respondToInteraction(session, interaction, "Failed to update channel settings")
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve category routes", fmt.Sprintf("Failed to get category routes of guild %s: %v", interaction.GuildID, err))
+		return
+	}
+	if len(routes) == 0 {
+		h.respondToInteraction(session, interaction, "No categories are routed in this server. Use `/route_category` to send a category's new markets to one channel.")
 		return
 	}
 
-	response := fmt.Sprintf("Update frequency has been set to: %s", frequency)
-	h.respondToInteraction(session, interaction, response)
+	var response strings.Builder
+	response.WriteString("**Category Routes**\n\n")
+	for _, route := range routes {
+		response.WriteString(fmt.Sprintf("- `%s` → <#%s>\n", route.Category, route.ChannelID))
+	}
+	response.WriteString("\nNew markets in other categories are announced in every feed channel.")
+	h.respondToInteraction(session, interaction, response.String())
 }
 
-// handleChannelSettings handles the channel_settings command
-func (h *CommandHandler) handleChannelSettings(session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID string) {
-	config, err := h.subscriptionService.GetChannelConfig(channelID)
+// handleTestAnnouncement handles the test_announcement command. The reply is sent first because
+// delivery, including the chart, can take longer than Discord allows before an interaction times out.
+func (h *CommandHandler) handleTestAnnouncement(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, eventType, channelID string) {
+	if h.testEventSender == nil {
+		h.respondToInteraction(session, interaction, "Test announcements are not available right now")
+		return
+	}
+
+	h.respondToInteraction(session, interaction, fmt.Sprintf("🧪 Sending a sample `%s` event to <#%s>...", eventType, channelID))
+
+	if err := h.testEventSender.SendTestEvent(ctx, eventType, channelID); err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to send test %s event to channel %s: %v", eventType, channelID, err))
+		_, err = session.FollowupMessageCreate(interaction.Interaction, true, &discordgo.WebhookParams{
+			Content: fmt.Sprintf("❌ The test event could not be posted in <#%s>. Check that the bot can view the channel and send messages there.", channelID),
+		})
+		if err != nil {
+			h.logger.Error(fmt.Sprintf("Failed to send interaction follow-up: %v", err))
+		}
+	}
+}
+
+// respondToInteraction sends a response to a Discord interaction, continued in follow-ups when it is
+// longer than Discord allows. Any components go under the first message.
+func (h *CommandHandler) respondToInteraction(session *discordgo.Session, interaction *discordgo.InteractionCreate, message string, components ...discordgo.MessageComponent) {
+	parts := services.SplitMessage(message, services.MaxMessageLength)
+	err := session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    parts[0],
+			Components: components,
+			// Replies show roles, such as a channel's ping role, without mentioning them
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
 	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to get channel config for %s: %v", channelID, err))
-		h.respondToInteraction(session, interaction, "Failed to retrieve channel settings")
+		h.logger.Error(fmt.Sprintf("Failed to respond to interaction: %v", err))
 		return
 	}
+	h.followUp(session, interaction, parts[1:], 0)
+}
 
-	settings := fmt.Sprintf("**Channel Settings**\n\n"+
-		"New Market Announcements: %s\n"+
-		"Allowed Categories: %s\n"+
-		"Update Frequency: %s\n"+
-		"Last Update: %s",
-		map[bool]string{true: "Enabled", false: "Disabled"}[config.FeedEnabled],
-		func() string {
-			if len(config.AllowedCategories) == 0 {
-				return "All categories"
+// followUp posts the rest of a long response as follow-ups with the given flags
+func (h *CommandHandler) followUp(session *discordgo.Session, interaction *discordgo.InteractionCreate, parts []string, flags discordgo.MessageFlags) {
+	for _, part := range parts {
+		_, err := session.FollowupMessageCreate(interaction.Interaction, true, &discordgo.WebhookParams{
+			Content:         part,
+			Flags:           flags,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		if err != nil {
+			h.logger.Error(fmt.Sprintf("Failed to send interaction follow-up: %v", err))
+			return
+		}
+	}
+}
+
+// handleAdminUserData handles the admin_user_data command. The export is attached as JSON and, like the
+// rest of the reply, only shown to the invoking owner.
+func (h *CommandHandler) handleAdminUserData(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, targetUserID string, purge bool) {
+	if !h.owners[userID] {
+		h.respondPrivately(session, interaction, "This command is restricted to bot owners", nil)
+		return
+	}
+	if h.userDataService == nil {
+		h.respondPrivately(session, interaction, "User data requests are not enabled", nil)
+		return
+	}
+	if targetUserID == "" {
+		h.respondPrivately(session, interaction, "A user ID is required", nil)
+		return
+	}
+
+	var data *models.UserData
+	var err error
+	if purge {
+		data, err = h.userDataService.DeleteUserData(ctx, targetUserID)
+	} else {
+		data, err = h.userDataService.ExportUserData(ctx, targetUserID)
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to handle data request of user %s: %v", targetUserID, err))
+		h.respondPrivately(session, interaction, "Failed to process the data request", nil)
+		return
+	}
+	if purge {
+		h.logger.Info(fmt.Sprintf("User %s deleted the data of user %s", userID, targetUserID))
+	}
+
+	export, _ := json.MarshalIndent(data, "", "  ")
+	var response strings.Builder
+	if purge {
+		response.WriteString(fmt.Sprintf("**Deleted the data of user `%s`**\n\n", targetUserID))
+	} else {
+		response.WriteString(fmt.Sprintf("**Data of user `%s`**\n\n", targetUserID))
+	}
+	subscription := data.Subscription
+	response.WriteString(fmt.Sprintf("- Markets: %d\n- Creators: %d\n- Outcomes: %d\n",
+		len(subscription.SubscribedMarkets), len(subscription.SubscribedCreators), len(subscription.SubscribedOutcomes)))
+	if subscription.MinBuyAmount > 0 {
+		response.WriteString(fmt.Sprintf("- Minimum buy: $%.2f\n", subscription.MinBuyAmount))
+	}
+	if subscription.Timezone != "" {
+		response.WriteString(fmt.Sprintf("- Timezone: %s\n", subscription.Timezone))
+	}
+	response.WriteString(fmt.Sprintf("- Reminders: %d\n- Watchlists: %d\n- Analytics records: %d\n", len(data.Reminders), len(data.Watchlists), len(data.Analytics)))
+
+	h.respondPrivately(session, interaction, response.String(), []*discordgo.File{{
+		Name:        fmt.Sprintf("user-%s.json", targetUserID),
+		ContentType: "application/json",
+		Reader:      bytes.NewReader(export),
+	}})
+}
+
+// maxListedDeadLetters is how many dead letters admin_dead_letters lists, to stay within Discord's message length
+const maxListedDeadLetters = 10
+
+// handleAdminDeadLetters handles the admin_dead_letters command. Retried dead letters are sent by the
+// next run of the dead letter worker.
+func (h *CommandHandler) handleAdminDeadLetters(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, action, id string) {
+	if !h.owners[userID] {
+		h.respondPrivately(session, interaction, "This command is restricted to bot owners", nil)
+		return
+	}
+	if h.deadLetters == nil {
+		h.respondPrivately(session, interaction, "Dead letters are not enabled", nil)
+		return
+	}
+	if action != "list" && id == "" {
+		h.respondPrivately(session, interaction, "A dead letter ID is required to "+action+" it", nil)
+		return
+	}
+
+	switch action {
+	case "retry":
+		letter, err := h.deadLetters.Redrive(ctx, id)
+		if errors.Is(err, services.ErrDeadLetterNotFound) {
+			h.respondPrivately(session, interaction, fmt.Sprintf("No dead letter `%s`", id), nil)
+			return
+		}
+		if err != nil {
+			h.logger.Error(fmt.Sprintf("Failed to re-drive dead letter %s: %v", id, err))
+			h.respondPrivately(session, interaction, "Failed to re-drive the dead letter", nil)
+			return
+		}
+		h.logger.Info(fmt.Sprintf("User %s re-drove dead letter %s", userID, id))
+		h.respondPrivately(session, interaction, fmt.Sprintf("Dead letter `%s` to %s `%s` will be retried within a minute", letter.ID, letter.Recipient, letter.RecipientID), nil)
+	case "discard":
+		err := h.deadLetters.Discard(ctx, id)
+		if errors.Is(err, services.ErrDeadLetterNotFound) {
+			h.respondPrivately(session, interaction, fmt.Sprintf("No dead letter `%s`", id), nil)
+			return
+		}
+		if err != nil {
+			h.logger.Error(fmt.Sprintf("Failed to discard dead letter %s: %v", id, err))
+			h.respondPrivately(session, interaction, "Failed to discard the dead letter", nil)
+			return
+		}
+		h.logger.Info(fmt.Sprintf("User %s discarded dead letter %s", userID, id))
+		h.respondPrivately(session, interaction, fmt.Sprintf("Discarded dead letter `%s`", id), nil)
+	default:
+		letters, err := h.deadLetters.List(ctx, "")
+		if err != nil {
+			h.respondFailure(ctx, session, interaction, "Failed to list dead letters", fmt.Sprintf("Failed to list dead letters: %v", err))
+			return
+		}
+		if len(letters) == 0 {
+			h.respondPrivately(session, interaction, "No dead letters", nil)
+			return
+		}
+
+		var response strings.Builder
+		response.WriteString(fmt.Sprintf("**Dead letters (%d)**\n\n", len(letters)))
+		for i, letter := range letters {
+			if i == maxListedDeadLetters {
+				response.WriteString(fmt.Sprintf("\n...and %d more, see GET /discord/admin/dead-letters", len(letters)-i))
+				break
 			}
-			return strings.Join(config.AllowedCategories, ", ")
-		}(),
-		config.FrequencyMode,
-		config.LastUpdateTimestamp.Format("2006-01-02 15:04:05"),
-	)
+			response.WriteString(fmt.Sprintf("- `%s` %s to %s `%s`, %s after %d attempts: %s\n",
+				letter.ID, letter.EventType, letter.Recipient, letter.RecipientID, letter.Status, letter.Attempts, letter.LastError))
+		}
+		h.respondPrivately(session, interaction, response.String(), nil)
+	}
+}
 
-	h.respondToInteraction(session, interaction, settings)
+// handleAdminResyncCommands handles the admin_resync_commands command, which brings the registered
+// commands in line with the running bot without restarting it
+func (h *CommandHandler) handleAdminResyncCommands(session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string) {
+	if !h.owners[userID] {
+		h.respondPrivately(session, interaction, "This command is restricted to bot owners", nil)
+		return
+	}
+	result, err := h.ResyncCommands(session)
+	if err != nil {
+		h.respondPrivately(session, interaction, fmt.Sprintf("Failed to sync commands: %v", err), nil)
+		return
+	}
+	h.logger.Info(fmt.Sprintf("User %s resynced the commands", userID))
+	scope := "globally"
+	if result.Scope != "global" {
+		scope = fmt.Sprintf("in guild `%s`", result.Scope)
+	}
+	h.respondPrivately(session, interaction, fmt.Sprintf("Synced commands %s: %d created, %d updated, %d deleted, %d unchanged", scope, result.Created, result.Updated, result.Deleted, result.Unchanged), nil)
 }
 
-// respondToInteraction sends a response to a Discord interaction
-func (h *CommandHandler) respondToInteraction(session *discordgo.Session, interaction *discordgo.InteractionCreate, message string) {
+// respondPrivately responds with a message, and optional files, only the invoking user can see
+func (h *CommandHandler) respondPrivately(session *discordgo.Session, interaction *discordgo.InteractionCreate, message string, files []*discordgo.File) {
+	parts := services.SplitMessage(message, services.MaxMessageLength)
 	err := session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content: message,
+			Content: parts[0],
+			Files:   files,
+			Flags:   discordgo.MessageFlagsEphemeral,
 		},
 	})
 	if err != nil {
 		h.logger.Error(fmt.Sprintf("Failed to respond to interaction: %v", err))
+		return
 	}
+	h.followUp(session, interaction, parts[1:], discordgo.MessageFlagsEphemeral)
 }
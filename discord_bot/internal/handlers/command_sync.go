@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"coral-bot/discord_bot/internal/models"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// CommandRegistrar manages an application's slash commands, implemented by *discordgo.Session
+type CommandRegistrar interface {
+	ApplicationCommands(appID, guildID string, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error)
+	ApplicationCommandCreate(appID, guildID string, cmd *discordgo.ApplicationCommand, options ...discordgo.RequestOption) (*discordgo.ApplicationCommand, error)
+	ApplicationCommandEdit(appID, guildID, cmdID string, cmd *discordgo.ApplicationCommand, options ...discordgo.RequestOption) (*discordgo.ApplicationCommand, error)
+	ApplicationCommandDelete(appID, guildID, cmdID string, options ...discordgo.RequestOption) error
+	ApplicationCommandBulkOverwrite(appID, guildID string, commands []*discordgo.ApplicationCommand, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error)
+}
+
+// SetCommandGuild registers the commands in one guild instead of globally. Guild commands update
+// instantly, which suits a development server; global commands can take up to an hour to appear.
+func (h *CommandHandler) SetCommandGuild(guildID string) {
+	h.commandGuildID = guildID
+}
+
+// RegisterCommands brings the bot's registered slash commands in line with Commands, creating,
+// updating and deleting only the commands that changed since the last boot
+func (h *CommandHandler) RegisterCommands(session *discordgo.Session) error {
+	_, err := h.ResyncCommands(session)
+	return err
+}
+
+// ResyncCommands runs the command sync of RegisterCommands again while the bot runs, for the
+// admin_resync_commands command and the REST API. Syncs run one at a time.
+func (h *CommandHandler) ResyncCommands(session *discordgo.Session) (models.CommandSyncResult, error) {
+	h.syncMutex.Lock()
+	defer h.syncMutex.Unlock()
+
+	scope := "globally"
+	if h.commandGuildID != "" {
+		scope = "in guild " + h.commandGuildID
+	}
+	h.logger.Info(fmt.Sprintf("Syncing commands %s...", scope))
+
+	result, err := SyncCommands(session, session.State.User.ID, h.commandGuildID, h.Commands())
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Cannot sync commands: %v", err))
+		return result, err
+	}
+	h.logger.Info(fmt.Sprintf("Synced commands: %d created, %d updated, %d deleted, %d unchanged", result.Created, result.Updated, result.Deleted, result.Unchanged))
+	return result, nil
+}
+
+// UnregisterCommands removes all of the bot's commands from its command guild in one request, so a
+// development bot leaves no commands behind when it stops. Global commands are never removed.
+func (h *CommandHandler) UnregisterCommands(session *discordgo.Session) error {
+	if h.commandGuildID == "" {
+		return fmt.Errorf("commands are registered globally, only guild commands are unregistered on shutdown")
+	}
+	if _, err := session.ApplicationCommandBulkOverwrite(session.State.User.ID, h.commandGuildID, []*discordgo.ApplicationCommand{}); err != nil {
+		return fmt.Errorf("failed to unregister commands in guild %s: %w", h.commandGuildID, err)
+	}
+	h.logger.Info(fmt.Sprintf("Unregistered commands in guild %s", h.commandGuildID))
+	return nil
+}
+
+// SyncCommands fetches the application's registered commands in a guild, or globally when guildID is
+// empty, and creates the desired commands that are missing, edits those that differ and deletes
+// those no longer desired
+func SyncCommands(registrar CommandRegistrar, appID, guildID string, desired []*discordgo.ApplicationCommand) (models.CommandSyncResult, error) {
+	result := models.CommandSyncResult{Scope: "global"}
+	if guildID != "" {
+		result.Scope = guildID
+	}
+	existing, err := registrar.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return result, fmt.Errorf("failed to list registered commands: %w", err)
+	}
+	registered := make(map[string]*discordgo.ApplicationCommand, len(existing))
+	for _, cmd := range existing {
+		registered[commandKey(cmd)] = cmd
+	}
+
+	for _, cmd := range desired {
+		key := commandKey(cmd)
+		current, ok := registered[key]
+		delete(registered, key)
+		switch {
+		case !ok:
+			if _, err := registrar.ApplicationCommandCreate(appID, guildID, cmd); err != nil {
+				return result, fmt.Errorf("cannot create '%s' command: %w", cmd.Name, err)
+			}
+			result.Created++
+		case commandsDiffer(current, cmd, guildID != ""):
+			if _, err := registrar.ApplicationCommandEdit(appID, guildID, current.ID, cmd); err != nil {
+				return result, fmt.Errorf("cannot update '%s' command: %w", cmd.Name, err)
+			}
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+	}
+
+	// Whatever is left was registered by an earlier version and no longer exists
+	for _, stale := range registered {
+		if err := registrar.ApplicationCommandDelete(appID, guildID, stale.ID); err != nil {
+			return result, fmt.Errorf("cannot delete stale '%s' command: %w", stale.Name, err)
+		}
+		result.Deleted++
+	}
+	return result, nil
+}
+
+// commandKey identifies a command; names are unique per command type
+func commandKey(cmd *discordgo.ApplicationCommand) string {
+	return fmt.Sprintf("%d/%s", commandType(cmd), cmd.Name)
+}
+
+// commandType returns a command's type, which Discord defaults to a chat input command
+func commandType(cmd *discordgo.ApplicationCommand) discordgo.ApplicationCommandType {
+	if cmd.Type == 0 {
+		return discordgo.ChatApplicationCommand
+	}
+	return cmd.Type
+}
+
+// comparableCommand holds the parts of a command a user sees, with Discord's defaults filled in
+type comparableCommand struct {
+	Description              string                                `json:"description"`
+	DescriptionLocalizations *map[discordgo.Locale]string          `json:"description_localizations"`
+	NameLocalizations        *map[discordgo.Locale]string          `json:"name_localizations"`
+	DefaultMemberPermissions *int64                                `json:"default_member_permissions"`
+	DMPermission             bool                                  `json:"dm_permission"`
+	NSFW                     bool                                  `json:"nsfw"`
+	Options                  []*discordgo.ApplicationCommandOption `json:"options"`
+}
+
+// commandsDiffer reports whether a registered command differs from the desired one. Discord ignores
+// the DM permission of guild commands, so it is only compared for global ones.
+func commandsDiffer(registered, desired *discordgo.ApplicationCommand, inGuild bool) bool {
+	a, _ := json.Marshal(comparableForm(registered, inGuild))
+	b, _ := json.Marshal(comparableForm(desired, inGuild))
+	return string(a) != string(b)
+}
+
+// comparableForm returns the comparable form of a command
+func comparableForm(cmd *discordgo.ApplicationCommand, inGuild bool) comparableCommand {
+	dmPermission := !inGuild && (cmd.DMPermission == nil || *cmd.DMPermission)
+	return comparableCommand{
+		Description:              cmd.Description,
+		DescriptionLocalizations: emptyLocalizations(cmd.DescriptionLocalizations),
+		NameLocalizations:        emptyLocalizations(cmd.NameLocalizations),
+		DefaultMemberPermissions: cmd.DefaultMemberPermissions,
+		DMPermission:             dmPermission,
+		NSFW:                     cmd.NSFW != nil && *cmd.NSFW,
+		Options:                  normalizeOptions(cmd.Options),
+	}
+}
+
+// emptyLocalizations treats an empty localization map like a missing one
+func emptyLocalizations(localizations *map[discordgo.Locale]string) *map[discordgo.Locale]string {
+	if localizations == nil || len(*localizations) == 0 {
+		return nil
+	}
+	return localizations
+}
+
+// normalizeOptions copies options with empty lists set to nil, since Discord leaves empty lists out
+func normalizeOptions(options []*discordgo.ApplicationCommandOption) []*discordgo.ApplicationCommandOption {
+	if len(options) == 0 {
+		return nil
+	}
+	normalized := make([]*discordgo.ApplicationCommandOption, len(options))
+	for i, option := range options {
+		copied := *option
+		copied.Options = normalizeOptions(option.Options)
+		if len(copied.ChannelTypes) == 0 {
+			copied.ChannelTypes = nil
+		}
+		if len(copied.Choices) == 0 {
+			copied.Choices = nil
+		}
+		normalized[i] = &copied
+	}
+	return normalized
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// SetCommunityStatsService sets the service posting the weekly community stats of the community_stats command
+func (h *CommandHandler) SetCommunityStatsService(stats services.CommunityStatsService) {
+	h.stats = stats
+}
+
+// handleCommunityStats handles the community_stats command: on posts the guild's stats in the current
+// channel every week, off stops them, and preview shows this week's stats so far only to the caller
+func (h *CommandHandler) handleCommunityStats(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, setting string) {
+	if h.stats == nil {
+		h.respondToInteraction(session, interaction, "Community stats are not enabled on this bot")
+		return
+	}
+	if setting == "preview" {
+		stats, err := h.stats.BuildStats(ctx, interaction.GuildID, time.Now())
+		if err != nil {
+			h.respondFailure(ctx, session, interaction, "Failed to build the community stats", fmt.Sprintf("Failed to build the community stats of guild %s: %v", interaction.GuildID, err))
+			return
+		}
+		h.respondPrivately(session, interaction, stats, nil)
+		return
+	}
+
+	config, err := h.subscriptionService.GetGuildConfig(ctx, interaction.GuildID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update server settings", fmt.Sprintf("Failed to get guild config for %s: %v", interaction.GuildID, err))
+		return
+	}
+	if config == nil {
+		config = &models.GuildConfig{GuildID: interaction.GuildID}
+	}
+	config.StatsChannelID = ""
+	if setting == "on" {
+		config.StatsChannelID = interaction.ChannelID
+	}
+	config.ConfiguredBy = userID
+	if err := h.subscriptionService.UpdateGuildConfig(ctx, config); err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update server settings", fmt.Sprintf("Failed to update guild config for %s: %v", interaction.GuildID, err))
+		return
+	}
+
+	if setting == "on" {
+		h.respondToInteraction(session, interaction, "📊 This channel will get the server's community stats every week, with the weekly digests: the alerts posted, the markets followed, the most active predictors and the biggest resolutions. See this week's so far with `/community_stats preview`")
+		return
+	}
+	h.respondToInteraction(session, interaction, "Weekly community stats are off for this server")
+}
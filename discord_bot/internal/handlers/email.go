@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// emailDisabledMessage answers the email commands when no mail server is configured
+const emailDisabledMessage = "Email notifications are not enabled on this bot"
+
+// handleLinkEmail handles the link_email command, sending a verification code to an address or, given
+// off, unlinking the user's address. Replies are private since they show the address.
+func (h *CommandHandler) handleLinkEmail(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, address string) {
+	if h.email == nil {
+		h.respondPrivately(session, interaction, emailDisabledMessage, nil)
+		return
+	}
+
+	if address = strings.TrimSpace(address); strings.EqualFold(address, "off") {
+		unlinked, err := h.email.UnlinkEmail(ctx, userID)
+		if err != nil {
+			h.respondFailure(ctx, session, interaction, "Failed to unlink your email address", fmt.Sprintf("Failed to unlink the email of user %s: %v", userID, err))
+			return
+		}
+		if !unlinked {
+			h.respondPrivately(session, interaction, "You have no email address linked", nil)
+			return
+		}
+		h.respondPrivately(session, interaction, "📭 Your email address is unlinked, notifications go back to DMs", nil)
+		return
+	}
+
+	err := h.email.SendVerificationCode(ctx, userID, address)
+	if errors.Is(err, services.ErrInvalidEmail) {
+		h.respondPrivately(session, interaction, fmt.Sprintf("%q is not an email address, use one like `name@example.com`", address), nil)
+		return
+	}
+	if errors.Is(err, services.ErrEmailCodeTooSoon) {
+		h.respondPrivately(session, interaction, "A code was sent moments ago, check your inbox or try again in a minute", nil)
+		return
+	}
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to send a verification code", fmt.Sprintf("Failed to send an email code to user %s: %v", userID, err))
+		return
+	}
+	h.respondPrivately(session, interaction, fmt.Sprintf("📧 A verification code was sent to %s. Enter it with `/verify_email <code>` within %d minutes.", services.EscapeMarkdown(address), int(services.EmailCodeTTL.Minutes())), nil)
+}
+
+// handleVerifyEmail handles the verify_email command
+func (h *CommandHandler) handleVerifyEmail(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, code string) {
+	if h.email == nil {
+		h.respondPrivately(session, interaction, emailDisabledMessage, nil)
+		return
+	}
+
+	address, err := h.email.VerifyEmail(ctx, userID, code)
+	if errors.Is(err, services.ErrEmailCodeExpired) {
+		h.respondPrivately(session, interaction, "This code expired, use `/link_email` to get a new one", nil)
+		return
+	}
+	if errors.Is(err, services.ErrEmailCodeInvalid) {
+		h.respondPrivately(session, interaction, "Wrong code, check the latest email from `/link_email`", nil)
+		return
+	}
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to verify your email address", fmt.Sprintf("Failed to verify the email of user %s: %v", userID, err))
+		return
+	}
+	h.respondPrivately(session, interaction, fmt.Sprintf("✅ %s is linked. Use `/email_notifications` to choose what is emailed to it.", services.EscapeMarkdown(address)), nil)
+}
+
+// handleEmailNotifications handles the email_notifications command
+func (h *CommandHandler) handleEmailNotifications(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, digest string, resolutions bool) {
+	if h.email == nil {
+		h.respondPrivately(session, interaction, emailDisabledMessage, nil)
+		return
+	}
+
+	if digest == "off" {
+		digest = models.DigestOff
+	}
+	settings, err := h.email.SetEmailNotifications(ctx, userID, digest, resolutions)
+	if errors.Is(err, services.ErrEmailNotLinked) {
+		h.respondPrivately(session, interaction, "Link an email address first with `/link_email <address>`", nil)
+		return
+	}
+	if errors.Is(err, services.ErrInvalidChannelSettings) {
+		h.respondPrivately(session, interaction, "Invalid digest, use daily, weekly or off", nil)
+		return
+	}
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update your email notifications", fmt.Sprintf("Failed to set the email notifications of user %s: %v", userID, err))
+		return
+	}
+	h.respondPrivately(session, interaction, fmt.Sprintf("📧 Email notifications updated: %s", describeEmailSettings(settings)), nil)
+}
+
+// describeEmailSettings summarizes what is emailed to a user, without their address
+func describeEmailSettings(settings *models.EmailSettings) string {
+	digest := "no digest"
+	if settings.Digest != models.DigestOff {
+		digest = settings.Digest + " digest"
+	}
+	resolutions := "resolutions by DM"
+	if settings.Resolutions {
+		resolutions = "resolutions by email"
+	}
+	return digest + ", " + resolutions
+}
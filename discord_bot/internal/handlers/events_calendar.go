@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"coral-bot/discord_bot/internal/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// handleEventsCalendar handles the events_calendar command, listing the upcoming events or, given an
+// event, the markets about it
+func (h *CommandHandler) handleEventsCalendar(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, query string) {
+	if h.calendar == nil {
+		h.respondToInteraction(session, interaction, "The events calendar is not enabled on this bot")
+		return
+	}
+
+	if query = strings.TrimSpace(query); query != "" {
+		event, err := h.calendar.FindEvent(ctx, query)
+		if errors.Is(err, services.ErrCalendarEventNotFound) {
+			h.respondToInteraction(session, interaction, fmt.Sprintf("No upcoming event matches %q. Use `/events_calendar` to list them", query))
+			return
+		}
+		if err != nil {
+			h.respondFailure(ctx, session, interaction, "Failed to retrieve the events calendar", fmt.Sprintf("Failed to find calendar event %q: %v", query, err))
+			return
+		}
+		digest, err := h.calendar.BuildThemedDigest(ctx, event, nil)
+		if err != nil {
+			h.respondFailure(ctx, session, interaction, "Failed to retrieve the markets about this event", fmt.Sprintf("Failed to build the digest of calendar event %s: %v", event.ID, err))
+			return
+		}
+		h.respondLocalized(ctx, session, interaction, digest)
+		return
+	}
+
+	events, err := h.calendar.UpcomingEvents(ctx, services.CalendarBrowseWindow)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve the events calendar", fmt.Sprintf("Failed to get calendar events: %v", err))
+		return
+	}
+	if len(events) == 0 {
+		h.respondToInteraction(session, interaction, "No events in the next two weeks")
+		return
+	}
+
+	var response strings.Builder
+	response.WriteString("🗓️ **Upcoming Events** — the next two weeks\n\n")
+	for _, event := range events {
+		kind := ""
+		if event.Kind != "" {
+			kind = ", " + event.Kind
+		}
+		response.WriteString(fmt.Sprintf("- **%s** (`%s`%s) — %s, %d %s\n", services.EscapeMarkdown(event.Name), services.EscapeCodeBlock(event.ID), services.EscapeMarkdown(kind), services.DiscordTimestamp(event.StartTime, services.TimestampShortDateTime), len(event.MarketIDs), pluralize(len(event.MarketIDs), "market")))
+	}
+	response.WriteString("\nUse `/events_calendar <event>` to list an event's markets")
+	h.respondLocalized(ctx, session, interaction, response.String())
+}
+
+// handleChannelCalendar handles the channel_calendar command
+func (h *CommandHandler) handleChannelCalendar(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, setting string) {
+	if h.calendar == nil {
+		h.respondToInteraction(session, interaction, "The events calendar is not enabled on this bot")
+		return
+	}
+
+	enabled := setting == "on"
+	err := h.subscriptionService.SetChannelCalendarDigests(ctx, channelID, interaction.GuildID, enabled, interactionUserID(interaction))
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to update channel settings", fmt.Sprintf("Failed to set event digests for channel %s: %v", channelID, err))
+		return
+	}
+
+	if !enabled {
+		h.respondToInteraction(session, interaction, "This channel will no longer post event digests")
+		return
+	}
+	h.respondToInteraction(session, interaction, "Before each upcoming event, like an election or a match, this channel will get a digest of the markets about it")
+}
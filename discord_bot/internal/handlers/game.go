@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+	"coral-bot/discord_bot/internal/tracing"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// betStakeField is the custom ID of the stake field of the bet modal
+const betStakeField = "stake"
+
+// gameDisabledMessage answers game commands and bet buttons when the bot does not run the game
+const gameDisabledMessage = "The prediction game is not enabled on this bot"
+
+// SetGameService sets the service running the prediction game of the bet buttons under markets
+func (h *CommandHandler) SetGameService(game services.GameService) {
+	h.game = game
+}
+
+// betComponentName returns the action a bet button or modal runs, or "" for other components
+func betComponentName(customID string) string {
+	if _, _, ok := services.ParseBetButton(customID); ok {
+		return services.GameBetButtonPrefix
+	}
+	return ""
+}
+
+// handleBetComponent handles a click on a bet button the way handleComponent handles suggested markets
+func (h *CommandHandler) handleBetComponent(session *discordgo.Session, interaction *discordgo.InteractionCreate, customID string) {
+	userID := interactionUserID(interaction)
+	if userID == "" {
+		h.logger.Warning(fmt.Sprintf("Ignoring component %s without a user", customID))
+		return
+	}
+	action := services.GameBetButtonPrefix
+
+	ctx, cancel := context.WithTimeout(context.Background(), interactionTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "discord.component."+action, attribute.String("discord.user_id", userID))
+	defer span.End()
+	defer h.recoverInteraction(ctx, span, session, interaction, action, userID)
+
+	h.logger.Info(fmt.Sprintf("Handling %s component %s from user: %s", action, customID, userID))
+	h.analyticsService.RecordCommand(ctx, action, userID)
+
+	marketID, index, _ := services.ParseBetButton(customID)
+	market, outcome := h.bettableMarket(ctx, session, interaction, marketID, index)
+	if market == nil {
+		return
+	}
+	wallet, err := h.game.GetWallet(ctx, interaction.GuildID, userID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to get your play money", fmt.Sprintf("Failed to get the game wallet of user %s in guild %s: %v", userID, interaction.GuildID, err))
+		return
+	}
+	h.respondWithModal(session, interaction, customID, truncateLabel("Bet on "+outcome, maxModalTitle),
+		discordgo.TextInput{
+			CustomID:    betStakeField,
+			Label:       fmt.Sprintf("Stake (you have $%.2f)", wallet.Balance),
+			Style:       discordgo.TextInputShort,
+			Placeholder: "50",
+			Required:    true,
+			MaxLength:   12,
+		},
+	)
+}
+
+// bettableMarket fetches a market a member is betting on and the outcome at index, replying privately
+// and returning nil when the game is off for the bot or guild, or the market is not open
+func (h *CommandHandler) bettableMarket(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, marketID string, index int) (*models.Market, string) {
+	if h.game == nil {
+		h.respondPrivately(session, interaction, gameDisabledMessage, nil)
+		return nil, ""
+	}
+	if guild := h.guildConfig(ctx, interaction.GuildID); guild == nil || !guild.GameEnabled {
+		h.respondPrivately(session, interaction, "The prediction game is not on in this server", nil)
+		return nil, ""
+	}
+	market, err := h.marketService.FetchMarket(ctx, marketID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve market information", fmt.Sprintf("Failed to fetch market %s to bet on: %v", marketID, err))
+		return nil, ""
+	}
+	if !services.Tradable(market, time.Now()) || index >= len(market.Outcomes) {
+		h.respondPrivately(session, interaction, "This market is closed to bets", nil)
+		return nil, ""
+	}
+	return market, market.Outcomes[index]
+}
+
+// handleBetSubmit places the bet entered in the bet modal and tells the member what it pays
+func (h *CommandHandler) handleBetSubmit(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, marketID string, index int, stakeValue string) {
+	stake, err := parseAmount(stakeValue)
+	if err != nil || stake <= 0 {
+		h.respondPrivately(session, interaction, "The stake must be an amount of play money more than 0, like 50", nil)
+		return
+	}
+	market, outcome := h.bettableMarket(ctx, session, interaction, marketID, index)
+	if market == nil {
+		return
+	}
+
+	bet, wallet, err := h.game.PlaceBet(ctx, interaction.GuildID, userID, market, outcome, stake)
+	switch {
+	case errors.Is(err, services.ErrInvalidChannelSettings):
+		h.respondPrivately(session, interaction, strings.TrimPrefix(err.Error(), services.ErrInvalidChannelSettings.Error()+": "), nil)
+		return
+	case errors.Is(err, services.ErrGameBalanceTooLow):
+		h.respondPrivately(session, interaction, "You don't have enough play money for that bet: "+strings.TrimPrefix(err.Error(), services.ErrGameBalanceTooLow.Error()+": "), nil)
+		return
+	case err != nil:
+		h.respondFailure(ctx, session, interaction, "Failed to place your bet", fmt.Sprintf("Failed to place a bet in market %s for user %s in guild %s: %v", marketID, userID, interaction.GuildID, err))
+		return
+	}
+	h.respondPrivately(session, interaction, fmt.Sprintf("🎲 You bet **$%.2f** of play money on **%s** in **%s** at %.1f%%. It pays **$%.2f** if %s wins.\nBalance: $%.2f",
+		bet.Stake,
+		services.EscapeMarkdown(bet.Outcome),
+		services.EscapeMarkdown(market.Title),
+		bet.Price*100,
+		bet.Shares,
+		services.EscapeMarkdown(bet.Outcome),
+		wallet.Balance,
+	), nil)
+}
+
+// handleGameMode handles the game_mode command, turning the prediction game on or off for the guild
+func (h *CommandHandler) handleGameMode(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string, enabled bool) {
+	if h.game == nil {
+		h.respondToInteraction(session, interaction, gameDisabledMessage)
+		return
+	}
+	config, err := h.subscriptionService.GetGuildConfig(ctx, interaction.GuildID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update server settings", fmt.Sprintf("Failed to get guild config for %s: %v", interaction.GuildID, err))
+		return
+	}
+	if config == nil {
+		config = &models.GuildConfig{GuildID: interaction.GuildID}
+	}
+	config.GameEnabled = enabled
+	config.ConfiguredBy = userID
+	if err := h.subscriptionService.UpdateGuildConfig(ctx, config); err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update server settings", fmt.Sprintf("Failed to update guild config for %s: %v", interaction.GuildID, err))
+		return
+	}
+
+	if enabled {
+		h.respondToInteraction(session, interaction, "🎲 The prediction game is on: members can bet play money on market alerts and `/market` in this server, and bets are settled when the markets resolve. See the standings with `/game_leaderboard`")
+		return
+	}
+	h.respondToInteraction(session, interaction, "The prediction game is off for this server. Open bets are still settled, and the standings are kept")
+}
+
+// handleGameLeaderboard handles the game_leaderboard command, ranking the guild's players by their
+// play money with open bets at their stake
+func (h *CommandHandler) handleGameLeaderboard(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string) {
+	if h.game == nil {
+		h.respondToInteraction(session, interaction, gameDisabledMessage)
+		return
+	}
+	standings, err := h.game.GetLeaderboard(ctx, interaction.GuildID, services.DefaultGameLeaderboardSize)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to get the leaderboard", fmt.Sprintf("Failed to get the game leaderboard of guild %s: %v", interaction.GuildID, err))
+		return
+	}
+	if len(standings) == 0 {
+		h.respondToInteraction(session, interaction, "No one has bet in this server yet. Click a 🎲 button under a market alert to play")
+		return
+	}
+
+	var message strings.Builder
+	message.WriteString("🏆 **Prediction game leaderboard**\n\n")
+	listed := false
+	for _, standing := range standings {
+		fmt.Fprintf(&message, "**%d.** <@%s> • $%.2f (%s) • %d bets, %d won\n", standing.Rank, standing.DiscordUserID, standing.Worth, signedPlayMoney(standing.Profit), standing.Bets, standing.Wins)
+		listed = listed || standing.DiscordUserID == userID
+	}
+	if !listed {
+		if wallet, err := h.game.GetWallet(ctx, interaction.GuildID, userID); err == nil {
+			fmt.Fprintf(&message, "\nYou: $%.2f", wallet.Worth())
+		}
+	}
+	h.respondToInteraction(session, interaction, strings.TrimSuffix(message.String(), "\n"))
+}
+
+// signedPlayMoney formats a game profit or loss, like +$40.00 or -$12.50
+func signedPlayMoney(amount float64) string {
+	if amount < 0 {
+		return fmt.Sprintf("-$%.2f", -amount)
+	}
+	return fmt.Sprintf("+$%.2f", amount)
+}
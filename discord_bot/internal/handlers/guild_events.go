@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+	"coral-bot/discord_bot/internal/tracing"
+	"coral-bot/discord_bot/internal/utils"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// guildEventTimeout bounds the cleanup done for a single gateway event
+const guildEventTimeout = 10 * time.Second
+
+// GuildEventHandler forgets channels that are deleted and guilds the bot is removed from, so events
+// stop being sent to channels that no longer exist
+type GuildEventHandler struct {
+	subscriptionService services.SubscriptionService
+	logger              *utils.Logger
+}
+
+// NewGuildEventHandler creates a new guild event handler
+func NewGuildEventHandler(subscriptionService services.SubscriptionService, logger *utils.Logger) *GuildEventHandler {
+	return &GuildEventHandler{
+		subscriptionService: subscriptionService,
+		logger:              logger,
+	}
+}
+
+// Register subscribes the handler to a session's channel and guild deletions
+func (h *GuildEventHandler) Register(session *discordgo.Session) {
+	session.AddHandler(h.HandleChannelDelete)
+	session.AddHandler(h.HandleGuildDelete)
+}
+
+// HandleChannelDelete removes the configuration, webhook registrations and category routes of a deleted channel
+func (h *GuildEventHandler) HandleChannelDelete(session *discordgo.Session, event *discordgo.ChannelDelete) {
+	if event.Channel == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), guildEventTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "discord.event.ChannelDelete", attribute.String("discord.channel_id", event.ID))
+
+	err := h.subscriptionService.RemoveChannel(ctx, event.ID, models.AuditActorDiscord)
+	tracing.End(span, err)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to clean up deleted channel %s: %v", event.ID, err))
+	}
+}
+
+// HandleGuildDelete removes everything configured in a guild the bot was removed from. A guild that is
+// only unavailable during a Discord outage is also reported as deleted, and is kept.
+func (h *GuildEventHandler) HandleGuildDelete(session *discordgo.Session, event *discordgo.GuildDelete) {
+	if event.Guild == nil {
+		return
+	}
+	if event.Unavailable {
+		h.logger.Warning(fmt.Sprintf("Guild %s is unavailable, keeping its configuration", event.ID))
+		return
+	}
+
+	// The state's copy of the guild lists channels that may have webhooks without a channel config
+	var channelIDs []string
+	if event.BeforeDelete != nil {
+		for _, channel := range event.BeforeDelete.Channels {
+			channelIDs = append(channelIDs, channel.ID)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), guildEventTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "discord.event.GuildDelete", attribute.String("discord.guild_id", event.ID))
+
+	err := h.subscriptionService.RemoveGuild(ctx, event.ID, channelIDs, models.AuditActorDiscord)
+	tracing.End(span, err)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to clean up after removal from guild %s: %v", event.ID, err))
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"coral-bot/discord_bot/internal/services"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newErrorID returns a short random ID that ties an error shown to a user to its log entry
+func newErrorID() string {
+	randomBytes := make([]byte, 4)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(randomBytes)
+}
+
+// recoverInteraction turns a panic in a command handler into a logged, traced and recorded failure
+// and a private reply, so the user is not left with "The application did not respond". It must be
+// deferred directly.
+func (h *CommandHandler) recoverInteraction(ctx context.Context, span trace.Span, session *discordgo.Session, interaction *discordgo.InteractionCreate, commandName, userID string) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	errorID := newErrorID()
+	h.logger.Error(fmt.Sprintf("[error %s] Command %s from user %s panicked: %v\n%s", errorID, commandName, userID, recovered, debug.Stack()))
+	span.SetAttributes(attribute.String("error.id", errorID))
+	span.SetStatus(codes.Error, fmt.Sprint(recovered))
+	h.recordFailure(ctx, commandName, userID)
+	h.respondWithErrorID(session, interaction, "Something went wrong while running this command", errorID)
+}
+
+// respondFailure logs a failed command with a new error ID, records the failure and privately tells the
+// user what failed along with the ID, which they can pass on so the log entry can be found
+func (h *CommandHandler) respondFailure(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, message, logMessage string) {
+	errorID := newErrorID()
+	h.logger.Error(fmt.Sprintf("[error %s] %s", errorID, logMessage))
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("error.id", errorID))
+	h.recordFailure(ctx, interactionName(interaction), interactionUserID(interaction))
+	h.respondWithErrorID(session, interaction, message, errorID)
+}
+
+// respondChangeFailure replies to a subscription or channel change that failed. A subscription limit
+// the user or server reached is explained privately instead of being reported as an error.
+func (h *CommandHandler) respondChangeFailure(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, err error, message, logMessage string) {
+	var limitErr *services.LimitExceededError
+	if errors.As(err, &limitErr) {
+		h.respondPrivately(session, interaction, limitErr.Error(), nil)
+		return
+	}
+	h.respondFailure(ctx, session, interaction, message, logMessage)
+}
+
+// interactionName returns the command an interaction ran, the custom ID of a submitted modal, the
+// command a suggested market button runs, or the action of a trade or bet button or modal
+func interactionName(interaction *discordgo.InteractionCreate) string {
+	switch interaction.Type {
+	case discordgo.InteractionModalSubmit:
+		customID := interaction.ModalSubmitData().CustomID
+		if action := tradeComponentName(customID); action != "" {
+			return action
+		}
+		if action := betComponentName(customID); action != "" {
+			return action
+		}
+		return customID
+	case discordgo.InteractionMessageComponent:
+		customID := interaction.MessageComponentData().CustomID
+		if command, _, _, ok := parseMarketPick(customID); ok {
+			return command
+		}
+		if action := tradeComponentName(customID); action != "" {
+			return action
+		}
+		if action := betComponentName(customID); action != "" {
+			return action
+		}
+		return customID
+	}
+	return interaction.ApplicationCommandData().Name
+}
+
+// recordFailure records a failed command in the analytics, even when the command ran out of time
+func (h *CommandHandler) recordFailure(ctx context.Context, commandName, userID string) {
+	h.analyticsService.RecordCommandFailure(context.WithoutCancel(ctx), commandName, userID)
+}
+
+// respondWithErrorID replies privately with a failure message and its error ID. When the handler had
+// already replied before failing, the message is sent as a private follow-up instead.
+func (h *CommandHandler) respondWithErrorID(session *discordgo.Session, interaction *discordgo.InteractionCreate, message, errorID string) {
+	content := fmt.Sprintf("%s. Please try again in a moment; if it keeps happening, share error ID `%s` with the bot's maintainers.", message, errorID)
+	err := session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err == nil {
+		return
+	}
+	if _, followupErr := session.FollowupMessageCreate(interaction.Interaction, true, &discordgo.WebhookParams{
+		Content: content,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	}); followupErr != nil {
+		h.logger.Error(fmt.Sprintf("[error %s] Failed to report the error to the user: %v", errorID, followupErr))
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"coral-bot/discord_bot/internal/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// SetMaintenanceMode sets the maintenance mode admin_maintenance turns on and off. During maintenance,
+// every command but the bot owner commands is answered with its notice.
+func (h *CommandHandler) SetMaintenanceMode(maintenance *services.MaintenanceMode) {
+	h.maintenance = maintenance
+}
+
+// maintenanceNotice returns the reply to a command, component or modal during maintenance, and whether
+// the bot is in maintenance. Bot owner commands keep working, so maintenance can be ended from Discord.
+func (h *CommandHandler) maintenanceNotice(name string) (string, bool) {
+	if h.maintenance == nil || strings.HasPrefix(name, "admin_") {
+		return "", false
+	}
+	return h.maintenance.Notice()
+}
+
+// handleAdminMaintenance handles the admin_maintenance command, which shows, starts or ends maintenance
+func (h *CommandHandler) handleAdminMaintenance(session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, action, notice string) {
+	if !h.owners[userID] {
+		h.respondPrivately(session, interaction, "This command is restricted to bot owners", nil)
+		return
+	}
+	if h.maintenance == nil {
+		h.respondPrivately(session, interaction, "Maintenance mode is not enabled", nil)
+		return
+	}
+
+	switch action {
+	case "on":
+		h.maintenance.Start(notice)
+		h.logger.Info(fmt.Sprintf("User %s started maintenance", userID))
+		notice, _ = h.maintenance.Notice()
+		h.respondPrivately(session, interaction, "🛠️ Maintenance started. Notifications are held until it ends, and commands are answered with:\n> "+notice, nil)
+	case "off":
+		flushing := h.maintenance.End()
+		h.logger.Info(fmt.Sprintf("User %s ended maintenance", userID))
+		h.respondPrivately(session, interaction, fmt.Sprintf("✅ Maintenance ended, sending %d held %s", flushing, pluralize(flushing, "notification")), nil)
+	default:
+		status := h.maintenance.Status()
+		if !status.Enabled {
+			h.respondPrivately(session, interaction, "Maintenance is off", nil)
+			return
+		}
+		h.respondPrivately(session, interaction, fmt.Sprintf("🛠️ Maintenance since %s, %d held %s (%d dropped). Commands are answered with:\n> %s", services.DiscordTimestamp(status.Since, services.TimestampRelative), status.Buffered, pluralize(status.Buffered, "notification"), status.Dropped, status.Notice), nil)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/services"
+	"coral-bot/discord_bot/internal/tracing"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// marketPickPrefix starts the custom ID of the buttons offered in place of an unknown market ID. The
+// custom ID also holds the command to run again, the chosen market ID and the command's other option.
+const marketPickPrefix = "pick_market"
+
+// marketPickSeparator separates the parts of a market pick custom ID
+const marketPickSeparator = "|"
+
+// maxMarketSuggestions is the number of close matches offered, one row of buttons
+const maxMarketSuggestions = 5
+
+// maxButtonLabel is the longest button label Discord accepts
+const maxButtonLabel = 80
+
+// marketCheckTimeout bounds the lookup that checks a market ID before subscribing, so a slow backend
+// leaves time to subscribe and reply
+const marketCheckTimeout = time.Second
+
+// confirmMarket reports whether the backend knows marketID. When it answers that it does not, confirmMarket
+// replies with the closest matches and returns false. A lookup that fails for any other reason, such as
+// no backend being configured, does not count against the ID.
+func (h *CommandHandler) confirmMarket(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, command, marketID, option string) bool {
+	lookupCtx, cancel := context.WithTimeout(ctx, marketCheckTimeout)
+	defer cancel()
+	_, err := h.marketService.FetchMarket(lookupCtx, marketID)
+	if errors.Is(err, services.ErrBackendNotFound) {
+		h.respondUnknownMarket(ctx, session, interaction, command, marketID, option)
+		return false
+	}
+	if err != nil {
+		h.logger.Warning(fmt.Sprintf("Could not check market %s for %s: %v", marketID, command, err))
+	}
+	return true
+}
+
+// respondUnknownMarket privately tells the user no market has the ID and offers a button for each of
+// the closest matches by ID prefix or title, which runs the command again with the chosen market
+func (h *CommandHandler) respondUnknownMarket(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, command, marketID, option string) {
+	matches, err := h.marketService.SearchMarkets(ctx, marketID, maxMarketSuggestions)
+	if err != nil {
+		h.logger.Warning(fmt.Sprintf("Failed to search markets matching %q: %v", marketID, err))
+	}
+
+	var buttons []discordgo.MessageComponent
+	var lines []string
+	for _, market := range matches {
+		customID := strings.Join([]string{marketPickPrefix, command, market.ID, option}, marketPickSeparator)
+		if len(customID) > 100 || len(buttons) == maxMarketSuggestions {
+			continue
+		}
+		buttons = append(buttons, discordgo.Button{Label: truncateLabel(market.Title, maxButtonLabel), Style: discordgo.SecondaryButton, CustomID: customID})
+		lines = append(lines, fmt.Sprintf("- %s (`%s`)", services.EscapeMarkdown(market.Title), services.EscapeCodeBlock(market.ID)))
+	}
+
+	if len(buttons) == 0 {
+		h.respondPrivately(session, interaction, fmt.Sprintf("No market has the ID `%s`. Check the ID in the market's link and try again", marketID), nil)
+		return
+	}
+	err = session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    fmt.Sprintf("No market has the ID `%s`. Did you mean:\n%s", marketID, strings.Join(lines, "\n")),
+			Components: []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}},
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to respond to interaction: %v", err))
+	}
+}
+
+// parseMarketPick splits a market pick custom ID into the command, the chosen market ID and the command's other option
+func parseMarketPick(customID string) (command, marketID, option string, ok bool) {
+	parts := strings.SplitN(customID, marketPickSeparator, 4)
+	if len(parts) != 4 || parts[0] != marketPickPrefix || parts[2] == "" {
+		return "", "", "", false
+	}
+	return parts[1], parts[2], parts[3], true
+}
+
+// handleComponent handles a click on a suggested market by running its command the way HandleInteraction
+// does, or on a trade or bet button
+func (h *CommandHandler) handleComponent(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	customID := interaction.MessageComponentData().CustomID
+	if action := tradeComponentName(customID); action != "" {
+		h.handleTradeComponent(session, interaction, customID, action)
+		return
+	}
+	if betComponentName(customID) != "" {
+		h.handleBetComponent(session, interaction, customID)
+		return
+	}
+	command, marketID, option, ok := parseMarketPick(customID)
+	if !ok {
+		h.logger.Warning(fmt.Sprintf("Ignoring unknown component %s", customID))
+		return
+	}
+	userID := interactionUserID(interaction)
+	if userID == "" {
+		h.logger.Warning(fmt.Sprintf("Ignoring component %s without a user", customID))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), interactionTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "discord.component."+command, attribute.String("discord.user_id", userID))
+	defer span.End()
+	defer h.recoverInteraction(ctx, span, session, interaction, command, userID)
+
+	h.logger.Info(fmt.Sprintf("Handling suggested market %s for %s from user: %s", marketID, command, userID))
+	h.analyticsService.RecordCommand(ctx, command, userID)
+
+	if interaction.GuildID == "" && guildOnlyCommand(command) {
+		h.respondToInteraction(session, interaction, "This command can only be used in a server channel")
+		return
+	}
+
+	switch command {
+	case "market":
+		h.handleGetMarket(ctx, session, interaction, marketID)
+	case "history":
+		h.handleHistory(ctx, session, interaction, marketID, option)
+	case "subscribe_market":
+		h.handleSubscribeMarket(ctx, session, interaction, userID, marketID)
+	case "remind_me":
+		h.handleRemindMe(ctx, session, interaction, userID, marketID, option)
+	case "channel_subscribe_market":
+		h.handleChannelSubscribeMarket(ctx, session, interaction, interaction.ChannelID, marketID)
+	case "channel_remind":
+		h.handleChannelRemind(ctx, session, interaction, interaction.ChannelID, marketID, option)
+	default:
+		h.respondToInteraction(session, interaction, "Unknown command")
+	}
+}
+
+// truncateLabel shortens text to at most max characters, ending it with an ellipsis when it was cut
+func truncateLabel(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
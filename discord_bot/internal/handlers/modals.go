@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+	"coral-bot/discord_bot/internal/tracing"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Modal custom IDs, named after the command that opens the modal so failures are recorded under it
+const (
+	categoriesModalID   = "channel_feed_categories"
+	channelSetupModalID = "channel_setup"
+)
+
+// Modal field custom IDs
+const (
+	categoriesField = "categories"
+	frequencyField  = "frequency"
+	minVolumeField  = "min_volume"
+	minBuyField     = "min_buy"
+)
+
+// openCategoriesModal opens a dialog to edit the channel's allowed categories, one field prefilled
+// with the current list
+func (h *CommandHandler) openCategoriesModal(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID string) {
+	config, err := h.subscriptionService.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve channel settings", fmt.Sprintf("Failed to get channel config for %s: %v", channelID, err))
+		return
+	}
+
+	h.respondWithModal(session, interaction, categoriesModalID, "Allowed categories", categoriesInput(config))
+}
+
+// openChannelSetupModal opens a dialog to set the channel's categories, update frequency, minimum
+// volume and minimum buy at once, prefilled with the current settings
+func (h *CommandHandler) openChannelSetupModal(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID string) {
+	if !h.checkFeedPermissions(ctx, session, interaction, channelID) {
+		return
+	}
+	config, err := h.subscriptionService.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve channel settings", fmt.Sprintf("Failed to get channel config for %s: %v", channelID, err))
+		return
+	}
+
+	h.respondWithModal(session, interaction, channelSetupModalID, "Channel setup",
+		categoriesInput(config),
+		discordgo.TextInput{
+			CustomID:    frequencyField,
+			Label:       "Update frequency",
+			Style:       discordgo.TextInputShort,
+			Placeholder: strings.Join(models.ChannelFrequencies, ", ") + " or custom 45m",
+			Value:       frequencyValue(config),
+			Required:    true,
+			MaxLength:   20,
+		},
+		discordgo.TextInput{
+			CustomID:    minVolumeField,
+			Label:       "Minimum market volume",
+			Style:       discordgo.TextInputShort,
+			Placeholder: "0 to announce every market",
+			Value:       amountValue(config.MinVolume),
+			MaxLength:   20,
+		},
+		discordgo.TextInput{
+			CustomID:    minBuyField,
+			Label:       "Minimum buy",
+			Style:       discordgo.TextInputShort,
+			Placeholder: "0 to post every buy",
+			Value:       amountValue(config.MinBuyAmount),
+			MaxLength:   20,
+		},
+	)
+}
+
+// categoriesInput returns the categories field of a modal, prefilled with the channel's categories
+func categoriesInput(config *models.ChannelConfig) discordgo.TextInput {
+	return discordgo.TextInput{
+		CustomID:    categoriesField,
+		Label:       "Allowed categories, comma-separated",
+		Style:       discordgo.TextInputParagraph,
+		Placeholder: "politics, sports, crypto (leave empty for all categories)",
+		Value:       strings.Join(config.AllowedCategories, ", "),
+		MaxLength:   1000,
+	}
+}
+
+// amountValue formats an amount for a modal field, leaving it empty when it is zero
+func amountValue(amount float64) string {
+	if amount == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+// frequencyValue formats the channel's update frequency for a modal field, with the interval of a custom one
+func frequencyValue(config *models.ChannelConfig) string {
+	if config.FrequencyMode == models.FrequencyCustom {
+		return models.FrequencyCustom + " " + models.FormatInterval(config.CustomInterval)
+	}
+	return config.FrequencyMode
+}
+
+// respondWithModal opens a modal with one text field per row
+func (h *CommandHandler) respondWithModal(session *discordgo.Session, interaction *discordgo.InteractionCreate, customID, title string, inputs ...discordgo.TextInput) {
+	rows := make([]discordgo.MessageComponent, len(inputs))
+	for i, input := range inputs {
+		rows[i] = discordgo.ActionsRow{Components: []discordgo.MessageComponent{input}}
+	}
+	err := session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID:   customID,
+			Title:      title,
+			Components: rows,
+		},
+	})
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to open %s modal: %v", customID, err))
+	}
+}
+
+// handleModalSubmit handles a submitted modal the same way HandleInteraction handles a command
+func (h *CommandHandler) handleModalSubmit(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	modal := interaction.ModalSubmitData()
+	userID := interactionUserID(interaction)
+	if userID == "" {
+		h.logger.Warning(fmt.Sprintf("Ignoring modal %s without a user", modal.CustomID))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), interactionTimeout)
+	defer cancel()
+	name := interactionName(interaction)
+	ctx, span := tracing.Start(ctx, "discord.modal."+name, attribute.String("discord.user_id", userID))
+	defer span.End()
+	defer h.recoverInteraction(ctx, span, session, interaction, name, userID)
+
+	h.logger.Info(fmt.Sprintf("Handling modal: %s from user: %s", modal.CustomID, userID))
+
+	if interaction.GuildID == "" {
+		h.respondToInteraction(session, interaction, "This command can only be used in a server channel")
+		return
+	}
+
+	values := modalValues(modal.Components)
+	switch modal.CustomID {
+	case categoriesModalID:
+		h.handleChannelFeedCategories(ctx, session, interaction, interaction.ChannelID, values[categoriesField])
+	case channelSetupModalID:
+		h.handleChannelSetupSubmit(ctx, session, interaction, interaction.ChannelID, values)
+	default:
+		if marketID, outcome, ok := services.ParseTradeButton(modal.CustomID); ok {
+			h.handleTradeSubmit(ctx, session, interaction, userID, marketID, outcome, values[tradeAmountField])
+			return
+		}
+		if marketID, outcome, ok := services.ParseBetButton(modal.CustomID); ok {
+			h.handleBetSubmit(ctx, session, interaction, userID, marketID, outcome, values[betStakeField])
+			return
+		}
+		h.respondToInteraction(session, interaction, "Unknown form")
+	}
+}
+
+// modalValues returns the submitted text of each field of a modal, keyed by field custom ID
+func modalValues(components []discordgo.MessageComponent) map[string]string {
+	values := make(map[string]string)
+	for _, component := range components {
+		var row *discordgo.ActionsRow
+		switch c := component.(type) {
+		case *discordgo.ActionsRow:
+			row = c
+		case discordgo.ActionsRow:
+			row = &c
+		default:
+			continue
+		}
+		for _, field := range row.Components {
+			switch input := field.(type) {
+			case *discordgo.TextInput:
+				values[input.CustomID] = strings.TrimSpace(input.Value)
+			case discordgo.TextInput:
+				values[input.CustomID] = strings.TrimSpace(input.Value)
+			}
+		}
+	}
+	return values
+}
+
+// handleChannelSetupSubmit validates the channel setup modal and applies every field at once, turning
+// the new market feed on. Nothing is saved when a field is invalid.
+func (h *CommandHandler) handleChannelSetupSubmit(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID string, values map[string]string) {
+	var problems []string
+	level, interval, _ := strings.Cut(strings.TrimSpace(values[frequencyField]), " ")
+	frequency, customInterval, err := services.ParseFrequency(level, interval)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("Update frequency must be one of %s, or custom with an interval between %s and %s like custom 45m",
+			strings.Join(models.ChannelFrequencies, ", "), models.FormatInterval(models.MinCustomInterval), models.FormatInterval(models.MaxCustomInterval)))
+	}
+	minVolume, err := parseAmount(values[minVolumeField])
+	if err != nil {
+		problems = append(problems, "Minimum market volume "+err.Error())
+	}
+	minBuy, err := parseAmount(values[minBuyField])
+	if err != nil {
+		problems = append(problems, "Minimum buy "+err.Error())
+	}
+	categoryList, problem := h.resolveCategories(ctx, parseCategories(values[categoriesField]))
+	if problem != "" {
+		problems = append(problems, problem)
+	}
+	if len(problems) > 0 {
+		h.respondPrivately(session, interaction, "Nothing was saved:\n- "+strings.Join(problems, "\n- "), nil)
+		return
+	}
+	if !h.checkFeedPermissions(ctx, session, interaction, channelID) {
+		return
+	}
+
+	config, err := h.subscriptionService.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update channel settings", fmt.Sprintf("Failed to get channel config for %s: %v", channelID, err))
+		return
+	}
+
+	config.FeedEnabled = true
+	config.AllowedCategories = categoryList
+	config.FrequencyMode = frequency
+	config.CustomInterval = customInterval
+	config.MinVolume = minVolume
+	config.MinBuyAmount = minBuy
+	config.GuildID = interaction.GuildID
+
+	err = h.subscriptionService.UpdateChannelConfig(ctx, config, interactionUserID(interaction))
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to update channel settings", fmt.Sprintf("Failed to update channel config for %s: %v", channelID, err))
+		return
+	}
+
+	categories := "all categories"
+	if len(config.AllowedCategories) > 0 {
+		categories = strings.Join(config.AllowedCategories, ", ")
+	}
+	response := fmt.Sprintf("New market announcements are on for this channel: %s, %s update frequency, minimum volume $%.2f, minimum buy $%.2f", categories, config.Frequency(), minVolume, minBuy)
+	h.respondToInteraction(session, interaction, response)
+}
+
+// parseCategories splits a comma-separated category list, dropping empty entries
+func parseCategories(categories string) []string {
+	var categoryList []string
+	for _, category := range strings.Split(categories, ",") {
+		if category = strings.TrimSpace(category); category != "" {
+			categoryList = append(categoryList, category)
+		}
+	}
+	return categoryList
+}
+
+// parseAmount parses a dollar amount typed into a modal, such as "1,500" or "$20"; empty means zero
+func parseAmount(value string) (float64, error) {
+	value = strings.ReplaceAll(strings.TrimPrefix(strings.TrimSpace(value), "$"), ",", "")
+	if value == "" {
+		return 0, nil
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, fmt.Errorf("must be a number")
+	}
+	if amount < 0 {
+		return 0, fmt.Errorf("cannot be negative")
+	}
+	return amount, nil
+}
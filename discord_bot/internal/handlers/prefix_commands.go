@@ -0,0 +1,545 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"coral-bot/discord_bot/internal/services"
+	"coral-bot/discord_bot/internal/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxPrefixUploadMemory is how much of the files attached to a message command's response is kept in memory
+const maxPrefixUploadMemory = 8 << 20
+
+// prefixAliases are short names message commands accept for slash commands
+var prefixAliases = map[string]string{
+	"subscribe":     "subscribe_market",
+	"unsubscribe":   "unsubscribe_market",
+	"subscriptions": "list_subscriptions",
+}
+
+// SetCommandPrefix enables message commands such as "!coral market m123", for servers that restrict slash
+// commands. Reading them needs the privileged message content intent.
+func (h *CommandHandler) SetCommandPrefix(prefix string) {
+	h.commandPrefix = strings.TrimSpace(prefix)
+}
+
+// HandleMessageCreate runs a message command through the same handlers as the slash command it names.
+// Responses are posted as replies to the message; private responses are sent to the author in a DM.
+func (h *CommandHandler) HandleMessageCreate(session *discordgo.Session, event *discordgo.MessageCreate) {
+	if h.commandPrefix == "" || event.Author == nil || event.Author.Bot {
+		return
+	}
+	args, ok := prefixArguments(h.commandPrefix, event.Content)
+	if !ok {
+		return
+	}
+	responder := newMessageResponder(session, event.Message, h.logger)
+
+	name := "help"
+	if len(args) > 0 {
+		name, args = strings.ReplaceAll(strings.ToLower(args[0]), "-", "_"), args[1:]
+	}
+	if alias, exists := prefixAliases[name]; exists {
+		name = alias
+	}
+	command := h.findCommand(name)
+	if command == nil {
+		responder.reply(fmt.Sprintf("Unknown command `%s`. Try `%s help` for the list of commands", name, h.commandPrefix))
+		return
+	}
+	if notice, ok := h.maintenanceNotice(command.Name); ok {
+		responder.reply(notice)
+		return
+	}
+	if event.GuildID == "" && guildOnlyCommand(command.Name) {
+		responder.reply("This command can only be used in a server channel")
+		return
+	}
+
+	var permissions int64
+	if event.GuildID != "" {
+		permissions, _ = session.State.MessagePermissions(event.Message)
+	}
+	if required := prefixPermissions(command); permissions&required != required {
+		responder.reply(fmt.Sprintf("You need the Manage Server permission to run `%s %s`. If you have it, use `/%s` instead", h.commandPrefix, command.Name, command.Name))
+		return
+	}
+
+	options, err := prefixOptions(command.Options, args)
+	if err != nil {
+		responder.reply(fmt.Sprintf("Can't run `%s`: %v. Usage: `%s`", command.Name, err, h.prefixUsage(command)))
+		return
+	}
+
+	interaction := &discordgo.Interaction{
+		ID:        event.ID,
+		Type:      discordgo.InteractionApplicationCommand,
+		GuildID:   event.GuildID,
+		ChannelID: event.ChannelID,
+		Token:     "message-" + event.ID,
+		Version:   1,
+		Data: discordgo.ApplicationCommandInteractionData{
+			ID:      command.ID,
+			Name:    command.Name,
+			Options: options,
+		},
+	}
+	if session.State != nil && session.State.User != nil {
+		interaction.AppID = session.State.User.ID
+	}
+	if event.GuildID == "" {
+		interaction.User = event.Author
+	} else {
+		interaction.Member = &discordgo.Member{GuildID: event.GuildID, User: event.Author, Permissions: permissions}
+		if event.Member != nil {
+			interaction.Member.Nick = event.Member.Nick
+			interaction.Member.Roles = event.Member.Roles
+		}
+	}
+	responder.interaction = interaction
+	h.HandleInteraction(responder.session, &discordgo.InteractionCreate{Interaction: interaction})
+}
+
+// findCommand returns the slash command with a name, or nil
+func (h *CommandHandler) findCommand(name string) *discordgo.ApplicationCommand {
+	for _, command := range h.Commands() {
+		if command.Name == name {
+			return command
+		}
+	}
+	return nil
+}
+
+// prefixPermissions returns the permissions a member needs to run a command from a message. Messages
+// bypass the command permissions a server sets up for its slash commands, so server and channel admin
+// commands need the Manage Server permission.
+func prefixPermissions(command *discordgo.ApplicationCommand) int64 {
+	if command.DefaultMemberPermissions != nil {
+		return *command.DefaultMemberPermissions
+	}
+	if guildOnlyCommand(command.Name) {
+		return manageGuildPermission
+	}
+	return 0
+}
+
+// prefixUsage describes a command's arguments, like "!coral subscribe_outcome <market_id> <outcome> [min_change]"
+func (h *CommandHandler) prefixUsage(command *discordgo.ApplicationCommand) string {
+	words := []string{h.commandPrefix, command.Name}
+	if len(command.Options) > 0 && command.Options[0].Type == discordgo.ApplicationCommandOptionSubCommand {
+		names := make([]string, 0, len(command.Options))
+		for _, subcommand := range command.Options {
+			names = append(names, subcommand.Name)
+		}
+		return strings.Join(append(words, strings.Join(names, "/"), "..."), " ")
+	}
+	for _, option := range command.Options {
+		if option.Required {
+			words = append(words, "<"+option.Name+">")
+		} else {
+			words = append(words, "["+option.Name+"]")
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// prefixArguments returns the words after the prefix of a message command, and false for other messages
+func prefixArguments(prefix, content string) ([]string, bool) {
+	content = strings.TrimSpace(content)
+	if len(content) < len(prefix) || !strings.EqualFold(content[:len(prefix)], prefix) {
+		return nil, false
+	}
+	rest := content[len(prefix):]
+	if rest != "" && !unicode.IsSpace([]rune(rest)[0]) {
+		return nil, false
+	}
+	return splitArguments(rest), true
+}
+
+// splitArguments splits a message command into words, keeping words in double quotes together
+func splitArguments(text string) []string {
+	var args []string
+	var word strings.Builder
+	quoted, started := false, false
+	for _, r := range text {
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			quoted = !quoted
+			started = true
+		case unicode.IsSpace(r) && !quoted:
+			if started {
+				args = append(args, word.String())
+				word.Reset()
+				started = false
+			}
+		default:
+			word.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, word.String())
+	}
+	return args
+}
+
+// prefixOptions fills a command's options from the words of a message command in the order the options
+// are defined. Words left over are added to a final text option, so it can hold several words without quotes.
+func prefixOptions(definitions []*discordgo.ApplicationCommandOption, args []string) ([]*discordgo.ApplicationCommandInteractionDataOption, error) {
+	if len(definitions) > 0 && definitions[0].Type == discordgo.ApplicationCommandOptionSubCommand {
+		if len(args) == 0 {
+			return nil, errors.New("missing subcommand")
+		}
+		name := strings.ToLower(args[0])
+		for _, subcommand := range definitions {
+			if subcommand.Name != name {
+				continue
+			}
+			options, err := prefixOptions(subcommand.Options, args[1:])
+			if err != nil {
+				return nil, err
+			}
+			return []*discordgo.ApplicationCommandInteractionDataOption{{Name: subcommand.Name, Type: subcommand.Type, Options: options}}, nil
+		}
+		return nil, fmt.Errorf("unknown subcommand %s", args[0])
+	}
+
+	var options []*discordgo.ApplicationCommandInteractionDataOption
+	for i, definition := range definitions {
+		if i >= len(args) {
+			if definition.Required {
+				return nil, fmt.Errorf("missing %s", definition.Name)
+			}
+			break
+		}
+		arg := args[i]
+		if i == len(definitions)-1 && definition.Type == discordgo.ApplicationCommandOptionString {
+			arg = strings.Join(args[i:], " ")
+		}
+		value, err := prefixOptionValue(definition, arg)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, &discordgo.ApplicationCommandInteractionDataOption{Name: definition.Name, Type: definition.Type, Value: value})
+	}
+	if len(args) > len(definitions) && (len(definitions) == 0 || definitions[len(definitions)-1].Type != discordgo.ApplicationCommandOptionString) {
+		return nil, fmt.Errorf("unexpected %s", args[len(definitions)])
+	}
+	return options, nil
+}
+
+// prefixOptionValue converts a word to an option's value, as Discord sends it for slash commands
+func prefixOptionValue(definition *discordgo.ApplicationCommandOption, arg string) (interface{}, error) {
+	if len(definition.Choices) > 0 {
+		for _, choice := range definition.Choices {
+			if strings.EqualFold(choice.Name, arg) || strings.EqualFold(fmt.Sprint(choice.Value), arg) {
+				return choice.Value, nil
+			}
+		}
+		names := make([]string, 0, len(definition.Choices))
+		for _, choice := range definition.Choices {
+			names = append(names, fmt.Sprint(choice.Value))
+		}
+		return nil, fmt.Errorf("%s must be one of %s", definition.Name, strings.Join(names, ", "))
+	}
+
+	switch definition.Type {
+	case discordgo.ApplicationCommandOptionInteger, discordgo.ApplicationCommandOptionNumber:
+		value, err := strconv.ParseFloat(arg, 64)
+		if err != nil || definition.Type == discordgo.ApplicationCommandOptionInteger && value != float64(int64(value)) {
+			return nil, fmt.Errorf("%s must be a number", definition.Name)
+		}
+		if definition.MinValue != nil && value < *definition.MinValue || definition.MaxValue != 0 && value > definition.MaxValue {
+			return nil, fmt.Errorf("%s is out of range", definition.Name)
+		}
+		return value, nil
+	case discordgo.ApplicationCommandOptionBoolean:
+		switch strings.ToLower(arg) {
+		case "true", "yes", "on":
+			return true, nil
+		case "false", "no", "off":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%s must be on or off", definition.Name)
+	case discordgo.ApplicationCommandOptionChannel:
+		channelID := strings.TrimSuffix(strings.TrimPrefix(arg, "<#"), ">")
+		if _, err := strconv.ParseUint(channelID, 10, 64); err != nil {
+			return nil, fmt.Errorf("%s must be a channel, like #general", definition.Name)
+		}
+		return channelID, nil
+	default:
+		return arg, nil
+	}
+}
+
+// messageResponder stands in for Discord's interaction endpoints while a message command is handled.
+// The handler's responses are posted as replies to the command's message, or sent to its author in a
+// DM when they are private; every other call goes to Discord as usual.
+type messageResponder struct {
+	base        *discordgo.Session
+	message     *discordgo.Message
+	interaction *discordgo.Interaction // set once the message was turned into an interaction
+	session     *discordgo.Session     // the bot's session, with its REST calls sent through the responder
+	next        http.RoundTripper
+	logger      *utils.Logger
+	mutex       sync.Mutex
+	private     bool               // the response was deferred privately
+	original    *discordgo.Message // the message standing for the interaction's response
+}
+
+// newMessageResponder creates a responder for a message command, sharing the bot session's token, rate
+// limits and state
+func newMessageResponder(base *discordgo.Session, message *discordgo.Message, logger *utils.Logger) *messageResponder {
+	responder := &messageResponder{base: base, message: message, logger: logger}
+	responder.session = services.SessionWithTransport(base, func(next http.RoundTripper) http.RoundTripper {
+		responder.next = next
+		return responder
+	})
+	return responder
+}
+
+// reply answers the command's message with a text reply
+func (responder *messageResponder) reply(content string) {
+	if _, err := responder.send(&discordgo.Message{Content: content}, nil, false); err != nil {
+		responder.logger.Error(fmt.Sprintf("Failed to reply to message %s: %v", responder.message.ID, err))
+	}
+}
+
+// send posts a response as a reply to the command's message, or as a DM to its author when it is private
+func (responder *messageResponder) send(response *discordgo.Message, files []*discordgo.File, private bool) (*discordgo.Message, error) {
+	data := &discordgo.MessageSend{
+		Content:         response.Content,
+		Embeds:          response.Embeds,
+		Components:      response.Components,
+		Files:           files,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}
+	channelID := responder.message.ChannelID
+	if private && responder.message.GuildID != "" {
+		channel, err := responder.base.UserChannelCreate(responder.message.Author.ID)
+		if err != nil {
+			return nil, err
+		}
+		channelID = channel.ID
+	} else {
+		data.Reference = responder.message.Reference()
+	}
+	return responder.base.ChannelMessageSendComplex(channelID, data)
+}
+
+// RoundTrip handles the requests for the interaction's response and follow-ups, and sends every other
+// request to Discord
+func (responder *messageResponder) RoundTrip(req *http.Request) (*http.Response, error) {
+	interaction := responder.interaction
+	if interaction == nil {
+		return responder.next.RoundTrip(req)
+	}
+	url := *req.URL
+	url.RawQuery = ""
+	endpoint := url.String()
+	switch {
+	case endpoint == discordgo.EndpointInteractionResponse(interaction.ID, interaction.Token):
+		return responder.respond(req)
+	case endpoint == discordgo.EndpointInteractionResponseActions(interaction.AppID, interaction.Token):
+		return responder.handleOriginal(req)
+	case endpoint == discordgo.EndpointFollowupMessage(interaction.AppID, interaction.Token) && req.Method == http.MethodPost:
+		body, files, err := readRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		response, err := decodeResponseMessage(body)
+		if err != nil {
+			return nil, err
+		}
+		sent, err := responder.send(response, files, response.Flags&discordgo.MessageFlagsEphemeral != 0)
+		if err != nil {
+			return nil, err
+		}
+		return localResponse(req, http.StatusOK, sent)
+	case strings.HasPrefix(endpoint, discordgo.EndpointFollowupMessage(interaction.AppID, interaction.Token)):
+		// The interaction's token is made up, so Discord would reject anything else done with it
+		return localResponse(req, http.StatusNotFound, nil)
+	}
+	return responder.next.RoundTrip(req)
+}
+
+// respond posts the handler's response to the interaction
+func (responder *messageResponder) respond(req *http.Request) (*http.Response, error) {
+	body, files, err := readRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Type discordgo.InteractionResponseType `json:"type"`
+		Data json.RawMessage                   `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	responder.mutex.Lock()
+	defer responder.mutex.Unlock()
+	switch response.Type {
+	case discordgo.InteractionResponseChannelMessageWithSource, discordgo.InteractionResponseUpdateMessage:
+		message, err := decodeResponseMessage(response.Data)
+		if err != nil {
+			return nil, err
+		}
+		if responder.original, err = responder.send(message, files, message.Flags&discordgo.MessageFlagsEphemeral != 0); err != nil {
+			return nil, err
+		}
+	case discordgo.InteractionResponseDeferredChannelMessageWithSource:
+		if message, err := decodeResponseMessage(response.Data); err == nil {
+			responder.private = message.Flags&discordgo.MessageFlagsEphemeral != 0
+		}
+		responder.base.ChannelTyping(responder.message.ChannelID)
+	case discordgo.InteractionResponseModal:
+		name := responder.interaction.ApplicationCommandData().Name
+		if _, err := responder.send(&discordgo.Message{Content: fmt.Sprintf("`/%s` opens a form, which only works as a slash command", name)}, nil, false); err != nil {
+			return nil, err
+		}
+	}
+	return localResponse(req, http.StatusNoContent, nil)
+}
+
+// handleOriginal fetches, edits or deletes the message standing for the interaction's response. Editing
+// a deferred response posts it.
+func (responder *messageResponder) handleOriginal(req *http.Request) (*http.Response, error) {
+	responder.mutex.Lock()
+	defer responder.mutex.Unlock()
+	switch req.Method {
+	case http.MethodGet:
+		if responder.original == nil {
+			return localResponse(req, http.StatusNotFound, nil)
+		}
+		return localResponse(req, http.StatusOK, responder.original)
+	case http.MethodDelete:
+		if responder.original != nil {
+			if err := responder.base.ChannelMessageDelete(responder.original.ChannelID, responder.original.ID); err != nil {
+				return nil, err
+			}
+			responder.original = nil
+		}
+		return localResponse(req, http.StatusNoContent, nil)
+	case http.MethodPatch:
+	default:
+		return localResponse(req, http.StatusMethodNotAllowed, nil)
+	}
+
+	body, files, err := readRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	response, err := decodeResponseMessage(body)
+	if err != nil {
+		return nil, err
+	}
+	if responder.original == nil {
+		if responder.original, err = responder.send(response, files, responder.private); err != nil {
+			return nil, err
+		}
+		return localResponse(req, http.StatusOK, responder.original)
+	}
+
+	// Fields left out of the edit keep their value
+	var fields map[string]json.RawMessage
+	json.Unmarshal(body, &fields)
+	edit := discordgo.NewMessageEdit(responder.original.ChannelID, responder.original.ID)
+	edit.Embeds, edit.Components, edit.Files = responder.original.Embeds, responder.original.Components, files
+	if _, exists := fields["content"]; exists {
+		edit.Content = &response.Content
+	}
+	if _, exists := fields["embeds"]; exists {
+		edit.Embeds = response.Embeds
+	}
+	if _, exists := fields["components"]; exists {
+		edit.Components = response.Components
+	}
+	if responder.original, err = responder.base.ChannelMessageEditComplex(edit); err != nil {
+		return nil, err
+	}
+	return localResponse(req, http.StatusOK, responder.original)
+}
+
+// decodeResponseMessage decodes the message of an interaction response, edit or follow-up. Messages
+// decode the components the response data types cannot.
+func decodeResponseMessage(data []byte) (*discordgo.Message, error) {
+	message := &discordgo.Message{}
+	if len(data) == 0 || string(data) == "null" {
+		return message, nil
+	}
+	if err := json.Unmarshal(data, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// readRequest returns the JSON payload of a REST request, and the files of a multipart request
+func readRequest(req *http.Request) ([]byte, []*discordgo.File, error) {
+	defer req.Body.Close()
+	if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType != "multipart/form-data" {
+		body, err := io.ReadAll(req.Body)
+		return body, nil, err
+	}
+	if err := req.ParseMultipartForm(maxPrefixUploadMemory); err != nil {
+		return nil, nil, err
+	}
+	form := req.MultipartForm
+	fields := make([]string, 0, len(form.File))
+	for field := range form.File {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	var files []*discordgo.File
+	for _, field := range fields {
+		for _, header := range form.File[field] {
+			file, err := header.Open()
+			if err != nil {
+				return nil, nil, err
+			}
+			files = append(files, &discordgo.File{Name: header.Filename, ContentType: header.Header.Get("Content-Type"), Reader: file})
+		}
+	}
+	var payload []byte
+	if values := form.Value["payload_json"]; len(values) > 0 {
+		payload = []byte(values[0])
+	}
+	return payload, files, nil
+}
+
+// localResponse answers a request in place of Discord, with body encoded as JSON unless it is nil
+func localResponse(req *http.Request, status int, body interface{}) (*http.Response, error) {
+	response := &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		Request:    req,
+	}
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		response.Header.Set("Content-Type", "application/json")
+		response.Body = io.NopCloser(bytes.NewReader(data))
+		response.ContentLength = int64(len(data))
+	}
+	return response, nil
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// handleChannelFeedPing handles the channel_feed_ping command, mentioning a role in the channel's alerts
+// of the given events, or nobody when no role is given
+func (h *CommandHandler) handleChannelFeedPing(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, channelID, roleID, events string) {
+	if roleID != "" && roleID == interaction.GuildID {
+		h.respondToInteraction(session, interaction, "Alerts cannot mention @everyone. Choose a role members can opt in to")
+		return
+	}
+	var pingEvents []string
+	if roleID != "" {
+		var err error
+		if pingEvents, err = services.ParsePingEvents(events); err != nil {
+			h.respondToInteraction(session, interaction, fmt.Sprintf("Choose events from %s, separated by commas", strings.Join(models.ChannelPingEvents, ", ")))
+			return
+		}
+	}
+
+	err := h.subscriptionService.SetChannelPing(ctx, channelID, interaction.GuildID, roleID, pingEvents, interactionUserID(interaction))
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to update channel settings", fmt.Sprintf("Failed to set the ping role of channel %s: %v", channelID, err))
+		return
+	}
+
+	if roleID == "" {
+		h.respondToInteraction(session, interaction, "Alerts in this channel will no longer mention a role")
+		return
+	}
+	h.respondToInteraction(session, interaction, fmt.Sprintf("Alerts of %s in this channel will mention <@&%s>. Mentioning a role needs it to be mentionable, or the bot to have the Mention Everyone permission", strings.Join(pingEvents, ", "), roleID))
+}
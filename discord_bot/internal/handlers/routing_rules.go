@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// minRoutingRulePosition is the lowest position of a routing rule, the first one evaluated
+var minRoutingRulePosition = 1.0
+
+// routingRulePositionOption is a required option naming a routing rule by its position
+func routingRulePositionOption(name, description string) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionInteger,
+		Name:        name,
+		Description: description,
+		Required:    true,
+		MinValue:    &minRoutingRulePosition,
+		MaxValue:    services.MaxRoutingRulesPerGuild,
+	}
+}
+
+// handleRoutingRules handles the routing_rules command by running its subcommand
+func (h *CommandHandler) handleRoutingRules(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string, subcommand *discordgo.ApplicationCommandInteractionDataOption) {
+	position := 0
+	if option := findOption(subcommand.Options, "position"); option != nil {
+		position = int(option.IntValue())
+	}
+
+	switch subcommand.Name {
+	case "add":
+		h.handleRoutingRuleAdd(ctx, session, interaction, userID, routingRuleFromOptions(interaction, subcommand.Options, position))
+	case "remove":
+		h.handleRoutingRuleRemove(ctx, session, interaction, userID, position)
+	case "move":
+		newPosition := 0
+		if option := findOption(subcommand.Options, "new_position"); option != nil {
+			newPosition = int(option.IntValue())
+		}
+		h.handleRoutingRuleMove(ctx, session, interaction, userID, position, newPosition)
+	case "list":
+		h.handleRoutingRuleList(ctx, session, interaction)
+	default:
+		h.respondToInteraction(session, interaction, "Unknown command")
+	}
+}
+
+// routingRuleFromOptions builds the rule described by the options of the add subcommand. The rule
+// applies to the channel the command ran in unless its scope is the whole server.
+func routingRuleFromOptions(interaction *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption, position int) *models.RoutingRule {
+	rule := &models.RoutingRule{GuildID: interaction.GuildID, ChannelID: interaction.ChannelID, Position: position}
+	if option := findOption(options, "action"); option != nil {
+		rule.Action = option.StringValue()
+	}
+	if option := findOption(options, "scope"); option != nil && option.StringValue() == "server" {
+		rule.ChannelID = ""
+	}
+	if option := findOption(options, "event_types"); option != nil {
+		rule.EventTypes = parseCategories(option.StringValue())
+	}
+	if option := findOption(options, "categories"); option != nil {
+		rule.Categories = parseCategories(option.StringValue())
+	}
+	if option := findOption(options, "creators"); option != nil {
+		rule.Creators = parseCategories(option.StringValue())
+	}
+	if option := findOption(options, "keywords"); option != nil {
+		rule.Keywords = parseCategories(option.StringValue())
+	}
+	if option := findOption(options, "min_volume"); option != nil {
+		rule.MinVolume = option.FloatValue()
+	}
+	if option := findOption(options, "max_volume"); option != nil {
+		rule.MaxVolume = option.FloatValue()
+	}
+	if option := findOption(options, "role"); option != nil {
+		rule.RoleID = option.RoleValue(nil, "").ID
+	}
+	if option := findOption(options, "target"); option != nil {
+		rule.TargetChannelID = option.ChannelValue(nil).ID
+	}
+	return rule
+}
+
+// handleRoutingRuleAdd handles the routing_rules add subcommand
+func (h *CommandHandler) handleRoutingRuleAdd(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string, rule *models.RoutingRule) {
+	added, err := h.subscriptionService.AddRoutingRule(ctx, rule, userID)
+	if errors.Is(err, services.ErrInvalidRoutingRule) {
+		h.respondToInteraction(session, interaction, fmt.Sprintf("Invalid rule: %s", strings.TrimPrefix(err.Error(), services.ErrInvalidRoutingRule.Error()+": ")))
+		return
+	}
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to add the routing rule", fmt.Sprintf("Failed to add a routing rule in guild %s: %v", interaction.GuildID, err))
+		return
+	}
+	h.respondToInteraction(session, interaction, fmt.Sprintf("Added rule %s\nSee every rule in order with `/routing_rules list`", describeRoutingRule(added)))
+}
+
+// handleRoutingRuleRemove handles the routing_rules remove subcommand
+func (h *CommandHandler) handleRoutingRuleRemove(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string, position int) {
+	rule, ok := h.routingRuleAt(ctx, session, interaction, position)
+	if !ok {
+		return
+	}
+	if _, err := h.subscriptionService.RemoveRoutingRule(ctx, interaction.GuildID, rule.ID, userID); err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to remove the routing rule", fmt.Sprintf("Failed to remove routing rule %s of guild %s: %v", rule.ID, interaction.GuildID, err))
+		return
+	}
+	h.respondToInteraction(session, interaction, fmt.Sprintf("Removed rule %s", describeRoutingRule(rule)))
+}
+
+// handleRoutingRuleMove handles the routing_rules move subcommand
+func (h *CommandHandler) handleRoutingRuleMove(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string, position, newPosition int) {
+	rule, ok := h.routingRuleAt(ctx, session, interaction, position)
+	if !ok {
+		return
+	}
+	moved, err := h.subscriptionService.MoveRoutingRule(ctx, interaction.GuildID, rule.ID, newPosition, userID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to move the routing rule", fmt.Sprintf("Failed to move routing rule %s of guild %s: %v", rule.ID, interaction.GuildID, err))
+		return
+	}
+	h.respondToInteraction(session, interaction, fmt.Sprintf("Moved rule %s", describeRoutingRule(moved)))
+}
+
+// handleRoutingRuleList handles the routing_rules list subcommand
+func (h *CommandHandler) handleRoutingRuleList(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	rules, err := h.subscriptionService.GetRoutingRules(ctx, interaction.GuildID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve routing rules", fmt.Sprintf("Failed to get routing rules of guild %s: %v", interaction.GuildID, err))
+		return
+	}
+	if len(rules) == 0 {
+		h.respondToInteraction(session, interaction, "This server has no routing rules, so each channel's own settings decide which alerts it posts. Add one with `/routing_rules add`.")
+		return
+	}
+
+	var response strings.Builder
+	response.WriteString("**Routing Rules**\n\n")
+	for _, rule := range rules {
+		response.WriteString(describeRoutingRule(rule) + "\n")
+	}
+	response.WriteString("\nFor each alert and channel, the first rule that applies decides; when none does, the channel's own settings do.")
+	h.respondToInteraction(session, interaction, response.String())
+}
+
+// routingRuleAt returns the guild's rule at a position, replying and returning false when there is none
+func (h *CommandHandler) routingRuleAt(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, position int) (*models.RoutingRule, bool) {
+	rules, err := h.subscriptionService.GetRoutingRules(ctx, interaction.GuildID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve routing rules", fmt.Sprintf("Failed to get routing rules of guild %s: %v", interaction.GuildID, err))
+		return nil, false
+	}
+	for _, rule := range rules {
+		if rule.Position == position {
+			return rule, true
+		}
+	}
+	h.respondToInteraction(session, interaction, fmt.Sprintf("This server has no rule %d. See its rules with `/routing_rules list`", position))
+	return nil, false
+}
+
+// describeRoutingRule describes a rule on one line: its position, action, channels and conditions
+func describeRoutingRule(rule *models.RoutingRule) string {
+	action := map[string]string{
+		models.RuleActionSend:     "**send**",
+		models.RuleActionSuppress: "**suppress**",
+		models.RuleActionPing:     fmt.Sprintf("**ping** <@&%s>", rule.RoleID),
+		models.RuleActionRoute:    fmt.Sprintf("**route** to <#%s>", rule.TargetChannelID),
+	}[rule.Action]
+	scope := "every channel"
+	if rule.ChannelID != "" {
+		scope = fmt.Sprintf("<#%s>", rule.ChannelID)
+	}
+
+	var conditions []string
+	if len(rule.EventTypes) > 0 {
+		conditions = append(conditions, "events "+strings.Join(rule.EventTypes, ", "))
+	}
+	if len(rule.Categories) > 0 {
+		conditions = append(conditions, "categories "+strings.Join(rule.Categories, ", "))
+	}
+	if len(rule.Creators) > 0 {
+		conditions = append(conditions, "creators "+strings.Join(rule.Creators, ", "))
+	}
+	if len(rule.Keywords) > 0 {
+		conditions = append(conditions, "keywords "+strings.Join(rule.Keywords, ", "))
+	}
+	if rule.MinVolume > 0 {
+		conditions = append(conditions, fmt.Sprintf("volume at least $%.2f", rule.MinVolume))
+	}
+	if rule.MaxVolume > 0 {
+		conditions = append(conditions, fmt.Sprintf("volume at most $%.2f", rule.MaxVolume))
+	}
+	matches := "every event"
+	if len(conditions) > 0 {
+		matches = strings.Join(conditions, "; ")
+	}
+	return fmt.Sprintf("%d. %s in %s: %s", rule.Position, action, scope, matches)
+}
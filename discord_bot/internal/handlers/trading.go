@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+	"coral-bot/discord_bot/internal/tracing"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Custom ID prefixes of the buttons under a trade summary, followed by the trade's ID
+const (
+	tradeConfirmPrefix = "trade_confirm"
+	tradeCancelPrefix  = "trade_cancel"
+)
+
+// tradeAmountField is the custom ID of the amount field of the buy modal
+const tradeAmountField = "amount"
+
+// maxModalTitle is the longest modal title Discord accepts
+const maxModalTitle = 45
+
+// tradingDisabledMessage answers buy buttons when the bot does not trade
+const tradingDisabledMessage = "Trading is not enabled on this bot"
+
+// SetTradingService sets the service placing the orders of the buy buttons under markets
+func (h *CommandHandler) SetTradingService(trading services.TradingService) {
+	h.trading = trading
+}
+
+// marketButtons returns the buttons to show under a market in a guild: buy buttons when the bot trades
+// and the guild turned trading on, and bet buttons when the guild plays the prediction game. It returns
+// nil outside guilds and for markets that are not open.
+func (h *CommandHandler) marketButtons(ctx context.Context, guildID string, market *models.Market) []discordgo.MessageComponent {
+	if h.trading == nil && h.game == nil || !services.Tradable(market, time.Now()) {
+		return nil
+	}
+	guild := h.guildConfig(ctx, guildID)
+	if guild == nil {
+		return nil
+	}
+	var rows []discordgo.MessageComponent
+	if h.trading != nil && guild.TradingEnabled {
+		rows = append(rows, services.TradeButtons(market)...)
+	}
+	if h.game != nil && guild.GameEnabled {
+		rows = append(rows, services.BetButtons(market)...)
+	}
+	return rows
+}
+
+// guildConfig returns a guild's config, or nil outside guilds and when the guild has none or it could
+// not be read
+func (h *CommandHandler) guildConfig(ctx context.Context, guildID string) *models.GuildConfig {
+	if guildID == "" {
+		return nil
+	}
+	guild, err := h.subscriptionService.GetGuildConfig(ctx, guildID)
+	if err != nil {
+		h.logger.Warning(fmt.Sprintf("Failed to get guild config for %s: %v", guildID, err))
+		return nil
+	}
+	return guild
+}
+
+// tradeComponentName returns the action a trade button runs, or "" for other components
+func tradeComponentName(customID string) string {
+	if _, _, ok := services.ParseTradeButton(customID); ok {
+		return services.TradeButtonPrefix
+	}
+	for _, prefix := range []string{tradeConfirmPrefix, tradeCancelPrefix} {
+		if strings.HasPrefix(customID, prefix+"|") {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// handleTradeComponent handles a click on a buy button or on the buttons under a trade summary the
+// way handleComponent handles suggested markets
+func (h *CommandHandler) handleTradeComponent(session *discordgo.Session, interaction *discordgo.InteractionCreate, customID, action string) {
+	userID := interactionUserID(interaction)
+	if userID == "" {
+		h.logger.Warning(fmt.Sprintf("Ignoring component %s without a user", customID))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), interactionTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "discord.component."+action, attribute.String("discord.user_id", userID))
+	defer span.End()
+	defer h.recoverInteraction(ctx, span, session, interaction, action, userID)
+
+	h.logger.Info(fmt.Sprintf("Handling %s component %s from user: %s", action, customID, userID))
+	h.analyticsService.RecordCommand(ctx, action, userID)
+
+	_, tradeID, _ := strings.Cut(customID, "|")
+	switch action {
+	case services.TradeButtonPrefix:
+		marketID, outcome, _ := services.ParseTradeButton(customID)
+		h.openTradeModal(ctx, session, interaction, userID, marketID, outcome)
+	case tradeConfirmPrefix:
+		h.handleTradeConfirm(ctx, session, interaction, userID, tradeID)
+	case tradeCancelPrefix:
+		if h.trading != nil && h.trading.CancelTrade(userID, tradeID) {
+			h.updateTradeMessage(session, interaction, "Order cancelled, nothing was bought", nil)
+			return
+		}
+		h.updateTradeMessage(session, interaction, "This order expired or was already placed", nil)
+	}
+}
+
+// tradableMarket fetches a market a user is buying and the outcome at index, replying privately and
+// returning nil when trading is off for the bot or guild, the user has no linked account, or the market
+// is not open
+func (h *CommandHandler) tradableMarket(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, marketID string, index int) (*models.Market, string) {
+	if h.trading == nil || h.accounts == nil {
+		h.respondPrivately(session, interaction, tradingDisabledMessage, nil)
+		return nil, ""
+	}
+	if guild := h.guildConfig(ctx, interaction.GuildID); guild == nil || !guild.TradingEnabled {
+		h.respondPrivately(session, interaction, "Trading is not enabled in this server", nil)
+		return nil, ""
+	}
+	if _, err := h.accounts.LinkedAccount(ctx, userID); err != nil {
+		if !errors.Is(err, services.ErrAccountNotLinked) {
+			h.respondFailure(ctx, session, interaction, "Failed to get your Coral account", fmt.Sprintf("Failed to get the Coral account of user %s: %v", userID, err))
+			return nil, ""
+		}
+		h.respondPrivately(session, interaction, "Link your Coral account with `/link_account` to trade from Discord", nil)
+		return nil, ""
+	}
+	market, err := h.marketService.FetchMarket(ctx, marketID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve market information", fmt.Sprintf("Failed to fetch market %s to trade: %v", marketID, err))
+		return nil, ""
+	}
+	if !services.Tradable(market, time.Now()) || index >= len(market.Outcomes) {
+		h.respondPrivately(session, interaction, "This market is no longer open for trading", nil)
+		return nil, ""
+	}
+	return market, market.Outcomes[index]
+}
+
+// openTradeModal asks the user how much of an outcome to buy
+func (h *CommandHandler) openTradeModal(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, marketID string, index int) {
+	market, outcome := h.tradableMarket(ctx, session, interaction, userID, marketID, index)
+	if market == nil {
+		return
+	}
+	h.respondWithModal(session, interaction, interaction.MessageComponentData().CustomID, truncateLabel("Buy "+outcome, maxModalTitle),
+		discordgo.TextInput{
+			CustomID:    tradeAmountField,
+			Label:       "Amount in dollars",
+			Style:       discordgo.TextInputShort,
+			Placeholder: "20",
+			Required:    true,
+			MaxLength:   12,
+		},
+	)
+}
+
+// handleTradeSubmit prepares the buy entered in the buy modal and shows the user a summary to confirm
+func (h *CommandHandler) handleTradeSubmit(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, marketID string, index int, amountValue string) {
+	amount, err := parseAmount(amountValue)
+	if err != nil || amount <= 0 {
+		h.respondPrivately(session, interaction, "The amount must be a number of dollars more than 0, like 20", nil)
+		return
+	}
+	market, outcome := h.tradableMarket(ctx, session, interaction, userID, marketID, index)
+	if market == nil {
+		return
+	}
+
+	trade, err := h.trading.PrepareTrade(ctx, userID, market, outcome, amount)
+	if errors.Is(err, services.ErrInvalidChannelSettings) {
+		h.respondPrivately(session, interaction, strings.TrimPrefix(err.Error(), services.ErrInvalidChannelSettings.Error()+": "), nil)
+		return
+	}
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to prepare your order", fmt.Sprintf("Failed to prepare a trade in market %s for user %s: %v", marketID, userID, err))
+		return
+	}
+
+	err = session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    describeTrade(trade),
+			Components: tradeConfirmButtons(trade.ID),
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to respond to interaction: %v", err))
+	}
+}
+
+// describeTrade summarizes a trade waiting to be confirmed
+func describeTrade(trade *services.PendingTrade) string {
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "🛒 Buy **$%.2f** of **%s** in **%s**", trade.Amount, services.EscapeMarkdown(trade.Outcome), services.EscapeMarkdown(trade.Market.Title))
+	if trade.Price > 0 {
+		fmt.Fprintf(&summary, " at %.1f%%, about %.2f shares", trade.Price*100, trade.Amount/trade.Price)
+	}
+	fmt.Fprintf(&summary, "?\nThe price is set when the order is placed. Confirm within %d minutes.", int(services.TradeTTL.Minutes()))
+	return summary.String()
+}
+
+// tradeConfirmButtons returns the confirm and cancel buttons of a trade summary
+func tradeConfirmButtons(tradeID string) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		discordgo.Button{Label: "Confirm", Style: discordgo.SuccessButton, CustomID: tradeConfirmPrefix + "|" + tradeID},
+		discordgo.Button{Label: "Cancel", Style: discordgo.SecondaryButton, CustomID: tradeCancelPrefix + "|" + tradeID},
+	}}}
+}
+
+// handleTradeConfirm places a confirmed trade and replaces its summary with the outcome. When the
+// backend could not be reached the buttons stay, so the user can confirm again.
+func (h *CommandHandler) handleTradeConfirm(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, tradeID string) {
+	if h.trading == nil {
+		h.updateTradeMessage(session, interaction, tradingDisabledMessage, nil)
+		return
+	}
+	trade, err := h.trading.ConfirmTrade(ctx, userID, tradeID)
+	switch {
+	case errors.Is(err, services.ErrTradeExpired):
+		h.updateTradeMessage(session, interaction, "This order expired, nothing was bought. Click a buy button to start again", nil)
+	case errors.Is(err, services.ErrOrderRejected):
+		h.updateTradeMessage(session, interaction, "❌ Coral Markets did not place the order: "+strings.TrimPrefix(err.Error(), services.ErrOrderRejected.Error()+": "), nil)
+	case err != nil:
+		h.logger.Error(fmt.Sprintf("Failed to place trade %s for user %s: %v", tradeID, userID, err))
+		h.updateTradeMessage(session, interaction, "⚠️ Coral Markets could not be reached to place the order. Confirm again to retry, it will not be bought twice", tradeConfirmButtons(tradeID))
+	case trade.Order.Status == models.OrderPending:
+		h.updateTradeMessage(session, interaction, fmt.Sprintf("⏳ Your order for **$%.2f** of **%s** was placed and is being filled. Check it with `/my_positions`", trade.Amount, services.EscapeMarkdown(trade.Outcome)), nil)
+	default:
+		message := fmt.Sprintf("✅ Bought **%s** for **$%.2f**", services.EscapeMarkdown(trade.Outcome), trade.Order.Amount)
+		if trade.Order.Shares > 0 {
+			message = fmt.Sprintf("✅ Bought %.2f shares of **%s** for **$%.2f** at %.1f%%", trade.Order.Shares, services.EscapeMarkdown(trade.Outcome), trade.Order.Amount, trade.Order.Price*100)
+		}
+		h.updateTradeMessage(session, interaction, message+" in **"+services.EscapeMarkdown(trade.Market.Title)+"**", nil)
+	}
+}
+
+// updateTradeMessage replaces the trade summary a button was clicked on, removing its buttons unless
+// components are given
+func (h *CommandHandler) updateTradeMessage(session *discordgo.Session, interaction *discordgo.InteractionCreate, content string, components []discordgo.MessageComponent) {
+	if components == nil {
+		components = []discordgo.MessageComponent{}
+	}
+	err := session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: components,
+		},
+	})
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to respond to interaction: %v", err))
+	}
+}
+
+// handleTradingButtons handles the trading_buttons command, turning the buy buttons under markets on or
+// off for the guild
+func (h *CommandHandler) handleTradingButtons(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string, enabled bool) {
+	if h.trading == nil {
+		h.respondToInteraction(session, interaction, tradingDisabledMessage)
+		return
+	}
+	config, err := h.subscriptionService.GetGuildConfig(ctx, interaction.GuildID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update server settings", fmt.Sprintf("Failed to get guild config for %s: %v", interaction.GuildID, err))
+		return
+	}
+	if config == nil {
+		config = &models.GuildConfig{GuildID: interaction.GuildID}
+	}
+	config.TradingEnabled = enabled
+	config.ConfiguredBy = userID
+	if err := h.subscriptionService.UpdateGuildConfig(ctx, config); err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update server settings", fmt.Sprintf("Failed to update guild config for %s: %v", interaction.GuildID, err))
+		return
+	}
+
+	if enabled {
+		h.respondToInteraction(session, interaction, "💸 Buy buttons are on: members with a linked Coral account can buy outcomes from market alerts and `/market` in this server")
+		return
+	}
+	h.respondToInteraction(session, interaction, "Buy buttons are off for this server")
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// watchlistNameOption is the name option shared by the watchlist subcommands
+func watchlistNameOption() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "name",
+		Description: "The watchlist's name, e.g. crypto",
+		Required:    true,
+		MaxLength:   services.MaxWatchlistNameLength,
+	}
+}
+
+// watchlistMarketOption is the market option of the watchlist add and remove subcommands
+func watchlistMarketOption() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "market_id",
+		Description: "The ID of the market",
+		Required:    true,
+	}
+}
+
+// handleWatchlist handles the watchlist command by running its subcommand
+func (h *CommandHandler) handleWatchlist(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string, subcommand *discordgo.ApplicationCommandInteractionDataOption) {
+	name := ""
+	if option := findOption(subcommand.Options, "name"); option != nil {
+		name = option.StringValue()
+	}
+	marketID := ""
+	if option := findOption(subcommand.Options, "market_id"); option != nil {
+		marketID = strings.TrimSpace(option.StringValue())
+	}
+
+	switch subcommand.Name {
+	case "create":
+		h.handleWatchlistCreate(ctx, session, interaction, userID, name)
+	case "add":
+		h.handleWatchlistAdd(ctx, session, interaction, userID, name, marketID)
+	case "remove":
+		h.handleWatchlistRemove(ctx, session, interaction, userID, name, marketID)
+	case "show":
+		h.handleWatchlistShow(ctx, session, interaction, userID, name)
+	case "list":
+		h.handleWatchlistList(ctx, session, interaction, userID)
+	case "settings":
+		var settings services.WatchlistSettings
+		if option := findOption(subcommand.Options, "notify"); option != nil {
+			notify := option.StringValue()
+			settings.Notify = &notify
+		}
+		if option := findOption(subcommand.Options, "min_buy"); option != nil {
+			amount := option.FloatValue()
+			settings.MinBuyAmount = &amount
+		}
+		h.handleWatchlistSettings(ctx, session, interaction, userID, name, settings)
+	case "delete":
+		h.handleWatchlistDelete(ctx, session, interaction, userID, name)
+	case "share":
+		h.handleWatchlistShare(ctx, session, interaction, userID, name)
+	case "import":
+		code := ""
+		if option := findOption(subcommand.Options, "code"); option != nil {
+			code = option.StringValue()
+		}
+		h.handleWatchlistImport(ctx, session, interaction, userID, code, name)
+	default:
+		h.respondToInteraction(session, interaction, "Unknown command")
+	}
+}
+
+// handleWatchlistCreate handles the watchlist create subcommand
+func (h *CommandHandler) handleWatchlistCreate(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, name string) {
+	watchlist, err := h.subscriptionService.CreateWatchlist(ctx, userID, name)
+	if h.respondWatchlistError(session, interaction, err, name) {
+		return
+	}
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to create watchlist", fmt.Sprintf("Failed to create watchlist %q for user %s: %v", name, userID, err))
+		return
+	}
+
+	h.respondToInteraction(session, interaction, fmt.Sprintf("Created watchlist `%s`. Add markets with `/watchlist add %s <market_id>`", watchlist.Name, watchlist.Name))
+}
+
+// handleWatchlistAdd handles the watchlist add subcommand
+func (h *CommandHandler) handleWatchlistAdd(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, name, marketID string) {
+	watchlist, err := h.subscriptionService.AddToWatchlist(ctx, userID, name, marketID)
+	if h.respondWatchlistError(session, interaction, err, name) {
+		return
+	}
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to add market to watchlist", fmt.Sprintf("Failed to add market %s to watchlist %q of user %s: %v", marketID, name, userID, err))
+		return
+	}
+
+	h.respondToInteraction(session, interaction, fmt.Sprintf("Market `%s` is on watchlist `%s` (%d %s)", marketID, watchlist.Name, len(watchlist.MarketIDs), pluralize(len(watchlist.MarketIDs), "market")))
+}
+
+// handleWatchlistRemove handles the watchlist remove subcommand
+func (h *CommandHandler) handleWatchlistRemove(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, name, marketID string) {
+	removed, err := h.subscriptionService.RemoveFromWatchlist(ctx, userID, name, marketID)
+	if h.respondWatchlistError(session, interaction, err, name) {
+		return
+	}
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to remove market from watchlist", fmt.Sprintf("Failed to remove market %s from watchlist %q of user %s: %v", marketID, name, userID, err))
+		return
+	}
+
+	name = models.NormalizeWatchlistName(name)
+	if !removed {
+		h.respondToInteraction(session, interaction, fmt.Sprintf("Market `%s` is not on watchlist `%s`", marketID, name))
+		return
+	}
+	h.respondToInteraction(session, interaction, fmt.Sprintf("Removed market `%s` from watchlist `%s`", marketID, name))
+}
+
+// handleWatchlistShow handles the watchlist show subcommand
+func (h *CommandHandler) handleWatchlistShow(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, name string) {
+	watchlist, err := h.subscriptionService.GetWatchlist(ctx, userID, name)
+	if h.respondWatchlistError(session, interaction, err, name) {
+		return
+	}
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve watchlist", fmt.Sprintf("Failed to get watchlist %q of user %s: %v", name, userID, err))
+		return
+	}
+
+	var response strings.Builder
+	response.WriteString(fmt.Sprintf("**Watchlist `%s`**\n\n", watchlist.Name))
+	if len(watchlist.MarketIDs) == 0 {
+		response.WriteString(fmt.Sprintf("No markets yet. Add one with `/watchlist add %s <market_id>`\n", watchlist.Name))
+	}
+	for _, marketID := range watchlist.MarketIDs {
+		response.WriteString(fmt.Sprintf("- `%s`\n", marketID))
+	}
+	response.WriteString("\n" + watchlistSettingsSummary(watchlist))
+	h.respondToInteraction(session, interaction, response.String())
+}
+
+// handleWatchlistList handles the watchlist list subcommand
+func (h *CommandHandler) handleWatchlistList(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID string) {
+	watchlists, err := h.subscriptionService.GetWatchlists(ctx, userID)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to retrieve watchlists", fmt.Sprintf("Failed to get watchlists of user %s: %v", userID, err))
+		return
+	}
+	if len(watchlists) == 0 {
+		h.respondToInteraction(session, interaction, "You have no watchlists. Create one with `/watchlist create <name>`")
+		return
+	}
+
+	var response strings.Builder
+	response.WriteString("**Your Watchlists:**\n\n")
+	for _, watchlist := range watchlists {
+		response.WriteString(fmt.Sprintf("- `%s` - %d %s, notify: %s\n", watchlist.Name, len(watchlist.MarketIDs), pluralize(len(watchlist.MarketIDs), "market"), watchlist.Notify))
+	}
+	h.respondToInteraction(session, interaction, response.String())
+}
+
+// handleWatchlistSettings handles the watchlist settings subcommand
+func (h *CommandHandler) handleWatchlistSettings(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, name string, settings services.WatchlistSettings) {
+	watchlist, err := h.subscriptionService.UpdateWatchlistSettings(ctx, userID, name, settings)
+	if h.respondWatchlistError(session, interaction, err, name) {
+		return
+	}
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to update watchlist settings", fmt.Sprintf("Failed to update settings of watchlist %q of user %s: %v", name, userID, err))
+		return
+	}
+
+	h.respondToInteraction(session, interaction, fmt.Sprintf("Updated watchlist `%s`\n%s", watchlist.Name, watchlistSettingsSummary(watchlist)))
+}
+
+// handleWatchlistDelete handles the watchlist delete subcommand
+func (h *CommandHandler) handleWatchlistDelete(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, name string) {
+	deleted, err := h.subscriptionService.DeleteWatchlist(ctx, userID, name)
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to delete watchlist", fmt.Sprintf("Failed to delete watchlist %q of user %s: %v", name, userID, err))
+		return
+	}
+
+	name = models.NormalizeWatchlistName(name)
+	if !deleted {
+		h.respondToInteraction(session, interaction, fmt.Sprintf("You have no watchlist named `%s`", name))
+		return
+	}
+	h.respondToInteraction(session, interaction, fmt.Sprintf("Deleted watchlist `%s`", name))
+}
+
+// handleWatchlistShare handles the watchlist share subcommand
+func (h *CommandHandler) handleWatchlistShare(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, name string) {
+	share, err := h.subscriptionService.ShareWatchlist(ctx, userID, name)
+	if h.respondWatchlistError(session, interaction, err, name) {
+		return
+	}
+	if err != nil {
+		h.respondFailure(ctx, session, interaction, "Failed to share watchlist", fmt.Sprintf("Failed to share watchlist %q of user %s: %v", name, userID, err))
+		return
+	}
+
+	code := services.FormatShareCode(share.Code)
+	response := fmt.Sprintf("Share code for watchlist `%s`: `%s`\nAnyone can copy its markets with `/watchlist import %s`. The code stops working if you delete the watchlist.", models.NormalizeWatchlistName(name), code, code)
+	h.respondToInteraction(session, interaction, response)
+}
+
+// handleWatchlistImport handles the watchlist import subcommand
+func (h *CommandHandler) handleWatchlistImport(ctx context.Context, session *discordgo.Session, interaction *discordgo.InteractionCreate, userID, code, name string) {
+	watchlist, err := h.subscriptionService.ImportWatchlist(ctx, userID, code, name)
+	if errors.Is(err, services.ErrShareCodeNotFound) {
+		h.respondToInteraction(session, interaction, fmt.Sprintf("No watchlist is shared under `%s`. Check the code, or ask for a new one if the watchlist was deleted.", strings.TrimSpace(code)))
+		return
+	}
+	if errors.Is(err, services.ErrWatchlistExists) {
+		h.respondToInteraction(session, interaction, "You already have a watchlist with that name. Pick another one with the `name` option.")
+		return
+	}
+	if h.respondWatchlistError(session, interaction, err, name) {
+		return
+	}
+	if err != nil {
+		h.respondChangeFailure(ctx, session, interaction, err, "Failed to import watchlist", fmt.Sprintf("Failed to import watchlist %q for user %s: %v", code, userID, err))
+		return
+	}
+
+	response := fmt.Sprintf("Imported watchlist `%s` with %d %s. Change what it notifies you about with `/watchlist settings %s`", watchlist.Name, len(watchlist.MarketIDs), pluralize(len(watchlist.MarketIDs), "market"), watchlist.Name)
+	h.respondToInteraction(session, interaction, response)
+}
+
+// respondWatchlistError explains a watchlist the user named wrongly or a setting they got wrong, and
+// reports whether it replied
+func (h *CommandHandler) respondWatchlistError(session *discordgo.Session, interaction *discordgo.InteractionCreate, err error, name string) bool {
+	name = models.NormalizeWatchlistName(name)
+	switch {
+	case errors.Is(err, services.ErrWatchlistNotFound):
+		h.respondToInteraction(session, interaction, fmt.Sprintf("You have no watchlist named `%s`. Create it with `/watchlist create %s`", name, name))
+	case errors.Is(err, services.ErrWatchlistExists):
+		h.respondToInteraction(session, interaction, fmt.Sprintf("You already have a watchlist named `%s`", name))
+	case errors.Is(err, services.ErrInvalidWatchlist):
+		h.respondToInteraction(session, interaction, fmt.Sprintf("Invalid watchlist: %s", strings.TrimPrefix(err.Error(), services.ErrInvalidWatchlist.Error()+": ")))
+	default:
+		return false
+	}
+	return true
+}
+
+// watchlistSettingsSummary describes which of a watchlist's events are sent
+func watchlistSettingsSummary(watchlist *models.Watchlist) string {
+	summary := map[string]string{
+		models.WatchlistNotifyAll:   "**Notifications:** all events",
+		models.WatchlistNotifyMajor: "**Notifications:** resolutions and trading starts only",
+		models.WatchlistNotifyOff:   "**Notifications:** off",
+	}[watchlist.Notify]
+	if watchlist.MinBuyAmount > 0 {
+		summary += fmt.Sprintf("\n**Minimum Buy:** $%.2f", watchlist.MinBuyAmount)
+	}
+	return summary
+}
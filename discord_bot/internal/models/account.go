@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Push notification modes of a linked Coral account
+const (
+	PushOff      = "off"
+	PushFallback = "fallback" // pushed when a DM cannot be delivered
+	PushAlways   = "always"   // pushed instead of DMed
+)
+
+// IsValidPushMode reports whether mode is a known push notification mode
+func IsValidPushMode(mode string) bool {
+	return mode == PushOff || mode == PushFallback || mode == PushAlways
+}
+
+// CoralAccount is the Coral account a user linked with link_account, whose devices get the user's
+// push notifications
+type CoralAccount struct {
+	AccountID string    `json:"account_id,omitempty"` // Coral account ID, empty until a link code is redeemed
+	Push      string    `json:"push,omitempty"`       // push notification mode, PushFallback once linked
+	LinkedAt  time.Time `json:"linked_at,omitempty"`
+
+	CodeHash     string    `json:"code_hash,omitempty"` // SHA-256 of the link code, the code itself is not stored
+	CodeIssuedAt time.Time `json:"code_issued_at,omitempty"`
+}
+
+// Linked reports whether the user has a linked Coral account
+func (account *CoralAccount) Linked() bool {
+	return account != nil && account.AccountID != ""
+}
+
+// PushMode returns how the user's notifications are pushed to their Coral account, PushOff when no
+// account is linked
+func (subscription *Subscription) PushMode() string {
+	if !subscription.Account.Linked() || subscription.Account.Push == "" {
+		return PushOff
+	}
+	return subscription.Account.Push
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Analytics event kinds
+const (
+	AnalyticsCommand          = "command"
+	AnalyticsCommandFailure   = "command_failure"
+	AnalyticsSubscribe        = "subscribe"
+	AnalyticsUnsubscribe      = "unsubscribe"
+	AnalyticsNotificationSent = "notification_sent"
+	AnalyticsDeliveryFailure  = "delivery_failure"
+)
+
+// AnalyticsEvent represents a single recorded usage or delivery event
+type AnalyticsEvent struct {
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`    // command name, subscription target type or market event type
+	Subject   string    `json:"subject"` // user or channel the event relates to
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AnalyticsSummary aggregates analytics events over a time window
+type AnalyticsSummary struct {
+	From              time.Time      `json:"from"`
+	To                time.Time      `json:"to"`
+	Commands          map[string]int `json:"commands"`
+	CommandFailures   map[string]int `json:"command_failures"`
+	Subscriptions     map[string]int `json:"subscriptions"`
+	Unsubscriptions   map[string]int `json:"unsubscriptions"`
+	NotificationsSent map[string]int `json:"notifications_sent"`
+	DeliveryFailures  map[string]int `json:"delivery_failures"`
+	ActiveUsers       int            `json:"active_users"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// API key scopes, each granting access to a group of HTTP endpoints
+const (
+	ScopeEventsWrite        = "events:write"        // market events, legacy webhooks and direct notifications
+	ScopeSubscriptionsRead  = "subscriptions:read"  // a user's subscriptions
+	ScopeSubscriptionsWrite = "subscriptions:write" // subscribe and unsubscribe users
+	ScopeChannelsRead       = "channels:read"       // channel settings
+	ScopeChannelsWrite      = "channels:write"      // channel feed settings and market subscriptions
+	ScopeWebhooksRead       = "webhooks:read"       // webhook registrations
+	ScopeWebhooksWrite      = "webhooks:write"      // register and unregister webhooks
+	ScopeAdminRead          = "admin:read"          // analytics, leaderboard, audit log, subscription dumps and user data exports
+	ScopeAdminWrite         = "admin:write"         // broadcasts, test events and user data deletion
+	ScopeKeysManage         = "keys:manage"         // create, list and revoke API keys
+)
+
+// Scopes lists every API key scope
+var Scopes = []string{
+	ScopeEventsWrite,
+	ScopeSubscriptionsRead,
+	ScopeSubscriptionsWrite,
+	ScopeChannelsRead,
+	ScopeChannelsWrite,
+	ScopeWebhooksRead,
+	ScopeWebhooksWrite,
+	ScopeAdminRead,
+	ScopeAdminWrite,
+	ScopeKeysManage,
+}
+
+// IsValidScope reports whether a scope is one of Scopes
+func IsValidScope(scope string) bool {
+	for _, known := range Scopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey is a credential for the HTTP API limited to a set of scopes. Only a hash of the
+// secret is stored; the secret itself is returned once, when the key is created.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"` // first characters of the secret, to recognise a key in listings
+	Hash      string     `json:"-"`      // hex SHA-256 of the secret
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"` // set when revoked, the record is kept for auditing
+
+	// Rotation: the key issued to replace this one works alongside it until this one expires or is revoked
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`   // the key stops working then, set when it is retired with a grace period
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"` // last authenticated request, to the minute
+	RotatedFrom string     `json:"rotated_from,omitempty"` // the key this one replaces
+	ReplacedBy  string     `json:"replaced_by,omitempty"`  // the key issued to replace this one
+}
+
+// Active reports whether the key authenticates requests at a time
+func (key *APIKey) Active(now time.Time) bool {
+	return key.RevokedAt == nil && (key.ExpiresAt == nil || now.Before(*key.ExpiresAt))
+}
+
+// HasScope reports whether the key grants a scope
+func (key *APIKey) HasScope(scope string) bool {
+	for _, granted := range key.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Clone returns a copy of the key that shares no slices with the original
+func (key *APIKey) Clone() *APIKey {
+	clone := *key
+	clone.Scopes = append([]string{}, key.Scopes...)
+	return &clone
+}
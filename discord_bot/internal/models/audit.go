@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Audit actions
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditActorDiscord is the actor of changes made in reaction to Discord gateway events, such as the
+// cleanup after a channel is deleted
+const AuditActorDiscord = "discord"
+
+// AuditActorDeletedUser replaces the Discord user ID of a user whose data was deleted as the actor of
+// their changes
+const AuditActorDeletedUser = "deleted-user"
+
+// Audited resource types
+const (
+	AuditResourceChannelConfig = "channel_config"
+	AuditResourceWebhook       = "webhook_registration"
+	AuditResourceCategoryRoute = "category_route"
+	AuditResourceLimitOverride = "limit_override"
+	AuditResourceRoutingRule   = "routing_rule"
+)
+
+// AuditEntry records a single change to a channel config, webhook registration, category route,
+// routing rule or subscription limit override
+type AuditEntry struct {
+	ID           string      `json:"id"`
+	Actor        string      `json:"actor"` // Discord user ID, "api" for REST callers, "discord" for gateway events or "deleted-user"
+	Action       string      `json:"action"`
+	ResourceType string      `json:"resource_type"`
+	ResourceID   string      `json:"resource_id"`
+	ChannelID    string      `json:"channel_id"`
+	Changes      []string    `json:"changes,omitempty"` // JSON field names that differ between old and new values
+	OldValue     interface{} `json:"old_value,omitempty"`
+	NewValue     interface{} `json:"new_value,omitempty"`
+	Timestamp    time.Time   `json:"timestamp"`
+}
+
+// AuditFilter selects audit entries, zero values match everything
+type AuditFilter struct {
+	ChannelID    string
+	ResourceType string
+	Limit        int // most recent entries to return, 0 for all
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// BusOffset is the offset of the last market event message processed from one partition of a
+// message bus topic, kept so messages redelivered after a crash are not processed twice
+type BusOffset struct {
+	Consumer  string    `json:"consumer"` // driver, topic and consumer group, e.g. kafka:markets/coral-discord-bot
+	Partition int32     `json:"partition"`
+	Offset    int64     `json:"offset"` // Kafka offset or JetStream stream sequence
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProcessedEvent is the normalized record of a market event the bot processed, published to the
+// outbound message bus for services such as analytics and archiving
+type ProcessedEvent struct {
+	ID          string        `json:"id"` // event ID of the delivery report, or the outbox item ID; repeated when a resumed event is delivered again
+	Type        string        `json:"type"`
+	Market      *Market       `json:"market"`
+	Content     string        `json:"content,omitempty"` // message as rendered, before per-recipient timezone localization
+	BuyAmount   float64       `json:"buy_amount,omitempty"`
+	Suppressed  bool          `json:"suppressed"` // true for buys below the global minimum, which are not delivered
+	ProcessedAt time.Time     `json:"processed_at"`
+	Delivery    DeliveryStats `json:"delivery"`
+}
+
+// DeliveryStats counts the recipients of a processed event
+type DeliveryStats struct {
+	Channels int `json:"channels"` // channels the message was sent or queued to
+	Users    int `json:"users"`    // users the message was sent or queued to as a DM
+	Failed   int `json:"failed"`   // sends that failed
+}
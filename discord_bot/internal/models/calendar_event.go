@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// CalendarEvent is an upcoming real-world event, like an election or a sports fixture, that the backend
+// tags markets to
+type CalendarEvent struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`           // such as "Sunday's match"
+	Kind      string    `json:"kind,omitempty"` // such as election or sports, free-form
+	StartTime time.Time `json:"start_time"`
+	MarketIDs []string  `json:"market_ids"` // the markets about the event
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// CategoryRoute sends a guild's new-market announcements of one category to a single channel,
+// instead of to every feed-enabled channel of the guild
+type CategoryRoute struct {
+	GuildID   string    `json:"guild_id"`
+	Category  string    `json:"category"` // normalized with NormalizeCategory
+	ChannelID string    `json:"channel_id"`
+	CreatedBy string    `json:"created_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NormalizeCategory returns the form categories are routed and matched in, so rules are case-insensitive
+func NormalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
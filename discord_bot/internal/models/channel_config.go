@@ -1,12 +1,176 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // ChannelConfig represents configuration for a Discord channel
 type ChannelConfig struct {
-	ChannelID           string    `json:"channel_id"`
-	FeedEnabled         bool      `json:"feed_enabled"`
-	AllowedCategories   []string  `json:"allowed_categories"`
-	FrequencyMode       string    `json:"frequency_mode"` // low, medium, high
-	LastUpdateTimestamp time.Time `json:"last_update_timestamp"`
+	ChannelID           string        `json:"channel_id"`
+	GuildID             string        `json:"guild_id,omitempty"`
+	FeedEnabled         bool          `json:"feed_enabled"`
+	AllowedCategories   []string      `json:"allowed_categories"`
+	FrequencyMode       string        `json:"frequency_mode"`              // low, medium, high or custom
+	CustomInterval      time.Duration `json:"custom_interval,omitempty"`   // update interval of the custom frequency
+	SubscribedMarkets   []string      `json:"subscribed_markets"`          // market IDs followed regardless of the feed setting
+	MinBuyAmount        float64       `json:"min_buy_amount"`              // buys below this amount are not posted
+	MinVolume           float64       `json:"min_volume"`                  // feed announcements of markets with less volume are not posted
+	ClosingSoonHours    int           `json:"closing_soon_hours"`          // announce markets entering their final hours, 0 disables
+	DigestMode          string        `json:"digest_mode,omitempty"`       // daily or weekly market roundups, empty for none
+	Timezone            string        `json:"timezone,omitempty"`          // IANA zone for displayed times, empty for each reader's locale
+	Crosspost           bool          `json:"crosspost"`                   // publish announcements to servers following this announcement channel
+	Board               bool          `json:"board"`                       // keep a pinned market board in the channel
+	BoardMessageID      string        `json:"board_message_id,omitempty"`  // the board message, empty until it is posted
+	QuietHoursStart     string        `json:"quiet_hours_start,omitempty"` // HH:MM in the channel's timezone from which market events are held
+	QuietHoursEnd       string        `json:"quiet_hours_end,omitempty"`   // HH:MM at which held events are posted as a summary
+	LiquidityAlerts     bool          `json:"liquidity_alerts"`            // post liquidity changes, which no channel gets by default
+	CalendarDigests     bool          `json:"calendar_digests,omitempty"`  // post a themed digest of the markets about each upcoming calendar event
+	ShadowMode          bool          `json:"shadow_mode,omitempty"`       // log and report what would be posted in the channel instead of posting it
+	PingRoleID          string        `json:"ping_role_id,omitempty"`      // role mentioned in the channel's alerts of PingEvents, empty for none
+	PingEvents          []string      `json:"ping_events,omitempty"`       // event types whose alerts mention PingRoleID
+	CalendarDigestsSent []string      `json:"calendar_sent,omitempty"`     // IDs of the upcoming calendar events whose digest was posted
+	LastDigestAt        time.Time     `json:"last_digest_at"`
+	LastUpdateTimestamp time.Time     `json:"last_update_timestamp"`
+}
+
+// DefaultClosingSoonHours is how long before a market closes it is announced in channels that did not choose a window
+const DefaultClosingSoonHours = 24
+
+// MaxClosingSoonHours is the longest closing-soon window a channel can choose
+const MaxClosingSoonHours = 168
+
+// Channel digest modes
+const (
+	DigestOff    = ""
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// IsValidDigestMode reports whether mode is a known digest mode
+func IsValidDigestMode(mode string) bool {
+	return mode == DigestOff || mode == DigestDaily || mode == DigestWeekly
+}
+
+// Clone returns a copy of the config that shares no slices with the original
+func (config *ChannelConfig) Clone() *ChannelConfig {
+	clone := *config
+	clone.AllowedCategories = append([]string{}, config.AllowedCategories...)
+	clone.SubscribedMarkets = append([]string{}, config.SubscribedMarkets...)
+	clone.CalendarDigestsSent = append([]string(nil), config.CalendarDigestsSent...)
+	clone.PingEvents = append([]string(nil), config.PingEvents...)
+	return &clone
+}
+
+// ChannelPingEvents lists the events whose channel alerts can mention a role, the events posted in channels
+var ChannelPingEvents = []string{EventNewMarket, EventMarketUpdate, EventTradingStarted, EventTradingEnded, EventMarketResolved, EventMarketCancelled, EventMarketBuy, EventMarketLiquidity}
+
+// DefaultPingEvents are the events a channel's role is mentioned for when none are chosen
+var DefaultPingEvents = []string{EventNewMarket, EventMarketResolved}
+
+// PingsFor reports whether the channel's alerts of an event type mention its role
+func (config *ChannelConfig) PingsFor(eventType string) bool {
+	if config.PingRoleID == "" {
+		return false
+	}
+	for _, pinged := range config.PingEvents {
+		if pinged == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// ChannelSettingsVersion is the version of the ChannelSettings export format
+const ChannelSettingsVersion = 1
+
+// ChannelFrequencies lists the valid channel update frequencies
+var ChannelFrequencies = []string{"low", "medium", "high"}
+
+// ChannelSettings are the portable parts of a channel's configuration, exported from one
+// channel and applied to another
+type ChannelSettings struct {
+	Version           int           `json:"version"`
+	FeedEnabled       bool          `json:"feed_enabled"`
+	AllowedCategories []string      `json:"allowed_categories"`
+	FrequencyMode     string        `json:"frequency_mode"`
+	CustomInterval    time.Duration `json:"custom_interval,omitempty"` // nanoseconds, for the custom frequency
+	SubscribedMarkets []string      `json:"subscribed_markets"`
+	MinBuyAmount      float64       `json:"min_buy_amount"`
+	MinVolume         float64       `json:"min_volume,omitempty"`
+	ClosingSoonHours  *int          `json:"closing_soon_hours,omitempty"` // missing uses DefaultClosingSoonHours
+	DigestMode        string        `json:"digest_mode,omitempty"`
+	Timezone          string        `json:"timezone,omitempty"`
+	Crosspost         bool          `json:"crosspost,omitempty"`
+	Board             bool          `json:"board,omitempty"`
+	QuietHoursStart   string        `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd     string        `json:"quiet_hours_end,omitempty"`
+	LiquidityAlerts   bool          `json:"liquidity_alerts,omitempty"`
+	CalendarDigests   bool          `json:"calendar_digests,omitempty"`
+	PingRoleID        string        `json:"ping_role_id,omitempty"`
+	PingEvents        []string      `json:"ping_events,omitempty"`
+}
+
+// Settings returns the channel's portable settings
+func (config *ChannelConfig) Settings() *ChannelSettings {
+	closingSoonHours := config.ClosingSoonHours
+	return &ChannelSettings{
+		Version:           ChannelSettingsVersion,
+		FeedEnabled:       config.FeedEnabled,
+		AllowedCategories: append([]string{}, config.AllowedCategories...),
+		FrequencyMode:     config.FrequencyMode,
+		CustomInterval:    config.CustomInterval,
+		SubscribedMarkets: append([]string{}, config.SubscribedMarkets...),
+		MinBuyAmount:      config.MinBuyAmount,
+		MinVolume:         config.MinVolume,
+		ClosingSoonHours:  &closingSoonHours,
+		DigestMode:        config.DigestMode,
+		Timezone:          config.Timezone,
+		Crosspost:         config.Crosspost,
+		Board:             config.Board,
+		QuietHoursStart:   config.QuietHoursStart,
+		QuietHoursEnd:     config.QuietHoursEnd,
+		LiquidityAlerts:   config.LiquidityAlerts,
+		CalendarDigests:   config.CalendarDigests,
+		PingRoleID:        config.PingRoleID,
+		PingEvents:        append([]string(nil), config.PingEvents...),
+	}
+}
+
+// ApplySettings replaces the channel's portable settings, keeping its channel and guild IDs
+func (config *ChannelConfig) ApplySettings(settings *ChannelSettings) {
+	config.FeedEnabled = settings.FeedEnabled
+	config.AllowedCategories = append([]string{}, settings.AllowedCategories...)
+	config.FrequencyMode = settings.FrequencyMode
+	config.CustomInterval = settings.CustomInterval
+	config.SubscribedMarkets = append([]string{}, settings.SubscribedMarkets...)
+	config.MinBuyAmount = settings.MinBuyAmount
+	config.MinVolume = settings.MinVolume
+	config.ClosingSoonHours = DefaultClosingSoonHours
+	if settings.ClosingSoonHours != nil {
+		config.ClosingSoonHours = *settings.ClosingSoonHours
+	}
+	config.DigestMode = settings.DigestMode
+	config.Timezone = settings.Timezone
+	config.Crosspost = settings.Crosspost
+	config.Board = settings.Board
+	config.QuietHoursStart = settings.QuietHoursStart
+	config.QuietHoursEnd = settings.QuietHoursEnd
+	config.LiquidityAlerts = settings.LiquidityAlerts
+	config.CalendarDigests = settings.CalendarDigests
+	config.PingRoleID = settings.PingRoleID
+	config.PingEvents = append([]string(nil), settings.PingEvents...)
+}
+
+// Frequency describes the channel's update frequency, with the interval of a custom one
+func (config *ChannelConfig) Frequency() string {
+	if config.FrequencyMode == FrequencyCustom {
+		return fmt.Sprintf("custom (every %s)", FormatInterval(config.CustomInterval))
+	}
+	return config.FrequencyMode
+}
+
+// HasQuietHours reports whether the channel has a quiet-hours window
+func (config *ChannelConfig) HasQuietHours() bool {
+	return config.QuietHoursStart != "" && config.QuietHoursEnd != ""
 }
@@ -0,0 +1,10 @@
+package models
+
+// CommandSyncResult counts what a sync of the bot's slash commands with Discord changed
+type CommandSyncResult struct {
+	Scope     string `json:"scope"` // "global", or the ID of the guild the commands are registered in
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Deleted   int    `json:"deleted"`
+	Unchanged int    `json:"unchanged"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// Creator is a market creator, as named in creator events. Name is the handle users subscribe to.
+type Creator struct {
+	Name        string  `json:"name"`
+	DisplayName string  `json:"display_name,omitempty"`
+	Bio         string  `json:"bio,omitempty"`
+	Volume      float64 `json:"volume"` // total volume of the creator's markets
+	Link        string  `json:"link"`
+}
+
+// Label returns the creator's display name, or their handle when they have none
+func (creator *Creator) Label() string {
+	if creator.DisplayName != "" {
+		return creator.DisplayName
+	}
+	return creator.Name
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Dead letter statuses
+const (
+	DeadLetterRetrying  = "retrying"  // retried automatically with backoff
+	DeadLetterExhausted = "exhausted" // no more automatic retries, waiting to be re-driven or discarded
+)
+
+// DeadLetter is a message to a channel or user that failed to send, kept so it can be retried
+type DeadLetter struct {
+	ID            string    `json:"id"`
+	EventID       string    `json:"event_id,omitempty"` // delivery report of the event the message belongs to
+	EventType     string    `json:"event_type"`
+	Recipient     string    `json:"recipient"` // channel or user
+	RecipientID   string    `json:"recipient_id"`
+	Content       string    `json:"content"`
+	Chart         []byte    `json:"chart,omitempty"`        // PNG attachment
+	PingRoleID    string    `json:"ping_role_id,omitempty"` // role the message mentions
+	AccentColor   int       `json:"accent_color,omitempty"` // color of the embed the message is posted in
+	Crosspost     bool      `json:"crosspost,omitempty"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"` // sends so far, including the one that first failed
+	LastError     string    `json:"last_error"`
+	CreatedAt     time.Time `json:"created_at"`
+	NextAttemptAt time.Time `json:"next_attempt_at"` // zero once retries are exhausted
+}
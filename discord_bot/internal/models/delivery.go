@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Delivery receipt statuses
+const (
+	DeliverySent     = "sent"
+	DeliveryFailed   = "failed"
+	DeliverySkipped  = "skipped"  // the channel's settings filtered the event out
+	DeliveryShadowed = "shadowed" // the event would have been sent, but shadow mode only logged it
+)
+
+// Delivery recipient kinds
+const (
+	RecipientChannel = "channel"
+	RecipientUser    = "user"
+)
+
+// DeliveryReceipt records what happened when an event was delivered to one channel or user
+type DeliveryReceipt struct {
+	Recipient   string    `json:"recipient"` // channel or user
+	RecipientID string    `json:"recipient_id"`
+	Status      string    `json:"status"`           // sent, failed, skipped or shadowed
+	Reason      string    `json:"reason,omitempty"` // the send error, why the channel was skipped, or what would have been sent
+	At          time.Time `json:"at"`
+}
+
+// DeliveryReport lists the receipts of one event. Messages buffered while the gateway was down get
+// their receipt once they are sent.
+type DeliveryReport struct {
+	EventID   string            `json:"event_id"`
+	EventType string            `json:"event_type"`
+	MarketID  string            `json:"market_id"`
+	CreatedAt time.Time         `json:"created_at"`
+	Stats     DeliveryStats     `json:"stats"`
+	Skipped   int               `json:"skipped"`  // channels whose settings filtered the event out
+	Shadowed  int               `json:"shadowed"` // channels and users the event would have been sent to in shadow mode
+	Receipts  []DeliveryReceipt `json:"receipts"`
+}
+
+// Count fills in the report's stats from its receipts
+func (report *DeliveryReport) Count() {
+	report.Stats, report.Skipped, report.Shadowed = DeliveryStats{}, 0, 0
+	for _, receipt := range report.Receipts {
+		switch {
+		case receipt.Status == DeliveryFailed:
+			report.Stats.Failed++
+		case receipt.Status == DeliverySkipped:
+			report.Skipped++
+		case receipt.Status == DeliveryShadowed:
+			report.Shadowed++
+		case receipt.Recipient == RecipientChannel:
+			report.Stats.Channels++
+		default:
+			report.Stats.Users++
+		}
+	}
+}
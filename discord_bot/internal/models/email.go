@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// EmailSettings is the email address a user linked with link_email and what the bot sends to it
+type EmailSettings struct {
+	Address      string    `json:"address,omitempty"`     // verified address, empty until a code is verified
+	Digest       string    `json:"digest,omitempty"`      // daily or weekly market roundups by email, empty for none
+	Resolutions  bool      `json:"resolutions,omitempty"` // resolution and cancellation notifications are emailed instead of DMed
+	LastDigestAt time.Time `json:"last_digest_at,omitempty"`
+
+	PendingAddress string    `json:"pending_address,omitempty"` // address the last verification code was sent to
+	CodeHash       string    `json:"code_hash,omitempty"`       // SHA-256 of the code, the code itself is not stored
+	CodeSentAt     time.Time `json:"code_sent_at,omitempty"`
+	CodeAttempts   int       `json:"code_attempts,omitempty"` // wrong codes entered since the code was sent
+}
+
+// Verified reports whether the user has a verified address
+func (settings *EmailSettings) Verified() bool {
+	return settings != nil && settings.Address != ""
+}
+
+// EmailsResolutions reports whether the user's resolution notifications go to their verified address
+func (subscription *Subscription) EmailsResolutions() bool {
+	return subscription.Email.Verified() && subscription.Email.Resolutions
+}
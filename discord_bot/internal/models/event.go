@@ -0,0 +1,73 @@
+package models
+
+// Market event types shared by webhook payloads, registrations and fan-out routing
+const (
+	EventNewMarket       = "new_market"
+	EventMarketUpdate    = "market_update"
+	EventTradingStarted  = "trading_started"
+	EventTradingEnded    = "trading_ended"
+	EventMarketResolved  = "market_resolved"
+	EventMarketCancelled = "market_cancelled"
+	EventMarketBuy       = "market_buy"
+	EventMarketComment   = "market_comment"
+	EventMarketLiquidity = "market_liquidity"
+)
+
+// Creator event types, delivered only to the users subscribed to the creator
+const (
+	EventCreatorJoined    = "creator_joined"
+	EventCreatorMilestone = "creator_milestone"
+)
+
+// BuiltinEventTypes lists the event types the bot handles itself. Custom event types cannot take
+// their names.
+var BuiltinEventTypes = []string{
+	EventNewMarket, EventMarketUpdate, EventTradingStarted, EventTradingEnded, EventMarketResolved, EventMarketCancelled,
+	EventMarketBuy, EventMarketComment, EventMarketLiquidity, EventCreatorJoined, EventCreatorMilestone,
+}
+
+// Priority is the delivery priority class of an event. Queued messages of a higher priority are sent
+// before those of a lower one, so a backlog of routine updates does not hold back a resolution.
+type Priority int
+
+// Delivery priority classes, from lowest to highest
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+)
+
+// Priorities lists the priority classes from highest to lowest
+var Priorities = []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+// eventPriorities assigns the event types that are not of normal priority their class
+var eventPriorities = map[string]Priority{
+	EventMarketResolved:  PriorityHigh,
+	EventMarketCancelled: PriorityHigh,
+	EventTradingStarted:  PriorityHigh,
+	EventMarketUpdate:    PriorityLow,
+	EventMarketBuy:       PriorityLow,
+	EventMarketComment:   PriorityLow,
+	EventMarketLiquidity: PriorityLow,
+}
+
+// EventPriority returns the priority class of an event type. New markets, trading ends and any
+// other messages are of normal priority.
+func EventPriority(eventType string) Priority {
+	if priority, ok := eventPriorities[eventType]; ok {
+		return priority
+	}
+	return PriorityNormal
+}
+
+// String returns the name of a priority class, as reported in queue status
+func (priority Priority) String() string {
+	switch priority {
+	case PriorityHigh:
+		return "high"
+	case PriorityLow:
+		return "low"
+	default:
+		return "normal"
+	}
+}
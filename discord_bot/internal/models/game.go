@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Game bet statuses
+const (
+	GameBetOpen     = "open"
+	GameBetWon      = "won"      // the resolution paid out to the bet's outcome
+	GameBetLost     = "lost"     // the resolution paid nothing to the bet's outcome
+	GameBetRefunded = "refunded" // the market was cancelled and the stake returned
+)
+
+// GameWallet is a member's play money in a guild's prediction game. Each guild's game has its own
+// wallets, so standings are per server.
+type GameWallet struct {
+	GuildID       string    `json:"guild_id"`
+	DiscordUserID string    `json:"discord_user_id"`
+	Balance       float64   `json:"balance"` // play money not staked on open bets
+	Staked        float64   `json:"staked"`  // play money on open bets
+	Bets          int       `json:"bets"`    // bets placed
+	Wins          int       `json:"wins"`    // bets that paid out
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Worth returns the wallet's balance with its open bets counted at their stake
+func (wallet *GameWallet) Worth() float64 {
+	return wallet.Balance + wallet.Staked
+}
+
+// GameBet is play money a member staked on an outcome of an announced market, at the outcome's
+// probability when the bet was placed
+type GameBet struct {
+	ID            string    `json:"id"`
+	GuildID       string    `json:"guild_id"`
+	DiscordUserID string    `json:"discord_user_id"`
+	MarketID      string    `json:"market_id"`
+	MarketTitle   string    `json:"market_title"`
+	Outcome       string    `json:"outcome"`
+	Stake         float64   `json:"stake"`
+	Price         float64   `json:"price"`  // the outcome's probability when the bet was placed, 0 to 1
+	Shares        float64   `json:"shares"` // stake divided by price, each paying up to 1 when the market resolves
+	Status        string    `json:"status"` // open, won, lost or refunded
+	Payout        float64   `json:"payout"` // play money returned when the bet was settled
+	PlacedAt      time.Time `json:"placed_at"`
+	SettledAt     time.Time `json:"settled_at,omitempty"`
+}
+
+// GameStanding is a member's place on a guild's game leaderboard
+type GameStanding struct {
+	Rank          int     `json:"rank"`
+	DiscordUserID string  `json:"discord_user_id"`
+	Worth         float64 `json:"worth"`  // balance with open bets at their stake
+	Profit        float64 `json:"profit"` // worth less the starting balance
+	Bets          int     `json:"bets"`
+	Wins          int     `json:"wins"`
+}
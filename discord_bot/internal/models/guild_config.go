@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// GuildConfig represents configuration for a Discord guild (server)
+type GuildConfig struct {
+	GuildID          string           `json:"guild_id"`
+	DefaultChannelID string           `json:"default_channel_id"`
+	ConfiguredBy     string           `json:"configured_by"`
+	UpdateIntervals  *UpdateIntervals `json:"update_intervals,omitempty"` // the guild's own update intervals, nil for the bot's
+	Branding         *GuildBranding   `json:"branding,omitempty"`         // how the guild's alerts look, nil for the bot's style
+	TradingEnabled   bool             `json:"trading_enabled,omitempty"`  // buy buttons are shown under the guild's market alerts
+	GameEnabled      bool             `json:"game_enabled,omitempty"`     // members bet play money on the guild's market alerts
+	StatsChannelID   string           `json:"stats_channel_id,omitempty"` // channel the weekly community stats are posted in, empty for none
+	LastStatsAt      time.Time        `json:"last_stats_at,omitempty"`    // when the weekly community stats were last handled
+	UpdatedAt        time.Time        `json:"updated_at"`
+}
+
+// GuildBranding restyles the alerts posted in a guild's channels, so white-label communities can
+// make the bot look like their own
+type GuildBranding struct {
+	Emojis      map[string]string `json:"emojis,omitempty"`       // emoji around the header of each event type's alerts, by event type
+	AccentColor int               `json:"accent_color,omitempty"` // RGB color of the embed alerts are posted in, 0 to post plain messages
+	FooterText  string            `json:"footer_text,omitempty"`  // line added under every alert
+	FooterURL   string            `json:"footer_url,omitempty"`   // link of the footer line
+}
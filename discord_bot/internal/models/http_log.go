@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// HTTPLogEntry is a request to the web server and its response, kept for debugging backend integrations.
+// Auth headers, secrets and user IDs are redacted before it is stored.
+type HTTPLogEntry struct {
+	ID             string            `json:"id"`
+	RequestID      string            `json:"request_id"`
+	Time           time.Time         `json:"time"`
+	Reason         string            `json:"reason"` // endpoint when its endpoint is always logged, sampled otherwise
+	Method         string            `json:"method"`
+	Route          string            `json:"route"` // the endpoint's pattern, such as /discord/users/{discord_user_id}/data
+	Path           string            `json:"path"`  // the requested path and query string
+	Status         int               `json:"status"`
+	DurationMs     int64             `json:"duration_ms"`
+	RequestHeaders map[string]string `json:"request_headers"`
+	RequestBody    string            `json:"request_body,omitempty"`
+	ResponseBody   string            `json:"response_body,omitempty"`
+	Truncated      bool              `json:"truncated,omitempty"` // a body was cut at the logged size
+}
+
+// HTTPLogFilter selects logged requests
+type HTTPLogFilter struct {
+	Route string // only requests to this endpoint pattern, every endpoint when empty
+	Limit int    // at most this many, 0 for all
+}
@@ -0,0 +1,13 @@
+package models
+
+// LeaderboardEntry is a market or creator ranked by subscriber count
+type LeaderboardEntry struct {
+	ID          string `json:"id"` // market ID or creator name
+	Subscribers int    `json:"subscribers"`
+}
+
+// Leaderboard lists the most-followed markets and creators
+type Leaderboard struct {
+	Markets  []LeaderboardEntry `json:"markets"`
+	Creators []LeaderboardEntry `json:"creators"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// LoadTestReport is the outcome of a load test, in which synthetic events were delivered to simulated
+// channels and users through the fan-out and a fake Discord API
+type LoadTestReport struct {
+	Channels      int     `json:"channels"`
+	Users         int     `json:"users"`
+	Events        int     `json:"events"`
+	Workers       int     `json:"workers"`         // fan-out workers, 0 when sends ran one after another
+	SendLatencyMs float64 `json:"send_latency_ms"` // time the fake Discord API took to answer each request
+	Messages      int     `json:"messages"`        // messages the fake Discord API received
+	DurationMs    float64 `json:"duration_ms"`
+	Throughput    float64 `json:"throughput"` // messages per second
+
+	// Delivery latency is the time from an event's arrival to one of its messages reaching Discord
+	LatencyP50Ms float64 `json:"latency_p50_ms"`
+	LatencyP99Ms float64 `json:"latency_p99_ms"`
+	LatencyMaxMs float64 `json:"latency_max_ms"`
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Market represents a market in Coral Markets
 type Market struct {
@@ -16,5 +19,46 @@ type Market struct {
 	EndTime         time.Time `json:"end_time"`
 	Status          string    `json:"status"` // active, closed, resolved
 	ResolvedOutcome string    `json:"resolved_outcome,omitempty"`
+	ResolvedAt      time.Time `json:"resolved_at,omitempty"`
+	Payouts         []Payout  `json:"payouts,omitempty"` // set for split resolutions that paid out to several outcomes
 	Link            string    `json:"link"`
 }
+
+// Payout is one outcome's part of a resolved market's pool
+type Payout struct {
+	Outcome   string  `json:"outcome"`
+	FinalPct  float64 `json:"final_pct"`  // the outcome's probability when trading ended
+	PoolShare float64 `json:"pool_share"` // percent of the pool paid to the outcome's holders
+}
+
+// Winners returns the outcomes a resolution paid out to: the payouts with a pool share, or the
+// resolved outcome when the market has no payout breakdown
+func (market *Market) Winners() []string {
+	var winners []string
+	for _, payout := range market.Payouts {
+		if payout.PoolShare > 0 {
+			winners = append(winners, payout.Outcome)
+		}
+	}
+	if len(winners) == 0 && market.ResolvedOutcome != "" {
+		winners = append(winners, market.ResolvedOutcome)
+	}
+	return winners
+}
+
+// PaidOut reports whether the resolution paid out to an outcome, ignoring case
+func (market *Market) PaidOut(outcome string) bool {
+	for _, winner := range market.Winners() {
+		if strings.EqualFold(winner, outcome) {
+			return true
+		}
+	}
+	return false
+}
+
+// MarketCleanup counts what was removed when a cancelled market was cleaned up
+type MarketCleanup struct {
+	Users     int `json:"users"`    // users whose market or outcome subscriptions were removed
+	Channels  int `json:"channels"` // channels that were subscribed to the market
+	Reminders int `json:"reminders"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// MarketSnapshot represents the outcome probabilities of a market at a point in time
+type MarketSnapshot struct {
+	MarketID    string    `json:"market_id"`
+	Outcomes    []string  `json:"outcomes"`
+	Percentages []float64 `json:"percentages"`
+	Volume      float64   `json:"volume"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// Percentage returns the probability recorded for an outcome and whether it was present
+func (snapshot *MarketSnapshot) Percentage(outcome string) (float64, bool) {
+	for i, name := range snapshot.Outcomes {
+		if name == outcome && i < len(snapshot.Percentages) {
+			return snapshot.Percentages[i], true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,18 @@
+package models
+
+// Order statuses reported by the backend
+const (
+	OrderFilled  = "filled"
+	OrderPending = "pending"
+)
+
+// Order is a buy the backend placed for a linked Coral account
+type Order struct {
+	ID       string  `json:"id"`
+	MarketID string  `json:"market_id"`
+	Outcome  string  `json:"outcome"`
+	Amount   float64 `json:"amount"` // dollars spent
+	Shares   float64 `json:"shares"` // shares bought, 0 until the order fills
+	Price    float64 `json:"price"`  // average price paid per share, 0 to 1
+	Status   string  `json:"status"` // filled, or pending while the backend fills it
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Outbox item statuses
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxFailed    = "failed" // given up after too many delivery attempts
+)
+
+// OutboxItem is a rendered market event notification, stored before it is delivered so that events
+// received shortly before a crash are still delivered after a restart
+type OutboxItem struct {
+	ID          string          `json:"id"`
+	EventType   string          `json:"event_type"`
+	Content     string          `json:"content"`
+	Chart       []byte          `json:"chart,omitempty"` // PNG attachment
+	BuyAmount   float64         `json:"buy_amount,omitempty"`
+	Market      *Market         `json:"market"`
+	Previous    *MarketSnapshot `json:"previous,omitempty"` // snapshot before the event, for outcome subscribers
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	CreatedAt   time.Time       `json:"created_at"`
+	DeliveredAt time.Time       `json:"delivered_at,omitempty"`
+}
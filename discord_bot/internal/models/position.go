@@ -0,0 +1,19 @@
+package models
+
+// Position is what a Coral account holds of a market outcome
+type Position struct {
+	AccountID   string  `json:"account_id,omitempty"` // set on the positions of a market, which span accounts
+	MarketID    string  `json:"market_id"`
+	MarketTitle string  `json:"market_title"`
+	Outcome     string  `json:"outcome"`
+	Shares      float64 `json:"shares"`
+	Cost        float64 `json:"cost"`             // amount paid for the shares
+	Value       float64 `json:"value"`            // what the shares are worth at the outcome's current price
+	Status      string  `json:"status,omitempty"` // the market's status, such as active or resolved
+	Link        string  `json:"link,omitempty"`
+}
+
+// ProfitLoss returns how much the position gained or lost
+func (position *Position) ProfitLoss() float64 {
+	return position.Value - position.Cost
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// HeldNotification counts the events of one type about one market that arrived during a channel's
+// quiet hours. Held events are posted as a single summary when the quiet hours end.
+type HeldNotification struct {
+	ChannelID   string    `json:"channel_id"`
+	MarketID    string    `json:"market_id"`
+	MarketTitle string    `json:"market_title"` // title at the latest held event
+	MarketLink  string    `json:"market_link"`
+	EventType   string    `json:"event_type"`
+	Count       int       `json:"count"`
+	FirstHeldAt time.Time `json:"first_held_at"`
+	LastHeldAt  time.Time `json:"last_held_at"`
+}
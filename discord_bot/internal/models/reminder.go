@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Reminder represents a scheduled "closing soon" notification for a market
+type Reminder struct {
+	ID            string        `json:"id"`
+	MarketID      string        `json:"market_id"`
+	MarketTitle   string        `json:"market_title"`
+	MarketLink    string        `json:"market_link"`
+	EndTime       time.Time     `json:"end_time"`
+	DiscordUserID string        `json:"discord_user_id,omitempty"` // set for DM reminders
+	ChannelID     string        `json:"channel_id,omitempty"`      // set for channel reminders
+	Before        time.Duration `json:"before"`
+	RemindAt      time.Time     `json:"remind_at"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
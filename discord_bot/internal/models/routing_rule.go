@@ -0,0 +1,101 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Routing rule actions
+const (
+	RuleActionSend     = "send"     // post the event, even when the channel's own filters would skip it
+	RuleActionSuppress = "suppress" // do not post the event
+	RuleActionPing     = "ping"     // post the event mentioning RoleID
+	RuleActionRoute    = "route"    // post the event in TargetChannelID instead
+)
+
+// RoutingRuleActions lists the actions a routing rule can take
+var RoutingRuleActions = []string{RuleActionSend, RuleActionSuppress, RuleActionPing, RuleActionRoute}
+
+// RoutingRule decides what happens to the market events a guild's channels receive. A guild's rules
+// are evaluated in ascending Position for each channel an event would be posted in, and the first
+// rule that applies to the channel and matches the event decides; when none does, the channel's own
+// filters do. Every condition set must match, and a list matches when any of its values does.
+type RoutingRule struct {
+	ID              string    `json:"id"`
+	GuildID         string    `json:"guild_id"`
+	ChannelID       string    `json:"channel_id,omitempty"` // channel the rule applies to, empty for every channel of the guild
+	Position        int       `json:"position"`             // 1 for the first rule of the guild
+	EventTypes      []string  `json:"event_types,omitempty"`
+	Categories      []string  `json:"categories,omitempty"` // normalized with NormalizeCategory
+	Creators        []string  `json:"creators,omitempty"`
+	Keywords        []string  `json:"keywords,omitempty"`   // matched in the market title, ignoring case
+	MinVolume       float64   `json:"min_volume,omitempty"` // 0 for no minimum
+	MaxVolume       float64   `json:"max_volume,omitempty"` // 0 for no maximum
+	Action          string    `json:"action"`
+	RoleID          string    `json:"role_id,omitempty"`           // role ping rules mention
+	TargetChannelID string    `json:"target_channel_id,omitempty"` // channel route rules post in
+	CreatedBy       string    `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Clone returns a copy of the rule that shares no slices with the original
+func (rule *RoutingRule) Clone() *RoutingRule {
+	clone := *rule
+	clone.EventTypes = append([]string(nil), rule.EventTypes...)
+	clone.Categories = append([]string(nil), rule.Categories...)
+	clone.Creators = append([]string(nil), rule.Creators...)
+	clone.Keywords = append([]string(nil), rule.Keywords...)
+	return &clone
+}
+
+// AppliesTo reports whether the rule is evaluated for a channel
+func (rule *RoutingRule) AppliesTo(channelID string) bool {
+	return rule.ChannelID == "" || rule.ChannelID == channelID
+}
+
+// Matches reports whether an event of the given type about a market meets every condition of the rule
+func (rule *RoutingRule) Matches(eventType string, market *Market) bool {
+	if len(rule.EventTypes) > 0 && !containsFold(rule.EventTypes, eventType) {
+		return false
+	}
+	if len(rule.Categories) > 0 && !containsFold(rule.Categories, NormalizeCategory(market.Category)) {
+		return false
+	}
+	if len(rule.Creators) > 0 && !containsFold(rule.Creators, market.Creator) {
+		return false
+	}
+	if len(rule.Keywords) > 0 {
+		title, found := strings.ToLower(market.Title), false
+		for _, keyword := range rule.Keywords {
+			found = found || strings.Contains(title, strings.ToLower(keyword))
+		}
+		if !found {
+			return false
+		}
+	}
+	if market.Volume < rule.MinVolume || (rule.MaxVolume > 0 && market.Volume > rule.MaxVolume) {
+		return false
+	}
+	return true
+}
+
+// FirstMatchingRule returns the first of a guild's rules, in order, that applies to the channel and
+// matches the event, or nil when none does
+func FirstMatchingRule(rules []*RoutingRule, channelID, eventType string, market *Market) *RoutingRule {
+	for _, rule := range rules {
+		if rule.AppliesTo(channelID) && rule.Matches(eventType, market) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// containsFold reports whether values holds value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Scheduled job kinds
+const (
+	JobReminder = "reminder" // sends the reminder whose ID is the job's key
+)
+
+// ScheduledJob is a job stored until it runs, so jobs pending when the bot stops run after it restarts.
+// A job is run at least once: it is rescheduled before it runs and only deleted once it succeeded.
+type ScheduledJob struct {
+	ID        string    `json:"id"`   // kind and key, so a job is scheduled once per key
+	Kind      string    `json:"kind"` // such as JobReminder
+	Key       string    `json:"key"`  // what the job acts on, such as a reminder ID
+	RunAt     time.Time `json:"run_at"`
+	Attempts  int       `json:"attempts"` // runs started so far
+	LastError string    `json:"last_error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// StorageMigration reports a copy of the bot's data to another storage backend. The counts are of
+// records found in the target afterwards, which match the source when the migration succeeded.
+type StorageMigration struct {
+	Driver               string    `json:"driver"`
+	Path                 string    `json:"path"`
+	Subscriptions        int       `json:"subscriptions"`
+	ChannelConfigs       int       `json:"channel_configs"`
+	WebhookRegistrations int       `json:"webhook_registrations"` // including unregistered ones, kept for the audit trail
+	StartedAt            time.Time `json:"started_at"`
+	FinishedAt           time.Time `json:"finished_at"`
+}
@@ -1,8 +1,30 @@
 package models
 
+import "time"
+
 // Subscription represents a user's subscription to markets or creators
 type Subscription struct {
-	DiscordUserID      string   `json:"discord_user_id"`
-	SubscribedMarkets  []string `json:"subscribed_markets"`  // market IDs
-	SubscribedCreators []string `json:"subscribed_creators"` // creator names
+	DiscordUserID      string                `json:"discord_user_id"`
+	SubscribedMarkets  []string              `json:"subscribed_markets"`  // market IDs
+	SubscribedCreators []string              `json:"subscribed_creators"` // creator names
+	SubscribedOutcomes []OutcomeSubscription `json:"subscribed_outcomes"`
+	MinBuyAmount       float64               `json:"min_buy_amount"`          // buys below this amount are not sent
+	Timezone           string                `json:"timezone,omitempty"`      // IANA zone for displayed times, empty for the reader's locale
+	SnoozedUntil       time.Time             `json:"snoozed_until,omitempty"` // no notification DMs are sent before this time
+	Activity           bool                  `json:"activity,omitempty"`      // comments and other activity on subscribed markets are sent
+	Email              *EmailSettings        `json:"email,omitempty"`         // linked email address, nil for none
+	Account            *CoralAccount         `json:"account,omitempty"`       // linked Coral account, nil for none
+}
+
+// Snoozed reports whether the user has paused their notification DMs at the given time
+func (subscription *Subscription) Snoozed(now time.Time) bool {
+	return now.Before(subscription.SnoozedUntil)
+}
+
+// OutcomeSubscription represents a subscription to a single outcome of a market
+type OutcomeSubscription struct {
+	MarketID  string   `json:"market_id"`
+	Outcome   string   `json:"outcome"`
+	MinChange float64  `json:"min_change"`         // percentage points the outcome must move before notifying
+	Baseline  *float64 `json:"baseline,omitempty"` // probability that moves are measured from, reset after each notification; nil until known
 }
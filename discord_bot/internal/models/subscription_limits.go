@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Subjects of subscription limits
+const (
+	LimitSubjectUser  = "user"
+	LimitSubjectGuild = "guild"
+)
+
+// SubscriptionLimits caps how much one user subscribes to and how many channels one guild configures,
+// so a single user or server cannot slow down every fan-out. Zero means unlimited.
+type SubscriptionLimits struct {
+	MaxMarkets  int `json:"max_markets"`  // markets a user subscribes to
+	MaxCreators int `json:"max_creators"` // creators a user subscribes to
+	MaxOutcomes int `json:"max_outcomes"` // market outcomes a user subscribes to
+	MaxChannels int `json:"max_channels"` // channels configured in a guild
+}
+
+// LimitOverride replaces some of the default limits for one user or guild. Nil fields keep the
+// default, and zero lifts the limit.
+type LimitOverride struct {
+	Subject     string    `json:"subject"` // user or guild
+	SubjectID   string    `json:"subject_id"`
+	MaxMarkets  *int      `json:"max_markets,omitempty"`
+	MaxCreators *int      `json:"max_creators,omitempty"`
+	MaxOutcomes *int      `json:"max_outcomes,omitempty"`
+	MaxChannels *int      `json:"max_channels,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	UpdatedBy   string    `json:"updated_by"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Apply returns the limits with the override's fields in place of the defaults
+func (override *LimitOverride) Apply(limits SubscriptionLimits) SubscriptionLimits {
+	if override == nil {
+		return limits
+	}
+	if override.MaxMarkets != nil {
+		limits.MaxMarkets = *override.MaxMarkets
+	}
+	if override.MaxCreators != nil {
+		limits.MaxCreators = *override.MaxCreators
+	}
+	if override.MaxOutcomes != nil {
+		limits.MaxOutcomes = *override.MaxOutcomes
+	}
+	if override.MaxChannels != nil {
+		limits.MaxChannels = *override.MaxChannels
+	}
+	return limits
+}
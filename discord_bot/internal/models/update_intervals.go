@@ -0,0 +1,81 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// FrequencyCustom is the update frequency of channels that chose their own interval
+const FrequencyCustom = "custom"
+
+// Limits of a channel's custom update interval
+const (
+	MinCustomInterval = 5 * time.Minute
+	MaxCustomInterval = 7 * 24 * time.Hour
+)
+
+// UpdateIntervals are how often market updates are sent at each frequency. A zero field leaves the
+// interval to the defaults the intervals are applied over.
+type UpdateIntervals struct {
+	High   time.Duration `json:"high"`
+	Medium time.Duration `json:"medium"`
+	Low    time.Duration `json:"low"`
+
+	// Markets closing within ClosingSoonWindow update every ClosingSoonInterval whatever the frequency
+	ClosingSoonWindow   time.Duration `json:"closing_soon_window"`
+	ClosingSoonInterval time.Duration `json:"closing_soon_interval"`
+}
+
+// DefaultUpdateIntervals are used unless the environment or a guild sets others
+var DefaultUpdateIntervals = UpdateIntervals{
+	High:                30 * time.Minute,
+	Medium:              time.Hour,
+	Low:                 3 * time.Hour,
+	ClosingSoonWindow:   6 * time.Hour,
+	ClosingSoonInterval: 15 * time.Minute,
+}
+
+// Over returns the intervals with their zero fields taken from defaults
+func (intervals UpdateIntervals) Over(defaults UpdateIntervals) UpdateIntervals {
+	pick := func(value, fallback time.Duration) time.Duration {
+		if value > 0 {
+			return value
+		}
+		return fallback
+	}
+	return UpdateIntervals{
+		High:                pick(intervals.High, defaults.High),
+		Medium:              pick(intervals.Medium, defaults.Medium),
+		Low:                 pick(intervals.Low, defaults.Low),
+		ClosingSoonWindow:   pick(intervals.ClosingSoonWindow, defaults.ClosingSoonWindow),
+		ClosingSoonInterval: pick(intervals.ClosingSoonInterval, defaults.ClosingSoonInterval),
+	}
+}
+
+// Interval returns how often updates are sent at a frequency. Custom frequencies use custom, and
+// unknown frequencies or a custom frequency without an interval the medium interval.
+func (intervals UpdateIntervals) Interval(frequency string, custom time.Duration) time.Duration {
+	switch frequency {
+	case "high":
+		return intervals.High
+	case "low":
+		return intervals.Low
+	case FrequencyCustom:
+		if custom > 0 {
+			return custom
+		}
+	}
+	return intervals.Medium
+}
+
+// FormatInterval shows an interval without trailing zero units, like 45m or 1h30m
+func FormatInterval(interval time.Duration) string {
+	formatted := interval.String()
+	if strings.HasSuffix(formatted, "m0s") {
+		formatted = strings.TrimSuffix(formatted, "0s")
+	}
+	if strings.HasSuffix(formatted, "h0m") {
+		formatted = strings.TrimSuffix(formatted, "0m")
+	}
+	return formatted
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// UserData is everything the bot stores about a Discord user, exported for data access and deletion requests
+type UserData struct {
+	DiscordUserID string            `json:"discord_user_id"`
+	Subscription  *Subscription     `json:"subscription"` // followed markets, creators and outcomes, minimum buy and timezone
+	Reminders     []*Reminder       `json:"reminders"`
+	Watchlists    []*Watchlist      `json:"watchlists"`
+	Analytics     []*AnalyticsEvent `json:"analytics"`    // commands, subscription changes and DM deliveries
+	GameWallets   []*GameWallet     `json:"game_wallets"` // play money in the prediction games of guilds
+	GameBets      []*GameBet        `json:"game_bets"`
+	ExportedAt    time.Time         `json:"exported_at"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Watchlist notification settings
+const (
+	WatchlistNotifyAll   = "all"   // every event about its markets
+	WatchlistNotifyMajor = "major" // only high priority events, such as resolutions
+	WatchlistNotifyOff   = "off"   // no notifications, the watchlist only organizes markets
+)
+
+// Watchlist is a named group of markets a user follows together, with its own notification settings.
+// Its owner is notified about its markets as if subscribed to them, once per event however many
+// watchlists or subscriptions include the market.
+type Watchlist struct {
+	ID            string    `json:"id"`
+	DiscordUserID string    `json:"discord_user_id"`
+	Name          string    `json:"name"` // unique per user, compared with NormalizeWatchlistName
+	MarketIDs     []string  `json:"market_ids"`
+	Notify        string    `json:"notify"`                  // all, major or off
+	MinBuyAmount  float64   `json:"min_buy_amount"`          // buys below this amount are not sent
+	ImportedFrom  string    `json:"imported_from,omitempty"` // share code the markets were copied from
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// WatchlistShare is a code that lets other users import a copy of a watchlist. The code follows the
+// watchlist, so an import copies its markets as they are at the time, and stops working once the
+// watchlist is deleted.
+type WatchlistShare struct {
+	Code        string    `json:"code"`
+	WatchlistID string    `json:"watchlist_id"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// HasMarket reports whether a market is on the watchlist
+func (watchlist *Watchlist) HasMarket(marketID string) bool {
+	for _, id := range watchlist.MarketIDs {
+		if id == marketID {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifiesAbout reports whether the watchlist's settings let an event type through
+func (watchlist *Watchlist) NotifiesAbout(eventType string) bool {
+	switch watchlist.Notify {
+	case WatchlistNotifyOff:
+		return false
+	case WatchlistNotifyMajor:
+		return EventPriority(eventType) == PriorityHigh
+	}
+	return true
+}
+
+// NormalizeShareCode returns the form share codes are stored in, so codes can be typed in any case and
+// with or without the dash
+func NormalizeShareCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// NormalizeWatchlistName returns the form watchlist names are matched in, so names are case-insensitive
+func NormalizeWatchlistName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
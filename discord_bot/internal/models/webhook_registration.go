@@ -1,14 +1,23 @@
-package models
-
-import "time"
-
-// WebhookRegistration represents a registered Discord webhook for a channel
-type WebhookRegistration struct {
-	ID                string    `json:"id"`
-	ChannelID         string    `json:"channel_id"`
-	WebhookURL        string    `json:"webhook_url"`
-	Events            []string  `json:"events"`
-	Frequency         string    `json:"frequency"` // low|medium|high
-	AllowedCategories []string  `json:"allowed_categories"`
-	CreatedAt         time.Time `json:"created_at"`
-}
+package models
+
+import "time"
+
+// WebhookRegistration represents a registered Discord webhook for a channel
+type WebhookRegistration struct {
+	ID                string     `json:"id"`
+	ChannelID         string     `json:"channel_id"`
+	WebhookURL        string     `json:"webhook_url"`
+	Events            []string   `json:"events"`
+	Frequency         string     `json:"frequency"` // low|medium|high
+	AllowedCategories []string   `json:"allowed_categories"`
+	CreatedAt         time.Time  `json:"created_at"`
+	DeletedAt         *time.Time `json:"deleted_at,omitempty"` // set when unregistered, the record is kept for auditing
+}
+
+// Clone returns a copy of the registration that shares no slices with the original
+func (registration *WebhookRegistration) Clone() *WebhookRegistration {
+	clone := *registration
+	clone.Events = append([]string{}, registration.Events...)
+	clone.AllowedCategories = append([]string{}, registration.AllowedCategories...)
+	return &clone
+}
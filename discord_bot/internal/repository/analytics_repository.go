@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// SaveAnalyticsEvent appends an analytics event
+func (repo *InMemorySubscriptionRepository) SaveAnalyticsEvent(ctx context.Context, event *models.AnalyticsEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.analytics = append(repo.analytics, event)
+	return nil
+}
+
+// GetAnalyticsEvents returns the analytics events recorded in [from, to)
+func (repo *InMemorySubscriptionRepository) GetAnalyticsEvents(ctx context.Context, from, to time.Time) ([]*models.AnalyticsEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	events := []*models.AnalyticsEvent{}
+	for _, event := range repo.analytics {
+		if event.Timestamp.Before(from) || !event.Timestamp.Before(to) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// GetAnalyticsEventsBySubject returns every analytics event about a user or channel, oldest first
+func (repo *InMemorySubscriptionRepository) GetAnalyticsEventsBySubject(ctx context.Context, subject string) ([]*models.AnalyticsEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	events := []*models.AnalyticsEvent{}
+	for _, event := range repo.analytics {
+		if event.Subject == subject {
+			copied := *event
+			events = append(events, &copied)
+		}
+	}
+	return events, nil
+}
+
+// DeleteAnalyticsEventsBySubject deletes every analytics event about a user or channel and returns
+// how many were deleted
+func (repo *InMemorySubscriptionRepository) DeleteAnalyticsEventsBySubject(ctx context.Context, subject string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	kept := repo.analytics[:0]
+	for _, event := range repo.analytics {
+		if event.Subject != subject {
+			kept = append(kept, event)
+		}
+	}
+	deleted := len(repo.analytics) - len(kept)
+	for i := len(kept); i < len(repo.analytics); i++ {
+		repo.analytics[i] = nil
+	}
+	repo.analytics = kept
+	return deleted, nil
+}
+
+// PruneAnalyticsEvents deletes the analytics events recorded before a point in time
+func (repo *InMemorySubscriptionRepository) PruneAnalyticsEvents(ctx context.Context, before time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	kept := repo.analytics[:0]
+	for _, event := range repo.analytics {
+		if !event.Timestamp.Before(before) {
+			kept = append(kept, event)
+		}
+	}
+	for i := len(kept); i < len(repo.analytics); i++ {
+		repo.analytics[i] = nil
+	}
+	repo.analytics = kept
+	return nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// SaveAPIKey stores or updates an API key
+func (repo *InMemorySubscriptionRepository) SaveAPIKey(ctx context.Context, key *models.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.apiKeys[key.ID] = key.Clone()
+	return nil
+}
+
+// GetAPIKey retrieves an API key by id, or nil if it does not exist
+func (repo *InMemorySubscriptionRepository) GetAPIKey(ctx context.Context, id string) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	key, exists := repo.apiKeys[id]
+	if !exists {
+		return nil, nil
+	}
+	return key.Clone(), nil
+}
+
+// GetAPIKeyByHash retrieves the API key whose secret has the given hash, or nil if there is none
+func (repo *InMemorySubscriptionRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	for _, key := range repo.apiKeys {
+		if key.Hash == hash {
+			return key.Clone(), nil
+		}
+	}
+	return nil, nil
+}
+
+// GetAllAPIKeys returns every API key, including revoked keys
+func (repo *InMemorySubscriptionRepository) GetAllAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	keys := make([]*models.APIKey, 0, len(repo.apiKeys))
+	for _, key := range repo.apiKeys {
+		keys = append(keys, key.Clone())
+	}
+	return keys, nil
+}
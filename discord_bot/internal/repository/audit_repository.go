@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"coral-bot/discord_bot/internal/models"
+)
+
+// SaveAuditEntry appends an audit entry
+func (repo *InMemorySubscriptionRepository) SaveAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.audit = append(repo.audit, entry)
+	return nil
+}
+
+// GetAuditEntries returns the audit entries matching a filter, most recent first
+func (repo *InMemorySubscriptionRepository) GetAuditEntries(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	entries := []*models.AuditEntry{}
+	for i := len(repo.audit) - 1; i >= 0; i-- {
+		entry := repo.audit[i]
+		if filter.ChannelID != "" && entry.ChannelID != filter.ChannelID {
+			continue
+		}
+		if filter.ResourceType != "" && entry.ResourceType != filter.ResourceType {
+			continue
+		}
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// RedactAuditActor replaces an actor on every audit entry they made and returns how many were changed.
+// Entries are replaced rather than changed in place, so entries already read are left alone.
+func (repo *InMemorySubscriptionRepository) RedactAuditActor(ctx context.Context, actor, replacement string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	redacted := 0
+	for i, entry := range repo.audit {
+		if entry.Actor != actor {
+			continue
+		}
+		copied := *entry
+		copied.Actor = replacement
+		repo.audit[i] = &copied
+		redacted++
+	}
+	return redacted, nil
+}
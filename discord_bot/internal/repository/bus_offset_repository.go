@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// busOffsetKey identifies a partition of a message bus consumer
+type busOffsetKey struct {
+	consumer  string
+	partition int32
+}
+
+// SaveBusOffset saves the last processed offset of a message bus partition
+func (repo *InMemorySubscriptionRepository) SaveBusOffset(ctx context.Context, offset *models.BusOffset) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	clone := *offset
+	repo.busOffsets[busOffsetKey{consumer: offset.Consumer, partition: offset.Partition}] = &clone
+	return nil
+}
+
+// GetBusOffset returns the last processed offset of a message bus partition, or nil when none was saved
+func (repo *InMemorySubscriptionRepository) GetBusOffset(ctx context.Context, consumer string, partition int32) (*models.BusOffset, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	offset, exists := repo.busOffsets[busOffsetKey{consumer: consumer, partition: partition}]
+	if !exists {
+		return nil, nil
+	}
+	clone := *offset
+	return &clone, nil
+}
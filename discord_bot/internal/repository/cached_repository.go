@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// CacheKind names a list kept by CachedSubscriptionRepository
+type CacheKind string
+
+// Cached lists
+const (
+	CacheSubscriptions  CacheKind = "subscriptions"
+	CacheChannelConfigs CacheKind = "channel_configs"
+)
+
+// InvalidationHook is told about every list a write through the cache changed, so deployments whose
+// instances share storage can publish the change to the other instances, which pass it to Invalidate
+type InvalidationHook func(ctx context.Context, kind CacheKind)
+
+// CachedSubscriptionRepository wraps a SubscriptionRepository with a read-through cache of
+// GetAllSubscriptions and GetAllChannelConfigs, which run for every event. Writes through the cache,
+// including writes in transactions, drop the lists they change; every other call goes straight to the
+// wrapped repository.
+type CachedSubscriptionRepository struct {
+	SubscriptionRepository
+	ttl           time.Duration // how long a list is kept, 0 until a write changes it
+	hook          InvalidationHook
+	mutex         sync.Mutex
+	subscriptions cachedList[*models.Subscription]
+	channels      cachedList[*models.ChannelConfig]
+}
+
+// cachedList is one cached list. generation counts invalidations, so a read that started before one does
+// not store what it loaded.
+type cachedList[T any] struct {
+	items      []T
+	loaded     bool
+	loadedAt   time.Time
+	generation uint64
+}
+
+// NewCachedSubscriptionRepository wraps a repository with a cache whose lists expire after ttl, or only
+// when they change when ttl is 0
+func NewCachedSubscriptionRepository(next SubscriptionRepository, ttl time.Duration) *CachedSubscriptionRepository {
+	return &CachedSubscriptionRepository{SubscriptionRepository: next, ttl: ttl}
+}
+
+// SetInvalidationHook sets the hook told about the lists changed by writes through this cache.
+// Invalidations passed to Invalidate are not repeated to it.
+func (repo *CachedSubscriptionRepository) SetInvalidationHook(hook InvalidationHook) {
+	repo.hook = hook
+}
+
+// Invalidate drops a cached list, so the next read loads it again. It is called with the
+// invalidations other instances published.
+func (repo *CachedSubscriptionRepository) Invalidate(kind CacheKind) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	switch kind {
+	case CacheSubscriptions:
+		repo.subscriptions = cachedList[*models.Subscription]{generation: repo.subscriptions.generation + 1}
+	case CacheChannelConfigs:
+		repo.channels = cachedList[*models.ChannelConfig]{generation: repo.channels.generation + 1}
+	}
+}
+
+// changed drops a list changed by a write through this cache and tells the hook
+func (repo *CachedSubscriptionRepository) changed(ctx context.Context, kind CacheKind) {
+	repo.Invalidate(kind)
+	if repo.hook != nil {
+		repo.hook(ctx, kind)
+	}
+}
+
+// GetAllSubscriptions returns the cached subscriptions, loading them on a miss
+func (repo *CachedSubscriptionRepository) GetAllSubscriptions(ctx context.Context) ([]*models.Subscription, error) {
+	return readThrough(ctx, repo, &repo.subscriptions, repo.SubscriptionRepository.GetAllSubscriptions)
+}
+
+// GetAllChannelConfigs returns the cached channel configs, loading them on a miss
+func (repo *CachedSubscriptionRepository) GetAllChannelConfigs(ctx context.Context) ([]*models.ChannelConfig, error) {
+	return readThrough(ctx, repo, &repo.channels, repo.SubscriptionRepository.GetAllChannelConfigs)
+}
+
+// readThrough returns a copy of a cached list, or loads and caches it unless it was invalidated meanwhile
+func readThrough[T any](ctx context.Context, repo *CachedSubscriptionRepository, list *cachedList[T], load func(context.Context) ([]T, error)) ([]T, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.Lock()
+	if list.loaded && (repo.ttl <= 0 || time.Since(list.loadedAt) < repo.ttl) {
+		items := append([]T(nil), list.items...)
+		repo.mutex.Unlock()
+		return items, nil
+	}
+	generation := list.generation
+	repo.mutex.Unlock()
+
+	items, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	if list.generation == generation {
+		list.items, list.loaded, list.loadedAt = items, true, time.Now()
+	}
+	return append([]T(nil), items...), nil
+}
+
+// SaveSubscription saves through the wrapped repository and drops the cached subscriptions
+func (repo *CachedSubscriptionRepository) SaveSubscription(ctx context.Context, subscription *models.Subscription) error {
+	defer repo.changed(ctx, CacheSubscriptions)
+	return repo.SubscriptionRepository.SaveSubscription(ctx, subscription)
+}
+
+// DeleteSubscription deletes through the wrapped repository and drops the cached subscriptions
+func (repo *CachedSubscriptionRepository) DeleteSubscription(ctx context.Context, discordUserID string) error {
+	defer repo.changed(ctx, CacheSubscriptions)
+	return repo.SubscriptionRepository.DeleteSubscription(ctx, discordUserID)
+}
+
+// SaveChannelConfig saves through the wrapped repository and drops the cached channel configs
+func (repo *CachedSubscriptionRepository) SaveChannelConfig(ctx context.Context, config *models.ChannelConfig) error {
+	defer repo.changed(ctx, CacheChannelConfigs)
+	return repo.SubscriptionRepository.SaveChannelConfig(ctx, config)
+}
+
+// DeleteChannelConfig deletes through the wrapped repository and drops the cached channel configs
+func (repo *CachedSubscriptionRepository) DeleteChannelConfig(ctx context.Context, channelID string) (bool, error) {
+	defer repo.changed(ctx, CacheChannelConfigs)
+	return repo.SubscriptionRepository.DeleteChannelConfig(ctx, channelID)
+}
+
+// WithTx runs a transaction on the wrapped repository. Reads in it skip the cache, and the lists its
+// writes change are dropped again once it ends, as another read may have cached them before the commit.
+func (repo *CachedSubscriptionRepository) WithTx(ctx context.Context, fn func(tx SubscriptionRepository) error) error {
+	changes := &cachedTxChanges{kinds: make(map[CacheKind]bool)}
+	defer func() {
+		for kind := range changes.kinds {
+			repo.changed(ctx, kind)
+		}
+	}()
+	return repo.SubscriptionRepository.WithTx(ctx, func(tx SubscriptionRepository) error {
+		return fn(&cachedTx{SubscriptionRepository: tx, cache: repo, changes: changes})
+	})
+}
+
+// cachedTxChanges collects the lists changed in a transaction
+type cachedTxChanges struct {
+	mutex sync.Mutex
+	kinds map[CacheKind]bool
+}
+
+// cachedTx is the repository passed to a transaction run through the cache
+type cachedTx struct {
+	SubscriptionRepository
+	cache   *CachedSubscriptionRepository
+	changes *cachedTxChanges
+}
+
+// changed drops a list changed in the transaction and marks it to be dropped again when it ends
+func (tx *cachedTx) changed(kind CacheKind) {
+	tx.changes.mutex.Lock()
+	tx.changes.kinds[kind] = true
+	tx.changes.mutex.Unlock()
+	tx.cache.Invalidate(kind)
+}
+
+// SaveSubscription saves in the transaction and drops the cached subscriptions
+func (tx *cachedTx) SaveSubscription(ctx context.Context, subscription *models.Subscription) error {
+	defer tx.changed(CacheSubscriptions)
+	return tx.SubscriptionRepository.SaveSubscription(ctx, subscription)
+}
+
+// DeleteSubscription deletes in the transaction and drops the cached subscriptions
+func (tx *cachedTx) DeleteSubscription(ctx context.Context, discordUserID string) error {
+	defer tx.changed(CacheSubscriptions)
+	return tx.SubscriptionRepository.DeleteSubscription(ctx, discordUserID)
+}
+
+// SaveChannelConfig saves in the transaction and drops the cached channel configs
+func (tx *cachedTx) SaveChannelConfig(ctx context.Context, config *models.ChannelConfig) error {
+	defer tx.changed(CacheChannelConfigs)
+	return tx.SubscriptionRepository.SaveChannelConfig(ctx, config)
+}
+
+// DeleteChannelConfig deletes in the transaction and drops the cached channel configs
+func (tx *cachedTx) DeleteChannelConfig(ctx context.Context, channelID string) (bool, error) {
+	defer tx.changed(CacheChannelConfigs)
+	return tx.SubscriptionRepository.DeleteChannelConfig(ctx, channelID)
+}
+
+// WithTx joins the running transaction, still tracking the lists it changes
+func (tx *cachedTx) WithTx(ctx context.Context, fn func(tx SubscriptionRepository) error) error {
+	return tx.SubscriptionRepository.WithTx(ctx, func(inner SubscriptionRepository) error {
+		return fn(&cachedTx{SubscriptionRepository: inner, cache: tx.cache, changes: tx.changes})
+	})
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// categoryRouteKey identifies the route of a category within a guild
+type categoryRouteKey struct {
+	guildID  string
+	category string
+}
+
+// SaveCategoryRoute saves a category route, replacing the guild's previous route for the category
+func (repo *InMemorySubscriptionRepository) SaveCategoryRoute(ctx context.Context, route *models.CategoryRoute) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	copied := *route
+	repo.categoryRoutes[categoryRouteKey{guildID: route.GuildID, category: route.Category}] = &copied
+	return nil
+}
+
+// DeleteCategoryRoute deletes a guild's route for a category and reports whether there was one
+func (repo *InMemorySubscriptionRepository) DeleteCategoryRoute(ctx context.Context, guildID, category string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	key := categoryRouteKey{guildID: guildID, category: category}
+	if _, exists := repo.categoryRoutes[key]; !exists {
+		return false, nil
+	}
+	delete(repo.categoryRoutes, key)
+	return true, nil
+}
+
+// GetCategoryRoutes returns a guild's category routes sorted by category
+func (repo *InMemorySubscriptionRepository) GetCategoryRoutes(ctx context.Context, guildID string) ([]*models.CategoryRoute, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	routes := []*models.CategoryRoute{}
+	for _, route := range repo.categoryRoutes {
+		if route.GuildID == guildID {
+			copied := *route
+			routes = append(routes, &copied)
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Category < routes[j].Category })
+	return routes, nil
+}
+
+// GetAllCategoryRoutes returns the category routes of every guild
+func (repo *InMemorySubscriptionRepository) GetAllCategoryRoutes(ctx context.Context) ([]*models.CategoryRoute, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	routes := make([]*models.CategoryRoute, 0, len(repo.categoryRoutes))
+	for _, route := range repo.categoryRoutes {
+		copied := *route
+		routes = append(routes, &copied)
+	}
+	return routes, nil
+}
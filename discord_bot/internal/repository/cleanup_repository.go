@@ -0,0 +1,31 @@
+package repository
+
+import "context"
+
+// DeleteChannelConfig deletes a channel's configuration, reporting whether it had one
+func (repo *InMemorySubscriptionRepository) DeleteChannelConfig(ctx context.Context, channelID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	_, exists := repo.channels[channelID]
+	delete(repo.channels, channelID)
+	return exists, nil
+}
+
+// DeleteGuildConfig deletes a guild's configuration, reporting whether it had one
+func (repo *InMemorySubscriptionRepository) DeleteGuildConfig(ctx context.Context, guildID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	_, exists := repo.guilds[guildID]
+	delete(repo.guilds, guildID)
+	return exists, nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// closingSoonKey identifies a market announced as closing soon in a channel
+type closingSoonKey struct {
+	channelID string
+	marketID  string
+}
+
+// MarkClosingSoonAnnounced records that a market was announced as closing soon in a channel. It returns
+// false when the market was already announced there, so concurrent scans announce each market once.
+func (repo *InMemorySubscriptionRepository) MarkClosingSoonAnnounced(ctx context.Context, channelID, marketID string, endTime time.Time) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	key := closingSoonKey{channelID: channelID, marketID: marketID}
+	if _, announced := repo.closingSoon[key]; announced {
+		return false, nil
+	}
+	repo.closingSoon[key] = endTime
+	return true, nil
+}
+
+// PruneClosingSoonAnnouncements forgets announcements for markets that closed before a point in time
+func (repo *InMemorySubscriptionRepository) PruneClosingSoonAnnouncements(ctx context.Context, before time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for key, endTime := range repo.closingSoon {
+		if endTime.Before(before) {
+			delete(repo.closingSoon, key)
+		}
+	}
+	return nil
+}
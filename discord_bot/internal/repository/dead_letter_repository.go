@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// SaveDeadLetter saves a dead letter, replacing any dead letter with the same ID
+func (repo *InMemorySubscriptionRepository) SaveDeadLetter(ctx context.Context, letter *models.DeadLetter) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	clone := *letter
+	repo.deadLetters[letter.ID] = &clone
+	return nil
+}
+
+// GetDeadLetter returns a dead letter by ID, or nil when there is none
+func (repo *InMemorySubscriptionRepository) GetDeadLetter(ctx context.Context, id string) (*models.DeadLetter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	letter, exists := repo.deadLetters[id]
+	if !exists {
+		return nil, nil
+	}
+	clone := *letter
+	return &clone, nil
+}
+
+// GetDeadLetters returns every dead letter, oldest first
+func (repo *InMemorySubscriptionRepository) GetDeadLetters(ctx context.Context) ([]*models.DeadLetter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	letters := make([]*models.DeadLetter, 0, len(repo.deadLetters))
+	for _, letter := range repo.deadLetters {
+		clone := *letter
+		letters = append(letters, &clone)
+	}
+	sort.Slice(letters, func(i, j int) bool { return letters[i].CreatedAt.Before(letters[j].CreatedAt) })
+	return letters, nil
+}
+
+// DeleteDeadLetter deletes a dead letter, reporting whether it existed
+func (repo *InMemorySubscriptionRepository) DeleteDeadLetter(ctx context.Context, id string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	_, exists := repo.deadLetters[id]
+	delete(repo.deadLetters, id)
+	return exists, nil
+}
+
+// PruneDeadLetters deletes the exhausted dead letters created before a point in time
+func (repo *InMemorySubscriptionRepository) PruneDeadLetters(ctx context.Context, before time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for id, letter := range repo.deadLetters {
+		if letter.Status == models.DeadLetterExhausted && letter.CreatedAt.Before(before) {
+			delete(repo.deadLetters, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// SaveDeliveryReport saves a delivery report, replacing any report of the same event
+func (repo *InMemorySubscriptionRepository) SaveDeliveryReport(ctx context.Context, report *models.DeliveryReport) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	clone := *report
+	clone.Receipts = append([]models.DeliveryReceipt{}, report.Receipts...)
+	repo.deliveries[report.EventID] = &clone
+	return nil
+}
+
+// AddDeliveryReceipt appends a receipt to an event's delivery report
+func (repo *InMemorySubscriptionRepository) AddDeliveryReceipt(ctx context.Context, eventID string, receipt models.DeliveryReceipt) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	report, exists := repo.deliveries[eventID]
+	if !exists {
+		return fmt.Errorf("no delivery report for event %s", eventID)
+	}
+	report.Receipts = append(report.Receipts, receipt)
+	return nil
+}
+
+// GetDeliveryReport returns an event's delivery report, or nil when there is none
+func (repo *InMemorySubscriptionRepository) GetDeliveryReport(ctx context.Context, eventID string) (*models.DeliveryReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	report, exists := repo.deliveries[eventID]
+	if !exists {
+		return nil, nil
+	}
+	clone := *report
+	clone.Receipts = append([]models.DeliveryReceipt{}, report.Receipts...)
+	return &clone, nil
+}
+
+// PruneDeliveryReports deletes the reports of events created before a point in time
+func (repo *InMemorySubscriptionRepository) PruneDeliveryReports(ctx context.Context, before time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for eventID, report := range repo.deliveries {
+		if report.CreatedAt.Before(before) {
+			delete(repo.deliveries, eventID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,398 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// fileSnapshotVersion is written to every data file so later layouts can tell old files apart
+const fileSnapshotVersion = 1
+
+// FileSubscriptionRepository keeps everything in memory like InMemorySubscriptionRepository and writes
+// it to a JSON file, so small deployments keep their data across restarts without a database. Changes
+// are written by Flush, which Run calls periodically and which should be called once more on shutdown;
+// changes made since the last flush are lost if the process dies.
+type FileSubscriptionRepository struct {
+	*InMemorySubscriptionRepository
+	path        string
+	logger      *utils.Logger
+	lastWritten []byte
+	flushMutex  sync.Mutex
+}
+
+// fileSnapshot is the layout of the data file. Maps keyed by more than one field are stored as lists,
+// since each item holds its own key.
+type fileSnapshot struct {
+	Version        int                                 `json:"version"`
+	SavedAt        time.Time                           `json:"saved_at"`
+	Subscriptions  []*models.Subscription              `json:"subscriptions"`
+	Channels       []*models.ChannelConfig             `json:"channels"`
+	Webhooks       []*models.WebhookRegistration       `json:"webhooks"`
+	Guilds         []*models.GuildConfig               `json:"guilds"`
+	Reminders      []*models.Reminder                  `json:"reminders"`
+	Jobs           []*models.ScheduledJob              `json:"scheduled_jobs"`
+	Analytics      []*models.AnalyticsEvent            `json:"analytics"`
+	Snapshots      []*models.MarketSnapshot            `json:"snapshots"`
+	History        map[string][]*models.MarketSnapshot `json:"history"`
+	Resolved       map[string]time.Time                `json:"resolved"` // resolution time by market ID
+	Audit          []*models.AuditEntry                `json:"audit"`
+	HTTPLog        []*models.HTTPLogEntry              `json:"http_log"`
+	APIKeys        []storedAPIKey                      `json:"api_keys"`
+	ClosingSoon    []closingSoonEntry                  `json:"closing_soon"`
+	Announcements  []marketAnnouncementEntry           `json:"market_announcements"`
+	Outbox         []*models.OutboxItem                `json:"outbox"`
+	Deliveries     []*models.DeliveryReport            `json:"deliveries"`
+	DeadLetters    []*models.DeadLetter                `json:"dead_letters"`
+	BusOffsets     []*models.BusOffset                 `json:"bus_offsets"`
+	CategoryRoutes []*models.CategoryRoute             `json:"category_routes"`
+	RoutingRules   []*models.RoutingRule               `json:"routing_rules"`
+	LimitOverrides []*models.LimitOverride             `json:"limit_overrides"`
+	Watchlists     []*models.Watchlist                 `json:"watchlists"`
+	Shares         []*models.WatchlistShare            `json:"shares"`
+	Held           []*models.HeldNotification          `json:"held"`
+	GameWallets    []*models.GameWallet                `json:"game_wallets"`
+	GameBets       []*models.GameBet                   `json:"game_bets"`
+}
+
+// storedAPIKey keeps an API key's hash, which models.APIKey leaves out of its JSON
+type storedAPIKey struct {
+	*models.APIKey
+	Hash string `json:"hash"`
+}
+
+// closingSoonEntry is a market announced as closing soon in a channel
+type closingSoonEntry struct {
+	ChannelID string    `json:"channel_id"`
+	MarketID  string    `json:"market_id"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// marketAnnouncementEntry is a new market announced in a channel
+type marketAnnouncementEntry struct {
+	ChannelID string    `json:"channel_id"`
+	MarketID  string    `json:"market_id"`
+	At        time.Time `json:"at"`
+}
+
+// NewFileSubscriptionRepository creates a repository persisted to path, loading the data already there.
+// A missing file starts an empty repository; an unreadable one is an error, so a bad file is never
+// silently replaced.
+func NewFileSubscriptionRepository(path string, logger *utils.Logger) (*FileSubscriptionRepository, error) {
+	repo := &FileSubscriptionRepository{
+		InMemorySubscriptionRepository: NewInMemorySubscriptionRepository(),
+		path:                           path,
+		logger:                         logger,
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return repo, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var snapshot fileSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if snapshot.Version > fileSnapshotVersion {
+		return nil, fmt.Errorf("%s was written by a newer version (%d)", path, snapshot.Version)
+	}
+	repo.restore(&snapshot)
+	repo.lastWritten = data
+	return repo, nil
+}
+
+// Flush writes the data to the file if it changed since the last flush. The file is replaced
+// atomically, so a crash while writing leaves the previous version.
+func (repo *FileSubscriptionRepository) Flush() error {
+	repo.flushMutex.Lock()
+	defer repo.flushMutex.Unlock()
+
+	data, err := repo.encode()
+	if err != nil {
+		return fmt.Errorf("failed to encode data: %w", err)
+	}
+	if repo.lastWritten != nil && bytes.Equal(withoutSavedAt(data), withoutSavedAt(repo.lastWritten)) {
+		return nil
+	}
+	if err := writeFileAtomic(repo.path, data); err != nil {
+		return err
+	}
+	repo.lastWritten = data
+	return nil
+}
+
+// Run flushes the data every interval until ctx is done
+func (repo *FileSubscriptionRepository) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := repo.Flush(); err != nil {
+				repo.logger.Error(fmt.Sprintf("Failed to save data to %s: %v", repo.path, err))
+			}
+		}
+	}
+}
+
+// encode returns the data as the file's JSON. Running transactions finish first, so the file never
+// holds half of one.
+func (repo *FileSubscriptionRepository) encode() ([]byte, error) {
+	repo.txMutex.Lock()
+	defer repo.txMutex.Unlock()
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	snapshot := fileSnapshot{
+		Version:       fileSnapshotVersion,
+		SavedAt:       time.Now().UTC(),
+		Subscriptions: mapValues(repo.subscriptions),
+		Channels:      mapValues(repo.channels),
+		Webhooks:      mapValues(repo.webhooks),
+		Guilds:        mapValues(repo.guilds),
+		Reminders:     repo.reminderQueue,
+		Jobs:          mapValues(repo.jobs),
+		Analytics:     repo.analytics,
+		Snapshots:     mapValues(repo.snapshots),
+		History:       repo.history,
+		Resolved:      repo.resolved,
+		Audit:         repo.audit,
+		HTTPLog:       repo.httpLog,
+		Outbox:        mapValues(repo.outbox),
+		Deliveries:    mapValues(repo.deliveries),
+		DeadLetters:   mapValues(repo.deadLetters),
+		Watchlists:    mapValues(repo.watchlists),
+		Shares:        mapValues(repo.shares),
+		GameBets:      mapValues(repo.gameBets),
+	}
+	for _, key := range mapValues(repo.apiKeys) {
+		snapshot.APIKeys = append(snapshot.APIKeys, storedAPIKey{APIKey: key, Hash: key.Hash})
+	}
+	for key, endTime := range repo.closingSoon {
+		snapshot.ClosingSoon = append(snapshot.ClosingSoon, closingSoonEntry{ChannelID: key.channelID, MarketID: key.marketID, EndTime: endTime})
+	}
+	sort.Slice(snapshot.ClosingSoon, func(i, j int) bool {
+		a, b := snapshot.ClosingSoon[i], snapshot.ClosingSoon[j]
+		return a.ChannelID < b.ChannelID || a.ChannelID == b.ChannelID && a.MarketID < b.MarketID
+	})
+	for key, at := range repo.announcements {
+		snapshot.Announcements = append(snapshot.Announcements, marketAnnouncementEntry{ChannelID: key.channelID, MarketID: key.marketID, At: at})
+	}
+	sort.Slice(snapshot.Announcements, func(i, j int) bool {
+		a, b := snapshot.Announcements[i], snapshot.Announcements[j]
+		return a.ChannelID < b.ChannelID || a.ChannelID == b.ChannelID && a.MarketID < b.MarketID
+	})
+	for _, offset := range repo.busOffsets {
+		snapshot.BusOffsets = append(snapshot.BusOffsets, offset)
+	}
+	sort.Slice(snapshot.BusOffsets, func(i, j int) bool {
+		a, b := snapshot.BusOffsets[i], snapshot.BusOffsets[j]
+		return a.Consumer < b.Consumer || a.Consumer == b.Consumer && a.Partition < b.Partition
+	})
+	for _, route := range repo.categoryRoutes {
+		snapshot.CategoryRoutes = append(snapshot.CategoryRoutes, route)
+	}
+	sort.Slice(snapshot.CategoryRoutes, func(i, j int) bool {
+		a, b := snapshot.CategoryRoutes[i], snapshot.CategoryRoutes[j]
+		return a.GuildID < b.GuildID || a.GuildID == b.GuildID && a.Category < b.Category
+	})
+	for _, rule := range repo.routingRules {
+		snapshot.RoutingRules = append(snapshot.RoutingRules, rule)
+	}
+	sortRoutingRules(snapshot.RoutingRules)
+	for _, override := range repo.limitOverrides {
+		snapshot.LimitOverrides = append(snapshot.LimitOverrides, override)
+	}
+	sort.Slice(snapshot.LimitOverrides, func(i, j int) bool {
+		a, b := snapshot.LimitOverrides[i], snapshot.LimitOverrides[j]
+		return a.Subject < b.Subject || a.Subject == b.Subject && a.SubjectID < b.SubjectID
+	})
+	for _, channel := range repo.held {
+		for _, held := range channel {
+			snapshot.Held = append(snapshot.Held, held)
+		}
+	}
+	sort.Slice(snapshot.Held, func(i, j int) bool {
+		a, b := snapshot.Held[i], snapshot.Held[j]
+		if a.ChannelID != b.ChannelID {
+			return a.ChannelID < b.ChannelID
+		}
+		if a.MarketID != b.MarketID {
+			return a.MarketID < b.MarketID
+		}
+		return a.EventType < b.EventType
+	})
+	for _, wallet := range repo.gameWallets {
+		snapshot.GameWallets = append(snapshot.GameWallets, wallet)
+	}
+	sort.Slice(snapshot.GameWallets, func(i, j int) bool {
+		a, b := snapshot.GameWallets[i], snapshot.GameWallets[j]
+		return a.GuildID < b.GuildID || a.GuildID == b.GuildID && a.DiscordUserID < b.DiscordUserID
+	})
+	return json.MarshalIndent(snapshot, "", "  ")
+}
+
+// restore fills the repository from a loaded snapshot
+func (repo *FileSubscriptionRepository) restore(snapshot *fileSnapshot) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for _, subscription := range snapshot.Subscriptions {
+		repo.subscriptions[subscription.DiscordUserID] = subscription
+	}
+	for _, config := range snapshot.Channels {
+		repo.channels[config.ChannelID] = config
+	}
+	for _, registration := range snapshot.Webhooks {
+		repo.webhooks[registration.ID] = registration
+	}
+	for _, guild := range snapshot.Guilds {
+		repo.guilds[guild.GuildID] = guild
+	}
+	for _, reminder := range snapshot.Reminders {
+		repo.reminders[reminder.ID] = reminder
+		repo.reminderQueue = append(repo.reminderQueue, reminder)
+	}
+	for _, job := range snapshot.Jobs {
+		repo.jobs[job.ID] = job
+	}
+	sort.SliceStable(repo.reminderQueue, func(i, j int) bool {
+		return repo.reminderQueue[i].RemindAt.Before(repo.reminderQueue[j].RemindAt)
+	})
+	repo.analytics = snapshot.Analytics
+	for _, marketSnapshot := range snapshot.Snapshots {
+		repo.snapshots[marketSnapshot.MarketID] = marketSnapshot
+	}
+	for marketID, history := range snapshot.History {
+		repo.history[marketID] = history
+	}
+	for marketID, resolvedAt := range snapshot.Resolved {
+		repo.resolved[marketID] = resolvedAt
+	}
+	repo.audit = snapshot.Audit
+	repo.httpLog = snapshot.HTTPLog
+	for _, stored := range snapshot.APIKeys {
+		if stored.APIKey == nil {
+			continue
+		}
+		stored.APIKey.Hash = stored.Hash
+		repo.apiKeys[stored.ID] = stored.APIKey
+	}
+	for _, entry := range snapshot.ClosingSoon {
+		repo.closingSoon[closingSoonKey{channelID: entry.ChannelID, marketID: entry.MarketID}] = entry.EndTime
+	}
+	for _, entry := range snapshot.Announcements {
+		repo.announcements[marketAnnouncementKey{channelID: entry.ChannelID, marketID: entry.MarketID}] = entry.At
+	}
+	for _, item := range snapshot.Outbox {
+		repo.outbox[item.ID] = item
+	}
+	for _, report := range snapshot.Deliveries {
+		repo.deliveries[report.EventID] = report
+	}
+	for _, letter := range snapshot.DeadLetters {
+		repo.deadLetters[letter.ID] = letter
+	}
+	for _, offset := range snapshot.BusOffsets {
+		repo.busOffsets[busOffsetKey{consumer: offset.Consumer, partition: offset.Partition}] = offset
+	}
+	for _, route := range snapshot.CategoryRoutes {
+		repo.categoryRoutes[categoryRouteKey{guildID: route.GuildID, category: route.Category}] = route
+	}
+	for _, rule := range snapshot.RoutingRules {
+		repo.routingRules[rule.ID] = rule
+	}
+	for _, override := range snapshot.LimitOverrides {
+		repo.limitOverrides[limitOverrideKey{subject: override.Subject, subjectID: override.SubjectID}] = override
+	}
+	for _, watchlist := range snapshot.Watchlists {
+		repo.watchlists[watchlist.ID] = watchlist
+	}
+	for _, share := range snapshot.Shares {
+		repo.shares[share.Code] = share
+	}
+	for _, held := range snapshot.Held {
+		channel, exists := repo.held[held.ChannelID]
+		if !exists {
+			channel = make(map[heldNotificationKey]*models.HeldNotification)
+			repo.held[held.ChannelID] = channel
+		}
+		channel[heldNotificationKey{marketID: held.MarketID, eventType: held.EventType}] = held
+	}
+	for _, wallet := range snapshot.GameWallets {
+		repo.gameWallets[gameWalletKey{guildID: wallet.GuildID, discordUserID: wallet.DiscordUserID}] = wallet
+	}
+	for _, bet := range snapshot.GameBets {
+		repo.gameBets[bet.ID] = bet
+	}
+}
+
+// mapValues returns a map's values ordered by key, so unchanged data encodes the same every time
+func mapValues[V any](values map[string]V) []V {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]V, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, values[key])
+	}
+	return result
+}
+
+// withoutSavedAt blanks the saved_at line of an encoded snapshot, so two encodings of the same data compare equal
+func withoutSavedAt(data []byte) []byte {
+	start := bytes.Index(data, []byte(`"saved_at": "`))
+	if start < 0 {
+		return data
+	}
+	end := bytes.IndexByte(data[start:], '\n')
+	if end < 0 {
+		return data
+	}
+	return append(append([]byte{}, data[:start]...), data[start+end:]...)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it over path
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	file, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", file.Name(), err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync %s: %w", file.Name(), err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", file.Name(), err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// gameWalletKey identifies a member's wallet in a guild's game
+type gameWalletKey struct {
+	guildID       string
+	discordUserID string
+}
+
+// SaveGameWallet saves a member's game wallet, replacing the previous version
+func (repo *InMemorySubscriptionRepository) SaveGameWallet(ctx context.Context, wallet *models.GameWallet) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	copied := *wallet
+	repo.gameWallets[gameWalletKey{guildID: wallet.GuildID, discordUserID: wallet.DiscordUserID}] = &copied
+	return nil
+}
+
+// GetGameWallet returns a member's wallet in a guild's game, or nil when they have not played there
+func (repo *InMemorySubscriptionRepository) GetGameWallet(ctx context.Context, guildID, discordUserID string) (*models.GameWallet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	wallet, exists := repo.gameWallets[gameWalletKey{guildID: guildID, discordUserID: discordUserID}]
+	if !exists {
+		return nil, nil
+	}
+	copied := *wallet
+	return &copied, nil
+}
+
+// GetGameWallets returns the wallets of a guild's game
+func (repo *InMemorySubscriptionRepository) GetGameWallets(ctx context.Context, guildID string) ([]*models.GameWallet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	wallets := []*models.GameWallet{}
+	for key, wallet := range repo.gameWallets {
+		if key.guildID == guildID {
+			copied := *wallet
+			wallets = append(wallets, &copied)
+		}
+	}
+	return wallets, nil
+}
+
+// GetGameWalletsByUser returns a user's wallets in every guild they played in, sorted by guild ID
+func (repo *InMemorySubscriptionRepository) GetGameWalletsByUser(ctx context.Context, discordUserID string) ([]*models.GameWallet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	wallets := []*models.GameWallet{}
+	for key, wallet := range repo.gameWallets {
+		if key.discordUserID == discordUserID {
+			copied := *wallet
+			wallets = append(wallets, &copied)
+		}
+	}
+	sort.Slice(wallets, func(i, j int) bool {
+		return wallets[i].GuildID < wallets[j].GuildID
+	})
+	return wallets, nil
+}
+
+// SaveGameBet saves a game bet, replacing the previous version with the same ID
+func (repo *InMemorySubscriptionRepository) SaveGameBet(ctx context.Context, bet *models.GameBet) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	copied := *bet
+	repo.gameBets[bet.ID] = &copied
+	return nil
+}
+
+// GetOpenGameBets returns the bets on a market still waiting for it to resolve, in every guild, oldest
+// first
+func (repo *InMemorySubscriptionRepository) GetOpenGameBets(ctx context.Context, marketID string) ([]*models.GameBet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	var bets []*models.GameBet
+	for _, bet := range repo.gameBets {
+		if bet.MarketID == marketID && bet.Status == models.GameBetOpen {
+			copied := *bet
+			bets = append(bets, &copied)
+		}
+	}
+	sortGameBets(bets)
+	return bets, nil
+}
+
+// GetGameBetsByUser returns a user's bets in every guild, oldest first
+func (repo *InMemorySubscriptionRepository) GetGameBetsByUser(ctx context.Context, discordUserID string) ([]*models.GameBet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	bets := []*models.GameBet{}
+	for _, bet := range repo.gameBets {
+		if bet.DiscordUserID == discordUserID {
+			copied := *bet
+			bets = append(bets, &copied)
+		}
+	}
+	sortGameBets(bets)
+	return bets, nil
+}
+
+// GetGameBetsByGuild returns the bets placed in a guild's game at or after since, oldest first
+func (repo *InMemorySubscriptionRepository) GetGameBetsByGuild(ctx context.Context, guildID string, since time.Time) ([]*models.GameBet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	bets := []*models.GameBet{}
+	for _, bet := range repo.gameBets {
+		if bet.GuildID == guildID && !bet.PlacedAt.Before(since) {
+			copied := *bet
+			bets = append(bets, &copied)
+		}
+	}
+	sortGameBets(bets)
+	return bets, nil
+}
+
+// DeleteGameData deletes a user's wallets and bets in every guild and returns the number of records deleted
+func (repo *InMemorySubscriptionRepository) DeleteGameData(ctx context.Context, discordUserID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	deleted := 0
+	for key := range repo.gameWallets {
+		if key.discordUserID == discordUserID {
+			delete(repo.gameWallets, key)
+			deleted++
+		}
+	}
+	for id, bet := range repo.gameBets {
+		if bet.DiscordUserID == discordUserID {
+			delete(repo.gameBets, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// sortGameBets sorts bets by the time they were placed, breaking ties by ID
+func sortGameBets(bets []*models.GameBet) {
+	sort.Slice(bets, func(i, j int) bool {
+		if !bets[i].PlacedAt.Equal(bets[j].PlacedAt) {
+			return bets[i].PlacedAt.Before(bets[j].PlacedAt)
+		}
+		return bets[i].ID < bets[j].ID
+	})
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// GetGuildConfig retrieves a guild configuration by guild ID, or nil if the guild has not been set up
+func (repo *InMemorySubscriptionRepository) GetGuildConfig(ctx context.Context, guildID string) (*models.GuildConfig, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	config, exists := repo.guilds[guildID]
+	if !exists {
+		return nil, nil
+	}
+	return config, nil
+}
+
+// SaveGuildConfig saves a guild configuration
+func (repo *InMemorySubscriptionRepository) SaveGuildConfig(ctx context.Context, config *models.GuildConfig) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.guilds[config.GuildID] = config
+	return nil
+}
+
+// GetAllGuildConfigs retrieves all guild configurations
+func (repo *InMemorySubscriptionRepository) GetAllGuildConfigs(ctx context.Context) ([]*models.GuildConfig, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	configs := make([]*models.GuildConfig, 0, len(repo.guilds))
+	for _, config := range repo.guilds {
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
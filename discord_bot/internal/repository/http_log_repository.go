@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// SaveHTTPLogEntry appends a logged request. The log is a ring buffer: once it holds capacity entries,
+// the oldest are dropped.
+func (repo *InMemorySubscriptionRepository) SaveHTTPLogEntry(ctx context.Context, entry *models.HTTPLogEntry, capacity int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.httpLog = append(repo.httpLog, entry)
+	if excess := len(repo.httpLog) - capacity; capacity > 0 && excess > 0 {
+		kept := copy(repo.httpLog, repo.httpLog[excess:])
+		clear(repo.httpLog[kept:])
+		repo.httpLog = repo.httpLog[:kept]
+	}
+	return nil
+}
+
+// GetHTTPLogEntries returns the logged requests matching a filter, most recent first
+func (repo *InMemorySubscriptionRepository) GetHTTPLogEntries(ctx context.Context, filter models.HTTPLogFilter) ([]*models.HTTPLogEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	entries := []*models.HTTPLogEntry{}
+	for i := len(repo.httpLog) - 1; i >= 0; i-- {
+		entry := repo.httpLog[i]
+		if filter.Route != "" && entry.Route != filter.Route {
+			continue
+		}
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// ClearHTTPLog deletes every logged request and returns how many there were
+func (repo *InMemorySubscriptionRepository) ClearHTTPLog(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	cleared := len(repo.httpLog)
+	repo.httpLog = nil
+	return cleared, nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// GetTopSubscribedMarkets returns the markets with the most user subscribers, most followed first
+func (repo *InMemorySubscriptionRepository) GetTopSubscribedMarkets(ctx context.Context, limit int) ([]models.LeaderboardEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	counts := make(map[string]int)
+	for _, subscription := range repo.subscriptions {
+		for _, marketID := range subscription.SubscribedMarkets {
+			counts[marketID]++
+		}
+	}
+	return topEntries(counts, limit), nil
+}
+
+// GetTopSubscribedCreators returns the creators with the most user subscribers, most followed first
+func (repo *InMemorySubscriptionRepository) GetTopSubscribedCreators(ctx context.Context, limit int) ([]models.LeaderboardEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	counts := make(map[string]int)
+	for _, subscription := range repo.subscriptions {
+		for _, creator := range subscription.SubscribedCreators {
+			counts[creator]++
+		}
+	}
+	return topEntries(counts, limit), nil
+}
+
+// topEntries ranks counts in descending order, breaking ties by ID, keeping at most limit entries
+func topEntries(counts map[string]int, limit int) []models.LeaderboardEntry {
+	entries := make([]models.LeaderboardEntry, 0, len(counts))
+	for id, count := range counts {
+		entries = append(entries, models.LeaderboardEntry{ID: id, Subscribers: count})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Subscribers != entries[j].Subscribers {
+			return entries[i].Subscribers > entries[j].Subscribers
+		}
+		return entries[i].ID < entries[j].ID
+	})
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// limitOverrideKey identifies the limit override of a user or guild
+type limitOverrideKey struct {
+	subject   string
+	subjectID string
+}
+
+// SaveLimitOverride saves a limit override, replacing the subject's previous one
+func (repo *InMemorySubscriptionRepository) SaveLimitOverride(ctx context.Context, override *models.LimitOverride) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	copied := *override
+	repo.limitOverrides[limitOverrideKey{subject: override.Subject, subjectID: override.SubjectID}] = &copied
+	return nil
+}
+
+// GetLimitOverride returns the limit override of a user or guild, or nil when there is none
+func (repo *InMemorySubscriptionRepository) GetLimitOverride(ctx context.Context, subject, subjectID string) (*models.LimitOverride, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	override, exists := repo.limitOverrides[limitOverrideKey{subject: subject, subjectID: subjectID}]
+	if !exists {
+		return nil, nil
+	}
+	copied := *override
+	return &copied, nil
+}
+
+// GetLimitOverrides returns every limit override, ordered by subject and subject ID
+func (repo *InMemorySubscriptionRepository) GetLimitOverrides(ctx context.Context) ([]*models.LimitOverride, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	overrides := make([]*models.LimitOverride, 0, len(repo.limitOverrides))
+	for _, override := range repo.limitOverrides {
+		copied := *override
+		overrides = append(overrides, &copied)
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		if overrides[i].Subject != overrides[j].Subject {
+			return overrides[i].Subject < overrides[j].Subject
+		}
+		return overrides[i].SubjectID < overrides[j].SubjectID
+	})
+	return overrides, nil
+}
+
+// DeleteLimitOverride deletes the limit override of a user or guild, reporting whether it existed
+func (repo *InMemorySubscriptionRepository) DeleteLimitOverride(ctx context.Context, subject, subjectID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	key := limitOverrideKey{subject: subject, subjectID: subjectID}
+	_, exists := repo.limitOverrides[key]
+	delete(repo.limitOverrides, key)
+	return exists, nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// marketAnnouncementKey identifies a new market announced in a channel
+type marketAnnouncementKey struct {
+	channelID string
+	marketID  string
+}
+
+// MarkMarketAnnounced records that a new market was announced in a channel. It returns false when the
+// market was already announced there.
+func (repo *InMemorySubscriptionRepository) MarkMarketAnnounced(ctx context.Context, channelID, marketID string, at time.Time) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	key := marketAnnouncementKey{channelID: channelID, marketID: marketID}
+	if _, announced := repo.announcements[key]; announced {
+		return false, nil
+	}
+	repo.announcements[key] = at
+	return true, nil
+}
+
+// GetAnnouncedMarkets returns the IDs of the new markets announced in a channel
+func (repo *InMemorySubscriptionRepository) GetAnnouncedMarkets(ctx context.Context, channelID string) (map[string]bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	announced := make(map[string]bool)
+	for key := range repo.announcements {
+		if key.channelID == channelID {
+			announced[key.marketID] = true
+		}
+	}
+	return announced, nil
+}
+
+// PruneMarketAnnouncements forgets the announcements made before a point in time
+func (repo *InMemorySubscriptionRepository) PruneMarketAnnouncements(ctx context.Context, before time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for key, at := range repo.announcements {
+		if at.Before(before) {
+			delete(repo.announcements, key)
+		}
+	}
+	return nil
+}
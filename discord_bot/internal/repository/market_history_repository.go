@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// maxHistoryPerMarket caps the snapshots kept per market; the oldest are dropped first
+const maxHistoryPerMarket = 2000
+
+// AppendMarketHistory appends a snapshot to a market's probability history
+func (repo *InMemorySubscriptionRepository) AppendMarketHistory(ctx context.Context, snapshot *models.MarketSnapshot) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	history := append(repo.history[snapshot.MarketID], snapshot)
+	if len(history) > maxHistoryPerMarket {
+		history = history[len(history)-maxHistoryPerMarket:]
+	}
+	repo.history[snapshot.MarketID] = history
+	return nil
+}
+
+// GetMarketHistory returns a market's snapshots recorded at or after since, oldest first
+func (repo *InMemorySubscriptionRepository) GetMarketHistory(ctx context.Context, marketID string, since time.Time) ([]*models.MarketSnapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	snapshots := []*models.MarketSnapshot{}
+	for _, snapshot := range repo.history[marketID] {
+		if snapshot.Timestamp.Before(since) {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// MarkMarketResolved records that a market resolved. A market already marked keeps its first resolution time.
+func (repo *InMemorySubscriptionRepository) MarkMarketResolved(ctx context.Context, marketID string, resolvedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, resolved := repo.resolved[marketID]; !resolved {
+		repo.resolved[marketID] = resolvedAt
+	}
+	return nil
+}
+
+// IsMarketResolved reports whether a market was marked resolved
+func (repo *InMemorySubscriptionRepository) IsMarketResolved(ctx context.Context, marketID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	_, resolved := repo.resolved[marketID]
+	return resolved, nil
+}
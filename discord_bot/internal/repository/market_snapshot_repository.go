@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// GetMarketSnapshot retrieves the latest snapshot of a market, or nil if none has been recorded
+func (repo *InMemorySubscriptionRepository) GetMarketSnapshot(ctx context.Context, marketID string) (*models.MarketSnapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	snapshot, exists := repo.snapshots[marketID]
+	if !exists {
+		return nil, nil
+	}
+	return snapshot, nil
+}
+
+// SaveMarketSnapshot stores the latest snapshot of a market
+func (repo *InMemorySubscriptionRepository) SaveMarketSnapshot(ctx context.Context, snapshot *models.MarketSnapshot) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.snapshots[snapshot.MarketID] = snapshot
+	return nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// SaveOutboxItem saves an outbox item, replacing any item with the same ID
+func (repo *InMemorySubscriptionRepository) SaveOutboxItem(ctx context.Context, item *models.OutboxItem) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	clone := *item
+	repo.outbox[item.ID] = &clone
+	return nil
+}
+
+// GetPendingOutboxItems returns the undelivered items created before a point in time, oldest first
+func (repo *InMemorySubscriptionRepository) GetPendingOutboxItems(ctx context.Context, before time.Time) ([]*models.OutboxItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	var items []*models.OutboxItem
+	for _, item := range repo.outbox {
+		if item.Status == models.OutboxPending && item.CreatedAt.Before(before) {
+			clone := *item
+			items = append(items, &clone)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	return items, nil
+}
+
+// PruneOutboxItems deletes the items delivered, or created and given up, before a point in time
+func (repo *InMemorySubscriptionRepository) PruneOutboxItems(ctx context.Context, before time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for id, item := range repo.outbox {
+		switch {
+		case item.Status == models.OutboxDelivered && item.DeliveredAt.Before(before),
+			item.Status == models.OutboxFailed && item.CreatedAt.Before(before):
+			delete(repo.outbox, id)
+		}
+	}
+	return nil
+}
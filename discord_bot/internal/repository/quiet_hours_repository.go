@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// heldNotificationKey identifies the held events of one type about one market in a channel
+type heldNotificationKey struct {
+	marketID  string
+	eventType string
+}
+
+// AddHeldNotification adds held events to a channel's count for their market and event type, keeping
+// the first time one was held and the latest title
+func (repo *InMemorySubscriptionRepository) AddHeldNotification(ctx context.Context, held *models.HeldNotification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	channel, exists := repo.held[held.ChannelID]
+	if !exists {
+		channel = make(map[heldNotificationKey]*models.HeldNotification)
+		repo.held[held.ChannelID] = channel
+	}
+	key := heldNotificationKey{marketID: held.MarketID, eventType: held.EventType}
+	existing, exists := channel[key]
+	if !exists {
+		copied := *held
+		channel[key] = &copied
+		return nil
+	}
+	existing.Count += held.Count
+	existing.MarketTitle = held.MarketTitle
+	existing.LastHeldAt = held.LastHeldAt
+	return nil
+}
+
+// GetHeldNotifications returns the events held for a channel, in the order they were first held
+func (repo *InMemorySubscriptionRepository) GetHeldNotifications(ctx context.Context, channelID string) ([]*models.HeldNotification, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	held := make([]*models.HeldNotification, 0, len(repo.held[channelID]))
+	for _, notification := range repo.held[channelID] {
+		copied := *notification
+		held = append(held, &copied)
+	}
+	sort.Slice(held, func(i, j int) bool {
+		return held[i].FirstHeldAt.Before(held[j].FirstHeldAt)
+	})
+	return held, nil
+}
+
+// DeleteHeldNotifications deletes the events held for a channel and returns how many counts it held
+func (repo *InMemorySubscriptionRepository) DeleteHeldNotifications(ctx context.Context, channelID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	deleted := len(repo.held[channelID])
+	delete(repo.held, channelID)
+	return deleted, nil
+}
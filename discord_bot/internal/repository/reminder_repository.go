@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// SaveReminder stores or updates a reminder, keeping the queue ordered by fire time
+func (repo *InMemorySubscriptionRepository) SaveReminder(ctx context.Context, reminder *models.Reminder) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, exists := repo.reminders[reminder.ID]; exists {
+		repo.removeQueuedReminder(reminder.ID)
+	}
+	repo.reminders[reminder.ID] = reminder
+
+	index := sort.Search(len(repo.reminderQueue), func(i int) bool {
+		return repo.reminderQueue[i].RemindAt.After(reminder.RemindAt)
+	})
+	repo.reminderQueue = append(repo.reminderQueue, nil)
+	copy(repo.reminderQueue[index+1:], repo.reminderQueue[index:])
+	repo.reminderQueue[index] = reminder
+	return nil
+}
+
+// DeleteReminder deletes a reminder by id
+func (repo *InMemorySubscriptionRepository) DeleteReminder(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, exists := repo.reminders[id]; !exists {
+		return nil
+	}
+	delete(repo.reminders, id)
+	repo.removeQueuedReminder(id)
+	return nil
+}
+
+// GetRemindersByUser returns the pending reminders of a user ordered by fire time
+func (repo *InMemorySubscriptionRepository) GetRemindersByUser(ctx context.Context, discordUserID string) ([]*models.Reminder, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	reminders := []*models.Reminder{}
+	for _, reminder := range repo.reminderQueue {
+		if reminder.DiscordUserID == discordUserID {
+			reminders = append(reminders, reminder)
+		}
+	}
+	return reminders, nil
+}
+
+// GetReminder returns a pending reminder by ID, or nil when there is none
+func (repo *InMemorySubscriptionRepository) GetReminder(ctx context.Context, id string) (*models.Reminder, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	return repo.reminders[id], nil
+}
+
+// GetDueReminders returns all reminders whose fire time is at or before now
+func (repo *InMemorySubscriptionRepository) GetDueReminders(ctx context.Context, now time.Time) ([]*models.Reminder, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	due := []*models.Reminder{}
+	for _, reminder := range repo.reminderQueue {
+		if reminder.RemindAt.After(now) {
+			break
+		}
+		due = append(due, reminder)
+	}
+	return due, nil
+}
+
+// DeleteRemindersByMarket deletes every reminder for a market and returns how many were deleted
+func (repo *InMemorySubscriptionRepository) DeleteRemindersByMarket(ctx context.Context, marketID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	kept := repo.reminderQueue[:0]
+	for _, reminder := range repo.reminderQueue {
+		if reminder.MarketID == marketID {
+			delete(repo.reminders, reminder.ID)
+			continue
+		}
+		kept = append(kept, reminder)
+	}
+	deleted := len(repo.reminderQueue) - len(kept)
+	repo.reminderQueue = kept
+	return deleted, nil
+}
+
+// removeQueuedReminder drops a reminder from the ordered queue; callers must hold the write lock
+func (repo *InMemorySubscriptionRepository) removeQueuedReminder(id string) {
+	for i, reminder := range repo.reminderQueue {
+		if reminder.ID == id {
+			repo.reminderQueue = append(repo.reminderQueue[:i], repo.reminderQueue[i+1:]...)
+			return
+		}
+	}
+}
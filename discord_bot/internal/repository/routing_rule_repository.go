@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// SaveRoutingRule saves a routing rule, replacing the previous version with the same ID
+func (repo *InMemorySubscriptionRepository) SaveRoutingRule(ctx context.Context, rule *models.RoutingRule) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.routingRules[rule.ID] = rule.Clone()
+	return nil
+}
+
+// DeleteRoutingRule deletes a routing rule and reports whether there was one
+func (repo *InMemorySubscriptionRepository) DeleteRoutingRule(ctx context.Context, id string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, exists := repo.routingRules[id]; !exists {
+		return false, nil
+	}
+	delete(repo.routingRules, id)
+	return true, nil
+}
+
+// GetRoutingRules returns a guild's routing rules in the order they are evaluated
+func (repo *InMemorySubscriptionRepository) GetRoutingRules(ctx context.Context, guildID string) ([]*models.RoutingRule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	rules := []*models.RoutingRule{}
+	for _, rule := range repo.routingRules {
+		if rule.GuildID == guildID {
+			rules = append(rules, rule.Clone())
+		}
+	}
+	sortRoutingRules(rules)
+	return rules, nil
+}
+
+// GetAllRoutingRules returns the routing rules of every guild, sorted by guild and then in the order
+// they are evaluated
+func (repo *InMemorySubscriptionRepository) GetAllRoutingRules(ctx context.Context) ([]*models.RoutingRule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	rules := make([]*models.RoutingRule, 0, len(repo.routingRules))
+	for _, rule := range repo.routingRules {
+		rules = append(rules, rule.Clone())
+	}
+	sortRoutingRules(rules)
+	return rules, nil
+}
+
+// sortRoutingRules sorts rules by guild, then by position, breaking ties by ID
+func sortRoutingRules(rules []*models.RoutingRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].GuildID != rules[j].GuildID {
+			return rules[i].GuildID < rules[j].GuildID
+		}
+		if rules[i].Position != rules[j].Position {
+			return rules[i].Position < rules[j].Position
+		}
+		return rules[i].ID < rules[j].ID
+	})
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// SaveScheduledJob saves a scheduled job, replacing any job with the same ID
+func (repo *InMemorySubscriptionRepository) SaveScheduledJob(ctx context.Context, job *models.ScheduledJob) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	clone := *job
+	repo.jobs[job.ID] = &clone
+	return nil
+}
+
+// GetScheduledJob returns a scheduled job by ID, or nil when there is none
+func (repo *InMemorySubscriptionRepository) GetScheduledJob(ctx context.Context, id string) (*models.ScheduledJob, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	job, exists := repo.jobs[id]
+	if !exists {
+		return nil, nil
+	}
+	clone := *job
+	return &clone, nil
+}
+
+// GetScheduledJobs returns every scheduled job, soonest first
+func (repo *InMemorySubscriptionRepository) GetScheduledJobs(ctx context.Context) ([]*models.ScheduledJob, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	jobs := []*models.ScheduledJob{}
+	for _, job := range repo.jobs {
+		clone := *job
+		jobs = append(jobs, &clone)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].RunAt.Equal(jobs[j].RunAt) {
+			return jobs[i].RunAt.Before(jobs[j].RunAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs, nil
+}
+
+// DeleteScheduledJob deletes a scheduled job and reports whether it existed
+func (repo *InMemorySubscriptionRepository) DeleteScheduledJob(ctx context.Context, id string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, exists := repo.jobs[id]; !exists {
+		return false, nil
+	}
+	delete(repo.jobs, id)
+	return true, nil
+}
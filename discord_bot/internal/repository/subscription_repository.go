@@ -1,49 +1,251 @@
 package repository
 
 import (
+	"context"
 	"sync"
+	"time"
 
 	"coral-bot/discord_bot/internal/models"
 )
 
 // SubscriptionRepository defines the interface for subscription data operations
 type SubscriptionRepository interface {
-	GetSubscription(discordUserID string) (*models.Subscription, error)
-	SaveSubscription(subscription *models.Subscription) error
-	DeleteSubscription(discordUserID string) error
-	GetAllSubscriptions() ([]*models.Subscription, error)
+	GetSubscription(ctx context.Context, discordUserID string) (*models.Subscription, error)
+	SaveSubscription(ctx context.Context, subscription *models.Subscription) error
+	DeleteSubscription(ctx context.Context, discordUserID string) error
+	GetAllSubscriptions(ctx context.Context) ([]*models.Subscription, error)
 
-	GetChannelConfig(channelID string) (*models.ChannelConfig, error)
-	SaveChannelConfig(config *models.ChannelConfig) error
-	GetAllChannelConfigs() ([]*models.ChannelConfig, error)
+	GetChannelConfig(ctx context.Context, channelID string) (*models.ChannelConfig, error)
+	HasChannelConfig(ctx context.Context, channelID string) (bool, error)
+	SaveChannelConfig(ctx context.Context, config *models.ChannelConfig) error
+	GetAllChannelConfigs(ctx context.Context) ([]*models.ChannelConfig, error)
+	DeleteChannelConfig(ctx context.Context, channelID string) (bool, error)
 
 	// Webhook registration methods
-	SaveWebhookRegistration(registration *models.WebhookRegistration) error
-	GetWebhookRegistration(id string) (*models.WebhookRegistration, error)
-	DeleteWebhookRegistration(id string) error
-	GetAllWebhookRegistrations() ([]*models.WebhookRegistration, error)
-	GetWebhookRegistrationsByChannel(channelID string) ([]*models.WebhookRegistration, error)
+	SaveWebhookRegistration(ctx context.Context, registration *models.WebhookRegistration) error
+	GetWebhookRegistration(ctx context.Context, id string) (*models.WebhookRegistration, error)
+	DeleteWebhookRegistration(ctx context.Context, id string) error
+	GetAllWebhookRegistrations(ctx context.Context) ([]*models.WebhookRegistration, error)
+	GetWebhookRegistrationsByChannel(ctx context.Context, channelID string) ([]*models.WebhookRegistration, error)
+
+	// Audit log methods
+	SaveAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+	GetAuditEntries(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEntry, error)
+	RedactAuditActor(ctx context.Context, actor, replacement string) (int, error)
+
+	// API key methods
+	SaveAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKey(ctx context.Context, id string) (*models.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	GetAllAPIKeys(ctx context.Context) ([]*models.APIKey, error)
+
+	// Guild configuration methods
+	GetGuildConfig(ctx context.Context, guildID string) (*models.GuildConfig, error)
+	SaveGuildConfig(ctx context.Context, config *models.GuildConfig) error
+	GetAllGuildConfigs(ctx context.Context) ([]*models.GuildConfig, error)
+	DeleteGuildConfig(ctx context.Context, guildID string) (bool, error)
+
+	// Category routing methods
+	SaveCategoryRoute(ctx context.Context, route *models.CategoryRoute) error
+	DeleteCategoryRoute(ctx context.Context, guildID, category string) (bool, error)
+	GetCategoryRoutes(ctx context.Context, guildID string) ([]*models.CategoryRoute, error)
+	GetAllCategoryRoutes(ctx context.Context) ([]*models.CategoryRoute, error)
+
+	// Routing rule methods
+	SaveRoutingRule(ctx context.Context, rule *models.RoutingRule) error
+	DeleteRoutingRule(ctx context.Context, id string) (bool, error)
+	GetRoutingRules(ctx context.Context, guildID string) ([]*models.RoutingRule, error)
+	GetAllRoutingRules(ctx context.Context) ([]*models.RoutingRule, error)
+
+	// Subscription limit override methods
+	SaveLimitOverride(ctx context.Context, override *models.LimitOverride) error
+	GetLimitOverride(ctx context.Context, subject, subjectID string) (*models.LimitOverride, error)
+	GetLimitOverrides(ctx context.Context) ([]*models.LimitOverride, error)
+	DeleteLimitOverride(ctx context.Context, subject, subjectID string) (bool, error)
+
+	// Watchlist methods
+	SaveWatchlist(ctx context.Context, watchlist *models.Watchlist) error
+	GetWatchlist(ctx context.Context, id string) (*models.Watchlist, error)
+	GetWatchlistsByUser(ctx context.Context, discordUserID string) ([]*models.Watchlist, error)
+	GetAllWatchlists(ctx context.Context) ([]*models.Watchlist, error)
+	DeleteWatchlist(ctx context.Context, id string) (bool, error)
+	SaveWatchlistShare(ctx context.Context, share *models.WatchlistShare) error
+	GetWatchlistShare(ctx context.Context, code string) (*models.WatchlistShare, error)
+	GetWatchlistShareByWatchlist(ctx context.Context, watchlistID string) (*models.WatchlistShare, error)
+
+	// Quiet hours methods
+	AddHeldNotification(ctx context.Context, held *models.HeldNotification) error
+	GetHeldNotifications(ctx context.Context, channelID string) ([]*models.HeldNotification, error)
+	DeleteHeldNotifications(ctx context.Context, channelID string) (int, error)
+
+	// Transactions: fn's calls on tx are committed together when it returns nil and rolled back when it
+	// returns an error. A WithTx called on tx joins the running transaction.
+	WithTx(ctx context.Context, fn func(tx SubscriptionRepository) error) error
+
+	// Reminder methods
+	SaveReminder(ctx context.Context, reminder *models.Reminder) error
+	DeleteReminder(ctx context.Context, id string) error
+	GetRemindersByUser(ctx context.Context, discordUserID string) ([]*models.Reminder, error)
+	GetDueReminders(ctx context.Context, now time.Time) ([]*models.Reminder, error)
+	DeleteRemindersByMarket(ctx context.Context, marketID string) (int, error)
+	GetReminder(ctx context.Context, id string) (*models.Reminder, error)
+
+	// Scheduled job methods
+	SaveScheduledJob(ctx context.Context, job *models.ScheduledJob) error
+	GetScheduledJob(ctx context.Context, id string) (*models.ScheduledJob, error)
+	GetScheduledJobs(ctx context.Context) ([]*models.ScheduledJob, error)
+	DeleteScheduledJob(ctx context.Context, id string) (bool, error)
+
+	// Closing-soon announcement methods
+	MarkClosingSoonAnnounced(ctx context.Context, channelID, marketID string, endTime time.Time) (bool, error)
+	PruneClosingSoonAnnouncements(ctx context.Context, before time.Time) error
+
+	// New market announcement methods
+	MarkMarketAnnounced(ctx context.Context, channelID, marketID string, at time.Time) (bool, error)
+	GetAnnouncedMarkets(ctx context.Context, channelID string) (map[string]bool, error)
+	PruneMarketAnnouncements(ctx context.Context, before time.Time) error
+
+	// Notification outbox methods
+	SaveOutboxItem(ctx context.Context, item *models.OutboxItem) error
+	GetPendingOutboxItems(ctx context.Context, before time.Time) ([]*models.OutboxItem, error)
+	PruneOutboxItems(ctx context.Context, before time.Time) error
+
+	// HTTP request log methods
+	SaveHTTPLogEntry(ctx context.Context, entry *models.HTTPLogEntry, capacity int) error
+	GetHTTPLogEntries(ctx context.Context, filter models.HTTPLogFilter) ([]*models.HTTPLogEntry, error)
+	ClearHTTPLog(ctx context.Context) (int, error)
+
+	// Delivery report methods
+	SaveDeliveryReport(ctx context.Context, report *models.DeliveryReport) error
+	AddDeliveryReceipt(ctx context.Context, eventID string, receipt models.DeliveryReceipt) error
+	GetDeliveryReport(ctx context.Context, eventID string) (*models.DeliveryReport, error)
+	PruneDeliveryReports(ctx context.Context, before time.Time) error
+
+	// Dead letter methods
+	SaveDeadLetter(ctx context.Context, letter *models.DeadLetter) error
+	GetDeadLetter(ctx context.Context, id string) (*models.DeadLetter, error)
+	GetDeadLetters(ctx context.Context) ([]*models.DeadLetter, error)
+	DeleteDeadLetter(ctx context.Context, id string) (bool, error)
+	PruneDeadLetters(ctx context.Context, before time.Time) error
+
+	// Message bus consumer offset methods
+	SaveBusOffset(ctx context.Context, offset *models.BusOffset) error
+	GetBusOffset(ctx context.Context, consumer string, partition int32) (*models.BusOffset, error)
+
+	// Analytics methods
+	SaveAnalyticsEvent(ctx context.Context, event *models.AnalyticsEvent) error
+	GetAnalyticsEvents(ctx context.Context, from, to time.Time) ([]*models.AnalyticsEvent, error)
+	GetAnalyticsEventsBySubject(ctx context.Context, subject string) ([]*models.AnalyticsEvent, error)
+	DeleteAnalyticsEventsBySubject(ctx context.Context, subject string) (int, error)
+	PruneAnalyticsEvents(ctx context.Context, before time.Time) error
+
+	// Market snapshot methods
+	GetMarketSnapshot(ctx context.Context, marketID string) (*models.MarketSnapshot, error)
+	SaveMarketSnapshot(ctx context.Context, snapshot *models.MarketSnapshot) error
+
+	// Market resolution methods
+	MarkMarketResolved(ctx context.Context, marketID string, resolvedAt time.Time) error
+	IsMarketResolved(ctx context.Context, marketID string) (bool, error)
+
+	// Market history methods
+	AppendMarketHistory(ctx context.Context, snapshot *models.MarketSnapshot) error
+	GetMarketHistory(ctx context.Context, marketID string, since time.Time) ([]*models.MarketSnapshot, error)
+
+	// Leaderboard methods
+	GetTopSubscribedMarkets(ctx context.Context, limit int) ([]models.LeaderboardEntry, error)
+	GetTopSubscribedCreators(ctx context.Context, limit int) ([]models.LeaderboardEntry, error)
+
+	// Game ledger methods
+	SaveGameWallet(ctx context.Context, wallet *models.GameWallet) error
+	GetGameWallet(ctx context.Context, guildID, discordUserID string) (*models.GameWallet, error)
+	GetGameWallets(ctx context.Context, guildID string) ([]*models.GameWallet, error)
+	GetGameWalletsByUser(ctx context.Context, discordUserID string) ([]*models.GameWallet, error)
+	SaveGameBet(ctx context.Context, bet *models.GameBet) error
+	GetOpenGameBets(ctx context.Context, marketID string) ([]*models.GameBet, error)
+	GetGameBetsByUser(ctx context.Context, discordUserID string) ([]*models.GameBet, error)
+	GetGameBetsByGuild(ctx context.Context, guildID string, since time.Time) ([]*models.GameBet, error)
+	DeleteGameData(ctx context.Context, discordUserID string) (int, error)
 }
 
 // InMemorySubscriptionRepository implements SubscriptionRepository using in-memory storage
 type InMemorySubscriptionRepository struct {
-    subscriptions map[string]*models.Subscription
-    channels      map[string]*models.ChannelConfig
-    webhooks      map[string]*models.WebhookRegistration
-    mutex         sync.RWMutex
+    inMemoryData
+    mutex   *sync.RWMutex // held for writing while a transaction runs, so it sees no other changes
+    txMutex *sync.Mutex   // held while a transaction runs
+}
+
+// inMemoryData is the data of an InMemorySubscriptionRepository, apart from its locks
+type inMemoryData struct {
+    subscriptions  map[string]*models.Subscription
+    channels       map[string]*models.ChannelConfig
+    webhooks       map[string]*models.WebhookRegistration
+    guilds         map[string]*models.GuildConfig
+    reminders      map[string]*models.Reminder
+    reminderQueue  []*models.Reminder // sorted by RemindAt
+    jobs           map[string]*models.ScheduledJob
+    analytics      []*models.AnalyticsEvent
+    snapshots      map[string]*models.MarketSnapshot
+    history        map[string][]*models.MarketSnapshot
+    resolved       map[string]time.Time // resolution time by market ID
+    audit          []*models.AuditEntry
+    httpLog        []*models.HTTPLogEntry // oldest first, at most the capacity it was last saved with
+    apiKeys        map[string]*models.APIKey
+    closingSoon    map[closingSoonKey]time.Time // market end time by channel and market
+    announcements  map[marketAnnouncementKey]time.Time // when each new market was announced, by channel and market
+    outbox         map[string]*models.OutboxItem
+    deliveries     map[string]*models.DeliveryReport // by event ID
+    deadLetters    map[string]*models.DeadLetter
+    busOffsets     map[busOffsetKey]*models.BusOffset
+    categoryRoutes map[categoryRouteKey]*models.CategoryRoute
+    routingRules   map[string]*models.RoutingRule
+    limitOverrides map[limitOverrideKey]*models.LimitOverride
+    watchlists     map[string]*models.Watchlist
+    shares         map[string]*models.WatchlistShare // by code
+    held           map[string]map[heldNotificationKey]*models.HeldNotification // by channel ID
+    gameWallets    map[gameWalletKey]*models.GameWallet
+    gameBets       map[string]*models.GameBet
 }
 
 // NewInMemorySubscriptionRepository creates a new in-memory subscription repository
 func NewInMemorySubscriptionRepository() *InMemorySubscriptionRepository {
 	return &InMemorySubscriptionRepository{
-		subscriptions: make(map[string]*models.Subscription),
-		channels:      make(map[string]*models.ChannelConfig),
-		webhooks:      make(map[string]*models.WebhookRegistration),
+		inMemoryData: inMemoryData{
+			subscriptions:  make(map[string]*models.Subscription),
+			channels:       make(map[string]*models.ChannelConfig),
+			webhooks:       make(map[string]*models.WebhookRegistration),
+			guilds:         make(map[string]*models.GuildConfig),
+			reminders:      make(map[string]*models.Reminder),
+			jobs:           make(map[string]*models.ScheduledJob),
+			snapshots:      make(map[string]*models.MarketSnapshot),
+			history:        make(map[string][]*models.MarketSnapshot),
+			resolved:       make(map[string]time.Time),
+			apiKeys:        make(map[string]*models.APIKey),
+			closingSoon:    make(map[closingSoonKey]time.Time),
+			announcements:  make(map[marketAnnouncementKey]time.Time),
+			outbox:         make(map[string]*models.OutboxItem),
+			deliveries:     make(map[string]*models.DeliveryReport),
+			deadLetters:    make(map[string]*models.DeadLetter),
+			busOffsets:     make(map[busOffsetKey]*models.BusOffset),
+			categoryRoutes: make(map[categoryRouteKey]*models.CategoryRoute),
+			routingRules:   make(map[string]*models.RoutingRule),
+			limitOverrides: make(map[limitOverrideKey]*models.LimitOverride),
+			watchlists:     make(map[string]*models.Watchlist),
+			shares:         make(map[string]*models.WatchlistShare),
+			held:           make(map[string]map[heldNotificationKey]*models.HeldNotification),
+			gameWallets:    make(map[gameWalletKey]*models.GameWallet),
+			gameBets:       make(map[string]*models.GameBet),
+		},
+		mutex:   &sync.RWMutex{},
+		txMutex: &sync.Mutex{},
 	}
 }
 
 // GetSubscription retrieves a subscription by Discord user ID
-func (repo *InMemorySubscriptionRepository) GetSubscription(discordUserID string) (*models.Subscription, error) {
+func (repo *InMemorySubscriptionRepository) GetSubscription(ctx context.Context, discordUserID string) (*models.Subscription, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
     repo.mutex.RLock()
     defer repo.mutex.RUnlock()
 
@@ -54,6 +256,7 @@ func (repo *InMemorySubscriptionRepository) GetSubscription(discordUserID string
 			DiscordUserID:      discordUserID,
 			SubscribedMarkets:  []string{},
 			SubscribedCreators: []string{},
+			SubscribedOutcomes: []models.OutcomeSubscription{},
 		}, nil
 	}
 
@@ -61,7 +264,11 @@ func (repo *InMemorySubscriptionRepository) GetSubscription(discordUserID string
 }
 
 // SaveSubscription saves a subscription
-func (repo *InMemorySubscriptionRepository) SaveSubscription(subscription *models.Subscription) error {
+func (repo *InMemorySubscriptionRepository) SaveSubscription(ctx context.Context, subscription *models.Subscription) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
     repo.mutex.Lock()
     defer repo.mutex.Unlock()
 
@@ -70,7 +277,11 @@ func (repo *InMemorySubscriptionRepository) SaveSubscription(subscription *model
 }
 
 // DeleteSubscription deletes a subscription
-func (repo *InMemorySubscriptionRepository) DeleteSubscription(discordUserID string) error {
+func (repo *InMemorySubscriptionRepository) DeleteSubscription(ctx context.Context, discordUserID string) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
     repo.mutex.Lock()
     defer repo.mutex.Unlock()
 
@@ -79,7 +290,11 @@ func (repo *InMemorySubscriptionRepository) DeleteSubscription(discordUserID str
 }
 
 // GetAllSubscriptions retrieves all subscriptions
-func (repo *InMemorySubscriptionRepository) GetAllSubscriptions() ([]*models.Subscription, error) {
+func (repo *InMemorySubscriptionRepository) GetAllSubscriptions(ctx context.Context) ([]*models.Subscription, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
     repo.mutex.RLock()
     defer repo.mutex.RUnlock()
 
@@ -92,7 +307,11 @@ func (repo *InMemorySubscriptionRepository) GetAllSubscriptions() ([]*models.Sub
 }
 
 // GetChannelConfig retrieves a channel configuration by channel ID
-func (repo *InMemorySubscriptionRepository) GetChannelConfig(channelID string) (*models.ChannelConfig, error) {
+func (repo *InMemorySubscriptionRepository) GetChannelConfig(ctx context.Context, channelID string) (*models.ChannelConfig, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
     repo.mutex.RLock()
     defer repo.mutex.RUnlock()
 
@@ -104,24 +323,49 @@ func (repo *InMemorySubscriptionRepository) GetChannelConfig(channelID string) (
 			FeedEnabled:         true,
 			AllowedCategories:   []string{},
 			FrequencyMode:       "medium",
+			SubscribedMarkets:   []string{},
+			ClosingSoonHours:    models.DefaultClosingSoonHours,
 			LastUpdateTimestamp: models.ChannelConfig{}.LastUpdateTimestamp,
 		}, nil
 	}
 
-    return config, nil
+    // Return a copy so callers can change it without affecting the stored config until saved
+    return config.Clone(), nil
+}
+
+// HasChannelConfig reports whether a channel has a saved configuration, rather than the default one
+// GetChannelConfig returns for it
+func (repo *InMemorySubscriptionRepository) HasChannelConfig(ctx context.Context, channelID string) (bool, error) {
+    if err := ctx.Err(); err != nil {
+        return false, err
+    }
+
+    repo.mutex.RLock()
+    defer repo.mutex.RUnlock()
+
+    _, exists := repo.channels[channelID]
+    return exists, nil
 }
 
 // SaveChannelConfig saves a channel configuration
-func (repo *InMemorySubscriptionRepository) SaveChannelConfig(config *models.ChannelConfig) error {
+func (repo *InMemorySubscriptionRepository) SaveChannelConfig(ctx context.Context, config *models.ChannelConfig) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
     repo.mutex.Lock()
     defer repo.mutex.Unlock()
 
-    repo.channels[config.ChannelID] = config
+    repo.channels[config.ChannelID] = config.Clone()
     return nil
 }
 
 // GetAllChannelConfigs retrieves all channel configurations
-func (repo *InMemorySubscriptionRepository) GetAllChannelConfigs() ([]*models.ChannelConfig, error) {
+func (repo *InMemorySubscriptionRepository) GetAllChannelConfigs(ctx context.Context) ([]*models.ChannelConfig, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
     repo.mutex.RLock()
     defer repo.mutex.RUnlock()
 
@@ -134,16 +378,24 @@ func (repo *InMemorySubscriptionRepository) GetAllChannelConfigs() ([]*models.Ch
 }
 
 // SaveWebhookRegistration stores or updates a webhook registration
-func (repo *InMemorySubscriptionRepository) SaveWebhookRegistration(registration *models.WebhookRegistration) error {
+func (repo *InMemorySubscriptionRepository) SaveWebhookRegistration(ctx context.Context, registration *models.WebhookRegistration) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
     repo.mutex.Lock()
     defer repo.mutex.Unlock()
 
-    repo.webhooks[registration.ID] = registration
+    repo.webhooks[registration.ID] = registration.Clone()
     return nil
 }
 
 // GetWebhookRegistration retrieves a webhook registration by id
-func (repo *InMemorySubscriptionRepository) GetWebhookRegistration(id string) (*models.WebhookRegistration, error) {
+func (repo *InMemorySubscriptionRepository) GetWebhookRegistration(ctx context.Context, id string) (*models.WebhookRegistration, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
     repo.mutex.RLock()
     defer repo.mutex.RUnlock()
 
@@ -151,11 +403,15 @@ func (repo *InMemorySubscriptionRepository) GetWebhookRegistration(id string) (*
 	if !exists {
 		return nil, nil
 	}
-	return reg, nil
+	return reg.Clone(), nil
 }
 
 // DeleteWebhookRegistration deletes a webhook registration by id
-func (repo *InMemorySubscriptionRepository) DeleteWebhookRegistration(id string) error {
+func (repo *InMemorySubscriptionRepository) DeleteWebhookRegistration(ctx context.Context, id string) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
     repo.mutex.Lock()
     defer repo.mutex.Unlock()
 
@@ -164,7 +420,11 @@ func (repo *InMemorySubscriptionRepository) DeleteWebhookRegistration(id string)
 }
 
 // GetAllWebhookRegistrations returns all webhook registrations
-func (repo *InMemorySubscriptionRepository) GetAllWebhookRegistrations() ([]*models.WebhookRegistration, error) {
+func (repo *InMemorySubscriptionRepository) GetAllWebhookRegistrations(ctx context.Context) ([]*models.WebhookRegistration, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
     repo.mutex.RLock()
     defer repo.mutex.RUnlock()
 
@@ -177,7 +437,11 @@ func (repo *InMemorySubscriptionRepository) GetAllWebhookRegistrations() ([]*mod
 }
 
 // GetWebhookRegistrationsByChannel returns registrations for a specific channel
-func (repo *InMemorySubscriptionRepository) GetWebhookRegistrationsByChannel(channelID string) ([]*models.WebhookRegistration, error) {
+func (repo *InMemorySubscriptionRepository) GetWebhookRegistrationsByChannel(ctx context.Context, channelID string) ([]*models.WebhookRegistration, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
     repo.mutex.RLock()
     defer repo.mutex.RUnlock()
 
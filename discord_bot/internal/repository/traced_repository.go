@@ -0,0 +1,845 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/tracing"
+)
+
+// TracedSubscriptionRepository wraps a SubscriptionRepository with a span around every call
+type TracedSubscriptionRepository struct {
+	next SubscriptionRepository
+}
+
+// NewTracedSubscriptionRepository wraps a repository so each call is recorded as a span
+func NewTracedSubscriptionRepository(next SubscriptionRepository) *TracedSubscriptionRepository {
+	return &TracedSubscriptionRepository{next: next}
+}
+
+// GetSubscription traces the wrapped repository's GetSubscription
+func (repo *TracedSubscriptionRepository) GetSubscription(ctx context.Context, discordUserID string) (*models.Subscription, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetSubscription")
+	result, err := repo.next.GetSubscription(ctx, discordUserID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveSubscription traces the wrapped repository's SaveSubscription
+func (repo *TracedSubscriptionRepository) SaveSubscription(ctx context.Context, subscription *models.Subscription) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveSubscription")
+	err := repo.next.SaveSubscription(ctx, subscription)
+	tracing.End(span, err)
+	return err
+}
+
+// DeleteSubscription traces the wrapped repository's DeleteSubscription
+func (repo *TracedSubscriptionRepository) DeleteSubscription(ctx context.Context, discordUserID string) error {
+	ctx, span := tracing.Start(ctx, "repository.DeleteSubscription")
+	err := repo.next.DeleteSubscription(ctx, discordUserID)
+	tracing.End(span, err)
+	return err
+}
+
+// GetAllSubscriptions traces the wrapped repository's GetAllSubscriptions
+func (repo *TracedSubscriptionRepository) GetAllSubscriptions(ctx context.Context) ([]*models.Subscription, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetAllSubscriptions")
+	result, err := repo.next.GetAllSubscriptions(ctx)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetChannelConfig traces the wrapped repository's GetChannelConfig
+func (repo *TracedSubscriptionRepository) GetChannelConfig(ctx context.Context, channelID string) (*models.ChannelConfig, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetChannelConfig")
+	result, err := repo.next.GetChannelConfig(ctx, channelID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// HasChannelConfig traces the wrapped repository's HasChannelConfig
+func (repo *TracedSubscriptionRepository) HasChannelConfig(ctx context.Context, channelID string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.HasChannelConfig")
+	result, err := repo.next.HasChannelConfig(ctx, channelID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveChannelConfig traces the wrapped repository's SaveChannelConfig
+func (repo *TracedSubscriptionRepository) SaveChannelConfig(ctx context.Context, config *models.ChannelConfig) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveChannelConfig")
+	err := repo.next.SaveChannelConfig(ctx, config)
+	tracing.End(span, err)
+	return err
+}
+
+// DeleteChannelConfig traces the wrapped repository's DeleteChannelConfig
+func (repo *TracedSubscriptionRepository) DeleteChannelConfig(ctx context.Context, channelID string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.DeleteChannelConfig")
+	result, err := repo.next.DeleteChannelConfig(ctx, channelID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetAllChannelConfigs traces the wrapped repository's GetAllChannelConfigs
+func (repo *TracedSubscriptionRepository) GetAllChannelConfigs(ctx context.Context) ([]*models.ChannelConfig, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetAllChannelConfigs")
+	result, err := repo.next.GetAllChannelConfigs(ctx)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveWebhookRegistration traces the wrapped repository's SaveWebhookRegistration
+func (repo *TracedSubscriptionRepository) SaveWebhookRegistration(ctx context.Context, registration *models.WebhookRegistration) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveWebhookRegistration")
+	err := repo.next.SaveWebhookRegistration(ctx, registration)
+	tracing.End(span, err)
+	return err
+}
+
+// GetWebhookRegistration traces the wrapped repository's GetWebhookRegistration
+func (repo *TracedSubscriptionRepository) GetWebhookRegistration(ctx context.Context, id string) (*models.WebhookRegistration, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetWebhookRegistration")
+	result, err := repo.next.GetWebhookRegistration(ctx, id)
+	tracing.End(span, err)
+	return result, err
+}
+
+// DeleteWebhookRegistration traces the wrapped repository's DeleteWebhookRegistration
+func (repo *TracedSubscriptionRepository) DeleteWebhookRegistration(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "repository.DeleteWebhookRegistration")
+	err := repo.next.DeleteWebhookRegistration(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+// GetAllWebhookRegistrations traces the wrapped repository's GetAllWebhookRegistrations
+func (repo *TracedSubscriptionRepository) GetAllWebhookRegistrations(ctx context.Context) ([]*models.WebhookRegistration, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetAllWebhookRegistrations")
+	result, err := repo.next.GetAllWebhookRegistrations(ctx)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetWebhookRegistrationsByChannel traces the wrapped repository's GetWebhookRegistrationsByChannel
+func (repo *TracedSubscriptionRepository) GetWebhookRegistrationsByChannel(ctx context.Context, channelID string) ([]*models.WebhookRegistration, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetWebhookRegistrationsByChannel")
+	result, err := repo.next.GetWebhookRegistrationsByChannel(ctx, channelID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveAuditEntry traces the wrapped repository's SaveAuditEntry
+func (repo *TracedSubscriptionRepository) SaveAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveAuditEntry")
+	err := repo.next.SaveAuditEntry(ctx, entry)
+	tracing.End(span, err)
+	return err
+}
+
+// GetAuditEntries traces the wrapped repository's GetAuditEntries
+func (repo *TracedSubscriptionRepository) GetAuditEntries(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEntry, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetAuditEntries")
+	result, err := repo.next.GetAuditEntries(ctx, filter)
+	tracing.End(span, err)
+	return result, err
+}
+
+// RedactAuditActor traces the wrapped repository's RedactAuditActor
+func (repo *TracedSubscriptionRepository) RedactAuditActor(ctx context.Context, actor, replacement string) (int, error) {
+	ctx, span := tracing.Start(ctx, "repository.RedactAuditActor")
+	result, err := repo.next.RedactAuditActor(ctx, actor, replacement)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveAPIKey traces the wrapped repository's SaveAPIKey
+func (repo *TracedSubscriptionRepository) SaveAPIKey(ctx context.Context, key *models.APIKey) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveAPIKey")
+	err := repo.next.SaveAPIKey(ctx, key)
+	tracing.End(span, err)
+	return err
+}
+
+// GetAPIKey traces the wrapped repository's GetAPIKey
+func (repo *TracedSubscriptionRepository) GetAPIKey(ctx context.Context, id string) (*models.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetAPIKey")
+	result, err := repo.next.GetAPIKey(ctx, id)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetAPIKeyByHash traces the wrapped repository's GetAPIKeyByHash
+func (repo *TracedSubscriptionRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetAPIKeyByHash")
+	result, err := repo.next.GetAPIKeyByHash(ctx, hash)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetAllAPIKeys traces the wrapped repository's GetAllAPIKeys
+func (repo *TracedSubscriptionRepository) GetAllAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetAllAPIKeys")
+	result, err := repo.next.GetAllAPIKeys(ctx)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetGuildConfig traces the wrapped repository's GetGuildConfig
+func (repo *TracedSubscriptionRepository) GetGuildConfig(ctx context.Context, guildID string) (*models.GuildConfig, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetGuildConfig")
+	result, err := repo.next.GetGuildConfig(ctx, guildID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveGuildConfig traces the wrapped repository's SaveGuildConfig
+func (repo *TracedSubscriptionRepository) SaveGuildConfig(ctx context.Context, config *models.GuildConfig) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveGuildConfig")
+	err := repo.next.SaveGuildConfig(ctx, config)
+	tracing.End(span, err)
+	return err
+}
+
+// GetAllGuildConfigs traces the wrapped repository's GetAllGuildConfigs
+func (repo *TracedSubscriptionRepository) GetAllGuildConfigs(ctx context.Context) ([]*models.GuildConfig, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetAllGuildConfigs")
+	result, err := repo.next.GetAllGuildConfigs(ctx)
+	tracing.End(span, err)
+	return result, err
+}
+
+// DeleteGuildConfig traces the wrapped repository's DeleteGuildConfig
+func (repo *TracedSubscriptionRepository) DeleteGuildConfig(ctx context.Context, guildID string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.DeleteGuildConfig")
+	result, err := repo.next.DeleteGuildConfig(ctx, guildID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveCategoryRoute traces the wrapped repository's SaveCategoryRoute
+func (repo *TracedSubscriptionRepository) SaveCategoryRoute(ctx context.Context, route *models.CategoryRoute) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveCategoryRoute")
+	err := repo.next.SaveCategoryRoute(ctx, route)
+	tracing.End(span, err)
+	return err
+}
+
+// DeleteCategoryRoute traces the wrapped repository's DeleteCategoryRoute
+func (repo *TracedSubscriptionRepository) DeleteCategoryRoute(ctx context.Context, guildID, category string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.DeleteCategoryRoute")
+	result, err := repo.next.DeleteCategoryRoute(ctx, guildID, category)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetCategoryRoutes traces the wrapped repository's GetCategoryRoutes
+func (repo *TracedSubscriptionRepository) GetCategoryRoutes(ctx context.Context, guildID string) ([]*models.CategoryRoute, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetCategoryRoutes")
+	result, err := repo.next.GetCategoryRoutes(ctx, guildID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetAllCategoryRoutes traces the wrapped repository's GetAllCategoryRoutes
+func (repo *TracedSubscriptionRepository) GetAllCategoryRoutes(ctx context.Context) ([]*models.CategoryRoute, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetAllCategoryRoutes")
+	result, err := repo.next.GetAllCategoryRoutes(ctx)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveRoutingRule traces the wrapped repository's SaveRoutingRule
+func (repo *TracedSubscriptionRepository) SaveRoutingRule(ctx context.Context, rule *models.RoutingRule) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveRoutingRule")
+	err := repo.next.SaveRoutingRule(ctx, rule)
+	tracing.End(span, err)
+	return err
+}
+
+// DeleteRoutingRule traces the wrapped repository's DeleteRoutingRule
+func (repo *TracedSubscriptionRepository) DeleteRoutingRule(ctx context.Context, id string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.DeleteRoutingRule")
+	result, err := repo.next.DeleteRoutingRule(ctx, id)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetRoutingRules traces the wrapped repository's GetRoutingRules
+func (repo *TracedSubscriptionRepository) GetRoutingRules(ctx context.Context, guildID string) ([]*models.RoutingRule, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetRoutingRules")
+	result, err := repo.next.GetRoutingRules(ctx, guildID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetAllRoutingRules traces the wrapped repository's GetAllRoutingRules
+func (repo *TracedSubscriptionRepository) GetAllRoutingRules(ctx context.Context) ([]*models.RoutingRule, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetAllRoutingRules")
+	result, err := repo.next.GetAllRoutingRules(ctx)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveLimitOverride traces the wrapped repository's SaveLimitOverride
+func (repo *TracedSubscriptionRepository) SaveLimitOverride(ctx context.Context, override *models.LimitOverride) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveLimitOverride")
+	err := repo.next.SaveLimitOverride(ctx, override)
+	tracing.End(span, err)
+	return err
+}
+
+// GetLimitOverride traces the wrapped repository's GetLimitOverride
+func (repo *TracedSubscriptionRepository) GetLimitOverride(ctx context.Context, subject, subjectID string) (*models.LimitOverride, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetLimitOverride")
+	result, err := repo.next.GetLimitOverride(ctx, subject, subjectID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetLimitOverrides traces the wrapped repository's GetLimitOverrides
+func (repo *TracedSubscriptionRepository) GetLimitOverrides(ctx context.Context) ([]*models.LimitOverride, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetLimitOverrides")
+	result, err := repo.next.GetLimitOverrides(ctx)
+	tracing.End(span, err)
+	return result, err
+}
+
+// DeleteLimitOverride traces the wrapped repository's DeleteLimitOverride
+func (repo *TracedSubscriptionRepository) DeleteLimitOverride(ctx context.Context, subject, subjectID string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.DeleteLimitOverride")
+	result, err := repo.next.DeleteLimitOverride(ctx, subject, subjectID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveWatchlist traces the wrapped repository's SaveWatchlist
+func (repo *TracedSubscriptionRepository) SaveWatchlist(ctx context.Context, watchlist *models.Watchlist) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveWatchlist")
+	err := repo.next.SaveWatchlist(ctx, watchlist)
+	tracing.End(span, err)
+	return err
+}
+
+// GetWatchlist traces the wrapped repository's GetWatchlist
+func (repo *TracedSubscriptionRepository) GetWatchlist(ctx context.Context, id string) (*models.Watchlist, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetWatchlist")
+	result, err := repo.next.GetWatchlist(ctx, id)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetWatchlistsByUser traces the wrapped repository's GetWatchlistsByUser
+func (repo *TracedSubscriptionRepository) GetWatchlistsByUser(ctx context.Context, discordUserID string) ([]*models.Watchlist, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetWatchlistsByUser")
+	result, err := repo.next.GetWatchlistsByUser(ctx, discordUserID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetAllWatchlists traces the wrapped repository's GetAllWatchlists
+func (repo *TracedSubscriptionRepository) GetAllWatchlists(ctx context.Context) ([]*models.Watchlist, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetAllWatchlists")
+	result, err := repo.next.GetAllWatchlists(ctx)
+	tracing.End(span, err)
+	return result, err
+}
+
+// DeleteWatchlist traces the wrapped repository's DeleteWatchlist
+func (repo *TracedSubscriptionRepository) DeleteWatchlist(ctx context.Context, id string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.DeleteWatchlist")
+	result, err := repo.next.DeleteWatchlist(ctx, id)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveWatchlistShare traces the wrapped repository's SaveWatchlistShare
+func (repo *TracedSubscriptionRepository) SaveWatchlistShare(ctx context.Context, share *models.WatchlistShare) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveWatchlistShare")
+	err := repo.next.SaveWatchlistShare(ctx, share)
+	tracing.End(span, err)
+	return err
+}
+
+// GetWatchlistShare traces the wrapped repository's GetWatchlistShare
+func (repo *TracedSubscriptionRepository) GetWatchlistShare(ctx context.Context, code string) (*models.WatchlistShare, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetWatchlistShare")
+	result, err := repo.next.GetWatchlistShare(ctx, code)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetWatchlistShareByWatchlist traces the wrapped repository's GetWatchlistShareByWatchlist
+func (repo *TracedSubscriptionRepository) GetWatchlistShareByWatchlist(ctx context.Context, watchlistID string) (*models.WatchlistShare, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetWatchlistShareByWatchlist")
+	result, err := repo.next.GetWatchlistShareByWatchlist(ctx, watchlistID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// AddHeldNotification traces the wrapped repository's AddHeldNotification
+func (repo *TracedSubscriptionRepository) AddHeldNotification(ctx context.Context, held *models.HeldNotification) error {
+	ctx, span := tracing.Start(ctx, "repository.AddHeldNotification")
+	err := repo.next.AddHeldNotification(ctx, held)
+	tracing.End(span, err)
+	return err
+}
+
+// GetHeldNotifications traces the wrapped repository's GetHeldNotifications
+func (repo *TracedSubscriptionRepository) GetHeldNotifications(ctx context.Context, channelID string) ([]*models.HeldNotification, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetHeldNotifications")
+	result, err := repo.next.GetHeldNotifications(ctx, channelID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// DeleteHeldNotifications traces the wrapped repository's DeleteHeldNotifications
+func (repo *TracedSubscriptionRepository) DeleteHeldNotifications(ctx context.Context, channelID string) (int, error) {
+	ctx, span := tracing.Start(ctx, "repository.DeleteHeldNotifications")
+	result, err := repo.next.DeleteHeldNotifications(ctx, channelID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// WithTx traces the wrapped repository's WithTx, including the calls made in the transaction
+func (repo *TracedSubscriptionRepository) WithTx(ctx context.Context, fn func(tx SubscriptionRepository) error) error {
+	ctx, span := tracing.Start(ctx, "repository.WithTx")
+	err := repo.next.WithTx(ctx, func(tx SubscriptionRepository) error {
+		return fn(NewTracedSubscriptionRepository(tx))
+	})
+	tracing.End(span, err)
+	return err
+}
+
+// MarkMarketResolved traces the wrapped repository's MarkMarketResolved
+func (repo *TracedSubscriptionRepository) MarkMarketResolved(ctx context.Context, marketID string, resolvedAt time.Time) error {
+	ctx, span := tracing.Start(ctx, "repository.MarkMarketResolved")
+	err := repo.next.MarkMarketResolved(ctx, marketID, resolvedAt)
+	tracing.End(span, err)
+	return err
+}
+
+// IsMarketResolved traces the wrapped repository's IsMarketResolved
+func (repo *TracedSubscriptionRepository) IsMarketResolved(ctx context.Context, marketID string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.IsMarketResolved")
+	result, err := repo.next.IsMarketResolved(ctx, marketID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveReminder traces the wrapped repository's SaveReminder
+func (repo *TracedSubscriptionRepository) SaveReminder(ctx context.Context, reminder *models.Reminder) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveReminder")
+	err := repo.next.SaveReminder(ctx, reminder)
+	tracing.End(span, err)
+	return err
+}
+
+// DeleteReminder traces the wrapped repository's DeleteReminder
+func (repo *TracedSubscriptionRepository) DeleteReminder(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "repository.DeleteReminder")
+	err := repo.next.DeleteReminder(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+// GetRemindersByUser traces the wrapped repository's GetRemindersByUser
+func (repo *TracedSubscriptionRepository) GetRemindersByUser(ctx context.Context, discordUserID string) ([]*models.Reminder, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetRemindersByUser")
+	result, err := repo.next.GetRemindersByUser(ctx, discordUserID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetDueReminders traces the wrapped repository's GetDueReminders
+func (repo *TracedSubscriptionRepository) GetDueReminders(ctx context.Context, now time.Time) ([]*models.Reminder, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetDueReminders")
+	result, err := repo.next.GetDueReminders(ctx, now)
+	tracing.End(span, err)
+	return result, err
+}
+
+// DeleteRemindersByMarket traces the wrapped repository's DeleteRemindersByMarket
+func (repo *TracedSubscriptionRepository) DeleteRemindersByMarket(ctx context.Context, marketID string) (int, error) {
+	ctx, span := tracing.Start(ctx, "repository.DeleteRemindersByMarket")
+	result, err := repo.next.DeleteRemindersByMarket(ctx, marketID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetReminder traces the wrapped repository's GetReminder
+func (repo *TracedSubscriptionRepository) GetReminder(ctx context.Context, id string) (*models.Reminder, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetReminder")
+	result, err := repo.next.GetReminder(ctx, id)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveScheduledJob traces the wrapped repository's SaveScheduledJob
+func (repo *TracedSubscriptionRepository) SaveScheduledJob(ctx context.Context, job *models.ScheduledJob) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveScheduledJob")
+	err := repo.next.SaveScheduledJob(ctx, job)
+	tracing.End(span, err)
+	return err
+}
+
+// GetScheduledJob traces the wrapped repository's GetScheduledJob
+func (repo *TracedSubscriptionRepository) GetScheduledJob(ctx context.Context, id string) (*models.ScheduledJob, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetScheduledJob")
+	result, err := repo.next.GetScheduledJob(ctx, id)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetScheduledJobs traces the wrapped repository's GetScheduledJobs
+func (repo *TracedSubscriptionRepository) GetScheduledJobs(ctx context.Context) ([]*models.ScheduledJob, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetScheduledJobs")
+	result, err := repo.next.GetScheduledJobs(ctx)
+	tracing.End(span, err)
+	return result, err
+}
+
+// DeleteScheduledJob traces the wrapped repository's DeleteScheduledJob
+func (repo *TracedSubscriptionRepository) DeleteScheduledJob(ctx context.Context, id string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.DeleteScheduledJob")
+	result, err := repo.next.DeleteScheduledJob(ctx, id)
+	tracing.End(span, err)
+	return result, err
+}
+
+// MarkClosingSoonAnnounced traces the wrapped repository's MarkClosingSoonAnnounced
+func (repo *TracedSubscriptionRepository) MarkClosingSoonAnnounced(ctx context.Context, channelID, marketID string, endTime time.Time) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.MarkClosingSoonAnnounced")
+	result, err := repo.next.MarkClosingSoonAnnounced(ctx, channelID, marketID, endTime)
+	tracing.End(span, err)
+	return result, err
+}
+
+// PruneClosingSoonAnnouncements traces the wrapped repository's PruneClosingSoonAnnouncements
+func (repo *TracedSubscriptionRepository) PruneClosingSoonAnnouncements(ctx context.Context, before time.Time) error {
+	ctx, span := tracing.Start(ctx, "repository.PruneClosingSoonAnnouncements")
+	err := repo.next.PruneClosingSoonAnnouncements(ctx, before)
+	tracing.End(span, err)
+	return err
+}
+
+// MarkMarketAnnounced traces the wrapped repository's MarkMarketAnnounced
+func (repo *TracedSubscriptionRepository) MarkMarketAnnounced(ctx context.Context, channelID, marketID string, at time.Time) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.MarkMarketAnnounced")
+	result, err := repo.next.MarkMarketAnnounced(ctx, channelID, marketID, at)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetAnnouncedMarkets traces the wrapped repository's GetAnnouncedMarkets
+func (repo *TracedSubscriptionRepository) GetAnnouncedMarkets(ctx context.Context, channelID string) (map[string]bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetAnnouncedMarkets")
+	result, err := repo.next.GetAnnouncedMarkets(ctx, channelID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// PruneMarketAnnouncements traces the wrapped repository's PruneMarketAnnouncements
+func (repo *TracedSubscriptionRepository) PruneMarketAnnouncements(ctx context.Context, before time.Time) error {
+	ctx, span := tracing.Start(ctx, "repository.PruneMarketAnnouncements")
+	err := repo.next.PruneMarketAnnouncements(ctx, before)
+	tracing.End(span, err)
+	return err
+}
+
+// SaveOutboxItem traces the wrapped repository's SaveOutboxItem
+func (repo *TracedSubscriptionRepository) SaveOutboxItem(ctx context.Context, item *models.OutboxItem) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveOutboxItem")
+	err := repo.next.SaveOutboxItem(ctx, item)
+	tracing.End(span, err)
+	return err
+}
+
+// GetPendingOutboxItems traces the wrapped repository's GetPendingOutboxItems
+func (repo *TracedSubscriptionRepository) GetPendingOutboxItems(ctx context.Context, before time.Time) ([]*models.OutboxItem, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetPendingOutboxItems")
+	result, err := repo.next.GetPendingOutboxItems(ctx, before)
+	tracing.End(span, err)
+	return result, err
+}
+
+// PruneOutboxItems traces the wrapped repository's PruneOutboxItems
+func (repo *TracedSubscriptionRepository) PruneOutboxItems(ctx context.Context, before time.Time) error {
+	ctx, span := tracing.Start(ctx, "repository.PruneOutboxItems")
+	err := repo.next.PruneOutboxItems(ctx, before)
+	tracing.End(span, err)
+	return err
+}
+
+// SaveHTTPLogEntry traces the wrapped repository's SaveHTTPLogEntry
+func (repo *TracedSubscriptionRepository) SaveHTTPLogEntry(ctx context.Context, entry *models.HTTPLogEntry, capacity int) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveHTTPLogEntry")
+	err := repo.next.SaveHTTPLogEntry(ctx, entry, capacity)
+	tracing.End(span, err)
+	return err
+}
+
+// GetHTTPLogEntries traces the wrapped repository's GetHTTPLogEntries
+func (repo *TracedSubscriptionRepository) GetHTTPLogEntries(ctx context.Context, filter models.HTTPLogFilter) ([]*models.HTTPLogEntry, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetHTTPLogEntries")
+	result, err := repo.next.GetHTTPLogEntries(ctx, filter)
+	tracing.End(span, err)
+	return result, err
+}
+
+// ClearHTTPLog traces the wrapped repository's ClearHTTPLog
+func (repo *TracedSubscriptionRepository) ClearHTTPLog(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "repository.ClearHTTPLog")
+	result, err := repo.next.ClearHTTPLog(ctx)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveDeliveryReport traces the wrapped repository's SaveDeliveryReport
+func (repo *TracedSubscriptionRepository) SaveDeliveryReport(ctx context.Context, report *models.DeliveryReport) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveDeliveryReport")
+	err := repo.next.SaveDeliveryReport(ctx, report)
+	tracing.End(span, err)
+	return err
+}
+
+// AddDeliveryReceipt traces the wrapped repository's AddDeliveryReceipt
+func (repo *TracedSubscriptionRepository) AddDeliveryReceipt(ctx context.Context, eventID string, receipt models.DeliveryReceipt) error {
+	ctx, span := tracing.Start(ctx, "repository.AddDeliveryReceipt")
+	err := repo.next.AddDeliveryReceipt(ctx, eventID, receipt)
+	tracing.End(span, err)
+	return err
+}
+
+// GetDeliveryReport traces the wrapped repository's GetDeliveryReport
+func (repo *TracedSubscriptionRepository) GetDeliveryReport(ctx context.Context, eventID string) (*models.DeliveryReport, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetDeliveryReport")
+	result, err := repo.next.GetDeliveryReport(ctx, eventID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// PruneDeliveryReports traces the wrapped repository's PruneDeliveryReports
+func (repo *TracedSubscriptionRepository) PruneDeliveryReports(ctx context.Context, before time.Time) error {
+	ctx, span := tracing.Start(ctx, "repository.PruneDeliveryReports")
+	err := repo.next.PruneDeliveryReports(ctx, before)
+	tracing.End(span, err)
+	return err
+}
+
+// SaveDeadLetter traces the wrapped repository's SaveDeadLetter
+func (repo *TracedSubscriptionRepository) SaveDeadLetter(ctx context.Context, letter *models.DeadLetter) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveDeadLetter")
+	err := repo.next.SaveDeadLetter(ctx, letter)
+	tracing.End(span, err)
+	return err
+}
+
+// GetDeadLetter traces the wrapped repository's GetDeadLetter
+func (repo *TracedSubscriptionRepository) GetDeadLetter(ctx context.Context, id string) (*models.DeadLetter, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetDeadLetter")
+	result, err := repo.next.GetDeadLetter(ctx, id)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetDeadLetters traces the wrapped repository's GetDeadLetters
+func (repo *TracedSubscriptionRepository) GetDeadLetters(ctx context.Context) ([]*models.DeadLetter, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetDeadLetters")
+	result, err := repo.next.GetDeadLetters(ctx)
+	tracing.End(span, err)
+	return result, err
+}
+
+// DeleteDeadLetter traces the wrapped repository's DeleteDeadLetter
+func (repo *TracedSubscriptionRepository) DeleteDeadLetter(ctx context.Context, id string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.DeleteDeadLetter")
+	result, err := repo.next.DeleteDeadLetter(ctx, id)
+	tracing.End(span, err)
+	return result, err
+}
+
+// PruneDeadLetters traces the wrapped repository's PruneDeadLetters
+func (repo *TracedSubscriptionRepository) PruneDeadLetters(ctx context.Context, before time.Time) error {
+	ctx, span := tracing.Start(ctx, "repository.PruneDeadLetters")
+	err := repo.next.PruneDeadLetters(ctx, before)
+	tracing.End(span, err)
+	return err
+}
+
+// SaveBusOffset traces the wrapped repository's SaveBusOffset
+func (repo *TracedSubscriptionRepository) SaveBusOffset(ctx context.Context, offset *models.BusOffset) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveBusOffset")
+	err := repo.next.SaveBusOffset(ctx, offset)
+	tracing.End(span, err)
+	return err
+}
+
+// GetBusOffset traces the wrapped repository's GetBusOffset
+func (repo *TracedSubscriptionRepository) GetBusOffset(ctx context.Context, consumer string, partition int32) (*models.BusOffset, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetBusOffset")
+	result, err := repo.next.GetBusOffset(ctx, consumer, partition)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveAnalyticsEvent traces the wrapped repository's SaveAnalyticsEvent
+func (repo *TracedSubscriptionRepository) SaveAnalyticsEvent(ctx context.Context, event *models.AnalyticsEvent) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveAnalyticsEvent")
+	err := repo.next.SaveAnalyticsEvent(ctx, event)
+	tracing.End(span, err)
+	return err
+}
+
+// GetAnalyticsEvents traces the wrapped repository's GetAnalyticsEvents
+func (repo *TracedSubscriptionRepository) GetAnalyticsEvents(ctx context.Context, from, to time.Time) ([]*models.AnalyticsEvent, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetAnalyticsEvents")
+	result, err := repo.next.GetAnalyticsEvents(ctx, from, to)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetAnalyticsEventsBySubject traces the wrapped repository's GetAnalyticsEventsBySubject
+func (repo *TracedSubscriptionRepository) GetAnalyticsEventsBySubject(ctx context.Context, subject string) ([]*models.AnalyticsEvent, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetAnalyticsEventsBySubject")
+	result, err := repo.next.GetAnalyticsEventsBySubject(ctx, subject)
+	tracing.End(span, err)
+	return result, err
+}
+
+// DeleteAnalyticsEventsBySubject traces the wrapped repository's DeleteAnalyticsEventsBySubject
+func (repo *TracedSubscriptionRepository) DeleteAnalyticsEventsBySubject(ctx context.Context, subject string) (int, error) {
+	ctx, span := tracing.Start(ctx, "repository.DeleteAnalyticsEventsBySubject")
+	result, err := repo.next.DeleteAnalyticsEventsBySubject(ctx, subject)
+	tracing.End(span, err)
+	return result, err
+}
+
+// PruneAnalyticsEvents traces the wrapped repository's PruneAnalyticsEvents
+func (repo *TracedSubscriptionRepository) PruneAnalyticsEvents(ctx context.Context, before time.Time) error {
+	ctx, span := tracing.Start(ctx, "repository.PruneAnalyticsEvents")
+	err := repo.next.PruneAnalyticsEvents(ctx, before)
+	tracing.End(span, err)
+	return err
+}
+
+// GetMarketSnapshot traces the wrapped repository's GetMarketSnapshot
+func (repo *TracedSubscriptionRepository) GetMarketSnapshot(ctx context.Context, marketID string) (*models.MarketSnapshot, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetMarketSnapshot")
+	result, err := repo.next.GetMarketSnapshot(ctx, marketID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveMarketSnapshot traces the wrapped repository's SaveMarketSnapshot
+func (repo *TracedSubscriptionRepository) SaveMarketSnapshot(ctx context.Context, snapshot *models.MarketSnapshot) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveMarketSnapshot")
+	err := repo.next.SaveMarketSnapshot(ctx, snapshot)
+	tracing.End(span, err)
+	return err
+}
+
+// AppendMarketHistory traces the wrapped repository's AppendMarketHistory
+func (repo *TracedSubscriptionRepository) AppendMarketHistory(ctx context.Context, snapshot *models.MarketSnapshot) error {
+	ctx, span := tracing.Start(ctx, "repository.AppendMarketHistory")
+	err := repo.next.AppendMarketHistory(ctx, snapshot)
+	tracing.End(span, err)
+	return err
+}
+
+// GetMarketHistory traces the wrapped repository's GetMarketHistory
+func (repo *TracedSubscriptionRepository) GetMarketHistory(ctx context.Context, marketID string, since time.Time) ([]*models.MarketSnapshot, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetMarketHistory")
+	result, err := repo.next.GetMarketHistory(ctx, marketID, since)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetTopSubscribedMarkets traces the wrapped repository's GetTopSubscribedMarkets
+func (repo *TracedSubscriptionRepository) GetTopSubscribedMarkets(ctx context.Context, limit int) ([]models.LeaderboardEntry, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetTopSubscribedMarkets")
+	result, err := repo.next.GetTopSubscribedMarkets(ctx, limit)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetTopSubscribedCreators traces the wrapped repository's GetTopSubscribedCreators
+func (repo *TracedSubscriptionRepository) GetTopSubscribedCreators(ctx context.Context, limit int) ([]models.LeaderboardEntry, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetTopSubscribedCreators")
+	result, err := repo.next.GetTopSubscribedCreators(ctx, limit)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveGameWallet traces the wrapped repository's SaveGameWallet
+func (repo *TracedSubscriptionRepository) SaveGameWallet(ctx context.Context, wallet *models.GameWallet) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveGameWallet")
+	err := repo.next.SaveGameWallet(ctx, wallet)
+	tracing.End(span, err)
+	return err
+}
+
+// GetGameWallet traces the wrapped repository's GetGameWallet
+func (repo *TracedSubscriptionRepository) GetGameWallet(ctx context.Context, guildID, discordUserID string) (*models.GameWallet, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetGameWallet")
+	result, err := repo.next.GetGameWallet(ctx, guildID, discordUserID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetGameWallets traces the wrapped repository's GetGameWallets
+func (repo *TracedSubscriptionRepository) GetGameWallets(ctx context.Context, guildID string) ([]*models.GameWallet, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetGameWallets")
+	result, err := repo.next.GetGameWallets(ctx, guildID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetGameWalletsByUser traces the wrapped repository's GetGameWalletsByUser
+func (repo *TracedSubscriptionRepository) GetGameWalletsByUser(ctx context.Context, discordUserID string) ([]*models.GameWallet, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetGameWalletsByUser")
+	result, err := repo.next.GetGameWalletsByUser(ctx, discordUserID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// SaveGameBet traces the wrapped repository's SaveGameBet
+func (repo *TracedSubscriptionRepository) SaveGameBet(ctx context.Context, bet *models.GameBet) error {
+	ctx, span := tracing.Start(ctx, "repository.SaveGameBet")
+	err := repo.next.SaveGameBet(ctx, bet)
+	tracing.End(span, err)
+	return err
+}
+
+// GetOpenGameBets traces the wrapped repository's GetOpenGameBets
+func (repo *TracedSubscriptionRepository) GetOpenGameBets(ctx context.Context, marketID string) ([]*models.GameBet, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetOpenGameBets")
+	result, err := repo.next.GetOpenGameBets(ctx, marketID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetGameBetsByUser traces the wrapped repository's GetGameBetsByUser
+func (repo *TracedSubscriptionRepository) GetGameBetsByUser(ctx context.Context, discordUserID string) ([]*models.GameBet, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetGameBetsByUser")
+	result, err := repo.next.GetGameBetsByUser(ctx, discordUserID)
+	tracing.End(span, err)
+	return result, err
+}
+
+// GetGameBetsByGuild traces the wrapped repository's GetGameBetsByGuild
+func (repo *TracedSubscriptionRepository) GetGameBetsByGuild(ctx context.Context, guildID string, since time.Time) ([]*models.GameBet, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetGameBetsByGuild")
+	result, err := repo.next.GetGameBetsByGuild(ctx, guildID, since)
+	tracing.End(span, err)
+	return result, err
+}
+
+// DeleteGameData traces the wrapped repository's DeleteGameData
+func (repo *TracedSubscriptionRepository) DeleteGameData(ctx context.Context, discordUserID string) (int, error) {
+	ctx, span := tracing.Start(ctx, "repository.DeleteGameData")
+	result, err := repo.next.DeleteGameData(ctx, discordUserID)
+	tracing.End(span, err)
+	return result, err
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// inMemoryTx is the repository passed to a running in-memory transaction
+type inMemoryTx struct {
+	*InMemorySubscriptionRepository
+}
+
+// WithTx runs fn as a transaction. The repository is locked for the whole transaction, so other
+// reads and writes wait for it to end and it sees no changes but its own. fn works on the stored data
+// through a repository with a lock of its own; when it returns an error the data is put back as it
+// was when the transaction started.
+func (repo *InMemorySubscriptionRepository) WithTx(ctx context.Context, fn func(tx SubscriptionRepository) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.txMutex.Lock()
+	defer repo.txMutex.Unlock()
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	saved := repo.snapshot()
+	tx := &InMemorySubscriptionRepository{inMemoryData: repo.inMemoryData, mutex: &sync.RWMutex{}, txMutex: &sync.Mutex{}}
+	if err := fn(inMemoryTx{tx}); err != nil {
+		repo.inMemoryData = saved
+		return err
+	}
+	// Maps are shared with tx, but slices it appended to or pruned are not
+	repo.inMemoryData = tx.inMemoryData
+	return nil
+}
+
+// WithTx joins the running transaction rather than waiting for it to end
+func (tx inMemoryTx) WithTx(ctx context.Context, fn func(tx SubscriptionRepository) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fn(tx)
+}
+
+// snapshot returns a copy of the repository's data to roll a transaction back to: its maps and slices
+// are copied, and so are the items they hold, since callers change some items in place before saving
+// them. The caller holds the lock.
+func (repo *InMemorySubscriptionRepository) snapshot() inMemoryData {
+	saved := repo.inMemoryData
+	saved.subscriptions = cloneItems(repo.subscriptions)
+	saved.channels = cloneItems(repo.channels)
+	saved.webhooks = cloneItems(repo.webhooks)
+	saved.guilds = cloneItems(repo.guilds)
+	saved.reminders = cloneItems(repo.reminders)
+	saved.reminderQueue = make([]*models.Reminder, 0, len(repo.reminderQueue))
+	for _, reminder := range repo.reminderQueue {
+		if clone, ok := saved.reminders[reminder.ID]; ok {
+			saved.reminderQueue = append(saved.reminderQueue, clone)
+		}
+	}
+	saved.jobs = cloneItems(repo.jobs)
+	saved.analytics = slices.Clone(repo.analytics)
+	saved.snapshots = cloneItems(repo.snapshots)
+	saved.history = make(map[string][]*models.MarketSnapshot, len(repo.history))
+	for marketID, history := range repo.history {
+		saved.history[marketID] = slices.Clone(history)
+	}
+	saved.resolved = maps.Clone(repo.resolved)
+	saved.audit = slices.Clone(repo.audit)
+	saved.httpLog = slices.Clone(repo.httpLog)
+	saved.apiKeys = cloneItems(repo.apiKeys)
+	saved.closingSoon = maps.Clone(repo.closingSoon)
+	saved.announcements = maps.Clone(repo.announcements)
+	saved.outbox = cloneItems(repo.outbox)
+	saved.deliveries = cloneItems(repo.deliveries)
+	saved.deadLetters = cloneItems(repo.deadLetters)
+	saved.busOffsets = cloneItems(repo.busOffsets)
+	saved.categoryRoutes = cloneItems(repo.categoryRoutes)
+	saved.routingRules = cloneItems(repo.routingRules)
+	saved.limitOverrides = cloneItems(repo.limitOverrides)
+	saved.watchlists = cloneItems(repo.watchlists)
+	saved.shares = cloneItems(repo.shares)
+	saved.held = make(map[string]map[heldNotificationKey]*models.HeldNotification, len(repo.held))
+	for channelID, held := range repo.held {
+		saved.held[channelID] = cloneItems(held)
+	}
+	saved.gameWallets = cloneItems(repo.gameWallets)
+	saved.gameBets = cloneItems(repo.gameBets)
+	return saved
+}
+
+// cloneItems copies a map and the items it points to
+func cloneItems[K comparable, V any](items map[K]*V) map[K]*V {
+	clone := make(map[K]*V, len(items))
+	for key, item := range items {
+		copied := *item
+		clone[key] = &copied
+	}
+	return clone
+}
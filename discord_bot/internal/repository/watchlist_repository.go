@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// copyWatchlist returns a copy of a watchlist that shares no market list with the original
+func copyWatchlist(watchlist *models.Watchlist) *models.Watchlist {
+	copied := *watchlist
+	copied.MarketIDs = append([]string(nil), watchlist.MarketIDs...)
+	return &copied
+}
+
+// SaveWatchlist saves a watchlist, replacing the previous version with the same ID
+func (repo *InMemorySubscriptionRepository) SaveWatchlist(ctx context.Context, watchlist *models.Watchlist) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.watchlists[watchlist.ID] = copyWatchlist(watchlist)
+	return nil
+}
+
+// GetWatchlist returns a watchlist by ID, or nil when there is none
+func (repo *InMemorySubscriptionRepository) GetWatchlist(ctx context.Context, id string) (*models.Watchlist, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	watchlist, exists := repo.watchlists[id]
+	if !exists {
+		return nil, nil
+	}
+	return copyWatchlist(watchlist), nil
+}
+
+// GetWatchlistsByUser returns a user's watchlists sorted by name
+func (repo *InMemorySubscriptionRepository) GetWatchlistsByUser(ctx context.Context, discordUserID string) ([]*models.Watchlist, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	watchlists := []*models.Watchlist{}
+	for _, watchlist := range repo.watchlists {
+		if watchlist.DiscordUserID == discordUserID {
+			watchlists = append(watchlists, copyWatchlist(watchlist))
+		}
+	}
+	sort.Slice(watchlists, func(i, j int) bool {
+		return watchlists[i].Name < watchlists[j].Name
+	})
+	return watchlists, nil
+}
+
+// GetAllWatchlists returns every user's watchlists
+func (repo *InMemorySubscriptionRepository) GetAllWatchlists(ctx context.Context) ([]*models.Watchlist, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	watchlists := make([]*models.Watchlist, 0, len(repo.watchlists))
+	for _, watchlist := range repo.watchlists {
+		watchlists = append(watchlists, copyWatchlist(watchlist))
+	}
+	return watchlists, nil
+}
+
+// DeleteWatchlist deletes a watchlist along with its share code and reports whether it existed
+func (repo *InMemorySubscriptionRepository) DeleteWatchlist(ctx context.Context, id string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	_, exists := repo.watchlists[id]
+	delete(repo.watchlists, id)
+	for code, share := range repo.shares {
+		if share.WatchlistID == id {
+			delete(repo.shares, code)
+		}
+	}
+	return exists, nil
+}
+
+// SaveWatchlistShare saves a watchlist share code
+func (repo *InMemorySubscriptionRepository) SaveWatchlistShare(ctx context.Context, share *models.WatchlistShare) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	copied := *share
+	repo.shares[share.Code] = &copied
+	return nil
+}
+
+// GetWatchlistShare returns the share with a code, or nil when no watchlist is shared under it
+func (repo *InMemorySubscriptionRepository) GetWatchlistShare(ctx context.Context, code string) (*models.WatchlistShare, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	share, exists := repo.shares[code]
+	if !exists {
+		return nil, nil
+	}
+	copied := *share
+	return &copied, nil
+}
+
+// GetWatchlistShareByWatchlist returns a watchlist's share, or nil when it has not been shared
+func (repo *InMemorySubscriptionRepository) GetWatchlistShareByWatchlist(ctx context.Context, watchlistID string) (*models.WatchlistShare, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	for _, share := range repo.shares {
+		if share.WatchlistID == watchlistID {
+			copied := *share
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// AccountCodeTTL is how long a code from link_account can be redeemed on Coral
+const AccountCodeTTL = 15 * time.Minute
+
+// accountCodeAlphabet leaves out characters easily mistaken for others, like 0 and O
+const accountCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// accountCodeLength is the number of characters of a link code, written in two groups of four
+const accountCodeLength = 8
+
+// maxAccountIDLength bounds the Coral account IDs stored with subscriptions
+const maxAccountIDLength = 128
+
+var (
+	// ErrAccountCodeInvalid is returned for a link code that is unknown, already redeemed or expired
+	ErrAccountCodeInvalid = errors.New("invalid or expired link code")
+	// ErrAccountNotLinked is returned when a user has no linked Coral account
+	ErrAccountNotLinked = errors.New("no Coral account is linked")
+)
+
+// AccountService defines the interface for linking users to their Coral accounts and choosing how
+// their notifications are pushed there
+type AccountService interface {
+	CreateLinkCode(ctx context.Context, discordUserID string) (string, error)
+	LinkAccount(ctx context.Context, code, accountID string) (string, error)
+	UnlinkAccount(ctx context.Context, discordUserID string) (bool, error)
+	UnlinkCoralAccount(ctx context.Context, accountID string) (string, error)
+	LinkedAccount(ctx context.Context, discordUserID string) (string, error)
+	SetPushMode(ctx context.Context, discordUserID, mode string) error
+}
+
+// AccountServiceImpl implements AccountService
+type AccountServiceImpl struct {
+	repo   repository.SubscriptionRepository
+	logger *utils.Logger
+	clockAndIDs
+}
+
+// NewAccountService creates a new account service
+func NewAccountService(repo repository.SubscriptionRepository, logger *utils.Logger) *AccountServiceImpl {
+	return &AccountServiceImpl{repo: repo, logger: logger}
+}
+
+// CreateLinkCode returns a code the user enters on Coral to link their account, replacing any code
+// issued before. A linked account stays linked until the code is redeemed.
+func (service *AccountServiceImpl) CreateLinkCode(ctx context.Context, discordUserID string) (string, error) {
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	var code strings.Builder
+	for i := 0; i < accountCodeLength; i++ {
+		index, err := rand.Int(rand.Reader, big.NewInt(int64(len(accountCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(accountCodeAlphabet[index.Int64()])
+	}
+
+	if subscription.Account == nil {
+		subscription.Account = &models.CoralAccount{}
+	}
+	subscription.Account.CodeHash = accountCodeHash(code.String())
+	subscription.Account.CodeIssuedAt = service.now()
+	if err := service.repo.SaveSubscription(ctx, subscription); err != nil {
+		return "", err
+	}
+	return code.String()[:accountCodeLength/2] + "-" + code.String()[accountCodeLength/2:], nil
+}
+
+// accountCodeHash hashes a link code, ignoring case, spaces and dashes
+func accountCodeHash(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// LinkAccount redeems a link code for a Coral account and returns the Discord user it was issued to.
+// An account is linked to one Discord user at a time, so another user linked to it is unlinked.
+// Notifications are pushed to a newly linked account when DMs fail.
+func (service *AccountServiceImpl) LinkAccount(ctx context.Context, code, accountID string) (string, error) {
+	accountID = strings.TrimSpace(accountID)
+	if accountID == "" || len(accountID) > maxAccountIDLength {
+		return "", fmt.Errorf("%w: account_id must be 1 to %d characters", ErrInvalidChannelSettings, maxAccountIDLength)
+	}
+	subscriptions, err := service.repo.GetAllSubscriptions(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get subscriptions: %w", err)
+	}
+
+	hash := accountCodeHash(code)
+	now := service.now()
+	var owner *models.Subscription
+	for _, subscription := range subscriptions {
+		account := subscription.Account
+		if account != nil && account.CodeHash == hash && now.Sub(account.CodeIssuedAt) <= AccountCodeTTL {
+			owner = subscription
+			break
+		}
+	}
+	if owner == nil {
+		return "", ErrAccountCodeInvalid
+	}
+
+	for _, subscription := range subscriptions {
+		if subscription != owner && subscription.Account.Linked() && subscription.Account.AccountID == accountID {
+			subscription.Account = nil
+			if err := service.repo.SaveSubscription(ctx, subscription); err != nil {
+				return "", err
+			}
+			service.logger.Info(fmt.Sprintf("Coral account %s moved from user %s to user %s", accountID, subscription.DiscordUserID, owner.DiscordUserID))
+		}
+	}
+
+	push := owner.Account.Push
+	if !owner.Account.Linked() || push == "" {
+		push = models.PushFallback
+	}
+	owner.Account = &models.CoralAccount{AccountID: accountID, Push: push, LinkedAt: now}
+	if err := service.repo.SaveSubscription(ctx, owner); err != nil {
+		return "", err
+	}
+	return owner.DiscordUserID, nil
+}
+
+// UnlinkAccount removes the user's Coral account and any code waiting to be redeemed, and reports
+// whether they had either
+func (service *AccountServiceImpl) UnlinkAccount(ctx context.Context, discordUserID string) (bool, error) {
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get subscription: %w", err)
+	}
+	if subscription.Account == nil {
+		return false, nil
+	}
+	subscription.Account = nil
+	return true, service.repo.SaveSubscription(ctx, subscription)
+}
+
+// UnlinkCoralAccount removes a Coral account from the Discord user it is linked to and returns that
+// user. ErrAccountNotLinked is returned when no user is linked to it.
+func (service *AccountServiceImpl) UnlinkCoralAccount(ctx context.Context, accountID string) (string, error) {
+	subscriptions, err := service.repo.GetAllSubscriptions(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get subscriptions: %w", err)
+	}
+	for _, subscription := range subscriptions {
+		if subscription.Account.Linked() && subscription.Account.AccountID == accountID {
+			subscription.Account = nil
+			return subscription.DiscordUserID, service.repo.SaveSubscription(ctx, subscription)
+		}
+	}
+	return "", ErrAccountNotLinked
+}
+
+// LinkedAccount returns the ID of the Coral account linked to the user. ErrAccountNotLinked is returned
+// when they have none.
+func (service *AccountServiceImpl) LinkedAccount(ctx context.Context, discordUserID string) (string, error) {
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get subscription: %w", err)
+	}
+	if !subscription.Account.Linked() {
+		return "", ErrAccountNotLinked
+	}
+	return subscription.Account.AccountID, nil
+}
+
+// SetPushMode chooses whether the user's notifications are pushed to their Coral account always, only
+// when DMs fail, or never
+func (service *AccountServiceImpl) SetPushMode(ctx context.Context, discordUserID, mode string) error {
+	if !models.IsValidPushMode(mode) {
+		return fmt.Errorf("%w: push mode must be always, fallback or off", ErrInvalidChannelSettings)
+	}
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+	if !subscription.Account.Linked() {
+		return ErrAccountNotLinked
+	}
+	subscription.Account.Push = mode
+	return service.repo.SaveSubscription(ctx, subscription)
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// DefaultAnalyticsRetention is how long analytics events are kept when no retention is set
+const DefaultAnalyticsRetention = 90 * 24 * time.Hour
+
+// AnalyticsService defines the interface for recording and aggregating bot usage
+type AnalyticsService interface {
+	RecordCommand(ctx context.Context, commandName, discordUserID string)
+	RecordCommandFailure(ctx context.Context, commandName, discordUserID string)
+	RecordDelivery(ctx context.Context, eventType, destination string, err error)
+	GetSummary(ctx context.Context, from, to time.Time) (*models.AnalyticsSummary, error)
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// AnalyticsServiceImpl implements AnalyticsService
+type AnalyticsServiceImpl struct {
+	repo      repository.SubscriptionRepository
+	logger    *utils.Logger
+	retention time.Duration // how long events are kept by Run
+	clockAndIDs
+}
+
+// NewAnalyticsService creates a new analytics service
+func NewAnalyticsService(repo repository.SubscriptionRepository, logger *utils.Logger) *AnalyticsServiceImpl {
+	return &AnalyticsServiceImpl{
+		repo:      repo,
+		logger:    logger,
+		retention: DefaultAnalyticsRetention,
+	}
+}
+
+// SetRetention sets how long analytics events are kept before Run prunes them, 0 for the default
+func (service *AnalyticsServiceImpl) SetRetention(retention time.Duration) {
+	if retention <= 0 {
+		retention = DefaultAnalyticsRetention
+	}
+	service.retention = retention
+}
+
+// RecordCommand records a slash command invocation
+func (service *AnalyticsServiceImpl) RecordCommand(ctx context.Context, commandName, discordUserID string) {
+	service.record(ctx, models.AnalyticsCommand, commandName, discordUserID)
+}
+
+// RecordCommandFailure records a slash command that failed or panicked
+func (service *AnalyticsServiceImpl) RecordCommandFailure(ctx context.Context, commandName, discordUserID string) {
+	service.record(ctx, models.AnalyticsCommandFailure, commandName, discordUserID)
+}
+
+// RecordDelivery records the outcome of sending an event notification to a channel or user
+func (service *AnalyticsServiceImpl) RecordDelivery(ctx context.Context, eventType, destination string, err error) {
+	if err != nil {
+		service.record(ctx, models.AnalyticsDeliveryFailure, eventType, destination)
+		return
+	}
+	service.record(ctx, models.AnalyticsNotificationSent, eventType, destination)
+}
+
+// GetSummary aggregates the analytics events recorded in [from, to)
+func (service *AnalyticsServiceImpl) GetSummary(ctx context.Context, from, to time.Time) (*models.AnalyticsSummary, error) {
+	events, err := service.repo.GetAnalyticsEvents(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get analytics events: %w", err)
+	}
+
+	summary := &models.AnalyticsSummary{
+		From:              from,
+		To:                to,
+		Commands:          map[string]int{},
+		CommandFailures:   map[string]int{},
+		Subscriptions:     map[string]int{},
+		Unsubscriptions:   map[string]int{},
+		NotificationsSent: map[string]int{},
+		DeliveryFailures:  map[string]int{},
+	}
+
+	activeUsers := map[string]bool{}
+	for _, event := range events {
+		switch event.Kind {
+		case models.AnalyticsCommand:
+			summary.Commands[event.Name]++
+			activeUsers[event.Subject] = true
+		case models.AnalyticsCommandFailure:
+			summary.CommandFailures[event.Name]++
+		case models.AnalyticsSubscribe:
+			summary.Subscriptions[event.Name]++
+		case models.AnalyticsUnsubscribe:
+			summary.Unsubscriptions[event.Name]++
+		case models.AnalyticsNotificationSent:
+			summary.NotificationsSent[event.Name]++
+		case models.AnalyticsDeliveryFailure:
+			summary.DeliveryFailures[event.Name]++
+		}
+	}
+	summary.ActiveUsers = len(activeUsers)
+
+	return summary, nil
+}
+
+// record stores an analytics event; failures are logged rather than surfaced to callers
+func (service *AnalyticsServiceImpl) record(ctx context.Context, kind, name, subject string) {
+	err := service.repo.SaveAnalyticsEvent(ctx, &models.AnalyticsEvent{
+		Kind:      kind,
+		Name:      name,
+		Subject:   subject,
+		Timestamp: service.now(),
+	})
+	if err != nil {
+		service.logger.Warning(fmt.Sprintf("Failed to record analytics event %s/%s: %v", kind, name, err))
+	}
+}
+
+// Prune deletes the analytics events recorded before a point in time
+func (service *AnalyticsServiceImpl) Prune(ctx context.Context, before time.Time) error {
+	return service.repo.PruneAnalyticsEvents(ctx, before)
+}
+
+// Run prunes the analytics events older than the retention once at startup and then on every tick,
+// until the context is cancelled
+func (service *AnalyticsServiceImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	service.logger.Info(fmt.Sprintf("Analytics pruning started (retention %s, interval %s)", service.retention, interval))
+	service.pruneExpired(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			service.logger.Info("Analytics pruning stopped")
+			return
+		case <-ticker.C:
+			service.pruneExpired(ctx)
+		}
+	}
+}
+
+// pruneExpired deletes the analytics events older than the retention
+func (service *AnalyticsServiceImpl) pruneExpired(ctx context.Context) {
+	if err := service.Prune(ctx, service.now().Add(-service.retention)); err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to prune analytics events: %v", err))
+	}
+}
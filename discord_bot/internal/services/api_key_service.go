@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// apiKeySecretPrefix marks the bot's API keys so they are recognisable, e.g. by secret scanners
+const apiKeySecretPrefix = "coral_"
+
+// apiKeyDisplayLength is how many characters of a secret are kept to identify the key in listings
+const apiKeyDisplayLength = len(apiKeySecretPrefix) + 6
+
+// apiKeyUsageInterval is how often the last use of a key is saved, so checking a key does not write
+// on every request
+const apiKeyUsageInterval = time.Minute
+
+// API key errors
+var (
+	ErrInvalidScope    = errors.New("invalid scope")
+	ErrAPIKeyNotFound  = errors.New("API key not found")
+	ErrAPIKeyInactive  = errors.New("API key is revoked or expired")
+	ErrInvalidKeyGrace = errors.New("invalid grace period")
+)
+
+// APIKeyService defines the interface for managing and checking scoped API keys
+type APIKeyService interface {
+	// CreateKey creates a key and returns it with its secret, which is not stored and cannot be retrieved later
+	CreateKey(ctx context.Context, name string, scopes []string) (*models.APIKey, string, error)
+	RevokeKey(ctx context.Context, id string) error
+	// RotateKey creates a key with the same name and scopes to replace an active one, which keeps working
+	// for the grace period, or until it is retired when grace is 0
+	RotateKey(ctx context.Context, id string, grace time.Duration) (*models.APIKey, string, error)
+	// RetireKey revokes a key after a grace period, or right away when it is 0
+	RetireKey(ctx context.Context, id string, grace time.Duration) (*models.APIKey, error)
+	ListKeys(ctx context.Context) ([]*models.APIKey, error)
+	// Authenticate returns the active key with the given secret, or nil if there is none
+	Authenticate(ctx context.Context, secret string) (*models.APIKey, error)
+	HasActiveKeys(ctx context.Context) (bool, error)
+}
+
+// APIKeyServiceImpl implements APIKeyService
+type APIKeyServiceImpl struct {
+	repo   repository.SubscriptionRepository
+	logger *utils.Logger
+	clockAndIDs
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(repo repository.SubscriptionRepository, logger *utils.Logger) *APIKeyServiceImpl {
+	return &APIKeyServiceImpl{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateKey creates a key granting the given scopes
+func (service *APIKeyServiceImpl) CreateKey(ctx context.Context, name string, scopes []string) (*models.APIKey, string, error) {
+	return service.createKey(ctx, name, scopes, "")
+}
+
+// createKey creates a key granting the given scopes, replacing the key rotatedFrom when it is set
+func (service *APIKeyServiceImpl) createKey(ctx context.Context, name string, scopes []string, rotatedFrom string) (*models.APIKey, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	granted := make([]string, 0, len(scopes))
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		if !models.IsValidScope(scope) {
+			return nil, "", fmt.Errorf("%w %q", ErrInvalidScope, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			granted = append(granted, scope)
+		}
+	}
+	sort.Strings(granted)
+
+	id, err := service.newID()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate key id: %w", err)
+	}
+	randomBytes := make([]byte, 24)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, "", fmt.Errorf("failed to generate key secret: %w", err)
+	}
+	secret := apiKeySecretPrefix + hex.EncodeToString(randomBytes)
+
+	key := &models.APIKey{
+		ID:          "key_" + id,
+		Name:        name,
+		Prefix:      secret[:apiKeyDisplayLength],
+		Hash:        hashAPIKeySecret(secret),
+		Scopes:      granted,
+		CreatedAt:   service.now(),
+		RotatedFrom: rotatedFrom,
+	}
+	if err := service.repo.SaveAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+	service.logger.Info(fmt.Sprintf("Created API key %s (%s) with scopes %v", key.ID, key.Name, key.Scopes))
+	return key, secret, nil
+}
+
+// RevokeKey revokes a key; the record is kept so it still appears in listings
+func (service *APIKeyServiceImpl) RevokeKey(ctx context.Context, id string) error {
+	_, err := service.RetireKey(ctx, id, 0)
+	return err
+}
+
+// RetireKey revokes a key once a grace period is over, so its user can switch to another key first.
+// Retiring a key again changes when it stops working.
+func (service *APIKeyServiceImpl) RetireKey(ctx context.Context, id string, grace time.Duration) (*models.APIKey, error) {
+	if grace < 0 {
+		return nil, fmt.Errorf("%w: it cannot be negative", ErrInvalidKeyGrace)
+	}
+	key, err := service.repo.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+	now := service.now()
+	if !key.Active(now) {
+		return key, nil
+	}
+
+	if grace == 0 {
+		key.RevokedAt = &now
+	} else {
+		expiresAt := now.Add(grace)
+		key.ExpiresAt = &expiresAt
+	}
+	if err := service.repo.SaveAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+	if grace == 0 {
+		service.logger.Info(fmt.Sprintf("Revoked API key %s (%s)", key.ID, key.Name))
+	} else {
+		service.logger.Info(fmt.Sprintf("API key %s (%s) expires at %s", key.ID, key.Name, key.ExpiresAt.Format(time.RFC3339)))
+	}
+	return key, nil
+}
+
+// RotateKey issues the next key for an active key. Both keys work until the old one is retired, so
+// the client can switch without any request being refused.
+func (service *APIKeyServiceImpl) RotateKey(ctx context.Context, id string, grace time.Duration) (*models.APIKey, string, error) {
+	if grace < 0 {
+		return nil, "", fmt.Errorf("%w: it cannot be negative", ErrInvalidKeyGrace)
+	}
+	old, err := service.repo.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if old == nil {
+		return nil, "", ErrAPIKeyNotFound
+	}
+	if !old.Active(service.now()) {
+		return nil, "", ErrAPIKeyInactive
+	}
+
+	key, secret, err := service.createKey(ctx, old.Name, old.Scopes, old.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	old.ReplacedBy = key.ID
+	if grace > 0 {
+		expiresAt := service.now().Add(grace)
+		old.ExpiresAt = &expiresAt
+	}
+	if err := service.repo.SaveAPIKey(ctx, old); err != nil {
+		return nil, "", err
+	}
+	service.logger.Info(fmt.Sprintf("Rotated API key %s (%s) to %s", old.ID, old.Name, key.ID))
+	return key, secret, nil
+}
+
+// ListKeys lists every key, including revoked keys, oldest first
+func (service *APIKeyServiceImpl) ListKeys(ctx context.Context) ([]*models.APIKey, error) {
+	keys, err := service.repo.GetAllAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// Authenticate looks up an active key by secret
+func (service *APIKeyServiceImpl) Authenticate(ctx context.Context, secret string) (*models.APIKey, error) {
+	if secret == "" {
+		return nil, nil
+	}
+	key, err := service.repo.GetAPIKeyByHash(ctx, hashAPIKeySecret(secret))
+	now := service.now()
+	if err != nil || key == nil || !key.Active(now) {
+		return nil, err
+	}
+
+	// The last use shows when a rotated key's client has switched to the next one
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyUsageInterval {
+		key.LastUsedAt = &now
+		if err := service.repo.SaveAPIKey(ctx, key); err != nil {
+			service.logger.Warning(fmt.Sprintf("Failed to record the use of API key %s: %v", key.ID, err))
+		}
+	}
+	return key, nil
+}
+
+// HasActiveKeys reports whether any key has not been revoked or expired
+func (service *APIKeyServiceImpl) HasActiveKeys(ctx context.Context) (bool, error) {
+	keys, err := service.repo.GetAllAPIKeys(ctx)
+	if err != nil {
+		return false, err
+	}
+	now := service.now()
+	for _, key := range keys {
+		if key.Active(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// hashAPIKeySecret returns the hex SHA-256 of a secret. Secrets are long and random, so a fast
+// unsalted hash is enough to keep them out of storage.
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// GetAuditLog returns the audit entries matching a filter, most recent first
+func (service *SubscriptionServiceImpl) GetAuditLog(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEntry, error) {
+	return service.repo.GetAuditEntries(ctx, filter)
+}
+
+// recordAudit stores a change in the audit log; failures are logged and never block the change itself.
+// The change has already been saved, so the entry is written even if the caller's context was cancelled.
+func (service *SubscriptionServiceImpl) recordAudit(ctx context.Context, actor, action, resourceType, resourceID, channelID string, oldValue, newValue interface{}) {
+	id, err := service.newID()
+	if err != nil {
+		service.logger.Warning(fmt.Sprintf("Failed to generate audit entry id: %v", err))
+		return
+	}
+
+	entry := &models.AuditEntry{
+		ID:           "audit_" + id,
+		Actor:        actor,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		ChannelID:    channelID,
+		Changes:      changedFields(oldValue, newValue),
+		OldValue:     oldValue,
+		NewValue:     newValue,
+		Timestamp:    service.now(),
+	}
+	if err := service.repo.SaveAuditEntry(context.WithoutCancel(ctx), entry); err != nil {
+		service.logger.Warning(fmt.Sprintf("Failed to record audit entry: %v", err))
+	}
+}
+
+// changedFields returns the sorted JSON field names whose values differ between two values
+func changedFields(oldValue, newValue interface{}) []string {
+	oldFields := jsonFields(oldValue)
+	newFields := jsonFields(newValue)
+
+	changes := []string{}
+	for name, value := range newFields {
+		if !reflect.DeepEqual(oldFields[name], value) {
+			changes = append(changes, name)
+		}
+	}
+	for name := range oldFields {
+		if _, ok := newFields[name]; !ok {
+			changes = append(changes, name)
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// jsonFields decodes a value's JSON representation into a field map, returning nil for nil values
+func jsonFields(value interface{}) map[string]interface{} {
+	if value == nil || reflect.ValueOf(value).IsNil() {
+		return nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil
+	}
+	return fields
+}
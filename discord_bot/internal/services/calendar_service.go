@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// DefaultCalendarLead is how long before a calendar event its themed digest is posted when
+// CALENDAR_DIGEST_LEAD is not set
+const DefaultCalendarLead = 24 * time.Hour
+
+// CalendarBrowseWindow is how far ahead events_calendar lists events
+const CalendarBrowseWindow = 14 * 24 * time.Hour
+
+// calendarDigestSize is the number of markets listed in a themed digest
+const calendarDigestSize = 10
+
+// ErrCalendarEventNotFound is returned for an event ID or name that matches no upcoming event
+var ErrCalendarEventNotFound = errors.New("no upcoming calendar event matches")
+
+// CalendarService defines the interface for browsing upcoming calendar events, like elections and
+// sports fixtures, and posting themed digests of the markets the backend tags to them
+type CalendarService interface {
+	UpcomingEvents(ctx context.Context, within time.Duration) ([]*models.CalendarEvent, error)
+	FindEvent(ctx context.Context, query string) (*models.CalendarEvent, error)
+	BuildThemedDigest(ctx context.Context, event *models.CalendarEvent, config *models.ChannelConfig) (string, error)
+	ProcessDueThemedDigests(ctx context.Context) int
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// CalendarServiceImpl implements CalendarService
+type CalendarServiceImpl struct {
+	repo          repository.SubscriptionRepository
+	marketService MarketService
+	notifier      Notifier
+	lead          time.Duration
+	logger        *utils.Logger
+	clockAndIDs
+	linkAllowlist
+}
+
+// NewCalendarService creates a calendar service posting each event's themed digest lead before it
+// starts, or DefaultCalendarLead before when lead is 0
+func NewCalendarService(
+	repo repository.SubscriptionRepository,
+	marketService MarketService,
+	notifier Notifier,
+	lead time.Duration,
+	logger *utils.Logger,
+) *CalendarServiceImpl {
+	if lead <= 0 {
+		lead = DefaultCalendarLead
+	}
+	return &CalendarServiceImpl{
+		repo:          repo,
+		marketService: marketService,
+		notifier:      notifier,
+		lead:          lead,
+		logger:        logger,
+	}
+}
+
+// UpcomingEvents returns the events starting within the given time, soonest first
+func (service *CalendarServiceImpl) UpcomingEvents(ctx context.Context, within time.Duration) ([]*models.CalendarEvent, error) {
+	events, err := service.marketService.FetchCalendarEvents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := service.now()
+	var upcoming []*models.CalendarEvent
+	for _, event := range events {
+		if event.StartTime.After(now) && !event.StartTime.After(now.Add(within)) {
+			upcoming = append(upcoming, event)
+		}
+	}
+	sort.Slice(upcoming, func(i, j int) bool { return upcoming[i].StartTime.Before(upcoming[j].StartTime) })
+	return upcoming, nil
+}
+
+// FindEvent returns the upcoming event with the given ID, or else the soonest whose name contains the
+// query, ignoring case
+func (service *CalendarServiceImpl) FindEvent(ctx context.Context, query string) (*models.CalendarEvent, error) {
+	events, err := service.UpcomingEvents(ctx, CalendarBrowseWindow)
+	if err != nil {
+		return nil, err
+	}
+	query = strings.TrimSpace(query)
+	for _, event := range events {
+		if event.ID == query {
+			return event, nil
+		}
+	}
+	for _, event := range events {
+		if query != "" && strings.Contains(strings.ToLower(event.Name), strings.ToLower(query)) {
+			return event, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrCalendarEventNotFound, query)
+}
+
+// BuildThemedDigest renders the digest of the active markets tagged to an event, keeping those in the
+// channel's allowed categories when a channel is given
+func (service *CalendarServiceImpl) BuildThemedDigest(ctx context.Context, event *models.CalendarEvent, config *models.ChannelConfig) (string, error) {
+	markets, err := service.marketService.FetchAllMarkets(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch markets: %w", err)
+	}
+	message, _ := service.renderThemedDigest(event, eventMarkets(event, filterDigestMarkets(markets, config)))
+	if config != nil {
+		message = LocalizeTimestamps(message, timezoneOrNil(config.Timezone))
+	}
+	return message, nil
+}
+
+// eventMarkets returns the active markets tagged to an event, by volume
+func eventMarkets(event *models.CalendarEvent, markets []*models.Market) []*models.Market {
+	tagged := make(map[string]bool, len(event.MarketIDs))
+	for _, marketID := range event.MarketIDs {
+		tagged[marketID] = true
+	}
+	var matches []*models.Market
+	for _, market := range markets {
+		if tagged[market.ID] && market.Status == "active" {
+			matches = append(matches, market)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Volume > matches[j].Volume })
+	return matches
+}
+
+// renderThemedDigest formats an event's digest, reporting whether it lists any markets
+func (service *CalendarServiceImpl) renderThemedDigest(event *models.CalendarEvent, markets []*models.Market) (string, bool) {
+	var message strings.Builder
+	fmt.Fprintf(&message, "🗓️ **Markets about %s** — starts %s\n", EscapeMarkdown(event.Name), DiscordTimestamp(event.StartTime, TimestampRelative))
+	if len(markets) == 0 {
+		message.WriteString("\nNo open markets are about this event yet.\n")
+		return message.String(), false
+	}
+	message.WriteString("\n")
+	for i, market := range markets {
+		if i == calendarDigestSize {
+			fmt.Fprintf(&message, "…and %d more\n", len(markets)-calendarDigestSize)
+			break
+		}
+		leader := ""
+		if outcome, percentage, ok := leadingOutcome(market); ok {
+			leader = fmt.Sprintf("%s %.0f%%, ", EscapeMarkdown(outcome), percentage)
+		}
+		fmt.Fprintf(&message, "• %s — %s%s volume\n", service.markdownLink(market.Title, digestTitleLength, market.Link), leader, compactAmount(market.Volume))
+	}
+	return message.String(), true
+}
+
+// ProcessDueThemedDigests posts the digest of each event starting within the lead to every opted-in
+// channel that has not had it, and returns how many were delivered. Events without markets in a
+// channel's categories are skipped, so their digest goes out once markets are tagged to them.
+func (service *CalendarServiceImpl) ProcessDueThemedDigests(ctx context.Context) int {
+	configs, err := service.repo.GetAllChannelConfigs(ctx)
+	if err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to get channel configs: %v", err))
+		return 0
+	}
+
+	var events []*models.CalendarEvent
+	var markets []*models.Market
+	sent := 0
+	for _, config := range configs {
+		if !config.CalendarDigests {
+			continue
+		}
+		if events == nil {
+			if events, err = service.UpcomingEvents(ctx, service.lead); err != nil {
+				service.logger.Error(fmt.Sprintf("Failed to fetch calendar events: %v", err))
+				return sent
+			}
+			if len(events) == 0 {
+				return sent
+			}
+			if markets, err = service.marketService.FetchAllMarkets(ctx); err != nil {
+				service.logger.Error(fmt.Sprintf("Failed to fetch markets for themed digests: %v", err))
+				return sent
+			}
+		}
+
+		posted := make(map[string]bool, len(config.CalendarDigestsSent))
+		for _, eventID := range config.CalendarDigestsSent {
+			posted[eventID] = true
+		}
+		var kept []string // the posted events still upcoming, so the list does not grow
+		changed := false
+		for _, event := range events {
+			if posted[event.ID] {
+				kept = append(kept, event.ID)
+				continue
+			}
+			message, ok := service.renderThemedDigest(event, eventMarkets(event, filterDigestMarkets(markets, config)))
+			if !ok {
+				continue
+			}
+			message = LocalizeTimestamps(message, timezoneOrNil(config.Timezone))
+			if err := service.notifier.SendChannelMessage(ctx, config.ChannelID, message); err != nil {
+				service.logger.Error(fmt.Sprintf("Failed to send the %s digest to channel %s: %v", event.Name, config.ChannelID, err))
+			} else {
+				sent++
+			}
+			// Like digests, a failed themed digest is not retried
+			kept = append(kept, event.ID)
+			changed = true
+		}
+
+		if changed || len(kept) != len(config.CalendarDigestsSent) {
+			config.CalendarDigestsSent = kept
+			if err := service.repo.SaveChannelConfig(ctx, config); err != nil {
+				service.logger.Error(fmt.Sprintf("Failed to save themed digests of channel %s: %v", config.ChannelID, err))
+			}
+		}
+	}
+	return sent
+}
+
+// Run checks for due themed digests on every tick until the context is cancelled
+func (service *CalendarServiceImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	service.logger.Info(fmt.Sprintf("Calendar digest scheduler started (lead %s, interval %s)", service.lead, interval))
+	for {
+		select {
+		case <-ctx.Done():
+			service.logger.Info("Calendar digest scheduler stopped")
+			return
+		case <-ticker.C:
+			if sent := service.ProcessDueThemedDigests(ctx); sent > 0 {
+				service.logger.Info(fmt.Sprintf("Sent %d themed digests", sent))
+			}
+		}
+	}
+}
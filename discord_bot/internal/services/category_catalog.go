@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// CategoryCacheTTL is how long the backend's category list is used before it is fetched again
+const CategoryCacheTTL = time.Hour
+
+// maxCategorySuggestions is the number of close matches suggested for an unknown category
+const maxCategorySuggestions = 3
+
+// UnknownCategory is a category name the backend does not know, with the closest names it does
+type UnknownCategory struct {
+	Name        string
+	Suggestions []string
+}
+
+// CategoryCatalog caches the canonical market categories from the backend, so commands can complete
+// and validate category names without a backend call on every keystroke
+type CategoryCatalog struct {
+	marketService MarketService
+	ttl           time.Duration
+	logger        *utils.Logger
+	categories    []string
+	fetchedAt     time.Time
+	mutex         sync.Mutex
+}
+
+// NewCategoryCatalog creates a category catalog that fetches the categories again once they are older than ttl
+func NewCategoryCatalog(marketService MarketService, ttl time.Duration, logger *utils.Logger) *CategoryCatalog {
+	return &CategoryCatalog{
+		marketService: marketService,
+		ttl:           ttl,
+		logger:        logger,
+	}
+}
+
+// Categories returns the canonical categories in alphabetical order. When the backend cannot be reached
+// the last list fetched is returned, and an error only when there is none.
+func (catalog *CategoryCatalog) Categories(ctx context.Context) ([]string, error) {
+	catalog.mutex.Lock()
+	defer catalog.mutex.Unlock()
+
+	if catalog.categories != nil && time.Since(catalog.fetchedAt) < catalog.ttl {
+		return catalog.categories, nil
+	}
+	fetched, err := catalog.marketService.FetchCategories(ctx)
+	if err != nil {
+		if catalog.categories != nil {
+			catalog.logger.Warning(fmt.Sprintf("Using cached market categories: %v", err))
+			return catalog.categories, nil
+		}
+		return nil, err
+	}
+
+	categories := make([]string, 0, len(fetched))
+	seen := make(map[string]bool, len(fetched))
+	for _, category := range fetched {
+		normalized := models.NormalizeCategory(category)
+		if normalized == "" || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		categories = append(categories, strings.TrimSpace(category))
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return models.NormalizeCategory(categories[i]) < models.NormalizeCategory(categories[j])
+	})
+	catalog.categories, catalog.fetchedAt = categories, time.Now()
+	return categories, nil
+}
+
+// Resolve returns the canonical spelling of each category name, matched case-insensitively, and the
+// names that match no category with suggestions for each
+func (catalog *CategoryCatalog) Resolve(ctx context.Context, names []string) ([]string, []UnknownCategory, error) {
+	categories, err := catalog.Categories(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	canonical := make(map[string]string, len(categories))
+	for _, category := range categories {
+		canonical[models.NormalizeCategory(category)] = category
+	}
+
+	var resolved []string
+	var unknown []UnknownCategory
+	for _, name := range names {
+		if category, ok := canonical[models.NormalizeCategory(name)]; ok {
+			resolved = append(resolved, category)
+			continue
+		}
+		unknown = append(unknown, UnknownCategory{Name: name, Suggestions: SuggestCategories(name, categories)})
+	}
+	return resolved, unknown, nil
+}
+
+// Complete returns up to limit categories starting with prefix, then those containing it
+func (catalog *CategoryCatalog) Complete(ctx context.Context, prefix string, limit int) []string {
+	categories, err := catalog.Categories(ctx)
+	if err != nil {
+		catalog.logger.Warning(fmt.Sprintf("Failed to get market categories for completion: %v", err))
+		return nil
+	}
+	prefix = models.NormalizeCategory(prefix)
+	var starting, containing []string
+	for _, category := range categories {
+		normalized := models.NormalizeCategory(category)
+		switch {
+		case strings.HasPrefix(normalized, prefix):
+			starting = append(starting, category)
+		case strings.Contains(normalized, prefix):
+			containing = append(containing, category)
+		}
+	}
+	matches := append(starting, containing...)
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// SuggestCategories returns the categories closest to an unknown name: those it is a prefix of, then
+// those within a few typos of it, nearest first
+func SuggestCategories(name string, categories []string) []string {
+	name = models.NormalizeCategory(name)
+	if name == "" {
+		return nil
+	}
+	type candidate struct {
+		category string
+		distance int
+	}
+	var candidates []candidate
+	maxDistance := len(name) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+	for _, category := range categories {
+		normalized := models.NormalizeCategory(category)
+		if strings.HasPrefix(normalized, name) {
+			candidates = append(candidates, candidate{category, 0})
+			continue
+		}
+		if distance := editDistance(name, normalized); distance <= maxDistance {
+			candidates = append(candidates, candidate{category, distance})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+
+	var suggestions []string
+	for i := 0; i < len(candidates) && i < maxCategorySuggestions; i++ {
+		suggestions = append(suggestions, candidates[i].category)
+	}
+	return suggestions
+}
+
+// editDistance returns the number of single-character insertions, deletions and substitutions that
+// turn a into b
+func editDistance(a, b string) int {
+	source, target := []rune(a), []rune(b)
+	previous := make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(source); i++ {
+		current := make([]int, len(target)+1)
+		current[0] = i
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(target)]
+}
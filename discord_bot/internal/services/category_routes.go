@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// ErrInvalidCategoryRoute is returned when a category route is missing its guild, category or channel
+var ErrInvalidCategoryRoute = errors.New("invalid category route")
+
+// SetCategoryRoute routes a guild's new-market announcements of a category to a channel
+func (service *SubscriptionServiceImpl) SetCategoryRoute(ctx context.Context, guildID, category, channelID, actor string) (*models.CategoryRoute, error) {
+	category = models.NormalizeCategory(category)
+	if guildID == "" || category == "" || channelID == "" {
+		return nil, fmt.Errorf("%w: guild, category and channel are required", ErrInvalidCategoryRoute)
+	}
+
+	previous, err := service.findCategoryRoute(ctx, guildID, category)
+	if err != nil {
+		return nil, err
+	}
+	route := &models.CategoryRoute{
+		GuildID:   guildID,
+		Category:  category,
+		ChannelID: channelID,
+		CreatedBy: actor,
+		UpdatedAt: service.now(),
+	}
+	if err := service.repo.SaveCategoryRoute(ctx, route); err != nil {
+		return nil, fmt.Errorf("failed to save category route: %w", err)
+	}
+
+	if previous == nil {
+		service.recordAudit(ctx, actor, models.AuditActionCreate, models.AuditResourceCategoryRoute, category, channelID, nil, route)
+	} else {
+		service.recordAudit(ctx, actor, models.AuditActionUpdate, models.AuditResourceCategoryRoute, category, channelID, previous, route)
+	}
+	return route, nil
+}
+
+// RemoveCategoryRoute removes a guild's route for a category and reports whether there was one
+func (service *SubscriptionServiceImpl) RemoveCategoryRoute(ctx context.Context, guildID, category, actor string) (bool, error) {
+	category = models.NormalizeCategory(category)
+	previous, err := service.findCategoryRoute(ctx, guildID, category)
+	if err != nil || previous == nil {
+		return false, err
+	}
+	if _, err := service.repo.DeleteCategoryRoute(ctx, guildID, category); err != nil {
+		return false, fmt.Errorf("failed to delete category route: %w", err)
+	}
+	service.recordAudit(ctx, actor, models.AuditActionDelete, models.AuditResourceCategoryRoute, category, previous.ChannelID, previous, nil)
+	return true, nil
+}
+
+// GetCategoryRoutes returns a guild's category routes sorted by category
+func (service *SubscriptionServiceImpl) GetCategoryRoutes(ctx context.Context, guildID string) ([]*models.CategoryRoute, error) {
+	return service.repo.GetCategoryRoutes(ctx, guildID)
+}
+
+// GetAllCategoryRoutes returns the category routes of every guild
+func (service *SubscriptionServiceImpl) GetAllCategoryRoutes(ctx context.Context) ([]*models.CategoryRoute, error) {
+	return service.repo.GetAllCategoryRoutes(ctx)
+}
+
+// findCategoryRoute returns a guild's route for a normalized category, or nil
+func (service *SubscriptionServiceImpl) findCategoryRoute(ctx context.Context, guildID, category string) (*models.CategoryRoute, error) {
+	routes, err := service.repo.GetCategoryRoutes(ctx, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category routes: %w", err)
+	}
+	for _, route := range routes {
+		if route.Category == category {
+			return route, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// MaxBackfillHours is the furthest back a channel backfill reaches
+const MaxBackfillHours = 72
+
+// BackfillCooldown is how long a channel waits between backfills
+const BackfillCooldown = 10 * time.Minute
+
+// maxBackfillMarkets is the number of markets a backfill digest lists, the newest first
+const maxBackfillMarkets = 20
+
+var (
+	// ErrInvalidBackfillWindow is returned for backfill windows outside 1 to MaxBackfillHours hours
+	ErrInvalidBackfillWindow = errors.New("invalid backfill window")
+	// ErrBackfillTooSoon is returned when the channel was backfilled less than BackfillCooldown ago
+	ErrBackfillTooSoon = errors.New("channel was backfilled too recently")
+)
+
+// ChannelBackfillService defines the interface for backfilling channels with the markets created before
+// they were configured, skipping the markets already announced in them
+type ChannelBackfillService interface {
+	Backfill(ctx context.Context, channelID string, hours int) (int, error)
+}
+
+// ChannelBackfillServiceImpl implements ChannelBackfillService
+type ChannelBackfillServiceImpl struct {
+	repo          repository.SubscriptionRepository
+	marketService MarketService
+	notifier      Notifier
+	logger        *utils.Logger
+	linkAllowlist
+
+	mutex        sync.Mutex
+	backfilledAt map[string]time.Time // last backfill by channel, for the cooldown
+	clockAndIDs
+}
+
+// NewChannelBackfillService creates a new channel backfill service
+func NewChannelBackfillService(
+	repo repository.SubscriptionRepository,
+	marketService MarketService,
+	notifier Notifier,
+	logger *utils.Logger,
+) *ChannelBackfillServiceImpl {
+	return &ChannelBackfillServiceImpl{
+		repo:          repo,
+		marketService: marketService,
+		notifier:      notifier,
+		logger:        logger,
+		backfilledAt:  make(map[string]time.Time),
+	}
+}
+
+// Backfill posts a digest of the active markets created in the last hours that the channel's settings
+// accept and that were not announced there yet, and returns how many it listed. Nothing is posted when
+// there are none. A channel is backfilled at most once per BackfillCooldown; a backfill that fails
+// does not count.
+func (service *ChannelBackfillServiceImpl) Backfill(ctx context.Context, channelID string, hours int) (int, error) {
+	if hours < 1 || hours > MaxBackfillHours {
+		return 0, fmt.Errorf("%w: hours must be between 1 and %d", ErrInvalidBackfillWindow, MaxBackfillHours)
+	}
+	now := service.now()
+	if !service.claim(channelID, now) {
+		return 0, ErrBackfillTooSoon
+	}
+	listed, err := service.backfill(ctx, channelID, time.Duration(hours)*time.Hour, now)
+	if err != nil || listed == 0 {
+		service.release(channelID, now)
+	}
+	return listed, err
+}
+
+// backfill posts the digest of a claimed backfill and records its markets as announced
+func (service *ChannelBackfillServiceImpl) backfill(ctx context.Context, channelID string, window time.Duration, now time.Time) (int, error) {
+	config, err := service.repo.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get channel config: %w", err)
+	}
+	announced, err := service.repo.GetAnnouncedMarkets(ctx, channelID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get announced markets: %w", err)
+	}
+	markets, err := service.marketService.FetchAllMarkets(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch markets: %w", err)
+	}
+
+	since := now.Add(-window)
+	var recent []*models.Market
+	for _, market := range markets {
+		if market.Status != "active" || market.StartTime.Before(since) || market.StartTime.After(now) || announced[market.ID] || !backfillAccepts(config, market) {
+			continue
+		}
+		recent = append(recent, market)
+	}
+	if len(recent) == 0 {
+		return 0, nil
+	}
+	sort.Slice(recent, func(i, j int) bool { return recent[i].StartTime.After(recent[j].StartTime) })
+
+	message := LocalizeTimestamps(service.digest(recent, window), timezoneOrNil(config.Timezone))
+	if err := service.notifier.SendChannelMessage(ctx, channelID, message); err != nil {
+		return 0, fmt.Errorf("failed to post backfill: %w", err)
+	}
+	for _, market := range recent {
+		if _, err := service.repo.MarkMarketAnnounced(ctx, channelID, market.ID, now); err != nil {
+			service.logger.Warning(fmt.Sprintf("Failed to record backfill of market %s in channel %s: %v", market.ID, channelID, err))
+		}
+	}
+	service.logger.Info(fmt.Sprintf("Backfilled channel %s with %d markets from the last %s", channelID, len(recent), window))
+	return len(recent), nil
+}
+
+// digest lists the newest of the recent markets on one line each, with how many more there are
+func (service *ChannelBackfillServiceImpl) digest(recent []*models.Market, window time.Duration) string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "🕰️ **Catching Up** — %d new %s from the last %d hours\n\n", len(recent), map[bool]string{true: "market", false: "markets"}[len(recent) == 1], int(window.Hours()))
+	for i, market := range recent {
+		if i == maxBackfillMarkets {
+			fmt.Fprintf(&message, "…and %d more\n", len(recent)-maxBackfillMarkets)
+			break
+		}
+		fmt.Fprintf(&message, "• %s — %s volume, opened %s\n", service.markdownLink(market.Title, digestTitleLength, market.Link), compactAmount(market.Volume), DiscordTimestamp(market.StartTime, TimestampRelative))
+	}
+	return message.String()
+}
+
+// backfillAccepts reports whether a channel's settings accept a new market: it follows the market, or
+// its feed is on and the market is in its allowed categories and at its minimum volume
+func backfillAccepts(config *models.ChannelConfig, market *models.Market) bool {
+	for _, id := range config.SubscribedMarkets {
+		if id == market.ID {
+			return true
+		}
+	}
+	if !config.FeedEnabled || market.Volume < config.MinVolume {
+		return false
+	}
+	if len(config.AllowedCategories) == 0 {
+		return true
+	}
+	for _, category := range config.AllowedCategories {
+		if category == market.Category {
+			return true
+		}
+	}
+	return false
+}
+
+// claim starts a channel's backfill, returning false while the channel is in its cooldown
+func (service *ChannelBackfillServiceImpl) claim(channelID string, now time.Time) bool {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	if last, ok := service.backfilledAt[channelID]; ok && now.Sub(last) < BackfillCooldown {
+		return false
+	}
+	service.backfilledAt[channelID] = now
+	return true
+}
+
+// release ends the cooldown of a backfill that posted nothing
+func (service *ChannelBackfillServiceImpl) release(channelID string, claimed time.Time) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	if service.backfilledAt[channelID].Equal(claimed) {
+		delete(service.backfilledAt, channelID)
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// RemoveChannel forgets a deleted channel, so events stop being sent to it: its configuration is
+// deleted, its webhook registrations are unregistered, category routes to it and routing rules scoped to
+// or routing to it are removed and it stops being the default or community stats channel of its guild
+func (service *SubscriptionServiceImpl) RemoveChannel(ctx context.Context, channelID, actor string) error {
+	return service.withTx(ctx, func(tx *SubscriptionServiceImpl) error {
+		return tx.removeChannel(ctx, channelID, actor)
+	})
+}
+
+// removeChannel is RemoveChannel within a transaction
+func (service *SubscriptionServiceImpl) removeChannel(ctx context.Context, channelID, actor string) error {
+	config, err := service.repo.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel config: %w", err)
+	}
+	deleted, err := service.repo.DeleteChannelConfig(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to delete channel config: %w", err)
+	}
+	if deleted {
+		service.recordAudit(ctx, actor, models.AuditActionDelete, models.AuditResourceChannelConfig, channelID, channelID, config, nil)
+	}
+
+	webhooks, err := service.ListWebhookRegistrationsByChannel(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get webhook registrations: %w", err)
+	}
+	for _, registration := range webhooks {
+		if err := service.UnregisterWebhook(ctx, registration.ID, actor); err != nil {
+			return fmt.Errorf("failed to unregister webhook %s: %w", registration.ID, err)
+		}
+	}
+
+	routes, err := service.repo.GetAllCategoryRoutes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get category routes: %w", err)
+	}
+	removedRoutes := 0
+	for _, route := range routes {
+		if route.ChannelID != channelID {
+			continue
+		}
+		if _, err := service.RemoveCategoryRoute(ctx, route.GuildID, route.Category, actor); err != nil {
+			return err
+		}
+		removedRoutes++
+	}
+	removedRules, err := service.removeRoutingRulesWhere(ctx, "", func(rule *models.RoutingRule) bool {
+		return rule.ChannelID == channelID || rule.TargetChannelID == channelID
+	}, actor)
+	if err != nil {
+		return err
+	}
+
+	guilds, err := service.repo.GetAllGuildConfigs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get guild configs: %w", err)
+	}
+	clearedDefault := false
+	for _, guild := range guilds {
+		if guild.DefaultChannelID != channelID && guild.StatsChannelID != channelID {
+			continue
+		}
+		if guild.DefaultChannelID == channelID {
+			guild.DefaultChannelID = ""
+			clearedDefault = true
+		}
+		if guild.StatsChannelID == channelID {
+			guild.StatsChannelID = ""
+		}
+		if err := service.UpdateGuildConfig(ctx, guild); err != nil {
+			return fmt.Errorf("failed to clear channel %s from guild %s: %w", channelID, guild.GuildID, err)
+		}
+	}
+
+	held, err := service.repo.DeleteHeldNotifications(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to delete held events: %w", err)
+	}
+
+	if deleted || len(webhooks) > 0 || removedRoutes > 0 || removedRules > 0 || clearedDefault || held > 0 {
+		service.logger.Info(fmt.Sprintf("Removed channel %s: config deleted %t, %d webhooks unregistered, %d category routes and %d routing rules removed, default channel cleared %t, %d held events dropped",
+			channelID, deleted, len(webhooks), removedRoutes, removedRules, clearedDefault, held))
+	}
+	return nil
+}
+
+// RemoveGuild forgets a guild the bot was removed from. Every channel of the guild, the configured ones
+// and the given ones known from the gateway, is removed like a deleted channel, then the guild's
+// category routes, routing rules and configuration are deleted.
+func (service *SubscriptionServiceImpl) RemoveGuild(ctx context.Context, guildID string, channelIDs []string, actor string) error {
+	return service.withTx(ctx, func(tx *SubscriptionServiceImpl) error {
+		return tx.removeGuild(ctx, guildID, channelIDs, actor)
+	})
+}
+
+// removeGuild is RemoveGuild within a transaction
+func (service *SubscriptionServiceImpl) removeGuild(ctx context.Context, guildID string, channelIDs []string, actor string) error {
+	channels := make(map[string]bool, len(channelIDs))
+	for _, channelID := range channelIDs {
+		channels[channelID] = true
+	}
+	configs, err := service.repo.GetAllChannelConfigs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get channel configs: %w", err)
+	}
+	for _, config := range configs {
+		if config.GuildID == guildID {
+			channels[config.ChannelID] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(channels))
+	for channelID := range channels {
+		sorted = append(sorted, channelID)
+	}
+	sort.Strings(sorted)
+	for _, channelID := range sorted {
+		if err := service.RemoveChannel(ctx, channelID, actor); err != nil {
+			return fmt.Errorf("failed to remove channel %s: %w", channelID, err)
+		}
+	}
+
+	// Routes to channels the bot did not know about are left over, as are the rules for every channel
+	routes, err := service.repo.GetCategoryRoutes(ctx, guildID)
+	if err != nil {
+		return fmt.Errorf("failed to get category routes: %w", err)
+	}
+	for _, route := range routes {
+		if _, err := service.RemoveCategoryRoute(ctx, guildID, route.Category, actor); err != nil {
+			return err
+		}
+	}
+	if _, err := service.removeRoutingRulesWhere(ctx, guildID, func(*models.RoutingRule) bool { return true }, actor); err != nil {
+		return err
+	}
+	if _, err := service.repo.DeleteGuildConfig(ctx, guildID); err != nil {
+		return fmt.Errorf("failed to delete guild config: %w", err)
+	}
+
+	service.logger.Info(fmt.Sprintf("Removed guild %s and %d of its channels", guildID, len(sorted)))
+	return nil
+}
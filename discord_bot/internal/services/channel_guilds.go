@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ChannelGuildResolver finds the guild a channel belongs to, for channel configs saved without one
+type ChannelGuildResolver interface {
+	ChannelGuild(ctx context.Context, channelID string) (guildID string, err error)
+}
+
+// DiscordChannelGuilds implements ChannelGuildResolver using a Discord session
+type DiscordChannelGuilds struct {
+	session *discordgo.Session
+}
+
+// NewDiscordChannelGuilds creates a new Discord channel guild resolver
+func NewDiscordChannelGuilds(session *discordgo.Session) *DiscordChannelGuilds {
+	return &DiscordChannelGuilds{session: session}
+}
+
+// ChannelGuild returns the guild of a channel, empty for DM channels. The channel is read from the
+// gateway state and fetched from the API when the state does not have it.
+func (guilds *DiscordChannelGuilds) ChannelGuild(ctx context.Context, channelID string) (string, error) {
+	channel, err := guilds.session.State.Channel(channelID)
+	if err != nil {
+		channel, err = guilds.session.Channel(channelID, discordgo.WithContext(ctx))
+		if err != nil {
+			return "", fmt.Errorf("failed to get channel: %w", err)
+		}
+	}
+	return channel.GuildID, nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// feedPermissions are the permissions the bot needs to post feed alerts, in the order they are
+// reported: link previews need Embed Links and probability charts are attached files
+var feedPermissions = []struct {
+	permission int64
+	name       string
+}{
+	{discordgo.PermissionViewChannel, "View Channel"},
+	{discordgo.PermissionSendMessages, "Send Messages"},
+	{discordgo.PermissionEmbedLinks, "Embed Links"},
+	{discordgo.PermissionAttachFiles, "Attach Files"},
+}
+
+// MissingPermissionsError is returned by CheckFeedPermissions for channels the bot cannot post alerts in
+type MissingPermissionsError struct {
+	Missing []string // permission names, as shown in Discord's channel settings
+}
+
+func (err *MissingPermissionsError) Error() string {
+	return "the bot is missing the " + strings.Join(err.Missing, ", ") + " permissions in the channel"
+}
+
+// MissingFeedPermissions returns a MissingPermissionsError listing the feed permissions absent from a
+// permission set, or nil when it has them all
+func MissingFeedPermissions(permissions int64) error {
+	if permissions&discordgo.PermissionAdministrator != 0 {
+		return nil
+	}
+	var missing []string
+	for _, required := range feedPermissions {
+		if permissions&required.permission == 0 {
+			missing = append(missing, required.name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &MissingPermissionsError{Missing: missing}
+}
+
+// CheckFeedPermissions verifies that the bot can post feed alerts in a channel, returning a
+// *MissingPermissionsError when it cannot
+func CheckFeedPermissions(ctx context.Context, session *discordgo.Session, channelID string) error {
+	permissions, err := botChannelPermissions(ctx, session, channelID)
+	if err != nil {
+		return err
+	}
+	return MissingFeedPermissions(permissions)
+}
+
+// botChannelPermissions returns the bot's permissions in a channel. They are read from the gateway
+// state, which the Guilds intent keeps up to date, and fetched from the API when the state does not
+// have them.
+func botChannelPermissions(ctx context.Context, session *discordgo.Session, channelID string) (int64, error) {
+	if session.State == nil || session.State.User == nil {
+		return 0, errors.New("not connected to Discord")
+	}
+	botID := session.State.User.ID
+	permissions, err := session.State.UserChannelPermissions(botID, channelID)
+	if err != nil {
+		permissions, err = session.UserChannelPermissions(botID, channelID, discordgo.WithContext(ctx))
+		if err != nil {
+			return 0, fmt.Errorf("failed to get the bot's permissions: %w", err)
+		}
+	}
+	return permissions, nil
+}
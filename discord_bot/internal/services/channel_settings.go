@@ -0,0 +1,450 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// ErrInvalidChannelSettings is returned when imported channel settings fail validation
+var ErrInvalidChannelSettings = errors.New("invalid channel settings")
+
+// ValidateChannelSettings checks settings before they are applied to a channel
+func ValidateChannelSettings(settings *models.ChannelSettings) error {
+	if settings == nil {
+		return fmt.Errorf("%w: settings are required", ErrInvalidChannelSettings)
+	}
+	if settings.Version > models.ChannelSettingsVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidChannelSettings, settings.Version)
+	}
+	if err := validateFrequency(settings.FrequencyMode, settings.CustomInterval); err != nil {
+		return err
+	}
+	if settings.MinBuyAmount < 0 {
+		return fmt.Errorf("%w: min_buy_amount cannot be negative", ErrInvalidChannelSettings)
+	}
+	if settings.MinVolume < 0 {
+		return fmt.Errorf("%w: min_volume cannot be negative", ErrInvalidChannelSettings)
+	}
+	if hours := settings.ClosingSoonHours; hours != nil && (*hours < 0 || *hours > models.MaxClosingSoonHours) {
+		return fmt.Errorf("%w: closing_soon_hours must be between 0 and %d", ErrInvalidChannelSettings, models.MaxClosingSoonHours)
+	}
+	if !models.IsValidDigestMode(settings.DigestMode) {
+		return fmt.Errorf("%w: digest_mode must be daily, weekly or empty", ErrInvalidChannelSettings)
+	}
+	if _, err := LoadTimezone(settings.Timezone); err != nil {
+		return fmt.Errorf("%w: timezone %q is not an IANA zone", ErrInvalidChannelSettings, settings.Timezone)
+	}
+	if err := validateQuietHours(settings.QuietHoursStart, settings.QuietHoursEnd); err != nil {
+		return err
+	}
+	if err := validatePing(settings.PingRoleID, "", settings.PingEvents); err != nil {
+		return err
+	}
+	for _, marketID := range settings.SubscribedMarkets {
+		if strings.TrimSpace(marketID) == "" {
+			return fmt.Errorf("%w: subscribed_markets cannot contain empty IDs", ErrInvalidChannelSettings)
+		}
+	}
+	return nil
+}
+
+// ParseFrequency reads an update frequency, one of ChannelFrequencies or custom with an interval such as 45m
+func ParseFrequency(frequency, interval string) (string, time.Duration, error) {
+	frequency = strings.ToLower(strings.TrimSpace(frequency))
+	if frequency != models.FrequencyCustom {
+		return frequency, 0, validateFrequency(frequency, 0)
+	}
+	parsed, err := time.ParseDuration(strings.TrimSpace(interval))
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: the custom frequency needs an interval like 45m or 2h", ErrInvalidChannelSettings)
+	}
+	return frequency, parsed, validateFrequency(frequency, parsed)
+}
+
+// validateFrequency checks a channel's update frequency and, for the custom frequency, its interval
+func validateFrequency(frequency string, interval time.Duration) error {
+	if frequency == models.FrequencyCustom {
+		return validateInterval("custom_interval", interval)
+	}
+	for _, mode := range models.ChannelFrequencies {
+		if frequency == mode {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: frequency_mode must be one of %s or %s", ErrInvalidChannelSettings, strings.Join(models.ChannelFrequencies, ", "), models.FrequencyCustom)
+}
+
+// validateInterval checks an update interval is within the custom interval limits
+func validateInterval(name string, interval time.Duration) error {
+	if interval < models.MinCustomInterval || interval > models.MaxCustomInterval {
+		return fmt.Errorf("%w: %s must be between %s and %s", ErrInvalidChannelSettings, name, models.FormatInterval(models.MinCustomInterval), models.FormatInterval(models.MaxCustomInterval))
+	}
+	return nil
+}
+
+// ValidateUpdateIntervals checks a guild's update intervals, whose zero fields keep the bot's
+func ValidateUpdateIntervals(intervals *models.UpdateIntervals) error {
+	for _, field := range []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"high", intervals.High},
+		{"medium", intervals.Medium},
+		{"low", intervals.Low},
+		{"closing_soon_window", intervals.ClosingSoonWindow},
+		{"closing_soon_interval", intervals.ClosingSoonInterval},
+	} {
+		if field.interval == 0 {
+			continue
+		}
+		if err := validateInterval(field.name, field.interval); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetGuildUpdateIntervals sets the update intervals of a guild's channels, or returns them to the bot's
+// when intervals is nil
+func (service *SubscriptionServiceImpl) SetGuildUpdateIntervals(ctx context.Context, guildID string, intervals *models.UpdateIntervals) (*models.GuildConfig, error) {
+	if intervals != nil {
+		if err := ValidateUpdateIntervals(intervals); err != nil {
+			return nil, err
+		}
+	}
+	var result *models.GuildConfig
+	err := service.withTx(ctx, func(tx *SubscriptionServiceImpl) error {
+		config, err := tx.repo.GetGuildConfig(ctx, guildID)
+		if err != nil {
+			return fmt.Errorf("failed to get guild config: %w", err)
+		}
+		if config == nil {
+			config = &models.GuildConfig{GuildID: guildID}
+		}
+		config.UpdateIntervals = intervals
+		config.UpdatedAt = tx.now()
+		result = config
+		return tx.repo.SaveGuildConfig(ctx, config)
+	})
+	return result, err
+}
+
+// ApplyChannelSettings replaces a channel's settings with imported ones. The guild ID is only set
+// when given, so REST imports keep the guild the bot already knows for the channel.
+func (service *SubscriptionServiceImpl) ApplyChannelSettings(ctx context.Context, channelID, guildID string, settings *models.ChannelSettings, actor string) (*models.ChannelConfig, error) {
+	var result *models.ChannelConfig
+	err := service.withTx(ctx, func(tx *SubscriptionServiceImpl) error {
+		var err error
+		result, err = tx.applyChannelSettings(ctx, channelID, guildID, settings, actor)
+		return err
+	})
+	return result, err
+}
+
+// applyChannelSettings is ApplyChannelSettings within a transaction
+func (service *SubscriptionServiceImpl) applyChannelSettings(ctx context.Context, channelID, guildID string, settings *models.ChannelSettings, actor string) (*models.ChannelConfig, error) {
+	if err := ValidateChannelSettings(settings); err != nil {
+		return nil, err
+	}
+	config, err := service.repo.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel config: %w", err)
+	}
+
+	config.ApplySettings(settings)
+	config.LastUpdateTimestamp = service.now()
+	if guildID != "" {
+		config.GuildID = guildID
+	}
+	if err := service.UpdateChannelConfig(ctx, config, actor); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// MaxBulkChannelSettings is the most channels ApplyChannelSettingsBulk configures at once
+const MaxBulkChannelSettings = 100
+
+// ChannelSettingsChange is the settings to apply to one channel of a bulk change
+type ChannelSettingsChange struct {
+	ChannelID string
+	GuildID   string // set on the channel when not empty
+	Settings  *models.ChannelSettings
+}
+
+// InvalidChannelSettings is a change of a bulk change that failed validation
+type InvalidChannelSettings struct {
+	Index     int    `json:"index"` // position of the change in the request
+	ChannelID string `json:"channel_id"`
+	Error     string `json:"error"`
+}
+
+// BulkChannelSettingsError is returned when any change of a bulk change is invalid, listing each of
+// them; none of the changes is applied
+type BulkChannelSettingsError struct {
+	Invalid []InvalidChannelSettings
+}
+
+func (err *BulkChannelSettingsError) Error() string {
+	first := err.Invalid[0]
+	return fmt.Sprintf("%d invalid channel settings, the first for channel %q: %s", len(err.Invalid), first.ChannelID, first.Error)
+}
+
+// Unwrap makes the error match ErrInvalidChannelSettings
+func (err *BulkChannelSettingsError) Unwrap() error {
+	return ErrInvalidChannelSettings
+}
+
+// ApplyChannelSettingsBulk replaces the settings of several channels at once, such as every channel of
+// a new server. Every change and the guilds' channel limits are checked before any is applied, and the
+// changes are then applied in one transaction.
+func (service *SubscriptionServiceImpl) ApplyChannelSettingsBulk(ctx context.Context, changes []ChannelSettingsChange, actor string) ([]*models.ChannelConfig, error) {
+	if len(changes) == 0 || len(changes) > MaxBulkChannelSettings {
+		return nil, fmt.Errorf("%w: between 1 and %d channels are required", ErrInvalidChannelSettings, MaxBulkChannelSettings)
+	}
+	invalid := []InvalidChannelSettings{}
+	seen := make(map[string]bool, len(changes))
+	for i, change := range changes {
+		var err error
+		switch {
+		case strings.TrimSpace(change.ChannelID) == "":
+			err = fmt.Errorf("channel_id required")
+		case seen[change.ChannelID]:
+			err = fmt.Errorf("channel %s appears more than once", change.ChannelID)
+		default:
+			err = ValidateChannelSettings(change.Settings)
+		}
+		seen[change.ChannelID] = true
+		if err != nil {
+			invalid = append(invalid, InvalidChannelSettings{Index: i, ChannelID: change.ChannelID, Error: err.Error()})
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, &BulkChannelSettingsError{Invalid: invalid}
+	}
+
+	results := make([]*models.ChannelConfig, 0, len(changes))
+	err := service.withTx(ctx, func(tx *SubscriptionServiceImpl) error {
+		if err := tx.checkBulkGuildChannelLimits(ctx, changes); err != nil {
+			return err
+		}
+		for _, change := range changes {
+			config, err := tx.applyChannelSettings(ctx, change.ChannelID, change.GuildID, change.Settings, actor)
+			if err != nil {
+				return fmt.Errorf("failed to configure channel %s: %w", change.ChannelID, err)
+			}
+			results = append(results, config)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// checkBulkGuildChannelLimits returns a LimitExceededError when the channels a bulk change configures
+// for the first time would take a guild past its channel limit
+func (service *SubscriptionServiceImpl) checkBulkGuildChannelLimits(ctx context.Context, changes []ChannelSettingsChange) error {
+	configs, err := service.repo.GetAllChannelConfigs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get channel configs: %w", err)
+	}
+	configured := make(map[string]bool, len(configs))
+	channels := make(map[string]int)
+	for _, config := range configs {
+		configured[config.ChannelID] = true
+		if config.GuildID != "" {
+			channels[config.GuildID]++
+		}
+	}
+
+	added := make(map[string]int)
+	for _, change := range changes {
+		if change.GuildID != "" && !configured[change.ChannelID] {
+			added[change.GuildID]++
+		}
+	}
+	for guildID, count := range added {
+		limits, err := service.GetSubscriptionLimits(ctx, models.LimitSubjectGuild, guildID)
+		if err != nil {
+			return err
+		}
+		if limits.MaxChannels > 0 && channels[guildID]+count > limits.MaxChannels {
+			return &LimitExceededError{Kind: limitChannels, Limit: limits.MaxChannels}
+		}
+	}
+	return nil
+}
+
+// SetChannelDigest opts a channel in to daily or weekly digests, or out with DigestOff. Changing the
+// mode restarts the schedule, so the first digest goes out at the next scheduled time.
+func (service *SubscriptionServiceImpl) SetChannelDigest(ctx context.Context, channelID, guildID, mode, actor string) error {
+	if !models.IsValidDigestMode(mode) {
+		return fmt.Errorf("%w: digest mode must be daily, weekly or off", ErrInvalidChannelSettings)
+	}
+	config, err := service.repo.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel config: %w", err)
+	}
+	if config.DigestMode == mode {
+		return nil
+	}
+
+	config.DigestMode = mode
+	config.LastDigestAt = time.Time{}
+	config.LastUpdateTimestamp = service.now()
+	if guildID != "" {
+		config.GuildID = guildID
+	}
+	return service.UpdateChannelConfig(ctx, config, actor)
+}
+
+// SetChannelClosingSoon sets how many hours before a market closes it is announced in a channel; 0 disables
+// the closing-soon feed for the channel
+func (service *SubscriptionServiceImpl) SetChannelClosingSoon(ctx context.Context, channelID, guildID string, hours int, actor string) error {
+	if hours < 0 || hours > models.MaxClosingSoonHours {
+		return fmt.Errorf("%w: closing-soon hours must be between 0 and %d", ErrInvalidChannelSettings, models.MaxClosingSoonHours)
+	}
+	config, err := service.repo.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel config: %w", err)
+	}
+
+	config.ClosingSoonHours = hours
+	config.LastUpdateTimestamp = service.now()
+	if guildID != "" {
+		config.GuildID = guildID
+	}
+	return service.UpdateChannelConfig(ctx, config, actor)
+}
+
+// SetChannelTimezone sets the timezone absolute times are shown in for a channel, or clears it when zone is empty
+func (service *SubscriptionServiceImpl) SetChannelTimezone(ctx context.Context, channelID, guildID, zone, actor string) error {
+	if _, err := LoadTimezone(zone); err != nil {
+		return err
+	}
+	config, err := service.repo.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel config: %w", err)
+	}
+
+	config.Timezone = zone
+	config.LastUpdateTimestamp = service.now()
+	if guildID != "" {
+		config.GuildID = guildID
+	}
+	return service.UpdateChannelConfig(ctx, config, actor)
+}
+
+// SetChannelCrosspost turns publishing of a channel's announcements to following servers on or off.
+// Callers check with CheckCrosspost that the channel is an announcement channel the bot can publish in.
+func (service *SubscriptionServiceImpl) SetChannelCrosspost(ctx context.Context, channelID, guildID string, enabled bool, actor string) error {
+	config, err := service.repo.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel config: %w", err)
+	}
+	if config.Crosspost == enabled {
+		return nil
+	}
+
+	config.Crosspost = enabled
+	config.LastUpdateTimestamp = service.now()
+	if guildID != "" {
+		config.GuildID = guildID
+	}
+	return service.UpdateChannelConfig(ctx, config, actor)
+}
+
+// SetChannelLiquidityAlerts turns posting a market's liquidity changes in a channel on or off
+func (service *SubscriptionServiceImpl) SetChannelLiquidityAlerts(ctx context.Context, channelID, guildID string, enabled bool, actor string) error {
+	config, err := service.repo.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel config: %w", err)
+	}
+	if config.LiquidityAlerts == enabled {
+		return nil
+	}
+
+	config.LiquidityAlerts = enabled
+	config.LastUpdateTimestamp = service.now()
+	if guildID != "" {
+		config.GuildID = guildID
+	}
+	return service.UpdateChannelConfig(ctx, config, actor)
+}
+
+// SetChannelShadowMode turns shadow mode of a channel on or off. In shadow mode the events the channel
+// would get are logged and reported, but not posted.
+func (service *SubscriptionServiceImpl) SetChannelShadowMode(ctx context.Context, channelID, guildID string, enabled bool, actor string) error {
+	config, err := service.repo.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel config: %w", err)
+	}
+	if config.ShadowMode == enabled {
+		return nil
+	}
+
+	config.ShadowMode = enabled
+	config.LastUpdateTimestamp = service.now()
+	if guildID != "" {
+		config.GuildID = guildID
+	}
+	return service.UpdateChannelConfig(ctx, config, actor)
+}
+
+// SetChannelCalendarDigests turns posting a themed digest before each upcoming calendar event in a
+// channel on or off
+func (service *SubscriptionServiceImpl) SetChannelCalendarDigests(ctx context.Context, channelID, guildID string, enabled bool, actor string) error {
+	config, err := service.repo.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel config: %w", err)
+	}
+	if config.CalendarDigests == enabled {
+		return nil
+	}
+
+	config.CalendarDigests = enabled
+	config.CalendarDigestsSent = nil
+	config.LastUpdateTimestamp = service.now()
+	if guildID != "" {
+		config.GuildID = guildID
+	}
+	return service.UpdateChannelConfig(ctx, config, actor)
+}
+
+// SetChannelBoard turns a channel's pinned market board on or off. The board service posts the board,
+// or removes it, on its next refresh.
+func (service *SubscriptionServiceImpl) SetChannelBoard(ctx context.Context, channelID, guildID string, enabled bool, actor string) error {
+	config, err := service.repo.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel config: %w", err)
+	}
+	if config.Board == enabled {
+		return nil
+	}
+
+	config.Board = enabled
+	config.LastUpdateTimestamp = service.now()
+	if guildID != "" {
+		config.GuildID = guildID
+	}
+	return service.UpdateChannelConfig(ctx, config, actor)
+}
+
+// CopyChannelSettings copies one channel's settings onto another
+func (service *SubscriptionServiceImpl) CopyChannelSettings(ctx context.Context, sourceChannelID, targetChannelID, guildID, actor string) (*models.ChannelConfig, error) {
+	if sourceChannelID == targetChannelID {
+		return nil, fmt.Errorf("%w: source and target channel are the same", ErrInvalidChannelSettings)
+	}
+	source, err := service.repo.GetChannelConfig(ctx, sourceChannelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel config: %w", err)
+	}
+	return service.ApplyChannelSettings(ctx, targetChannelID, guildID, source.Settings(), actor)
+}
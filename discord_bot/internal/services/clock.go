@@ -0,0 +1,118 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock tells services the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// IDGenerator creates the IDs of stored records such as reminders, webhooks and audit entries
+type IDGenerator interface {
+	NewID() (string, error)
+}
+
+// SystemClock is the clock services use unless they are given another one
+type SystemClock struct{}
+
+// Now returns the current local time
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// RandomIDs generates random hex IDs, the IDs services use unless they are given another generator
+type RandomIDs struct{}
+
+// NewID returns 24 random hex characters
+func (RandomIDs) NewID() (string, error) {
+	randomBytes := make([]byte, 12)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(randomBytes), nil
+}
+
+// FakeClock is a Clock for tests that stands still until it is moved
+type FakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// NewFakeClock creates a fake clock showing now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the fake clock's time
+func (clock *FakeClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.now
+}
+
+// Advance moves the fake clock forward by duration
+func (clock *FakeClock) Advance(duration time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.now = clock.now.Add(duration)
+}
+
+// Set moves the fake clock to now
+func (clock *FakeClock) Set(now time.Time) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.now = now
+}
+
+// SequentialIDs is an IDGenerator for tests returning id1, id2 and so on
+type SequentialIDs struct {
+	mutex sync.Mutex
+	next  int
+}
+
+// NewID returns the next ID in the sequence
+func (ids *SequentialIDs) NewID() (string, error) {
+	ids.mutex.Lock()
+	defer ids.mutex.Unlock()
+	ids.next++
+	return fmt.Sprintf("id%d", ids.next), nil
+}
+
+// clockAndIDs gives a service its clock and ID generator. Services embed it, so tests can replace both
+// through SetClock and SetIDGenerator; the zero value uses the system clock and random IDs.
+type clockAndIDs struct {
+	clock Clock
+	ids   IDGenerator
+}
+
+// SetClock sets the clock the service reads the time from
+func (c *clockAndIDs) SetClock(clock Clock) {
+	c.clock = clock
+}
+
+// SetIDGenerator sets the generator of the IDs the service creates
+func (c *clockAndIDs) SetIDGenerator(ids IDGenerator) {
+	c.ids = ids
+}
+
+// now returns the current time of the service's clock
+func (c *clockAndIDs) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}
+
+// newID returns a new ID from the service's generator
+func (c *clockAndIDs) newID() (string, error) {
+	if c.ids == nil {
+		return RandomIDs{}.NewID()
+	}
+	return c.ids.NewID()
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// ClosingSoonService defines the interface for the automatic "closing soon" channel feed
+type ClosingSoonService interface {
+	ProcessClosingSoon(ctx context.Context, now time.Time) int
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// ClosingSoonServiceImpl implements ClosingSoonService
+type ClosingSoonServiceImpl struct {
+	repo          repository.SubscriptionRepository
+	marketService MarketService
+	notifier      Notifier
+	logger        *utils.Logger
+}
+
+// NewClosingSoonService creates a new closing-soon feed service
+func NewClosingSoonService(
+	repo repository.SubscriptionRepository,
+	marketService MarketService,
+	notifier Notifier,
+	logger *utils.Logger,
+) *ClosingSoonServiceImpl {
+	return &ClosingSoonServiceImpl{
+		repo:          repo,
+		marketService: marketService,
+		notifier:      notifier,
+		logger:        logger,
+	}
+}
+
+// closingSoonTarget is a channel the closing-soon feed posts to; config is nil for guild default channels
+type closingSoonTarget struct {
+	channelID string
+	window    time.Duration
+	config    *models.ChannelConfig
+}
+
+// location returns the timezone the channel shows absolute times in, or nil for each reader's locale
+func (target closingSoonTarget) location() *time.Location {
+	if target.config == nil {
+		return nil
+	}
+	return timezoneOrNil(target.config.Timezone)
+}
+
+// accepts reports whether the channel wants a market announced, following the same feed, followed-market
+// and category rules as market event fan-out
+func (target closingSoonTarget) accepts(market *models.Market) bool {
+	if target.config == nil {
+		return true
+	}
+	for _, id := range target.config.SubscribedMarkets {
+		if id == market.ID {
+			return true
+		}
+	}
+	if !target.config.FeedEnabled {
+		return false
+	}
+	if len(target.config.AllowedCategories) == 0 {
+		return true
+	}
+	for _, category := range target.config.AllowedCategories {
+		if category == market.Category {
+			return true
+		}
+	}
+	return false
+}
+
+// ProcessClosingSoon announces every active market that entered a channel's closing-soon window and
+// was not announced there before, and returns how many announcements were delivered
+func (service *ClosingSoonServiceImpl) ProcessClosingSoon(ctx context.Context, now time.Time) int {
+	targets, err := service.targets(ctx)
+	if err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to get closing-soon channels: %v", err))
+		return 0
+	}
+	if len(targets) == 0 {
+		return 0
+	}
+
+	markets, err := service.marketService.FetchAllMarkets(ctx)
+	if err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to fetch markets for the closing-soon feed: %v", err))
+		return 0
+	}
+
+	sent := 0
+	for _, market := range markets {
+		if market.Status != "active" || !market.EndTime.After(now) {
+			continue
+		}
+		left := market.EndTime.Sub(now)
+		for _, target := range targets {
+			if left > target.window || !target.accepts(market) {
+				continue
+			}
+			first, err := service.repo.MarkClosingSoonAnnounced(ctx, target.channelID, market.ID, market.EndTime)
+			if err != nil {
+				service.logger.Error(fmt.Sprintf("Failed to record closing-soon announcement for market %s: %v", market.ID, err))
+				continue
+			}
+			if !first {
+				continue
+			}
+
+			// Like reminders, a failed announcement is not retried
+			message := LocalizeTimestamps(service.marketService.CreateMarketClosingSoonMessage(market), target.location())
+			if err := service.notifier.SendChannelMessage(ctx, target.channelID, message); err != nil {
+				service.logger.Error(fmt.Sprintf("Failed to announce market %s closing soon in channel %s: %v", market.ID, target.channelID, err))
+				continue
+			}
+			sent++
+		}
+	}
+
+	if err := service.repo.PruneClosingSoonAnnouncements(ctx, now); err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to prune closing-soon announcements: %v", err))
+	}
+	return sent
+}
+
+// targets lists the channels with a closing-soon window: configured channels, and the default channel
+// of guilds without any channel configuration
+func (service *ClosingSoonServiceImpl) targets(ctx context.Context) ([]closingSoonTarget, error) {
+	configs, err := service.repo.GetAllChannelConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	guilds, err := service.repo.GetAllGuildConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []closingSoonTarget
+	guildsWithChannels := make(map[string]bool)
+	for _, config := range configs {
+		if config.GuildID != "" {
+			guildsWithChannels[config.GuildID] = true
+		}
+		if config.ClosingSoonHours > 0 {
+			targets = append(targets, closingSoonTarget{channelID: config.ChannelID, window: time.Duration(config.ClosingSoonHours) * time.Hour, config: config})
+		}
+	}
+	for _, guild := range guilds {
+		if guild.DefaultChannelID != "" && !guildsWithChannels[guild.GuildID] {
+			targets = append(targets, closingSoonTarget{channelID: guild.DefaultChannelID, window: models.DefaultClosingSoonHours * time.Hour})
+		}
+	}
+	return targets, nil
+}
+
+// Run scans for markets closing soon on every tick until the context is cancelled
+func (service *ClosingSoonServiceImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	service.logger.Info(fmt.Sprintf("Closing-soon feed started (interval %s)", interval))
+	for {
+		select {
+		case <-ctx.Done():
+			service.logger.Info("Closing-soon feed stopped")
+			return
+		case now := <-ticker.C:
+			if sent := service.ProcessClosingSoon(ctx, now); sent > 0 {
+				service.logger.Info(fmt.Sprintf("Announced %d markets closing soon", sent))
+			}
+		}
+	}
+}
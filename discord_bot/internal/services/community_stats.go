@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// communityStatsWindow is the period the community stats cover
+const communityStatsWindow = 7 * 24 * time.Hour
+
+// CommunityStatsService defines the interface for the weekly community stats posts of guilds
+type CommunityStatsService interface {
+	BuildStats(ctx context.Context, guildID string, now time.Time) (string, error)
+	ProcessDueStats(ctx context.Context, now time.Time) int
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// CommunityStatsServiceImpl implements CommunityStatsService
+type CommunityStatsServiceImpl struct {
+	repo          repository.SubscriptionRepository
+	marketService MarketService // lists the week's resolutions, nil leaves them out
+	notifier      Notifier
+	schedule      DigestSchedule
+	logger        *utils.Logger
+	linkAllowlist
+}
+
+// NewCommunityStatsService creates a new community stats service, posting on the schedule of weekly digests
+func NewCommunityStatsService(repo repository.SubscriptionRepository, notifier Notifier, schedule DigestSchedule, logger *utils.Logger) *CommunityStatsServiceImpl {
+	if schedule.Location == nil {
+		schedule.Location = time.UTC
+	}
+	return &CommunityStatsServiceImpl{
+		repo:     repo,
+		notifier: notifier,
+		schedule: schedule,
+		logger:   logger,
+	}
+}
+
+// SetMarketService sets the service listing the markets whose resolutions the stats rank
+func (service *CommunityStatsServiceImpl) SetMarketService(marketService MarketService) {
+	service.marketService = marketService
+}
+
+// communityWeek is what the stats of every guild are computed from, loaded once for all the guilds due
+type communityWeek struct {
+	since    time.Time
+	events   []*models.AnalyticsEvent
+	channels map[string][]*models.ChannelConfig // configured channels by guild
+	markets  []*models.Market                   // nil when markets could not be listed
+}
+
+// loadWeek loads the analytics events, channels and markets of the week before now. Markets that
+// cannot be listed only leave the resolutions out of the stats.
+func (service *CommunityStatsServiceImpl) loadWeek(ctx context.Context, now time.Time) (*communityWeek, error) {
+	week := &communityWeek{since: now.Add(-communityStatsWindow), channels: map[string][]*models.ChannelConfig{}}
+
+	events, err := service.repo.GetAnalyticsEvents(ctx, week.since, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get analytics events: %w", err)
+	}
+	week.events = events
+
+	configs, err := service.repo.GetAllChannelConfigs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel configs: %w", err)
+	}
+	for _, config := range configs {
+		if config.GuildID != "" {
+			week.channels[config.GuildID] = append(week.channels[config.GuildID], config)
+		}
+	}
+
+	if service.marketService != nil {
+		if week.markets, err = service.marketService.FetchAllMarkets(ctx); err != nil {
+			service.logger.Warning(fmt.Sprintf("Failed to fetch markets for community stats, leaving resolutions out: %v", err))
+		}
+	}
+	return week, nil
+}
+
+// BuildStats renders the stats of a guild's last seven days: the alerts posted in its channels, the
+// markets they follow, its most active predictors and the biggest markets that resolved
+func (service *CommunityStatsServiceImpl) BuildStats(ctx context.Context, guildID string, now time.Time) (string, error) {
+	week, err := service.loadWeek(ctx, now)
+	if err != nil {
+		return "", err
+	}
+	return service.renderStats(ctx, guildID, week, now)
+}
+
+// renderStats formats a guild's stats for the week
+func (service *CommunityStatsServiceImpl) renderStats(ctx context.Context, guildID string, week *communityWeek, now time.Time) (string, error) {
+	bets, err := service.repo.GetGameBetsByGuild(ctx, guildID, week.since)
+	if err != nil {
+		return "", fmt.Errorf("failed to get game bets: %w", err)
+	}
+
+	channels := map[string]bool{}
+	followed := map[string]bool{}
+	for _, config := range week.channels[guildID] {
+		channels[config.ChannelID] = true
+		for _, marketID := range config.SubscribedMarkets {
+			followed[marketID] = true
+		}
+	}
+
+	alerts, failed, newFollows := map[string]int{}, 0, 0
+	alerted := map[string]bool{}
+	for _, event := range week.events {
+		if !channels[event.Subject] {
+			continue
+		}
+		switch {
+		case event.Kind == models.AnalyticsNotificationSent:
+			alerts[event.Name]++
+			alerted[event.Subject] = true
+		case event.Kind == models.AnalyticsDeliveryFailure:
+			failed++
+		case event.Kind == models.AnalyticsSubscribe && event.Name == "channel_market":
+			newFollows++
+		}
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "📊 **Weekly Community Stats** — %s\n", DiscordTimestamp(now, TimestampLongDate))
+
+	if total := sumCounts(alerts); total > 0 {
+		fmt.Fprintf(&message, "\n**🔔 Alerts**\n• %d %s posted in %d %s: %s\n", total, plural(total, "alert", "alerts"), len(alerted), plural(len(alerted), "channel", "channels"), eventCounts(alerts))
+		if failed > 0 {
+			fmt.Fprintf(&message, "• %d could not be delivered\n", failed)
+		}
+	}
+	if len(followed) > 0 || newFollows > 0 {
+		fmt.Fprintf(&message, "\n**📌 Markets Followed**\n• %d %s followed by this server's channels, %d new this week\n", len(followed), plural(len(followed), "market", "markets"), newFollows)
+	}
+
+	predictors := activePredictors(bets)
+	if len(predictors) > 0 {
+		message.WriteString("\n**🎲 Most Active Predictors**\n")
+		for i := 0; i < len(predictors) && i < digestSectionSize; i++ {
+			predictor := predictors[i]
+			fmt.Fprintf(&message, "• <@%s> — %d %s, $%.2f staked\n", predictor.userID, predictor.bets, plural(predictor.bets, "bet", "bets"), predictor.staked)
+		}
+	}
+
+	resolved := resolvedSince(week.markets, week.since, now)
+	if len(resolved) > 0 {
+		message.WriteString("\n**✅ Biggest Resolutions**\n")
+		for i := 0; i < len(resolved) && i < digestSectionSize; i++ {
+			market := resolved[i]
+			fmt.Fprintf(&message, "• %s — %s volume, %s\n", service.markdownLink(market.Title, digestTitleLength, market.Link), compactAmount(market.Volume), strings.Join(escapeAll(market.Winners()), " / "))
+		}
+	}
+
+	if sumCounts(alerts)+len(followed)+newFollows+len(predictors)+len(resolved) == 0 {
+		message.WriteString("\nA quiet week — no alerts, followed markets, bets or resolutions.\n")
+	}
+	return message.String(), nil
+}
+
+// communityPredictor is a member's activity in the game during the week
+type communityPredictor struct {
+	userID string
+	bets   int
+	staked float64
+}
+
+// activePredictors ranks the members who placed bets by how many they placed, then by their stakes
+func activePredictors(bets []*models.GameBet) []communityPredictor {
+	byUser := map[string]*communityPredictor{}
+	var predictors []*communityPredictor
+	for _, bet := range bets {
+		predictor, ok := byUser[bet.DiscordUserID]
+		if !ok {
+			predictor = &communityPredictor{userID: bet.DiscordUserID}
+			byUser[bet.DiscordUserID] = predictor
+			predictors = append(predictors, predictor)
+		}
+		predictor.bets++
+		predictor.staked += bet.Stake
+	}
+
+	ranked := make([]communityPredictor, 0, len(predictors))
+	for _, predictor := range predictors {
+		ranked = append(ranked, *predictor)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].bets != ranked[j].bets {
+			return ranked[i].bets > ranked[j].bets
+		}
+		return ranked[i].staked > ranked[j].staked
+	})
+	return ranked
+}
+
+// resolvedSince returns the markets that resolved in [since, now], biggest volume first
+func resolvedSince(markets []*models.Market, since, now time.Time) []*models.Market {
+	var resolved []*models.Market
+	for _, market := range markets {
+		if market.Status == "resolved" && !market.EndTime.Before(since) && !market.EndTime.After(now) {
+			resolved = append(resolved, market)
+		}
+	}
+	sort.SliceStable(resolved, func(i, j int) bool { return resolved[i].Volume > resolved[j].Volume })
+	return resolved
+}
+
+// eventCounts lists alert counts by event type, most frequent first, like "12 updates, 3 resolved"
+func eventCounts(counts map[string]int) string {
+	eventTypes := make([]string, 0, len(counts))
+	for eventType := range counts {
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Slice(eventTypes, func(i, j int) bool {
+		if counts[eventTypes[i]] != counts[eventTypes[j]] {
+			return counts[eventTypes[i]] > counts[eventTypes[j]]
+		}
+		return eventTypes[i] < eventTypes[j]
+	})
+
+	parts := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		labels, known := heldEventLabels[eventType]
+		if !known {
+			labels = [2]string{eventType, eventType}
+		}
+		parts = append(parts, fmt.Sprintf("%d %s", counts[eventType], plural(counts[eventType], labels[0], labels[1])))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sumCounts adds up the counts of a map
+func sumCounts(counts map[string]int) int {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
+
+// plural returns singular when count is 1 and plural otherwise
+func plural(count int, singular, plural string) string {
+	if count == 1 {
+		return singular
+	}
+	return plural
+}
+
+// statsTimezone returns the timezone of a guild's stats channel, or nil when it did not choose one
+func statsTimezone(week *communityWeek, guild *models.GuildConfig) *time.Location {
+	for _, config := range week.channels[guild.GuildID] {
+		if config.ChannelID == guild.StatsChannelID {
+			return timezoneOrNil(config.Timezone)
+		}
+	}
+	return nil
+}
+
+// ProcessDueStats posts the stats of every opted-in guild whose weekly time has passed since its last
+// post and returns how many were posted. Guilds seen for the first time are only marked, so their first
+// stats go out at the next weekly time.
+func (service *CommunityStatsServiceImpl) ProcessDueStats(ctx context.Context, now time.Time) int {
+	guilds, err := service.repo.GetAllGuildConfigs(ctx)
+	if err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to get guild configs: %v", err))
+		return 0
+	}
+
+	var week *communityWeek
+	sent := 0
+	for _, guild := range guilds {
+		if guild.StatsChannelID == "" {
+			continue
+		}
+		if guild.LastStatsAt.IsZero() {
+			service.markStats(ctx, guild, now)
+			continue
+		}
+
+		if week == nil {
+			if week, err = service.loadWeek(ctx, now); err != nil {
+				service.logger.Error(fmt.Sprintf("Failed to load community stats: %v", err))
+				return sent
+			}
+		}
+		// Stats are scheduled in the stats channel's timezone when it chose one, like its digests
+		timezone := statsTimezone(week, guild)
+		schedule := service.schedule
+		if timezone != nil {
+			schedule.Location = timezone
+		}
+		if !guild.LastStatsAt.Before(schedule.LastOccurrence(models.DigestWeekly, now)) {
+			continue
+		}
+
+		message, err := service.renderStats(ctx, guild.GuildID, week, now)
+		if err != nil {
+			service.logger.Error(fmt.Sprintf("Failed to build community stats of guild %s: %v", guild.GuildID, err))
+			continue
+		}
+		message = LocalizeTimestamps(message, timezone)
+		if err := service.notifier.SendChannelMessage(ctx, guild.StatsChannelID, message); err != nil {
+			service.logger.Error(fmt.Sprintf("Failed to send community stats of guild %s to channel %s: %v", guild.GuildID, guild.StatsChannelID, err))
+		} else {
+			sent++
+		}
+
+		// Like digests, failed stats are not retried; the guild gets next week's
+		service.markStats(ctx, guild, now)
+	}
+	return sent
+}
+
+// markStats records when a guild's stats were last handled
+func (service *CommunityStatsServiceImpl) markStats(ctx context.Context, guild *models.GuildConfig, now time.Time) {
+	guild.LastStatsAt = now
+	if err := service.repo.SaveGuildConfig(ctx, guild); err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to save community stats time for guild %s: %v", guild.GuildID, err))
+	}
+}
+
+// Run checks for due community stats on every tick until the context is cancelled
+func (service *CommunityStatsServiceImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	service.logger.Info(fmt.Sprintf("Community stats scheduler started (%s %02d:%02d %s, interval %s)", service.schedule.Weekday, service.schedule.Hour, service.schedule.Minute, service.schedule.Location, interval))
+	for {
+		select {
+		case <-ctx.Done():
+			service.logger.Info("Community stats scheduler stopped")
+			return
+		case now := <-ticker.C:
+			if sent := service.ProcessDueStats(ctx, now); sent > 0 {
+				service.logger.Info(fmt.Sprintf("Posted the community stats of %d guilds", sent))
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Errors returned by CheckCrosspost
+var (
+	ErrNotAnnouncementChannel     = errors.New("not an announcement channel")
+	ErrMissingCrosspostPermission = errors.New("the bot cannot send messages in the channel")
+)
+
+// crosspostPermissions are the permissions the bot needs to publish its own messages. Publishing
+// other members' messages would also need Manage Messages.
+const crosspostPermissions = discordgo.PermissionViewChannel | discordgo.PermissionSendMessages
+
+// CheckCrosspost verifies that a channel is an announcement channel the bot can publish its messages
+// in. The channel is read from the gateway state, which the Guilds intent keeps up to date, and
+// fetched from the API when the state does not have it.
+func CheckCrosspost(ctx context.Context, session *discordgo.Session, channelID string) error {
+	if session.State == nil || session.State.User == nil {
+		return errors.New("not connected to Discord")
+	}
+
+	channel, err := session.State.Channel(channelID)
+	if err != nil {
+		channel, err = session.Channel(channelID, discordgo.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("failed to get channel: %w", err)
+		}
+	}
+	if channel.Type != discordgo.ChannelTypeGuildNews {
+		return ErrNotAnnouncementChannel
+	}
+
+	permissions, err := botChannelPermissions(ctx, session, channelID)
+	if err != nil {
+		return err
+	}
+	if permissions&crosspostPermissions != crosspostPermissions {
+		return ErrMissingCrosspostPermission
+	}
+	return nil
+}
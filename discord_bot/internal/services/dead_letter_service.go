@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// DeadLetterMaxAttempts is how many times a message is sent, counting the first send, before its
+// dead letter stops being retried automatically
+const DeadLetterMaxAttempts = 5
+
+// Backoff between automatic retries: the first retry waits deadLetterBaseBackoff and every later
+// one four times longer than the previous, up to deadLetterMaxBackoff
+const (
+	deadLetterBaseBackoff = time.Minute
+	deadLetterMaxBackoff  = time.Hour
+)
+
+// ErrDeadLetterNotFound is returned for unknown dead letter IDs
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetterService defines the interface for the dead-letter store of messages that failed to send
+type DeadLetterService interface {
+	Add(ctx context.Context, letter *models.DeadLetter, permanent bool) error
+	List(ctx context.Context, status string) ([]*models.DeadLetter, error)
+	Due(ctx context.Context, now time.Time) ([]*models.DeadLetter, error)
+	StartRetry(ctx context.Context, letter *models.DeadLetter) error
+	Succeeded(ctx context.Context, letter *models.DeadLetter) error
+	Failed(ctx context.Context, letter *models.DeadLetter, sendErr error, permanent bool) error
+	Redrive(ctx context.Context, id string) (*models.DeadLetter, error)
+	Discard(ctx context.Context, id string) error
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// DeadLetterServiceImpl implements DeadLetterService
+type DeadLetterServiceImpl struct {
+	repo   repository.SubscriptionRepository
+	logger *utils.Logger
+	clockAndIDs
+}
+
+// NewDeadLetterService creates a new dead letter service
+func NewDeadLetterService(repo repository.SubscriptionRepository, logger *utils.Logger) *DeadLetterServiceImpl {
+	return &DeadLetterServiceImpl{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// deadLetterBackoff returns how long to wait before retrying a message sent attempts times
+func deadLetterBackoff(attempts int) time.Duration {
+	backoff := deadLetterBaseBackoff
+	for i := 1; i < attempts && backoff < deadLetterMaxBackoff; i++ {
+		backoff *= 4
+	}
+	if backoff > deadLetterMaxBackoff {
+		backoff = deadLetterMaxBackoff
+	}
+	return backoff
+}
+
+// Add stores the dead letter of a message whose first send failed with letter.LastError. A permanent
+// failure, such as a deleted channel, is not retried automatically.
+func (service *DeadLetterServiceImpl) Add(ctx context.Context, letter *models.DeadLetter, permanent bool) error {
+	id, err := service.newID()
+	if err != nil {
+		return fmt.Errorf("failed to generate dead letter id: %w", err)
+	}
+	now := service.now()
+	letter.ID = id
+	letter.CreatedAt = now
+	letter.Attempts = 1
+	service.schedule(letter, now, permanent)
+
+	if err := service.repo.SaveDeadLetter(ctx, letter); err != nil {
+		return fmt.Errorf("failed to save dead letter: %w", err)
+	}
+	service.logger.Info(fmt.Sprintf("Stored dead letter %s for %s %s (%s)", letter.ID, letter.Recipient, letter.RecipientID, letter.Status))
+	return nil
+}
+
+// List returns the dead letters, oldest first, only those with the given status when it is not empty
+func (service *DeadLetterServiceImpl) List(ctx context.Context, status string) ([]*models.DeadLetter, error) {
+	letters, err := service.repo.GetDeadLetters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if status == "" {
+		return letters, nil
+	}
+	filtered := make([]*models.DeadLetter, 0, len(letters))
+	for _, letter := range letters {
+		if letter.Status == status {
+			filtered = append(filtered, letter)
+		}
+	}
+	return filtered, nil
+}
+
+// Due returns the dead letters whose next retry is due, oldest first
+func (service *DeadLetterServiceImpl) Due(ctx context.Context, now time.Time) ([]*models.DeadLetter, error) {
+	letters, err := service.List(ctx, models.DeadLetterRetrying)
+	if err != nil {
+		return nil, err
+	}
+	due := make([]*models.DeadLetter, 0, len(letters))
+	for _, letter := range letters {
+		if !letter.NextAttemptAt.After(now) {
+			due = append(due, letter)
+		}
+	}
+	return due, nil
+}
+
+// StartRetry counts a retry and moves the next one back before the message is sent, so a retry that
+// never reports back, for example because the bot stopped, is not repeated on every tick
+func (service *DeadLetterServiceImpl) StartRetry(ctx context.Context, letter *models.DeadLetter) error {
+	letter.Attempts++
+	letter.NextAttemptAt = service.now().Add(deadLetterBackoff(letter.Attempts))
+	return service.repo.SaveDeadLetter(ctx, letter)
+}
+
+// Succeeded deletes the dead letter of a message that was sent
+func (service *DeadLetterServiceImpl) Succeeded(ctx context.Context, letter *models.DeadLetter) error {
+	_, err := service.repo.DeleteDeadLetter(ctx, letter.ID)
+	return err
+}
+
+// Failed records a failed retry, scheduling the next one or, after DeadLetterMaxAttempts sends or a
+// permanent failure, marking the letter exhausted
+func (service *DeadLetterServiceImpl) Failed(ctx context.Context, letter *models.DeadLetter, sendErr error, permanent bool) error {
+	letter.LastError = sendErr.Error()
+	service.schedule(letter, service.now(), permanent)
+	return service.repo.SaveDeadLetter(ctx, letter)
+}
+
+// schedule sets a dead letter's status and next retry after a failed send
+func (service *DeadLetterServiceImpl) schedule(letter *models.DeadLetter, now time.Time, permanent bool) {
+	if permanent || letter.Attempts >= DeadLetterMaxAttempts {
+		letter.Status = models.DeadLetterExhausted
+		letter.NextAttemptAt = time.Time{}
+		return
+	}
+	letter.Status = models.DeadLetterRetrying
+	letter.NextAttemptAt = now.Add(deadLetterBackoff(letter.Attempts))
+}
+
+// Redrive makes a dead letter due for an immediate retry, including an exhausted one. An exhausted
+// letter whose retry fails again is exhausted again rather than getting another round of retries.
+func (service *DeadLetterServiceImpl) Redrive(ctx context.Context, id string) (*models.DeadLetter, error) {
+	letter, err := service.repo.GetDeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if letter == nil {
+		return nil, ErrDeadLetterNotFound
+	}
+	letter.Status = models.DeadLetterRetrying
+	letter.NextAttemptAt = service.now()
+	if err := service.repo.SaveDeadLetter(ctx, letter); err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
+
+// Discard deletes a dead letter without sending it
+func (service *DeadLetterServiceImpl) Discard(ctx context.Context, id string) error {
+	deleted, err := service.repo.DeleteDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// Prune deletes the exhausted dead letters created before a point in time
+func (service *DeadLetterServiceImpl) Prune(ctx context.Context, before time.Time) error {
+	return service.repo.PruneDeadLetters(ctx, before)
+}
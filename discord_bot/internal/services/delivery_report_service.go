@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// DeliveryReportRetention is how long an event's delivery report is kept
+const DeliveryReportRetention = 24 * time.Hour
+
+// deliveryReportPruneInterval is how often expired reports are pruned when a new report starts
+const deliveryReportPruneInterval = time.Hour
+
+// ErrDeliveryReportNotFound is returned for events without a delivery report, because they are
+// unknown or their report expired
+var ErrDeliveryReportNotFound = errors.New("delivery report not found")
+
+// DeliveryReportService defines the interface for per-event delivery reports, which record the
+// channels and users each event was sent to, failed for or skipped
+type DeliveryReportService interface {
+	Start(ctx context.Context, eventID, eventType, marketID string) (string, error)
+	Record(ctx context.Context, eventID string, receipt models.DeliveryReceipt)
+	Get(ctx context.Context, eventID string) (*models.DeliveryReport, error)
+}
+
+// DeliveryReportServiceImpl implements DeliveryReportService
+type DeliveryReportServiceImpl struct {
+	repo   repository.SubscriptionRepository
+	logger *utils.Logger
+
+	mutex      sync.Mutex
+	lastPruned time.Time
+	clockAndIDs
+}
+
+// NewDeliveryReportService creates a new delivery report service
+func NewDeliveryReportService(repo repository.SubscriptionRepository, logger *utils.Logger) *DeliveryReportServiceImpl {
+	return &DeliveryReportServiceImpl{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Start creates the empty report of an event and returns its event ID. An empty eventID gets a new
+// one, and a known one, such as a resumed outbox item, starts over.
+func (service *DeliveryReportServiceImpl) Start(ctx context.Context, eventID, eventType, marketID string) (string, error) {
+	if eventID == "" {
+		id, err := service.newID()
+		if err != nil {
+			return "", fmt.Errorf("failed to generate event id: %w", err)
+		}
+		eventID = id
+	}
+	service.pruneExpired(ctx)
+
+	report := &models.DeliveryReport{
+		EventID:   eventID,
+		EventType: eventType,
+		MarketID:  marketID,
+		CreatedAt: service.now(),
+		Receipts:  []models.DeliveryReceipt{},
+	}
+	if err := service.repo.SaveDeliveryReport(ctx, report); err != nil {
+		return "", fmt.Errorf("failed to save delivery report: %w", err)
+	}
+	return eventID, nil
+}
+
+// Record adds a receipt to an event's report. Reports are for debugging, so failures are only logged.
+func (service *DeliveryReportServiceImpl) Record(ctx context.Context, eventID string, receipt models.DeliveryReceipt) {
+	if receipt.At.IsZero() {
+		receipt.At = service.now()
+	}
+	if err := service.repo.AddDeliveryReceipt(ctx, eventID, receipt); err != nil {
+		service.logger.Warning(fmt.Sprintf("Failed to record delivery to %s %s for event %s: %v", receipt.Recipient, receipt.RecipientID, eventID, err))
+	}
+}
+
+// Get returns an event's report with its stats counted
+func (service *DeliveryReportServiceImpl) Get(ctx context.Context, eventID string) (*models.DeliveryReport, error) {
+	report, err := service.repo.GetDeliveryReport(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, ErrDeliveryReportNotFound
+	}
+	report.Count()
+	return report, nil
+}
+
+// pruneExpired deletes the reports older than DeliveryReportRetention, at most once per prune interval
+func (service *DeliveryReportServiceImpl) pruneExpired(ctx context.Context) {
+	service.mutex.Lock()
+	now := service.now()
+	due := now.Sub(service.lastPruned) >= deliveryReportPruneInterval
+	if due {
+		service.lastPruned = now
+	}
+	service.mutex.Unlock()
+
+	if !due {
+		return
+	}
+	if err := service.repo.PruneDeliveryReports(ctx, now.Add(-DeliveryReportRetention)); err != nil {
+		service.logger.Warning(fmt.Sprintf("Failed to prune delivery reports: %v", err))
+	}
+}
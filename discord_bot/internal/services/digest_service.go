@@ -0,0 +1,412 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// DefaultDigestTime is the local time digests are posted at when DIGEST_TIME is not set
+const DefaultDigestTime = "09:00"
+
+// digestSectionSize is the number of markets listed in each digest section
+const digestSectionSize = 5
+
+// digestTitleLength keeps each listed market on a single line and the digest under Discord's message limit
+const digestTitleLength = 80
+
+// DigestSchedule is the local time digests are posted at; weekly digests go out on Weekday
+type DigestSchedule struct {
+	Hour     int
+	Minute   int
+	Weekday  time.Weekday
+	Location *time.Location
+}
+
+// ParseDigestSchedule parses a time of day (HH:MM), an IANA time zone and a weekday name.
+// Empty values default to 09:00, UTC and Monday.
+func ParseDigestSchedule(timeOfDay, timezone, weekday string) (DigestSchedule, error) {
+	schedule := DigestSchedule{Weekday: time.Monday, Location: time.UTC}
+
+	if timeOfDay == "" {
+		timeOfDay = DefaultDigestTime
+	}
+	clock, err := time.Parse("15:04", timeOfDay)
+	if err != nil {
+		return schedule, fmt.Errorf("invalid digest time %q, expected HH:MM", timeOfDay)
+	}
+	schedule.Hour, schedule.Minute = clock.Hour(), clock.Minute()
+
+	if timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return schedule, fmt.Errorf("invalid digest time zone %q: %w", timezone, err)
+		}
+		schedule.Location = location
+	}
+
+	if weekday != "" {
+		found := false
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.EqualFold(day.String(), weekday) || strings.EqualFold(day.String()[:3], weekday) {
+				schedule.Weekday, found = day, true
+			}
+		}
+		if !found {
+			return schedule, fmt.Errorf("invalid digest weekday %q", weekday)
+		}
+	}
+	return schedule, nil
+}
+
+// LastOccurrence returns the most recent time at or before now that a digest of the given mode was scheduled
+func (schedule DigestSchedule) LastOccurrence(mode string, now time.Time) time.Time {
+	local := now.In(schedule.Location)
+	occurrence := time.Date(local.Year(), local.Month(), local.Day(), schedule.Hour, schedule.Minute, 0, 0, schedule.Location)
+	if occurrence.After(local) {
+		occurrence = occurrence.AddDate(0, 0, -1)
+	}
+	if mode == models.DigestWeekly {
+		for occurrence.Weekday() != schedule.Weekday {
+			occurrence = occurrence.AddDate(0, 0, -1)
+		}
+	}
+	return occurrence
+}
+
+// digestWindow is the period a digest of the given mode covers
+func digestWindow(mode string) time.Duration {
+	if mode == models.DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// DigestService defines the interface for scheduled market roundup posts
+type DigestService interface {
+	BuildDigest(ctx context.Context, mode string, config *models.ChannelConfig, now time.Time) (string, error)
+	ProcessDueDigests(ctx context.Context, now time.Time) int
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// DigestServiceImpl implements DigestService
+type DigestServiceImpl struct {
+	repo          repository.SubscriptionRepository
+	marketService MarketService
+	notifier      Notifier
+	schedule      DigestSchedule
+	logger        *utils.Logger
+	email         Notifier // emails the digests of users who chose one with email_notifications, nil when email is off
+	linkAllowlist
+}
+
+// NewDigestService creates a new digest service
+func NewDigestService(
+	repo repository.SubscriptionRepository,
+	marketService MarketService,
+	notifier Notifier,
+	schedule DigestSchedule,
+	logger *utils.Logger,
+) *DigestServiceImpl {
+	if schedule.Location == nil {
+		schedule.Location = time.UTC
+	}
+	return &DigestServiceImpl{
+		repo:          repo,
+		marketService: marketService,
+		notifier:      notifier,
+		schedule:      schedule,
+		logger:        logger,
+	}
+}
+
+// SetEmailNotifier sets the notifier emailing users their digests
+func (service *DigestServiceImpl) SetEmailNotifier(email Notifier) {
+	service.email = email
+}
+
+// digestMover is a market whose leading probability moved during the digest window
+type digestMover struct {
+	market  *models.Market
+	outcome string
+	from    float64
+	to      float64
+}
+
+// BuildDigest renders a roundup of the markets in the channel's allowed categories: the biggest new
+// markets, the biggest probability moves recorded from market updates, markets closing soon and resolutions
+func (service *DigestServiceImpl) BuildDigest(ctx context.Context, mode string, config *models.ChannelConfig, now time.Time) (string, error) {
+	markets, err := service.marketService.FetchAllMarkets(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch markets: %w", err)
+	}
+	message := service.renderDigest(ctx, mode, filterDigestMarkets(markets, config), service.location(config), now)
+	if config != nil {
+		message = LocalizeTimestamps(message, timezoneOrNil(config.Timezone))
+	}
+	return message, nil
+}
+
+// location returns the timezone a channel's digest is scheduled and dated in: the channel's own
+// timezone when it chose one, otherwise the configured digest timezone
+func (service *DigestServiceImpl) location(config *models.ChannelConfig) *time.Location {
+	if config != nil {
+		if loc := timezoneOrNil(config.Timezone); loc != nil {
+			return loc
+		}
+	}
+	return service.schedule.Location
+}
+
+// filterDigestMarkets keeps the markets in the channel's allowed categories, or every market when none are set
+func filterDigestMarkets(markets []*models.Market, config *models.ChannelConfig) []*models.Market {
+	if config == nil || len(config.AllowedCategories) == 0 {
+		return markets
+	}
+	var filtered []*models.Market
+	for _, market := range markets {
+		for _, category := range config.AllowedCategories {
+			if category == market.Category {
+				filtered = append(filtered, market)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// renderDigest formats the digest sections for a set of markets. The location decides where "today"
+// ends for daily digests; times are written as Discord timestamps.
+func (service *DigestServiceImpl) renderDigest(ctx context.Context, mode string, markets []*models.Market, location *time.Location, now time.Time) string {
+	since := now.Add(-digestWindow(mode))
+	local := now.In(location)
+
+	var newMarkets, closing, resolved []*models.Market
+	var movers []digestMover
+	closingBy := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location).AddDate(0, 0, 1)
+	if mode == models.DigestWeekly {
+		closingBy = now.Add(digestWindow(mode))
+	}
+
+	for _, market := range markets {
+		switch market.Status {
+		case "resolved":
+			if !market.EndTime.Before(since) {
+				resolved = append(resolved, market)
+			}
+			continue
+		case "active":
+		default:
+			continue
+		}
+
+		if !market.StartTime.Before(since) && !market.StartTime.After(now) {
+			newMarkets = append(newMarkets, market)
+		}
+		if market.EndTime.After(now) && market.EndTime.Before(closingBy) {
+			closing = append(closing, market)
+		}
+		if mover, ok := service.marketMove(ctx, market, since); ok {
+			movers = append(movers, mover)
+		}
+	}
+
+	sort.Slice(newMarkets, func(i, j int) bool { return newMarkets[i].Volume > newMarkets[j].Volume })
+	sort.Slice(closing, func(i, j int) bool { return closing[i].EndTime.Before(closing[j].EndTime) })
+	sort.Slice(resolved, func(i, j int) bool { return resolved[i].EndTime.After(resolved[j].EndTime) })
+	sort.Slice(movers, func(i, j int) bool {
+		return math.Abs(movers[i].to-movers[i].from) > math.Abs(movers[j].to-movers[j].from)
+	})
+
+	var message strings.Builder
+	title, closingTitle := "Daily Market Roundup", "Closing Today"
+	if mode == models.DigestWeekly {
+		title, closingTitle = "Weekly Market Roundup", "Closing This Week"
+	}
+	fmt.Fprintf(&message, "📰 **%s** — %s\n", title, DiscordTimestamp(now, TimestampLongDate))
+
+	writeSection := func(heading string, count int, line func(i int) string) {
+		if count == 0 {
+			return
+		}
+		fmt.Fprintf(&message, "\n**%s**\n", heading)
+		for i := 0; i < count && i < digestSectionSize; i++ {
+			message.WriteString("• " + line(i) + "\n")
+		}
+	}
+	writeSection("🆕 Top New Markets", len(newMarkets), func(i int) string {
+		return fmt.Sprintf("%s — %s volume", service.markdownLink(newMarkets[i].Title, digestTitleLength, newMarkets[i].Link), compactAmount(newMarkets[i].Volume))
+	})
+	writeSection("📈 Biggest Movers", len(movers), func(i int) string {
+		mover := movers[i]
+		return fmt.Sprintf("%s — %s %.0f%% → %.0f%% (%+.0f)", service.markdownLink(mover.market.Title, digestTitleLength, mover.market.Link), EscapeMarkdown(mover.outcome), mover.from, mover.to, mover.to-mover.from)
+	})
+	writeSection("⏳ "+closingTitle, len(closing), func(i int) string {
+		return fmt.Sprintf("%s — closes %s", service.markdownLink(closing[i].Title, digestTitleLength, closing[i].Link), DiscordTimestamp(closing[i].EndTime, TimestampShortDateTime))
+	})
+	writeSection("✅ Resolutions", len(resolved), func(i int) string {
+		return fmt.Sprintf("%s — %s", service.markdownLink(resolved[i].Title, digestTitleLength, resolved[i].Link), strings.Join(escapeAll(resolved[i].Winners()), " / "))
+	})
+
+	if len(newMarkets)+len(movers)+len(closing)+len(resolved) == 0 {
+		message.WriteString("\nA quiet " + map[bool]string{true: "week", false: "day"}[mode == models.DigestWeekly] + " — no new markets, moves, closings or resolutions.\n")
+	}
+	return message.String()
+}
+
+// marketMove finds the outcome that moved the most since the start of the window, comparing the
+// earliest snapshot recorded in the window with the market's current probabilities
+func (service *DigestServiceImpl) marketMove(ctx context.Context, market *models.Market, since time.Time) (digestMover, bool) {
+	history, err := service.repo.GetMarketHistory(ctx, market.ID, since)
+	if err != nil || len(history) == 0 {
+		return digestMover{}, false
+	}
+
+	best := digestMover{market: market}
+	for i, outcome := range market.Outcomes {
+		if i >= len(market.Percentages) {
+			break
+		}
+		from, ok := history[0].Percentage(outcome)
+		if !ok {
+			continue
+		}
+		if math.Abs(market.Percentages[i]-from) > math.Abs(best.to-best.from) {
+			best.outcome, best.from, best.to = outcome, from, market.Percentages[i]
+		}
+	}
+	return best, best.outcome != "" && math.Round(best.to-best.from) != 0
+}
+
+// ProcessDueDigests posts a digest to every opted-in channel whose scheduled time has passed since
+// its last digest, emails one to every opted-in user the same way, and returns how many were
+// delivered. Channels and users seen for the first time are only marked, so their first digest goes
+// out at the next scheduled time.
+func (service *DigestServiceImpl) ProcessDueDigests(ctx context.Context, now time.Time) int {
+	var markets []*models.Market
+	sent := service.processDueEmailDigests(ctx, now, &markets)
+
+	configs, err := service.repo.GetAllChannelConfigs(ctx)
+	if err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to get channel configs: %v", err))
+		return sent
+	}
+
+	for _, config := range configs {
+		if config.DigestMode == models.DigestOff {
+			continue
+		}
+		if config.LastDigestAt.IsZero() {
+			service.markDigest(ctx, config, now)
+			continue
+		}
+		schedule := service.schedule
+		schedule.Location = service.location(config)
+		if !config.LastDigestAt.Before(schedule.LastOccurrence(config.DigestMode, now)) {
+			continue
+		}
+
+		if markets == nil {
+			if markets, err = service.marketService.FetchAllMarkets(ctx); err != nil {
+				service.logger.Error(fmt.Sprintf("Failed to fetch markets for digests: %v", err))
+				return sent
+			}
+		}
+
+		message := service.renderDigest(ctx, config.DigestMode, filterDigestMarkets(markets, config), schedule.Location, now)
+		message = LocalizeTimestamps(message, timezoneOrNil(config.Timezone))
+		if err := service.notifier.SendChannelMessage(ctx, config.ChannelID, message); err != nil {
+			service.logger.Error(fmt.Sprintf("Failed to send %s digest to channel %s: %v", config.DigestMode, config.ChannelID, err))
+		} else {
+			sent++
+		}
+
+		// Like reminders, a failed digest is not retried; the channel gets the next one
+		service.markDigest(ctx, config, now)
+	}
+	return sent
+}
+
+// processDueEmailDigests emails a digest of every market to each user due one and returns how many
+// were sent. Markets are fetched into markets once, for the channels' digests to reuse.
+func (service *DigestServiceImpl) processDueEmailDigests(ctx context.Context, now time.Time, markets *[]*models.Market) int {
+	if service.email == nil {
+		return 0
+	}
+	subscriptions, err := service.repo.GetAllSubscriptions(ctx)
+	if err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to get subscriptions: %v", err))
+		return 0
+	}
+
+	sent := 0
+	for _, subscription := range subscriptions {
+		settings := subscription.Email
+		if !settings.Verified() || settings.Digest == models.DigestOff {
+			continue
+		}
+		schedule := service.schedule
+		if loc := timezoneOrNil(subscription.Timezone); loc != nil {
+			schedule.Location = loc
+		}
+		if !settings.LastDigestAt.IsZero() && !settings.LastDigestAt.Before(schedule.LastOccurrence(settings.Digest, now)) {
+			continue
+		}
+
+		if !settings.LastDigestAt.IsZero() {
+			if *markets == nil {
+				if *markets, err = service.marketService.FetchAllMarkets(ctx); err != nil {
+					service.logger.Error(fmt.Sprintf("Failed to fetch markets for digests: %v", err))
+					return sent
+				}
+			}
+			message := service.renderDigest(ctx, settings.Digest, *markets, schedule.Location, now)
+			if err := service.email.SendDirectMessage(ctx, subscription.DiscordUserID, message); err != nil {
+				service.logger.Error(fmt.Sprintf("Failed to email %s digest to user %s: %v", settings.Digest, subscription.DiscordUserID, err))
+			} else {
+				sent++
+			}
+		}
+
+		// Like channels, a failed digest is not retried
+		settings.LastDigestAt = now
+		if err := service.repo.SaveSubscription(ctx, subscription); err != nil {
+			service.logger.Error(fmt.Sprintf("Failed to save digest time for user %s: %v", subscription.DiscordUserID, err))
+		}
+	}
+	return sent
+}
+
+// markDigest records when a channel's digest was last handled
+func (service *DigestServiceImpl) markDigest(ctx context.Context, config *models.ChannelConfig, now time.Time) {
+	config.LastDigestAt = now
+	if err := service.repo.SaveChannelConfig(ctx, config); err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to save digest time for channel %s: %v", config.ChannelID, err))
+	}
+}
+
+// Run checks for due digests on every tick until the context is cancelled
+func (service *DigestServiceImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	service.logger.Info(fmt.Sprintf("Digest scheduler started (%02d:%02d %s, interval %s)", service.schedule.Hour, service.schedule.Minute, service.schedule.Location, interval))
+	for {
+		select {
+		case <-ctx.Done():
+			service.logger.Info("Digest scheduler stopped")
+			return
+		case now := <-ticker.C:
+			if sent := service.ProcessDueDigests(ctx, now); sent > 0 {
+				service.logger.Info(fmt.Sprintf("Sent %d digests", sent))
+			}
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"net/http"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// SessionWithTransport returns a session sharing base's token, rate limits and state whose REST calls go
+// through the transport returned by wrap. wrap is given the transport base sends requests with, so the
+// new transport can pass on the requests it does not handle itself.
+func SessionWithTransport(base *discordgo.Session, wrap func(next http.RoundTripper) http.RoundTripper) *discordgo.Session {
+	next := http.DefaultTransport
+	client := &http.Client{}
+	if base.Client != nil {
+		client.Timeout = base.Client.Timeout
+		if base.Client.Transport != nil {
+			next = base.Client.Transport
+		}
+	}
+	client.Transport = wrap(next)
+	return &discordgo.Session{
+		Token:                  base.Token,
+		State:                  base.State,
+		StateEnabled:           base.StateEnabled,
+		Ratelimiter:            base.Ratelimiter,
+		MaxRestRetries:         base.MaxRestRetries,
+		ShouldRetryOnRateLimit: base.ShouldRetryOnRateLimit,
+		UserAgent:              base.UserAgent,
+		Client:                 client,
+	}
+}
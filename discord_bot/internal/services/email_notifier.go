@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"coral-bot/discord_bot/internal/repository"
+)
+
+// ErrEmailToChannel is returned when an EmailNotifier without a channel notifier is asked to post to a
+// channel
+var ErrEmailToChannel = errors.New("email notifier cannot post to channels")
+
+// emailSubjectPrefix starts the subject of every notification email
+const emailSubjectPrefix = "Coral Markets: "
+
+// EmailNotifier implements Notifier by emailing direct messages to the verified address a user linked
+// with link_email. Channel messages go to the notifier it wraps.
+type EmailNotifier struct {
+	repo     repository.SubscriptionRepository
+	mailer   Mailer
+	channels Notifier // nil when only emails are sent
+	clockAndIDs
+}
+
+// NewEmailNotifier creates an email notifier posting channel messages with channels, which may be nil
+func NewEmailNotifier(repo repository.SubscriptionRepository, mailer Mailer, channels Notifier) *EmailNotifier {
+	return &EmailNotifier{repo: repo, mailer: mailer, channels: channels}
+}
+
+// SendChannelMessage posts a message with the wrapped notifier
+func (n *EmailNotifier) SendChannelMessage(ctx context.Context, channelID string, message string) error {
+	if n.channels == nil {
+		return ErrEmailToChannel
+	}
+	return n.channels.SendChannelMessage(ctx, channelID, message)
+}
+
+// SendDirectMessage emails a message to a user as plain text, with times in their timezone or UTC.
+// ErrEmailNotLinked is returned when the user has no verified address.
+func (n *EmailNotifier) SendDirectMessage(ctx context.Context, discordUserID string, message string) error {
+	subscription, err := n.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+	if !subscription.Email.Verified() {
+		return ErrEmailNotLinked
+	}
+	loc := timezoneOrNil(subscription.Timezone)
+	if loc == nil {
+		loc = time.UTC
+	}
+	subject, body := EmailMessage(message, loc, n.now())
+	return n.mailer.SendMail(ctx, subscription.Email.Address, subject, body)
+}
+
+// EmailMessage turns a Discord message into the subject and plain text body of an email, as written by
+// PlainTextMessage
+func EmailMessage(message string, loc *time.Location, now time.Time) (subject, body string) {
+	title, body := PlainTextMessage(message, loc, now)
+	return emailSubjectPrefix + title, body
+}
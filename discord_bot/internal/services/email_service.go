@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/mail"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// EmailCodeTTL is how long a verification code sent by link_email can be entered
+const EmailCodeTTL = 15 * time.Minute
+
+// EmailCodeCooldown is how long a user waits before another verification code is sent
+const EmailCodeCooldown = time.Minute
+
+// maxEmailCodeAttempts is the number of wrong codes after which a code can no longer be entered
+const maxEmailCodeAttempts = 5
+
+var (
+	// ErrInvalidEmail is returned for a value that is not a plain email address
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrEmailCodeTooSoon is returned when a verification code was sent less than EmailCodeCooldown ago
+	ErrEmailCodeTooSoon = errors.New("a verification code was sent moments ago")
+	// ErrEmailCodeInvalid is returned for a wrong code, or when no code is waiting to be entered
+	ErrEmailCodeInvalid = errors.New("wrong verification code")
+	// ErrEmailCodeExpired is returned for a code entered after EmailCodeTTL or too many wrong attempts
+	ErrEmailCodeExpired = errors.New("verification code expired")
+	// ErrEmailNotLinked is returned when a user has no verified email address
+	ErrEmailNotLinked = errors.New("no email address is linked")
+)
+
+// EmailService defines the interface for linking users' email addresses and choosing what the bot
+// emails them
+type EmailService interface {
+	SendVerificationCode(ctx context.Context, discordUserID, address string) error
+	VerifyEmail(ctx context.Context, discordUserID, code string) (string, error)
+	UnlinkEmail(ctx context.Context, discordUserID string) (bool, error)
+	SetEmailNotifications(ctx context.Context, discordUserID, digest string, resolutions bool) (*models.EmailSettings, error)
+}
+
+// EmailServiceImpl implements EmailService
+type EmailServiceImpl struct {
+	repo   repository.SubscriptionRepository
+	mailer Mailer
+	logger *utils.Logger
+	clockAndIDs
+}
+
+// NewEmailService creates an email service sending verification codes with mailer
+func NewEmailService(repo repository.SubscriptionRepository, mailer Mailer, logger *utils.Logger) *EmailServiceImpl {
+	return &EmailServiceImpl{repo: repo, mailer: mailer, logger: logger}
+}
+
+// SendVerificationCode emails a code the user enters with VerifyEmail to link the address. A new code
+// replaces the previous one, and the linked address stays in use until the new one is verified.
+func (service *EmailServiceImpl) SendVerificationCode(ctx context.Context, discordUserID, address string) error {
+	address = strings.TrimSpace(address)
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Name != "" || parsed.Address != address {
+		return fmt.Errorf("%w %q", ErrInvalidEmail, address)
+	}
+
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+	now := service.now()
+	if subscription.Email != nil && now.Sub(subscription.Email.CodeSentAt) < EmailCodeCooldown {
+		return ErrEmailCodeTooSoon
+	}
+
+	number, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return err
+	}
+	code := fmt.Sprintf("%06d", number.Int64())
+	body := fmt.Sprintf("Your Coral Markets verification code is %s.\n\n"+
+		"Enter it in Discord with /verify_email within %d minutes to get notifications at this address. "+
+		"If you did not ask for it, ignore this email.\n", code, int(EmailCodeTTL.Minutes()))
+	if err := service.mailer.SendMail(ctx, address, "Your Coral Markets verification code", body); err != nil {
+		return fmt.Errorf("failed to send verification code: %w", err)
+	}
+
+	if subscription.Email == nil {
+		subscription.Email = &models.EmailSettings{}
+	}
+	subscription.Email.PendingAddress = address
+	subscription.Email.CodeHash = emailCodeHash(discordUserID, address, code)
+	subscription.Email.CodeSentAt = now
+	subscription.Email.CodeAttempts = 0
+	return service.repo.SaveSubscription(ctx, subscription)
+}
+
+// emailCodeHash hashes a verification code with the user and address it was sent for
+func emailCodeHash(discordUserID, address, code string) string {
+	sum := sha256.Sum256([]byte(discordUserID + "\n" + address + "\n" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyEmail links the address the user's pending code was sent to and returns it. Notification
+// settings chosen for a previous address carry over.
+func (service *EmailServiceImpl) VerifyEmail(ctx context.Context, discordUserID, code string) (string, error) {
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get subscription: %w", err)
+	}
+	settings := subscription.Email
+	if settings == nil || settings.CodeHash == "" {
+		return "", ErrEmailCodeInvalid
+	}
+	if service.now().Sub(settings.CodeSentAt) > EmailCodeTTL || settings.CodeAttempts >= maxEmailCodeAttempts {
+		clearEmailCode(settings)
+		return "", errors.Join(ErrEmailCodeExpired, service.repo.SaveSubscription(ctx, subscription))
+	}
+
+	hash := emailCodeHash(discordUserID, settings.PendingAddress, strings.TrimSpace(code))
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(settings.CodeHash)) != 1 {
+		settings.CodeAttempts++
+		return "", errors.Join(ErrEmailCodeInvalid, service.repo.SaveSubscription(ctx, subscription))
+	}
+
+	settings.Address = settings.PendingAddress
+	clearEmailCode(settings)
+	if err := service.repo.SaveSubscription(ctx, subscription); err != nil {
+		return "", err
+	}
+	return settings.Address, nil
+}
+
+// clearEmailCode forgets a pending verification code, keeping when it was sent for the cooldown
+func clearEmailCode(settings *models.EmailSettings) {
+	settings.PendingAddress = ""
+	settings.CodeHash = ""
+	settings.CodeAttempts = 0
+}
+
+// UnlinkEmail removes the user's address, verified or pending, and reports whether they had one.
+// Everything goes back to DMs.
+func (service *EmailServiceImpl) UnlinkEmail(ctx context.Context, discordUserID string) (bool, error) {
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get subscription: %w", err)
+	}
+	if subscription.Email == nil || (!subscription.Email.Verified() && subscription.Email.CodeHash == "") {
+		return false, nil
+	}
+	// When the last code was sent is kept, so unlinking does not skip the cooldown
+	subscription.Email = &models.EmailSettings{CodeSentAt: subscription.Email.CodeSentAt}
+	return true, service.repo.SaveSubscription(ctx, subscription)
+}
+
+// SetEmailNotifications chooses the digest, daily, weekly or off, emailed to the user and whether
+// their resolution notifications are emailed instead of DMed. The user must have a verified address.
+func (service *EmailServiceImpl) SetEmailNotifications(ctx context.Context, discordUserID, digest string, resolutions bool) (*models.EmailSettings, error) {
+	if !models.IsValidDigestMode(digest) {
+		return nil, fmt.Errorf("%w: digest must be daily, weekly or off", ErrInvalidChannelSettings)
+	}
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+	if !subscription.Email.Verified() {
+		return nil, ErrEmailNotLinked
+	}
+	subscription.Email.Digest = digest
+	subscription.Email.Resolutions = resolutions
+	if err := service.repo.SaveSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return subscription.Email, nil
+}
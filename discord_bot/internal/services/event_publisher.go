@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"coral-bot/discord_bot/internal/bus"
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/tracing"
+	"coral-bot/discord_bot/internal/utils"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// EventPublisher defines the interface for publishing the market events the bot processed, with
+// their delivery stats, so other services can consume what the bot saw and sent
+type EventPublisher interface {
+	Publish(ctx context.Context, event *models.ProcessedEvent) error
+}
+
+// BusEventPublisher implements EventPublisher on top of a message bus writer
+type BusEventPublisher struct {
+	writer bus.Writer
+	logger *utils.Logger
+	clockAndIDs
+}
+
+// NewBusEventPublisher creates a new publisher writing to writer
+func NewBusEventPublisher(writer bus.Writer, logger *utils.Logger) *BusEventPublisher {
+	return &BusEventPublisher{
+		writer: writer,
+		logger: logger,
+	}
+}
+
+// Publish publishes an event as JSON, keyed by its market so a market's events stay in order. Events
+// without an ID, delivered without the outbox, are given one so consumers can tell them apart.
+func (publisher *BusEventPublisher) Publish(ctx context.Context, event *models.ProcessedEvent) (err error) {
+	if event.ID == "" {
+		id, err := publisher.newID()
+		if err != nil {
+			return fmt.Errorf("failed to generate event id: %w", err)
+		}
+		event.ID = id
+	}
+	ctx, span := tracing.Start(ctx, "bus.publish",
+		attribute.String("messaging.destination", publisher.writer.Name()),
+		attribute.String("event.type", event.Type),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	key := ""
+	if event.Market != nil {
+		key = event.Market.ID
+	}
+	return publisher.writer.Publish(ctx, key, data)
+}
+
+// Close closes the writer
+func (publisher *BusEventPublisher) Close() error {
+	return publisher.writer.Close()
+}
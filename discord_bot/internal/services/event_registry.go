@@ -0,0 +1,130 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// customEventNamePattern matches the names custom event types can take, like the built-in ones
+var customEventNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ErrInvalidCustomEvent is returned when registering a custom event type that is incomplete, or whose
+// name is taken
+var ErrInvalidCustomEvent = errors.New("invalid custom event type")
+
+// EventRoutingPolicy decides who a custom event is delivered to
+type EventRoutingPolicy int
+
+const (
+	// RouteToFeed delivers the event like a market update: to the channels whose feed settings and
+	// routing rules accept it, to the guild default channels, and to the users subscribed to its market,
+	// creator or outcomes. The event needs a market ID.
+	RouteToFeed EventRoutingPolicy = iota
+	// RouteToSubscribers DMs the event to the users subscribed to its market only
+	RouteToSubscribers
+)
+
+// CustomEvent is a decoded custom event: the market it is about and the fields its message template
+// reads
+type CustomEvent struct {
+	Market models.Market
+	Fields map[string]interface{}
+}
+
+// CustomEventType describes an event type added without changing the web handler. Its payloads are
+// accepted at POST /discord/events/{name, with dashes for underscores}, in event batches and from the
+// message bus, decoded with Decode, written with Template and delivered as Routing says. Custom events
+// are of normal priority.
+type CustomEventType struct {
+	Name     string                                              // event type, such as market_featured
+	Summary  string                                              // describes the endpoint in the OpenAPI document
+	Decode   func(payload json.RawMessage) (*CustomEvent, error) // returned errors are sent back to the caller
+	Template string                                              // text/template of the message, executed on the CustomEvent
+	Routing  EventRoutingPolicy
+
+	template *template.Template
+}
+
+// Path returns the endpoint of the event type under /discord/events/
+func (eventType *CustomEventType) Path() string {
+	return strings.ReplaceAll(eventType.Name, "_", "-")
+}
+
+// Render writes the message of a custom event with the event type's template
+func (eventType *CustomEventType) Render(event *CustomEvent) (string, error) {
+	var message bytes.Buffer
+	if err := eventType.template.Execute(&message, event); err != nil {
+		return "", fmt.Errorf("failed to render %s message: %w", eventType.Name, err)
+	}
+	return strings.TrimSpace(message.String()), nil
+}
+
+// EventRegistry holds the custom event types. Types are registered at startup, before the web server
+// starts serving their endpoints.
+type EventRegistry struct {
+	mutex sync.RWMutex
+	types []*CustomEventType
+}
+
+// NewEventRegistry creates an empty event registry
+func NewEventRegistry() *EventRegistry {
+	return &EventRegistry{}
+}
+
+// Register adds a custom event type. Its name must be lowercase words joined by underscores and not be
+// the name of a built-in or already registered type, and it needs a decoder and a template that parses.
+func (registry *EventRegistry) Register(eventType CustomEventType) error {
+	if !customEventNamePattern.MatchString(eventType.Name) {
+		return fmt.Errorf("%w: name %q must be lowercase letters, digits and underscores", ErrInvalidCustomEvent, eventType.Name)
+	}
+	for _, builtin := range models.BuiltinEventTypes {
+		if builtin == eventType.Name {
+			return fmt.Errorf("%w: %s is a built-in event type", ErrInvalidCustomEvent, eventType.Name)
+		}
+	}
+	if eventType.Decode == nil {
+		return fmt.Errorf("%w: %s needs a decoder", ErrInvalidCustomEvent, eventType.Name)
+	}
+	tmpl, err := template.New(eventType.Name).Option("missingkey=error").Parse(eventType.Template)
+	if err != nil {
+		return fmt.Errorf("%w: %s template: %v", ErrInvalidCustomEvent, eventType.Name, err)
+	}
+	eventType.template = tmpl
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	for _, registered := range registry.types {
+		if registered.Name == eventType.Name {
+			return fmt.Errorf("%w: %s is already registered", ErrInvalidCustomEvent, eventType.Name)
+		}
+	}
+	registry.types = append(registry.types, &eventType)
+	return nil
+}
+
+// Lookup returns the custom event type of a name
+func (registry *EventRegistry) Lookup(name string) (*CustomEventType, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	for _, eventType := range registry.types {
+		if eventType.Name == name {
+			return eventType, true
+		}
+	}
+	return nil, false
+}
+
+// Types returns the custom event types in the order they were registered
+func (registry *EventRegistry) Types() []*CustomEventType {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	return append([]*CustomEventType(nil), registry.types...)
+}
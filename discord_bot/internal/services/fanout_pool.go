@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// DefaultFanoutWorkers is the number of sends run concurrently when FANOUT_WORKERS is not set
+const DefaultFanoutWorkers = 8
+
+// DefaultFanoutQueueSize is the number of sends of each priority a worker holds when FANOUT_QUEUE_SIZE
+// is not set
+const DefaultFanoutQueueSize = 100
+
+// FanoutStatus is a snapshot of the fan-out pool's load
+type FanoutStatus struct {
+	Workers   int   `json:"workers"`
+	Queued    int   `json:"queued"`     // sends waiting for a worker
+	MaxQueued int   `json:"max_queued"` // highest number of waiting sends since startup
+	InFlight  int   `json:"in_flight"`  // sends running now
+	Completed int64 `json:"completed"`
+	Blocked   int64 `json:"blocked"` // submissions that waited because their worker's queue was full
+
+	QueuedByPriority map[string]int `json:"queued_by_priority"` // waiting sends of each priority class
+}
+
+// fanoutJob is a send waiting in a worker's queue
+type fanoutJob struct {
+	ctx      context.Context
+	priority models.Priority
+	send     func(context.Context) error
+	done     func(error)
+}
+
+// fanoutWorker holds the queues of one worker, one per priority class
+type fanoutWorker map[models.Priority]chan fanoutJob
+
+// FanoutPool runs the sends of event fan-outs on a fixed number of workers. Every destination is
+// served by one worker, so the messages of a priority class to a channel or user are sent in the
+// order they were submitted even when several events fan out at once. A worker runs its queued
+// high-priority sends before normal ones and normal ones before low ones, so while Discord rate
+// limits the bot or a burst is backlogged, resolutions are not stuck behind routine updates. Each
+// queue is bounded, and Submit blocks while its queue is full, slowing fan-outs down to the rate
+// Discord accepts.
+type FanoutPool struct {
+	workers  []fanoutWorker
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	mutex     sync.Mutex
+	queued    map[models.Priority]int
+	maxQueued int
+	inFlight  int
+	completed int64
+	blocked   int64
+}
+
+// NewFanoutPool starts a pool of workers, each holding up to queueSize sends of each priority. Zero
+// or negative sizes use the defaults.
+func NewFanoutPool(workers, queueSize int) *FanoutPool {
+	if workers <= 0 {
+		workers = DefaultFanoutWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultFanoutQueueSize
+	}
+	pool := &FanoutPool{
+		workers: make([]fanoutWorker, workers),
+		stop:    make(chan struct{}),
+		queued:  make(map[models.Priority]int),
+	}
+	for i := range pool.workers {
+		pool.workers[i] = make(fanoutWorker)
+		for _, priority := range models.Priorities {
+			pool.workers[i][priority] = make(chan fanoutJob, queueSize)
+		}
+		go pool.work(pool.workers[i])
+	}
+	return pool
+}
+
+// Submit queues a send to a destination with a priority and calls done with its result once it ran.
+// When ctx ends before the send is queued or run, done gets the context's error and the send is skipped.
+func (pool *FanoutPool) Submit(ctx context.Context, destination string, priority models.Priority, send func(context.Context) error, done func(error)) {
+	worker := pool.workers[pool.shard(destination)]
+	queue, ok := worker[priority]
+	if !ok {
+		priority = models.PriorityNormal
+		queue = worker[priority]
+	}
+	job := fanoutJob{ctx: ctx, priority: priority, send: send, done: done}
+
+	pool.mutex.Lock()
+	pool.queued[priority]++
+	if queued := pool.totalQueued(); queued > pool.maxQueued {
+		pool.maxQueued = queued
+	}
+	pool.mutex.Unlock()
+
+	select {
+	case queue <- job:
+		return
+	default:
+	}
+
+	pool.mutex.Lock()
+	pool.blocked++
+	pool.mutex.Unlock()
+	select {
+	case queue <- job:
+	case <-ctx.Done():
+		pool.mutex.Lock()
+		pool.queued[priority]--
+		pool.mutex.Unlock()
+		done(ctx.Err())
+	}
+}
+
+// shard returns the worker serving a destination
+func (pool *FanoutPool) shard(destination string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(destination))
+	return int(hash.Sum32() % uint32(len(pool.workers)))
+}
+
+// totalQueued returns the number of waiting sends of every priority; the caller holds the mutex
+func (pool *FanoutPool) totalQueued() int {
+	total := 0
+	for _, queued := range pool.queued {
+		total += queued
+	}
+	return total
+}
+
+// Stop stops the workers once their current sends finish. Sends still queued never run, so a pool is
+// only stopped once nothing submits to it anymore.
+func (pool *FanoutPool) Stop() {
+	pool.stopOnce.Do(func() { close(pool.stop) })
+}
+
+// work runs the sends of one worker, each queue in order and higher priorities first, until the pool stops
+func (pool *FanoutPool) work(worker fanoutWorker) {
+	for {
+		job, ok := worker.next(pool.stop)
+		if !ok {
+			return
+		}
+		pool.mutex.Lock()
+		pool.queued[job.priority]--
+		pool.inFlight++
+		pool.mutex.Unlock()
+
+		err := job.ctx.Err()
+		if err == nil {
+			err = job.send(job.ctx)
+		}
+
+		pool.mutex.Lock()
+		pool.inFlight--
+		pool.completed++
+		pool.mutex.Unlock()
+		job.done(err)
+	}
+}
+
+// next waits for the worker's next send, taking it from the highest priority queue holding one, and
+// returns false once stop is closed
+func (worker fanoutWorker) next(stop <-chan struct{}) (fanoutJob, bool) {
+	for _, priority := range models.Priorities {
+		select {
+		case job := <-worker[priority]:
+			return job, true
+		default:
+		}
+	}
+	select {
+	case job := <-worker[models.PriorityHigh]:
+		return job, true
+	case job := <-worker[models.PriorityNormal]:
+		return job, true
+	case job := <-worker[models.PriorityLow]:
+		return job, true
+	case <-stop:
+		return fanoutJob{}, false
+	}
+}
+
+// Status returns the pool's current load
+func (pool *FanoutPool) Status() FanoutStatus {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	queuedByPriority := make(map[string]int, len(models.Priorities))
+	for _, priority := range models.Priorities {
+		queuedByPriority[priority.String()] = pool.queued[priority]
+	}
+	return FanoutStatus{
+		Workers:          len(pool.workers),
+		Queued:           pool.totalQueued(),
+		MaxQueued:        pool.maxQueued,
+		InFlight:         pool.inFlight,
+		Completed:        pool.completed,
+		Blocked:          pool.blocked,
+		QueuedByPriority: queuedByPriority,
+	}
+}
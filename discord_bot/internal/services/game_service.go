@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// DefaultGameStartingBalance is the play money a member starts a guild's game with
+const DefaultGameStartingBalance = 1000.0
+
+// DefaultGameLeaderboardSize is the number of members ranked by game_leaderboard
+const DefaultGameLeaderboardSize = 10
+
+// GameBetButtonPrefix starts the custom ID of the bet buttons under a market, followed by the market
+// ID and the index of the outcome
+const GameBetButtonPrefix = "bet"
+
+// ErrGameBalanceTooLow is returned for a bet larger than the member's balance, wrapped with the balance
+var ErrGameBalanceTooLow = errors.New("not enough play money")
+
+// GameService defines the interface for the per-guild prediction game, where members bet play money on
+// announced markets and their bets are settled when the markets resolve
+type GameService interface {
+	GetWallet(ctx context.Context, guildID, discordUserID string) (*models.GameWallet, error)
+	PlaceBet(ctx context.Context, guildID, discordUserID string, market *models.Market, outcome string, stake float64) (*models.GameBet, *models.GameWallet, error)
+	SettleMarket(ctx context.Context, market *models.Market) ([]*models.GameBet, error)
+	RefundMarket(ctx context.Context, marketID string) ([]*models.GameBet, error)
+	GetLeaderboard(ctx context.Context, guildID string, limit int) ([]models.GameStanding, error)
+	CreateSettlementMessage(market *models.Market, bets []*models.GameBet) string
+}
+
+// GameServiceImpl implements GameService on the repository's game ledger
+type GameServiceImpl struct {
+	repo            repository.SubscriptionRepository
+	startingBalance float64
+	logger          *utils.Logger
+	clockAndIDs
+}
+
+// NewGameService creates a game service giving members startingBalance of play money, or
+// DefaultGameStartingBalance when it is not above 0
+func NewGameService(repo repository.SubscriptionRepository, startingBalance float64, logger *utils.Logger) *GameServiceImpl {
+	if startingBalance <= 0 {
+		startingBalance = DefaultGameStartingBalance
+	}
+	return &GameServiceImpl{repo: repo, startingBalance: startingBalance, logger: logger}
+}
+
+// GetWallet returns a member's wallet in a guild's game, with the starting balance when they have not
+// played there yet
+func (service *GameServiceImpl) GetWallet(ctx context.Context, guildID, discordUserID string) (*models.GameWallet, error) {
+	return service.wallet(ctx, service.repo, guildID, discordUserID)
+}
+
+// wallet returns a member's wallet from repo, or a new one with the starting balance
+func (service *GameServiceImpl) wallet(ctx context.Context, repo repository.SubscriptionRepository, guildID, discordUserID string) (*models.GameWallet, error) {
+	wallet, err := repo.GetGameWallet(ctx, guildID, discordUserID)
+	if err != nil || wallet != nil {
+		return wallet, err
+	}
+	now := service.now()
+	return &models.GameWallet{GuildID: guildID, DiscordUserID: discordUserID, Balance: service.startingBalance, CreatedAt: now, UpdatedAt: now}, nil
+}
+
+// PlaceBet stakes play money from a member's wallet on an outcome of an open market, at the outcome's
+// current probability, and returns the bet with the wallet after it
+func (service *GameServiceImpl) PlaceBet(ctx context.Context, guildID, discordUserID string, market *models.Market, outcome string, stake float64) (*models.GameBet, *models.GameWallet, error) {
+	stake = math.Round(stake*100) / 100
+	if stake <= 0 || math.IsInf(stake, 0) || math.IsNaN(stake) {
+		return nil, nil, fmt.Errorf("%w: the stake must be at least $0.01", ErrInvalidChannelSettings)
+	}
+	index := outcomeIndex(market, outcome)
+	if index < 0 {
+		return nil, nil, fmt.Errorf("%w: %q is not an outcome of the market", ErrInvalidChannelSettings, outcome)
+	}
+	if !Tradable(market, service.now()) {
+		return nil, nil, fmt.Errorf("%w: the market is closed to bets", ErrInvalidChannelSettings)
+	}
+	if index >= len(market.Percentages) || market.Percentages[index] <= 0 {
+		return nil, nil, fmt.Errorf("%w: %s has no price to bet at yet", ErrInvalidChannelSettings, market.Outcomes[index])
+	}
+	id, err := service.newID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := service.now()
+	price := math.Min(market.Percentages[index]/100, 1)
+	bet := &models.GameBet{
+		ID:            "bet_" + id,
+		GuildID:       guildID,
+		DiscordUserID: discordUserID,
+		MarketID:      market.ID,
+		MarketTitle:   market.Title,
+		Outcome:       market.Outcomes[index],
+		Stake:         stake,
+		Price:         price,
+		Shares:        stake / price,
+		Status:        models.GameBetOpen,
+		PlacedAt:      now,
+	}
+	var wallet *models.GameWallet
+	err = service.repo.WithTx(ctx, func(tx repository.SubscriptionRepository) error {
+		var err error
+		if wallet, err = service.wallet(ctx, tx, guildID, discordUserID); err != nil {
+			return fmt.Errorf("failed to get wallet: %w", err)
+		}
+		if stake > wallet.Balance+0.005 {
+			return fmt.Errorf("%w: you have $%.2f", ErrGameBalanceTooLow, wallet.Balance)
+		}
+		wallet.Balance = math.Max(wallet.Balance-stake, 0)
+		wallet.Staked += stake
+		wallet.Bets++
+		wallet.UpdatedAt = now
+		if err := tx.SaveGameBet(ctx, bet); err != nil {
+			return fmt.Errorf("failed to save bet: %w", err)
+		}
+		return tx.SaveGameWallet(ctx, wallet)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	service.logger.Info(fmt.Sprintf("User %s bet $%.2f of play money on %s in market %s in guild %s", discordUserID, stake, bet.Outcome, market.ID, guildID))
+	return bet, wallet, nil
+}
+
+// SettleMarket pays out the open bets on a resolved market: each share of an outcome pays its pool
+// share of a split resolution, or 1 when the outcome won outright. A market resolved without a winner
+// is left unsettled. Bets are settled once, so a repeated resolution settles nothing.
+func (service *GameServiceImpl) SettleMarket(ctx context.Context, market *models.Market) ([]*models.GameBet, error) {
+	if len(market.Winners()) == 0 {
+		return nil, nil
+	}
+	return service.settle(ctx, market.ID, func(bet *models.GameBet) {
+		bet.Payout = math.Round(bet.Shares*payoutPerShare(market, bet.Outcome)*100) / 100
+		bet.Status = models.GameBetLost
+		if bet.Payout > 0 {
+			bet.Status = models.GameBetWon
+		}
+	})
+}
+
+// RefundMarket returns the stakes of the open bets on a cancelled market
+func (service *GameServiceImpl) RefundMarket(ctx context.Context, marketID string) ([]*models.GameBet, error) {
+	return service.settle(ctx, marketID, func(bet *models.GameBet) {
+		bet.Payout = bet.Stake
+		bet.Status = models.GameBetRefunded
+	})
+}
+
+// settle closes the open bets on a market with outcome, which sets each bet's status and payout, and
+// credits the payouts to the wallets in one transaction
+func (service *GameServiceImpl) settle(ctx context.Context, marketID string, outcome func(bet *models.GameBet)) ([]*models.GameBet, error) {
+	var settled []*models.GameBet
+	err := service.repo.WithTx(ctx, func(tx repository.SubscriptionRepository) error {
+		bets, err := tx.GetOpenGameBets(ctx, marketID)
+		if err != nil {
+			return fmt.Errorf("failed to get open bets: %w", err)
+		}
+		now := service.now()
+		wallets := make(map[string]*models.GameWallet)
+		for _, bet := range bets {
+			outcome(bet)
+			bet.SettledAt = now
+			key := bet.GuildID + "/" + bet.DiscordUserID
+			wallet := wallets[key]
+			if wallet == nil {
+				if wallet, err = service.wallet(ctx, tx, bet.GuildID, bet.DiscordUserID); err != nil {
+					return fmt.Errorf("failed to get wallet: %w", err)
+				}
+				wallets[key] = wallet
+			}
+			wallet.Balance += bet.Payout
+			wallet.Staked = math.Max(wallet.Staked-bet.Stake, 0)
+			if bet.Status == models.GameBetWon {
+				wallet.Wins++
+			}
+			wallet.UpdatedAt = now
+			if err := tx.SaveGameBet(ctx, bet); err != nil {
+				return fmt.Errorf("failed to save bet %s: %w", bet.ID, err)
+			}
+		}
+		for _, wallet := range wallets {
+			if err := tx.SaveGameWallet(ctx, wallet); err != nil {
+				return fmt.Errorf("failed to save the wallet of user %s in guild %s: %w", wallet.DiscordUserID, wallet.GuildID, err)
+			}
+		}
+		settled = bets
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(settled) > 0 {
+		service.logger.Info(fmt.Sprintf("Settled %d game bets on market %s", len(settled), marketID))
+	}
+	return settled, nil
+}
+
+// payoutPerShare returns what a share of an outcome pays once a market resolved
+func payoutPerShare(market *models.Market, outcome string) float64 {
+	split := false
+	for _, payout := range market.Payouts {
+		split = split || payout.PoolShare > 0
+	}
+	if !split {
+		if market.PaidOut(outcome) {
+			return 1
+		}
+		return 0
+	}
+	for _, payout := range market.Payouts {
+		if strings.EqualFold(payout.Outcome, outcome) {
+			return payout.PoolShare / 100
+		}
+	}
+	return 0
+}
+
+// GetLeaderboard ranks the members of a guild's game by their balance with open bets at their stake,
+// keeping at most limit, or DefaultGameLeaderboardSize when limit is not above 0
+func (service *GameServiceImpl) GetLeaderboard(ctx context.Context, guildID string, limit int) ([]models.GameStanding, error) {
+	if limit <= 0 {
+		limit = DefaultGameLeaderboardSize
+	}
+	wallets, err := service.repo.GetGameWallets(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(wallets, func(i, j int) bool {
+		if wallets[i].Worth() != wallets[j].Worth() {
+			return wallets[i].Worth() > wallets[j].Worth()
+		}
+		return wallets[i].DiscordUserID < wallets[j].DiscordUserID
+	})
+	if len(wallets) > limit {
+		wallets = wallets[:limit]
+	}
+	standings := make([]models.GameStanding, 0, len(wallets))
+	for i, wallet := range wallets {
+		standings = append(standings, models.GameStanding{
+			Rank:          i + 1,
+			DiscordUserID: wallet.DiscordUserID,
+			Worth:         wallet.Worth(),
+			Profit:        wallet.Worth() - service.startingBalance,
+			Bets:          wallet.Bets,
+			Wins:          wallet.Wins,
+		})
+	}
+	return standings, nil
+}
+
+// CreateSettlementMessage tells a member how their bets on a market were settled
+func (service *GameServiceImpl) CreateSettlementMessage(market *models.Market, bets []*models.GameBet) string {
+	title := market.Title
+	if title == "" && len(bets) > 0 {
+		title = bets[0].MarketTitle
+	}
+	var message strings.Builder
+	fmt.Fprintf(&message, "🎲 **GAME BETS SETTLED** 🎲\n\n**%s**\n", EscapeMarkdown(title))
+	for _, bet := range bets {
+		switch bet.Status {
+		case models.GameBetWon:
+			fmt.Fprintf(&message, "✅ $%.2f on **%s** at %.1f%% paid **$%.2f** (%s)\n", bet.Stake, EscapeMarkdown(bet.Outcome), bet.Price*100, bet.Payout, signedAmount(bet.Payout-bet.Stake))
+		case models.GameBetRefunded:
+			fmt.Fprintf(&message, "↩️ $%.2f on **%s** was refunded, the market was cancelled\n", bet.Stake, EscapeMarkdown(bet.Outcome))
+		default:
+			fmt.Fprintf(&message, "❌ $%.2f on **%s** at %.1f%% lost\n", bet.Stake, EscapeMarkdown(bet.Outcome), bet.Price*100)
+		}
+	}
+	message.WriteString("\nPlay money only. See the standings with `/game_leaderboard`")
+	return message.String()
+}
+
+// BetButtons returns a row of bet buttons for the outcomes of a market, or nil when it has none or
+// its ID does not fit in a custom ID
+func BetButtons(market *models.Market) []discordgo.MessageComponent {
+	return outcomeButtons(market, GameBetButtonPrefix, "🎲 Bet ", discordgo.SecondaryButton)
+}
+
+// ParseBetButton splits the custom ID of a bet button, or of the modal it opens, into the market ID
+// and the outcome's index
+func ParseBetButton(customID string) (marketID string, outcome int, ok bool) {
+	return parseOutcomeButton(customID, GameBetButtonPrefix)
+}
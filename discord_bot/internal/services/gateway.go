@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Gateway connection states reported by GatewayMonitor
+const (
+	GatewayConnecting   = "connecting"
+	GatewayConnected    = "connected"
+	GatewayDisconnected = "disconnected"
+)
+
+// DefaultGatewayBufferSize is the number of outbound messages held while the gateway is disconnected
+const DefaultGatewayBufferSize = 500
+
+// gatewayFlushTimeout bounds each buffered message sent after a reconnect
+const gatewayFlushTimeout = 10 * time.Second
+
+// GatewayStatus is a snapshot of the Discord gateway connection
+type GatewayStatus struct {
+	State      string    `json:"state"`
+	Since      time.Time `json:"since"`
+	Reconnects int       `json:"reconnects"`
+	Buffered   int       `json:"buffered"` // outbound messages waiting for a reconnect
+	Dropped    int       `json:"dropped"`  // buffered messages discarded because the buffer was full
+}
+
+// bufferedMessage is an outbound message held while the gateway is down or the bot is in maintenance
+type bufferedMessage struct {
+	priority models.Priority
+	send     func(context.Context) error
+}
+
+// messageQueue holds buffered messages, taken higher priorities first and each priority in order
+type messageQueue []bufferedMessage
+
+// push adds a message to a queue holding at most max. When the queue is full, the oldest message of the
+// lowest priority is dropped, which is the new message itself when everything queued ranks higher. It
+// returns the priority of the dropped message and whether one was dropped.
+func (queue *messageQueue) push(message bufferedMessage, max int) (models.Priority, bool) {
+	*queue = append(*queue, message)
+	if len(*queue) <= max {
+		return 0, false
+	}
+	drop := 0
+	for i, queued := range *queue {
+		if queued.priority < (*queue)[drop].priority {
+			drop = i
+		}
+	}
+	dropped := (*queue)[drop].priority
+	*queue = append((*queue)[:drop], (*queue)[drop+1:]...)
+	return dropped, true
+}
+
+// pop removes and returns the oldest message of the highest priority
+func (queue *messageQueue) pop() bufferedMessage {
+	next := 0
+	for i, queued := range *queue {
+		if queued.priority > (*queue)[next].priority {
+			next = i
+		}
+	}
+	message := (*queue)[next]
+	*queue = append((*queue)[:next], (*queue)[next+1:]...)
+	return message
+}
+
+// GatewayMonitor tracks the Discord gateway connection and buffers outbound messages while it is down,
+// sending them once the connection is resumed or re-established, higher priorities first and each
+// priority in order.
+type GatewayMonitor struct {
+	state         string
+	since         time.Time
+	everConnected bool
+	reconnects    int
+	dropped       int
+	outbox        messageQueue
+	maxBuffered   int
+	logger        *utils.Logger
+	mutex         sync.Mutex
+}
+
+// NewGatewayMonitor creates a monitor that buffers up to maxBuffered messages while disconnected
+func NewGatewayMonitor(maxBuffered int, logger *utils.Logger) *GatewayMonitor {
+	if maxBuffered <= 0 {
+		maxBuffered = DefaultGatewayBufferSize
+	}
+	return &GatewayMonitor{
+		state:       GatewayConnecting,
+		since:       time.Now(),
+		maxBuffered: maxBuffered,
+		logger:      logger,
+	}
+}
+
+// Register subscribes the monitor to a session's connection events
+func (monitor *GatewayMonitor) Register(session *discordgo.Session) {
+	session.AddHandler(monitor.HandleConnect)
+	session.AddHandler(monitor.HandleDisconnect)
+	session.AddHandler(monitor.HandleResumed)
+}
+
+// HandleConnect marks the gateway connected and flushes buffered messages
+func (monitor *GatewayMonitor) HandleConnect(session *discordgo.Session, event *discordgo.Connect) {
+	monitor.connected("connected")
+}
+
+// HandleResumed marks the gateway connected after a session resume and flushes buffered messages
+func (monitor *GatewayMonitor) HandleResumed(session *discordgo.Session, event *discordgo.Resumed) {
+	monitor.connected("resumed")
+}
+
+// HandleDisconnect marks the gateway disconnected so outbound messages are buffered
+func (monitor *GatewayMonitor) HandleDisconnect(session *discordgo.Session, event *discordgo.Disconnect) {
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+
+	if monitor.state == GatewayDisconnected {
+		return
+	}
+	monitor.state = GatewayDisconnected
+	monitor.since = time.Now()
+	monitor.logger.Warning("Discord gateway disconnected; buffering outbound messages until it reconnects")
+}
+
+// connected records a (re)connection and sends everything buffered while the gateway was down
+func (monitor *GatewayMonitor) connected(how string) {
+	monitor.mutex.Lock()
+	if monitor.state == GatewayConnected {
+		monitor.mutex.Unlock()
+		return
+	}
+	if monitor.everConnected {
+		monitor.reconnects++
+		monitor.logger.Info(fmt.Sprintf("Discord gateway %s; flushing %d buffered messages", how, len(monitor.outbox)))
+	}
+	monitor.everConnected = true
+	monitor.state = GatewayConnected
+	monitor.since = time.Now()
+	monitor.mutex.Unlock()
+
+	monitor.flush()
+}
+
+// flush sends buffered messages, higher priorities first and each priority in order, stopping if the
+// gateway drops again
+func (monitor *GatewayMonitor) flush() {
+	for {
+		monitor.mutex.Lock()
+		if monitor.state != GatewayConnected || len(monitor.outbox) == 0 {
+			monitor.mutex.Unlock()
+			return
+		}
+		send := monitor.outbox.pop().send
+		monitor.mutex.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), gatewayFlushTimeout)
+		if err := send(ctx); err != nil {
+			monitor.logger.Error(fmt.Sprintf("Failed to send buffered message: %v", err))
+		}
+		cancel()
+	}
+}
+
+// Deliver runs send immediately while the gateway is connected, or buffers it with normal priority
+func (monitor *GatewayMonitor) Deliver(ctx context.Context, send func(context.Context) error) error {
+	return monitor.DeliverPriority(ctx, models.PriorityNormal, send)
+}
+
+// DeliverPriority runs send immediately while the gateway is connected. Otherwise the send is buffered
+// for the next reconnect and DeliverPriority returns nil. When the buffer is full, the oldest message
+// of the lowest priority is dropped, which is the new message itself when everything buffered ranks higher.
+func (monitor *GatewayMonitor) DeliverPriority(ctx context.Context, priority models.Priority, send func(context.Context) error) error {
+	monitor.mutex.Lock()
+	if monitor.state != GatewayDisconnected {
+		monitor.mutex.Unlock()
+		return send(ctx)
+	}
+	defer monitor.mutex.Unlock()
+
+	if dropped, ok := monitor.outbox.push(bufferedMessage{priority: priority, send: send}, monitor.maxBuffered); ok {
+		monitor.dropped++
+		monitor.logger.Warning(fmt.Sprintf("Outbound message buffer full; dropped the oldest buffered %s priority message", dropped))
+	}
+	return nil
+}
+
+// Status returns the current connection state
+func (monitor *GatewayMonitor) Status() GatewayStatus {
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+
+	return GatewayStatus{
+		State:      monitor.state,
+		Since:      monitor.since,
+		Reconnects: monitor.reconnects,
+		Buffered:   len(monitor.outbox),
+		Dropped:    monitor.dropped,
+	}
+}
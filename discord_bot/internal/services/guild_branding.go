@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// maxFooterLength is the longest footer line a guild's branding can add, in characters
+const maxFooterLength = 256
+
+// customEmojiPattern matches a Discord custom emoji, like <:coral:123456789012345678>
+var customEmojiPattern = regexp.MustCompile(`^<a?:\w{2,32}:\d{17,20}>$`)
+
+// ParseAccentColor reads an accent color written as hex RGB, like #1abc9c or 1abc9c
+func ParseAccentColor(value string) (int, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(value), "#")
+	color, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return 0, fmt.Errorf("%w: accent color %q is not a hex color like #1abc9c", ErrInvalidChannelSettings, value)
+	}
+	return int(color), nil
+}
+
+// FormatAccentColor writes an accent color as hex RGB, or returns "" for no color
+func FormatAccentColor(color int) string {
+	if color == 0 {
+		return ""
+	}
+	return fmt.Sprintf("#%06x", color)
+}
+
+// ValidateGuildBranding checks a guild's branding: each emoji must be a custom emoji or a few emoji
+// characters for an event posted in channels, and the footer a single line with an optional web link
+func ValidateGuildBranding(branding *models.GuildBranding) error {
+	allowed := make(map[string]bool, len(models.ChannelPingEvents))
+	for _, event := range models.ChannelPingEvents {
+		allowed[event] = true
+	}
+	for event, emoji := range branding.Emojis {
+		if !allowed[event] {
+			return fmt.Errorf("%w: %q is not an event posted in channels, expected some of %s", ErrInvalidChannelSettings, event, strings.Join(models.ChannelPingEvents, ", "))
+		}
+		if !validEmoji(emoji) {
+			return fmt.Errorf("%w: the %s emoji %q is not an emoji", ErrInvalidChannelSettings, event, emoji)
+		}
+	}
+	if branding.AccentColor < 0 || branding.AccentColor > 0xffffff {
+		return fmt.Errorf("%w: the accent color must be an RGB color", ErrInvalidChannelSettings)
+	}
+	if strings.ContainsAny(branding.FooterText, "\r\n") || utf8.RuneCountInString(branding.FooterText) > maxFooterLength {
+		return fmt.Errorf("%w: the footer must be a single line of at most %d characters", ErrInvalidChannelSettings, maxFooterLength)
+	}
+	if branding.FooterURL != "" {
+		if branding.FooterText == "" {
+			return fmt.Errorf("%w: a footer link needs footer text", ErrInvalidChannelSettings)
+		}
+		link, err := url.Parse(branding.FooterURL)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+			return fmt.Errorf("%w: footer link %q is not a web address", ErrInvalidChannelSettings, branding.FooterURL)
+		}
+	}
+	return nil
+}
+
+// validEmoji reports whether a value is a custom emoji or up to 8 characters, none of them plain
+// text, so an emoji cannot carry markdown or mentions
+func validEmoji(emoji string) bool {
+	if customEmojiPattern.MatchString(emoji) {
+		return true
+	}
+	if emoji == "" || utf8.RuneCountInString(emoji) > 8 {
+		return false
+	}
+	for _, r := range emoji {
+		if r < utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// SetGuildBranding sets how the alerts in a guild's channels look, or returns them to the bot's style
+// when branding is nil
+func (service *SubscriptionServiceImpl) SetGuildBranding(ctx context.Context, guildID string, branding *models.GuildBranding) (*models.GuildConfig, error) {
+	if branding != nil {
+		if err := ValidateGuildBranding(branding); err != nil {
+			return nil, err
+		}
+	}
+	var result *models.GuildConfig
+	err := service.withTx(ctx, func(tx *SubscriptionServiceImpl) error {
+		config, err := tx.repo.GetGuildConfig(ctx, guildID)
+		if err != nil {
+			return fmt.Errorf("failed to get guild config: %w", err)
+		}
+		if config == nil {
+			config = &models.GuildConfig{GuildID: guildID}
+		}
+		config.Branding = branding
+		config.UpdatedAt = tx.now()
+		result = config
+		return tx.repo.SaveGuildConfig(ctx, config)
+	})
+	return result, err
+}
+
+// BrandMessage restyles an event message with a guild's branding: the emojis around its header, like
+// "🎉 **NEW MARKET ALERT** 🎉", become the guild's emoji for the event, and the guild's footer is added
+// under it. The accent color is applied when the message is sent.
+func BrandMessage(message, eventType string, branding *models.GuildBranding) string {
+	if branding == nil {
+		return message
+	}
+	if emoji := branding.Emojis[eventType]; emoji != "" {
+		header, rest, multiline := strings.Cut(message, "\n")
+		start, end := strings.Index(header, " **"), strings.LastIndex(header, "** ")
+		if start > 0 && end > start {
+			message = emoji + header[start:end+3] + emoji
+			if multiline {
+				message += "\n" + rest
+			}
+		}
+	}
+	switch {
+	case branding.FooterURL != "":
+		message += fmt.Sprintf("\n\n-# [%s](%s)", branding.FooterText, branding.FooterURL)
+	case branding.FooterText != "":
+		message += "\n\n-# " + branding.FooterText
+	}
+	return message
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// DefaultHTTPLogCapacity is the number of logged requests kept unless another capacity is set
+const DefaultHTTPLogCapacity = 200
+
+// MaxHTTPLogCapacity is the most logged requests that can be kept
+const MaxHTTPLogCapacity = 5000
+
+// DefaultHTTPLogBodyBytes is the longest request or response body logged unless another limit is set;
+// longer bodies are cut
+const DefaultHTTPLogBodyBytes = 4096
+
+// MaxHTTPLogBodyBytes is the highest body limit that can be set
+const MaxHTTPLogBodyBytes = 64 * 1024
+
+// Values logged in place of what was redacted
+const (
+	redactedValue    = "[REDACTED]"
+	redactedUserMark = "user:"
+)
+
+// ErrInvalidHTTPLogConfig is returned when setting a sample rate, capacity, body limit or endpoint out of range
+var ErrInvalidHTTPLogConfig = errors.New("invalid HTTP log config")
+
+// HTTPLogConfig decides which requests to the web server are logged, and how much of them is kept
+type HTTPLogConfig struct {
+	SampleRate   float64  `json:"sample_rate"`    // share of the requests to any endpoint that are logged, from 0 to 1
+	Endpoints    []string `json:"endpoints"`      // endpoint patterns whose requests are all logged; a trailing * matches a prefix
+	Capacity     int      `json:"capacity"`       // logged requests kept, the oldest dropped first
+	MaxBodyBytes int      `json:"max_body_bytes"` // longest request or response body logged
+}
+
+// Enabled reports whether any request is logged
+func (config HTTPLogConfig) Enabled() bool {
+	return config.SampleRate > 0 || len(config.Endpoints) > 0
+}
+
+// sensitiveHeaders are the request headers that carry credentials, logged as redacted
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"X-Api-Key":           true,
+}
+
+// secretKeyParts mark JSON fields and query parameters holding credentials or contact details, whose
+// values are logged as redacted
+var secretKeyParts = []string{"token", "secret", "password", "api_key", "apikey", "authorization", "email"}
+
+// userObjectKeys are JSON fields holding a Discord user object, such as the user of an interaction
+var userObjectKeys = map[string]bool{"user": true, "author": true}
+
+// userObjectFields are the identifying fields of a Discord user object
+var userObjectFields = map[string]bool{"id": true, "username": true, "global_name": true}
+
+// mentionPattern matches Discord user mentions in message text
+var mentionPattern = regexp.MustCompile(`<@!?(\d+)>`)
+
+// HTTPLogService defines the interface for the debug log of requests to the web server, kept in a ring
+// buffer in the store
+type HTTPLogService interface {
+	Config() HTTPLogConfig
+	SetConfig(config HTTPLogConfig) (HTTPLogConfig, error)
+	ShouldLog(route string) (reason string, ok bool)
+	Record(ctx context.Context, entry *models.HTTPLogEntry) error
+	Entries(ctx context.Context, filter models.HTTPLogFilter) ([]*models.HTTPLogEntry, error)
+	Clear(ctx context.Context) (int, error)
+}
+
+// HTTPLogServiceImpl implements HTTPLogService
+type HTTPLogServiceImpl struct {
+	repo   repository.SubscriptionRepository
+	logger *utils.Logger
+	key    []byte // keys the pseudonyms of redacted user IDs, new on every start
+
+	mutex  sync.RWMutex
+	config HTTPLogConfig
+	clockAndIDs
+}
+
+// NewHTTPLogService creates the request log with a config, which must be valid
+func NewHTTPLogService(repo repository.SubscriptionRepository, config HTTPLogConfig, logger *utils.Logger) (*HTTPLogServiceImpl, error) {
+	service := &HTTPLogServiceImpl{repo: repo, logger: logger, key: make([]byte, 32)}
+	if _, err := rand.Read(service.key); err != nil {
+		return nil, fmt.Errorf("failed to create the pseudonym key: %w", err)
+	}
+	if _, err := service.SetConfig(config); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// Config returns which requests are logged
+func (service *HTTPLogServiceImpl) Config() HTTPLogConfig {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+	config := service.config
+	config.Endpoints = append([]string{}, config.Endpoints...)
+	return config
+}
+
+// SetConfig changes which requests are logged and returns the config with defaults filled in. A zero
+// capacity or body limit takes the default. A smaller capacity drops the oldest entries on the next
+// request logged.
+func (service *HTTPLogServiceImpl) SetConfig(config HTTPLogConfig) (HTTPLogConfig, error) {
+	if config.SampleRate < 0 || config.SampleRate > 1 {
+		return HTTPLogConfig{}, fmt.Errorf("%w: sample_rate must be between 0 and 1", ErrInvalidHTTPLogConfig)
+	}
+	if config.Capacity == 0 {
+		config.Capacity = DefaultHTTPLogCapacity
+	}
+	if config.Capacity < 0 || config.Capacity > MaxHTTPLogCapacity {
+		return HTTPLogConfig{}, fmt.Errorf("%w: capacity must be between 1 and %d", ErrInvalidHTTPLogConfig, MaxHTTPLogCapacity)
+	}
+	if config.MaxBodyBytes == 0 {
+		config.MaxBodyBytes = DefaultHTTPLogBodyBytes
+	}
+	if config.MaxBodyBytes < 0 || config.MaxBodyBytes > MaxHTTPLogBodyBytes {
+		return HTTPLogConfig{}, fmt.Errorf("%w: max_body_bytes must be between 1 and %d", ErrInvalidHTTPLogConfig, MaxHTTPLogBodyBytes)
+	}
+	endpoints := []string{}
+	for _, endpoint := range config.Endpoints {
+		endpoint = strings.TrimSpace(endpoint)
+		if !strings.HasPrefix(endpoint, "/") {
+			return HTTPLogConfig{}, fmt.Errorf("%w: endpoint %q must start with /", ErrInvalidHTTPLogConfig, endpoint)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	config.Endpoints = endpoints
+
+	service.mutex.Lock()
+	service.config = config
+	service.mutex.Unlock()
+	if config.Enabled() {
+		service.logger.Info(fmt.Sprintf("Logging requests: %g sampled, endpoints %v", config.SampleRate, config.Endpoints))
+	}
+	return service.Config(), nil
+}
+
+// ShouldLog decides whether to log a request to an endpoint, and why: every request to the configured
+// endpoints is logged, and a sample of the others
+func (service *HTTPLogServiceImpl) ShouldLog(route string) (string, bool) {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+	for _, endpoint := range service.config.Endpoints {
+		if endpoint == route || strings.HasSuffix(endpoint, "*") && strings.HasPrefix(route, strings.TrimSuffix(endpoint, "*")) {
+			return "endpoint", true
+		}
+	}
+	if service.config.SampleRate > 0 && mathrand.Float64() < service.config.SampleRate {
+		return "sampled", true
+	}
+	return "", false
+}
+
+// Record redacts a logged request and stores it, dropping the oldest entries beyond the capacity. Auth
+// headers and secret fields are replaced with [REDACTED], and user IDs, in the path, the query string,
+// JSON fields and mentions, with pseudonyms that stay the same until the bot restarts.
+func (service *HTTPLogServiceImpl) Record(ctx context.Context, entry *models.HTTPLogEntry) error {
+	config := service.Config()
+	id, err := service.newID()
+	if err != nil {
+		return fmt.Errorf("failed to create log entry ID: %w", err)
+	}
+	entry.ID = id
+	if entry.Time.IsZero() {
+		entry.Time = service.now().UTC()
+	}
+
+	for name := range entry.RequestHeaders {
+		if sensitiveHeaders[name] || secretKey(name) {
+			entry.RequestHeaders[name] = redactedValue
+		}
+	}
+	entry.Path = service.redactPath(entry.Route, entry.Path)
+	var truncated bool
+	entry.RequestBody, truncated = service.redactBody(entry.RequestBody, config.MaxBodyBytes)
+	entry.Truncated = entry.Truncated || truncated
+	entry.ResponseBody, truncated = service.redactBody(entry.ResponseBody, config.MaxBodyBytes)
+	entry.Truncated = entry.Truncated || truncated
+
+	if err := service.repo.SaveHTTPLogEntry(ctx, entry, config.Capacity); err != nil {
+		return fmt.Errorf("failed to save log entry: %w", err)
+	}
+	return nil
+}
+
+// Entries returns the logged requests matching a filter, most recent first
+func (service *HTTPLogServiceImpl) Entries(ctx context.Context, filter models.HTTPLogFilter) ([]*models.HTTPLogEntry, error) {
+	entries, err := service.repo.GetHTTPLogEntries(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logged requests: %w", err)
+	}
+	return entries, nil
+}
+
+// Clear deletes every logged request and returns how many there were
+func (service *HTTPLogServiceImpl) Clear(ctx context.Context) (int, error) {
+	cleared, err := service.repo.ClearHTTPLog(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear logged requests: %w", err)
+	}
+	return cleared, nil
+}
+
+// secretKey reports whether a field, parameter or header name holds credentials or contact details
+func secretKey(name string) bool {
+	name = strings.ToLower(strings.ReplaceAll(name, "-", "_"))
+	for _, part := range secretKeyParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// userIDKey reports whether a field or parameter name holds Discord user IDs
+func userIDKey(name string) bool {
+	name = strings.ToLower(name)
+	return name == "user_id" || name == "user_ids" || strings.HasSuffix(name, "_user_id") || strings.HasSuffix(name, "_user_ids")
+}
+
+// pseudonym replaces a user ID with a keyed hash, so requests about the same user can be matched
+// without logging who they are
+func (service *HTTPLogServiceImpl) pseudonym(userID string) string {
+	mac := hmac.New(sha256.New, service.key)
+	mac.Write([]byte(userID))
+	return redactedUserMark + hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// redactPath redacts the path segments of user ID parameters of the route, and the query parameters
+// holding user IDs or secrets
+func (service *HTTPLogServiceImpl) redactPath(route, path string) string {
+	rawPath, rawQuery, _ := strings.Cut(path, "?")
+	routeSegments, pathSegments := strings.Split(route, "/"), strings.Split(rawPath, "/")
+	if len(routeSegments) == len(pathSegments) {
+		for i, segment := range routeSegments {
+			if strings.HasPrefix(segment, "{") && userIDKey(strings.Trim(segment, "{}")) {
+				pathSegments[i] = service.pseudonym(pathSegments[i])
+			}
+		}
+	}
+	redacted := strings.Join(pathSegments, "/")
+	if rawQuery == "" {
+		return redacted
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return redacted + "?" + redactedValue
+	}
+	for name, values := range query {
+		for i, value := range values {
+			switch {
+			case secretKey(name):
+				values[i] = redactedValue
+			case userIDKey(name):
+				values[i] = service.pseudonym(value)
+			}
+		}
+	}
+	return redacted + "?" + query.Encode()
+}
+
+// redactBody redacts a JSON body and cuts it at limit bytes, reporting whether it was cut. Bodies that
+// are not JSON cannot be redacted, so only their size is logged.
+func (service *HTTPLogServiceImpl) redactBody(body string, limit int) (string, bool) {
+	if strings.TrimSpace(body) == "" {
+		return "", false
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		return fmt.Sprintf("[%d bytes, not JSON]", len(body)), false
+	}
+	encoded, _ := json.Marshal(service.redactValue("", value))
+	if len(encoded) > limit {
+		return string(encoded[:limit]), true
+	}
+	return string(encoded), false
+}
+
+// redactValue redacts a decoded JSON value found under a field name
+func (service *HTTPLogServiceImpl) redactValue(name string, value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, field := range typed {
+			if userObjectKeys[name] && userObjectFields[key] {
+				if text, ok := field.(string); ok {
+					typed[key] = service.pseudonym(text)
+					continue
+				}
+			}
+			typed[key] = service.redactValue(key, field)
+		}
+		return typed
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = service.redactValue(name, item)
+		}
+		return typed
+	case string:
+		switch {
+		case secretKey(name):
+			return redactedValue
+		case userIDKey(name):
+			return service.pseudonym(typed)
+		}
+		return mentionPattern.ReplaceAllStringFunc(typed, func(mention string) string {
+			return "<@" + service.pseudonym(mentionPattern.FindStringSubmatch(mention)[1]) + ">"
+		})
+	case nil:
+		return nil
+	default:
+		if secretKey(name) {
+			return redactedValue
+		}
+		return typed
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"coral-bot/discord_bot/internal/tracing"
+	"coral-bot/discord_bot/internal/utils"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// UTMSource is the utm_source of decorated links
+const UTMSource = "discord"
+
+// ShortenerTimeout bounds a request to the link shortener
+const ShortenerTimeout = 5 * time.Second
+
+// maxShortLinks is the number of short links kept before the cache is cleared
+const maxShortLinks = 10000
+
+// unsafeLinkCharacters would end a link inside a Markdown link, or start Markdown of their own
+const unsafeLinkCharacters = " \t\r\n()<>[]`"
+
+// markdownLinkTarget matches the target of a Markdown link, like the one of [View](https://...)
+var markdownLinkTarget = regexp.MustCompile(`\]\((https://[^\s()<>]+)\)`)
+
+// LinkDecorator adds click tracking to the links of outgoing messages: UTM parameters naming Discord
+// and the channel the message is posted in, and a short link from the shortener service when one is
+// set. Only links on the allowed domains are decorated, so a guild's footer link is left as written.
+// A nil decorator leaves messages unchanged.
+type LinkDecorator struct {
+	utm          bool
+	campaign     string // utm_campaign of decorated links, empty for none
+	shortenerURL string
+	token        string
+	client       *http.Client
+	logger       *utils.Logger
+	linkAllowlist
+
+	mutex sync.Mutex
+	short map[string]string // long link to its short link
+}
+
+// NewLinkDecorator creates a link decorator that leaves links unchanged until SetUTM or SetShortener
+// is called
+func NewLinkDecorator(logger *utils.Logger) *LinkDecorator {
+	return &LinkDecorator{
+		client: &http.Client{},
+		logger: logger,
+		short:  make(map[string]string),
+	}
+}
+
+// SetUTM sets whether links carry UTM parameters, with the given campaign when not empty
+func (decorator *LinkDecorator) SetUTM(enabled bool, campaign string) {
+	decorator.utm = enabled
+	decorator.campaign = campaign
+}
+
+// SetShortener routes links through the shortener service at shortenerURL, authenticating with token
+// when not empty. An empty URL turns shortening off.
+func (decorator *LinkDecorator) SetShortener(shortenerURL, token string) {
+	decorator.shortenerURL = shortenerURL
+	decorator.token = token
+}
+
+// Decorate returns content with the links of its Markdown links decorated for the channel it is posted
+// in. A link the shortener fails to shorten keeps its UTM parameters.
+func (decorator *LinkDecorator) Decorate(ctx context.Context, content, channelID string) string {
+	if decorator == nil || (!decorator.utm && decorator.shortenerURL == "") {
+		return content
+	}
+	return markdownLinkTarget.ReplaceAllStringFunc(content, func(target string) string {
+		link := target[len("](") : len(target)-len(")")]
+		if decorator.safeLink(link) == "" {
+			return target
+		}
+		return "](" + decorator.decorate(ctx, link, channelID) + ")"
+	})
+}
+
+// decorate adds the UTM parameters to a link and shortens it
+func (decorator *LinkDecorator) decorate(ctx context.Context, link, channelID string) string {
+	if decorator.utm {
+		link = decorator.withUTM(link, channelID)
+	}
+	if decorator.shortenerURL == "" {
+		return link
+	}
+	short, err := decorator.shorten(ctx, link)
+	if err != nil {
+		decorator.logger.Warning(fmt.Sprintf("Failed to shorten link %s, posting it in full: %v", link, err))
+		return link
+	}
+	return short
+}
+
+// withUTM returns a link with utm_source, utm_campaign when set and the channel as utm_content
+func (decorator *LinkDecorator) withUTM(link, channelID string) string {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	query := parsed.Query()
+	query.Set("utm_source", UTMSource)
+	if decorator.campaign != "" {
+		query.Set("utm_campaign", decorator.campaign)
+	}
+	if channelID != "" {
+		query.Set("utm_content", channelID)
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// shortenRequest is the body of a request to the link shortener
+type shortenRequest struct {
+	URL string `json:"url"`
+}
+
+// shortenResponse is the body the link shortener answers with
+type shortenResponse struct {
+	ShortURL string `json:"short_url"`
+}
+
+// shorten returns the short link of a link, asking the shortener service for links not shortened
+// before
+func (decorator *LinkDecorator) shorten(ctx context.Context, link string) (short string, err error) {
+	decorator.mutex.Lock()
+	short, ok := decorator.short[link]
+	decorator.mutex.Unlock()
+	if ok {
+		return short, nil
+	}
+
+	body, err := json.Marshal(shortenRequest{URL: link})
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, ShortenerTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, decorator.shortenerURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if decorator.token != "" {
+		req.Header.Set("Authorization", "Bearer "+decorator.token)
+	}
+	req, span := tracing.StartClient(req, "shortener.Shorten")
+	defer func() { tracing.End(span, err) }()
+
+	resp, err := decorator.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("shortener returned status %d", resp.StatusCode)
+	}
+	var shortened shortenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&shortened); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	parsed, err := url.Parse(shortened.ShortURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" || strings.ContainsAny(shortened.ShortURL, unsafeLinkCharacters) {
+		return "", fmt.Errorf("shortener returned %q, not an https link", shortened.ShortURL)
+	}
+
+	decorator.mutex.Lock()
+	if len(decorator.short) >= maxShortLinks {
+		decorator.short = make(map[string]string)
+	}
+	decorator.short[link] = shortened.ShortURL
+	decorator.mutex.Unlock()
+	return shortened.ShortURL, nil
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultSMTPPort is the submission port used when SMTP_PORT is not set
+const DefaultSMTPPort = 587
+
+// Mailer sends plain text emails
+type Mailer interface {
+	SendMail(ctx context.Context, to, subject, body string) error
+}
+
+// SMTPMailer implements Mailer with an SMTP server, upgrading the connection with STARTTLS when the
+// server offers it
+type SMTPMailer struct {
+	host     string
+	port     int
+	username string // empty sends without authenticating
+	password string
+	from     string
+}
+
+// NewSMTPMailer creates a mailer sending from the given address through an SMTP server, on
+// DefaultSMTPPort when port is 0
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	if port == 0 {
+		port = DefaultSMTPPort
+	}
+	return &SMTPMailer{host: host, port: port, username: username, password: password, from: from}
+}
+
+// SendMail sends an email. The SMTP client cannot be cancelled, so the context is only checked before
+// connecting.
+func (m *SMTPMailer) SendMail(ctx context.Context, to, subject, body string) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, span := tracing.Start(ctx, "smtp.SendMail", attribute.String("smtp.host", m.host))
+	defer func() { tracing.End(span, err) }()
+
+	message, err := composeMail(m.from, to, subject, body, time.Now())
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+	return smtp.SendMail(net.JoinHostPort(m.host, strconv.Itoa(m.port)), auth, m.from, []string{to}, message)
+}
+
+// composeMail writes a UTF-8 plain text email with its headers. The body is quoted-printable, so long
+// lines and any character survive the trip.
+func composeMail(from, to, subject, body string, date time.Time) ([]byte, error) {
+	if strings.ContainsAny(from+to+subject, "\r\n") {
+		return nil, fmt.Errorf("email headers cannot contain line breaks")
+	}
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", to)
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	writer := quotedprintable.NewWriter(&message)
+	if _, err := writer.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return message.Bytes(), nil
+}
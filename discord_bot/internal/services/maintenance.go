@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// DefaultMaintenanceBufferSize is the number of outbound messages held while the bot is in maintenance
+const DefaultMaintenanceBufferSize = 5000
+
+// MaxMaintenanceNoticeLength is the longest maintenance notice, in characters
+const MaxMaintenanceNoticeLength = 1000
+
+// DefaultMaintenanceNotice is the reply to commands while the bot is in maintenance, unless another
+// notice is given
+const DefaultMaintenanceNotice = "🛠️ The bot is down for maintenance and will be back shortly. Notifications are paused and will be sent once maintenance is over."
+
+// MaintenanceStatus is a snapshot of maintenance mode
+type MaintenanceStatus struct {
+	Enabled  bool      `json:"enabled"`
+	Since    time.Time `json:"since"`            // when maintenance last started or ended
+	Notice   string    `json:"notice,omitempty"` // the reply to commands during maintenance
+	Buffered int       `json:"buffered"`         // outbound messages waiting for maintenance to end
+	Dropped  int       `json:"dropped"`          // buffered messages discarded because the buffer was full
+}
+
+// MaintenanceMode pauses outbound notifications while the bot is maintained. Messages sent during
+// maintenance are buffered and sent once it ends, higher priorities first and each priority in order,
+// and commands are answered with a maintenance notice. It is the bot-wide counterpart of the gateway
+// monitor's buffer, and sits in front of it.
+type MaintenanceMode struct {
+	enabled     bool
+	since       time.Time
+	notice      string
+	outbox      messageQueue
+	sending     int // buffered messages taken from the outbox and being sent
+	flushing    bool
+	maxBuffered int
+	dropped     int
+	logger      *utils.Logger
+	mutex       sync.Mutex
+}
+
+// NewMaintenanceMode creates a maintenance mode that is off and buffers up to maxBuffered messages
+// once started
+func NewMaintenanceMode(maxBuffered int, logger *utils.Logger) *MaintenanceMode {
+	if maxBuffered <= 0 {
+		maxBuffered = DefaultMaintenanceBufferSize
+	}
+	return &MaintenanceMode{
+		since:       time.Now(),
+		maxBuffered: maxBuffered,
+		logger:      logger,
+	}
+}
+
+// Start puts the bot in maintenance, answering commands with notice, or DefaultMaintenanceNotice when
+// it is empty. Starting it again only changes the notice.
+func (maintenance *MaintenanceMode) Start(notice string) {
+	if notice == "" {
+		notice = DefaultMaintenanceNotice
+	}
+	maintenance.mutex.Lock()
+	defer maintenance.mutex.Unlock()
+
+	maintenance.notice = notice
+	if maintenance.enabled {
+		return
+	}
+	maintenance.enabled = true
+	maintenance.since = time.Now()
+	maintenance.logger.Warning("Maintenance mode is on; buffering outbound messages until it ends")
+}
+
+// End takes the bot out of maintenance and sends the buffered messages in the background. It returns
+// how many messages are being sent, 0 when the bot was not in maintenance.
+func (maintenance *MaintenanceMode) End() int {
+	maintenance.mutex.Lock()
+	defer maintenance.mutex.Unlock()
+
+	if !maintenance.enabled {
+		return 0
+	}
+	maintenance.enabled = false
+	maintenance.since = time.Now()
+	buffered := len(maintenance.outbox)
+	maintenance.logger.Info(fmt.Sprintf("Maintenance mode is off; flushing %d buffered messages", buffered))
+	if !maintenance.flushing && buffered > 0 {
+		maintenance.flushing = true
+		go maintenance.flush()
+	}
+	return buffered
+}
+
+// flush sends buffered messages, higher priorities first and each priority in order, stopping if
+// maintenance starts again
+func (maintenance *MaintenanceMode) flush() {
+	for {
+		maintenance.mutex.Lock()
+		maintenance.sending = 0
+		if maintenance.enabled || len(maintenance.outbox) == 0 {
+			maintenance.flushing = false
+			maintenance.mutex.Unlock()
+			return
+		}
+		send := maintenance.outbox.pop().send
+		maintenance.sending = 1
+		maintenance.mutex.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), gatewayFlushTimeout)
+		if err := send(ctx); err != nil {
+			maintenance.logger.Error(fmt.Sprintf("Failed to send message buffered during maintenance: %v", err))
+		}
+		cancel()
+	}
+}
+
+// Notice returns the reply to commands and whether the bot is in maintenance
+func (maintenance *MaintenanceMode) Notice() (string, bool) {
+	maintenance.mutex.Lock()
+	defer maintenance.mutex.Unlock()
+	return maintenance.notice, maintenance.enabled
+}
+
+// Deliver runs send immediately outside maintenance, or buffers it with normal priority
+func (maintenance *MaintenanceMode) Deliver(ctx context.Context, send func(context.Context) error) error {
+	return maintenance.DeliverPriority(ctx, models.PriorityNormal, send)
+}
+
+// DeliverPriority runs send immediately outside maintenance. During maintenance the send is buffered
+// until it ends and DeliverPriority returns nil. When the buffer is full, the oldest message of the
+// lowest priority is dropped, as in the gateway monitor's buffer.
+func (maintenance *MaintenanceMode) DeliverPriority(ctx context.Context, priority models.Priority, send func(context.Context) error) error {
+	maintenance.mutex.Lock()
+	if !maintenance.enabled {
+		maintenance.mutex.Unlock()
+		return send(ctx)
+	}
+	defer maintenance.mutex.Unlock()
+
+	if dropped, ok := maintenance.outbox.push(bufferedMessage{priority: priority, send: send}, maintenance.maxBuffered); ok {
+		maintenance.dropped++
+		maintenance.logger.Warning(fmt.Sprintf("Maintenance buffer full; dropped the oldest buffered %s priority message", dropped))
+	}
+	return nil
+}
+
+// Status returns whether the bot is in maintenance and how many messages wait for it to end
+func (maintenance *MaintenanceMode) Status() MaintenanceStatus {
+	maintenance.mutex.Lock()
+	defer maintenance.mutex.Unlock()
+
+	status := MaintenanceStatus{
+		Enabled:  maintenance.enabled,
+		Since:    maintenance.since,
+		Buffered: len(maintenance.outbox) + maintenance.sending,
+		Dropped:  maintenance.dropped,
+	}
+	if maintenance.enabled {
+		status.Notice = maintenance.notice
+	}
+	return status
+}
+
+// MaintenanceNotifier implements Notifier by holding the messages of the notifier it wraps while the
+// bot is in maintenance
+type MaintenanceNotifier struct {
+	maintenance *MaintenanceMode
+	next        Notifier
+}
+
+// NewMaintenanceNotifier creates a notifier sending with next outside maintenance, and buffering the
+// messages until maintenance ends otherwise
+func NewMaintenanceNotifier(maintenance *MaintenanceMode, next Notifier) *MaintenanceNotifier {
+	return &MaintenanceNotifier{maintenance: maintenance, next: next}
+}
+
+// SendChannelMessage posts a message with the wrapped notifier, or buffers it during maintenance
+func (n *MaintenanceNotifier) SendChannelMessage(ctx context.Context, channelID string, message string) error {
+	return n.maintenance.Deliver(ctx, func(ctx context.Context) error {
+		return n.next.SendChannelMessage(ctx, channelID, message)
+	})
+}
+
+// SendDirectMessage sends a DM with the wrapped notifier, or buffers it during maintenance
+func (n *MaintenanceNotifier) SendDirectMessage(ctx context.Context, discordUserID string, message string) error {
+	return n.maintenance.Deliver(ctx, func(ctx context.Context) error {
+		return n.next.SendDirectMessage(ctx, discordUserID, message)
+	})
+}
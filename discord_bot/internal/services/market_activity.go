@@ -0,0 +1,17 @@
+package services
+
+import (
+	"context"
+	"fmt"
+)
+
+// SetMarketActivity turns a user's activity preference on or off. With it on, comments and other activity
+// on the markets they subscribed to are sent to them as well.
+func (service *SubscriptionServiceImpl) SetMarketActivity(ctx context.Context, discordUserID string, enabled bool) error {
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+	subscription.Activity = enabled
+	return service.repo.SaveSubscription(ctx, subscription)
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// MarketAnnouncementRetention is how long the new markets announced in each channel are remembered,
+// to skip repeated announcements and the markets backfills already posted. It covers the longest
+// backfill window.
+const MarketAnnouncementRetention = 7 * 24 * time.Hour
+
+// marketAnnouncementPruneInterval is how often expired announcements are pruned when one is claimed
+const marketAnnouncementPruneInterval = time.Hour
+
+// MarketAnnouncementService defines the interface for remembering which new markets were announced in
+// each channel, so a market the backend announces twice is only posted once per channel
+type MarketAnnouncementService interface {
+	Claim(ctx context.Context, channelID, marketID string) (bool, error)
+}
+
+// MarketAnnouncementServiceImpl implements MarketAnnouncementService
+type MarketAnnouncementServiceImpl struct {
+	repo   repository.SubscriptionRepository
+	logger *utils.Logger
+
+	mutex      sync.Mutex
+	lastPruned time.Time
+	clockAndIDs
+}
+
+// NewMarketAnnouncementService creates a new market announcement service
+func NewMarketAnnouncementService(repo repository.SubscriptionRepository, logger *utils.Logger) *MarketAnnouncementServiceImpl {
+	return &MarketAnnouncementServiceImpl{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Claim records that a new market is announced in a channel and returns true, or returns false when
+// it was already announced there within MarketAnnouncementRetention
+func (service *MarketAnnouncementServiceImpl) Claim(ctx context.Context, channelID, marketID string) (bool, error) {
+	now := service.now()
+	service.pruneExpired(ctx, now)
+	claimed, err := service.repo.MarkMarketAnnounced(ctx, channelID, marketID, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to record announcement: %w", err)
+	}
+	return claimed, nil
+}
+
+// pruneExpired forgets the announcements older than MarketAnnouncementRetention, at most once per
+// prune interval
+func (service *MarketAnnouncementServiceImpl) pruneExpired(ctx context.Context, now time.Time) {
+	service.mutex.Lock()
+	due := now.Sub(service.lastPruned) >= marketAnnouncementPruneInterval
+	if due {
+		service.lastPruned = now
+	}
+	service.mutex.Unlock()
+
+	if !due {
+		return
+	}
+	if err := service.repo.PruneMarketAnnouncements(ctx, now.Add(-MarketAnnouncementRetention)); err != nil {
+		service.logger.Warning(fmt.Sprintf("Failed to prune market announcements: %v", err))
+	}
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/tracing"
+	"coral-bot/discord_bot/internal/utils"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// boardDebounce is how long the board waits after an event before refreshing, so a burst of
+// events becomes a single edit per channel
+const boardDebounce = 15 * time.Second
+
+// boardSize is the number of markets listed on a board
+const boardSize = 10
+
+// boardTitleLength keeps each board row on a single line
+const boardTitleLength = 60
+
+// maxBoardLength is Discord's message length limit; rows that would not fit are left off
+const maxBoardLength = 2000
+
+// ErrBoardMessageGone is returned by a BoardMessenger when the board message was deleted
+var ErrBoardMessageGone = errors.New("board message not found")
+
+// BoardMessenger posts, edits and removes the pinned board messages
+type BoardMessenger interface {
+	PostBoard(ctx context.Context, channelID, content string) (messageID string, err error)
+	PinBoard(ctx context.Context, channelID, messageID string) error
+	EditBoard(ctx context.Context, channelID, messageID, content string) error
+	DeleteBoard(ctx context.Context, channelID, messageID string) error
+}
+
+// DiscordBoardMessenger implements BoardMessenger using a Discord session
+type DiscordBoardMessenger struct {
+	session *discordgo.Session
+	links   *LinkDecorator // nil posts links as they were rendered
+}
+
+// NewDiscordBoardMessenger creates a new Discord board messenger
+func NewDiscordBoardMessenger(session *discordgo.Session) *DiscordBoardMessenger {
+	return &DiscordBoardMessenger{session: session}
+}
+
+// SetLinkDecorator sets the decorator adding click tracking to the links of boards
+func (m *DiscordBoardMessenger) SetLinkDecorator(decorator *LinkDecorator) {
+	m.links = decorator
+}
+
+// PostBoard sends a new board message and returns its ID
+func (m *DiscordBoardMessenger) PostBoard(ctx context.Context, channelID, content string) (messageID string, err error) {
+	ctx, span := tracing.Start(ctx, "discord.ChannelMessageSend", attribute.String("discord.channel_id", channelID))
+	defer func() { tracing.End(span, err) }()
+
+	message, err := m.session.ChannelMessageSend(channelID, m.links.Decorate(ctx, content, channelID), discordgo.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	return message.ID, nil
+}
+
+// PinBoard pins a board message, which needs the Manage Messages permission
+func (m *DiscordBoardMessenger) PinBoard(ctx context.Context, channelID, messageID string) (err error) {
+	ctx, span := tracing.Start(ctx, "discord.ChannelMessagePin", attribute.String("discord.channel_id", channelID))
+	defer func() { tracing.End(span, err) }()
+
+	return m.session.ChannelMessagePin(channelID, messageID, discordgo.WithContext(ctx))
+}
+
+// EditBoard replaces a board message's content, returning ErrBoardMessageGone when it was deleted
+func (m *DiscordBoardMessenger) EditBoard(ctx context.Context, channelID, messageID, content string) (err error) {
+	ctx, span := tracing.Start(ctx, "discord.ChannelMessageEdit", attribute.String("discord.channel_id", channelID))
+	defer func() { tracing.End(span, err) }()
+
+	_, err = m.session.ChannelMessageEdit(channelID, messageID, m.links.Decorate(ctx, content, channelID), discordgo.WithContext(ctx))
+	if isUnknownMessage(err) {
+		return ErrBoardMessageGone
+	}
+	return err
+}
+
+// DeleteBoard deletes a board message; a message that is already gone is not an error
+func (m *DiscordBoardMessenger) DeleteBoard(ctx context.Context, channelID, messageID string) (err error) {
+	ctx, span := tracing.Start(ctx, "discord.ChannelMessageDelete", attribute.String("discord.channel_id", channelID))
+	defer func() { tracing.End(span, err) }()
+
+	err = m.session.ChannelMessageDelete(channelID, messageID, discordgo.WithContext(ctx))
+	if isUnknownMessage(err) {
+		return nil
+	}
+	return err
+}
+
+// isUnknownMessage reports whether Discord rejected a request because the message does not exist
+func isUnknownMessage(err error) bool {
+	var restErr *discordgo.RESTError
+	return errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownMessage
+}
+
+// MarketBoardService defines the interface for the pinned market boards kept in opted-in channels
+type MarketBoardService interface {
+	BuildBoard(markets []*models.Market, config *models.ChannelConfig) string
+	RefreshBoards(ctx context.Context) int
+	MarketsChanged()
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// MarketBoardServiceImpl implements MarketBoardService
+type MarketBoardServiceImpl struct {
+	repo          repository.SubscriptionRepository
+	marketService MarketService
+	messenger     BoardMessenger
+	logger        *utils.Logger
+	changed       chan struct{} // signals that events arrived since the last refresh
+	linkAllowlist
+
+	mutex    sync.Mutex
+	rendered map[string]string // channel ID to the content its board shows, to skip edits that change nothing
+}
+
+// NewMarketBoardService creates a new market board service
+func NewMarketBoardService(
+	repo repository.SubscriptionRepository,
+	marketService MarketService,
+	messenger BoardMessenger,
+	logger *utils.Logger,
+) *MarketBoardServiceImpl {
+	return &MarketBoardServiceImpl{
+		repo:          repo,
+		marketService: marketService,
+		messenger:     messenger,
+		logger:        logger,
+		changed:       make(chan struct{}, 1),
+		rendered:      make(map[string]string),
+	}
+}
+
+// BuildBoard renders the most traded active markets in the channel's allowed categories, with their
+// leading outcome, volume and close time. Close times are relative Discord timestamps, so they stay
+// current between edits and need no timezone.
+func (service *MarketBoardServiceImpl) BuildBoard(markets []*models.Market, config *models.ChannelConfig) string {
+	var active []*models.Market
+	for _, market := range filterDigestMarkets(markets, config) {
+		if market.Status == "active" {
+			active = append(active, market)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].Volume > active[j].Volume })
+
+	var board strings.Builder
+	board.WriteString("📌 **Market Board** — top active markets by volume\n\n")
+	if len(active) == 0 {
+		board.WriteString("No active markets right now.\n")
+	}
+	for i, market := range active {
+		if i == boardSize {
+			break
+		}
+		leader := "—"
+		if outcome, percentage, ok := leadingOutcome(market); ok {
+			leader = fmt.Sprintf("%s %.0f%%", EscapeMarkdown(outcome), percentage)
+		}
+		closes := "no close time"
+		if !market.EndTime.IsZero() {
+			closes = "closes " + DiscordTimestamp(market.EndTime, TimestampRelative)
+		}
+		row := fmt.Sprintf("%d. %s — %s • %s • %s\n", i+1, service.markdownLink(market.Title, boardTitleLength, market.Link), leader, compactAmount(market.Volume), closes)
+		if board.Len()+len(row) > maxBoardLength {
+			break
+		}
+		board.WriteString(row)
+	}
+	return board.String()
+}
+
+// leadingOutcome returns the outcome with the highest probability
+func leadingOutcome(market *models.Market) (string, float64, bool) {
+	best := -1
+	for i := range market.Outcomes {
+		if i >= len(market.Percentages) {
+			break
+		}
+		if best < 0 || market.Percentages[i] > market.Percentages[best] {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "", 0, false
+	}
+	return market.Outcomes[best], market.Percentages[best], true
+}
+
+// RefreshBoards brings the board of every opted-in channel up to date, posting and pinning it when
+// it does not exist yet, and removes the boards of channels that turned the board off. It returns
+// how many boards were posted or edited.
+func (service *MarketBoardServiceImpl) RefreshBoards(ctx context.Context) int {
+	configs, err := service.repo.GetAllChannelConfigs(ctx)
+	if err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to get channel configs: %v", err))
+		return 0
+	}
+
+	var markets []*models.Market
+	refreshed := 0
+	for _, config := range configs {
+		if !config.Board {
+			if config.BoardMessageID != "" {
+				service.removeBoard(ctx, config)
+			}
+			continue
+		}
+
+		if markets == nil {
+			if markets, err = service.marketService.FetchAllMarkets(ctx); err != nil {
+				service.logger.Error(fmt.Sprintf("Failed to fetch markets for boards: %v", err))
+				return refreshed
+			}
+		}
+		if service.refreshBoard(ctx, config, service.BuildBoard(markets, config)) {
+			refreshed++
+		}
+	}
+	return refreshed
+}
+
+// refreshBoard edits a channel's board when its content changed, or posts a new one when the channel
+// has none or it was deleted. It reports whether a message was posted or edited.
+func (service *MarketBoardServiceImpl) refreshBoard(ctx context.Context, config *models.ChannelConfig, content string) bool {
+	if config.BoardMessageID != "" {
+		if service.renderedBoard(config.ChannelID) == content {
+			return false
+		}
+		err := service.messenger.EditBoard(ctx, config.ChannelID, config.BoardMessageID, content)
+		if err == nil {
+			service.setRendered(config.ChannelID, content)
+			return true
+		}
+		if !errors.Is(err, ErrBoardMessageGone) {
+			service.logger.Error(fmt.Sprintf("Failed to edit market board in channel %s: %v", config.ChannelID, err))
+			return false
+		}
+		service.logger.Info(fmt.Sprintf("Market board in channel %s was deleted, posting a new one", config.ChannelID))
+	}
+
+	messageID, err := service.messenger.PostBoard(ctx, config.ChannelID, content)
+	if err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to post market board in channel %s: %v", config.ChannelID, err))
+		return false
+	}
+	if err := service.messenger.PinBoard(ctx, config.ChannelID, messageID); err != nil {
+		service.logger.Warning(fmt.Sprintf("Failed to pin market board in channel %s, the bot needs Manage Messages: %v", config.ChannelID, err))
+	}
+	service.setRendered(config.ChannelID, content)
+	service.saveBoardMessage(ctx, config, messageID)
+	return true
+}
+
+// removeBoard deletes the board of a channel that turned it off
+func (service *MarketBoardServiceImpl) removeBoard(ctx context.Context, config *models.ChannelConfig) {
+	if err := service.messenger.DeleteBoard(ctx, config.ChannelID, config.BoardMessageID); err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to delete market board in channel %s: %v", config.ChannelID, err))
+		return
+	}
+	service.setRendered(config.ChannelID, "")
+	service.saveBoardMessage(ctx, config, "")
+}
+
+// saveBoardMessage records the ID of a channel's board message
+func (service *MarketBoardServiceImpl) saveBoardMessage(ctx context.Context, config *models.ChannelConfig, messageID string) {
+	config.BoardMessageID = messageID
+	if err := service.repo.SaveChannelConfig(ctx, config); err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to save market board of channel %s: %v", config.ChannelID, err))
+	}
+}
+
+// renderedBoard returns the content a channel's board was last set to
+func (service *MarketBoardServiceImpl) renderedBoard(channelID string) string {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	return service.rendered[channelID]
+}
+
+// setRendered records the content a channel's board was set to
+func (service *MarketBoardServiceImpl) setRendered(channelID, content string) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	if content == "" {
+		delete(service.rendered, channelID)
+		return
+	}
+	service.rendered[channelID] = content
+}
+
+// MarketsChanged asks for the boards to be refreshed shortly, after a market event or a change to a
+// channel's board setting. It never blocks.
+func (service *MarketBoardServiceImpl) MarketsChanged() {
+	select {
+	case service.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Run refreshes the boards immediately, then on every tick and shortly after markets change, until
+// the context is cancelled
+func (service *MarketBoardServiceImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	service.logger.Info(fmt.Sprintf("Market board updater started (interval %s)", interval))
+	for {
+		service.RefreshBoards(ctx)
+
+		select {
+		case <-ctx.Done():
+			service.logger.Info("Market board updater stopped")
+			return
+		case <-ticker.C:
+		case <-service.changed:
+			select {
+			case <-ctx.Done():
+				service.logger.Info("Market board updater stopped")
+				return
+			case <-time.After(boardDebounce):
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// CleanUpCancelledMarket removes everything that refers to a cancelled market: users' market and outcome
+// subscriptions, channel market subscriptions and reminders. It runs after the cancellation was delivered,
+// so the subscribers hear about it first.
+func (service *SubscriptionServiceImpl) CleanUpCancelledMarket(ctx context.Context, marketID, actor string) (*models.MarketCleanup, error) {
+	cleanup := &models.MarketCleanup{}
+	err := service.withTx(ctx, func(tx *SubscriptionServiceImpl) error {
+		*cleanup = models.MarketCleanup{}
+		return tx.cleanUpCancelledMarket(ctx, marketID, actor, cleanup)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cleanup.Users+cleanup.Channels+cleanup.Reminders > 0 {
+		service.logger.Info(fmt.Sprintf("Cleaned up cancelled market %s: %d users unsubscribed, %d channels unsubscribed, %d reminders deleted",
+			marketID, cleanup.Users, cleanup.Channels, cleanup.Reminders))
+	}
+	return cleanup, nil
+}
+
+// cleanUpCancelledMarket is CleanUpCancelledMarket within a transaction
+func (service *SubscriptionServiceImpl) cleanUpCancelledMarket(ctx context.Context, marketID, actor string, cleanup *models.MarketCleanup) error {
+	subscriptions, err := service.repo.GetAllSubscriptions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get subscriptions: %w", err)
+	}
+	for _, subscription := range subscriptions {
+		subscribed := false
+		for _, id := range subscription.SubscribedMarkets {
+			if id == marketID {
+				subscribed = true
+				break
+			}
+		}
+		if subscribed {
+			if err := service.UnsubscribeFromMarket(ctx, subscription.DiscordUserID, marketID); err != nil {
+				return fmt.Errorf("failed to unsubscribe user %s: %w", subscription.DiscordUserID, err)
+			}
+		}
+
+		outcomes := map[string]bool{}
+		for _, outcomeSub := range subscription.SubscribedOutcomes {
+			if outcomeSub.MarketID == marketID {
+				outcomes[outcomeSub.Outcome] = true
+			}
+		}
+		for outcome := range outcomes {
+			if err := service.UnsubscribeFromOutcome(ctx, subscription.DiscordUserID, marketID, outcome); err != nil {
+				return fmt.Errorf("failed to unsubscribe user %s from outcome %s: %w", subscription.DiscordUserID, outcome, err)
+			}
+		}
+		if subscribed || len(outcomes) > 0 {
+			cleanup.Users++
+		}
+	}
+
+	channels, err := service.repo.GetAllChannelConfigs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get channel configs: %w", err)
+	}
+	for _, config := range channels {
+		for _, id := range config.SubscribedMarkets {
+			if id != marketID {
+				continue
+			}
+			if err := service.UnsubscribeChannelFromMarket(ctx, config.ChannelID, marketID, actor); err != nil {
+				return fmt.Errorf("failed to unsubscribe channel %s: %w", config.ChannelID, err)
+			}
+			cleanup.Channels++
+			break
+		}
+	}
+
+	reminders, err := service.repo.DeleteRemindersByMarket(ctx, marketID)
+	if err != nil {
+		return fmt.Errorf("failed to delete reminders: %w", err)
+	}
+	cleanup.Reminders = reminders
+	return nil
+}
@@ -1,33 +1,76 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"coral-bot/discord_bot/internal/charts"
 	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/tracing"
 	"coral-bot/discord_bot/internal/utils"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 // MarketService defines the interface for market-related operations
 type MarketService interface {
-	FetchMarket(marketID string) (*models.Market, error)
-	FetchAllMarkets() ([]*models.Market, error)
+	FetchMarket(ctx context.Context, marketID string) (*models.Market, error)
+	FetchAllMarkets(ctx context.Context) ([]*models.Market, error)
+	SearchMarkets(ctx context.Context, query string, limit int) ([]*models.Market, error)
+	FetchMarketHistory(ctx context.Context, marketID string) ([]*models.MarketSnapshot, error)
+	FetchCategories(ctx context.Context) ([]string, error)
+	FetchCalendarEvents(ctx context.Context) ([]*models.CalendarEvent, error)
+	FetchPositions(ctx context.Context, accountID string) ([]*models.Position, error)
+	FetchMarketPositions(ctx context.Context, marketID string) ([]*models.Position, error)
 	CreateMarketAnnouncement(market *models.Market) string
-	CreateMarketUpdateMessage(market *models.Market) string
+	CreateMarketUpdateMessage(market *models.Market, previous *models.MarketSnapshot) string
+	CreateMarketLiquidityMessage(market *models.Market, change, liquidity float64, previous *models.MarketSnapshot) string
 	CreateTradingStartMessage(market *models.Market) string
 	CreateTradingEndMessage(market *models.Market) string
 	CreateMarketResolutionMessage(market *models.Market) string
+	CreateMarketCancelledMessage(market *models.Market, reason string) string
 	CreateMarketBuyMessage(marketID string, title string, amount float64, outcome string, buyer string, link string) string
+	CreateWhaleBuyMessage(marketID string, title string, amount float64, outcome string, buyer string, link string) string
+	CreateMarketClosingSoonMessage(market *models.Market) string
+	CreateMarketCommentMessage(market *models.Market, author, comment string) string
+	CreateCreatorJoinedMessage(creator *models.Creator) string
+	CreateCreatorMilestoneMessage(creator *models.Creator) string
+	CreateProbabilityChart(ctx context.Context, market *models.Market) (*ProbabilityChart, error)
+	CreateMarketHistoryMessage(market *models.Market, history []*models.MarketSnapshot, period string) string
+	CreatePositionsEmbed(positions []*models.Position) *discordgo.MessageEmbed
+	CreatePositionResolvedMessage(market *models.Market, positions []*models.Position) string
 	ShouldSendUpdate(market *models.Market, frequency string, lastUpdate time.Time) bool
+	ShouldSendChannelUpdate(market *models.Market, config *models.ChannelConfig, guild *models.GuildConfig) bool
+	UpdateIntervals(guild *models.GuildConfig) models.UpdateIntervals
 }
 
+// ErrBackendNotConfigured is returned by market lookups when no backend URL is configured
+var ErrBackendNotConfigured = errors.New("market backend URL not configured")
+
+// ErrBackendNotFound is returned by market lookups when the backend answers 404, such as for an unknown market ID
+var ErrBackendNotFound = errors.New("not found in the market backend")
+
+// BackendTimeout bounds each call to the backend API, on top of any deadline on the caller's context
+const BackendTimeout = 10 * time.Second
+
 // MarketServiceImpl implements MarketService
 type MarketServiceImpl struct {
-	baseURL string
-	logger  *utils.Logger
-	client  *http.Client
+	baseURL   string
+	logger    *utils.Logger
+	client    *http.Client
+	intervals models.UpdateIntervals // the bot's update intervals, zero fields use the defaults
+	clockAndIDs
+	linkAllowlist
 }
 
 // NewMarketService creates a new market service
@@ -35,104 +78,121 @@ func NewMarketService(baseURL string, logger *utils.Logger) *MarketServiceImpl {
 	return &MarketServiceImpl{
 		baseURL: baseURL,
 		logger:  logger,
-		client:  &http.Client{Timeout: 10 * time.Second},
+		client:  &http.Client{},
 	}
 }
 
 // FetchMarket fetches a market by ID from the backend API
-func (service *MarketServiceImpl) FetchMarket(marketID string) (*models.Market, error) {
-	if service.baseURL == "" {
-		service.logger.Warning("Backend URL not configured, returning mock market data")
-		// Return mock data for testing
-		return &models.Market{
-			ID:          marketID,
-			Title:       "Test Market",
-			Description: "This is a test market",
-			Outcomes:    []string{"Yes", "No"},
-			Percentages: []float64{50.0, 50.0},
-			Category:    "Test",
-			Creator:     "Test Creator",
-			Volume:      1000.0,
-			StartTime:   time.Now(),
-			EndTime:     time.Now().Add(24 * time.Hour),
-			Status:      "active",
-			Link:        "https://coral.markets/market/" + marketID,
-		}, nil
-	}
-
-	url := fmt.Sprintf("%s/markets/%s", service.baseURL, marketID)
-	resp, err := service.client.Get(url)
-	if err != nil {
+func (service *MarketServiceImpl) FetchMarket(ctx context.Context, marketID string) (*models.Market, error) {
+	var market models.Market
+	if err := service.getJSON(ctx, "backend.FetchMarket", "/markets/"+marketID, &market); err != nil {
 		return nil, fmt.Errorf("failed to fetch market: %w", err)
 	}
-	defer resp.Body.Close()
+	return &market, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("backend returned status %d", resp.StatusCode)
+// FetchAllMarkets fetches all markets from the backend API
+func (service *MarketServiceImpl) FetchAllMarkets(ctx context.Context) ([]*models.Market, error) {
+	var markets []*models.Market
+	if err := service.getJSON(ctx, "backend.FetchAllMarkets", "/markets", &markets); err != nil {
+		return nil, fmt.Errorf("failed to fetch markets: %w", err)
 	}
+	return markets, nil
+}
 
-	var market models.Market
-	if err := json.NewDecoder(resp.Body).Decode(&market); err != nil {
-		return nil, fmt.Errorf("failed to decode market response: %w", err)
+// SearchMarkets asks the backend API for up to limit markets whose ID starts with or whose title matches the query
+func (service *MarketServiceImpl) SearchMarkets(ctx context.Context, query string, limit int) ([]*models.Market, error) {
+	var markets []*models.Market
+	path := fmt.Sprintf("/markets/search?q=%s&limit=%d", url.QueryEscape(query), limit)
+	if err := service.getJSON(ctx, "backend.SearchMarkets", path, &markets); err != nil {
+		return nil, fmt.Errorf("failed to search markets: %w", err)
+	}
+	if len(markets) > limit {
+		markets = markets[:limit]
 	}
+	return markets, nil
+}
 
-	return &market, nil
+// FetchMarketHistory fetches a market's probability history from the backend API, oldest first
+func (service *MarketServiceImpl) FetchMarketHistory(ctx context.Context, marketID string) ([]*models.MarketSnapshot, error) {
+	var history []*models.MarketSnapshot
+	if err := service.getJSON(ctx, "backend.FetchMarketHistory", "/markets/"+marketID+"/history", &history); err != nil {
+		return nil, fmt.Errorf("failed to fetch market history: %w", err)
+	}
+	return history, nil
 }
 
-// FetchAllMarkets fetches all markets from the backend API
-func (service *MarketServiceImpl) FetchAllMarkets() ([]*models.Market, error) {
+// FetchCategories fetches the canonical market category names from the backend API
+func (service *MarketServiceImpl) FetchCategories(ctx context.Context) ([]string, error) {
+	var categories []string
+	if err := service.getJSON(ctx, "backend.FetchCategories", "/categories", &categories); err != nil {
+		return nil, fmt.Errorf("failed to fetch categories: %w", err)
+	}
+	return categories, nil
+}
+
+// FetchCalendarEvents fetches the upcoming calendar events, like elections and sports fixtures, and the
+// markets tagged to each from the backend API
+func (service *MarketServiceImpl) FetchCalendarEvents(ctx context.Context) ([]*models.CalendarEvent, error) {
+	var events []*models.CalendarEvent
+	if err := service.getJSON(ctx, "backend.FetchCalendarEvents", "/calendar/events", &events); err != nil {
+		return nil, fmt.Errorf("failed to fetch calendar events: %w", err)
+	}
+	return events, nil
+}
+
+// FetchPositions fetches the positions held by a linked Coral account from the backend API
+func (service *MarketServiceImpl) FetchPositions(ctx context.Context, accountID string) ([]*models.Position, error) {
+	var positions []*models.Position
+	if err := service.getJSON(ctx, "backend.FetchPositions", "/accounts/"+url.PathEscape(accountID)+"/positions", &positions); err != nil {
+		return nil, fmt.Errorf("failed to fetch positions: %w", err)
+	}
+	return positions, nil
+}
+
+// FetchMarketPositions fetches the positions every linked Coral account holds in a market from the
+// backend API, each with its account ID
+func (service *MarketServiceImpl) FetchMarketPositions(ctx context.Context, marketID string) ([]*models.Position, error) {
+	var positions []*models.Position
+	if err := service.getJSON(ctx, "backend.FetchMarketPositions", "/markets/"+url.PathEscape(marketID)+"/positions", &positions); err != nil {
+		return nil, fmt.Errorf("failed to fetch market positions: %w", err)
+	}
+	return positions, nil
+}
+
+// getJSON performs a traced GET against the backend API and decodes the JSON response into target
+func (service *MarketServiceImpl) getJSON(ctx context.Context, operation, path string, target interface{}) (err error) {
 	if service.baseURL == "" {
-		service.logger.Warning("Backend URL not configured, returning mock markets")
-		// Return mock data for testing
-		return []*models.Market{
-			{
-				ID:          "1",
-				Title:       "Test Market 1",
-				Description: "This is a test market",
-				Outcomes:    []string{"Yes", "No"},
-				Percentages: []float64{50.0, 50.0},
-				Category:    "Test",
-				Creator:     "Test Creator",
-				Volume:      1000.0,
-				StartTime:   time.Now(),
-				EndTime:     time.Now().Add(24 * time.Hour),
-				Status:      "active",
-				Link:        "https://coral.markets/market/1",
-			},
-			{
-				ID:          "2",
-				Title:       "Test Market 2",
-				Description: "This is another test market",
-				Outcomes:    []string{"Option A", "Option B", "Option C"},
-				Percentages: []float64{33.3, 33.3, 33.4},
-				Category:    "Test",
-				Creator:     "Another Creator",
-				Volume:      2500.0,
-				StartTime:   time.Now().Add(-1 * time.Hour),
-				EndTime:     time.Now().Add(12 * time.Hour),
-				Status:      "active",
-				Link:        "https://coral.markets/market/2",
-			},
-		}, nil
-	}
-
-	url := fmt.Sprintf("%s/markets", service.baseURL)
-	resp, err := service.client.Get(url)
+		return ErrBackendNotConfigured
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, BackendTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, service.baseURL+path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch markets: %w", err)
+		return err
+	}
+	req, span := tracing.StartClient(req, operation)
+	defer func() { tracing.End(span, err) }()
+
+	resp, err := service.client.Do(req)
+	if err != nil {
+		return err
 	}
 	defer resp.Body.Close()
 
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrBackendNotFound
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("backend returned status %d", resp.StatusCode)
+		return fmt.Errorf("backend returned status %d", resp.StatusCode)
 	}
-
-	var markets []*models.Market
-	if err := json.NewDecoder(resp.Body).Decode(&markets); err != nil {
-		return nil, fmt.Errorf("failed to decode markets response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
 	}
-
-	return markets, nil
+	return nil
 }
 
 // CreateMarketAnnouncement creates a formatted announcement message for a new market
@@ -142,12 +202,12 @@ func (service *MarketServiceImpl) CreateMarketAnnouncement(market *models.Market
 			"**%s**\n"+
 			"%s\n\n"+
 			"📊 Volume: $%.2f\n"+
-			"⏰ Time Left: %s\n\n"+
+			"⏰ Closes: %s\n\n"+
 			"**Outcomes:**\n",
-		market.Title,
-		market.Description,
+		EscapeMarkdown(market.Title),
+		service.description(market),
 		market.Volume,
-		time.Until(market.EndTime).String(),
+		absoluteTime(market.EndTime),
 	)
 
 	for i, outcome := range market.Outcomes {
@@ -155,36 +215,102 @@ func (service *MarketServiceImpl) CreateMarketAnnouncement(market *models.Market
 		if i < len(market.Percentages) {
 			percentage = market.Percentages[i]
 		}
-		message += fmt.Sprintf("- %s (%.1f%%)\n", outcome, percentage)
+		message += fmt.Sprintf("- %s (%.1f%%)\n", EscapeMarkdown(outcome), percentage)
 	}
 
-	message += fmt.Sprintf("\n🔗 [View on Coral Markets](%s)", market.Link)
-	return message
+	return message + service.viewLink("\n", market.Link)
 }
 
-// CreateMarketUpdateMessage creates a formatted update message for a market
-func (service *MarketServiceImpl) CreateMarketUpdateMessage(market *models.Market) string {
+// minOutcomeDelta is the smallest probability move, in percentage points, shown next to an outcome
+const minOutcomeDelta = 0.05
+
+// CreateMarketUpdateMessage creates a formatted update message for a market. When the previous snapshot
+// is known, each outcome shows how far it moved since then and the biggest mover is highlighted.
+func (service *MarketServiceImpl) CreateMarketUpdateMessage(market *models.Market, previous *models.MarketSnapshot) string {
 	message := fmt.Sprintf(
 		"📈 **MARKET UPDATE** 📈\n\n"+
 			"**%s**\n\n"+
 			"📊 Volume: $%.2f\n"+
-			"⏰ Time Left: %s\n\n"+
+			"⏰ Closes: %s\n\n"+
 			"**Current Probabilities:**\n",
-		market.Title,
+		EscapeMarkdown(market.Title),
 		market.Volume,
-		time.Until(market.EndTime).String(),
+		absoluteTime(market.EndTime),
 	)
+	message += outcomeLines(market, previous)
+	return message + service.viewLink("\n", market.Link)
+}
 
+// CreateMarketLiquidityMessage creates a message for liquidity added to or, when change is negative, removed
+// from a market, with the odds it left the market at and how far they shifted since the previous snapshot.
+// A liquidity of 0 leaves the pool total out.
+func (service *MarketServiceImpl) CreateMarketLiquidityMessage(market *models.Market, change, liquidity float64, previous *models.MarketSnapshot) string {
+	header, verb := "💧 **LIQUIDITY ADDED** 💧", "Added"
+	if change < 0 {
+		header, verb = "🔻 **LIQUIDITY REMOVED** 🔻", "Removed"
+	}
+	message := fmt.Sprintf("%s\n\n**%s**\n\n%s: $%.2f\n", header, EscapeMarkdown(market.Title), verb, math.Abs(change))
+	if liquidity > 0 {
+		message += fmt.Sprintf("💰 Pool liquidity: $%.2f\n", liquidity)
+	}
+	if len(market.Outcomes) > 0 {
+		message += "\n**Odds Now:**\n" + outcomeLines(market, previous)
+	}
+	return message + service.viewLink("\n", market.Link)
+}
+
+// outcomeLines lists the market's outcomes with their probabilities, one per line, marking each move since
+// the previous snapshot and highlighting the biggest mover
+func outcomeLines(market *models.Market, previous *models.MarketSnapshot) string {
+	var lines strings.Builder
+	deltas, biggest := outcomeDeltas(market, previous)
 	for i, outcome := range market.Outcomes {
 		percentage := 0.0
 		if i < len(market.Percentages) {
 			percentage = market.Percentages[i]
 		}
-		message += fmt.Sprintf("- %s (%.1f%%)\n", outcome, percentage)
+		line := fmt.Sprintf("%s (%.1f%%)", EscapeMarkdown(outcome), percentage)
+		if delta, ok := deltas[i]; ok {
+			if delta > 0 {
+				line += fmt.Sprintf(" ▲ %+.1f%%", delta)
+			} else {
+				line += fmt.Sprintf(" ▼ %+.1f%%", delta)
+			}
+		}
+		if i == biggest {
+			line = "**" + line + "** 🔥"
+		}
+		lines.WriteString("- " + line + "\n")
 	}
+	return lines.String()
+}
 
-	message += fmt.Sprintf("\n🔗 [View on Coral Markets](%s)", market.Link)
-	return message
+// outcomeDeltas returns the move, in percentage points, of each outcome that moved since the previous
+// snapshot, keyed by outcome index, and the index of the biggest mover, or -1 when nothing moved
+func outcomeDeltas(market *models.Market, previous *models.MarketSnapshot) (map[int]float64, int) {
+	deltas := make(map[int]float64)
+	biggest := -1
+	if previous == nil {
+		return deltas, biggest
+	}
+	for i, outcome := range market.Outcomes {
+		if i >= len(market.Percentages) {
+			break
+		}
+		before, ok := previous.Percentage(outcome)
+		if !ok {
+			continue
+		}
+		delta := market.Percentages[i] - before
+		if math.Abs(delta) < minOutcomeDelta {
+			continue
+		}
+		deltas[i] = delta
+		if biggest < 0 || math.Abs(delta) > math.Abs(deltas[biggest]) {
+			biggest = i
+		}
+	}
+	return deltas, biggest
 }
 
 // CreateTradingStartMessage creates a message for when trading starts
@@ -192,11 +318,9 @@ func (service *MarketServiceImpl) CreateTradingStartMessage(market *models.Marke
 	return fmt.Sprintf(
 		"🟢 **TRADING STARTED** 🟢\n\n"+
 			"**%s**\n\n"+
-			"Trading is now open! Place your bets.\n\n"+
-			"🔗 [View on Coral Markets](%s)",
-		market.Title,
-		market.Link,
-	)
+			"Trading is now open! Place your bets.",
+		EscapeMarkdown(market.Title),
+	) + service.viewLink("\n\n", market.Link)
 }
 
 // CreateTradingEndMessage creates a message for when trading ends
@@ -204,29 +328,84 @@ func (service *MarketServiceImpl) CreateTradingEndMessage(market *models.Market)
 	return fmt.Sprintf(
 		"🔴 **TRADING CLOSED** 🔴\n\n"+
 			"**%s**\n\n"+
-			"Betting is now closed. Market will resolve soon.\n\n"+
-			"🔗 [View on Coral Markets](%s)",
-		market.Title,
-		market.Link,
-	)
+			"Betting is now closed. Market will resolve soon.",
+		EscapeMarkdown(market.Title),
+	) + service.viewLink("\n\n", market.Link)
 }
 
-// CreateMarketResolutionMessage creates a message for when a market is resolved
+// CreateMarketResolutionMessage creates a message for when a market is resolved. Split resolutions name
+// every outcome that was paid out, and markets with a payout breakdown list each outcome's final odds and
+// share of the pool.
 func (service *MarketServiceImpl) CreateMarketResolutionMessage(market *models.Market) string {
 	resolution := "Market resolved"
-	if market.ResolvedOutcome != "" {
-		resolution = fmt.Sprintf("Resolved: **%s**", market.ResolvedOutcome)
+	winners := market.Winners()
+	for i, winner := range winners {
+		winners[i] = EscapeMarkdown(winner)
+	}
+	switch len(winners) {
+	case 0:
+	case 1:
+		resolution = fmt.Sprintf("Resolved: **%s**", winners[0])
+	default:
+		resolution = fmt.Sprintf("Split between **%s** and **%s**", strings.Join(winners[:len(winners)-1], "**, **"), winners[len(winners)-1])
+	}
+	if !market.ResolvedAt.IsZero() {
+		resolution += "\n🕒 " + absoluteTime(market.ResolvedAt)
+	}
+	if len(market.Payouts) > 0 {
+		resolution += "\n\n" + payoutTable(market)
 	}
 
 	return fmt.Sprintf(
 		"✅ **MARKET RESOLVED** ✅\n\n"+
 			"**%s**\n\n"+
-			"%s\n\n"+
-			"🔗 [View on Coral Markets](%s)",
-		market.Title,
+			"%s",
+		EscapeMarkdown(market.Title),
 		resolution,
-		market.Link,
-	)
+	) + service.viewLink("\n\n", market.Link)
+}
+
+// payoutTable lays out a resolved market's payout breakdown in a code block, with the amount each
+// outcome's holders receive when the market's total pool is known
+func payoutTable(market *models.Market) string {
+	var table strings.Builder
+	table.WriteString("```\n")
+	if market.Volume > 0 {
+		table.WriteString(fmt.Sprintf("%-16s %7s %11s %12s\n", "Outcome", "Final", "Pool share", "Payout"))
+	} else {
+		table.WriteString(fmt.Sprintf("%-16s %7s %11s\n", "Outcome", "Final", "Pool share"))
+	}
+	for _, payout := range market.Payouts {
+		row := fmt.Sprintf("%-16s %6.1f%% %10.1f%%", truncate(EscapeCodeBlock(payout.Outcome), 16), payout.FinalPct, payout.PoolShare)
+		if market.Volume > 0 {
+			row += fmt.Sprintf(" %12s", fmt.Sprintf("$%.2f", market.Volume*payout.PoolShare/100))
+		}
+		table.WriteString(row + "\n")
+	}
+	table.WriteString("```")
+	return table.String()
+}
+
+// CreateMarketCancelledMessage creates a message for a market that was voided instead of resolved,
+// explaining that positions are refunded
+func (service *MarketServiceImpl) CreateMarketCancelledMessage(market *models.Market, reason string) string {
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("🚫 **MARKET CANCELLED** 🚫\n\n**%s**\n\nThis market was voided and will not resolve.", EscapeMarkdown(market.Title)))
+	if reason != "" {
+		message.WriteString(fmt.Sprintf("\nReason: %s", EscapeMarkdown(reason)))
+	}
+	if !market.ResolvedAt.IsZero() {
+		message.WriteString("\n🕒 " + absoluteTime(market.ResolvedAt))
+	}
+
+	message.WriteString("\n\n💸 Every position is refunded at cost, whichever outcome it was on.")
+	if market.Volume > 0 {
+		message.WriteString(fmt.Sprintf(" $%.2f is being returned to traders.", market.Volume))
+	}
+	message.WriteString("\nSubscriptions and reminders for this market have been removed.")
+
+	message.WriteString(service.viewLink("\n\n", market.Link))
+	return message.String()
 }
 
 func (s *MarketServiceImpl) CreateMarketBuyMessage(marketID string, title string, amount float64, outcome string, buyer string, link string) string {
@@ -239,42 +418,257 @@ func (s *MarketServiceImpl) CreateMarketBuyMessage(marketID string, title string
 			"**%s**\n\n"+
 			"Buyer: %s\n"+
 			"Amount: $%.2f\n"+
-			"Outcome: %s\n\n"+
-			"🔗 [View on Coral Markets](%s)",
-		title,
-		buyerText,
+			"Outcome: %s",
+		EscapeMarkdown(title),
+		EscapeMarkdown(buyerText),
 		amount,
-		outcome,
-		link,
-	)
+		EscapeMarkdown(outcome),
+	) + s.viewLink("\n\n", link)
+}
+
+// CreateWhaleBuyMessage creates a message for a buy large enough to be called out as a whale
+func (s *MarketServiceImpl) CreateWhaleBuyMessage(marketID string, title string, amount float64, outcome string, buyer string, link string) string {
+	buyerText := buyer
+	if buyerText == "" {
+		buyerText = "Anonymous"
+	}
+	return fmt.Sprintf(
+		"🐋 **WHALE BUY** 🐋\n\n"+
+			"**%s**\n\n"+
+			"A whale just placed **$%.2f** on **%s**!\n\n"+
+			"Buyer: %s",
+		EscapeMarkdown(title),
+		amount,
+		EscapeMarkdown(outcome),
+		EscapeMarkdown(buyerText),
+	) + s.viewLink("\n\n", link)
+}
+
+// CreateMarketClosingSoonMessage creates a reminder message for a market that is about to close
+func (service *MarketServiceImpl) CreateMarketClosingSoonMessage(market *models.Market) string {
+	return fmt.Sprintf(
+		"⏳ **CLOSING SOON** ⏳\n\n"+
+			"**%s**\n\n"+
+			"⏰ Closes: %s\n\n"+
+			"Last chance to place your bets!",
+		EscapeMarkdown(market.Title),
+		absoluteTime(market.EndTime),
+	) + service.viewLink("\n\n", market.Link)
+}
+
+// maxCommentLength is the most of a comment quoted in a market comment message
+const maxCommentLength = 500
+
+// CreateMarketCommentMessage creates a message for a comment posted on a market, quoting at most
+// maxCommentLength characters of it
+func (service *MarketServiceImpl) CreateMarketCommentMessage(market *models.Market, author, comment string) string {
+	if author == "" {
+		author = "Anonymous"
+	}
+	quoted := "> " + strings.ReplaceAll(EscapeMarkdownBlock(truncate(strings.TrimSpace(comment), maxCommentLength)), "\n", "\n> ")
+	return fmt.Sprintf(
+		"💬 **NEW COMMENT** 💬\n\n"+
+			"**%s**\n\n"+
+			"%s wrote:\n%s",
+		EscapeMarkdown(market.Title),
+		EscapeMarkdown(author),
+		quoted,
+	) + service.viewLink("\n\n", market.Link)
+}
+
+// CreateCreatorJoinedMessage creates a message for a creator joining Coral Markets
+func (service *MarketServiceImpl) CreateCreatorJoinedMessage(creator *models.Creator) string {
+	message := fmt.Sprintf("👋 **NEW CREATOR** 👋\n\n**%s** joined Coral Markets.", EscapeMarkdown(creator.Label()))
+	if creator.Bio != "" {
+		message += "\n\n" + EscapeMarkdownBlock(creator.Bio)
+	}
+	return message + service.viewLink("\n\n", creator.Link)
+}
+
+// CreateCreatorMilestoneMessage creates a message for a creator's markets reaching a total volume milestone
+func (service *MarketServiceImpl) CreateCreatorMilestoneMessage(creator *models.Creator) string {
+	return fmt.Sprintf(
+		"🏆 **CREATOR MILESTONE** 🏆\n\n"+
+			"**%s** passed $%.2f in total market volume.",
+		EscapeMarkdown(creator.Label()),
+		creator.Volume,
+	) + service.viewLink("\n\n", creator.Link)
+}
+
+// ProbabilityChart is a rendered probability history chart and its text legend
+type ProbabilityChart struct {
+	Image  []byte // PNG encoded
+	Legend string
+}
+
+// CreateProbabilityChart renders a PNG chart of the market's probability history,
+// ending with the market's current probabilities
+func (service *MarketServiceImpl) CreateProbabilityChart(ctx context.Context, market *models.Market) (*ProbabilityChart, error) {
+	history, err := service.FetchMarketHistory(ctx, market.ID)
+	if err != nil {
+		return nil, err
+	}
+	return renderProbabilityChart(ctx, market, history)
+}
+
+// renderProbabilityChart renders a market's history followed by its current probabilities
+func renderProbabilityChart(ctx context.Context, market *models.Market, history []*models.MarketSnapshot) (chart *ProbabilityChart, err error) {
+	_, span := tracing.Start(ctx, "render.ProbabilityChart", attribute.String("market.id", market.ID), attribute.Int("market.history_points", len(history)))
+	defer func() { tracing.End(span, err) }()
+
+	if len(market.Percentages) > 0 {
+		history = append(history, &models.MarketSnapshot{
+			MarketID:    market.ID,
+			Outcomes:    market.Outcomes,
+			Percentages: market.Percentages,
+			Volume:      market.Volume,
+			Timestamp:   time.Now(),
+		})
+	}
+
+	image, err := charts.RenderProbabilityChart(history, charts.DefaultWidth, charts.DefaultHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProbabilityChart{
+		Image:  image,
+		Legend: charts.Legend(escapeAll(history[len(history)-1].Outcomes)),
+	}, nil
+}
+
+// historySparklineWidth is the number of characters used for each outcome's sparkline
+const historySparklineWidth = 24
+
+// CreateMarketHistoryMessage creates a summary of how a market's probabilities moved over a period
+func (service *MarketServiceImpl) CreateMarketHistoryMessage(market *models.Market, history []*models.MarketSnapshot, period string) string {
+	title := EscapeMarkdown(market.Title)
+	if title == "" {
+		title = EscapeMarkdown(market.ID)
+	}
+
+	if len(history) == 0 {
+		return fmt.Sprintf("📜 **PROBABILITY HISTORY** 📜\n\n**%s**\n\nNo probability updates recorded in the last %s.", title, period)
+	}
+
+	first := history[0]
+	last := history[len(history)-1]
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf(
+		"📜 **PROBABILITY HISTORY** 📜\n\n"+
+			"**%s**\n"+
+			"Last %s • %d updates since %s\n\n",
+		title,
+		period,
+		len(history),
+		DiscordTimestamp(first.Timestamp, TimestampShortDateTime),
+	))
+
+	message.WriteString("```\n")
+	message.WriteString(fmt.Sprintf("%-16s %7s %7s %8s  %s\n", "Outcome", "Start", "Now", "Change", "Trend"))
+	for _, outcome := range last.Outcomes {
+		percentages := make([]float64, 0, len(history))
+		for _, snapshot := range history {
+			if percentage, ok := snapshot.Percentage(outcome); ok {
+				percentages = append(percentages, percentage)
+			}
+		}
+		if len(percentages) == 0 {
+			continue
+		}
+
+		start := percentages[0]
+		now := percentages[len(percentages)-1]
+		message.WriteString(fmt.Sprintf("%-16s %6.1f%% %6.1f%% %+7.1f%%  %s\n",
+			truncate(EscapeCodeBlock(outcome), 16),
+			start,
+			now,
+			now-start,
+			charts.Sparkline(percentages, historySparklineWidth),
+		))
+	}
+	message.WriteString("```")
+	message.WriteString(service.viewLink("\n", market.Link))
+	return message.String()
+}
+
+// maxDescriptionLength keeps a market's description from crowding out the rest of its announcement
+const maxDescriptionLength = 600
+
+// description returns a market's description escaped for its announcement, shortened when long and
+// ending with a link to the full one
+func (service *MarketServiceImpl) description(market *models.Market) string {
+	if utf8.RuneCountInString(market.Description) <= maxDescriptionLength {
+		return EscapeMarkdownBlock(market.Description)
+	}
+	description := EscapeMarkdownBlock(truncate(market.Description, maxDescriptionLength))
+	if link := service.safeLink(market.Link); link != "" {
+		description += fmt.Sprintf(" [Read more](%s)", link)
+	}
+	return description
+}
+
+// escapeAll escapes each of a list of single lines of backend text
+func escapeAll(values []string) []string {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = EscapeMarkdown(value)
+	}
+	return escaped
+}
+
+// truncate shortens a string to at most max runes
+func truncate(value string, max int) string {
+	runes := []rune(value)
+	if len(runes) <= max {
+		return value
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+// SetUpdateIntervals sets how often market updates are sent at each frequency, zero fields keeping the
+// defaults
+func (service *MarketServiceImpl) SetUpdateIntervals(intervals models.UpdateIntervals) {
+	service.intervals = intervals
+}
+
+// UpdateIntervals returns the update intervals of a guild: its own, then the bot's, then the defaults.
+// guild may be nil.
+func (service *MarketServiceImpl) UpdateIntervals(guild *models.GuildConfig) models.UpdateIntervals {
+	intervals := service.intervals.Over(models.DefaultUpdateIntervals)
+	if guild != nil && guild.UpdateIntervals != nil {
+		intervals = guild.UpdateIntervals.Over(intervals)
+	}
+	return intervals
 }
 
 // ShouldSendUpdate determines if an update should be sent based on frequency settings
 func (service *MarketServiceImpl) ShouldSendUpdate(market *models.Market, frequency string, lastUpdate time.Time) bool {
+	intervals := service.UpdateIntervals(nil)
+	return service.updateDue(market, intervals, intervals.Interval(frequency, 0), lastUpdate)
+}
+
+// ShouldSendChannelUpdate determines if a channel is due an update of a market, following its frequency
+// or custom interval and its guild's intervals. guild may be nil.
+func (service *MarketServiceImpl) ShouldSendChannelUpdate(market *models.Market, config *models.ChannelConfig, guild *models.GuildConfig) bool {
+	intervals := service.UpdateIntervals(guild)
+	return service.updateDue(market, intervals, intervals.Interval(config.FrequencyMode, config.CustomInterval), config.LastUpdateTimestamp)
+}
+
+// updateDue reports whether interval has passed since the last update of an active market, or the
+// shorter closing-soon interval for markets about to close
+func (service *MarketServiceImpl) updateDue(market *models.Market, intervals models.UpdateIntervals, interval time.Duration, lastUpdate time.Time) bool {
 	if market.Status != "active" {
 		return false
 	}
 
-	timeSinceLastUpdate := time.Since(lastUpdate)
-	timeLeft := time.Until(market.EndTime)
+	timeSinceLastUpdate := service.now().Sub(lastUpdate)
+	timeLeft := market.EndTime.Sub(service.now())
 
-	// For markets closing soon (less than 6 hours), increase update frequency
-	if timeLeft < 6*time.Hour {
-		return timeSinceLastUpdate >= 15*time.Minute
-	}
-
-	switch frequency {
-	case "high":
-		// Send updates every 30 minutes for active markets
-		return timeSinceLastUpdate >= 30*time.Minute
-	case "medium":
-		// Send updates every hour for active markets
-		return timeSinceLastUpdate >= 1*time.Hour
-	case "low":
-		// Send updates every 3 hours for active markets
-		return timeSinceLastUpdate >= 3*time.Hour
-	default:
-		// Medium frequency for unknown values
-		return timeSinceLastUpdate >= 1*time.Hour
+	// For markets closing soon, increase update frequency
+	if timeLeft < intervals.ClosingSoonWindow && intervals.ClosingSoonInterval < interval {
+		interval = intervals.ClosingSoonInterval
 	}
+	return timeSinceLastUpdate >= interval
 }
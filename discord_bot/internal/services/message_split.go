@@ -0,0 +1,54 @@
+package services
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Discord's length limits, in characters
+const (
+	MaxMessageLength          = 2000 // content of a message
+	MaxEmbedDescriptionLength = 4096 // description of an embed
+)
+
+// codeFence opens and closes a Markdown code block
+const codeFence = "```"
+
+// SplitMessage splits content into parts of at most limit characters, breaking between paragraphs,
+// then between lines, then between words where it can. A code block cut in two is closed at the end of
+// one part and reopened in the next, so every part renders on its own. Content within the limit is
+// returned as its only part.
+func SplitMessage(content string, limit int) []string {
+	var parts []string
+	for utf8.RuneCountInString(content) > limit {
+		// Keep room to close a code block
+		part, rest := cutMessage(content, limit-len("\n"+codeFence))
+		if strings.Count(part, codeFence)%2 == 1 {
+			part += "\n" + codeFence
+			rest = codeFence + "\n" + rest
+		}
+		parts = append(parts, part)
+		content = rest
+	}
+	return append(parts, content)
+}
+
+// cutMessage cuts content at the last paragraph, line or word break within limit characters found in
+// its second half, or at the limit when there is none
+func cutMessage(content string, limit int) (part, rest string) {
+	end := len(content)
+	if limit < utf8.RuneCountInString(content) {
+		end = 0
+		for i := 0; i < limit; i++ {
+			_, size := utf8.DecodeRuneInString(content[end:])
+			end += size
+		}
+	}
+	window := content[:end]
+	for _, separator := range []string{"\n\n", "\n", " "} {
+		if i := strings.LastIndex(window, separator); i > len(window)/2 {
+			return strings.TrimRight(content[:i], " "), strings.TrimLeft(content[i+len(separator):], "\n")
+		}
+	}
+	return window, content[end:]
+}
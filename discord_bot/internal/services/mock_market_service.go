@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"coral-bot/discord_bot/internal/fixtures"
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// MockMarketService is a MarketService that serves fixture markets instead of calling the backend.
+// Message formatting is shared with MarketServiceImpl so tests exercise the real templates.
+type MockMarketService struct {
+	*MarketServiceImpl
+	markets       map[string]*models.Market
+	histories     map[string][]*models.MarketSnapshot
+	categories    []string
+	events        []*models.CalendarEvent
+	positions     map[string][]*models.Position
+	holders       map[string][]*models.Position
+	strict        bool // unknown market IDs are not found instead of served as fixtures
+	err           error
+	referenceTime time.Time
+	mutex         sync.RWMutex
+}
+
+// NewMockMarketService creates a mock market service that returns fixtures for unknown market IDs
+func NewMockMarketService(logger *utils.Logger) *MockMarketService {
+	return &MockMarketService{
+		MarketServiceImpl: NewMarketService("", logger),
+		markets:           make(map[string]*models.Market),
+		histories:         make(map[string][]*models.MarketSnapshot),
+		positions:         make(map[string][]*models.Position),
+		holders:           make(map[string][]*models.Position),
+	}
+}
+
+// SetMarket makes FetchMarket and FetchAllMarkets return a market instead of a fixture
+func (service *MockMarketService) SetMarket(market *models.Market) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.markets[market.ID] = market
+}
+
+// SetMarketHistory makes FetchMarketHistory return the given snapshots for a market
+func (service *MockMarketService) SetMarketHistory(marketID string, history []*models.MarketSnapshot) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.histories[marketID] = history
+}
+
+// SetStrict makes FetchMarket fail with ErrBackendNotFound for markets not set with SetMarket, like the
+// backend does for unknown IDs
+func (service *MockMarketService) SetStrict(strict bool) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.strict = strict
+}
+
+// SetCategories makes FetchCategories return the given categories instead of the fixture categories
+func (service *MockMarketService) SetCategories(categories []string) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.categories = categories
+}
+
+// SetCalendarEvents makes FetchCalendarEvents return the given events instead of the fixture events
+func (service *MockMarketService) SetCalendarEvents(events []*models.CalendarEvent) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.events = events
+}
+
+// SetPositions makes FetchPositions return the given positions for an account instead of the fixture
+// positions
+func (service *MockMarketService) SetPositions(accountID string, positions []*models.Position) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.positions[accountID] = positions
+}
+
+// SetMarketPositions makes FetchMarketPositions return the given positions for a market
+func (service *MockMarketService) SetMarketPositions(marketID string, positions []*models.Position) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.holders[marketID] = positions
+}
+
+// SetError makes every fetch fail with err until it is reset with nil
+func (service *MockMarketService) SetError(err error) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.err = err
+}
+
+// SetReferenceTime pins fixture timestamps to t; the zero time uses the current time
+func (service *MockMarketService) SetReferenceTime(t time.Time) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.referenceTime = t
+}
+
+// now returns the time fixtures are generated relative to
+func (service *MockMarketService) now() time.Time {
+	if service.referenceTime.IsZero() {
+		return time.Now()
+	}
+	return service.referenceTime
+}
+
+// FetchMarket returns the market set with SetMarket, or a fixture market
+func (service *MockMarketService) FetchMarket(ctx context.Context, marketID string) (*models.Market, error) {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+
+	if service.err != nil {
+		return nil, service.err
+	}
+	if market, ok := service.markets[marketID]; ok {
+		return market, nil
+	}
+	if service.strict {
+		return nil, fmt.Errorf("failed to fetch market: %w", ErrBackendNotFound)
+	}
+	return fixtures.Market(marketID, service.now()), nil
+}
+
+// SearchMarkets returns up to limit of the markets FetchAllMarkets returns whose ID starts with the
+// query or whose title contains it, ignoring case, in ID order
+func (service *MockMarketService) SearchMarkets(ctx context.Context, query string, limit int) ([]*models.Market, error) {
+	markets, err := service.FetchAllMarkets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(markets, func(i, j int) bool { return markets[i].ID < markets[j].ID })
+	query = strings.ToLower(strings.TrimSpace(query))
+	var matches []*models.Market
+	for _, market := range markets {
+		if len(matches) == limit {
+			break
+		}
+		if strings.HasPrefix(strings.ToLower(market.ID), query) || strings.Contains(strings.ToLower(market.Title), query) {
+			matches = append(matches, market)
+		}
+	}
+	return matches, nil
+}
+
+// FetchAllMarkets returns the markets set with SetMarket, or the fixture markets when none are set
+func (service *MockMarketService) FetchAllMarkets(ctx context.Context) ([]*models.Market, error) {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+
+	if service.err != nil {
+		return nil, service.err
+	}
+	if len(service.markets) == 0 {
+		return fixtures.Markets(service.now()), nil
+	}
+	markets := make([]*models.Market, 0, len(service.markets))
+	for _, market := range service.markets {
+		markets = append(markets, market)
+	}
+	return markets, nil
+}
+
+// FetchMarketHistory returns the history set with SetMarketHistory, or a fixture history
+func (service *MockMarketService) FetchMarketHistory(ctx context.Context, marketID string) ([]*models.MarketSnapshot, error) {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+
+	if service.err != nil {
+		return nil, service.err
+	}
+	if history, ok := service.histories[marketID]; ok {
+		return history, nil
+	}
+	return fixtures.MarketHistory(marketID, service.now()), nil
+}
+
+// FetchCategories returns the categories set with SetCategories, or the fixture categories
+func (service *MockMarketService) FetchCategories(ctx context.Context) ([]string, error) {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+
+	if service.err != nil {
+		return nil, service.err
+	}
+	if service.categories != nil {
+		return service.categories, nil
+	}
+	return fixtures.Categories(), nil
+}
+
+// FetchCalendarEvents returns the events set with SetCalendarEvents, or the fixture events
+func (service *MockMarketService) FetchCalendarEvents(ctx context.Context) ([]*models.CalendarEvent, error) {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+
+	if service.err != nil {
+		return nil, service.err
+	}
+	if service.events != nil {
+		return service.events, nil
+	}
+	return fixtures.CalendarEvents(service.now()), nil
+}
+
+// FetchPositions returns the positions set with SetPositions for an account, or the fixture positions
+func (service *MockMarketService) FetchPositions(ctx context.Context, accountID string) ([]*models.Position, error) {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+
+	if service.err != nil {
+		return nil, service.err
+	}
+	if positions, ok := service.positions[accountID]; ok {
+		return positions, nil
+	}
+	return fixtures.Positions(), nil
+}
+
+// FetchMarketPositions returns the positions set with SetMarketPositions for a market, or none
+func (service *MockMarketService) FetchMarketPositions(ctx context.Context, marketID string) ([]*models.Position, error) {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+
+	if service.err != nil {
+		return nil, service.err
+	}
+	return service.holders[marketID], nil
+}
+
+// CreateProbabilityChart renders a chart from the mock history rather than the backend's
+func (service *MockMarketService) CreateProbabilityChart(ctx context.Context, market *models.Market) (*ProbabilityChart, error) {
+	history, err := service.FetchMarketHistory(ctx, market.ID)
+	if err != nil {
+		return nil, err
+	}
+	return renderProbabilityChart(ctx, market, history)
+}
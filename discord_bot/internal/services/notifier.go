@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"coral-bot/discord_bot/internal/tracing"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Notifier defines the interface for delivering messages to Discord channels and users
+type Notifier interface {
+	SendChannelMessage(ctx context.Context, channelID string, message string) error
+	SendDirectMessage(ctx context.Context, discordUserID string, message string) error
+}
+
+// DiscordNotifier implements Notifier using a Discord session
+type DiscordNotifier struct {
+	session *discordgo.Session
+	gateway *GatewayMonitor // buffers messages while the gateway is down, nil sends immediately
+	links   *LinkDecorator  // nil posts links as they were rendered
+}
+
+// NewDiscordNotifier creates a new Discord notifier; gateway may be nil
+func NewDiscordNotifier(session *discordgo.Session, gateway *GatewayMonitor) *DiscordNotifier {
+	return &DiscordNotifier{session: session, gateway: gateway}
+}
+
+// SetLinkDecorator sets the decorator adding click tracking to the links of sent messages
+func (n *DiscordNotifier) SetLinkDecorator(decorator *LinkDecorator) {
+	n.links = decorator
+}
+
+// deliver sends through the gateway monitor when one is set
+func (n *DiscordNotifier) deliver(ctx context.Context, send func(context.Context) error) error {
+	if n.gateway == nil {
+		return send(ctx)
+	}
+	return n.gateway.Deliver(ctx, send)
+}
+
+// SendChannelMessage posts a message to a channel, or buffers it while the gateway is disconnected
+func (n *DiscordNotifier) SendChannelMessage(ctx context.Context, channelID string, message string) error {
+	if n.session == nil {
+		return fmt.Errorf("discord session not set")
+	}
+	return n.deliver(ctx, func(ctx context.Context) error {
+		return n.sendChannelMessage(ctx, channelID, message)
+	})
+}
+
+// sendChannelMessage posts a message to a channel
+func (n *DiscordNotifier) sendChannelMessage(ctx context.Context, channelID string, message string) (err error) {
+	ctx, span := tracing.Start(ctx, "discord.ChannelMessageSend", attribute.String("discord.channel_id", channelID))
+	defer func() { tracing.End(span, err) }()
+
+	return n.send(ctx, channelID, message)
+}
+
+// SendDirectMessage sends a DM to a user, or buffers it while the gateway is disconnected
+func (n *DiscordNotifier) SendDirectMessage(ctx context.Context, discordUserID string, message string) error {
+	if n.session == nil {
+		return fmt.Errorf("discord session not set")
+	}
+	return n.deliver(ctx, func(ctx context.Context) error {
+		return n.sendDirectMessage(ctx, discordUserID, message)
+	})
+}
+
+// sendDirectMessage sends a DM to a user
+func (n *DiscordNotifier) sendDirectMessage(ctx context.Context, discordUserID string, message string) (err error) {
+	ctx, span := tracing.Start(ctx, "discord.DirectMessage", attribute.String("discord.user_id", discordUserID))
+	defer func() { tracing.End(span, err) }()
+
+	channel, err := n.session.UserChannelCreate(discordUserID, discordgo.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to create DM channel: %w", err)
+	}
+	return n.send(ctx, channel.ID, message)
+}
+
+// send posts a message with its links decorated for the channel, split over several when it is
+// longer than Discord allows
+func (n *DiscordNotifier) send(ctx context.Context, channelID string, message string) error {
+	for _, part := range SplitMessage(n.links.Decorate(ctx, message, channelID), MaxMessageLength) {
+		if _, err := n.session.ChannelMessageSend(channelID, part, discordgo.WithContext(ctx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
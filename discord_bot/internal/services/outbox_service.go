@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// OutboxMaxAttempts is how many deliveries of an item are started before it is given up, so an event
+// that crashes the bot or never finishes its fan-out is not replayed forever
+const OutboxMaxAttempts = 5
+
+// ErrOutboxAttemptsExhausted is returned when an item was already attempted OutboxMaxAttempts times
+var ErrOutboxAttemptsExhausted = errors.New("outbox item delivery attempts exhausted")
+
+// OutboxService defines the interface for the notification outbox. Notifications are stored before
+// they are delivered and marked delivered afterwards, so undelivered ones can be resumed after a crash.
+type OutboxService interface {
+	Enqueue(ctx context.Context, item *models.OutboxItem) error
+	StartAttempt(ctx context.Context, item *models.OutboxItem) error
+	Complete(ctx context.Context, item *models.OutboxItem) error
+	Pending(ctx context.Context, before time.Time) ([]*models.OutboxItem, error)
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// OutboxServiceImpl implements OutboxService
+type OutboxServiceImpl struct {
+	repo   repository.SubscriptionRepository
+	logger *utils.Logger
+	clockAndIDs
+}
+
+// NewOutboxService creates a new outbox service
+func NewOutboxService(repo repository.SubscriptionRepository, logger *utils.Logger) *OutboxServiceImpl {
+	return &OutboxServiceImpl{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Enqueue assigns the item an ID and stores it as pending
+func (service *OutboxServiceImpl) Enqueue(ctx context.Context, item *models.OutboxItem) error {
+	id, err := service.newID()
+	if err != nil {
+		return fmt.Errorf("failed to generate outbox item id: %w", err)
+	}
+	item.ID = id
+	item.Status = models.OutboxPending
+	item.CreatedAt = service.now()
+	if err := service.repo.SaveOutboxItem(ctx, item); err != nil {
+		return fmt.Errorf("failed to save outbox item: %w", err)
+	}
+	return nil
+}
+
+// StartAttempt records that a delivery of the item is starting. An item already attempted
+// OutboxMaxAttempts times is marked failed instead and ErrOutboxAttemptsExhausted is returned.
+func (service *OutboxServiceImpl) StartAttempt(ctx context.Context, item *models.OutboxItem) error {
+	if item.Attempts >= OutboxMaxAttempts {
+		item.Status = models.OutboxFailed
+		if err := service.repo.SaveOutboxItem(ctx, item); err != nil {
+			service.logger.Error(fmt.Sprintf("Failed to mark outbox item %s failed: %v", item.ID, err))
+		}
+		return fmt.Errorf("%w after %d attempts", ErrOutboxAttemptsExhausted, item.Attempts)
+	}
+	item.Attempts++
+	return service.repo.SaveOutboxItem(ctx, item)
+}
+
+// Complete marks the item delivered
+func (service *OutboxServiceImpl) Complete(ctx context.Context, item *models.OutboxItem) error {
+	item.Status = models.OutboxDelivered
+	item.DeliveredAt = service.now()
+	return service.repo.SaveOutboxItem(ctx, item)
+}
+
+// Pending lists the items created before a point in time that were not delivered, oldest first
+func (service *OutboxServiceImpl) Pending(ctx context.Context, before time.Time) ([]*models.OutboxItem, error) {
+	return service.repo.GetPendingOutboxItems(ctx, before)
+}
+
+// Prune deletes the items delivered, or created and given up, before a point in time
+func (service *OutboxServiceImpl) Prune(ctx context.Context, before time.Time) error {
+	return service.repo.PruneOutboxItems(ctx, before)
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	// relativeTimestampPattern matches relative timestamp markup, which only Discord can render
+	relativeTimestampPattern = regexp.MustCompile(`<t:(-?\d+):R>`)
+	// markdownLinkPattern matches a Markdown link, keeping its text and target
+	markdownLinkPattern = regexp.MustCompile(`\[([^\]]*)\]\((https?://[^\s()]+)\)`)
+	// markdownEscapePattern matches the backslash escapes added by EscapeMarkdown
+	markdownEscapePattern = regexp.MustCompile(`\\([\\*_~` + "`" + `|\[\]<>#.+-])`)
+)
+
+// PlainTextMessage turns a Discord message into a title and plain text body for emails and push
+// notifications. Timestamps are written out in loc, relative ones against now, links show their
+// address, and Markdown and escapes are removed. The title is the message's first line without its
+// emojis.
+func PlainTextMessage(message string, loc *time.Location, now time.Time) (title, body string) {
+	body = LocalizeTimestamps(message, loc)
+	body = relativeTimestampPattern.ReplaceAllStringFunc(body, func(tag string) string {
+		unix, err := strconv.ParseInt(relativeTimestampPattern.FindStringSubmatch(tag)[1], 10, 64)
+		if err != nil {
+			return tag
+		}
+		return relativeTime(time.Unix(unix, 0), now)
+	})
+	body = markdownLinkPattern.ReplaceAllString(body, "$1: $2")
+
+	lines := strings.Split(body, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if strings.TrimSpace(line) == codeFence {
+			continue
+		}
+		line = strings.TrimPrefix(line, "-# ")
+		line = strings.NewReplacer("**", "", "__", "", "\u200b", "").Replace(line)
+		kept = append(kept, markdownEscapePattern.ReplaceAllString(line, "$1"))
+	}
+	body = strings.TrimSpace(strings.Join(kept, "\n")) + "\n"
+
+	first, _, _ := strings.Cut(body, "\n")
+	title = strings.TrimFunc(first, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	if title == "" {
+		title = "Notification"
+	}
+	return title, body
+}
+
+// relativeTime writes how far t is from now, like "in 3 hours", "2 days ago" or "just now"
+func relativeTime(t, now time.Time) string {
+	difference := t.Sub(now)
+	minutes := math.Round(math.Abs(difference.Minutes()))
+	var amount string
+	switch {
+	case minutes < 1:
+		return "just now"
+	case minutes < 60:
+		amount = pluralUnit(int(minutes), "minute")
+	case minutes < 48*60:
+		amount = pluralUnit(int(math.Round(minutes/60)), "hour")
+	default:
+		amount = pluralUnit(int(math.Round(minutes/(24*60))), "day")
+	}
+	if difference < 0 {
+		return amount + " ago"
+	}
+	return "in " + amount
+}
+
+// pluralUnit writes a count of a unit, like "1 hour" or "3 hours"
+func pluralUnit(count int, unit string) string {
+	if count == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", count, unit)
+}
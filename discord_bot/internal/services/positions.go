@@ -0,0 +1,126 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"coral-bot/discord_bot/internal/models"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxListedPositionMarkets keeps a positions embed under Discord's limit of 25 fields
+const maxListedPositionMarkets = 20
+
+// Colors of a positions embed, by whether the positions gained or lost overall
+const (
+	positionsGainColor = 0x2ecc71
+	positionsLossColor = 0xe74c3c
+)
+
+// positionMarket groups the positions a user holds in one market
+type positionMarket struct {
+	title     string
+	link      string
+	positions []*models.Position
+	value     float64
+}
+
+// CreatePositionsEmbed lays out a user's positions with a field for each market, the most valuable
+// first, showing the stake, current value and P&L of each outcome held, and the totals across all of
+// them in the description
+func (service *MarketServiceImpl) CreatePositionsEmbed(positions []*models.Position) *discordgo.MessageEmbed {
+	var markets []*positionMarket
+	byID := make(map[string]*positionMarket)
+	var stake, value float64
+	for _, position := range positions {
+		stake += position.Cost
+		value += position.Value
+		market := byID[position.MarketID]
+		if market == nil {
+			market = &positionMarket{title: position.MarketTitle, link: position.Link}
+			if market.title == "" {
+				market.title = position.MarketID
+			}
+			byID[position.MarketID] = market
+			markets = append(markets, market)
+		}
+		market.positions = append(market.positions, position)
+		market.value += position.Value
+	}
+	sort.SliceStable(markets, func(i, j int) bool { return markets[i].value > markets[j].value })
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "💼 Your positions",
+		Description: fmt.Sprintf("**Total:** stake $%.2f • value $%.2f • P&L %s", stake, value, signedAmount(value-stake)),
+		Color:       positionsGainColor,
+	}
+	if value < stake {
+		embed.Color = positionsLossColor
+	}
+	for i, market := range markets {
+		if i >= maxListedPositionMarkets {
+			embed.Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("...and %d more markets", len(markets)-maxListedPositionMarkets)}
+			break
+		}
+		lines := make([]string, 0, len(market.positions))
+		for _, position := range market.positions {
+			lines = append(lines, positionLine(position, "value"))
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  truncate(market.title, digestTitleLength),
+			Value: strings.Join(lines, "\n") + service.viewLink("\n", market.link),
+		})
+	}
+	return embed
+}
+
+// CreatePositionResolvedMessage tells a user how their positions in a resolved market ended, each
+// position's value being its payout
+func (service *MarketServiceImpl) CreatePositionResolvedMessage(market *models.Market, positions []*models.Position) string {
+	title := market.Title
+	if title == "" && len(positions) > 0 {
+		title = positions[0].MarketTitle
+	}
+	var message strings.Builder
+	fmt.Fprintf(&message, "🎯 **YOUR POSITION RESOLVED** 🎯\n\n**%s**\n", EscapeMarkdown(title))
+	var stake, payout float64
+	for _, position := range positions {
+		stake += position.Cost
+		payout += position.Value
+		switch {
+		case len(market.Winners()) == 0:
+			message.WriteString("• ")
+		case market.PaidOut(position.Outcome):
+			message.WriteString("✅ ")
+		default:
+			message.WriteString("❌ ")
+		}
+		message.WriteString(positionLine(position, "payout") + "\n")
+	}
+	if len(positions) > 1 {
+		fmt.Fprintf(&message, "\n**Total:** stake $%.2f • payout $%.2f • P&L %s\n", stake, payout, signedAmount(payout-stake))
+	}
+	return strings.TrimSuffix(message.String(), "\n") + service.viewLink("\n\n", market.Link)
+}
+
+// positionLine describes a position's shares, stake, value under the given name and P&L on one line
+func positionLine(position *models.Position, valueName string) string {
+	return fmt.Sprintf("%.2f × **%s** • stake $%.2f • %s $%.2f • P&L %s",
+		position.Shares,
+		EscapeMarkdown(position.Outcome),
+		position.Cost,
+		valueName,
+		position.Value,
+		signedAmount(position.ProfitLoss()),
+	)
+}
+
+// signedAmount formats a gain or loss in dollars, like +$4.00 or -$2.50
+func signedAmount(amount float64) string {
+	if amount < 0 {
+		return fmt.Sprintf("-$%.2f", -amount)
+	}
+	return fmt.Sprintf("+$%.2f", amount)
+}
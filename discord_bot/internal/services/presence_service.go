@@ -0,0 +1,135 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"coral-bot/discord_bot/internal/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// DefaultPresenceTemplate renders the bot's "Watching ..." status when PRESENCE_TEMPLATE is not set
+const DefaultPresenceTemplate = "{{.ActiveMarkets}} active markets • {{.Volume}} volume"
+
+// maxPresenceLength is the longest activity name Discord accepts
+const maxPresenceLength = 128
+
+// StatusUpdater sets the bot's Discord presence
+type StatusUpdater interface {
+	SetWatching(text string) error
+}
+
+// DiscordStatusUpdater implements StatusUpdater using a Discord session
+type DiscordStatusUpdater struct {
+	session *discordgo.Session
+}
+
+// NewDiscordStatusUpdater creates a new Discord status updater
+func NewDiscordStatusUpdater(session *discordgo.Session) *DiscordStatusUpdater {
+	return &DiscordStatusUpdater{session: session}
+}
+
+// SetWatching sets the bot's activity to "Watching <text>"
+func (u *DiscordStatusUpdater) SetWatching(text string) error {
+	return u.session.UpdateWatchStatus(0, text)
+}
+
+// PresenceStats are the values available to the presence template
+type PresenceStats struct {
+	ActiveMarkets int
+	TotalMarkets  int
+	Volume        string // total volume of active markets, e.g. $1.2M
+}
+
+// PresenceUpdater periodically sets the bot's status to live market stats
+type PresenceUpdater struct {
+	marketService MarketService
+	statusUpdater StatusUpdater
+	template      *template.Template
+	logger        *utils.Logger
+}
+
+// NewPresenceUpdater creates a presence updater rendering the given template, or DefaultPresenceTemplate when empty
+func NewPresenceUpdater(marketService MarketService, statusUpdater StatusUpdater, text string, logger *utils.Logger) (*PresenceUpdater, error) {
+	if text == "" {
+		text = DefaultPresenceTemplate
+	}
+	tmpl, err := template.New("presence").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid presence template: %w", err)
+	}
+	return &PresenceUpdater{
+		marketService: marketService,
+		statusUpdater: statusUpdater,
+		template:      tmpl,
+		logger:        logger,
+	}, nil
+}
+
+// Update fetches the current markets and sets the presence, returning the text that was set
+func (updater *PresenceUpdater) Update(ctx context.Context) (string, error) {
+	markets, err := updater.marketService.FetchAllMarkets(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch markets: %w", err)
+	}
+
+	stats := PresenceStats{TotalMarkets: len(markets)}
+	volume := 0.0
+	for _, market := range markets {
+		if market.Status == "active" {
+			stats.ActiveMarkets++
+			volume += market.Volume
+		}
+	}
+	stats.Volume = compactAmount(volume)
+
+	var text bytes.Buffer
+	if err := updater.template.Execute(&text, stats); err != nil {
+		return "", fmt.Errorf("failed to render presence: %w", err)
+	}
+	status := truncate(strings.TrimSpace(text.String()), maxPresenceLength)
+	if err := updater.statusUpdater.SetWatching(status); err != nil {
+		return "", fmt.Errorf("failed to update presence: %w", err)
+	}
+	return status, nil
+}
+
+// Run refreshes the presence immediately and then on every tick until the context is cancelled.
+// The presence is set on every tick rather than only on change, so it is restored after a gateway reconnect.
+func (updater *PresenceUpdater) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	updater.logger.Info(fmt.Sprintf("Presence updater started (interval %s)", interval))
+	for {
+		if _, err := updater.Update(ctx); err != nil {
+			updater.logger.Warning(fmt.Sprintf("Failed to refresh presence: %v", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			updater.logger.Info("Presence updater stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// compactAmount formats a dollar amount with a K, M or B suffix, e.g. $1.2M
+func compactAmount(amount float64) string {
+	switch {
+	case amount >= 1e9:
+		return fmt.Sprintf("$%.1fB", amount/1e9)
+	case amount >= 1e6:
+		return fmt.Sprintf("$%.1fM", amount/1e6)
+	case amount >= 1e3:
+		return fmt.Sprintf("$%.1fK", amount/1e3)
+	default:
+		return fmt.Sprintf("$%.0f", amount)
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/tracing"
+	"coral-bot/discord_bot/internal/utils"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// PushNotification is the body of a request to the backend's POST /notify, pushing a notification to
+// the devices of a Coral account
+type PushNotification struct {
+	AccountID string `json:"account_id"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	URL       string `json:"url,omitempty"` // first link of the message, opened when the notification is tapped
+	Source    string `json:"source"`
+}
+
+// PushNotifier implements Notifier for users who linked a Coral account: their direct messages are
+// pushed through the backend's notification API instead of DMed, or when the DM fails, as they chose
+// with push_notifications. Channel messages and other users' messages go to the notifier it wraps.
+type PushNotifier struct {
+	repo    repository.SubscriptionRepository
+	baseURL string
+	token   string // bearer token of the backend, empty for none
+	client  *http.Client
+	dms     Notifier
+	logger  *utils.Logger
+	clockAndIDs
+}
+
+// NewPushNotifier creates a push notifier calling the backend at baseURL and sending DMs with dms
+func NewPushNotifier(repo repository.SubscriptionRepository, baseURL, token string, dms Notifier, logger *utils.Logger) *PushNotifier {
+	return &PushNotifier{
+		repo:    repo,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client:  &http.Client{},
+		dms:     dms,
+		logger:  logger,
+	}
+}
+
+// SendChannelMessage posts a message with the wrapped notifier
+func (n *PushNotifier) SendChannelMessage(ctx context.Context, channelID string, message string) error {
+	return n.dms.SendChannelMessage(ctx, channelID, message)
+}
+
+// SendDirectMessage DMs a message with the wrapped notifier, pushing it to the user's Coral account
+// instead when they chose so, or when the DM fails and they chose the fallback
+func (n *PushNotifier) SendDirectMessage(ctx context.Context, discordUserID string, message string) error {
+	subscription, err := n.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+	mode := subscription.PushMode()
+	if mode == models.PushAlways {
+		pushErr := n.Push(ctx, discordUserID, message)
+		if pushErr == nil {
+			return nil
+		}
+		n.logger.Warning(fmt.Sprintf("Failed to push to user %s, sending a DM instead: %v", discordUserID, pushErr))
+	}
+
+	err = n.dms.SendDirectMessage(ctx, discordUserID, message)
+	if err != nil && mode == models.PushFallback {
+		if pushErr := n.Push(ctx, discordUserID, message); pushErr != nil {
+			return fmt.Errorf("%w, and pushing it failed: %v", err, pushErr)
+		}
+		n.logger.Info(fmt.Sprintf("DM to user %s failed, pushed it instead: %v", discordUserID, err))
+		return nil
+	}
+	return err
+}
+
+// Push sends a message to the devices of the user's Coral account as plain text, with times in their
+// timezone or UTC, whatever their push mode. ErrAccountNotLinked is returned when the user has no
+// linked account.
+func (n *PushNotifier) Push(ctx context.Context, discordUserID string, message string) (err error) {
+	subscription, err := n.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+	if !subscription.Account.Linked() {
+		return ErrAccountNotLinked
+	}
+	loc := timezoneOrNil(subscription.Timezone)
+	if loc == nil {
+		loc = time.UTC
+	}
+	notification := PushMessage(message, loc, n.now())
+	notification.AccountID = subscription.Account.AccountID
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, BackendTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.baseURL+"/notify", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+	req, span := tracing.StartClient(req, "backend.Notify")
+	defer func() { tracing.End(span, err) }()
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("backend returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// PushMessage turns a Discord message into a push notification: the plain text title of
+// PlainTextMessage, the rest of the message as the body, and its first link to open when tapped
+func PushMessage(message string, loc *time.Location, now time.Time) PushNotification {
+	title, body := PlainTextMessage(message, loc, now)
+	_, rest, _ := strings.Cut(body, "\n")
+	notification := PushNotification{Title: title, Body: strings.TrimSpace(rest), Source: UTMSource}
+	if link := markdownLinkPattern.FindStringSubmatch(message); link != nil {
+		notification.URL = link[2]
+	}
+	return notification
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// quietSummarySize is the number of markets listed in a quiet-hours summary
+const quietSummarySize = 10
+
+// heldEventLabels names held events in a quiet-hours summary, singular and plural
+var heldEventLabels = map[string][2]string{
+	models.EventNewMarket:       {"new market", "new market"},
+	models.EventMarketUpdate:    {"update", "updates"},
+	models.EventTradingStarted:  {"trading started", "trading started"},
+	models.EventTradingEnded:    {"trading ended", "trading ended"},
+	models.EventMarketResolved:  {"resolved", "resolved"},
+	models.EventMarketCancelled: {"cancelled", "cancelled"},
+	models.EventMarketBuy:       {"buy", "buys"},
+	models.EventMarketLiquidity: {"liquidity change", "liquidity changes"},
+}
+
+// ParseQuietHours parses a quiet-hours window written as HH:MM-HH:MM, such as 23:00-08:00, and returns
+// its start and end as HH:MM. A window whose end is before its start runs past midnight.
+func ParseQuietHours(window string) (string, string, error) {
+	window = strings.NewReplacer("–", "-", "—", "-").Replace(window)
+	parts := strings.Split(window, "-")
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("%w: quiet hours must be written as HH:MM-HH:MM", ErrInvalidChannelSettings)
+	}
+	start, end := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	if err := validateQuietHours(start, end); err != nil {
+		return "", "", err
+	}
+	startClock, _ := time.Parse("15:04", start)
+	endClock, _ := time.Parse("15:04", end)
+	return startClock.Format("15:04"), endClock.Format("15:04"), nil
+}
+
+// validateQuietHours checks that a quiet-hours window is either unset or two different times of day
+func validateQuietHours(start, end string) error {
+	if start == "" && end == "" {
+		return nil
+	}
+	startClock, err := time.Parse("15:04", start)
+	if err != nil {
+		return fmt.Errorf("%w: quiet hours start %q is not HH:MM", ErrInvalidChannelSettings, start)
+	}
+	endClock, err := time.Parse("15:04", end)
+	if err != nil {
+		return fmt.Errorf("%w: quiet hours end %q is not HH:MM", ErrInvalidChannelSettings, end)
+	}
+	if startClock.Equal(endClock) {
+		return fmt.Errorf("%w: quiet hours must start and end at different times", ErrInvalidChannelSettings)
+	}
+	return nil
+}
+
+// InQuietHours reports whether now falls in a channel's quiet hours, read in the channel's timezone or UTC
+func InQuietHours(config *models.ChannelConfig, now time.Time) bool {
+	if config == nil || !config.HasQuietHours() {
+		return false
+	}
+	startClock, err := time.Parse("15:04", config.QuietHoursStart)
+	if err != nil {
+		return false
+	}
+	endClock, err := time.Parse("15:04", config.QuietHoursEnd)
+	if err != nil {
+		return false
+	}
+
+	location := timezoneOrNil(config.Timezone)
+	if location == nil {
+		location = time.UTC
+	}
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	start, end := startClock.Hour()*60+startClock.Minute(), endClock.Hour()*60+endClock.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// SetChannelQuietHours sets the window in which a channel's market events are held, or clears it when
+// start and end are empty. Events held so far are posted once the channel is no longer quiet.
+func (service *SubscriptionServiceImpl) SetChannelQuietHours(ctx context.Context, channelID, guildID, start, end, actor string) error {
+	if err := validateQuietHours(start, end); err != nil {
+		return err
+	}
+	config, err := service.repo.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel config: %w", err)
+	}
+
+	config.QuietHoursStart, config.QuietHoursEnd = start, end
+	config.LastUpdateTimestamp = service.now()
+	if guildID != "" {
+		config.GuildID = guildID
+	}
+	return service.UpdateChannelConfig(ctx, config, actor)
+}
+
+// QuietHoursService defines the interface for holding channel posts during quiet hours
+type QuietHoursService interface {
+	Hold(ctx context.Context, config *models.ChannelConfig, eventType string, market *models.Market, now time.Time) bool
+	FlushEnded(ctx context.Context, now time.Time) int
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// QuietHoursServiceImpl implements QuietHoursService
+type QuietHoursServiceImpl struct {
+	repo     repository.SubscriptionRepository
+	notifier Notifier
+	logger   *utils.Logger
+	clockAndIDs
+	linkAllowlist
+}
+
+// NewQuietHoursService creates a new quiet hours service
+func NewQuietHoursService(repo repository.SubscriptionRepository, notifier Notifier, logger *utils.Logger) *QuietHoursServiceImpl {
+	return &QuietHoursServiceImpl{
+		repo:     repo,
+		notifier: notifier,
+		logger:   logger,
+	}
+}
+
+// Hold keeps an event for a channel in its quiet hours and reports whether it did. An event that
+// cannot be stored is not held, so the channel gets it right away instead of never.
+func (service *QuietHoursServiceImpl) Hold(ctx context.Context, config *models.ChannelConfig, eventType string, market *models.Market, now time.Time) bool {
+	if !InQuietHours(config, now) {
+		return false
+	}
+	held := &models.HeldNotification{
+		ChannelID:   config.ChannelID,
+		MarketID:    market.ID,
+		MarketTitle: market.Title,
+		MarketLink:  market.Link,
+		EventType:   eventType,
+		Count:       1,
+		FirstHeldAt: now,
+		LastHeldAt:  now,
+	}
+	if err := service.repo.AddHeldNotification(ctx, held); err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to hold %s event for channel %s, posting it now: %v", eventType, config.ChannelID, err))
+		return false
+	}
+	return true
+}
+
+// FlushEnded posts a summary of the held events to every channel that is no longer in its quiet hours
+// and returns how many summaries were delivered
+func (service *QuietHoursServiceImpl) FlushEnded(ctx context.Context, now time.Time) int {
+	configs, err := service.repo.GetAllChannelConfigs(ctx)
+	if err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to get channel configs: %v", err))
+		return 0
+	}
+
+	sent := 0
+	for _, config := range configs {
+		if InQuietHours(config, now) {
+			continue
+		}
+		held, err := service.repo.GetHeldNotifications(ctx, config.ChannelID)
+		if err != nil {
+			service.logger.Error(fmt.Sprintf("Failed to get held events for channel %s: %v", config.ChannelID, err))
+			continue
+		}
+		if len(held) == 0 {
+			continue
+		}
+
+		if err := service.notifier.SendChannelMessage(ctx, config.ChannelID, service.renderQuietHoursSummary(held)); err != nil {
+			service.logger.Error(fmt.Sprintf("Failed to send quiet hours summary to channel %s: %v", config.ChannelID, err))
+		} else {
+			sent++
+		}
+
+		// Like digests, a failed summary is not retried; the events are dropped
+		if _, err := service.repo.DeleteHeldNotifications(ctx, config.ChannelID); err != nil {
+			service.logger.Error(fmt.Sprintf("Failed to delete held events for channel %s: %v", config.ChannelID, err))
+		}
+	}
+	return sent
+}
+
+// renderQuietHoursSummary lists the markets with held events, in the order their first event arrived,
+// with how many events of each type were held
+func (service *QuietHoursServiceImpl) renderQuietHoursSummary(held []*models.HeldNotification) string {
+	var order []string
+	markets := make(map[string][]*models.HeldNotification)
+	total := 0
+	for _, notification := range held {
+		if _, seen := markets[notification.MarketID]; !seen {
+			order = append(order, notification.MarketID)
+		}
+		markets[notification.MarketID] = append(markets[notification.MarketID], notification)
+		total += notification.Count
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "🌙 **While this channel was quiet** — %d %s on %d %s\n", total, map[bool]string{true: "event", false: "events"}[total == 1], len(order), map[bool]string{true: "market", false: "markets"}[len(order) == 1])
+	for i, marketID := range order {
+		if i == quietSummarySize {
+			fmt.Fprintf(&message, "…and %d more\n", len(order)-quietSummarySize)
+			break
+		}
+		events := markets[marketID]
+		var counts []string
+		for _, notification := range events {
+			labels, known := heldEventLabels[notification.EventType]
+			if !known {
+				labels = [2]string{notification.EventType, notification.EventType}
+			}
+			if notification.Count == 1 {
+				counts = append(counts, labels[0])
+			} else {
+				counts = append(counts, fmt.Sprintf("%d %s", notification.Count, labels[1]))
+			}
+		}
+		latest := events[len(events)-1]
+		for _, notification := range events {
+			if notification.LastHeldAt.After(latest.LastHeldAt) {
+				latest = notification
+			}
+		}
+		fmt.Fprintf(&message, "• %s — %s\n", service.markdownLink(latest.MarketTitle, digestTitleLength, latest.MarketLink), strings.Join(counts, ", "))
+	}
+	return message.String()
+}
+
+// Run posts the summaries of ended quiet hours on every tick until the context is cancelled
+func (service *QuietHoursServiceImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	service.logger.Info(fmt.Sprintf("Quiet hours scheduler started (interval %s)", interval))
+	for {
+		select {
+		case <-ctx.Done():
+			service.logger.Info("Quiet hours scheduler stopped")
+			return
+		case now := <-ticker.C:
+			if sent := service.FlushEnded(ctx, now); sent > 0 {
+				service.logger.Info(fmt.Sprintf("Sent %d quiet hours summaries", sent))
+			}
+		}
+	}
+}
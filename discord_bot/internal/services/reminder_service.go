@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// ReminderService defines the interface for scheduling "closing soon" reminders
+type ReminderService interface {
+	CreateUserReminder(ctx context.Context, discordUserID, marketID string, before time.Duration) (*models.Reminder, error)
+	CreateChannelReminder(ctx context.Context, channelID, marketID string, before time.Duration) (*models.Reminder, error)
+	ListUserReminders(ctx context.Context, discordUserID string) ([]*models.Reminder, error)
+	CancelReminder(ctx context.Context, id string) error
+	ProcessDueReminders(ctx context.Context, now time.Time) int
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// ReminderServiceImpl implements ReminderService
+type ReminderServiceImpl struct {
+	repo          repository.SubscriptionRepository
+	marketService MarketService
+	notifier      Notifier
+	scheduler     SchedulerService // nil when due reminders are only sent by Run
+	logger        *utils.Logger
+	clockAndIDs
+}
+
+// NewReminderService creates a new reminder service
+func NewReminderService(
+	repo repository.SubscriptionRepository,
+	marketService MarketService,
+	notifier Notifier,
+	logger *utils.Logger,
+) *ReminderServiceImpl {
+	return &ReminderServiceImpl{
+		repo:          repo,
+		marketService: marketService,
+		notifier:      notifier,
+		logger:        logger,
+	}
+}
+
+// SetScheduler has every new reminder stored as a job of the scheduler, which sends it when it is due
+// and retries it when the send fails. Run is then not needed.
+func (service *ReminderServiceImpl) SetScheduler(scheduler SchedulerService) {
+	service.scheduler = scheduler
+	scheduler.Handle(models.JobReminder, service.runReminderJob)
+}
+
+// ScheduleStoredReminders schedules a job for every pending reminder that has none, like the reminders
+// created before the scheduler was set, and returns how many it scheduled
+func (service *ReminderServiceImpl) ScheduleStoredReminders(ctx context.Context) (int, error) {
+	if service.scheduler == nil {
+		return 0, nil
+	}
+	reminders, err := service.repo.GetDueReminders(ctx, endOfTime)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get reminders: %w", err)
+	}
+	scheduled := 0
+	for _, reminder := range reminders {
+		job, err := service.repo.GetScheduledJob(ctx, jobID(models.JobReminder, reminder.ID))
+		if err != nil {
+			return scheduled, fmt.Errorf("failed to get reminder job: %w", err)
+		}
+		if job != nil {
+			continue
+		}
+		if err := service.scheduler.Schedule(ctx, models.JobReminder, reminder.ID, reminder.RemindAt); err != nil {
+			return scheduled, err
+		}
+		scheduled++
+	}
+	return scheduled, nil
+}
+
+// endOfTime is later than any reminder fires
+var endOfTime = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+// CreateUserReminder schedules a DM reminder for a user
+func (service *ReminderServiceImpl) CreateUserReminder(ctx context.Context, discordUserID, marketID string, before time.Duration) (*models.Reminder, error) {
+	return service.createReminder(ctx, &models.Reminder{DiscordUserID: discordUserID}, marketID, before)
+}
+
+// CreateChannelReminder schedules a reminder posted to a channel
+func (service *ReminderServiceImpl) CreateChannelReminder(ctx context.Context, channelID, marketID string, before time.Duration) (*models.Reminder, error) {
+	return service.createReminder(ctx, &models.Reminder{ChannelID: channelID}, marketID, before)
+}
+
+// createReminder resolves the market close time and stores the reminder
+func (service *ReminderServiceImpl) createReminder(ctx context.Context, reminder *models.Reminder, marketID string, before time.Duration) (*models.Reminder, error) {
+	if before <= 0 {
+		return nil, fmt.Errorf("reminder duration must be positive")
+	}
+
+	market, err := service.marketService.FetchMarket(ctx, marketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch market: %w", err)
+	}
+	if market.EndTime.IsZero() {
+		return nil, fmt.Errorf("market %s has no close time", marketID)
+	}
+
+	remindAt := market.EndTime.Add(-before)
+	if !remindAt.After(service.now()) {
+		return nil, fmt.Errorf("market %s closes in less than %s", marketID, before)
+	}
+
+	id, err := service.newID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate id: %w", err)
+	}
+
+	reminder.ID = "rem_" + id
+	reminder.MarketID = market.ID
+	reminder.MarketTitle = market.Title
+	reminder.MarketLink = market.Link
+	reminder.EndTime = market.EndTime
+	reminder.Before = before
+	reminder.RemindAt = remindAt
+	reminder.CreatedAt = service.now()
+
+	if err := service.repo.SaveReminder(ctx, reminder); err != nil {
+		return nil, fmt.Errorf("failed to save reminder: %w", err)
+	}
+	if service.scheduler != nil {
+		if err := service.scheduler.Schedule(ctx, models.JobReminder, reminder.ID, remindAt); err != nil {
+			service.repo.DeleteReminder(ctx, reminder.ID)
+			return nil, err
+		}
+	}
+	return reminder, nil
+}
+
+// ListUserReminders lists a user's pending reminders
+func (service *ReminderServiceImpl) ListUserReminders(ctx context.Context, discordUserID string) ([]*models.Reminder, error) {
+	return service.repo.GetRemindersByUser(ctx, discordUserID)
+}
+
+// CancelReminder removes a pending reminder and its job
+func (service *ReminderServiceImpl) CancelReminder(ctx context.Context, id string) error {
+	if err := service.repo.DeleteReminder(ctx, id); err != nil {
+		return err
+	}
+	if service.scheduler != nil {
+		return service.scheduler.Cancel(ctx, models.JobReminder, id)
+	}
+	return nil
+}
+
+// ProcessDueReminders sends every reminder that is due and returns how many were delivered. Reminders
+// of snoozed users are postponed.
+func (service *ReminderServiceImpl) ProcessDueReminders(ctx context.Context, now time.Time) int {
+	due, err := service.repo.GetDueReminders(ctx, now)
+	if err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to get due reminders: %v", err))
+		return 0
+	}
+
+	sent := 0
+	for _, reminder := range due {
+		if until, snoozed := service.snoozedUntil(ctx, reminder, now); snoozed {
+			if err := service.postpone(ctx, reminder, until); err != nil {
+				service.logger.Error(fmt.Sprintf("Failed to postpone reminder %s: %v", reminder.ID, err))
+			}
+			continue
+		}
+		if err := service.send(ctx, reminder); err != nil {
+			service.logger.Error(fmt.Sprintf("Failed to send reminder %s: %v", reminder.ID, err))
+		} else {
+			sent++
+		}
+
+		// Reminders fire once; a failed delivery is not retried
+		if err := service.repo.DeleteReminder(ctx, reminder.ID); err != nil {
+			service.logger.Error(fmt.Sprintf("Failed to delete reminder %s: %v", reminder.ID, err))
+		}
+	}
+	return sent
+}
+
+// runReminderJob sends the reminder of a job, deleting it once it was sent. Reminders deleted since
+// the job was scheduled, like those of a cancelled market, are skipped, and those of snoozed users
+// are postponed.
+func (service *ReminderServiceImpl) runReminderJob(ctx context.Context, job *models.ScheduledJob) error {
+	reminder, err := service.repo.GetReminder(ctx, job.Key)
+	if err != nil {
+		return fmt.Errorf("failed to get reminder: %w", err)
+	}
+	if reminder == nil {
+		return nil
+	}
+	if until, snoozed := service.snoozedUntil(ctx, reminder, service.now()); snoozed {
+		return service.postpone(ctx, reminder, until)
+	}
+	if err := service.send(ctx, reminder); err != nil {
+		return err
+	}
+	if err := service.repo.DeleteReminder(ctx, reminder.ID); err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to delete reminder %s: %v", reminder.ID, err))
+	}
+	return nil
+}
+
+// snoozedUntil returns when the snooze of a DM reminder's user ends, and whether they are snoozed at
+// now. Channel reminders are never snoozed.
+func (service *ReminderServiceImpl) snoozedUntil(ctx context.Context, reminder *models.Reminder, now time.Time) (time.Time, bool) {
+	if reminder.ChannelID != "" {
+		return time.Time{}, false
+	}
+	subscription, err := service.repo.GetSubscription(ctx, reminder.DiscordUserID)
+	if err != nil || !subscription.Snoozed(now) {
+		return time.Time{}, false
+	}
+	return subscription.SnoozedUntil, true
+}
+
+// postpone moves a reminder of a snoozed user to the end of the snooze, or deletes it when the market
+// closes by then
+func (service *ReminderServiceImpl) postpone(ctx context.Context, reminder *models.Reminder, until time.Time) error {
+	if !until.Before(reminder.EndTime) {
+		service.logger.Info(fmt.Sprintf("Dropping reminder %s: %s is snoozed until the market closes", reminder.ID, reminder.DiscordUserID))
+		return service.repo.DeleteReminder(ctx, reminder.ID)
+	}
+	reminder.RemindAt = until
+	if err := service.repo.SaveReminder(ctx, reminder); err != nil {
+		return fmt.Errorf("failed to save reminder: %w", err)
+	}
+	if service.scheduler != nil {
+		return service.scheduler.Schedule(ctx, models.JobReminder, reminder.ID, until)
+	}
+	return nil
+}
+
+// send posts a reminder to its channel or DMs it to its user
+func (service *ReminderServiceImpl) send(ctx context.Context, reminder *models.Reminder) error {
+	market := &models.Market{
+		ID:      reminder.MarketID,
+		Title:   reminder.MarketTitle,
+		EndTime: reminder.EndTime,
+		Link:    reminder.MarketLink,
+	}
+	message := LocalizeTimestamps(service.marketService.CreateMarketClosingSoonMessage(market), service.location(ctx, reminder))
+	if reminder.ChannelID != "" {
+		return service.notifier.SendChannelMessage(ctx, reminder.ChannelID, message)
+	}
+	return service.notifier.SendDirectMessage(ctx, reminder.DiscordUserID, message)
+}
+
+// Run checks for due reminders on every tick until the context is cancelled
+func (service *ReminderServiceImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	service.logger.Info(fmt.Sprintf("Reminder scheduler started (interval %s)", interval))
+	for {
+		select {
+		case <-ctx.Done():
+			service.logger.Info("Reminder scheduler stopped")
+			return
+		case <-ticker.C:
+			if sent := service.ProcessDueReminders(ctx, service.now()); sent > 0 {
+				service.logger.Info(fmt.Sprintf("Sent %d reminders", sent))
+			}
+		}
+	}
+}
+
+// location returns the timezone chosen by the reminder's channel or user, or nil for the reader's locale
+func (service *ReminderServiceImpl) location(ctx context.Context, reminder *models.Reminder) *time.Location {
+	if reminder.ChannelID != "" {
+		config, err := service.repo.GetChannelConfig(ctx, reminder.ChannelID)
+		if err != nil {
+			return nil
+		}
+		return timezoneOrNil(config.Timezone)
+	}
+	subscription, err := service.repo.GetSubscription(ctx, reminder.DiscordUserID)
+	if err != nil {
+		return nil
+	}
+	return timezoneOrNil(subscription.Timezone)
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// ParsePingEvents reads a comma-separated list of the events whose channel alerts mention a role, such
+// as "new_market,market_resolved". An empty list gives DefaultPingEvents.
+func ParsePingEvents(value string) ([]string, error) {
+	var events []string
+	seen := make(map[string]bool)
+	for _, event := range strings.Split(value, ",") {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if event == "" || seen[event] {
+			continue
+		}
+		seen[event] = true
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		return append([]string(nil), models.DefaultPingEvents...), nil
+	}
+	if err := validatePingEvents(events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// validatePing checks a channel's ping role and events. The role must be a role ID other than the
+// guild's, which is @everyone, so a misconfiguration cannot ping the whole server.
+func validatePing(roleID, guildID string, events []string) error {
+	if roleID == "" {
+		return nil
+	}
+	if strings.Trim(roleID, "0123456789") != "" {
+		return fmt.Errorf("%w: ping role %q is not a role ID", ErrInvalidChannelSettings, roleID)
+	}
+	if roleID == guildID {
+		return fmt.Errorf("%w: the ping role cannot be @everyone", ErrInvalidChannelSettings)
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("%w: ping events are required with a ping role", ErrInvalidChannelSettings)
+	}
+	return validatePingEvents(events)
+}
+
+// validatePingEvents checks that each event is posted in channels, so its alerts can mention a role
+func validatePingEvents(events []string) error {
+	allowed := make(map[string]bool, len(models.ChannelPingEvents))
+	for _, event := range models.ChannelPingEvents {
+		allowed[event] = true
+	}
+	for _, event := range events {
+		if !allowed[event] {
+			return fmt.Errorf("%w: %q is not an event posted in channels, expected some of %s", ErrInvalidChannelSettings, event, strings.Join(models.ChannelPingEvents, ", "))
+		}
+	}
+	return nil
+}
+
+// SetChannelPing makes a channel's alerts of the given events mention a role, or mention nobody when
+// roleID is empty
+func (service *SubscriptionServiceImpl) SetChannelPing(ctx context.Context, channelID, guildID, roleID string, events []string, actor string) error {
+	config, err := service.repo.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel config: %w", err)
+	}
+	if guildID != "" {
+		config.GuildID = guildID
+	}
+	if roleID == "" {
+		events = nil
+	}
+	if err := validatePing(roleID, config.GuildID, events); err != nil {
+		return err
+	}
+
+	config.PingRoleID = roleID
+	config.PingEvents = append([]string(nil), events...)
+	config.LastUpdateTimestamp = service.now()
+	return service.UpdateChannelConfig(ctx, config, actor)
+}
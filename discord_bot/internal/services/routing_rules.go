@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// MaxRoutingRulesPerGuild is the number of routing rules a guild may have
+const MaxRoutingRulesPerGuild = 25
+
+var (
+	// ErrInvalidRoutingRule is returned for routing rules without a guild, with an unknown action or event
+	// type, a ping without a role, a route without another channel or a negative or inverted volume range
+	ErrInvalidRoutingRule = errors.New("invalid routing rule")
+	// ErrRoutingRuleNotFound is returned when a guild has no routing rule with the ID
+	ErrRoutingRuleNotFound = errors.New("routing rule not found")
+)
+
+// AddRoutingRule adds a rule to a guild's routing rules at the rule's position, the end when it is zero
+// or past the last rule, and renumbers the guild's other rules after it
+func (service *SubscriptionServiceImpl) AddRoutingRule(ctx context.Context, rule *models.RoutingRule, actor string) (*models.RoutingRule, error) {
+	if err := normalizeRoutingRule(rule); err != nil {
+		return nil, err
+	}
+	var added *models.RoutingRule
+	err := service.withTx(ctx, func(tx *SubscriptionServiceImpl) error {
+		rules, err := tx.repo.GetRoutingRules(ctx, rule.GuildID)
+		if err != nil {
+			return fmt.Errorf("failed to get routing rules: %w", err)
+		}
+		if len(rules) >= MaxRoutingRulesPerGuild {
+			return &LimitExceededError{Kind: limitRoutingRules, Limit: MaxRoutingRulesPerGuild}
+		}
+
+		id, err := tx.newID()
+		if err != nil {
+			return fmt.Errorf("failed to generate routing rule ID: %w", err)
+		}
+		added = rule.Clone()
+		added.ID, added.CreatedBy, added.CreatedAt = id, actor, tx.now()
+		if err := tx.saveRoutingRuleOrder(ctx, insertRoutingRule(rules, added, rule.Position)); err != nil {
+			return err
+		}
+		tx.recordAudit(ctx, actor, models.AuditActionCreate, models.AuditResourceRoutingRule, added.ID, added.ChannelID, nil, added)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// MoveRoutingRule moves one of a guild's routing rules to a position, the end when it is zero or past
+// the last rule, and renumbers the guild's other rules
+func (service *SubscriptionServiceImpl) MoveRoutingRule(ctx context.Context, guildID, id string, position int, actor string) (*models.RoutingRule, error) {
+	if position < 0 {
+		return nil, fmt.Errorf("%w: positions start at 1", ErrInvalidRoutingRule)
+	}
+	var moved *models.RoutingRule
+	err := service.withTx(ctx, func(tx *SubscriptionServiceImpl) error {
+		rules, err := tx.repo.GetRoutingRules(ctx, guildID)
+		if err != nil {
+			return fmt.Errorf("failed to get routing rules: %w", err)
+		}
+		rest := make([]*models.RoutingRule, 0, len(rules))
+		for _, rule := range rules {
+			if rule.ID == id {
+				moved = rule
+			} else {
+				rest = append(rest, rule)
+			}
+		}
+		if moved == nil {
+			return ErrRoutingRuleNotFound
+		}
+		previous := moved.Clone()
+		if err := tx.saveRoutingRuleOrder(ctx, insertRoutingRule(rest, moved, position)); err != nil {
+			return err
+		}
+		tx.recordAudit(ctx, actor, models.AuditActionUpdate, models.AuditResourceRoutingRule, id, moved.ChannelID, previous, moved)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// RemoveRoutingRule removes one of a guild's routing rules, renumbers the rules after it and reports
+// whether there was one
+func (service *SubscriptionServiceImpl) RemoveRoutingRule(ctx context.Context, guildID, id, actor string) (bool, error) {
+	removed := false
+	err := service.withTx(ctx, func(tx *SubscriptionServiceImpl) error {
+		rules, err := tx.repo.GetRoutingRules(ctx, guildID)
+		if err != nil {
+			return fmt.Errorf("failed to get routing rules: %w", err)
+		}
+		rest := make([]*models.RoutingRule, 0, len(rules))
+		for _, rule := range rules {
+			if rule.ID != id {
+				rest = append(rest, rule)
+				continue
+			}
+			if _, err := tx.repo.DeleteRoutingRule(ctx, id); err != nil {
+				return fmt.Errorf("failed to delete routing rule: %w", err)
+			}
+			tx.recordAudit(ctx, actor, models.AuditActionDelete, models.AuditResourceRoutingRule, id, rule.ChannelID, rule, nil)
+			removed = true
+		}
+		if !removed {
+			return nil
+		}
+		return tx.saveRoutingRuleOrder(ctx, rest)
+	})
+	return removed, err
+}
+
+// GetRoutingRules returns a guild's routing rules in the order they are evaluated
+func (service *SubscriptionServiceImpl) GetRoutingRules(ctx context.Context, guildID string) ([]*models.RoutingRule, error) {
+	return service.repo.GetRoutingRules(ctx, guildID)
+}
+
+// GetAllRoutingRules returns the routing rules of every guild, each guild's in the order they are evaluated
+func (service *SubscriptionServiceImpl) GetAllRoutingRules(ctx context.Context) ([]*models.RoutingRule, error) {
+	return service.repo.GetAllRoutingRules(ctx)
+}
+
+// removeRoutingRulesWhere removes the routing rules of a guild, or of every guild when guildID is empty,
+// that remove reports true for, and returns how many it removed
+func (service *SubscriptionServiceImpl) removeRoutingRulesWhere(ctx context.Context, guildID string, remove func(*models.RoutingRule) bool, actor string) (int, error) {
+	var rules []*models.RoutingRule
+	var err error
+	if guildID == "" {
+		rules, err = service.repo.GetAllRoutingRules(ctx)
+	} else {
+		rules, err = service.repo.GetRoutingRules(ctx, guildID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get routing rules: %w", err)
+	}
+	removed := 0
+	for _, rule := range rules {
+		if !remove(rule) {
+			continue
+		}
+		if _, err := service.RemoveRoutingRule(ctx, rule.GuildID, rule.ID, actor); err != nil {
+			return removed, fmt.Errorf("failed to remove routing rule %s: %w", rule.ID, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// saveRoutingRuleOrder numbers a guild's rules from 1 in order and saves them
+func (service *SubscriptionServiceImpl) saveRoutingRuleOrder(ctx context.Context, rules []*models.RoutingRule) error {
+	for i, rule := range rules {
+		rule.Position = i + 1
+		if err := service.repo.SaveRoutingRule(ctx, rule); err != nil {
+			return fmt.Errorf("failed to save routing rule: %w", err)
+		}
+	}
+	return nil
+}
+
+// insertRoutingRule returns rules with rule inserted at a 1-based position, or appended when position is
+// zero or past the end
+func insertRoutingRule(rules []*models.RoutingRule, rule *models.RoutingRule, position int) []*models.RoutingRule {
+	if position <= 0 || position > len(rules) {
+		return append(rules, rule)
+	}
+	ordered := make([]*models.RoutingRule, 0, len(rules)+1)
+	ordered = append(ordered, rules[:position-1]...)
+	ordered = append(ordered, rule)
+	return append(ordered, rules[position-1:]...)
+}
+
+// normalizeRoutingRule checks a new routing rule and normalizes its conditions: event types are
+// lowercased, categories normalized and blank values dropped
+func normalizeRoutingRule(rule *models.RoutingRule) error {
+	if rule.GuildID == "" {
+		return fmt.Errorf("%w: guild is required", ErrInvalidRoutingRule)
+	}
+	if rule.Position < 0 {
+		return fmt.Errorf("%w: positions start at 1", ErrInvalidRoutingRule)
+	}
+	switch rule.Action {
+	case models.RuleActionSend, models.RuleActionSuppress:
+		rule.RoleID, rule.TargetChannelID = "", ""
+	case models.RuleActionPing:
+		if rule.RoleID == "" || strings.Trim(rule.RoleID, "0123456789") != "" || rule.RoleID == rule.GuildID {
+			return fmt.Errorf("%w: ping rules need a role other than @everyone", ErrInvalidRoutingRule)
+		}
+		rule.TargetChannelID = ""
+	case models.RuleActionRoute:
+		if rule.TargetChannelID == "" || rule.TargetChannelID == rule.ChannelID {
+			return fmt.Errorf("%w: route rules need another channel to post in", ErrInvalidRoutingRule)
+		}
+		rule.RoleID = ""
+	default:
+		return fmt.Errorf("%w: action must be one of %s", ErrInvalidRoutingRule, strings.Join(models.RoutingRuleActions, ", "))
+	}
+
+	rule.EventTypes = trimmedValues(rule.EventTypes, strings.ToLower)
+	if err := validatePingEvents(rule.EventTypes); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidRoutingRule, strings.TrimPrefix(err.Error(), ErrInvalidChannelSettings.Error()+": "))
+	}
+	rule.Categories = trimmedValues(rule.Categories, models.NormalizeCategory)
+	rule.Creators = trimmedValues(rule.Creators, nil)
+	rule.Keywords = trimmedValues(rule.Keywords, nil)
+
+	if rule.MinVolume < 0 || rule.MaxVolume < 0 {
+		return fmt.Errorf("%w: volumes cannot be negative", ErrInvalidRoutingRule)
+	}
+	if rule.MaxVolume > 0 && rule.MaxVolume < rule.MinVolume {
+		return fmt.Errorf("%w: the maximum volume is below the minimum", ErrInvalidRoutingRule)
+	}
+	return nil
+}
+
+// trimmedValues returns values trimmed, passed through normalize when it is set, without blanks and
+// duplicates
+func trimmedValues(values []string, normalize func(string) string) []string {
+	var trimmed []string
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if normalize != nil {
+			value = normalize(value)
+		}
+		if value != "" && !seen[value] {
+			seen[value] = true
+			trimmed = append(trimmed, value)
+		}
+	}
+	return trimmed
+}
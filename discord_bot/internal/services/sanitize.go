@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultLinkDomains are the domains links in messages can point to unless LINK_ALLOWED_DOMAINS sets
+// others. Subdomains are allowed too.
+var DefaultLinkDomains = []string{"coral.markets"}
+
+// markdownEscaper escapes the characters Discord reads as formatting or mentions, so backend text shows
+// as written. Backslashes before punctuation are hidden when Discord renders the message.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "|", `\|`,
+	"[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`,
+	// A zero-width space after @ keeps @everyone and @here from pinging anyone
+	"@", "@\u200b",
+)
+
+// urlPattern matches the web addresses Discord turns into links
+var urlPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<]+`)
+
+// listMarker matches the start of a line Discord shows as a list item
+var listMarker = regexp.MustCompile(`^(\s*)([-+]|\d+\.) `)
+
+// EscapeMarkdown escapes a single line of backend text, like a market title or an outcome, for a
+// message: line breaks become spaces, formatting and mentions are escaped and web addresses are kept
+// from becoming links
+func EscapeMarkdown(value string) string {
+	return escapeLine(strings.Join(strings.Fields(value), " "))
+}
+
+// EscapeMarkdownBlock escapes backend text of several lines, like a market description, keeping its
+// line breaks but not the headings, quotes and lists they would start
+func EscapeMarkdownBlock(value string) string {
+	lines := strings.Split(strings.ReplaceAll(strings.TrimSpace(value), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = escapeLine(line)
+		if match := listMarker.FindStringSubmatch(line); match != nil {
+			// A numbered item is escaped at its dot, as a backslash before a digit would show
+			marker := `\` + match[2]
+			if strings.HasSuffix(match[2], ".") {
+				marker = strings.TrimSuffix(match[2], ".") + `\.`
+			}
+			line = match[1] + marker + line[len(match[0])-1:]
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escapeLine escapes the formatting and mentions of a line and breaks up its web addresses, which a
+// zero-width space after the scheme stops Discord from linking
+func escapeLine(line string) string {
+	var escaped strings.Builder
+	last := 0
+	for _, match := range urlPattern.FindAllStringIndex(line, -1) {
+		escaped.WriteString(markdownEscaper.Replace(line[last:match[0]]))
+		address := line[match[0]:match[1]]
+		scheme, rest, _ := strings.Cut(address, "://")
+		escaped.WriteString(scheme + ":\u200b//" + markdownEscaper.Replace(rest))
+		last = match[1]
+	}
+	escaped.WriteString(markdownEscaper.Replace(line[last:]))
+	return escaped.String()
+}
+
+// EscapeCodeBlock makes backend text safe inside a code block, where escapes are shown as written, by
+// dropping the backticks that would close the block
+func EscapeCodeBlock(value string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(value), " "), "`", "")
+}
+
+// linkAllowlist checks the links of the services that render backend links into messages. Services
+// embed it; links must be https addresses on DefaultLinkDomains unless SetLinkDomains sets others.
+type linkAllowlist struct {
+	domains []string
+}
+
+// SetLinkDomains sets the domains links in messages can point to, or DefaultLinkDomains when empty
+func (links *linkAllowlist) SetLinkDomains(domains []string) {
+	links.domains = domains
+}
+
+// safeLink returns a link when it is an https address on an allowed domain and cannot break out of a
+// Markdown link, or "" otherwise
+func (links *linkAllowlist) safeLink(link string) string {
+	if link == "" || strings.ContainsAny(link, unsafeLinkCharacters) {
+		return ""
+	}
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Scheme != "https" || parsed.User != nil {
+		return ""
+	}
+	domains := links.domains
+	if len(domains) == 0 {
+		domains = DefaultLinkDomains
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return link
+		}
+	}
+	return ""
+}
+
+// viewLink returns separator and the line linking to a page on Coral Markets, or "" when the link is
+// not allowed
+func (links *linkAllowlist) viewLink(separator, link string) string {
+	if link = links.safeLink(link); link == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s🔗 [View on Coral Markets](%s)", separator, link)
+}
+
+// markdownLink returns a title shortened to max characters and escaped, linked to link when it is
+// allowed
+func (links *linkAllowlist) markdownLink(title string, max int, link string) string {
+	title = EscapeMarkdown(truncate(title, max))
+	if link = links.safeLink(link); link == "" {
+		return title
+	}
+	return fmt.Sprintf("[%s](%s)", title, link)
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// JobMaxAttempts is how many times a job is run before it is given up
+const JobMaxAttempts = 5
+
+// Backoff between the runs of a failing job: the first retry waits jobBaseBackoff and every later
+// one four times longer than the previous, up to jobMaxBackoff. A job is rescheduled this far ahead
+// before it runs, so a job cut short by a crash runs again once the wait is over.
+const (
+	jobBaseBackoff = time.Minute
+	jobMaxBackoff  = time.Hour
+)
+
+// DefaultSchedulerJitter is the most a recovered or retried job is delayed by at random, so the jobs
+// that fell due while the bot was down do not all run on its first tick
+const DefaultSchedulerJitter = 30 * time.Second
+
+// JobHandler runs a scheduled job. A returned error has the job retried with backoff. A handler may
+// schedule its job again, for later, rather than have it deleted once it succeeded.
+type JobHandler func(ctx context.Context, job *models.ScheduledJob) error
+
+// SchedulerService defines the interface for the persistent scheduler of one-off jobs, such as
+// reminders, that must run even when the bot restarts before they are due
+type SchedulerService interface {
+	Handle(kind string, handler JobHandler)
+	Schedule(ctx context.Context, kind, key string, runAt time.Time) error
+	Cancel(ctx context.Context, kind, key string) error
+	Recover(ctx context.Context) (int, error)
+	ProcessDueJobs(ctx context.Context, now time.Time) int
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// SchedulerServiceImpl implements SchedulerService
+type SchedulerServiceImpl struct {
+	repo   repository.SubscriptionRepository
+	logger *utils.Logger
+
+	mutex    sync.RWMutex
+	handlers map[string]JobHandler
+	jitter   time.Duration
+	clockAndIDs
+}
+
+// NewSchedulerService creates a new scheduler
+func NewSchedulerService(repo repository.SubscriptionRepository, logger *utils.Logger) *SchedulerServiceImpl {
+	return &SchedulerServiceImpl{
+		repo:     repo,
+		logger:   logger,
+		handlers: make(map[string]JobHandler),
+		jitter:   DefaultSchedulerJitter,
+	}
+}
+
+// SetJitter sets the most recovered and retried jobs are delayed by at random, 0 for none
+func (service *SchedulerServiceImpl) SetJitter(jitter time.Duration) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.jitter = jitter
+}
+
+// Handle sets the handler running the jobs of a kind. Jobs of kinds without a handler stay stored
+// until one is set.
+func (service *SchedulerServiceImpl) Handle(kind string, handler JobHandler) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.handlers[kind] = handler
+}
+
+// handler returns the handler of a job kind
+func (service *SchedulerServiceImpl) handler(kind string) (JobHandler, bool) {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+	handler, ok := service.handlers[kind]
+	return handler, ok
+}
+
+// jobID returns the ID of the job of a kind for a key
+func jobID(kind, key string) string {
+	return kind + ":" + key
+}
+
+// Schedule stores a job to run at runAt. Scheduling a job again for the same key replaces it.
+func (service *SchedulerServiceImpl) Schedule(ctx context.Context, kind, key string, runAt time.Time) error {
+	job := &models.ScheduledJob{ID: jobID(kind, key), Kind: kind, Key: key, RunAt: runAt, CreatedAt: service.now()}
+	if err := service.repo.SaveScheduledJob(ctx, job); err != nil {
+		return fmt.Errorf("failed to schedule %s job: %w", kind, err)
+	}
+	return nil
+}
+
+// Cancel removes the pending job of a kind for a key, if there is one
+func (service *SchedulerServiceImpl) Cancel(ctx context.Context, kind, key string) error {
+	if _, err := service.repo.DeleteScheduledJob(ctx, jobID(kind, key)); err != nil {
+		return fmt.Errorf("failed to cancel %s job: %w", kind, err)
+	}
+	return nil
+}
+
+// Recover reloads the stored jobs at startup and returns how many are pending. Jobs that fell due
+// while the bot was down, including jobs a crash cut short, are spread over the jitter window from
+// now rather than all run at once.
+func (service *SchedulerServiceImpl) Recover(ctx context.Context) (int, error) {
+	jobs, err := service.repo.GetScheduledJobs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load scheduled jobs: %w", err)
+	}
+
+	now := service.now()
+	overdue := 0
+	for _, job := range jobs {
+		if _, ok := service.handler(job.Kind); !ok {
+			service.logger.Warning(fmt.Sprintf("Scheduled job %s has no handler and will not run", job.ID))
+		}
+		if job.RunAt.After(now) {
+			continue
+		}
+		overdue++
+		job.RunAt = service.jittered(now)
+		if err := service.repo.SaveScheduledJob(ctx, job); err != nil {
+			service.logger.Error(fmt.Sprintf("Failed to reschedule job %s: %v", job.ID, err))
+		}
+	}
+	if len(jobs) > 0 {
+		service.logger.Info(fmt.Sprintf("Recovered %d scheduled jobs, %d overdue", len(jobs), overdue))
+	}
+	return len(jobs), nil
+}
+
+// jittered returns a time up to the jitter window after at
+func (service *SchedulerServiceImpl) jittered(at time.Time) time.Time {
+	service.mutex.RLock()
+	jitter := service.jitter
+	service.mutex.RUnlock()
+	if jitter <= 0 {
+		return at
+	}
+	return at.Add(time.Duration(rand.Int63n(int64(jitter))))
+}
+
+// jobBackoff returns how long to wait before running again a job that was run attempts times
+func jobBackoff(attempts int) time.Duration {
+	backoff := jobBaseBackoff
+	for i := 1; i < attempts && backoff < jobMaxBackoff; i++ {
+		backoff *= 4
+	}
+	if backoff > jobMaxBackoff {
+		backoff = jobMaxBackoff
+	}
+	return backoff
+}
+
+// ProcessDueJobs runs every job that is due and has a handler, and returns how many succeeded. Each job
+// is rescheduled before it runs and deleted once it succeeded, so it runs at least once even when the
+// bot stops while it runs. Failed jobs are retried with backoff until JobMaxAttempts, then dropped.
+func (service *SchedulerServiceImpl) ProcessDueJobs(ctx context.Context, now time.Time) int {
+	jobs, err := service.repo.GetScheduledJobs(ctx)
+	if err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to get scheduled jobs: %v", err))
+		return 0
+	}
+
+	succeeded := 0
+	for _, job := range jobs {
+		if job.RunAt.After(now) {
+			break
+		}
+		handler, ok := service.handler(job.Kind)
+		if !ok {
+			continue
+		}
+
+		job.Attempts++
+		job.RunAt = service.jittered(now.Add(jobBackoff(job.Attempts)))
+		if err := service.repo.SaveScheduledJob(ctx, job); err != nil {
+			service.logger.Error(fmt.Sprintf("Failed to start job %s: %v", job.ID, err))
+			continue
+		}
+
+		if err := handler(ctx, job); err != nil {
+			job.LastError = err.Error()
+			if job.Attempts >= JobMaxAttempts {
+				service.logger.Error(fmt.Sprintf("Giving up job %s after %d attempts: %v", job.ID, job.Attempts, err))
+				service.delete(ctx, job)
+				continue
+			}
+			service.logger.Warning(fmt.Sprintf("Job %s failed, retrying at %s: %v", job.ID, job.RunAt.Format(time.RFC3339), err))
+			if err := service.repo.SaveScheduledJob(ctx, job); err != nil {
+				service.logger.Error(fmt.Sprintf("Failed to save job %s: %v", job.ID, err))
+			}
+			continue
+		}
+		succeeded++
+		service.finish(ctx, job)
+	}
+	return succeeded
+}
+
+// finish deletes a job that succeeded, unless its handler scheduled it again: that job is a new one,
+// not run yet
+func (service *SchedulerServiceImpl) finish(ctx context.Context, job *models.ScheduledJob) {
+	stored, err := service.repo.GetScheduledJob(ctx, job.ID)
+	if err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to get job %s: %v", job.ID, err))
+		return
+	}
+	if stored != nil && stored.Attempts == 0 {
+		return
+	}
+	service.delete(ctx, job)
+}
+
+// delete removes a job that is done with
+func (service *SchedulerServiceImpl) delete(ctx context.Context, job *models.ScheduledJob) {
+	if _, err := service.repo.DeleteScheduledJob(ctx, job.ID); err != nil {
+		service.logger.Error(fmt.Sprintf("Failed to delete job %s: %v", job.ID, err))
+	}
+}
+
+// Run recovers the stored jobs, then runs due jobs right away and on every tick until the context is
+// cancelled
+func (service *SchedulerServiceImpl) Run(ctx context.Context, interval time.Duration) {
+	if _, err := service.Recover(ctx); err != nil {
+		service.logger.Error(err.Error())
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	service.logger.Info(fmt.Sprintf("Job scheduler started (interval %s)", interval))
+	for {
+		if ran := service.ProcessDueJobs(ctx, service.now()); ran > 0 {
+			service.logger.Info(fmt.Sprintf("Ran %d scheduled jobs", ran))
+		}
+		select {
+		case <-ctx.Done():
+			service.logger.Info("Job scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
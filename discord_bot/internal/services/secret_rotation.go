@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// secretRotationTimeout bounds each read of the secrets and each rotation
+const secretRotationTimeout = 30 * time.Second
+
+// SecretsSource reads the bot's current secrets, keyed by their environment variable name. The secrets
+// providers of the config package implement it.
+type SecretsSource interface {
+	Name() string
+	Secrets(ctx context.Context) (map[string]string, error)
+}
+
+// SecretRotation applies the new value of a rotated secret. A returned error keeps the old value, and
+// the rotation is tried again on the next check.
+type SecretRotation func(ctx context.Context, value string) error
+
+// SecretRotator reads the secrets again at intervals and applies the ones whose value changed, so
+// credentials rotated in the secrets manager take effect without a restart
+type SecretRotator struct {
+	source SecretsSource
+	logger *utils.Logger
+
+	mutex     sync.Mutex
+	current   map[string]string
+	rotations map[string]SecretRotation
+}
+
+// NewSecretRotator creates a rotator for secrets whose values in use are current
+func NewSecretRotator(source SecretsSource, current map[string]string, logger *utils.Logger) *SecretRotator {
+	values := make(map[string]string, len(current))
+	for name, value := range current {
+		values[name] = value
+	}
+	return &SecretRotator{
+		source:    source,
+		logger:    logger,
+		current:   values,
+		rotations: make(map[string]SecretRotation),
+	}
+}
+
+// Handle sets how a secret is rotated. Changes to secrets without a rotation are logged and take effect
+// on the next restart.
+func (rotator *SecretRotator) Handle(name string, rotation SecretRotation) {
+	rotator.mutex.Lock()
+	defer rotator.mutex.Unlock()
+	rotator.rotations[name] = rotation
+}
+
+// Check reads the secrets and applies the ones that changed, and returns how many were rotated.
+// Secrets removed from the source, or emptied, keep their value.
+func (rotator *SecretRotator) Check(ctx context.Context) (int, error) {
+	secrets, err := rotator.source.Secrets(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read secrets from %s: %w", rotator.source.Name(), err)
+	}
+
+	rotator.mutex.Lock()
+	defer rotator.mutex.Unlock()
+
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rotated := 0
+	var failed []string
+	for _, name := range names {
+		value := secrets[name]
+		previous, known := rotator.current[name]
+		if !known || value == "" || value == previous {
+			continue
+		}
+		rotation, ok := rotator.rotations[name]
+		if !ok {
+			rotator.logger.Warning(fmt.Sprintf("Secret %s changed in %s; restart the bot to apply it", name, rotator.source.Name()))
+			rotator.current[name] = value
+			continue
+		}
+		if err := rotation(ctx, value); err != nil {
+			rotator.logger.Error(fmt.Sprintf("Failed to rotate secret %s, keeping the previous value: %v", name, err))
+			failed = append(failed, name)
+			continue
+		}
+		rotator.current[name] = value
+		rotated++
+		rotator.logger.Info(fmt.Sprintf("Rotated secret %s from %s", name, rotator.source.Name()))
+	}
+	if len(failed) > 0 {
+		return rotated, fmt.Errorf("failed to rotate %s", strings.Join(failed, ", "))
+	}
+	return rotated, nil
+}
+
+// Run checks the secrets on every tick until the context is cancelled
+func (rotator *SecretRotator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	rotator.logger.Info(fmt.Sprintf("Reading secrets from %s every %s", rotator.source.Name(), interval))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, secretRotationTimeout)
+		if _, err := rotator.Check(checkCtx); err != nil && !errors.Is(err, context.Canceled) {
+			rotator.logger.Error(err.Error())
+		}
+		cancel()
+	}
+}
+
+// RotateDiscordToken switches a session to a new bot token once Discord accepts it. REST calls use the
+// new token right away. The gateway connection is kept: when Discord closes it because the old token
+// was reset, the session reconnects with the new one, and the gateway monitor buffers messages
+// meanwhile.
+func RotateDiscordToken(ctx context.Context, session *discordgo.Session, token string) error {
+	token = "Bot " + strings.TrimPrefix(token, "Bot ")
+	probe, err := discordgo.New(token)
+	if err != nil {
+		return fmt.Errorf("failed to create Discord session: %w", err)
+	}
+	probe.Client = session.Client
+	probe.UserAgent = session.UserAgent
+	current, err := probe.User("@me", discordgo.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("discord rejected the new token: %w", err)
+	}
+	if session.State != nil && session.State.User != nil && session.State.User.ID != current.ID {
+		return fmt.Errorf("the new token belongs to %s, not the bot %s", current.ID, session.State.User.ID)
+	}
+
+	session.Lock()
+	session.Token = token
+	session.Identify.Token = token
+	session.Unlock()
+	return nil
+}
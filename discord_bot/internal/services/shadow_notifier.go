@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// maxShadowPreviewLength is the longest preview of a message withheld by shadow mode, in characters
+const maxShadowPreviewLength = 200
+
+// ShadowNotifier implements Notifier for shadow mode: messages to channels in shadow mode, or every
+// message when the whole bot is in it, are logged with their recipient instead of sent. Everything else
+// goes to the notifier it wraps.
+type ShadowNotifier struct {
+	repo   repository.SubscriptionRepository
+	next   Notifier
+	global bool // every message is withheld, whatever the channel settings
+	logger *utils.Logger
+}
+
+// NewShadowNotifier creates a notifier withholding the messages of channels in shadow mode, or every
+// message when global is set, and sending the rest with next
+func NewShadowNotifier(repo repository.SubscriptionRepository, next Notifier, global bool, logger *utils.Logger) *ShadowNotifier {
+	return &ShadowNotifier{repo: repo, next: next, global: global, logger: logger}
+}
+
+// SendChannelMessage logs the message when the bot or the channel is in shadow mode, and posts it with
+// the wrapped notifier otherwise. When the channel config cannot be read the message is posted.
+func (n *ShadowNotifier) SendChannelMessage(ctx context.Context, channelID string, message string) error {
+	shadow := n.global
+	if !shadow {
+		config, err := n.repo.GetChannelConfig(ctx, channelID)
+		if err != nil {
+			n.logger.Warning(fmt.Sprintf("Failed to check shadow mode of channel %s, sending: %v", channelID, err))
+		}
+		shadow = err == nil && config != nil && config.ShadowMode
+	}
+	if shadow {
+		n.logger.Info(fmt.Sprintf("Shadow mode: would have sent to channel %s: %s", channelID, ShadowPreview(message)))
+		return nil
+	}
+	return n.next.SendChannelMessage(ctx, channelID, message)
+}
+
+// SendDirectMessage logs the message when the bot is in shadow mode, and sends it with the wrapped
+// notifier otherwise
+func (n *ShadowNotifier) SendDirectMessage(ctx context.Context, discordUserID string, message string) error {
+	if n.global {
+		n.logger.Info(fmt.Sprintf("Shadow mode: would have sent to user %s: %s", discordUserID, ShadowPreview(message)))
+		return nil
+	}
+	return n.next.SendDirectMessage(ctx, discordUserID, message)
+}
+
+// ShadowPreview returns the start of a message withheld by shadow mode on one line, for logs and
+// delivery reports
+func ShadowPreview(message string) string {
+	preview := strings.Join(strings.Fields(message), " ")
+	if runes := []rune(preview); len(runes) > maxShadowPreviewLength {
+		preview = string(runes[:maxShadowPreviewLength-1]) + "…"
+	}
+	return preview
+}
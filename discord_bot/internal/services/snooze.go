@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxSnooze is the longest a user may pause their notification DMs for
+const MaxSnooze = 30 * 24 * time.Hour
+
+// ErrInvalidSnooze is returned for snooze durations that are not positive or longer than MaxSnooze
+var ErrInvalidSnooze = errors.New("invalid snooze duration")
+
+// SnoozeNotifications pauses a user's notification DMs for a duration and returns when they resume.
+// Snoozing again replaces the previous end time.
+func (service *SubscriptionServiceImpl) SnoozeNotifications(ctx context.Context, discordUserID string, duration time.Duration) (time.Time, error) {
+	if duration <= 0 || duration > MaxSnooze {
+		return time.Time{}, fmt.Errorf("%w: snooze for up to %d days", ErrInvalidSnooze, int(MaxSnooze.Hours()/24))
+	}
+
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get subscription: %w", err)
+	}
+	subscription.SnoozedUntil = service.now().Add(duration).Truncate(time.Second)
+	if err := service.repo.SaveSubscription(ctx, subscription); err != nil {
+		return time.Time{}, err
+	}
+	return subscription.SnoozedUntil, nil
+}
+
+// ResumeNotifications ends a user's snooze early and reports whether they were snoozed
+func (service *SubscriptionServiceImpl) ResumeNotifications(ctx context.Context, discordUserID string) (bool, error) {
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get subscription: %w", err)
+	}
+	if !subscription.Snoozed(service.now()) {
+		return false, nil
+	}
+	subscription.SnoozedUntil = time.Time{}
+	return true, service.repo.SaveSubscription(ctx, subscription)
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// StorageDriverFile is the storage driver of a FileSubscriptionRepository
+const StorageDriverFile = "file"
+
+var (
+	// ErrInvalidStorageMigration is returned for migrations to an unknown driver or without a path
+	ErrInvalidStorageMigration = errors.New("invalid storage migration")
+	// ErrMigrationTargetExists is returned when the target already holds data, which a migration never merges into
+	ErrMigrationTargetExists = errors.New("migration target already exists")
+	// ErrMigrationMismatch is returned when the target does not hold every record copied to it
+	ErrMigrationMismatch = errors.New("migrated data does not match the source")
+)
+
+// StorageMigrationService copies the bot's data from its running repository to another backend
+type StorageMigrationService interface {
+	Migrate(ctx context.Context, target repository.SubscriptionRepository) (*models.StorageMigration, error)
+	MigrateToFile(ctx context.Context, path string) (*models.StorageMigration, error)
+}
+
+// StorageMigrationServiceImpl implements StorageMigrationService
+type StorageMigrationServiceImpl struct {
+	repo   repository.SubscriptionRepository
+	logger *utils.Logger
+	clockAndIDs
+}
+
+// NewStorageMigrationService creates a storage migration service copying from repo
+func NewStorageMigrationService(repo repository.SubscriptionRepository, logger *utils.Logger) *StorageMigrationServiceImpl {
+	return &StorageMigrationServiceImpl{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// MigrateToFile copies the data to a new data file for STORAGE_DRIVER=file. The file must not exist yet.
+func (service *StorageMigrationServiceImpl) MigrateToFile(ctx context.Context, path string) (*models.StorageMigration, error) {
+	if path == "" {
+		return nil, fmt.Errorf("%w: path required", ErrInvalidStorageMigration)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrMigrationTargetExists, path)
+	}
+	target, err := repository.NewFileSubscriptionRepository(path, service.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	migration, err := service.Migrate(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if err := target.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	migration.Driver, migration.Path = StorageDriverFile, path
+	return migration, nil
+}
+
+// Migrate copies every subscription, channel config and webhook registration to target, then reads
+// them back and fails with ErrMigrationMismatch unless target holds exactly the records of the source
+func (service *StorageMigrationServiceImpl) Migrate(ctx context.Context, target repository.SubscriptionRepository) (*models.StorageMigration, error) {
+	migration := &models.StorageMigration{StartedAt: service.now()}
+
+	var subscriptions []*models.Subscription
+	var configs []*models.ChannelConfig
+	var registrations []*models.WebhookRegistration
+	err := service.repo.WithTx(ctx, func(tx repository.SubscriptionRepository) error {
+		var err error
+		if subscriptions, err = tx.GetAllSubscriptions(ctx); err != nil {
+			return fmt.Errorf("failed to get subscriptions: %w", err)
+		}
+		if configs, err = tx.GetAllChannelConfigs(ctx); err != nil {
+			return fmt.Errorf("failed to get channel configs: %w", err)
+		}
+		if registrations, err = tx.GetAllWebhookRegistrations(ctx); err != nil {
+			return fmt.Errorf("failed to get webhook registrations: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = target.WithTx(ctx, func(tx repository.SubscriptionRepository) error {
+		for _, subscription := range subscriptions {
+			if err := tx.SaveSubscription(ctx, subscription); err != nil {
+				return fmt.Errorf("failed to copy the subscription of user %s: %w", subscription.DiscordUserID, err)
+			}
+		}
+		for _, config := range configs {
+			if err := tx.SaveChannelConfig(ctx, config); err != nil {
+				return fmt.Errorf("failed to copy the config of channel %s: %w", config.ChannelID, err)
+			}
+		}
+		for _, registration := range registrations {
+			if err := tx.SaveWebhookRegistration(ctx, registration); err != nil {
+				return fmt.Errorf("failed to copy webhook registration %s: %w", registration.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.verify(ctx, target, migration, subscriptions, configs, registrations); err != nil {
+		return nil, err
+	}
+	migration.FinishedAt = service.now()
+	service.logger.Info(fmt.Sprintf("Migrated %d subscriptions, %d channel configs and %d webhook registrations in %s",
+		migration.Subscriptions, migration.ChannelConfigs, migration.WebhookRegistrations, migration.FinishedAt.Sub(migration.StartedAt).Round(time.Millisecond)))
+	return migration, nil
+}
+
+// verify reads the migrated records back from target, counts them into migration and checks every
+// source record is there
+func (service *StorageMigrationServiceImpl) verify(ctx context.Context, target repository.SubscriptionRepository, migration *models.StorageMigration,
+	subscriptions []*models.Subscription, configs []*models.ChannelConfig, registrations []*models.WebhookRegistration) error {
+	copiedSubscriptions, err := target.GetAllSubscriptions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read back subscriptions: %w", err)
+	}
+	copiedConfigs, err := target.GetAllChannelConfigs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read back channel configs: %w", err)
+	}
+	copiedRegistrations, err := target.GetAllWebhookRegistrations(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read back webhook registrations: %w", err)
+	}
+	migration.Subscriptions = len(copiedSubscriptions)
+	migration.ChannelConfigs = len(copiedConfigs)
+	migration.WebhookRegistrations = len(copiedRegistrations)
+
+	if err := compareIDs("subscriptions", subscriptions, copiedSubscriptions, func(s *models.Subscription) string { return s.DiscordUserID }); err != nil {
+		return err
+	}
+	if err := compareIDs("channel configs", configs, copiedConfigs, func(c *models.ChannelConfig) string { return c.ChannelID }); err != nil {
+		return err
+	}
+	return compareIDs("webhook registrations", registrations, copiedRegistrations, func(r *models.WebhookRegistration) string { return r.ID })
+}
+
+// compareIDs checks that copied holds exactly the records of source, by ID
+func compareIDs[T any](kind string, source, copied []T, id func(T) string) error {
+	if len(copied) != len(source) {
+		return fmt.Errorf("%w: %d of %d %s in the target", ErrMigrationMismatch, len(copied), len(source), kind)
+	}
+	found := make(map[string]bool, len(copied))
+	for _, record := range copied {
+		found[id(record)] = true
+	}
+	for _, record := range source {
+		if !found[id(record)] {
+			return fmt.Errorf("%w: %s %s missing from the target", ErrMigrationMismatch, kind, id(record))
+		}
+	}
+	return nil
+}
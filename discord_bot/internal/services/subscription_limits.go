@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// ErrInvalidLimitOverride is returned for limit overrides with an unknown subject, no subject ID or a
+// negative limit
+var ErrInvalidLimitOverride = errors.New("invalid limit override")
+
+// Kinds of subscription counted against a limit
+const (
+	limitMarkets  = "markets"
+	limitCreators = "creators"
+	limitOutcomes = "outcomes"
+	limitChannels = "channels"
+
+	limitWatchlists       = "watchlists"
+	limitWatchlistMarkets = "watchlist markets"
+	limitRoutingRules     = "routing rules"
+)
+
+// LimitExceededError is returned when a subscription or channel config would take a user or guild
+// past its limit. Its message is meant for the user.
+type LimitExceededError struct {
+	Kind  string // markets, creators, outcomes, channels, watchlists, watchlist markets or routing rules
+	Limit int
+}
+
+func (err *LimitExceededError) Error() string {
+	switch err.Kind {
+	case limitChannels:
+		return fmt.Sprintf("This server already has alerts set up in %d channels, the most allowed. Ask the bot's maintainers to raise the limit.", err.Limit)
+	case limitWatchlists:
+		return fmt.Sprintf("You already have %d watchlists, the most allowed. Delete one before creating another.", err.Limit)
+	case limitWatchlistMarkets:
+		return fmt.Sprintf("This watchlist already has %d markets, the most allowed. Remove one before adding another.", err.Limit)
+	case limitRoutingRules:
+		return fmt.Sprintf("This server already has %d routing rules, the most allowed. Remove one before adding another.", err.Limit)
+	}
+	return fmt.Sprintf("You are already subscribed to %d %s, the most allowed. Unsubscribe from one before adding another.", err.Limit, err.Kind)
+}
+
+// SetSubscriptionLimits sets the default limits of every user and guild; zero fields are unlimited
+func (service *SubscriptionServiceImpl) SetSubscriptionLimits(limits models.SubscriptionLimits) {
+	service.limits = limits
+}
+
+// DefaultSubscriptionLimits returns the limits of users and guilds without an override
+func (service *SubscriptionServiceImpl) DefaultSubscriptionLimits() models.SubscriptionLimits {
+	return service.limits
+}
+
+// GetSubscriptionLimits returns the limits of a user or guild, with its override applied
+func (service *SubscriptionServiceImpl) GetSubscriptionLimits(ctx context.Context, subject, subjectID string) (models.SubscriptionLimits, error) {
+	override, err := service.repo.GetLimitOverride(ctx, subject, subjectID)
+	if err != nil {
+		return models.SubscriptionLimits{}, fmt.Errorf("failed to get limit override: %w", err)
+	}
+	return override.Apply(service.limits), nil
+}
+
+// SetLimitOverride replaces the limit override of a user or guild
+func (service *SubscriptionServiceImpl) SetLimitOverride(ctx context.Context, override *models.LimitOverride, actor string) (*models.LimitOverride, error) {
+	if override.Subject != models.LimitSubjectUser && override.Subject != models.LimitSubjectGuild {
+		return nil, fmt.Errorf("%w: subject must be user or guild", ErrInvalidLimitOverride)
+	}
+	if override.SubjectID == "" {
+		return nil, fmt.Errorf("%w: subject ID is required", ErrInvalidLimitOverride)
+	}
+	for _, limit := range []*int{override.MaxMarkets, override.MaxCreators, override.MaxOutcomes, override.MaxChannels} {
+		if limit != nil && *limit < 0 {
+			return nil, fmt.Errorf("%w: limits cannot be negative", ErrInvalidLimitOverride)
+		}
+	}
+
+	previous, err := service.repo.GetLimitOverride(ctx, override.Subject, override.SubjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get limit override: %w", err)
+	}
+	override.UpdatedBy, override.UpdatedAt = actor, service.now()
+	if err := service.repo.SaveLimitOverride(ctx, override); err != nil {
+		return nil, fmt.Errorf("failed to save limit override: %w", err)
+	}
+
+	resourceID := override.Subject + ":" + override.SubjectID
+	if previous == nil {
+		service.recordAudit(ctx, actor, models.AuditActionCreate, models.AuditResourceLimitOverride, resourceID, "", nil, override)
+	} else {
+		service.recordAudit(ctx, actor, models.AuditActionUpdate, models.AuditResourceLimitOverride, resourceID, "", previous, override)
+	}
+	return override, nil
+}
+
+// RemoveLimitOverride returns a user or guild to the default limits and reports whether it had an override
+func (service *SubscriptionServiceImpl) RemoveLimitOverride(ctx context.Context, subject, subjectID, actor string) (bool, error) {
+	previous, err := service.repo.GetLimitOverride(ctx, subject, subjectID)
+	if err != nil || previous == nil {
+		return false, err
+	}
+	if _, err := service.repo.DeleteLimitOverride(ctx, subject, subjectID); err != nil {
+		return false, fmt.Errorf("failed to delete limit override: %w", err)
+	}
+	service.recordAudit(ctx, actor, models.AuditActionDelete, models.AuditResourceLimitOverride, subject+":"+subjectID, "", previous, nil)
+	return true, nil
+}
+
+// ListLimitOverrides returns every limit override
+func (service *SubscriptionServiceImpl) ListLimitOverrides(ctx context.Context) ([]*models.LimitOverride, error) {
+	return service.repo.GetLimitOverrides(ctx)
+}
+
+// checkUserLimit returns a LimitExceededError when a user who already has count subscriptions of a
+// kind may not add another
+func (service *SubscriptionServiceImpl) checkUserLimit(ctx context.Context, discordUserID, kind string, count int) error {
+	limits, err := service.GetSubscriptionLimits(ctx, models.LimitSubjectUser, discordUserID)
+	if err != nil {
+		return err
+	}
+	limit := map[string]int{
+		limitMarkets:  limits.MaxMarkets,
+		limitCreators: limits.MaxCreators,
+		limitOutcomes: limits.MaxOutcomes,
+	}[kind]
+	if limit > 0 && count >= limit {
+		return &LimitExceededError{Kind: kind, Limit: limit}
+	}
+	return nil
+}
+
+// checkGuildChannelLimit returns a LimitExceededError when a channel config would be the first of its
+// channel in a guild that already configures as many channels as it may
+func (service *SubscriptionServiceImpl) checkGuildChannelLimit(ctx context.Context, config *models.ChannelConfig) error {
+	if config.GuildID == "" {
+		return nil
+	}
+	limits, err := service.GetSubscriptionLimits(ctx, models.LimitSubjectGuild, config.GuildID)
+	if err != nil || limits.MaxChannels <= 0 {
+		return err
+	}
+
+	configs, err := service.repo.GetAllChannelConfigs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get channel configs: %w", err)
+	}
+	channels := 0
+	for _, existing := range configs {
+		if existing.ChannelID == config.ChannelID {
+			return nil // already configured, so not a new channel
+		}
+		if existing.GuildID == config.GuildID {
+			channels++
+		}
+	}
+	if channels >= limits.MaxChannels {
+		return &LimitExceededError{Kind: limitChannels, Limit: limits.MaxChannels}
+	}
+	return nil
+}
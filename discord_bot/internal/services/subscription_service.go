@@ -1,9 +1,10 @@
 package services
 
 import (
-	"crypto/rand"
-	"encoding/hex"
+	"context"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"coral-bot/discord_bot/internal/models"
@@ -13,34 +14,122 @@ import (
 
 // SubscriptionService defines the interface for subscription-related operations
 type SubscriptionService interface {
-	SubscribeToMarket(discordUserID, marketID string) error
-	UnsubscribeFromMarket(discordUserID, marketID string) error
-	SubscribeToCreator(discordUserID, creator string) error
-	UnsubscribeFromCreator(discordUserID, creator string) error
-	GetUserSubscriptions(discordUserID string) (*models.Subscription, error)
-	GetAllSubscriptions() ([]*models.Subscription, error)
+	SubscribeToMarket(ctx context.Context, discordUserID, marketID string) error
+	UnsubscribeFromMarket(ctx context.Context, discordUserID, marketID string) error
+	SubscribeToCreator(ctx context.Context, discordUserID, creator string) error
+	UnsubscribeFromCreator(ctx context.Context, discordUserID, creator string) error
+	SubscribeToOutcome(ctx context.Context, discordUserID, marketID, outcome string, minChange float64) error
+	UnsubscribeFromOutcome(ctx context.Context, discordUserID, marketID, outcome string) error
+	SetMinBuyAmount(ctx context.Context, discordUserID string, amount float64) error
+	SetUserTimezone(ctx context.Context, discordUserID, zone string) error
+	SnoozeNotifications(ctx context.Context, discordUserID string, duration time.Duration) (time.Time, error)
+	SetMarketActivity(ctx context.Context, discordUserID string, enabled bool) error
+	ResumeNotifications(ctx context.Context, discordUserID string) (bool, error)
+	GetUserSubscriptions(ctx context.Context, discordUserID string) (*models.Subscription, error)
+	GetAllSubscriptions(ctx context.Context) ([]*models.Subscription, error)
 
 	// Channel configuration
-	UpdateChannelConfig(config *models.ChannelConfig) error
-	GetChannelConfig(channelID string) (*models.ChannelConfig, error)
-	GetAllChannelConfigs() ([]*models.ChannelConfig, error)
+	UpdateChannelConfig(ctx context.Context, config *models.ChannelConfig, actor string) error
+	GetChannelConfig(ctx context.Context, channelID string) (*models.ChannelConfig, error)
+	GetAllChannelConfigs(ctx context.Context) ([]*models.ChannelConfig, error)
+	SubscribeChannelToMarket(ctx context.Context, channelID, marketID, actor string) error
+	UnsubscribeChannelFromMarket(ctx context.Context, channelID, marketID, actor string) error
+	ApplyChannelSettings(ctx context.Context, channelID, guildID string, settings *models.ChannelSettings, actor string) (*models.ChannelConfig, error)
+	CopyChannelSettings(ctx context.Context, sourceChannelID, targetChannelID, guildID, actor string) (*models.ChannelConfig, error)
+	ApplyChannelSettingsBulk(ctx context.Context, changes []ChannelSettingsChange, actor string) ([]*models.ChannelConfig, error)
+	SetChannelDigest(ctx context.Context, channelID, guildID, mode, actor string) error
+	SetChannelClosingSoon(ctx context.Context, channelID, guildID string, hours int, actor string) error
+	SetChannelTimezone(ctx context.Context, channelID, guildID, zone, actor string) error
+	SetChannelCrosspost(ctx context.Context, channelID, guildID string, enabled bool, actor string) error
+	SetChannelBoard(ctx context.Context, channelID, guildID string, enabled bool, actor string) error
+	SetChannelLiquidityAlerts(ctx context.Context, channelID, guildID string, enabled bool, actor string) error
+	SetChannelShadowMode(ctx context.Context, channelID, guildID string, enabled bool, actor string) error
+	SetChannelCalendarDigests(ctx context.Context, channelID, guildID string, enabled bool, actor string) error
+	SetChannelPing(ctx context.Context, channelID, guildID, roleID string, events []string, actor string) error
+	SetChannelQuietHours(ctx context.Context, channelID, guildID, start, end, actor string) error
+
+	// Guild configuration
+	UpdateGuildConfig(ctx context.Context, config *models.GuildConfig) error
+	GetGuildConfig(ctx context.Context, guildID string) (*models.GuildConfig, error)
+	GetAllGuildConfigs(ctx context.Context) ([]*models.GuildConfig, error)
+	SetGuildUpdateIntervals(ctx context.Context, guildID string, intervals *models.UpdateIntervals) (*models.GuildConfig, error)
+	SetGuildBranding(ctx context.Context, guildID string, branding *models.GuildBranding) (*models.GuildConfig, error)
+
+	// Cleanup after deleted channels and guilds the bot left
+	RemoveChannel(ctx context.Context, channelID, actor string) error
+	RemoveGuild(ctx context.Context, guildID string, channelIDs []string, actor string) error
+
+	// Category routing
+	SetCategoryRoute(ctx context.Context, guildID, category, channelID, actor string) (*models.CategoryRoute, error)
+	RemoveCategoryRoute(ctx context.Context, guildID, category, actor string) (bool, error)
+	GetCategoryRoutes(ctx context.Context, guildID string) ([]*models.CategoryRoute, error)
+	GetAllCategoryRoutes(ctx context.Context) ([]*models.CategoryRoute, error)
+
+	// Routing rules
+	AddRoutingRule(ctx context.Context, rule *models.RoutingRule, actor string) (*models.RoutingRule, error)
+	MoveRoutingRule(ctx context.Context, guildID, id string, position int, actor string) (*models.RoutingRule, error)
+	RemoveRoutingRule(ctx context.Context, guildID, id, actor string) (bool, error)
+	GetRoutingRules(ctx context.Context, guildID string) ([]*models.RoutingRule, error)
+	GetAllRoutingRules(ctx context.Context) ([]*models.RoutingRule, error)
+
+	// Subscription limits
+	DefaultSubscriptionLimits() models.SubscriptionLimits
+	GetSubscriptionLimits(ctx context.Context, subject, subjectID string) (models.SubscriptionLimits, error)
+	SetLimitOverride(ctx context.Context, override *models.LimitOverride, actor string) (*models.LimitOverride, error)
+	RemoveLimitOverride(ctx context.Context, subject, subjectID, actor string) (bool, error)
+	ListLimitOverrides(ctx context.Context) ([]*models.LimitOverride, error)
+
+	// Watchlists
+	CreateWatchlist(ctx context.Context, discordUserID, name string) (*models.Watchlist, error)
+	DeleteWatchlist(ctx context.Context, discordUserID, name string) (bool, error)
+	AddToWatchlist(ctx context.Context, discordUserID, name, marketID string) (*models.Watchlist, error)
+	RemoveFromWatchlist(ctx context.Context, discordUserID, name, marketID string) (bool, error)
+	UpdateWatchlistSettings(ctx context.Context, discordUserID, name string, settings WatchlistSettings) (*models.Watchlist, error)
+	GetWatchlist(ctx context.Context, discordUserID, name string) (*models.Watchlist, error)
+	GetWatchlists(ctx context.Context, discordUserID string) ([]*models.Watchlist, error)
+	GetAllWatchlists(ctx context.Context) ([]*models.Watchlist, error)
+	ShareWatchlist(ctx context.Context, discordUserID, name string) (*models.WatchlistShare, error)
+	ImportWatchlist(ctx context.Context, discordUserID, code, name string) (*models.Watchlist, error)
 
 	// Notification logic
 	ShouldNotifyUser(subscription *models.Subscription, market *models.Market) bool
-	SendNotificationToUser(discordUserID string, message string) error
+	ShouldNotifyOutcomeSubscriber(subscription *models.Subscription, market *models.Market, eventType string, previous *models.MarketSnapshot) bool
+	UpdateOutcomeBaselines(ctx context.Context, discordUserID string, market *models.Market, notified bool) error
+	RecordMarketSnapshot(ctx context.Context, market *models.Market) (*models.MarketSnapshot, error)
+	GetMarketSnapshot(ctx context.Context, marketID string) (*models.MarketSnapshot, error)
+	GetMarketHistory(ctx context.Context, marketID string, since time.Time) ([]*models.MarketSnapshot, error)
+	MarkMarketResolved(ctx context.Context, marketID string, resolvedAt time.Time) error
+	IsMarketResolved(ctx context.Context, marketID string) (bool, error)
+	CleanUpCancelledMarket(ctx context.Context, marketID, actor string) (*models.MarketCleanup, error)
+	SendNotificationToUser(ctx context.Context, discordUserID string, message string) error
+
+	// Leaderboard
+	GetLeaderboard(ctx context.Context, limit int) (*models.Leaderboard, error)
 
 	// Webhook registration management
-	RegisterWebhook(registration *models.WebhookRegistration) (*models.WebhookRegistration, error)
-	UnregisterWebhook(id string) error
-	GetWebhookRegistration(id string) (*models.WebhookRegistration, error)
-	ListWebhookRegistrations() ([]*models.WebhookRegistration, error)
-	ListWebhookRegistrationsByChannel(channelID string) ([]*models.WebhookRegistration, error)
+	RegisterWebhook(ctx context.Context, registration *models.WebhookRegistration, actor string) (*models.WebhookRegistration, error)
+	UnregisterWebhook(ctx context.Context, id, actor string) error
+	GetWebhookRegistration(ctx context.Context, id string) (*models.WebhookRegistration, error)
+	ListWebhookRegistrations(ctx context.Context) ([]*models.WebhookRegistration, error)
+	ListWebhookRegistrationsByChannel(ctx context.Context, channelID string) ([]*models.WebhookRegistration, error)
+
+	// Audit log
+	GetAuditLog(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEntry, error)
 }
 
+// DefaultLeaderboardSize is the number of markets and creators ranked when no limit is given
+const DefaultLeaderboardSize = 10
+
+// DefaultOutcomeMinChange is the probability move, in percentage points, that triggers an outcome notification
+const DefaultOutcomeMinChange = 5.0
+
 // SubscriptionServiceImpl implements SubscriptionService
 type SubscriptionServiceImpl struct {
     repo   repository.SubscriptionRepository
     logger *utils.Logger
+    limits models.SubscriptionLimits // defaults, zero until SetSubscriptionLimits
+    guilds ChannelGuildResolver      // nil saves channel configs without a guild as they are
+    clockAndIDs
 }
 
 // NewSubscriptionService creates a new subscription service
@@ -51,9 +140,19 @@ func NewSubscriptionService(repo repository.SubscriptionRepository, logger *util
     }
 }
 
+// withTx runs fn on a copy of the service whose repository calls, including those of the service
+// methods fn calls, all belong to one transaction
+func (service *SubscriptionServiceImpl) withTx(ctx context.Context, fn func(tx *SubscriptionServiceImpl) error) error {
+	return service.repo.WithTx(ctx, func(repo repository.SubscriptionRepository) error {
+		tx := *service
+		tx.repo = repo
+		return fn(&tx)
+	})
+}
+
 // SubscribeToMarket subscribes a user to a market
-func (service *SubscriptionServiceImpl) SubscribeToMarket(discordUserID, marketID string) error {
-    subscription, err := service.repo.GetSubscription(discordUserID)
+func (service *SubscriptionServiceImpl) SubscribeToMarket(ctx context.Context, discordUserID, marketID string) error {
+    subscription, err := service.repo.GetSubscription(ctx, discordUserID)
 	if err != nil {
 		return fmt.Errorf("failed to get subscription: %w", err)
 	}
@@ -65,15 +164,23 @@ func (service *SubscriptionServiceImpl) SubscribeToMarket(discordUserID, marketI
 		}
 	}
 
+	if err := service.checkUserLimit(ctx, discordUserID, limitMarkets, len(subscription.SubscribedMarkets)); err != nil {
+		return err
+	}
+
 	// Add to subscribed markets
 	subscription.SubscribedMarkets = append(subscription.SubscribedMarkets, marketID)
 
-    return service.repo.SaveSubscription(subscription)
+    if err := service.repo.SaveSubscription(ctx, subscription); err != nil {
+        return err
+    }
+    service.recordChurn(ctx, models.AnalyticsSubscribe, "market", discordUserID)
+    return nil
 }
 
 // UnsubscribeFromMarket unsubscribes a user from a market
-func (service *SubscriptionServiceImpl) UnsubscribeFromMarket(discordUserID, marketID string) error {
-    subscription, err := service.repo.GetSubscription(discordUserID)
+func (service *SubscriptionServiceImpl) UnsubscribeFromMarket(ctx context.Context, discordUserID, marketID string) error {
+    subscription, err := service.repo.GetSubscription(ctx, discordUserID)
 	if err != nil {
 		return fmt.Errorf("failed to get subscription: %w", err)
 	}
@@ -86,13 +193,20 @@ func (service *SubscriptionServiceImpl) UnsubscribeFromMarket(discordUserID, mar
 		}
 	}
 
+	removed := len(newMarkets) < len(subscription.SubscribedMarkets)
 	subscription.SubscribedMarkets = newMarkets
-    return service.repo.SaveSubscription(subscription)
+    if err := service.repo.SaveSubscription(ctx, subscription); err != nil {
+        return err
+    }
+    if removed {
+        service.recordChurn(ctx, models.AnalyticsUnsubscribe, "market", discordUserID)
+    }
+    return nil
 }
 
 // SubscribeToCreator subscribes a user to a creator
-func (service *SubscriptionServiceImpl) SubscribeToCreator(discordUserID, creator string) error {
-    subscription, err := service.repo.GetSubscription(discordUserID)
+func (service *SubscriptionServiceImpl) SubscribeToCreator(ctx context.Context, discordUserID, creator string) error {
+    subscription, err := service.repo.GetSubscription(ctx, discordUserID)
 	if err != nil {
 		return fmt.Errorf("failed to get subscription: %w", err)
 	}
@@ -104,15 +218,23 @@ func (service *SubscriptionServiceImpl) SubscribeToCreator(discordUserID, creato
 		}
 	}
 
+	if err := service.checkUserLimit(ctx, discordUserID, limitCreators, len(subscription.SubscribedCreators)); err != nil {
+		return err
+	}
+
 	// Add to subscribed creators
 	subscription.SubscribedCreators = append(subscription.SubscribedCreators, creator)
 
-    return service.repo.SaveSubscription(subscription)
+    if err := service.repo.SaveSubscription(ctx, subscription); err != nil {
+        return err
+    }
+    service.recordChurn(ctx, models.AnalyticsSubscribe, "creator", discordUserID)
+    return nil
 }
 
 // UnsubscribeFromCreator unsubscribes a user from a creator
-func (service *SubscriptionServiceImpl) UnsubscribeFromCreator(discordUserID, creator string) error {
-    subscription, err := service.repo.GetSubscription(discordUserID)
+func (service *SubscriptionServiceImpl) UnsubscribeFromCreator(ctx context.Context, discordUserID, creator string) error {
+    subscription, err := service.repo.GetSubscription(ctx, discordUserID)
 	if err != nil {
 		return fmt.Errorf("failed to get subscription: %w", err)
 	}
@@ -125,33 +247,249 @@ func (service *SubscriptionServiceImpl) UnsubscribeFromCreator(discordUserID, cr
 		}
 	}
 
+	removed := len(newCreators) < len(subscription.SubscribedCreators)
 	subscription.SubscribedCreators = newCreators
-    return service.repo.SaveSubscription(subscription)
+    if err := service.repo.SaveSubscription(ctx, subscription); err != nil {
+        return err
+    }
+    if removed {
+        service.recordChurn(ctx, models.AnalyticsUnsubscribe, "creator", discordUserID)
+    }
+    return nil
+}
+
+// SubscribeToOutcome subscribes a user to a single outcome of a market
+func (service *SubscriptionServiceImpl) SubscribeToOutcome(ctx context.Context, discordUserID, marketID, outcome string, minChange float64) error {
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	if minChange <= 0 {
+		minChange = DefaultOutcomeMinChange
+	}
+
+	// Update the threshold if already subscribed
+	for i, existing := range subscription.SubscribedOutcomes {
+		if existing.MarketID == marketID && strings.EqualFold(existing.Outcome, outcome) {
+			subscription.SubscribedOutcomes[i].MinChange = minChange
+			return service.repo.SaveSubscription(ctx, subscription)
+		}
+	}
+
+	if err := service.checkUserLimit(ctx, discordUserID, limitOutcomes, len(subscription.SubscribedOutcomes)); err != nil {
+		return err
+	}
+	snapshot, err := service.repo.GetMarketSnapshot(ctx, marketID)
+	if err != nil {
+		return fmt.Errorf("failed to get market snapshot: %w", err)
+	}
+	subscription.SubscribedOutcomes = append(subscription.SubscribedOutcomes, models.OutcomeSubscription{
+		MarketID:  marketID,
+		Outcome:   outcome,
+		MinChange: minChange,
+		Baseline:  snapshotPercentage(snapshot, outcome),
+	})
+
+	if err := service.repo.SaveSubscription(ctx, subscription); err != nil {
+		return err
+	}
+	service.recordChurn(ctx, models.AnalyticsSubscribe, "outcome", discordUserID)
+	return nil
+}
+
+// UnsubscribeFromOutcome unsubscribes a user from a single outcome of a market
+func (service *SubscriptionServiceImpl) UnsubscribeFromOutcome(ctx context.Context, discordUserID, marketID, outcome string) error {
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	newOutcomes := []models.OutcomeSubscription{}
+	for _, existing := range subscription.SubscribedOutcomes {
+		if existing.MarketID != marketID || !strings.EqualFold(existing.Outcome, outcome) {
+			newOutcomes = append(newOutcomes, existing)
+		}
+	}
+
+	removed := len(newOutcomes) < len(subscription.SubscribedOutcomes)
+	subscription.SubscribedOutcomes = newOutcomes
+	if err := service.repo.SaveSubscription(ctx, subscription); err != nil {
+		return err
+	}
+	if removed {
+		service.recordChurn(ctx, models.AnalyticsUnsubscribe, "outcome", discordUserID)
+	}
+	return nil
+}
+
+// SetMinBuyAmount sets the smallest buy a user is notified about
+func (service *SubscriptionServiceImpl) SetMinBuyAmount(ctx context.Context, discordUserID string, amount float64) error {
+	if amount < 0 {
+		return fmt.Errorf("minimum buy amount cannot be negative")
+	}
+
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	subscription.MinBuyAmount = amount
+	return service.repo.SaveSubscription(ctx, subscription)
+}
+
+// SetUserTimezone sets the timezone absolute times are shown in for a user, or clears it when zone is empty
+func (service *SubscriptionServiceImpl) SetUserTimezone(ctx context.Context, discordUserID, zone string) error {
+	if _, err := LoadTimezone(zone); err != nil {
+		return err
+	}
+
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	subscription.Timezone = zone
+	return service.repo.SaveSubscription(ctx, subscription)
 }
 
 // GetUserSubscriptions gets a user's subscriptions
-func (service *SubscriptionServiceImpl) GetUserSubscriptions(discordUserID string) (*models.Subscription, error) {
-    return service.repo.GetSubscription(discordUserID)
+func (service *SubscriptionServiceImpl) GetUserSubscriptions(ctx context.Context, discordUserID string) (*models.Subscription, error) {
+    return service.repo.GetSubscription(ctx, discordUserID)
 }
 
 // GetAllSubscriptions gets all subscriptions
-func (service *SubscriptionServiceImpl) GetAllSubscriptions() ([]*models.Subscription, error) {
-    return service.repo.GetAllSubscriptions()
+func (service *SubscriptionServiceImpl) GetAllSubscriptions(ctx context.Context) ([]*models.Subscription, error) {
+    return service.repo.GetAllSubscriptions(ctx)
+}
+
+// UpdateChannelConfig updates a channel's configuration and records the change in the audit log. The
+// guild's channel limit is checked in the same transaction as the save, so two new channels cannot
+// both take the last place.
+func (service *SubscriptionServiceImpl) UpdateChannelConfig(ctx context.Context, config *models.ChannelConfig, actor string) error {
+	service.fillChannelGuild(ctx, config)
+	return service.withTx(ctx, func(tx *SubscriptionServiceImpl) error {
+		return tx.updateChannelConfig(ctx, config, actor)
+	})
+}
+
+// updateChannelConfig is UpdateChannelConfig within a transaction
+func (service *SubscriptionServiceImpl) updateChannelConfig(ctx context.Context, config *models.ChannelConfig, actor string) error {
+	previous, err := service.repo.GetChannelConfig(ctx, config.ChannelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel config: %w", err)
+	}
+	if err := service.checkGuildChannelLimit(ctx, config); err != nil {
+		return err
+	}
+
+	if err := service.repo.SaveChannelConfig(ctx, config); err != nil {
+		return err
+	}
+	service.recordAudit(ctx, actor, models.AuditActionUpdate, models.AuditResourceChannelConfig, config.ChannelID, config.ChannelID, previous, config.Clone())
+	return nil
 }
 
-// UpdateChannelConfig updates a channel's configuration
-func (service *SubscriptionServiceImpl) UpdateChannelConfig(config *models.ChannelConfig) error {
-    return service.repo.SaveChannelConfig(config)
+// SetChannelGuildResolver sets the resolver filling in the guild of channel configs saved without one
+func (service *SubscriptionServiceImpl) SetChannelGuildResolver(guilds ChannelGuildResolver) {
+	service.guilds = guilds
+}
+
+// fillChannelGuild sets the guild of a config saved without one, like those changed over the REST
+// API, so the channel counts towards the guild's channel limit and replaces the guild's default
+// channel. A channel whose guild cannot be found is saved without one.
+func (service *SubscriptionServiceImpl) fillChannelGuild(ctx context.Context, config *models.ChannelConfig) {
+	if config.GuildID != "" || service.guilds == nil {
+		return
+	}
+	guildID, err := service.guilds.ChannelGuild(ctx, config.ChannelID)
+	if err != nil {
+		service.logger.Warning(fmt.Sprintf("Failed to find the guild of channel %s: %v", config.ChannelID, err))
+		return
+	}
+	config.GuildID = guildID
 }
 
 // GetChannelConfig gets a channel's configuration
-func (service *SubscriptionServiceImpl) GetChannelConfig(channelID string) (*models.ChannelConfig, error) {
-    return service.repo.GetChannelConfig(channelID)
+func (service *SubscriptionServiceImpl) GetChannelConfig(ctx context.Context, channelID string) (*models.ChannelConfig, error) {
+    return service.repo.GetChannelConfig(ctx, channelID)
 }
 
 // GetAllChannelConfigs gets all channel configurations
-func (service *SubscriptionServiceImpl) GetAllChannelConfigs() ([]*models.ChannelConfig, error) {
-    return service.repo.GetAllChannelConfigs()
+func (service *SubscriptionServiceImpl) GetAllChannelConfigs(ctx context.Context) ([]*models.ChannelConfig, error) {
+    return service.repo.GetAllChannelConfigs(ctx)
+}
+
+// SubscribeChannelToMarket subscribes a channel to a market. A channel without a config is configured
+// with its feed off, so it gets the market's events and not the whole feed.
+func (service *SubscriptionServiceImpl) SubscribeChannelToMarket(ctx context.Context, channelID, marketID, actor string) error {
+	config, err := service.repo.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel config: %w", err)
+	}
+	configured, err := service.repo.HasChannelConfig(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel config: %w", err)
+	}
+	if !configured {
+		config.FeedEnabled = false
+	}
+
+	// Check if already subscribed
+	for _, id := range config.SubscribedMarkets {
+		if id == marketID {
+			return nil // Already subscribed
+		}
+	}
+
+	config.SubscribedMarkets = append(config.SubscribedMarkets, marketID)
+	if err := service.UpdateChannelConfig(ctx, config, actor); err != nil {
+		return err
+	}
+	service.recordChurn(ctx, models.AnalyticsSubscribe, "channel_market", channelID)
+	return nil
+}
+
+// UnsubscribeChannelFromMarket unsubscribes a channel from a market
+func (service *SubscriptionServiceImpl) UnsubscribeChannelFromMarket(ctx context.Context, channelID, marketID, actor string) error {
+	config, err := service.repo.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel config: %w", err)
+	}
+
+	newMarkets := []string{}
+	for _, id := range config.SubscribedMarkets {
+		if id != marketID {
+			newMarkets = append(newMarkets, id)
+		}
+	}
+
+	if len(newMarkets) == len(config.SubscribedMarkets) {
+		return nil // Not subscribed
+	}
+
+	config.SubscribedMarkets = newMarkets
+	if err := service.UpdateChannelConfig(ctx, config, actor); err != nil {
+		return err
+	}
+	service.recordChurn(ctx, models.AnalyticsUnsubscribe, "channel_market", channelID)
+	return nil
+}
+
+// UpdateGuildConfig updates a guild's configuration
+func (service *SubscriptionServiceImpl) UpdateGuildConfig(ctx context.Context, config *models.GuildConfig) error {
+	config.UpdatedAt = service.now()
+	return service.repo.SaveGuildConfig(ctx, config)
+}
+
+// GetGuildConfig gets a guild's configuration, or nil if the guild has not been set up
+func (service *SubscriptionServiceImpl) GetGuildConfig(ctx context.Context, guildID string) (*models.GuildConfig, error) {
+	return service.repo.GetGuildConfig(ctx, guildID)
+}
+
+// GetAllGuildConfigs gets all guild configurations
+func (service *SubscriptionServiceImpl) GetAllGuildConfigs(ctx context.Context) ([]*models.GuildConfig, error) {
+	return service.repo.GetAllGuildConfigs(ctx)
 }
 
 // ShouldNotifyUser determines if a user should be notified about a market
@@ -173,59 +511,273 @@ func (service *SubscriptionServiceImpl) ShouldNotifyUser(subscription *models.Su
 	return false
 }
 
+// ShouldNotifyOutcomeSubscriber determines if a user's outcome subscriptions match an event.
+// Outcome subscribers are notified when the outcome is paid out at resolution, when the market is cancelled,
+// or when an update or a liquidity change moves its probability by at least the subscription's threshold
+// away from the subscription's baseline, or from the previous snapshot while it has none.
+func (service *SubscriptionServiceImpl) ShouldNotifyOutcomeSubscriber(subscription *models.Subscription, market *models.Market, eventType string, previous *models.MarketSnapshot) bool {
+	for _, outcomeSub := range subscription.SubscribedOutcomes {
+		if outcomeSub.MarketID != market.ID {
+			continue
+		}
+
+		switch eventType {
+		case models.EventMarketResolved:
+			if market.PaidOut(outcomeSub.Outcome) {
+				return true
+			}
+		case models.EventMarketCancelled:
+			return true
+		case models.EventMarketUpdate, models.EventMarketLiquidity:
+			if previous == nil && outcomeSub.Baseline == nil {
+				continue
+			}
+			current, ok := outcomePercentage(market, outcomeSub.Outcome)
+			if !ok {
+				continue
+			}
+			var before float64
+			if outcomeSub.Baseline != nil {
+				before = *outcomeSub.Baseline
+			} else if before, ok = previous.Percentage(current.name); !ok {
+				continue
+			}
+			minChange := outcomeSub.MinChange
+			if minChange <= 0 {
+				minChange = DefaultOutcomeMinChange
+			}
+			if math.Abs(current.percentage-before) >= minChange {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UpdateOutcomeBaselines moves the baselines of a user's subscriptions to a market's outcomes to the
+// outcomes' current probabilities: all of them once the user was notified about the market, otherwise
+// only those without one
+func (service *SubscriptionServiceImpl) UpdateOutcomeBaselines(ctx context.Context, discordUserID string, market *models.Market, notified bool) error {
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	changed := false
+	for i, outcomeSub := range subscription.SubscribedOutcomes {
+		if outcomeSub.MarketID != market.ID || (outcomeSub.Baseline != nil && !notified) {
+			continue
+		}
+		current, ok := outcomePercentage(market, outcomeSub.Outcome)
+		if !ok {
+			continue
+		}
+		subscription.SubscribedOutcomes[i].Baseline = &current.percentage
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return service.repo.SaveSubscription(ctx, subscription)
+}
+
+// snapshotPercentage returns the probability of an outcome in a snapshot, matching the name
+// case-insensitively, or nil when there is no snapshot or it lacks the outcome
+func snapshotPercentage(snapshot *models.MarketSnapshot, outcome string) *float64 {
+	if snapshot == nil {
+		return nil
+	}
+	for i, name := range snapshot.Outcomes {
+		if strings.EqualFold(name, outcome) && i < len(snapshot.Percentages) {
+			percentage := snapshot.Percentages[i]
+			return &percentage
+		}
+	}
+	return nil
+}
+
+// outcomeProbability pairs an outcome's canonical name with its current probability
+type outcomeProbability struct {
+	name       string
+	percentage float64
+}
+
+// outcomePercentage looks up an outcome's probability on a market, matching the name case-insensitively
+func outcomePercentage(market *models.Market, outcome string) (outcomeProbability, bool) {
+	for i, name := range market.Outcomes {
+		if strings.EqualFold(name, outcome) && i < len(market.Percentages) {
+			return outcomeProbability{name: name, percentage: market.Percentages[i]}, true
+		}
+	}
+	return outcomeProbability{}, false
+}
+
+// RecordMarketSnapshot stores the market's current probabilities as its latest snapshot and in its history,
+// and returns the previous snapshot, if any.
+// Markets without probabilities are not recorded.
+func (service *SubscriptionServiceImpl) RecordMarketSnapshot(ctx context.Context, market *models.Market) (*models.MarketSnapshot, error) {
+	previous, err := service.repo.GetMarketSnapshot(ctx, market.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get market snapshot: %w", err)
+	}
+	if market.ID == "" || len(market.Percentages) == 0 {
+		return previous, nil
+	}
+
+	snapshot := &models.MarketSnapshot{
+		MarketID:    market.ID,
+		Outcomes:    append([]string{}, market.Outcomes...),
+		Percentages: append([]float64{}, market.Percentages...),
+		Volume:      market.Volume,
+		Timestamp:   service.now(),
+	}
+	if err := service.repo.SaveMarketSnapshot(ctx, snapshot); err != nil {
+		return previous, fmt.Errorf("failed to save market snapshot: %w", err)
+	}
+	if err := service.repo.AppendMarketHistory(ctx, snapshot); err != nil {
+		return previous, fmt.Errorf("failed to append market history: %w", err)
+	}
+	return previous, nil
+}
+
+// GetMarketSnapshot returns the latest recorded snapshot of a market, or nil if none was recorded
+func (service *SubscriptionServiceImpl) GetMarketSnapshot(ctx context.Context, marketID string) (*models.MarketSnapshot, error) {
+	return service.repo.GetMarketSnapshot(ctx, marketID)
+}
+
+// GetMarketHistory returns the probability snapshots recorded for a market since a point in time
+func (service *SubscriptionServiceImpl) GetMarketHistory(ctx context.Context, marketID string, since time.Time) ([]*models.MarketSnapshot, error) {
+	return service.repo.GetMarketHistory(ctx, marketID, since)
+}
+
+// MarkMarketResolved records that a market resolved, so later events for it can be told apart
+func (service *SubscriptionServiceImpl) MarkMarketResolved(ctx context.Context, marketID string, resolvedAt time.Time) error {
+	return service.repo.MarkMarketResolved(ctx, marketID, resolvedAt)
+}
+
+// IsMarketResolved reports whether a market was marked resolved
+func (service *SubscriptionServiceImpl) IsMarketResolved(ctx context.Context, marketID string) (bool, error) {
+	return service.repo.IsMarketResolved(ctx, marketID)
+}
+
 // SendNotificationToUser sends a notification to a user (placeholder implementation)
-func (service *SubscriptionServiceImpl) SendNotificationToUser(discordUserID string, message string) error {
+func (service *SubscriptionServiceImpl) SendNotificationToUser(ctx context.Context, discordUserID string, message string) error {
     service.logger.Info(fmt.Sprintf("Would send DM to user %s: %s", discordUserID, message))
     return nil
 }
 
-// helper to create a secure random id
-func generateID() (string, error) {
-    randomBytes := make([]byte, 12)
-    if _, err := rand.Read(randomBytes); err != nil {
-        return "", err
-    }
-    return hex.EncodeToString(randomBytes), nil
+// GetLeaderboard returns the most-followed markets and creators
+func (service *SubscriptionServiceImpl) GetLeaderboard(ctx context.Context, limit int) (*models.Leaderboard, error) {
+	if limit <= 0 {
+		limit = DefaultLeaderboardSize
+	}
+
+	markets, err := service.repo.GetTopSubscribedMarkets(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank markets: %w", err)
+	}
+
+	creators, err := service.repo.GetTopSubscribedCreators(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank creators: %w", err)
+	}
+
+	return &models.Leaderboard{Markets: markets, Creators: creators}, nil
 }
 
-// RegisterWebhook registers a webhook and persists it
-func (service *SubscriptionServiceImpl) RegisterWebhook(registration *models.WebhookRegistration) (*models.WebhookRegistration, error) {
+// recordChurn stores a subscription change for analytics; failures are logged and never block the change itself
+func (service *SubscriptionServiceImpl) recordChurn(ctx context.Context, kind, target, subject string) {
+	err := service.repo.SaveAnalyticsEvent(context.WithoutCancel(ctx), &models.AnalyticsEvent{
+		Kind:      kind,
+		Name:      target,
+		Subject:   subject,
+		Timestamp: service.now(),
+	})
+	if err != nil {
+		service.logger.Warning(fmt.Sprintf("Failed to record subscription churn: %v", err))
+	}
+}
+
+// RegisterWebhook registers a webhook and persists it. The URL must be a Discord webhook URL.
+func (service *SubscriptionServiceImpl) RegisterWebhook(ctx context.Context, registration *models.WebhookRegistration, actor string) (*models.WebhookRegistration, error) {
+	if _, _, err := ParseDiscordWebhookURL(registration.WebhookURL); err != nil {
+		return nil, err
+	}
 	// generate a simple id and set createdAt
-	// use time.Now().UnixNano() and fmt.Sprintf random hex
+	// use service.now().UnixNano() and fmt.Sprintf random hex
 	// generate id and timestamp
-	idBytes, err := generateID()
+	idBytes, err := service.newID()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate id: %w", err)
 	}
 	registration.ID = "wh_" + idBytes
-	registration.CreatedAt = time.Now()
+	registration.CreatedAt = service.now()
 
 	if registration.Frequency == "" {
 		registration.Frequency = "medium"
 	}
 
-    if err := service.repo.SaveWebhookRegistration(registration); err != nil {
+    if err := service.repo.SaveWebhookRegistration(ctx, registration); err != nil {
         return nil, fmt.Errorf("failed to save webhook registration: %w", err)
     }
+    service.recordAudit(ctx, actor, models.AuditActionCreate, models.AuditResourceWebhook, registration.ID, registration.ChannelID, nil, registration.Clone())
     return registration, nil
 }
 
-// UnregisterWebhook removes a webhook registration
-func (service *SubscriptionServiceImpl) UnregisterWebhook(id string) error {
-    return service.repo.DeleteWebhookRegistration(id)
+// UnregisterWebhook soft-deletes a webhook registration so it stops receiving events but stays in the audit trail
+func (service *SubscriptionServiceImpl) UnregisterWebhook(ctx context.Context, id, actor string) error {
+	registration, err := service.repo.GetWebhookRegistration(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get webhook registration: %w", err)
+	}
+	if registration == nil || registration.DeletedAt != nil {
+		return nil // Already removed
+	}
+
+	previous := registration.Clone()
+	deletedAt := service.now()
+	registration.DeletedAt = &deletedAt
+	if err := service.repo.SaveWebhookRegistration(ctx, registration); err != nil {
+		return err
+	}
+	service.recordAudit(ctx, actor, models.AuditActionDelete, models.AuditResourceWebhook, id, registration.ChannelID, previous, registration)
+	return nil
+}
+
+// GetWebhookRegistration returns a registration by id, or nil if it does not exist or was unregistered
+func (service *SubscriptionServiceImpl) GetWebhookRegistration(ctx context.Context, id string) (*models.WebhookRegistration, error) {
+	registration, err := service.repo.GetWebhookRegistration(ctx, id)
+	if err != nil || registration == nil || registration.DeletedAt != nil {
+		return nil, err
+	}
+	return registration, nil
 }
 
-// GetWebhookRegistration returns a registration by id
-func (service *SubscriptionServiceImpl) GetWebhookRegistration(id string) (*models.WebhookRegistration, error) {
-    return service.repo.GetWebhookRegistration(id)
+// ListWebhookRegistrations lists all active registrations
+func (service *SubscriptionServiceImpl) ListWebhookRegistrations(ctx context.Context) ([]*models.WebhookRegistration, error) {
+	registrations, err := service.repo.GetAllWebhookRegistrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return activeWebhookRegistrations(registrations), nil
 }
 
-// ListWebhookRegistrations lists all registrations
-func (service *SubscriptionServiceImpl) ListWebhookRegistrations() ([]*models.WebhookRegistration, error) {
-    return service.repo.GetAllWebhookRegistrations()
+// ListWebhookRegistrationsByChannel lists active registrations for a channel
+func (service *SubscriptionServiceImpl) ListWebhookRegistrationsByChannel(ctx context.Context, channelID string) ([]*models.WebhookRegistration, error) {
+	registrations, err := service.repo.GetWebhookRegistrationsByChannel(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	return activeWebhookRegistrations(registrations), nil
 }
 
-// ListWebhookRegistrationsByChannel lists registrations for a channel
-func (service *SubscriptionServiceImpl) ListWebhookRegistrationsByChannel(channelID string) ([]*models.WebhookRegistration, error) {
-    return service.repo.GetWebhookRegistrationsByChannel(channelID)
+// activeWebhookRegistrations filters out soft-deleted registrations
+func activeWebhookRegistrations(registrations []*models.WebhookRegistration) []*models.WebhookRegistration {
+	active := make([]*models.WebhookRegistration, 0, len(registrations))
+	for _, registration := range registrations {
+		if registration.DeletedAt == nil {
+			active = append(active, registration)
+		}
+	}
+	return active
 }
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Discord timestamp styles, see https://discord.com/developers/docs/reference#message-formatting-timestamp-styles
+const (
+	TimestampShortTime     = "t"
+	TimestampLongTime      = "T"
+	TimestampShortDate     = "d"
+	TimestampLongDate      = "D"
+	TimestampShortDateTime = "f"
+	TimestampLongDateTime  = "F"
+	TimestampRelative      = "R"
+)
+
+// ErrInvalidTimezone is returned when a timezone is not a known IANA zone name
+var ErrInvalidTimezone = errors.New("invalid timezone")
+
+// DiscordTimestamp returns Discord's timestamp markup for t, which every client renders in its own locale
+func DiscordTimestamp(t time.Time, style string) string {
+	return fmt.Sprintf("<t:%d:%s>", t.Unix(), style)
+}
+
+// absoluteTime renders a close or resolution time as an absolute time followed by a relative one,
+// e.g. "Friday, March 6, 2026 6:00 PM (in 2 days)"
+func absoluteTime(t time.Time) string {
+	return DiscordTimestamp(t, TimestampLongDateTime) + " (" + DiscordTimestamp(t, TimestampRelative) + ")"
+}
+
+// timestampLayouts renders each absolute timestamp style as text
+var timestampLayouts = map[string]string{
+	TimestampShortTime:     "15:04 MST",
+	TimestampLongTime:      "15:04:05 MST",
+	TimestampShortDate:     "Jan 2, 2006",
+	TimestampLongDate:      "January 2, 2006",
+	TimestampShortDateTime: "January 2, 2006 15:04 MST",
+	TimestampLongDateTime:  "Monday, January 2, 2006 15:04 MST",
+}
+
+// absoluteTimestampPattern matches timestamp markup with an absolute style
+var absoluteTimestampPattern = regexp.MustCompile(`<t:(-?\d+):([tTdDfF])>`)
+
+// LocalizeTimestamps rewrites the absolute timestamps in a message as text in loc, for readers who chose
+// a timezone with /set_timezone. Relative timestamps are left for Discord to render. A nil loc leaves
+// the message unchanged, so each reader's client shows their own locale.
+func LocalizeTimestamps(content string, loc *time.Location) string {
+	if loc == nil {
+		return content
+	}
+	return absoluteTimestampPattern.ReplaceAllStringFunc(content, func(tag string) string {
+		match := absoluteTimestampPattern.FindStringSubmatch(tag)
+		unix, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return tag
+		}
+		return time.Unix(unix, 0).In(loc).Format(timestampLayouts[match[2]])
+	})
+}
+
+// timezones caches loaded locations, which are otherwise read from the zoneinfo database on every load
+var timezones sync.Map
+
+// LoadTimezone loads an IANA timezone such as Europe/Berlin. An empty name returns nil, meaning the
+// reader's own locale.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if loc, ok := timezones.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return nil, fmt.Errorf("%w %q, use an IANA name such as Europe/Berlin or America/New_York", ErrInvalidTimezone, name)
+	}
+	timezones.Store(name, loc)
+	return loc, nil
+}
+
+// timezoneOrNil loads a stored timezone, treating unknown names as unset
+func timezoneOrNil(name string) *time.Location {
+	loc, _ := LoadTimezone(name)
+	return loc
+}
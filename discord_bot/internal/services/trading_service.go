@@ -0,0 +1,298 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/tracing"
+	"coral-bot/discord_bot/internal/utils"
+
+	"github.com/bwmarrin/discordgo"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// TradeTTL is how long a trade summary can be confirmed before the trade has to be started again
+const TradeTTL = 2 * time.Minute
+
+// TradeButtonPrefix starts the custom ID of the buy buttons under a market, followed by the market ID
+// and the index of the outcome
+const TradeButtonPrefix = "trade"
+
+// tradeButtonSeparator separates the parts of a buy button custom ID
+const tradeButtonSeparator = "|"
+
+// maxTradeButtons is the number of outcomes offered, one row of buttons
+const maxTradeButtons = 5
+
+var (
+	// ErrTradeExpired is returned when a trade to confirm is unknown, expired or belongs to another user
+	ErrTradeExpired = errors.New("trade expired")
+	// ErrOrderRejected is returned when the backend refused an order, wrapped with its reason
+	ErrOrderRejected = errors.New("order rejected")
+)
+
+// PendingTrade is a buy a user prepared and has yet to confirm
+type PendingTrade struct {
+	ID            string // idempotency key of the order, so confirming twice places it once
+	DiscordUserID string
+	AccountID     string
+	Market        *models.Market
+	Outcome       string
+	Amount        float64
+	Price         float64 // the outcome's probability when the trade was prepared, 0 to 1
+	CreatedAt     time.Time
+	Order         *models.Order // set once the backend placed the order
+}
+
+// orderRequest is the body of a request to the backend's POST /accounts/{account_id}/orders
+type orderRequest struct {
+	MarketID string  `json:"market_id"`
+	Outcome  string  `json:"outcome"`
+	Amount   float64 `json:"amount"`
+	Source   string  `json:"source"`
+}
+
+// TradingService defines the interface for buying outcomes for linked Coral accounts from Discord
+type TradingService interface {
+	PrepareTrade(ctx context.Context, discordUserID string, market *models.Market, outcome string, amount float64) (*PendingTrade, error)
+	ConfirmTrade(ctx context.Context, discordUserID, tradeID string) (*PendingTrade, error)
+	CancelTrade(discordUserID, tradeID string) bool
+}
+
+// TradingServiceImpl implements TradingService. Prepared trades are kept in memory, since they only
+// live for TradeTTL.
+type TradingServiceImpl struct {
+	accounts AccountService
+	baseURL  string
+	token    string // bearer token of the backend's order API, empty for none
+	client   *http.Client
+	logger   *utils.Logger
+	mutex    sync.Mutex
+	trades   map[string]*PendingTrade
+	clockAndIDs
+}
+
+// NewTradingService creates a trading service placing orders with the backend at baseURL
+func NewTradingService(accounts AccountService, baseURL, token string, logger *utils.Logger) *TradingServiceImpl {
+	return &TradingServiceImpl{
+		accounts: accounts,
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		token:    token,
+		client:   &http.Client{},
+		logger:   logger,
+		trades:   make(map[string]*PendingTrade),
+	}
+}
+
+// PrepareTrade records a buy of an outcome for the user's linked account, to be placed once the user
+// confirms it. ErrAccountNotLinked is returned when the user has no linked account.
+func (service *TradingServiceImpl) PrepareTrade(ctx context.Context, discordUserID string, market *models.Market, outcome string, amount float64) (*PendingTrade, error) {
+	if amount <= 0 || math.IsInf(amount, 0) || math.IsNaN(amount) {
+		return nil, fmt.Errorf("%w: the amount must be more than 0", ErrInvalidChannelSettings)
+	}
+	index := outcomeIndex(market, outcome)
+	if index < 0 {
+		return nil, fmt.Errorf("%w: %q is not an outcome of the market", ErrInvalidChannelSettings, outcome)
+	}
+	accountID, err := service.accounts.LinkedAccount(ctx, discordUserID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := service.newID()
+	if err != nil {
+		return nil, err
+	}
+
+	trade := &PendingTrade{
+		ID:            id,
+		DiscordUserID: discordUserID,
+		AccountID:     accountID,
+		Market:        market,
+		Outcome:       market.Outcomes[index],
+		Amount:        math.Round(amount*100) / 100,
+		CreatedAt:     service.now(),
+	}
+	if index < len(market.Percentages) {
+		trade.Price = market.Percentages[index] / 100
+	}
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	for tradeID, pending := range service.trades {
+		if service.now().Sub(pending.CreatedAt) > TradeTTL {
+			delete(service.trades, tradeID)
+		}
+	}
+	service.trades[id] = trade
+	return trade, nil
+}
+
+// ConfirmTrade places a prepared trade through the backend and returns it with its order. A trade that
+// was already placed is returned as it is. A rejected order is dropped and returned as ErrOrderRejected
+// with the backend's reason; after any other failure the trade can be confirmed again, the
+// idempotency key keeping the backend from placing it twice.
+func (service *TradingServiceImpl) ConfirmTrade(ctx context.Context, discordUserID, tradeID string) (*PendingTrade, error) {
+	service.mutex.Lock()
+	trade := service.trades[tradeID]
+	if trade == nil || trade.DiscordUserID != discordUserID || (trade.Order == nil && service.now().Sub(trade.CreatedAt) > TradeTTL) {
+		service.mutex.Unlock()
+		return nil, ErrTradeExpired
+	}
+	if trade.Order != nil {
+		service.mutex.Unlock()
+		return trade, nil
+	}
+	service.mutex.Unlock()
+
+	order, err := service.placeOrder(ctx, trade)
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	if errors.Is(err, ErrOrderRejected) {
+		delete(service.trades, tradeID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	trade.Order = order
+	service.logger.Info(fmt.Sprintf("Placed order %s for user %s: $%.2f of %s in market %s", order.ID, discordUserID, trade.Amount, trade.Outcome, trade.Market.ID))
+	return trade, nil
+}
+
+// CancelTrade drops a prepared trade that was not placed, and reports whether there was one
+func (service *TradingServiceImpl) CancelTrade(discordUserID, tradeID string) bool {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	trade := service.trades[tradeID]
+	if trade == nil || trade.DiscordUserID != discordUserID || trade.Order != nil {
+		return false
+	}
+	delete(service.trades, tradeID)
+	return true
+}
+
+// placeOrder sends a trade to the backend's order API with the trade's ID as the idempotency key
+func (service *TradingServiceImpl) placeOrder(ctx context.Context, trade *PendingTrade) (order *models.Order, err error) {
+	body, err := json.Marshal(orderRequest{MarketID: trade.Market.ID, Outcome: trade.Outcome, Amount: trade.Amount, Source: UTMSource})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, BackendTimeout)
+	defer cancel()
+	endpoint := service.baseURL + "/accounts/" + url.PathEscape(trade.AccountID) + "/orders"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", trade.ID)
+	if service.token != "" {
+		req.Header.Set("Authorization", "Bearer "+service.token)
+	}
+	req, span := tracing.StartClient(req, "backend.PlaceOrder")
+	defer func() { tracing.End(span, err) }()
+
+	resp, err := service.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		order = &models.Order{}
+		if err := json.NewDecoder(resp.Body).Decode(order); err != nil {
+			return nil, fmt.Errorf("failed to decode order: %w", err)
+		}
+		return order, nil
+	case resp.StatusCode >= 400 && resp.StatusCode <= 499 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return nil, fmt.Errorf("%w: %s", ErrOrderRejected, rejectionReason(resp))
+	}
+	return nil, fmt.Errorf("backend returned status %d", resp.StatusCode)
+}
+
+// rejectionReason reads the reason of a refused order from the backend's {"error": "..."} body,
+// falling back on the status text
+func rejectionReason(resp *http.Response) string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(data, &body) == nil && strings.TrimSpace(body.Error) != "" {
+		return strings.TrimSpace(body.Error)
+	}
+	return strings.ToLower(http.StatusText(resp.StatusCode))
+}
+
+// outcomeIndex returns the index of an outcome of the market, ignoring case, or -1
+func outcomeIndex(market *models.Market, outcome string) int {
+	for i, name := range market.Outcomes {
+		if strings.EqualFold(name, outcome) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Tradable reports whether a market can be bought from Discord: it has outcomes and is still open
+func Tradable(market *models.Market, now time.Time) bool {
+	if len(market.Outcomes) == 0 || (market.Status != "" && market.Status != "active") {
+		return false
+	}
+	return market.EndTime.IsZero() || market.EndTime.After(now)
+}
+
+// TradeButtons returns a row of buy buttons for the outcomes of a market, or nil when it has none or
+// its ID does not fit in a custom ID
+func TradeButtons(market *models.Market) []discordgo.MessageComponent {
+	return outcomeButtons(market, TradeButtonPrefix, "Buy ", discordgo.SuccessButton)
+}
+
+// ParseTradeButton splits the custom ID of a buy button, or of the modal it opens, into the market ID
+// and the outcome's index
+func ParseTradeButton(customID string) (marketID string, outcome int, ok bool) {
+	return parseOutcomeButton(customID, TradeButtonPrefix)
+}
+
+// outcomeButtons returns a row of buttons for the outcomes of a market, labelled with the outcome after
+// label, whose custom IDs are prefix, the market ID and the outcome's index
+func outcomeButtons(market *models.Market, prefix, label string, style discordgo.ButtonStyle) []discordgo.MessageComponent {
+	var buttons []discordgo.MessageComponent
+	for i, outcome := range market.Outcomes {
+		customID := strings.Join([]string{prefix, market.ID, strconv.Itoa(i)}, tradeButtonSeparator)
+		if len(customID) > 100 || i == maxTradeButtons {
+			break
+		}
+		buttons = append(buttons, discordgo.Button{Label: truncate(label+outcome, 80), Style: style, CustomID: customID})
+	}
+	if len(buttons) == 0 {
+		return nil
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+// parseOutcomeButton splits the custom ID of an outcome button with prefix into the market ID and the
+// outcome's index
+func parseOutcomeButton(customID, prefix string) (marketID string, outcome int, ok bool) {
+	parts := strings.Split(customID, tradeButtonSeparator)
+	if len(parts) != 3 || parts[0] != prefix || parts[1] == "" {
+		return "", 0, false
+	}
+	outcome, err := strconv.Atoi(parts[2])
+	if err != nil || outcome < 0 {
+		return "", 0, false
+	}
+	return parts[1], outcome, true
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/utils"
+)
+
+// UserDataService defines the interface for data access and deletion requests about a single user
+type UserDataService interface {
+	ExportUserData(ctx context.Context, discordUserID string) (*models.UserData, error)
+	DeleteUserData(ctx context.Context, discordUserID string) (*models.UserData, error)
+}
+
+// UserDataServiceImpl implements UserDataService
+type UserDataServiceImpl struct {
+	repo   repository.SubscriptionRepository
+	logger *utils.Logger
+	clockAndIDs
+}
+
+// NewUserDataService creates a new user data service
+func NewUserDataService(repo repository.SubscriptionRepository, logger *utils.Logger) *UserDataServiceImpl {
+	return &UserDataServiceImpl{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// ExportUserData collects a user's subscriptions, preferences, reminders, watchlists, analytics records
+// and game wallets and bets
+func (service *UserDataServiceImpl) ExportUserData(ctx context.Context, discordUserID string) (*models.UserData, error) {
+	subscription, err := service.repo.GetSubscription(ctx, discordUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+	reminders, err := service.repo.GetRemindersByUser(ctx, discordUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reminders: %w", err)
+	}
+	watchlists, err := service.repo.GetWatchlistsByUser(ctx, discordUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchlists: %w", err)
+	}
+	analytics, err := service.repo.GetAnalyticsEventsBySubject(ctx, discordUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get analytics events: %w", err)
+	}
+	wallets, err := service.repo.GetGameWalletsByUser(ctx, discordUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game wallets: %w", err)
+	}
+	bets, err := service.repo.GetGameBetsByUser(ctx, discordUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game bets: %w", err)
+	}
+	if reminders == nil {
+		reminders = []*models.Reminder{}
+	}
+	return &models.UserData{
+		DiscordUserID: discordUserID,
+		Subscription:  subscription,
+		Reminders:     reminders,
+		Watchlists:    watchlists,
+		Analytics:     analytics,
+		GameWallets:   wallets,
+		GameBets:      bets,
+		ExportedAt:    service.now(),
+	}, nil
+}
+
+// DeleteUserData exports a user's data, then deletes all of it and returns the export. Reminders are
+// deleted first so none fires for a user whose data is half gone. Audit entries of the user's changes to
+// channels are kept for the audit trail, with the user's ID replaced by models.AuditActorDeletedUser.
+func (service *UserDataServiceImpl) DeleteUserData(ctx context.Context, discordUserID string) (*models.UserData, error) {
+	data, err := service.ExportUserData(ctx, discordUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	redacted := 0
+	// One transaction, so a backend that rolls back keeps the data whole for another attempt when a deletion fails
+	err = service.repo.WithTx(ctx, func(tx repository.SubscriptionRepository) error {
+		for _, reminder := range data.Reminders {
+			if err := tx.DeleteReminder(ctx, reminder.ID); err != nil {
+				return fmt.Errorf("failed to delete reminder %s: %w", reminder.ID, err)
+			}
+		}
+		for _, watchlist := range data.Watchlists {
+			if _, err := tx.DeleteWatchlist(ctx, watchlist.ID); err != nil {
+				return fmt.Errorf("failed to delete watchlist %s: %w", watchlist.ID, err)
+			}
+		}
+		if err := tx.DeleteSubscription(ctx, discordUserID); err != nil {
+			return fmt.Errorf("failed to delete subscription: %w", err)
+		}
+		if _, err := tx.DeleteAnalyticsEventsBySubject(ctx, discordUserID); err != nil {
+			return fmt.Errorf("failed to delete analytics events: %w", err)
+		}
+		if _, err := tx.DeleteGameData(ctx, discordUserID); err != nil {
+			return fmt.Errorf("failed to delete game data: %w", err)
+		}
+		if redacted, err = tx.RedactAuditActor(ctx, discordUserID, models.AuditActorDeletedUser); err != nil {
+			return fmt.Errorf("failed to redact audit entries: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	service.logger.Info(fmt.Sprintf("Deleted the data of user %s: %d reminders, %d watchlists, %d analytics events and %d game bets, and redacted %d audit entries", discordUserID, len(data.Reminders), len(data.Watchlists), len(data.Analytics), len(data.GameBets), redacted))
+	return data, nil
+}
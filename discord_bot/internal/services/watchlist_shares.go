@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// ErrShareCodeNotFound is returned for share codes that were never issued or whose watchlist was deleted
+var ErrShareCodeNotFound = errors.New("share code not found")
+
+// shareCodeAlphabet leaves out letters and digits that are easily confused, such as O and 0
+const shareCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// shareCodeLength is the number of characters in a share code, shown in two groups of four
+const shareCodeLength = 8
+
+// FormatShareCode returns a stored share code the way it is shown to users, e.g. K7QX-2M9P
+func FormatShareCode(code string) string {
+	if len(code) != shareCodeLength {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
+
+// ShareWatchlist returns the share code of one of a user's watchlists, issuing one the first time
+func (service *SubscriptionServiceImpl) ShareWatchlist(ctx context.Context, discordUserID, name string) (*models.WatchlistShare, error) {
+	watchlist, err := service.GetWatchlist(ctx, discordUserID, name)
+	if err != nil {
+		return nil, err
+	}
+	share, err := service.repo.GetWatchlistShareByWatchlist(ctx, watchlist.ID)
+	if err != nil || share != nil {
+		return share, err
+	}
+
+	code, err := service.newShareCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	share = &models.WatchlistShare{
+		Code:        code,
+		WatchlistID: watchlist.ID,
+		CreatedBy:   discordUserID,
+		CreatedAt:   service.now(),
+	}
+	if err := service.repo.SaveWatchlistShare(ctx, share); err != nil {
+		return nil, fmt.Errorf("failed to save watchlist share: %w", err)
+	}
+	return share, nil
+}
+
+// ImportWatchlist copies the markets of a shared watchlist into a new watchlist of the user, named
+// like the shared one unless a name is given. The copy gets the default notification settings.
+func (service *SubscriptionServiceImpl) ImportWatchlist(ctx context.Context, discordUserID, code, name string) (*models.Watchlist, error) {
+	var result *models.Watchlist
+	err := service.withTx(ctx, func(tx *SubscriptionServiceImpl) error {
+		var err error
+		result, err = tx.importWatchlist(ctx, discordUserID, code, name)
+		return err
+	})
+	return result, err
+}
+
+// importWatchlist is ImportWatchlist within a transaction
+func (service *SubscriptionServiceImpl) importWatchlist(ctx context.Context, discordUserID, code, name string) (*models.Watchlist, error) {
+	share, err := service.repo.GetWatchlistShare(ctx, models.NormalizeShareCode(code))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchlist share: %w", err)
+	}
+	if share == nil {
+		return nil, ErrShareCodeNotFound
+	}
+	source, err := service.repo.GetWatchlist(ctx, share.WatchlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shared watchlist: %w", err)
+	}
+	if source == nil {
+		return nil, ErrShareCodeNotFound
+	}
+
+	limits, err := service.GetSubscriptionLimits(ctx, models.LimitSubjectUser, discordUserID)
+	if err != nil {
+		return nil, err
+	}
+	if limits.MaxMarkets > 0 && len(source.MarketIDs) > limits.MaxMarkets {
+		return nil, &LimitExceededError{Kind: limitWatchlistMarkets, Limit: limits.MaxMarkets}
+	}
+
+	if models.NormalizeWatchlistName(name) == "" {
+		name = source.Name
+	}
+	watchlist, err := service.CreateWatchlist(ctx, discordUserID, name)
+	if err != nil {
+		return nil, err
+	}
+	watchlist.MarketIDs = source.MarketIDs
+	watchlist.ImportedFrom = share.Code
+	if err := service.repo.SaveWatchlist(ctx, watchlist); err != nil {
+		return nil, fmt.Errorf("failed to save watchlist: %w", err)
+	}
+	service.recordChurn(ctx, models.AnalyticsSubscribe, "watchlist", discordUserID)
+	return watchlist, nil
+}
+
+// newShareCode returns a random share code that is not in use yet
+func (service *SubscriptionServiceImpl) newShareCode(ctx context.Context) (string, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		randomBytes := make([]byte, shareCodeLength)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return "", fmt.Errorf("failed to generate share code: %w", err)
+		}
+		code := make([]byte, shareCodeLength)
+		for i, b := range randomBytes {
+			code[i] = shareCodeAlphabet[int(b)%len(shareCodeAlphabet)]
+		}
+
+		existing, err := service.repo.GetWatchlistShare(ctx, string(code))
+		if err != nil {
+			return "", fmt.Errorf("failed to get watchlist share: %w", err)
+		}
+		if existing == nil {
+			return string(code), nil
+		}
+	}
+	return "", errors.New("failed to generate an unused share code")
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// MaxWatchlistsPerUser is the number of watchlists a user may have
+const MaxWatchlistsPerUser = 25
+
+// MaxWatchlistNameLength is the longest watchlist name, in characters
+const MaxWatchlistNameLength = 32
+
+var (
+	// ErrInvalidWatchlist is returned for watchlists with an empty or too long name, an unknown
+	// notification setting or a negative minimum buy
+	ErrInvalidWatchlist = errors.New("invalid watchlist")
+	// ErrWatchlistExists is returned when a user already has a watchlist with the name
+	ErrWatchlistExists = errors.New("watchlist already exists")
+	// ErrWatchlistNotFound is returned when a user has no watchlist with the name
+	ErrWatchlistNotFound = errors.New("watchlist not found")
+)
+
+// WatchlistSettings changes a watchlist's notification settings; nil fields are left as they are
+type WatchlistSettings struct {
+	Notify       *string
+	MinBuyAmount *float64
+}
+
+// CreateWatchlist creates an empty watchlist that notifies about every event of its markets
+func (service *SubscriptionServiceImpl) CreateWatchlist(ctx context.Context, discordUserID, name string) (*models.Watchlist, error) {
+	key := models.NormalizeWatchlistName(name)
+	if key == "" || len([]rune(key)) > MaxWatchlistNameLength {
+		return nil, fmt.Errorf("%w: names are 1 to %d characters", ErrInvalidWatchlist, MaxWatchlistNameLength)
+	}
+
+	watchlists, err := service.repo.GetWatchlistsByUser(ctx, discordUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchlists: %w", err)
+	}
+	for _, watchlist := range watchlists {
+		if watchlist.Name == key {
+			return nil, ErrWatchlistExists
+		}
+	}
+	if len(watchlists) >= MaxWatchlistsPerUser {
+		return nil, &LimitExceededError{Kind: limitWatchlists, Limit: MaxWatchlistsPerUser}
+	}
+
+	id, err := service.newID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate watchlist ID: %w", err)
+	}
+	now := service.now()
+	watchlist := &models.Watchlist{
+		ID:            id,
+		DiscordUserID: discordUserID,
+		Name:          key,
+		MarketIDs:     []string{},
+		Notify:        models.WatchlistNotifyAll,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := service.repo.SaveWatchlist(ctx, watchlist); err != nil {
+		return nil, fmt.Errorf("failed to save watchlist: %w", err)
+	}
+	return watchlist, nil
+}
+
+// DeleteWatchlist deletes one of a user's watchlists and reports whether it existed
+func (service *SubscriptionServiceImpl) DeleteWatchlist(ctx context.Context, discordUserID, name string) (bool, error) {
+	watchlist, err := service.GetWatchlist(ctx, discordUserID, name)
+	if errors.Is(err, ErrWatchlistNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if _, err := service.repo.DeleteWatchlist(ctx, watchlist.ID); err != nil {
+		return false, fmt.Errorf("failed to delete watchlist: %w", err)
+	}
+	return true, nil
+}
+
+// AddToWatchlist adds a market to one of a user's watchlists. Each watchlist holds at most as many
+// markets as the user may subscribe to.
+func (service *SubscriptionServiceImpl) AddToWatchlist(ctx context.Context, discordUserID, name, marketID string) (*models.Watchlist, error) {
+	watchlist, err := service.GetWatchlist(ctx, discordUserID, name)
+	if err != nil {
+		return nil, err
+	}
+	if watchlist.HasMarket(marketID) {
+		return watchlist, nil
+	}
+
+	limits, err := service.GetSubscriptionLimits(ctx, models.LimitSubjectUser, discordUserID)
+	if err != nil {
+		return nil, err
+	}
+	if limits.MaxMarkets > 0 && len(watchlist.MarketIDs) >= limits.MaxMarkets {
+		return nil, &LimitExceededError{Kind: limitWatchlistMarkets, Limit: limits.MaxMarkets}
+	}
+
+	watchlist.MarketIDs = append(watchlist.MarketIDs, marketID)
+	watchlist.UpdatedAt = service.now()
+	if err := service.repo.SaveWatchlist(ctx, watchlist); err != nil {
+		return nil, fmt.Errorf("failed to save watchlist: %w", err)
+	}
+	service.recordChurn(ctx, models.AnalyticsSubscribe, "watchlist", discordUserID)
+	return watchlist, nil
+}
+
+// RemoveFromWatchlist removes a market from one of a user's watchlists and reports whether it was there
+func (service *SubscriptionServiceImpl) RemoveFromWatchlist(ctx context.Context, discordUserID, name, marketID string) (bool, error) {
+	watchlist, err := service.GetWatchlist(ctx, discordUserID, name)
+	if err != nil {
+		return false, err
+	}
+	if !watchlist.HasMarket(marketID) {
+		return false, nil
+	}
+
+	markets := []string{}
+	for _, id := range watchlist.MarketIDs {
+		if id != marketID {
+			markets = append(markets, id)
+		}
+	}
+	watchlist.MarketIDs = markets
+	watchlist.UpdatedAt = service.now()
+	if err := service.repo.SaveWatchlist(ctx, watchlist); err != nil {
+		return false, fmt.Errorf("failed to save watchlist: %w", err)
+	}
+	service.recordChurn(ctx, models.AnalyticsUnsubscribe, "watchlist", discordUserID)
+	return true, nil
+}
+
+// UpdateWatchlistSettings changes the notification settings of one of a user's watchlists
+func (service *SubscriptionServiceImpl) UpdateWatchlistSettings(ctx context.Context, discordUserID, name string, settings WatchlistSettings) (*models.Watchlist, error) {
+	if settings.Notify != nil {
+		switch *settings.Notify {
+		case models.WatchlistNotifyAll, models.WatchlistNotifyMajor, models.WatchlistNotifyOff:
+		default:
+			return nil, fmt.Errorf("%w: notify must be all, major or off", ErrInvalidWatchlist)
+		}
+	}
+	if settings.MinBuyAmount != nil && *settings.MinBuyAmount < 0 {
+		return nil, fmt.Errorf("%w: minimum buy cannot be negative", ErrInvalidWatchlist)
+	}
+
+	watchlist, err := service.GetWatchlist(ctx, discordUserID, name)
+	if err != nil {
+		return nil, err
+	}
+	if settings.Notify != nil {
+		watchlist.Notify = *settings.Notify
+	}
+	if settings.MinBuyAmount != nil {
+		watchlist.MinBuyAmount = *settings.MinBuyAmount
+	}
+	watchlist.UpdatedAt = service.now()
+	if err := service.repo.SaveWatchlist(ctx, watchlist); err != nil {
+		return nil, fmt.Errorf("failed to save watchlist: %w", err)
+	}
+	return watchlist, nil
+}
+
+// GetWatchlist returns one of a user's watchlists by name, or ErrWatchlistNotFound
+func (service *SubscriptionServiceImpl) GetWatchlist(ctx context.Context, discordUserID, name string) (*models.Watchlist, error) {
+	watchlists, err := service.repo.GetWatchlistsByUser(ctx, discordUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchlists: %w", err)
+	}
+	key := models.NormalizeWatchlistName(name)
+	for _, watchlist := range watchlists {
+		if watchlist.Name == key {
+			return watchlist, nil
+		}
+	}
+	return nil, ErrWatchlistNotFound
+}
+
+// GetWatchlists returns a user's watchlists sorted by name
+func (service *SubscriptionServiceImpl) GetWatchlists(ctx context.Context, discordUserID string) ([]*models.Watchlist, error) {
+	return service.repo.GetWatchlistsByUser(ctx, discordUserID)
+}
+
+// GetAllWatchlists returns every user's watchlists
+func (service *SubscriptionServiceImpl) GetAllWatchlists(ctx context.Context) ([]*models.Watchlist, error) {
+	return service.repo.GetAllWatchlists(ctx)
+}
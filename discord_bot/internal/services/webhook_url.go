@@ -0,0 +1,37 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidWebhookURL is returned for webhook URLs that are not Discord webhook URLs
+var ErrInvalidWebhookURL = errors.New("invalid webhook URL")
+
+// discordWebhookHosts are the hosts Discord serves webhooks on
+var discordWebhookHosts = map[string]bool{
+	"discord.com":        true,
+	"discordapp.com":     true,
+	"ptb.discord.com":    true,
+	"canary.discord.com": true,
+}
+
+// discordWebhookPath matches /api/webhooks/{id}/{token}, with an optional API version after /api
+var discordWebhookPath = regexp.MustCompile(`^/api(?:/v[0-9]+)?/webhooks/([0-9]+)/([A-Za-z0-9_-]+)/?$`)
+
+// ParseDiscordWebhookURL checks that raw is a Discord webhook URL, like
+// https://discord.com/api/webhooks/{id}/{token}, and returns the webhook's ID and token
+func ParseDiscordWebhookURL(raw string) (id, token string, err error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme != "https" || u.User != nil || u.Port() != "" || u.Fragment != "" || !discordWebhookHosts[strings.ToLower(u.Hostname())] {
+		return "", "", fmt.Errorf("%w: expected https://discord.com/api/webhooks/{id}/{token}", ErrInvalidWebhookURL)
+	}
+	match := discordWebhookPath.FindStringSubmatch(u.Path)
+	if match == nil {
+		return "", "", fmt.Errorf("%w: expected https://discord.com/api/webhooks/{id}/{token}", ErrInvalidWebhookURL)
+	}
+	return match[1], match[2], nil
+}
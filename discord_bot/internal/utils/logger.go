@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"io"
 	"log"
 	"os"
 )
@@ -21,6 +22,14 @@ func NewLogger() *Logger {
 	}
 }
 
+// NewQuietLogger creates a logger that drops info messages, for runs such as load tests that would
+// otherwise log every event
+func NewQuietLogger() *Logger {
+	logger := NewLogger()
+	logger.infoLogger = log.New(io.Discard, "", 0)
+	return logger
+}
+
 // Info logs an info message
 func (l *Logger) Info(message string) {
 	l.infoLogger.Println(message)
@@ -0,0 +1,155 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// SetAccountService sets the service linking users to their Coral accounts
+func (h *WebhookHandler) SetAccountService(accounts services.AccountService) {
+	h.accounts = accounts
+}
+
+// HandleLinkConfirm handles POST /discord/link/confirm, called by the backend when a signed-in user
+// enters the code /link_account gave them
+func (h *WebhookHandler) HandleLinkConfirm(w http.ResponseWriter, r *http.Request) {
+	if h.accounts == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Account linking not enabled")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload AccountLinkRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.Code == "" || payload.AccountID == "" {
+		writeJSONError(w, http.StatusBadRequest, "code and account_id required")
+		return
+	}
+
+	discordUserID, err := h.accounts.LinkAccount(r.Context(), payload.Code, payload.AccountID)
+	if errors.Is(err, services.ErrAccountCodeInvalid) {
+		writeJSONError(w, http.StatusNotFound, "code is unknown or expired")
+		return
+	}
+	if errors.Is(err, services.ErrInvalidChannelSettings) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err, "Failed to link account")
+		return
+	}
+	writeAccountLink(w, discordUserID)
+}
+
+// HandleLinkRevoke handles POST /discord/link/revoke, called by the backend when a Coral account
+// disconnects Discord or is deleted
+func (h *WebhookHandler) HandleLinkRevoke(w http.ResponseWriter, r *http.Request) {
+	if h.accounts == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Account linking not enabled")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload AccountUnlinkRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.AccountID == "" {
+		writeJSONError(w, http.StatusBadRequest, "account_id required")
+		return
+	}
+
+	discordUserID, err := h.accounts.UnlinkCoralAccount(r.Context(), payload.AccountID)
+	if errors.Is(err, services.ErrAccountNotLinked) {
+		writeJSONError(w, http.StatusNotFound, "No Discord user is linked to this account")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err, "Failed to unlink account")
+		return
+	}
+	writeAccountLink(w, discordUserID)
+}
+
+// writeAccountLink writes the Discord user an account was linked to or unlinked from
+func writeAccountLink(w http.ResponseWriter, discordUserID string) {
+	b, _ := json.Marshal(AccountLinkResponse{DiscordUserID: discordUserID})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// notifyPositionHolders tells each user whose linked Coral account held a position in a resolved market
+// how it ended, in a message of their own on top of the market's resolution. Snoozed users are skipped
+// like for any other notification, and failures are only logged since the resolution was delivered.
+func (h *WebhookHandler) notifyPositionHolders(ctx context.Context, market *models.Market) {
+	if h.accounts == nil || market.ID == "" || h.discordSession == nil {
+		return
+	}
+	positions, err := h.marketService.FetchMarketPositions(ctx, market.ID)
+	if err != nil {
+		h.logger.Warning(fmt.Sprintf("Failed to fetch positions in resolved market %s: %v", market.ID, err))
+		return
+	}
+	if len(positions) == 0 {
+		return
+	}
+	subscriptions, err := h.subscriptionService.GetAllSubscriptions(ctx)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to get subscriptions: %v", err))
+		return
+	}
+	holders := make(map[string]*models.Subscription)
+	for _, subscription := range subscriptions {
+		if subscription.Account.Linked() {
+			holders[subscription.Account.AccountID] = subscription
+		}
+	}
+	held := make(map[string][]*models.Position)
+	var accountIDs []string
+	for _, position := range positions {
+		if holders[position.AccountID] == nil {
+			continue
+		}
+		if held[position.AccountID] == nil {
+			accountIDs = append(accountIDs, position.AccountID)
+		}
+		held[position.AccountID] = append(held[position.AccountID], position)
+	}
+
+	now := time.Now()
+	batch := h.newFanoutBatch(models.EventPriority(models.EventMarketResolved))
+	for _, accountID := range accountIDs {
+		subscription := holders[accountID]
+		if subscription.Snoozed(now) {
+			continue
+		}
+		notification := &eventNotification{
+			eventType: models.EventMarketResolved,
+			content:   renderMessage(ctx, "PositionResolvedMessage", func() string { return h.marketService.CreatePositionResolvedMessage(market, held[accountID]) }),
+		}
+		h.sendToUser(ctx, notification, subscription.DiscordUserID, subscription, batch)
+	}
+	if sent, failed := batch.wait(); sent+failed > 0 {
+		h.logger.Info(fmt.Sprintf("Told %d holders of market %s how their positions resolved, %d failed", sent, market.ID, failed))
+	}
+}
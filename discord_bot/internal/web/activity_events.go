@@ -0,0 +1,37 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// deliverMarketComment validates a market comment event and delivers its message to the users who
+// subscribed to the market and turned on their activity preference
+func (h *WebhookHandler) deliverMarketComment(ctx context.Context, event *MarketEvent) (suppressed bool, err error) {
+	if event.Market.ID == "" {
+		return false, errMarketIDRequired
+	}
+	if strings.TrimSpace(event.Comment) == "" {
+		return false, errors.New("comment required")
+	}
+	msg := renderMessage(ctx, "MarketCommentMessage", func() string {
+		return h.marketService.CreateMarketCommentMessage(&event.Market, event.Author, event.Comment)
+	})
+	h.dispatchToUsers(ctx, &eventNotification{eventType: models.EventMarketComment, content: msg}, func(subscription *models.Subscription) bool {
+		return subscription.Activity && subscribedToMarket(subscription, event.Market.ID)
+	})
+	return false, nil
+}
+
+// subscribedToMarket reports whether a subscription explicitly follows a market
+func subscribedToMarket(subscription *models.Subscription, marketID string) bool {
+	for _, subscribed := range subscription.SubscribedMarkets {
+		if subscribed == marketID {
+			return true
+		}
+	}
+	return false
+}
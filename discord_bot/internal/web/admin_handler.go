@@ -0,0 +1,398 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// defaultAnalyticsWindow is used when no window is requested
+const defaultAnalyticsWindow = 24 * time.Hour
+
+// HandleAdminAnalytics handles GET /discord/admin/analytics
+//
+// The window is selected with either ?window=<duration> (e.g. 1h, 24h, 7d) ending now,
+// or an explicit ?from=<RFC3339>&to=<RFC3339> range.
+func (h *WebhookHandler) HandleAdminAnalytics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if h.analyticsService == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Analytics not enabled")
+		return
+	}
+
+	from, to, err := parseTimeWindow(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	summary, err := h.analyticsService.GetSummary(r.Context(), from, to)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to build analytics summary: %v", err))
+		writeServiceError(w, err, "Failed to load analytics")
+		return
+	}
+
+	b, _ := json.Marshal(summary)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// maxLeaderboardSize caps the limit accepted by the leaderboard endpoint
+const maxLeaderboardSize = 100
+
+// HandleAdminLeaderboard handles GET /discord/admin/leaderboard
+//
+// The number of markets and creators returned is selected with ?limit=<n> (default 10, max 100).
+func (h *WebhookHandler) HandleAdminLeaderboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxLeaderboardSize {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxLeaderboardSize))
+			return
+		}
+		limit = parsed
+	}
+
+	leaderboard, err := h.subscriptionService.GetLeaderboard(r.Context(), limit)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to build leaderboard: %v", err))
+		writeServiceError(w, err, "Failed to load leaderboard")
+		return
+	}
+
+	b, _ := json.Marshal(leaderboard)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// Audit log query limits
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// HandleAdminAudit handles GET /discord/admin/audit
+//
+// Entries are filtered with ?channel_id=<id> and ?resource_type=<channel_config|webhook_registration>,
+// and capped with ?limit=<n> (default 50, max 500). The most recent entries are returned first.
+func (h *WebhookHandler) HandleAdminAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.AuditFilter{
+		ChannelID:    query.Get("channel_id"),
+		ResourceType: query.Get("resource_type"),
+		Limit:        defaultAuditLimit,
+	}
+	switch filter.ResourceType {
+	case "", models.AuditResourceChannelConfig, models.AuditResourceWebhook, models.AuditResourceCategoryRoute, models.AuditResourceRoutingRule, models.AuditResourceLimitOverride:
+	default:
+		writeJSONError(w, http.StatusBadRequest, "resource_type must be channel_config, webhook_registration, category_route, routing_rule or limit_override")
+		return
+	}
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxAuditLimit {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxAuditLimit))
+			return
+		}
+		filter.Limit = parsed
+	}
+
+	entries, err := h.subscriptionService.GetAuditLog(r.Context(), filter)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to load audit log: %v", err))
+		writeServiceError(w, err, "Failed to load audit log")
+		return
+	}
+
+	b, _ := json.Marshal(AuditLogResponse{Entries: entries})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// HandleAdminSubscriptions handles GET /discord/admin/subscriptions
+//
+// Users are listed by ID, filtered with ?market_id=<id> (the market or one of its outcomes) and
+// ?creator=<name>, and paged with ?limit=<n> and ?cursor=<next_cursor>.
+func (h *WebhookHandler) HandleAdminSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	p, err := parsePage(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	subscriptions, err := h.subscriptionService.GetAllSubscriptions(r.Context())
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to load subscriptions: %v", err))
+		writeServiceError(w, err, "Failed to load subscriptions")
+		return
+	}
+	marketID, creator := r.URL.Query().Get("market_id"), r.URL.Query().Get("creator")
+	matching := []*models.Subscription{}
+	for _, subscription := range subscriptions {
+		if (marketID == "" || followsMarket(subscription, marketID)) && (creator == "" || followsCreator(subscription, creator)) {
+			matching = append(matching, subscription)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return matching[i].DiscordUserID < matching[j].DiscordUserID
+	})
+
+	b, _ := json.Marshal(SubscriptionsResponse{Subscriptions: paginate(matching, p), Total: len(matching), NextCursor: p.next(len(matching))})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// followsMarket reports whether a user is subscribed to a market or one of its outcomes
+func followsMarket(subscription *models.Subscription, marketID string) bool {
+	for _, market := range subscription.SubscribedMarkets {
+		if market == marketID {
+			return true
+		}
+	}
+	for _, outcome := range subscription.SubscribedOutcomes {
+		if outcome.MarketID == marketID {
+			return true
+		}
+	}
+	return false
+}
+
+// followsCreator reports whether a user is subscribed to a creator
+func followsCreator(subscription *models.Subscription, creator string) bool {
+	for _, name := range subscription.SubscribedCreators {
+		if name == creator {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleAdminChannels handles GET /discord/admin/channels
+//
+// Configured channels are listed by ID, filtered with ?guild_id=<id> and ?market_id=<id> (channels
+// following the market), and paged with ?limit=<n> and ?cursor=<next_cursor>.
+func (h *WebhookHandler) HandleAdminChannels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	p, err := parsePage(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	configs, err := h.subscriptionService.GetAllChannelConfigs(r.Context())
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to load channel configs: %v", err))
+		writeServiceError(w, err, "Failed to load channels")
+		return
+	}
+	guildID, marketID := r.URL.Query().Get("guild_id"), r.URL.Query().Get("market_id")
+	matching := []*models.ChannelConfig{}
+	for _, config := range configs {
+		if guildID != "" && config.GuildID != guildID {
+			continue
+		}
+		if marketID != "" && !channelFollows(config, marketID) {
+			continue
+		}
+		matching = append(matching, config)
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return matching[i].ChannelID < matching[j].ChannelID
+	})
+
+	b, _ := json.Marshal(ChannelsResponse{Channels: paginate(matching, p), Total: len(matching), NextCursor: p.next(len(matching))})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// channelFollows reports whether a channel is subscribed to a market
+func channelFollows(config *models.ChannelConfig, marketID string) bool {
+	for _, market := range config.SubscribedMarkets {
+		if market == marketID {
+			return true
+		}
+	}
+	return false
+}
+
+// broadcastEventType identifies operator announcements in delivery analytics
+const broadcastEventType = "announcement"
+
+// maxBroadcastLength leaves room for the announcement header within Discord's 2000 character limit
+const maxBroadcastLength = 1900
+
+// HandleAdminBroadcast handles POST /discord/admin/broadcast
+//
+// The message is posted to every channel with the new market feed enabled and to the default
+// channel of guilds without any channel configuration. Users are not messaged.
+func (h *WebhookHandler) HandleAdminBroadcast(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload BroadcastRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	message := strings.TrimSpace(payload.Message)
+	if message == "" {
+		writeJSONError(w, http.StatusBadRequest, "message is required")
+		return
+	}
+	if len([]rune(message)) > maxBroadcastLength {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("message must be at most %d characters", maxBroadcastLength))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), dispatchTimeout)
+	defer cancel()
+	notification := &eventNotification{eventType: broadcastEventType, content: "📢 **Announcement**\n\n" + message}
+	batch := h.newFanoutBatch(notification.priority())
+	h.sendToChannels(ctx, notification, nil, func(channelConfig *models.ChannelConfig) (*eventNotification, string) {
+		if !channelConfig.FeedEnabled {
+			return nil, skipBroadcastFeedOff
+		}
+		return notification, ""
+	}, batch)
+	channels, _ := batch.wait()
+	h.logger.Info(fmt.Sprintf("Broadcast announcement to %d channels", channels))
+
+	b, _ := json.Marshal(BroadcastResponse{Accepted: true, Channels: channels})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(b)
+}
+
+// HandleAdminTestEvent handles POST /discord/admin/test-event
+//
+// A synthetic event is delivered to the requested channel only, regardless of its feed settings,
+// so operators can verify a new server's setup without announcing anything elsewhere.
+func (h *WebhookHandler) HandleAdminTestEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload TestEventRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.ChannelID == "" {
+		writeJSONError(w, http.StatusBadRequest, "channel_id is required")
+		return
+	}
+	if payload.EventType == "" {
+		payload.EventType = models.EventNewMarket
+	}
+
+	err = h.SendTestEvent(r.Context(), payload.EventType, payload.ChannelID)
+	switch {
+	case errors.Is(err, ErrUnknownTestEvent):
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("event_type must be one of %s", strings.Join(TestEventTypes, ", ")))
+		return
+	case errors.Is(err, ErrDiscordSessionNotSet):
+		writeJSONError(w, http.StatusServiceUnavailable, "Discord session not available")
+		return
+	case err != nil:
+		writeJSONError(w, http.StatusBadGateway, "Failed to send test event: "+err.Error())
+		return
+	}
+
+	b, _ := json.Marshal(TestEventResponse{Accepted: true, EventType: payload.EventType, ChannelID: payload.ChannelID})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(b)
+}
+
+// parseTimeWindow reads the from/to or window query parameters of an admin request
+func parseTimeWindow(r *http.Request) (time.Time, time.Time, error) {
+	query := r.URL.Query()
+	to := time.Now()
+	if raw := query.Get("to"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to, expected RFC3339")
+		}
+		to = parsed
+	}
+
+	if raw := query.Get("from"); raw != "" {
+		from, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from, expected RFC3339")
+		}
+		if !from.Before(to) {
+			return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+		}
+		return from, to, nil
+	}
+
+	window := defaultAnalyticsWindow
+	if raw := query.Get("window"); raw != "" {
+		parsed, err := parseWindowDuration(raw)
+		if err != nil || parsed <= 0 {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid window, use a duration like 1h, 24h or 7d")
+		}
+		window = parsed
+	}
+	return to.Add(-window), to, nil
+}
+
+// parseWindowDuration parses a Go duration, additionally accepting a day suffix such as 7d
+func parseWindowDuration(raw string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(raw)
+}
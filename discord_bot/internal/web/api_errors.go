@@ -0,0 +1,137 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"coral-bot/discord_bot/internal/services"
+)
+
+// Error codes in ErrorResponse.Code. Clients should branch on these rather than on the message,
+// which is meant for people and may change.
+const (
+	ErrorCodeInvalidRequest     = "invalid_request"
+	ErrorCodeUnauthenticated    = "unauthenticated"
+	ErrorCodeForbidden          = "forbidden"
+	ErrorCodeNotFound           = "not_found"
+	ErrorCodeMethodNotAllowed   = "method_not_allowed"
+	ErrorCodeConflict           = "conflict"
+	ErrorCodeLimitExceeded      = "limit_exceeded"
+	ErrorCodeRateLimited        = "rate_limited"
+	ErrorCodeInternal           = "internal_error"
+	ErrorCodeUpstreamFailed     = "upstream_failed"
+	ErrorCodeUnavailable        = "unavailable"
+	ErrorCodeBackendUnavailable = "backend_unavailable"
+	ErrorCodeTimeout            = "timeout"
+)
+
+// requestIDHeader carries the ID of a request, taken from the caller or generated, on every response
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds a caller's request ID so it can be echoed and logged safely
+const maxRequestIDLength = 128
+
+// statusErrorCodes is the code of an error written with only a status
+var statusErrorCodes = map[int]string{
+	http.StatusBadRequest:          ErrorCodeInvalidRequest,
+	http.StatusUnauthorized:        ErrorCodeUnauthenticated,
+	http.StatusForbidden:           ErrorCodeForbidden,
+	http.StatusNotFound:            ErrorCodeNotFound,
+	http.StatusMethodNotAllowed:    ErrorCodeMethodNotAllowed,
+	http.StatusConflict:            ErrorCodeConflict,
+	http.StatusTooManyRequests:     ErrorCodeRateLimited,
+	http.StatusInternalServerError: ErrorCodeInternal,
+	http.StatusBadGateway:          ErrorCodeUpstreamFailed,
+	http.StatusServiceUnavailable:  ErrorCodeUnavailable,
+	http.StatusGatewayTimeout:      ErrorCodeTimeout,
+}
+
+// apiError is a failure ready to be written as an ErrorResponse
+type apiError struct {
+	status  int
+	code    string
+	message string
+	details map[string]interface{}
+}
+
+// errorCodeForStatus returns the code of an error written with only a status
+func errorCodeForStatus(status int) string {
+	if code, ok := statusErrorCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return ErrorCodeInternal
+	}
+	return ErrorCodeInvalidRequest
+}
+
+// writeJSONError writes an ErrorResponse with the given status and the status's error code
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeAPIError(w, apiError{status: status, code: errorCodeForStatus(status), message: message})
+}
+
+// writeServiceError writes the ErrorResponse for an error returned by a service: the status and code of
+// the errors clients can act on, or a 500 with message for anything else
+func writeServiceError(w http.ResponseWriter, err error, message string) {
+	writeAPIError(w, mapServiceError(err, message))
+}
+
+// mapServiceError maps an error returned by a service to its status, code and details. Limits explain
+// themselves and keep their message; the rest use message so internal details are not leaked.
+func mapServiceError(err error, message string) apiError {
+	var limitErr *services.LimitExceededError
+	switch {
+	case errors.As(err, &limitErr):
+		return apiError{status: http.StatusConflict, code: ErrorCodeLimitExceeded, message: limitErr.Error(),
+			details: map[string]interface{}{"kind": limitErr.Kind, "limit": limitErr.Limit}}
+	case errors.Is(err, services.ErrBackendNotConfigured):
+		return apiError{status: http.StatusServiceUnavailable, code: ErrorCodeBackendUnavailable, message: message}
+	case errors.Is(err, services.ErrBackendNotFound):
+		return apiError{status: http.StatusNotFound, code: ErrorCodeNotFound, message: message}
+	case errors.Is(err, ErrDiscordSessionNotSet):
+		return apiError{status: http.StatusServiceUnavailable, code: ErrorCodeUnavailable, message: message}
+	case errors.Is(err, context.DeadlineExceeded):
+		return apiError{status: http.StatusGatewayTimeout, code: ErrorCodeTimeout, message: message}
+	}
+	return apiError{status: http.StatusInternalServerError, code: ErrorCodeInternal, message: message}
+}
+
+// writeAPIError writes apiErr as an ErrorResponse tagged with the request's ID
+func writeAPIError(w http.ResponseWriter, apiErr apiError) {
+	b, _ := json.Marshal(ErrorResponse{
+		Code:      apiErr.code,
+		Message:   apiErr.message,
+		Details:   apiErr.details,
+		RequestID: w.Header().Get(requestIDHeader),
+		Error:     apiErr.message,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.status)
+	w.Write(b)
+}
+
+// requestID returns the caller's X-Request-ID if it is usable, or a new random ID
+func requestID(r *http.Request) string {
+	if id := r.Header.Get(requestIDHeader); id != "" && len(id) <= maxRequestIDLength && printableASCII(id) {
+		return id
+	}
+	randomBytes := make([]byte, 8)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(randomBytes)
+}
+
+// printableASCII reports whether s holds only printable ASCII characters
+func printableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
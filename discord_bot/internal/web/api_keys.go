@@ -0,0 +1,154 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// HandleCreateAPIKey handles POST /discord/admin/api-keys
+func (h *WebhookHandler) HandleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if h.apiKeyService == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "API keys not enabled")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload CreateAPIKeyRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if strings.TrimSpace(payload.Name) == "" {
+		writeJSONError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	key, secret, err := h.apiKeyService.CreateKey(r.Context(), strings.TrimSpace(payload.Name), payload.Scopes)
+	if errors.Is(err, services.ErrInvalidScope) {
+		writeJSONError(w, http.StatusBadRequest, err.Error()+"; valid scopes are "+strings.Join(models.Scopes, ", "))
+		return
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to create API key: %v", err))
+		writeServiceError(w, err, "Failed to create API key")
+		return
+	}
+
+	b, _ := json.Marshal(CreateAPIKeyResponse{APIKey: key, Secret: secret})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(b)
+}
+
+// HandleListAPIKeys handles GET /discord/admin/api-keys
+func (h *WebhookHandler) HandleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if h.apiKeyService == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "API keys not enabled")
+		return
+	}
+
+	keys, err := h.apiKeyService.ListKeys(r.Context())
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to list API keys: %v", err))
+		writeServiceError(w, err, "Failed to list API keys")
+		return
+	}
+
+	b, _ := json.Marshal(APIKeysResponse{Keys: keys})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// HandleRevokeAPIKey handles DELETE /discord/admin/api-keys/{id}
+//
+// With ?grace=<duration>, such as 24h or 7d, the key keeps working until the grace period is over.
+func (h *WebhookHandler) HandleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if h.apiKeyService == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "API keys not enabled")
+		return
+	}
+	var grace time.Duration
+	if raw := r.URL.Query().Get("grace"); raw != "" {
+		parsed, err := parseWindowDuration(raw)
+		if err != nil || parsed <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "grace must be a duration like 1h, 24h or 7d")
+			return
+		}
+		grace = parsed
+	}
+
+	_, err := h.apiKeyService.RetireKey(r.Context(), r.PathValue("id"), grace)
+	if errors.Is(err, services.ErrAPIKeyNotFound) {
+		writeJSONError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to revoke API key: %v", err))
+		writeServiceError(w, err, "Failed to revoke API key")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleRotateAPIKey handles POST /discord/admin/api-keys/{id}/rotate
+//
+// The new key has the same name and scopes. The old key keeps working for the grace period, or until
+// it is revoked when there is none, so the client can switch keys without a request being refused.
+func (h *WebhookHandler) HandleRotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if h.apiKeyService == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "API keys not enabled")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload RotateAPIKeyRequest
+	if len(body) > 0 {
+		if err := decodePayload(r.Context(), body, &payload); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+			return
+		}
+	}
+	var grace time.Duration
+	if payload.Grace != "" {
+		grace, err = parseWindowDuration(payload.Grace)
+		if err != nil || grace <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "grace must be a duration like 1h, 24h or 7d")
+			return
+		}
+	}
+
+	key, secret, err := h.apiKeyService.RotateKey(r.Context(), r.PathValue("id"), grace)
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		writeJSONError(w, http.StatusNotFound, "API key not found")
+		return
+	case errors.Is(err, services.ErrAPIKeyInactive):
+		writeJSONError(w, http.StatusConflict, "API key is revoked or expired; create a new key instead")
+		return
+	case err != nil:
+		h.logger.Error(fmt.Sprintf("Failed to rotate API key: %v", err))
+		writeServiceError(w, err, "Failed to rotate API key")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("API key %s rotated to %s by %s", r.PathValue("id"), key.ID, apiActor(r)))
+
+	b, _ := json.Marshal(CreateAPIKeyResponse{APIKey: key, Secret: secret})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(b)
+}
@@ -0,0 +1,569 @@
+package web
+
+import (
+	"encoding/json"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// LegacyMarketEventRequest is the body of the /webhooks/* market event endpoints
+type LegacyMarketEventRequest struct {
+	EventType string        `json:"event_type"` // must match the endpoint, e.g. new_market
+	Market    models.Market `json:"market"`
+}
+
+// EventOutcome is a market outcome as sent by the backend in event payloads
+type EventOutcome struct {
+	ID   string  `json:"id"`
+	Name string  `json:"name"`
+	Pct  float64 `json:"pct,omitempty"`
+}
+
+// NewMarketEventRequest is the body of POST /discord/events/new-market
+type NewMarketEventRequest struct {
+	MarketID    string         `json:"market_id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Creator     string         `json:"creator"`
+	Category    string         `json:"category"`
+	Outcomes    []EventOutcome `json:"outcomes"`
+	StartTime   string         `json:"start_time"` // RFC3339
+	EndTime     string         `json:"end_time"`   // RFC3339
+	Volume      float64        `json:"volume"`
+	Link        string         `json:"link"`
+}
+
+// MarketUpdateEventRequest is the body of POST /discord/events/market-update
+type MarketUpdateEventRequest struct {
+	MarketID       string         `json:"market_id"`
+	Title          string         `json:"title"`
+	Volume         float64        `json:"volume"`
+	VolumeDeltaPct float64        `json:"volume_delta_pct"`
+	TimeLeft       string         `json:"time_left"`
+	EndTime        string         `json:"end_time"` // RFC3339
+	Link           string         `json:"link"`
+	Outcomes       []EventOutcome `json:"outcomes"`
+}
+
+// TradingStartEventRequest is the body of POST /discord/events/trading-start
+type TradingStartEventRequest struct {
+	MarketID      string   `json:"market_id"`
+	Title         string   `json:"title"`
+	Description   string   `json:"description"`
+	Duration      string   `json:"duration"`
+	OutcomesCount int      `json:"outcomes_count"`
+	Outcomes      []string `json:"outcomes"`
+	Link          string   `json:"link"`
+}
+
+// TradingEndEventRequest is the body of POST /discord/events/trading-end
+type TradingEndEventRequest struct {
+	MarketID    string         `json:"market_id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Outcomes    []EventOutcome `json:"outcomes"`
+	FinalPool   float64        `json:"final_pool"`
+	Link        string         `json:"link"`
+}
+
+// MarketResolvedEventRequest is the body of POST /discord/events/market-resolved
+type MarketResolvedEventRequest struct {
+	MarketID       string          `json:"market_id"`
+	Title          string          `json:"title"`
+	WinningOutcome string          `json:"winning_outcome"`
+	Payouts        []models.Payout `json:"payouts,omitempty"` // for split resolutions, each outcome's final odds and pool share
+	TotalPool      float64         `json:"total_pool"`
+	Link           string          `json:"link"`
+	ResolvedAt     time.Time       `json:"resolved_at,omitempty"` // RFC 3339, defaults to when the event is received
+}
+
+// MarketCancelledEventRequest is the body of POST /discord/events/market-cancelled
+type MarketCancelledEventRequest struct {
+	MarketID    string    `json:"market_id"`
+	Title       string    `json:"title"`
+	Reason      string    `json:"reason"`
+	RefundTotal float64   `json:"refund_total"` // amount returned to traders, usually the whole pool
+	Link        string    `json:"link"`
+	CancelledAt time.Time `json:"cancelled_at,omitempty"` // RFC 3339, defaults to when the event is received
+}
+
+// MarketBuyEventRequest is the body of POST /discord/events/market-buy
+type MarketBuyEventRequest struct {
+	MarketID string  `json:"market_id"`
+	Title    string  `json:"title"`
+	Amount   float64 `json:"amount"`
+	Outcome  string  `json:"outcome"`
+	Buyer    string  `json:"buyer"`
+	Link     string  `json:"link"`
+}
+
+// MarketLiquidityEventRequest is the body of POST /discord/events/market-liquidity
+type MarketLiquidityEventRequest struct {
+	MarketID  string         `json:"market_id"`
+	Title     string         `json:"title"`
+	Change    float64        `json:"change"`    // liquidity added, negative when it was removed
+	Liquidity float64        `json:"liquidity"` // the market's liquidity after the change, optional
+	Outcomes  []EventOutcome `json:"outcomes"`  // odds after the change
+	Link      string         `json:"link"`
+}
+
+// MarketCommentEventRequest is the body of POST /discord/events/market-comment
+type MarketCommentEventRequest struct {
+	MarketID string `json:"market_id"`
+	Title    string `json:"title"`
+	Author   string `json:"author"`
+	Comment  string `json:"comment"`
+	Link     string `json:"link"`
+}
+
+// CreatorJoinedEventRequest is the body of POST /discord/events/creator-joined
+type CreatorJoinedEventRequest struct {
+	Creator     string `json:"creator"` // handle users subscribe to with /subscribe_creator
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	Link        string `json:"link"`
+}
+
+// CreatorMilestoneEventRequest is the body of POST /discord/events/creator-milestone
+type CreatorMilestoneEventRequest struct {
+	Creator     string  `json:"creator"`
+	DisplayName string  `json:"display_name"`
+	Volume      float64 `json:"volume"` // total volume milestone the creator's markets reached
+	Link        string  `json:"link"`
+}
+
+// BatchEventRequest is the body of POST /discord/events/batch
+type BatchEventRequest struct {
+	Events []BatchEvent `json:"events"`
+}
+
+// BatchEvent is one event of a batch. Payload is the body the event type's own endpoint accepts.
+type BatchEvent struct {
+	Type    string          `json:"type"` // new_market, market_update, trading_started, trading_ended, market_resolved, market_cancelled, market_buy, market_liquidity, market_comment, creator_joined or creator_milestone
+	Payload json.RawMessage `json:"payload"`
+}
+
+// BatchEventResponse reports the outcome of each event of a batch, in request order
+type BatchEventResponse struct {
+	Accepted int                `json:"accepted"`
+	Failed   int                `json:"failed"`
+	Results  []BatchEventResult `json:"results"`
+}
+
+// BatchEventResult is the outcome of one event of a batch
+type BatchEventResult struct {
+	Index      int                   `json:"index"`
+	Type       string                `json:"type"`
+	Accepted   bool                  `json:"accepted"`
+	Suppressed bool                  `json:"suppressed,omitempty"`
+	EventID    string                `json:"event_id,omitempty"`
+	Delivery   *models.DeliveryStats `json:"delivery,omitempty"`
+	Error      string                `json:"error,omitempty"`
+}
+
+// DirectNotificationRequest is the body of POST /discord/notifications/dm
+type DirectNotificationRequest struct {
+	DiscordUserID string                 `json:"discord_user_id"`
+	Type          string                 `json:"type"` // market_update, trading_start, trading_end, market_resolved or market_buy
+	Data          map[string]interface{} `json:"payload"`
+}
+
+// RegisterWebhookRequest is the body of POST /discord/webhooks/register
+type RegisterWebhookRequest struct {
+	ChannelID         string   `json:"channel_id"`
+	WebhookURL        string   `json:"webhook_url"`
+	Events            []string `json:"events"`
+	Frequency         string   `json:"frequency"`
+	AllowedCategories []string `json:"allowed_categories"`
+	TestPing          *bool    `json:"test_ping,omitempty"` // post a test message through the webhook first, default WEBHOOK_TEST_PING
+}
+
+// UnregisterWebhookRequest is the body of /discord/webhooks/unregister
+type UnregisterWebhookRequest struct {
+	ID string `json:"id"`
+}
+
+// MarketSubscriptionRequest is the body of the user market subscribe and unsubscribe endpoints
+type MarketSubscriptionRequest struct {
+	DiscordUserID string `json:"discord_user_id"`
+	MarketID      string `json:"market_id"`
+}
+
+// CreatorSubscriptionRequest is the body of the user creator subscribe and unsubscribe endpoints
+type CreatorSubscriptionRequest struct {
+	DiscordUserID string `json:"discord_user_id"`
+	CreatorID     string `json:"creator_id"`
+}
+
+// OutcomeSubscriptionRequest is the body of the user outcome subscribe and unsubscribe endpoints
+type OutcomeSubscriptionRequest struct {
+	DiscordUserID string  `json:"discord_user_id"`
+	MarketID      string  `json:"market_id"`
+	Outcome       string  `json:"outcome"`
+	MinChange     float64 `json:"min_change,omitempty"` // subscribe only, defaults to 5 points
+}
+
+// UserTimezoneRequest is the body of POST /discord/timezone
+type UserTimezoneRequest struct {
+	DiscordUserID string `json:"discord_user_id"`
+	Timezone      string `json:"timezone"` // IANA zone, empty for the user's Discord locale
+}
+
+// AccountLinkRequest is the body of POST /discord/link/confirm
+type AccountLinkRequest struct {
+	Code      string `json:"code"`       // code the user got from /link_account
+	AccountID string `json:"account_id"` // Coral account signed in when the code was entered
+}
+
+// AccountUnlinkRequest is the body of POST /discord/link/revoke
+type AccountUnlinkRequest struct {
+	AccountID string `json:"account_id"`
+}
+
+// AccountLinkResponse is returned by the account linking endpoints
+type AccountLinkResponse struct {
+	DiscordUserID string `json:"discord_user_id"`
+}
+
+// ChannelFeedNewMarketsRequest is the body of POST /discord/channel/feed/new_markets
+type ChannelFeedNewMarketsRequest struct {
+	ChannelID string `json:"channel_id"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// ChannelFeedCategoriesRequest is the body of POST /discord/channel/feed/categories
+type ChannelFeedCategoriesRequest struct {
+	ChannelID         string   `json:"channel_id"`
+	AllowedCategories []string `json:"allowed_categories"`
+}
+
+// ChannelFeedFrequencyRequest is the body of POST /discord/channel/feed/frequency
+type ChannelFeedFrequencyRequest struct {
+	ChannelID string `json:"channel_id"`
+	Frequency string `json:"frequency"`          // low, medium, high or custom
+	Interval  string `json:"interval,omitempty"` // update interval of the custom frequency, like 45m
+}
+
+// UpdateIntervalsRequest is the body of PUT /discord/guild/update_intervals/{guild_id}. Intervals are
+// durations like 45m or 2h; omitted ones keep the bot's.
+type UpdateIntervalsRequest struct {
+	High                string `json:"high,omitempty"`
+	Medium              string `json:"medium,omitempty"`
+	Low                 string `json:"low,omitempty"`
+	ClosingSoonWindow   string `json:"closing_soon_window,omitempty"`
+	ClosingSoonInterval string `json:"closing_soon_interval,omitempty"`
+}
+
+// UpdateIntervalsResponse is the update intervals a guild's channels follow
+type UpdateIntervalsResponse struct {
+	GuildID             string `json:"guild_id"`
+	Overridden          bool   `json:"overridden"` // the guild has intervals of its own
+	High                string `json:"high"`
+	Medium              string `json:"medium"`
+	Low                 string `json:"low"`
+	ClosingSoonWindow   string `json:"closing_soon_window"`
+	ClosingSoonInterval string `json:"closing_soon_interval"`
+}
+
+// GuildBrandingRequest is the body of PUT /discord/guild/branding/{guild_id}
+type GuildBrandingRequest struct {
+	Emojis      map[string]string `json:"emojis,omitempty"`       // header emoji by event type, like {"new_market": "<:coral:123456789012345678>"}
+	AccentColor string            `json:"accent_color,omitempty"` // hex RGB like #1abc9c, omitted to post plain messages
+	FooterText  string            `json:"footer_text,omitempty"`
+	FooterURL   string            `json:"footer_url,omitempty"`
+}
+
+// GuildBrandingResponse is the branding of a guild's alerts
+type GuildBrandingResponse struct {
+	GuildID     string            `json:"guild_id"`
+	Branded     bool              `json:"branded"` // the guild has branding of its own
+	Emojis      map[string]string `json:"emojis"`
+	AccentColor string            `json:"accent_color"`
+	FooterText  string            `json:"footer_text"`
+	FooterURL   string            `json:"footer_url"`
+}
+
+// RoutingRuleRequest is the body of POST /discord/guild/routing_rules/{guild_id}
+type RoutingRuleRequest struct {
+	ChannelID       string   `json:"channel_id,omitempty"` // channel the rule applies to, omitted for every channel of the guild
+	Position        int      `json:"position,omitempty"`   // 1 for the first rule, omitted to add it last
+	EventTypes      []string `json:"event_types,omitempty"`
+	Categories      []string `json:"categories,omitempty"`
+	Creators        []string `json:"creators,omitempty"`
+	Keywords        []string `json:"keywords,omitempty"`
+	MinVolume       float64  `json:"min_volume,omitempty"`
+	MaxVolume       float64  `json:"max_volume,omitempty"`
+	Action          string   `json:"action"`                      // send, suppress, ping or route
+	RoleID          string   `json:"role_id,omitempty"`           // required to ping
+	TargetChannelID string   `json:"target_channel_id,omitempty"` // required to route
+}
+
+// RoutingRuleMoveRequest is the body of POST /discord/guild/routing_rules/{guild_id}/{id}/move
+type RoutingRuleMoveRequest struct {
+	Position int `json:"position"` // 1 for the first rule, 0 for the last
+}
+
+// RoutingRulesResponse lists a guild's routing rules in the order they are evaluated
+type RoutingRulesResponse struct {
+	GuildID string                `json:"guild_id"`
+	Rules   []*models.RoutingRule `json:"rules"`
+}
+
+// ChannelMarketSubscriptionRequest is the body of the channel market subscribe and unsubscribe endpoints
+type ChannelMarketSubscriptionRequest struct {
+	ChannelID string `json:"channel_id"`
+	MarketID  string `json:"market_id"`
+}
+
+// ChannelClosingSoonRequest is the body of POST /discord/channel/feed/closing_soon
+type ChannelClosingSoonRequest struct {
+	ChannelID string `json:"channel_id"`
+	Hours     int    `json:"hours"` // 0 to 168, 0 disables the closing-soon feed
+}
+
+// ChannelTimezoneRequest is the body of POST /discord/channel/timezone
+type ChannelTimezoneRequest struct {
+	ChannelID string `json:"channel_id"`
+	Timezone  string `json:"timezone"` // IANA zone, empty for each reader's Discord locale
+}
+
+// ChannelDigestRequest is the body of POST /discord/channel/digest
+type ChannelDigestRequest struct {
+	ChannelID string `json:"channel_id"`
+	Mode      string `json:"mode"` // daily, weekly or off
+}
+
+// ChannelCrosspostRequest is the body of POST /discord/channel/crosspost
+type ChannelCrosspostRequest struct {
+	ChannelID string `json:"channel_id"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// ChannelLiquidityRequest is the body of POST /discord/channel/liquidity
+type ChannelLiquidityRequest struct {
+	ChannelID string `json:"channel_id"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// ChannelShadowModeRequest is the body of POST /discord/channel/shadow_mode
+type ChannelShadowModeRequest struct {
+	ChannelID string `json:"channel_id"`
+	Enabled   bool   `json:"enabled"` // log the channel's messages instead of sending them
+}
+
+// ChannelCalendarRequest is the body of POST /discord/channel/calendar
+type ChannelCalendarRequest struct {
+	ChannelID string `json:"channel_id"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// ChannelPingRequest is the body of POST /discord/channel/feed/ping
+type ChannelPingRequest struct {
+	ChannelID string   `json:"channel_id"`
+	RoleID    string   `json:"role_id"`          // role mentioned in the channel's alerts, empty to mention nobody
+	Events    []string `json:"events,omitempty"` // events whose alerts mention the role, new_market and market_resolved when empty
+}
+
+// ChannelBoardRequest is the body of POST /discord/channel/board
+type ChannelBoardRequest struct {
+	ChannelID string `json:"channel_id"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// ChannelQuietHoursRequest is the body of POST /discord/channel/quiet_hours
+type ChannelQuietHoursRequest struct {
+	ChannelID string `json:"channel_id"`
+	Hours     string `json:"hours"` // HH:MM-HH:MM in the channel's timezone, or off
+}
+
+// ChannelSettingsCopyRequest is the body of POST /discord/channel/settings/copy
+type ChannelSettingsCopyRequest struct {
+	SourceChannelID string `json:"source_channel_id"`
+	TargetChannelID string `json:"target_channel_id"`
+}
+
+// BulkChannelSettings is one channel of the body of POST /discord/channel/settings/bulk, a JSON array
+type BulkChannelSettings struct {
+	ChannelID string                  `json:"channel_id"`
+	GuildID   string                  `json:"guild_id,omitempty"` // kept as the bot knows it when empty
+	Settings  *models.ChannelSettings `json:"settings"`           // as exported by GET /discord/channel/settings/{channel_id}/export
+}
+
+// BulkChannelSettingsResponse is returned by POST /discord/channel/settings/bulk
+type BulkChannelSettingsResponse struct {
+	Channels []*models.ChannelConfig `json:"channels"` // in the order of the request
+}
+
+// ErrorResponse is returned with every 4xx and 5xx status
+type ErrorResponse struct {
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Error     string                 `json:"error"` // Same as Message, kept for clients written before codes
+}
+
+// AcceptedResponse is returned when an event has been queued for delivery
+type AcceptedResponse struct {
+	Accepted   bool                  `json:"accepted"`
+	Suppressed bool                  `json:"suppressed,omitempty"` // the event was dropped by a filter such as MIN_BUY_AMOUNT
+	EventID    string                `json:"event_id,omitempty"`   // look up the event's delivery report with it
+	Delivery   *models.DeliveryStats `json:"delivery,omitempty"`   // recipients the event was sent or queued to, and failed sends
+}
+
+// OKResponse is returned by endpoints that only report success
+type OKResponse struct {
+	OK bool `json:"ok"`
+}
+
+// SubscriptionStatusResponse is returned by the subscribe and unsubscribe endpoints
+type SubscriptionStatusResponse struct {
+	Subscribed bool `json:"subscribed"`
+}
+
+// UserSubscriptionsResponse is returned by GET /discord/subscriptions/{discord_user_id}. Each list is
+// paged with the same limit and cursor.
+type UserSubscriptionsResponse struct {
+	Markets    []string                     `json:"markets"`
+	Creators   []string                     `json:"creators"`
+	Outcomes   []models.OutcomeSubscription `json:"outcomes"`
+	Total      UserSubscriptionTotals       `json:"total"`                 // matching subscriptions on every page
+	NextCursor string                       `json:"next_cursor,omitempty"` // set while any list has more pages
+}
+
+// UserSubscriptionTotals counts a user's subscriptions matching the filters
+type UserSubscriptionTotals struct {
+	Markets  int `json:"markets"`
+	Creators int `json:"creators"`
+	Outcomes int `json:"outcomes"`
+}
+
+// HealthResponse is returned by GET /discord/health
+type HealthResponse struct {
+	Status      string                      `json:"status"` // ok, or degraded while the Discord gateway is not connected
+	Time        time.Time                   `json:"time"`
+	Gateway     *services.GatewayStatus     `json:"gateway,omitempty"`
+	Fanout      *services.FanoutStatus      `json:"fanout,omitempty"` // load of the pool events are fanned out through
+	Maintenance *services.MaintenanceStatus `json:"maintenance,omitempty"`
+	Events      EventCounts                 `json:"events"`
+}
+
+// EventCounts counts events the bot received but did not deliver, since startup
+type EventCounts struct {
+	SuppressedAfterResolution int64 `json:"suppressed_after_resolution"` // non-resolution events for markets that already resolved
+}
+
+// SubscriptionsResponse is returned by GET /discord/admin/subscriptions
+type SubscriptionsResponse struct {
+	Subscriptions []*models.Subscription `json:"subscriptions"`
+	Total         int                    `json:"total"`                 // matching subscriptions on every page
+	NextCursor    string                 `json:"next_cursor,omitempty"` // empty on the last page
+}
+
+// ChannelsResponse is returned by GET /discord/admin/channels
+type ChannelsResponse struct {
+	Channels   []*models.ChannelConfig `json:"channels"`
+	Total      int                     `json:"total"`                 // matching channels on every page
+	NextCursor string                  `json:"next_cursor,omitempty"` // empty on the last page
+}
+
+// BroadcastRequest is the body of POST /discord/admin/broadcast
+type BroadcastRequest struct {
+	Message string `json:"message"`
+}
+
+// MaintenanceRequest is the body of PUT /discord/admin/maintenance
+type MaintenanceRequest struct {
+	Enabled *bool  `json:"enabled"`
+	Notice  string `json:"notice,omitempty"` // reply to commands during maintenance, a default one when empty
+}
+
+// HTTPLogResponse is returned by GET /discord/admin/http-log
+type HTTPLogResponse struct {
+	Config  services.HTTPLogConfig `json:"config"`
+	Entries []*models.HTTPLogEntry `json:"entries"` // most recent first
+}
+
+// StorageMigrationRequest is the body of POST /discord/admin/storage/migrate
+type StorageMigrationRequest struct {
+	Driver string `json:"driver"` // file
+	Path   string `json:"path"`   // data file to create, on the bot's host
+}
+
+// BroadcastResponse is returned by POST /discord/admin/broadcast
+type BroadcastResponse struct {
+	Accepted bool `json:"accepted"`
+	Channels int  `json:"channels"` // channels the announcement was sent or queued to
+}
+
+// TestEventRequest is the body of POST /discord/admin/test-event
+type TestEventRequest struct {
+	ChannelID string `json:"channel_id"`
+	EventType string `json:"event_type"` // new_market (default), market_update, trading_started, trading_ended, market_resolved or market_buy
+}
+
+// TestEventResponse is returned by POST /discord/admin/test-event
+type TestEventResponse struct {
+	Accepted  bool   `json:"accepted"`
+	EventType string `json:"event_type"`
+	ChannelID string `json:"channel_id"`
+}
+
+// LoadTestRequest is the body of POST /discord/admin/loadtest
+type LoadTestRequest struct {
+	Channels      int  `json:"channels"`          // simulated channels with the market feed on
+	Users         int  `json:"users"`             // simulated users subscribed to the load test market
+	Events        int  `json:"events"`            // synthetic buys sent one after another
+	SendLatencyMs int  `json:"send_latency_ms"`   // time the fake Discord API takes to answer each request
+	Workers       *int `json:"workers,omitempty"` // fan-out workers, as many as the bot's pool when omitted
+}
+
+// CreateAPIKeyRequest is the body of POST /discord/admin/api-keys
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"` // e.g. events:write, admin:read
+}
+
+// CreateAPIKeyResponse is returned by POST /discord/admin/api-keys
+type CreateAPIKeyResponse struct {
+	APIKey *models.APIKey `json:"api_key"`
+	Secret string         `json:"secret"` // only returned here; send it as X-API-Key or a bearer token
+}
+
+// RotateAPIKeyRequest is the body of POST /discord/admin/api-keys/{id}/rotate
+type RotateAPIKeyRequest struct {
+	Grace string `json:"grace,omitempty"` // how long the old key keeps working, e.g. 24h or 7d; until it is revoked when empty
+}
+
+// APIKeysResponse is returned by GET /discord/admin/api-keys
+type APIKeysResponse struct {
+	Keys []*models.APIKey `json:"keys"`
+}
+
+// DeadLettersResponse is returned by GET /discord/admin/dead-letters
+type DeadLettersResponse struct {
+	DeadLetters []*models.DeadLetter `json:"dead_letters"`
+}
+
+// LimitsResponse is returned by GET /discord/admin/limits
+type LimitsResponse struct {
+	Defaults  models.SubscriptionLimits `json:"defaults"`
+	Overrides []*models.LimitOverride   `json:"overrides"`
+}
+
+// LimitOverrideRequest is the body of PUT /discord/admin/limits/{subject}/{subject_id}. Omitted
+// limits keep the default and zero lifts the limit.
+type LimitOverrideRequest struct {
+	MaxMarkets  *int   `json:"max_markets,omitempty"`
+	MaxCreators *int   `json:"max_creators,omitempty"`
+	MaxOutcomes *int   `json:"max_outcomes,omitempty"`
+	MaxChannels *int   `json:"max_channels,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// AuditLogResponse is returned by GET /discord/admin/audit
+type AuditLogResponse struct {
+	Entries []*models.AuditEntry `json:"entries"`
+}
@@ -0,0 +1,186 @@
+package web
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// apiKeyContextKey carries the stored API key that authenticated a request
+type apiKeyContextKey struct{}
+
+// SetAPIKeyService sets the service used to check scoped API keys
+func (h *WebhookHandler) SetAPIKeyService(apiKeyService services.APIKeyService) {
+	h.apiKeyService = apiKeyService
+}
+
+// RootCredentials are the API keys and bearer tokens granting every scope. The next key and token are
+// accepted alongside the primary ones, so the backend can switch to them before the primary ones are
+// retired.
+type RootCredentials struct {
+	APIKey     string
+	NextAPIKey string
+	Token      string
+	NextToken  string
+}
+
+// configured reports whether any root credential is set
+func (root RootCredentials) configured() bool {
+	return root.APIKey != "" || root.NextAPIKey != "" || root.Token != "" || root.NextToken != ""
+}
+
+// accepts reports whether an API key or bearer token is one of the root credentials
+func (root RootCredentials) accepts(apiKey, bearer string) bool {
+	return secretMatches(apiKey, root.APIKey, root.NextAPIKey) || secretMatches(bearer, root.Token, root.NextToken)
+}
+
+// secretMatches compares a secret with each non-empty candidate in constant time
+func secretMatches(secret string, candidates ...string) bool {
+	matched := false
+	for _, candidate := range candidates {
+		if candidate != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(candidate)) == 1 {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// SetRootCredentials sets the API keys and bearer tokens granting every scope, in place of
+// CORAL_API_KEY, CORAL_TOKEN and their _NEXT variants from the environment. It can be called again
+// while serving to rotate them; empty values accept nothing.
+func (h *WebhookHandler) SetRootCredentials(root RootCredentials) {
+	h.rootCredentials.Store(&root)
+}
+
+// root returns the credentials granting every scope, from the environment until they are set
+func (h *WebhookHandler) root() RootCredentials {
+	if root := h.rootCredentials.Load(); root != nil {
+		return *root
+	}
+	return RootCredentials{
+		APIKey:     os.Getenv("CORAL_API_KEY"),
+		NextAPIKey: os.Getenv("CORAL_API_KEY_NEXT"),
+		Token:      os.Getenv("CORAL_TOKEN"),
+		NextToken:  os.Getenv("CORAL_TOKEN_NEXT"),
+	}
+}
+
+// credential is the result of checking the credentials sent with a request
+type credential struct {
+	root bool           // a root credential, or no credentials configured at all; grants every scope
+	key  *models.APIKey // the stored API key that matched, when not root
+}
+
+// allows reports whether the credential grants a scope
+func (c credential) allows(scope string) bool {
+	return c.root || (c.key != nil && c.key.HasScope(scope))
+}
+
+// authenticate checks the X-API-Key header and bearer token against the root credentials and
+// the stored API keys. Requests are let through as root while no credential is configured at all.
+func (h *WebhookHandler) authenticate(r *http.Request) (credential, error) {
+	return h.checkCredentials(r.Context(), r.Header.Get("X-API-Key"), r.Header.Get("Authorization"))
+}
+
+// checkCredentials checks an API key and an Authorization value, which may carry a bearer token
+func (h *WebhookHandler) checkCredentials(ctx context.Context, apiKey, authorization string) (credential, error) {
+	bearer := authorization
+	if len(bearer) > 7 && bearer[:7] == "Bearer " {
+		bearer = bearer[7:]
+	}
+
+	root := h.root()
+	if root.accepts(apiKey, bearer) {
+		return credential{root: true}, nil
+	}
+	if h.apiKeyService == nil {
+		return credential{root: !root.configured()}, nil
+	}
+
+	for _, secret := range []string{apiKey, bearer} {
+		key, err := h.apiKeyService.Authenticate(ctx, secret)
+		if err != nil {
+			return credential{}, err
+		}
+		if key != nil {
+			return credential{key: key}, nil
+		}
+	}
+
+	if root.configured() {
+		return credential{}, nil
+	}
+	hasKeys, err := h.apiKeyService.HasActiveKeys(ctx)
+	if err != nil {
+		return credential{}, err
+	}
+	return credential{root: !hasKeys}, nil
+}
+
+// AuthOk checks if the request is properly authenticated by any credential, whatever its scopes
+func (h *WebhookHandler) AuthOk(r *http.Request) bool {
+	cred, err := h.authenticate(r)
+	return err == nil && (cred.root || cred.key != nil)
+}
+
+// Authorization errors returned by Authorize
+var (
+	ErrUnauthenticated = errors.New("missing or unknown credentials")
+	ErrForbidden       = errors.New("API key lacks the required scope")
+)
+
+// Authorize checks credentials sent outside an HTTP request, such as gRPC metadata, against a scope.
+// It fails with ErrUnauthenticated or ErrForbidden, or with the error of the API key lookup.
+func (h *WebhookHandler) Authorize(ctx context.Context, apiKey, authorization, scope string) error {
+	cred, err := h.checkCredentials(ctx, apiKey, authorization)
+	if err != nil {
+		return err
+	}
+	if !cred.root && cred.key == nil {
+		return ErrUnauthenticated
+	}
+	if !cred.allows(scope) {
+		return fmt.Errorf("%w %s", ErrForbidden, scope)
+	}
+	return nil
+}
+
+// requireScope wraps a handler so it only runs for requests whose credential grants the scope.
+// Missing or unknown credentials get a 401 and keys without the scope get a 403.
+func (h *WebhookHandler) requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cred, err := h.authenticate(r)
+		if err != nil {
+			h.logger.Error(fmt.Sprintf("Failed to check API key: %v", err))
+			writeServiceError(w, err, "Failed to check credentials")
+			return
+		}
+		if !cred.root && cred.key == nil {
+			writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if !cred.allows(scope) {
+			writeJSONError(w, http.StatusForbidden, fmt.Sprintf("API key lacks the %s scope", scope))
+			return
+		}
+		if cred.key != nil {
+			r = r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, cred.key))
+		}
+		next(w, r)
+	}
+}
+
+// apiActor identifies the caller of a REST request in the audit log: "api" for the shared
+// credentials, or "api:<key id>" for a stored API key
+func apiActor(r *http.Request) string {
+	if key, ok := r.Context().Value(apiKeyContextKey{}).(*models.APIKey); ok {
+		return apiAuditActor + ":" + key.ID
+	}
+	return apiAuditActor
+}
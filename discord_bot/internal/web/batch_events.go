@@ -0,0 +1,70 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"coral-bot/discord_bot/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MaxBatchEvents is the largest number of events accepted in one batch
+const MaxBatchEvents = 100
+
+// HandleEventBatch handles POST /discord/events/batch
+//
+// Each event is validated and delivered on its own, in order, through the same pipeline as the
+// single-event endpoints. An invalid event is reported in its result and does not stop the batch.
+func (h *WebhookHandler) HandleEventBatch(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload BatchEventRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if len(payload.Events) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "events required")
+		return
+	}
+	if len(payload.Events) > MaxBatchEvents {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("a batch holds at most %d events", MaxBatchEvents))
+		return
+	}
+
+	response := BatchEventResponse{Results: make([]BatchEventResult, 0, len(payload.Events))}
+	for i, event := range payload.Events {
+		result := BatchEventResult{Index: i, Type: event.Type}
+		ctx, summary := withDeliverySummary(r.Context())
+		suppressed, err := h.processBatchEvent(ctx, event)
+		if err != nil {
+			result.Error = err.Error()
+			response.Failed++
+		} else {
+			result.Accepted, result.Suppressed = true, suppressed
+			result.EventID, result.Delivery = summary.eventID, summary.delivery
+			response.Accepted++
+		}
+		response.Results = append(response.Results, result)
+	}
+	h.logger.Info(fmt.Sprintf("Processed event batch: %d accepted, %d failed", response.Accepted, response.Failed))
+
+	b, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// processBatchEvent processes one event of a batch
+func (h *WebhookHandler) processBatchEvent(ctx context.Context, event BatchEvent) (suppressed bool, err error) {
+	ctx, span := tracing.Start(ctx, "batch.event", attribute.String("event.type", event.Type))
+	defer func() { tracing.End(span, err) }()
+	return h.ProcessEventJSON(ctx, event.Type, event.Payload)
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"io"
+	"net/http"
+
+	"coral-bot/discord_bot/internal/services"
+)
+
+// SetMarketBoardService sets the service keeping the pinned market boards, which is told when events arrive
+func (h *WebhookHandler) SetMarketBoardService(boards services.MarketBoardService) {
+	h.boards = boards
+}
+
+// marketsChanged asks for the market boards to be refreshed when boards are kept
+func (h *WebhookHandler) marketsChanged() {
+	if h.boards != nil {
+		h.boards.MarketsChanged()
+	}
+}
+
+// HandleChannelBoard handles POST /discord/channel/board
+func (h *WebhookHandler) HandleChannelBoard(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload ChannelBoardRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.ChannelID == "" {
+		writeJSONError(w, http.StatusBadRequest, "channel_id required")
+		return
+	}
+	if err := h.subscriptionService.SetChannelBoard(r.Context(), payload.ChannelID, "", payload.Enabled, apiActor(r)); err != nil {
+		writeServiceError(w, err, "Failed to save config")
+		return
+	}
+	h.marketsChanged()
+	w.WriteHeader(http.StatusOK)
+}
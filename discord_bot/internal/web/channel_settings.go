@@ -0,0 +1,349 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// HandleExportChannelSettings handles GET /discord/channel/settings/{channel_id}/export
+func (h *WebhookHandler) HandleExportChannelSettings(w http.ResponseWriter, r *http.Request) {
+	channelID := r.PathValue("channel_id")
+	cfg, err := h.subscriptionService.GetChannelConfig(r.Context(), channelID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to load config")
+		return
+	}
+	b, _ := json.Marshal(cfg.Settings())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// HandleImportChannelSettings handles PUT /discord/channel/settings/{channel_id}, replacing the
+// channel's settings with an exported document
+func (h *WebhookHandler) HandleImportChannelSettings(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var settings models.ChannelSettings
+	if err := decodePayload(r.Context(), body, &settings); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	cfg, err := h.subscriptionService.ApplyChannelSettings(r.Context(), r.PathValue("channel_id"), "", &settings, apiActor(r))
+	h.writeChannelSettingsResult(w, cfg, err)
+}
+
+// HandleCopyChannelSettings handles POST /discord/channel/settings/copy
+func (h *WebhookHandler) HandleCopyChannelSettings(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload ChannelSettingsCopyRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.SourceChannelID == "" || payload.TargetChannelID == "" {
+		writeJSONError(w, http.StatusBadRequest, "source_channel_id and target_channel_id required")
+		return
+	}
+	cfg, err := h.subscriptionService.CopyChannelSettings(r.Context(), payload.SourceChannelID, payload.TargetChannelID, "", apiActor(r))
+	h.writeChannelSettingsResult(w, cfg, err)
+}
+
+// HandleBulkChannelSettings handles POST /discord/channel/settings/bulk
+//
+// Every channel is validated before any is changed. When one is invalid, none is applied and the
+// response lists each invalid channel in its details.
+func (h *WebhookHandler) HandleBulkChannelSettings(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload []BulkChannelSettings
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON: the body must be an array of channels")
+		return
+	}
+	changes := make([]services.ChannelSettingsChange, len(payload))
+	for i, channel := range payload {
+		changes[i] = services.ChannelSettingsChange{ChannelID: channel.ChannelID, GuildID: channel.GuildID, Settings: channel.Settings}
+	}
+
+	configs, err := h.subscriptionService.ApplyChannelSettingsBulk(r.Context(), changes, apiActor(r))
+	var bulkErr *services.BulkChannelSettingsError
+	if errors.As(err, &bulkErr) {
+		writeAPIError(w, apiError{status: http.StatusBadRequest, code: ErrorCodeInvalidRequest, message: err.Error(),
+			details: map[string]interface{}{"invalid": bulkErr.Invalid}})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidChannelSettings) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to apply bulk channel settings: %v", err))
+		writeServiceError(w, err, "Failed to save config")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("Configured %d channels in bulk by %s", len(configs), apiActor(r)))
+
+	b, _ := json.Marshal(BulkChannelSettingsResponse{Channels: configs})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// HandleChannelClosingSoon handles POST /discord/channel/feed/closing_soon
+func (h *WebhookHandler) HandleChannelClosingSoon(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload ChannelClosingSoonRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.ChannelID == "" {
+		writeJSONError(w, http.StatusBadRequest, "channel_id required")
+		return
+	}
+	err = h.subscriptionService.SetChannelClosingSoon(r.Context(), payload.ChannelID, "", payload.Hours, apiActor(r))
+	if errors.Is(err, services.ErrInvalidChannelSettings) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("hours must be between 0 and %d", models.MaxClosingSoonHours))
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err, "Failed to save config")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleChannelDigest handles POST /discord/channel/digest
+func (h *WebhookHandler) HandleChannelDigest(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload ChannelDigestRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.ChannelID == "" {
+		writeJSONError(w, http.StatusBadRequest, "channel_id required")
+		return
+	}
+	mode := payload.Mode
+	if mode == "off" {
+		mode = models.DigestOff
+	}
+	err = h.subscriptionService.SetChannelDigest(r.Context(), payload.ChannelID, "", mode, apiActor(r))
+	if errors.Is(err, services.ErrInvalidChannelSettings) {
+		writeJSONError(w, http.StatusBadRequest, "mode must be daily, weekly or off")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err, "Failed to save config")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleChannelCrosspost handles POST /discord/channel/crosspost. Crossposting can only be turned on
+// in an announcement channel the bot can send messages in, which is checked while connected to Discord.
+func (h *WebhookHandler) HandleChannelCrosspost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload ChannelCrosspostRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.ChannelID == "" {
+		writeJSONError(w, http.StatusBadRequest, "channel_id required")
+		return
+	}
+	if payload.Enabled {
+		if h.discordSession == nil {
+			writeJSONError(w, http.StatusServiceUnavailable, "Discord session not available")
+			return
+		}
+		err := services.CheckCrosspost(r.Context(), h.discordSession, payload.ChannelID)
+		switch {
+		case errors.Is(err, services.ErrNotAnnouncementChannel):
+			writeJSONError(w, http.StatusConflict, "channel is not an announcement channel")
+			return
+		case errors.Is(err, services.ErrMissingCrosspostPermission):
+			writeJSONError(w, http.StatusConflict, "the bot needs the View Channel and Send Messages permissions in the channel")
+			return
+		case err != nil:
+			h.logger.Error(fmt.Sprintf("Failed to check crossposting in channel %s: %v", payload.ChannelID, err))
+			writeJSONError(w, http.StatusBadGateway, "Failed to check channel")
+			return
+		}
+	}
+	if err := h.subscriptionService.SetChannelCrosspost(r.Context(), payload.ChannelID, "", payload.Enabled, apiActor(r)); err != nil {
+		writeServiceError(w, err, "Failed to save config")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleChannelLiquidity handles POST /discord/channel/liquidity
+func (h *WebhookHandler) HandleChannelLiquidity(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload ChannelLiquidityRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.ChannelID == "" {
+		writeJSONError(w, http.StatusBadRequest, "channel_id required")
+		return
+	}
+	if err := h.subscriptionService.SetChannelLiquidityAlerts(r.Context(), payload.ChannelID, "", payload.Enabled, apiActor(r)); err != nil {
+		writeServiceError(w, err, "Failed to save config")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleChannelShadowMode handles POST /discord/channel/shadow_mode
+func (h *WebhookHandler) HandleChannelShadowMode(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload ChannelShadowModeRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.ChannelID == "" {
+		writeJSONError(w, http.StatusBadRequest, "channel_id required")
+		return
+	}
+	if err := h.subscriptionService.SetChannelShadowMode(r.Context(), payload.ChannelID, "", payload.Enabled, apiActor(r)); err != nil {
+		writeServiceError(w, err, "Failed to save config")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleChannelCalendar handles POST /discord/channel/calendar
+func (h *WebhookHandler) HandleChannelCalendar(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload ChannelCalendarRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.ChannelID == "" {
+		writeJSONError(w, http.StatusBadRequest, "channel_id required")
+		return
+	}
+	if err := h.subscriptionService.SetChannelCalendarDigests(r.Context(), payload.ChannelID, "", payload.Enabled, apiActor(r)); err != nil {
+		writeServiceError(w, err, "Failed to save config")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleChannelPing handles POST /discord/channel/feed/ping
+func (h *WebhookHandler) HandleChannelPing(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload ChannelPingRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.ChannelID == "" {
+		writeJSONError(w, http.StatusBadRequest, "channel_id required")
+		return
+	}
+	events, err := services.ParsePingEvents(strings.Join(payload.Events, ","))
+	if err == nil {
+		err = h.subscriptionService.SetChannelPing(r.Context(), payload.ChannelID, "", payload.RoleID, events, apiActor(r))
+	}
+	if errors.Is(err, services.ErrInvalidChannelSettings) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err, "Failed to save config")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// checkFeedPermissions verifies that the bot can post feed alerts in a channel, answering 409 with
+// the missing permissions when it cannot. Without a Discord session there is nothing to check against,
+// and the channel is configured as requested.
+func (h *WebhookHandler) checkFeedPermissions(w http.ResponseWriter, r *http.Request, channelID string) bool {
+	if h.discordSession == nil {
+		return true
+	}
+	err := services.CheckFeedPermissions(r.Context(), h.discordSession, channelID)
+	var missing *services.MissingPermissionsError
+	switch {
+	case errors.As(err, &missing):
+		writeJSONError(w, http.StatusConflict, err.Error())
+		return false
+	case err != nil:
+		h.logger.Error(fmt.Sprintf("Failed to check feed permissions in channel %s: %v", channelID, err))
+		writeJSONError(w, http.StatusBadGateway, "Failed to check channel")
+		return false
+	}
+	return true
+}
+
+// writeChannelSettingsResult writes the updated config, or a 400 for settings that failed validation
+func (h *WebhookHandler) writeChannelSettingsResult(w http.ResponseWriter, cfg *models.ChannelConfig, err error) {
+	if errors.Is(err, services.ErrInvalidChannelSettings) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to apply channel settings: %v", err))
+		writeServiceError(w, err, "Failed to save config")
+		return
+	}
+	b, _ := json.Marshal(cfg)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
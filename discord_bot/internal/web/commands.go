@@ -0,0 +1,43 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"coral-bot/discord_bot/internal/models"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// CommandSyncer brings the bot's registered slash commands in line with the commands it handles, like
+// the command handler does
+type CommandSyncer interface {
+	ResyncCommands(session *discordgo.Session) (models.CommandSyncResult, error)
+}
+
+// SetCommandSyncer sets what POST /discord/admin/commands/resync syncs the commands with
+func (h *WebhookHandler) SetCommandSyncer(syncer CommandSyncer) {
+	h.commands = syncer
+}
+
+// HandleResyncCommands handles POST /discord/admin/commands/resync
+//
+// The commands are synced with Discord like at startup, so commands changed by new configuration
+// appear without restarting the bot.
+func (h *WebhookHandler) HandleResyncCommands(w http.ResponseWriter, r *http.Request) {
+	if h.commands == nil || h.discordSession == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Command sync not enabled")
+		return
+	}
+	result, err := h.commands.ResyncCommands(h.discordSession)
+	if err != nil {
+		writeJSONError(w, http.StatusBadGateway, "Failed to sync commands: "+err.Error())
+		return
+	}
+	h.logger.Info("Commands resynced through the API")
+
+	b, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
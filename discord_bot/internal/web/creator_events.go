@@ -0,0 +1,50 @@
+package web
+
+import (
+	"context"
+	"errors"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// errCreatorRequired is returned for creator events without a creator
+var errCreatorRequired = errors.New("creator required")
+
+// deliverCreatorJoined validates a creator joined event and delivers its message
+func (h *WebhookHandler) deliverCreatorJoined(ctx context.Context, event *MarketEvent) (suppressed bool, err error) {
+	creator := &event.Creator
+	if creator.Name == "" {
+		return false, errCreatorRequired
+	}
+	msg := renderMessage(ctx, "CreatorJoinedMessage", func() string { return h.marketService.CreateCreatorJoinedMessage(creator) })
+	h.dispatchToUsers(ctx, &eventNotification{eventType: models.EventCreatorJoined, content: msg}, func(subscription *models.Subscription) bool {
+		return subscribedToCreator(subscription, creator.Name)
+	})
+	return false, nil
+}
+
+// deliverCreatorMilestone validates a creator milestone event and delivers its message
+func (h *WebhookHandler) deliverCreatorMilestone(ctx context.Context, event *MarketEvent) (suppressed bool, err error) {
+	creator := &event.Creator
+	if creator.Name == "" {
+		return false, errCreatorRequired
+	}
+	if creator.Volume <= 0 {
+		return false, errors.New("volume must be positive")
+	}
+	msg := renderMessage(ctx, "CreatorMilestoneMessage", func() string { return h.marketService.CreateCreatorMilestoneMessage(creator) })
+	h.dispatchToUsers(ctx, &eventNotification{eventType: models.EventCreatorMilestone, content: msg}, func(subscription *models.Subscription) bool {
+		return subscribedToCreator(subscription, creator.Name)
+	})
+	return false, nil
+}
+
+// subscribedToCreator reports whether a subscription follows a creator
+func subscribedToCreator(subscription *models.Subscription, creator string) bool {
+	for _, subscribed := range subscription.SubscribedCreators {
+		if subscribed == creator {
+			return true
+		}
+	}
+	return false
+}
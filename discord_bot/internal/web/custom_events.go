@@ -0,0 +1,117 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// SetEventRegistry sets the registry of custom event types, whose endpoints are served next to the
+// built-in ones and which are accepted in batches and from the message bus. Types whose endpoint a
+// built-in one already takes are logged and left out.
+func (h *WebhookHandler) SetEventRegistry(registry *services.EventRegistry) {
+	h.eventRegistry = registry
+	for _, eventType := range registry.Types() {
+		if builtinEventPath(eventType.Path()) {
+			h.logger.Warning(fmt.Sprintf("Custom event %s is not served: /discord/events/%s is taken", eventType.Name, eventType.Path()))
+		}
+	}
+}
+
+// builtinEventPath reports whether a built-in endpoint is served under /discord/events/{path}
+func builtinEventPath(path string) bool {
+	if path == "batch" {
+		return true
+	}
+	for _, kind := range eventKinds {
+		if kind.path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// customEventPayload is the body of a custom event, kept as sent for the event type's decoder
+type customEventPayload struct {
+	eventType *services.CustomEventType
+	body      json.RawMessage
+}
+
+// UnmarshalJSON keeps the body of a custom event to decode later
+func (payload *customEventPayload) UnmarshalJSON(data []byte) error {
+	payload.body = append(payload.body[:0], data...)
+	return nil
+}
+
+// marketEvent decodes a custom event with its event type's decoder
+func (payload *customEventPayload) marketEvent() (*MarketEvent, error) {
+	event, err := payload.eventType.Decode(payload.body)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, fmt.Errorf("%s payload required", payload.eventType.Name)
+	}
+	return &MarketEvent{Type: payload.eventType.Name, Market: event.Market, Fields: event.Fields}, nil
+}
+
+// customEventKind registers a custom event type like the built-in ones, without a legacy endpoint
+func customEventKind(eventType *services.CustomEventType) *eventKind {
+	summary := eventType.Summary
+	if summary == "" {
+		summary = "Post a " + eventType.Name + " event"
+	}
+	return &eventKind{
+		eventType: eventType.Name,
+		path:      eventType.Path(),
+		summary:   summary,
+		payload:   func() eventPayload { return &customEventPayload{eventType: eventType} },
+		deliver: func(h *WebhookHandler, ctx context.Context, event *MarketEvent) (bool, error) {
+			return h.deliverCustomEvent(ctx, eventType, event)
+		},
+	}
+}
+
+// customEventKinds returns the registrations of the custom event types, leaving out those whose
+// endpoint a built-in one already takes
+func (h *WebhookHandler) customEventKinds() []*eventKind {
+	if h.eventRegistry == nil {
+		return nil
+	}
+	var kinds []*eventKind
+	for _, eventType := range h.eventRegistry.Types() {
+		if !builtinEventPath(eventType.Path()) {
+			kinds = append(kinds, customEventKind(eventType))
+		}
+	}
+	return kinds
+}
+
+// deliverCustomEvent writes a custom event with its template and delivers it as its routing policy says
+func (h *WebhookHandler) deliverCustomEvent(ctx context.Context, eventType *services.CustomEventType, event *MarketEvent) (suppressed bool, err error) {
+	if event.Market.ID == "" {
+		return false, errMarketIDRequired
+	}
+	var renderErr error
+	msg := renderMessage(ctx, eventType.Name, func() string {
+		var msg string
+		msg, renderErr = eventType.Render(&services.CustomEvent{Market: event.Market, Fields: event.Fields})
+		return msg
+	})
+	if renderErr != nil {
+		h.logger.Error(renderErr.Error())
+		return false, fmt.Errorf("%s payload is missing fields its message needs", eventType.Name)
+	}
+
+	notification := &eventNotification{eventType: eventType.Name, content: msg}
+	if eventType.Routing == services.RouteToSubscribers {
+		h.dispatchToUsers(ctx, notification, func(subscription *models.Subscription) bool {
+			return subscribedToMarket(subscription, event.Market.ID)
+		})
+		return false, nil
+	}
+	return h.dispatchNotification(ctx, notification, &event.Market), nil
+}
@@ -0,0 +1,227 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+	"coral-bot/discord_bot/internal/tracing"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// deadLetterRetention is how long exhausted dead letters are kept for inspection before they are pruned
+const deadLetterRetention = 7 * 24 * time.Hour
+
+// SetDeadLetterService sets the store failed channel messages and DMs are kept in for retrying
+func (h *WebhookHandler) SetDeadLetterService(deadLetters services.DeadLetterService) {
+	h.deadLetters = deadLetters
+}
+
+// permanentSendError reports whether a send failed because its recipient is gone or refuses DMs, so
+// retrying it cannot succeed. Missing permissions are not permanent, an admin can fix them.
+func permanentSendError(err error) bool {
+	var restErr *discordgo.RESTError
+	if !errors.As(err, &restErr) || restErr.Message == nil {
+		return false
+	}
+	switch restErr.Message.Code {
+	case discordgo.ErrCodeUnknownChannel, discordgo.ErrCodeUnknownUser, discordgo.ErrCodeCannotSendMessagesToThisUser:
+		return true
+	}
+	return false
+}
+
+// deadLetter stores a notification that failed to send to a channel or user in the dead-letter store
+func (h *WebhookHandler) deadLetter(ctx context.Context, notification *eventNotification, recipient, recipientID string, crosspost bool, sendErr error) {
+	if h.deadLetters == nil || notification.direct {
+		return
+	}
+	letter := &models.DeadLetter{
+		EventID:     notification.id,
+		EventType:   notification.eventType,
+		Recipient:   recipient,
+		RecipientID: recipientID,
+		Content:     notification.content,
+		Chart:       notification.chart,
+		PingRoleID:  notification.pingRole,
+		AccentColor: notification.accent,
+		Crosspost:   crosspost,
+		LastError:   sendErr.Error(),
+	}
+	if err := h.deadLetters.Add(ctx, letter, permanentSendError(sendErr)); err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to store dead letter for %s %s: %v", recipient, recipientID, err))
+	}
+}
+
+// RetryDeadLetters retries the dead letters that are due and returns how many it retried. Retries go
+// through the gateway monitor like any send, so while it is down they are buffered until the reconnect.
+func (h *WebhookHandler) RetryDeadLetters(ctx context.Context, now time.Time) int {
+	if h.deadLetters == nil || h.discordSession == nil {
+		return 0
+	}
+	letters, err := h.deadLetters.Due(ctx, now)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to get due dead letters: %v", err))
+		return 0
+	}
+
+	for _, letter := range letters {
+		h.retryDeadLetter(ctx, letter)
+	}
+	return len(letters)
+}
+
+// retryDeadLetter sends a dead letter again, deleting it when the send succeeds
+func (h *WebhookHandler) retryDeadLetter(ctx context.Context, letter *models.DeadLetter) {
+	ctx, cancel := context.WithTimeout(ctx, dispatchTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "dispatch.dead_letter."+letter.EventType,
+		attribute.String("dead_letter.id", letter.ID),
+		attribute.Int("dead_letter.attempts", letter.Attempts),
+	)
+	defer span.End()
+
+	if err := h.deadLetters.StartRetry(ctx, letter); err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to record retry of dead letter %s, leaving it for the next tick: %v", letter.ID, err))
+		return
+	}
+
+	notification := &eventNotification{eventType: letter.EventType, content: letter.Content, chart: letter.Chart, pingRole: letter.PingRoleID, accent: letter.AccentColor}
+	h.deliver(ctx, notification.priority(), func(ctx context.Context) error {
+		var err error
+		if letter.Recipient == models.RecipientUser {
+			err = h.sendDirectNotification(ctx, letter.RecipientID, notification)
+		} else {
+			var messages []*discordgo.Message
+			messages, err = h.sendNotification(ctx, letter.RecipientID, notification)
+			if letter.Crosspost {
+				for _, message := range messages {
+					h.crosspost(ctx, letter.RecipientID, message)
+				}
+			}
+		}
+		h.recordDelivery(ctx, letter.EventType, letter.RecipientID, err)
+
+		if err != nil {
+			h.logger.Warning(fmt.Sprintf("Retry %d of dead letter %s to %s %s failed: %v", letter.Attempts-1, letter.ID, letter.Recipient, letter.RecipientID, err))
+			rest := unsent(notification, err)
+			letter.Content, letter.PingRoleID = rest.content, rest.pingRole
+			if err := h.deadLetters.Failed(ctx, letter, err, permanentSendError(err)); err != nil {
+				h.logger.Error(fmt.Sprintf("Failed to record failed retry of dead letter %s: %v", letter.ID, err))
+			}
+			return err
+		}
+		h.logger.Info(fmt.Sprintf("Delivered dead letter %s to %s %s", letter.ID, letter.Recipient, letter.RecipientID))
+		if err := h.deadLetters.Succeeded(ctx, letter); err != nil {
+			h.logger.Error(fmt.Sprintf("Failed to delete delivered dead letter %s: %v", letter.ID, err))
+		}
+		return nil
+	})
+}
+
+// RunDeadLetterWorker retries due dead letters and prunes old exhausted ones on every tick, until ctx
+// is cancelled
+func (h *WebhookHandler) RunDeadLetterWorker(ctx context.Context, interval time.Duration) {
+	if h.deadLetters == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	h.logger.Info(fmt.Sprintf("Dead letter worker started (interval %s)", interval))
+	for {
+		select {
+		case <-ctx.Done():
+			h.logger.Info("Dead letter worker stopped")
+			return
+		case now := <-ticker.C:
+			if retried := h.RetryDeadLetters(ctx, now); retried > 0 {
+				h.logger.Info(fmt.Sprintf("Retried %d dead letters", retried))
+			}
+			if err := h.deadLetters.Prune(ctx, now.Add(-deadLetterRetention)); err != nil {
+				h.logger.Error(fmt.Sprintf("Failed to prune dead letters: %v", err))
+			}
+		}
+	}
+}
+
+// HandleAdminDeadLetters handles GET /discord/admin/dead-letters
+func (h *WebhookHandler) HandleAdminDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if h.deadLetters == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Dead letters not enabled")
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status != "" && status != models.DeadLetterRetrying && status != models.DeadLetterExhausted {
+		writeJSONError(w, http.StatusBadRequest, "status must be retrying or exhausted")
+		return
+	}
+
+	letters, err := h.deadLetters.List(r.Context(), status)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to list dead letters: %v", err))
+		writeServiceError(w, err, "Failed to list dead letters")
+		return
+	}
+
+	b, _ := json.Marshal(DeadLettersResponse{DeadLetters: letters})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// HandleRetryDeadLetter handles POST /discord/admin/dead-letters/{id}/retry
+//
+// The dead letter is retried by the next run of the dead letter worker, whatever its status.
+func (h *WebhookHandler) HandleRetryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if h.deadLetters == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Dead letters not enabled")
+		return
+	}
+
+	letter, err := h.deadLetters.Redrive(r.Context(), r.PathValue("id"))
+	if errors.Is(err, services.ErrDeadLetterNotFound) {
+		writeJSONError(w, http.StatusNotFound, "Dead letter not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to re-drive dead letter: %v", err))
+		writeServiceError(w, err, "Failed to re-drive dead letter")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("Dead letter %s re-driven by %s", letter.ID, apiActor(r)))
+
+	b, _ := json.Marshal(letter)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(b)
+}
+
+// HandleDiscardDeadLetter handles DELETE /discord/admin/dead-letters/{id}
+func (h *WebhookHandler) HandleDiscardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if h.deadLetters == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Dead letters not enabled")
+		return
+	}
+
+	id := r.PathValue("id")
+	err := h.deadLetters.Discard(r.Context(), id)
+	if errors.Is(err, services.ErrDeadLetterNotFound) {
+		writeJSONError(w, http.StatusNotFound, "Dead letter not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to discard dead letter: %v", err))
+		writeServiceError(w, err, "Failed to discard dead letter")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("Dead letter %s discarded by %s", id, apiActor(r)))
+	w.WriteHeader(http.StatusNoContent)
+}
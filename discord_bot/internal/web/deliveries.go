@@ -0,0 +1,153 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// Reasons a channel is skipped, shown in delivery reports
+const (
+	skipFeedDisabled     = "new market feed is off"
+	skipBelowMinBuy      = "buy is below the channel's minimum buy"
+	skipBelowMinVolume   = "market volume is below the channel's minimum volume"
+	skipCategoryFiltered = "category is not in the channel's allowed categories"
+	skipCategoryRouted   = "the guild routes this category to another channel"
+	skipBroadcastFeedOff = "feed is off, broadcasts only go to feed channels"
+	skipQuietHours       = "held for the channel's quiet hours summary"
+	skipLiquidityOff     = "liquidity alerts are off"
+	skipRuleSuppressed   = "a routing rule of the guild suppresses this event"
+	skipRuleRouted       = "a routing rule of the guild routes this event to another channel"
+	skipAlreadyAnnounced = "the market was already announced in this channel"
+)
+
+// SetDeliveryReportService sets the service recording who each event was delivered to
+func (h *WebhookHandler) SetDeliveryReportService(deliveries services.DeliveryReportService) {
+	h.deliveries = deliveries
+}
+
+// deliverySummaryKey carries the deliverySummary of the event processed with a context
+type deliverySummaryKey struct{}
+
+// deliverySummary is filled in with the event ID and recipient counts of the event processed with
+// its context, for the response to the request that posted the event
+type deliverySummary struct {
+	eventID  string
+	delivery *models.DeliveryStats
+}
+
+// withDeliverySummary returns a context whose event's delivery is summarized in the returned summary
+func withDeliverySummary(ctx context.Context) (context.Context, *deliverySummary) {
+	summary := &deliverySummary{}
+	return context.WithValue(ctx, deliverySummaryKey{}, summary), summary
+}
+
+// acceptedResponse returns the response to an event, with its delivery summary when it was delivered
+func (summary *deliverySummary) acceptedResponse(suppressed bool) AcceptedResponse {
+	return AcceptedResponse{Accepted: true, Suppressed: suppressed, EventID: summary.eventID, Delivery: summary.delivery}
+}
+
+// writeAccepted writes the 202 response to an event
+func writeAccepted(w http.ResponseWriter, response AcceptedResponse) {
+	b, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(b)
+}
+
+// summarizeDelivery fills in the delivery summary of the context, if it has one
+func summarizeDelivery(ctx context.Context, notification *eventNotification, delivery models.DeliveryStats) {
+	if summary, ok := ctx.Value(deliverySummaryKey{}).(*deliverySummary); ok {
+		summary.eventID = notification.id
+		summary.delivery = &delivery
+	}
+}
+
+// startDeliveryReport starts the delivery report of a notification, giving it an event ID when it
+// has none
+func (h *WebhookHandler) startDeliveryReport(ctx context.Context, notification *eventNotification, market *models.Market) {
+	if h.deliveries == nil {
+		return
+	}
+	eventID, err := h.deliveries.Start(ctx, notification.id, notification.eventType, market.ID)
+	if err != nil {
+		h.logger.Warning(fmt.Sprintf("Failed to start delivery report for %s event of market %s: %v", notification.eventType, market.ID, err))
+		return
+	}
+	notification.id = eventID
+}
+
+// recordReceipt records the outcome of sending a notification to a channel or user in its delivery report
+func (h *WebhookHandler) recordReceipt(ctx context.Context, notification *eventNotification, recipient, recipientID string, err error) {
+	if h.deliveries == nil || notification.id == "" {
+		return
+	}
+	receipt := models.DeliveryReceipt{Recipient: recipient, RecipientID: recipientID, Status: models.DeliverySent}
+	if err != nil {
+		receipt.Status, receipt.Reason = models.DeliveryFailed, err.Error()
+	}
+	h.deliveries.Record(ctx, notification.id, receipt)
+}
+
+// recordSkip records in a notification's delivery report that a channel's settings filtered it out
+func (h *WebhookHandler) recordSkip(ctx context.Context, notification *eventNotification, channelID, reason string) {
+	if h.deliveries == nil || notification.id == "" {
+		return
+	}
+	h.deliveries.Record(ctx, notification.id, models.DeliveryReceipt{
+		Recipient:   models.RecipientChannel,
+		RecipientID: channelID,
+		Status:      models.DeliverySkipped,
+		Reason:      reason,
+	})
+}
+
+// recordShadow logs what shadow mode kept from being sent to a channel or user, and records it in the
+// notification's delivery report
+func (h *WebhookHandler) recordShadow(ctx context.Context, notification *eventNotification, recipient, recipientID string) {
+	preview := services.ShadowPreview(notification.content)
+	if notification.pingRole != "" {
+		preview = fmt.Sprintf("<@&%s> %s", notification.pingRole, preview)
+	}
+	h.logger.Info(fmt.Sprintf("Shadow mode: would have sent %s event to %s %s: %s", notification.eventType, recipient, recipientID, preview))
+	if h.deliveries == nil || notification.id == "" {
+		return
+	}
+	h.deliveries.Record(ctx, notification.id, models.DeliveryReceipt{
+		Recipient:   recipient,
+		RecipientID: recipientID,
+		Status:      models.DeliveryShadowed,
+		Reason:      preview,
+	})
+}
+
+// HandleAdminDelivery handles GET /discord/admin/deliveries/{event_id}
+//
+// The report lists every channel and user the event was sent to or failed for, and every channel
+// whose settings skipped it, with the reason.
+func (h *WebhookHandler) HandleAdminDelivery(w http.ResponseWriter, r *http.Request) {
+	if h.deliveries == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Delivery reports not enabled")
+		return
+	}
+	report, err := h.deliveries.Get(r.Context(), r.PathValue("event_id"))
+	if errors.Is(err, services.ErrDeliveryReportNotFound) {
+		writeJSONError(w, http.StatusNotFound, "No delivery report for this event, it is unknown or older than a day")
+		return
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to load delivery report: %v", err))
+		writeServiceError(w, err, "Failed to load delivery report")
+		return
+	}
+
+	b, _ := json.Marshal(report)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
@@ -0,0 +1,345 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// errMarketIDRequired is returned for events without a market ID
+var errMarketIDRequired = errors.New("market_id required")
+
+// eventKind registers an event type: its endpoints, the body its /discord/events endpoint accepts and
+// how the normalized event is validated and delivered
+type eventKind struct {
+	eventType string
+	path      string // endpoint under /discord/events/
+	legacy    string // endpoint under /webhooks/, empty when the event has none
+	summary   string
+	payload   func() eventPayload // empty body of the /discord/events endpoint, also accepted in batches and from the bus
+	deliver   func(h *WebhookHandler, ctx context.Context, event *MarketEvent) (suppressed bool, err error)
+}
+
+// eventKinds lists every event type the bot accepts. Registering one here serves and documents its
+// endpoints and accepts it in batches and from the message bus.
+var eventKinds = []eventKind{
+	{eventType: models.EventNewMarket, path: "new-market", legacy: "new_market", summary: "Announce a new market",
+		payload: func() eventPayload { return &NewMarketEventRequest{} }, deliver: (*WebhookHandler).deliverNewMarket},
+	{eventType: models.EventMarketUpdate, path: "market-update", legacy: "market_update", summary: "Post a market update",
+		payload: func() eventPayload { return &MarketUpdateEventRequest{} }, deliver: (*WebhookHandler).deliverMarketUpdate},
+	{eventType: models.EventTradingStarted, path: "trading-start", legacy: "trading_started", summary: "Announce that trading started",
+		payload: func() eventPayload { return &TradingStartEventRequest{} }, deliver: (*WebhookHandler).deliverTradingStarted},
+	{eventType: models.EventTradingEnded, path: "trading-end", legacy: "trading_ended", summary: "Announce that trading ended",
+		payload: func() eventPayload { return &TradingEndEventRequest{} }, deliver: (*WebhookHandler).deliverTradingEnded},
+	{eventType: models.EventMarketResolved, path: "market-resolved", legacy: "market_resolved", summary: "Announce a market resolution",
+		payload: func() eventPayload { return &MarketResolvedEventRequest{} }, deliver: (*WebhookHandler).deliverMarketResolved},
+	{eventType: models.EventMarketCancelled, path: "market-cancelled", summary: "Announce that a market was cancelled and refunded",
+		payload: func() eventPayload { return &MarketCancelledEventRequest{} }, deliver: (*WebhookHandler).deliverMarketCancelled},
+	{eventType: models.EventMarketBuy, path: "market-buy", summary: "Post a market buy",
+		payload: func() eventPayload { return &MarketBuyEventRequest{} }, deliver: (*WebhookHandler).deliverMarketBuy},
+	{eventType: models.EventMarketLiquidity, path: "market-liquidity", summary: "Post liquidity added to or removed from a market",
+		payload: func() eventPayload { return &MarketLiquidityEventRequest{} }, deliver: (*WebhookHandler).deliverMarketLiquidity},
+	{eventType: models.EventMarketComment, path: "market-comment", summary: "Post a market comment to subscribers following its activity",
+		payload: func() eventPayload { return &MarketCommentEventRequest{} }, deliver: (*WebhookHandler).deliverMarketComment},
+	{eventType: models.EventCreatorJoined, path: "creator-joined", summary: "Announce a new creator to their subscribers",
+		payload: func() eventPayload { return &CreatorJoinedEventRequest{} }, deliver: (*WebhookHandler).deliverCreatorJoined},
+	{eventType: models.EventCreatorMilestone, path: "creator-milestone", summary: "Announce a creator's volume milestone to their subscribers",
+		payload: func() eventPayload { return &CreatorMilestoneEventRequest{} }, deliver: (*WebhookHandler).deliverCreatorMilestone},
+}
+
+// eventKind returns the registration of a built-in or custom event type
+func (h *WebhookHandler) eventKind(eventType string) (*eventKind, bool) {
+	for i := range eventKinds {
+		if eventKinds[i].eventType == eventType {
+			return &eventKinds[i], true
+		}
+	}
+	for _, kind := range h.customEventKinds() {
+		if kind.eventType == eventType {
+			return kind, true
+		}
+	}
+	return nil, false
+}
+
+// eventRoutes returns the legacy /webhooks endpoint and the /discord/events endpoint of every
+// registered event type. The bodies of custom event endpoints are documented as any JSON value, their
+// format being up to the event type's decoder.
+func (h *WebhookHandler) eventRoutes() (legacy, events []route) {
+	for i := range eventKinds {
+		kind := &eventKinds[i]
+		if kind.legacy != "" {
+			legacy = append(legacy, route{method: http.MethodPost, path: "/webhooks/" + kind.legacy, scope: models.ScopeEventsWrite, tag: "legacy", summary: kind.summary,
+				request: LegacyMarketEventRequest{}, response: OKResponse{}, status: http.StatusOK, handler: h.eventHandler(kind, true)})
+		}
+		events = append(events, route{method: http.MethodPost, path: "/discord/events/" + kind.path, scope: models.ScopeEventsWrite, tag: "events", summary: kind.summary,
+			request: kind.payload(), response: AcceptedResponse{}, status: http.StatusAccepted, handler: h.eventHandler(kind, false)})
+	}
+	for _, kind := range h.customEventKinds() {
+		events = append(events, route{method: http.MethodPost, path: "/discord/events/" + kind.path, scope: models.ScopeEventsWrite, tag: "events", summary: kind.summary,
+			request: json.RawMessage{}, response: AcceptedResponse{}, status: http.StatusAccepted, handler: h.eventHandler(kind, false)})
+	}
+	return legacy, events
+}
+
+// eventHandler returns the handler of an event type's endpoint, which decodes the body in the
+// endpoint's format, normalizes it and delivers the event. Legacy bodies must name the endpoint's
+// event type and are answered with {"ok": true}; the others are answered with a 202 summarizing the
+// delivery. Invalid bodies get a 400.
+func (h *WebhookHandler) eventHandler(kind *eventKind, legacy bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.logger.Error(fmt.Sprintf("Failed to read request body: %v", err))
+			writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		payload := kind.payload()
+		if legacy {
+			payload = &LegacyMarketEventRequest{}
+		}
+		if err := decodePayload(r.Context(), body, payload); err != nil {
+			h.logger.Error(fmt.Sprintf("Failed to parse JSON: %v", err))
+			writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+			return
+		}
+		event, err := payload.marketEvent()
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if event.Type != kind.eventType {
+			h.logger.Error(fmt.Sprintf("Invalid event type %q on the %s endpoint", event.Type, kind.eventType))
+			writeJSONError(w, http.StatusBadRequest, "Invalid event type")
+			return
+		}
+
+		ctx, summary := withDeliverySummary(r.Context())
+		suppressed, err := kind.deliver(h, ctx, event)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if legacy {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"ok": true}`))
+			return
+		}
+		writeAccepted(w, summary.acceptedResponse(suppressed))
+	}
+}
+
+// ProcessEventJSON decodes an event's JSON payload by its type, such as new_market, and processes it.
+// The payload is the body of the event type's own HTTP endpoint.
+func (h *WebhookHandler) ProcessEventJSON(ctx context.Context, eventType string, body json.RawMessage) (suppressed bool, err error) {
+	if len(body) == 0 {
+		return false, errors.New("payload required")
+	}
+	kind, ok := h.eventKind(eventType)
+	if !ok {
+		return false, fmt.Errorf("unknown event type %q", eventType)
+	}
+	payload := kind.payload()
+	if err := decodePayload(ctx, body, payload); err != nil {
+		return false, errors.New("invalid payload JSON")
+	}
+	return h.ProcessEvent(ctx, payload)
+}
+
+// ProcessEvent validates and delivers an event given as one of the *EventRequest payloads, for
+// ingest paths other than the HTTP endpoints such as the gRPC server. Every returned error is a
+// validation error describing what is wrong with the payload.
+func (h *WebhookHandler) ProcessEvent(ctx context.Context, payload interface{}) (suppressed bool, err error) {
+	body, ok := payload.(eventPayload)
+	if !ok {
+		return false, fmt.Errorf("unsupported event payload %T", payload)
+	}
+	event, err := body.marketEvent()
+	if err != nil {
+		return false, err
+	}
+	kind, ok := h.eventKind(event.Type)
+	if !ok {
+		return false, fmt.Errorf("unknown event type %q", event.Type)
+	}
+	return kind.deliver(h, ctx, event)
+}
+
+// ParseEventTime parses an optional RFC3339 time from an event payload, naming the field when it is invalid
+func ParseEventTime(field, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC3339 time", field)
+	}
+	return parsed, nil
+}
+
+// deliverNewMarket validates a new market event and delivers its announcement
+func (h *WebhookHandler) deliverNewMarket(ctx context.Context, event *MarketEvent) (suppressed bool, err error) {
+	if event.Market.ID == "" {
+		return false, errMarketIDRequired
+	}
+	msg := renderMessage(ctx, "MarketAnnouncement", func() string { return h.marketService.CreateMarketAnnouncement(&event.Market) })
+	return h.dispatchEvent(ctx, msg, &event.Market, models.EventNewMarket), nil
+}
+
+// deliverMarketUpdate validates a market update event and delivers its update message, unless
+// coalescing holds it back
+func (h *WebhookHandler) deliverMarketUpdate(ctx context.Context, event *MarketEvent) (suppressed bool, err error) {
+	if event.Market.ID == "" {
+		return false, errMarketIDRequired
+	}
+	replaced := 0
+	if h.coalescer != nil {
+		var held bool
+		if held, replaced = h.coalescer.hold(&event.Market, h.now()); held {
+			return false, nil
+		}
+	}
+	return h.dispatchMarketUpdate(ctx, &event.Market, replaced), nil
+}
+
+// dispatchMarketUpdate renders and delivers a market update, noting how many updates it replaced that
+// coalescing held back
+func (h *WebhookHandler) dispatchMarketUpdate(ctx context.Context, market *models.Market, replaced int) (suppressed bool) {
+	previous := h.previousSnapshot(ctx, market.ID)
+	msg := renderMessage(ctx, "MarketUpdateMessage", func() string { return h.marketService.CreateMarketUpdateMessage(market, previous) })
+	switch {
+	case replaced == 1:
+		msg += fmt.Sprintf("\n\n🔁 1 earlier update in the last %s was replaced by this one", models.FormatInterval(h.coalescer.window))
+	case replaced > 1:
+		msg += fmt.Sprintf("\n\n🔁 %d earlier updates in the last %s were replaced by this one", replaced, models.FormatInterval(h.coalescer.window))
+	}
+	return h.dispatchEvent(ctx, msg, market, models.EventMarketUpdate)
+}
+
+// deliverTradingStarted validates a trading start event and delivers its message
+func (h *WebhookHandler) deliverTradingStarted(ctx context.Context, event *MarketEvent) (suppressed bool, err error) {
+	if event.Market.ID == "" {
+		return false, errMarketIDRequired
+	}
+	messageBody := renderMessage(ctx, "TradingStartMessage", func() string { return h.marketService.CreateTradingStartMessage(&event.Market) })
+	return h.dispatchEvent(ctx, messageBody, &event.Market, models.EventTradingStarted), nil
+}
+
+// deliverTradingEnded validates a trading end event and delivers its message
+func (h *WebhookHandler) deliverTradingEnded(ctx context.Context, event *MarketEvent) (suppressed bool, err error) {
+	if event.Market.ID == "" {
+		return false, errMarketIDRequired
+	}
+	messageBody := renderMessage(ctx, "TradingEndMessage", func() string { return h.marketService.CreateTradingEndMessage(&event.Market) })
+	return h.dispatchEvent(ctx, messageBody, &event.Market, models.EventTradingEnded), nil
+}
+
+// deliverMarketResolved validates a market resolution event and delivers its message
+func (h *WebhookHandler) deliverMarketResolved(ctx context.Context, event *MarketEvent) (suppressed bool, err error) {
+	if event.Market.ID == "" {
+		return false, errMarketIDRequired
+	}
+	if err := validatePayouts(event.Market.Payouts); err != nil {
+		return false, err
+	}
+	if event.Market.ResolvedAt.IsZero() {
+		event.Market.ResolvedAt = time.Now()
+	}
+	msg := renderMessage(ctx, "MarketResolutionMessage", func() string { return h.marketService.CreateMarketResolutionMessage(&event.Market) })
+	return h.dispatchEvent(ctx, msg, &event.Market, models.EventMarketResolved), nil
+}
+
+// deliverMarketCancelled validates a market cancellation event and delivers its message. Once it
+// was delivered, the market's subscriptions and reminders are removed.
+func (h *WebhookHandler) deliverMarketCancelled(ctx context.Context, event *MarketEvent) (suppressed bool, err error) {
+	if event.Market.ID == "" {
+		return false, errMarketIDRequired
+	}
+	if event.Market.Volume < 0 {
+		return false, errors.New("refund_total cannot be negative")
+	}
+	if event.Market.ResolvedAt.IsZero() {
+		event.Market.ResolvedAt = time.Now()
+	}
+	msg := renderMessage(ctx, "MarketCancelledMessage", func() string { return h.marketService.CreateMarketCancelledMessage(&event.Market, event.Reason) })
+	return h.dispatchEvent(ctx, msg, &event.Market, models.EventMarketCancelled), nil
+}
+
+// validatePayouts checks a resolution's payout breakdown: every row names an outcome, percentages lie
+// between 0 and 100, and the pool shares add up to at most the whole pool
+func validatePayouts(payouts []models.Payout) error {
+	total := 0.0
+	for _, payout := range payouts {
+		if strings.TrimSpace(payout.Outcome) == "" {
+			return errors.New("payout outcome required")
+		}
+		if payout.FinalPct < 0 || payout.FinalPct > 100 {
+			return fmt.Errorf("final_pct for %q must be between 0 and 100", payout.Outcome)
+		}
+		if payout.PoolShare < 0 || payout.PoolShare > 100 {
+			return fmt.Errorf("pool_share for %q must be between 0 and 100", payout.Outcome)
+		}
+		total += payout.PoolShare
+	}
+	// Allow for rounding in shares such as three thirds
+	if total > 100.01 {
+		return fmt.Errorf("pool shares add up to %.2f%%, more than the whole pool", total)
+	}
+	return nil
+}
+
+// deliverMarketLiquidity validates a liquidity event and delivers its message, showing how far the
+// odds shifted since the market's previous snapshot
+func (h *WebhookHandler) deliverMarketLiquidity(ctx context.Context, event *MarketEvent) (suppressed bool, err error) {
+	if event.Market.ID == "" {
+		return false, errMarketIDRequired
+	}
+	if event.Change == 0 {
+		return false, errors.New("change required")
+	}
+	if event.Liquidity < 0 {
+		return false, errors.New("liquidity cannot be negative")
+	}
+	previous := h.previousSnapshot(ctx, event.Market.ID)
+	msg := renderMessage(ctx, "MarketLiquidityMessage", func() string {
+		return h.marketService.CreateMarketLiquidityMessage(&event.Market, event.Change, event.Liquidity, previous)
+	})
+	return h.dispatchEvent(ctx, msg, &event.Market, models.EventMarketLiquidity), nil
+}
+
+// deliverMarketBuy validates a buy event and delivers its message, in the whale format for buys at
+// or above the whale threshold. Buys below the global minimum, and buys on markets that already resolved,
+// are suppressed and suppressed is true.
+func (h *WebhookHandler) deliverMarketBuy(ctx context.Context, event *MarketEvent) (suppressed bool, err error) {
+	market := &event.Market
+	if market.ID == "" {
+		return false, errMarketIDRequired
+	}
+	if event.Amount < 0 {
+		return false, errors.New("amount cannot be negative")
+	}
+	if event.Amount < h.minBuyAmount {
+		h.publishSuppressed(ctx, &eventNotification{eventType: models.EventMarketBuy, buyAmount: event.Amount}, market)
+		return true, nil
+	}
+
+	var msg string
+	if h.whaleBuyAmount > 0 && event.Amount >= h.whaleBuyAmount {
+		msg = renderMessage(ctx, "WhaleBuyMessage", func() string {
+			return h.marketService.CreateWhaleBuyMessage(market.ID, market.Title, event.Amount, event.Outcome, event.Buyer, market.Link)
+		})
+	} else {
+		msg = renderMessage(ctx, "MarketBuyMessage", func() string {
+			return h.marketService.CreateMarketBuyMessage(market.ID, market.Title, event.Amount, event.Outcome, event.Buyer, market.Link)
+		})
+	}
+	return h.dispatchNotification(ctx, &eventNotification{eventType: models.EventMarketBuy, content: msg, buyAmount: event.Amount}, market), nil
+}
@@ -0,0 +1,867 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+	"coral-bot/discord_bot/internal/tracing"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// dispatchTimeout bounds the delivery of a single event to every subscribed channel and user
+const dispatchTimeout = 2 * time.Minute
+
+// eventNotification is a rendered market event ready to be delivered
+type eventNotification struct {
+	id        string // event ID of the delivery report, empty when reports are off
+	eventType string
+	content   string
+	chart     []byte  // optional PNG attachment
+	buyAmount float64 // amount of a market_buy event, compared against per-channel and per-user minimums
+	pingRole  string  // role the message mentions, set per channel, empty for none
+	accent    int     // accent color of the embed the message is posted in, set per guild, 0 to post plain content
+	direct    bool    // sent on request to a caller that sees the outcome, so failures are not dead-lettered
+	shadow    bool    // logged and reported instead of sent, set per channel
+	announces string  // ID of the new market the notification announces, posted once per channel
+
+	offers  []discordgo.MessageComponent // buy buttons of the market, shown in guilds that trade
+	bets    []discordgo.MessageComponent // bet buttons of the market, shown in guilds that play the game
+	buttons []discordgo.MessageComponent // components under the message, set per guild
+}
+
+// localized returns the notification with its absolute times written out in the given timezone,
+// or the notification itself when no timezone is set
+func (notification *eventNotification) localized(zone string) *eventNotification {
+	loc, err := services.LoadTimezone(zone)
+	if err != nil || loc == nil {
+		return notification
+	}
+	copied := *notification
+	copied.content = services.LocalizeTimestamps(notification.content, loc)
+	return &copied
+}
+
+// pinging returns the notification mentioning the channel's ping role when the channel pings it for
+// the notification's event, or the notification itself otherwise. A role a routing rule mentions is
+// kept over the channel's.
+func (notification *eventNotification) pinging(channelConfig *models.ChannelConfig) *eventNotification {
+	if notification.pingRole != "" {
+		return notification
+	}
+	return notification.mentioning(channelConfig.PingRoleID, channelConfig.PingsFor(notification.eventType))
+}
+
+// mentioning returns the notification mentioning a role when mention is set, or the notification
+// itself otherwise
+func (notification *eventNotification) mentioning(roleID string, mention bool) *eventNotification {
+	if !mention {
+		return notification
+	}
+	copied := *notification
+	copied.pingRole = roleID
+	return &copied
+}
+
+// shadowing returns the notification marked to be logged instead of sent when the channel is in shadow
+// mode, or the notification itself otherwise
+func (notification *eventNotification) shadowing(channelConfig *models.ChannelConfig) *eventNotification {
+	if !channelConfig.ShadowMode {
+		return notification
+	}
+	copied := *notification
+	copied.shadow = true
+	return &copied
+}
+
+// branded returns the notification restyled with a guild's branding, or the notification itself when
+// the guild has none
+func (notification *eventNotification) branded(branding *models.GuildBranding) *eventNotification {
+	if branding == nil {
+		return notification
+	}
+	copied := *notification
+	copied.content = services.BrandMessage(notification.content, notification.eventType, branding)
+	copied.accent = branding.AccentColor
+	return &copied
+}
+
+// offering returns the notification with the buttons a guild shows under it: buy buttons when it
+// turned trading on and bet buttons when it plays the prediction game. Without either, or without a
+// guild config, the notification itself is returned.
+func (notification *eventNotification) offering(guild *models.GuildConfig) *eventNotification {
+	if guild == nil {
+		return notification
+	}
+	var buttons []discordgo.MessageComponent
+	if guild.TradingEnabled {
+		buttons = append(buttons, notification.offers...)
+	}
+	if guild.GameEnabled {
+		buttons = append(buttons, notification.bets...)
+	}
+	if len(buttons) == 0 {
+		return notification
+	}
+	copied := *notification
+	copied.buttons = buttons
+	return &copied
+}
+
+// remainder returns the notification with only the given parts of its content left to post. The ping
+// role was mentioned with the first part, so it is not mentioned again.
+func (notification *eventNotification) remainder(parts []string) *eventNotification {
+	copied := *notification
+	copied.content = strings.Join(parts, "\n")
+	copied.pingRole = ""
+	return &copied
+}
+
+// priority returns the delivery priority of the notification's event
+func (notification *eventNotification) priority() models.Priority {
+	return models.EventPriority(notification.eventType)
+}
+
+// chartEvents lists the events whose messages carry a probability history chart
+var chartEvents = map[string]bool{
+	models.EventMarketUpdate:   true,
+	models.EventMarketResolved: true,
+}
+
+// channelMarketSubscriptionEvents lists the events delivered to channels subscribed to a specific market
+var channelMarketSubscriptionEvents = map[string]bool{
+	models.EventMarketUpdate:    true,
+	models.EventMarketResolved:  true,
+	models.EventMarketCancelled: true,
+	models.EventMarketBuy:       true,
+	models.EventMarketLiquidity: true,
+}
+
+// optInEvents lists the events only posted in channels that turned them on, never in guild default channels
+var optInEvents = map[string]bool{
+	models.EventMarketLiquidity: true,
+}
+
+// emailedEvents lists the events a user's notifications are emailed for when they route resolutions
+// to email
+var emailedEvents = map[string]bool{
+	models.EventMarketResolved:  true,
+	models.EventMarketCancelled: true,
+}
+
+// tradeEvents lists the events whose messages offer buy buttons in guilds that trade, and bet buttons in
+// guilds that play the prediction game
+var tradeEvents = map[string]bool{
+	models.EventNewMarket:      true,
+	models.EventMarketUpdate:   true,
+	models.EventTradingStarted: true,
+}
+
+// minVolumeEvents lists the feed events a channel's minimum volume applies to, the ones carrying a market's volume
+var minVolumeEvents = map[string]bool{
+	models.EventNewMarket:    true,
+	models.EventMarketUpdate: true,
+}
+
+// dispatchEvent records the market snapshot and delivers an event message to subscribed channels and users.
+// It returns true when the event was suppressed because its market already resolved.
+func (h *WebhookHandler) dispatchEvent(ctx context.Context, message string, market *models.Market, eventType string) (suppressed bool) {
+	return h.dispatchNotification(ctx, &eventNotification{eventType: eventType, content: message}, market)
+}
+
+// dispatchNotification records the market snapshot and delivers a prepared notification, storing it in
+// the outbox first when one is set. Delivery is detached from the caller's cancellation so a
+// disconnecting client cannot cut a fan-out short. Events other than the resolution of a market that
+// already resolved are suppressed and suppressed is true.
+func (h *WebhookHandler) dispatchNotification(ctx context.Context, notification *eventNotification, market *models.Market) (suppressed bool) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dispatchTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "dispatch."+notification.eventType, attribute.String("market.id", market.ID))
+	defer span.End()
+
+	if h.suppressAfterResolution(ctx, notification, market) {
+		return true
+	}
+	previous, err := h.subscriptionService.RecordMarketSnapshot(ctx, market)
+	if err != nil {
+		h.logger.Warning(fmt.Sprintf("Failed to record snapshot for market %s: %v", market.ID, err))
+	}
+	h.marketsChanged()
+
+	if chartEvents[notification.eventType] && h.discordSession != nil {
+		chart, err := h.marketService.CreateProbabilityChart(ctx, market)
+		if err != nil {
+			h.logger.Warning(fmt.Sprintf("Skipping chart for market %s: %v", market.ID, err))
+		} else {
+			notification.chart = chart.Image
+			notification.content += "\n\n📉 " + chart.Legend
+		}
+	}
+
+	if h.outbox == nil {
+		delivery := h.fanOut(ctx, notification, market, previous)
+		h.publishProcessed(ctx, notification.id, notification, market, delivery)
+		return false
+	}
+	item := &models.OutboxItem{
+		EventType: notification.eventType,
+		Content:   notification.content,
+		Chart:     notification.chart,
+		BuyAmount: notification.buyAmount,
+		Market:    market,
+		Previous:  previous,
+	}
+	if err := h.outbox.Enqueue(ctx, item); err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to store %s event for market %s in the outbox, delivering it directly: %v", notification.eventType, market.ID, err))
+		delivery := h.fanOut(ctx, notification, market, previous)
+		h.publishProcessed(ctx, notification.id, notification, market, delivery)
+		return false
+	}
+	h.deliverOutboxItem(ctx, item)
+	return false
+}
+
+// fanOut delivers a notification to the subscribed channels and users, recording a receipt for each
+// in the notification's delivery report, and counts the recipients. With a fan-out pool the channels
+// and users are sent to concurrently, and fanOut returns once every send ran. Users holding positions
+// in a resolved market are then told how theirs ended, game bets on a resolved or cancelled market are
+// settled, and a cancelled market's subscriptions and reminders are removed once its subscribers were
+// sent the cancellation.
+func (h *WebhookHandler) fanOut(ctx context.Context, notification *eventNotification, market *models.Market, previous *models.MarketSnapshot) models.DeliveryStats {
+	h.startDeliveryReport(ctx, notification, market)
+	if tradeEvents[notification.eventType] && services.Tradable(market, time.Now()) {
+		if h.trading {
+			notification.offers = services.TradeButtons(market)
+		}
+		if h.game != nil {
+			notification.bets = services.BetButtons(market)
+		}
+	}
+	if notification.eventType == models.EventNewMarket {
+		notification.announces = market.ID
+	}
+	channelBatch, userBatch := h.newFanoutBatch(notification.priority()), h.newFanoutBatch(notification.priority())
+	h.sendToSubscribedChannels(ctx, notification, market, channelBatch)
+	h.sendToSubscribedUsers(ctx, notification, market, previous, userBatch)
+	channels, channelsFailed := channelBatch.wait()
+	users, usersFailed := userBatch.wait()
+	delivery := models.DeliveryStats{Channels: channels, Users: users, Failed: channelsFailed + usersFailed}
+	summarizeDelivery(ctx, notification, delivery)
+	if notification.eventType == models.EventMarketResolved {
+		h.notifyPositionHolders(ctx, market)
+	}
+	if notification.eventType == models.EventMarketResolved || notification.eventType == models.EventMarketCancelled {
+		h.settleGameBets(ctx, notification.eventType, market)
+	}
+	if notification.eventType == models.EventMarketCancelled {
+		h.cleanUpCancelledMarket(ctx, market)
+	}
+	return delivery
+}
+
+// previousSnapshot returns the market's last recorded snapshot, so update messages can show how far each
+// outcome moved. It must be called before the event is dispatched, which records the new snapshot.
+func (h *WebhookHandler) previousSnapshot(ctx context.Context, marketID string) *models.MarketSnapshot {
+	if marketID == "" {
+		return nil
+	}
+	snapshot, err := h.subscriptionService.GetMarketSnapshot(ctx, marketID)
+	if err != nil {
+		h.logger.Warning(fmt.Sprintf("Failed to get previous snapshot for market %s: %v", marketID, err))
+		return nil
+	}
+	return snapshot
+}
+
+// sendToSubscribedChannels submits a message to all subscribed channels to a batch.
+// A new market in a category a guild routes goes to the routed channel only, instead of the guild's feed channels.
+// Otherwise the first of a guild's routing rules that applies to a channel and matches the event decides
+// whether it is posted there, mentioning a role or in another channel, and the channel's own filters
+// decide when no rule does.
+func (h *WebhookHandler) sendToSubscribedChannels(ctx context.Context, notification *eventNotification, market *models.Market, batch *fanoutBatch) {
+	routes := h.categoryRoutes(ctx, notification, market)
+	routedGuilds := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		routedGuilds[route.GuildID] = true
+	}
+
+	rules := h.routingRules(ctx, market)
+	delivered := make(map[string]bool)
+	var ruleRoutes []*models.RoutingRule
+	h.sendToChannels(ctx, notification, routedGuilds, func(channelConfig *models.ChannelConfig) (*eventNotification, string) {
+		channelNotification, reason := notification, ""
+		switch rule := models.FirstMatchingRule(rules[channelConfig.GuildID], channelConfig.ChannelID, notification.eventType, market); {
+		case rule == nil:
+			reason = h.channelFilters(ctx, channelConfig, notification, market)
+		case rule.Action == models.RuleActionSuppress:
+			return nil, skipRuleSuppressed
+		case rule.Action == models.RuleActionRoute:
+			ruleRoutes = append(ruleRoutes, rule)
+			return nil, skipRuleRouted
+		default:
+			// send and ping rules post the event whatever the channel's filters, though not in its quiet hours
+			channelNotification = notification.mentioning(rule.RoleID, rule.Action == models.RuleActionPing)
+			reason = h.holdDuringQuietHours(ctx, channelConfig, notification, market)
+		}
+		delivered[channelConfig.ChannelID] = delivered[channelConfig.ChannelID] || reason == ""
+		return channelNotification, reason
+	}, batch)
+
+	for _, route := range routes {
+		h.sendToRoutedChannel(ctx, route.GuildID, route.ChannelID, notification, market, batch)
+	}
+	for _, rule := range ruleRoutes {
+		if delivered[rule.TargetChannelID] {
+			continue
+		}
+		delivered[rule.TargetChannelID] = true
+		h.sendToRoutedChannel(ctx, rule.GuildID, rule.TargetChannelID, notification, market, batch)
+	}
+}
+
+// channelFilters returns the reason a channel's own settings skip an event, or no reason when the
+// event is to be posted now
+func (h *WebhookHandler) channelFilters(ctx context.Context, channelConfig *models.ChannelConfig, notification *eventNotification, market *models.Market) string {
+	if notification.eventType == models.EventMarketLiquidity && !channelConfig.LiquidityAlerts {
+		return skipLiquidityOff
+	}
+
+	// Channels subscribed to this market receive its events even when the general feed is off
+	marketSubscribed := false
+	if channelMarketSubscriptionEvents[notification.eventType] {
+		for _, marketID := range channelConfig.SubscribedMarkets {
+			if marketID == market.ID {
+				marketSubscribed = true
+				break
+			}
+		}
+	}
+
+	// Check if feed is enabled for this channel
+	if !channelConfig.FeedEnabled && !marketSubscribed {
+		return skipFeedDisabled
+	}
+
+	// Skip buys smaller than the channel's minimum
+	if belowMinBuyAmount(notification, channelConfig.MinBuyAmount) {
+		return skipBelowMinBuy
+	}
+
+	// Skip feed announcements of markets below the channel's minimum volume
+	if minVolumeEvents[notification.eventType] && !marketSubscribed && market.Volume < channelConfig.MinVolume {
+		return skipBelowMinVolume
+	}
+
+	// Check if market category is allowed
+	if len(channelConfig.AllowedCategories) > 0 && !marketSubscribed {
+		for _, category := range channelConfig.AllowedCategories {
+			if category == market.Category {
+				return h.holdDuringQuietHours(ctx, channelConfig, notification, market)
+			}
+		}
+		return skipCategoryFiltered
+	}
+	return h.holdDuringQuietHours(ctx, channelConfig, notification, market)
+}
+
+// routingRules returns every guild's routing rules by guild. Without them, channels' own filters decide.
+func (h *WebhookHandler) routingRules(ctx context.Context, market *models.Market) map[string][]*models.RoutingRule {
+	if h.discordSession == nil {
+		return nil
+	}
+	all, err := h.subscriptionService.GetAllRoutingRules(ctx)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to get routing rules, filtering market %s with channel settings only: %v", market.ID, err))
+		return nil
+	}
+	rules := make(map[string][]*models.RoutingRule)
+	for _, rule := range all {
+		rules[rule.GuildID] = append(rules[rule.GuildID], rule)
+	}
+	return rules
+}
+
+// sendToRoutedChannel submits a notification routed to a channel, by a category route or a routing
+// rule, to a batch unless the channel holds it for its quiet hours
+func (h *WebhookHandler) sendToRoutedChannel(ctx context.Context, guildID, channelID string, notification *eventNotification, market *models.Market, batch *fanoutBatch) {
+	shadow := false
+	if channelConfig, err := h.subscriptionService.GetChannelConfig(ctx, channelID); err == nil && channelConfig != nil {
+		if reason := h.holdDuringQuietHours(ctx, channelConfig, notification, market); reason != "" {
+			h.recordSkip(ctx, notification, channelID, reason)
+			return
+		}
+		shadow = channelConfig.ShadowMode
+	}
+	if h.announcedBefore(ctx, notification, channelID, shadow) {
+		return
+	}
+	batch.submit(ctx, channelID, func(ctx context.Context) error {
+		return h.sendRoutedMessage(ctx, guildID, channelID, notification)
+	})
+}
+
+// announcedBefore reports whether the new market a notification announces was already announced in a
+// channel, recording the skip, and otherwise remembers it as announced there. Other events, and
+// announcements shadow mode only logs, are never skipped or remembered. When the announcements cannot be
+// read the market is announced.
+func (h *WebhookHandler) announcedBefore(ctx context.Context, notification *eventNotification, channelID string, shadow bool) bool {
+	if h.announcements == nil || notification.announces == "" || shadow || h.shadowMode {
+		return false
+	}
+	claimed, err := h.announcements.Claim(ctx, channelID, notification.announces)
+	if err != nil {
+		h.logger.Warning(fmt.Sprintf("Failed to check earlier announcements of market %s in channel %s, announcing: %v", notification.announces, channelID, err))
+		return false
+	}
+	if !claimed {
+		h.logger.Info(fmt.Sprintf("Skipping repeated announcement of market %s in channel %s", notification.announces, channelID))
+		h.recordSkip(ctx, notification, channelID, skipAlreadyAnnounced)
+	}
+	return !claimed
+}
+
+// categoryRoutes returns the routes of the guilds that route a new market's category
+func (h *WebhookHandler) categoryRoutes(ctx context.Context, notification *eventNotification, market *models.Market) []*models.CategoryRoute {
+	category := models.NormalizeCategory(market.Category)
+	if notification.eventType != models.EventNewMarket || category == "" || h.discordSession == nil {
+		return nil
+	}
+	all, err := h.subscriptionService.GetAllCategoryRoutes(ctx)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to get category routes, announcing market %s in every feed channel: %v", market.ID, err))
+		return nil
+	}
+	var routes []*models.CategoryRoute
+	for _, route := range all {
+		if route.Category == category {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// sendRoutedMessage sends a notification to a channel a guild routes it to, in the channel's timezone
+// and the guild's branding, mentioning its ping role and crossposted when the channel has crossposting on
+func (h *WebhookHandler) sendRoutedMessage(ctx context.Context, guildID, channelID string, notification *eventNotification) error {
+	if guild, err := h.subscriptionService.GetGuildConfig(ctx, guildID); err == nil && guild != nil {
+		notification = notification.branded(guild.Branding).offering(guild)
+	}
+	crosspost := false
+	if channelConfig, err := h.subscriptionService.GetChannelConfig(ctx, channelID); err == nil && channelConfig != nil {
+		notification, crosspost = notification.localized(channelConfig.Timezone).pinging(channelConfig).shadowing(channelConfig), channelConfig.Crosspost
+	}
+	return h.sendChannelMessage(ctx, channelID, notification, crosspost)
+}
+
+// sendToChannels submits a notification to a batch for every configured channel for which decide
+// returns no reason, as the notification decide returns, and for the default channel of guilds without
+// any channel configuration, skipping the channels of skipGuilds. Messages are styled with their
+// guild's branding. Skipped channels are recorded in the delivery report with their reason.
+func (h *WebhookHandler) sendToChannels(ctx context.Context, notification *eventNotification, skipGuilds map[string]bool, decide func(*models.ChannelConfig) (*eventNotification, string), batch *fanoutBatch) {
+	if h.discordSession == nil {
+		h.logger.Error("Discord session not set")
+		return
+	}
+
+	// Get all channel configurations
+	channels, err := h.subscriptionService.GetAllChannelConfigs(ctx)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to get channel configs: %v", err))
+		return
+	}
+	guilds, guildsErr := h.subscriptionService.GetAllGuildConfigs(ctx)
+	if guildsErr != nil {
+		h.logger.Error(fmt.Sprintf("Failed to get guild configs, sending unbranded messages: %v", guildsErr))
+	}
+	guildBranding := make(map[string]*models.GuildBranding, len(guilds))
+	guildConfigs := make(map[string]*models.GuildConfig, len(guilds))
+	for _, guildConfig := range guilds {
+		guildBranding[guildConfig.GuildID] = guildConfig.Branding
+		guildConfigs[guildConfig.GuildID] = guildConfig
+	}
+
+	guildsWithChannels := make(map[string]bool)
+	for _, channelConfig := range channels {
+		if channelConfig.GuildID != "" {
+			guildsWithChannels[channelConfig.GuildID] = true
+		}
+		if skipGuilds[channelConfig.GuildID] {
+			h.recordSkip(ctx, notification, channelConfig.ChannelID, skipCategoryRouted)
+			continue
+		}
+		decided, reason := decide(channelConfig)
+		if reason != "" {
+			h.recordSkip(ctx, notification, channelConfig.ChannelID, reason)
+			continue
+		}
+
+		// Send message to channel
+		channelID, channelNotification, crosspost := channelConfig.ChannelID, decided.localized(channelConfig.Timezone).branded(guildBranding[channelConfig.GuildID]).offering(guildConfigs[channelConfig.GuildID]).pinging(channelConfig).shadowing(channelConfig), channelConfig.Crosspost
+		if h.announcedBefore(ctx, channelNotification, channelID, channelNotification.shadow) {
+			continue
+		}
+		batch.submit(ctx, channelID, func(ctx context.Context) error {
+			return h.sendChannelMessage(ctx, channelID, channelNotification, crosspost)
+		})
+	}
+
+	// Fall back to the guild default channel for guilds without any explicit channel configuration
+	for _, guildConfig := range guilds {
+		if guildConfig.DefaultChannelID == "" || guildsWithChannels[guildConfig.GuildID] || skipGuilds[guildConfig.GuildID] || optInEvents[notification.eventType] {
+			continue
+		}
+		channelID, guildNotification := guildConfig.DefaultChannelID, notification.branded(guildConfig.Branding).offering(guildConfig)
+		if h.announcedBefore(ctx, guildNotification, channelID, false) {
+			continue
+		}
+		batch.submit(ctx, channelID, func(ctx context.Context) error {
+			return h.sendChannelMessage(ctx, channelID, guildNotification, false)
+		})
+	}
+}
+
+// sendChannelMessage sends a message to a single channel and logs the outcome, crossposting it to
+// following servers when crosspost is set. A failed message is stored as a dead letter to be retried. While the gateway is disconnected the message is buffered
+// and sent, logged and recorded after the reconnect, and nil is returned. In shadow mode the message is
+// only logged and recorded as shadowed.
+func (h *WebhookHandler) sendChannelMessage(ctx context.Context, channelID string, notification *eventNotification, crosspost bool) error {
+	if h.shadowMode || notification.shadow {
+		h.recordShadow(ctx, notification, models.RecipientChannel, channelID)
+		return nil
+	}
+	return h.deliver(ctx, notification.priority(), func(ctx context.Context) error {
+		messages, err := h.sendNotification(ctx, channelID, notification)
+		if err != nil {
+			h.logger.Error(fmt.Sprintf("Failed to send message to channel %s: %v", channelID, err))
+			h.deadLetter(ctx, unsent(notification, err), models.RecipientChannel, channelID, crosspost, err)
+		} else {
+			h.logger.Info(fmt.Sprintf("Sent message to channel %s", channelID))
+		}
+		if crosspost {
+			for _, message := range messages {
+				h.crosspost(ctx, channelID, message)
+			}
+		}
+		h.recordDelivery(ctx, notification.eventType, channelID, err)
+		h.recordReceipt(ctx, notification, models.RecipientChannel, channelID, err)
+		return err
+	})
+}
+
+// crosspost publishes a sent message to the servers following its announcement channel. The message
+// was delivered to the channel either way, so a failure is only logged.
+func (h *WebhookHandler) crosspost(ctx context.Context, channelID string, message *discordgo.Message) {
+	if message == nil {
+		return
+	}
+	if channel, err := h.discordSession.State.Channel(channelID); err == nil && channel.Type != discordgo.ChannelTypeGuildNews {
+		h.logger.Warning(fmt.Sprintf("Not crossposting to channel %s, it is no longer an announcement channel", channelID))
+		return
+	}
+
+	ctx, span := tracing.Start(ctx, "discord.ChannelMessageCrosspost", attribute.String("discord.channel_id", channelID))
+	_, err := h.discordSession.ChannelMessageCrosspost(channelID, message.ID, discordgo.WithContext(ctx))
+	tracing.End(span, err)
+	if err != nil {
+		h.logger.Warning(fmt.Sprintf("Failed to crosspost message %s in channel %s: %v", message.ID, channelID, err))
+	}
+}
+
+// deliver sends through the maintenance mode and the gateway monitor when they are set, so messages
+// are buffered during maintenance or while disconnected and flushed by priority
+func (h *WebhookHandler) deliver(ctx context.Context, priority models.Priority, send func(context.Context) error) error {
+	return h.holdDuringMaintenance(ctx, priority, func(ctx context.Context) error {
+		if h.gateway == nil {
+			return send(ctx)
+		}
+		return h.gateway.DeliverPriority(ctx, priority, send)
+	})
+}
+
+// sendNotification posts a notification to a channel, attaching its chart when present. A notification
+// with an accent color is posted as an embed of that color showing the chart. Only the notification's
+// ping role is mentioned: @everyone, @here and any other mention in market text is not. Content over
+// Discord's length limit is split over several messages, the first mentioning the ping role and the
+// last carrying the chart. Links are decorated for the channel at this point, so a notification fanned
+// out to many channels is rendered once. When a part after the first fails, the parts posted are
+// returned with an unsentError holding the rest of the notification.
+func (h *WebhookHandler) sendNotification(ctx context.Context, channelID string, notification *eventNotification) (messages []*discordgo.Message, err error) {
+	ping := ""
+	if notification.pingRole != "" {
+		ping = fmt.Sprintf("<@&%s> ", notification.pingRole)
+	}
+	limit := services.MaxMessageLength - utf8.RuneCountInString(ping)
+	if notification.accent != 0 {
+		limit = services.MaxEmbedDescriptionLength
+	}
+	parts := services.SplitMessage(h.linkDecorator.Decorate(ctx, notification.content, channelID), limit)
+
+	ctx, span := tracing.Start(ctx, "discord.ChannelMessageSend",
+		attribute.String("discord.channel_id", channelID),
+		attribute.String("event.type", notification.eventType),
+		attribute.Bool("message.has_chart", len(notification.chart) > 0),
+		attribute.Int("message.parts", len(parts)),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	for i, part := range parts {
+		send := &discordgo.MessageSend{
+			Content:         part,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		}
+		last := i == len(parts)-1
+		if notification.accent != 0 {
+			send.Content = ""
+			send.Embeds = []*discordgo.MessageEmbed{{Description: part, Color: notification.accent}}
+			if last && len(notification.chart) > 0 {
+				send.Embeds[0].Image = &discordgo.MessageEmbedImage{URL: "attachment://probability.png"}
+			}
+		}
+		if i == 0 && ping != "" {
+			// Mentions in embeds notify nobody, so the ping stays in the content
+			send.Content = strings.TrimSpace(ping + send.Content)
+			send.AllowedMentions.Roles = []string{notification.pingRole}
+		}
+		if last {
+			send.Components = notification.buttons
+		}
+		if last && len(notification.chart) > 0 {
+			send.Files = []*discordgo.File{
+				{
+					Name:        "probability.png",
+					ContentType: "image/png",
+					Reader:      bytes.NewReader(notification.chart),
+				},
+			}
+		}
+		message, err := h.discordSession.ChannelMessageSendComplex(channelID, send, discordgo.WithContext(ctx))
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			return messages, &unsentError{rest: notification.remainder(parts[i:]), err: err}
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// unsentError is returned when a notification split over several messages failed after its first
+// parts were posted, so only the rest is retried
+type unsentError struct {
+	rest *eventNotification
+	err  error
+}
+
+func (e *unsentError) Error() string { return e.err.Error() }
+
+func (e *unsentError) Unwrap() error { return e.err }
+
+// unsent returns the part of a notification a send error left unposted, which is all of it unless
+// the error is an unsentError
+func unsent(notification *eventNotification, err error) *eventNotification {
+	var partial *unsentError
+	if errors.As(err, &partial) {
+		return partial.rest
+	}
+	return notification
+}
+
+// recordDelivery records a delivery outcome when analytics are enabled
+func (h *WebhookHandler) recordDelivery(ctx context.Context, eventType, destination string, err error) {
+	if h.analyticsService != nil {
+		h.analyticsService.RecordDelivery(ctx, eventType, destination, err)
+	}
+}
+
+// sendToSubscribedUsers submits a DM to all subscribed users to a batch
+func (h *WebhookHandler) sendToSubscribedUsers(ctx context.Context, notification *eventNotification, market *models.Market, previous *models.MarketSnapshot, batch *fanoutBatch) {
+	if h.discordSession == nil {
+		h.logger.Error("Discord session not set")
+		return
+	}
+
+	// Get all subscriptions
+	subscriptions, err := h.subscriptionService.GetAllSubscriptions(ctx)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to get subscriptions: %v", err))
+		return
+	}
+
+	now := time.Now()
+	notified := make(map[string]bool)
+	byUser := make(map[string]*models.Subscription, len(subscriptions))
+	for _, subscription := range subscriptions {
+		byUser[subscription.DiscordUserID] = subscription
+		if subscription.Snoozed(now) {
+			notified[subscription.DiscordUserID] = true // nothing is sent, not even for their watchlists
+			continue
+		}
+
+		// Check if user is subscribed to this market, creator or one of its outcomes
+		shouldNotify := h.subscriptionService.ShouldNotifyUser(subscription, market) ||
+			h.subscriptionService.ShouldNotifyOutcomeSubscriber(subscription, market, notification.eventType, previous)
+		if !shouldNotify || belowMinBuyAmount(notification, subscription.MinBuyAmount) {
+			h.updateOutcomeBaselines(ctx, notification, subscription, market, false)
+			continue
+		}
+		notified[subscription.DiscordUserID] = true
+		h.sendToUser(ctx, notification, subscription.DiscordUserID, subscription, batch)
+		h.updateOutcomeBaselines(ctx, notification, subscription, market, true)
+	}
+
+	// Watchlist owners get one DM however many of their watchlists have the market, and none when
+	// their subscriptions already notified them
+	watchlists, err := h.subscriptionService.GetAllWatchlists(ctx)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to get watchlists: %v", err))
+		return
+	}
+	for _, watchlist := range watchlists {
+		if notified[watchlist.DiscordUserID] || !watchlist.HasMarket(market.ID) || !watchlist.NotifiesAbout(notification.eventType) || belowMinBuyAmount(notification, watchlist.MinBuyAmount) {
+			continue
+		}
+		notified[watchlist.DiscordUserID] = true
+		h.sendToUser(ctx, notification, watchlist.DiscordUserID, byUser[watchlist.DiscordUserID], batch)
+	}
+}
+
+// updateOutcomeBaselines moves the baselines of a user's subscriptions to the outcomes of a market whose
+// probabilities changed, so that later moves are measured from the probabilities the user last saw
+func (h *WebhookHandler) updateOutcomeBaselines(ctx context.Context, notification *eventNotification, subscription *models.Subscription, market *models.Market, notified bool) {
+	if notification.eventType != models.EventMarketUpdate && notification.eventType != models.EventMarketLiquidity {
+		return
+	}
+	for _, outcomeSub := range subscription.SubscribedOutcomes {
+		if outcomeSub.MarketID == market.ID {
+			if err := h.subscriptionService.UpdateOutcomeBaselines(ctx, subscription.DiscordUserID, market, notified); err != nil {
+				h.logger.Error(fmt.Sprintf("Failed to update outcome baselines of %s: %v", subscription.DiscordUserID, err))
+			}
+			return
+		}
+	}
+}
+
+// dispatchToUsers sends a notification that is not delivered to channels to the users whose subscription
+// matches, unless they are snoozed. Creator and activity events take this path, so channel feeds, outcome
+// subscriptions and watchlists never receive them.
+func (h *WebhookHandler) dispatchToUsers(ctx context.Context, notification *eventNotification, matches func(subscription *models.Subscription) bool) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dispatchTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "dispatch."+notification.eventType)
+	defer span.End()
+	if h.discordSession == nil {
+		h.logger.Error("Discord session not set")
+		return
+	}
+
+	subscriptions, err := h.subscriptionService.GetAllSubscriptions(ctx)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to get subscriptions: %v", err))
+		return
+	}
+	now := time.Now()
+	batch := h.newFanoutBatch(notification.priority())
+	for _, subscription := range subscriptions {
+		if subscription.Snoozed(now) || !matches(subscription) {
+			continue
+		}
+		h.sendToUser(ctx, notification, subscription.DiscordUserID, subscription, batch)
+	}
+	users, failed := batch.wait()
+	summarizeDelivery(ctx, notification, models.DeliveryStats{Users: users, Failed: failed})
+}
+
+// sendToUser submits a notification to a user, DMed in their timezone or emailed when they route its
+// event to email. subscription is nil for users without one, like the owners of shared watchlists. In
+// shadow mode the notification is only logged and recorded as shadowed.
+func (h *WebhookHandler) sendToUser(ctx context.Context, notification *eventNotification, discordUserID string, subscription *models.Subscription, batch *fanoutBatch) {
+	timezone := ""
+	if subscription != nil {
+		timezone = subscription.Timezone
+	}
+	userNotification := notification.localized(timezone)
+	emailed := h.email != nil && subscription != nil && subscription.EmailsResolutions() && emailedEvents[notification.eventType]
+	pushMode := models.PushOff
+	if h.push != nil && subscription != nil {
+		pushMode = subscription.PushMode()
+	}
+	batch.submit(ctx, discordUserID, func(ctx context.Context) error {
+		if h.shadowMode {
+			h.recordShadow(ctx, userNotification, models.RecipientUser, discordUserID)
+			return nil
+		}
+		// Emails and pushes are held during maintenance like DMs, and write out times in the user's
+		// timezone themselves
+		return h.holdDuringMaintenance(ctx, notification.priority(), func(ctx context.Context) error {
+			if emailed && h.sendInstead(ctx, h.email.SendDirectMessage, "an email", notification, discordUserID) {
+				return nil
+			}
+			if pushMode == models.PushAlways && h.sendInstead(ctx, h.push.Push, "a push notification", notification, discordUserID) {
+				return nil
+			}
+			return h.deliver(ctx, notification.priority(), func(ctx context.Context) error {
+				err := h.sendDirectNotification(ctx, discordUserID, userNotification)
+				if err != nil && pushMode == models.PushFallback {
+					h.logger.Warning(fmt.Sprintf("Failed to send DM to user %s, pushing it instead: %v", discordUserID, err))
+					if h.sendInstead(ctx, h.push.Push, "a push notification", notification, discordUserID) {
+						return nil
+					}
+				}
+				if err != nil {
+					h.logger.Error(fmt.Sprintf("Failed to send DM to user %s: %v", discordUserID, err))
+					h.deadLetter(ctx, unsent(userNotification, err), models.RecipientUser, discordUserID, false, err)
+				} else {
+					h.logger.Info(fmt.Sprintf("Sent DM to user %s", discordUserID))
+				}
+				h.recordDelivery(ctx, notification.eventType, discordUserID, err)
+				h.recordReceipt(ctx, notification, models.RecipientUser, discordUserID, err)
+				return err
+			})
+		})
+	})
+}
+
+// sendInstead delivers a user's notification some other way than a DM, recording it like a DM, and
+// reports whether it was sent. A failure is only logged, leaving the caller to DM the user.
+func (h *WebhookHandler) sendInstead(ctx context.Context, send func(ctx context.Context, discordUserID, message string) error, medium string, notification *eventNotification, discordUserID string) bool {
+	if err := send(ctx, discordUserID, notification.content); err != nil {
+		h.logger.Warning(fmt.Sprintf("Failed to send user %s %s: %v", discordUserID, medium, err))
+		return false
+	}
+	h.logger.Info(fmt.Sprintf("Sent user %s %s", discordUserID, medium))
+	h.recordDelivery(ctx, notification.eventType, discordUserID, nil)
+	h.recordReceipt(ctx, notification, models.RecipientUser, discordUserID, nil)
+	return true
+}
+
+// sendDirectNotification opens a user's DM channel and posts a notification to it
+func (h *WebhookHandler) sendDirectNotification(ctx context.Context, discordUserID string, notification *eventNotification) error {
+	channel, err := h.createDMChannel(ctx, discordUserID)
+	if err != nil {
+		return fmt.Errorf("failed to create DM channel: %w", err)
+	}
+	_, err = h.sendNotification(ctx, channel.ID, notification)
+	return err
+}
+
+// createDMChannel opens the DM channel used to message a user
+func (h *WebhookHandler) createDMChannel(ctx context.Context, discordUserID string) (*discordgo.Channel, error) {
+	ctx, span := tracing.Start(ctx, "discord.UserChannelCreate", attribute.String("discord.user_id", discordUserID))
+	channel, err := h.discordSession.UserChannelCreate(discordUserID, discordgo.WithContext(ctx))
+	tracing.End(span, err)
+	return channel, err
+}
+
+// belowMinBuyAmount reports whether a notification is a buy smaller than the given minimum
+func belowMinBuyAmount(notification *eventNotification, minAmount float64) bool {
+	return notification.eventType == models.EventMarketBuy && notification.buyAmount < minAmount
+}
@@ -0,0 +1,61 @@
+package web
+
+import (
+	"context"
+	"sync"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// SetFanoutPool sets the worker pool event fan-outs send through concurrently
+func (h *WebhookHandler) SetFanoutPool(pool *services.FanoutPool) {
+	h.fanout = pool
+}
+
+// fanoutBatch collects the outcome of the sends of one fan-out
+type fanoutBatch struct {
+	pool     *services.FanoutPool // nil runs every send before submit returns
+	priority models.Priority      // priority of the fan-out's event in the pool's queues
+	wg       sync.WaitGroup
+	mutex    sync.Mutex
+	sent     int
+	failed   int
+}
+
+// newFanoutBatch starts collecting the sends of a fan-out of the given priority
+func (h *WebhookHandler) newFanoutBatch(priority models.Priority) *fanoutBatch {
+	return &fanoutBatch{pool: h.fanout, priority: priority}
+}
+
+// submit sends to a destination through the pool, or right away without one, counting the outcome
+func (batch *fanoutBatch) submit(ctx context.Context, destination string, send func(context.Context) error) {
+	if batch.pool == nil {
+		batch.record(send(ctx))
+		return
+	}
+	batch.wg.Add(1)
+	batch.pool.Submit(ctx, destination, batch.priority, send, func(err error) {
+		batch.record(err)
+		batch.wg.Done()
+	})
+}
+
+// record counts a send's outcome
+func (batch *fanoutBatch) record(err error) {
+	batch.mutex.Lock()
+	defer batch.mutex.Unlock()
+	if err != nil {
+		batch.failed++
+	} else {
+		batch.sent++
+	}
+}
+
+// wait waits for every submitted send and returns how many were sent or queued and how many failed
+func (batch *fanoutBatch) wait() (sent, failed int) {
+	batch.wg.Wait()
+	batch.mutex.Lock()
+	defer batch.mutex.Unlock()
+	return batch.sent, batch.failed
+}
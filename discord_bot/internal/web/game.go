@@ -0,0 +1,62 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// settleGameBets settles the prediction game's bets on a resolved market, or refunds them when it was
+// cancelled, then DMs each bettor one message with how their bets ended. Snoozed users are not DMed,
+// and failures are only logged since the event was delivered.
+func (h *WebhookHandler) settleGameBets(ctx context.Context, eventType string, market *models.Market) {
+	if h.game == nil || market.ID == "" {
+		return
+	}
+	var bets []*models.GameBet
+	var err error
+	if eventType == models.EventMarketCancelled {
+		bets, err = h.game.RefundMarket(ctx, market.ID)
+	} else {
+		bets, err = h.game.SettleMarket(ctx, market)
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to settle game bets on market %s: %v", market.ID, err))
+		return
+	}
+	if len(bets) == 0 || h.discordSession == nil {
+		return
+	}
+
+	byUser := make(map[string][]*models.GameBet)
+	var userIDs []string
+	for _, bet := range bets {
+		if byUser[bet.DiscordUserID] == nil {
+			userIDs = append(userIDs, bet.DiscordUserID)
+		}
+		byUser[bet.DiscordUserID] = append(byUser[bet.DiscordUserID], bet)
+	}
+
+	now := time.Now()
+	batch := h.newFanoutBatch(models.EventPriority(eventType))
+	for _, userID := range userIDs {
+		subscription, err := h.subscriptionService.GetUserSubscriptions(ctx, userID)
+		if err != nil {
+			h.logger.Warning(fmt.Sprintf("Failed to get the subscription of user %s, not telling them how their bets settled: %v", userID, err))
+			continue
+		}
+		if subscription.Snoozed(now) {
+			continue
+		}
+		notification := &eventNotification{
+			eventType: eventType,
+			content:   renderMessage(ctx, "GameSettlementMessage", func() string { return h.game.CreateSettlementMessage(market, byUser[userID]) }),
+		}
+		h.sendToUser(ctx, notification, userID, subscription, batch)
+	}
+	if sent, failed := batch.wait(); sent+failed > 0 {
+		h.logger.Info(fmt.Sprintf("Told %d bettors on market %s how their bets settled, %d failed", sent, market.ID, failed))
+	}
+}
@@ -0,0 +1,91 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// HandleGetGuildBranding handles GET /discord/guild/branding/{guild_id}
+func (h *WebhookHandler) HandleGetGuildBranding(w http.ResponseWriter, r *http.Request) {
+	guildID := r.PathValue("guild_id")
+	guild, err := h.subscriptionService.GetGuildConfig(r.Context(), guildID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to load guild config")
+		return
+	}
+	writeGuildBranding(w, guildID, guild)
+}
+
+// HandleSetGuildBranding handles PUT /discord/guild/branding/{guild_id}
+//
+// The branding replaces any the guild set before; omitted fields follow the bot's style.
+func (h *WebhookHandler) HandleSetGuildBranding(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload GuildBrandingRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	branding := &models.GuildBranding{Emojis: payload.Emojis, FooterText: payload.FooterText, FooterURL: payload.FooterURL}
+	if payload.AccentColor != "" {
+		if branding.AccentColor, err = services.ParseAccentColor(payload.AccentColor); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	guildID := r.PathValue("guild_id")
+	guild, err := h.subscriptionService.SetGuildBranding(r.Context(), guildID, branding)
+	if errors.Is(err, services.ErrInvalidChannelSettings) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to save branding of guild %s: %v", guildID, err))
+		writeServiceError(w, err, "Failed to save branding")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("Branding of guild %s set by %s", guildID, apiActor(r)))
+	writeGuildBranding(w, guildID, guild)
+}
+
+// HandleResetGuildBranding handles DELETE /discord/guild/branding/{guild_id}
+func (h *WebhookHandler) HandleResetGuildBranding(w http.ResponseWriter, r *http.Request) {
+	guildID := r.PathValue("guild_id")
+	guild, err := h.subscriptionService.SetGuildBranding(r.Context(), guildID, nil)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to reset branding of guild %s: %v", guildID, err))
+		writeServiceError(w, err, "Failed to reset branding")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("Branding of guild %s reset by %s", guildID, apiActor(r)))
+	writeGuildBranding(w, guildID, guild)
+}
+
+// writeGuildBranding writes the branding of a guild's alerts. guild may be nil.
+func writeGuildBranding(w http.ResponseWriter, guildID string, guild *models.GuildConfig) {
+	response := GuildBrandingResponse{GuildID: guildID, Emojis: map[string]string{}}
+	if guild != nil && guild.Branding != nil {
+		branding := guild.Branding
+		response.Branded = true
+		for event, emoji := range branding.Emojis {
+			response.Emojis[event] = emoji
+		}
+		response.AccentColor, response.FooterText, response.FooterURL = services.FormatAccentColor(branding.AccentColor), branding.FooterText, branding.FooterURL
+	}
+	b, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
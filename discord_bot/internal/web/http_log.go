@@ -0,0 +1,212 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// HTTP log query limits
+const (
+	defaultHTTPLogLimit = 50
+	maxHTTPLogLimit     = services.MaxHTTPLogCapacity
+)
+
+// maxHTTPLogCapture is the most of a response body kept for the log; larger responses cannot be
+// redacted, so their body is left out
+const maxHTTPLogCapture = 1 << 20
+
+// httpLogRoutePrefix is the path of the log's own endpoints, which are never logged
+const httpLogRoutePrefix = "/discord/admin/http-log"
+
+// SetHTTPLogService sets the service logging sampled requests and requests to chosen endpoints
+func (h *WebhookHandler) SetHTTPLogService(httpLog services.HTTPLogService) {
+	h.httpLog = httpLog
+}
+
+// bodyRecorder records the status and body of a response as it is written
+type bodyRecorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+// WriteHeader records the status before writing it
+func (recorder *bodyRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body up to maxHTTPLogCapture bytes before writing it
+func (recorder *bodyRecorder) Write(b []byte) (int, error) {
+	if recorder.body.Len()+len(b) > maxHTTPLogCapture {
+		recorder.overflow = true
+	} else if !recorder.overflow {
+		recorder.body.Write(b)
+	}
+	return recorder.ResponseWriter.Write(b)
+}
+
+// withHTTPLog logs the requests to a route that the HTTP log service picks, with their bodies and
+// response. Redaction is left to the service.
+func (h *WebhookHandler) withHTTPLog(route string, next http.HandlerFunc) http.HandlerFunc {
+	if strings.HasPrefix(route, httpLogRoutePrefix) {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.httpLog == nil {
+			next(w, r)
+			return
+		}
+		reason, ok := h.httpLog.ShouldLog(route)
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		start := time.Now()
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		recorder := &bodyRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+
+		headers := make(map[string]string, len(r.Header))
+		for name, values := range r.Header {
+			headers[name] = strings.Join(values, ", ")
+		}
+		entry := &models.HTTPLogEntry{
+			RequestID:      w.Header().Get(requestIDHeader),
+			Time:           start.UTC(),
+			Reason:         reason,
+			Method:         r.Method,
+			Route:          route,
+			Path:           r.URL.RequestURI(),
+			Status:         recorder.status,
+			DurationMs:     time.Since(start).Milliseconds(),
+			RequestHeaders: headers,
+			RequestBody:    string(body),
+			ResponseBody:   recorder.body.String(),
+			Truncated:      recorder.overflow,
+		}
+		if recorder.overflow {
+			entry.ResponseBody = ""
+		}
+		// The request's context ends with its timeout, but the entry is saved regardless
+		if err := h.httpLog.Record(context.WithoutCancel(r.Context()), entry); err != nil {
+			h.logger.Error(fmt.Sprintf("Failed to log request %s: %v", entry.RequestID, err))
+		}
+	}
+}
+
+// HandleHTTPLog handles GET /discord/admin/http-log
+func (h *WebhookHandler) HandleHTTPLog(w http.ResponseWriter, r *http.Request) {
+	if h.httpLog == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "HTTP logging not enabled")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.HTTPLogFilter{Route: query.Get("route"), Limit: defaultHTTPLogLimit}
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxHTTPLogLimit {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxHTTPLogLimit))
+			return
+		}
+		filter.Limit = parsed
+	}
+
+	entries, err := h.httpLog.Entries(r.Context(), filter)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to load HTTP log: %v", err))
+		writeServiceError(w, err, "Failed to load HTTP log")
+		return
+	}
+
+	b, _ := json.Marshal(HTTPLogResponse{Config: h.httpLog.Config(), Entries: entries})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// HandleClearHTTPLog handles DELETE /discord/admin/http-log
+func (h *WebhookHandler) HandleClearHTTPLog(w http.ResponseWriter, r *http.Request) {
+	if h.httpLog == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "HTTP logging not enabled")
+		return
+	}
+
+	cleared, err := h.httpLog.Clear(r.Context())
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to clear HTTP log: %v", err))
+		writeServiceError(w, err, "Failed to clear HTTP log")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("HTTP log of %d requests cleared by %s", cleared, apiActor(r)))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleHTTPLogConfig handles GET /discord/admin/http-log/config
+func (h *WebhookHandler) HandleHTTPLogConfig(w http.ResponseWriter, r *http.Request) {
+	if h.httpLog == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "HTTP logging not enabled")
+		return
+	}
+	writeHTTPLogConfig(w, h.httpLog.Config())
+}
+
+// HandleSetHTTPLogConfig handles PUT /discord/admin/http-log/config
+//
+// The config replaces the current one until the bot restarts, when the environment's applies again.
+func (h *WebhookHandler) HandleSetHTTPLogConfig(w http.ResponseWriter, r *http.Request) {
+	if h.httpLog == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "HTTP logging not enabled")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload services.HTTPLogConfig
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	config, err := h.httpLog.SetConfig(payload)
+	if errors.Is(err, services.ErrInvalidHTTPLogConfig) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to set HTTP log config: %v", err))
+		writeServiceError(w, err, "Failed to set HTTP log config")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("HTTP log config set by %s", apiActor(r)))
+	writeHTTPLogConfig(w, config)
+}
+
+// writeHTTPLogConfig writes the HTTP log config as JSON
+func writeHTTPLogConfig(w http.ResponseWriter, config services.HTTPLogConfig) {
+	b, _ := json.Marshal(config)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
@@ -0,0 +1,247 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"coral-bot/discord_bot/internal/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// interactionResponseTimeout is how long a handler has to respond to an interaction before Discord's
+// request is answered with a deferred response. Discord gives up on requests after three seconds.
+const interactionResponseTimeout = 2500 * time.Millisecond
+
+// InteractionHandler handles Discord interactions, like the command handler does for the interaction
+// events of the gateway
+type InteractionHandler interface {
+	HandleInteraction(session *discordgo.Session, interaction *discordgo.InteractionCreate)
+}
+
+// SetInteractionHandler serves Discord interactions over HTTP at POST /discord/interactions, so commands
+// work without a gateway connection. Requests are verified against the application's public key, hex
+// encoded as shown in the Discord developer portal.
+func (h *WebhookHandler) SetInteractionHandler(handler InteractionHandler, publicKey string) error {
+	key, err := hex.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("public key must be %d hex-encoded bytes", ed25519.PublicKeySize)
+	}
+	h.interactions = handler
+	h.interactionKey = ed25519.PublicKey(key)
+	return nil
+}
+
+// HandleInteraction verifies the signature of an interaction sent by Discord and runs it through the
+// interaction handler. The handler's first response to the interaction is the body of the HTTP
+// response; when it takes too long, the interaction is deferred and the response edited in later.
+func (h *WebhookHandler) HandleInteraction(w http.ResponseWriter, r *http.Request) {
+	if h.interactions == nil || h.discordSession == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Interactions endpoint not configured")
+		return
+	}
+	if !discordgo.VerifyInteraction(r, h.interactionKey) {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid request signature")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var interaction discordgo.Interaction
+	if err := decodePayload(r.Context(), body, &interaction); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if interaction.Type == discordgo.InteractionPing {
+		writeInteractionResponse(w, &discordgo.InteractionResponse{Type: discordgo.InteractionResponsePong})
+		return
+	}
+
+	responder := h.newInteractionResponder(&interaction)
+	h.interactionsRunning.Add(1)
+	go func() {
+		defer h.interactionsRunning.Done()
+		defer close(responder.done)
+		h.interactions.HandleInteraction(responder.session, &discordgo.InteractionCreate{Interaction: &interaction})
+	}()
+	responder.answer(r.Context(), w)
+}
+
+// WaitForInteractions waits for the handlers of the interactions received over HTTP to return, since
+// they can run on after Discord's request was answered
+func (h *WebhookHandler) WaitForInteractions() {
+	h.interactionsRunning.Wait()
+}
+
+// writeInteractionResponse writes an interaction response as the body of Discord's request
+func writeInteractionResponse(w http.ResponseWriter, response *discordgo.InteractionResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// capturedResponse is a response to an interaction the handler sent to Discord's callback endpoint
+type capturedResponse struct {
+	contentType string
+	body        []byte
+}
+
+// interactionResponder stands in for Discord's REST API while an interaction received over HTTP is
+// handled. The handler's first response to the interaction answers Discord's request instead of being
+// posted to the callback endpoint; edits, follow-ups and every other call go to Discord as usual.
+type interactionResponder struct {
+	h           *WebhookHandler
+	interaction *discordgo.Interaction
+	session     *discordgo.Session // the bot's session, with its REST calls sent through the responder
+	next        http.RoundTripper
+	callback    string                // URL of the interaction's callback endpoint
+	responses   chan capturedResponse // the handler's response, received by answer
+	answered    chan struct{}         // closed once Discord's request was answered
+	done        chan struct{}         // closed when the handler returns
+	deferred    atomic.Bool           // Discord's request was answered with a deferred response
+}
+
+// newInteractionResponder creates a responder for an interaction, sharing the bot session's token,
+// rate limits and state
+func (h *WebhookHandler) newInteractionResponder(interaction *discordgo.Interaction) *interactionResponder {
+	responder := &interactionResponder{
+		h:           h,
+		interaction: interaction,
+		callback:    discordgo.EndpointInteractionResponse(interaction.ID, interaction.Token),
+		responses:   make(chan capturedResponse),
+		answered:    make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	responder.session = services.SessionWithTransport(h.discordSession, func(next http.RoundTripper) http.RoundTripper {
+		responder.next = next
+		return responder
+	})
+	return responder
+}
+
+// answer answers Discord's request with the handler's response, or with a deferred response when the
+// handler takes too long. A handler that returns without responding leaves the request unanswered.
+func (responder *interactionResponder) answer(ctx context.Context, w http.ResponseWriter) {
+	defer close(responder.answered)
+	timer := time.NewTimer(interactionResponseTimeout)
+	defer timer.Stop()
+
+	select {
+	case response := <-responder.responses:
+		w.Header().Set("Content-Type", response.contentType)
+		w.Write(response.body)
+	case <-responder.done:
+		responder.h.logger.Warning(fmt.Sprintf("Interaction %s was handled without a response", responder.interaction.ID))
+		w.WriteHeader(http.StatusNoContent)
+	case <-timer.C:
+		responder.deferred.Store(true)
+		responder.h.logger.Warning(fmt.Sprintf("Deferring interaction %s, its handler did not respond within %s", responder.interaction.ID, interactionResponseTimeout))
+		writeInteractionResponse(w, deferredResponse(responder.interaction))
+	case <-ctx.Done():
+	}
+}
+
+// deferredResponse acknowledges an interaction whose response follows later. Command responses are
+// deferred privately, since the response might be meant for the user alone.
+func deferredResponse(interaction *discordgo.Interaction) *discordgo.InteractionResponse {
+	switch interaction.Type {
+	case discordgo.InteractionMessageComponent:
+		return &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate}
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		return &discordgo.InteractionResponse{
+			Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+			Data: &discordgo.InteractionResponseData{Choices: []*discordgo.ApplicationCommandOptionChoice{}},
+		}
+	default:
+		return &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+		}
+	}
+}
+
+// RoundTrip hands the handler's first response to the interaction to answer, and sends every other
+// request to Discord. A response that comes after the interaction was deferred edits the deferred
+// message instead.
+func (responder *interactionResponder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || req.URL.String() != responder.callback {
+		return responder.next.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	select {
+	case responder.responses <- capturedResponse{contentType: req.Header.Get("Content-Type"), body: body}:
+		<-responder.answered
+		return noContentResponse(req), nil
+	case <-responder.answered:
+	}
+	if !responder.deferred.Load() {
+		// Answered already, so Discord rejects the second response as it would over the gateway
+		return responder.next.RoundTrip(req)
+	}
+	if err := responder.editDeferred(req.Header.Get("Content-Type"), body); err != nil {
+		return nil, err
+	}
+	return noContentResponse(req), nil
+}
+
+// editDeferred turns a response sent after the interaction was deferred into an edit of the deferred message
+func (responder *interactionResponder) editDeferred(contentType string, body []byte) error {
+	if !strings.HasPrefix(contentType, "application/json") {
+		return errors.New("responses with files cannot replace a deferred interaction response")
+	}
+	var response discordgo.InteractionResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return err
+	}
+	switch response.Type {
+	case discordgo.InteractionResponseChannelMessageWithSource, discordgo.InteractionResponseUpdateMessage:
+	default:
+		responder.h.logger.Warning(fmt.Sprintf("Dropping response of type %d to deferred interaction %s", response.Type, responder.interaction.ID))
+		return nil
+	}
+	edit := &discordgo.WebhookEdit{}
+	if data := response.Data; data != nil {
+		edit.Content = &data.Content
+		edit.AllowedMentions = data.AllowedMentions
+		if len(data.Embeds) > 0 {
+			edit.Embeds = &data.Embeds
+		}
+		if len(data.Components) > 0 {
+			edit.Components = &data.Components
+		}
+	}
+	_, err := responder.h.discordSession.InteractionResponseEdit(responder.interaction, edit)
+	return err
+}
+
+// noContentResponse is the reply of Discord's callback endpoint to a response it accepted
+func noContentResponse(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:     "204 No Content",
+		StatusCode: http.StatusNoContent,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		Request:    req,
+	}
+}
@@ -0,0 +1,333 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/repository"
+	"coral-bot/discord_bot/internal/services"
+	"coral-bot/discord_bot/internal/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Load test limits keep one run of the admin endpoint from tying up the bot
+const (
+	maxLoadTestChannels = 10000
+	maxLoadTestUsers    = 100000
+	maxLoadTestEvents   = 1000
+	maxLoadTestLatency  = 5 * time.Second
+	maxLoadTestWorkers  = 256
+)
+
+// loadTestMarketID is the market of every synthetic event, followed by every simulated user
+const loadTestMarketID = "loadtest"
+
+// LoadTestOptions sizes a load test
+type LoadTestOptions struct {
+	Channels    int           // channels with the market feed on
+	Users       int           // users subscribed to the load test market
+	Events      int           // events sent by RunLoadTest
+	SendLatency time.Duration // time the fake Discord API takes to answer each request
+	Workers     int           // fan-out workers, 0 sends one message after another
+	QueueSize   int           // sends of each priority a worker holds, DefaultFanoutQueueSize when 0
+}
+
+// LoadTest delivers synthetic market buys to simulated channels and users through the fan-out, with a
+// fake Discord API in place of Discord. Nothing leaves the process, and the simulated subscriptions live
+// in a repository of their own.
+type LoadTest struct {
+	options LoadTestOptions
+	handler *WebhookHandler
+	discord *simulatedDiscord
+	pool    *services.FanoutPool
+}
+
+// NewLoadTest subscribes the simulated channels and users of a load test
+func NewLoadTest(ctx context.Context, options LoadTestOptions, logger *utils.Logger) (*LoadTest, error) {
+	repo := repository.NewInMemorySubscriptionRepository()
+	for i := 0; i < options.Channels; i++ {
+		config := &models.ChannelConfig{
+			ChannelID:     fmt.Sprintf("loadtest-channel-%d", i),
+			GuildID:       fmt.Sprintf("loadtest-guild-%d", i),
+			FeedEnabled:   true,
+			FrequencyMode: "medium",
+		}
+		if err := repo.SaveChannelConfig(ctx, config); err != nil {
+			return nil, fmt.Errorf("failed to configure load test channel: %w", err)
+		}
+	}
+	for i := 0; i < options.Users; i++ {
+		subscription := &models.Subscription{
+			DiscordUserID:     fmt.Sprintf("loadtest-user-%d", i),
+			SubscribedMarkets: []string{loadTestMarketID},
+		}
+		if err := repo.SaveSubscription(ctx, subscription); err != nil {
+			return nil, fmt.Errorf("failed to subscribe load test user: %w", err)
+		}
+	}
+
+	discord := &simulatedDiscord{latency: options.SendLatency}
+	session, err := discordgo.New("Bot loadtest")
+	if err != nil {
+		return nil, err
+	}
+	session.Client = &http.Client{Transport: discord}
+
+	handler := NewWebhookHandler(services.NewMockMarketService(logger), services.NewSubscriptionService(repo, logger), logger)
+	handler.SetDiscordSession(session)
+	test := &LoadTest{options: options, handler: handler, discord: discord}
+	if options.Workers > 0 {
+		queueSize := options.QueueSize
+		if queueSize <= 0 {
+			queueSize = services.DefaultFanoutQueueSize
+		}
+		test.pool = services.NewFanoutPool(options.Workers, queueSize)
+		handler.SetFanoutPool(test.pool)
+	}
+	return test, nil
+}
+
+// Run sends events one after another, each a buy in the load test market, and reports the throughput
+// and delivery latency of their messages
+func (test *LoadTest) Run(ctx context.Context, events int) (*models.LoadTestReport, error) {
+	test.discord.reset()
+	start := time.Now()
+	for i := 0; i < events; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		payload, _ := json.Marshal(MarketBuyEventRequest{
+			MarketID: loadTestMarketID,
+			Title:    "Load test market",
+			Amount:   float64(100 + i),
+			Outcome:  "Yes",
+			Buyer:    "loadtest",
+		})
+		test.discord.startEvent()
+		if _, err := test.handler.ProcessEventJSON(ctx, models.EventMarketBuy, payload); err != nil {
+			return nil, fmt.Errorf("load test event %d failed: %w", i+1, err)
+		}
+	}
+	duration := time.Since(start)
+
+	latencies := test.discord.results()
+	report := &models.LoadTestReport{
+		Channels:      test.options.Channels,
+		Users:         test.options.Users,
+		Events:        events,
+		Workers:       test.options.Workers,
+		SendLatencyMs: milliseconds(test.options.SendLatency),
+		Messages:      len(latencies),
+		DurationMs:    milliseconds(duration),
+	}
+	if duration > 0 {
+		report.Throughput = float64(len(latencies)) / duration.Seconds()
+	}
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		report.LatencyP50Ms = milliseconds(percentile(latencies, 0.50))
+		report.LatencyP99Ms = milliseconds(percentile(latencies, 0.99))
+		report.LatencyMaxMs = milliseconds(latencies[len(latencies)-1])
+	}
+	return report, nil
+}
+
+// Close stops the load test's fan-out workers
+func (test *LoadTest) Close() {
+	if test.pool != nil {
+		test.pool.Stop()
+	}
+}
+
+// RunLoadTest sets up a load test, sends options.Events events through it and cleans up
+func RunLoadTest(ctx context.Context, options LoadTestOptions, logger *utils.Logger) (*models.LoadTestReport, error) {
+	test, err := NewLoadTest(ctx, options, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer test.Close()
+	return test.Run(ctx, options.Events)
+}
+
+// percentile returns the q-th quantile of sorted latencies, by the nearest rank
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	rank := int(math.Ceil(q * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+// simulatedDiscord is the Discord API of a load test. It answers every request after a fixed latency and
+// times each message from the start of the event that sent it.
+type simulatedDiscord struct {
+	latency    time.Duration
+	mutex      sync.Mutex
+	eventStart time.Time
+	latencies  []time.Duration
+}
+
+// reset forgets the messages of earlier runs
+func (discord *simulatedDiscord) reset() {
+	discord.mutex.Lock()
+	defer discord.mutex.Unlock()
+	discord.latencies = nil
+}
+
+// startEvent marks the arrival of the next event
+func (discord *simulatedDiscord) startEvent() {
+	discord.mutex.Lock()
+	defer discord.mutex.Unlock()
+	discord.eventStart = time.Now()
+}
+
+// results returns the delivery latency of every message received since the last reset
+func (discord *simulatedDiscord) results() []time.Duration {
+	discord.mutex.Lock()
+	defer discord.mutex.Unlock()
+	return append([]time.Duration(nil), discord.latencies...)
+}
+
+// RoundTrip answers a request of the bot's Discord session
+func (discord *simulatedDiscord) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+	}
+	if discord.latency > 0 {
+		timer := time.NewTimer(discord.latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	path := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, "/api/v"+discordgo.APIVersion), "/"), "/")
+	switch {
+	case req.Method == http.MethodPost && len(path) == 3 && path[0] == "users" && path[2] == "channels":
+		var recipient struct {
+			RecipientID string `json:"recipient_id"`
+		}
+		json.Unmarshal(body, &recipient)
+		return simulatedResponse(req, discordgo.Channel{ID: "dm-" + recipient.RecipientID, Type: discordgo.ChannelTypeDM})
+	case req.Method == http.MethodPost && len(path) == 3 && path[0] == "channels" && path[2] == "messages":
+		discord.mutex.Lock()
+		discord.latencies = append(discord.latencies, time.Since(discord.eventStart))
+		id := len(discord.latencies)
+		discord.mutex.Unlock()
+		return simulatedResponse(req, discordgo.Message{ID: fmt.Sprintf("%d", id), ChannelID: path[1]})
+	case req.Method == http.MethodGet && len(path) == 2 && path[0] == "channels":
+		return simulatedResponse(req, discordgo.Channel{ID: path[1], Type: discordgo.ChannelTypeGuildText})
+	}
+	return simulatedResponse(req, struct{}{})
+}
+
+// simulatedResponse answers a request to the simulated Discord API with a JSON body
+func simulatedResponse(req *http.Request, body interface{}) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
+
+// HandleAdminLoadTest handles POST /discord/admin/loadtest
+//
+// Synthetic buys are delivered to simulated channels and users through the fan-out and a fake Discord
+// API, so nothing is sent to Discord and the bot's own subscriptions are untouched. The run uses as many
+// workers as the bot's fan-out pool unless the request says otherwise.
+func (h *WebhookHandler) HandleAdminLoadTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload LoadTestRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	options := LoadTestOptions{
+		Channels:    payload.Channels,
+		Users:       payload.Users,
+		Events:      payload.Events,
+		SendLatency: time.Duration(payload.SendLatencyMs) * time.Millisecond,
+	}
+	if payload.Workers != nil {
+		options.Workers = *payload.Workers
+	} else if h.fanout != nil {
+		options.Workers = h.fanout.Status().Workers
+	}
+	switch {
+	case options.Channels < 0 || options.Channels > maxLoadTestChannels:
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("channels must be between 0 and %d", maxLoadTestChannels))
+		return
+	case options.Users < 0 || options.Users > maxLoadTestUsers:
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("users must be between 0 and %d", maxLoadTestUsers))
+		return
+	case options.Channels+options.Users == 0:
+		writeJSONError(w, http.StatusBadRequest, "channels or users is required")
+		return
+	case options.Events < 1 || options.Events > maxLoadTestEvents:
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("events must be between 1 and %d", maxLoadTestEvents))
+		return
+	case options.SendLatency < 0 || options.SendLatency > maxLoadTestLatency:
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("send_latency_ms must be between 0 and %d", maxLoadTestLatency.Milliseconds()))
+		return
+	case options.Workers < 0 || options.Workers > maxLoadTestWorkers:
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("workers must be between 0 and %d", maxLoadTestWorkers))
+		return
+	}
+
+	report, err := RunLoadTest(r.Context(), options, utils.NewQuietLogger())
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		writeJSONError(w, http.StatusServiceUnavailable, "Load test cancelled")
+		return
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Load test failed: %v", err))
+		writeJSONError(w, http.StatusInternalServerError, "Load test failed")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("Load test delivered %d messages at %.0f/s, p99 %.1fms", report.Messages, report.Throughput, report.LatencyP99Ms))
+
+	b, _ := json.Marshal(report)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
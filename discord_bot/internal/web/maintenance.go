@@ -0,0 +1,78 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// SetMaintenanceMode sets the maintenance mode outbound messages are held by while it is on
+func (h *WebhookHandler) SetMaintenanceMode(maintenance *services.MaintenanceMode) {
+	h.maintenance = maintenance
+}
+
+// holdDuringMaintenance runs send right away, or buffers it until maintenance ends
+func (h *WebhookHandler) holdDuringMaintenance(ctx context.Context, priority models.Priority, send func(context.Context) error) error {
+	if h.maintenance == nil {
+		return send(ctx)
+	}
+	return h.maintenance.DeliverPriority(ctx, priority, send)
+}
+
+// HandleMaintenanceStatus handles GET /discord/admin/maintenance
+func (h *WebhookHandler) HandleMaintenanceStatus(w http.ResponseWriter, r *http.Request) {
+	if h.maintenance == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Maintenance mode not enabled")
+		return
+	}
+	writeMaintenanceStatus(w, h.maintenance.Status())
+}
+
+// HandleSetMaintenance handles PUT /discord/admin/maintenance
+//
+// Starting maintenance pauses every outbound notification and answers commands with the notice.
+// Ending it sends the messages buffered meanwhile, in the background.
+func (h *WebhookHandler) HandleSetMaintenance(w http.ResponseWriter, r *http.Request) {
+	if h.maintenance == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Maintenance mode not enabled")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload MaintenanceRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil || payload.Enabled == nil {
+		writeJSONError(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+	notice := strings.TrimSpace(payload.Notice)
+	if len([]rune(notice)) > services.MaxMaintenanceNoticeLength {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("notice must be at most %d characters", services.MaxMaintenanceNoticeLength))
+		return
+	}
+
+	if *payload.Enabled {
+		h.maintenance.Start(notice)
+		h.logger.Info("Maintenance started through the API")
+	} else {
+		flushing := h.maintenance.End()
+		h.logger.Info(fmt.Sprintf("Maintenance ended through the API, sending %d buffered messages", flushing))
+	}
+	writeMaintenanceStatus(w, h.maintenance.Status())
+}
+
+// writeMaintenanceStatus writes the maintenance status as JSON
+func writeMaintenanceStatus(w http.ResponseWriter, status services.MaintenanceStatus) {
+	b, _ := json.Marshal(status)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
@@ -0,0 +1,131 @@
+package web
+
+import "coral-bot/discord_bot/internal/models"
+
+// MarketEvent is an event normalized from whichever format it arrived in: a legacy /webhooks body, a
+// /discord/events body, a batch entry, a bus message or a gRPC event. Only the fields of its type are
+// set besides the market.
+type MarketEvent struct {
+	Type    string         // such as models.EventNewMarket
+	Market  models.Market  // the market the event is about, empty for creator events
+	Creator models.Creator // the creator of creator events
+
+	Reason    string  // why a cancelled market was voided
+	Amount    float64 // amount of a buy
+	Outcome   string  // outcome of a buy
+	Buyer     string  // buyer of a buy
+	Change    float64 // liquidity added, negative when it was removed
+	Liquidity float64 // the market's liquidity after a liquidity change
+	Author    string  // author of a comment
+	Comment   string  // text of a comment
+
+	Fields map[string]interface{} // fields of a custom event, read by its template
+}
+
+// eventPayload is an event body in one of the formats the backend sends, decoded from JSON and
+// normalized into a MarketEvent. Errors describe what is wrong with the body.
+type eventPayload interface {
+	marketEvent() (*MarketEvent, error)
+}
+
+// outcomeNames returns the names of an event's outcomes
+func outcomeNames(outcomes []EventOutcome) []string {
+	names := make([]string, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		names = append(names, outcome.Name)
+	}
+	return names
+}
+
+// outcomePercentages returns the odds of an event's outcomes
+func outcomePercentages(outcomes []EventOutcome) []float64 {
+	percentages := make([]float64, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		percentages = append(percentages, outcome.Pct)
+	}
+	return percentages
+}
+
+// marketEvent normalizes a legacy webhook body, whose event_type names the event and whose market is
+// passed on as sent
+func (payload *LegacyMarketEventRequest) marketEvent() (*MarketEvent, error) {
+	return &MarketEvent{Type: payload.EventType, Market: payload.Market}, nil
+}
+
+func (payload *NewMarketEventRequest) marketEvent() (*MarketEvent, error) {
+	start, err := ParseEventTime("start_time", payload.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	end, err := ParseEventTime("end_time", payload.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	return &MarketEvent{Type: models.EventNewMarket, Market: models.Market{
+		ID:          payload.MarketID,
+		Title:       payload.Title,
+		Description: payload.Description,
+		Outcomes:    outcomeNames(payload.Outcomes),
+		Percentages: []float64{},
+		Category:    payload.Category,
+		Creator:     payload.Creator,
+		Volume:      payload.Volume,
+		StartTime:   start,
+		EndTime:     end,
+		Status:      "active",
+		Link:        payload.Link,
+	}}, nil
+}
+
+func (payload *MarketUpdateEventRequest) marketEvent() (*MarketEvent, error) {
+	end, err := ParseEventTime("end_time", payload.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	return &MarketEvent{Type: models.EventMarketUpdate, Market: models.Market{
+		ID:          payload.MarketID,
+		Title:       payload.Title,
+		Outcomes:    outcomeNames(payload.Outcomes),
+		Percentages: outcomePercentages(payload.Outcomes),
+		Volume:      payload.Volume,
+		EndTime:     end,
+		Status:      "active",
+		Link:        payload.Link,
+	}}, nil
+}
+
+func (payload *TradingStartEventRequest) marketEvent() (*MarketEvent, error) {
+	return &MarketEvent{Type: models.EventTradingStarted, Market: models.Market{ID: payload.MarketID, Title: payload.Title, Description: payload.Description, Outcomes: payload.Outcomes, Link: payload.Link}}, nil
+}
+
+func (payload *TradingEndEventRequest) marketEvent() (*MarketEvent, error) {
+	return &MarketEvent{Type: models.EventTradingEnded, Market: models.Market{ID: payload.MarketID, Title: payload.Title, Description: payload.Description, Outcomes: outcomeNames(payload.Outcomes), Volume: payload.FinalPool, Link: payload.Link}}, nil
+}
+
+func (payload *MarketResolvedEventRequest) marketEvent() (*MarketEvent, error) {
+	return &MarketEvent{Type: models.EventMarketResolved, Market: models.Market{ID: payload.MarketID, Title: payload.Title, ResolvedOutcome: payload.WinningOutcome, Payouts: payload.Payouts, Volume: payload.TotalPool, Link: payload.Link, ResolvedAt: payload.ResolvedAt}}, nil
+}
+
+func (payload *MarketCancelledEventRequest) marketEvent() (*MarketEvent, error) {
+	return &MarketEvent{Type: models.EventMarketCancelled, Market: models.Market{ID: payload.MarketID, Title: payload.Title, Status: "cancelled", Volume: payload.RefundTotal, Link: payload.Link, ResolvedAt: payload.CancelledAt}, Reason: payload.Reason}, nil
+}
+
+func (payload *MarketBuyEventRequest) marketEvent() (*MarketEvent, error) {
+	return &MarketEvent{Type: models.EventMarketBuy, Market: models.Market{ID: payload.MarketID, Title: payload.Title, Link: payload.Link}, Amount: payload.Amount, Outcome: payload.Outcome, Buyer: payload.Buyer}, nil
+}
+
+func (payload *MarketLiquidityEventRequest) marketEvent() (*MarketEvent, error) {
+	return &MarketEvent{Type: models.EventMarketLiquidity, Market: models.Market{ID: payload.MarketID, Title: payload.Title, Outcomes: outcomeNames(payload.Outcomes), Percentages: outcomePercentages(payload.Outcomes), Link: payload.Link}, Change: payload.Change, Liquidity: payload.Liquidity}, nil
+}
+
+func (payload *MarketCommentEventRequest) marketEvent() (*MarketEvent, error) {
+	return &MarketEvent{Type: models.EventMarketComment, Market: models.Market{ID: payload.MarketID, Title: payload.Title, Link: payload.Link}, Author: payload.Author, Comment: payload.Comment}, nil
+}
+
+func (payload *CreatorJoinedEventRequest) marketEvent() (*MarketEvent, error) {
+	return &MarketEvent{Type: models.EventCreatorJoined, Creator: models.Creator{Name: payload.Creator, DisplayName: payload.DisplayName, Bio: payload.Bio, Link: payload.Link}}, nil
+}
+
+func (payload *CreatorMilestoneEventRequest) marketEvent() (*MarketEvent, error) {
+	return &MarketEvent{Type: models.EventCreatorMilestone, Creator: models.Creator{Name: payload.Creator, DisplayName: payload.DisplayName, Volume: payload.Volume, Link: payload.Link}}, nil
+}
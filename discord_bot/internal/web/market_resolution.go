@@ -0,0 +1,53 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// suppressAfterResolution marks a market resolved on its resolution or cancellation event, and reports
+// whether any other event is for a market that already resolved. Backends can send updates and buys for a market after its
+// resolution, which would announce a settled market as live again, so those are counted and published as
+// suppressed instead of delivered. Repository errors let the event through.
+func (h *WebhookHandler) suppressAfterResolution(ctx context.Context, notification *eventNotification, market *models.Market) bool {
+	if market.ID == "" {
+		return false
+	}
+	if notification.eventType == models.EventMarketResolved || notification.eventType == models.EventMarketCancelled {
+		resolvedAt := market.ResolvedAt
+		if resolvedAt.IsZero() {
+			resolvedAt = time.Now()
+		}
+		if err := h.subscriptionService.MarkMarketResolved(ctx, market.ID, resolvedAt); err != nil {
+			h.logger.Warning(fmt.Sprintf("Failed to mark market %s resolved: %v", market.ID, err))
+		}
+		return false
+	}
+
+	resolved, err := h.subscriptionService.IsMarketResolved(ctx, market.ID)
+	if err != nil {
+		h.logger.Warning(fmt.Sprintf("Failed to check whether market %s resolved: %v", market.ID, err))
+		return false
+	}
+	if !resolved {
+		return false
+	}
+	h.suppressedResolved.Add(1)
+	h.logger.Info(fmt.Sprintf("Suppressed %s event for resolved market %s", notification.eventType, market.ID))
+	h.publishSuppressed(ctx, notification, market)
+	return true
+}
+
+// cleanUpCancelledMarket removes the subscriptions and reminders of a cancelled market. The cancellation
+// was already delivered, so a failure is only logged.
+func (h *WebhookHandler) cleanUpCancelledMarket(ctx context.Context, market *models.Market) {
+	if market.ID == "" {
+		return
+	}
+	if _, err := h.subscriptionService.CleanUpCancelledMarket(ctx, market.ID, apiAuditActor); err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to clean up cancelled market %s: %v", market.ID, err))
+	}
+}
@@ -0,0 +1,225 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// openAPIVersion is the version of the bot's HTTP API reported in the OpenAPI document
+const openAPIVersion = "1.0.0"
+
+// pathParamPattern matches {name} path parameters
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// HandleOpenAPI handles GET /discord/openapi.json
+func (h *WebhookHandler) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	b, err := json.Marshal(h.OpenAPIDocument())
+	if err != nil {
+		writeServiceError(w, err, "Failed to build OpenAPI document")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// OpenAPIDocument builds an OpenAPI 3.0 document describing every route
+func (h *WebhookHandler) OpenAPIDocument() map[string]interface{} {
+	schemas := newSchemaRegistry()
+	paths := map[string]interface{}{}
+
+	for _, rt := range h.routes() {
+		operation := map[string]interface{}{
+			"summary":     rt.summary,
+			"operationId": operationID(rt),
+			"tags":        []string{rt.tag},
+		}
+
+		parameters := []interface{}{}
+		for _, match := range pathParamPattern.FindAllStringSubmatch(rt.path, -1) {
+			parameters = append(parameters, map[string]interface{}{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+		for _, param := range rt.query {
+			parameters = append(parameters, map[string]interface{}{
+				"name":        param.name,
+				"in":          "query",
+				"description": param.description,
+				"schema":      map[string]interface{}{"type": "string"},
+			})
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		if rt.request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schemas.schemaFor(reflect.TypeOf(rt.request))},
+				},
+			}
+		}
+
+		success := map[string]interface{}{"description": http.StatusText(rt.status)}
+		if rt.response != nil {
+			success["content"] = map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": schemas.schemaFor(reflect.TypeOf(rt.response))},
+			}
+		}
+		errorResponse := map[string]interface{}{
+			"description": "Error",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": schemas.schemaFor(reflect.TypeOf(ErrorResponse{}))},
+			},
+		}
+		operation["responses"] = map[string]interface{}{
+			strconv.Itoa(rt.status): success,
+			"default":               errorResponse,
+		}
+
+		if rt.public {
+			operation["security"] = []interface{}{}
+		} else {
+			operation["x-required-scope"] = rt.scope
+		}
+
+		item, ok := paths[rt.path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[rt.path] = item
+		}
+		item[strings.ToLower(rt.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Coral Markets Discord Bot API",
+			"version":     openAPIVersion,
+			"description": "Webhooks and admin endpoints of the Coral Markets Discord bot. API keys created at /discord/admin/api-keys only reach operations whose x-required-scope they grant; CORAL_API_KEY and CORAL_TOKEN grant every scope.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.schemas,
+			"securitySchemes": map[string]interface{}{
+				"ApiKeyAuth": map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"BearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		// Either credential is accepted; both are optional while CORAL_API_KEY and CORAL_TOKEN are unset and
+		// no API key has been created. Stored API keys must grant the operation's x-required-scope.
+		"security": []interface{}{
+			map[string]interface{}{"ApiKeyAuth": []string{}},
+			map[string]interface{}{"BearerAuth": []string{}},
+		},
+	}
+}
+
+// operationID derives a stable operation ID from a route's method and path
+func operationID(rt route) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(rt.method))
+	for _, part := range strings.FieldsFunc(pathParamPattern.ReplaceAllString(rt.path, "by_$1"), func(r rune) bool {
+		return r == '/' || r == '-' || r == '_' || r == '.'
+	}) {
+		id.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return id.String()
+}
+
+// schemaRegistry converts Go types to JSON schemas, collecting named structs as components
+type schemaRegistry struct {
+	schemas map[string]interface{}
+}
+
+// newSchemaRegistry creates an empty schema registry
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: map[string]interface{}{}}
+}
+
+// timeType is described as an RFC3339 string rather than a struct
+var timeType = reflect.TypeOf(time.Time{})
+
+// rawMessageType is described as any JSON value rather than an array of bytes
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// schemaFor returns the schema of a type, as a $ref for named structs
+func (registry *schemaRegistry) schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]interface{}{}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		name := t.Name()
+		if _, ok := registry.schemas[name]; !ok {
+			// Reserve the name first so recursive types terminate
+			registry.schemas[name] = map[string]interface{}{}
+			registry.schemas[name] = registry.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	case t.Kind() == reflect.Struct:
+		return registry.structSchema(t)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": registry.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": registry.schemaFor(t.Elem())}
+	default:
+		// interface{} and anything else accept any JSON value
+		return map[string]interface{}{}
+	}
+}
+
+// structSchema describes the JSON-visible fields of a struct
+func (registry *schemaRegistry) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		properties[name] = registry.schemaFor(field.Type)
+	}
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
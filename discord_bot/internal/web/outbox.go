@@ -0,0 +1,116 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+	"coral-bot/discord_bot/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// outboxRetention is how long delivered outbox items are kept before they are pruned
+const outboxRetention = 24 * time.Hour
+
+// deliverOutboxItem fans out a stored notification, marks it delivered and reports whether it was
+// delivered. Without a Discord session the item stays pending, to be picked up by the outbox worker;
+// an item attempted services.OutboxMaxAttempts times is marked failed and not sent again.
+func (h *WebhookHandler) deliverOutboxItem(ctx context.Context, item *models.OutboxItem) bool {
+	if h.discordSession == nil {
+		h.logger.Error("Discord session not set")
+		return false
+	}
+	if err := h.outbox.StartAttempt(ctx, item); errors.Is(err, services.ErrOutboxAttemptsExhausted) {
+		h.logger.Error(fmt.Sprintf("Giving up %s event %s from the outbox: %v", item.EventType, item.ID, err))
+		return false
+	} else if err != nil {
+		h.logger.Warning(fmt.Sprintf("Failed to record delivery attempt of outbox item %s: %v", item.ID, err))
+	}
+
+	notification := &eventNotification{
+		id:        item.ID,
+		eventType: item.EventType,
+		content:   item.Content,
+		chart:     item.Chart,
+		buyAmount: item.BuyAmount,
+	}
+	delivery := h.fanOut(ctx, notification, item.Market, item.Previous)
+
+	if err := h.outbox.Complete(ctx, item); err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to mark outbox item %s delivered: %v", item.ID, err))
+	}
+	h.publishProcessed(ctx, item.ID, notification, item.Market, delivery)
+	return true
+}
+
+// ResumeOutbox delivers the outbox items created before a point in time that were never marked
+// delivered, oldest first, and returns how many it delivered. Delivery is at least once: an event cut
+// short part way through its fan-out is sent again to every recipient.
+func (h *WebhookHandler) ResumeOutbox(ctx context.Context, before time.Time) int {
+	if h.outbox == nil || h.discordSession == nil {
+		return 0
+	}
+	items, err := h.outbox.Pending(ctx, before)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to get pending outbox items: %v", err))
+		return 0
+	}
+
+	delivered := 0
+	for _, item := range items {
+		if h.resumeOutboxItem(ctx, item) {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// resumeOutboxItem delivers a single pending outbox item within the usual dispatch timeout and reports
+// whether it was delivered
+func (h *WebhookHandler) resumeOutboxItem(ctx context.Context, item *models.OutboxItem) bool {
+	ctx, cancel := context.WithTimeout(ctx, dispatchTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "dispatch.resume."+item.EventType,
+		attribute.String("outbox.id", item.ID),
+		attribute.Int("outbox.attempts", item.Attempts),
+	)
+	defer span.End()
+
+	h.logger.Info(fmt.Sprintf("Resuming %s event %s from the outbox (attempt %d)", item.EventType, item.ID, item.Attempts+1))
+	return h.deliverOutboxItem(ctx, item)
+}
+
+// RunOutboxWorker delivers the events a previous run stored but did not deliver, then on every tick
+// delivers items whose delivery was cut short and prunes delivered ones, until ctx is cancelled.
+// Items younger than the dispatch timeout may still be in flight and are left alone.
+func (h *WebhookHandler) RunOutboxWorker(ctx context.Context, interval time.Duration) {
+	if h.outbox == nil {
+		return
+	}
+	if resumed := h.ResumeOutbox(ctx, time.Now()); resumed > 0 {
+		h.logger.Info(fmt.Sprintf("Resumed %d undelivered events from the outbox", resumed))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	h.logger.Info(fmt.Sprintf("Outbox worker started (interval %s)", interval))
+	for {
+		select {
+		case <-ctx.Done():
+			h.logger.Info("Outbox worker stopped")
+			return
+		case now := <-ticker.C:
+			if resumed := h.ResumeOutbox(ctx, now.Add(-dispatchTimeout)); resumed > 0 {
+				h.logger.Info(fmt.Sprintf("Redelivered %d events from the outbox", resumed))
+			}
+			if err := h.outbox.Prune(ctx, now.Add(-outboxRetention)); err != nil {
+				h.logger.Error(fmt.Sprintf("Failed to prune the outbox: %v", err))
+			}
+		}
+	}
+}
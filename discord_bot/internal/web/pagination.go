@@ -0,0 +1,65 @@
+package web
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// maxPageLimit is the most items a list endpoint returns in one page
+const maxPageLimit = 500
+
+// pageQueryParams document the pagination parameters of list endpoints
+var pageQueryParams = []queryParam{
+	{name: "limit", description: "Items per page, 1-500 (default every item)"},
+	{name: "cursor", description: "next_cursor of the previous page"},
+}
+
+// page selects a page of a list: the items from offset, up to limit. A zero limit selects every item.
+type page struct {
+	offset int
+	limit  int
+}
+
+// parsePage reads ?limit=<n> and ?cursor=<next_cursor of the previous page>
+func parsePage(r *http.Request) (page, error) {
+	query := r.URL.Query()
+	var p page
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxPageLimit {
+			return page{}, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		p.limit = limit
+	}
+	if raw := query.Get("cursor"); raw != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(raw)
+		offset, convErr := strconv.Atoi(string(decoded))
+		if err != nil || convErr != nil || offset < 0 {
+			return page{}, fmt.Errorf("invalid cursor")
+		}
+		p.offset = offset
+	}
+	return p, nil
+}
+
+// next returns the cursor of the page after this one in a list of total items, empty on the last page
+func (p page) next(total int) string {
+	if p.limit == 0 || p.offset+p.limit >= total {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(p.offset + p.limit)))
+}
+
+// paginate returns the items of a page of a list, which must be in a stable order
+func paginate[T any](items []T, p page) []T {
+	if p.offset >= len(items) {
+		return []T{}
+	}
+	items = items[p.offset:]
+	if p.limit > 0 && p.limit < len(items) {
+		items = items[:p.limit]
+	}
+	return items
+}
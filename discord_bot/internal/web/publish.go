@@ -0,0 +1,43 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// publishProcessed publishes the record of a delivered event when a publisher is set. Publishing
+// failures are logged, they never affect delivery.
+func (h *WebhookHandler) publishProcessed(ctx context.Context, id string, notification *eventNotification, market *models.Market, delivery models.DeliveryStats) {
+	h.publish(ctx, &models.ProcessedEvent{
+		ID:        id,
+		Type:      notification.eventType,
+		Market:    market,
+		Content:   notification.content,
+		BuyAmount: notification.buyAmount,
+		Delivery:  delivery,
+	})
+}
+
+// publishSuppressed publishes the record of an event that was seen but not delivered
+func (h *WebhookHandler) publishSuppressed(ctx context.Context, notification *eventNotification, market *models.Market) {
+	h.publish(ctx, &models.ProcessedEvent{
+		Type:       notification.eventType,
+		Market:     market,
+		BuyAmount:  notification.buyAmount,
+		Suppressed: true,
+	})
+}
+
+// publish stamps and publishes a processed event
+func (h *WebhookHandler) publish(ctx context.Context, event *models.ProcessedEvent) {
+	if h.publisher == nil {
+		return
+	}
+	event.ProcessedAt = time.Now()
+	if err := h.publisher.Publish(ctx, event); err != nil {
+		h.logger.Warning(fmt.Sprintf("Failed to publish processed %s event for market %s: %v", event.Type, event.Market.ID, err))
+	}
+}
@@ -0,0 +1,63 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// SetQuietHoursService sets the service holding channel posts during the channels' quiet hours
+func (h *WebhookHandler) SetQuietHoursService(quietHours services.QuietHoursService) {
+	h.quietHours = quietHours
+}
+
+// holdDuringQuietHours holds a market event for a channel in its quiet hours and returns the skip
+// reason when it did, or no reason when the event is to be posted now
+func (h *WebhookHandler) holdDuringQuietHours(ctx context.Context, channelConfig *models.ChannelConfig, notification *eventNotification, market *models.Market) string {
+	if h.quietHours == nil || !h.quietHours.Hold(ctx, channelConfig, notification.eventType, market, time.Now()) {
+		return ""
+	}
+	return skipQuietHours
+}
+
+// HandleChannelQuietHours handles POST /discord/channel/quiet_hours
+func (h *WebhookHandler) HandleChannelQuietHours(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload ChannelQuietHoursRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.ChannelID == "" {
+		writeJSONError(w, http.StatusBadRequest, "channel_id required")
+		return
+	}
+
+	start, end := "", ""
+	if hours := strings.TrimSpace(payload.Hours); hours != "" && !strings.EqualFold(hours, "off") {
+		if start, end, err = services.ParseQuietHours(hours); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "hours must be HH:MM-HH:MM with different times, or off")
+			return
+		}
+	}
+	err = h.subscriptionService.SetChannelQuietHours(r.Context(), payload.ChannelID, "", start, end, apiActor(r))
+	if errors.Is(err, services.ErrInvalidChannelSettings) {
+		writeJSONError(w, http.StatusBadRequest, "hours must be HH:MM-HH:MM with different times, or off")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err, "Failed to save config")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,213 @@
+package web
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// route describes an HTTP endpoint; the same table drives routing and the OpenAPI document
+type route struct {
+	method   string
+	path     string // ServeMux pattern; {name} segments are path parameters read with r.PathValue
+	summary  string
+	tag      string
+	query    []queryParam
+	request  interface{} // zero value of the JSON request body type, nil for none
+	response interface{} // zero value of the JSON response body type, nil for none
+	status   int
+	scope    string // API key scope required to call the route
+	public   bool   // no API key or bearer token required
+	handler  http.HandlerFunc
+}
+
+// queryParam documents a query string parameter of a route
+type queryParam struct {
+	name        string
+	description string
+}
+
+// routes returns every endpoint served by the web server
+func (h *WebhookHandler) routes() []route {
+	// Legacy backend webhooks, one per registered event type that has one
+	legacy, events := h.eventRoutes()
+	routes := append(legacy, []route{
+		// Webhook registrations
+		{method: http.MethodPost, path: "/discord/webhooks/register", scope: models.ScopeWebhooksWrite, tag: "webhooks", summary: "Register a Discord webhook for a channel", request: RegisterWebhookRequest{}, response: models.WebhookRegistration{}, status: http.StatusCreated, handler: h.HandleRegisterWebhook},
+		{method: http.MethodDelete, path: "/discord/webhooks/unregister", scope: models.ScopeWebhooksWrite, tag: "webhooks", summary: "Unregister a webhook", request: UnregisterWebhookRequest{}, response: OKResponse{}, status: http.StatusOK, handler: h.HandleUnregisterWebhook},
+		{method: http.MethodPost, path: "/discord/webhooks/unregister", scope: models.ScopeWebhooksWrite, tag: "webhooks", summary: "Unregister a webhook (for clients that cannot send DELETE)", request: UnregisterWebhookRequest{}, response: OKResponse{}, status: http.StatusOK, handler: h.HandleUnregisterWebhook},
+		{method: http.MethodGet, path: "/discord/webhooks", scope: models.ScopeWebhooksRead, tag: "webhooks", summary: "List webhook registrations, oldest first, with the total in X-Total-Count and the next page's cursor in X-Next-Cursor", query: append([]queryParam{
+			{name: "channel_id", description: "Only registrations for this channel"},
+			{name: "event", description: "Only registrations receiving this event type"},
+		}, pageQueryParams...), response: []models.WebhookRegistration{}, status: http.StatusOK, handler: h.HandleListWebhooks},
+		{method: http.MethodDelete, path: "/discord/webhooks/{id}", scope: models.ScopeWebhooksWrite, tag: "webhooks", summary: "Unregister a webhook by ID", status: http.StatusNoContent, handler: h.HandleUnregisterWebhookByPath},
+	}...)
+
+	// Market events, one per registered event type
+	routes = append(routes, events...)
+	return append(routes, []route{
+		{method: http.MethodPost, path: "/discord/events/batch", scope: models.ScopeEventsWrite, tag: "events", summary: "Post several market events in one request", request: BatchEventRequest{}, response: BatchEventResponse{}, status: http.StatusOK, handler: h.HandleEventBatch},
+
+		{method: http.MethodPost, path: "/discord/notifications/dm", scope: models.ScopeEventsWrite, tag: "notifications", summary: "Send a market event to a single user by DM", request: DirectNotificationRequest{}, response: AcceptedResponse{}, status: http.StatusAccepted, handler: h.HandleNotificationsDM},
+
+		// User subscriptions
+		{method: http.MethodPost, path: "/discord/subscribe/market", scope: models.ScopeSubscriptionsWrite, tag: "subscriptions", summary: "Subscribe a user to a market", request: MarketSubscriptionRequest{}, response: SubscriptionStatusResponse{}, status: http.StatusOK, handler: h.HandleSubscribeMarket},
+		{method: http.MethodPost, path: "/discord/unsubscribe/market", scope: models.ScopeSubscriptionsWrite, tag: "subscriptions", summary: "Unsubscribe a user from a market", request: MarketSubscriptionRequest{}, response: SubscriptionStatusResponse{}, status: http.StatusOK, handler: h.HandleUnsubscribeMarket},
+		{method: http.MethodPost, path: "/discord/subscribe/creator", scope: models.ScopeSubscriptionsWrite, tag: "subscriptions", summary: "Subscribe a user to a creator", request: CreatorSubscriptionRequest{}, status: http.StatusOK, handler: h.HandleSubscribeCreator},
+		{method: http.MethodPost, path: "/discord/unsubscribe/creator", scope: models.ScopeSubscriptionsWrite, tag: "subscriptions", summary: "Unsubscribe a user from a creator", request: CreatorSubscriptionRequest{}, status: http.StatusOK, handler: h.HandleUnsubscribeCreator},
+		{method: http.MethodPost, path: "/discord/subscribe/outcome", scope: models.ScopeSubscriptionsWrite, tag: "subscriptions", summary: "Follow a single market outcome", request: OutcomeSubscriptionRequest{}, response: SubscriptionStatusResponse{}, status: http.StatusOK, handler: h.HandleSubscribeOutcome},
+		{method: http.MethodPost, path: "/discord/unsubscribe/outcome", scope: models.ScopeSubscriptionsWrite, tag: "subscriptions", summary: "Stop following a market outcome", request: OutcomeSubscriptionRequest{}, response: SubscriptionStatusResponse{}, status: http.StatusOK, handler: h.HandleUnsubscribeOutcome},
+		{method: http.MethodPost, path: "/discord/timezone", scope: models.ScopeSubscriptionsWrite, tag: "subscriptions", summary: "Set the timezone a user's messages show times in", request: UserTimezoneRequest{}, status: http.StatusOK, handler: h.HandleUserTimezone},
+		{method: http.MethodPost, path: "/discord/link/confirm", scope: models.ScopeSubscriptionsWrite, tag: "subscriptions", summary: "Link a Coral account to the user a /link_account code was issued to", request: AccountLinkRequest{}, response: AccountLinkResponse{}, status: http.StatusOK, handler: h.HandleLinkConfirm},
+		{method: http.MethodPost, path: "/discord/link/revoke", scope: models.ScopeSubscriptionsWrite, tag: "subscriptions", summary: "Unlink a Coral account from its Discord user", request: AccountUnlinkRequest{}, response: AccountLinkResponse{}, status: http.StatusOK, handler: h.HandleLinkRevoke},
+		{method: http.MethodGet, path: "/discord/subscriptions/{discord_user_id}", scope: models.ScopeSubscriptionsRead, tag: "subscriptions", summary: "List a user's subscriptions", query: append([]queryParam{
+			{name: "market_id", description: "Only subscriptions to this market and its outcomes"},
+			{name: "creator", description: "Only subscriptions to this creator"},
+		}, pageQueryParams...), response: UserSubscriptionsResponse{}, status: http.StatusOK, handler: h.HandleGetUserSubscriptions},
+
+		// Channel settings
+		{method: http.MethodPost, path: "/discord/channel/feed/new_markets", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Enable or disable a channel's feed", request: ChannelFeedNewMarketsRequest{}, status: http.StatusOK, handler: h.HandleChannelFeedNewMarkets},
+		{method: http.MethodPost, path: "/discord/channel/feed/categories", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Set a channel's allowed categories", request: ChannelFeedCategoriesRequest{}, status: http.StatusOK, handler: h.HandleChannelFeedCategories},
+		{method: http.MethodPost, path: "/discord/channel/feed/frequency", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Set a channel's update frequency", request: ChannelFeedFrequencyRequest{}, status: http.StatusOK, handler: h.HandleChannelFeedFrequency},
+		{method: http.MethodGet, path: "/discord/guild/update_intervals/{guild_id}", scope: models.ScopeChannelsRead, tag: "channels", summary: "Get the update intervals of a guild's channels", response: UpdateIntervalsResponse{}, status: http.StatusOK, handler: h.HandleGetUpdateIntervals},
+		{method: http.MethodPut, path: "/discord/guild/update_intervals/{guild_id}", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Set update intervals for a guild's channels", request: UpdateIntervalsRequest{}, response: UpdateIntervalsResponse{}, status: http.StatusOK, handler: h.HandleSetUpdateIntervals},
+		{method: http.MethodDelete, path: "/discord/guild/update_intervals/{guild_id}", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Return a guild's channels to the bot's update intervals", response: UpdateIntervalsResponse{}, status: http.StatusOK, handler: h.HandleResetUpdateIntervals},
+		{method: http.MethodGet, path: "/discord/guild/branding/{guild_id}", scope: models.ScopeChannelsRead, tag: "channels", summary: "Get the branding of a guild's alerts", response: GuildBrandingResponse{}, status: http.StatusOK, handler: h.HandleGetGuildBranding},
+		{method: http.MethodPut, path: "/discord/guild/branding/{guild_id}", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Set the emojis, accent color and footer of a guild's alerts", request: GuildBrandingRequest{}, response: GuildBrandingResponse{}, status: http.StatusOK, handler: h.HandleSetGuildBranding},
+		{method: http.MethodDelete, path: "/discord/guild/branding/{guild_id}", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Return a guild's alerts to the bot's style", response: GuildBrandingResponse{}, status: http.StatusOK, handler: h.HandleResetGuildBranding},
+		{method: http.MethodGet, path: "/discord/guild/routing_rules/{guild_id}", scope: models.ScopeChannelsRead, tag: "channels", summary: "List a guild's routing rules in the order they are evaluated", response: RoutingRulesResponse{}, status: http.StatusOK, handler: h.HandleGetRoutingRules},
+		{method: http.MethodPost, path: "/discord/guild/routing_rules/{guild_id}", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Add a rule sending, suppressing, pinging a role for or routing the events it matches", request: RoutingRuleRequest{}, response: models.RoutingRule{}, status: http.StatusCreated, handler: h.HandleAddRoutingRule},
+		{method: http.MethodPost, path: "/discord/guild/routing_rules/{guild_id}/{id}/move", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Move a routing rule to another position", request: RoutingRuleMoveRequest{}, response: models.RoutingRule{}, status: http.StatusOK, handler: h.HandleMoveRoutingRule},
+		{method: http.MethodDelete, path: "/discord/guild/routing_rules/{guild_id}/{id}", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Remove a routing rule", status: http.StatusNoContent, handler: h.HandleRemoveRoutingRule},
+		{method: http.MethodPost, path: "/discord/channel/feed/closing_soon", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Set how long before a market closes it is announced in a channel", request: ChannelClosingSoonRequest{}, status: http.StatusOK, handler: h.HandleChannelClosingSoon},
+		{method: http.MethodPost, path: "/discord/channel/feed/ping", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Mention a role in a channel's alerts of some events", request: ChannelPingRequest{}, status: http.StatusOK, handler: h.HandleChannelPing},
+		{method: http.MethodPost, path: "/discord/channel/subscribe/market", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Follow a market in a channel", request: ChannelMarketSubscriptionRequest{}, response: SubscriptionStatusResponse{}, status: http.StatusOK, handler: h.HandleChannelSubscribeMarket},
+		{method: http.MethodPost, path: "/discord/channel/unsubscribe/market", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Stop following a market in a channel", request: ChannelMarketSubscriptionRequest{}, response: SubscriptionStatusResponse{}, status: http.StatusOK, handler: h.HandleChannelUnsubscribeMarket},
+		{method: http.MethodPost, path: "/discord/channel/timezone", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Set the timezone a channel's messages show times in", request: ChannelTimezoneRequest{}, status: http.StatusOK, handler: h.HandleChannelTimezone},
+		{method: http.MethodPost, path: "/discord/channel/digest", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Opt a channel in to daily or weekly market digests", request: ChannelDigestRequest{}, status: http.StatusOK, handler: h.HandleChannelDigest},
+		{method: http.MethodPost, path: "/discord/channel/crosspost", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Publish an announcement channel's alerts to the servers following it", request: ChannelCrosspostRequest{}, status: http.StatusOK, handler: h.HandleChannelCrosspost},
+		{method: http.MethodPost, path: "/discord/channel/liquidity", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Post market liquidity changes in a channel", request: ChannelLiquidityRequest{}, status: http.StatusOK, handler: h.HandleChannelLiquidity},
+		{method: http.MethodPost, path: "/discord/channel/shadow_mode", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Log a channel's messages, and what they would have been, instead of sending them", request: ChannelShadowModeRequest{}, status: http.StatusOK, handler: h.HandleChannelShadowMode},
+		{method: http.MethodPost, path: "/discord/channel/calendar", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Post a digest of the markets about each upcoming calendar event in a channel", request: ChannelCalendarRequest{}, status: http.StatusOK, handler: h.HandleChannelCalendar},
+		{method: http.MethodPost, path: "/discord/channel/board", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Keep a pinned board of the top active markets in a channel", request: ChannelBoardRequest{}, status: http.StatusOK, handler: h.HandleChannelBoard},
+		{method: http.MethodPost, path: "/discord/channel/quiet_hours", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Hold a channel's market events during quiet hours and post them as a summary afterwards", request: ChannelQuietHoursRequest{}, status: http.StatusOK, handler: h.HandleChannelQuietHours},
+		{method: http.MethodGet, path: "/discord/channel/settings/{channel_id}", scope: models.ScopeChannelsRead, tag: "channels", summary: "Get a channel's settings", response: models.ChannelConfig{}, status: http.StatusOK, handler: h.HandleGetChannelSettings},
+		{method: http.MethodPut, path: "/discord/channel/settings/{channel_id}", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Apply exported settings to a channel", request: models.ChannelSettings{}, response: models.ChannelConfig{}, status: http.StatusOK, handler: h.HandleImportChannelSettings},
+		{method: http.MethodGet, path: "/discord/channel/settings/{channel_id}/export", scope: models.ScopeChannelsRead, tag: "channels", summary: "Export a channel's settings as portable JSON", response: models.ChannelSettings{}, status: http.StatusOK, handler: h.HandleExportChannelSettings},
+		{method: http.MethodPost, path: "/discord/channel/settings/copy", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Copy one channel's settings to another", request: ChannelSettingsCopyRequest{}, response: models.ChannelConfig{}, status: http.StatusOK, handler: h.HandleCopyChannelSettings},
+		{method: http.MethodPost, path: "/discord/channel/settings/bulk", scope: models.ScopeChannelsWrite, tag: "channels", summary: "Apply settings to up to 100 channels at once, all or none", request: []BulkChannelSettings{}, response: BulkChannelSettingsResponse{}, status: http.StatusOK, handler: h.HandleBulkChannelSettings},
+
+		// Operations
+		{method: http.MethodGet, path: "/discord/health", tag: "operations", summary: "Health check", response: HealthResponse{}, status: http.StatusOK, public: true, handler: h.HandleHealth},
+		{method: http.MethodPost, path: "/discord/interactions", tag: "operations", summary: "Discord interactions endpoint, verified by Ed25519 signature", status: http.StatusOK, public: true, handler: h.HandleInteraction},
+		{method: http.MethodGet, path: "/discord/openapi.json", tag: "operations", summary: "This OpenAPI document", status: http.StatusOK, public: true, handler: h.HandleOpenAPI},
+
+		// Admin
+		{method: http.MethodGet, path: "/discord/admin/analytics", scope: models.ScopeAdminRead, tag: "admin", summary: "Aggregated usage and delivery analytics", query: []queryParam{
+			{name: "window", description: "Duration ending now, e.g. 1h, 24h or 7d (default 24h)"},
+			{name: "from", description: "RFC3339 start of the range, used with to"},
+			{name: "to", description: "RFC3339 end of the range (default now)"},
+		}, response: models.AnalyticsSummary{}, status: http.StatusOK, handler: h.HandleAdminAnalytics},
+		{method: http.MethodGet, path: "/discord/admin/leaderboard", scope: models.ScopeAdminRead, tag: "admin", summary: "Most-followed markets and creators", query: []queryParam{
+			{name: "limit", description: "Entries per list, 1-100 (default 10)"},
+		}, response: models.Leaderboard{}, status: http.StatusOK, handler: h.HandleAdminLeaderboard},
+		{method: http.MethodGet, path: "/discord/admin/audit", scope: models.ScopeAdminRead, tag: "admin", summary: "Channel config and webhook audit log", query: []queryParam{
+			{name: "channel_id", description: "Only entries for this channel"},
+			{name: "resource_type", description: "channel_config, webhook_registration, category_route, routing_rule or limit_override"},
+			{name: "limit", description: "Most recent entries to return, 1-500 (default 50)"},
+		}, response: AuditLogResponse{}, status: http.StatusOK, handler: h.HandleAdminAudit},
+		{method: http.MethodGet, path: "/discord/admin/deliveries/{event_id}", scope: models.ScopeAdminRead, tag: "admin", summary: "Who an event was delivered to, failed for or skipped, and why", response: models.DeliveryReport{}, status: http.StatusOK, handler: h.HandleAdminDelivery},
+		{method: http.MethodGet, path: "/discord/admin/dead-letters", scope: models.ScopeAdminRead, tag: "admin", summary: "Messages that failed to send, retried with backoff", query: []queryParam{
+			{name: "status", description: "retrying or exhausted (default both)"},
+		}, response: DeadLettersResponse{}, status: http.StatusOK, handler: h.HandleAdminDeadLetters},
+		{method: http.MethodPost, path: "/discord/admin/dead-letters/{id}/retry", scope: models.ScopeAdminWrite, tag: "admin", summary: "Re-drive a dead letter on the next worker run", response: models.DeadLetter{}, status: http.StatusAccepted, handler: h.HandleRetryDeadLetter},
+		{method: http.MethodDelete, path: "/discord/admin/dead-letters/{id}", scope: models.ScopeAdminWrite, tag: "admin", summary: "Discard a dead letter", status: http.StatusNoContent, handler: h.HandleDiscardDeadLetter},
+		{method: http.MethodGet, path: "/discord/admin/limits", scope: models.ScopeAdminRead, tag: "admin", summary: "Default subscription limits and per-user and per-guild overrides", response: LimitsResponse{}, status: http.StatusOK, handler: h.HandleAdminLimits},
+		{method: http.MethodPut, path: "/discord/admin/limits/{subject}/{subject_id}", scope: models.ScopeAdminWrite, tag: "admin", summary: "Override the subscription limits of a user or guild", request: LimitOverrideRequest{}, response: models.LimitOverride{}, status: http.StatusOK, handler: h.HandleSetLimitOverride},
+		{method: http.MethodDelete, path: "/discord/admin/limits/{subject}/{subject_id}", scope: models.ScopeAdminWrite, tag: "admin", summary: "Return a user or guild to the default subscription limits", status: http.StatusNoContent, handler: h.HandleRemoveLimitOverride},
+		{method: http.MethodGet, path: "/discord/admin/subscriptions", scope: models.ScopeAdminRead, tag: "admin", summary: "Every user's subscriptions, by user ID", query: append([]queryParam{
+			{name: "market_id", description: "Only users subscribed to this market or one of its outcomes"},
+			{name: "creator", description: "Only users subscribed to this creator"},
+		}, pageQueryParams...), response: SubscriptionsResponse{}, status: http.StatusOK, handler: h.HandleAdminSubscriptions},
+		{method: http.MethodGet, path: "/discord/admin/channels", scope: models.ScopeAdminRead, tag: "admin", summary: "Every configured channel's settings, by channel ID", query: append([]queryParam{
+			{name: "guild_id", description: "Only channels in this guild"},
+			{name: "market_id", description: "Only channels following this market"},
+		}, pageQueryParams...), response: ChannelsResponse{}, status: http.StatusOK, handler: h.HandleAdminChannels},
+		{method: http.MethodPost, path: "/discord/admin/broadcast", scope: models.ScopeAdminWrite, tag: "admin", summary: "Post an announcement to every feed channel", request: BroadcastRequest{}, response: BroadcastResponse{}, status: http.StatusAccepted, handler: h.HandleAdminBroadcast},
+		{method: http.MethodPost, path: "/discord/admin/test-event", scope: models.ScopeAdminWrite, tag: "admin", summary: "Send a synthetic market event to one channel", request: TestEventRequest{}, response: TestEventResponse{}, status: http.StatusAccepted, handler: h.HandleAdminTestEvent},
+		{method: http.MethodPost, path: "/discord/admin/loadtest", scope: models.ScopeAdminWrite, tag: "admin", summary: "Deliver synthetic events to simulated channels and users and report throughput and latency", request: LoadTestRequest{}, response: models.LoadTestReport{}, status: http.StatusOK, handler: h.HandleAdminLoadTest},
+		{method: http.MethodGet, path: "/discord/admin/maintenance", scope: models.ScopeAdminRead, tag: "admin", summary: "Whether the bot is in maintenance and how many messages wait for it to end", response: services.MaintenanceStatus{}, status: http.StatusOK, handler: h.HandleMaintenanceStatus},
+		{method: http.MethodPut, path: "/discord/admin/maintenance", scope: models.ScopeAdminWrite, tag: "admin", summary: "Start or end maintenance, pausing outbound notifications until it ends", request: MaintenanceRequest{}, response: services.MaintenanceStatus{}, status: http.StatusOK, handler: h.HandleSetMaintenance},
+		{method: http.MethodGet, path: "/discord/admin/http-log", scope: models.ScopeAdminRead, tag: "admin", summary: "Logged requests and responses, redacted, most recent first", query: []queryParam{
+			{name: "route", description: "Only requests to this endpoint pattern, e.g. /discord/events/new-market"},
+			{name: "limit", description: "Most recent entries to return, 1-5000 (default 50)"},
+		}, response: HTTPLogResponse{}, status: http.StatusOK, handler: h.HandleHTTPLog},
+		{method: http.MethodDelete, path: "/discord/admin/http-log", scope: models.ScopeAdminWrite, tag: "admin", summary: "Delete every logged request", status: http.StatusNoContent, handler: h.HandleClearHTTPLog},
+		{method: http.MethodGet, path: "/discord/admin/http-log/config", scope: models.ScopeAdminRead, tag: "admin", summary: "Which requests are logged", response: services.HTTPLogConfig{}, status: http.StatusOK, handler: h.HandleHTTPLogConfig},
+		{method: http.MethodPut, path: "/discord/admin/http-log/config", scope: models.ScopeAdminWrite, tag: "admin", summary: "Change the sample rate, always-logged endpoints and size of the request log until restart", request: services.HTTPLogConfig{}, response: services.HTTPLogConfig{}, status: http.StatusOK, handler: h.HandleSetHTTPLogConfig},
+		{method: http.MethodPost, path: "/discord/admin/commands/resync", scope: models.ScopeAdminWrite, tag: "admin", summary: "Sync the bot's slash commands with Discord without restarting it", response: models.CommandSyncResult{}, status: http.StatusOK, handler: h.HandleResyncCommands},
+		{method: http.MethodPost, path: "/discord/admin/storage/migrate", scope: models.ScopeAdminWrite, tag: "admin", summary: "Copy subscriptions, channel configs and webhook registrations to another storage backend", request: StorageMigrationRequest{}, response: models.StorageMigration{}, status: http.StatusOK, handler: h.HandleStorageMigration},
+		{method: http.MethodPost, path: "/discord/admin/api-keys", scope: models.ScopeKeysManage, tag: "admin", summary: "Create a scoped API key", request: CreateAPIKeyRequest{}, response: CreateAPIKeyResponse{}, status: http.StatusCreated, handler: h.HandleCreateAPIKey},
+		{method: http.MethodGet, path: "/discord/admin/api-keys", scope: models.ScopeKeysManage, tag: "admin", summary: "List API keys, including revoked keys", response: APIKeysResponse{}, status: http.StatusOK, handler: h.HandleListAPIKeys},
+		{method: http.MethodDelete, path: "/discord/admin/api-keys/{id}", scope: models.ScopeKeysManage, tag: "admin", summary: "Revoke an API key, now or after a grace period", query: []queryParam{
+			{name: "grace", description: "How long the key keeps working, e.g. 1h, 24h or 7d (default: revoked now)"},
+		}, status: http.StatusNoContent, handler: h.HandleRevokeAPIKey},
+		{method: http.MethodPost, path: "/discord/admin/api-keys/{id}/rotate", scope: models.ScopeKeysManage, tag: "admin", summary: "Issue the next key with the same scopes, both working until the old one is retired", request: RotateAPIKeyRequest{}, response: CreateAPIKeyResponse{}, status: http.StatusCreated, handler: h.HandleRotateAPIKey},
+		{method: http.MethodGet, path: "/discord/users/{discord_user_id}/data", scope: models.ScopeAdminRead, tag: "admin", summary: "Export everything stored about a user", response: models.UserData{}, status: http.StatusOK, handler: h.HandleExportUserData},
+		{method: http.MethodDelete, path: "/discord/users/{discord_user_id}/data", scope: models.ScopeAdminWrite, tag: "admin", summary: "Delete everything stored about a user and return it", response: models.UserData{}, status: http.StatusOK, handler: h.HandleDeleteUserData},
+	}...)
+}
+
+// Handler returns the HTTP handler serving every route. Unknown paths get a JSON 404 and known
+// paths called with another method get a JSON 405 with an Allow header.
+func (h *WebhookHandler) Handler() http.Handler {
+	byPath := make(map[string]map[string]http.HandlerFunc)
+	var paths []string
+	for _, rt := range h.routes() {
+		if byPath[rt.path] == nil {
+			byPath[rt.path] = make(map[string]http.HandlerFunc)
+			paths = append(paths, rt.path)
+		}
+		handler := rt.handler
+		if !rt.public {
+			handler = h.requireScope(rt.scope, handler)
+		}
+		if isIngestRoute(rt.path) {
+			handler = h.requireIngestIP(handler)
+		}
+		byPath[rt.path][rt.method] = h.withHTTPLog(rt.path, handler)
+	}
+
+	mux := http.NewServeMux()
+	for _, path := range paths {
+		mux.Handle(path, traceRoute(path, methodHandler(byPath[path])))
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONError(w, http.StatusNotFound, "Not found")
+	})
+	return h.withClientHandling(mux)
+}
+
+// methodHandler dispatches a request to the handler registered for its method
+func methodHandler(handlers map[string]http.HandlerFunc) http.Handler {
+	allowed := make([]string, 0, len(handlers))
+	for method := range handlers {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+	allow := strings.Join(allowed, ", ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := handlers[r.Method]
+		if !ok {
+			w.Header().Set("Allow", allow)
+			writeAPIError(w, apiError{status: http.StatusMethodNotAllowed, code: ErrorCodeMethodNotAllowed, message: "Method not allowed",
+				details: map[string]interface{}{"allowed": allowed}})
+			return
+		}
+		handler(w, r)
+	})
+}
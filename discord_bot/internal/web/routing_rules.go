@@ -0,0 +1,121 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// HandleGetRoutingRules handles GET /discord/guild/routing_rules/{guild_id}
+func (h *WebhookHandler) HandleGetRoutingRules(w http.ResponseWriter, r *http.Request) {
+	guildID := r.PathValue("guild_id")
+	rules, err := h.subscriptionService.GetRoutingRules(r.Context(), guildID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to load routing rules")
+		return
+	}
+	b, _ := json.Marshal(RoutingRulesResponse{GuildID: guildID, Rules: rules})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// HandleAddRoutingRule handles POST /discord/guild/routing_rules/{guild_id}
+func (h *WebhookHandler) HandleAddRoutingRule(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload RoutingRuleRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	guildID := r.PathValue("guild_id")
+	rule, err := h.subscriptionService.AddRoutingRule(r.Context(), &models.RoutingRule{
+		GuildID:         guildID,
+		ChannelID:       payload.ChannelID,
+		Position:        payload.Position,
+		EventTypes:      payload.EventTypes,
+		Categories:      payload.Categories,
+		Creators:        payload.Creators,
+		Keywords:        payload.Keywords,
+		MinVolume:       payload.MinVolume,
+		MaxVolume:       payload.MaxVolume,
+		Action:          payload.Action,
+		RoleID:          payload.RoleID,
+		TargetChannelID: payload.TargetChannelID,
+	}, apiActor(r))
+	if errors.Is(err, services.ErrInvalidRoutingRule) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to add routing rule to guild %s: %v", guildID, err))
+		writeServiceError(w, err, "Failed to add routing rule")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("Routing rule %s added to guild %s by %s", rule.ID, guildID, apiActor(r)))
+	b, _ := json.Marshal(rule)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(b)
+}
+
+// HandleMoveRoutingRule handles POST /discord/guild/routing_rules/{guild_id}/{id}/move
+func (h *WebhookHandler) HandleMoveRoutingRule(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload RoutingRuleMoveRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	guildID, id := r.PathValue("guild_id"), r.PathValue("id")
+	rule, err := h.subscriptionService.MoveRoutingRule(r.Context(), guildID, id, payload.Position, apiActor(r))
+	switch {
+	case errors.Is(err, services.ErrInvalidRoutingRule):
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrRoutingRuleNotFound):
+		writeJSONError(w, http.StatusNotFound, "No routing rule with this ID in the guild")
+		return
+	case err != nil:
+		h.logger.Error(fmt.Sprintf("Failed to move routing rule %s of guild %s: %v", id, guildID, err))
+		writeServiceError(w, err, "Failed to move routing rule")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("Routing rule %s of guild %s moved to %d by %s", id, guildID, rule.Position, apiActor(r)))
+	b, _ := json.Marshal(rule)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// HandleRemoveRoutingRule handles DELETE /discord/guild/routing_rules/{guild_id}/{id}
+func (h *WebhookHandler) HandleRemoveRoutingRule(w http.ResponseWriter, r *http.Request) {
+	guildID, id := r.PathValue("guild_id"), r.PathValue("id")
+	removed, err := h.subscriptionService.RemoveRoutingRule(r.Context(), guildID, id, apiActor(r))
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to remove routing rule %s of guild %s: %v", id, guildID, err))
+		writeServiceError(w, err, "Failed to remove routing rule")
+		return
+	}
+	if !removed {
+		writeJSONError(w, http.StatusNotFound, "No routing rule with this ID in the guild")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("Routing rule %s of guild %s removed by %s", id, guildID, apiActor(r)))
+	w.WriteHeader(http.StatusNoContent)
+}
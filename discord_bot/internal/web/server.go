@@ -0,0 +1,233 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// readHeaderTimeout bounds how long a client may take to send request headers
+const readHeaderTimeout = 10 * time.Second
+
+// requestTimeout bounds the service calls made while handling a request
+const requestTimeout = 30 * time.Second
+
+// SetTLS configures the certificate and key used to serve HTTPS; both empty serves plain HTTP
+func (h *WebhookHandler) SetTLS(certFile, keyFile string) {
+	h.tlsCertFile = certFile
+	h.tlsKeyFile = keyFile
+}
+
+// SetTrustedProxies sets the proxies, as IPs or CIDR ranges, whose X-Forwarded-For headers are trusted
+func (h *WebhookHandler) SetTrustedProxies(proxies []string) error {
+	networks, err := parseNetworks(proxies)
+	if err != nil {
+		return fmt.Errorf("invalid trusted proxy %w", err)
+	}
+	h.trustedProxies = networks
+	return nil
+}
+
+// SetIngestAllowlist limits the event ingest endpoints, /discord/events/* and /webhooks/*, to clients
+// whose IP is in one of the given IPs or CIDR ranges; an empty list accepts events from any client
+func (h *WebhookHandler) SetIngestAllowlist(allowed []string) error {
+	networks, err := parseNetworks(allowed)
+	if err != nil {
+		return fmt.Errorf("invalid ingest allowlist entry %w", err)
+	}
+	h.ingestAllowlist = networks
+	return nil
+}
+
+// parseNetworks parses IPs and CIDR ranges, treating a bare IP as a range of one address
+func parseNetworks(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", entry, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// SetRateLimit limits each client IP to a number of requests per minute; 0 disables the limit
+func (h *WebhookHandler) SetRateLimit(requestsPerMinute int) {
+	if requestsPerMinute <= 0 {
+		h.rateLimiter = nil
+		return
+	}
+	h.rateLimiter = newRateLimiter(requestsPerMinute, time.Minute)
+}
+
+// StartWebServer starts the webhook server
+func (h *WebhookHandler) StartWebServer(port string) {
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           h.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	var err error
+	switch {
+	case h.tlsCertFile != "" && h.tlsKeyFile != "":
+		h.logger.Info(fmt.Sprintf("Starting webhook server with TLS on port %s", port))
+		err = server.ListenAndServeTLS(h.tlsCertFile, h.tlsKeyFile)
+	case h.tlsCertFile != "" || h.tlsKeyFile != "":
+		err = fmt.Errorf("both TLS_CERT_FILE and TLS_KEY_FILE must be set to enable TLS")
+	default:
+		h.logger.Info(fmt.Sprintf("Starting webhook server on port %s", port))
+		err = server.ListenAndServe()
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to start webhook server: %v", err))
+	}
+}
+
+// clientIP returns the address of the client that made a request. X-Forwarded-For is only
+// honored when the connection comes from a trusted proxy, and is read right to left so a
+// client cannot spoof its address by sending the header itself.
+func (h *WebhookHandler) clientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if !h.isTrustedProxy(remote) {
+		return remote
+	}
+
+	hops := []string{}
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		if !h.isTrustedProxy(hop) {
+			return hop
+		}
+		remote = hop
+	}
+	return remote
+}
+
+// isTrustedProxy reports whether an IP belongs to a trusted proxy
+func (h *WebhookHandler) isTrustedProxy(address string) bool {
+	return inNetworks(address, h.trustedProxies)
+}
+
+// inNetworks reports whether an IP belongs to one of the networks
+func inNetworks(address string, networks []*net.IPNet) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIngestRoute reports whether a route receives events from the backend, and so is subject to the
+// ingest allowlist
+func isIngestRoute(route string) bool {
+	return strings.HasPrefix(route, "/discord/events/") || strings.HasPrefix(route, "/webhooks/")
+}
+
+// requireIngestIP rejects requests from clients outside the ingest allowlist, when one is set. The
+// client IP is taken behind trusted proxies like for rate limiting, so the allowlist holds the
+// backend's egress IPs rather than the proxy's.
+func (h *WebhookHandler) requireIngestIP(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(h.ingestAllowlist) > 0 {
+			if client := h.clientIP(r); !inNetworks(client, h.ingestAllowlist) {
+				h.logger.Warning(fmt.Sprintf("Rejected %s %s from %s, which is not in the ingest allowlist", r.Method, r.URL.Path, client))
+				writeJSONError(w, http.StatusForbidden, "Client IP not allowed to post events")
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status before writing it
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+// withClientHandling rate limits requests per client IP, applies the request timeout and logs each request with its client IP
+func (h *WebhookHandler) withClientHandling(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		client := h.clientIP(r)
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		id := requestID(r)
+		recorder.Header().Set(requestIDHeader, id)
+
+		if h.rateLimiter != nil && !h.rateLimiter.allow(client, start) {
+			recorder.Header().Set("Retry-After", "60")
+			writeAPIError(recorder, apiError{status: http.StatusTooManyRequests, code: ErrorCodeRateLimited, message: "Rate limit exceeded",
+				details: map[string]interface{}{"retry_after_seconds": 60}})
+		} else {
+			ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+			defer cancel()
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+		}
+
+		h.logger.Info(fmt.Sprintf("%s %s %d %s client=%s request_id=%s", r.Method, r.URL.Path, recorder.status, time.Since(start).Round(time.Millisecond), client, id))
+	})
+}
+
+// rateLimiter counts requests per client in fixed windows
+type rateLimiter struct {
+	limit       int
+	window      time.Duration
+	windowStart time.Time
+	counts      map[string]int
+	mutex       sync.Mutex
+}
+
+// newRateLimiter creates a rate limiter allowing limit requests per client per window
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, counts: make(map[string]int)}
+}
+
+// allow records a request from a client and reports whether it is within the limit
+func (limiter *rateLimiter) allow(client string, now time.Time) bool {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	if now.Sub(limiter.windowStart) >= limiter.window {
+		limiter.windowStart = now
+		limiter.counts = make(map[string]int)
+	}
+	limiter.counts[client]++
+	return limiter.counts[client] <= limiter.limit
+}
@@ -0,0 +1,64 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"coral-bot/discord_bot/internal/services"
+)
+
+// SetStorageMigrationService sets the service copying the bot's data to another storage backend
+func (h *WebhookHandler) SetStorageMigrationService(migrations services.StorageMigrationService) {
+	h.migrations = migrations
+}
+
+// HandleStorageMigration handles POST /discord/admin/storage/migrate
+//
+// The running repository is copied to the target and read back; the bot keeps using the running
+// repository until it is restarted with the target configured.
+func (h *WebhookHandler) HandleStorageMigration(w http.ResponseWriter, r *http.Request) {
+	if h.migrations == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Storage migration not enabled")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload StorageMigrationRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.Driver != services.StorageDriverFile {
+		writeJSONError(w, http.StatusBadRequest, "driver must be file")
+		return
+	}
+
+	migration, err := h.migrations.MigrateToFile(r.Context(), payload.Path)
+	switch {
+	case errors.Is(err, services.ErrInvalidStorageMigration):
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrMigrationTargetExists):
+		writeJSONError(w, http.StatusConflict, err.Error())
+		return
+	case errors.Is(err, services.ErrMigrationMismatch):
+		h.logger.Error(fmt.Sprintf("Storage migration to %s failed verification: %v", payload.Path, err))
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	case err != nil:
+		h.logger.Error(fmt.Sprintf("Failed to migrate storage to %s: %v", payload.Path, err))
+		writeServiceError(w, err, "Failed to migrate storage")
+		return
+	}
+
+	b, _ := json.Marshal(migration)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
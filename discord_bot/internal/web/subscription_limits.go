@@ -0,0 +1,85 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// HandleAdminLimits handles GET /discord/admin/limits
+func (h *WebhookHandler) HandleAdminLimits(w http.ResponseWriter, r *http.Request) {
+	overrides, err := h.subscriptionService.ListLimitOverrides(r.Context())
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to list limit overrides: %v", err))
+		writeServiceError(w, err, "Failed to list limit overrides")
+		return
+	}
+
+	b, _ := json.Marshal(LimitsResponse{Defaults: h.subscriptionService.DefaultSubscriptionLimits(), Overrides: overrides})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// HandleSetLimitOverride handles PUT /discord/admin/limits/{subject}/{subject_id}
+//
+// The override replaces any previous override of the user or guild as a whole.
+func (h *WebhookHandler) HandleSetLimitOverride(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload LimitOverrideRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	override, err := h.subscriptionService.SetLimitOverride(r.Context(), &models.LimitOverride{
+		Subject:     r.PathValue("subject"),
+		SubjectID:   r.PathValue("subject_id"),
+		MaxMarkets:  payload.MaxMarkets,
+		MaxCreators: payload.MaxCreators,
+		MaxOutcomes: payload.MaxOutcomes,
+		MaxChannels: payload.MaxChannels,
+		Reason:      payload.Reason,
+	}, apiActor(r))
+	if errors.Is(err, services.ErrInvalidLimitOverride) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to save limit override: %v", err))
+		writeServiceError(w, err, "Failed to save limit override")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("Limits of %s %s overridden by %s", override.Subject, override.SubjectID, apiActor(r)))
+
+	b, _ := json.Marshal(override)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// HandleRemoveLimitOverride handles DELETE /discord/admin/limits/{subject}/{subject_id}
+func (h *WebhookHandler) HandleRemoveLimitOverride(w http.ResponseWriter, r *http.Request) {
+	subject, subjectID := r.PathValue("subject"), r.PathValue("subject_id")
+	removed, err := h.subscriptionService.RemoveLimitOverride(r.Context(), subject, subjectID, apiActor(r))
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to remove limit override: %v", err))
+		writeServiceError(w, err, "Failed to remove limit override")
+		return
+	}
+	if !removed {
+		writeJSONError(w, http.StatusNotFound, "No limit override for this user or guild")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("Limit override of %s %s removed by %s", subject, subjectID, apiActor(r)))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,113 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// testEventMarketID identifies the synthetic market used by test events
+const testEventMarketID = "test-event"
+
+// TestEventTypes lists the event types that can be sent as test events
+var TestEventTypes = []string{
+	models.EventNewMarket,
+	models.EventMarketUpdate,
+	models.EventTradingStarted,
+	models.EventTradingEnded,
+	models.EventMarketResolved,
+	models.EventMarketBuy,
+}
+
+// Test event errors
+var (
+	ErrUnknownTestEvent     = errors.New("unknown event type")
+	ErrDiscordSessionNotSet = errors.New("Discord session not set")
+)
+
+// SendTestEvent renders a synthetic event of the given type and delivers it to a single channel
+// through the normal delivery path: charts, gateway buffering, tracing and delivery analytics.
+// Channel settings and subscriptions are bypassed so the message always reaches the channel, in the
+// channel's timezone and its guild's branding.
+func (h *WebhookHandler) SendTestEvent(ctx context.Context, eventType, channelID string) (err error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dispatchTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "dispatch.test."+eventType, attribute.String("discord.channel_id", channelID))
+	defer func() { tracing.End(span, err) }()
+
+	if h.discordSession == nil {
+		return ErrDiscordSessionNotSet
+	}
+	notification, err := h.renderTestEvent(ctx, eventType, time.Now())
+	if err != nil {
+		return err
+	}
+	if config, err := h.subscriptionService.GetChannelConfig(ctx, channelID); err == nil {
+		notification = notification.localized(config.Timezone)
+		if guild, err := h.subscriptionService.GetGuildConfig(ctx, config.GuildID); err == nil && guild != nil {
+			notification = notification.branded(guild.Branding)
+		}
+	}
+	notification.direct = true
+	return h.sendChannelMessage(ctx, channelID, notification, false)
+}
+
+// renderTestEvent builds the notification for a synthetic event on a realistic sample market
+func (h *WebhookHandler) renderTestEvent(ctx context.Context, eventType string, now time.Time) (*eventNotification, error) {
+	market := &models.Market{
+		ID:          testEventMarketID,
+		Title:       "Will Bitcoin close above $100,000 this Friday?",
+		Description: "Resolves Yes if the BTC/USD daily close on Friday is above $100,000.",
+		Outcomes:    []string{"Yes", "No"},
+		Percentages: []float64{62.0, 38.0},
+		Category:    "Crypto",
+		Creator:     "coral",
+		Volume:      48250.0,
+		StartTime:   now.Add(-48 * time.Hour),
+		EndTime:     now.Add(72 * time.Hour),
+		Status:      "active",
+		Link:        "https://coral.markets/market/" + testEventMarketID,
+	}
+
+	notification := &eventNotification{eventType: eventType}
+	switch eventType {
+	case models.EventNewMarket:
+		notification.content = h.marketService.CreateMarketAnnouncement(market)
+	case models.EventMarketUpdate:
+		previous := &models.MarketSnapshot{MarketID: market.ID, Outcomes: market.Outcomes, Percentages: []float64{54.8, 45.2}, Timestamp: now.Add(-time.Hour)}
+		notification.content = h.marketService.CreateMarketUpdateMessage(market, previous)
+	case models.EventTradingStarted:
+		notification.content = h.marketService.CreateTradingStartMessage(market)
+	case models.EventTradingEnded:
+		notification.content = h.marketService.CreateTradingEndMessage(market)
+	case models.EventMarketResolved:
+		market.Status = "resolved"
+		market.ResolvedOutcome = "Yes"
+		market.ResolvedAt = now
+		notification.content = h.marketService.CreateMarketResolutionMessage(market)
+	case models.EventMarketBuy:
+		notification.buyAmount = 2500.0
+		notification.content = h.marketService.CreateMarketBuyMessage(market.ID, market.Title, notification.buyAmount, "Yes", "test-trader", market.Link)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownTestEvent, eventType)
+	}
+
+	if chartEvents[eventType] {
+		chart, err := h.marketService.CreateProbabilityChart(ctx, market)
+		if err != nil {
+			h.logger.Warning(fmt.Sprintf("Skipping chart for test event: %v", err))
+		} else {
+			notification.chart = chart.Image
+			notification.content += "\n\n📉 " + chart.Legend
+		}
+	}
+
+	notification.content = fmt.Sprintf("🧪 **TEST EVENT** — sample `%s` message, no real market is affected\n\n%s", eventType, notification.content)
+	return notification, nil
+}
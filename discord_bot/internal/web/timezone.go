@@ -0,0 +1,65 @@
+package web
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"coral-bot/discord_bot/internal/services"
+)
+
+// HandleUserTimezone handles POST /discord/timezone
+func (h *WebhookHandler) HandleUserTimezone(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload UserTimezoneRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.DiscordUserID == "" {
+		writeJSONError(w, http.StatusBadRequest, "discord_user_id required")
+		return
+	}
+	err = h.subscriptionService.SetUserTimezone(r.Context(), payload.DiscordUserID, payload.Timezone)
+	if errors.Is(err, services.ErrInvalidTimezone) {
+		writeJSONError(w, http.StatusBadRequest, "timezone must be an IANA zone such as Europe/Berlin")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err, "Failed to save subscription")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleChannelTimezone handles POST /discord/channel/timezone
+func (h *WebhookHandler) HandleChannelTimezone(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload ChannelTimezoneRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if payload.ChannelID == "" {
+		writeJSONError(w, http.StatusBadRequest, "channel_id required")
+		return
+	}
+	err = h.subscriptionService.SetChannelTimezone(r.Context(), payload.ChannelID, "", payload.Timezone, apiActor(r))
+	if errors.Is(err, services.ErrInvalidTimezone) {
+		writeJSONError(w, http.StatusBadRequest, "timezone must be an IANA zone such as Europe/Berlin")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err, "Failed to save config")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"coral-bot/discord_bot/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// traceRoute wraps a route's handler in a server span, continuing any trace context sent by the caller
+func traceRoute(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.StartServer(r, route)
+		defer span.End()
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		}
+	})
+}
+
+// decodePayload unmarshals a request body inside a span so slow or malformed payloads show up in traces
+func decodePayload(ctx context.Context, body []byte, payload interface{}) error {
+	_, span := tracing.Start(ctx, "decode.payload", attribute.Int("payload.bytes", len(body)))
+	err := json.Unmarshal(body, payload)
+	tracing.End(span, err)
+	return err
+}
+
+// renderMessage renders an event message inside a span named after the message
+func renderMessage(ctx context.Context, name string, render func() string) string {
+	_, span := tracing.Start(ctx, "render."+name)
+	defer span.End()
+	return render()
+}
@@ -0,0 +1,137 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+)
+
+// updateCoalescer holds back market updates that arrive within a window of the last update sent for
+// their market. When the window ends only the latest held update is sent, noting how many it replaced,
+// and a new window starts. Windows are timed by the handler's clock and ended by flushDue.
+type updateCoalescer struct {
+	window  time.Duration
+	send    func(market *models.Market, replaced int)
+	mutex   sync.Mutex
+	pending map[string]*coalescedUpdate // by market ID, for markets within their window
+}
+
+// coalescedUpdate is the window of one market
+type coalescedUpdate struct {
+	ends   time.Time
+	latest *models.Market // the latest held update, nil when none arrived in the window
+	held   int
+}
+
+// SetUpdateCoalescing coalesces the updates of each market that arrive within window of the last one
+// sent, so only the latest is sent when the window ends. A window of 0 sends every update.
+func (h *WebhookHandler) SetUpdateCoalescing(window time.Duration) {
+	if window <= 0 {
+		h.coalescer = nil
+		return
+	}
+	h.coalescer = &updateCoalescer{
+		window:  window,
+		pending: make(map[string]*coalescedUpdate),
+		send: func(market *models.Market, replaced int) {
+			h.dispatchMarketUpdate(context.Background(), market, replaced)
+		},
+	}
+}
+
+// FlushCoalescedUpdates sends the updates coalescing is holding back right away, as on shutdown
+func (h *WebhookHandler) FlushCoalescedUpdates() {
+	if h.coalescer != nil {
+		h.coalescer.flushAll()
+	}
+}
+
+// FlushDueCoalescedUpdates ends the coalescing windows that are over at now, sending their latest held
+// update, and returns how many updates it sent
+func (h *WebhookHandler) FlushDueCoalescedUpdates(now time.Time) int {
+	if h.coalescer == nil {
+		return 0
+	}
+	return h.coalescer.flushDue(now)
+}
+
+// RunUpdateCoalescing ends the coalescing windows that are over on every tick, until ctx is cancelled.
+// Windows end up to interval late.
+func (h *WebhookHandler) RunUpdateCoalescing(ctx context.Context, interval time.Duration) {
+	if h.coalescer == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	h.logger.Info(fmt.Sprintf("Update coalescing started (window %s)", h.coalescer.window))
+	for {
+		select {
+		case <-ctx.Done():
+			h.logger.Info("Update coalescing stopped")
+			return
+		case <-ticker.C:
+			h.FlushDueCoalescedUpdates(h.now())
+		}
+	}
+}
+
+// hold reports whether a market's update is held back because the market is within its window. An
+// update that is not held opens a window for its market and must be sent by the caller, noting the
+// replaced updates held in a window that is over but was not flushed yet.
+func (coalescer *updateCoalescer) hold(market *models.Market, now time.Time) (held bool, replaced int) {
+	coalescer.mutex.Lock()
+	defer coalescer.mutex.Unlock()
+	pending, ok := coalescer.pending[market.ID]
+	if ok && now.Before(pending.ends) {
+		pending.latest = market
+		pending.held++
+		return true, 0
+	}
+	if ok {
+		replaced = pending.held
+	}
+	coalescer.pending[market.ID] = &coalescedUpdate{ends: now.Add(coalescer.window)}
+	return false, replaced
+}
+
+// flushDue ends the windows that are over at now, sending their latest held update and opening the
+// next window for the markets that had one, and returns how many updates it sent
+func (coalescer *updateCoalescer) flushDue(now time.Time) int {
+	coalescer.mutex.Lock()
+	due := []*coalescedUpdate{}
+	for marketID, pending := range coalescer.pending {
+		if now.Before(pending.ends) {
+			continue
+		}
+		if pending.latest == nil {
+			delete(coalescer.pending, marketID)
+			continue
+		}
+		due = append(due, pending)
+		coalescer.pending[marketID] = &coalescedUpdate{ends: now.Add(coalescer.window)}
+	}
+	coalescer.mutex.Unlock()
+
+	for _, pending := range due {
+		coalescer.send(pending.latest, pending.held-1)
+	}
+	return len(due)
+}
+
+// flushAll sends every held update without opening new windows
+func (coalescer *updateCoalescer) flushAll() {
+	coalescer.mutex.Lock()
+	pending := coalescer.pending
+	coalescer.pending = make(map[string]*coalescedUpdate)
+	coalescer.mutex.Unlock()
+
+	for _, update := range pending {
+		if update.latest != nil {
+			coalescer.send(update.latest, update.held-1)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"coral-bot/discord_bot/internal/models"
+	"coral-bot/discord_bot/internal/services"
+)
+
+// HandleGetUpdateIntervals handles GET /discord/guild/update_intervals/{guild_id}
+func (h *WebhookHandler) HandleGetUpdateIntervals(w http.ResponseWriter, r *http.Request) {
+	guildID := r.PathValue("guild_id")
+	guild, err := h.subscriptionService.GetGuildConfig(r.Context(), guildID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to load guild config")
+		return
+	}
+	h.writeUpdateIntervals(w, guildID, guild)
+}
+
+// HandleSetUpdateIntervals handles PUT /discord/guild/update_intervals/{guild_id}
+//
+// The intervals replace any the guild set before; omitted ones follow the bot's.
+func (h *WebhookHandler) HandleSetUpdateIntervals(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var payload UpdateIntervalsRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	var intervals models.UpdateIntervals
+	for _, field := range []struct {
+		name     string
+		raw      string
+		interval *time.Duration
+	}{
+		{"high", payload.High, &intervals.High},
+		{"medium", payload.Medium, &intervals.Medium},
+		{"low", payload.Low, &intervals.Low},
+		{"closing_soon_window", payload.ClosingSoonWindow, &intervals.ClosingSoonWindow},
+		{"closing_soon_interval", payload.ClosingSoonInterval, &intervals.ClosingSoonInterval},
+	} {
+		if field.raw == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(field.raw)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("%s must be a duration like 45m or 2h", field.name))
+			return
+		}
+		*field.interval = parsed
+	}
+
+	guildID := r.PathValue("guild_id")
+	guild, err := h.subscriptionService.SetGuildUpdateIntervals(r.Context(), guildID, &intervals)
+	if errors.Is(err, services.ErrInvalidChannelSettings) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to save update intervals of guild %s: %v", guildID, err))
+		writeServiceError(w, err, "Failed to save update intervals")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("Update intervals of guild %s set by %s", guildID, apiActor(r)))
+	h.writeUpdateIntervals(w, guildID, guild)
+}
+
+// HandleResetUpdateIntervals handles DELETE /discord/guild/update_intervals/{guild_id}
+func (h *WebhookHandler) HandleResetUpdateIntervals(w http.ResponseWriter, r *http.Request) {
+	guildID := r.PathValue("guild_id")
+	guild, err := h.subscriptionService.SetGuildUpdateIntervals(r.Context(), guildID, nil)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to reset update intervals of guild %s: %v", guildID, err))
+		writeServiceError(w, err, "Failed to reset update intervals")
+		return
+	}
+	h.logger.Info(fmt.Sprintf("Update intervals of guild %s reset by %s", guildID, apiActor(r)))
+	h.writeUpdateIntervals(w, guildID, guild)
+}
+
+// writeUpdateIntervals writes the intervals a guild's channels follow. guild may be nil.
+func (h *WebhookHandler) writeUpdateIntervals(w http.ResponseWriter, guildID string, guild *models.GuildConfig) {
+	intervals := h.marketService.UpdateIntervals(guild)
+	b, _ := json.Marshal(UpdateIntervalsResponse{
+		GuildID:             guildID,
+		Overridden:          guild != nil && guild.UpdateIntervals != nil,
+		High:                models.FormatInterval(intervals.High),
+		Medium:              models.FormatInterval(intervals.Medium),
+		Low:                 models.FormatInterval(intervals.Low),
+		ClosingSoonWindow:   models.FormatInterval(intervals.ClosingSoonWindow),
+		ClosingSoonInterval: models.FormatInterval(intervals.ClosingSoonInterval),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
@@ -0,0 +1,56 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"coral-bot/discord_bot/internal/services"
+)
+
+// SetUserDataService sets the service answering data access and deletion requests
+func (h *WebhookHandler) SetUserDataService(userDataService services.UserDataService) {
+	h.userDataService = userDataService
+}
+
+// HandleExportUserData handles GET /discord/users/{discord_user_id}/data
+func (h *WebhookHandler) HandleExportUserData(w http.ResponseWriter, r *http.Request) {
+	if h.userDataService == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "User data requests not enabled")
+		return
+	}
+	discordUserID := r.PathValue("discord_user_id")
+	data, err := h.userDataService.ExportUserData(r.Context(), discordUserID)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to export the data of user %s: %v", discordUserID, err))
+		writeServiceError(w, err, "Failed to export user data")
+		return
+	}
+
+	b, _ := json.Marshal(data)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// HandleDeleteUserData handles DELETE /discord/users/{discord_user_id}/data
+//
+// The response holds the deleted data, so the caller can hand the user a copy.
+func (h *WebhookHandler) HandleDeleteUserData(w http.ResponseWriter, r *http.Request) {
+	if h.userDataService == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "User data requests not enabled")
+		return
+	}
+	discordUserID := r.PathValue("discord_user_id")
+	data, err := h.userDataService.DeleteUserData(r.Context(), discordUserID)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to delete the data of user %s: %v", discordUserID, err))
+		writeServiceError(w, err, "Failed to delete user data")
+		return
+	}
+
+	b, _ := json.Marshal(data)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
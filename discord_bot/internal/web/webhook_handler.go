@@ -1,13 +1,17 @@
 package web
 
 import (
+	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
-	"os"
+	"sort"
 	"strconv"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"coral-bot/discord_bot/internal/models"
@@ -21,9 +25,51 @@ import (
 type WebhookHandler struct {
 	marketService       services.MarketService
 	subscriptionService services.SubscriptionService
+	analyticsService    services.AnalyticsService
+	apiKeyService       services.APIKeyService             // nil when only CORAL_API_KEY and CORAL_TOKEN are accepted
+	outbox              services.OutboxService             // nil delivers events without storing them first
+	publisher           services.EventPublisher            // nil when processed events are not published
+	userDataService     services.UserDataService           // nil disables the user data endpoints
+	boards              services.MarketBoardService        // nil when market boards are not kept
+	deliveries          services.DeliveryReportService     // nil when delivery reports are not recorded
+	deadLetters         services.DeadLetterService         // nil when failed sends are only logged
+	quietHours          services.QuietHoursService         // nil posts events during channels' quiet hours
+	announcements       services.MarketAnnouncementService // nil announces a new market as often as the backend sends it
+	migrations          services.StorageMigrationService   // nil disables storage migration
+	httpLog             services.HTTPLogService            // nil disables request logging
 	logger              *utils.Logger
-	discordSession      *discordgo.Session // Store the Discord session to send messages
-}
+	discordSession      *discordgo.Session        // Store the Discord session to send messages
+	gateway             *services.GatewayMonitor  // buffers outbound messages while the gateway is down
+	maintenance         *services.MaintenanceMode // buffers outbound messages during maintenance, nil when it cannot be turned on
+	fanout              *services.FanoutPool      // nil sends the messages of a fan-out one at a time
+	coalescer           *updateCoalescer          // nil sends every market update as it arrives
+	clock               services.Clock            // times update coalescing windows, nil for the system clock
+	eventRegistry       *services.EventRegistry   // custom event types, nil when there are none
+	linkDecorator       *services.LinkDecorator   // nil posts links as the backend sent them
+	email               services.Notifier         // emails users who route resolutions to email, nil DMs everyone
+	push                *services.PushNotifier    // pushes to users' linked Coral accounts, nil DMs everyone
+	accounts            services.AccountService   // nil when Coral accounts cannot be linked
+	trading             bool                      // buy buttons go under the alerts of guilds that turned them on
+	game                services.GameService      // nil when the prediction game is off
+	minBuyAmount        float64                   // buys below this amount are suppressed everywhere
+	whaleBuyAmount      float64                   // buys at or above this amount use the whale format, 0 disables it
+	shadowMode          bool                      // every message is logged and reported instead of sent
+	webhookTestPing     bool                      // registered webhooks are tested with a message unless the registration says otherwise
+	tlsCertFile         string
+	tlsKeyFile          string
+	trustedProxies      []*net.IPNet                    // proxies whose X-Forwarded-For header is trusted
+	ingestAllowlist     []*net.IPNet                    // clients allowed to post events, any when empty
+	rateLimiter         *rateLimiter                    // nil when rate limiting is disabled
+	suppressedResolved  atomic.Int64                    // events suppressed because their market had already resolved
+	rootCredentials     atomic.Pointer[RootCredentials] // nil reads the root credentials from the environment
+	interactions        InteractionHandler              // nil when interactions only arrive over the gateway
+	interactionKey      ed25519.PublicKey               // verifies the signatures of interactions received over HTTP
+	interactionsRunning sync.WaitGroup                  // handlers of interactions received over HTTP that have not returned
+	commands            CommandSyncer                   // nil when the commands cannot be resynced over the API
+}
+
+// apiAuditActor identifies changes made through the REST API in the audit log
+const apiAuditActor = "api"
 
 // NewWebhookHandler creates a new webhook handler
 func NewWebhookHandler(
@@ -43,365 +89,129 @@ func (h *WebhookHandler) SetDiscordSession(session *discordgo.Session) {
 	h.discordSession = session
 }
 
-// AuthOk checks if the request is properly authenticated
-func (h *WebhookHandler) AuthOk(r *http.Request) bool {
-	apiKey := r.Header.Get("X-API-Key")
-	bearer := r.Header.Get("Authorization")
-	if len(bearer) > 7 && bearer[:7] == "Bearer " {
-		bearer = bearer[7:]
-	}
-
-	requiredAPIKey := os.Getenv("CORAL_API_KEY")
-	requiredToken := os.Getenv("CORAL_TOKEN")
-
-	if requiredAPIKey != "" && apiKey == requiredAPIKey {
-		return true
-	}
-	if requiredToken != "" && bearer == requiredToken {
-		return true
-	}
-	return requiredAPIKey == "" && requiredToken == ""
+// SetGateway sets the monitor that reports the gateway state and buffers messages while it is down
+func (h *WebhookHandler) SetGateway(gateway *services.GatewayMonitor) {
+	h.gateway = gateway
 }
 
-// sendToSubscribedChannels sends a message to all subscribed channels
-func (h *WebhookHandler) sendToSubscribedChannels(message string, market *models.Market) {
-	if h.discordSession == nil {
-		h.logger.Error("Discord session not set")
-		return
-	}
-
-	// Get all channel configurations
-	channels, err := h.subscriptionService.GetAllChannelConfigs()
-	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to get channel configs: %v", err))
-		return
-	}
-
-	for _, channelConfig := range channels {
-		// Check if feed is enabled for this channel
-		if !channelConfig.FeedEnabled {
-			continue
-		}
-
-		// Check if market category is allowed
-		if len(channelConfig.AllowedCategories) > 0 {
-			allowed := false
-			for _, category := range channelConfig.AllowedCategories {
-				if category == market.Category {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				continue
-			}
-		}
-
-		// Send message to channel
-		_, err := h.discordSession.ChannelMessageSend(channelConfig.ChannelID, message)
-		if err != nil {
-			h.logger.Error(fmt.Sprintf("Failed to send message to channel %s: %v", channelConfig.ChannelID, err))
-		} else {
-			h.logger.Info(fmt.Sprintf("Sent message to channel %s", channelConfig.ChannelID))
-		}
-	}
+// SetClock sets the clock update coalescing windows are timed by
+func (h *WebhookHandler) SetClock(clock services.Clock) {
+	h.clock = clock
 }
 
-// sendToSubscribedUsers sends a DM to all subscribed users
-func (h *WebhookHandler) sendToSubscribedUsers(message string, market *models.Market) {
-	if h.discordSession == nil {
-		h.logger.Error("Discord session not set")
-		return
-	}
-
-	// Get all subscriptions
-	subscriptions, err := h.subscriptionService.GetAllSubscriptions()
-	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to get subscriptions: %v", err))
-		return
-	}
-
-	for _, subscription := range subscriptions {
-		// Check if user is subscribed to this market or creator
-		shouldNotify := h.subscriptionService.ShouldNotifyUser(subscription, market)
-		if !shouldNotify {
-			continue
-		}
-
-		// Send DM to user
-		channel, err := h.discordSession.UserChannelCreate(subscription.DiscordUserID)
-		if err != nil {
-			h.logger.Error(fmt.Sprintf("Failed to create DM channel for user %s: %v", subscription.DiscordUserID, err))
-			continue
-		}
-
-		_, err = h.discordSession.ChannelMessageSend(channel.ID, message)
-		if err != nil {
-			h.logger.Error(fmt.Sprintf("Failed to send DM to user %s: %v", subscription.DiscordUserID, err))
-		} else {
-			h.logger.Info(fmt.Sprintf("Sent DM to user %s", subscription.DiscordUserID))
-		}
+// now returns the current time of the handler's clock
+func (h *WebhookHandler) now() time.Time {
+	if h.clock == nil {
+		return time.Now()
 	}
+	return h.clock.Now()
 }
 
-// HandleNewMarket handles the new_market webhook
-func (h *WebhookHandler) HandleNewMarket(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
-
-	requestBody, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to read request body: %v", err))
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
-		return
-	}
-
-	var payload struct {
-		EventType string        `json:"event_type"`
-		Market    models.Market `json:"market"`
-	}
-
-	if err := json.Unmarshal(requestBody, &payload); err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to parse JSON: %v", err))
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
-		return
-	}
-
-	if payload.EventType != "new_market" {
-		h.logger.Error("Invalid event type")
-		http.Error(w, `{"error": "Invalid event type"}`, http.StatusBadRequest)
-		return
-	}
-
-	// Create announcement message
-	announcement := h.marketService.CreateMarketAnnouncement(&payload.Market)
-	h.logger.Info(fmt.Sprintf("New market announcement: %s", announcement))
-
-	// Send to subscribed channels and users
-	h.sendToSubscribedChannels(announcement, &payload.Market)
-	h.sendToSubscribedUsers(announcement, &payload.Market)
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"ok": true}`))
+// SetAnalyticsService sets the analytics service used to record deliveries
+func (h *WebhookHandler) SetAnalyticsService(analyticsService services.AnalyticsService) {
+	h.analyticsService = analyticsService
 }
 
-// HandleMarketUpdate handles the market_update webhook
-func (h *WebhookHandler) HandleMarketUpdate(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
-
-	requestBody, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to read request body: %v", err))
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
-		return
-	}
-
-	var payload struct {
-		EventType string        `json:"event_type"`
-		Market    models.Market `json:"market"`
-	}
-
-	if err := json.Unmarshal(requestBody, &payload); err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to parse JSON: %v", err))
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
-		return
-	}
-
-	if payload.EventType != "market_update" {
-		h.logger.Error("Invalid event type")
-		http.Error(w, `{"error": "Invalid event type"}`, http.StatusBadRequest)
-		return
-	}
-
-	// Create update message
-	updateMessage := h.marketService.CreateMarketUpdateMessage(&payload.Market)
-	h.logger.Info(fmt.Sprintf("Market update: %s", updateMessage))
-
-	// Send to subscribed channels and users
-	h.sendToSubscribedChannels(updateMessage, &payload.Market)
-	h.sendToSubscribedUsers(updateMessage, &payload.Market)
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"ok": true}`))
+// SetOutboxService sets the outbox events are stored in before they are delivered
+func (h *WebhookHandler) SetOutboxService(outbox services.OutboxService) {
+	h.outbox = outbox
 }
 
-// HandleTradingStarted handles the trading_started webhook
-func (h *WebhookHandler) HandleTradingStarted(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
-
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to read request body: %v", err))
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
-		return
-	}
-
-	var payload struct {
-		EventType string        `json:"event_type"`
-		Market    models.Market `json:"market"`
-	}
-
-	if err := json.Unmarshal(body, &payload); err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to parse JSON: %v", err))
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
-		return
-	}
-
-	if payload.EventType != "trading_started" {
-		h.logger.Error("Invalid event type")
-		http.Error(w, `{"error": "Invalid event type"}`, http.StatusBadRequest)
-		return
-	}
-
-	// Create trading start message
-	startMessage := h.marketService.CreateTradingStartMessage(&payload.Market)
-	h.logger.Info(fmt.Sprintf("Trading started: %s", startMessage))
-
-	// Send to subscribed channels and users
-	h.sendToSubscribedChannels(startMessage, &payload.Market)
-	h.sendToSubscribedUsers(startMessage, &payload.Market)
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"ok": true}`))
+// SetEventPublisher sets the publisher processed events and their delivery stats are sent to
+func (h *WebhookHandler) SetEventPublisher(publisher services.EventPublisher) {
+	h.publisher = publisher
 }
 
-// HandleTradingEnded handles the trading_ended webhook
-func (h *WebhookHandler) HandleTradingEnded(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
-
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to read request body: %v", err))
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
-		return
-	}
-
-	var payload struct {
-		EventType string        `json:"event_type"`
-		Market    models.Market `json:"market"`
-	}
-
-	if err := json.Unmarshal(body, &payload); err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to parse JSON: %v", err))
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
-		return
-	}
-
-	if payload.EventType != "trading_ended" {
-		h.logger.Error("Invalid event type")
-		http.Error(w, `{"error": "Invalid event type"}`, http.StatusBadRequest)
-		return
-	}
-
-	// Create trading end message
-	endMessage := h.marketService.CreateTradingEndMessage(&payload.Market)
-	h.logger.Info(fmt.Sprintf("Trading ended: %s", endMessage))
-
-	// Send to subscribed channels and users
-	h.sendToSubscribedChannels(endMessage, &payload.Market)
-	h.sendToSubscribedUsers(endMessage, &payload.Market)
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"ok": true}`))
+// SetLinkDecorator sets the decorator adding click tracking to the links of posted messages
+func (h *WebhookHandler) SetLinkDecorator(decorator *services.LinkDecorator) {
+	h.linkDecorator = decorator
 }
 
-// HandleMarketResolved handles the market_resolved webhook
-func (h *WebhookHandler) HandleMarketResolved(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
-
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to read request body: %v", err))
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
-		return
-	}
+// SetPushNotifier sets the notifier pushing user notifications to the Coral accounts users linked
+func (h *WebhookHandler) SetPushNotifier(push *services.PushNotifier) {
+	h.push = push
+}
 
-	var payload struct {
-		EventType string        `json:"event_type"`
-		Market    models.Market `json:"market"`
-	}
+// SetTradingEnabled shows buy buttons under the market alerts of guilds that turned trading on
+func (h *WebhookHandler) SetTradingEnabled(enabled bool) {
+	h.trading = enabled
+}
 
-	if err := json.Unmarshal(body, &payload); err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to parse JSON: %v", err))
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
-		return
-	}
+// SetGameService shows bet buttons under the market alerts of guilds that play the prediction game,
+// and settles their bets when the markets resolve or are cancelled
+func (h *WebhookHandler) SetGameService(game services.GameService) {
+	h.game = game
+}
 
-	if payload.EventType != "market_resolved" {
-		h.logger.Error("Invalid event type")
-		http.Error(w, `{"error": "Invalid event type"}`, http.StatusBadRequest)
-		return
-	}
+// SetEmailNotifier sets the notifier emailing resolution notifications to users who chose email
+func (h *WebhookHandler) SetEmailNotifier(email services.Notifier) {
+	h.email = email
+}
 
-	// Create resolution message
-	resolutionMessage := h.marketService.CreateMarketResolutionMessage(&payload.Market)
-	h.logger.Info(fmt.Sprintf("Market resolved: %s", resolutionMessage))
+// SetMarketAnnouncementService sets the service remembering the new markets announced in each channel,
+// so a market the backend sends twice, over both new market endpoints or as a retry, is posted once
+func (h *WebhookHandler) SetMarketAnnouncementService(announcements services.MarketAnnouncementService) {
+	h.announcements = announcements
+}
 
-	// Send to subscribed channels and users
-	h.sendToSubscribedChannels(resolutionMessage, &payload.Market)
-	h.sendToSubscribedUsers(resolutionMessage, &payload.Market)
+// SetShadowMode logs and reports every message as it would have been sent, to every channel and user,
+// without sending it
+func (h *WebhookHandler) SetShadowMode(enabled bool) {
+	h.shadowMode = enabled
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"ok": true}`))
+// SetBuyThresholds sets the global minimum buy amount and the whale alert threshold
+func (h *WebhookHandler) SetBuyThresholds(minAmount, whaleAmount float64) {
+	h.minBuyAmount = minAmount
+	h.whaleBuyAmount = whaleAmount
 }
 
 // HandleRegisterWebhook handles POST /discord/webhooks/register
 func (h *WebhookHandler) HandleRegisterWebhook(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
 
 	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		h.logger.Error(fmt.Sprintf("Failed to read request body: %v", err))
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
 
-	var payload struct {
-		ChannelID         string   `json:"channel_id"`
-		WebhookURL        string   `json:"webhook_url"`
-		Events            []string `json:"events"`
-		Frequency         string   `json:"frequency"`
-		AllowedCategories []string `json:"allowed_categories"`
-	}
+	var payload RegisterWebhookRequest
 
-	if err := json.Unmarshal(body, &payload); err != nil {
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
 		h.logger.Error(fmt.Sprintf("Failed to parse JSON: %v", err))
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 
 	if payload.ChannelID == "" || payload.WebhookURL == "" {
-		http.Error(w, `{"error": "channel_id and webhook_url are required"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "channel_id and webhook_url are required")
+		return
+	}
+	webhookID, token, err := services.ParseDiscordWebhookURL(payload.WebhookURL)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	// a webhook that is gone or posts elsewhere is refused now rather than failing on its first event
+	ping := h.webhookTestPing
+	if payload.TestPing != nil {
+		ping = *payload.TestPing
+	}
+	if ping {
+		if err := h.pingWebhook(r.Context(), payload.ChannelID, webhookID, token); err != nil {
+			h.logger.Warning(fmt.Sprintf("Test ping of webhook %s for channel %s failed: %v", webhookID, payload.ChannelID, err))
+			writeAPIError(w, mapWebhookPingError(err))
+			return
+		}
+	}
+
 	// create registration
 	reg := &models.WebhookRegistration{
 		ChannelID:         payload.ChannelID,
@@ -411,10 +221,10 @@ func (h *WebhookHandler) HandleRegisterWebhook(w http.ResponseWriter, r *http.Re
 		AllowedCategories: payload.AllowedCategories,
 	}
 
-	saved, err := h.subscriptionService.RegisterWebhook(reg)
+	saved, err := h.subscriptionService.RegisterWebhook(r.Context(), reg, apiActor(r))
 	if err != nil {
 		h.logger.Error(fmt.Sprintf("Failed to save webhook registration: %v", err))
-		http.Error(w, `{"error": "Failed to register webhook"}`, http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to register webhook")
 		return
 	}
 
@@ -428,309 +238,136 @@ func (h *WebhookHandler) HandleRegisterWebhook(w http.ResponseWriter, r *http.Re
 }
 
 func (h *WebhookHandler) HandleUnregisterWebhookByPath(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
 	if r.Method != http.MethodDelete {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
-		return
-	}
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	if len(parts) < 3 {
-		http.Error(w, `{"error": "id required"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	id := parts[len(parts)-1]
+	id := r.PathValue("id")
 	if id == "" {
-		http.Error(w, `{"error": "id required"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "id required")
 		return
 	}
-	if err := h.subscriptionService.UnregisterWebhook(id); err != nil {
+	if err := h.subscriptionService.UnregisterWebhook(r.Context(), id, apiActor(r)); err != nil {
 		h.logger.Error(fmt.Sprintf("Failed to unregister webhook: %v", err))
-		http.Error(w, `{"error": "Failed to unregister webhook"}`, http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to unregister webhook")
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *WebhookHandler) HandleEventNewMarket(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
-	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
-		return
-	}
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
-		return
-	}
-	var payload struct {
-		MarketID    string `json:"market_id"`
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		Creator     string `json:"creator"`
-		Category    string `json:"category"`
-		Outcomes    []struct {
-			ID   string `json:"id"`
-			Name string `json:"name"`
-		} `json:"outcomes"`
-		StartTime string  `json:"start_time"`
-		EndTime   string  `json:"end_time"`
-		Volume    float64 `json:"volume"`
-		Link      string  `json:"link"`
-	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
-		return
-	}
-	st, _ := time.Parse(time.RFC3339, payload.StartTime)
-	et, _ := time.Parse(time.RFC3339, payload.EndTime)
-	outs := make([]string, 0, len(payload.Outcomes))
-	for _, o := range payload.Outcomes {
-		outs = append(outs, o.Name)
-	}
-	market := models.Market{
-		ID:          payload.MarketID,
-		Title:       payload.Title,
-		Description: payload.Description,
-		Outcomes:    outs,
-		Percentages: []float64{},
-		Category:    payload.Category,
-		Creator:     payload.Creator,
-		Volume:      payload.Volume,
-		StartTime:   st,
-		EndTime:     et,
-		Status:      "active",
-		Link:        payload.Link,
-	}
-	msg := h.marketService.CreateMarketAnnouncement(&market)
-	h.sendToSubscribedChannels(msg, &market)
-	h.sendToSubscribedUsers(msg, &market)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
-	w.Write([]byte(`{"accepted": true}`))
-}
-
-func (h *WebhookHandler) HandleEventMarketUpdate(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
+func (h *WebhookHandler) HandleSubscribeMarket(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
+	var payload MarketSubscriptionRequest
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
-		return
-	}
-	var payload struct {
-		MarketID       string  `json:"market_id"`
-		Title          string  `json:"title"`
-		Volume         float64 `json:"volume"`
-		VolumeDeltaPct float64 `json:"volume_delta_pct"`
-		TimeLeft       string  `json:"time_left"`
-		EndTime        string  `json:"end_time"`
-		Link           string  `json:"link"`
-	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
-		return
-	}
-	et, _ := time.Parse(time.RFC3339, payload.EndTime)
-	market := models.Market{
-		ID:      payload.MarketID,
-		Title:   payload.Title,
-		Volume:  payload.Volume,
-		EndTime: et,
-		Status:  "active",
-		Link:    payload.Link,
-	}
-	msg := h.marketService.CreateMarketUpdateMessage(&market)
-	h.sendToSubscribedChannels(msg, &market)
-	h.sendToSubscribedUsers(msg, &market)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
-	w.Write([]byte(`{"accepted": true}`))
-}
-
-func (h *WebhookHandler) HandleEventTradingStart(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
-	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
-	requestBody, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
-	var eventPayload struct {
-		MarketID      string   `json:"market_id"`
-		Title         string   `json:"title"`
-		Description   string   `json:"description"`
-		Duration      string   `json:"duration"`
-		OutcomesCount int      `json:"outcomes_count"`
-		Outcomes      []string `json:"outcomes"`
-		Link          string   `json:"link"`
-	}
-	if err := json.Unmarshal(requestBody, &eventPayload); err != nil {
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
+	if err := h.subscriptionService.SubscribeToMarket(r.Context(), payload.DiscordUserID, payload.MarketID); err != nil {
+		writeServiceError(w, err, "Failed to subscribe")
 		return
 	}
-	market := models.Market{ID: eventPayload.MarketID, Title: eventPayload.Title, Description: eventPayload.Description, Outcomes: eventPayload.Outcomes, Link: eventPayload.Link}
-	messageBody := h.marketService.CreateTradingStartMessage(&market)
-	h.sendToSubscribedChannels(messageBody, &market)
-	h.sendToSubscribedUsers(messageBody, &market)
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
-	w.Write([]byte(`{"accepted": true}`))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"subscribed": true}`))
 }
 
-func (h *WebhookHandler) HandleEventTradingEnd(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
+func (h *WebhookHandler) HandleUnsubscribeMarket(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	requestBody, err := io.ReadAll(r.Body)
+	var payload MarketSubscriptionRequest
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
-	var eventPayload struct {
-		MarketID    string `json:"market_id"`
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		Outcomes    []struct {
-			ID   string  `json:"id"`
-			Name string  `json:"name"`
-			Pct  float64 `json:"pct"`
-		} `json:"outcomes"`
-		FinalPool float64 `json:"final_pool"`
-		Link      string  `json:"link"`
-	}
-	if err := json.Unmarshal(requestBody, &eventPayload); err != nil {
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
-	outcomeNames := make([]string, 0, len(eventPayload.Outcomes))
-	for _, outcome := range eventPayload.Outcomes {
-		outcomeNames = append(outcomeNames, outcome.Name)
+	if err := h.subscriptionService.UnsubscribeFromMarket(r.Context(), payload.DiscordUserID, payload.MarketID); err != nil {
+		writeServiceError(w, err, "Failed to unsubscribe")
+		return
 	}
-	market := models.Market{ID: eventPayload.MarketID, Title: eventPayload.Title, Description: eventPayload.Description, Outcomes: outcomeNames, Volume: eventPayload.FinalPool, Link: eventPayload.Link}
-	messageBody := h.marketService.CreateTradingEndMessage(&market)
-	h.sendToSubscribedChannels(messageBody, &market)
-	h.sendToSubscribedUsers(messageBody, &market)
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
-	w.Write([]byte(`{"accepted": true}`))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"subscribed": false}`))
 }
 
-func (h *WebhookHandler) HandleEventMarketResolved(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
+func (h *WebhookHandler) HandleSubscribeCreator(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
+	var payload CreatorSubscriptionRequest
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
-	var payload struct {
-		MarketID       string  `json:"market_id"`
-		Title          string  `json:"title"`
-		WinningOutcome string  `json:"winning_outcome"`
-		TotalPool      float64 `json:"total_pool"`
-		Link           string  `json:"link"`
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
 	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
+	if err := h.subscriptionService.SubscribeToCreator(r.Context(), payload.DiscordUserID, payload.CreatorID); err != nil {
+		writeServiceError(w, err, "Failed to subscribe")
 		return
 	}
-	market := models.Market{ID: payload.MarketID, Title: payload.Title, ResolvedOutcome: payload.WinningOutcome, Volume: payload.TotalPool, Link: payload.Link}
-	msg := h.marketService.CreateMarketResolutionMessage(&market)
-	h.sendToSubscribedChannels(msg, &market)
-	h.sendToSubscribedUsers(msg, &market)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
-	w.Write([]byte(`{"accepted": true}`))
+	w.WriteHeader(http.StatusOK)
 }
 
-func (h *WebhookHandler) HandleEventMarketBuy(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
+func (h *WebhookHandler) HandleUnsubscribeCreator(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
+	var payload CreatorSubscriptionRequest
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
-	var payload struct {
-		MarketID string  `json:"market_id"`
-		Title    string  `json:"title"`
-		Amount   float64 `json:"amount"`
-		Outcome  string  `json:"outcome"`
-		Buyer    string  `json:"buyer"`
-		Link     string  `json:"link"`
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
 	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
+	if err := h.subscriptionService.UnsubscribeFromCreator(r.Context(), payload.DiscordUserID, payload.CreatorID); err != nil {
+		writeServiceError(w, err, "Failed to unsubscribe")
 		return
 	}
-	msg := h.marketService.CreateMarketBuyMessage(payload.MarketID, payload.Title, payload.Amount, payload.Outcome, payload.Buyer, payload.Link)
-	market := models.Market{ID: payload.MarketID, Title: payload.Title, Link: payload.Link}
-	h.sendToSubscribedChannels(msg, &market)
-	h.sendToSubscribedUsers(msg, &market)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
-	w.Write([]byte(`{"accepted": true}`))
+	w.WriteHeader(http.StatusOK)
 }
 
-func (h *WebhookHandler) HandleSubscribeMarket(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
+func (h *WebhookHandler) HandleSubscribeOutcome(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	var payload struct {
-		DiscordUserID string `json:"discord_user_id"`
-		MarketID      string `json:"market_id"`
-	}
+	var payload OutcomeSubscriptionRequest
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
-	if err := h.subscriptionService.SubscribeToMarket(payload.DiscordUserID, payload.MarketID); err != nil {
-		http.Error(w, `{"error": "Failed to subscribe"}`, http.StatusInternalServerError)
+	if payload.DiscordUserID == "" || payload.MarketID == "" || payload.Outcome == "" {
+		writeJSONError(w, http.StatusBadRequest, "discord_user_id, market_id and outcome are required")
+		return
+	}
+	if err := h.subscriptionService.SubscribeToOutcome(r.Context(), payload.DiscordUserID, payload.MarketID, payload.Outcome, payload.MinChange); err != nil {
+		writeServiceError(w, err, "Failed to subscribe")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -738,30 +375,23 @@ func (h *WebhookHandler) HandleSubscribeMarket(w http.ResponseWriter, r *http.Re
 	w.Write([]byte(`{"subscribed": true}`))
 }
 
-func (h *WebhookHandler) HandleUnsubscribeMarket(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
+func (h *WebhookHandler) HandleUnsubscribeOutcome(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	var payload struct {
-		DiscordUserID string `json:"discord_user_id"`
-		MarketID      string `json:"market_id"`
-	}
+	var payload OutcomeSubscriptionRequest
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
-	if err := h.subscriptionService.UnsubscribeFromMarket(payload.DiscordUserID, payload.MarketID); err != nil {
-		http.Error(w, `{"error": "Failed to unsubscribe"}`, http.StatusInternalServerError)
+	if err := h.subscriptionService.UnsubscribeFromOutcome(r.Context(), payload.DiscordUserID, payload.MarketID, payload.Outcome); err != nil {
+		writeServiceError(w, err, "Failed to unsubscribe")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -769,223 +399,233 @@ func (h *WebhookHandler) HandleUnsubscribeMarket(w http.ResponseWriter, r *http.
 	w.Write([]byte(`{"subscribed": false}`))
 }
 
-func (h *WebhookHandler) HandleSubscribeCreator(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+// HandleGetUserSubscriptions handles GET /discord/subscriptions/{discord_user_id}
+//
+// With ?market_id=<id> or ?creator=<name>, only the subscriptions to that market, its outcomes or that
+// creator are returned. ?limit=<n> and ?cursor=<next_cursor> page every list at once.
+func (h *WebhookHandler) HandleGetUserSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+	discordUserID := r.PathValue("discord_user_id")
+	if discordUserID == "" {
+		writeJSONError(w, http.StatusBadRequest, "discord_user_id required")
 		return
 	}
-	var payload struct {
-		DiscordUserID string `json:"discord_user_id"`
-		CreatorID     string `json:"creator_id"`
-	}
-	body, err := io.ReadAll(r.Body)
+	p, err := parsePage(r)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
+	sub, err := h.subscriptionService.GetUserSubscriptions(r.Context(), discordUserID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to get subscriptions")
 		return
 	}
-	if err := h.subscriptionService.SubscribeToCreator(payload.DiscordUserID, payload.CreatorID); err != nil {
-		http.Error(w, `{"error": "Failed to subscribe"}`, http.StatusInternalServerError)
-		return
+
+	markets, creators, outcomes := sub.SubscribedMarkets, sub.SubscribedCreators, sub.SubscribedOutcomes
+	marketID, creator := r.URL.Query().Get("market_id"), r.URL.Query().Get("creator")
+	if marketID != "" || creator != "" {
+		markets, creators, outcomes = []string{}, []string{}, []models.OutcomeSubscription{}
+		for _, market := range sub.SubscribedMarkets {
+			if market == marketID {
+				markets = append(markets, market)
+			}
+		}
+		for _, name := range sub.SubscribedCreators {
+			if name == creator {
+				creators = append(creators, name)
+			}
+		}
+		for _, outcome := range sub.SubscribedOutcomes {
+			if outcome.MarketID == marketID {
+				outcomes = append(outcomes, outcome)
+			}
+		}
+	}
+
+	resp := UserSubscriptionsResponse{
+		Markets:    paginate(markets, p),
+		Creators:   paginate(creators, p),
+		Outcomes:   paginate(outcomes, p),
+		Total:      UserSubscriptionTotals{Markets: len(markets), Creators: len(creators), Outcomes: len(outcomes)},
+		NextCursor: p.next(max(len(markets), len(creators), len(outcomes))),
 	}
+	b, _ := json.Marshal(resp)
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	w.Write(b)
 }
 
-func (h *WebhookHandler) HandleUnsubscribeCreator(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
+func (h *WebhookHandler) HandleChannelFeedNewMarkets(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	var payload struct {
-		DiscordUserID string `json:"discord_user_id"`
-		CreatorID     string `json:"creator_id"`
-	}
+	var payload ChannelFeedNewMarketsRequest
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
-	if err := h.subscriptionService.UnsubscribeFromCreator(payload.DiscordUserID, payload.CreatorID); err != nil {
-		http.Error(w, `{"error": "Failed to unsubscribe"}`, http.StatusInternalServerError)
+	if payload.Enabled && !h.checkFeedPermissions(w, r, payload.ChannelID) {
+		return
+	}
+	cfg, err := h.subscriptionService.GetChannelConfig(r.Context(), payload.ChannelID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to load config")
+		return
+	}
+	cfg.ChannelID = payload.ChannelID
+	cfg.FeedEnabled = payload.Enabled
+	cfg.LastUpdateTimestamp = time.Now()
+	if err := h.subscriptionService.UpdateChannelConfig(r.Context(), cfg, apiActor(r)); err != nil {
+		writeServiceError(w, err, "Failed to save config")
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
-func (h *WebhookHandler) HandleGetUserSubscriptions(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+func (h *WebhookHandler) HandleChannelFeedCategories(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	if r.Method != http.MethodGet {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+	var payload ChannelFeedCategoriesRequest
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	if len(parts) < 3 {
-		http.Error(w, `{"error": "discord_user_id required"}`, http.StatusBadRequest)
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
-	discordUserID := parts[len(parts)-1]
-	sub, err := h.subscriptionService.GetUserSubscriptions(discordUserID)
+	cfg, err := h.subscriptionService.GetChannelConfig(r.Context(), payload.ChannelID)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to get subscriptions"}`, http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to load config")
+		return
+	}
+	cfg.ChannelID = payload.ChannelID
+	cfg.AllowedCategories = payload.AllowedCategories
+	cfg.LastUpdateTimestamp = time.Now()
+	if err := h.subscriptionService.UpdateChannelConfig(r.Context(), cfg, apiActor(r)); err != nil {
+		writeServiceError(w, err, "Failed to save config")
 		return
 	}
-	resp := struct {
-		Markets  []string `json:"markets"`
-		Creators []string `json:"creators"`
-	}{Markets: sub.SubscribedMarkets, Creators: sub.SubscribedCreators}
-	b, _ := json.Marshal(resp)
-	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write(b)
 }
 
-func (h *WebhookHandler) HandleChannelFeedNewMarkets(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
+func (h *WebhookHandler) HandleChannelFeedFrequency(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	var payload struct {
-		ChannelID string `json:"channel_id"`
-		Enabled   bool   `json:"enabled"`
-	}
+	var payload ChannelFeedFrequencyRequest
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	frequency, interval, err := services.ParseFrequency(payload.Frequency, payload.Interval)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	cfg, err := h.subscriptionService.GetChannelConfig(payload.ChannelID)
+	cfg, err := h.subscriptionService.GetChannelConfig(r.Context(), payload.ChannelID)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to load config"}`, http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to load config")
 		return
 	}
 	cfg.ChannelID = payload.ChannelID
-	cfg.FeedEnabled = payload.Enabled
+	cfg.FrequencyMode = frequency
+	cfg.CustomInterval = interval
 	cfg.LastUpdateTimestamp = time.Now()
-	if err := h.subscriptionService.UpdateChannelConfig(cfg); err != nil {
-		http.Error(w, `{"error": "Failed to save config"}`, http.StatusInternalServerError)
+	if err := h.subscriptionService.UpdateChannelConfig(r.Context(), cfg, apiActor(r)); err != nil {
+		writeServiceError(w, err, "Failed to save config")
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
-func (h *WebhookHandler) HandleChannelFeedCategories(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
+func (h *WebhookHandler) HandleChannelSubscribeMarket(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	var payload struct {
-		ChannelID         string   `json:"channel_id"`
-		AllowedCategories []string `json:"allowed_categories"`
-	}
+	var payload ChannelMarketSubscriptionRequest
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
-	cfg, err := h.subscriptionService.GetChannelConfig(payload.ChannelID)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to load config"}`, http.StatusInternalServerError)
+	if payload.ChannelID == "" || payload.MarketID == "" {
+		writeJSONError(w, http.StatusBadRequest, "channel_id and market_id are required")
 		return
 	}
-	cfg.ChannelID = payload.ChannelID
-	cfg.AllowedCategories = payload.AllowedCategories
-	cfg.LastUpdateTimestamp = time.Now()
-	if err := h.subscriptionService.UpdateChannelConfig(cfg); err != nil {
-		http.Error(w, `{"error": "Failed to save config"}`, http.StatusInternalServerError)
+	if err := h.subscriptionService.SubscribeChannelToMarket(r.Context(), payload.ChannelID, payload.MarketID, apiActor(r)); err != nil {
+		writeServiceError(w, err, "Failed to subscribe")
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"subscribed": true}`))
 }
 
-func (h *WebhookHandler) HandleChannelFeedFrequency(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
+func (h *WebhookHandler) HandleChannelUnsubscribeMarket(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	var payload struct {
-		ChannelID string `json:"channel_id"`
-		Frequency string `json:"frequency"`
-	}
+	var payload ChannelMarketSubscriptionRequest
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
-	cfg, err := h.subscriptionService.GetChannelConfig(payload.ChannelID)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to load config"}`, http.StatusInternalServerError)
+	if payload.ChannelID == "" || payload.MarketID == "" {
+		writeJSONError(w, http.StatusBadRequest, "channel_id and market_id are required")
 		return
 	}
-	cfg.ChannelID = payload.ChannelID
-	cfg.FrequencyMode = payload.Frequency
-	cfg.LastUpdateTimestamp = time.Now()
-	if err := h.subscriptionService.UpdateChannelConfig(cfg); err != nil {
-		http.Error(w, `{"error": "Failed to save config"}`, http.StatusInternalServerError)
+	if err := h.subscriptionService.UnsubscribeChannelFromMarket(r.Context(), payload.ChannelID, payload.MarketID, apiActor(r)); err != nil {
+		writeServiceError(w, err, "Failed to unsubscribe")
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"subscribed": false}`))
 }
 
 func (h *WebhookHandler) HandleGetChannelSettings(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
 	if r.Method != http.MethodGet {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	if len(parts) < 4 {
-		http.Error(w, `{"error": "channel_id required"}`, http.StatusBadRequest)
+	channelID := r.PathValue("channel_id")
+	if channelID == "" {
+		writeJSONError(w, http.StatusBadRequest, "channel_id required")
 		return
 	}
-	channelID := parts[len(parts)-1]
-	cfg, err := h.subscriptionService.GetChannelConfig(channelID)
+	cfg, err := h.subscriptionService.GetChannelConfig(r.Context(), channelID)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to load config"}`, http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to load config")
 		return
 	}
 	b, _ := json.Marshal(cfg)
@@ -996,10 +636,26 @@ func (h *WebhookHandler) HandleGetChannelSettings(w http.ResponseWriter, r *http
 
 func (h *WebhookHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	resp := map[string]interface{}{"status": "ok", "time": time.Now().UTC()}
+	resp := HealthResponse{Status: "ok", Time: time.Now().UTC()}
+	if h.gateway != nil {
+		status := h.gateway.Status()
+		resp.Gateway = &status
+		if status.State != services.GatewayConnected {
+			resp.Status = "degraded"
+		}
+	}
+	if h.fanout != nil {
+		status := h.fanout.Status()
+		resp.Fanout = &status
+	}
+	if h.maintenance != nil {
+		status := h.maintenance.Status()
+		resp.Maintenance = &status
+	}
+	resp.Events.SuppressedAfterResolution = h.suppressedResolved.Load()
 	b, _ := json.Marshal(resp)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -1007,30 +663,22 @@ func (h *WebhookHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *WebhookHandler) HandleNotificationsDM(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
 	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 	if h.discordSession == nil {
-		http.Error(w, `{"error": "Discord not ready"}`, http.StatusServiceUnavailable)
+		writeJSONError(w, http.StatusServiceUnavailable, "Discord not ready")
 		return
 	}
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
-	var payload struct {
-		DiscordUserID string                 `json:"discord_user_id"`
-		Type          string                 `json:"type"`
-		Data          map[string]interface{} `json:"payload"`
-	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
+	var payload DirectNotificationRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 	var msg string
@@ -1040,33 +688,42 @@ func (h *WebhookHandler) HandleNotificationsDM(w http.ResponseWriter, r *http.Re
 		if v, ok := payload.Data["volume"].(float64); ok {
 			m.Volume = v
 		}
-		msg = h.marketService.CreateMarketUpdateMessage(&m)
+		msg = renderMessage(r.Context(), "MarketUpdateMessage", func() string { return h.marketService.CreateMarketUpdateMessage(&m, nil) })
 	case "trading_start":
 		m := models.Market{ID: toString(payload.Data["market_id"]), Title: toString(payload.Data["title"]), Description: toString(payload.Data["description"]), Link: toString(payload.Data["link"])}
-		msg = h.marketService.CreateTradingStartMessage(&m)
+		msg = renderMessage(r.Context(), "TradingStartMessage", func() string { return h.marketService.CreateTradingStartMessage(&m) })
 	case "trading_end":
 		m := models.Market{ID: toString(payload.Data["market_id"]), Title: toString(payload.Data["title"]), Link: toString(payload.Data["link"])}
-		msg = h.marketService.CreateTradingEndMessage(&m)
+		msg = renderMessage(r.Context(), "TradingEndMessage", func() string { return h.marketService.CreateTradingEndMessage(&m) })
 	case "market_resolved":
 		m := models.Market{ID: toString(payload.Data["market_id"]), Title: toString(payload.Data["title"]), ResolvedOutcome: toString(payload.Data["winning_outcome"]), Link: toString(payload.Data["link"])}
-		msg = h.marketService.CreateMarketResolutionMessage(&m)
+		msg = renderMessage(r.Context(), "MarketResolutionMessage", func() string { return h.marketService.CreateMarketResolutionMessage(&m) })
 	case "market_buy":
 		amt := toFloat(payload.Data["amount"])
-		msg = h.marketService.CreateMarketBuyMessage(toString(payload.Data["market_id"]), toString(payload.Data["title"]), amt, toString(payload.Data["outcome"]), toString(payload.Data["buyer"]), toString(payload.Data["link"]))
+		msg = renderMessage(r.Context(), "MarketBuyMessage", func() string {
+			return h.marketService.CreateMarketBuyMessage(toString(payload.Data["market_id"]), toString(payload.Data["title"]), amt, toString(payload.Data["outcome"]), toString(payload.Data["buyer"]), toString(payload.Data["link"]))
+		})
 	default:
 		msg = ""
 	}
 	if msg == "" {
-		http.Error(w, `{"error": "Unsupported type"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Unsupported type")
 		return
 	}
-	ch, err := h.discordSession.UserChannelCreate(payload.DiscordUserID)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to create DM channel"}`, http.StatusInternalServerError)
-		return
+	notification := &eventNotification{eventType: payload.Type, content: msg}
+	if subscription, err := h.subscriptionService.GetUserSubscriptions(r.Context(), payload.DiscordUserID); err == nil {
+		notification = notification.localized(subscription.Timezone)
 	}
-	if _, err := h.discordSession.ChannelMessageSend(ch.ID, msg); err != nil {
-		http.Error(w, `{"error": "Failed to send DM"}`, http.StatusInternalServerError)
+	err = h.deliver(r.Context(), notification.priority(), func(ctx context.Context) error {
+		if h.shadowMode {
+			h.recordShadow(ctx, notification, models.RecipientUser, payload.DiscordUserID)
+			return nil
+		}
+		return h.sendDirectNotification(ctx, payload.DiscordUserID, notification)
+	})
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to send DM to user %s: %v", payload.DiscordUserID, err))
+		writeServiceError(w, err, "Failed to send DM")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -1106,41 +763,35 @@ func toFloat(v interface{}) float64 {
 
 // HandleUnregisterWebhook handles DELETE /discord/webhooks/unregister
 func (h *WebhookHandler) HandleUnregisterWebhook(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
 
 	if r.Method != http.MethodDelete && r.Method != http.MethodPost {
 		// accept POST too to make testing simpler (some clients can't send DELETE easily)
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		h.logger.Error(fmt.Sprintf("Failed to read request body: %v", err))
-		http.Error(w, `{"error": "Failed to read request body"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
 
-	var payload struct {
-		ID string `json:"id"`
-	}
-	if err := json.Unmarshal(body, &payload); err != nil {
+	var payload UnregisterWebhookRequest
+	if err := decodePayload(r.Context(), body, &payload); err != nil {
 		h.logger.Error(fmt.Sprintf("Failed to parse JSON: %v", err))
-		http.Error(w, `{"error": "Invalid JSON"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 
 	if payload.ID == "" {
-		http.Error(w, `{"error": "id is required"}`, http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "id is required")
 		return
 	}
 
-	if err := h.subscriptionService.UnregisterWebhook(payload.ID); err != nil {
+	if err := h.subscriptionService.UnregisterWebhook(r.Context(), payload.ID, apiActor(r)); err != nil {
 		h.logger.Error(fmt.Sprintf("Failed to unregister webhook: %v", err))
-		http.Error(w, `{"error": "Failed to unregister webhook"}`, http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to unregister webhook")
 		return
 	}
 
@@ -1149,25 +800,68 @@ func (h *WebhookHandler) HandleUnregisterWebhook(w http.ResponseWriter, r *http.
 	w.Write([]byte(`{"ok": true}`))
 }
 
+// webhookReceives reports whether a registration asked for an event type; one without any asked for all
+func webhookReceives(registration *models.WebhookRegistration, event string) bool {
+	if len(registration.Events) == 0 {
+		return true
+	}
+	for _, registered := range registration.Events {
+		if registered == event {
+			return true
+		}
+	}
+	return false
+}
+
 // HandleListWebhooks handles GET /discord/webhooks
+//
+// Registrations are listed oldest first, filtered with ?channel_id=<id> and ?event=<type> and paged
+// with ?limit=<n> and ?cursor=<next_cursor>. The body stays a plain array, so the total and the next
+// cursor are sent in the X-Total-Count and X-Next-Cursor headers.
 func (h *WebhookHandler) HandleListWebhooks(w http.ResponseWriter, r *http.Request) {
-	if !h.AuthOk(r) {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
 
 	if r.Method != http.MethodGet {
-		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	p, err := parsePage(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	regs, err := h.subscriptionService.ListWebhookRegistrations()
+	var regs []*models.WebhookRegistration
+	if channelID := r.URL.Query().Get("channel_id"); channelID != "" {
+		regs, err = h.subscriptionService.ListWebhookRegistrationsByChannel(r.Context(), channelID)
+	} else {
+		regs, err = h.subscriptionService.ListWebhookRegistrations(r.Context())
+	}
 	if err != nil {
 		h.logger.Error(fmt.Sprintf("Failed to list webhook registrations: %v", err))
-		http.Error(w, `{"error": "Failed to list"}`, http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to list")
 		return
 	}
+	if event := r.URL.Query().Get("event"); event != "" {
+		matching := []*models.WebhookRegistration{}
+		for _, reg := range regs {
+			if webhookReceives(reg, event) {
+				matching = append(matching, reg)
+			}
+		}
+		regs = matching
+	}
+	sort.SliceStable(regs, func(i, j int) bool {
+		if !regs[i].CreatedAt.Equal(regs[j].CreatedAt) {
+			return regs[i].CreatedAt.Before(regs[j].CreatedAt)
+		}
+		return regs[i].ID < regs[j].ID
+	})
 
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(regs)))
+	if next := p.next(len(regs)); next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
+	regs = paginate(regs, p)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if b, err := json.Marshal(regs); err == nil {
@@ -1176,48 +870,3 @@ func (h *WebhookHandler) HandleListWebhooks(w http.ResponseWriter, r *http.Reque
 		w.Write([]byte("[]"))
 	}
 }
-
-// StartWebServer starts the webhook server
-func (h *WebhookHandler) StartWebServer(port string) {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/webhooks/new_market", h.HandleNewMarket)
-	mux.HandleFunc("/webhooks/market_update", h.HandleMarketUpdate)
-	mux.HandleFunc("/webhooks/trading_started", h.HandleTradingStarted)
-	mux.HandleFunc("/webhooks/trading_ended", h.HandleTradingEnded)
-	mux.HandleFunc("/webhooks/market_resolved", h.HandleMarketResolved)
-
-	// Admin endpoints for managing Discord webhook registrations
-	mux.HandleFunc("/discord/webhooks/register", h.HandleRegisterWebhook)
-	mux.HandleFunc("/discord/webhooks/unregister", h.HandleUnregisterWebhook)
-	mux.HandleFunc("/discord/webhooks", h.HandleListWebhooks)
-
-	mux.HandleFunc("/discord/webhooks/", h.HandleUnregisterWebhookByPath)
-
-	mux.HandleFunc("/discord/events/new-market", h.HandleEventNewMarket)
-	mux.HandleFunc("/discord/events/market-update", h.HandleEventMarketUpdate)
-	mux.HandleFunc("/discord/events/trading-start", h.HandleEventTradingStart)
-	mux.HandleFunc("/discord/events/trading-end", h.HandleEventTradingEnd)
-	mux.HandleFunc("/discord/events/market-resolved", h.HandleEventMarketResolved)
-	mux.HandleFunc("/discord/events/market-buy", h.HandleEventMarketBuy)
-
-	mux.HandleFunc("/discord/notifications/dm", h.HandleNotificationsDM)
-
-	mux.HandleFunc("/discord/subscribe/market", h.HandleSubscribeMarket)
-	mux.HandleFunc("/discord/unsubscribe/market", h.HandleUnsubscribeMarket)
-	mux.HandleFunc("/discord/subscribe/creator", h.HandleSubscribeCreator)
-	mux.HandleFunc("/discord/unsubscribe/creator", h.HandleUnsubscribeCreator)
-	mux.HandleFunc("/discord/subscriptions/", h.HandleGetUserSubscriptions)
-
-	mux.HandleFunc("/discord/channel/feed/new_markets", h.HandleChannelFeedNewMarkets)
-	mux.HandleFunc("/discord/channel/feed/categories", h.HandleChannelFeedCategories)
-	mux.HandleFunc("/discord/channel/feed/frequency", h.HandleChannelFeedFrequency)
-	mux.HandleFunc("/discord/channel/settings/", h.HandleGetChannelSettings)
-
-	mux.HandleFunc("/discord/health", h.HandleHealth)
-
-	h.logger.Info(fmt.Sprintf("Starting webhook server on port %s", port))
-	err := http.ListenAndServe(":"+port, mux)
-	if err != nil {
-		h.logger.Error(fmt.Sprintf("Failed to start webhook server: %v", err))
-	}
-}
@@ -0,0 +1,78 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// webhookPingTimeout bounds the Discord calls testing a webhook while its registration waits
+const webhookPingTimeout = 10 * time.Second
+
+// webhookPingContent is the test message posted through a webhook when it is registered
+const webhookPingContent = "✅ This channel will receive Coral market alerts through this webhook."
+
+// webhookChannelError is returned when a webhook posts to another channel than the one it is registered for
+type webhookChannelError struct {
+	WebhookChannelID string
+}
+
+func (e *webhookChannelError) Error() string {
+	return fmt.Sprintf("the webhook posts to channel %s", e.WebhookChannelID)
+}
+
+// SetWebhookTestPing sets whether webhooks are tested with a message when they are registered, unless
+// the registration says otherwise
+func (h *WebhookHandler) SetWebhookTestPing(enabled bool) {
+	h.webhookTestPing = enabled
+}
+
+// pingWebhook checks that a webhook exists and posts to channelID, then posts a test message through it
+func (h *WebhookHandler) pingWebhook(ctx context.Context, channelID, webhookID, token string) error {
+	if h.discordSession == nil {
+		return ErrDiscordSessionNotSet
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookPingTimeout)
+	defer cancel()
+
+	webhook, err := h.discordSession.WebhookWithToken(webhookID, token, discordgo.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	if webhook.ChannelID != channelID {
+		return &webhookChannelError{WebhookChannelID: webhook.ChannelID}
+	}
+	_, err = h.discordSession.WebhookExecute(webhookID, token, true, &discordgo.WebhookParams{
+		Content:         webhookPingContent,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}, discordgo.WithContext(ctx))
+	return err
+}
+
+// mapWebhookPingError maps a failed test ping to its response: webhooks Discord rejects or that post
+// elsewhere are the client's to fix, Discord being unreachable is not
+func mapWebhookPingError(err error) apiError {
+	var channelErr *webhookChannelError
+	var restErr *discordgo.RESTError
+	switch {
+	case errors.As(err, &channelErr):
+		return apiError{status: http.StatusBadRequest, code: ErrorCodeInvalidRequest,
+			message: channelErr.Error() + ", not channel_id",
+			details: map[string]interface{}{"webhook_channel_id": channelErr.WebhookChannelID}}
+	case errors.As(err, &restErr) && restErr.Response != nil && restErr.Response.StatusCode < http.StatusInternalServerError:
+		details := map[string]interface{}{"discord_status": restErr.Response.StatusCode}
+		message := "Discord rejected the test ping"
+		if restErr.Message != nil {
+			details["discord_code"] = restErr.Message.Code
+			message += ": " + restErr.Message.Message
+		}
+		return apiError{status: http.StatusBadRequest, code: ErrorCodeInvalidRequest, message: message, details: details}
+	case errors.Is(err, ErrDiscordSessionNotSet), errors.Is(err, context.DeadlineExceeded):
+		return mapServiceError(err, "Failed to test the webhook")
+	}
+	return apiError{status: http.StatusBadGateway, code: ErrorCodeUpstreamFailed, message: "Failed to reach Discord to test the webhook"}
+}
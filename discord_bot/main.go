@@ -1,15 +1,23 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"coral-bot/discord_bot/internal/bus"
 	"coral-bot/discord_bot/internal/config"
+	"coral-bot/discord_bot/internal/grpcapi"
 	"coral-bot/discord_bot/internal/handlers"
+	"coral-bot/discord_bot/internal/models"
 	"coral-bot/discord_bot/internal/repository"
 	"coral-bot/discord_bot/internal/services"
+	"coral-bot/discord_bot/internal/tracing"
 	"coral-bot/discord_bot/internal/utils"
 	"coral-bot/discord_bot/internal/web"
 
@@ -17,34 +25,333 @@ import (
 )
 
 func main() {
+    loadTest := flag.Bool("loadtest", false, "deliver synthetic events to simulated channels and users, print a report and exit")
+    loadTestChannels := flag.Int("loadtest-channels", 1000, "simulated feed channels of the load test")
+    loadTestUsers := flag.Int("loadtest-users", 10000, "simulated subscribed users of the load test")
+    loadTestEvents := flag.Int("loadtest-events", 100, "synthetic events sent by the load test")
+    loadTestLatency := flag.Duration("loadtest-latency", 0, "time the simulated Discord API takes per request")
+    loadTestWorkers := flag.Int("loadtest-workers", services.DefaultFanoutWorkers, "fan-out workers of the load test, 0 sends one message at a time")
+    flag.Parse()
+    if *loadTest {
+        report, err := web.RunLoadTest(context.Background(), web.LoadTestOptions{
+            Channels:    *loadTestChannels,
+            Users:       *loadTestUsers,
+            Events:      *loadTestEvents,
+            SendLatency: *loadTestLatency,
+            Workers:     *loadTestWorkers,
+        }, utils.NewQuietLogger())
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Load test failed: %v\n", err)
+            os.Exit(1)
+        }
+        encoder := json.NewEncoder(os.Stdout)
+        encoder.SetIndent("", "  ")
+        encoder.Encode(report)
+        return
+    }
+
     appConfig := config.LoadConfig()
 
 	logger := utils.NewLogger()
 	logger.Info("Starting Coral Markets Discord Bot")
 
-    subscriptionRepo := repository.NewInMemorySubscriptionRepository()
+    shutdownTracing, err := tracing.Setup(context.Background(), appConfig.TracingEnabled)
+    if err != nil {
+        logger.Error(fmt.Sprintf("Error setting up tracing: %v", err))
+        return
+    }
+    if appConfig.TracingEnabled {
+        logger.Info("Exporting traces over OTLP")
+    }
 
+    var storage repository.SubscriptionRepository = repository.NewInMemorySubscriptionRepository()
+    var fileStorage *repository.FileSubscriptionRepository
+    if appConfig.StorageDriver == "file" {
+        fileStorage, err = repository.NewFileSubscriptionRepository(appConfig.StoragePath, logger)
+        if err != nil {
+            logger.Error(fmt.Sprintf("Error loading data: %v", err))
+            return
+        }
+        storage = fileStorage
+        logger.Info(fmt.Sprintf("Saving data to %s every %s", appConfig.StoragePath, appConfig.StorageInterval))
+    }
+    var subscriptionRepo repository.SubscriptionRepository = repository.NewTracedSubscriptionRepository(storage)
+    if appConfig.StorageCache {
+        subscriptionRepo = repository.NewCachedSubscriptionRepository(subscriptionRepo, appConfig.StorageCacheTTL)
+    }
+
+    if appConfig.CoralBackendURL == "" {
+        logger.Warning("CORAL_BACKEND_URL is not set; market lookups, reminders and charts will fail")
+    }
     marketService := services.NewMarketService(appConfig.CoralBackendURL, logger)
+    marketService.SetUpdateIntervals(models.UpdateIntervals{
+        High:                appConfig.UpdateHigh,
+        Medium:              appConfig.UpdateMedium,
+        Low:                 appConfig.UpdateLow,
+        ClosingSoonWindow:   appConfig.UpdateClosingSoon,
+        ClosingSoonInterval: appConfig.UpdateClosing,
+    })
+    marketService.SetLinkDomains(appConfig.LinkDomains)
     subscriptionService := services.NewSubscriptionService(subscriptionRepo, logger)
-
-	commandHandler := handlers.NewCommandHandler(marketService, subscriptionService, logger)
-
-	webhookHandler := web.NewWebhookHandler(marketService, subscriptionService, logger)
+    subscriptionService.SetSubscriptionLimits(models.SubscriptionLimits{
+        MaxMarkets:  appConfig.MaxUserMarkets,
+        MaxCreators: appConfig.MaxUserCreators,
+        MaxOutcomes: appConfig.MaxUserOutcomes,
+        MaxChannels: appConfig.MaxGuildChannels,
+    })
 
     discordSession, err := discordgo.New("Bot " + appConfig.DiscordBotToken)
     if err != nil {
         logger.Error(fmt.Sprintf("Error creating Discord session: %v", err))
         return
     }
+	// Channel types and the bot's channel permissions, checked before crossposting, come from the
+	// guild state kept up to date by the Guilds intent
+	discordSession.Identify.Intents |= discordgo.IntentsGuilds
+	if appConfig.PrefixCommands {
+		// Reading message commands needs the privileged intent, enabled in the developer portal
+		discordSession.Identify.Intents |= discordgo.IntentsGuildMessages | discordgo.IntentsDirectMessages | discordgo.IntentMessageContent
+	}
+	// Channels configured over the API are saved with their guild, so they replace the guild's default channel
+	subscriptionService.SetChannelGuildResolver(services.NewDiscordChannelGuilds(discordSession))
+
+	// Without the gateway there is nothing to monitor, and messages are always sent right away
+	var gateway *services.GatewayMonitor
+	if appConfig.GatewayEnabled {
+		gateway = services.NewGatewayMonitor(appConfig.GatewayBufferSize, logger)
+		gateway.Register(discordSession)
+	}
+
+	// Market links carry click tracking only when UTM parameters or the shortener are configured
+	var linkDecorator *services.LinkDecorator
+	if appConfig.LinkUTM || appConfig.LinkShortenerURL != "" {
+		linkDecorator = services.NewLinkDecorator(logger)
+		linkDecorator.SetLinkDomains(appConfig.LinkDomains)
+		linkDecorator.SetUTM(appConfig.LinkUTM, appConfig.LinkUTMCampaign)
+		linkDecorator.SetShortener(appConfig.LinkShortenerURL, appConfig.LinkShortenerKey)
+	}
+
+	// During maintenance, started here or at runtime by a bot owner, notifications are held until it ends
+	maintenance := services.NewMaintenanceMode(appConfig.MaintenanceBuffer, logger)
+	if appConfig.MaintenanceMode {
+		maintenance.Start(appConfig.MaintenanceNotice)
+	}
+
+	discordNotifier := services.NewDiscordNotifier(discordSession, gateway)
+	discordNotifier.SetLinkDecorator(linkDecorator)
+	// In shadow mode, for the whole bot or a channel, messages are logged instead of sent
+	notifier := services.NewMaintenanceNotifier(maintenance, services.NewShadowNotifier(subscriptionRepo, discordNotifier, appConfig.ShadowMode, logger))
+	if appConfig.ShadowMode {
+		logger.Warning("Shadow mode is on: messages are logged instead of sent")
+	}
+
+	// Users who link a Coral account can get their notifications pushed through the backend
+	var pushNotifier *services.PushNotifier
+	var directNotifier services.Notifier = notifier
+	if appConfig.PushEnabled && appConfig.CoralBackendURL != "" {
+		pushNotifier = services.NewPushNotifier(subscriptionRepo, appConfig.CoralBackendURL, appConfig.PushToken, notifier, logger)
+		directNotifier = services.NewMaintenanceNotifier(maintenance, services.NewShadowNotifier(subscriptionRepo, pushNotifier, appConfig.ShadowMode, logger))
+	}
+	reminderService := services.NewReminderService(subscriptionRepo, marketService, directNotifier, logger)
+	jobScheduler := services.NewSchedulerService(subscriptionRepo, logger)
+	reminderService.SetScheduler(jobScheduler)
+
+	analyticsService := services.NewAnalyticsService(subscriptionRepo, logger)
+	analyticsService.SetRetention(appConfig.AnalyticsMaxAge)
+
+	userDataService := services.NewUserDataService(subscriptionRepo, logger)
+
+	deadLetterService := services.NewDeadLetterService(subscriptionRepo, logger)
+
+	quietHoursService := services.NewQuietHoursService(subscriptionRepo, notifier, logger)
+	quietHoursService.SetLinkDomains(appConfig.LinkDomains)
+
+	commandHandler := handlers.NewCommandHandler(marketService, subscriptionService, reminderService, analyticsService, logger)
+	commandHandler.SetUserDataService(userDataService)
+	commandHandler.SetDeadLetterService(deadLetterService)
+	commandHandler.SetOwners(appConfig.BotOwnerIDs)
+	commandHandler.SetCommandGuild(appConfig.CommandGuildID)
+	commandHandler.SetLinkDecorator(linkDecorator)
+	if appConfig.CoralBackendURL != "" {
+		commandHandler.SetCategoryCatalog(services.NewCategoryCatalog(marketService, services.CategoryCacheTTL, logger))
+	}
+
+	webhookHandler := web.NewWebhookHandler(marketService, subscriptionService, logger)
+	webhookHandler.SetAnalyticsService(analyticsService)
+	webhookHandler.SetOutboxService(services.NewOutboxService(subscriptionRepo, logger))
+	webhookHandler.SetDeliveryReportService(services.NewDeliveryReportService(subscriptionRepo, logger))
+	webhookHandler.SetAPIKeyService(services.NewAPIKeyService(subscriptionRepo, logger))
+	webhookHandler.SetUserDataService(userDataService)
+	webhookHandler.SetStorageMigrationService(services.NewStorageMigrationService(subscriptionRepo, logger))
+	webhookHandler.SetDeadLetterService(deadLetterService)
+	webhookHandler.SetQuietHoursService(quietHoursService)
+	webhookHandler.SetMarketAnnouncementService(services.NewMarketAnnouncementService(subscriptionRepo, logger))
+	eventRegistry := services.NewEventRegistry()
+	if err := registerCustomEvents(eventRegistry); err != nil {
+		logger.Error(fmt.Sprintf("Failed to register custom events: %v", err))
+		return
+	}
+	webhookHandler.SetEventRegistry(eventRegistry)
+	httpLog, err := services.NewHTTPLogService(subscriptionRepo, services.HTTPLogConfig{
+		SampleRate:   appConfig.HTTPLogSampleRate,
+		Endpoints:    appConfig.HTTPLogEndpoints,
+		Capacity:     appConfig.HTTPLogCapacity,
+		MaxBodyBytes: appConfig.HTTPLogBodyBytes,
+	}, logger)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Invalid HTTP log settings: %v", err))
+		return
+	}
+	webhookHandler.SetHTTPLogService(httpLog)
+	if appConfig.DiscordPublicKey != "" {
+		if err := webhookHandler.SetInteractionHandler(commandHandler, appConfig.DiscordPublicKey); err != nil {
+			logger.Error(fmt.Sprintf("Invalid DISCORD_PUBLIC_KEY: %v", err))
+			return
+		}
+		logger.Info("Receiving interactions over HTTP at /discord/interactions")
+	}
+
+	// Boards list markets from the backend, like digests
+	var boardService *services.MarketBoardServiceImpl
+	if appConfig.CoralBackendURL != "" {
+		boardMessenger := services.NewDiscordBoardMessenger(discordSession)
+		boardMessenger.SetLinkDecorator(linkDecorator)
+		boardService = services.NewMarketBoardService(subscriptionRepo, marketService, boardMessenger, logger)
+		boardService.SetLinkDomains(appConfig.LinkDomains)
+		commandHandler.SetMarketBoardService(boardService)
+		webhookHandler.SetMarketBoardService(boardService)
+	}
+
+	// Themed digests group the backend's markets by the calendar events it tags them to
+	var calendarService *services.CalendarServiceImpl
+	if appConfig.CalendarEnabled && appConfig.CoralBackendURL != "" {
+		calendarService = services.NewCalendarService(subscriptionRepo, marketService, notifier, appConfig.CalendarLead, logger)
+		calendarService.SetLinkDomains(appConfig.LinkDomains)
+		commandHandler.SetCalendarService(calendarService)
+	}
+
+	// Accounts are linked on Coral, which calls POST /discord/link/confirm with the user's code, and
+	// their positions come from the backend
+	if appConfig.CoralBackendURL != "" {
+		accountService := services.NewAccountService(subscriptionRepo, logger)
+		commandHandler.SetAccountService(accountService)
+		webhookHandler.SetAccountService(accountService)
+
+		// Guilds turn the buy buttons on with /trading_buttons; orders are placed for the linked account
+		if appConfig.TradingEnabled {
+			commandHandler.SetTradingService(services.NewTradingService(accountService, appConfig.CoralBackendURL, appConfig.TradingToken, logger))
+			webhookHandler.SetTradingEnabled(true)
+		}
+	}
+
+	// Channels catch up on the markets created before they were set up with /channel_backfill, skipping
+	// the markets already announced in them
+	if appConfig.CoralBackendURL != "" {
+		backfillService := services.NewChannelBackfillService(subscriptionRepo, marketService, notifier, logger)
+		backfillService.SetLinkDomains(appConfig.LinkDomains)
+		commandHandler.SetChannelBackfillService(backfillService)
+	}
+
+	// Guilds start the prediction game with /game_mode; bets are settled when their markets resolve
+	if appConfig.GameEnabled {
+		gameService := services.NewGameService(subscriptionRepo, appConfig.GameBalance, logger)
+		commandHandler.SetGameService(gameService)
+		webhookHandler.SetGameService(gameService)
+	}
+
+	// Guilds get their community stats with /community_stats, on the schedule of weekly digests
+	var statsService *services.CommunityStatsServiceImpl
+	if statsSchedule, err := services.ParseDigestSchedule(appConfig.DigestTime, appConfig.DigestTimezone, appConfig.DigestWeekday); err == nil {
+		statsService = services.NewCommunityStatsService(subscriptionRepo, notifier, statsSchedule, logger)
+		statsService.SetLinkDomains(appConfig.LinkDomains)
+		if appConfig.CoralBackendURL != "" {
+			statsService.SetMarketService(marketService)
+		}
+		commandHandler.SetCommunityStatsService(statsService)
+	}
+	if pushNotifier != nil {
+		commandHandler.SetPushEnabled(true)
+		webhookHandler.SetPushNotifier(pushNotifier)
+	}
+
+	// Users can link an email address only when a mail server is configured
+	var emailNotifier *services.EmailNotifier
+	if appConfig.SMTPHost != "" && appConfig.SMTPFrom != "" {
+		mailer := services.NewSMTPMailer(appConfig.SMTPHost, appConfig.SMTPPort, appConfig.SMTPUsername, appConfig.SMTPPassword, appConfig.SMTPFrom)
+		emailNotifier = services.NewEmailNotifier(subscriptionRepo, mailer, notifier)
+		commandHandler.SetEmailService(services.NewEmailService(subscriptionRepo, mailer, logger))
+		webhookHandler.SetEmailNotifier(emailNotifier)
+	}
+	webhookHandler.SetBuyThresholds(appConfig.MinBuyAmount, appConfig.WhaleBuyAmount)
+	webhookHandler.SetShadowMode(appConfig.ShadowMode)
+	webhookHandler.SetWebhookTestPing(appConfig.WebhookTestPing)
+	webhookHandler.SetTLS(appConfig.TLSCertFile, appConfig.TLSKeyFile)
+	webhookHandler.SetLinkDecorator(linkDecorator)
+	webhookHandler.SetRateLimit(appConfig.RateLimit)
+	if err := webhookHandler.SetTrustedProxies(appConfig.TrustedProxies); err != nil {
+		logger.Error(fmt.Sprintf("Invalid TRUSTED_PROXIES: %v", err))
+		return
+	}
+	if err := webhookHandler.SetIngestAllowlist(appConfig.IngestAllowlist); err != nil {
+		logger.Error(fmt.Sprintf("Invalid INGEST_ALLOWED_IPS: %v", err))
+		return
+	}
+	rootCredentials := web.RootCredentials{
+		APIKey:     appConfig.CoralAPIKey,
+		NextAPIKey: appConfig.CoralAPIKeyNext,
+		Token:      appConfig.CoralToken,
+		NextToken:  appConfig.CoralTokenNext,
+	}
+	webhookHandler.SetRootCredentials(rootCredentials)
+
+	var eventPublisher *services.BusEventPublisher
+	if appConfig.BusDriver != "" && appConfig.BusPublishTopic != "" {
+		writer, err := bus.NewWriter(bus.Config{Driver: appConfig.BusDriver, URL: appConfig.BusURL, Topic: appConfig.BusPublishTopic})
+		if err != nil {
+			logger.Error(fmt.Sprintf("Invalid message bus configuration: %v", err))
+		} else {
+			eventPublisher = services.NewBusEventPublisher(writer, logger)
+			webhookHandler.SetEventPublisher(eventPublisher)
+			logger.Info(fmt.Sprintf("Publishing processed events to %s", writer.Name()))
+		}
+	}
 
     discordSession.AddHandler(commandHandler.HandleInteraction)
+	if appConfig.PrefixCommands {
+		if appConfig.GatewayEnabled {
+			commandHandler.SetCommandPrefix(appConfig.CommandPrefix)
+			discordSession.AddHandler(commandHandler.HandleMessageCreate)
+			logger.Info(fmt.Sprintf("Running message commands starting with %s", appConfig.CommandPrefix))
+		} else {
+			logger.Warning("PREFIX_COMMANDS needs the gateway, message commands are off")
+		}
+	}
+    handlers.NewGuildEventHandler(subscriptionService, logger).Register(discordSession)
 
     webhookHandler.SetDiscordSession(discordSession)
+    webhookHandler.SetGateway(gateway)
+    webhookHandler.SetMaintenanceMode(maintenance)
+    webhookHandler.SetFanoutPool(services.NewFanoutPool(appConfig.FanoutWorkers, appConfig.FanoutQueueSize))
+    webhookHandler.SetUpdateCoalescing(appConfig.UpdateCoalesce)
+    commandHandler.SetTestEventSender(webhookHandler)
+    commandHandler.SetMaintenanceMode(maintenance)
+    webhookHandler.SetCommandSyncer(commandHandler)
 
-    err = discordSession.Open()
-    if err != nil {
-        logger.Error(fmt.Sprintf("Error opening connection: %v", err))
-        return
+    if appConfig.GatewayEnabled {
+        err = discordSession.Open()
+        if err != nil {
+            logger.Error(fmt.Sprintf("Error opening connection: %v", err))
+            return
+        }
+    } else {
+        // Commands are synced for the bot's own application, which the gateway's ready event would provide
+        botUser, err := discordSession.User("@me")
+        if err != nil {
+            logger.Error(fmt.Sprintf("Error fetching the bot user: %v", err))
+            return
+        }
+        discordSession.State.User = botUser
+        logger.Info("Not connecting to the Discord gateway; commands arrive over HTTP only")
     }
 
     err = commandHandler.RegisterCommands(discordSession)
@@ -59,11 +366,130 @@ func main() {
 	}
 	go webhookHandler.StartWebServer(port)
 
+	var grpcServer *grpcapi.Server
+	if appConfig.GRPCPort != "" {
+		grpcServer = grpcapi.NewServer(webhookHandler, subscriptionService, logger)
+		grpcServer.SetReflection(appConfig.GRPCReflection)
+		go grpcServer.Start(appConfig.GRPCPort, appConfig.TLSCertFile, appConfig.TLSKeyFile)
+	}
+
+	schedulerCtx, stopSchedulers := context.WithCancel(context.Background())
+	if scheduled, err := reminderService.ScheduleStoredReminders(schedulerCtx); err != nil {
+		logger.Error(fmt.Sprintf("Failed to schedule stored reminders: %v", err))
+	} else if scheduled > 0 {
+		logger.Info(fmt.Sprintf("Scheduled %d stored reminders", scheduled))
+	}
+	go jobScheduler.Run(schedulerCtx, time.Minute)
+	go webhookHandler.RunOutboxWorker(schedulerCtx, time.Minute)
+	go webhookHandler.RunDeadLetterWorker(schedulerCtx, time.Minute)
+	go webhookHandler.RunUpdateCoalescing(schedulerCtx, time.Second)
+	go analyticsService.Run(schedulerCtx, time.Hour)
+	go quietHoursService.Run(schedulerCtx, time.Minute)
+	if appConfig.SecretsRefresh > 0 {
+		// The Discord token and the root API credentials are rotated in place; other secrets need a restart
+		rotator := services.NewSecretRotator(appConfig.SecretsProvider, appConfig.SecretValues(), logger)
+		rotator.Handle("DISCORD_BOT_TOKEN", func(ctx context.Context, token string) error {
+			return services.RotateDiscordToken(ctx, discordSession, token)
+		})
+		for name, field := range map[string]*string{
+			"CORAL_API_KEY":      &rootCredentials.APIKey,
+			"CORAL_API_KEY_NEXT": &rootCredentials.NextAPIKey,
+			"CORAL_TOKEN":        &rootCredentials.Token,
+			"CORAL_TOKEN_NEXT":   &rootCredentials.NextToken,
+		} {
+			rotator.Handle(name, func(ctx context.Context, value string) error {
+				*field = value
+				webhookHandler.SetRootCredentials(rootCredentials)
+				return nil
+			})
+		}
+		go rotator.Run(schedulerCtx, appConfig.SecretsRefresh)
+	}
+	if fileStorage != nil {
+		go fileStorage.Run(schedulerCtx, appConfig.StorageInterval)
+	}
+
+	if appConfig.BusDriver != "" && appConfig.BusTopic != "" {
+		reader, err := bus.NewReader(bus.Config{
+			Driver:     appConfig.BusDriver,
+			URL:        appConfig.BusURL,
+			Topic:      appConfig.BusTopic,
+			Group:      appConfig.BusGroup,
+			Stream:     appConfig.BusStream,
+			FetchBatch: appConfig.BusFetchBatch,
+		})
+		if err != nil {
+			logger.Error(fmt.Sprintf("Invalid message bus configuration: %v", err))
+		} else {
+			go bus.NewConsumer(reader, webhookHandler, subscriptionRepo, logger).Run(schedulerCtx)
+		}
+	}
+
+	if appConfig.CoralBackendURL != "" {
+		closingSoonService := services.NewClosingSoonService(subscriptionRepo, marketService, notifier, logger)
+		go closingSoonService.Run(schedulerCtx, time.Minute)
+	}
+
+	digestSchedule, err := services.ParseDigestSchedule(appConfig.DigestTime, appConfig.DigestTimezone, appConfig.DigestWeekday)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Invalid digest schedule: %v", err))
+	} else if appConfig.CoralBackendURL != "" {
+		digestService := services.NewDigestService(subscriptionRepo, marketService, notifier, digestSchedule, logger)
+		digestService.SetLinkDomains(appConfig.LinkDomains)
+		if emailNotifier != nil {
+			digestService.SetEmailNotifier(services.NewMaintenanceNotifier(maintenance, services.NewShadowNotifier(subscriptionRepo, emailNotifier, appConfig.ShadowMode, logger)))
+		}
+		go digestService.Run(schedulerCtx, time.Minute)
+	}
+
+	if boardService != nil {
+		go boardService.Run(schedulerCtx, appConfig.BoardInterval)
+	}
+
+	if calendarService != nil {
+		go calendarService.Run(schedulerCtx, time.Minute)
+	}
+
+	if statsService != nil {
+		go statsService.Run(schedulerCtx, time.Minute)
+	}
+
+	if appConfig.PresenceEnabled && appConfig.GatewayEnabled && appConfig.CoralBackendURL != "" {
+		presenceUpdater, err := services.NewPresenceUpdater(marketService, services.NewDiscordStatusUpdater(discordSession), appConfig.PresenceTemplate, logger)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Invalid PRESENCE_TEMPLATE: %v", err))
+		} else {
+			go presenceUpdater.Run(schedulerCtx, appConfig.PresenceInterval)
+		}
+	}
+
 	logger.Info("Coral Markets Discord Bot is now running. Press CTRL-C to exit.")
     shutdownSignal := make(chan os.Signal, 1)
     signal.Notify(shutdownSignal, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
     <-shutdownSignal
 
+    stopSchedulers()
+    if appConfig.UnregisterOnExit {
+        if err := commandHandler.UnregisterCommands(discordSession); err != nil {
+            logger.Warning(fmt.Sprintf("Failed to unregister commands: %v", err))
+        }
+    }
+    if grpcServer != nil {
+        grpcServer.Stop()
+    }
+    webhookHandler.FlushCoalescedUpdates()
+    webhookHandler.WaitForInteractions()
     discordSession.Close()
+    if eventPublisher != nil {
+        eventPublisher.Close()
+    }
+    if fileStorage != nil {
+        if err := fileStorage.Flush(); err != nil {
+            logger.Error(fmt.Sprintf("Failed to save data to %s: %v", appConfig.StoragePath, err))
+        }
+    }
+    if err := shutdownTracing(context.Background()); err != nil {
+        logger.Warning(fmt.Sprintf("Failed to flush traces: %v", err))
+    }
     logger.Info("Coral Markets Discord Bot stopped")
 }
@@ -0,0 +1,152 @@
+package tests
+
+import (
+    "context"
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestAdminAnalyticsAggregatesChurn(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    marketService := services.NewMockMarketService(logger)
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    analyticsService := services.NewAnalyticsService(repo, logger)
+    h := web.NewWebhookHandler(marketService, subscriptionService, logger)
+    h.SetAnalyticsService(analyticsService)
+
+    analyticsService.RecordCommand(ctx, "help", "u1")
+    analyticsService.RecordDelivery(ctx, models.EventNewMarket, "ch1", nil)
+
+    b, _ := json.Marshal(map[string]string{"discord_user_id": "u1", "market_id": "m1"})
+    h.HandleSubscribeMarket(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/discord/subscribe/market", bytes.NewBuffer(b)))
+    h.HandleUnsubscribeMarket(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/discord/unsubscribe/market", bytes.NewBuffer(b)))
+
+    rec := httptest.NewRecorder()
+    h.HandleAdminAnalytics(rec, httptest.NewRequest(http.MethodGet, "/discord/admin/analytics?window=1h", nil))
+    if rec.Code != http.StatusOK { t.Fatalf("expected %d got %d", http.StatusOK, rec.Code) }
+
+    var summary models.AnalyticsSummary
+    if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil { t.Fatalf("failed to decode summary: %v", err) }
+    if summary.Commands["help"] != 1 || summary.ActiveUsers != 1 {
+        t.Fatalf("unexpected command stats: %+v", summary)
+    }
+    if summary.Subscriptions["market"] != 1 || summary.Unsubscriptions["market"] != 1 {
+        t.Fatalf("unexpected churn: %+v", summary)
+    }
+    if summary.NotificationsSent[models.EventNewMarket] != 1 {
+        t.Fatalf("unexpected notifications: %+v", summary.NotificationsSent)
+    }
+
+    bad := httptest.NewRecorder()
+    h.HandleAdminAnalytics(bad, httptest.NewRequest(http.MethodGet, "/discord/admin/analytics?window=soon", nil))
+    if bad.Code != http.StatusBadRequest { t.Fatalf("expected %d got %d", http.StatusBadRequest, bad.Code) }
+}
+
+func TestAdminLeaderboardRanksSubscribers(t *testing.T) {
+    h := setupHandler()
+    for _, sub := range []map[string]string{
+        {"discord_user_id": "u1", "market_id": "m1"},
+        {"discord_user_id": "u2", "market_id": "m1"},
+        {"discord_user_id": "u2", "market_id": "m2"},
+    } {
+        b, _ := json.Marshal(sub)
+        h.HandleSubscribeMarket(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/discord/subscribe/market", bytes.NewBuffer(b)))
+    }
+
+    rec := httptest.NewRecorder()
+    h.HandleAdminLeaderboard(rec, httptest.NewRequest(http.MethodGet, "/discord/admin/leaderboard?limit=1", nil))
+    if rec.Code != http.StatusOK { t.Fatalf("expected %d got %d", http.StatusOK, rec.Code) }
+
+    var leaderboard models.Leaderboard
+    if err := json.Unmarshal(rec.Body.Bytes(), &leaderboard); err != nil { t.Fatalf("failed to decode leaderboard: %v", err) }
+    if len(leaderboard.Markets) != 1 || leaderboard.Markets[0].ID != "m1" || leaderboard.Markets[0].Subscribers != 2 {
+        t.Fatalf("unexpected market ranking: %+v", leaderboard.Markets)
+    }
+
+    bad := httptest.NewRecorder()
+    h.HandleAdminLeaderboard(bad, httptest.NewRequest(http.MethodGet, "/discord/admin/leaderboard?limit=0", nil))
+    if bad.Code != http.StatusBadRequest { t.Fatalf("expected %d got %d", http.StatusBadRequest, bad.Code) }
+}
+
+func TestAdminAuditRecordsChannelAndWebhookChanges(t *testing.T) {
+    h := setupHandler()
+
+    b, _ := json.Marshal(map[string]string{"channel_id": "c1", "market_id": "m1"})
+    h.HandleChannelSubscribeMarket(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/discord/channel/subscribe/market", bytes.NewBuffer(b)))
+
+    b, _ = json.Marshal(map[string]interface{}{"channel_id": "c1", "webhook_url": "https://discord.com/api/webhooks/1/token", "events": []string{"new_market"}})
+    regRec := httptest.NewRecorder()
+    h.HandleRegisterWebhook(regRec, httptest.NewRequest(http.MethodPost, "/discord/webhooks/register", bytes.NewBuffer(b)))
+    var created models.WebhookRegistration
+    if err := json.Unmarshal(regRec.Body.Bytes(), &created); err != nil { t.Fatalf("failed to decode registration: %v", err) }
+
+    b, _ = json.Marshal(map[string]string{"id": created.ID})
+    h.HandleUnregisterWebhook(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/discord/webhooks/unregister", bytes.NewBuffer(b)))
+
+    rec := httptest.NewRecorder()
+    h.HandleAdminAudit(rec, httptest.NewRequest(http.MethodGet, "/discord/admin/audit?channel_id=c1", nil))
+    if rec.Code != http.StatusOK { t.Fatalf("expected %d got %d", http.StatusOK, rec.Code) }
+
+    var resp struct {
+        Entries []models.AuditEntry `json:"entries"`
+    }
+    if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil { t.Fatalf("failed to decode audit log: %v", err) }
+    if len(resp.Entries) != 3 {
+        t.Fatalf("expected 3 audit entries, got %+v", resp.Entries)
+    }
+    if resp.Entries[0].Action != models.AuditActionDelete || resp.Entries[0].ResourceID != created.ID {
+        t.Fatalf("expected newest entry to be the webhook deletion, got %+v", resp.Entries[0])
+    }
+    update := resp.Entries[2]
+    if update.ResourceType != models.AuditResourceChannelConfig || update.Actor != "api" || fmt.Sprint(update.Changes) != "[feed_enabled subscribed_markets]" {
+        t.Fatalf("unexpected channel config entry: %+v", update)
+    }
+
+    bad := httptest.NewRecorder()
+    h.HandleAdminAudit(bad, httptest.NewRequest(http.MethodGet, "/discord/admin/audit?resource_type=user", nil))
+    if bad.Code != http.StatusBadRequest { t.Fatalf("expected %d got %d", http.StatusBadRequest, bad.Code) }
+}
+
+func TestAnalyticsEventsArePrunedAfterRetention(t *testing.T) {
+    ctx := context.Background()
+    repo := repository.NewInMemorySubscriptionRepository()
+    analyticsService := services.NewAnalyticsService(repo, utils.NewLogger())
+    clock := services.NewFakeClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+    analyticsService.SetClock(clock)
+
+    analyticsService.RecordCommand(ctx, "help", "u1")
+    clock.Advance(48 * time.Hour)
+    analyticsService.RecordCommand(ctx, "market", "u2")
+    analyticsService.RecordDelivery(ctx, models.EventNewMarket, "c1", nil)
+
+    if err := analyticsService.Prune(ctx, clock.Now().Add(-24*time.Hour)); err != nil { t.Fatalf("failed to prune: %v", err) }
+    events, _ := repo.GetAnalyticsEvents(ctx, time.Time{}, clock.Now().Add(time.Second))
+    if len(events) != 2 || events[0].Name != "market" { t.Fatalf("expected only the recent events kept, got %+v", events) }
+
+    // Run prunes the events older than the retention straight away
+    analyticsService.SetRetention(time.Hour)
+    clock.Advance(2 * time.Hour)
+    analyticsService.RecordCommand(ctx, "help", "u3")
+    runCtx, stop := context.WithCancel(ctx)
+    defer stop()
+    go analyticsService.Run(runCtx, time.Hour)
+    deadline := time.Now().Add(time.Second)
+    for len(events) != 1 && time.Now().Before(deadline) {
+        time.Sleep(5 * time.Millisecond)
+        events, _ = repo.GetAnalyticsEvents(ctx, time.Time{}, clock.Now().Add(time.Second))
+    }
+    if len(events) != 1 || events[0].Subject != "u3" { t.Fatalf("expected Run to keep only the last hour, got %+v", events) }
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestAdminSubscriptionsDumpsEveryUser(t *testing.T) {
+    h := setupHandler()
+    for _, sub := range []map[string]string{
+        {"discord_user_id": "u2", "market_id": "m1"},
+        {"discord_user_id": "u1", "market_id": "m2"},
+    } {
+        b, _ := json.Marshal(sub)
+        h.HandleSubscribeMarket(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/discord/subscribe/market", bytes.NewBuffer(b)))
+    }
+
+    rec := httptest.NewRecorder()
+    h.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/discord/admin/subscriptions", nil))
+    if rec.Code != http.StatusOK { t.Fatalf("expected %d got %d", http.StatusOK, rec.Code) }
+
+    var resp web.SubscriptionsResponse
+    if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil { t.Fatalf("failed to decode subscriptions: %v", err) }
+    if len(resp.Subscriptions) != 2 || resp.Subscriptions[0].DiscordUserID != "u1" || resp.Subscriptions[1].SubscribedMarkets[0] != "m1" {
+        t.Fatalf("expected both users sorted by ID, got %+v", resp.Subscriptions)
+    }
+}
+
+func TestAdminBroadcastTargetsFeedChannelsAndGuildDefaults(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "test")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c2", GuildID: "g1", FeedEnabled: false}, "test")
+    subscriptionService.UpdateGuildConfig(ctx, &models.GuildConfig{GuildID: "g1", DefaultChannelID: "g1-default"})
+    subscriptionService.UpdateGuildConfig(ctx, &models.GuildConfig{GuildID: "g2", DefaultChannelID: "g2-default"})
+
+    // A disconnected gateway buffers the sends, so nothing reaches Discord
+    session, _ := discordgo.New("Bot test")
+    h.SetDiscordSession(session)
+    gateway := services.NewGatewayMonitor(0, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+
+    rec := httptest.NewRecorder()
+    h.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/discord/admin/broadcast", strings.NewReader(`{"message": "Maintenance at 18:00 UTC"}`)))
+    if rec.Code != http.StatusAccepted { t.Fatalf("expected %d got %d: %s", http.StatusAccepted, rec.Code, rec.Body.String()) }
+
+    var resp web.BroadcastResponse
+    if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil { t.Fatalf("failed to decode response: %v", err) }
+    // c1 and the default channel of g2, which has no channel configuration
+    if !resp.Accepted || resp.Channels != 2 { t.Fatalf("expected two target channels, got %+v", resp) }
+    if buffered := gateway.Status().Buffered; buffered != 2 { t.Fatalf("expected two buffered messages, got %d", buffered) }
+}
+
+func TestAdminBroadcastValidation(t *testing.T) {
+    h := setupHandler()
+    for _, body := range []string{`{"message": "   "}`, `{"message": "` + strings.Repeat("x", 1901) + `"}`, `not json`} {
+        rec := httptest.NewRecorder()
+        h.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/discord/admin/broadcast", strings.NewReader(body)))
+        if rec.Code != http.StatusBadRequest { t.Fatalf("expected %d for %.20q, got %d", http.StatusBadRequest, body, rec.Code) }
+    }
+
+    t.Setenv("CORAL_API_KEY", "test-key")
+    rec := httptest.NewRecorder()
+    h.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/discord/admin/broadcast", strings.NewReader(`{"message": "hi"}`)))
+    if rec.Code != http.StatusUnauthorized { t.Fatalf("expected %d got %d", http.StatusUnauthorized, rec.Code) }
+}
+
+func TestAdminTestEventTargetsOneChannel(t *testing.T) {
+    logger := utils.NewLogger()
+    h := setupHandler()
+
+    rec := httptest.NewRecorder()
+    h.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/discord/admin/test-event", strings.NewReader(`{"channel_id": "c1"}`)))
+    if rec.Code != http.StatusServiceUnavailable { t.Fatalf("expected %d without a Discord session, got %d", http.StatusServiceUnavailable, rec.Code) }
+
+    // A disconnected gateway buffers the send, so nothing reaches Discord
+    session, _ := discordgo.New("Bot test")
+    h.SetDiscordSession(session)
+    gateway := services.NewGatewayMonitor(0, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+
+    // The channel has no configuration and its feed is off, but test events bypass channel settings
+    rec = httptest.NewRecorder()
+    h.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/discord/admin/test-event", strings.NewReader(`{"channel_id": "c1", "event_type": "market_update"}`)))
+    if rec.Code != http.StatusAccepted { t.Fatalf("expected %d got %d: %s", http.StatusAccepted, rec.Code, rec.Body.String()) }
+
+    var resp web.TestEventResponse
+    if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil { t.Fatalf("failed to decode response: %v", err) }
+    if !resp.Accepted || resp.EventType != models.EventMarketUpdate || resp.ChannelID != "c1" { t.Fatalf("unexpected response %+v", resp) }
+    if buffered := gateway.Status().Buffered; buffered != 1 { t.Fatalf("expected one buffered message, got %d", buffered) }
+
+    for _, body := range []string{`{"event_type": "new_market"}`, `{"channel_id": "c1", "event_type": "bogus"}`} {
+        bad := httptest.NewRecorder()
+        h.Handler().ServeHTTP(bad, httptest.NewRequest(http.MethodPost, "/discord/admin/test-event", strings.NewReader(body)))
+        if bad.Code != http.StatusBadRequest { t.Fatalf("expected %d for %s, got %d", http.StatusBadRequest, body, bad.Code) }
+    }
+}
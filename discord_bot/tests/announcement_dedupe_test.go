@@ -0,0 +1,52 @@
+package tests
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+)
+
+func TestNewMarketIsAnnouncedOncePerChannel(t *testing.T) {
+    h := newHarness(t)
+    h.handler.SetDeliveryReportService(services.NewDeliveryReportService(h.repo, utils.NewLogger()))
+    h.handler.SetMarketAnnouncementService(services.NewMarketAnnouncementService(h.repo, utils.NewLogger()))
+    h.feedChannel("c1", "g1", nil)
+    h.feedChannel("c2", "g1", func(config *models.ChannelConfig) { config.ShadowMode = true })
+
+    h.postEvent("new-market", newMarketEvent("m1", "sports", "alice", 5000))
+    legacy := `{"event_type": "new_market", "market": {"market_id": "m1", "title": "Market m1", "volume": 5000, "status": "active"}}`
+    if rec := serveWithKey(h.handler, http.MethodPost, "/webhooks/new_market", legacy, harnessAPIKey); rec.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String()) }
+    if len(h.discord.channelMessages("c1")) != 1 { t.Fatalf("expected the market announced once over both endpoints, got %+v", h.discord.messages) }
+
+    h.feedChannel("c3", "g1", nil)
+    report := postShadowedEvent(h, `{"market_id": "m1", "title": "Market m1", "volume": 5000}`)
+    if len(h.discord.channelMessages("c1")) != 1 || len(h.discord.channelMessages("c3")) != 1 { t.Fatalf("expected only the new channel to get the resent market, got %+v", h.discord.messages) }
+    for _, receipt := range report.Receipts {
+        if receipt.RecipientID == "c1" && (receipt.Status != models.DeliverySkipped || receipt.Reason != "the market was already announced in this channel") { t.Fatalf("expected c1 skipped as a repeat, got %+v", receipt) }
+        if receipt.RecipientID == "c2" && receipt.Status != models.DeliveryShadowed { t.Fatalf("expected shadowed announcements not to be remembered, got %+v", receipt) }
+    }
+
+    h.postEvent("new-market", newMarketEvent("m2", "sports", "alice", 5000))
+    if len(h.discord.channelMessages("c1")) != 2 { t.Fatalf("expected another market to be announced, got %+v", h.discord.messages) }
+}
+
+func TestMarketAnnouncementsExpire(t *testing.T) {
+    ctx := context.Background()
+    repo := repository.NewInMemorySubscriptionRepository()
+    clock := services.NewFakeClock(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+    announcements := services.NewMarketAnnouncementService(repo, utils.NewLogger())
+    announcements.SetClock(clock)
+
+    if claimed, err := announcements.Claim(ctx, "c1", "m1"); err != nil || !claimed { t.Fatalf("expected the first announcement claimed, got %v, %v", claimed, err) }
+    if claimed, _ := announcements.Claim(ctx, "c1", "m1"); claimed { t.Fatalf("expected a repeat in the same channel refused") }
+    if claimed, _ := announcements.Claim(ctx, "c2", "m1"); !claimed { t.Fatalf("expected another channel to announce the market") }
+
+    clock.Advance(services.MarketAnnouncementRetention + time.Hour)
+    if claimed, _ := announcements.Claim(ctx, "c1", "m1"); !claimed { t.Fatalf("expected the announcement forgotten after the retention") }
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestAPIKeyRotationKeepsBothKeysWorking(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    h, apiKeyService := setupAPIKeyHandler()
+    clock := services.NewFakeClock(time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC))
+    apiKeyService.SetClock(clock)
+    old, oldSecret, err := apiKeyService.CreateKey(context.Background(), "backend", []string{models.ScopeEventsWrite})
+    if err != nil { t.Fatalf("failed to create key: %v", err) }
+    event := `{"market_id": "m1", "title": "T", "outcome": "Yes", "amount": 5}`
+    clock.Advance(time.Hour)
+
+    rec := serveWithKey(h, http.MethodPost, "/discord/admin/api-keys/"+old.ID+"/rotate", `{"grace": "1d"}`, "root-key")
+    if rec.Code != http.StatusCreated { t.Fatalf("expected %d got %d: %s", http.StatusCreated, rec.Code, rec.Body.String()) }
+    var next web.CreateAPIKeyResponse
+    if err := json.Unmarshal(rec.Body.Bytes(), &next); err != nil { t.Fatalf("failed to decode key: %v", err) }
+    if next.APIKey.RotatedFrom != old.ID || next.APIKey.Name != "backend" || !next.APIKey.HasScope(models.ScopeEventsWrite) { t.Fatalf("expected the key's name and scopes carried over, got %+v", next.APIKey) }
+
+    // Both keys work during the grace period, and their use is recorded
+    for _, secret := range []string{oldSecret, next.Secret} {
+        if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-buy", event, secret); rec.Code != http.StatusAccepted { t.Fatalf("expected both keys accepted during the grace period, got %d", rec.Code) }
+    }
+    keys, _ := apiKeyService.ListKeys(context.Background())
+    if len(keys) != 2 || keys[0].ReplacedBy != next.APIKey.ID || keys[0].ExpiresAt == nil || keys[0].LastUsedAt == nil || keys[1].LastUsedAt == nil { t.Fatalf("unexpected keys after rotation %+v", keys) }
+
+    clock.Advance(25 * time.Hour)
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-buy", event, oldSecret); rec.Code != http.StatusUnauthorized { t.Fatalf("expected the old key refused after the grace period, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-buy", event, next.Secret); rec.Code != http.StatusAccepted { t.Fatalf("expected the new key accepted, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/admin/api-keys/"+old.ID+"/rotate", "", "root-key"); rec.Code != http.StatusConflict { t.Fatalf("expected an expired key not rotated, got %d", rec.Code) }
+
+    // Without a grace period the old key works until it is retired
+    rec = serveWithKey(h, http.MethodPost, "/discord/admin/api-keys/"+next.APIKey.ID+"/rotate", "", "root-key")
+    var third web.CreateAPIKeyResponse
+    json.Unmarshal(rec.Body.Bytes(), &third)
+    clock.Advance(30 * 24 * time.Hour)
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-buy", event, next.Secret); rec.Code != http.StatusAccepted { t.Fatalf("expected the rotated key accepted until retired, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodDelete, "/discord/admin/api-keys/"+next.APIKey.ID+"?grace=2h", "", "root-key"); rec.Code != http.StatusNoContent { t.Fatalf("expected %d got %d", http.StatusNoContent, rec.Code) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-buy", event, next.Secret); rec.Code != http.StatusAccepted { t.Fatalf("expected the key accepted until its grace period ends, got %d", rec.Code) }
+    clock.Advance(2 * time.Hour)
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-buy", event, next.Secret); rec.Code != http.StatusUnauthorized { t.Fatalf("expected the retired key refused, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-buy", event, third.Secret); rec.Code != http.StatusAccepted { t.Fatalf("expected the latest key accepted, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodDelete, "/discord/admin/api-keys/"+third.APIKey.ID+"?grace=soon", "", "root-key"); rec.Code != http.StatusBadRequest { t.Fatalf("expected %d for an invalid grace period, got %d", http.StatusBadRequest, rec.Code) }
+}
+
+func TestNextRootCredentialsAreAccepted(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "current-key")
+    t.Setenv("CORAL_API_KEY_NEXT", "next-key")
+    h, _ := setupAPIKeyHandler()
+
+    for _, key := range []string{"current-key", "next-key"} {
+        if rec := serveWithKey(h, http.MethodGet, "/discord/admin/api-keys", "", key); rec.Code != http.StatusOK { t.Fatalf("expected %s accepted, got %d", key, rec.Code) }
+    }
+    if rec := serveWithKey(h, http.MethodGet, "/discord/admin/api-keys", "", "other-key"); rec.Code != http.StatusUnauthorized { t.Fatalf("expected other keys refused, got %d", rec.Code) }
+
+    // Once the backend uses the next key it is promoted, and the old one stops working
+    h.SetRootCredentials(web.RootCredentials{APIKey: "next-key", Token: "token", NextToken: "next-token"})
+    if rec := serveWithKey(h, http.MethodGet, "/discord/admin/api-keys", "", "current-key"); rec.Code != http.StatusUnauthorized { t.Fatalf("expected the retired key refused, got %d", rec.Code) }
+    for token, accepted := range map[string]bool{"token": true, "next-token": true, "current-key": false} {
+        req := httptest.NewRequest(http.MethodGet, "/discord/webhooks", nil)
+        req.Header.Set("Authorization", "Bearer "+token)
+        if h.AuthOk(req) != accepted { t.Fatalf("expected bearer token %s accepted: %v", token, accepted) }
+    }
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func setupAPIKeyHandler() (*web.WebhookHandler, *services.APIKeyServiceImpl) {
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), services.NewSubscriptionService(repo, logger), logger)
+    apiKeyService := services.NewAPIKeyService(repo, logger)
+    h.SetAPIKeyService(apiKeyService)
+    return h, apiKeyService
+}
+
+// serveWithKey sends a request through the router with an X-API-Key header, or none when key is empty
+func serveWithKey(h *web.WebhookHandler, method, path, body, key string) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(method, path, strings.NewReader(body))
+    if key != "" {
+        req.Header.Set("X-API-Key", key)
+    }
+    rec := httptest.NewRecorder()
+    h.Handler().ServeHTTP(rec, req)
+    return rec
+}
+
+func TestAPIKeyScopesAreEnforcedPerEndpoint(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    h, _ := setupAPIKeyHandler()
+
+    rec := serveWithKey(h, http.MethodPost, "/discord/admin/api-keys", `{"name": "backend", "scopes": ["events:write", "channels:write"]}`, "root-key")
+    if rec.Code != http.StatusCreated { t.Fatalf("expected %d got %d: %s", http.StatusCreated, rec.Code, rec.Body.String()) }
+    var created web.CreateAPIKeyResponse
+    if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil { t.Fatalf("failed to decode key: %v", err) }
+    if !strings.HasPrefix(created.Secret, created.APIKey.Prefix) || strings.Contains(rec.Body.String(), "hash") {
+        t.Fatalf("expected the secret once and no hash, got %s", rec.Body.String())
+    }
+
+    event := `{"market_id": "m1", "title": "T", "outcome": "Yes", "amount": 5}`
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-buy", event, created.Secret); rec.Code != http.StatusAccepted {
+        t.Fatalf("expected events:write to reach the event endpoint, got %d", rec.Code)
+    }
+    if rec := serveWithKey(h, http.MethodGet, "/discord/admin/analytics", "", created.Secret); rec.Code != http.StatusForbidden {
+        t.Fatalf("expected %d without admin:read, got %d", http.StatusForbidden, rec.Code)
+    }
+    if rec := serveWithKey(h, http.MethodGet, "/discord/admin/api-keys", "", created.Secret); rec.Code != http.StatusForbidden {
+        t.Fatalf("expected %d without keys:manage, got %d", http.StatusForbidden, rec.Code)
+    }
+
+    // Changes made with a stored key are attributed to it in the audit log
+    serveWithKey(h, http.MethodPost, "/discord/channel/subscribe/market", `{"channel_id": "c1", "market_id": "m1"}`, created.Secret)
+    rec = serveWithKey(h, http.MethodGet, "/discord/admin/audit", "", "root-key")
+    var audit web.AuditLogResponse
+    if err := json.Unmarshal(rec.Body.Bytes(), &audit); err != nil { t.Fatalf("failed to decode audit log: %v", err) }
+    if len(audit.Entries) != 1 || audit.Entries[0].Actor != "api:"+created.APIKey.ID {
+        t.Fatalf("expected the change attributed to the key, got %+v", audit.Entries)
+    }
+
+    if rec := serveWithKey(h, http.MethodDelete, "/discord/admin/api-keys/"+created.APIKey.ID, "", "root-key"); rec.Code != http.StatusNoContent {
+        t.Fatalf("expected %d got %d", http.StatusNoContent, rec.Code)
+    }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-buy", event, created.Secret); rec.Code != http.StatusUnauthorized {
+        t.Fatalf("expected a revoked key to be rejected, got %d", rec.Code)
+    }
+    if rec := serveWithKey(h, http.MethodDelete, "/discord/admin/api-keys/key_missing", "", "root-key"); rec.Code != http.StatusNotFound {
+        t.Fatalf("expected %d got %d", http.StatusNotFound, rec.Code)
+    }
+
+    rec = serveWithKey(h, http.MethodGet, "/discord/admin/api-keys", "", "root-key")
+    var listed web.APIKeysResponse
+    if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil { t.Fatalf("failed to decode keys: %v", err) }
+    if len(listed.Keys) != 1 || listed.Keys[0].RevokedAt == nil { t.Fatalf("expected the revoked key to be listed, got %+v", listed.Keys) }
+}
+
+func TestFirstAPIKeyClosesOpenAccess(t *testing.T) {
+    h, apiKeyService := setupAPIKeyHandler()
+
+    if rec := serveWithKey(h, http.MethodGet, "/discord/webhooks", "", ""); rec.Code != http.StatusOK {
+        t.Fatalf("expected open access without any credentials configured, got %d", rec.Code)
+    }
+
+    _, secret, err := apiKeyService.CreateKey(context.Background(), "ops", []string{models.ScopeWebhooksRead})
+    if err != nil { t.Fatalf("failed to create key: %v", err) }
+
+    if rec := serveWithKey(h, http.MethodGet, "/discord/webhooks", "", ""); rec.Code != http.StatusUnauthorized {
+        t.Fatalf("expected %d once a key exists, got %d", http.StatusUnauthorized, rec.Code)
+    }
+    req := httptest.NewRequest(http.MethodGet, "/discord/webhooks", nil)
+    req.Header.Set("Authorization", "Bearer "+secret)
+    rec := httptest.NewRecorder()
+    h.Handler().ServeHTTP(rec, req)
+    if rec.Code != http.StatusOK { t.Fatalf("expected the key to work as a bearer token, got %d", rec.Code) }
+    if !h.AuthOk(req) { t.Fatal("expected AuthOk to accept a stored key") }
+
+    if rec := serveWithKey(h, http.MethodGet, "/discord/health", "", ""); rec.Code != http.StatusOK {
+        t.Fatalf("expected public routes to stay open, got %d", rec.Code)
+    }
+}
+
+func TestCreateAPIKeyRejectsUnknownScopes(t *testing.T) {
+    h, apiKeyService := setupAPIKeyHandler()
+
+    if rec := serveWithKey(h, http.MethodPost, "/discord/admin/api-keys", `{"name": "bad", "scopes": ["events:write", "everything"]}`, ""); rec.Code != http.StatusBadRequest {
+        t.Fatalf("expected %d got %d", http.StatusBadRequest, rec.Code)
+    }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/admin/api-keys", `{"name": "none", "scopes": []}`, ""); rec.Code != http.StatusBadRequest {
+        t.Fatalf("expected %d for an empty scope list, got %d", http.StatusBadRequest, rec.Code)
+    }
+    if active, _ := apiKeyService.HasActiveKeys(context.Background()); active { t.Fatal("expected no key to be created") }
+}
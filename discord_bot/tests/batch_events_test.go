@@ -0,0 +1,65 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestEventBatchReportsEachEvent(t *testing.T) {
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    h.SetBuyThresholds(10, 0)
+    subscriptionService.UpdateChannelConfig(context.Background(), &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "test")
+
+    // A disconnected gateway buffers the sends, so nothing reaches Discord
+    session, _ := discordgo.New("Bot test")
+    h.SetDiscordSession(session)
+    gateway := services.NewGatewayMonitor(0, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+
+    body := `{"events": [
+        {"type": "new_market", "payload": {"market_id": "m1", "title": "Batch Market", "end_time": "2030-01-01T00:00:00Z"}},
+        {"type": "market_update", "payload": {"title": "No ID"}},
+        {"type": "market_buy", "payload": {"market_id": "m1", "amount": 5}},
+        {"type": "market_buy", "payload": {"market_id": "m1", "amount": 50, "outcome": "Yes"}},
+        {"type": "new_market", "payload": {"market_id": "m2", "end_time": "tomorrow"}},
+        {"type": "market_exploded", "payload": {"market_id": "m1"}},
+        {"type": "market_resolved", "payload": "not an object"},
+        {"type": "market_resolved", "payload": {"market_id": "m1", "winning_outcome": "Yes"}}
+    ]}`
+    rec := serveWithKey(h, http.MethodPost, "/discord/events/batch", body, "")
+    if rec.Code != http.StatusOK { t.Fatalf("expected %d got %d: %s", http.StatusOK, rec.Code, rec.Body.String()) }
+
+    var resp web.BatchEventResponse
+    if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil { t.Fatalf("failed to decode response: %v", err) }
+    if resp.Accepted != 4 || resp.Failed != 4 || len(resp.Results) != 8 { t.Fatalf("expected 4 accepted and 4 failed events, got %+v", resp) }
+    for i, want := range []string{"", "market_id required", "", "", "end_time must be an RFC3339 time", `unknown event type "market_exploded"`, "invalid payload JSON", ""} {
+        result := resp.Results[i]
+        if result.Index != i || result.Error != want || result.Accepted != (want == "") { t.Fatalf("unexpected result %d: %+v", i, result) }
+    }
+    if !resp.Results[2].Suppressed || resp.Results[3].Suppressed { t.Fatalf("expected only the small buy to be suppressed, got %+v", resp.Results) }
+
+    // The new market, the large buy and the resolution reach the feed channel
+    if buffered := gateway.Status().Buffered; buffered != 3 { t.Fatalf("expected three delivered events, got %d", buffered) }
+}
+
+func TestEventBatchValidation(t *testing.T) {
+    h := setupHandler()
+    tooMany := `{"events": [` + strings.TrimSuffix(strings.Repeat(`{"type": "new_market", "payload": {"market_id": "m1"}},`, 101), ",") + `]}`
+    for _, body := range []string{`{"events": []}`, `not json`, tooMany} {
+        if rec := serveWithKey(h, http.MethodPost, "/discord/events/batch", body, ""); rec.Code != http.StatusBadRequest { t.Fatalf("expected %d for %.30q, got %d", http.StatusBadRequest, body, rec.Code) }
+    }
+}
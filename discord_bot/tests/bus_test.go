@@ -0,0 +1,386 @@
+package tests
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "strings"
+    "sync"
+    "testing"
+
+    "coral-bot/discord_bot/internal/bus"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// fakeBusReader serves scripted batches and records commits
+type fakeBusReader struct {
+    batches    [][]*bus.Message
+    commits    []int64
+    failCommit map[int64]bool
+}
+
+func (r *fakeBusReader) Name() string { return "fake:markets/test" }
+
+func (r *fakeBusReader) Fetch(ctx context.Context) ([]*bus.Message, error) {
+    if len(r.batches) == 0 {
+        return nil, nil
+    }
+    batch := r.batches[0]
+    r.batches = r.batches[1:]
+    return batch, nil
+}
+
+func (r *fakeBusReader) Commit(ctx context.Context, msg *bus.Message) error {
+    if r.failCommit[msg.Offset] {
+        return errors.New("broker unavailable")
+    }
+    r.commits = append(r.commits, msg.Offset)
+    return nil
+}
+
+func (r *fakeBusReader) Close() error { return nil }
+
+// setupBusHandler returns a handler delivering to one feed channel through a disconnected gateway,
+// which buffers the sends so they can be counted
+func setupBusHandler() (*web.WebhookHandler, *services.GatewayMonitor, *repository.InMemorySubscriptionRepository) {
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    subscriptionService.UpdateChannelConfig(context.Background(), &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "test")
+    session, _ := discordgo.New("Bot test")
+    h.SetDiscordSession(session)
+    gateway := services.NewGatewayMonitor(0, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+    return h, gateway, repo
+}
+
+func busEvent(eventType, marketID string) []byte {
+    b, _ := json.Marshal(map[string]interface{}{"type": eventType, "payload": map[string]string{"market_id": marketID, "title": "Bus Market"}})
+    return b
+}
+
+func TestBusConsumerSkipsMessagesProcessedBeforeARestart(t *testing.T) {
+    ctx := context.Background()
+    h, gateway, repo := setupBusHandler()
+
+    reader := &fakeBusReader{
+        batches: [][]*bus.Message{{
+            {Topic: "markets", Offset: 1, Data: busEvent(models.EventNewMarket, "m1")},
+            {Topic: "markets", Offset: 2, Data: []byte("not json")},
+            {Topic: "markets", Offset: 3, Data: busEvent(models.EventMarketUpdate, "")},
+            {Topic: "markets", Offset: 4, Data: busEvent(models.EventTradingStarted, "m1")},
+        }},
+        failCommit: map[int64]bool{4: true},
+    }
+    delivered, err := bus.NewConsumer(reader, h, repo, utils.NewLogger()).ProcessMessages(ctx)
+    if err != nil || delivered != 2 { t.Fatalf("expected two delivered events, got %d, %v", delivered, err) }
+    if fmt.Sprint(reader.commits) != "[1 2 3]" { t.Fatalf("expected invalid messages to be committed too, got %v", reader.commits) }
+    if buffered := gateway.Status().Buffered; buffered != 2 { t.Fatalf("expected two sends, got %d", buffered) }
+
+    // After a restart the uncommitted message is redelivered, and skipped because it was processed
+    restarted := &fakeBusReader{batches: [][]*bus.Message{{
+        {Topic: "markets", Offset: 4, Data: busEvent(models.EventTradingStarted, "m1")},
+        {Topic: "markets", Offset: 5, Data: busEvent(models.EventTradingEnded, "m1")},
+    }}}
+    delivered, err = bus.NewConsumer(restarted, h, repo, utils.NewLogger()).ProcessMessages(ctx)
+    if err != nil || delivered != 1 { t.Fatalf("expected only the new event to be delivered, got %d, %v", delivered, err) }
+    if fmt.Sprint(restarted.commits) != "[4 5]" { t.Fatalf("expected both messages to be committed, got %v", restarted.commits) }
+    if buffered := gateway.Status().Buffered; buffered != 3 { t.Fatalf("expected three sends, got %d", buffered) }
+    offset, _ := repo.GetBusOffset(ctx, "fake:markets/test", 0)
+    if offset == nil || offset.Offset != 5 { t.Fatalf("expected offset 5 to be saved, got %+v", offset) }
+}
+
+func TestBusReaderConfiguration(t *testing.T) {
+    for _, config := range []bus.Config{
+        {Driver: "rabbitmq", URL: "amqp://localhost", Topic: "markets"},
+        {Driver: bus.DriverKafka, URL: "http://localhost:8082"},
+        {Driver: bus.DriverNATS, Topic: "markets"},
+    } {
+        if _, err := bus.NewReader(config); err == nil { t.Fatalf("expected an error for %+v", config) }
+    }
+    reader, err := bus.NewReader(bus.Config{Driver: bus.DriverKafka, URL: "http://localhost:8082", Topic: "markets"})
+    if err != nil || reader.Name() != "kafka:markets/"+bus.DefaultGroup { t.Fatalf("unexpected reader %v, %v", reader, err) }
+}
+
+// fakeKafkaProxy emulates the consumer endpoints of a Kafka REST Proxy
+type fakeKafkaProxy struct {
+    mutex     sync.Mutex
+    records   [][]map[string]interface{}
+    instances int
+    expire    bool // answer the next records call as if the instance had expired
+    committed []string
+    produced  []map[string][]byte
+}
+
+func (p *fakeKafkaProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    base := fmt.Sprintf("/consumers/grp/instances/i%d", p.instances)
+    switch {
+    case r.Method == http.MethodPost && r.URL.Path == "/consumers/grp":
+        p.instances++
+        w.Write([]byte(fmt.Sprintf(`{"instance_id": "i%d", "base_uri": "http://%s/consumers/grp/instances/i%d"}`, p.instances, r.Host, p.instances)))
+    case r.Method == http.MethodPost && r.URL.Path == base+"/subscription":
+        w.WriteHeader(http.StatusNoContent)
+    case r.Method == http.MethodGet && r.URL.Path == base+"/records":
+        if p.expire {
+            p.expire = false
+            w.WriteHeader(http.StatusNotFound)
+            w.Write([]byte(`{"error_code": 40403, "message": "Consumer instance not found."}`))
+            return
+        }
+        batch := []map[string]interface{}{}
+        if len(p.records) > 0 {
+            batch, p.records = p.records[0], p.records[1:]
+        }
+        b, _ := json.Marshal(batch)
+        w.Write(b)
+    case r.Method == http.MethodPost && r.URL.Path == base+"/offsets":
+        var body struct {
+            Offsets []struct {
+                Topic     string `json:"topic"`
+                Partition int32  `json:"partition"`
+                Offset    int64  `json:"offset"`
+            } `json:"offsets"`
+        }
+        json.NewDecoder(r.Body).Decode(&body)
+        for _, offset := range body.Offsets {
+            p.committed = append(p.committed, fmt.Sprintf("%s/%d@%d", offset.Topic, offset.Partition, offset.Offset))
+        }
+        w.WriteHeader(http.StatusNoContent)
+    case r.Method == http.MethodPost && r.URL.Path == "/topics/markets.processed":
+        if r.Header.Get("Content-Type") != "application/vnd.kafka.binary.v2+json" {
+            w.WriteHeader(http.StatusUnsupportedMediaType)
+            w.Write([]byte(`{"error_code": 415, "message": "unsupported content type"}`))
+            return
+        }
+        var body struct {
+            Records []map[string][]byte `json:"records"`
+        }
+        json.NewDecoder(r.Body).Decode(&body)
+        p.produced = append(p.produced, body.Records...)
+        w.Write([]byte(`{"offsets": [{"partition": 0, "offset": 1, "error_code": null, "error": null}]}`))
+    case r.Method == http.MethodDelete && r.URL.Path == base:
+        w.WriteHeader(http.StatusNoContent)
+    default:
+        w.WriteHeader(http.StatusNotFound)
+        w.Write([]byte(`{"error_code": 40401, "message": "not found"}`))
+    }
+}
+
+func TestKafkaReaderCommitsOffsetsForItsGroup(t *testing.T) {
+    ctx := context.Background()
+    h, gateway, repo := setupBusHandler()
+    proxy := &fakeKafkaProxy{
+        records: [][]map[string]interface{}{
+            {
+                {"topic": "markets", "partition": 0, "offset": 10, "value": busEvent(models.EventNewMarket, "m1")},
+                {"topic": "markets", "partition": 1, "offset": 7, "value": busEvent(models.EventNewMarket, "m2")},
+            },
+            {{"topic": "markets", "partition": 0, "offset": 11, "value": busEvent(models.EventTradingStarted, "m1")}},
+        },
+    }
+    server := httptest.NewServer(proxy)
+    defer server.Close()
+
+    reader, err := bus.NewReader(bus.Config{Driver: bus.DriverKafka, URL: server.URL, Topic: "markets", Group: "grp"})
+    if err != nil { t.Fatalf("failed to create reader: %v", err) }
+    consumer := bus.NewConsumer(reader, h, repo, utils.NewLogger())
+
+    if delivered, err := consumer.ProcessMessages(ctx); err != nil || delivered != 2 { t.Fatalf("expected two delivered events, got %d, %v", delivered, err) }
+
+    // An expired consumer instance is recreated on the next fetch
+    proxy.mutex.Lock()
+    proxy.expire = true
+    proxy.mutex.Unlock()
+    if _, err := consumer.ProcessMessages(ctx); err == nil { t.Fatalf("expected the expired instance to fail the fetch") }
+    if delivered, err := consumer.ProcessMessages(ctx); err != nil || delivered != 1 { t.Fatalf("expected one delivered event after rejoining, got %d, %v", delivered, err) }
+
+    if proxy.instances != 2 { t.Fatalf("expected the consumer instance to be recreated once, got %d instances", proxy.instances) }
+    if strings.Join(proxy.committed, " ") != "markets/0@10 markets/1@7 markets/0@11" { t.Fatalf("unexpected commits %v", proxy.committed) }
+    if buffered := gateway.Status().Buffered; buffered != 3 { t.Fatalf("expected three sends, got %d", buffered) }
+    reader.Close()
+}
+
+// startFakeNATS serves a JetStream stream MARKETS holding the given messages on markets.events. It
+// reports the stream sequence of each acknowledged message and the batch size of each pull request.
+func startFakeNATS(t *testing.T, messages [][]byte) (string, chan int64, chan int) {
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil { t.Fatalf("failed to listen: %v", err) }
+    t.Cleanup(func() { listener.Close() })
+    acks := make(chan int64, len(messages))
+    pulls := make(chan int, 16)
+
+    go func() {
+        conn, err := listener.Accept()
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        reader := bufio.NewReader(conn)
+        fmt.Fprintf(conn, "INFO {\"server_id\":\"fake\",\"version\":\"2.10.0\",\"headers\":true,\"jetstream\":true,\"max_payload\":1048576}\r\n")
+        subscriptions := map[string]string{}
+        // sid returns the subscription a subject is delivered to, either exactly or through the
+        // client's <inbox>.* request subscription
+        sid := func(subject string) string {
+            if sid, ok := subscriptions[subject]; ok {
+                return sid
+            }
+            return subscriptions[subject[:strings.LastIndex(subject, ".")+1]+"*"]
+        }
+        reply := func(subject, body string) {
+            fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", subject, sid(subject), len(body), body)
+        }
+        next := 0
+        for {
+            line, err := reader.ReadString('\n')
+            if err != nil {
+                return
+            }
+            fields := strings.Fields(line)
+            if len(fields) == 0 {
+                continue
+            }
+            switch fields[0] {
+            case "PING":
+                fmt.Fprintf(conn, "PONG\r\n")
+            case "SUB":
+                subscriptions[fields[1]] = fields[len(fields)-1]
+            case "PUB":
+                size, _ := strconv.Atoi(fields[len(fields)-1])
+                payload := make([]byte, size+2)
+                io.ReadFull(reader, payload)
+                subject, inbox := fields[1], ""
+                if len(fields) == 4 {
+                    inbox = fields[2]
+                }
+                switch {
+                case subject == "$JS.API.STREAM.NAMES":
+                    reply(inbox, `{"type":"io.nats.jetstream.api.v1.stream_names_response","total":1,"offset":0,"limit":1024,"streams":["MARKETS"]}`)
+                case strings.HasPrefix(subject, "$JS.API.CONSUMER.CREATE.MARKETS."+bus.DefaultGroup):
+                    var request struct {
+                        Config json.RawMessage `json:"config"`
+                    }
+                    json.Unmarshal(payload[:size], &request)
+                    reply(inbox, fmt.Sprintf(`{"type":"io.nats.jetstream.api.v1.consumer_create_response","stream_name":"MARKETS","name":%q,"config":%s}`, bus.DefaultGroup, request.Config))
+                case strings.HasPrefix(subject, "$JS.API.CONSUMER.MSG.NEXT.MARKETS."):
+                    var request struct {
+                        Batch int `json:"batch"`
+                    }
+                    json.Unmarshal(payload[:size], &request)
+                    pulls <- request.Batch
+                    for sent := 0; sent < request.Batch && next < len(messages); sent++ {
+                        next++
+                        ack := fmt.Sprintf("$JS.ACK.MARKETS.%s.1.%d.%d.1700000000000000000.0", bus.DefaultGroup, next, next)
+                        fmt.Fprintf(conn, "MSG markets.events %s %s %d\r\n%s\r\n", sid(inbox), ack, len(messages[next-1]), messages[next-1])
+                    }
+                    // The pull expires before the batch is full
+                    header := "NATS/1.0 408 Request Timeout\r\n\r\n"
+                    fmt.Fprintf(conn, "HMSG %s %s %d %d\r\n%s\r\n", inbox, sid(inbox), len(header), len(header), header)
+                case subject == "markets.processed":
+                    reply(inbox, `{"stream":"PROCESSED","seq":1}`)
+                case strings.HasPrefix(subject, "$JS.ACK.MARKETS."):
+                    sequence, _ := strconv.ParseInt(strings.Split(subject, ".")[5], 10, 64)
+                    acks <- sequence
+                }
+            }
+        }
+    }()
+    return listener.Addr().String(), acks, pulls
+}
+
+func TestNATSReaderPullsFromADurableConsumer(t *testing.T) {
+    ctx := context.Background()
+    h, gateway, repo := setupBusHandler()
+    addr, acks, pulls := startFakeNATS(t, [][]byte{busEvent(models.EventNewMarket, "m1"), busEvent(models.EventMarketUpdate, "m1"), busEvent(models.EventMarketResolved, "m1")})
+
+    reader, err := bus.NewReader(bus.Config{Driver: bus.DriverNATS, URL: "nats://" + addr, Topic: "markets.events", FetchBatch: 2})
+    if err != nil { t.Fatalf("failed to create reader: %v", err) }
+    defer reader.Close()
+    consumer := bus.NewConsumer(reader, h, repo, utils.NewLogger())
+
+    if delivered, err := consumer.ProcessMessages(ctx); err != nil || delivered != 2 { t.Fatalf("expected the first batch of two messages to be delivered, got %d, %v", delivered, err) }
+    if batch := <-pulls; batch != 2 { t.Fatalf("expected pulls of the configured batch, got %d", batch) }
+    if delivered, err := consumer.ProcessMessages(ctx); err != nil || delivered != 1 { t.Fatalf("expected the rest of the stream to be delivered, got %d, %v", delivered, err) }
+    for i := 1; i <= 3; i++ {
+        if sequence := <-acks; sequence != int64(i) { t.Fatalf("expected message %d to be acknowledged, got %d", i, sequence) }
+    }
+    if delivered, err := consumer.ProcessMessages(ctx); err != nil || delivered != 0 { t.Fatalf("expected an empty pull once the stream is drained, got %d, %v", delivered, err) }
+
+    offset, _ := repo.GetBusOffset(ctx, reader.Name(), 0)
+    if offset == nil || offset.Offset != 3 { t.Fatalf("expected stream sequence 3 to be saved, got %+v", offset) }
+    if buffered := gateway.Status().Buffered; buffered != 3 { t.Fatalf("expected three sends, got %d", buffered) }
+}
+
+// fakeEventPublisher records the events it is given
+type fakeEventPublisher struct {
+    events []*models.ProcessedEvent
+}
+
+func (p *fakeEventPublisher) Publish(ctx context.Context, event *models.ProcessedEvent) error {
+    p.events = append(p.events, event)
+    return nil
+}
+
+func TestProcessedEventsArePublishedWithDeliveryStats(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    h, _, repo := setupBusHandler()
+    h.SetOutboxService(services.NewOutboxService(repo, logger))
+    h.SetBuyThresholds(100, 0)
+    publisher := &fakeEventPublisher{}
+    h.SetEventPublisher(publisher)
+    services.NewSubscriptionService(repo, logger).SubscribeToMarket(ctx, "u1", "m1")
+
+    if _, err := h.ProcessEventJSON(ctx, models.EventMarketBuy, json.RawMessage(`{"market_id": "m1", "title": "Bus Market", "amount": 250}`)); err != nil { t.Fatalf("failed to process buy: %v", err) }
+    if suppressed, err := h.ProcessEventJSON(ctx, models.EventMarketBuy, json.RawMessage(`{"market_id": "m1", "title": "Bus Market", "amount": 5}`)); err != nil || !suppressed { t.Fatalf("expected the small buy to be suppressed, got %v, %v", suppressed, err) }
+
+    if len(publisher.events) != 2 { t.Fatalf("expected two published events, got %d", len(publisher.events)) }
+    delivered, suppressed := publisher.events[0], publisher.events[1]
+    if delivered.ID == "" || delivered.Type != models.EventMarketBuy || delivered.Market.ID != "m1" || delivered.BuyAmount != 250 || delivered.Suppressed { t.Fatalf("unexpected delivered event %+v", delivered) }
+    if delivered.Delivery != (models.DeliveryStats{Channels: 1, Users: 1}) { t.Fatalf("expected one channel and one user, got %+v", delivered.Delivery) }
+    if !strings.Contains(delivered.Content, "Bus Market") || delivered.ProcessedAt.IsZero() { t.Fatalf("expected the rendered message and a timestamp, got %+v", delivered) }
+    if !suppressed.Suppressed || suppressed.Delivery != (models.DeliveryStats{}) || suppressed.Content != "" { t.Fatalf("unexpected suppressed event %+v", suppressed) }
+}
+
+func TestKafkaWriterProducesEventsKeyedByMarket(t *testing.T) {
+    proxy := &fakeKafkaProxy{}
+    server := httptest.NewServer(proxy)
+    defer server.Close()
+
+    writer, err := bus.NewWriter(bus.Config{Driver: bus.DriverKafka, URL: server.URL, Topic: "markets.processed"})
+    if err != nil { t.Fatalf("failed to create writer: %v", err) }
+    publisher := services.NewBusEventPublisher(writer, utils.NewLogger())
+    event := &models.ProcessedEvent{Type: models.EventNewMarket, Market: &models.Market{ID: "m1"}, Delivery: models.DeliveryStats{Channels: 2, Failed: 1}}
+    if err := publisher.Publish(context.Background(), event); err != nil { t.Fatalf("failed to publish: %v", err) }
+
+    if len(proxy.produced) != 1 || string(proxy.produced[0]["key"]) != "m1" { t.Fatalf("expected one record keyed by market, got %v", proxy.produced) }
+    var published models.ProcessedEvent
+    if err := json.Unmarshal(proxy.produced[0]["value"], &published); err != nil { t.Fatalf("failed to decode record: %v", err) }
+    if published.ID == "" || published.ID != event.ID || published.Delivery != event.Delivery { t.Fatalf("unexpected record %+v", published) }
+
+    if _, err := bus.NewWriter(bus.Config{Driver: bus.DriverKafka, URL: server.URL}); err == nil { t.Fatalf("expected a missing topic to be rejected") }
+}
+
+func TestNATSWriterWaitsForTheStreamAcknowledgement(t *testing.T) {
+    addr, _, _ := startFakeNATS(t, nil)
+    writer, err := bus.NewWriter(bus.Config{Driver: bus.DriverNATS, URL: "nats://" + addr, Topic: "markets.processed"})
+    if err != nil { t.Fatalf("failed to create writer: %v", err) }
+    defer writer.Close()
+    if err := writer.Publish(context.Background(), "m1", []byte(`{"type":"new_market"}`)); err != nil { t.Fatalf("failed to publish: %v", err) }
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+)
+
+// countingRepository counts the list reads that reach the in-memory repository
+type countingRepository struct {
+    *repository.InMemorySubscriptionRepository
+    subscriptionReads int
+    channelReads      int
+}
+
+func (repo *countingRepository) GetAllSubscriptions(ctx context.Context) ([]*models.Subscription, error) {
+    repo.subscriptionReads++
+    return repo.InMemorySubscriptionRepository.GetAllSubscriptions(ctx)
+}
+
+func (repo *countingRepository) GetAllChannelConfigs(ctx context.Context) ([]*models.ChannelConfig, error) {
+    repo.channelReads++
+    return repo.InMemorySubscriptionRepository.GetAllChannelConfigs(ctx)
+}
+
+func TestCachedRepositoryReadsThroughUntilAWrite(t *testing.T) {
+    ctx := context.Background()
+    storage := &countingRepository{InMemorySubscriptionRepository: repository.NewInMemorySubscriptionRepository()}
+    cache := repository.NewCachedSubscriptionRepository(storage, 0)
+    var published []repository.CacheKind
+    cache.SetInvalidationHook(func(ctx context.Context, kind repository.CacheKind) { published = append(published, kind) })
+    subscriptionService := services.NewSubscriptionService(cache, utils.NewLogger())
+
+    subscriptionService.SubscribeToMarket(ctx, "u1", "m1")
+    for i := 0; i < 3; i++ {
+        if subscriptions, _ := cache.GetAllSubscriptions(ctx); len(subscriptions) != 1 { t.Fatalf("expected one subscription, got %d", len(subscriptions)) }
+    }
+    if storage.subscriptionReads != 1 { t.Fatalf("expected one read to reach storage, got %d", storage.subscriptionReads) }
+
+    subscriptionService.SubscribeToMarket(ctx, "u2", "m1")
+    if subscriptions, _ := cache.GetAllSubscriptions(ctx); len(subscriptions) != 2 || storage.subscriptionReads != 2 { t.Fatalf("expected the write to drop the cached list, got %d subscriptions after %d reads", len(subscriptions), storage.subscriptionReads) }
+    if len(published) == 0 || published[len(published)-1] != repository.CacheSubscriptions { t.Fatalf("expected the hook to hear about the change, got %v", published) }
+
+    // Changing the returned list leaves the cache alone
+    subscriptions, _ := cache.GetAllSubscriptions(ctx)
+    subscriptions[0] = nil
+    if again, _ := cache.GetAllSubscriptions(ctx); again[0] == nil { t.Fatalf("expected each read to return its own list") }
+
+    // Writes in a transaction drop the list too, even when the transaction fails
+    cache.GetAllChannelConfigs(ctx)
+    cache.WithTx(ctx, func(tx repository.SubscriptionRepository) error {
+        tx.SaveChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true})
+        return errors.New("rolled back")
+    })
+    if configs, _ := cache.GetAllChannelConfigs(ctx); len(configs) != 0 || storage.channelReads != 2 { t.Fatalf("expected the transaction to drop the cached configs and roll back, got %d configs after %d reads", len(configs), storage.channelReads) }
+
+    // Invalidations from other instances drop the list without being published again
+    count := len(published)
+    cache.Invalidate(repository.CacheChannelConfigs)
+    cache.GetAllChannelConfigs(ctx)
+    if storage.channelReads != 3 || len(published) != count { t.Fatalf("expected Invalidate to force a read and stay local, got %d reads and %v", storage.channelReads, published[count:]) }
+}
+
+func TestCachedRepositoryExpiresLists(t *testing.T) {
+    ctx := context.Background()
+    storage := &countingRepository{InMemorySubscriptionRepository: repository.NewInMemorySubscriptionRepository()}
+    cache := repository.NewCachedSubscriptionRepository(storage, 20*time.Millisecond)
+
+    cache.GetAllChannelConfigs(ctx)
+    cache.GetAllChannelConfigs(ctx)
+    if storage.channelReads != 1 { t.Fatalf("expected the second read to hit the cache, got %d reads", storage.channelReads) }
+
+    // A change made by another instance, straight to the shared storage, shows up once the list expires
+    storage.SaveChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1"})
+    if configs, _ := cache.GetAllChannelConfigs(ctx); len(configs) != 0 { t.Fatalf("expected the cached list before it expires, got %d configs", len(configs)) }
+    time.Sleep(30 * time.Millisecond)
+    if configs, _ := cache.GetAllChannelConfigs(ctx); len(configs) != 1 { t.Fatalf("expected the expired list to be loaded again, got %d configs", len(configs)) }
+}
+
+func TestChannelMarketSubscriptionsLookUpTheChannelOnly(t *testing.T) {
+    ctx := context.Background()
+    storage := &countingRepository{InMemorySubscriptionRepository: repository.NewInMemorySubscriptionRepository()}
+    subscriptionService := services.NewSubscriptionService(storage, utils.NewLogger())
+    for i := 0; i < 50; i++ {
+        storage.SaveChannelConfig(ctx, &models.ChannelConfig{ChannelID: fmt.Sprintf("c%d", i), FeedEnabled: true})
+    }
+
+    if err := subscriptionService.SubscribeChannelToMarket(ctx, "c-new", "m1", "test"); err != nil { t.Fatalf("failed to subscribe channel: %v", err) }
+    if err := subscriptionService.SubscribeChannelToMarket(ctx, "c1", "m1", "test"); err != nil { t.Fatalf("failed to subscribe channel: %v", err) }
+    if storage.channelReads != 0 { t.Fatalf("expected no channel list reads, got %d", storage.channelReads) }
+
+    if found, _ := storage.HasChannelConfig(ctx, "c-new"); !found { t.Fatalf("expected the subscribed channel to be saved") }
+    if found, _ := storage.HasChannelConfig(ctx, "c-missing"); found { t.Fatalf("expected no config for a channel never saved") }
+    if config, _ := subscriptionService.GetChannelConfig(ctx, "c-new"); config.FeedEnabled { t.Fatalf("expected a new channel to have its feed off, got %+v", config) }
+    if config, _ := subscriptionService.GetChannelConfig(ctx, "c1"); !config.FeedEnabled { t.Fatalf("expected a configured channel to keep its feed, got %+v", config) }
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+    "context"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// calendarMarkets sets up a match tomorrow with a sports and a politics market, and an election next week
+func calendarMarkets(marketService *services.MockMarketService, now time.Time) {
+    marketService.SetMarket(&models.Market{ID: "m1", Title: "Home team wins", Status: "active", Category: "Sports", Volume: 500, Outcomes: []string{"Yes", "No"}, Percentages: []float64{62, 38}, EndTime: now.Add(30 * time.Hour)})
+    marketService.SetMarket(&models.Market{ID: "m2", Title: "Minister attends", Status: "active", Category: "Politics", Volume: 9000, Outcomes: []string{"Yes", "No"}, Percentages: []float64{20, 80}, EndTime: now.Add(30 * time.Hour)})
+    marketService.SetMarket(&models.Market{ID: "m3", Title: "Old market", Status: "resolved", Category: "Sports", EndTime: now.Add(-time.Hour)})
+    marketService.SetCalendarEvents([]*models.CalendarEvent{
+        {ID: "ev2", Name: "General election", Kind: "election", StartTime: now.Add(7 * 24 * time.Hour), MarketIDs: []string{"m2"}},
+        {ID: "ev1", Name: "Sunday's match", Kind: "sports", StartTime: now.Add(20 * time.Hour), MarketIDs: []string{"m1", "m2", "m3"}},
+        {ID: "ev0", Name: "Yesterday's race", StartTime: now.Add(-24 * time.Hour), MarketIDs: []string{"m1"}},
+    })
+}
+
+func TestThemedDigestsArePostedOncePerEvent(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    clock := services.NewFakeClock(time.Now())
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    marketService := services.NewMockMarketService(logger)
+    calendarMarkets(marketService, clock.Now())
+    notifier := newRecordingNotifier()
+    calendar := services.NewCalendarService(repo, marketService, notifier, 0, logger)
+    calendar.SetClock(clock)
+
+    subscriptionService.SetChannelCalendarDigests(ctx, "c-all", "g1", true, "test")
+    subscriptionService.SetChannelCalendarDigests(ctx, "c-sports", "g1", true, "test")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c-politics-off", AllowedCategories: []string{"Politics"}}, "test")
+    config, _ := subscriptionService.GetChannelConfig(ctx, "c-sports")
+    config.AllowedCategories = []string{"Sports"}
+    subscriptionService.UpdateChannelConfig(ctx, config, "test")
+
+    if sent := calendar.ProcessDueThemedDigests(ctx); sent != 2 { t.Fatalf("expected the match digest in both opted-in channels, got %d", sent) }
+    digest := notifier.channelMessages["c-all"][0]
+    if !strings.Contains(digest, "Markets about Sunday's match") || strings.Index(digest, "Minister attends") > strings.Index(digest, "Home team wins") || !strings.Contains(digest, "Yes 62%") || strings.Contains(digest, "Old market") { t.Fatalf("expected the match's active markets by volume, got %q", digest) }
+    if digest := notifier.channelMessages["c-sports"][0]; strings.Contains(digest, "Minister attends") { t.Fatalf("expected the sports channel to get only sports markets, got %q", digest) }
+    if len(notifier.channelMessages["c-politics-off"]) != 0 { t.Fatalf("expected no digest in a channel that did not opt in") }
+
+    if sent := calendar.ProcessDueThemedDigests(ctx); sent != 0 { t.Fatalf("expected each event's digest once, got %d more", sent) }
+    clock.Advance(6 * 24 * time.Hour + time.Hour)
+    if sent := calendar.ProcessDueThemedDigests(ctx); sent != 1 || !strings.Contains(notifier.channelMessages["c-all"][1], "General election") { t.Fatalf("expected the election digest in the channel with politics markets, got %d", sent) }
+    if config, _ := subscriptionService.GetChannelConfig(ctx, "c-all"); len(config.CalendarDigestsSent) != 1 || config.CalendarDigestsSent[0] != "ev2" { t.Fatalf("expected only the upcoming event to be remembered, got %v", config.CalendarDigestsSent) }
+}
+
+func TestEventsCalendarCommand(t *testing.T) {
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    marketService := services.NewMockMarketService(logger)
+    calendarMarkets(marketService, time.Now())
+    h := handlers.NewCommandHandler(marketService, subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger)
+
+    h.HandleInteraction(session, commandInteraction("events_calendar"))
+    if len(*responses) != 1 || !strings.Contains((*responses)[0].Data.Content, "not enabled") { t.Fatalf("expected the calendar to be off without a calendar service, got %+v", *responses) }
+
+    h.SetCalendarService(services.NewCalendarService(repo, marketService, newRecordingNotifier(), 0, logger))
+    h.HandleInteraction(session, commandInteraction("events_calendar"))
+    list := (*responses)[1].Data.Content
+    if !strings.Contains(list, "Sunday's match") || strings.Index(list, "General election") < strings.Index(list, "Sunday's match") || strings.Contains(list, "Yesterday's race") { t.Fatalf("expected the upcoming events soonest first, got %q", list) }
+
+    h.HandleInteraction(session, commandInteraction("events_calendar", &discordgo.ApplicationCommandInteractionDataOption{Name: "event", Type: discordgo.ApplicationCommandOptionString, Value: "election"}))
+    if digest := (*responses)[2].Data.Content; !strings.Contains(digest, "Markets about General election") || !strings.Contains(digest, "Minister attends") { t.Fatalf("expected the election's markets, got %q", digest) }
+
+    h.HandleInteraction(session, commandInteraction("events_calendar", &discordgo.ApplicationCommandInteractionDataOption{Name: "event", Type: discordgo.ApplicationCommandOptionString, Value: "cup final"}))
+    if reply := (*responses)[3].Data.Content; !strings.Contains(reply, "No upcoming event") { t.Fatalf("expected an unknown event to be reported, got %q", reply) }
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestCategoryCatalog(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    marketService := services.NewMockMarketService(logger)
+    marketService.SetCategories([]string{"Sports", "Politics", "politics ", "Crypto", "Pop Culture"})
+    catalog := services.NewCategoryCatalog(marketService, time.Hour, logger)
+
+    categories, err := catalog.Categories(ctx)
+    if err != nil || strings.Join(categories, ",") != "Crypto,Politics,Pop Culture,Sports" { t.Fatalf("expected the deduplicated categories in order, got %q %v", categories, err) }
+
+    resolved, unknown, err := catalog.Resolve(ctx, []string{"sports", "POLITICS", "politcs", "weather"})
+    if err != nil || strings.Join(resolved, ",") != "Sports,Politics" { t.Fatalf("expected the canonical spellings, got %q %v", resolved, err) }
+    if len(unknown) != 2 || unknown[0].Name != "politcs" || len(unknown[0].Suggestions) != 1 || unknown[0].Suggestions[0] != "Politics" || len(unknown[1].Suggestions) != 0 { t.Fatalf("expected a suggestion for the typo only, got %+v", unknown) }
+    if suggestions := services.SuggestCategories("po", categories); strings.Join(suggestions, ",") != "Politics,Pop Culture" { t.Fatalf("expected the categories starting with the name, got %q", suggestions) }
+    if completions := catalog.Complete(ctx, "ort", 5); len(completions) != 1 || completions[0] != "Sports" { t.Fatalf("expected a completion containing the text, got %q", completions) }
+
+    // A backend outage keeps the cached list
+    marketService.SetError(errors.New("backend down"))
+    if categories, err := catalog.Categories(ctx); err != nil || len(categories) != 4 { t.Fatalf("expected the cached categories, got %q %v", categories, err) }
+    stale := services.NewCategoryCatalog(marketService, 0, logger)
+    if _, err := stale.Categories(ctx); err == nil { t.Fatalf("expected an error without any cached categories") }
+}
+
+func TestChannelFeedCategoriesValidation(t *testing.T) {
+    ctx := context.Background()
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    marketService := services.NewMockMarketService(logger)
+    h := handlers.NewCommandHandler(marketService, subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger)
+    h.SetCategoryCatalog(services.NewCategoryCatalog(marketService, time.Hour, logger))
+
+    setCategories := func(value string) {
+        interaction := commandInteraction("channel_feed_categories", &discordgo.ApplicationCommandInteractionDataOption{Name: "categories", Type: discordgo.ApplicationCommandOptionString, Value: value})
+        interaction.GuildID, interaction.ChannelID = "g1", "c1"
+        h.HandleInteraction(session, interaction)
+    }
+    setCategories("sports, Sprots")
+    reply := (*responses)[0].Data
+    if reply.Flags != discordgo.MessageFlagsEphemeral || !strings.Contains(reply.Content, `"Sprots" is not a market category (did you mean Sports?)`) { t.Fatalf("expected the unknown category to be explained, got %+v", reply) }
+    if config, _ := subscriptionService.GetChannelConfig(ctx, "c1"); len(config.AllowedCategories) != 0 { t.Fatalf("expected nothing to be saved, got %q", config.AllowedCategories) }
+
+    setCategories("sports, crypto")
+    if config, _ := subscriptionService.GetChannelConfig(ctx, "c1"); strings.Join(config.AllowedCategories, ",") != "Sports,Crypto" { t.Fatalf("expected the backend's spelling to be saved, got %q", config.AllowedCategories) }
+
+    h.HandleInteraction(session, modalSubmission("channel_setup", map[string]string{"categories": "weather", "frequency": "high"}))
+    if reply := (*responses)[2].Data; !strings.Contains(reply.Content, `"weather" is not a market category`) { t.Fatalf("expected the setup form to check categories, got %+v", reply) }
+
+    autocomplete := commandInteraction("channel_feed_categories", &discordgo.ApplicationCommandInteractionDataOption{Name: "categories", Type: discordgo.ApplicationCommandOptionString, Value: "Sports, ec", Focused: true})
+    autocomplete.Type = discordgo.InteractionApplicationCommandAutocomplete
+    h.HandleInteraction(session, autocomplete)
+    response := (*responses)[3]
+    if response.Type != discordgo.InteractionApplicationCommandAutocompleteResult || len(response.Data.Choices) != 1 || response.Data.Choices[0].Value != "Sports, Economics" { t.Fatalf("expected the last entry to be completed, got %+v", response.Data) }
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestNewMarketsFollowCategoryRoutes(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+
+    // g1 routes politics to c3, g2 does not route, g3 only has a default channel and routes politics too
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "test")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c2", GuildID: "g1", FeedEnabled: true}, "test")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c4", GuildID: "g2", FeedEnabled: true}, "test")
+    subscriptionService.UpdateGuildConfig(ctx, &models.GuildConfig{GuildID: "g3", DefaultChannelID: "g3-default"})
+    if _, err := subscriptionService.SetCategoryRoute(ctx, "g1", " Politics", "c3", "admin"); err != nil { t.Fatalf("failed to route: %v", err) }
+    if _, err := subscriptionService.SetCategoryRoute(ctx, "g3", "politics", "g3-politics", "admin"); err != nil { t.Fatalf("failed to route: %v", err) }
+
+    // A disconnected gateway buffers the sends, so they can be counted
+    session, _ := discordgo.New("Bot test")
+    h.SetDiscordSession(session)
+    gateway := services.NewGatewayMonitor(0, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+
+    newMarket := func(category string) {
+        payload, _ := json.Marshal(map[string]string{"market_id": "m-" + category, "title": "Routed", "category": category})
+        if _, err := h.ProcessEventJSON(ctx, models.EventNewMarket, payload); err != nil { t.Fatalf("failed to process new market: %v", err) }
+    }
+
+    // c3, c4 and g3-politics
+    newMarket("POLITICS")
+    if buffered := gateway.Status().Buffered; buffered != 3 { t.Fatalf("expected three sends for a routed category, got %d", buffered) }
+
+    // c1, c2, c4 and g3-default
+    newMarket("sports")
+    if buffered := gateway.Status().Buffered; buffered != 7 { t.Fatalf("expected four more sends for an unrouted category, got %d", buffered - 3) }
+
+    if removed, err := subscriptionService.RemoveCategoryRoute(ctx, "g1", "politics", "admin"); err != nil || !removed { t.Fatalf("expected the route to be removed, got %v, %v", removed, err) }
+    if removed, _ := subscriptionService.RemoveCategoryRoute(ctx, "g1", "politics", "admin"); removed { t.Fatalf("expected nothing left to remove") }
+
+    // c1, c2, c4 and g3-politics
+    newMarket("politics")
+    if buffered := gateway.Status().Buffered; buffered != 11 { t.Fatalf("expected four more sends once g1 stopped routing, got %d", buffered - 7) }
+}
+
+func TestCategoryRoutesAreValidatedAndAudited(t *testing.T) {
+    ctx := context.Background()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), utils.NewLogger())
+
+    if _, err := subscriptionService.SetCategoryRoute(ctx, "g1", "  ", "c1", "admin"); err == nil { t.Fatalf("expected an empty category to be rejected") }
+    subscriptionService.SetCategoryRoute(ctx, "g1", "sports", "c1", "admin")
+    subscriptionService.SetCategoryRoute(ctx, "g1", "Sports", "c2", "admin")
+    subscriptionService.SetCategoryRoute(ctx, "g1", "politics", "c2", "admin")
+
+    routes, _ := subscriptionService.GetCategoryRoutes(ctx, "g1")
+    if len(routes) != 2 || routes[0].Category != "politics" || routes[1].Category != "sports" || routes[1].ChannelID != "c2" { t.Fatalf("unexpected routes %+v", routes) }
+
+    entries, _ := subscriptionService.GetAuditLog(ctx, models.AuditFilter{ResourceType: models.AuditResourceCategoryRoute})
+    if len(entries) != 3 { t.Fatalf("expected three audit entries, got %d", len(entries)) }
+    actions := map[string]int{}
+    for _, entry := range entries {
+        actions[entry.Action]++
+    }
+    if actions[models.AuditActionCreate] != 2 || actions[models.AuditActionUpdate] != 1 { t.Fatalf("unexpected audit actions %v", actions) }
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestChannelBackfillPostsUnannouncedRecentMarkets(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+    markets := services.NewMockMarketService(logger)
+    market := func(id, category, status string, age time.Duration) {
+        markets.SetMarket(&models.Market{ID: id, Title: "Market " + id, Category: category, Status: status, Volume: 1000, StartTime: now.Add(-age), EndTime: now.Add(48 * time.Hour)})
+    }
+    market("m1", "sports", "active", 2*time.Hour)
+    market("m2", "sports", "active", 30*time.Hour)
+    market("m3", "politics", "active", time.Hour)
+    market("m4", "sports", "resolved", time.Hour)
+    market("m5", "sports", "active", 3*time.Hour)
+    market("m6", "politics", "active", 5*time.Hour)
+    repo.SaveChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", FeedEnabled: true, AllowedCategories: []string{"sports"}, SubscribedMarkets: []string{"m6"}})
+
+    notifier := newRecordingNotifier()
+    clock := services.NewFakeClock(now)
+    backfill := services.NewChannelBackfillService(repo, markets, notifier, logger)
+    backfill.SetClock(clock)
+    repo.MarkMarketAnnounced(ctx, "c1", "m5", now.Add(-time.Hour))
+
+    for _, hours := range []int{0, services.MaxBackfillHours + 1} {
+        if _, err := backfill.Backfill(ctx, "c1", hours); !errors.Is(err, services.ErrInvalidBackfillWindow) { t.Fatalf("expected %d hours to be rejected, got %v", hours, err) }
+    }
+
+    listed, err := backfill.Backfill(ctx, "c1", 24)
+    if err != nil || listed != 2 { t.Fatalf("expected two markets backfilled, got %d, %v", listed, err) }
+    messages := notifier.channelMessages["c1"]
+    if len(messages) != 1 || !strings.Contains(messages[0], "Catching Up") || !strings.Contains(messages[0], "Market m1") || !strings.Contains(messages[0], "Market m6") { t.Fatalf("unexpected backfill %v", messages) }
+    for _, id := range []string{"m2", "m3", "m4", "m5"} {
+        if strings.Contains(messages[0], "Market "+id+" ") { t.Fatalf("expected %s left out of the backfill, got %q", id, messages[0]) }
+    }
+    if strings.Index(messages[0], "Market m1") > strings.Index(messages[0], "Market m6") { t.Fatalf("expected the newest market first, got %q", messages[0]) }
+
+    if _, err := backfill.Backfill(ctx, "c1", 24); !errors.Is(err, services.ErrBackfillTooSoon) { t.Fatalf("expected the cooldown, got %v", err) }
+    clock.Advance(services.BackfillCooldown)
+    market("m7", "sports", "active", time.Minute)
+    if listed, err := backfill.Backfill(ctx, "c1", 24); err != nil || listed != 1 || !strings.Contains(notifier.channelMessages["c1"][1], "Market m7") { t.Fatalf("expected only the new market backfilled, got %d, %v", listed, err) }
+
+    // A backfill that finds nothing posts nothing and leaves the channel free to try again
+    clock.Advance(services.BackfillCooldown)
+    if listed, err := backfill.Backfill(ctx, "c1", 24); err != nil || listed != 0 { t.Fatalf("expected nothing to backfill, got %d, %v", listed, err) }
+    if _, err := backfill.Backfill(ctx, "c1", 72); err != nil { t.Fatalf("expected an empty backfill not to start the cooldown, got %v", err) }
+    if len(notifier.channelMessages["c1"]) != 3 || !strings.Contains(notifier.channelMessages["c1"][2], "Market m2") { t.Fatalf("expected the wider window to reach m2, got %v", notifier.channelMessages["c1"]) }
+}
+
+func TestNewMarketAlertsAreNotBackfilled(t *testing.T) {
+    h := newHarness(t)
+    notifier := newRecordingNotifier()
+    backfill := services.NewChannelBackfillService(h.repo, h.markets, notifier, utils.NewLogger())
+    h.handler.SetMarketAnnouncementService(services.NewMarketAnnouncementService(h.repo, utils.NewLogger()))
+    h.feedChannel("c1", "g1", nil)
+    h.feedChannel("c2", "g1", func(config *models.ChannelConfig) { config.FeedEnabled = false })
+
+    h.postEvent("new-market", newMarketEvent("m1", "sports", "alice", 5000))
+    h.markets.SetMarket(&models.Market{ID: "m1", Title: "Market m1", Category: "sports", Status: "active", Volume: 5000, StartTime: time.Now().Add(-time.Minute)})
+    if len(h.discord.channelMessages("c1")) != 1 || len(h.discord.channelMessages("c2")) != 0 { t.Fatalf("expected only c1 to get the market, got %+v", h.discord.messages) }
+    h.feedChannel("c2", "g1", nil)
+
+    if listed, err := backfill.Backfill(h.ctx, "c1", 1); err != nil || listed != 0 { t.Fatalf("expected the announced market skipped in c1, got %d, %v", listed, err) }
+    if listed, err := backfill.Backfill(h.ctx, "c2", 1); err != nil || listed != 1 { t.Fatalf("expected the market backfilled in c2, got %d, %v", listed, err) }
+}
+
+func TestChannelBackfillCommand(t *testing.T) {
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    markets := services.NewMockMarketService(logger)
+    h := handlers.NewCommandHandler(markets, services.NewSubscriptionService(repo, logger), nil, services.NewAnalyticsService(repo, logger), logger)
+    run := func() string {
+        interaction := commandInteraction("channel_backfill", &discordgo.ApplicationCommandInteractionDataOption{Name: "hours", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(24)})
+        interaction.GuildID, interaction.ChannelID = "g1", "c1"
+        h.HandleInteraction(session, interaction)
+        return (*responses)[len(*responses)-1].Data.Content
+    }
+
+    if reply := run(); !strings.Contains(reply, "not enabled") { t.Fatalf("unexpected reply %q", reply) }
+    h.SetChannelBackfillService(services.NewChannelBackfillService(repo, markets, newRecordingNotifier(), logger))
+    markets.SetMarket(&models.Market{ID: "m1", Title: "Stale", Status: "active", StartTime: time.Now().Add(-48 * time.Hour)})
+    if reply := run(); !strings.Contains(reply, "No markets") { t.Fatalf("expected nothing to backfill, got %q", reply) }
+    markets.SetMarket(&models.Market{ID: "m2", Title: "Fresh", Status: "active", StartTime: time.Now().Add(-time.Hour)})
+    if reply := run(); !strings.Contains(reply, "Posted 1 market ") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := run(); !strings.Contains(reply, "less than 10 minutes ago") { t.Fatalf("expected the cooldown, got %q", reply) }
+}
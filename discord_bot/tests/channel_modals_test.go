@@ -0,0 +1,102 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// modalSubmission returns a submitted modal from user u1 in channel c1 of guild g1, where the bot may post
+// alerts, with one text field per value
+func modalSubmission(customID string, values map[string]string) *discordgo.InteractionCreate {
+    var rows []discordgo.MessageComponent
+    for field, value := range values {
+        rows = append(rows, &discordgo.ActionsRow{Components: []discordgo.MessageComponent{&discordgo.TextInput{CustomID: field, Value: value}}})
+    }
+    return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+        ID:             "interaction-1",
+        Token:          "token",
+        Type:           discordgo.InteractionModalSubmit,
+        GuildID:        "g1",
+        ChannelID:      "c1",
+        AppPermissions: discordgo.PermissionAllText,
+        Member:         &discordgo.Member{User: &discordgo.User{ID: "u1"}},
+        Data:           discordgo.ModalSubmitInteractionData{CustomID: customID, Components: rows},
+    }}
+}
+
+func TestChannelSetupModal(t *testing.T) {
+    ctx := context.Background()
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := handlers.NewCommandHandler(services.NewMockMarketService(logger), subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger)
+
+    open := commandInteraction("channel_setup")
+    open.GuildID, open.ChannelID, open.AppPermissions = "g1", "c1", discordgo.PermissionAllText
+    h.HandleInteraction(session, open)
+    if len(*responses) != 1 || (*responses)[0].Type != discordgo.InteractionResponseModal { t.Fatalf("expected a modal, got %+v", *responses) }
+    if modal := (*responses)[0].Data; modal.CustomID != "channel_setup" || modal.Title == "" { t.Fatalf("expected the channel setup modal, got %+v", modal) }
+
+    h.HandleInteraction(session, modalSubmission("channel_setup", map[string]string{"categories": "politics", "frequency": "hourly", "min_volume": "-5", "min_buy": "10"}))
+    reply := (*responses)[1].Data
+    if reply.Flags != discordgo.MessageFlagsEphemeral || !strings.Contains(reply.Content, "Update frequency") || !strings.Contains(reply.Content, "Minimum market volume cannot be negative") { t.Fatalf("expected both invalid fields to be reported privately, got %+v", reply) }
+    config, _ := subscriptionService.GetChannelConfig(ctx, "c1")
+    if config.FrequencyMode != "medium" || len(config.AllowedCategories) != 0 { t.Fatalf("expected nothing to be saved, got %+v", config) }
+
+    h.HandleInteraction(session, modalSubmission("channel_setup", map[string]string{"categories": "politics, , Sports", "frequency": "High", "min_volume": "$1,500", "min_buy": ""}))
+    config, _ = subscriptionService.GetChannelConfig(ctx, "c1")
+    if !config.FeedEnabled || config.FrequencyMode != "high" || config.MinVolume != 1500 || config.MinBuyAmount != 0 || config.GuildID != "g1" { t.Fatalf("expected the form to be applied, got %+v", config) }
+    if len(config.AllowedCategories) != 2 || config.AllowedCategories[1] != "Sports" { t.Fatalf("expected two categories, got %q", config.AllowedCategories) }
+
+    // Without the categories option, channel_feed_categories opens a form, and submitting it empty allows every category
+    h.HandleInteraction(session, commandInteraction("channel_feed_categories"))
+    if (*responses)[3].Type == discordgo.InteractionResponseModal { t.Fatalf("expected the form to be refused outside a server") }
+    categories := commandInteraction("channel_feed_categories")
+    categories.GuildID, categories.ChannelID = "g1", "c1"
+    h.HandleInteraction(session, categories)
+    if (*responses)[4].Type != discordgo.InteractionResponseModal || (*responses)[4].Data.CustomID != "channel_feed_categories" { t.Fatalf("expected the categories modal, got %+v", (*responses)[4]) }
+    h.HandleInteraction(session, modalSubmission("channel_feed_categories", map[string]string{"categories": " "}))
+    config, _ = subscriptionService.GetChannelConfig(ctx, "c1")
+    if len(config.AllowedCategories) != 0 { t.Fatalf("expected all categories to be allowed, got %q", config.AllowedCategories) }
+}
+
+func TestChannelMinVolumeFiltersFeed(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true, MinVolume: 1000}, "test")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c2", GuildID: "g2", FeedEnabled: true}, "test")
+
+    // A disconnected gateway buffers the sends, so they can be counted
+    session, _ := discordgo.New("Bot test")
+    h.SetDiscordSession(session)
+    gateway := services.NewGatewayMonitor(0, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+
+    newMarket := func(id string, volume float64) {
+        payload, _ := json.Marshal(map[string]interface{}{"market_id": id, "title": "Volume", "category": "sports", "volume": volume})
+        if _, err := h.ProcessEventJSON(ctx, models.EventNewMarket, payload); err != nil { t.Fatalf("failed to process new market: %v", err) }
+    }
+
+    newMarket("small", 200)
+    if buffered := gateway.Status().Buffered; buffered != 1 { t.Fatalf("expected only c2 to receive a small market, got %d sends", buffered) }
+    newMarket("large", 5000)
+    if buffered := gateway.Status().Buffered; buffered != 3 { t.Fatalf("expected both channels to receive a large market, got %d more sends", buffered - 1) }
+
+    settings := &models.ChannelSettings{FrequencyMode: "low", MinVolume: -1}
+    if err := services.ValidateChannelSettings(settings); err == nil { t.Fatalf("expected a negative minimum volume to be rejected") }
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestBulkChannelSettingsAppliesEveryChannel(t *testing.T) {
+    h := newHarness(t)
+    h.feedChannel("existing", "g1", nil)
+
+    body := `[
+        {"channel_id": "c1", "guild_id": "g1", "settings": {"feed_enabled": true, "frequency_mode": "high", "allowed_categories": ["sports"]}},
+        {"channel_id": "c2", "guild_id": "g1", "settings": {"feed_enabled": false, "frequency_mode": "low", "subscribed_markets": ["m1"], "digest_mode": "daily"}},
+        {"channel_id": "existing", "settings": {"feed_enabled": true, "frequency_mode": "medium", "min_buy_amount": 25}}
+    ]`
+    rec := serveWithKey(h.handler, http.MethodPost, "/discord/channel/settings/bulk", body, harnessAPIKey)
+    if rec.Code != http.StatusOK { t.Fatalf("expected %d got %d: %s", http.StatusOK, rec.Code, rec.Body.String()) }
+    var resp web.BulkChannelSettingsResponse
+    if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil { t.Fatalf("failed to decode response: %v", err) }
+    if len(resp.Channels) != 3 || resp.Channels[0].ChannelID != "c1" || resp.Channels[2].ChannelID != "existing" { t.Fatalf("expected the channels in request order, got %+v", resp.Channels) }
+
+    c2, _ := h.subscriptions.GetChannelConfig(h.ctx, "c2")
+    if c2.GuildID != "g1" || c2.FeedEnabled || c2.FrequencyMode != "low" || len(c2.SubscribedMarkets) != 1 || c2.DigestMode != models.DigestDaily { t.Fatalf("expected c2 configured, got %+v", c2) }
+    existing, _ := h.subscriptions.GetChannelConfig(h.ctx, "existing")
+    if existing.GuildID != "g1" || existing.MinBuyAmount != 25 { t.Fatalf("expected the existing channel updated and its guild kept, got %+v", existing) }
+    entries, _ := h.subscriptions.GetAuditLog(h.ctx, models.AuditFilter{ChannelID: "c1"})
+    if len(entries) != 1 || entries[0].Actor != "api" { t.Fatalf("expected the change in the audit log, got %+v", entries) }
+}
+
+func TestBulkChannelSettingsAppliesNothingWhenAnyIsInvalid(t *testing.T) {
+    h := newHarness(t)
+
+    body := `[
+        {"channel_id": "c1", "settings": {"feed_enabled": true, "frequency_mode": "high"}},
+        {"channel_id": "c2", "settings": {"frequency_mode": "sometimes"}},
+        {"channel_id": "c1", "settings": {"frequency_mode": "low"}},
+        {"settings": {"frequency_mode": "low"}},
+        {"channel_id": "c3"}
+    ]`
+    rec := serveWithKey(h.handler, http.MethodPost, "/discord/channel/settings/bulk", body, harnessAPIKey)
+    if rec.Code != http.StatusBadRequest { t.Fatalf("expected %d got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String()) }
+    var errResp web.ErrorResponse
+    json.Unmarshal(rec.Body.Bytes(), &errResp)
+    invalid, _ := errResp.Details["invalid"].([]interface{})
+    if errResp.Code != web.ErrorCodeInvalidRequest || len(invalid) != 4 { t.Fatalf("expected the four invalid channels listed, got %s", rec.Body.String()) }
+    if first := invalid[0].(map[string]interface{}); first["index"] != float64(1) || first["channel_id"] != "c2" { t.Fatalf("expected each invalid channel identified by index, got %v", first) }
+    if configs, _ := h.subscriptions.GetAllChannelConfigs(h.ctx); len(configs) != 0 { t.Fatalf("expected no channel configured, got %d", len(configs)) }
+
+    for _, body := range []string{`{"channel_id": "c1"}`, `[]`} {
+        if rec := serveWithKey(h.handler, http.MethodPost, "/discord/channel/settings/bulk", body, harnessAPIKey); rec.Code != http.StatusBadRequest { t.Fatalf("expected %s rejected, got %d", body, rec.Code) }
+    }
+
+    // A batch that would take a guild past its channel limit is refused as a whole
+    h.subscriptions.SetSubscriptionLimits(models.SubscriptionLimits{MaxChannels: 2})
+    h.feedChannel("existing", "g1", nil)
+    var channels []string
+    for i := 1; i <= 2; i++ {
+        channels = append(channels, fmt.Sprintf(`{"channel_id": "new%d", "guild_id": "g1", "settings": {"frequency_mode": "low"}}`, i))
+    }
+    rec = serveWithKey(h.handler, http.MethodPost, "/discord/channel/settings/bulk", "["+strings.Join(channels, ",")+"]", harnessAPIKey)
+    if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), web.ErrorCodeLimitExceeded) { t.Fatalf("expected the guild limit enforced, got %d: %s", rec.Code, rec.Body.String()) }
+    if configs, _ := h.subscriptions.GetAllChannelConfigs(h.ctx); len(configs) != 1 { t.Fatalf("expected only the existing channel configured, got %d", len(configs)) }
+}
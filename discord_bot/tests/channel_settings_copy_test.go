@@ -0,0 +1,75 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestChannelSettingsExportImportAndCopy(t *testing.T) {
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    ctx := context.Background()
+
+    source := &models.ChannelConfig{ChannelID: "src", GuildID: "g1", FeedEnabled: false, AllowedCategories: []string{"sports"}, FrequencyMode: "high", SubscribedMarkets: []string{"m1", "m2"}, MinBuyAmount: 50}
+    if err := subscriptionService.UpdateChannelConfig(ctx, source, "u1"); err != nil { t.Fatalf("failed to save source: %v", err) }
+
+    rec := serveWithKey(h, http.MethodGet, "/discord/channel/settings/src/export", "", "")
+    if rec.Code != http.StatusOK { t.Fatalf("expected %d got %d: %s", http.StatusOK, rec.Code, rec.Body.String()) }
+    var exported models.ChannelSettings
+    if err := json.Unmarshal(rec.Body.Bytes(), &exported); err != nil { t.Fatalf("failed to decode export: %v", err) }
+    if exported.Version != models.ChannelSettingsVersion || exported.FrequencyMode != "high" || len(exported.SubscribedMarkets) != 2 || exported.MinBuyAmount != 50 {
+        t.Fatalf("unexpected export %+v", exported)
+    }
+
+    if rec := serveWithKey(h, http.MethodPut, "/discord/channel/settings/dst1", rec.Body.String(), ""); rec.Code != http.StatusOK {
+        t.Fatalf("expected import to succeed, got %d: %s", rec.Code, rec.Body.String())
+    }
+    imported, _ := subscriptionService.GetChannelConfig(ctx, "dst1")
+    if imported.FeedEnabled || imported.FrequencyMode != "high" || len(imported.AllowedCategories) != 1 || imported.MinBuyAmount != 50 {
+        t.Fatalf("expected the settings to be applied, got %+v", imported)
+    }
+
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/settings/copy", `{"source_channel_id": "src", "target_channel_id": "dst2"}`, ""); rec.Code != http.StatusOK {
+        t.Fatalf("expected copy to succeed, got %d: %s", rec.Code, rec.Body.String())
+    }
+    copied, _ := subscriptionService.GetChannelConfig(ctx, "dst2")
+    if copied.ChannelID != "dst2" || len(copied.SubscribedMarkets) != 2 || copied.SubscribedMarkets[1] != "m2" {
+        t.Fatalf("expected the settings to be copied, got %+v", copied)
+    }
+
+    // Changing the copy must not affect the source
+    subscriptionService.UnsubscribeChannelFromMarket(ctx, "dst2", "m1", "u1")
+    if source, _ := subscriptionService.GetChannelConfig(ctx, "src"); len(source.SubscribedMarkets) != 2 { t.Fatalf("expected the source to be unchanged, got %v", source.SubscribedMarkets) }
+
+    entries, _ := subscriptionService.GetAuditLog(ctx, models.AuditFilter{ChannelID: "dst2"})
+    if len(entries) == 0 || entries[len(entries)-1].Actor != "api" { t.Fatalf("expected the copy in the audit log, got %+v", entries) }
+}
+
+func TestChannelSettingsImportValidation(t *testing.T) {
+    h := setupHandler()
+
+    for _, body := range []string{
+        `{"version": 1, "frequency_mode": "hourly"}`,
+        `{"version": 1, "frequency_mode": "low", "min_buy_amount": -1}`,
+        `{"version": 99, "frequency_mode": "low"}`,
+    } {
+        if rec := serveWithKey(h, http.MethodPut, "/discord/channel/settings/c1", body, ""); rec.Code != http.StatusBadRequest {
+            t.Fatalf("expected %d for %s, got %d", http.StatusBadRequest, body, rec.Code)
+        }
+    }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/settings/copy", `{"source_channel_id": "c1", "target_channel_id": "c1"}`, ""); rec.Code != http.StatusBadRequest {
+        t.Fatalf("expected %d when copying a channel onto itself, got %d", http.StatusBadRequest, rec.Code)
+    }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/settings/copy", `{"source_channel_id": "c1"}`, ""); rec.Code != http.StatusBadRequest {
+        t.Fatalf("expected %d without a target, got %d", http.StatusBadRequest, rec.Code)
+    }
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+)
+
+func TestShouldSendUpdateFollowsTheClock(t *testing.T) {
+    logger := utils.NewLogger()
+    clock := services.NewFakeClock(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+    marketService := services.NewMarketService("", logger)
+    marketService.SetClock(clock)
+    market := &models.Market{ID: "m1", Status: "active", EndTime: clock.Now().Add(48 * time.Hour)}
+    lastUpdate := clock.Now().Add(-45 * time.Minute)
+
+    if !marketService.ShouldSendUpdate(market, "high", lastUpdate) { t.Fatalf("expected a high frequency update after 45 minutes") }
+    if marketService.ShouldSendUpdate(market, "medium", lastUpdate) { t.Fatalf("expected no medium frequency update after 45 minutes") }
+    clock.Advance(15 * time.Minute)
+    if !marketService.ShouldSendUpdate(market, "medium", lastUpdate) { t.Fatalf("expected a medium frequency update after an hour") }
+    if marketService.ShouldSendUpdate(market, "low", lastUpdate) { t.Fatalf("expected no low frequency update after an hour") }
+
+    // In its last six hours a market updates every 15 minutes whatever the frequency
+    clock.Set(market.EndTime.Add(-5 * time.Hour))
+    if !marketService.ShouldSendUpdate(market, "low", clock.Now().Add(-15*time.Minute)) { t.Fatalf("expected a closing market to update every 15 minutes") }
+    if marketService.ShouldSendUpdate(market, "low", clock.Now().Add(-10*time.Minute)) { t.Fatalf("expected no update within 15 minutes") }
+}
+
+func TestServicesUseTheirClockAndIDs(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+    clock := services.NewFakeClock(now)
+    ids := &services.SequentialIDs{}
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    subscriptionService.SetClock(clock)
+    subscriptionService.SetIDGenerator(ids)
+
+    registration, err := subscriptionService.RegisterWebhook(ctx, &models.WebhookRegistration{ChannelID: "c1", WebhookURL: "https://discord.com/api/webhooks/1/x"}, "test")
+    if err != nil || registration.ID != "wh_id1" || !registration.CreatedAt.Equal(now) { t.Fatalf("expected webhook wh_id1 created at the clock's time, got %+v, %v", registration, err) }
+    if until, _ := subscriptionService.SnoozeNotifications(ctx, "u1", 2*time.Hour); !until.Equal(now.Add(2 * time.Hour)) { t.Fatalf("expected the snooze to end two hours after the clock's time, got %v", until) }
+
+    marketService := services.NewMockMarketService(logger)
+    marketService.SetMarket(&models.Market{ID: "m1", Title: "Fixed", Status: "active", EndTime: now.Add(3 * time.Hour)})
+    reminderService := services.NewReminderService(repo, marketService, newRecordingNotifier(), logger)
+    reminderService.SetClock(clock)
+    reminderService.SetIDGenerator(ids)
+    reminder, err := reminderService.CreateUserReminder(ctx, "u1", "m1", time.Hour)
+    if err != nil || reminder.ID != "rem_id3" || !reminder.CreatedAt.Equal(now) || !reminder.RemindAt.Equal(now.Add(2*time.Hour)) { t.Fatalf("expected reminder rem_id3, after the webhook and its audit entry, due two hours from the clock's time, got %+v, %v", reminder, err) }
+
+    clock.Advance(2*time.Hour + time.Minute)
+    if _, err := reminderService.CreateUserReminder(ctx, "u1", "m1", time.Hour); err == nil { t.Fatalf("expected a reminder for a market closing within the hour to be refused") }
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestClosingSoonFeedAnnouncesOncePerChannel(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    marketService := services.NewMockMarketService(logger)
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    notifier := newRecordingNotifier()
+    closingSoonService := services.NewClosingSoonService(repo, marketService, notifier, logger)
+    now := time.Now()
+
+    marketService.SetMarket(&models.Market{ID: "soon", Title: "Closing Soon", Category: "sports", Status: "active", EndTime: now.Add(10 * time.Hour)})
+    marketService.SetMarket(&models.Market{ID: "later", Title: "Closing Later", Category: "sports", Status: "active", EndTime: now.Add(30 * time.Hour)})
+    marketService.SetMarket(&models.Market{ID: "closed", Title: "Already Closed", Category: "sports", Status: "closed", EndTime: now.Add(time.Hour)})
+    marketService.SetMarket(&models.Market{ID: "politics", Title: "Other Category", Category: "politics", Status: "active", EndTime: now.Add(time.Hour)})
+
+    save := func(config *models.ChannelConfig) {
+        if err := subscriptionService.UpdateChannelConfig(ctx, config, "u1"); err != nil { t.Fatalf("failed to save config: %v", err) }
+    }
+    save(&models.ChannelConfig{ChannelID: "feed", GuildID: "g1", FeedEnabled: true, AllowedCategories: []string{"sports"}, ClosingSoonHours: 24})
+    save(&models.ChannelConfig{ChannelID: "wide", GuildID: "g1", FeedEnabled: true, ClosingSoonHours: 48})
+    save(&models.ChannelConfig{ChannelID: "off", GuildID: "g1", FeedEnabled: true, ClosingSoonHours: 0})
+    save(&models.ChannelConfig{ChannelID: "follower", GuildID: "g1", FeedEnabled: false, SubscribedMarkets: []string{"later"}, ClosingSoonHours: 48})
+    if err := subscriptionService.UpdateGuildConfig(ctx, &models.GuildConfig{GuildID: "g2", DefaultChannelID: "g2-default"}); err != nil { t.Fatalf("failed to save guild: %v", err) }
+
+    if sent := closingSoonService.ProcessClosingSoon(ctx, now); sent != 7 { t.Fatalf("expected 7 announcements, got %d: %v", sent, notifier.channelMessages) }
+    for channel, want := range map[string]int{"feed": 1, "wide": 3, "off": 0, "follower": 1, "g2-default": 2} {
+        if got := len(notifier.channelMessages[channel]); got != want { t.Fatalf("expected %d announcements in %s, got %d", want, channel, got) }
+    }
+
+    if sent := closingSoonService.ProcessClosingSoon(ctx, now.Add(time.Minute)); sent != 0 { t.Fatalf("expected markets to be announced once per channel, got %d", sent) }
+
+    // 24-hour windows open for the later market once it is within 24 hours of closing
+    if sent := closingSoonService.ProcessClosingSoon(ctx, now.Add(7*time.Hour)); sent != 2 || len(notifier.channelMessages["feed"]) != 2 || len(notifier.channelMessages["g2-default"]) != 3 {
+        t.Fatalf("expected the later market in the 24-hour channels, got %d: %v", sent, notifier.channelMessages)
+    }
+}
+
+func TestChannelClosingSoonEndpoint(t *testing.T) {
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+
+    if cfg, _ := subscriptionService.GetChannelConfig(context.Background(), "c1"); cfg.ClosingSoonHours != models.DefaultClosingSoonHours {
+        t.Fatalf("expected a default window of %d hours, got %d", models.DefaultClosingSoonHours, cfg.ClosingSoonHours)
+    }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/feed/closing_soon", `{"channel_id": "c1", "hours": 6}`, ""); rec.Code != http.StatusOK {
+        t.Fatalf("expected %d got %d", http.StatusOK, rec.Code)
+    }
+    if cfg, _ := subscriptionService.GetChannelConfig(context.Background(), "c1"); cfg.ClosingSoonHours != 6 { t.Fatalf("expected 6 hours, got %d", cfg.ClosingSoonHours) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/feed/closing_soon", `{"channel_id": "c1", "hours": 500}`, ""); rec.Code != http.StatusBadRequest {
+        t.Fatalf("expected %d got %d", http.StatusBadRequest, rec.Code)
+    }
+}
@@ -0,0 +1,62 @@
+package tests
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// stubCommandSyncer returns a fixed sync result or error
+type stubCommandSyncer struct {
+    result models.CommandSyncResult
+    err    error
+    syncs  int
+}
+
+func (s *stubCommandSyncer) ResyncCommands(session *discordgo.Session) (models.CommandSyncResult, error) {
+    s.syncs++
+    return s.result, s.err
+}
+
+func TestResyncCommandsEndpoint(t *testing.T) {
+    h := newHarness(t)
+    if rec := serveWithKey(h.handler, http.MethodPost, "/discord/admin/commands/resync", "", harnessAPIKey); rec.Code != http.StatusServiceUnavailable { t.Fatalf("expected 503 without a syncer, got %d", rec.Code) }
+
+    syncer := &stubCommandSyncer{result: models.CommandSyncResult{Scope: "global", Created: 1, Updated: 2, Unchanged: 30}}
+    h.handler.SetCommandSyncer(syncer)
+    rec := serveWithKey(h.handler, http.MethodPost, "/discord/admin/commands/resync", "", harnessAPIKey)
+    var result models.CommandSyncResult
+    json.Unmarshal(rec.Body.Bytes(), &result)
+    if rec.Code != http.StatusOK || syncer.syncs != 1 || result != syncer.result { t.Fatalf("expected the sync result, got %d: %s", rec.Code, rec.Body.String()) }
+
+    syncer.err = errors.New("rate limited")
+    rec = serveWithKey(h.handler, http.MethodPost, "/discord/admin/commands/resync", "", harnessAPIKey)
+    if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "rate limited") { t.Fatalf("expected the sync error, got %d: %s", rec.Code, rec.Body.String()) }
+}
+
+func TestResyncCommandsIsOwnerOnly(t *testing.T) {
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    h := handlers.NewCommandHandler(services.NewMockMarketService(logger), nil, nil, services.NewAnalyticsService(repo, logger), logger)
+    h.SetOwners([]string{"owner"})
+
+    h.HandleInteraction(session, commandInteraction("admin_resync_commands"))
+    if len(*responses) != 1 || !strings.Contains((*responses)[0].Data.Content, "restricted to bot owners") { t.Fatalf("expected non-owners refused, got %+v", *responses) }
+}
+
+func TestSyncCommandsReportsTheirScope(t *testing.T) {
+    command := []*discordgo.ApplicationCommand{{Name: "cmd", Description: "A command"}}
+    if result, _ := handlers.SyncCommands(newFakeRegistrar(), "app", "", command); result.Scope != "global" { t.Fatalf("expected a global sync, got %+v", result) }
+    if result, _ := handlers.SyncCommands(newFakeRegistrar(), "app", "guild", command); result.Scope != "guild" { t.Fatalf("expected a guild sync, got %+v", result) }
+}
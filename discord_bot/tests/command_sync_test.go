@@ -0,0 +1,107 @@
+package tests
+
+import (
+    "encoding/json"
+    "fmt"
+    "testing"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// fakeRegistrar keeps registered commands in memory, round-tripping them through JSON like Discord does
+type fakeRegistrar struct {
+    commands map[string]*discordgo.ApplicationCommand // ID to command
+    nextID   int
+    created  []string
+    edited   []string
+    deleted  []string
+}
+
+func newFakeRegistrar() *fakeRegistrar {
+    return &fakeRegistrar{commands: map[string]*discordgo.ApplicationCommand{}}
+}
+
+func (r *fakeRegistrar) store(id string, cmd *discordgo.ApplicationCommand) *discordgo.ApplicationCommand {
+    encoded, _ := json.Marshal(cmd)
+    var stored discordgo.ApplicationCommand
+    json.Unmarshal(encoded, &stored)
+    stored.ID = id
+    stored.Type = discordgo.ChatApplicationCommand
+    r.commands[id] = &stored
+    return &stored
+}
+
+func (r *fakeRegistrar) ApplicationCommands(appID, guildID string, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+    var commands []*discordgo.ApplicationCommand
+    for _, cmd := range r.commands {
+        commands = append(commands, cmd)
+    }
+    return commands, nil
+}
+
+func (r *fakeRegistrar) ApplicationCommandCreate(appID, guildID string, cmd *discordgo.ApplicationCommand, options ...discordgo.RequestOption) (*discordgo.ApplicationCommand, error) {
+    r.nextID++
+    r.created = append(r.created, cmd.Name)
+    return r.store(fmt.Sprintf("cmd-%d", r.nextID), cmd), nil
+}
+
+func (r *fakeRegistrar) ApplicationCommandEdit(appID, guildID, cmdID string, cmd *discordgo.ApplicationCommand, options ...discordgo.RequestOption) (*discordgo.ApplicationCommand, error) {
+    r.edited = append(r.edited, cmd.Name)
+    return r.store(cmdID, cmd), nil
+}
+
+func (r *fakeRegistrar) ApplicationCommandDelete(appID, guildID, cmdID string, options ...discordgo.RequestOption) error {
+    r.deleted = append(r.deleted, r.commands[cmdID].Name)
+    delete(r.commands, cmdID)
+    return nil
+}
+
+func (r *fakeRegistrar) ApplicationCommandBulkOverwrite(appID, guildID string, commands []*discordgo.ApplicationCommand, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+    r.commands = map[string]*discordgo.ApplicationCommand{}
+    for _, cmd := range commands {
+        r.ApplicationCommandCreate(appID, guildID, cmd)
+    }
+    return nil, nil
+}
+
+func TestSyncCommandsOnlyChangesWhatDiffers(t *testing.T) {
+    logger := utils.NewLogger()
+    h := handlers.NewCommandHandler(services.NewMockMarketService(logger), nil, nil, nil, logger)
+    registrar := newFakeRegistrar()
+
+    result, err := handlers.SyncCommands(registrar, "app", "", h.Commands())
+    if err != nil { t.Fatalf("first sync failed: %v", err) }
+    if result.Created != len(h.Commands()) || result.Updated+result.Deleted+result.Unchanged != 0 { t.Fatalf("expected every command to be created, got %+v", result) }
+
+    // A reboot with the same commands changes nothing
+    result, err = handlers.SyncCommands(registrar, "app", "", h.Commands())
+    if err != nil { t.Fatalf("second sync failed: %v", err) }
+    if result.Unchanged != len(h.Commands()) || result.Created+result.Updated+result.Deleted != 0 { t.Fatalf("expected no changes on an identical sync, got %+v (edited %v)", result, registrar.edited) }
+
+    // A changed description is updated, a removed command deleted and a new one created
+    desired := h.Commands()
+    desired[0].Description = "A new description"
+    removed := desired[1].Name
+    desired = append(desired[:1], desired[2:]...)
+    desired = append(desired, &discordgo.ApplicationCommand{Name: "brand_new", Description: "Just added"})
+    result, err = handlers.SyncCommands(registrar, "app", "", desired)
+    if err != nil { t.Fatalf("third sync failed: %v", err) }
+    if result.Created != 1 || result.Updated != 1 || result.Deleted != 1 { t.Fatalf("expected one create, update and delete, got %+v", result) }
+    if registrar.edited[0] != desired[0].Name || registrar.deleted[0] != removed { t.Fatalf("unexpected changes: edited %v, deleted %v", registrar.edited, registrar.deleted) }
+}
+
+func TestSyncCommandsComparesDMPermissionOnlyGlobally(t *testing.T) {
+    registrar := newFakeRegistrar()
+    allow, deny := true, false
+    handlers.SyncCommands(registrar, "app", "guild", []*discordgo.ApplicationCommand{{Name: "cmd", Description: "A command", DMPermission: &allow}})
+
+    result, _ := handlers.SyncCommands(registrar, "app", "guild", []*discordgo.ApplicationCommand{{Name: "cmd", Description: "A command", DMPermission: &deny}})
+    if result.Unchanged != 1 { t.Fatalf("expected the DM permission of a guild command to be ignored, got %+v", result) }
+
+    result, _ = handlers.SyncCommands(registrar, "app", "", []*discordgo.ApplicationCommand{{Name: "cmd", Description: "A command", DMPermission: &deny}})
+    if result.Updated != 1 { t.Fatalf("expected the DM permission of a global command to be compared, got %+v", result) }
+}
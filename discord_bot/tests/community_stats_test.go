@@ -0,0 +1,102 @@
+package tests
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestCommunityStatsSummarizeAGuildsWeek(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    analytics := services.NewAnalyticsService(repo, logger)
+    game := services.NewGameService(repo, 0, logger)
+    markets := services.NewMockMarketService(logger)
+    now := time.Now()
+
+    repo.SaveChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", SubscribedMarkets: []string{"m1", "m2"}})
+    repo.SaveChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c2", GuildID: "g1", SubscribedMarkets: []string{"m2"}})
+    repo.SaveChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c3", GuildID: "g2"})
+    analytics.RecordDelivery(ctx, models.EventMarketUpdate, "c1", nil)
+    analytics.RecordDelivery(ctx, models.EventMarketUpdate, "c1", nil)
+    analytics.RecordDelivery(ctx, models.EventNewMarket, "c2", nil)
+    analytics.RecordDelivery(ctx, models.EventNewMarket, "c2", errors.New("missing access"))
+    analytics.RecordDelivery(ctx, models.EventNewMarket, "c3", nil)
+    repo.SaveAnalyticsEvent(ctx, &models.AnalyticsEvent{Kind: models.AnalyticsSubscribe, Name: "channel_market", Subject: "c1", Timestamp: now})
+
+    market := tradableMarket()
+    game.PlaceBet(ctx, "g1", "u1", market, "Yes", 50)
+    game.PlaceBet(ctx, "g1", "u2", market, "No", 20)
+    game.PlaceBet(ctx, "g1", "u2", market, "Yes", 10)
+    game.PlaceBet(ctx, "g2", "u3", market, "Yes", 500)
+
+    markets.SetMarket(&models.Market{ID: "big", Title: "Big Resolution", Status: "resolved", ResolvedOutcome: "Yes", Volume: 25000, EndTime: now.Add(-time.Hour), Link: "https://coral.markets/big"})
+    markets.SetMarket(&models.Market{ID: "small", Title: "Small Resolution", Status: "resolved", ResolvedOutcome: "No", Volume: 300, EndTime: now.Add(-48 * time.Hour)})
+    markets.SetMarket(&models.Market{ID: "old", Title: "Old Resolution", Status: "resolved", ResolvedOutcome: "No", Volume: 90000, EndTime: now.Add(-10 * 24 * time.Hour)})
+
+    stats := services.NewCommunityStatsService(repo, newRecordingNotifier(), services.DigestSchedule{Hour: 9}, logger)
+    stats.SetMarketService(markets)
+    message, err := stats.BuildStats(ctx, "g1", now.Add(time.Minute))
+    if err != nil { t.Fatalf("failed to build stats: %v", err) }
+    for _, want := range []string{"Weekly Community Stats", "3 alerts posted in 2 channels: 2 updates, 1 new market", "1 could not be delivered", "2 markets followed by this server's channels, 1 new this week", "• <@u2> — 2 bets, $30.00 staked\n• <@u1> — 1 bet, $50.00 staked", "[Big Resolution](https://coral.markets/big) — $25.0K volume, Yes\n• Small Resolution"} {
+        if !strings.Contains(message, want) { t.Fatalf("expected stats to contain %q, got:\n%s", want, message) }
+    }
+    if strings.Contains(message, "u3") || strings.Contains(message, "Old Resolution") { t.Fatalf("expected other guilds and older weeks to be left out, got:\n%s", message) }
+
+    quiet := services.NewCommunityStatsService(repo, newRecordingNotifier(), services.DigestSchedule{Hour: 9}, logger)
+    if message, _ := quiet.BuildStats(ctx, "g3", now.Add(time.Minute)); !strings.Contains(message, "A quiet week") || strings.Contains(message, "Alerts") { t.Fatalf("expected a quiet week for a guild without activity, got:\n%s", message) }
+}
+
+func TestCommunityStatsPostWeeklyInOptedInGuilds(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    notifier := newRecordingNotifier()
+    stats := services.NewCommunityStatsService(repo, notifier, services.DigestSchedule{Hour: 9, Weekday: time.Monday}, logger)
+    repo.SaveGuildConfig(ctx, &models.GuildConfig{GuildID: "g1", StatsChannelID: "c1"})
+    repo.SaveGuildConfig(ctx, &models.GuildConfig{GuildID: "g2"})
+
+    // Wednesday 4 March 2026; the first pass only starts the schedule
+    start := time.Date(2026, time.March, 4, 10, 0, 0, 0, time.UTC)
+    if sent := stats.ProcessDueStats(ctx, start); sent != 0 { t.Fatalf("expected no stats right after opting in, sent %d", sent) }
+    if sent := stats.ProcessDueStats(ctx, start.Add(4*24*time.Hour+22*time.Hour)); sent != 0 { t.Fatalf("expected no stats before Monday 09:00, sent %d", sent) }
+    if sent := stats.ProcessDueStats(ctx, start.Add(4*24*time.Hour+23*time.Hour)); sent != 1 { t.Fatalf("expected stats on Monday 09:00, sent %d", sent) }
+    if sent := stats.ProcessDueStats(ctx, start.Add(6*24*time.Hour)); sent != 0 { t.Fatalf("expected stats once a week, sent %d", sent) }
+    if len(notifier.channelMessages) != 1 || len(notifier.channelMessages["c1"]) != 1 || !strings.Contains(notifier.channelMessages["c1"][0], "Weekly Community Stats") { t.Fatalf("unexpected deliveries %v", notifier.channelMessages) }
+
+    // A deleted stats channel stops the posts
+    if err := services.NewSubscriptionService(repo, logger).RemoveChannel(ctx, "c1", "system"); err != nil { t.Fatalf("failed to remove channel: %v", err) }
+    if guild, _ := repo.GetGuildConfig(ctx, "g1"); guild.StatsChannelID != "" { t.Fatalf("expected the stats channel to be cleared, got %+v", guild) }
+}
+
+func TestCommunityStatsCommand(t *testing.T) {
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    h := handlers.NewCommandHandler(services.NewMockMarketService(logger), services.NewSubscriptionService(repo, logger), nil, services.NewAnalyticsService(repo, logger), logger)
+    run := func(setting string) *discordgo.InteractionResponseData {
+        interaction := commandInteraction("community_stats", &discordgo.ApplicationCommandInteractionDataOption{Name: "setting", Type: discordgo.ApplicationCommandOptionString, Value: setting})
+        interaction.GuildID, interaction.ChannelID = "g1", "c1"
+        h.HandleInteraction(session, interaction)
+        return (*responses)[len(*responses)-1].Data
+    }
+
+    if reply := run("on"); !strings.Contains(reply.Content, "not enabled on this bot") { t.Fatalf("expected the command to need the service, got %+v", reply) }
+    h.SetCommunityStatsService(services.NewCommunityStatsService(repo, newRecordingNotifier(), services.DigestSchedule{Hour: 9}, logger))
+    if reply := run("on"); !strings.Contains(reply.Content, "community stats every week") { t.Fatalf("unexpected reply %+v", reply) }
+    if guild, _ := repo.GetGuildConfig(context.Background(), "g1"); guild == nil || guild.StatsChannelID != "c1" { t.Fatalf("expected stats to be posted in the channel, got %+v", guild) }
+    if reply := run("preview"); reply.Flags&discordgo.MessageFlagsEphemeral == 0 || !strings.Contains(reply.Content, "Weekly Community Stats") { t.Fatalf("expected a private preview, got %+v", reply) }
+    run("off")
+    if guild, _ := repo.GetGuildConfig(context.Background(), "g1"); guild.StatsChannelID != "" { t.Fatalf("expected stats to stop, got %+v", guild) }
+}
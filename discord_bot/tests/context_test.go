@@ -0,0 +1,50 @@
+package tests
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+)
+
+func TestCancelledContextStopsSubscriptionChanges(t *testing.T) {
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, utils.NewLogger())
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if err := subscriptionService.SubscribeToMarket(ctx, "u1", "m1"); !errors.Is(err, context.Canceled) {
+        t.Fatalf("expected context.Canceled, got %v", err)
+    }
+
+    sub, err := subscriptionService.GetUserSubscriptions(context.Background(), "u1")
+    if err != nil { t.Fatalf("unexpected error: %v", err) }
+    if len(sub.SubscribedMarkets) != 0 {
+        t.Fatalf("expected no subscription to be saved, got %v", sub.SubscribedMarkets)
+    }
+}
+
+func TestFetchMarketHonorsContextDeadline(t *testing.T) {
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        <-r.Context().Done()
+    }))
+    defer backend.Close()
+
+    marketService := services.NewMarketService(backend.URL, utils.NewLogger())
+    ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+    defer cancel()
+
+    start := time.Now()
+    if _, err := marketService.FetchMarket(ctx, "m1"); !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+    }
+    if elapsed := time.Since(start); elapsed > services.BackendTimeout/2 {
+        t.Fatalf("expected the caller's deadline to cut the request short, took %s", elapsed)
+    }
+}
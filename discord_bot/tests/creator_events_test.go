@@ -0,0 +1,52 @@
+package tests
+
+import (
+    "context"
+    "net/http"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestCreatorEventsReachCreatorSubscribers(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    subscriptionService.SubscribeToCreator(ctx, "u1", "alice")
+    subscriptionService.SubscribeToCreator(ctx, "u2", "alice")
+    subscriptionService.SubscribeToCreator(ctx, "u3", "bob")
+    subscriptionService.SubscribeToMarket(ctx, "u4", "m1")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "test")
+
+    // A disconnected gateway buffers the sends, so they can be counted
+    session, _ := discordgo.New("Bot test")
+    h.SetDiscordSession(session)
+    gateway := services.NewGatewayMonitor(0, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/creator-joined", `{"creator": "alice", "display_name": "Alice", "bio": "Sports markets"}`, "root-key"); rec.Code != http.StatusAccepted { t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String()) }
+    if buffered := gateway.Status().Buffered; buffered != 2 { t.Fatalf("expected only alice's two subscribers to be sent to, got %d sends", buffered) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/creator-milestone", `{"creator": "bob", "volume": 100000}`, "root-key"); rec.Code != http.StatusAccepted { t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String()) }
+    if buffered := gateway.Status().Buffered; buffered != 3 { t.Fatalf("expected bob's subscriber to be sent to, got %d sends", buffered) }
+
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/creator-joined", `{"display_name": "Nobody"}`, "root-key"); rec.Code != http.StatusBadRequest { t.Fatalf("expected 400 without a creator, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/creator-milestone", `{"creator": "bob"}`, "root-key"); rec.Code != http.StatusBadRequest { t.Fatalf("expected 400 without a volume, got %d", rec.Code) }
+}
+
+func TestCreatorEventMessages(t *testing.T) {
+    marketService := services.NewMockMarketService(utils.NewLogger())
+    joined := marketService.CreateCreatorJoinedMessage(&models.Creator{Name: "alice", DisplayName: "Alice", Bio: "Sports markets", Link: "https://coral.markets/creator/alice"})
+    if !strings.Contains(joined, "**Alice** joined") || !strings.Contains(joined, "Sports markets") || !strings.Contains(joined, "https://coral.markets/creator/alice") { t.Fatalf("unexpected message %q", joined) }
+    milestone := marketService.CreateCreatorMilestoneMessage(&models.Creator{Name: "bob", Volume: 100000})
+    if !strings.Contains(milestone, "**bob** passed $100000.00") { t.Fatalf("unexpected message %q", milestone) }
+}
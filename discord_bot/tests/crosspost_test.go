@@ -0,0 +1,77 @@
+package tests
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "testing"
+
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// crosspostSession returns a session whose state holds a guild with an announcement channel, a text
+// channel and an announcement channel the bot cannot send messages in
+func crosspostSession() *discordgo.Session {
+    session, _ := discordgo.New("Bot test")
+    session.State.User = &discordgo.User{ID: "bot"}
+    session.State.GuildAdd(&discordgo.Guild{ID: "g1", OwnerID: "owner", Roles: []*discordgo.Role{
+        {ID: "g1", Permissions: discordgo.PermissionViewChannel | discordgo.PermissionSendMessages},
+    }})
+    session.State.MemberAdd(&discordgo.Member{GuildID: "g1", User: &discordgo.User{ID: "bot"}})
+    session.State.ChannelAdd(&discordgo.Channel{ID: "news", GuildID: "g1", Type: discordgo.ChannelTypeGuildNews})
+    session.State.ChannelAdd(&discordgo.Channel{ID: "text", GuildID: "g1", Type: discordgo.ChannelTypeGuildText})
+    session.State.ChannelAdd(&discordgo.Channel{ID: "locked", GuildID: "g1", Type: discordgo.ChannelTypeGuildNews, PermissionOverwrites: []*discordgo.PermissionOverwrite{
+        {ID: "g1", Type: discordgo.PermissionOverwriteTypeRole, Deny: discordgo.PermissionSendMessages},
+    }})
+    return session
+}
+
+func TestCheckCrosspost(t *testing.T) {
+    ctx := context.Background()
+    session := crosspostSession()
+
+    if err := services.CheckCrosspost(ctx, session, "news"); err != nil { t.Fatalf("expected an announcement channel to pass, got %v", err) }
+    if err := services.CheckCrosspost(ctx, session, "text"); !errors.Is(err, services.ErrNotAnnouncementChannel) { t.Fatalf("expected a text channel to be rejected, got %v", err) }
+    if err := services.CheckCrosspost(ctx, session, "locked"); !errors.Is(err, services.ErrMissingCrosspostPermission) { t.Fatalf("expected a channel without Send Messages to be rejected, got %v", err) }
+}
+
+func TestChannelCrosspostSetting(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+
+    if err := subscriptionService.SetChannelCrosspost(ctx, "news", "g1", true, "admin"); err != nil { t.Fatalf("failed to turn crossposting on: %v", err) }
+    config, _ := subscriptionService.GetChannelConfig(ctx, "news")
+    if !config.Crosspost || config.GuildID != "g1" { t.Fatalf("expected crossposting on in g1, got %+v", config) }
+    if !config.Settings().Crosspost { t.Fatalf("expected crossposting to be exported with the channel settings") }
+
+    copied, err := subscriptionService.CopyChannelSettings(ctx, "news", "other", "g1", "admin")
+    if err != nil || !copied.Crosspost { t.Fatalf("expected crossposting to be copied, got %+v, %v", copied, err) }
+
+    if err := subscriptionService.SetChannelCrosspost(ctx, "news", "g1", false, "admin"); err != nil { t.Fatalf("failed to turn crossposting off: %v", err) }
+    config, _ = subscriptionService.GetChannelConfig(ctx, "news")
+    if config.Crosspost { t.Fatalf("expected crossposting off") }
+}
+
+func TestChannelCrosspostEndpoint(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/crosspost", `{"enabled": true}`, "root-key"); rec.Code != http.StatusBadRequest { t.Fatalf("expected 400 without a channel, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/crosspost", `{"channel_id": "news", "enabled": true}`, "root-key"); rec.Code != http.StatusServiceUnavailable { t.Fatalf("expected 503 without a Discord session, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/crosspost", `{"channel_id": "news", "enabled": false}`, "root-key"); rec.Code != http.StatusOK { t.Fatalf("expected turning crossposting off to need no session, got %d", rec.Code) }
+
+    h.SetDiscordSession(crosspostSession())
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/crosspost", `{"channel_id": "text", "enabled": true}`, "root-key"); rec.Code != http.StatusConflict { t.Fatalf("expected 409 for a text channel, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/crosspost", `{"channel_id": "news", "enabled": true}`, "root-key"); rec.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String()) }
+
+    config, _ := subscriptionService.GetChannelConfig(context.Background(), "news")
+    if !config.Crosspost { t.Fatalf("expected crossposting to be on") }
+}
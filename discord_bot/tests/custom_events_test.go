@@ -0,0 +1,96 @@
+package tests
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/services"
+)
+
+// featuredEvent registers a market_featured event, posted as {"market_id", "title", "reason"}
+func featuredEvent(routing services.EventRoutingPolicy) services.CustomEventType {
+    return services.CustomEventType{
+        Name:     "market_featured",
+        Template: "⭐ **{{.Market.Title}}** is featured: {{.Fields.reason}}",
+        Routing:  routing,
+        Decode: func(payload json.RawMessage) (*services.CustomEvent, error) {
+            var body struct {
+                MarketID string `json:"market_id"`
+                Title    string `json:"title"`
+                Reason   string `json:"reason"`
+            }
+            if err := json.Unmarshal(payload, &body); err != nil {
+                return nil, err
+            }
+            if body.Reason == "" {
+                return nil, errors.New("reason required")
+            }
+            return &services.CustomEvent{Market: models.Market{ID: body.MarketID, Title: body.Title}, Fields: map[string]interface{}{"reason": body.Reason}}, nil
+        },
+    }
+}
+
+func TestCustomEventsAreServedAndDelivered(t *testing.T) {
+    h := newHarness(t)
+    registry := services.NewEventRegistry()
+    if err := registry.Register(featuredEvent(services.RouteToFeed)); err != nil { t.Fatalf("failed to register: %v", err) }
+    h.handler.SetEventRegistry(registry)
+    h.feedChannel("c1", "g1", nil)
+    h.subscriptions.SubscribeToMarket(h.ctx, "u1", "m1")
+
+    rec := serveWithKey(h.handler, http.MethodPost, "/discord/events/market-featured", `{"market_id": "m1", "title": "Market m1", "reason": "most traded today"}`, harnessAPIKey)
+    if rec.Code != http.StatusAccepted { t.Fatalf("expected the event accepted, got %d: %s", rec.Code, rec.Body.String()) }
+    messages := h.discord.channelMessages("c1")
+    if len(messages) != 1 || messages[0].Content != "⭐ **Market m1** is featured: most traded today" { t.Fatalf("unexpected channel messages %+v", messages) }
+    if dms := h.discord.directMessages("u1"); len(dms) != 1 || !strings.Contains(dms[0].Content, "is featured") { t.Fatalf("expected the subscriber DMed, got %+v", dms) }
+
+    // Batches and the bus decode custom events by their type too
+    if _, err := h.handler.ProcessEventJSON(h.ctx, "market_featured", json.RawMessage(`{"market_id": "m2", "title": "Market m2", "reason": "new"}`)); err != nil { t.Fatalf("failed to process: %v", err) }
+    if len(h.discord.channelMessages("c1")) != 2 { t.Fatalf("expected the processed event posted, got %+v", h.discord.messages) }
+
+    cases := map[string]string{
+        `{"market_id": "m1", "title": "Market m1"}`: "reason required",
+        `{"title": "Market m1", "reason": "new"}`:   "market_id required",
+        `{"market_id": `:                            "Invalid JSON",
+    }
+    for body, expected := range cases {
+        rec := serveWithKey(h.handler, http.MethodPost, "/discord/events/market-featured", body, harnessAPIKey)
+        if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), expected) { t.Fatalf("expected %q for %s, got %d: %s", expected, body, rec.Code, rec.Body.String()) }
+    }
+    if rec := serveWithKey(h.handler, http.MethodGet, "/discord/openapi.json", "", harnessAPIKey); !strings.Contains(rec.Body.String(), "/discord/events/market-featured") { t.Fatalf("expected the custom endpoint documented") }
+}
+
+func TestCustomEventsRoutedToSubscribers(t *testing.T) {
+    h := newHarness(t)
+    registry := services.NewEventRegistry()
+    registry.Register(featuredEvent(services.RouteToSubscribers))
+    h.handler.SetEventRegistry(registry)
+    h.feedChannel("c1", "g1", nil)
+    h.subscriptions.SubscribeToMarket(h.ctx, "u1", "m1")
+    h.subscriptions.SubscribeToMarket(h.ctx, "u2", "m2")
+
+    if _, err := h.handler.ProcessEventJSON(h.ctx, "market_featured", json.RawMessage(`{"market_id": "m1", "title": "Market m1", "reason": "new"}`)); err != nil { t.Fatalf("failed to process: %v", err) }
+    if len(h.discord.channelMessages("c1")) != 0 || len(h.discord.directMessages("u2")) != 0 { t.Fatalf("expected only the market's subscribers messaged, got %+v", h.discord.messages) }
+    if len(h.discord.directMessages("u1")) != 1 { t.Fatalf("expected u1 DMed, got %+v", h.discord.messages) }
+}
+
+func TestCustomEventRegistration(t *testing.T) {
+    registry := services.NewEventRegistry()
+    if err := registry.Register(featuredEvent(services.RouteToFeed)); err != nil { t.Fatalf("failed to register: %v", err) }
+
+    invalid := map[string]services.CustomEventType{
+        "duplicate":     featuredEvent(services.RouteToFeed),
+        "built-in name": {Name: models.EventNewMarket, Template: "x", Decode: featuredEvent(services.RouteToFeed).Decode},
+        "bad name":      {Name: "Market Featured", Template: "x", Decode: featuredEvent(services.RouteToFeed).Decode},
+        "no decoder":    {Name: "market_pinned", Template: "x"},
+        "bad template":  {Name: "market_pinned", Template: "{{.Market.Title", Decode: featuredEvent(services.RouteToFeed).Decode},
+    }
+    for name, eventType := range invalid {
+        if err := registry.Register(eventType); !errors.Is(err, services.ErrInvalidCustomEvent) { t.Fatalf("expected the %s rejected, got %v", name, err) }
+    }
+    if types := registry.Types(); len(types) != 1 || types[0].Name != "market_featured" { t.Fatalf("unexpected types %+v", types) }
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestDeadLetters(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    discord := serveChannelMessages(t)
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    h.SetDiscordSession(discord.session)
+
+    if rec := serveWithKey(h, http.MethodGet, "/discord/admin/dead-letters", "", "root-key"); rec.Code != http.StatusServiceUnavailable { t.Fatalf("expected 503 without dead letters, got %d", rec.Code) }
+    deadLetters := services.NewDeadLetterService(repo, logger)
+    h.SetDeadLetterService(deadLetters)
+
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", FeedEnabled: true}, "test")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "forbidden-c2", FeedEnabled: true}, "test")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "deleted-c3", FeedEnabled: true}, "test")
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/new-market", `{"market_id": "m1", "title": "Dead letters"}`, "root-key"); rec.Code != http.StatusAccepted { t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String()) }
+
+    letters := func(query string) map[string]*models.DeadLetter {
+        rec := serveWithKey(h, http.MethodGet, "/discord/admin/dead-letters"+query, "", "root-key")
+        if rec.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String()) }
+        var response web.DeadLettersResponse
+        json.Unmarshal(rec.Body.Bytes(), &response)
+        byRecipient := make(map[string]*models.DeadLetter)
+        for _, letter := range response.DeadLetters {
+            byRecipient[letter.RecipientID] = letter
+        }
+        return byRecipient
+    }
+    all := letters("")
+    if len(all) != 2 { t.Fatalf("expected the two failed channels to be dead-lettered, got %d", len(all)) }
+    forbidden, deleted := all["forbidden-c2"], all["deleted-c3"]
+    if forbidden.Status != models.DeadLetterRetrying || forbidden.Attempts != 1 || !strings.Contains(forbidden.LastError, "Missing Permissions") || forbidden.Content == "" { t.Fatalf("expected missing permissions to be retried, got %+v", forbidden) }
+    if deleted.Status != models.DeadLetterExhausted || !deleted.NextAttemptAt.IsZero() { t.Fatalf("expected a deleted channel not to be retried, got %+v", deleted) }
+    if retrying := letters("?status=retrying"); len(retrying) != 1 || retrying["forbidden-c2"] == nil { t.Fatalf("expected only the forbidden channel to be retrying, got %v", retrying) }
+    if rec := serveWithKey(h, http.MethodGet, "/discord/admin/dead-letters?status=lost", "", "root-key"); rec.Code != http.StatusBadRequest { t.Fatalf("expected 400 for an unknown status, got %d", rec.Code) }
+
+    // Retries back off: nothing is due right away, and a failed retry waits longer for the next one
+    if retried := h.RetryDeadLetters(ctx, time.Now()); retried != 0 { t.Fatalf("expected no retry before the backoff, got %d", retried) }
+    if retried := h.RetryDeadLetters(ctx, time.Now().Add(2 * time.Minute)); retried != 1 { t.Fatalf("expected one retry after the backoff, got %d", retried) }
+    forbidden = letters("")["forbidden-c2"]
+    if forbidden.Attempts != 2 || forbidden.Status != models.DeadLetterRetrying || time.Until(forbidden.NextAttemptAt) < 3 * time.Minute { t.Fatalf("expected a longer backoff after the second failure, got %+v", forbidden) }
+
+    // Once the bot may post in the channel again, a re-driven letter is sent and deleted
+    discord.permit("forbidden-c2")
+    if rec := serveWithKey(h, http.MethodPost, "/discord/admin/dead-letters/"+forbidden.ID+"/retry", "", "root-key"); rec.Code != http.StatusAccepted { t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String()) }
+    if retried := h.RetryDeadLetters(ctx, time.Now()); retried != 1 { t.Fatalf("expected the re-driven letter to be retried, got %d", retried) }
+    if remaining := letters(""); len(remaining) != 1 || remaining["forbidden-c2"] != nil { t.Fatalf("expected the delivered letter to be deleted, got %v", remaining) }
+
+    if rec := serveWithKey(h, http.MethodDelete, "/discord/admin/dead-letters/"+deleted.ID, "", "root-key"); rec.Code != http.StatusNoContent { t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String()) }
+    if rec := serveWithKey(h, http.MethodDelete, "/discord/admin/dead-letters/"+deleted.ID, "", "root-key"); rec.Code != http.StatusNotFound { t.Fatalf("expected 404 for a discarded letter, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/admin/dead-letters/unknown/retry", "", "root-key"); rec.Code != http.StatusNotFound { t.Fatalf("expected 404 for an unknown letter, got %d", rec.Code) }
+
+    // Test events report their failure to the caller and are not dead-lettered
+    if err := h.SendTestEvent(ctx, models.EventNewMarket, "deleted-c3"); err == nil { t.Fatalf("expected the test event to fail") }
+    if remaining := letters(""); len(remaining) != 0 { t.Fatalf("expected no dead letter for a test event, got %v", remaining) }
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// channelMessages stands in for Discord's channel messages endpoint. It accepts messages, except in
+// channels named "forbidden-*", which answer 403 like a channel the bot cannot post in until they are
+// permitted, and in channels named "deleted-*", which answer 404 like a deleted channel.
+type channelMessages struct {
+    session   *discordgo.Session // sends channel messages to the fake endpoint
+    mutex     sync.Mutex
+    permitted map[string]bool
+}
+
+// serveChannelMessages starts a fake channel messages endpoint, stopped when the test ends
+func serveChannelMessages(t *testing.T) *channelMessages {
+    messages := &channelMessages{permitted: map[string]bool{}}
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        channelID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v"+discordgo.APIVersion+"/channels/"), "/messages")
+        if strings.HasPrefix(channelID, "deleted-") {
+            w.WriteHeader(http.StatusNotFound)
+            w.Write([]byte(`{"code": 10003, "message": "Unknown Channel"}`))
+            return
+        }
+        if strings.HasPrefix(channelID, "forbidden-") && !messages.isPermitted(channelID) {
+            w.WriteHeader(http.StatusForbidden)
+            w.Write([]byte(`{"code": 50013, "message": "Missing Permissions"}`))
+            return
+        }
+        w.Write([]byte(`{"id": "msg-1"}`))
+    }))
+    t.Cleanup(server.Close)
+    messages.session = redirectedSession(server, isChannelMessagesPath)
+    return messages
+}
+
+// isChannelMessagesPath reports whether a request path is a channel's messages endpoint
+func isChannelMessagesPath(path string) bool {
+    return strings.HasPrefix(path, "/api/v"+discordgo.APIVersion+"/channels/") && strings.HasSuffix(path, "/messages")
+}
+
+// permit lets the bot post in a "forbidden-*" channel
+func (messages *channelMessages) permit(channelID string) {
+    messages.mutex.Lock()
+    defer messages.mutex.Unlock()
+    messages.permitted[channelID] = true
+}
+
+func (messages *channelMessages) isPermitted(channelID string) bool {
+    messages.mutex.Lock()
+    defer messages.mutex.Unlock()
+    return messages.permitted[channelID]
+}
+
+func TestDeliveryReports(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    discord := serveChannelMessages(t)
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    h.SetDiscordSession(discord.session)
+
+    if rec := serveWithKey(h, http.MethodGet, "/discord/admin/deliveries/abc", "", "root-key"); rec.Code != http.StatusServiceUnavailable { t.Fatalf("expected 503 without delivery reports, got %d", rec.Code) }
+    h.SetDeliveryReportService(services.NewDeliveryReportService(repo, logger))
+
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", FeedEnabled: true}, "test")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "forbidden-c2", FeedEnabled: true}, "test")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c3", FeedEnabled: false}, "test")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c4", FeedEnabled: true, MinVolume: 1000}, "test")
+
+    rec := serveWithKey(h, http.MethodPost, "/discord/events/new-market", `{"market_id": "m1", "title": "Receipts", "category": "sports", "volume": 100}`, "root-key")
+    if rec.Code != http.StatusAccepted { t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String()) }
+    var accepted web.AcceptedResponse
+    json.Unmarshal(rec.Body.Bytes(), &accepted)
+    if accepted.EventID == "" || accepted.Delivery == nil { t.Fatalf("expected an event ID and delivery summary, got %s", rec.Body.String()) }
+    if accepted.Delivery.Channels != 1 || accepted.Delivery.Failed != 1 { t.Fatalf("expected one channel sent and one failed, got %+v", *accepted.Delivery) }
+
+    rec = serveWithKey(h, http.MethodGet, "/discord/admin/deliveries/"+accepted.EventID, "", "root-key")
+    if rec.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String()) }
+    var report models.DeliveryReport
+    json.Unmarshal(rec.Body.Bytes(), &report)
+    if report.EventType != models.EventNewMarket || report.MarketID != "m1" || report.Stats.Channels != 1 || report.Stats.Failed != 1 || report.Skipped != 2 { t.Fatalf("unexpected report %+v", report) }
+    receipts := make(map[string]models.DeliveryReceipt)
+    for _, receipt := range report.Receipts {
+        receipts[receipt.RecipientID] = receipt
+    }
+    if receipts["c1"].Status != models.DeliverySent { t.Fatalf("expected c1 to be sent, got %+v", receipts["c1"]) }
+    if receipts["forbidden-c2"].Status != models.DeliveryFailed || !strings.Contains(receipts["forbidden-c2"].Reason, "Missing Permissions") { t.Fatalf("expected c2 to fail with the Discord error, got %+v", receipts["forbidden-c2"]) }
+    if receipts["c3"].Status != models.DeliverySkipped || !strings.Contains(receipts["c3"].Reason, "feed is off") { t.Fatalf("expected c3 to be skipped for its feed, got %+v", receipts["c3"]) }
+    if receipts["c4"].Status != models.DeliverySkipped || !strings.Contains(receipts["c4"].Reason, "minimum volume") { t.Fatalf("expected c4 to be skipped for its minimum volume, got %+v", receipts["c4"]) }
+
+    if rec := serveWithKey(h, http.MethodGet, "/discord/admin/deliveries/unknown", "", "root-key"); rec.Code != http.StatusNotFound { t.Fatalf("expected 404 for an unknown event, got %d", rec.Code) }
+
+    rec = serveWithKey(h, http.MethodPost, "/discord/events/batch", `{"events": [{"type": "new_market", "payload": {"market_id": "m2", "title": "Batched", "volume": 5000}}]}`, "root-key")
+    var batch web.BatchEventResponse
+    json.Unmarshal(rec.Body.Bytes(), &batch)
+    if len(batch.Results) != 1 || batch.Results[0].EventID == "" || batch.Results[0].Delivery == nil || batch.Results[0].Delivery.Channels != 2 { t.Fatalf("expected the batch result to summarize its delivery, got %s", rec.Body.String()) }
+}
@@ -0,0 +1,84 @@
+package tests
+
+import (
+    "context"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+)
+
+func TestDigestScheduleOccurrences(t *testing.T) {
+    schedule, err := services.ParseDigestSchedule("09:30", "America/New_York", "fri")
+    if err != nil { t.Fatalf("failed to parse schedule: %v", err) }
+    ny, _ := time.LoadLocation("America/New_York")
+
+    // Wednesday 08:00 in New York is before the daily time, so the last daily digest was Tuesday's
+    now := time.Date(2026, time.March, 4, 8, 0, 0, 0, ny)
+    if got := schedule.LastOccurrence(models.DigestDaily, now); !got.Equal(time.Date(2026, time.March, 3, 9, 30, 0, 0, ny)) {
+        t.Fatalf("unexpected daily occurrence %v", got)
+    }
+    if got := schedule.LastOccurrence(models.DigestWeekly, now); !got.Equal(time.Date(2026, time.February, 27, 9, 30, 0, 0, ny)) {
+        t.Fatalf("unexpected weekly occurrence %v", got)
+    }
+
+    for _, args := range [][3]string{{"9am", "", ""}, {"", "Mars/Olympus", ""}, {"", "", "someday"}} {
+        if _, err := services.ParseDigestSchedule(args[0], args[1], args[2]); err == nil { t.Fatalf("expected an error for %v", args) }
+    }
+}
+
+func TestDigestListsNewMoversClosingAndResolved(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    marketService := services.NewMockMarketService(logger)
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    now := time.Now()
+
+    marketService.SetMarket(&models.Market{ID: "new", Title: "Fresh Market", Category: "sports", Status: "active", Volume: 900, StartTime: now.Add(-2 * time.Hour), EndTime: now.Add(30 * 24 * time.Hour), Link: "https://coral.markets/new"})
+    marketService.SetMarket(&models.Market{ID: "closing", Title: "Closing Market", Category: "sports", Status: "active", StartTime: now.Add(-30 * 24 * time.Hour), EndTime: now.Add(48 * time.Hour), Link: "https://coral.markets/closing"})
+    marketService.SetMarket(&models.Market{ID: "done", Title: "Resolved Market", Category: "sports", Status: "resolved", ResolvedOutcome: "Yes", StartTime: now.Add(-30 * 24 * time.Hour), EndTime: now.Add(-time.Hour), Link: "https://coral.markets/done"})
+    marketService.SetMarket(&models.Market{ID: "politics", Title: "Other Category", Category: "politics", Status: "active", StartTime: now.Add(-time.Hour), EndTime: now.Add(24 * time.Hour)})
+
+    moving := &models.Market{ID: "moving", Title: "Moving Market", Category: "sports", Status: "active", Outcomes: []string{"Yes", "No"}, Percentages: []float64{40, 60}, StartTime: now.Add(-30 * 24 * time.Hour), EndTime: now.Add(30 * 24 * time.Hour)}
+    if _, err := subscriptionService.RecordMarketSnapshot(ctx, moving); err != nil { t.Fatalf("failed to record snapshot: %v", err) }
+    marketService.SetMarket(&models.Market{ID: "moving", Title: "Moving Market", Category: "sports", Status: "active", Outcomes: []string{"Yes", "No"}, Percentages: []float64{65, 35}, StartTime: moving.StartTime, EndTime: moving.EndTime})
+
+    digestService := services.NewDigestService(repo, marketService, newRecordingNotifier(), services.DigestSchedule{Hour: 9}, logger)
+    digest, err := digestService.BuildDigest(ctx, models.DigestWeekly, &models.ChannelConfig{AllowedCategories: []string{"sports"}}, now)
+    if err != nil { t.Fatalf("failed to build digest: %v", err) }
+
+    for _, want := range []string{"Weekly Market Roundup", "[Fresh Market](https://coral.markets/new) — $900 volume", "Moving Market", "Yes 40% → 65% (+25)", "Closing This Week", "Closing Market", "[Resolved Market](https://coral.markets/done) — Yes"} {
+        if !strings.Contains(digest, want) { t.Fatalf("expected digest to contain %q, got:\n%s", want, digest) }
+    }
+    if strings.Contains(digest, "Other Category") { t.Fatalf("expected markets outside the channel's categories to be left out, got:\n%s", digest) }
+}
+
+func TestDigestsPostOncePerScheduledTime(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    notifier := newRecordingNotifier()
+    digestService := services.NewDigestService(repo, services.NewMockMarketService(logger), notifier, services.DigestSchedule{Hour: 9}, logger)
+
+    if err := subscriptionService.SetChannelDigest(ctx, "ch1", "g1", models.DigestDaily, "u1"); err != nil { t.Fatalf("failed to opt in: %v", err) }
+    if err := subscriptionService.SetChannelDigest(ctx, "ch2", "g1", "hourly", "u1"); err == nil { t.Fatal("expected an unknown digest mode to be rejected") }
+
+    // The first pass only starts the schedule
+    start := time.Date(2026, time.March, 4, 10, 0, 0, 0, time.UTC)
+    if sent := digestService.ProcessDueDigests(ctx, start); sent != 0 { t.Fatalf("expected no digest right after opting in, sent %d", sent) }
+    if sent := digestService.ProcessDueDigests(ctx, start.Add(22*time.Hour)); sent != 0 { t.Fatalf("expected no digest before 09:00, sent %d", sent) }
+    if sent := digestService.ProcessDueDigests(ctx, start.Add(23*time.Hour)); sent != 1 { t.Fatalf("expected a digest at 09:00, sent %d", sent) }
+    if sent := digestService.ProcessDueDigests(ctx, start.Add(24*time.Hour)); sent != 0 { t.Fatalf("expected one digest per day, sent %d", sent) }
+    if len(notifier.channelMessages["ch1"]) != 1 || !strings.Contains(notifier.channelMessages["ch1"][0], "Daily Market Roundup") {
+        t.Fatalf("unexpected deliveries %v", notifier.channelMessages)
+    }
+
+    if err := subscriptionService.SetChannelDigest(ctx, "ch1", "g1", models.DigestOff, "u1"); err != nil { t.Fatalf("failed to opt out: %v", err) }
+    if sent := digestService.ProcessDueDigests(ctx, start.Add(48*time.Hour)); sent != 0 { t.Fatalf("expected no digest after opting out, sent %d", sent) }
+}
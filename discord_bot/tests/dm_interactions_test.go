@@ -0,0 +1,56 @@
+package tests
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// newDMCommandHandler creates a command handler with in-memory subscriptions
+func newDMCommandHandler() (*handlers.CommandHandler, *services.SubscriptionServiceImpl) {
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    return handlers.NewCommandHandler(services.NewMockMarketService(logger), subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger), subscriptionService
+}
+
+func TestCommandsTakeTheUserOfDMsAndTheMemberOfGuilds(t *testing.T) {
+    session, responses := captureInteractionResponses(t)
+    h, subscriptionService := newDMCommandHandler()
+    creator := func(name string) *discordgo.ApplicationCommandInteractionDataOption {
+        return &discordgo.ApplicationCommandInteractionDataOption{Name: "creator", Type: discordgo.ApplicationCommandOptionString, Value: name}
+    }
+
+    // In a DM, Discord sends the user and no member
+    dm := commandInteraction("subscribe_creator", creator("alice"))
+    h.HandleInteraction(session, dm)
+    if len(*responses) != 1 || errorIDPattern.MatchString((*responses)[0].Data.Content) { t.Fatalf("expected the DM command to succeed, got %+v", *responses) }
+    if subscription, _ := subscriptionService.GetUserSubscriptions(context.Background(), "u1"); subscription == nil || len(subscription.SubscribedCreators) != 1 { t.Fatalf("expected the DM's user to be subscribed, got %+v", subscription) }
+
+    // In a guild, Discord sends the member and no user
+    guild := commandInteraction("subscribe_creator", creator("bob"))
+    guild.GuildID, guild.User, guild.Member = "g1", nil, &discordgo.Member{User: &discordgo.User{ID: "u2"}}
+    h.HandleInteraction(session, guild)
+    if subscription, _ := subscriptionService.GetUserSubscriptions(context.Background(), "u2"); subscription == nil || len(subscription.SubscribedCreators) != 1 { t.Fatalf("expected the member to be subscribed, got %+v", subscription) }
+}
+
+func TestGuildOnlyCommandsAreRejectedPrivatelyInDMs(t *testing.T) {
+    session, responses := captureInteractionResponses(t)
+    h, subscriptionService := newDMCommandHandler()
+    setting := &discordgo.ApplicationCommandInteractionDataOption{Name: "setting", Type: discordgo.ApplicationCommandOptionString, Value: "on"}
+
+    for i, interaction := range []*discordgo.InteractionCreate{commandInteraction("channel_feed_new_markets", setting), commandInteraction("setup"), commandInteraction("route_category")} {
+        h.HandleInteraction(session, interaction)
+        if len(*responses) != i+1 { t.Fatalf("expected a reply to %s, got %d", interaction.ApplicationCommandData().Name, len(*responses)) }
+        reply := (*responses)[i].Data
+        if reply.Flags != discordgo.MessageFlagsEphemeral || !strings.Contains(reply.Content, "server channel") { t.Fatalf("expected a private rejection of %s, got %+v", interaction.ApplicationCommandData().Name, reply) }
+    }
+    if configs, _ := subscriptionService.GetAllChannelConfigs(context.Background()); len(configs) != 0 { t.Fatalf("expected no channel to be configured from a DM, got %+v", configs) }
+}
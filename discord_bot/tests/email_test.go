@@ -0,0 +1,199 @@
+package tests
+
+import (
+    "context"
+    "errors"
+    "regexp"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+type sentMail struct {
+    to, subject, body string
+}
+
+type fakeMailer struct {
+    mutex sync.Mutex
+    mails []sentMail
+}
+
+func (m *fakeMailer) SendMail(ctx context.Context, to, subject, body string) error {
+    m.mutex.Lock()
+    defer m.mutex.Unlock()
+    m.mails = append(m.mails, sentMail{to: to, subject: subject, body: body})
+    return nil
+}
+
+func (m *fakeMailer) sent() []sentMail {
+    m.mutex.Lock()
+    defer m.mutex.Unlock()
+    return append([]sentMail(nil), m.mails...)
+}
+
+var verificationCodePattern = regexp.MustCompile(`\b\d{6}\b`)
+
+// lastCode returns the verification code of the latest email
+func (m *fakeMailer) lastCode(t *testing.T) string {
+    mails := m.sent()
+    if len(mails) == 0 { t.Fatalf("expected a verification email") }
+    code := verificationCodePattern.FindString(mails[len(mails)-1].body)
+    if code == "" { t.Fatalf("expected a code in %q", mails[len(mails)-1].body) }
+    return code
+}
+
+// linkEmail links a verified address for a user
+func linkEmail(t *testing.T, emails *services.EmailServiceImpl, mailer *fakeMailer, userID, address string) {
+    ctx := context.Background()
+    if err := emails.SendVerificationCode(ctx, userID, address); err != nil { t.Fatalf("failed to send code: %v", err) }
+    if _, err := emails.VerifyEmail(ctx, userID, mailer.lastCode(t)); err != nil { t.Fatalf("failed to verify: %v", err) }
+}
+
+func TestEmailVerification(t *testing.T) {
+    ctx := context.Background()
+    repo := repository.NewInMemorySubscriptionRepository()
+    mailer := &fakeMailer{}
+    emails := services.NewEmailService(repo, mailer, utils.NewLogger())
+    clock := services.NewFakeClock(time.Date(2026, time.March, 4, 10, 0, 0, 0, time.UTC))
+    emails.SetClock(clock)
+
+    for _, address := range []string{"not an address", "Alice <alice@example.com>", "alice@example.com\r\nBcc: x@example.com"} {
+        if err := emails.SendVerificationCode(ctx, "u1", address); !errors.Is(err, services.ErrInvalidEmail) { t.Fatalf("expected %q to be rejected, got %v", address, err) }
+    }
+    if err := emails.SendVerificationCode(ctx, "u1", "alice@example.com"); err != nil { t.Fatalf("failed to send code: %v", err) }
+    if mails := mailer.sent(); len(mails) != 1 || mails[0].to != "alice@example.com" { t.Fatalf("expected the code mailed to alice, got %+v", mails) }
+    if err := emails.SendVerificationCode(ctx, "u1", "alice@example.com"); !errors.Is(err, services.ErrEmailCodeTooSoon) { t.Fatalf("expected a cooldown, got %v", err) }
+    if _, err := emails.SetEmailNotifications(ctx, "u1", models.DigestDaily, true); !errors.Is(err, services.ErrEmailNotLinked) { t.Fatalf("expected an unverified address to be refused, got %v", err) }
+
+    code := mailer.lastCode(t)
+    if _, err := emails.VerifyEmail(ctx, "u2", code); !errors.Is(err, services.ErrEmailCodeInvalid) { t.Fatalf("expected another user's code to be refused, got %v", err) }
+    if _, err := emails.VerifyEmail(ctx, "u1", "000000x"); !errors.Is(err, services.ErrEmailCodeInvalid) { t.Fatalf("expected a wrong code to be refused, got %v", err) }
+    clock.Advance(services.EmailCodeTTL + time.Second)
+    if _, err := emails.VerifyEmail(ctx, "u1", code); !errors.Is(err, services.ErrEmailCodeExpired) { t.Fatalf("expected the code to expire, got %v", err) }
+    if _, err := emails.VerifyEmail(ctx, "u1", code); !errors.Is(err, services.ErrEmailCodeInvalid) { t.Fatalf("expected an expired code to be forgotten, got %v", err) }
+
+    linkEmail(t, emails, mailer, "u1", "alice@example.com")
+    settings, err := emails.SetEmailNotifications(ctx, "u1", models.DigestWeekly, true)
+    if err != nil || settings.Address != "alice@example.com" || settings.Digest != models.DigestWeekly || !settings.Resolutions { t.Fatalf("unexpected settings %+v, %v", settings, err) }
+    if _, err := emails.SetEmailNotifications(ctx, "u1", "hourly", true); !errors.Is(err, services.ErrInvalidChannelSettings) { t.Fatalf("expected an unknown digest to be rejected, got %v", err) }
+
+    // Too many wrong codes expire the pending one, and the linked address stays in use meanwhile
+    clock.Advance(services.EmailCodeCooldown)
+    if err := emails.SendVerificationCode(ctx, "u1", "alice@work.example"); err != nil { t.Fatalf("failed to send code: %v", err) }
+    for i := 0; i < 5; i++ { emails.VerifyEmail(ctx, "u1", "000000") }
+    if _, err := emails.VerifyEmail(ctx, "u1", mailer.lastCode(t)); !errors.Is(err, services.ErrEmailCodeExpired) { t.Fatalf("expected the code to expire after wrong attempts, got %v", err) }
+    subscription, _ := repo.GetSubscription(ctx, "u1")
+    if subscription.Email.Address != "alice@example.com" || !subscription.EmailsResolutions() { t.Fatalf("expected the linked address kept, got %+v", subscription.Email) }
+
+    if unlinked, err := emails.UnlinkEmail(ctx, "u1"); !unlinked || err != nil { t.Fatalf("expected the address unlinked, got %v, %v", unlinked, err) }
+    if unlinked, _ := emails.UnlinkEmail(ctx, "u1"); unlinked { t.Fatalf("expected nothing left to unlink") }
+    subscription, _ = repo.GetSubscription(ctx, "u1")
+    if subscription.Email.Verified() || subscription.EmailsResolutions() { t.Fatalf("expected notifications back on DMs, got %+v", subscription.Email) }
+}
+
+func TestEmailMessageIsPlainText(t *testing.T) {
+    now := time.Date(2026, time.March, 4, 10, 0, 0, 0, time.UTC)
+    berlin, _ := time.LoadLocation("Europe/Berlin")
+    message := "🏁 **Market Resolved:** Will it \\*rain\\*?\n\n" +
+        "Closed " + services.DiscordTimestamp(now.Add(-2*24*time.Hour), services.TimestampRelative) + " at " + services.DiscordTimestamp(now, services.TimestampShortDateTime) + "\n" +
+        "```\nYes  60%\n```\n" +
+        "🔗 [View on Coral Markets](https://coral.markets/market/m1)\n-# Shared by Alice"
+    subject, body := services.EmailMessage(message, berlin, now)
+
+    if subject != "Coral Markets: Market Resolved: Will it *rain" { t.Fatalf("unexpected subject %q", subject) }
+    for _, want := range []string{"Market Resolved: Will it *rain*?", "Closed 2 days ago", "View on Coral Markets: https://coral.markets/market/m1", "\nYes  60%\n", "\nShared by Alice\n"} {
+        if !strings.Contains(body, want) { t.Fatalf("expected the email to contain %q, got:\n%s", want, body) }
+    }
+    for _, unwanted := range []string{"**", "<t:", "```", "](", "\\*"} {
+        if strings.Contains(body, unwanted) { t.Fatalf("expected no %q in the email, got:\n%s", unwanted, body) }
+    }
+}
+
+func TestResolutionsAreEmailedWhenChosen(t *testing.T) {
+    h := newHarness(t)
+    mailer := &fakeMailer{}
+    emails := services.NewEmailService(h.repo, mailer, utils.NewLogger())
+    h.handler.SetEmailNotifier(services.NewEmailNotifier(h.repo, mailer, nil))
+    h.subscriptions.SubscribeToMarket(h.ctx, "u1", "m1")
+    h.subscriptions.SubscribeToMarket(h.ctx, "u2", "m1")
+    linkEmail(t, emails, mailer, "u1", "alice@example.com")
+    if _, err := emails.SetEmailNotifications(h.ctx, "u1", models.DigestOff, true); err != nil { t.Fatalf("failed to choose email resolutions: %v", err) }
+
+    h.postEvent("market-update", marketUpdateEvent("m1", 60))
+    if messages := h.discord.directMessages("u1"); len(messages) != 1 { t.Fatalf("expected updates to stay on DMs, got %+v", messages) }
+
+    h.postEvent("market-resolved", map[string]interface{}{"market_id": "m1", "title": "Market m1", "winning_outcome": "Yes", "total_pool": 1000})
+    if messages := h.discord.directMessages("u1"); len(messages) != 1 { t.Fatalf("expected the resolution not to be DMed to u1, got %+v", messages) }
+    if messages := h.discord.directMessages("u2"); len(messages) != 2 { t.Fatalf("expected u2 to get the resolution by DM, got %+v", messages) }
+    mails := mailer.sent()
+    last := mails[len(mails)-1]
+    if len(mails) != 2 || last.to != "alice@example.com" || !strings.Contains(last.body, "Market m1") || !strings.Contains(last.body, "Resolved: Yes") { t.Fatalf("expected the resolution emailed to alice, got %+v", mails) }
+}
+
+func TestEmailDigestsFollowTheUsersSchedule(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    mailer := &fakeMailer{}
+    emails := services.NewEmailService(repo, mailer, logger)
+    marketService := services.NewMockMarketService(logger)
+    start := time.Date(2026, time.March, 4, 10, 0, 0, 0, time.UTC)
+    marketService.SetMarket(&models.Market{ID: "new", Title: "Fresh Market", Status: "active", Volume: 900, StartTime: start.Add(20 * time.Hour), EndTime: start.Add(30 * 24 * time.Hour), Link: "https://coral.markets/new"})
+    digestService := services.NewDigestService(repo, marketService, newRecordingNotifier(), services.DigestSchedule{Hour: 9}, logger)
+    digestService.SetEmailNotifier(services.NewEmailNotifier(repo, mailer, nil))
+
+    linkEmail(t, emails, mailer, "u1", "alice@example.com")
+    if _, err := emails.SetEmailNotifications(ctx, "u1", models.DigestDaily, false); err != nil { t.Fatalf("failed to choose a digest: %v", err) }
+
+    if sent := digestService.ProcessDueDigests(ctx, start); sent != 0 { t.Fatalf("expected no digest right after opting in, sent %d", sent) }
+    if sent := digestService.ProcessDueDigests(ctx, start.Add(23*time.Hour)); sent != 1 { t.Fatalf("expected a digest at 09:00, sent %d", sent) }
+    if sent := digestService.ProcessDueDigests(ctx, start.Add(24*time.Hour)); sent != 0 { t.Fatalf("expected one digest per day, sent %d", sent) }
+    mails := mailer.sent()
+    last := mails[len(mails)-1]
+    if len(mails) != 2 || !strings.HasPrefix(last.subject, "Coral Markets: Daily Market Roundup") || !strings.Contains(last.body, "Fresh Market: https://coral.markets/new") { t.Fatalf("expected the digest emailed, got %+v", mails) }
+
+    emails.UnlinkEmail(ctx, "u1")
+    if sent := digestService.ProcessDueDigests(ctx, start.Add(47*time.Hour)); sent != 0 { t.Fatalf("expected no digest after unlinking, sent %d", sent) }
+}
+
+func TestEmailCommands(t *testing.T) {
+    ctx := context.Background()
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    h := handlers.NewCommandHandler(services.NewMockMarketService(logger), services.NewSubscriptionService(repo, logger), nil, services.NewAnalyticsService(repo, logger), logger)
+    run := func(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) discordgo.InteractionResponse {
+        h.HandleInteraction(session, commandInteraction(name, options...))
+        return (*responses)[len(*responses)-1]
+    }
+    option := func(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+        return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionString, Value: value}
+    }
+
+    if reply := run("link_email", option("address", "alice@example.com")).Data.Content; !strings.Contains(reply, "not enabled") { t.Fatalf("unexpected reply %q", reply) }
+
+    mailer := &fakeMailer{}
+    h.SetEmailService(services.NewEmailService(repo, mailer, logger))
+    if reply := run("email_notifications", option("digest", "daily")).Data.Content; !strings.Contains(reply, "/link_email") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := run("link_email", option("address", "alice")).Data.Content; !strings.Contains(reply, "not an email address") { t.Fatalf("unexpected reply %q", reply) }
+    response := run("link_email", option("address", "alice@example.com"))
+    if response.Data.Flags&discordgo.MessageFlagsEphemeral == 0 || !strings.Contains(response.Data.Content, "code was sent to alice@") { t.Fatalf("expected a private reply, got %+v", response.Data) }
+    if reply := run("verify_email", option("code", "abc")).Data.Content; !strings.Contains(reply, "Wrong code") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := run("verify_email", option("code", mailer.lastCode(t))).Data.Content; !strings.Contains(reply, "is linked") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := run("email_notifications", option("digest", "weekly"), option("resolutions", "on")).Data.Content; !strings.Contains(reply, "weekly digest, resolutions by email") { t.Fatalf("unexpected reply %q", reply) }
+
+    run("subscribe_market", option("market_id", "m1"))
+    if reply := run("list_subscriptions").Data.Content; !strings.Contains(reply, "**Email:** weekly digest") || strings.Contains(reply, "alice@") { t.Fatalf("expected the email settings without the address, got %q", reply) }
+    if reply := run("link_email", option("address", "off")).Data.Content; !strings.Contains(reply, "unlinked") { t.Fatalf("unexpected reply %q", reply) }
+    subscription, _ := repo.GetSubscription(ctx, "u1")
+    if subscription.Email.Verified() { t.Fatalf("expected the address unlinked, got %+v", subscription.Email) }
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestErrorResponsesHaveCodes(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    subscriptionService.SetSubscriptionLimits(models.SubscriptionLimits{MaxMarkets: 1})
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    serveWithKey(h, http.MethodPost, "/discord/subscribe/market", `{"discord_user_id": "u1", "market_id": "m1"}`, "root-key")
+
+    cases := []struct {
+        method, path, body, key string
+        status                  int
+        code                    string
+    }{
+        {http.MethodPost, "/discord/subscribe/market", `{"discord_user_id": "u1"`, "root-key", http.StatusBadRequest, web.ErrorCodeInvalidRequest},
+        {http.MethodPost, "/discord/subscribe/market", `{"discord_user_id": "u1", "market_id": "m2"}`, "wrong-key", http.StatusUnauthorized, web.ErrorCodeUnauthenticated},
+        {http.MethodGet, "/discord/nowhere", "", "root-key", http.StatusNotFound, web.ErrorCodeNotFound},
+        {http.MethodDelete, "/discord/subscribe/market", "", "root-key", http.StatusMethodNotAllowed, web.ErrorCodeMethodNotAllowed},
+        {http.MethodPost, "/discord/subscribe/market", `{"discord_user_id": "u1", "market_id": "m2"}`, "root-key", http.StatusConflict, web.ErrorCodeLimitExceeded},
+        {http.MethodGet, "/discord/admin/dead-letters", "", "root-key", http.StatusServiceUnavailable, web.ErrorCodeUnavailable},
+    }
+    for _, c := range cases {
+        rec := serveWithKey(h, c.method, c.path, c.body, c.key)
+        var resp web.ErrorResponse
+        if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil { t.Fatalf("%s %s: expected a JSON error, got %s", c.method, c.path, rec.Body.String()) }
+        if rec.Code != c.status || resp.Code != c.code { t.Fatalf("%s %s: expected %d %s, got %d %s", c.method, c.path, c.status, c.code, rec.Code, rec.Body.String()) }
+        if resp.Message == "" || resp.Error != resp.Message || rec.Header().Get("Content-Type") != "application/json" { t.Fatalf("%s %s: expected a message repeated in error, got %s", c.method, c.path, rec.Body.String()) }
+        if resp.RequestID == "" || resp.RequestID != rec.Header().Get("X-Request-ID") { t.Fatalf("%s %s: expected the request ID in the body and header, got %s", c.method, c.path, rec.Body.String()) }
+    }
+
+    rec := serveWithKey(h, http.MethodPost, "/discord/subscribe/market", `{"discord_user_id": "u1", "market_id": "m2"}`, "root-key")
+    var limit web.ErrorResponse
+    json.Unmarshal(rec.Body.Bytes(), &limit)
+    if limit.Details["kind"] != "markets" || limit.Details["limit"] != float64(1) || !strings.Contains(limit.Message, "1 markets") { t.Fatalf("expected the limit in the details, got %s", rec.Body.String()) }
+}
+
+func TestRequestIDIsEchoed(t *testing.T) {
+    logger := utils.NewLogger()
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger), logger)
+
+    req := httptest.NewRequest(http.MethodGet, "/discord/nowhere", nil)
+    req.Header.Set("X-Request-ID", "trace-123")
+    rec := httptest.NewRecorder()
+    h.Handler().ServeHTTP(rec, req)
+    var resp web.ErrorResponse
+    json.Unmarshal(rec.Body.Bytes(), &resp)
+    if rec.Header().Get("X-Request-ID") != "trace-123" || resp.RequestID != "trace-123" { t.Fatalf("expected the caller's request ID echoed, got %q and %s", rec.Header().Get("X-Request-ID"), rec.Body.String()) }
+
+    rec = serveWithKey(h, http.MethodGet, "/discord/health", "", "")
+    if len(rec.Header().Get("X-Request-ID")) != 16 { t.Fatalf("expected a generated request ID on successful responses, got %q", rec.Header().Get("X-Request-ID")) }
+}
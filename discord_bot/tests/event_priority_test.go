@@ -0,0 +1,106 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "strings"
+    "sync"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestEventPriorities(t *testing.T) {
+    if models.EventPriority(models.EventMarketResolved) != models.PriorityHigh || models.EventPriority(models.EventTradingStarted) != models.PriorityHigh { t.Fatalf("expected resolutions and trading starts to be high priority") }
+    if models.EventPriority(models.EventMarketUpdate) != models.PriorityLow || models.EventPriority(models.EventMarketBuy) != models.PriorityLow { t.Fatalf("expected updates and buys to be low priority") }
+    if models.EventPriority(models.EventNewMarket) != models.PriorityNormal || models.EventPriority("broadcast") != models.PriorityNormal { t.Fatalf("expected new markets and other messages to be normal priority") }
+}
+
+func TestFanoutPoolRunsHighPriorityFirst(t *testing.T) {
+    pool := services.NewFanoutPool(1, 10)
+    release := make(chan struct{})
+    started := make(chan struct{})
+
+    var mutex sync.Mutex
+    var wg sync.WaitGroup
+    order := []string{}
+    submit := func(name string, priority models.Priority) {
+        wg.Add(1)
+        pool.Submit(context.Background(), "c1", priority, func(ctx context.Context) error {
+            mutex.Lock()
+            defer mutex.Unlock()
+            order = append(order, name)
+            return nil
+        }, func(err error) { wg.Done() })
+    }
+
+    // The worker is busy, so the next sends wait in their queues
+    wg.Add(1)
+    pool.Submit(context.Background(), "c1", models.PriorityLow, func(ctx context.Context) error {
+        close(started)
+        <-release
+        return nil
+    }, func(err error) { wg.Done() })
+    <-started
+    submit("update-1", models.PriorityLow)
+    submit("update-2", models.PriorityLow)
+    submit("new-market", models.PriorityNormal)
+    submit("resolved-1", models.PriorityHigh)
+    submit("resolved-2", models.PriorityHigh)
+
+    status := pool.Status()
+    if status.Queued != 5 || status.QueuedByPriority["high"] != 2 || status.QueuedByPriority["normal"] != 1 || status.QueuedByPriority["low"] != 2 { t.Fatalf("expected the queue depth of each priority, got %+v", status) }
+
+    close(release)
+    wg.Wait()
+    if strings.Join(order, ",") != "resolved-1,resolved-2,new-market,update-1,update-2" { t.Fatalf("expected higher priorities first and each in order, got %v", order) }
+}
+
+func TestGatewayFlushesAndKeepsHighPriorityFirst(t *testing.T) {
+    ctx := context.Background()
+    gateway := services.NewGatewayMonitor(3, utils.NewLogger())
+    gateway.HandleConnect(nil, &discordgo.Connect{})
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+
+    sent := []string{}
+    send := func(message string) func(context.Context) error {
+        return func(context.Context) error { sent = append(sent, message); return nil }
+    }
+    gateway.DeliverPriority(ctx, models.PriorityLow, send("update-1"))
+    gateway.DeliverPriority(ctx, models.PriorityHigh, send("resolved"))
+    gateway.DeliverPriority(ctx, models.PriorityLow, send("update-2"))
+    gateway.DeliverPriority(ctx, models.PriorityNormal, send("new-market"))
+    if status := gateway.Status(); status.Buffered != 3 || status.Dropped != 1 { t.Fatalf("expected one message dropped from a full buffer, got %+v", status) }
+
+    gateway.HandleResumed(nil, &discordgo.Resumed{})
+    if strings.Join(sent, ",") != "resolved,new-market,update-2" { t.Fatalf("expected the oldest low priority message dropped and the rest flushed by priority, got %v", sent) }
+}
+
+func TestBufferedFanoutKeepsResolutionsAheadOfUpdates(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "test")
+    session, _ := discordgo.New("Bot test")
+    h.SetDiscordSession(session)
+
+    // Fill a disconnected gateway's buffer with updates, then check a resolution still gets in
+    gateway := services.NewGatewayMonitor(2, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+    for _, id := range []string{"m1", "m2", "m3"} {
+        payload, _ := json.Marshal(map[string]interface{}{"market_id": id, "title": "Update", "probability": 0.5})
+        if _, err := h.ProcessEventJSON(ctx, models.EventMarketUpdate, payload); err != nil { t.Fatalf("failed to process update: %v", err) }
+    }
+    if status := gateway.Status(); status.Buffered != 2 || status.Dropped != 1 { t.Fatalf("expected a full buffer of updates, got %+v", status) }
+    payload, _ := json.Marshal(map[string]interface{}{"market_id": "m1", "title": "Resolved", "outcome": "Yes"})
+    if _, err := h.ProcessEventJSON(ctx, models.EventMarketResolved, payload); err != nil { t.Fatalf("failed to process resolution: %v", err) }
+    if status := gateway.Status(); status.Buffered != 2 || status.Dropped != 2 { t.Fatalf("expected an update to make way for the resolution, got %+v", status) }
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+)
+
+func TestEndToEndNewMarketAppliesChannelFilters(t *testing.T) {
+    h := newHarness(t)
+    h.feedChannel("c-sports", "g1", func(config *models.ChannelConfig) { config.AllowedCategories = []string{"Sports"} })
+    h.feedChannel("c-all", "g2", nil)
+    h.feedChannel("c-off", "g3", func(config *models.ChannelConfig) { config.FeedEnabled = false })
+    h.feedChannel("c-big", "g4", func(config *models.ChannelConfig) { config.MinVolume = 5000 })
+    h.subscriptions.SubscribeToCreator(h.ctx, "u1", "alice")
+
+    h.postEvent("new-market", newMarketEvent("m1", "Politics", "alice", 1000))
+    if messages := h.discord.channelMessages("c-all"); len(messages) != 1 || !strings.Contains(messages[0].Content, "Market m1") { t.Fatalf("expected the announcement in the unfiltered channel, got %+v", messages) }
+    for _, channelID := range []string{"c-sports", "c-off", "c-big"} {
+        if messages := h.discord.channelMessages(channelID); len(messages) != 0 { t.Fatalf("expected channel %s to filter the announcement, got %+v", channelID, messages) }
+    }
+    if messages := h.discord.directMessages("u1"); len(messages) != 1 { t.Fatalf("expected the creator's subscriber to get one DM, got %+v", messages) }
+
+    h.postEvent("new-market", newMarketEvent("m2", "Sports", "bob", 9000))
+    if messages := h.discord.channelMessages("c-sports"); len(messages) != 1 || !strings.Contains(messages[0].Content, "Market m2") { t.Fatalf("expected the sports channel to get the sports market, got %+v", messages) }
+    if messages := h.discord.channelMessages("c-big"); len(messages) != 1 { t.Fatalf("expected the minimum volume channel to get the large market, got %+v", messages) }
+    if count := h.discord.count(); count != 5 { t.Fatalf("expected 5 messages in all, got %d", count) }
+}
+
+func TestEndToEndBuysAreFilteredAndDeduplicated(t *testing.T) {
+    h := newHarness(t)
+    h.subscriptions.SubscribeChannelToMarket(h.ctx, "c-small", "m1", "test")
+    h.feedChannel("c-large", "g1", func(config *models.ChannelConfig) {
+        config.FeedEnabled, config.MinBuyAmount, config.SubscribedMarkets = false, 500, []string{"m1"}
+    })
+
+    // u1 follows the market directly and through a watchlist, and still gets one DM per buy
+    h.subscriptions.SubscribeToMarket(h.ctx, "u1", "m1")
+    h.subscriptions.CreateWatchlist(h.ctx, "u1", "crypto")
+    h.subscriptions.AddToWatchlist(h.ctx, "u1", "crypto", "m1")
+    h.subscriptions.SubscribeToMarket(h.ctx, "u2", "m1")
+    h.subscriptions.SetMinBuyAmount(h.ctx, "u2", 1000)
+
+    h.postEvent("market-buy", buyEvent("m1", 100))
+    if messages := h.discord.channelMessages("c-small"); len(messages) != 1 { t.Fatalf("expected the subscribed channel to get the buy, got %+v", messages) }
+    if messages := h.discord.channelMessages("c-large"); len(messages) != 0 { t.Fatalf("expected the channel's minimum buy to filter the buy, got %+v", messages) }
+    if messages := h.discord.directMessages("u1"); len(messages) != 1 { t.Fatalf("expected one DM for u1, got %+v", messages) }
+    if messages := h.discord.directMessages("u2"); len(messages) != 0 { t.Fatalf("expected u2's minimum buy to filter the buy, got %+v", messages) }
+
+    h.postEvent("market-buy", buyEvent("m1", 2000))
+    if messages := h.discord.channelMessages("c-large"); len(messages) != 1 { t.Fatalf("expected the large buy in the minimum buy channel, got %+v", messages) }
+    if messages := h.discord.directMessages("u2"); len(messages) != 1 { t.Fatalf("expected the large buy to reach u2, got %+v", messages) }
+}
+
+func TestEndToEndUpdatesAttachChartsAndStopAfterResolution(t *testing.T) {
+    h := newHarness(t)
+    h.subscriptions.SubscribeToMarket(h.ctx, "u1", "m1")
+
+    h.postEvent("market-update", marketUpdateEvent("m1", 60))
+    messages := h.discord.directMessages("u1")
+    if len(messages) != 1 || !strings.Contains(messages[0].Content, "Market m1") || messages[0].Files != 1 { t.Fatalf("expected the update as a DM with its chart, got %+v", messages) }
+
+    h.postEvent("market-resolved", map[string]interface{}{"market_id": "m1", "title": "Market m1", "winning_outcome": "Yes", "total_pool": 1000})
+    h.postEvent("market-update", marketUpdateEvent("m1", 70))
+    if messages := h.discord.directMessages("u1"); len(messages) != 2 || !strings.Contains(messages[1].Content, "Yes") { t.Fatalf("expected the resolution and no update after it, got %+v", messages) }
+}
+
+func TestEndToEndMarketOnlyChannelSkipsTheFeed(t *testing.T) {
+    h := newHarness(t)
+    if err := h.subscriptions.SubscribeChannelToMarket(h.ctx, "c1", "m1", "test"); err != nil { t.Fatalf("failed to subscribe channel: %v", err) }
+    if config, _ := h.subscriptions.GetChannelConfig(h.ctx, "c1"); config.FeedEnabled { t.Fatalf("expected a channel configured by a market subscription to have its feed off, got %+v", config) }
+
+    h.postEvent("new-market", newMarketEvent("m2", "Politics", "alice", 1000))
+    h.postEvent("market-update", marketUpdateEvent("m2", 70))
+    h.postEvent("market-buy", buyEvent("m2", 100))
+    if messages := h.discord.channelMessages("c1"); len(messages) != 0 { t.Fatalf("expected no events of other markets, got %+v", messages) }
+
+    h.postEvent("market-buy", buyEvent("m1", 100))
+    if messages := h.discord.channelMessages("c1"); len(messages) != 1 { t.Fatalf("expected the subscribed market's buy, got %+v", messages) }
+
+    // A channel that already has its feed on keeps it
+    h.feedChannel("c2", "g1", nil)
+    h.subscriptions.SubscribeChannelToMarket(h.ctx, "c2", "m1", "test")
+    if config, _ := h.subscriptions.GetChannelConfig(h.ctx, "c2"); !config.FeedEnabled { t.Fatalf("expected c2 to keep its feed, got %+v", config) }
+}
+
+func TestEndToEndGuildDefaultChannelGetsTheFeed(t *testing.T) {
+    h := newHarness(t)
+    h.subscriptions.UpdateGuildConfig(h.ctx, &models.GuildConfig{GuildID: "g1", DefaultChannelID: "c-default"})
+
+    h.postEvent("new-market", newMarketEvent("m1", "Politics", "alice", 1000))
+    if messages := h.discord.channelMessages("c-default"); len(messages) != 1 || !strings.Contains(messages[0].Content, "Market m1") { t.Fatalf("expected the announcement in the guild's default channel, got %+v", messages) }
+}
+
+func TestEndToEndConfiguredChannelReplacesTheGuildDefault(t *testing.T) {
+    h := newHarness(t)
+    h.subscriptions.UpdateGuildConfig(h.ctx, &models.GuildConfig{GuildID: "g1", DefaultChannelID: "c-default"})
+    h.subscriptions.UpdateGuildConfig(h.ctx, &models.GuildConfig{GuildID: "g2", DefaultChannelID: "c-other-default"})
+    h.feedChannel("c-feed", "g1", nil)
+
+    h.postEvent("new-market", newMarketEvent("m1", "Politics", "alice", 1000))
+    if messages := h.discord.channelMessages("c-feed"); len(messages) != 1 { t.Fatalf("expected the announcement in the configured channel, got %+v", messages) }
+    if messages := h.discord.channelMessages("c-default"); len(messages) != 0 { t.Fatalf("expected the configured channel to replace the guild's default, got %+v", messages) }
+    if messages := h.discord.channelMessages("c-other-default"); len(messages) != 1 { t.Fatalf("expected other guilds to keep their default channel, got %+v", messages) }
+}
+
+func TestEndToEndChannelConfiguredOverTheAPIReplacesTheGuildDefault(t *testing.T) {
+    h := newHarness(t)
+    h.subscriptions.UpdateGuildConfig(h.ctx, &models.GuildConfig{GuildID: "g1", DefaultChannelID: "c-default"})
+    h.discord.addChannel("c-api", "g1")
+
+    rec := serveWithKey(h.handler, "POST", "/discord/channel/feed/categories", `{"channel_id":"c-api","allowed_categories":["Politics"]}`, harnessAPIKey)
+    if rec.Code != 200 { t.Fatalf("expected the channel to be configured, got %d: %s", rec.Code, rec.Body.String()) }
+    if config, _ := h.subscriptions.GetChannelConfig(h.ctx, "c-api"); config.GuildID != "g1" { t.Fatalf("expected the channel to be saved with its guild, got %+v", config) }
+
+    h.postEvent("new-market", newMarketEvent("m1", "Politics", "alice", 1000))
+    if messages := h.discord.channelMessages("c-api"); len(messages) != 1 { t.Fatalf("expected the announcement in the channel configured over the API, got %+v", messages) }
+    if messages := h.discord.channelMessages("c-default"); len(messages) != 0 { t.Fatalf("expected the guild to get the announcement once, got %+v in its default channel", messages) }
+}
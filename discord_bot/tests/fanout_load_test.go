@@ -0,0 +1,66 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestLoadTestDeliversToEveryChannelAndUser(t *testing.T) {
+    ctx := context.Background()
+    for _, workers := range []int{0, 4} {
+        report, err := web.RunLoadTest(ctx, web.LoadTestOptions{Channels: 5, Users: 20, Events: 3, SendLatency: time.Millisecond, Workers: workers}, utils.NewQuietLogger())
+        if err != nil { t.Fatalf("load test with %d workers failed: %v", workers, err) }
+        if report.Messages != 75 || report.Events != 3 || report.Workers != workers { t.Fatalf("expected 75 messages from 3 events to 25 destinations, got %+v", report) }
+        if report.Throughput <= 0 || report.LatencyP50Ms < 1 || report.LatencyP50Ms > report.LatencyP99Ms || report.LatencyP99Ms > report.LatencyMaxMs { t.Fatalf("expected ordered latencies of at least the send latency, got %+v", report) }
+    }
+}
+
+func TestAdminLoadTestLeavesTheBotsDataAlone(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "test-key")
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), services.NewSubscriptionService(repo, logger), logger)
+
+    rec := serveWithKey(h, http.MethodPost, "/discord/admin/loadtest", `{"channels": 10, "users": 40, "events": 2}`, "test-key")
+    if rec.Code != http.StatusOK { t.Fatalf("expected %d got %d: %s", http.StatusOK, rec.Code, rec.Body.String()) }
+    var report models.LoadTestReport
+    if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil { t.Fatalf("failed to decode report: %v", err) }
+    if report.Messages != 100 || report.Workers != 0 { t.Fatalf("expected 100 messages sent one at a time without a pool, got %+v", report) }
+    if subscriptions, _ := repo.GetAllSubscriptions(context.Background()); len(subscriptions) != 0 { t.Fatalf("expected the simulated users to stay out of the bot's repository, got %d", len(subscriptions)) }
+    if configs, _ := repo.GetAllChannelConfigs(context.Background()); len(configs) != 0 { t.Fatalf("expected the simulated channels to stay out of the bot's repository, got %d", len(configs)) }
+
+    for _, body := range []string{`{"events": 1}`, `{"users": 10, "events": 0}`, `{"users": 10, "events": 1, "send_latency_ms": 60000}`, `{"channels": -1, "events": 1}`, `{"users": 10, "events": 1, "workers": -2}`} {
+        if bad := serveWithKey(h, http.MethodPost, "/discord/admin/loadtest", body, "test-key"); bad.Code != http.StatusBadRequest { t.Fatalf("expected %d for %s, got %d", http.StatusBadRequest, body, bad.Code) }
+    }
+    rec = httptest.NewRecorder()
+    h.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/discord/admin/loadtest", strings.NewReader(`{"users": 1, "events": 1}`)))
+    if rec.Code != http.StatusUnauthorized { t.Fatalf("expected %d got %d", http.StatusUnauthorized, rec.Code) }
+}
+
+func BenchmarkFanOut(b *testing.B) {
+    ctx := context.Background()
+    for _, size := range []struct{ channels, users, workers int }{{100, 0, 8}, {0, 1000, 8}, {100, 1000, 0}, {100, 1000, 8}, {100, 1000, 32}} {
+        b.Run(fmt.Sprintf("channels=%d/users=%d/workers=%d", size.channels, size.users, size.workers), func(b *testing.B) {
+            test, err := web.NewLoadTest(ctx, web.LoadTestOptions{Channels: size.channels, Users: size.users, Workers: size.workers}, utils.NewQuietLogger())
+            if err != nil { b.Fatalf("failed to set up the load test: %v", err) }
+            defer test.Close()
+            b.ResetTimer()
+            report, err := test.Run(ctx, b.N)
+            if err != nil { b.Fatalf("load test failed: %v", err) }
+            b.ReportMetric(report.Throughput, "msgs/s")
+            b.ReportMetric(report.LatencyP99Ms, "p99-ms")
+        })
+    }
+}
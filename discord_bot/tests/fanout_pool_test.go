@@ -0,0 +1,107 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sync"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestFanoutPoolKeepsDestinationOrder(t *testing.T) {
+    ctx := context.Background()
+    pool := services.NewFanoutPool(4, 2)
+
+    var mutex sync.Mutex
+    var wg sync.WaitGroup
+    received := make(map[string][]int)
+    for i := 0; i < 50; i++ {
+        for _, destination := range []string{"c1", "c2", "c3", "u1", "u2"} {
+            i, destination := i, destination
+            wg.Add(1)
+            pool.Submit(ctx, destination, models.PriorityNormal, func(ctx context.Context) error {
+                mutex.Lock()
+                defer mutex.Unlock()
+                received[destination] = append(received[destination], i)
+                return nil
+            }, func(err error) { wg.Done() })
+        }
+    }
+    wg.Wait()
+
+    for destination, order := range received {
+        if len(order) != 50 { t.Fatalf("expected 50 sends to %s, got %d", destination, len(order)) }
+        for i, n := range order {
+            if n != i { t.Fatalf("expected the sends to %s in order, got %v", destination, order) }
+        }
+    }
+    status := pool.Status()
+    if status.Workers != 4 || status.Completed != 250 || status.Queued != 0 || status.InFlight != 0 { t.Fatalf("unexpected status %+v", status) }
+}
+
+func TestFanoutPoolQueueDepth(t *testing.T) {
+    pool := services.NewFanoutPool(1, 1)
+    release := make(chan struct{})
+    started := make(chan struct{})
+    results := make(chan error, 3)
+
+    pool.Submit(context.Background(), "c1", models.PriorityNormal, func(ctx context.Context) error {
+        close(started)
+        <-release
+        return nil
+    }, func(err error) { results <- err })
+    <-started
+    pool.Submit(context.Background(), "c1", models.PriorityNormal, func(ctx context.Context) error { return fmt.Errorf("send failed") }, func(err error) { results <- err })
+    if status := pool.Status(); status.InFlight != 1 || status.Queued != 1 || status.Blocked != 0 { t.Fatalf("expected one running and one queued send, got %+v", status) }
+
+    // The queue is full, so the next submission waits until its context ends
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    pool.Submit(ctx, "c1", models.PriorityNormal, func(ctx context.Context) error { return nil }, func(err error) { results <- err })
+    if err := <-results; err != context.Canceled { t.Fatalf("expected the cancelled submission to fail with its context, got %v", err) }
+    if status := pool.Status(); status.Blocked != 1 || status.Queued != 1 { t.Fatalf("expected a blocked submission, got %+v", status) }
+
+    close(release)
+    if err := <-results; err != nil { t.Fatalf("expected the first send to succeed, got %v", err) }
+    if err := <-results; err == nil { t.Fatalf("expected the second send's error to be passed on") }
+    if status := pool.Status(); status.Completed != 2 || status.MaxQueued != 2 { t.Fatalf("unexpected status %+v", status) }
+}
+
+func TestFanoutThroughPool(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    discord := serveChannelMessages(t)
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    h.SetDiscordSession(discord.session)
+    h.SetDeliveryReportService(services.NewDeliveryReportService(repo, logger))
+    h.SetFanoutPool(services.NewFanoutPool(3, 1))
+
+    for i := 0; i < 20; i++ {
+        subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: fmt.Sprintf("c%d", i), FeedEnabled: true}, "test")
+    }
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "forbidden-c", FeedEnabled: true}, "test")
+
+    rec := serveWithKey(h, http.MethodPost, "/discord/events/new-market", `{"market_id": "m1", "title": "Pooled", "volume": 100}`, "root-key")
+    if rec.Code != http.StatusAccepted { t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String()) }
+    var accepted web.AcceptedResponse
+    json.Unmarshal(rec.Body.Bytes(), &accepted)
+    if accepted.Delivery == nil || accepted.Delivery.Channels != 20 || accepted.Delivery.Failed != 1 { t.Fatalf("expected 20 channels sent and one failed, got %s", rec.Body.String()) }
+
+    report, _ := services.NewDeliveryReportService(repo, logger).Get(ctx, accepted.EventID)
+    if report == nil || len(report.Receipts) != 21 { t.Fatalf("expected a receipt per channel, got %+v", report) }
+
+    rec = serveWithKey(h, http.MethodGet, "/discord/health", "", "root-key")
+    var health web.HealthResponse
+    json.Unmarshal(rec.Body.Bytes(), &health)
+    if health.Fanout == nil || health.Fanout.Workers != 3 || health.Fanout.Completed != 21 || health.Fanout.Queued != 0 { t.Fatalf("expected the pool's status in the health check, got %s", rec.Body.String()) }
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestMissingFeedPermissions(t *testing.T) {
+    if err := services.MissingFeedPermissions(discordgo.PermissionAllText); err != nil { t.Fatalf("expected all text permissions to be enough, got %v", err) }
+    if err := services.MissingFeedPermissions(discordgo.PermissionAdministrator); err != nil { t.Fatalf("expected administrators to need nothing else, got %v", err) }
+
+    var missing *services.MissingPermissionsError
+    err := services.MissingFeedPermissions(discordgo.PermissionViewChannel | discordgo.PermissionAttachFiles)
+    if !errors.As(err, &missing) || strings.Join(missing.Missing, ",") != "Send Messages,Embed Links" { t.Fatalf("expected Send Messages and Embed Links to be missing, got %v", err) }
+
+    // The locked channel of the crosspost session denies Send Messages, the role grants no Embed Links anywhere
+    err = services.CheckFeedPermissions(context.Background(), crosspostSession(), "locked")
+    if !errors.As(err, &missing) || strings.Join(missing.Missing, ",") != "Send Messages,Embed Links,Attach Files" { t.Fatalf("expected the locked channel to be reported, got %v", err) }
+}
+
+func TestChannelFeedPermissionPreflight(t *testing.T) {
+    ctx := context.Background()
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := handlers.NewCommandHandler(services.NewMockMarketService(logger), subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger)
+
+    feed := func(setting string, permissions int64) {
+        interaction := commandInteraction("channel_feed_new_markets", &discordgo.ApplicationCommandInteractionDataOption{Name: "setting", Type: discordgo.ApplicationCommandOptionString, Value: setting})
+        interaction.GuildID, interaction.ChannelID, interaction.AppPermissions = "g1", "c1", permissions
+        h.HandleInteraction(session, interaction)
+    }
+
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1"}, "test")
+    feed("on", discordgo.PermissionViewChannel | discordgo.PermissionSendMessages)
+    if reply := (*responses)[0].Data.Content; !strings.Contains(reply, "Embed Links, Attach Files") { t.Fatalf("expected the missing permissions to be reported, got %q", reply) }
+    if config, _ := subscriptionService.GetChannelConfig(ctx, "c1"); config.FeedEnabled { t.Fatalf("expected the feed to stay off") }
+
+    feed("on", discordgo.PermissionAllText)
+    if config, _ := subscriptionService.GetChannelConfig(ctx, "c1"); !config.FeedEnabled { t.Fatalf("expected the feed to be turned on, got %q", (*responses)[1].Data.Content) }
+
+    // Turning the feed off needs no permissions
+    feed("off", discordgo.PermissionViewChannel)
+    if config, _ := subscriptionService.GetChannelConfig(ctx, "c1"); config.FeedEnabled { t.Fatalf("expected the feed to be turned off, got %q", (*responses)[2].Data.Content) }
+}
+
+func TestChannelFeedEndpointPermissionPreflight(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    logger := utils.NewLogger()
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger), logger)
+    h.SetDiscordSession(crosspostSession())
+
+    rec := serveWithKey(h, http.MethodPost, "/discord/channel/feed/new_markets", `{"channel_id": "locked", "enabled": true}`, "root-key")
+    if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "Send Messages") { t.Fatalf("expected 409 listing the missing permissions, got %d: %s", rec.Code, rec.Body.String()) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/feed/new_markets", `{"channel_id": "locked", "enabled": false}`, "root-key"); rec.Code != http.StatusOK { t.Fatalf("expected turning the feed off to be allowed, got %d", rec.Code) }
+}
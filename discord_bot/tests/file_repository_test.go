@@ -0,0 +1,54 @@
+package tests
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+)
+
+func TestFileRepositoryKeepsDataAcrossRestarts(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    path := filepath.Join(t.TempDir(), "data", "bot.json")
+
+    repo, err := repository.NewFileSubscriptionRepository(path, logger)
+    if err != nil { t.Fatalf("expected a missing file to start empty, got %v", err) }
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    subscriptionService.SubscribeToMarket(ctx, "u1", "m1")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true, QuietHoursStart: "22:00", QuietHoursEnd: "07:00"}, "test")
+    subscriptionService.SetCategoryRoute(ctx, "g1", "Sports", "c1", "test")
+    repo.SaveAPIKey(ctx, &models.APIKey{ID: "key_1", Name: "backend", Hash: "abc123", Scopes: []string{models.ScopeEventsWrite}})
+    repo.SaveReminder(ctx, &models.Reminder{ID: "r2", MarketID: "m2", RemindAt: time.Now().Add(2 * time.Hour)})
+    repo.SaveReminder(ctx, &models.Reminder{ID: "r1", MarketID: "m1", RemindAt: time.Now().Add(-time.Minute)})
+    repo.MarkClosingSoonAnnounced(ctx, "c1", "m1", time.Now().Add(time.Hour))
+    repo.AddHeldNotification(ctx, &models.HeldNotification{ChannelID: "c1", MarketID: "m1", EventType: models.EventMarketUpdate, Count: 2})
+    repo.MarkMarketAnnounced(ctx, "c1", "m1", time.Now())
+    if err := repo.Flush(); err != nil { t.Fatalf("failed to flush: %v", err) }
+
+    entries, _ := os.ReadDir(filepath.Dir(path))
+    if len(entries) != 1 || entries[0].Name() != "bot.json" { t.Fatalf("expected only the data file to be left, got %v", entries) }
+    written, _ := os.ReadFile(path)
+    if err := repo.Flush(); err != nil { t.Fatalf("failed to flush: %v", err) }
+    if unchanged, _ := os.ReadFile(path); string(unchanged) != string(written) { t.Fatalf("expected an unchanged repository not to be written again") }
+
+    reloaded, err := repository.NewFileSubscriptionRepository(path, logger)
+    if err != nil { t.Fatalf("failed to reload: %v", err) }
+    if subscription, _ := reloaded.GetSubscription(ctx, "u1"); len(subscription.SubscribedMarkets) != 1 { t.Fatalf("expected the subscription to be reloaded, got %+v", subscription) }
+    if config, _ := reloaded.GetChannelConfig(ctx, "c1"); !config.FeedEnabled || config.QuietHoursStart != "22:00" { t.Fatalf("expected the channel config to be reloaded, got %+v", config) }
+    if routes, _ := reloaded.GetCategoryRoutes(ctx, "g1"); len(routes) != 1 || routes[0].ChannelID != "c1" { t.Fatalf("expected the category route to be reloaded, got %+v", routes) }
+    if key, _ := reloaded.GetAPIKeyByHash(ctx, "abc123"); key == nil || key.ID != "key_1" { t.Fatalf("expected the API key to be found by its hash, got %+v", key) }
+    if due, _ := reloaded.GetDueReminders(ctx, time.Now()); len(due) != 1 || due[0].ID != "r1" { t.Fatalf("expected the reminder queue to be rebuilt in order, got %+v", due) }
+    if fresh, _ := reloaded.MarkClosingSoonAnnounced(ctx, "c1", "m1", time.Now().Add(time.Hour)); fresh { t.Fatalf("expected the closing-soon announcement to be remembered") }
+    if held, _ := reloaded.GetHeldNotifications(ctx, "c1"); len(held) != 1 || held[0].Count != 2 { t.Fatalf("expected the held events to be reloaded, got %+v", held) }
+    if fresh, _ := reloaded.MarkMarketAnnounced(ctx, "c1", "m1", time.Now()); fresh { t.Fatalf("expected the new market announcement to be remembered") }
+
+    os.WriteFile(path, []byte("{not json"), 0o644)
+    if _, err := repository.NewFileSubscriptionRepository(path, logger); err == nil { t.Fatalf("expected an unreadable file to be an error") }
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestGameLedgerSettlesBets(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    game := services.NewGameService(repo, 0, logger)
+    game.SetIDGenerator(&services.SequentialIDs{})
+    market := tradableMarket()
+
+    if wallet, err := game.GetWallet(ctx, "g1", "u1"); err != nil || wallet.Balance != services.DefaultGameStartingBalance { t.Fatalf("expected a new player to start with the starting balance, got %+v, %v", wallet, err) }
+    bet, wallet, err := game.PlaceBet(ctx, "g1", "u1", market, "yes", 100)
+    if err != nil || bet.Outcome != "Yes" || bet.Price != 0.4 || bet.Shares != 250 || wallet.Balance != 900 || wallet.Staked != 100 { t.Fatalf("unexpected bet %+v and wallet %+v, %v", bet, wallet, err) }
+    if _, _, err := game.PlaceBet(ctx, "g1", "u1", market, "No", 950); !errors.Is(err, services.ErrGameBalanceTooLow) || !strings.Contains(err.Error(), "$900.00") { t.Fatalf("expected a bet above the balance to be refused, got %v", err) }
+    if _, _, err := game.PlaceBet(ctx, "g1", "u2", market, "No", 0); !errors.Is(err, services.ErrInvalidChannelSettings) { t.Fatalf("expected a stake of 0 to be refused, got %v", err) }
+    game.PlaceBet(ctx, "g1", "u2", market, "No", 300)
+    game.PlaceBet(ctx, "g2", "u1", market, "No", 60)
+    closed := tradableMarket()
+    closed.EndTime = time.Now().Add(-time.Minute)
+    if _, _, err := game.PlaceBet(ctx, "g1", "u1", closed, "Yes", 10); !errors.Is(err, services.ErrInvalidChannelSettings) { t.Fatalf("expected a closed market to refuse bets, got %v", err) }
+
+    if standings, _ := game.GetLeaderboard(ctx, "g1", 0); len(standings) != 2 || standings[0].Worth != 1000 || standings[0].DiscordUserID != "u1" { t.Fatalf("expected open bets to count at their stake, got %+v", standings) }
+
+    resolved := tradableMarket()
+    resolved.Status, resolved.ResolvedOutcome = "resolved", "Yes"
+    settled, err := game.SettleMarket(ctx, resolved)
+    if err != nil || len(settled) != 3 || settled[0].Status != models.GameBetWon || settled[0].Payout != 250 || settled[1].Status != models.GameBetLost || settled[1].Payout != 0 { t.Fatalf("unexpected settlement %+v, %v", settled, err) }
+    if again, _ := game.SettleMarket(ctx, resolved); len(again) != 0 { t.Fatalf("expected bets to be settled once, got %+v", again) }
+    standings, _ := game.GetLeaderboard(ctx, "g1", 0)
+    if len(standings) != 2 || standings[0].DiscordUserID != "u1" || standings[0].Worth != 1150 || standings[0].Profit != 150 || standings[0].Wins != 1 || standings[1].Worth != 700 || standings[1].Rank != 2 { t.Fatalf("unexpected standings %+v", standings) }
+    if wallet, _ := game.GetWallet(ctx, "g2", "u1"); wallet.Balance != 940 || wallet.Staked != 0 { t.Fatalf("expected each guild's wallet to be settled on its own, got %+v", wallet) }
+    if message := game.CreateSettlementMessage(resolved, settled[:1]); !strings.Contains(message, "✅ $100.00 on **Yes** at 40.0% paid **$250.00** (+$150.00)") { t.Fatalf("unexpected message %q", message) }
+
+    split := tradableMarket()
+    split.ID = "m2"
+    game.PlaceBet(ctx, "g1", "u2", split, "Yes", 40)
+    split.Payouts = []models.Payout{{Outcome: "Yes", PoolShare: 70}, {Outcome: "No", PoolShare: 30}}
+    if settled, _ := game.SettleMarket(ctx, split); len(settled) != 1 || settled[0].Payout != 70 { t.Fatalf("expected shares to pay their outcome's pool share, got %+v", settled) }
+
+    cancelled := tradableMarket()
+    cancelled.ID = "m3"
+    game.PlaceBet(ctx, "g1", "u2", cancelled, "No", 100)
+    refunded, _ := game.RefundMarket(ctx, "m3")
+    if wallet, _ := game.GetWallet(ctx, "g1", "u2"); len(refunded) != 1 || refunded[0].Status != models.GameBetRefunded || wallet.Balance != 730 || wallet.Staked != 0 { t.Fatalf("expected the stake back, got %+v and %+v", refunded, wallet) }
+
+    userData := services.NewUserDataService(repo, logger)
+    if data, _ := userData.DeleteUserData(ctx, "u1"); len(data.GameWallets) != 2 || len(data.GameBets) != 2 { t.Fatalf("expected the export to hold the game data, got %+v", data) }
+    if bets, _ := repo.GetGameBetsByUser(ctx, "u1"); len(bets) != 0 { t.Fatalf("expected the user's bets to be deleted, got %+v", bets) }
+}
+
+func TestGameFromDiscord(t *testing.T) {
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    markets := services.NewMockMarketService(logger)
+    markets.SetMarket(tradableMarket())
+    h := handlers.NewCommandHandler(markets, services.NewSubscriptionService(repo, logger), nil, services.NewAnalyticsService(repo, logger), logger)
+    last := func() *discordgo.InteractionResponse { return &(*responses)[len(*responses)-1] }
+    inGuild := func(interaction *discordgo.InteractionCreate) *discordgo.InteractionResponse {
+        interaction.GuildID = "g1"
+        h.HandleInteraction(session, interaction)
+        return last()
+    }
+    setting := func(value string) *discordgo.InteractionCreate {
+        return commandInteraction("game_mode", &discordgo.ApplicationCommandInteractionDataOption{Name: "setting", Type: discordgo.ApplicationCommandOptionString, Value: value})
+    }
+
+    if reply := inGuild(buttonClick("bet|m1|0")).Data; !strings.Contains(reply.Content, "not enabled on this bot") { t.Fatalf("expected bet buttons to need the game, got %+v", reply) }
+    h.SetGameService(services.NewGameService(repo, 0, logger))
+    if reply := inGuild(buttonClick("bet|m1|0")).Data; !strings.Contains(reply.Content, "not on in this server") { t.Fatalf("expected bet buttons to need the server to play, got %+v", reply) }
+    if reply := inGuild(setting("on")).Data; !strings.Contains(reply.Content, "prediction game is on") { t.Fatalf("unexpected reply %+v", reply) }
+    if reply := inGuild(commandInteraction("game_leaderboard")).Data; !strings.Contains(reply.Content, "No one has bet") { t.Fatalf("unexpected reply %+v", reply) }
+
+    if response := inGuild(buttonClick("bet|m1|1")); response.Type != discordgo.InteractionResponseModal || response.Data.CustomID != "bet|m1|1" || response.Data.Title != "Bet on No" { t.Fatalf("expected a stake modal, got %+v", response) }
+    h.HandleInteraction(session, modalSubmission("bet|m1|1", map[string]string{"stake": "2000"}))
+    if reply := last().Data; !strings.Contains(reply.Content, "you have $1000.00") { t.Fatalf("expected a bet above the balance to be refused, got %+v", reply) }
+    h.HandleInteraction(session, modalSubmission("bet|m1|1", map[string]string{"stake": "150"}))
+    reply := last().Data
+    if reply.Flags&discordgo.MessageFlagsEphemeral == 0 || !strings.Contains(reply.Content, "You bet **$150.00** of play money on **No** in **Will it rain?** at 60.0%. It pays **$250.00** if No wins.\nBalance: $850.00") { t.Fatalf("expected a private bet receipt, got %+v", reply) }
+
+    reply = inGuild(commandInteraction("game_leaderboard")).Data
+    if !strings.Contains(reply.Content, "**1.** <@u1> • $1000.00 (+$0.00) • 1 bets, 0 won") { t.Fatalf("unexpected leaderboard %q", reply.Content) }
+    inGuild(setting("off"))
+    if reply := inGuild(buttonClick("bet|m1|0")).Data; !strings.Contains(reply.Content, "not on in this server") { t.Fatalf("expected bets to stop when the server turns the game off, got %+v", reply) }
+}
+
+func TestGameBetsOnAlertsAreSettledWhenMarketsResolve(t *testing.T) {
+    h := newHarness(t)
+    game := services.NewGameService(h.repo, 0, utils.NewLogger())
+    h.handler.SetGameService(game)
+    h.feedChannel("c1", "g1", nil)
+    h.feedChannel("c2", "g2", nil)
+    h.subscriptions.UpdateGuildConfig(h.ctx, &models.GuildConfig{GuildID: "g1", GameEnabled: true})
+
+    h.postEvent("new-market", newMarketEvent("m1", "", "", 1000))
+    if messages := h.discord.channelMessages("c1"); len(messages) != 1 || messages[0].Components != 1 || !strings.Contains(messages[0].Content, "Market m1") { t.Fatalf("expected bet buttons under the alert of a playing guild, got %+v", messages) }
+    if messages := h.discord.channelMessages("c2"); len(messages) != 1 || messages[0].Components != 0 { t.Fatalf("expected no buttons in other guilds, got %+v", messages) }
+
+    market := tradableMarket()
+    game.PlaceBet(h.ctx, "g1", "u1", market, "Yes", 100)
+    game.PlaceBet(h.ctx, "g1", "u2", market, "No", 100)
+    h.subscriptions.SnoozeNotifications(h.ctx, "u2", time.Hour)
+    h.postEvent("market-resolved", map[string]interface{}{"market_id": "m1", "title": "Will it rain?", "winning_outcome": "Yes", "total_pool": 1000})
+
+    dms := h.discord.directMessages("u1")
+    if len(dms) != 1 || !strings.Contains(dms[0].Content, "GAME BETS SETTLED") || !strings.Contains(dms[0].Content, "paid **$250.00**") { t.Fatalf("expected the bettor to be told how their bet settled, got %+v", dms) }
+    if dms := h.discord.directMessages("u2"); len(dms) != 0 { t.Fatalf("expected snoozed bettors not to be DMed, got %+v", dms) }
+    if wallet, _ := game.GetWallet(h.ctx, "g1", "u2"); wallet.Balance != 900 || wallet.Staked != 0 { t.Fatalf("expected the losing bet to be settled anyway, got %+v", wallet) }
+}
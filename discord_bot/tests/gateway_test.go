@@ -0,0 +1,65 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestGatewayBuffersWhileDisconnectedAndFlushesOnResume(t *testing.T) {
+    ctx := context.Background()
+    gateway := services.NewGatewayMonitor(2, utils.NewLogger())
+    gateway.HandleConnect(nil, &discordgo.Connect{})
+
+    sent := []string{}
+    send := func(message string) func(context.Context) error {
+        return func(context.Context) error { sent = append(sent, message); return nil }
+    }
+
+    if err := gateway.Deliver(ctx, send("first")); err != nil { t.Fatalf("unexpected error: %v", err) }
+    if len(sent) != 1 { t.Fatalf("expected immediate delivery while connected, got %v", sent) }
+
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    for _, message := range []string{"second", "third", "fourth"} {
+        if err := gateway.Deliver(ctx, send(message)); err != nil { t.Fatalf("unexpected error: %v", err) }
+    }
+    status := gateway.Status()
+    if len(sent) != 1 || status.State != services.GatewayDisconnected || status.Buffered != 2 || status.Dropped != 1 {
+        t.Fatalf("expected two buffered and one dropped message while disconnected, got %+v sent=%v", status, sent)
+    }
+
+    gateway.HandleResumed(nil, &discordgo.Resumed{})
+    status = gateway.Status()
+    if status.State != services.GatewayConnected || status.Reconnects != 1 || status.Buffered != 0 {
+        t.Fatalf("expected a flushed reconnect, got %+v", status)
+    }
+    if len(sent) != 3 || sent[1] != "third" || sent[2] != "fourth" {
+        t.Fatalf("expected buffered messages flushed in order, got %v", sent)
+    }
+}
+
+func TestHealthReportsGatewayState(t *testing.T) {
+    h := setupHandler()
+    gateway := services.NewGatewayMonitor(0, utils.NewLogger())
+    h.SetGateway(gateway)
+    gateway.HandleConnect(nil, &discordgo.Connect{})
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+
+    rec := httptest.NewRecorder()
+    h.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/discord/health", nil))
+    if rec.Code != http.StatusOK { t.Fatalf("expected 200, got %d", rec.Code) }
+
+    var resp web.HealthResponse
+    if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil { t.Fatalf("invalid JSON: %v", err) }
+    if resp.Status != "degraded" || resp.Gateway == nil || resp.Gateway.State != services.GatewayDisconnected {
+        t.Fatalf("expected degraded health with a disconnected gateway, got %+v", resp)
+    }
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+    "context"
+    "net"
+    "testing"
+
+    "coral-bot/discord_bot/internal/grpcapi"
+    "coral-bot/discord_bot/internal/grpcapi/ingestpb"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/credentials/insecure"
+    "google.golang.org/grpc/metadata"
+    "google.golang.org/grpc/status"
+    "google.golang.org/grpc/test/bufconn"
+)
+
+// dialGRPC serves the gRPC ingest API over an in-memory listener and returns a client connection to it
+func dialGRPC(t *testing.T, h *web.WebhookHandler, subscriptionService services.SubscriptionService) *grpc.ClientConn {
+    listener := bufconn.Listen(1 << 20)
+    server := grpcapi.NewServer(h, subscriptionService, utils.NewLogger())
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+
+    conn, err := grpc.NewClient("passthrough:///bufnet",
+        grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+        grpc.WithTransportCredentials(insecure.NewCredentials()),
+    )
+    if err != nil { t.Fatalf("failed to dial gRPC server: %v", err) }
+    t.Cleanup(func() { conn.Close() })
+    return conn
+}
+
+func TestGRPCPublishEventsFeedTheEventPipeline(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    h.SetBuyThresholds(10, 0)
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "test")
+
+    // A disconnected gateway buffers the sends, so nothing reaches Discord
+    session, _ := discordgo.New("Bot test")
+    h.SetDiscordSession(session)
+    gateway := services.NewGatewayMonitor(0, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+
+    client := ingestpb.NewEventIngestClient(dialGRPC(t, h, subscriptionService))
+    resp, err := client.PublishEvent(ctx, &ingestpb.MarketEvent{Event: &ingestpb.MarketEvent_NewMarket{NewMarket: &ingestpb.NewMarket{
+        MarketId: "m1", Title: "gRPC Market", EndTime: "2030-01-01T00:00:00Z",
+        Outcomes: []*ingestpb.Outcome{{Id: "o1", Name: "Yes"}, {Id: "o2", Name: "No"}},
+    }}})
+    if err != nil || !resp.Accepted { t.Fatalf("expected the new market to be accepted, got %+v, %v", resp, err) }
+
+    _, err = client.PublishEvent(ctx, &ingestpb.MarketEvent{Event: &ingestpb.MarketEvent_MarketUpdate{MarketUpdate: &ingestpb.MarketUpdate{Title: "No ID"}}})
+    if status.Code(err) != codes.InvalidArgument { t.Fatalf("expected InvalidArgument without a market ID, got %v", err) }
+    if _, err := client.PublishEvent(ctx, &ingestpb.MarketEvent{}); status.Code(err) != codes.InvalidArgument { t.Fatalf("expected InvalidArgument for an empty event, got %v", err) }
+
+    batch, err := client.PublishEvents(ctx, &ingestpb.PublishEventsRequest{Events: []*ingestpb.MarketEvent{
+        {Event: &ingestpb.MarketEvent_MarketBuy{MarketBuy: &ingestpb.MarketBuy{MarketId: "m1", Amount: 5}}},
+        {Event: &ingestpb.MarketEvent_MarketResolved{MarketResolved: &ingestpb.MarketResolved{MarketId: "m1", ResolvedAt: "yesterday"}}},
+        {Event: &ingestpb.MarketEvent_MarketResolved{MarketResolved: &ingestpb.MarketResolved{MarketId: "m1", WinningOutcome: "Yes"}}},
+    }})
+    if err != nil { t.Fatalf("failed to publish batch: %v", err) }
+    if batch.Accepted != 2 || batch.Failed != 1 || len(batch.Results) != 3 { t.Fatalf("expected 2 accepted and 1 failed events, got %+v", batch) }
+    if !batch.Results[0].Suppressed || batch.Results[0].Type != models.EventMarketBuy { t.Fatalf("expected the small buy to be suppressed, got %+v", batch.Results[0]) }
+    if batch.Results[1].Error != "resolved_at must be an RFC3339 time" { t.Fatalf("unexpected result %+v", batch.Results[1]) }
+
+    // The new market and the resolution reach the feed channel
+    if buffered := gateway.Status().Buffered; buffered != 2 { t.Fatalf("expected two delivered events, got %d", buffered) }
+}
+
+func TestGRPCSubscriptions(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    client := ingestpb.NewSubscriptionsClient(dialGRPC(t, h, subscriptionService))
+
+    if resp, err := client.SubscribeMarket(ctx, &ingestpb.MarketSubscriptionRequest{DiscordUserId: "u1", MarketId: "m1"}); err != nil || !resp.Subscribed { t.Fatalf("failed to subscribe to market: %+v, %v", resp, err) }
+    if _, err := client.SubscribeCreator(ctx, &ingestpb.CreatorSubscriptionRequest{DiscordUserId: "u1", CreatorId: "alice"}); err != nil { t.Fatalf("failed to subscribe to creator: %v", err) }
+    if _, err := client.SubscribeOutcome(ctx, &ingestpb.OutcomeSubscriptionRequest{DiscordUserId: "u1", MarketId: "m2", Outcome: "Yes", MinChange: 10}); err != nil { t.Fatalf("failed to subscribe to outcome: %v", err) }
+    if _, err := client.SubscribeMarket(ctx, &ingestpb.MarketSubscriptionRequest{DiscordUserId: "u1"}); status.Code(err) != codes.InvalidArgument { t.Fatalf("expected InvalidArgument without a market ID, got %v", err) }
+
+    subs, err := client.GetSubscriptions(ctx, &ingestpb.GetSubscriptionsRequest{DiscordUserId: "u1"})
+    if err != nil { t.Fatalf("failed to get subscriptions: %v", err) }
+    if len(subs.Markets) != 1 || len(subs.Creators) != 1 || len(subs.Outcomes) != 1 || subs.Outcomes[0].MinChange != 10 { t.Fatalf("unexpected subscriptions %+v", subs) }
+
+    if resp, err := client.UnsubscribeMarket(ctx, &ingestpb.MarketSubscriptionRequest{DiscordUserId: "u1", MarketId: "m1"}); err != nil || resp.Subscribed { t.Fatalf("failed to unsubscribe from market: %+v, %v", resp, err) }
+    sub, _ := subscriptionService.GetUserSubscriptions(ctx, "u1")
+    if len(sub.SubscribedMarkets) != 0 { t.Fatalf("expected no market subscriptions, got %v", sub.SubscribedMarkets) }
+}
+
+func TestGRPCCallsNeedTheScopeOfTheirMethod(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    h, apiKeyService := setupAPIKeyHandler()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), utils.NewLogger())
+    conn := dialGRPC(t, h, subscriptionService)
+    events := ingestpb.NewEventIngestClient(conn)
+    subscriptions := ingestpb.NewSubscriptionsClient(conn)
+
+    _, secret, err := apiKeyService.CreateKey(context.Background(), "backend", []string{models.ScopeSubscriptionsRead})
+    if err != nil { t.Fatalf("failed to create key: %v", err) }
+    event := &ingestpb.MarketEvent{Event: &ingestpb.MarketEvent_MarketBuy{MarketBuy: &ingestpb.MarketBuy{MarketId: "m1", Amount: 5}}}
+
+    if _, err := events.PublishEvent(context.Background(), event); status.Code(err) != codes.Unauthenticated { t.Fatalf("expected Unauthenticated without credentials, got %v", err) }
+    keyed := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", secret)
+    if _, err := events.PublishEvent(keyed, event); status.Code(err) != codes.PermissionDenied { t.Fatalf("expected PermissionDenied without events:write, got %v", err) }
+    if _, err := subscriptions.GetSubscriptions(keyed, &ingestpb.GetSubscriptionsRequest{DiscordUserId: "u1"}); err != nil { t.Fatalf("expected subscriptions:read to list subscriptions, got %v", err) }
+
+    bearer := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+secret)
+    if _, err := subscriptions.GetSubscriptions(bearer, &ingestpb.GetSubscriptionsRequest{DiscordUserId: "u1"}); err != nil { t.Fatalf("expected the key to work as a bearer token, got %v", err) }
+    root := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "root-key")
+    if _, err := events.PublishEvent(root, event); err != nil { t.Fatalf("expected CORAL_API_KEY to grant every scope, got %v", err) }
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestGuildBrandingRestylesChannelAlerts(t *testing.T) {
+    h := newHarness(t)
+    h.feedChannel("c1", "g1", nil)
+    h.feedChannel("c2", "g2", nil)
+    branding := &models.GuildBranding{Emojis: map[string]string{models.EventNewMarket: "<:coral:123456789012345678>"}, FooterText: "Coral Club", FooterURL: "https://club.example"}
+    if _, err := h.subscriptions.SetGuildBranding(h.ctx, "g1", branding); err != nil { t.Fatalf("failed to set the branding: %v", err) }
+
+    h.postEvent("new-market", newMarketEvent("m1", "Politics", "alice", 1000))
+    branded := h.discord.channelMessages("c1")
+    if len(branded) != 1 || !strings.HasPrefix(branded[0].Content, "<:coral:123456789012345678> **NEW MARKET ALERT** <:coral:123456789012345678>\n") { t.Fatalf("expected the guild's emoji around the header, got %+v", branded) }
+    if !strings.HasSuffix(branded[0].Content, "\n\n-# [Coral Club](https://club.example)") || len(branded[0].Embeds) != 0 { t.Fatalf("expected a plain message with the guild's footer, got %+v", branded[0]) }
+    if plain := h.discord.channelMessages("c2"); len(plain) != 1 || !strings.HasPrefix(plain[0].Content, "🎉 **NEW MARKET ALERT** 🎉") || strings.Contains(plain[0].Content, "Coral Club") { t.Fatalf("expected another guild's alert in the bot's style, got %+v", plain) }
+
+    branding.AccentColor = 0x1abc9c
+    h.subscriptions.SetGuildBranding(h.ctx, "g1", branding)
+    h.subscriptions.SetChannelPing(h.ctx, "c1", "g1", "555", []string{models.EventMarketUpdate}, "test")
+    h.postEvent("market-update", marketUpdateEvent("m1", 60))
+    messages := h.discord.channelMessages("c1")
+    if len(messages) != 2 || len(messages[1].Embeds) != 1 { t.Fatalf("expected the update in an embed, got %+v", messages) }
+    embed := messages[1].Embeds[0]
+    if embed.Color != 0x1abc9c || !strings.HasPrefix(embed.Description, "📈 **MARKET UPDATE** 📈") || !strings.Contains(embed.Description, "Coral Club") { t.Fatalf("expected the update in the guild's color with its footer, got %+v", embed) }
+    if embed.Image == nil || embed.Image.URL != "attachment://probability.png" || messages[1].Files != 1 { t.Fatalf("expected the chart inside the embed, got %+v", messages[1]) }
+    if messages[1].Content != "<@&555>" || len(messages[1].Mentions.Roles) != 1 { t.Fatalf("expected the ping outside the embed, got %+v", messages[1]) }
+}
+
+func TestGuildBrandingEndpoints(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "test-key")
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    subscriptionService.UpdateGuildConfig(ctx, &models.GuildConfig{GuildID: "g1", DefaultChannelID: "c1"})
+
+    rec := serveWithKey(h, http.MethodPut, "/discord/guild/branding/g1", `{"emojis": {"market_resolved": "🏁"}, "accent_color": "#1ABC9C", "footer_text": "Coral Club"}`, "test-key")
+    if rec.Code != http.StatusOK { t.Fatalf("expected %d got %d: %s", http.StatusOK, rec.Code, rec.Body.String()) }
+    var branding web.GuildBrandingResponse
+    json.Unmarshal(rec.Body.Bytes(), &branding)
+    if !branding.Branded || branding.AccentColor != "#1abc9c" || branding.Emojis["market_resolved"] != "🏁" || branding.FooterText != "Coral Club" { t.Fatalf("expected the guild's branding, got %+v", branding) }
+    if guild, _ := subscriptionService.GetGuildConfig(ctx, "g1"); guild.DefaultChannelID != "c1" || guild.Branding.AccentColor != 0x1abc9c { t.Fatalf("expected the branding saved with the guild's default channel kept, got %+v", guild) }
+
+    for _, body := range []string{
+        `{"emojis": {"market_comment": "💬"}}`,
+        `{"emojis": {"new_market": "@everyone"}}`,
+        `{"accent_color": "teal"}`,
+        `{"footer_text": "one\ntwo"}`,
+        `{"footer_url": "https://club.example"}`,
+        `{"footer_text": "Coral Club", "footer_url": "javascript:alert(1)"}`,
+    } {
+        if bad := serveWithKey(h, http.MethodPut, "/discord/guild/branding/g1", body, "test-key"); bad.Code != http.StatusBadRequest { t.Fatalf("expected %d for %s, got %d", http.StatusBadRequest, body, bad.Code) }
+    }
+
+    rec = serveWithKey(h, http.MethodDelete, "/discord/guild/branding/g1", "", "test-key")
+    branding = web.GuildBrandingResponse{}
+    json.Unmarshal(rec.Body.Bytes(), &branding)
+    if rec.Code != http.StatusOK || branding.Branded || branding.AccentColor != "" { t.Fatalf("expected the guild back in the bot's style, got %d %+v", rec.Code, branding) }
+    if rec := serveWithKey(h, http.MethodGet, "/discord/guild/branding/g2", "", "test-key"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"branded":false`) { t.Fatalf("expected an unknown guild in the bot's style, got %d %s", rec.Code, rec.Body.String()) }
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+    "context"
+    "testing"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestChannelDeleteCleansUp(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := handlers.NewGuildEventHandler(subscriptionService, logger)
+
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "admin")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c2", GuildID: "g1", FeedEnabled: true}, "admin")
+    subscriptionService.UpdateGuildConfig(ctx, &models.GuildConfig{GuildID: "g1", DefaultChannelID: "c1"})
+    subscriptionService.SetCategoryRoute(ctx, "g1", "politics", "c1", "admin")
+    subscriptionService.SetCategoryRoute(ctx, "g1", "sports", "c2", "admin")
+    subscriptionService.RegisterWebhook(ctx, &models.WebhookRegistration{ChannelID: "c1", WebhookURL: "https://discord.com/api/webhooks/1/a"}, "admin")
+
+    h.HandleChannelDelete(nil, &discordgo.ChannelDelete{Channel: &discordgo.Channel{ID: "c1", GuildID: "g1"}})
+
+    configs, _ := subscriptionService.GetAllChannelConfigs(ctx)
+    if len(configs) != 1 || configs[0].ChannelID != "c2" { t.Fatalf("expected only c2 to stay configured, got %d configs", len(configs)) }
+    if webhooks, _ := subscriptionService.ListWebhookRegistrationsByChannel(ctx, "c1"); len(webhooks) != 0 { t.Fatalf("expected the channel's webhook to be unregistered, got %d", len(webhooks)) }
+    routes, _ := subscriptionService.GetCategoryRoutes(ctx, "g1")
+    if len(routes) != 1 || routes[0].ChannelID != "c2" { t.Fatalf("expected only the route to c2 to stay, got %+v", routes) }
+    if guild, _ := subscriptionService.GetGuildConfig(ctx, "g1"); guild == nil || guild.DefaultChannelID != "" { t.Fatalf("expected the default channel to be cleared, got %+v", guild) }
+    entries, _ := subscriptionService.GetAuditLog(ctx, models.AuditFilter{ChannelID: "c1"})
+    if len(entries) == 0 || entries[0].Actor != models.AuditActorDiscord || entries[0].Action != models.AuditActionDelete { t.Fatalf("expected the cleanup to be audited, got %+v", entries[0]) }
+}
+
+func TestGuildDeleteCleansUp(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := handlers.NewGuildEventHandler(subscriptionService, logger)
+
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "admin")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c9", GuildID: "g2", FeedEnabled: true}, "admin")
+    subscriptionService.UpdateGuildConfig(ctx, &models.GuildConfig{GuildID: "g1", DefaultChannelID: "c1"})
+    subscriptionService.SetCategoryRoute(ctx, "g1", "politics", "c3", "admin")
+    subscriptionService.RegisterWebhook(ctx, &models.WebhookRegistration{ChannelID: "c2", WebhookURL: "https://discord.com/api/webhooks/1/a"}, "admin")
+
+    // An outage makes guilds unavailable without removing the bot from them
+    h.HandleGuildDelete(nil, &discordgo.GuildDelete{Guild: &discordgo.Guild{ID: "g1", Unavailable: true}})
+    if configs, _ := subscriptionService.GetAllChannelConfigs(ctx); len(configs) != 2 { t.Fatalf("expected an unavailable guild to be kept, got %d configs", len(configs)) }
+
+    // c2 has no channel config, the state's copy of the guild is how its webhook is found
+    h.HandleGuildDelete(nil, &discordgo.GuildDelete{Guild: &discordgo.Guild{ID: "g1"}, BeforeDelete: &discordgo.Guild{ID: "g1", Channels: []*discordgo.Channel{{ID: "c2"}}}})
+    configs, _ := subscriptionService.GetAllChannelConfigs(ctx)
+    if len(configs) != 1 || configs[0].GuildID != "g2" { t.Fatalf("expected only the other guild's channel to stay, got %d configs", len(configs)) }
+    if webhooks, _ := subscriptionService.ListWebhookRegistrations(ctx); len(webhooks) != 0 { t.Fatalf("expected the guild's webhook to be unregistered, got %d", len(webhooks)) }
+    if routes, _ := subscriptionService.GetCategoryRoutes(ctx, "g1"); len(routes) != 0 { t.Fatalf("expected the guild's routes to be removed, got %+v", routes) }
+    if guild, _ := subscriptionService.GetGuildConfig(ctx, "g1"); guild != nil { t.Fatalf("expected the guild config to be deleted, got %+v", guild) }
+}
@@ -0,0 +1,303 @@
+package tests
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "mime"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// harnessAPIKey authenticates the harness's requests to the web server
+const harnessAPIKey = "harness-key"
+
+// harness runs the bot's web server on a random port, with the bot's Discord session talking to a fake
+// Discord REST API, so events can be posted over HTTP and the messages they send inspected
+type harness struct {
+    t             *testing.T
+    ctx           context.Context
+    repo          *repository.InMemorySubscriptionRepository
+    subscriptions *services.SubscriptionServiceImpl
+    markets       *services.MockMarketService
+    handler       *web.WebhookHandler
+    server        *httptest.Server // the bot's web server
+    discord       *fakeDiscord
+}
+
+// newHarness starts the web server and the fake Discord API, both stopped when the test ends
+func newHarness(t *testing.T) *harness {
+    t.Setenv("CORAL_API_KEY", harnessAPIKey)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    marketService := services.NewMockMarketService(logger)
+    handler := web.NewWebhookHandler(marketService, subscriptionService, logger)
+
+    discord := newFakeDiscord(t)
+    session, _ := discordgo.New("Bot test")
+    session.Client = &http.Client{Transport: discord}
+    handler.SetDiscordSession(session)
+    subscriptionService.SetChannelGuildResolver(services.NewDiscordChannelGuilds(session))
+
+    server := httptest.NewServer(handler.Handler())
+    t.Cleanup(server.Close)
+    return &harness{t: t, ctx: context.Background(), repo: repo, subscriptions: subscriptionService, markets: marketService, handler: handler, server: server, discord: discord}
+}
+
+// feedChannel configures a channel with its feed on, changed by configure before it is saved
+func (h *harness) feedChannel(channelID, guildID string, configure func(config *models.ChannelConfig)) {
+    config := &models.ChannelConfig{ChannelID: channelID, GuildID: guildID, FeedEnabled: true, FrequencyMode: "medium"}
+    if configure != nil {
+        configure(config)
+    }
+    if err := h.subscriptions.UpdateChannelConfig(h.ctx, config, "test"); err != nil { h.t.Fatalf("failed to configure channel %s: %v", channelID, err) }
+}
+
+// postEvent posts an event to one of the /discord/events endpoints, like "new-market", and fails the
+// test unless it is accepted
+func (h *harness) postEvent(endpoint string, event interface{}) {
+    body, _ := json.Marshal(event)
+    req, _ := http.NewRequest(http.MethodPost, h.server.URL+"/discord/events/"+endpoint, bytes.NewReader(body))
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("X-API-Key", harnessAPIKey)
+    resp, err := h.server.Client().Do(req)
+    if err != nil { h.t.Fatalf("failed to post %s event: %v", endpoint, err) }
+    defer resp.Body.Close()
+    if resp.StatusCode >= 300 {
+        response, _ := io.ReadAll(resp.Body)
+        h.t.Fatalf("expected the %s event to be accepted, got %d: %s", endpoint, resp.StatusCode, response)
+    }
+}
+
+// newMarketEvent builds a new market event with two outcomes, closing in two days
+func newMarketEvent(marketID, category, creator string, volume float64) web.NewMarketEventRequest {
+    return web.NewMarketEventRequest{
+        MarketID: marketID,
+        Title:    "Market " + marketID,
+        Creator:  creator,
+        Category: category,
+        Outcomes: []web.EventOutcome{{ID: "1", Name: "Yes"}, {ID: "2", Name: "No"}},
+        EndTime:  time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339),
+        Volume:   volume,
+        Link:     "https://coral.markets/market/" + marketID,
+    }
+}
+
+// marketUpdateEvent builds an update of a market with Yes at yes percent
+func marketUpdateEvent(marketID string, yes float64) web.MarketUpdateEventRequest {
+    return web.MarketUpdateEventRequest{
+        MarketID: marketID,
+        Title:    "Market " + marketID,
+        Volume:   1000,
+        EndTime:  time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339),
+        Outcomes: []web.EventOutcome{{ID: "1", Name: "Yes", Pct: yes}, {ID: "2", Name: "No", Pct: 100 - yes}},
+    }
+}
+
+// buyEvent builds a buy of Yes in a market
+func buyEvent(marketID string, amount float64) web.MarketBuyEventRequest {
+    return web.MarketBuyEventRequest{MarketID: marketID, Title: "Market " + marketID, Amount: amount, Outcome: "Yes", Buyer: "trader"}
+}
+
+// fakeMessage is a message posted to the fake Discord API
+type fakeMessage struct {
+    ChannelID   string
+    RecipientID string // the user of a DM channel, empty for guild channels
+    Content     string
+    Files       int
+    Mentions    *discordgo.MessageAllowedMentions // the mentions the message allows, nil for Discord's default of all
+    Embeds      []*discordgo.MessageEmbed
+    Components  int // rows of components under the message
+}
+
+// fakeDiscord is a fake Discord REST API. It serves as the bot session's transport, sending the
+// session's requests to a test server that records the messages posted to channels and DMs.
+type fakeDiscord struct {
+    t        *testing.T
+    server   *httptest.Server
+    target   *url.URL
+    mutex    sync.Mutex
+    messages []fakeMessage
+    nextID   int
+    closed   map[string]bool // DM channels of users who do not accept DMs from the bot
+    webhooks map[string]string // channels of the webhooks added with addWebhook, by webhook ID
+    guilds   map[string]string // guilds of the channels added with addChannel, by channel ID
+}
+
+// newFakeDiscord starts a fake Discord API, stopped when the test ends
+func newFakeDiscord(t *testing.T) *fakeDiscord {
+    discord := &fakeDiscord{t: t, closed: map[string]bool{}, webhooks: map[string]string{}, guilds: map[string]string{}}
+    discord.server = httptest.NewServer(http.HandlerFunc(discord.serve))
+    discord.target, _ = url.Parse(discord.server.URL)
+    t.Cleanup(discord.server.Close)
+    return discord
+}
+
+// RoundTrip sends a request meant for Discord to the fake API
+func (discord *fakeDiscord) RoundTrip(req *http.Request) (*http.Response, error) {
+    req = req.Clone(req.Context())
+    req.URL.Scheme, req.URL.Host, req.Host = discord.target.Scheme, discord.target.Host, ""
+    return http.DefaultTransport.RoundTrip(req)
+}
+
+// serve answers the REST calls the bot makes while delivering events
+func (discord *fakeDiscord) serve(w http.ResponseWriter, r *http.Request) {
+    path := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v"+discordgo.APIVersion), "/"), "/")
+    switch {
+    case r.Method == http.MethodPost && len(path) == 3 && path[0] == "users" && path[2] == "channels":
+        var body struct {
+            RecipientID string `json:"recipient_id"`
+        }
+        json.NewDecoder(r.Body).Decode(&body)
+        writeFakeJSON(w, discordgo.Channel{ID: "dm-" + body.RecipientID, Type: discordgo.ChannelTypeDM})
+    case r.Method == http.MethodPost && len(path) == 3 && path[0] == "channels" && path[2] == "messages" && discord.dmsClosed(path[1]):
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(http.StatusForbidden)
+        json.NewEncoder(w).Encode(map[string]interface{}{"code": discordgo.ErrCodeCannotSendMessagesToThisUser, "message": "Cannot send messages to this user"})
+    case r.Method == http.MethodPost && len(path) == 3 && path[0] == "channels" && path[2] == "messages":
+        message := discord.record(path[1], r)
+        writeFakeJSON(w, discordgo.Message{ID: message, ChannelID: path[1]})
+    case r.Method == http.MethodPost && len(path) == 5 && path[0] == "channels" && path[4] == "crosspost":
+        writeFakeJSON(w, discordgo.Message{ID: path[3], ChannelID: path[1]})
+    case r.Method == http.MethodGet && len(path) == 2 && path[0] == "channels":
+        writeFakeJSON(w, discordgo.Channel{ID: path[1], GuildID: discord.channelGuild(path[1]), Type: discordgo.ChannelTypeGuildText})
+    case len(path) == 3 && path[0] == "webhooks" && discord.webhookChannel(path[1]) == "":
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(http.StatusNotFound)
+        json.NewEncoder(w).Encode(map[string]interface{}{"code": discordgo.ErrCodeUnknownWebhook, "message": "Unknown Webhook"})
+    case r.Method == http.MethodGet && len(path) == 3 && path[0] == "webhooks":
+        writeFakeJSON(w, discordgo.Webhook{ID: path[1], ChannelID: discord.webhookChannel(path[1]), Token: path[2]})
+    case r.Method == http.MethodPost && len(path) == 3 && path[0] == "webhooks":
+        channelID := discord.webhookChannel(path[1])
+        writeFakeJSON(w, discordgo.Message{ID: discord.record(channelID, r), ChannelID: channelID, WebhookID: path[1]})
+    default:
+        discord.t.Logf("fake Discord API: unhandled %s %s", r.Method, r.URL.Path)
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(http.StatusNotFound)
+        json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "message": "404: Not Found"})
+    }
+}
+
+// record stores a message posted to a channel, with JSON or multipart body, and returns its ID
+func (discord *fakeDiscord) record(channelID string, r *http.Request) string {
+    message := fakeMessage{ChannelID: channelID, RecipientID: strings.TrimPrefix(channelID, "dm-")}
+    if !strings.HasPrefix(channelID, "dm-") {
+        message.RecipientID = ""
+    }
+    var data []byte
+    if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+        r.ParseMultipartForm(8 << 20)
+        data = []byte(r.FormValue("payload_json"))
+        for _, files := range r.MultipartForm.File {
+            message.Files += len(files)
+        }
+    } else {
+        data, _ = io.ReadAll(r.Body)
+    }
+    // Components are interfaces, so they are only counted
+    var payload discordgo.MessageSend
+    var components struct {
+        Components []json.RawMessage `json:"components"`
+    }
+    json.Unmarshal(data, &payload)
+    json.Unmarshal(data, &components)
+    message.Content, message.Mentions, message.Embeds, message.Components = payload.Content, payload.AllowedMentions, payload.Embeds, len(components.Components)
+
+    discord.mutex.Lock()
+    defer discord.mutex.Unlock()
+    discord.messages = append(discord.messages, message)
+    discord.nextID++
+    return fmt.Sprintf("%d", discord.nextID)
+}
+
+// closeDMs makes DMs to a user fail, like for a user who does not accept DMs from the bot
+func (discord *fakeDiscord) closeDMs(userID string) {
+    discord.mutex.Lock()
+    defer discord.mutex.Unlock()
+    discord.closed["dm-"+userID] = true
+}
+
+// addWebhook creates a webhook posting to a channel
+func (discord *fakeDiscord) addWebhook(webhookID, channelID string) {
+    discord.mutex.Lock()
+    defer discord.mutex.Unlock()
+    discord.webhooks[webhookID] = channelID
+}
+
+// addChannel creates a text channel in a guild
+func (discord *fakeDiscord) addChannel(channelID, guildID string) {
+    discord.mutex.Lock()
+    defer discord.mutex.Unlock()
+    discord.guilds[channelID] = guildID
+}
+
+// channelGuild returns the guild of a channel, empty for channels not added with addChannel
+func (discord *fakeDiscord) channelGuild(channelID string) string {
+    discord.mutex.Lock()
+    defer discord.mutex.Unlock()
+    return discord.guilds[channelID]
+}
+
+// webhookChannel returns the channel a webhook posts to, empty for unknown webhooks
+func (discord *fakeDiscord) webhookChannel(webhookID string) string {
+    discord.mutex.Lock()
+    defer discord.mutex.Unlock()
+    return discord.webhooks[webhookID]
+}
+
+// dmsClosed reports whether messages posted to a channel fail as a closed DM channel
+func (discord *fakeDiscord) dmsClosed(channelID string) bool {
+    discord.mutex.Lock()
+    defer discord.mutex.Unlock()
+    return discord.closed[channelID]
+}
+
+// channelMessages returns the messages posted to a channel
+func (discord *fakeDiscord) channelMessages(channelID string) []fakeMessage {
+    return discord.filter(func(message fakeMessage) bool { return message.ChannelID == channelID })
+}
+
+// directMessages returns the DMs sent to a user
+func (discord *fakeDiscord) directMessages(userID string) []fakeMessage {
+    return discord.filter(func(message fakeMessage) bool { return message.RecipientID == userID })
+}
+
+// count returns how many messages were posted
+func (discord *fakeDiscord) count() int {
+    return len(discord.filter(func(fakeMessage) bool { return true }))
+}
+
+// filter returns the posted messages matching keep
+func (discord *fakeDiscord) filter(keep func(fakeMessage) bool) []fakeMessage {
+    discord.mutex.Lock()
+    defer discord.mutex.Unlock()
+    var messages []fakeMessage
+    for _, message := range discord.messages {
+        if keep(message) {
+            messages = append(messages, message)
+        }
+    }
+    return messages
+}
+
+// writeFakeJSON writes a fake Discord API response
+func writeFakeJSON(w http.ResponseWriter, value interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+// httpLog returns the requests logged by the harness's bot
+func (h *harness) httpLog(query string) web.HTTPLogResponse {
+    rec := serveWithKey(h.handler, http.MethodGet, "/discord/admin/http-log"+query, "", harnessAPIKey)
+    if rec.Code != http.StatusOK { h.t.Fatalf("failed to get the HTTP log: %d %s", rec.Code, rec.Body.String()) }
+    var response web.HTTPLogResponse
+    json.Unmarshal(rec.Body.Bytes(), &response)
+    return response
+}
+
+func TestHTTPLogRedactsLoggedRequests(t *testing.T) {
+    h := newHarness(t)
+    httpLog, err := services.NewHTTPLogService(h.repo, services.HTTPLogConfig{Endpoints: []string{"/discord/subscribe/*", "/discord/subscriptions/{discord_user_id}"}}, utils.NewLogger())
+    if err != nil { t.Fatalf("failed to create the HTTP log: %v", err) }
+    h.handler.SetHTTPLogService(httpLog)
+
+    if rec := serveWithKey(h.handler, http.MethodPost, "/discord/subscribe/market", `{"discord_user_id": "123456789", "market_id": "m1"}`, harnessAPIKey); rec.Code != http.StatusOK { t.Fatalf("failed to subscribe: %d %s", rec.Code, rec.Body.String()) }
+    serveWithKey(h.handler, http.MethodGet, "/discord/subscriptions/123456789", "", harnessAPIKey)
+    serveWithKey(h.handler, http.MethodPost, "/discord/events/new-market", `{"market_id": "m2"}`, harnessAPIKey)
+
+    response := h.httpLog("")
+    if len(response.Entries) != 2 { t.Fatalf("expected only the chosen endpoints logged, got %+v", response.Entries) }
+    read, subscribe := response.Entries[0], response.Entries[1]
+    if subscribe.Route != "/discord/subscribe/market" || subscribe.Reason != "endpoint" || subscribe.Status != http.StatusOK || subscribe.RequestID == "" { t.Fatalf("unexpected entry %+v", subscribe) }
+    if subscribe.RequestHeaders["X-Api-Key"] != "[REDACTED]" { t.Fatalf("expected the API key redacted, got %v", subscribe.RequestHeaders) }
+    for _, entry := range response.Entries {
+        logged, _ := json.Marshal(entry)
+        if strings.Contains(string(logged), "123456789") || strings.Contains(string(logged), harnessAPIKey) { t.Fatalf("expected the user ID and API key redacted, got %s", logged) }
+    }
+    var body map[string]string
+    json.Unmarshal([]byte(subscribe.RequestBody), &body)
+    if body["market_id"] != "m1" || !strings.HasPrefix(body["discord_user_id"], "user:") || read.Path != "/discord/subscriptions/"+body["discord_user_id"] { t.Fatalf("expected the user pseudonymized the same way in bodies and paths, got %s and %s", subscribe.RequestBody, read.Path) }
+
+    if filtered := h.httpLog("?route=/discord/subscribe/market"); len(filtered.Entries) != 1 { t.Fatalf("expected the log filtered by route, got %+v", filtered.Entries) }
+    if rec := serveWithKey(h.handler, http.MethodDelete, "/discord/admin/http-log", "", harnessAPIKey); rec.Code != http.StatusNoContent { t.Fatalf("failed to clear: %d", rec.Code) }
+    if cleared := h.httpLog(""); len(cleared.Entries) != 0 { t.Fatalf("expected the log cleared, got %+v", cleared.Entries) }
+}
+
+func TestHTTPLogIsARingBufferConfiguredAtRuntime(t *testing.T) {
+    h := newHarness(t)
+    httpLog, _ := services.NewHTTPLogService(h.repo, services.HTTPLogConfig{}, utils.NewLogger())
+    h.handler.SetHTTPLogService(httpLog)
+
+    serveWithKey(h.handler, http.MethodGet, "/discord/subscriptions/u1", "", harnessAPIKey)
+    if response := h.httpLog(""); len(response.Entries) != 0 || response.Config.Capacity != services.DefaultHTTPLogCapacity { t.Fatalf("expected nothing logged by default, got %+v", response) }
+
+    for _, body := range []string{`{"sample_rate": 1.5}`, `{"endpoints": ["discord/health"]}`, `{"capacity": -1}`, `{"max_body_bytes": 1000000}`} {
+        if rec := serveWithKey(h.handler, http.MethodPut, "/discord/admin/http-log/config", body, harnessAPIKey); rec.Code != http.StatusBadRequest { t.Fatalf("expected %s rejected, got %d", body, rec.Code) }
+    }
+    rec := serveWithKey(h.handler, http.MethodPut, "/discord/admin/http-log/config", `{"sample_rate": 1, "capacity": 2, "max_body_bytes": 10}`, harnessAPIKey)
+    if rec.Code != http.StatusOK { t.Fatalf("failed to configure: %d %s", rec.Code, rec.Body.String()) }
+
+    for _, user := range []string{"u1", "u2", "u3"} {
+        serveWithKey(h.handler, http.MethodGet, "/discord/subscriptions/"+user, "", harnessAPIKey)
+    }
+    response := h.httpLog("")
+    if len(response.Entries) != 2 || response.Entries[0].Reason != "sampled" { t.Fatalf("expected the two most recent requests kept, got %+v", response.Entries) }
+    if entry := response.Entries[0]; len(entry.ResponseBody) != 10 || !entry.Truncated { t.Fatalf("expected the response body cut, got %+v", entry) }
+}
+
+func TestHTTPLogConfigValidation(t *testing.T) {
+    h := newHarness(t)
+    if _, err := services.NewHTTPLogService(h.repo, services.HTTPLogConfig{SampleRate: -0.1}, utils.NewLogger()); !errors.Is(err, services.ErrInvalidHTTPLogConfig) { t.Fatalf("expected a negative sample rate rejected, got %v", err) }
+    httpLog, _ := services.NewHTTPLogService(h.repo, services.HTTPLogConfig{Endpoints: []string{" /discord/events/* "}}, utils.NewLogger())
+    if reason, ok := httpLog.ShouldLog("/discord/events/new-market"); !ok || reason != "endpoint" { t.Fatalf("expected prefix endpoints matched, got %q", reason) }
+    if _, ok := httpLog.ShouldLog("/discord/health"); ok { t.Fatalf("expected other endpoints not logged without sampling") }
+}
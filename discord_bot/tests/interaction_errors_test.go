@@ -0,0 +1,107 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "regexp"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// captureInteractionResponses returns a bot session whose responses to interactions, follow-ups included,
+// go to a test server, and the responses it receives. Other requests go to Discord as usual.
+func captureInteractionResponses(t *testing.T) (*discordgo.Session, *[]discordgo.InteractionResponse) {
+    responses := &[]discordgo.InteractionResponse{}
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        var response discordgo.InteractionResponse
+        if strings.Contains(r.URL.Path, "/webhooks/") {
+            // A follow-up, recorded as a response with its message
+            response.Data = &discordgo.InteractionResponseData{}
+            json.NewDecoder(r.Body).Decode(response.Data)
+            *responses = append(*responses, response)
+            writeFakeJSON(w, discordgo.Message{ID: "followup"})
+            return
+        }
+        json.NewDecoder(r.Body).Decode(&response)
+        *responses = append(*responses, response)
+        w.WriteHeader(http.StatusNoContent)
+    }))
+    t.Cleanup(server.Close)
+    session := redirectedSession(server, func(path string) bool {
+        return strings.Contains(path, "/interactions/") || strings.Contains(path, "/webhooks/")
+    })
+    return session, responses
+}
+
+// redirectedSession returns a bot session that sends the requests meant for Discord whose path matches
+// to server, and the others to Discord as usual
+func redirectedSession(server *httptest.Server, match func(path string) bool) *discordgo.Session {
+    target, _ := url.Parse(server.URL)
+    session, _ := discordgo.New("Bot test")
+    session.Client = &http.Client{Transport: redirectTransport{target: target, match: match}}
+    return session
+}
+
+// redirectTransport sends the requests whose path matches to target
+type redirectTransport struct {
+    target *url.URL
+    match  func(path string) bool
+}
+
+func (transport redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    if transport.match(req.URL.Path) {
+        req = req.Clone(req.Context())
+        req.URL.Scheme, req.URL.Host, req.Host = transport.target.Scheme, transport.target.Host, ""
+    }
+    return http.DefaultTransport.RoundTrip(req)
+}
+
+// commandInteraction returns a slash command interaction from user u1
+func commandInteraction(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+    return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+        ID:    "interaction-1",
+        Token: "token",
+        Type:  discordgo.InteractionApplicationCommand,
+        User:  &discordgo.User{ID: "u1"},
+        Data:  discordgo.ApplicationCommandInteractionData{Name: name, Options: options},
+    }}
+}
+
+var errorIDPattern = regexp.MustCompile("error ID `[0-9a-f]{8}`")
+
+func TestCommandFailuresReplyPrivatelyWithAnErrorID(t *testing.T) {
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    marketService := services.NewMockMarketService(logger)
+    analyticsService := services.NewAnalyticsService(repo, logger)
+    h := handlers.NewCommandHandler(marketService, services.NewSubscriptionService(repo, logger), nil, analyticsService, logger)
+
+    // subscribe_market without its market_id option makes the handler panic
+    h.HandleInteraction(session, commandInteraction("subscribe_market"))
+    if len(*responses) != 1 { t.Fatalf("expected a reply after the panic, got %d", len(*responses)) }
+    reply := (*responses)[0].Data
+    if reply.Flags != discordgo.MessageFlagsEphemeral || !errorIDPattern.MatchString(reply.Content) { t.Fatalf("expected a private reply with an error ID, got %+v", reply) }
+
+    marketService.SetError(errors.New("backend down"))
+    h.HandleInteraction(session, commandInteraction("market", &discordgo.ApplicationCommandInteractionDataOption{Name: "market_id", Type: discordgo.ApplicationCommandOptionString, Value: "m1"}))
+    if len(*responses) != 2 { t.Fatalf("expected a reply to the failed command, got %d", len(*responses)) }
+    reply = (*responses)[1].Data
+    if reply.Flags != discordgo.MessageFlagsEphemeral || !errorIDPattern.MatchString(reply.Content) { t.Fatalf("expected a private reply with an error ID, got %+v", reply) }
+
+    summary, err := analyticsService.GetSummary(context.Background(), time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+    if err != nil { t.Fatalf("failed to get analytics: %v", err) }
+    if summary.CommandFailures["subscribe_market"] != 1 || summary.CommandFailures["market"] != 1 { t.Fatalf("expected both failures to be recorded, got %v", summary.CommandFailures) }
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+    "crypto/ed25519"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// signedInteraction builds a POST to the interactions endpoint signed the way Discord signs it
+func signedInteraction(key ed25519.PrivateKey, body string) *http.Request {
+    timestamp := "1700000000"
+    req := httptest.NewRequest(http.MethodPost, "/discord/interactions", strings.NewReader(body))
+    req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(key, []byte(timestamp+body))))
+    req.Header.Set("X-Signature-Timestamp", timestamp)
+    return req
+}
+
+func TestInteractionsEndpointVerifiesAndRoutesCommands(t *testing.T) {
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    marketService := services.NewMockMarketService(logger)
+    commandHandler := handlers.NewCommandHandler(marketService, subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger)
+    h := web.NewWebhookHandler(marketService, subscriptionService, logger)
+    h.SetDiscordSession(session)
+
+    serve := func(req *http.Request) *httptest.ResponseRecorder {
+        rec := httptest.NewRecorder()
+        h.Handler().ServeHTTP(rec, req)
+        return rec
+    }
+    public, private, _ := ed25519.GenerateKey(rand.Reader)
+    if rec := serve(signedInteraction(private, `{"type": 1}`)); rec.Code != http.StatusServiceUnavailable { t.Fatalf("expected 503 before the endpoint is configured, got %d", rec.Code) }
+    if err := h.SetInteractionHandler(commandHandler, "not-a-key"); err == nil { t.Fatalf("expected an invalid public key to be rejected") }
+    if err := h.SetInteractionHandler(commandHandler, hex.EncodeToString(public)); err != nil { t.Fatalf("failed to set the interaction handler: %v", err) }
+
+    rec := serve(signedInteraction(private, `{"type": 1}`))
+    var pong discordgo.InteractionResponse
+    json.Unmarshal(rec.Body.Bytes(), &pong)
+    if rec.Code != http.StatusOK || pong.Type != discordgo.InteractionResponsePong { t.Fatalf("expected a pong, got %d %s", rec.Code, rec.Body.String()) }
+
+    _, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+    if rec := serve(signedInteraction(otherKey, `{"type": 1}`)); rec.Code != http.StatusUnauthorized { t.Fatalf("expected 401 for a wrong signature, got %d", rec.Code) }
+    unsigned := httptest.NewRequest(http.MethodPost, "/discord/interactions", strings.NewReader(`{"type": 1}`))
+    if rec := serve(unsigned); rec.Code != http.StatusUnauthorized { t.Fatalf("expected 401 without a signature, got %d", rec.Code) }
+
+    // The command's reply is the body of the response instead of a call back to Discord
+    command := `{"id": "i1", "application_id": "app", "type": 2, "token": "tok", "user": {"id": "u1"}, "data": {"id": "c1", "name": "help", "type": 1}}`
+    rec = serve(signedInteraction(private, command))
+    h.WaitForInteractions()
+    var reply discordgo.InteractionResponse
+    json.Unmarshal(rec.Body.Bytes(), &reply)
+    if rec.Code != http.StatusOK || reply.Type != discordgo.InteractionResponseChannelMessageWithSource || reply.Data == nil || !strings.Contains(reply.Data.Content, "Coral Markets Bot Help") { t.Fatalf("expected the help reply, got %d %s", rec.Code, rec.Body.String()) }
+    // The rest of the help text follows up through Discord as usual
+    if len(*responses) == 0 { t.Fatalf("expected the rest of the help text as follow-ups") }
+    for _, response := range *responses {
+        if response.Type != 0 { t.Fatalf("expected no response to be posted to Discord's callback, got %+v", response) }
+    }
+}
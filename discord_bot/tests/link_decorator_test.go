@@ -0,0 +1,75 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+)
+
+func TestLinkDecoratorAddsUTMParametersToAllowedLinks(t *testing.T) {
+    ctx := context.Background()
+    var decorator *services.LinkDecorator
+    content := "🔗 [View on Coral Markets](https://coral.markets/market/m1)"
+    if got := decorator.Decorate(ctx, content, "c1"); got != content { t.Fatalf("expected a nil decorator to leave links alone, got %q", got) }
+
+    decorator = services.NewLinkDecorator(utils.NewLogger())
+    if got := decorator.Decorate(ctx, content, "c1"); got != content { t.Fatalf("expected links left alone until tracking is configured, got %q", got) }
+
+    decorator.SetUTM(true, "launch")
+    got := decorator.Decorate(ctx, content+"\n\n-# [Our server](https://guild.example/invite)", "c1")
+    if !strings.Contains(got, "(https://coral.markets/market/m1?utm_campaign=launch&utm_content=c1&utm_source=discord)") { t.Fatalf("expected the market link to carry UTM parameters, got %q", got) }
+    if !strings.Contains(got, "(https://guild.example/invite)") { t.Fatalf("expected a link off the allowed domains to be left alone, got %q", got) }
+
+    if got := decorator.Decorate(ctx, "[Market](https://coral.markets/market/m1?ref=feed)", "c2"); got != "[Market](https://coral.markets/market/m1?ref=feed&utm_campaign=launch&utm_content=c2&utm_source=discord)" { t.Fatalf("expected existing parameters kept, got %q", got) }
+}
+
+func TestLinkDecoratorShortensLinks(t *testing.T) {
+    var requests atomic.Int32
+    var failing atomic.Bool
+    shortener := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests.Add(1)
+        if r.Header.Get("Authorization") != "Bearer secret" { w.WriteHeader(http.StatusUnauthorized); return }
+        if failing.Load() { w.WriteHeader(http.StatusInternalServerError); return }
+        var body struct{ URL string `json:"url"` }
+        _ = json.NewDecoder(r.Body).Decode(&body)
+        if !strings.Contains(body.URL, "utm_source=discord") { w.WriteHeader(http.StatusBadRequest); return }
+        _ = json.NewEncoder(w).Encode(map[string]string{"short_url": "https://crl.example/abc"})
+    }))
+    defer shortener.Close()
+
+    ctx := context.Background()
+    decorator := services.NewLinkDecorator(utils.NewLogger())
+    decorator.SetUTM(true, "")
+    decorator.SetShortener(shortener.URL, "secret")
+
+    content := "🔗 [View on Coral Markets](https://coral.markets/market/m1)"
+    for i := 0; i < 2; i++ {
+        if got := decorator.Decorate(ctx, content, "c1"); got != "🔗 [View on Coral Markets](https://crl.example/abc)" { t.Fatalf("expected the short link, got %q", got) }
+    }
+    if requests.Load() != 1 { t.Fatalf("expected a shortened link to be cached, got %d requests", requests.Load()) }
+
+    failing.Store(true)
+    if got := decorator.Decorate(ctx, content, "c2"); got != "🔗 [View on Coral Markets](https://coral.markets/market/m1?utm_content=c2&utm_source=discord)" { t.Fatalf("expected the full link when the shortener fails, got %q", got) }
+}
+
+func TestAlertLinksAreDecoratedPerChannel(t *testing.T) {
+    h := newHarness(t)
+    decorator := services.NewLinkDecorator(utils.NewLogger())
+    decorator.SetUTM(true, "")
+    h.handler.SetLinkDecorator(decorator)
+    h.feedChannel("c1", "g1", nil)
+    h.feedChannel("c2", "g1", nil)
+
+    h.postEvent("new-market", newMarketEvent("m1", "Politics", "alice", 1000))
+    for _, channelID := range []string{"c1", "c2"} {
+        messages := h.discord.channelMessages(channelID)
+        if len(messages) != 1 || !strings.Contains(messages[0].Content, "(https://coral.markets/market/m1?utm_content="+channelID+"&utm_source=discord)") { t.Fatalf("expected the link tagged with channel %s, got %+v", channelID, messages) }
+    }
+}
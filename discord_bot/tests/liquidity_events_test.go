@@ -0,0 +1,50 @@
+package tests
+
+import (
+    "context"
+    "net/http"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestLiquidityEventsOnlyReachOptedInChannels(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "test")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c2", GuildID: "g1", FeedEnabled: true}, "test")
+    subscriptionService.UpdateGuildConfig(ctx, &models.GuildConfig{GuildID: "g2", DefaultChannelID: "c3"})
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/liquidity", `{"channel_id": "c1", "enabled": true}`, "root-key"); rec.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String()) }
+    if config, _ := subscriptionService.GetChannelConfig(ctx, "c1"); !config.LiquidityAlerts || !config.Settings().LiquidityAlerts { t.Fatalf("expected liquidity alerts to be on and exported, got %+v", config) }
+
+    // A disconnected gateway buffers the sends, so they can be counted
+    session, _ := discordgo.New("Bot test")
+    h.SetDiscordSession(session)
+    gateway := services.NewGatewayMonitor(0, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-liquidity", `{"market_id": "m1", "title": "Rain?", "change": -500, "outcomes": [{"name": "Yes", "pct": 55}]}`, "root-key"); rec.Code != http.StatusAccepted { t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String()) }
+    if buffered := gateway.Status().Buffered; buffered != 1 { t.Fatalf("expected only c1 to be posted to, got %d sends", buffered) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-liquidity", `{"market_id": "m1", "title": "Rain?"}`, "root-key"); rec.Code != http.StatusBadRequest { t.Fatalf("expected 400 without a change, got %d", rec.Code) }
+}
+
+func TestLiquidityMessageShowsOddsShift(t *testing.T) {
+    marketService := services.NewMockMarketService(utils.NewLogger())
+    market := &models.Market{Title: "Rain?", Outcomes: []string{"Yes", "No"}, Percentages: []float64{62, 38}}
+    previous := &models.MarketSnapshot{Outcomes: []string{"Yes", "No"}, Percentages: []float64{55, 45}}
+    added := marketService.CreateMarketLiquidityMessage(market, 1000, 25000, previous)
+    if !strings.Contains(added, "LIQUIDITY ADDED") || !strings.Contains(added, "Added: $1000.00") || !strings.Contains(added, "$25000.00") || !strings.Contains(added, "Yes (62.0%) ▲ +7.0%") { t.Fatalf("unexpected message %q", added) }
+    removed := marketService.CreateMarketLiquidityMessage(market, -250, 0, nil)
+    if !strings.Contains(removed, "LIQUIDITY REMOVED") || !strings.Contains(removed, "Removed: $250.00") || strings.Contains(removed, "Pool liquidity") { t.Fatalf("unexpected message %q", removed) }
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestMaintenanceHoldsNotificationsUntilItEnds(t *testing.T) {
+    h := newHarness(t)
+    maintenance := services.NewMaintenanceMode(0, utils.NewLogger())
+    h.handler.SetMaintenanceMode(maintenance)
+    h.feedChannel("c1", "g1", nil)
+    h.subscriptions.SubscribeToMarket(h.ctx, "u1", "m1")
+    notifier := newRecordingNotifier()
+    reminders := services.NewMaintenanceNotifier(maintenance, notifier)
+
+    rec := serveWithKey(h.handler, http.MethodPut, "/discord/admin/maintenance", `{"enabled": true, "notice": "Back at 18:00 UTC"}`, harnessAPIKey)
+    var status services.MaintenanceStatus
+    json.Unmarshal(rec.Body.Bytes(), &status)
+    if rec.Code != http.StatusOK || !status.Enabled || status.Notice != "Back at 18:00 UTC" { t.Fatalf("expected maintenance started, got %d: %s", rec.Code, rec.Body.String()) }
+
+    h.postEvent("new-market", newMarketEvent("m1", "", "", 100))
+    if err := reminders.SendDirectMessage(h.ctx, "u2", "Market m1 closes soon"); err != nil { t.Fatalf("expected the reminder held, got %v", err) }
+    if h.discord.count() != 0 || len(notifier.directMessages["u2"]) != 0 { t.Fatalf("expected nothing sent during maintenance, got %+v", h.discord.messages) }
+    if status := maintenance.Status(); status.Buffered != 3 { t.Fatalf("expected the post, the DM and the reminder held, got %+v", status) }
+    if rec := serveWithKey(h.handler, http.MethodGet, "/discord/health", "", harnessAPIKey); !strings.Contains(rec.Body.String(), `"maintenance":{"enabled":true`) { t.Fatalf("expected maintenance in the health check, got %s", rec.Body.String()) }
+
+    rec = serveWithKey(h.handler, http.MethodPut, "/discord/admin/maintenance", `{"enabled": false}`, harnessAPIKey)
+    if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"enabled":true`) { t.Fatalf("expected maintenance ended, got %d: %s", rec.Code, rec.Body.String()) }
+    deadline := time.Now().Add(2 * time.Second)
+    for maintenance.Status().Buffered > 0 && time.Now().Before(deadline) {
+        time.Sleep(10 * time.Millisecond)
+    }
+    if len(h.discord.channelMessages("c1")) != 1 || len(h.discord.directMessages("u1")) != 1 || len(notifier.directMessages["u2"]) != 1 { t.Fatalf("expected the held messages sent once maintenance ended, got %+v", h.discord.messages) }
+
+    // Outside maintenance messages go out right away
+    h.postEvent("new-market", newMarketEvent("m2", "", "", 100))
+    if len(h.discord.channelMessages("c1")) != 2 { t.Fatalf("expected the next event posted right away, got %+v", h.discord.messages) }
+
+    if rec := serveWithKey(h.handler, http.MethodPut, "/discord/admin/maintenance", `{"notice": "soon"}`, harnessAPIKey); rec.Code != http.StatusBadRequest { t.Fatalf("expected enabled required, got %d", rec.Code) }
+}
+
+func TestCommandsAreAnsweredWithTheMaintenanceNotice(t *testing.T) {
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    h := handlers.NewCommandHandler(services.NewMockMarketService(logger), services.NewSubscriptionService(repo, logger), nil, services.NewAnalyticsService(repo, logger), logger)
+    maintenance := services.NewMaintenanceMode(0, logger)
+    maintenance.Start("")
+    h.SetMaintenanceMode(maintenance)
+    h.SetOwners([]string{"u1"})
+
+    h.HandleInteraction(session, commandInteraction("list_subscriptions"))
+    if len(*responses) != 1 || (*responses)[0].Data.Content != services.DefaultMaintenanceNotice || (*responses)[0].Data.Flags != discordgo.MessageFlagsEphemeral { t.Fatalf("expected the maintenance notice, got %+v", *responses) }
+
+    // Bot owner commands still run, so maintenance can be ended from Discord
+    h.HandleInteraction(session, commandInteraction("admin_maintenance", &discordgo.ApplicationCommandInteractionDataOption{Name: "action", Type: discordgo.ApplicationCommandOptionString, Value: "off"}))
+    if len(*responses) != 2 || !strings.Contains((*responses)[1].Data.Content, "Maintenance ended") { t.Fatalf("expected maintenance ended, got %+v", *responses) }
+    if _, on := maintenance.Notice(); on { t.Fatalf("expected maintenance off") }
+
+    h.HandleInteraction(session, commandInteraction("list_subscriptions"))
+    if len(*responses) != 3 || !strings.Contains((*responses)[2].Data.Content, "subscri") { t.Fatalf("expected the command to run after maintenance, got %+v", *responses) }
+}
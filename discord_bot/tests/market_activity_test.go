@@ -0,0 +1,56 @@
+package tests
+
+import (
+    "context"
+    "net/http"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestMarketCommentsReachOptedInSubscribers(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    session, responses := captureInteractionResponses(t)
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    subscriptionService.SubscribeToMarket(ctx, "u1", "m1")
+    subscriptionService.SubscribeToMarket(ctx, "u2", "m1")
+    subscriptionService.SubscribeToMarket(ctx, "u3", "m2")
+    subscriptionService.SubscribeToCreator(ctx, "u4", "alice")
+    subscriptionService.SetMarketActivity(ctx, "u3", true)
+    subscriptionService.SetMarketActivity(ctx, "u4", true)
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "test")
+
+    // u1 opts in with the slash command
+    commands := handlers.NewCommandHandler(services.NewMockMarketService(logger), subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger)
+    commands.HandleInteraction(session, commandInteraction("activity", &discordgo.ApplicationCommandInteractionDataOption{Name: "setting", Type: discordgo.ApplicationCommandOptionString, Value: "on"}))
+    if reply := (*responses)[0].Data; !strings.Contains(reply.Content, "comments") { t.Fatalf("unexpected reply %q", reply.Content) }
+    if subscription, _ := subscriptionService.GetUserSubscriptions(ctx, "u1"); !subscription.Activity { t.Fatalf("expected activity to be turned on") }
+
+    // A disconnected gateway buffers the sends, so they can be counted
+    h.SetDiscordSession(session)
+    gateway := services.NewGatewayMonitor(0, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-comment", `{"market_id": "m1", "title": "Rain?", "author": "bob", "comment": "Forecast says yes"}`, "root-key"); rec.Code != http.StatusAccepted { t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String()) }
+    if buffered := gateway.Status().Buffered; buffered != 1 { t.Fatalf("expected only u1 to be sent the comment, got %d sends", buffered) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-comment", `{"market_id": "m1", "comment": "  "}`, "root-key"); rec.Code != http.StatusBadRequest { t.Fatalf("expected 400 for an empty comment, got %d", rec.Code) }
+}
+
+func TestMarketCommentMessageQuotesComment(t *testing.T) {
+    marketService := services.NewMockMarketService(utils.NewLogger())
+    message := marketService.CreateMarketCommentMessage(&models.Market{Title: "Rain?"}, "", "first line\nsecond line"+strings.Repeat("x", 600))
+    if !strings.Contains(message, "Anonymous wrote:\n> first line\n> second line") || !strings.Contains(message, "…") { t.Fatalf("unexpected message %q", message) }
+}
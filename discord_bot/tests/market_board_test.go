@@ -0,0 +1,139 @@
+package tests
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+// fakeBoardMessenger keeps board messages in memory
+type fakeBoardMessenger struct {
+    messages map[string]string // message ID to content
+    pinned   map[string]bool
+    posts    int
+    edits    int
+}
+
+func newFakeBoardMessenger() *fakeBoardMessenger {
+    return &fakeBoardMessenger{messages: map[string]string{}, pinned: map[string]bool{}}
+}
+
+func (m *fakeBoardMessenger) PostBoard(ctx context.Context, channelID, content string) (string, error) {
+    m.posts++
+    id := fmt.Sprintf("%s-board-%d", channelID, m.posts)
+    m.messages[id] = content
+    return id, nil
+}
+
+func (m *fakeBoardMessenger) PinBoard(ctx context.Context, channelID, messageID string) error {
+    m.pinned[messageID] = true
+    return nil
+}
+
+func (m *fakeBoardMessenger) EditBoard(ctx context.Context, channelID, messageID, content string) error {
+    if _, ok := m.messages[messageID]; !ok { return services.ErrBoardMessageGone }
+    m.edits++
+    m.messages[messageID] = content
+    return nil
+}
+
+func (m *fakeBoardMessenger) DeleteBoard(ctx context.Context, channelID, messageID string) error {
+    delete(m.messages, messageID)
+    return nil
+}
+
+func TestMarketBoardListsTopActiveMarkets(t *testing.T) {
+    logger := utils.NewLogger()
+    boards := services.NewMarketBoardService(repository.NewInMemorySubscriptionRepository(), services.NewMockMarketService(logger), newFakeBoardMessenger(), logger)
+    closes := time.Now().Add(48 * time.Hour)
+
+    markets := []*models.Market{
+        {ID: "m1", Title: "Small", Status: "active", Category: "sports", Volume: 500, Outcomes: []string{"Yes", "No"}, Percentages: []float64{30, 70}, EndTime: closes, Link: "https://coral.markets/market/m1"},
+        {ID: "m2", Title: "Big", Status: "active", Category: "sports", Volume: 2500000, Outcomes: []string{"Yes", "No"}, Percentages: []float64{62, 38}, EndTime: closes, Link: "https://coral.markets/market/m2"},
+        {ID: "m3", Title: "Done", Status: "resolved", Category: "sports", Volume: 9000000},
+        {ID: "m4", Title: "Elsewhere", Status: "active", Category: "politics", Volume: 1000000},
+    }
+    for i := 0; i < 12; i++ {
+        markets = append(markets, &models.Market{ID: fmt.Sprintf("f%d", i), Title: fmt.Sprintf("Filler %d", i), Status: "active", Category: "sports", Volume: 100})
+    }
+
+    board := boards.BuildBoard(markets, &models.ChannelConfig{AllowedCategories: []string{"sports"}})
+    if !strings.Contains(board, "1. [Big]") || !strings.Contains(board, "Yes 62% • $2.5M • closes "+services.DiscordTimestamp(closes, services.TimestampRelative)) { t.Fatalf("expected the biggest market first with its leader, volume and close time, got %q", board) }
+    if !strings.Contains(board, "2. [Small]") || !strings.Contains(board, "No 70%") { t.Fatalf("expected the second market with its leading outcome, got %q", board) }
+    if strings.Contains(board, "Done") || strings.Contains(board, "Elsewhere") { t.Fatalf("expected resolved and other-category markets to be left out, got %q", board) }
+    if strings.Contains(board, "11. ") || !strings.Contains(board, "10. ") { t.Fatalf("expected ten rows, got %q", board) }
+
+    if empty := boards.BuildBoard(nil, nil); !strings.Contains(empty, "No active markets") { t.Fatalf("unexpected empty board %q", empty) }
+}
+
+func TestMarketBoardIsPostedEditedAndRemoved(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    marketService := services.NewMockMarketService(logger)
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    messenger := newFakeBoardMessenger()
+    boards := services.NewMarketBoardService(repo, marketService, messenger, logger)
+
+    marketService.SetMarket(&models.Market{ID: "m1", Title: "Board market", Status: "active", Volume: 1000, Outcomes: []string{"Yes", "No"}, Percentages: []float64{55, 45}})
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "quiet", FeedEnabled: true}, "test")
+    if err := subscriptionService.SetChannelBoard(ctx, "c1", "g1", true, "admin"); err != nil { t.Fatalf("failed to turn the board on: %v", err) }
+
+    if refreshed := boards.RefreshBoards(ctx); refreshed != 1 || messenger.posts != 1 { t.Fatalf("expected one board to be posted, got %d refreshed and %d posts", refreshed, messenger.posts) }
+    config, _ := subscriptionService.GetChannelConfig(ctx, "c1")
+    if config.BoardMessageID == "" || !messenger.pinned[config.BoardMessageID] { t.Fatalf("expected the posted board to be pinned and saved, got %+v", config) }
+
+    if refreshed := boards.RefreshBoards(ctx); refreshed != 0 { t.Fatalf("expected an unchanged board not to be edited, got %d", refreshed) }
+
+    marketService.SetMarket(&models.Market{ID: "m1", Title: "Board market", Status: "active", Volume: 5000, Outcomes: []string{"Yes", "No"}, Percentages: []float64{40, 60}})
+    if refreshed := boards.RefreshBoards(ctx); refreshed != 1 || messenger.edits != 1 || messenger.posts != 1 { t.Fatalf("expected the board to be edited in place, got %d edits and %d posts", messenger.edits, messenger.posts) }
+    if content := messenger.messages[config.BoardMessageID]; !strings.Contains(content, "No 60%") { t.Fatalf("expected the edit to show the new leader, got %q", content) }
+
+    // A board deleted in Discord is posted again
+    delete(messenger.messages, config.BoardMessageID)
+    marketService.SetMarket(&models.Market{ID: "m1", Title: "Board market", Status: "active", Volume: 6000, Outcomes: []string{"Yes", "No"}, Percentages: []float64{40, 60}})
+    boards.RefreshBoards(ctx)
+    reposted, _ := subscriptionService.GetChannelConfig(ctx, "c1")
+    if messenger.posts != 2 || reposted.BoardMessageID == config.BoardMessageID { t.Fatalf("expected a deleted board to be reposted, got %d posts and message %s", messenger.posts, reposted.BoardMessageID) }
+
+    subscriptionService.SetChannelBoard(ctx, "c1", "g1", false, "admin")
+    boards.RefreshBoards(ctx)
+    config, _ = subscriptionService.GetChannelConfig(ctx, "c1")
+    if config.BoardMessageID != "" || len(messenger.messages) != 0 { t.Fatalf("expected the board to be deleted, got %+v and %d messages", config, len(messenger.messages)) }
+}
+
+// countingBoardService counts refresh requests
+type countingBoardService struct {
+    services.MarketBoardService
+    changes int
+}
+
+func (b *countingBoardService) MarketsChanged() {
+    b.changes++
+}
+
+func TestEventsAndBoardEndpointRefreshBoards(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    boards := &countingBoardService{}
+    h.SetMarketBoardService(boards)
+
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/board", `{"enabled": true}`, "root-key"); rec.Code != http.StatusBadRequest { t.Fatalf("expected 400 without a channel, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/board", `{"channel_id": "c1", "enabled": true}`, "root-key"); rec.Code != http.StatusOK { t.Fatalf("expected 200, got %d", rec.Code) }
+    config, _ := subscriptionService.GetChannelConfig(context.Background(), "c1")
+    if !config.Board || boards.changes != 1 { t.Fatalf("expected the board to be turned on and refreshed, got %+v and %d refreshes", config, boards.changes) }
+
+    if _, err := h.ProcessEventJSON(context.Background(), models.EventMarketUpdate, []byte(`{"market_id": "m1", "title": "Moved"}`)); err != nil { t.Fatalf("failed to process update: %v", err) }
+    if boards.changes != 2 { t.Fatalf("expected a market event to refresh the boards, got %d refreshes", boards.changes) }
+}
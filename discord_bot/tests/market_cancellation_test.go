@@ -0,0 +1,62 @@
+package tests
+
+import (
+    "context"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestMarketCancellationNotifiesAndCleansUp(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    subscriptionService.SubscribeToMarket(ctx, "u1", "m1")
+    subscriptionService.SubscribeToMarket(ctx, "u1", "m2")
+    subscriptionService.SubscribeToOutcome(ctx, "u2", "m1", "Yes", 0)
+    subscriptionService.SubscribeToMarket(ctx, "u3", "m2")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1"}, "test")
+    subscriptionService.SubscribeChannelToMarket(ctx, "c1", "m1", "test")
+    repo.SaveReminder(ctx, &models.Reminder{ID: "r1", MarketID: "m1", DiscordUserID: "u1", RemindAt: time.Now().Add(time.Hour)})
+    repo.SaveReminder(ctx, &models.Reminder{ID: "r2", MarketID: "m2", DiscordUserID: "u1", RemindAt: time.Now().Add(2 * time.Hour)})
+
+    // A disconnected gateway buffers the sends, so they can be counted
+    session, _ := discordgo.New("Bot test")
+    h.SetDiscordSession(session)
+    gateway := services.NewGatewayMonitor(0, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+
+    body := `{"market_id": "m1", "title": "Rain?", "reason": "The weather station went offline", "refund_total": 1200}`
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-cancelled", body, "root-key"); rec.Code != http.StatusAccepted { t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String()) }
+    if buffered := gateway.Status().Buffered; buffered != 3 { t.Fatalf("expected c1, u1 and u2 to be sent the cancellation, got %d sends", buffered) }
+
+    if subscription, _ := repo.GetSubscription(ctx, "u1"); len(subscription.SubscribedMarkets) != 1 || subscription.SubscribedMarkets[0] != "m2" { t.Fatalf("expected only m2 to be left, got %+v", subscription.SubscribedMarkets) }
+    if subscription, _ := repo.GetSubscription(ctx, "u2"); len(subscription.SubscribedOutcomes) != 0 { t.Fatalf("expected the outcome subscription to be removed, got %+v", subscription.SubscribedOutcomes) }
+    if config, _ := subscriptionService.GetChannelConfig(ctx, "c1"); len(config.SubscribedMarkets) != 0 { t.Fatalf("expected the channel to be unsubscribed, got %+v", config.SubscribedMarkets) }
+    if reminders, _ := repo.GetRemindersByUser(ctx, "u1"); len(reminders) != 1 || reminders[0].ID != "r2" { t.Fatalf("expected only the m2 reminder to be left, got %+v", reminders) }
+    if due, _ := repo.GetDueReminders(ctx, time.Now().Add(3*time.Hour)); len(due) != 1 { t.Fatalf("expected the reminder queue to drop the m1 reminder, got %d", len(due)) }
+    if resolved, _ := repo.IsMarketResolved(ctx, "m1"); !resolved { t.Fatalf("expected later events for the cancelled market to be suppressed") }
+
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-cancelled", `{"title": "Rain?"}`, "root-key"); rec.Code != http.StatusBadRequest { t.Fatalf("expected 400 without a market, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/market-cancelled", `{"market_id": "m3", "refund_total": -1}`, "root-key"); rec.Code != http.StatusBadRequest { t.Fatalf("expected 400 for a negative refund, got %d", rec.Code) }
+}
+
+func TestMarketCancelledMessageExplainsRefunds(t *testing.T) {
+    marketService := services.NewMockMarketService(utils.NewLogger())
+    message := marketService.CreateMarketCancelledMessage(&models.Market{Title: "Rain?", Volume: 1200, Link: "https://coral.markets/m1"}, "Ambiguous question")
+    if !strings.Contains(message, "MARKET CANCELLED") || !strings.Contains(message, "Reason: Ambiguous question") || !strings.Contains(message, "refunded") || !strings.Contains(message, "$1200.00") { t.Fatalf("unexpected message %q", message) }
+    if plain := marketService.CreateMarketCancelledMessage(&models.Market{Title: "Rain?"}, ""); strings.Contains(plain, "Reason") || strings.Contains(plain, "$") { t.Fatalf("expected no reason or amount, got %q", plain) }
+}
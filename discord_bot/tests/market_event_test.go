@@ -0,0 +1,31 @@
+package tests
+
+import (
+    "net/http"
+    "strings"
+    "testing"
+)
+
+func TestLegacyAndNewEventFormatsShareOneHandler(t *testing.T) {
+    h := newHarness(t)
+    h.feedChannel("c1", "g1", nil)
+
+    legacy := `{"event_type": "new_market", "market": {"market_id": "m1", "title": "Legacy market", "category": "sports", "volume": 5000, "status": "active"}}`
+    if rec := serveWithKey(h.handler, http.MethodPost, "/webhooks/new_market", legacy, harnessAPIKey); rec.Code != http.StatusOK || rec.Body.String() != `{"ok": true}` { t.Fatalf("expected the legacy response, got %d: %s", rec.Code, rec.Body.String()) }
+    if rec := serveWithKey(h.handler, http.MethodPost, "/discord/events/new-market", `{"market_id": "m2", "title": "New market", "category": "sports", "volume": 5000}`, harnessAPIKey); rec.Code != http.StatusAccepted || !strings.Contains(rec.Body.String(), `"accepted":true`) { t.Fatalf("expected the events response, got %d: %s", rec.Code, rec.Body.String()) }
+    messages := h.discord.channelMessages("c1")
+    if len(messages) != 2 || !strings.Contains(messages[0].Content, "Legacy market") || !strings.Contains(messages[1].Content, "New market") { t.Fatalf("expected both formats announced alike, got %+v", messages) }
+
+    // Legacy bodies are validated like the others now
+    cases := map[string]string{
+        `{"event_type": "market_update", "market": {"market_id": "m1"}}`: "Invalid event type",
+        `{"event_type": "new_market", "market": {"title": "No ID"}}`:     "market_id required",
+        `{"event_type": "new_market", "market": `:                        "Invalid JSON",
+    }
+    for body, expected := range cases {
+        rec := serveWithKey(h.handler, http.MethodPost, "/webhooks/new_market", body, harnessAPIKey)
+        if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), expected) { t.Fatalf("expected %q for %s, got %d: %s", expected, body, rec.Code, rec.Body.String()) }
+    }
+    if rec := serveWithKey(h.handler, http.MethodPost, "/discord/events/market-cancelled", `{"market_id": "m1", "refund_total": -1}`, harnessAPIKey); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "refund_total cannot be negative") { t.Fatalf("expected the cancellation rejected, got %d: %s", rec.Code, rec.Body.String()) }
+    if len(h.discord.channelMessages("c1")) != 2 { t.Fatalf("expected nothing posted for invalid events, got %+v", h.discord.messages) }
+}
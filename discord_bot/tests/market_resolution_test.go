@@ -0,0 +1,41 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "testing"
+
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestEventsAfterResolutionAreSuppressed(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), services.NewSubscriptionService(repo, logger), logger)
+
+    suppressed := func(path, body string) bool {
+        rec := serveWithKey(h, http.MethodPost, path, body, "root-key")
+        if rec.Code != http.StatusAccepted { t.Fatalf("%s: expected 202, got %d: %s", path, rec.Code, rec.Body.String()) }
+        var resp map[string]interface{}
+        json.Unmarshal(rec.Body.Bytes(), &resp)
+        return resp["suppressed"] == true
+    }
+    update := `{"market_id": "m1", "title": "Rain?", "outcomes": [{"name": "Yes", "pct": 60}]}`
+    if suppressed("/discord/events/market-update", update) { t.Fatalf("expected an update before resolution to be delivered") }
+    if suppressed("/discord/events/market-resolved", `{"market_id": "m1", "title": "Rain?", "winning_outcome": "Yes"}`) { t.Fatalf("expected the resolution to be delivered") }
+
+    if !suppressed("/discord/events/market-update", update) { t.Fatalf("expected an update after resolution to be suppressed") }
+    if !suppressed("/discord/events/market-buy", `{"market_id": "m1", "amount": 50, "outcome": "Yes"}`) { t.Fatalf("expected a buy after resolution to be suppressed") }
+    if suppressed("/discord/events/market-resolved", `{"market_id": "m1", "title": "Rain?", "winning_outcome": "Yes"}`) { t.Fatalf("expected a repeated resolution to be delivered") }
+    if suppressed("/discord/events/market-buy", `{"market_id": "m2", "amount": 50, "outcome": "Yes"}`) { t.Fatalf("expected other markets to be unaffected") }
+
+    if resolved, _ := repo.IsMarketResolved(context.Background(), "m1"); !resolved { t.Fatalf("expected m1 to be marked resolved") }
+    var health web.HealthResponse
+    json.Unmarshal(serveWithKey(h, http.MethodGet, "/discord/health", "", "").Body.Bytes(), &health)
+    if health.Events.SuppressedAfterResolution != 2 { t.Fatalf("expected 2 suppressed events, got %d", health.Events.SuppressedAfterResolution) }
+}
@@ -0,0 +1,110 @@
+package tests
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/fixtures"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+)
+
+func TestMarketServiceFailsFastWithoutBackend(t *testing.T) {
+    ctx := context.Background()
+    marketService := services.NewMarketService("", utils.NewLogger())
+
+    if _, err := marketService.FetchMarket(ctx, "m1"); !errors.Is(err, services.ErrBackendNotConfigured) {
+        t.Fatalf("expected ErrBackendNotConfigured from FetchMarket, got %v", err)
+    }
+    if _, err := marketService.FetchAllMarkets(ctx); !errors.Is(err, services.ErrBackendNotConfigured) {
+        t.Fatalf("expected ErrBackendNotConfigured from FetchAllMarkets, got %v", err)
+    }
+    if _, err := marketService.FetchMarketHistory(ctx, "m1"); !errors.Is(err, services.ErrBackendNotConfigured) {
+        t.Fatalf("expected ErrBackendNotConfigured from FetchMarketHistory, got %v", err)
+    }
+}
+
+func TestMockMarketServiceFixturesAndErrorInjection(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    marketService := services.NewMockMarketService(logger)
+    marketService.SetReferenceTime(fixtures.ReferenceTime)
+
+    market, err := marketService.FetchMarket(ctx, "m1")
+    if err != nil { t.Fatalf("unexpected error: %v", err) }
+    if market.ID != "m1" || !market.EndTime.Equal(fixtures.ReferenceTime.Add(24*time.Hour)) {
+        t.Fatalf("expected deterministic fixture market, got %+v", market)
+    }
+
+    custom := &models.Market{ID: "m2", Title: "Custom", Outcomes: []string{"Yes", "No"}, Percentages: []float64{70, 30}, EndTime: fixtures.ReferenceTime.Add(time.Hour)}
+    marketService.SetMarket(custom)
+    if got, _ := marketService.FetchMarket(ctx, "m2"); got != custom {
+        t.Fatalf("expected configured market, got %+v", got)
+    }
+    if all, _ := marketService.FetchAllMarkets(ctx); len(all) != 1 || all[0] != custom {
+        t.Fatalf("expected only configured markets, got %d", len(all))
+    }
+
+    if _, err := marketService.CreateProbabilityChart(ctx, custom); err != nil {
+        t.Fatalf("expected chart from fixture history, got %v", err)
+    }
+
+    backendDown := errors.New("backend unavailable")
+    marketService.SetError(backendDown)
+    if _, err := marketService.FetchMarket(ctx, "m2"); !errors.Is(err, backendDown) {
+        t.Fatalf("expected injected error, got %v", err)
+    }
+    reminderService := services.NewReminderService(repository.NewInMemorySubscriptionRepository(), marketService, newRecordingNotifier(), logger)
+    if _, err := reminderService.CreateUserReminder(ctx, "u1", "m2", time.Hour); err == nil {
+        t.Fatalf("expected reminder creation to surface the injected error")
+    }
+
+    marketService.SetError(nil)
+    if _, err := marketService.FetchMarketHistory(ctx, "m2"); err != nil {
+        t.Fatalf("expected fetches to recover after clearing the error, got %v", err)
+    }
+}
+
+func TestMarketUpdateMessageShowsOutcomeDeltas(t *testing.T) {
+    marketService := services.NewMarketService("", utils.NewLogger())
+    market := &models.Market{ID: "m1", Title: "Three-way", Outcomes: []string{"A", "B", "C"}, Percentages: []float64{47.2, 30.0, 22.8}, EndTime: time.Now().Add(time.Hour)}
+    previous := &models.MarketSnapshot{MarketID: "m1", Outcomes: []string{"A", "B", "C"}, Percentages: []float64{40.0, 30.0, 30.0}}
+
+    message := marketService.CreateMarketUpdateMessage(market, previous)
+    for _, want := range []string{"- **A (47.2%) ▲ +7.2%** 🔥\n", "- B (30.0%)\n", "- C (22.8%) ▼ -7.2%\n"} {
+        if !strings.Contains(message, want) { t.Fatalf("expected %q in message:\n%s", want, message) }
+    }
+
+    if message := marketService.CreateMarketUpdateMessage(market, nil); strings.Contains(message, "▲") || strings.Contains(message, "**A") {
+        t.Fatalf("expected no deltas without a previous snapshot:\n%s", message)
+    }
+}
+
+func TestMessageBuildersUseDiscordTimestamps(t *testing.T) {
+    marketService := services.NewMarketService("", utils.NewLogger())
+    closes := time.Date(2026, time.March, 6, 18, 0, 0, 0, time.UTC)
+    market := &models.Market{ID: "m1", Title: "Timestamps", Outcomes: []string{"Yes", "No"}, Percentages: []float64{60, 40}, EndTime: closes, ResolvedOutcome: "Yes", ResolvedAt: closes}
+
+    for name, message := range map[string]string{
+        "announcement": marketService.CreateMarketAnnouncement(market),
+        "update":       marketService.CreateMarketUpdateMessage(market, nil),
+        "closing soon": marketService.CreateMarketClosingSoonMessage(market),
+        "resolution":   marketService.CreateMarketResolutionMessage(market),
+    } {
+        if !strings.Contains(message, "<t:1772820000:F> (<t:1772820000:R>)") { t.Fatalf("expected %s message to use timestamp markup, got %q", name, message) }
+        if strings.Contains(message, "Time Left") { t.Fatalf("expected %s message to drop the raw duration, got %q", name, message) }
+    }
+
+    history := []*models.MarketSnapshot{
+        {MarketID: "m1", Outcomes: []string{"Yes", "No"}, Percentages: []float64{50, 50}, Timestamp: closes.Add(-2 * time.Hour)},
+        {MarketID: "m1", Outcomes: []string{"Yes", "No"}, Percentages: []float64{60, 40}, Timestamp: closes.Add(-time.Hour)},
+    }
+    if message := marketService.CreateMarketHistoryMessage(market, history, "day"); !strings.Contains(message, "since <t:1772812800:f>") {
+        t.Fatalf("expected the history start as timestamp markup, got %q", message)
+    }
+}
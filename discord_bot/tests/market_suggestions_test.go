@@ -0,0 +1,63 @@
+package tests
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// buttonClick returns a click from user u1 on a message button
+func buttonClick(customID string) *discordgo.InteractionCreate {
+    return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+        ID:    "interaction-2",
+        Token: "token",
+        Type:  discordgo.InteractionMessageComponent,
+        User:  &discordgo.User{ID: "u1"},
+        Data:  discordgo.MessageComponentInteractionData{CustomID: customID, ComponentType: discordgo.ButtonComponent},
+    }}
+}
+
+func TestUnknownMarketSuggestions(t *testing.T) {
+    ctx := context.Background()
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    marketService := services.NewMockMarketService(logger)
+    marketService.SetStrict(true)
+    marketService.SetMarket(&models.Market{ID: "rain-paris", Title: "Will it rain in Paris tomorrow?", Status: "active"})
+    marketService.SetMarket(&models.Market{ID: "rain-london", Title: "Will it rain in London tomorrow?", Status: "active"})
+    marketService.SetMarket(&models.Market{ID: "btc-100k", Title: "Bitcoin above $100k?", Status: "active"})
+    h := handlers.NewCommandHandler(marketService, subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger)
+    marketOption := func(value string) *discordgo.ApplicationCommandInteractionDataOption {
+        return &discordgo.ApplicationCommandInteractionDataOption{Name: "market_id", Type: discordgo.ApplicationCommandOptionString, Value: value}
+    }
+
+    h.HandleInteraction(session, commandInteraction("market", marketOption("rain")))
+    reply := (*responses)[0].Data
+    if reply.Flags != discordgo.MessageFlagsEphemeral || !strings.Contains(reply.Content, "Did you mean") || !strings.Contains(reply.Content, "`rain-london`") { t.Fatalf("expected private suggestions, got %+v", reply) }
+    if len(reply.Components) != 1 || !strings.Contains(reply.Content, "`rain-paris`") || strings.Contains(reply.Content, "btc-100k") { t.Fatalf("expected a row of buttons for the matching markets only, got %+v", reply) }
+
+    h.HandleInteraction(session, buttonClick("pick_market|market|rain-paris|"))
+    if reply := (*responses)[1].Data; !strings.Contains(reply.Content, "Will it rain in Paris tomorrow?") { t.Fatalf("expected the picked market to be shown, got %+v", reply) }
+
+    h.HandleInteraction(session, commandInteraction("subscribe_market", marketOption("BTC")))
+    if reply := (*responses)[2].Data; !strings.Contains(reply.Content, "`btc-100k`") { t.Fatalf("expected an ID prefix to be matched, got %+v", reply) }
+    if subscription, _ := subscriptionService.GetUserSubscriptions(ctx, "u1"); len(subscription.SubscribedMarkets) != 0 { t.Fatalf("expected no subscription to an unknown market, got %+v", subscription.SubscribedMarkets) }
+    h.HandleInteraction(session, buttonClick("pick_market|subscribe_market|btc-100k|"))
+    if subscription, _ := subscriptionService.GetUserSubscriptions(ctx, "u1"); len(subscription.SubscribedMarkets) != 1 || subscription.SubscribedMarkets[0] != "btc-100k" { t.Fatalf("expected the picked market to be subscribed, got %+v", subscription) }
+
+    h.HandleInteraction(session, commandInteraction("subscribe_market", marketOption("snow")))
+    if reply := (*responses)[4].Data; !strings.Contains(reply.Content, "No market has the ID `snow`") || len(reply.Components) != 0 { t.Fatalf("expected no suggestions without matches, got %+v", reply) }
+
+    h.HandleInteraction(session, buttonClick("pick_market|channel_subscribe_market|btc-100k|"))
+    if reply := (*responses)[5].Data; !strings.Contains(reply.Content, "only be used in a server") { t.Fatalf("expected channel commands to stay server-only, got %+v", reply) }
+}
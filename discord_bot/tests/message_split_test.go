@@ -0,0 +1,57 @@
+package tests
+
+import (
+    "fmt"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestSplitMessageKeepsPartsWithinTheLimit(t *testing.T) {
+    if parts := services.SplitMessage("short", 2000); len(parts) != 1 || parts[0] != "short" { t.Fatalf("expected a short message as its only part, got %q", parts) }
+
+    paragraphs := strings.Repeat("word ", 30) + "\n\n" + strings.Repeat("more ", 30)
+    parts := services.SplitMessage(paragraphs, 200)
+    if len(parts) != 2 || strings.Contains(parts[0], "more") || strings.HasPrefix(parts[1], "\n") { t.Fatalf("expected a split between the paragraphs, got %q", parts) }
+
+    var table strings.Builder
+    table.WriteString("Payouts:\n```\n")
+    for i := 0; i < 40; i++ {
+        table.WriteString(fmt.Sprintf("Outcome %02d      10.0%%\n", i))
+    }
+    table.WriteString("```\nDone")
+    parts = services.SplitMessage(table.String(), 300)
+    if len(parts) < 3 { t.Fatalf("expected the table over several parts, got %d", len(parts)) }
+    for i, part := range parts {
+        if length := len([]rune(part)); length > 300 { t.Fatalf("expected part %d within the limit, got %d characters", i, length) }
+        if strings.Count(part, "```")%2 != 0 { t.Fatalf("expected part %d to close its code block, got %q", i, part) }
+    }
+    if joined := strings.Join(parts, "\n"); !strings.Contains(joined, "Outcome 00") || !strings.Contains(joined, "Outcome 39") || !strings.HasSuffix(joined, "Done") { t.Fatalf("expected no row to be lost, got %q", joined) }
+
+    // Text without any break is cut at the limit, without splitting a character
+    if parts := services.SplitMessage(strings.Repeat("é", 250), 100); len(parts) != 3 || len([]rune(parts[0])) != 96 || parts[2] != strings.Repeat("é", 58) { t.Fatalf("expected hard cuts within the limit, got %d parts", len(parts)) }
+}
+
+func TestLongAnnouncementsAreSplitAndTruncated(t *testing.T) {
+    h := newHarness(t)
+    h.feedChannel("c1", "g1", func(config *models.ChannelConfig) { config.PingRoleID, config.PingEvents = "555", []string{models.EventNewMarket} })
+
+    event := newMarketEvent("m1", "Sports", "alice", 1000)
+    event.Description = strings.Repeat("A very long description. ", 100)
+    for i := 0; i < 80; i++ {
+        event.Outcomes = append(event.Outcomes, web.EventOutcome{ID: fmt.Sprintf("o%d", i), Name: fmt.Sprintf("Team number %02d of the league", i)})
+    }
+    h.postEvent("new-market", event)
+
+    messages := h.discord.channelMessages("c1")
+    if len(messages) < 2 { t.Fatalf("expected the announcement over several messages, got %d", len(messages)) }
+    if !strings.HasPrefix(messages[0].Content, "<@&555> ") || !strings.Contains(messages[0].Content, "… [Read more](https://coral.markets/market/m1)") { t.Fatalf("expected the first part to ping and cut the description short, got %q", messages[0].Content) }
+    for i, message := range messages {
+        if length := len([]rune(message.Content)); length > 2000 { t.Fatalf("expected part %d within Discord's limit, got %d characters", i, length) }
+        if i > 0 && (strings.Contains(message.Content, "<@&555>") || len(message.Mentions.Roles) != 0) { t.Fatalf("expected only the first part to ping, got %+v", message) }
+    }
+    if last := messages[len(messages)-1].Content; !strings.Contains(last, "Team number 79") || !strings.Contains(last, "View on Coral Markets") { t.Fatalf("expected the last part to end the announcement, got %q", last) }
+}
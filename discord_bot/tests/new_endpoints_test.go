@@ -17,7 +17,7 @@ import (
 func setupHandler() *web.WebhookHandler {
     logger := utils.NewLogger()
     repo := repository.NewInMemorySubscriptionRepository()
-    marketService := services.NewMarketService("", logger)
+    marketService := services.NewMockMarketService(logger)
     subscriptionService := services.NewSubscriptionService(repo, logger)
     return web.NewWebhookHandler(marketService, subscriptionService, logger)
 }
@@ -34,12 +34,12 @@ func TestEventNewMarketAccepted(t *testing.T) {
         "start_time": time.Now().Add(-time.Hour).Format(time.RFC3339),
         "end_time":   time.Now().Add(time.Hour).Format(time.RFC3339),
         "volume":     100.0,
-        "link":       "https://example.com/m1",
+        "link":       "https://coral.markets/m1",
     }
     b, _ := json.Marshal(payload)
     req := httptest.NewRequest(http.MethodPost, "/discord/events/new-market", bytes.NewBuffer(b))
     rec := httptest.NewRecorder()
-    h.HandleEventNewMarket(rec, req)
+    h.Handler().ServeHTTP(rec, req)
     if rec.Code != http.StatusAccepted {
         t.Fatalf("expected %d got %d", http.StatusAccepted, rec.Code)
     }
@@ -54,12 +54,12 @@ func TestEventMarketUpdateAccepted(t *testing.T) {
         "volume_delta_pct": 5.0,
         "time_left":       "1h",
         "end_time":        time.Now().Add(time.Hour).Format(time.RFC3339),
-        "link":            "https://example.com/m1",
+        "link":            "https://coral.markets/m1",
     }
     b, _ := json.Marshal(payload)
     req := httptest.NewRequest(http.MethodPost, "/discord/events/market-update", bytes.NewBuffer(b))
     rec := httptest.NewRecorder()
-    h.HandleEventMarketUpdate(rec, req)
+    h.Handler().ServeHTTP(rec, req)
     if rec.Code != http.StatusAccepted {
         t.Fatalf("expected %d got %d", http.StatusAccepted, rec.Code)
     }
@@ -74,12 +74,12 @@ func TestEventTradingStartAccepted(t *testing.T) {
         "duration":     "2h",
         "outcomes_count": 2,
         "outcomes":     []string{"Yes", "No"},
-        "link":         "https://example.com/m1",
+        "link":         "https://coral.markets/m1",
     }
     b, _ := json.Marshal(payload)
     req := httptest.NewRequest(http.MethodPost, "/discord/events/trading-start", bytes.NewBuffer(b))
     rec := httptest.NewRecorder()
-    h.HandleEventTradingStart(rec, req)
+    h.Handler().ServeHTTP(rec, req)
     if rec.Code != http.StatusAccepted {
         t.Fatalf("expected %d got %d", http.StatusAccepted, rec.Code)
     }
@@ -93,12 +93,12 @@ func TestEventTradingEndAccepted(t *testing.T) {
         "description": "Desc",
         "outcomes":    []map[string]interface{}{{"id": "o1", "name": "Yes", "pct": 60.0}, {"id": "o2", "name": "No", "pct": 40.0}},
         "final_pool":  500.0,
-        "link":        "https://example.com/m1",
+        "link":        "https://coral.markets/m1",
     }
     b, _ := json.Marshal(payload)
     req := httptest.NewRequest(http.MethodPost, "/discord/events/trading-end", bytes.NewBuffer(b))
     rec := httptest.NewRecorder()
-    h.HandleEventTradingEnd(rec, req)
+    h.Handler().ServeHTTP(rec, req)
     if rec.Code != http.StatusAccepted {
         t.Fatalf("expected %d got %d", http.StatusAccepted, rec.Code)
     }
@@ -111,12 +111,12 @@ func TestEventMarketResolvedAccepted(t *testing.T) {
         "title":          "Test Market",
         "winning_outcome": "Yes",
         "total_pool":     1000.0,
-        "link":           "https://example.com/m1",
+        "link":           "https://coral.markets/m1",
     }
     b, _ := json.Marshal(payload)
     req := httptest.NewRequest(http.MethodPost, "/discord/events/market-resolved", bytes.NewBuffer(b))
     rec := httptest.NewRecorder()
-    h.HandleEventMarketResolved(rec, req)
+    h.Handler().ServeHTTP(rec, req)
     if rec.Code != http.StatusAccepted {
         t.Fatalf("expected %d got %d", http.StatusAccepted, rec.Code)
     }
@@ -130,23 +130,46 @@ func TestEventMarketBuyAccepted(t *testing.T) {
         "amount":    42.50,
         "outcome":   "Yes",
         "buyer":     "u1",
-        "link":      "https://example.com/m1",
+        "link":      "https://coral.markets/m1",
     }
     b, _ := json.Marshal(payload)
     req := httptest.NewRequest(http.MethodPost, "/discord/events/market-buy", bytes.NewBuffer(b))
     rec := httptest.NewRecorder()
-    h.HandleEventMarketBuy(rec, req)
+    h.Handler().ServeHTTP(rec, req)
     if rec.Code != http.StatusAccepted {
         t.Fatalf("expected %d got %d", http.StatusAccepted, rec.Code)
     }
 }
 
+func TestEventMarketBuyBelowMinimumSuppressed(t *testing.T) {
+    h := setupHandler()
+    h.SetBuyThresholds(100, 10000)
+    payload := map[string]interface{}{
+        "market_id": "m1",
+        "title":     "Test Market",
+        "amount":    42.50,
+        "outcome":   "Yes",
+    }
+    b, _ := json.Marshal(payload)
+    req := httptest.NewRequest(http.MethodPost, "/discord/events/market-buy", bytes.NewBuffer(b))
+    rec := httptest.NewRecorder()
+    h.Handler().ServeHTTP(rec, req)
+    if rec.Code != http.StatusAccepted {
+        t.Fatalf("expected %d got %d", http.StatusAccepted, rec.Code)
+    }
+    var resp map[string]interface{}
+    _ = json.Unmarshal(rec.Body.Bytes(), &resp)
+    if resp["suppressed"] != true {
+        t.Fatalf("expected buy below the minimum to be suppressed, got %v", resp)
+    }
+}
+
 func TestNotificationsDMRequiresDiscordSession(t *testing.T) {
     h := setupHandler()
     payload := map[string]interface{}{
         "discord_user_id": "user-1",
         "type":            "market_update",
-        "payload":         map[string]interface{}{"market_id": "m1", "title": "Test", "link": "https://example.com/m1"},
+        "payload":         map[string]interface{}{"market_id": "m1", "title": "Test", "link": "https://coral.markets/m1"},
     }
     b, _ := json.Marshal(payload)
     req := httptest.NewRequest(http.MethodPost, "/discord/notifications/dm", bytes.NewBuffer(b))
@@ -184,7 +207,7 @@ func TestSubscribeUnsubscribeCreatorAndList(t *testing.T) {
 
     listReq := httptest.NewRequest(http.MethodGet, "/discord/subscriptions/u1", nil)
     listRec := httptest.NewRecorder()
-    h.HandleGetUserSubscriptions(listRec, listReq)
+    h.Handler().ServeHTTP(listRec, listReq)
     if listRec.Code != http.StatusOK { t.Fatalf("expected %d got %d", http.StatusOK, listRec.Code) }
 
     b2, _ := json.Marshal(sub)
@@ -216,7 +239,7 @@ func TestChannelAdminSettings(t *testing.T) {
 
     r4 := httptest.NewRequest(http.MethodGet, "/discord/channel/settings/ch1", nil)
     w4 := httptest.NewRecorder()
-    h.HandleGetChannelSettings(w4, r4)
+    h.Handler().ServeHTTP(w4, r4)
     if w4.Code != http.StatusOK { t.Fatalf("expected %d got %d", http.StatusOK, w4.Code) }
 }
 
@@ -230,7 +253,7 @@ func TestHealthEndpointOK(t *testing.T) {
 
 func TestDeleteWebhookByPath204(t *testing.T) {
     h := setupHandler()
-    regBody := map[string]interface{}{"channel_id": "channel-2", "webhook_url": "https://discordapp.test/webhook/2", "events": []string{"new_market"}, "frequency": "low"}
+    regBody := map[string]interface{}{"channel_id": "channel-2", "webhook_url": "https://discord.com/api/webhooks/2/token", "events": []string{"new_market"}, "frequency": "low"}
     rb, _ := json.Marshal(regBody)
     regReq := httptest.NewRequest(http.MethodPost, "/discord/webhooks/register", bytes.NewBuffer(rb))
     regRec := httptest.NewRecorder()
@@ -241,7 +264,37 @@ func TestDeleteWebhookByPath204(t *testing.T) {
     _ = json.Unmarshal(regRec.Body.Bytes(), &created)
     delReq := httptest.NewRequest(http.MethodDelete, "/discord/webhooks/"+created.ID, nil)
     delRec := httptest.NewRecorder()
-    h.HandleUnregisterWebhookByPath(delRec, delReq)
+    h.Handler().ServeHTTP(delRec, delReq)
     if delRec.Code != http.StatusNoContent { t.Fatalf("expected %d got %d", http.StatusNoContent, delRec.Code) }
 }
 
+
+func TestChannelSubscribeUnsubscribeMarket(t *testing.T) {
+    h := setupHandler()
+    b1, _ := json.Marshal(map[string]string{"channel_id": "ch1", "market_id": "m1"})
+    r1 := httptest.NewRequest(http.MethodPost, "/discord/channel/subscribe/market", bytes.NewBuffer(b1))
+    w1 := httptest.NewRecorder()
+    h.HandleChannelSubscribeMarket(w1, r1)
+    if w1.Code != http.StatusOK { t.Fatalf("expected %d got %d", http.StatusOK, w1.Code) }
+
+    r2 := httptest.NewRequest(http.MethodGet, "/discord/channel/settings/ch1", nil)
+    w2 := httptest.NewRecorder()
+    h.Handler().ServeHTTP(w2, r2)
+    var cfg struct{ SubscribedMarkets []string `json:"subscribed_markets"` }
+    _ = json.Unmarshal(w2.Body.Bytes(), &cfg)
+    if len(cfg.SubscribedMarkets) != 1 || cfg.SubscribedMarkets[0] != "m1" {
+        t.Fatalf("expected channel to follow m1, got %v", cfg.SubscribedMarkets)
+    }
+
+    b3, _ := json.Marshal(map[string]string{"channel_id": "ch1", "market_id": "m1"})
+    r3 := httptest.NewRequest(http.MethodPost, "/discord/channel/unsubscribe/market", bytes.NewBuffer(b3))
+    w3 := httptest.NewRecorder()
+    h.HandleChannelUnsubscribeMarket(w3, r3)
+    if w3.Code != http.StatusOK { t.Fatalf("expected %d got %d", http.StatusOK, w3.Code) }
+
+    b4, _ := json.Marshal(map[string]string{"channel_id": "ch1"})
+    r4 := httptest.NewRequest(http.MethodPost, "/discord/channel/subscribe/market", bytes.NewBuffer(b4))
+    w4 := httptest.NewRecorder()
+    h.HandleChannelSubscribeMarket(w4, r4)
+    if w4.Code != http.StatusBadRequest { t.Fatalf("expected %d got %d", http.StatusBadRequest, w4.Code) }
+}
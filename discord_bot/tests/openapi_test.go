@@ -0,0 +1,150 @@
+package tests
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestOpenAPIDocumentDescribesRoutes(t *testing.T) {
+    h := setupHandler()
+    rec := httptest.NewRecorder()
+    h.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/discord/openapi.json", nil))
+    if rec.Code != http.StatusOK {
+        t.Fatalf("expected %d got %d", http.StatusOK, rec.Code)
+    }
+
+    var doc struct {
+        OpenAPI    string                            `json:"openapi"`
+        Paths      map[string]map[string]interface{} `json:"paths"`
+        Components struct {
+            Schemas map[string]interface{} `json:"schemas"`
+        } `json:"components"`
+    }
+    if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+        t.Fatalf("failed to decode document: %v", err)
+    }
+    if doc.OpenAPI == "" {
+        t.Fatalf("expected openapi version")
+    }
+    if _, ok := doc.Paths["/discord/subscriptions/{discord_user_id}"]["get"]; !ok {
+        t.Fatalf("expected GET subscriptions path, got %v", doc.Paths["/discord/subscriptions/{discord_user_id}"])
+    }
+    unregister := doc.Paths["/discord/webhooks/unregister"]
+    if _, ok := unregister["delete"]; !ok {
+        t.Fatalf("expected DELETE unregister")
+    }
+    if _, ok := unregister["post"]; !ok {
+        t.Fatalf("expected POST unregister")
+    }
+    if _, ok := doc.Components.Schemas["MarketBuyEventRequest"]; !ok {
+        t.Fatalf("expected request schema to be registered")
+    }
+}
+
+func TestRouterRejectsUnsupportedMethods(t *testing.T) {
+    h := setupHandler()
+    rec := httptest.NewRecorder()
+    h.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhooks/new_market", nil))
+    if rec.Code != http.StatusMethodNotAllowed {
+        t.Fatalf("expected %d got %d", http.StatusMethodNotAllowed, rec.Code)
+    }
+    if allow := rec.Header().Get("Allow"); allow != http.MethodPost {
+        t.Fatalf("expected Allow: POST, got %q", allow)
+    }
+}
+
+func TestRouterReturnsJSONNotFound(t *testing.T) {
+    h := setupHandler()
+    for _, path := range []string{"/nope", "/discord/subscriptions/u1/extra", "/discord/subscriptions/"} {
+        rec := httptest.NewRecorder()
+        h.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+        if rec.Code != http.StatusNotFound {
+            t.Fatalf("%s: expected %d got %d", path, http.StatusNotFound, rec.Code)
+        }
+        var resp map[string]string
+        if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp["error"] == "" {
+            t.Fatalf("%s: expected JSON error body, got %q", path, rec.Body.String())
+        }
+    }
+}
+
+func TestRateLimitUsesForwardedClientFromTrustedProxy(t *testing.T) {
+    h := setupHandler()
+    h.SetRateLimit(1)
+    if err := h.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    router := h.Handler()
+
+    send := func(remote, forwarded string) int {
+        req := httptest.NewRequest(http.MethodGet, "/discord/health", nil)
+        req.RemoteAddr = remote
+        if forwarded != "" {
+            req.Header.Set("X-Forwarded-For", forwarded)
+        }
+        rec := httptest.NewRecorder()
+        router.ServeHTTP(rec, req)
+        return rec.Code
+    }
+
+    if code := send("10.0.0.1:1234", "203.0.113.5"); code != http.StatusOK {
+        t.Fatalf("expected first request to pass, got %d", code)
+    }
+    if code := send("10.0.0.2:1234", "203.0.113.6"); code != http.StatusOK {
+        t.Fatalf("expected a different forwarded client to pass, got %d", code)
+    }
+    if code := send("10.0.0.1:1234", "203.0.113.5"); code != http.StatusTooManyRequests {
+        t.Fatalf("expected repeated client to be limited, got %d", code)
+    }
+    // An untrusted peer cannot escape the limit by spoofing X-Forwarded-For
+    if code := send("198.51.100.1:1234", "203.0.113.7"); code != http.StatusOK {
+        t.Fatalf("expected first untrusted request to pass, got %d", code)
+    }
+    if code := send("198.51.100.1:1234", "203.0.113.8"); code != http.StatusTooManyRequests {
+        t.Fatalf("expected spoofed header to be ignored, got %d", code)
+    }
+}
+
+func TestIngestAllowlistRejectsOtherClients(t *testing.T) {
+    h := setupHandler()
+    if err := h.SetIngestAllowlist([]string{"not-an-ip"}); err == nil {
+        t.Fatalf("expected an invalid allowlist entry rejected")
+    }
+    h.SetTrustedProxies([]string{"10.0.0.0/8"})
+    if err := h.SetIngestAllowlist([]string{"203.0.113.0/24", "2001:db8::1"}); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    router := h.Handler()
+
+    send := func(method, path, remote, forwarded string) int {
+        req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+        req.RemoteAddr = remote
+        if forwarded != "" {
+            req.Header.Set("X-Forwarded-For", forwarded)
+        }
+        rec := httptest.NewRecorder()
+        router.ServeHTTP(rec, req)
+        return rec.Code
+    }
+
+    for _, path := range []string{"/discord/events/new-market", "/discord/events/batch", "/webhooks/new_market"} {
+        if code := send(http.MethodPost, path, "198.51.100.1:1234", ""); code != http.StatusForbidden {
+            t.Fatalf("%s: expected an unlisted client rejected, got %d", path, code)
+        }
+        if code := send(http.MethodPost, path, "198.51.100.1:1234", "203.0.113.5"); code != http.StatusForbidden {
+            t.Fatalf("%s: expected X-Forwarded-For from an untrusted peer ignored, got %d", path, code)
+        }
+        if code := send(http.MethodPost, path, "10.0.0.1:1234", "203.0.113.5"); code == http.StatusForbidden {
+            t.Fatalf("%s: expected the backend behind the proxy allowed", path)
+        }
+        if code := send(http.MethodPost, path, "[2001:db8::1]:1234", ""); code == http.StatusForbidden {
+            t.Fatalf("%s: expected the listed IPv6 address allowed", path)
+        }
+    }
+    if code := send(http.MethodGet, "/discord/health", "198.51.100.1:1234", ""); code != http.StatusOK {
+        t.Fatalf("expected endpoints other than ingest unaffected, got %d", code)
+    }
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestOutboxResumesUndeliveredEvents(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    h.SetOutboxService(services.NewOutboxService(repo, logger))
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "test")
+
+    // Without a Discord session the event is stored but cannot be delivered, as after a crash
+    body := `{"market_id": "m1", "title": "Outbox Market", "outcomes": [{"id": "o1", "name": "Yes"}, {"id": "o2", "name": "No"}]}`
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/new-market", body, ""); rec.Code != http.StatusAccepted { t.Fatalf("expected %d got %d", http.StatusAccepted, rec.Code) }
+    pending, _ := repo.GetPendingOutboxItems(ctx, time.Now().Add(time.Second))
+    if len(pending) != 1 || pending[0].EventType != models.EventNewMarket || pending[0].Market.ID != "m1" { t.Fatalf("expected the event to be pending in the outbox, got %+v", pending) }
+
+    // A disconnected gateway buffers the sends, so nothing reaches Discord
+    session, _ := discordgo.New("Bot test")
+    h.SetDiscordSession(session)
+    gateway := services.NewGatewayMonitor(0, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+
+    if resumed := h.ResumeOutbox(ctx, time.Now().Add(time.Second)); resumed != 1 { t.Fatalf("expected one resumed event, got %d", resumed) }
+    if buffered := gateway.Status().Buffered; buffered != 1 { t.Fatalf("expected the resumed event to be sent to c1, got %d buffered", buffered) }
+    if pending, _ := repo.GetPendingOutboxItems(ctx, time.Now().Add(time.Second)); len(pending) != 0 { t.Fatalf("expected the event to be marked delivered, got %d pending", len(pending)) }
+    if resumed := h.ResumeOutbox(ctx, time.Now().Add(time.Second)); resumed != 0 { t.Fatalf("expected delivered events not to be resumed, got %d", resumed) }
+
+    // Events received with a session are delivered straight away and marked delivered
+    if rec := serveWithKey(h, http.MethodPost, "/discord/events/new-market", body, ""); rec.Code != http.StatusAccepted { t.Fatalf("expected %d got %d", http.StatusAccepted, rec.Code) }
+    if buffered := gateway.Status().Buffered; buffered != 2 { t.Fatalf("expected the second event to be sent, got %d buffered", buffered) }
+    if pending, _ := repo.GetPendingOutboxItems(ctx, time.Now().Add(time.Second)); len(pending) != 0 { t.Fatalf("expected no pending events, got %d", len(pending)) }
+}
+
+func TestOutboxGivesUpAfterMaxAttempts(t *testing.T) {
+    h := newHarness(t)
+    outbox := services.NewOutboxService(h.repo, utils.NewLogger())
+    h.handler.SetOutboxService(outbox)
+    h.feedChannel("c1", "g1", nil)
+
+    // An event whose delivery kept crashing the bot is given up instead of replayed forever
+    poison := &models.OutboxItem{EventType: models.EventNewMarket, Content: "poison", Market: &models.Market{ID: "m1", Title: "Poison"}}
+    outbox.Enqueue(h.ctx, poison)
+    poison.Attempts = services.OutboxMaxAttempts
+    h.repo.SaveOutboxItem(h.ctx, poison)
+    retried := &models.OutboxItem{EventType: models.EventNewMarket, Content: "retried", Market: &models.Market{ID: "m2", Title: "Retried"}}
+    outbox.Enqueue(h.ctx, retried)
+    retried.Attempts = services.OutboxMaxAttempts - 1
+    h.repo.SaveOutboxItem(h.ctx, retried)
+
+    if resumed := h.handler.ResumeOutbox(h.ctx, time.Now().Add(time.Second)); resumed != 1 { t.Fatalf("expected only the item under the cap resumed, got %d", resumed) }
+    if messages := h.discord.channelMessages("c1"); len(messages) != 1 || messages[0].Content != "retried" { t.Fatalf("expected only the retried event posted, got %+v", messages) }
+    if pending, _ := outbox.Pending(h.ctx, time.Now().Add(time.Second)); len(pending) != 0 { t.Fatalf("expected the poison event not pending, got %+v", pending) }
+    if resumed := h.handler.ResumeOutbox(h.ctx, time.Now().Add(time.Second)); resumed != 0 || h.discord.count() != 1 { t.Fatalf("expected nothing resumed again, got %d", resumed) }
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+    "context"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+)
+
+func TestOutcomeSubscriptionMatching(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+
+    if err := subscriptionService.SubscribeToOutcome(ctx, "u1", "m1", "yes", 0); err != nil {
+        t.Fatalf("failed to subscribe: %v", err)
+    }
+    sub, _ := subscriptionService.GetUserSubscriptions(ctx, "u1")
+
+    market := &models.Market{ID: "m1", Outcomes: []string{"Yes", "No"}, Percentages: []float64{50, 50}}
+    previous, _ := subscriptionService.RecordMarketSnapshot(ctx, market)
+    if subscriptionService.ShouldNotifyOutcomeSubscriber(sub, market, models.EventMarketUpdate, previous) {
+        t.Fatalf("expected no notification without a previous snapshot")
+    }
+
+    small := &models.Market{ID: "m1", Outcomes: []string{"Yes", "No"}, Percentages: []float64{53, 47}}
+    previous, _ = subscriptionService.RecordMarketSnapshot(ctx, small)
+    if subscriptionService.ShouldNotifyOutcomeSubscriber(sub, small, models.EventMarketUpdate, previous) {
+        t.Fatalf("expected no notification for a 3 point move")
+    }
+
+    large := &models.Market{ID: "m1", Outcomes: []string{"Yes", "No"}, Percentages: []float64{60, 40}}
+    previous, _ = subscriptionService.RecordMarketSnapshot(ctx, large)
+    if !subscriptionService.ShouldNotifyOutcomeSubscriber(sub, large, models.EventMarketUpdate, previous) {
+        t.Fatalf("expected notification for a 7 point move")
+    }
+
+    lost := &models.Market{ID: "m1", ResolvedOutcome: "No"}
+    if subscriptionService.ShouldNotifyOutcomeSubscriber(sub, lost, models.EventMarketResolved, nil) {
+        t.Fatalf("expected no notification when another outcome wins")
+    }
+    won := &models.Market{ID: "m1", ResolvedOutcome: "Yes"}
+    if !subscriptionService.ShouldNotifyOutcomeSubscriber(sub, won, models.EventMarketResolved, nil) {
+        t.Fatalf("expected notification when the followed outcome wins")
+    }
+    if subscriptionService.ShouldNotifyUser(sub, won) {
+        t.Fatalf("outcome subscribers should not match the whole market")
+    }
+}
+
+func TestOutcomeSubscribersAreNotifiedOfGradualMoves(t *testing.T) {
+    h := newHarness(t)
+    h.postEvent("market-update", marketUpdateEvent("m1", 50))
+    if err := h.subscriptions.SubscribeToOutcome(h.ctx, "u1", "m1", "yes", 5); err != nil { t.Fatalf("failed to subscribe: %v", err) }
+    if sub, _ := h.subscriptions.GetUserSubscriptions(h.ctx, "u1"); sub.SubscribedOutcomes[0].Baseline == nil || *sub.SubscribedOutcomes[0].Baseline != 50 { t.Fatalf("expected the baseline set from the latest snapshot, got %+v", sub.SubscribedOutcomes[0]) }
+
+    // Moves of 2 points each add up to the threshold
+    for _, yes := range []float64{52, 54} {
+        h.postEvent("market-update", marketUpdateEvent("m1", yes))
+    }
+    if messages := h.discord.directMessages("u1"); len(messages) != 0 { t.Fatalf("expected no DM under the threshold, got %+v", messages) }
+    h.postEvent("market-update", marketUpdateEvent("m1", 56))
+    if messages := h.discord.directMessages("u1"); len(messages) != 1 { t.Fatalf("expected a DM once the outcome moved 6 points, got %+v", messages) }
+
+    // The notification resets the baseline
+    h.postEvent("market-update", marketUpdateEvent("m1", 59))
+    if messages := h.discord.directMessages("u1"); len(messages) != 1 { t.Fatalf("expected no DM 3 points after the notification, got %+v", messages) }
+    h.postEvent("market-update", marketUpdateEvent("m1", 61))
+    if messages := h.discord.directMessages("u1"); len(messages) != 2 { t.Fatalf("expected a DM 5 points after the notification, got %+v", messages) }
+}
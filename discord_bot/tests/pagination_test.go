@@ -0,0 +1,98 @@
+package tests
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestAdminListsArePagedAndFiltered(t *testing.T) {
+    h := newHarness(t)
+    for i := 1; i <= 5; i++ {
+        user := fmt.Sprintf("u%d", i)
+        h.subscriptions.SubscribeToMarket(h.ctx, user, "m1")
+        if i%2 == 0 {
+            h.subscriptions.SubscribeToCreator(h.ctx, user, "alice")
+        }
+        h.feedChannel(fmt.Sprintf("c%d", i), fmt.Sprintf("g%d", i%2), nil)
+    }
+    h.subscriptions.SubscribeToOutcome(h.ctx, "u6", "m1", "Yes", 5)
+    h.subscriptions.SubscribeToMarket(h.ctx, "u7", "m2")
+    h.subscriptions.SubscribeChannelToMarket(h.ctx, "c3", "m1", "test")
+
+    // Walking the cursor visits every user subscribed to m1 or its outcomes once, in order
+    var users []string
+    cursor := ""
+    for pages := 0; pages < 5; pages++ {
+        rec := serveWithKey(h.handler, http.MethodGet, "/discord/admin/subscriptions?market_id=m1&limit=4&cursor="+cursor, "", harnessAPIKey)
+        var page web.SubscriptionsResponse
+        if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || rec.Code != http.StatusOK { t.Fatalf("failed to list subscriptions: %d %s", rec.Code, rec.Body.String()) }
+        if page.Total != 6 { t.Fatalf("expected 6 matching users, got %d", page.Total) }
+        for _, subscription := range page.Subscriptions {
+            users = append(users, subscription.DiscordUserID)
+        }
+        if cursor = page.NextCursor; cursor == "" {
+            break
+        }
+    }
+    if fmt.Sprint(users) != "[u1 u2 u3 u4 u5 u6]" { t.Fatalf("expected every matching user once, got %v", users) }
+
+    rec := serveWithKey(h.handler, http.MethodGet, "/discord/admin/subscriptions?creator=alice&market_id=m1", "", harnessAPIKey)
+    var page web.SubscriptionsResponse
+    json.Unmarshal(rec.Body.Bytes(), &page)
+    if page.Total != 2 || len(page.Subscriptions) != 2 || page.NextCursor != "" { t.Fatalf("expected both filters applied, got %+v", page) }
+
+    rec = serveWithKey(h.handler, http.MethodGet, "/discord/admin/channels?guild_id=g1&limit=2", "", harnessAPIKey)
+    var channels web.ChannelsResponse
+    json.Unmarshal(rec.Body.Bytes(), &channels)
+    if channels.Total != 3 || len(channels.Channels) != 2 || channels.Channels[0].ChannelID != "c1" || channels.NextCursor == "" { t.Fatalf("unexpected first page of channels %+v", channels) }
+    rec = serveWithKey(h.handler, http.MethodGet, "/discord/admin/channels?guild_id=g1&limit=2&cursor="+channels.NextCursor, "", harnessAPIKey)
+    channels = web.ChannelsResponse{}
+    json.Unmarshal(rec.Body.Bytes(), &channels)
+    if len(channels.Channels) != 1 || channels.Channels[0].ChannelID != "c5" || channels.NextCursor != "" { t.Fatalf("unexpected last page of channels %+v", channels) }
+    rec = serveWithKey(h.handler, http.MethodGet, "/discord/admin/channels?market_id=m1", "", harnessAPIKey)
+    json.Unmarshal(rec.Body.Bytes(), &channels)
+    if channels.Total != 1 || channels.Channels[0].ChannelID != "c3" { t.Fatalf("expected only the channel following m1, got %+v", channels) }
+
+    for _, query := range []string{"limit=0", "limit=501", "cursor=not-a-cursor"} {
+        if rec := serveWithKey(h.handler, http.MethodGet, "/discord/admin/channels?"+query, "", harnessAPIKey); rec.Code != http.StatusBadRequest { t.Fatalf("expected %s rejected, got %d", query, rec.Code) }
+    }
+}
+
+func TestUserSubscriptionsAndWebhooksArePaged(t *testing.T) {
+    h := newHarness(t)
+    for _, market := range []string{"m1", "m2", "m3"} {
+        h.subscriptions.SubscribeToMarket(h.ctx, "u1", market)
+    }
+    h.subscriptions.SubscribeToCreator(h.ctx, "u1", "alice")
+    h.subscriptions.SubscribeToOutcome(h.ctx, "u1", "m2", "Yes", 5)
+
+    rec := serveWithKey(h.handler, http.MethodGet, "/discord/subscriptions/u1?limit=2", "", harnessAPIKey)
+    var subscriptions web.UserSubscriptionsResponse
+    json.Unmarshal(rec.Body.Bytes(), &subscriptions)
+    if len(subscriptions.Markets) != 2 || len(subscriptions.Creators) != 1 || subscriptions.Total.Markets != 3 || subscriptions.NextCursor == "" { t.Fatalf("unexpected first page %+v", subscriptions) }
+    rec = serveWithKey(h.handler, http.MethodGet, "/discord/subscriptions/u1?limit=2&cursor="+subscriptions.NextCursor, "", harnessAPIKey)
+    subscriptions = web.UserSubscriptionsResponse{}
+    json.Unmarshal(rec.Body.Bytes(), &subscriptions)
+    if fmt.Sprint(subscriptions.Markets) != "[m3]" || len(subscriptions.Creators) != 0 || subscriptions.NextCursor != "" { t.Fatalf("unexpected last page %+v", subscriptions) }
+
+    rec = serveWithKey(h.handler, http.MethodGet, "/discord/subscriptions/u1?market_id=m2", "", harnessAPIKey)
+    json.Unmarshal(rec.Body.Bytes(), &subscriptions)
+    if fmt.Sprint(subscriptions.Markets) != "[m2]" || len(subscriptions.Outcomes) != 1 || len(subscriptions.Creators) != 0 || subscriptions.Total.Outcomes != 1 { t.Fatalf("expected only m2 and its outcome, got %+v", subscriptions) }
+
+    for i, events := range [][]string{{"new_market"}, nil, {"market_resolved"}} {
+        registration := &models.WebhookRegistration{ChannelID: fmt.Sprintf("c%d", i%2), WebhookURL: fmt.Sprintf("https://discord.com/api/webhooks/%d/token", i), Events: events}
+        if _, err := h.subscriptions.RegisterWebhook(h.ctx, registration, "test"); err != nil { t.Fatalf("failed to register webhook: %v", err) }
+    }
+    rec = serveWithKey(h.handler, http.MethodGet, "/discord/webhooks?event=new_market&limit=1", "", harnessAPIKey)
+    var registrations []models.WebhookRegistration
+    if err := json.Unmarshal(rec.Body.Bytes(), &registrations); err != nil { t.Fatalf("expected the body to stay an array: %v", err) }
+    if len(registrations) != 1 || rec.Header().Get("X-Total-Count") != "2" || rec.Header().Get("X-Next-Cursor") == "" { t.Fatalf("unexpected page %+v with headers %v", registrations, rec.Header()) }
+    rec = serveWithKey(h.handler, http.MethodGet, "/discord/webhooks?channel_id=c0", "", harnessAPIKey)
+    json.Unmarshal(rec.Body.Bytes(), &registrations)
+    if len(registrations) != 2 || rec.Header().Get("X-Total-Count") != "2" || rec.Header().Get("X-Next-Cursor") != "" { t.Fatalf("expected both registrations of c0, got %+v", registrations) }
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// postedMessage is a message the bot posted to a channel
+type postedMessage struct {
+    ChannelID string
+    Message   discordgo.MessageSend
+}
+
+// capturePostedMessages returns a bot session whose channel messages go to a test server, and the messages
+// it receives
+func capturePostedMessages(t *testing.T) (*discordgo.Session, *[]postedMessage) {
+    messages := &[]postedMessage{}
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        channelID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v"+discordgo.APIVersion+"/channels/"), "/messages")
+        var message discordgo.MessageSend
+        json.NewDecoder(r.Body).Decode(&message)
+        *messages = append(*messages, postedMessage{ChannelID: channelID, Message: message})
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(discordgo.Message{ID: "reply", ChannelID: channelID, Content: message.Content})
+    }))
+    t.Cleanup(server.Close)
+    return redirectedSession(server, isChannelMessagesPath), messages
+}
+
+func TestPrefixCommandsRunSlashCommandHandlers(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := handlers.NewCommandHandler(services.NewMockMarketService(logger), subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger)
+    h.SetCommandPrefix("!coral")
+    session, messages := capturePostedMessages(t)
+
+    send := func(content string, bot bool) {
+        h.HandleMessageCreate(session, &discordgo.MessageCreate{Message: &discordgo.Message{
+            ID:        "msg1",
+            ChannelID: "c1",
+            GuildID:   "g1",
+            Content:   content,
+            Author:    &discordgo.User{ID: "u1", Bot: bot},
+            Member:    &discordgo.Member{},
+        }})
+    }
+    last := func() postedMessage { return (*messages)[len(*messages)-1] }
+
+    send("!CORAL subscribe m123", false)
+    if subscription, _ := repo.GetSubscription(ctx, "u1"); subscription == nil || len(subscription.SubscribedMarkets) != 1 || subscription.SubscribedMarkets[0] != "m123" { t.Fatalf("expected the message to subscribe u1 to m123, got %+v", subscription) }
+    if len(*messages) != 1 || last().ChannelID != "c1" || !strings.Contains(last().Message.Content, "subscribed to market `m123`") { t.Fatalf("expected a reply in the channel, got %+v", *messages) }
+    if reference := last().Message.Reference; reference == nil || reference.MessageID != "msg1" { t.Fatalf("expected the reply to reference the command, got %+v", reference) }
+
+    send("!coral market", false)
+    if content := last().Message.Content; !strings.Contains(content, "missing market_id") || !strings.Contains(content, "`!coral market <market_id>`") { t.Fatalf("expected the usage for a missing argument, got %q", content) }
+
+    send("!coral channel_min_buy 50", false)
+    if content := last().Message.Content; !strings.Contains(content, "Manage Server") { t.Fatalf("expected admin commands to need Manage Server, got %q", content) }
+
+    send("!coral dance", false)
+    if content := last().Message.Content; !strings.Contains(content, "Unknown command `dance`") { t.Fatalf("expected unknown commands to be named, got %q", content) }
+
+    // The help is longer than a Discord message, so it is sent in parts
+    before := len(*messages)
+    send("!coral", false)
+    if help := (*messages)[before:]; len(help) < 2 || !strings.Contains(help[0].Message.Content, "Coral Markets Bot Help") || !strings.Contains(last().Message.Content, "direct message with the bot") { t.Fatalf("expected the prefix alone to show the whole help, got %+v", help) }
+    for _, message := range (*messages)[before:] {
+        if length := len([]rune(message.Message.Content)); length > 2000 { t.Fatalf("expected every part of the help to fit in a message, got %d characters", length) }
+    }
+
+    count := len(*messages)
+    send("!coralsubscribe m1", false)
+    send("hello !coral market m1", false)
+    send("!coral market m1", true)
+    if len(*messages) != count { t.Fatalf("expected other messages and bots to be ignored, got %+v", (*messages)[count:]) }
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+    "context"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+)
+
+type recordingStatusUpdater struct {
+    statuses []string
+}
+
+func (u *recordingStatusUpdater) SetWatching(text string) error {
+    u.statuses = append(u.statuses, text)
+    return nil
+}
+
+func TestPresenceShowsActiveMarketStats(t *testing.T) {
+    logger := utils.NewLogger()
+    marketService := services.NewMockMarketService(logger)
+    marketService.SetMarket(&models.Market{ID: "m1", Status: "active", Volume: 1000000})
+    marketService.SetMarket(&models.Market{ID: "m2", Status: "active", Volume: 250000})
+    marketService.SetMarket(&models.Market{ID: "m3", Status: "resolved", Volume: 9000000})
+    statusUpdater := &recordingStatusUpdater{}
+
+    updater, err := services.NewPresenceUpdater(marketService, statusUpdater, "", logger)
+    if err != nil { t.Fatalf("unexpected error: %v", err) }
+    status, err := updater.Update(context.Background())
+    if err != nil { t.Fatalf("unexpected error: %v", err) }
+    if status != "2 active markets • $1.2M volume" || len(statusUpdater.statuses) != 1 {
+        t.Fatalf("unexpected presence %q", status)
+    }
+
+    custom, err := services.NewPresenceUpdater(marketService, statusUpdater, "{{.TotalMarkets}} markets", logger)
+    if err != nil { t.Fatalf("unexpected error: %v", err) }
+    if status, _ := custom.Update(context.Background()); status != "3 markets" {
+        t.Fatalf("expected custom template, got %q", status)
+    }
+
+    if _, err := services.NewPresenceUpdater(marketService, statusUpdater, "{{.Missing", logger); err == nil {
+        t.Fatalf("expected an invalid template to be rejected")
+    }
+}
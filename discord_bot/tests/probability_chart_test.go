@@ -0,0 +1,59 @@
+package tests
+
+import (
+    "bytes"
+    "image/png"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/charts"
+    "coral-bot/discord_bot/internal/models"
+)
+
+func TestRenderProbabilityChart(t *testing.T) {
+    now := time.Now()
+    history := []*models.MarketSnapshot{
+        {MarketID: "m1", Outcomes: []string{"Yes", "No"}, Percentages: []float64{50, 50}, Timestamp: now.Add(-2 * time.Hour)},
+        {MarketID: "m1", Outcomes: []string{"Yes", "No"}, Percentages: []float64{70, 30}, Timestamp: now.Add(-time.Hour)},
+        {MarketID: "m1", Outcomes: []string{"Yes", "No"}, Percentages: []float64{65, 35}, Timestamp: now},
+    }
+
+    image, err := charts.RenderProbabilityChart(history, charts.DefaultWidth, charts.DefaultHeight)
+    if err != nil { t.Fatalf("failed to render chart: %v", err) }
+
+    decoded, err := png.Decode(bytes.NewReader(image))
+    if err != nil { t.Fatalf("chart is not a valid PNG: %v", err) }
+    if decoded.Bounds().Dx() != charts.DefaultWidth || decoded.Bounds().Dy() != charts.DefaultHeight {
+        t.Fatalf("unexpected chart size %v", decoded.Bounds())
+    }
+
+    if _, err := charts.RenderProbabilityChart(history[:1], charts.DefaultWidth, charts.DefaultHeight); err != charts.ErrNotEnoughHistory {
+        t.Fatalf("expected ErrNotEnoughHistory, got %v", err)
+    }
+
+    if legend := charts.Legend([]string{"Yes", "No"}); legend != "🟦 Yes  🟥 No" {
+        t.Fatalf("unexpected legend %q", legend)
+    }
+}
+
+func TestSparklineDownsamplesAndScales(t *testing.T) {
+    if line := charts.Sparkline(nil, 10); line != "" {
+        t.Fatalf("expected empty sparkline for no data, got %q", line)
+    }
+
+    if line := charts.Sparkline([]float64{0, 50, 100}, 10); line != "▁▄█" {
+        t.Fatalf("unexpected sparkline %q", line)
+    }
+
+    values := make([]float64, 100)
+    for i := range values {
+        values[i] = float64(i) * 100 / float64(len(values)-1)
+    }
+    runes := []rune(charts.Sparkline(values, 24))
+    if len(runes) != 24 {
+        t.Fatalf("expected 24 points, got %d", len(runes))
+    }
+    if runes[0] != '▁' || runes[23] != '█' {
+        t.Fatalf("expected sparkline to span lowest to highest block, got %q", string(runes))
+    }
+}
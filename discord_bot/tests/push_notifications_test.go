@@ -0,0 +1,226 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// fakePushBackend is the backend's POST /notify, recording the notifications pushed to it
+type fakePushBackend struct {
+    server *httptest.Server
+    mutex  sync.Mutex
+    pushed []services.PushNotification
+}
+
+func newFakePushBackend(t *testing.T) *fakePushBackend {
+    backend := &fakePushBackend{}
+    backend.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path != "/notify" || r.Header.Get("Authorization") != "Bearer push-token" { w.WriteHeader(http.StatusUnauthorized); return }
+        var notification services.PushNotification
+        json.NewDecoder(r.Body).Decode(&notification)
+        backend.mutex.Lock()
+        backend.pushed = append(backend.pushed, notification)
+        backend.mutex.Unlock()
+        w.WriteHeader(http.StatusAccepted)
+    }))
+    t.Cleanup(backend.server.Close)
+    return backend
+}
+
+func (backend *fakePushBackend) notifications() []services.PushNotification {
+    backend.mutex.Lock()
+    defer backend.mutex.Unlock()
+    return append([]services.PushNotification(nil), backend.pushed...)
+}
+
+// failingNotifier fails every message, like DMs to a user who does not accept them
+type failingNotifier struct{}
+
+func (failingNotifier) SendChannelMessage(ctx context.Context, channelID string, message string) error {
+    return errors.New("cannot send messages to this user")
+}
+
+func (failingNotifier) SendDirectMessage(ctx context.Context, discordUserID string, message string) error {
+    return errors.New("cannot send messages to this user")
+}
+
+func TestAccountLinkingEndpoints(t *testing.T) {
+    h := newHarness(t)
+    accounts := services.NewAccountService(h.repo, utils.NewLogger())
+    clock := services.NewFakeClock(time.Now())
+    accounts.SetClock(clock)
+
+    link := func(code, accountID string) (int, string) {
+        rec := serveWithKey(h.handler, http.MethodPost, "/discord/link/confirm", `{"code": "`+code+`", "account_id": "`+accountID+`"}`, harnessAPIKey)
+        var response struct{ DiscordUserID string `json:"discord_user_id"` }
+        json.Unmarshal(rec.Body.Bytes(), &response)
+        return rec.Code, response.DiscordUserID
+    }
+    if status, _ := link("ABCD-2345", "acc-1"); status != http.StatusServiceUnavailable { t.Fatalf("expected linking off without the account service, got %d", status) }
+    h.handler.SetAccountService(accounts)
+
+    code, err := accounts.CreateLinkCode(h.ctx, "u1")
+    if err != nil || len(code) != 9 || code[4] != '-' { t.Fatalf("expected a code like ABCD-2345, got %q, %v", code, err) }
+    if status, _ := link("ZZZZ-ZZZZ", "acc-1"); status != http.StatusNotFound { t.Fatalf("expected an unknown code to be refused, got %d", status) }
+    if status, _ := link(code, ""); status != http.StatusBadRequest { t.Fatalf("expected an account ID to be required, got %d", status) }
+    if status, user := link(strings.ToLower(strings.ReplaceAll(code, "-", "")), "acc-1"); status != http.StatusOK || user != "u1" { t.Fatalf("expected the code to link u1 whatever its case and dash, got %d %q", status, user) }
+    if status, _ := link(code, "acc-1"); status != http.StatusNotFound { t.Fatalf("expected a redeemed code to be refused, got %d", status) }
+    subscription, _ := h.repo.GetSubscription(h.ctx, "u1")
+    if subscription.PushMode() != models.PushFallback || subscription.Account.AccountID != "acc-1" { t.Fatalf("expected acc-1 linked with the DM fallback, got %+v", subscription.Account) }
+
+    // A code expires, and an account moves to the last user who linked it
+    code, _ = accounts.CreateLinkCode(h.ctx, "u2")
+    clock.Advance(services.AccountCodeTTL + time.Second)
+    if status, _ := link(code, "acc-1"); status != http.StatusNotFound { t.Fatalf("expected an expired code to be refused, got %d", status) }
+    code, _ = accounts.CreateLinkCode(h.ctx, "u2")
+    if status, user := link(code, "acc-1"); status != http.StatusOK || user != "u2" { t.Fatalf("expected acc-1 to move to u2, got %d %q", status, user) }
+    subscription, _ = h.repo.GetSubscription(h.ctx, "u1")
+    if subscription.Account.Linked() { t.Fatalf("expected u1 unlinked, got %+v", subscription.Account) }
+
+    unlink := func(accountID string) int {
+        return serveWithKey(h.handler, http.MethodPost, "/discord/link/revoke", `{"account_id": "`+accountID+`"}`, harnessAPIKey).Code
+    }
+    if status := unlink("acc-1"); status != http.StatusOK { t.Fatalf("expected acc-1 unlinked, got %d", status) }
+    if status := unlink("acc-1"); status != http.StatusNotFound { t.Fatalf("expected nothing left to unlink, got %d", status) }
+    if err := accounts.SetPushMode(h.ctx, "u2", models.PushAlways); !errors.Is(err, services.ErrAccountNotLinked) { t.Fatalf("expected push settings to need a linked account, got %v", err) }
+}
+
+func TestPushNotifierFollowsTheUsersMode(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    accounts := services.NewAccountService(repo, logger)
+    backend := newFakePushBackend(t)
+    for _, userID := range []string{"u1", "u2"} {
+        code, _ := accounts.CreateLinkCode(ctx, userID)
+        if _, err := accounts.LinkAccount(ctx, code, "acc-"+userID); err != nil { t.Fatalf("failed to link: %v", err) }
+    }
+    message := "🔔 **Reminder:** Will it rain?\nCloses " + services.DiscordTimestamp(time.Now().Add(time.Hour), services.TimestampRelative) + "\n🔗 [View on Coral Markets](https://coral.markets/market/m1)"
+
+    dms := newRecordingNotifier()
+    notifier := services.NewPushNotifier(repo, backend.server.URL+"/", "push-token", dms, logger)
+    if err := accounts.SetPushMode(ctx, "u1", models.PushAlways); err != nil { t.Fatalf("failed to set push mode: %v", err) }
+    if err := notifier.SendDirectMessage(ctx, "u1", message); err != nil { t.Fatalf("failed to push: %v", err) }
+    if err := notifier.SendDirectMessage(ctx, "u2", message); err != nil { t.Fatalf("failed to DM: %v", err) }
+    if len(dms.directMessages["u1"]) != 0 || len(dms.directMessages["u2"]) != 1 { t.Fatalf("expected u1 pushed and u2 DMed, got %v", dms.directMessages) }
+    pushed := backend.notifications()
+    if len(pushed) != 1 || pushed[0].AccountID != "acc-u1" || pushed[0].Title != "Reminder: Will it rain" || pushed[0].URL != "https://coral.markets/market/m1" || !strings.Contains(pushed[0].Body, "Closes in 1 hour") { t.Fatalf("unexpected push %+v", pushed) }
+
+    // With DMs failing, only users on the fallback get the message
+    notifier = services.NewPushNotifier(repo, backend.server.URL, "push-token", failingNotifier{}, logger)
+    if err := accounts.SetPushMode(ctx, "u1", models.PushOff); err != nil { t.Fatalf("failed to set push mode: %v", err) }
+    if err := notifier.SendDirectMessage(ctx, "u1", message); err == nil { t.Fatalf("expected the failed DM reported with pushes off") }
+    if err := notifier.SendDirectMessage(ctx, "u2", message); err != nil { t.Fatalf("expected the failed DM pushed instead, got %v", err) }
+    if pushed := backend.notifications(); len(pushed) != 2 || pushed[1].AccountID != "acc-u2" { t.Fatalf("expected a push to u2, got %+v", pushed) }
+
+    if err := notifier.Push(ctx, "u3", message); !errors.Is(err, services.ErrAccountNotLinked) { t.Fatalf("expected no push without an account, got %v", err) }
+}
+
+func TestMarketNotificationsArePushedToLinkedAccounts(t *testing.T) {
+    h := newHarness(t)
+    backend := newFakePushBackend(t)
+    accounts := services.NewAccountService(h.repo, utils.NewLogger())
+    h.handler.SetPushNotifier(services.NewPushNotifier(h.repo, backend.server.URL, "push-token", nil, utils.NewLogger()))
+    for _, userID := range []string{"u1", "u2", "u3"} {
+        h.subscriptions.SubscribeToMarket(h.ctx, userID, "m1")
+        code, _ := accounts.CreateLinkCode(h.ctx, userID)
+        accounts.LinkAccount(h.ctx, code, "acc-"+userID)
+        h.discord.closeDMs(userID)
+    }
+    accounts.SetPushMode(h.ctx, "u1", models.PushAlways)
+    accounts.SetPushMode(h.ctx, "u3", models.PushOff)
+
+    h.postEvent("market-update", marketUpdateEvent("m1", 60))
+    pushed := backend.notifications()
+    accountIDs := map[string]bool{}
+    for _, notification := range pushed { accountIDs[notification.AccountID] = true }
+    if len(pushed) != 2 || !accountIDs["acc-u1"] || !accountIDs["acc-u2"] { t.Fatalf("expected pushes to u1, instead of a DM, and u2, whose DM failed, got %+v", pushed) }
+    if h.discord.count() != 0 { t.Fatalf("expected every DM to fail, got %d messages", h.discord.count()) }
+}
+
+func TestAccountCommands(t *testing.T) {
+    ctx := context.Background()
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    markets := services.NewMockMarketService(logger)
+    h := handlers.NewCommandHandler(markets, services.NewSubscriptionService(repo, logger), nil, services.NewAnalyticsService(repo, logger), logger)
+    run := func(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) string {
+        h.HandleInteraction(session, commandInteraction(name, options...))
+        return (*responses)[len(*responses)-1].Data.Content
+    }
+    mode := func(value string) *discordgo.ApplicationCommandInteractionDataOption {
+        return &discordgo.ApplicationCommandInteractionDataOption{Name: "mode", Type: discordgo.ApplicationCommandOptionString, Value: value}
+    }
+
+    if reply := run("link_account"); !strings.Contains(reply, "not enabled") { t.Fatalf("unexpected reply %q", reply) }
+    accounts := services.NewAccountService(repo, logger)
+    h.SetAccountService(accounts)
+    if reply := run("push_notifications", mode("always")); !strings.Contains(reply, "Push notifications are not enabled") { t.Fatalf("expected pushes off without the push bridge, got %q", reply) }
+    h.SetPushEnabled(true)
+    if reply := run("push_notifications", mode("always")); !strings.Contains(reply, "/link_account") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := run("my_positions"); !strings.Contains(reply, "/link_account") { t.Fatalf("expected positions to need a linked account, got %q", reply) }
+
+    reply := run("link_account")
+    start := strings.Index(reply, "`")
+    if start < 0 || (*responses)[len(*responses)-1].Data.Flags&discordgo.MessageFlagsEphemeral == 0 { t.Fatalf("expected a private reply with a code, got %q", reply) }
+    if _, err := accounts.LinkAccount(ctx, reply[start+1:start+10], "acc-1"); err != nil { t.Fatalf("failed to redeem the code from %q: %v", reply, err) }
+    if reply := run("push_notifications", mode("always")); !strings.Contains(reply, "pushed instead of DMed") { t.Fatalf("unexpected reply %q", reply) }
+
+    markets.SetPositions("acc-1", []*models.Position{
+        {MarketID: "m1", MarketTitle: "Will it rain?", Outcome: "Yes", Shares: 10, Cost: 4, Value: 6, Link: "https://coral.markets/market/m1"},
+        {MarketID: "m2", MarketTitle: "Who wins?", Outcome: "Blue", Shares: 20, Cost: 10, Value: 7.5, Link: "https://coral.markets/market/m2"},
+        {MarketID: "m1", MarketTitle: "Will it rain?", Outcome: "No", Shares: 5, Cost: 3, Value: 2},
+    })
+    run("my_positions")
+    response := (*responses)[len(*responses)-1]
+    if response.Data.Flags&discordgo.MessageFlagsEphemeral == 0 || len(response.Data.Embeds) != 1 { t.Fatalf("expected positions in a private embed, got %+v", response.Data) }
+    embed := response.Data.Embeds[0]
+    if embed.Description != "**Total:** stake $17.00 • value $15.50 • P&L -$1.50" { t.Fatalf("unexpected totals %q", embed.Description) }
+    if len(embed.Fields) != 2 || embed.Fields[0].Name != "Will it rain?" || embed.Fields[1].Name != "Who wins?" { t.Fatalf("expected a field per market, the most valuable first, got %+v", embed.Fields) }
+    if !strings.Contains(embed.Fields[0].Value, "10.00 × **Yes** • stake $4.00 • value $6.00 • P&L +$2.00") || !strings.Contains(embed.Fields[0].Value, "P&L -$1.00") { t.Fatalf("expected each outcome's stake, value and P&L, got %q", embed.Fields[0].Value) }
+    markets.SetPositions("acc-1", nil)
+    if reply := run("my_positions"); !strings.Contains(reply, "no open positions") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := run("link_account", &discordgo.ApplicationCommandInteractionDataOption{Name: "action", Type: discordgo.ApplicationCommandOptionString, Value: "unlink"}); !strings.Contains(reply, "unlinked") { t.Fatalf("unexpected reply %q", reply) }
+}
+
+func TestPositionHoldersAreToldHowTheirMarketResolved(t *testing.T) {
+    h := newHarness(t)
+    accounts := services.NewAccountService(h.repo, utils.NewLogger())
+    h.handler.SetAccountService(accounts)
+    for _, userID := range []string{"u1", "u2"} {
+        code, _ := accounts.CreateLinkCode(h.ctx, userID)
+        accounts.LinkAccount(h.ctx, code, "acc-"+userID)
+    }
+    h.markets.SetMarketPositions("m1", []*models.Position{
+        {AccountID: "acc-u1", MarketID: "m1", Outcome: "Yes", Shares: 10, Cost: 4, Value: 10},
+        {AccountID: "acc-u1", MarketID: "m1", Outcome: "No", Shares: 5, Cost: 3, Value: 0},
+        {AccountID: "acc-u2", MarketID: "m1", Outcome: "No", Shares: 8, Cost: 5, Value: 0},
+        {AccountID: "acc-unknown", MarketID: "m1", Outcome: "Yes", Shares: 1, Cost: 1, Value: 2},
+    })
+
+    h.postEvent("market-update", marketUpdateEvent("m1", 60))
+    if h.discord.count() != 0 { t.Fatalf("expected holders only told of resolutions, got %d messages", h.discord.count()) }
+    h.postEvent("market-resolved", map[string]interface{}{"market_id": "m1", "title": "Market m1", "winning_outcome": "Yes", "total_pool": 1000})
+    messages := h.discord.directMessages("u1")
+    if len(messages) != 1 { t.Fatalf("expected one message for u1's two positions, got %+v", messages) }
+    content := messages[0].Content
+    if !strings.Contains(content, "YOUR POSITION RESOLVED") || !strings.Contains(content, "✅ 10.00 × **Yes**") || !strings.Contains(content, "❌ 5.00 × **No**") || !strings.Contains(content, "**Total:** stake $7.00 • payout $10.00 • P&L +$3.00") { t.Fatalf("unexpected message %q", content) }
+    if messages := h.discord.directMessages("u2"); len(messages) != 1 || !strings.Contains(messages[0].Content, "P&L -$5.00") { t.Fatalf("expected u2 told of their loss, got %+v", messages) }
+    if h.discord.count() != 2 { t.Fatalf("expected only linked holders messaged, got %d messages", h.discord.count()) }
+}
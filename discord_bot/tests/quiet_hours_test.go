@@ -0,0 +1,86 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestQuietHoursWindow(t *testing.T) {
+    start, end, err := services.ParseQuietHours("23:00–8:00")
+    if err != nil || start != "23:00" || end != "08:00" { t.Fatalf("expected 23:00-08:00, got %q %q %v", start, end, err) }
+    if _, _, err := services.ParseQuietHours("08:00-08:00"); !errors.Is(err, services.ErrInvalidChannelSettings) { t.Fatalf("expected an empty window to be rejected, got %v", err) }
+    if _, _, err := services.ParseQuietHours("late"); !errors.Is(err, services.ErrInvalidChannelSettings) { t.Fatalf("expected a malformed window to be rejected, got %v", err) }
+
+    overnight := &models.ChannelConfig{QuietHoursStart: "23:00", QuietHoursEnd: "08:00", Timezone: "America/New_York"}
+    if !services.InQuietHours(overnight, time.Date(2024, 1, 15, 5, 0, 0, 0, time.UTC)) { t.Fatalf("expected midnight in New York to be quiet") }
+    if services.InQuietHours(overnight, time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)) { t.Fatalf("expected 09:00 in New York not to be quiet") }
+    daytime := &models.ChannelConfig{QuietHoursStart: "09:00", QuietHoursEnd: "17:00"}
+    if !services.InQuietHours(daytime, time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)) || services.InQuietHours(daytime, time.Date(2024, 1, 15, 17, 0, 0, 0, time.UTC)) { t.Fatalf("expected the window to include its start and exclude its end") }
+
+    settings := &models.ChannelSettings{FrequencyMode: "medium", QuietHoursStart: "22:00"}
+    if err := services.ValidateChannelSettings(settings); err == nil { t.Fatalf("expected a window without an end to be rejected") }
+}
+
+func TestQuietHoursHoldAndFlush(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    notifier := newRecordingNotifier()
+    quietHours := services.NewQuietHoursService(repo, notifier, logger)
+    h.SetQuietHoursService(quietHours)
+    h.SetDeliveryReportService(services.NewDeliveryReportService(repo, logger))
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "test")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c2", GuildID: "g2", FeedEnabled: true}, "test")
+
+    // A disconnected gateway buffers the sends, so they can be counted
+    session, _ := discordgo.New("Bot test")
+    h.SetDiscordSession(session)
+    gateway := services.NewGatewayMonitor(0, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+
+    // Quiet around the clock but for the minute before now, so the events are held and a flush a minute ago posts them
+    now := time.Now().UTC()
+    window := now.Format("15:04") + "-" + now.Add(-time.Minute).Format("15:04")
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/quiet_hours", `{"channel_id": "c1", "hours": "25:00-08:00"}`, "root-key"); rec.Code != http.StatusBadRequest { t.Fatalf("expected 400 for an invalid window, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/quiet_hours", `{"channel_id": "c1", "hours": "`+window+`"}`, "root-key"); rec.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String()) }
+
+    event := func(eventType, id, title string) {
+        payload, _ := json.Marshal(map[string]interface{}{"market_id": id, "title": title, "probability": 0.5, "outcome": "Yes", "volume": 100})
+        if _, err := h.ProcessEventJSON(ctx, eventType, payload); err != nil { t.Fatalf("failed to process %s: %v", eventType, err) }
+    }
+    event(models.EventMarketUpdate, "m1", "Rain")
+    event(models.EventMarketUpdate, "m1", "Rain")
+    event(models.EventMarketResolved, "m1", "Rain tomorrow")
+    event(models.EventNewMarket, "m2", "Snow")
+    if buffered := gateway.Status().Buffered; buffered != 4 { t.Fatalf("expected only c2 to be posted to, got %d sends", buffered) }
+    held, _ := repo.GetHeldNotifications(ctx, "c1")
+    if len(held) != 3 || held[0].Count != 2 || held[1].MarketTitle != "Rain tomorrow" { t.Fatalf("expected the held events counted per market and type, got %+v", held) }
+
+    if sent := quietHours.FlushEnded(ctx, now); sent != 0 { t.Fatalf("expected nothing flushed during quiet hours, got %d", sent) }
+    if sent := quietHours.FlushEnded(ctx, now.Add(-time.Minute)); sent != 1 { t.Fatalf("expected a summary once the window ended, got %d", sent) }
+    summary := notifier.channelMessages["c1"][0]
+    if !strings.Contains(summary, "4 events on 2 markets") || !strings.Contains(summary, "Rain tomorrow") || !strings.Contains(summary, "2 updates, resolved") || !strings.Contains(summary, "new market") { t.Fatalf("unexpected summary %q", summary) }
+    if held, _ := repo.GetHeldNotifications(ctx, "c1"); len(held) != 0 { t.Fatalf("expected the held events to be cleared, got %+v", held) }
+    if sent := quietHours.FlushEnded(ctx, now.Add(-time.Minute)); sent != 0 { t.Fatalf("expected a single summary, got %d", sent) }
+
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/quiet_hours", `{"channel_id": "c1", "hours": "off"}`, "root-key"); rec.Code != http.StatusOK { t.Fatalf("expected 200, got %d", rec.Code) }
+    event(models.EventMarketUpdate, "m3", "Hail")
+    if buffered := gateway.Status().Buffered; buffered != 6 { t.Fatalf("expected both channels to be posted to without quiet hours, got %d sends", buffered) }
+}
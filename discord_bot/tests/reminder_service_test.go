@@ -0,0 +1,108 @@
+package tests
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+)
+
+type recordingNotifier struct {
+    channelMessages map[string][]string
+    directMessages  map[string][]string
+}
+
+func newRecordingNotifier() *recordingNotifier {
+    return &recordingNotifier{channelMessages: map[string][]string{}, directMessages: map[string][]string{}}
+}
+
+func (n *recordingNotifier) SendChannelMessage(ctx context.Context, channelID string, message string) error {
+    n.channelMessages[channelID] = append(n.channelMessages[channelID], message)
+    return nil
+}
+
+func (n *recordingNotifier) SendDirectMessage(ctx context.Context, discordUserID string, message string) error {
+    n.directMessages[discordUserID] = append(n.directMessages[discordUserID], message)
+    return nil
+}
+
+func TestRemindersFireOnceWhenDue(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    marketService := services.NewMockMarketService(logger)
+    notifier := newRecordingNotifier()
+    reminderService := services.NewReminderService(repo, marketService, notifier, logger)
+
+    userReminder, err := reminderService.CreateUserReminder(ctx, "u1", "m1", time.Hour)
+    if err != nil { t.Fatalf("failed to create user reminder: %v", err) }
+    if _, err := reminderService.CreateChannelReminder(ctx, "ch1", "m1", 2*time.Hour); err != nil {
+        t.Fatalf("failed to create channel reminder: %v", err)
+    }
+    if _, err := reminderService.CreateUserReminder(ctx, "u1", "m1", 48*time.Hour); err == nil {
+        t.Fatalf("expected error for reminder that would fire in the past")
+    }
+
+    if sent := reminderService.ProcessDueReminders(ctx, time.Now()); sent != 0 {
+        t.Fatalf("expected no reminders due yet, sent %d", sent)
+    }
+
+    if sent := reminderService.ProcessDueReminders(ctx, userReminder.RemindAt); sent != 2 {
+        t.Fatalf("expected 2 reminders sent, got %d", sent)
+    }
+    if len(notifier.directMessages["u1"]) != 1 || len(notifier.channelMessages["ch1"]) != 1 {
+        t.Fatalf("unexpected deliveries: dms=%v channels=%v", notifier.directMessages, notifier.channelMessages)
+    }
+
+    if sent := reminderService.ProcessDueReminders(ctx, userReminder.RemindAt.Add(time.Hour)); sent != 0 {
+        t.Fatalf("expected reminders to fire only once, sent %d", sent)
+    }
+}
+
+func TestSnoozedRemindersWaitForTheSnoozeToEnd(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+    clock := services.NewFakeClock(now)
+    markets := services.NewMockMarketService(logger)
+    markets.SetMarket(&models.Market{ID: "m1", Title: "Market m1", Status: "active", EndTime: now.Add(3 * time.Hour)})
+    subscriptions := services.NewSubscriptionService(repo, logger)
+    subscriptions.SetClock(clock)
+    notifier := newRecordingNotifier()
+    reminders := services.NewReminderService(repo, markets, notifier, logger)
+    reminders.SetClock(clock)
+    scheduler := services.NewSchedulerService(repo, logger)
+    scheduler.SetClock(clock)
+    scheduler.SetJitter(0)
+    reminders.SetScheduler(scheduler)
+
+    // u1's snooze ends before the market closes, u2's does not
+    for _, user := range []string{"u1", "u2"} {
+        if _, err := reminders.CreateUserReminder(ctx, user, "m1", 2*time.Hour); err != nil { t.Fatalf("failed to create reminder: %v", err) }
+    }
+    subscriptions.SnoozeNotifications(ctx, "u1", 90*time.Minute)
+    subscriptions.SnoozeNotifications(ctx, "u2", 4*time.Hour)
+
+    clock.Advance(time.Hour)
+    if ran := scheduler.ProcessDueJobs(ctx, clock.Now()); ran != 2 || len(notifier.directMessages) != 0 { t.Fatalf("expected both reminders held back, ran %d: %v", ran, notifier.directMessages) }
+    if jobs, _ := repo.GetScheduledJobs(ctx); len(jobs) != 1 || !jobs[0].RunAt.Equal(now.Add(90*time.Minute)) { t.Fatalf("expected only u1's reminder rescheduled for the end of the snooze, got %+v", jobs) }
+    if pending, _ := repo.GetRemindersByUser(ctx, "u2"); len(pending) != 0 { t.Fatalf("expected u2's reminder dropped, got %+v", pending) }
+
+    clock.Advance(30 * time.Minute)
+    if ran := scheduler.ProcessDueJobs(ctx, clock.Now()); ran != 1 || len(notifier.directMessages["u1"]) != 1 { t.Fatalf("expected u1 reminded once the snooze ended, ran %d: %v", ran, notifier.directMessages) }
+    if jobs, _ := repo.GetScheduledJobs(ctx); len(jobs) != 0 { t.Fatalf("expected no jobs left, got %+v", jobs) }
+
+    // Without a scheduler the reminder is moved in the queue instead
+    unscheduled := services.NewReminderService(repo, markets, notifier, logger)
+    unscheduled.SetClock(clock)
+    reminder, _ := unscheduled.CreateUserReminder(ctx, "u3", "m1", time.Hour)
+    subscriptions.SnoozeNotifications(ctx, "u3", 45*time.Minute)
+    clock.Advance(30 * time.Minute)
+    if sent := unscheduled.ProcessDueReminders(ctx, reminder.RemindAt); sent != 0 || len(notifier.directMessages["u3"]) != 0 { t.Fatalf("expected the snoozed reminder held back, sent %d", sent) }
+    if sent := unscheduled.ProcessDueReminders(ctx, now.Add(2*time.Hour+15*time.Minute)); sent != 1 || len(notifier.directMessages["u3"]) != 1 { t.Fatalf("expected u3 reminded once the snooze ended, sent %d", sent) }
+}
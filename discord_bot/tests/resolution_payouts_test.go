@@ -0,0 +1,57 @@
+package tests
+
+import (
+    "net/http"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestSplitResolutionMessage(t *testing.T) {
+    marketService := services.NewMockMarketService(utils.NewLogger())
+    market := &models.Market{Title: "Who wins?", Volume: 1000, Payouts: []models.Payout{
+        {Outcome: "Alice", FinalPct: 55, PoolShare: 60},
+        {Outcome: "Bob", FinalPct: 40, PoolShare: 40},
+        {Outcome: "Carol", FinalPct: 5},
+    }}
+    message := marketService.CreateMarketResolutionMessage(market)
+    if !strings.Contains(message, "Split between **Alice** and **Bob**") { t.Fatalf("expected both paid outcomes to be named, got %s", message) }
+    if !strings.Contains(message, "Pool share") || !strings.Contains(message, "$600.00") || !strings.Contains(message, "$400.00") { t.Fatalf("expected a payout table, got %s", message) }
+    if !strings.Contains(message, "Carol") || !strings.Contains(message, "$0.00") { t.Fatalf("expected unpaid outcomes in the table, got %s", message) }
+
+    single := marketService.CreateMarketResolutionMessage(&models.Market{Title: "Rain?", ResolvedOutcome: "Yes"})
+    if !strings.Contains(single, "Resolved: **Yes**") || strings.Contains(single, "Pool share") { t.Fatalf("expected a single-outcome resolution without a table, got %s", single) }
+}
+
+func TestSplitResolutionNotifiesEveryPaidOutcome(t *testing.T) {
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), utils.NewLogger())
+    market := &models.Market{ID: "m1", Payouts: []models.Payout{{Outcome: "Alice", PoolShare: 50}, {Outcome: "Bob", PoolShare: 50}, {Outcome: "Carol"}}}
+    for outcome, want := range map[string]bool{"alice": true, "Bob": true, "Carol": false} {
+        subscription := &models.Subscription{SubscribedOutcomes: []models.OutcomeSubscription{{MarketID: "m1", Outcome: outcome}}}
+        if got := subscriptionService.ShouldNotifyOutcomeSubscriber(subscription, market, models.EventMarketResolved, nil); got != want { t.Fatalf("%s: expected %v, got %v", outcome, want, got) }
+    }
+}
+
+func TestResolutionPayoutsAreValidated(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    logger := utils.NewLogger()
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger), logger)
+
+    cases := map[string]int{
+        `[{"outcome": "A", "final_pct": 50, "pool_share": 50}, {"outcome": "B", "final_pct": 50, "pool_share": 50}]`: http.StatusAccepted,
+        `[{"outcome": "A", "pool_share": 33.333}, {"outcome": "B", "pool_share": 33.333}, {"outcome": "C", "pool_share": 33.334}]`: http.StatusAccepted,
+        `[{"outcome": "", "pool_share": 50}]`: http.StatusBadRequest,
+        `[{"outcome": "A", "final_pct": 120, "pool_share": 50}]`: http.StatusBadRequest,
+        `[{"outcome": "A", "pool_share": -5}]`: http.StatusBadRequest,
+        `[{"outcome": "A", "pool_share": 70}, {"outcome": "B", "pool_share": 70}]`: http.StatusBadRequest,
+    }
+    for payouts, status := range cases {
+        rec := serveWithKey(h, http.MethodPost, "/discord/events/market-resolved", `{"market_id": "m1", "title": "Who wins?", "payouts": `+payouts+`}`, "root-key")
+        if rec.Code != status { t.Fatalf("%s: expected %d, got %d: %s", payouts, status, rec.Code, rec.Body.String()) }
+    }
+}
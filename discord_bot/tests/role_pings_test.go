@@ -0,0 +1,80 @@
+package tests
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestChannelAlertsMentionOnlyThePingRole(t *testing.T) {
+    h := newHarness(t)
+    h.feedChannel("c1", "g1", nil)
+    h.feedChannel("c2", "g1", nil)
+    if err := h.subscriptions.SetChannelPing(h.ctx, "c1", "g1", "555", []string{models.EventNewMarket}, "test"); err != nil { t.Fatalf("failed to set the ping role: %v", err) }
+
+    event := newMarketEvent("m1", "Politics", "alice", 1000)
+    event.Title = "Will @everyone show up?"
+    h.postEvent("new-market", event)
+    pinged := h.discord.channelMessages("c1")
+    if len(pinged) != 1 || !strings.HasPrefix(pinged[0].Content, "<@&555> ") { t.Fatalf("expected the announcement to start with the role mention, got %+v", pinged) }
+    if mentions := pinged[0].Mentions; mentions == nil || len(mentions.Parse) != 0 || len(mentions.Roles) != 1 || mentions.Roles[0] != "555" { t.Fatalf("expected only the ping role to be mentionable, got %+v", mentions) }
+    unpinged := h.discord.channelMessages("c2")
+    if len(unpinged) != 1 || strings.Contains(unpinged[0].Content, "<@&") || unpinged[0].Mentions == nil || len(unpinged[0].Mentions.Parse)+len(unpinged[0].Mentions.Roles) != 0 { t.Fatalf("expected a channel without a ping role to mention nobody, got %+v", unpinged) }
+
+    h.postEvent("market-update", marketUpdateEvent("m1", 60))
+    if messages := h.discord.channelMessages("c1"); len(messages) != 2 || strings.Contains(messages[1].Content, "<@&555>") { t.Fatalf("expected no mention for events the channel does not ping for, got %+v", messages) }
+}
+
+func TestChannelPingValidation(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+
+    if events, err := services.ParsePingEvents(" New_Market, market_resolved ,new_market"); err != nil || len(events) != 2 || events[0] != models.EventNewMarket { t.Fatalf("expected two events, got %v, %v", events, err) }
+    if events, _ := services.ParsePingEvents(""); len(events) != len(models.DefaultPingEvents) { t.Fatalf("expected the default events, got %v", events) }
+    if _, err := services.ParsePingEvents("market_comment"); !errors.Is(err, services.ErrInvalidChannelSettings) { t.Fatalf("expected an event not posted in channels to be refused, got %v", err) }
+
+    if err := subscriptionService.SetChannelPing(ctx, "c1", "g1", "g1", models.DefaultPingEvents, "test"); !errors.Is(err, services.ErrInvalidChannelSettings) { t.Fatalf("expected the @everyone role to be refused, got %v", err) }
+    if err := subscriptionService.SetChannelPing(ctx, "c1", "g1", "<@&555>", models.DefaultPingEvents, "test"); !errors.Is(err, services.ErrInvalidChannelSettings) { t.Fatalf("expected a role mention instead of an ID to be refused, got %v", err) }
+    subscriptionService.SetChannelPing(ctx, "c1", "g1", "555", models.DefaultPingEvents, "test")
+    subscriptionService.SetChannelPing(ctx, "c1", "g1", "", nil, "test")
+    if config, _ := subscriptionService.GetChannelConfig(ctx, "c1"); config.PingRoleID != "" || len(config.PingEvents) != 0 { t.Fatalf("expected the ping to be turned off, got %+v", config) }
+
+    settings := &models.ChannelSettings{FrequencyMode: "medium", PingRoleID: "555", PingEvents: []string{"market_comment"}}
+    if err := services.ValidateChannelSettings(settings); !errors.Is(err, services.ErrInvalidChannelSettings) { t.Fatalf("expected imported settings with an unknown ping event to be refused, got %v", err) }
+}
+
+func TestChannelFeedPingCommand(t *testing.T) {
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := handlers.NewCommandHandler(services.NewMockMarketService(logger), subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger)
+
+    ping := func(options ...*discordgo.ApplicationCommandInteractionDataOption) string {
+        interaction := commandInteraction("channel_feed_ping", options...)
+        interaction.GuildID, interaction.ChannelID = "g1", "c1"
+        h.HandleInteraction(session, interaction)
+        return (*responses)[len(*responses)-1].Data.Content
+    }
+    role := func(roleID string) *discordgo.ApplicationCommandInteractionDataOption {
+        return &discordgo.ApplicationCommandInteractionDataOption{Name: "role", Type: discordgo.ApplicationCommandOptionRole, Value: roleID}
+    }
+    events := &discordgo.ApplicationCommandInteractionDataOption{Name: "events", Type: discordgo.ApplicationCommandOptionString, Value: "market_resolved,market_cancelled"}
+
+    if reply := ping(role("555"), events); !strings.Contains(reply, "<@&555>") || !strings.Contains(reply, "market_cancelled") { t.Fatalf("expected the role and events to be confirmed, got %q", reply) }
+    if mentions := (*responses)[0].Data.AllowedMentions; mentions == nil || len(mentions.Parse)+len(mentions.Roles) != 0 { t.Fatalf("expected the reply not to mention the role, got %+v", mentions) }
+    if config, _ := subscriptionService.GetChannelConfig(context.Background(), "c1"); !config.PingsFor(models.EventMarketCancelled) || config.PingsFor(models.EventNewMarket) { t.Fatalf("expected pings for the chosen events only, got %+v", config) }
+    if reply := ping(role("g1")); !strings.Contains(reply, "@everyone") { t.Fatalf("expected @everyone to be refused, got %q", reply) }
+    if reply := ping(role("555"), &discordgo.ApplicationCommandInteractionDataOption{Name: "events", Type: discordgo.ApplicationCommandOptionString, Value: "comments"}); !strings.Contains(reply, "Choose events") { t.Fatalf("expected unknown events to be refused, got %q", reply) }
+    if reply := ping(); !strings.Contains(reply, "no longer") { t.Fatalf("expected the ping to be turned off, got %q", reply) }
+}
@@ -0,0 +1,174 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestRoutingRulesAreOrderedValidatedAndAudited(t *testing.T) {
+    ctx := context.Background()
+    service := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), utils.NewLogger())
+
+    for _, rule := range []*models.RoutingRule{
+        {Action: models.RuleActionSend},
+        {GuildID: "g1", Action: "forward"},
+        {GuildID: "g1", Action: models.RuleActionPing},
+        {GuildID: "g1", Action: models.RuleActionPing, RoleID: "g1"},
+        {GuildID: "g1", ChannelID: "c1", Action: models.RuleActionRoute, TargetChannelID: "c1"},
+        {GuildID: "g1", Action: models.RuleActionSend, EventTypes: []string{"market_comment"}},
+        {GuildID: "g1", Action: models.RuleActionSend, MinVolume: 500, MaxVolume: 100},
+    } {
+        if _, err := service.AddRoutingRule(ctx, rule, "admin"); !errors.Is(err, services.ErrInvalidRoutingRule) { t.Fatalf("expected %+v to be rejected, got %v", rule, err) }
+    }
+
+    suppress, err := service.AddRoutingRule(ctx, &models.RoutingRule{GuildID: "g1", Action: models.RuleActionSuppress, Categories: []string{" Sports", "sports"}, EventTypes: []string{"MARKET_UPDATE"}}, "admin")
+    if err != nil { t.Fatalf("failed to add rule: %v", err) }
+    if suppress.ID == "" || suppress.Position != 1 || len(suppress.Categories) != 1 || suppress.Categories[0] != "sports" || suppress.EventTypes[0] != models.EventMarketUpdate { t.Fatalf("expected a normalized first rule, got %+v", suppress) }
+    send, _ := service.AddRoutingRule(ctx, &models.RoutingRule{GuildID: "g1", Action: models.RuleActionSend}, "admin")
+    ping, _ := service.AddRoutingRule(ctx, &models.RoutingRule{GuildID: "g1", Action: models.RuleActionPing, RoleID: "555", Position: 1}, "admin")
+
+    order := func() string {
+        rules, _ := service.GetRoutingRules(ctx, "g1")
+        var ids []string
+        for i, rule := range rules {
+            if rule.Position != i+1 { t.Fatalf("expected positions from 1, got %+v", rules) }
+            ids = append(ids, rule.ID)
+        }
+        return strings.Join(ids, ",")
+    }
+    if got, want := order(), strings.Join([]string{ping.ID, suppress.ID, send.ID}, ","); got != want { t.Fatalf("expected the ping rule inserted first, got %s want %s", got, want) }
+    if _, err := service.MoveRoutingRule(ctx, "g1", ping.ID, 0, "admin"); err != nil { t.Fatalf("failed to move rule: %v", err) }
+    if got, want := order(), strings.Join([]string{suppress.ID, send.ID, ping.ID}, ","); got != want { t.Fatalf("expected the ping rule moved last, got %s want %s", got, want) }
+    if _, err := service.MoveRoutingRule(ctx, "g1", "missing", 1, "admin"); !errors.Is(err, services.ErrRoutingRuleNotFound) { t.Fatalf("expected an unknown rule not to be found, got %v", err) }
+    if removed, err := service.RemoveRoutingRule(ctx, "g1", suppress.ID, "admin"); err != nil || !removed { t.Fatalf("expected the rule to be removed, got %v, %v", removed, err) }
+    if removed, _ := service.RemoveRoutingRule(ctx, "g2", send.ID, "admin"); removed { t.Fatalf("expected another guild's rule to be left alone") }
+    if got, want := order(), strings.Join([]string{send.ID, ping.ID}, ","); got != want { t.Fatalf("expected the rules renumbered, got %s want %s", got, want) }
+
+    for i := 0; i < services.MaxRoutingRulesPerGuild-2; i++ {
+        service.AddRoutingRule(ctx, &models.RoutingRule{GuildID: "g1", Action: models.RuleActionSend}, "admin")
+    }
+    var limitErr *services.LimitExceededError
+    if _, err := service.AddRoutingRule(ctx, &models.RoutingRule{GuildID: "g1", Action: models.RuleActionSend}, "admin"); !errors.As(err, &limitErr) { t.Fatalf("expected the rule limit, got %v", err) }
+
+    entries, _ := service.GetAuditLog(ctx, models.AuditFilter{ResourceType: models.AuditResourceRoutingRule})
+    actions := map[string]int{}
+    for _, entry := range entries {
+        actions[entry.Action]++
+    }
+    if actions[models.AuditActionCreate] != services.MaxRoutingRulesPerGuild+1 || actions[models.AuditActionUpdate] != 1 || actions[models.AuditActionDelete] != 1 { t.Fatalf("unexpected audit actions %v", actions) }
+
+    // Rules for or routing to a deleted channel go with it, and a removed guild's rules go with the guild
+    route, _ := service.AddRoutingRule(ctx, &models.RoutingRule{GuildID: "g2", Action: models.RuleActionRoute, TargetChannelID: "c9"}, "admin")
+    service.AddRoutingRule(ctx, &models.RoutingRule{GuildID: "g2", ChannelID: "c8", Action: models.RuleActionSuppress}, "admin")
+    service.AddRoutingRule(ctx, &models.RoutingRule{GuildID: "g2", Action: models.RuleActionSend}, "admin")
+    if err := service.RemoveChannel(ctx, "c9", "system"); err != nil { t.Fatalf("failed to remove channel: %v", err) }
+    if err := service.RemoveChannel(ctx, "c8", "system"); err != nil { t.Fatalf("failed to remove channel: %v", err) }
+    if rules, _ := service.GetRoutingRules(ctx, "g2"); len(rules) != 1 || rules[0].ID == route.ID || rules[0].Position != 1 { t.Fatalf("expected one rule left, got %+v", rules) }
+    if err := service.RemoveGuild(ctx, "g2", nil, "system"); err != nil { t.Fatalf("failed to remove guild: %v", err) }
+    if rules, _ := service.GetRoutingRules(ctx, "g2"); len(rules) != 0 { t.Fatalf("expected the guild's rules removed, got %+v", rules) }
+}
+
+func TestRoutingRulesDecideWhereEventsArePosted(t *testing.T) {
+    h := newHarness(t)
+    h.feedChannel("c1", "g1", nil)
+    h.feedChannel("c2", "g1", func(config *models.ChannelConfig) { config.FeedEnabled = false })
+    h.feedChannel("c3", "g1", func(config *models.ChannelConfig) { config.MinVolume = 100000 })
+    h.feedChannel("c4", "g2", nil)
+    add := func(rule *models.RoutingRule) {
+        if _, err := h.subscriptions.AddRoutingRule(h.ctx, rule, "admin"); err != nil { h.t.Fatalf("failed to add rule: %v", err) }
+    }
+    add(&models.RoutingRule{GuildID: "g1", Action: models.RuleActionSuppress, Creators: []string{"spammer"}})
+    add(&models.RoutingRule{GuildID: "g1", ChannelID: "c1", Action: models.RuleActionRoute, TargetChannelID: "c2", Categories: []string{"sports"}})
+    add(&models.RoutingRule{GuildID: "g1", ChannelID: "c3", Action: models.RuleActionPing, RoleID: "777", Keywords: []string{"ELECTION"}, EventTypes: []string{models.EventNewMarket}})
+    add(&models.RoutingRule{GuildID: "g1", Action: models.RuleActionSuppress, MaxVolume: 10})
+
+    // The spam rule suppresses the market in g1 only
+    h.postEvent("new-market", newMarketEvent("m1", "politics", "spammer", 5000))
+    if len(h.discord.channelMessages("c1")) != 0 || len(h.discord.channelMessages("c3")) != 0 || len(h.discord.channelMessages("c4")) != 1 { t.Fatalf("expected the market suppressed in g1 only, got %+v", h.discord.messages) }
+
+    // Sports markets of c1 go to c2, whose feed is off, instead
+    h.postEvent("new-market", newMarketEvent("m2", "Sports", "alice", 5000))
+    if len(h.discord.channelMessages("c1")) != 0 || len(h.discord.channelMessages("c2")) != 1 { t.Fatalf("expected the sports market routed from c1 to c2, got %+v", h.discord.messages) }
+
+    // c3 posts election markets below its minimum volume, mentioning the rule's role
+    election := newMarketEvent("m3", "politics", "alice", 5000)
+    election.Title = "Who wins the election?"
+    h.postEvent("new-market", election)
+    messages := h.discord.channelMessages("c3")
+    if len(messages) != 1 || !strings.HasPrefix(messages[0].Content, "<@&777>") || len(messages[0].Mentions.Roles) != 1 || messages[0].Mentions.Roles[0] != "777" { t.Fatalf("expected the election market in c3 mentioning the role, got %+v", messages) }
+    if messages := h.discord.channelMessages("c1"); len(messages) != 1 || strings.Contains(messages[0].Content, "<@&777>") { t.Fatalf("expected c1 to follow its own settings without the ping, got %+v", messages) }
+
+    // Tiny markets are suppressed everywhere in g1; with no rule matching, c3's minimum volume applies
+    h.postEvent("new-market", newMarketEvent("m4", "politics", "alice", 5))
+    h.postEvent("new-market", newMarketEvent("m5", "politics", "alice", 5000))
+    if len(h.discord.channelMessages("c1")) != 2 || len(h.discord.channelMessages("c3")) != 1 || len(h.discord.channelMessages("c4")) != 5 { t.Fatalf("unexpected deliveries %+v", h.discord.messages) }
+}
+
+func TestRoutingRulesCommand(t *testing.T) {
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    h := handlers.NewCommandHandler(services.NewMockMarketService(logger), services.NewSubscriptionService(repo, logger), nil, services.NewAnalyticsService(repo, logger), logger)
+    run := func(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) string {
+        interaction := commandInteraction("routing_rules", &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options})
+        interaction.GuildID, interaction.ChannelID = "g1", "c1"
+        h.HandleInteraction(session, interaction)
+        return (*responses)[len(*responses)-1].Data.Content
+    }
+    text := func(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+        return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionString, Value: value}
+    }
+    integer := func(name string, value int) *discordgo.ApplicationCommandInteractionDataOption {
+        return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionInteger, Value: float64(value)}
+    }
+
+    if reply := run("list"); !strings.Contains(reply, "no routing rules") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := run("add", text("action", "ping")); !strings.Contains(reply, "Invalid rule") { t.Fatalf("expected a ping rule without a role to be rejected, got %q", reply) }
+    if reply := run("add", text("action", "suppress"), text("categories", "Sports, esports")); !strings.Contains(reply, "1. **suppress** in <#c1>: categories sports, esports") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := run("add", text("action", "send"), text("scope", "server"), &discordgo.ApplicationCommandInteractionDataOption{Name: "min_volume", Type: discordgo.ApplicationCommandOptionNumber, Value: 2500.0}, integer("position", 1)); !strings.Contains(reply, "1. **send** in every channel: volume at least $2500.00") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := run("move", integer("position", 1), integer("new_position", 2)); !strings.Contains(reply, "2. **send**") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := run("list"); !strings.Contains(reply, "1. **suppress**") || !strings.Contains(reply, "2. **send**") { t.Fatalf("unexpected list %q", reply) }
+    if reply := run("remove", integer("position", 3)); !strings.Contains(reply, "no rule 3") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := run("remove", integer("position", 1)); !strings.Contains(reply, "Removed rule 1. **suppress**") { t.Fatalf("unexpected reply %q", reply) }
+    if rules, _ := repo.GetRoutingRules(context.Background(), "g1"); len(rules) != 1 || rules[0].Action != models.RuleActionSend || rules[0].Position != 1 || rules[0].CreatedBy != "u1" { t.Fatalf("unexpected rules %+v", rules) }
+}
+
+func TestRoutingRuleEndpoints(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "test-key")
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+
+    rec := serveWithKey(h, http.MethodPost, "/discord/guild/routing_rules/g1", `{"action": "route", "categories": ["crypto"], "target_channel_id": "c2"}`, "test-key")
+    if rec.Code != http.StatusCreated { t.Fatalf("expected %d got %d: %s", http.StatusCreated, rec.Code, rec.Body.String()) }
+    var route models.RoutingRule
+    json.Unmarshal(rec.Body.Bytes(), &route)
+    if route.ID == "" || route.GuildID != "g1" || route.Position != 1 || route.CreatedBy == "" { t.Fatalf("unexpected rule %+v", route) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/guild/routing_rules/g1", `{"action": "suppress", "position": 1}`, "test-key"); rec.Code != http.StatusCreated { t.Fatalf("expected %d got %d: %s", http.StatusCreated, rec.Code, rec.Body.String()) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/guild/routing_rules/g1", `{"action": "route"}`, "test-key"); rec.Code != http.StatusBadRequest { t.Fatalf("expected a route without a target to be rejected, got %d", rec.Code) }
+
+    rec = serveWithKey(h, http.MethodPost, "/discord/guild/routing_rules/g1/"+route.ID+"/move", `{"position": 1}`, "test-key")
+    if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"position":1`) { t.Fatalf("expected the rule moved first, got %d %s", rec.Code, rec.Body.String()) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/guild/routing_rules/g1/missing/move", `{"position": 1}`, "test-key"); rec.Code != http.StatusNotFound { t.Fatalf("expected %d got %d", http.StatusNotFound, rec.Code) }
+
+    var rules web.RoutingRulesResponse
+    rec = serveWithKey(h, http.MethodGet, "/discord/guild/routing_rules/g1", "", "test-key")
+    json.Unmarshal(rec.Body.Bytes(), &rules)
+    if rec.Code != http.StatusOK || len(rules.Rules) != 2 || rules.Rules[0].ID != route.ID || rules.Rules[1].Action != models.RuleActionSuppress { t.Fatalf("unexpected rules %d %+v", rec.Code, rules) }
+
+    if rec := serveWithKey(h, http.MethodDelete, "/discord/guild/routing_rules/g1/"+route.ID, "", "test-key"); rec.Code != http.StatusNoContent { t.Fatalf("expected %d got %d", http.StatusNoContent, rec.Code) }
+    if rec := serveWithKey(h, http.MethodDelete, "/discord/guild/routing_rules/g1/"+route.ID, "", "test-key"); rec.Code != http.StatusNotFound { t.Fatalf("expected %d got %d", http.StatusNotFound, rec.Code) }
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+)
+
+func TestEscapeMarkdownNeutralizesFormattingAndMentions(t *testing.T) {
+    cases := map[string]string{
+        "@everyone wins":           "@\u200beveryone wins",
+        "<@123> and <@&456>":       `\<@` + "\u200b" + `123\> and \<@` + "\u200b" + `&456\>`,
+        "**bold** _it_ ~~x~~":      `\*\*bold\*\* \_it\_ \~\~x\~\~`,
+        "[click](https://evil.io)": `\[click\](https:` + "\u200b" + `//evil.io)`,
+        "see https://evil.io now":  "see https:\u200b//evil.io now",
+        "# Heading\n> quote":       `\# Heading \> quote`,
+        "spoiler ||x|| `code`":     `spoiler \|\|x\|\| ` + "\\`code\\`",
+    }
+    for input, want := range cases {
+        if got := services.EscapeMarkdown(input); got != want { t.Fatalf("expected %q escaped as %q, got %q", input, want, got) }
+    }
+
+    block := services.EscapeMarkdownBlock("First line\n- item\n1. step\n# Heading")
+    if block != "First line\n\\- item\n1\\. step\n\\# Heading" { t.Fatalf("expected line breaks kept and list markers escaped, got %q", block) }
+
+    if got := services.EscapeCodeBlock("Yes```\n@everyone"); got != "Yes @everyone" { t.Fatalf("expected backticks dropped inside code blocks, got %q", got) }
+}
+
+func TestMessagesEscapeBackendText(t *testing.T) {
+    marketService := services.NewMarketService("", utils.NewLogger())
+    market := &models.Market{
+        ID:          "m1",
+        Title:       "**Pwned** @everyone",
+        Description: "# Big\n[free money](https://evil.io)",
+        Outcomes:    []string{"<@123>", "No"},
+        Percentages: []float64{60, 40},
+        Link:        "https://coral.markets/market/m1",
+    }
+
+    announcement := marketService.CreateMarketAnnouncement(market)
+    for _, want := range []string{`**\*\*Pwned\*\* @` + "\u200b" + `everyone**`, `\# Big`, `\[free money\](https:` + "\u200b" + `//evil.io)`, `- \<@` + "\u200b" + `123\> (60.0%)`, "🔗 [View on Coral Markets](https://coral.markets/market/m1)"} {
+        if !strings.Contains(announcement, want) { t.Fatalf("expected the announcement to contain %q, got %q", want, announcement) }
+    }
+    if strings.Contains(announcement, "@everyone") || strings.Contains(announcement, "<@123>") { t.Fatalf("expected no mention left in the announcement, got %q", announcement) }
+
+    market.ResolvedOutcome = "No"
+    market.Payouts = []models.Payout{{Outcome: "Yes```@here", FinalPct: 10}, {Outcome: "No", FinalPct: 90, PoolShare: 100}}
+    resolution := marketService.CreateMarketResolutionMessage(market)
+    if strings.Count(resolution, "```") != 2 || !strings.Contains(resolution, "Yes@here") { t.Fatalf("expected an outcome unable to close the payout table, got %q", resolution) }
+
+    buy := marketService.CreateMarketBuyMessage("m1", "Title", 50, "Yes", "[me](https://evil.io)", "https://coral.markets/market/m1")
+    if !strings.Contains(buy, `Buyer: \[me\]`) { t.Fatalf("expected the buyer escaped, got %q", buy) }
+
+    comment := marketService.CreateMarketCommentMessage(market, "@here", "nice\n# shouting")
+    if !strings.Contains(comment, "@\u200bhere wrote:\n> nice\n> \\# shouting") { t.Fatalf("expected the comment quoted and escaped, got %q", comment) }
+}
+
+func TestMessagesLinkOnlyToAllowedDomains(t *testing.T) {
+    marketService := services.NewMarketService("", utils.NewLogger())
+    links := map[string]bool{
+        "https://coral.markets/market/m1":        true,
+        "https://app.coral.markets/market/m1":    true,
+        "http://coral.markets/market/m1":         false,
+        "javascript:alert(1)":                    false,
+        "https://coral.markets.evil.io/m1":       false,
+        "https://evilcoral.markets/m1":           false,
+        "https://user@coral.markets/m1":          false,
+        "https://coral.markets/m1) [x](https://": false,
+    }
+    for link, allowed := range links {
+        message := marketService.CreateTradingStartMessage(&models.Market{ID: "m1", Title: "Market", Link: link})
+        if strings.Contains(message, "View on Coral Markets") != allowed { t.Fatalf("expected link %q allowed to be %v, got %q", link, allowed, message) }
+    }
+
+    marketService.SetLinkDomains([]string{"markets.example"})
+    if message := marketService.CreateTradingStartMessage(&models.Market{ID: "m1", Title: "Market", Link: "https://markets.example/m1"}); !strings.Contains(message, "(https://markets.example/m1)") { t.Fatalf("expected a configured domain to be allowed, got %q", message) }
+    if message := marketService.CreateTradingStartMessage(&models.Market{ID: "m1", Title: "Market", Link: "https://coral.markets/m1"}); strings.Contains(message, "View on Coral Markets") { t.Fatalf("expected the configured domains to replace the default, got %q", message) }
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+    "context"
+    "errors"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+)
+
+// flakyNotifier fails the first sends, then records them like a recordingNotifier
+type flakyNotifier struct {
+    *recordingNotifier
+    failures int
+}
+
+func (n *flakyNotifier) SendDirectMessage(ctx context.Context, discordUserID string, message string) error {
+    if n.failures > 0 {
+        n.failures--
+        return errors.New("discord unavailable")
+    }
+    return n.recordingNotifier.SendDirectMessage(ctx, discordUserID, message)
+}
+
+func TestScheduledJobsAreRetriedUntilTheySucceed(t *testing.T) {
+    ctx := context.Background()
+    repo := repository.NewInMemorySubscriptionRepository()
+    now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+    scheduler := services.NewSchedulerService(repo, utils.NewLogger())
+    scheduler.SetClock(services.NewFakeClock(now))
+    scheduler.SetJitter(0)
+    failures := map[string]int{"flaky": 1, "broken": services.JobMaxAttempts}
+    runs := map[string]int{}
+    scheduler.Handle("test", func(ctx context.Context, job *models.ScheduledJob) error {
+        runs[job.Key]++
+        if runs[job.Key] <= failures[job.Key] {
+            return errors.New("failed")
+        }
+        return nil
+    })
+
+    for _, key := range []string{"flaky", "broken", "fine"} {
+        if err := scheduler.Schedule(ctx, "test", key, now.Add(time.Hour)); err != nil { t.Fatalf("failed to schedule: %v", err) }
+    }
+    scheduler.Schedule(ctx, "unhandled", "k", now)
+    if ran := scheduler.ProcessDueJobs(ctx, now); ran != 0 || len(runs) != 0 { t.Fatalf("expected nothing due yet, ran %d", ran) }
+    if ran := scheduler.ProcessDueJobs(ctx, now.Add(time.Hour)); ran != 1 || runs["flaky"] != 1 || runs["fine"] != 1 { t.Fatalf("expected only the fine job to succeed, ran %d: %v", ran, runs) }
+    if job, _ := repo.GetScheduledJob(ctx, "test:flaky"); job == nil || job.Attempts != 1 || job.LastError != "failed" || !job.RunAt.Equal(now.Add(time.Hour+time.Minute)) { t.Fatalf("expected the failed job rescheduled a minute later, got %+v", job) }
+    if job, _ := repo.GetScheduledJob(ctx, "test:fine"); job != nil { t.Fatalf("expected the job deleted once it succeeded, got %+v", job) }
+
+    for at := now.Add(time.Hour); at.Before(now.Add(12 * time.Hour)); at = at.Add(time.Minute) {
+        scheduler.ProcessDueJobs(ctx, at)
+    }
+    if runs["flaky"] != 2 || runs["broken"] != services.JobMaxAttempts { t.Fatalf("expected retries until success or the last attempt, got %v", runs) }
+    if jobs, _ := repo.GetScheduledJobs(ctx); len(jobs) != 1 || jobs[0].Kind != "unhandled" { t.Fatalf("expected only the job without a handler left, got %+v", jobs) }
+}
+
+func TestScheduledJobsSurviveRestarts(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    path := filepath.Join(t.TempDir(), "bot.json")
+    now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+    repo, _ := repository.NewFileSubscriptionRepository(path, logger)
+    markets := services.NewMockMarketService(logger)
+    markets.SetMarket(&models.Market{ID: "m1", Title: "Market m1", Status: "active", EndTime: now.Add(3 * time.Hour)})
+
+    // A reminder created before the scheduler was set gets its job at startup
+    reminders := services.NewReminderService(repo, markets, newRecordingNotifier(), logger)
+    reminders.SetClock(services.NewFakeClock(now))
+    old, _ := reminders.CreateUserReminder(ctx, "u1", "m1", 2*time.Hour)
+    scheduler := services.NewSchedulerService(repo, logger)
+    reminders.SetScheduler(scheduler)
+    if scheduled, err := reminders.ScheduleStoredReminders(ctx); err != nil || scheduled != 1 { t.Fatalf("expected the stored reminder scheduled, got %d, %v", scheduled, err) }
+    if scheduled, _ := reminders.ScheduleStoredReminders(ctx); scheduled != 0 { t.Fatalf("expected reminders scheduled once, got %d", scheduled) }
+    if _, err := reminders.CreateUserReminder(ctx, "u2", "m1", time.Hour); err != nil { t.Fatalf("failed to create reminder: %v", err) }
+    cancelled, _ := reminders.CreateUserReminder(ctx, "u3", "m1", time.Hour)
+    if err := reminders.CancelReminder(ctx, cancelled.ID); err != nil { t.Fatalf("failed to cancel: %v", err) }
+    if job, _ := repo.GetScheduledJob(ctx, models.JobReminder+":"+cancelled.ID); job != nil { t.Fatalf("expected the cancelled reminder's job removed, got %+v", job) }
+    repo.Flush()
+
+    // The bot restarts after both reminders fell due, and the first send fails
+    restarted, err := repository.NewFileSubscriptionRepository(path, logger)
+    if err != nil { t.Fatalf("failed to reload: %v", err) }
+    later := old.RemindAt.Add(time.Hour + time.Minute)
+    notifier := &flakyNotifier{recordingNotifier: newRecordingNotifier(), failures: 1}
+    reminders = services.NewReminderService(restarted, markets, notifier, logger)
+    scheduler = services.NewSchedulerService(restarted, logger)
+    scheduler.SetClock(services.NewFakeClock(later))
+    scheduler.SetJitter(10 * time.Second)
+    reminders.SetScheduler(scheduler)
+    if pending, err := scheduler.Recover(ctx); err != nil || pending != 2 { t.Fatalf("expected both jobs recovered, got %d, %v", pending, err) }
+    jobs, _ := restarted.GetScheduledJobs(ctx)
+    for _, job := range jobs {
+        if job.RunAt.Before(later) || !job.RunAt.Before(later.Add(10*time.Second)) { t.Fatalf("expected overdue jobs spread over the jitter window, got %s", job.RunAt) }
+    }
+
+    if ran := scheduler.ProcessDueJobs(ctx, later.Add(10*time.Second)); ran != 1 { t.Fatalf("expected one reminder sent, ran %d", ran) }
+    if ran := scheduler.ProcessDueJobs(ctx, later.Add(2*time.Minute)); ran != 1 { t.Fatalf("expected the failed reminder retried, ran %d", ran) }
+    if len(notifier.directMessages["u1"]) != 1 || len(notifier.directMessages["u2"]) != 1 || len(notifier.directMessages["u3"]) != 0 { t.Fatalf("unexpected reminders %v", notifier.directMessages) }
+    if pending, _ := restarted.GetRemindersByUser(ctx, "u1"); len(pending) != 0 { t.Fatalf("expected sent reminders deleted, got %+v", pending) }
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/bwmarrin/discordgo"
+
+    "coral-bot/discord_bot/internal/config"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+// staticSecrets is a secrets source whose values the test changes
+type staticSecrets map[string]string
+
+func (s staticSecrets) Name() string { return "static" }
+
+func (s staticSecrets) Secrets(ctx context.Context) (map[string]string, error) { return s, nil }
+
+// roundTripFunc serves a session's requests with a function
+type roundTripFunc func(req *http.Request) *http.Response
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req), nil }
+
+func TestSecretsProviders(t *testing.T) {
+    ctx := context.Background()
+    dir := t.TempDir()
+    os.WriteFile(filepath.Join(dir, "DISCORD_BOT_TOKEN"), []byte("file-token\n"), 0o600)
+    os.WriteFile(filepath.Join(dir, ".hidden"), []byte("x"), 0o600)
+    os.Mkdir(filepath.Join(dir, "..data"), 0o700)
+    files, _ := config.NewSecretsProvider(config.SecretsConfig{Provider: config.SecretsFile, Dir: dir})
+    if secrets, err := files.Secrets(ctx); err != nil || len(secrets) != 1 || secrets["DISCORD_BOT_TOKEN"] != "file-token" { t.Fatalf("unexpected file secrets %v, %v", secrets, err) }
+
+    vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path != "/v1/kv/data/bots/coral" || r.Header.Get("X-Vault-Token") != "vault-token" || r.Header.Get("X-Vault-Namespace") != "team" {
+            w.WriteHeader(http.StatusForbidden)
+            return
+        }
+        json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": map[string]string{"DISCORD_BOT_TOKEN": "vault-token-value"}}})
+    }))
+    defer vault.Close()
+    provider, err := config.NewSecretsProvider(config.SecretsConfig{Provider: config.SecretsVault, VaultAddr: vault.URL, VaultToken: "vault-token", VaultNamespace: "team", VaultPath: "kv/bots/coral"})
+    if err != nil { t.Fatalf("failed to create the vault provider: %v", err) }
+    if secrets, err := provider.Secrets(ctx); err != nil || secrets["DISCORD_BOT_TOKEN"] != "vault-token-value" { t.Fatalf("unexpected vault secrets %v, %v", secrets, err) }
+    denied, _ := config.NewSecretsProvider(config.SecretsConfig{Provider: config.SecretsVault, VaultAddr: vault.URL, VaultToken: "wrong", VaultPath: "kv/bots/coral"})
+    if _, err := denied.Secrets(ctx); err == nil || !strings.Contains(err.Error(), "403") { t.Fatalf("expected the vault error returned, got %v", err) }
+
+    aws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        var body map[string]string
+        json.NewDecoder(r.Body).Decode(&body)
+        auth := r.Header.Get("Authorization")
+        if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" || body["SecretId"] != "prod/coral-bot" || r.Header.Get("X-Amz-Security-Token") != "session" ||
+            !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/secretsmanager/aws4_request") || !strings.Contains(auth, "x-amz-security-token") {
+            w.WriteHeader(http.StatusBadRequest)
+            return
+        }
+        json.NewEncoder(w).Encode(map[string]string{"SecretString": `{"DISCORD_BOT_TOKEN": "aws-token", "CORAL_API_KEY": "aws-key"}`})
+    }))
+    defer aws.Close()
+    provider, err = config.NewSecretsProvider(config.SecretsConfig{Provider: config.SecretsAWS, AWSRegion: "eu-west-1", AWSSecretID: "prod/coral-bot", AWSEndpoint: aws.URL,
+        AWSAccessKeyID: "AKID", AWSSecretAccessKey: "secret", AWSSessionToken: "session"})
+    if err != nil { t.Fatalf("failed to create the aws provider: %v", err) }
+    if secrets, err := provider.Secrets(ctx); err != nil || secrets["DISCORD_BOT_TOKEN"] != "aws-token" || secrets["CORAL_API_KEY"] != "aws-key" { t.Fatalf("unexpected aws secrets %v, %v", secrets, err) }
+
+    invalid := []config.SecretsConfig{
+        {Provider: "keychain"},
+        {Provider: config.SecretsFile},
+        {Provider: config.SecretsVault, VaultAddr: vault.URL},
+        {Provider: config.SecretsVault, VaultAddr: vault.URL, VaultToken: "t", VaultPath: "secret"},
+        {Provider: config.SecretsAWS, AWSRegion: "eu-west-1", AWSSecretID: "s"},
+    }
+    for _, settings := range invalid {
+        if _, err := config.NewSecretsProvider(settings); err == nil { t.Fatalf("expected %+v rejected", settings) }
+    }
+}
+
+func TestLoadConfigReadsSecretsFromProvider(t *testing.T) {
+    dir := t.TempDir()
+    os.WriteFile(filepath.Join(dir, "DISCORD_BOT_TOKEN"), []byte("file-token"), 0o600)
+    os.WriteFile(filepath.Join(dir, "CORAL_API_KEY"), []byte("file-key"), 0o600)
+    t.Setenv("DISCORD_BOT_TOKEN", "env-token")
+    t.Setenv("CORAL_TOKEN", "env-bearer")
+    t.Setenv("SECRETS_PROVIDER", config.SecretsFile)
+    t.Setenv("SECRETS_DIR", dir)
+    t.Setenv("SECRETS_REFRESH_INTERVAL", "1m")
+
+    loaded := config.LoadConfig()
+    if loaded.DiscordBotToken != "file-token" || loaded.CoralAPIKey != "file-key" || loaded.CoralToken != "env-bearer" { t.Fatalf("expected the provider's secrets over the environment's, got %+v", loaded.SecretValues()) }
+    if loaded.SecretsProvider.Name() != "file:"+dir || loaded.SecretsRefresh != time.Minute { t.Fatalf("unexpected provider %s refreshed every %s", loaded.SecretsProvider.Name(), loaded.SecretsRefresh) }
+}
+
+func TestSecretRotation(t *testing.T) {
+    ctx := context.Background()
+    session, _ := discordgo.New("Bot old-token")
+    session.Client = &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+        rec := httptest.NewRecorder()
+        if req.URL.Path == "/api/v"+discordgo.APIVersion+"/users/@me" && req.Header.Get("Authorization") == "Bot new-token" {
+            json.NewEncoder(rec).Encode(discordgo.User{ID: "bot"})
+        } else {
+            rec.WriteHeader(http.StatusUnauthorized)
+            rec.WriteString(`{"message": "401: Unauthorized", "code": 0}`)
+        }
+        return rec.Result()
+    })}
+    session.State.User = &discordgo.User{ID: "bot"}
+
+    h := newHarness(t)
+    h.handler.SetRootCredentials(web.RootCredentials{APIKey: "old-key"})
+    source := staticSecrets{"DISCORD_BOT_TOKEN": "old-token", "CORAL_API_KEY": "old-key", "SMTP_PASSWORD": "old"}
+    rotator := services.NewSecretRotator(source, map[string]string{"DISCORD_BOT_TOKEN": "old-token", "CORAL_API_KEY": "old-key", "SMTP_PASSWORD": "old"}, utils.NewLogger())
+    rotator.Handle("DISCORD_BOT_TOKEN", func(ctx context.Context, token string) error { return services.RotateDiscordToken(ctx, session, token) })
+    rotator.Handle("CORAL_API_KEY", func(ctx context.Context, key string) error { h.handler.SetRootCredentials(web.RootCredentials{APIKey: key}); return nil })
+    if rotated, err := rotator.Check(ctx); rotated != 0 || err != nil { t.Fatalf("expected nothing rotated, got %d, %v", rotated, err) }
+
+    // A token Discord rejects is kept out, and retried on the next check
+    source["DISCORD_BOT_TOKEN"], source["CORAL_API_KEY"], source["SMTP_PASSWORD"] = "bad-token", "new-key", "new"
+    if rotated, err := rotator.Check(ctx); rotated != 1 || err == nil || session.Token != "Bot old-token" { t.Fatalf("expected only the API key rotated, got %d, %v with %s", rotated, err, session.Token) }
+    if rec := serveWithKey(h.handler, http.MethodGet, "/discord/admin/subscriptions", "", "old-key"); rec.Code != http.StatusUnauthorized { t.Fatalf("expected the old API key refused, got %d", rec.Code) }
+    if rec := serveWithKey(h.handler, http.MethodGet, "/discord/admin/subscriptions", "", "new-key"); rec.Code != http.StatusOK { t.Fatalf("expected the rotated API key accepted, got %d", rec.Code) }
+
+    source["DISCORD_BOT_TOKEN"] = "new-token"
+    if rotated, err := rotator.Check(ctx); rotated != 1 || err != nil { t.Fatalf("expected the Discord token rotated, got %d, %v", rotated, err) }
+    if session.Token != "Bot new-token" || session.Identify.Token != "Bot new-token" { t.Fatalf("expected REST and gateway to use the new token, got %q and %q", session.Token, session.Identify.Token) }
+}
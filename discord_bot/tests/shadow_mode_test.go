@@ -0,0 +1,111 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// postShadowedEvent posts a new market event through the harness's handler and returns its delivery report
+func postShadowedEvent(h *harness, body string) *models.DeliveryReport {
+    rec := serveWithKey(h.handler, http.MethodPost, "/discord/events/new-market", body, harnessAPIKey)
+    if rec.Code != http.StatusAccepted { h.t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String()) }
+    var accepted web.AcceptedResponse
+    json.Unmarshal(rec.Body.Bytes(), &accepted)
+    report, err := services.NewDeliveryReportService(h.repo, utils.NewLogger()).Get(h.ctx, accepted.EventID)
+    if err != nil { h.t.Fatalf("failed to get delivery report: %v", err) }
+    return report
+}
+
+func TestChannelShadowModeLogsInsteadOfPosting(t *testing.T) {
+    h := newHarness(t)
+    h.handler.SetDeliveryReportService(services.NewDeliveryReportService(h.repo, utils.NewLogger()))
+    h.feedChannel("c1", "g1", nil)
+    h.feedChannel("c2", "g1", func(config *models.ChannelConfig) { config.PingRoleID, config.PingEvents = "777", []string{models.EventNewMarket} })
+    h.feedChannel("c3", "g1", func(config *models.ChannelConfig) { config.MinVolume = 100000 })
+    if err := h.subscriptions.SetChannelShadowMode(h.ctx, "c2", "g1", true, "admin"); err != nil { t.Fatalf("failed to turn shadow mode on: %v", err) }
+    if err := h.subscriptions.SetChannelShadowMode(h.ctx, "c3", "g1", true, "admin"); err != nil { t.Fatalf("failed to turn shadow mode on: %v", err) }
+    h.subscriptions.SubscribeToMarket(h.ctx, "u1", "m1")
+
+    report := postShadowedEvent(h, `{"market_id": "m1", "title": "Shadowed market", "volume": 5000}`)
+    if len(h.discord.channelMessages("c1")) != 1 || len(h.discord.channelMessages("c2")) != 0 || len(h.discord.channelMessages("c3")) != 0 { t.Fatalf("expected only c1 to get the market, got %+v", h.discord.messages) }
+    if report.Shadowed != 1 || report.Skipped != 1 || report.Stats.Channels != 1 { t.Fatalf("unexpected report %+v", report) }
+    for _, receipt := range report.Receipts {
+        if receipt.RecipientID == "c2" && (receipt.Status != models.DeliveryShadowed || !strings.HasPrefix(receipt.Reason, "<@&777>") || !strings.Contains(receipt.Reason, "Shadowed market")) { t.Fatalf("expected c2's receipt to show the message it would have got, got %+v", receipt) }
+        if receipt.RecipientID == "c3" && receipt.Status != models.DeliverySkipped { t.Fatalf("expected c3's settings to still filter the market, got %+v", receipt) }
+        if receipt.RecipientID == "u1" && receipt.Status != models.DeliverySent { t.Fatalf("expected the subscriber to be DMed, got %+v", receipt) }
+    }
+
+    if err := h.subscriptions.SetChannelShadowMode(h.ctx, "c2", "g1", false, "admin"); err != nil { t.Fatalf("failed to turn shadow mode off: %v", err) }
+    postShadowedEvent(h, `{"market_id": "m2", "title": "Live market", "volume": 5000}`)
+    if len(h.discord.channelMessages("c2")) != 1 { t.Fatalf("expected c2 to post again, got %+v", h.discord.messages) }
+}
+
+func TestGlobalShadowModeSendsNothing(t *testing.T) {
+    h := newHarness(t)
+    h.handler.SetDeliveryReportService(services.NewDeliveryReportService(h.repo, utils.NewLogger()))
+    h.handler.SetShadowMode(true)
+    h.feedChannel("c1", "g1", nil)
+    h.subscriptions.SubscribeToMarket(h.ctx, "u1", "m1")
+
+    report := postShadowedEvent(h, `{"market_id": "m1", "title": "Staging market", "volume": 5000}`)
+    if len(h.discord.messages) != 0 { t.Fatalf("expected nothing sent, got %+v", h.discord.messages) }
+    if report.Shadowed != 2 || report.Stats.Channels != 0 || report.Stats.Users != 0 { t.Fatalf("expected the channel and the subscriber shadowed, got %+v", report) }
+
+    rec := serveWithKey(h.handler, http.MethodPost, "/discord/notifications/dm", `{"discord_user_id": "u1", "type": "market_resolved", "payload": {"market_id": "m1", "title": "Staging market", "winning_outcome": "Yes"}}`, harnessAPIKey)
+    if rec.Code != http.StatusAccepted || len(h.discord.messages) != 0 { t.Fatalf("expected the DM accepted but not sent, got %d and %+v", rec.Code, h.discord.messages) }
+}
+
+func TestShadowNotifier(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    services.NewSubscriptionService(repo, logger).SetChannelShadowMode(ctx, "c2", "g1", true, "admin")
+    next := newRecordingNotifier()
+
+    notifier := services.NewShadowNotifier(repo, next, false, logger)
+    notifier.SendChannelMessage(ctx, "c1", "posted")
+    notifier.SendChannelMessage(ctx, "c2", "withheld")
+    notifier.SendDirectMessage(ctx, "u1", "dm")
+    if len(next.channelMessages["c1"]) != 1 || len(next.channelMessages["c2"]) != 0 || len(next.directMessages["u1"]) != 1 { t.Fatalf("expected only c2 withheld, got %v and %v", next.channelMessages, next.directMessages) }
+
+    global := services.NewShadowNotifier(repo, next, true, logger)
+    global.SendChannelMessage(ctx, "c1", "withheld")
+    global.SendDirectMessage(ctx, "u1", "withheld")
+    if len(next.channelMessages["c1"]) != 1 || len(next.directMessages["u1"]) != 1 { t.Fatalf("expected everything withheld, got %v and %v", next.channelMessages, next.directMessages) }
+
+    if preview := services.ShadowPreview("line one\nline two"); preview != "line one line two" { t.Fatalf("expected the preview on one line, got %q", preview) }
+    if preview := []rune(services.ShadowPreview(strings.Repeat("é", 500))); len(preview) != 200 || preview[199] != '…' { t.Fatalf("expected the preview cut to 200 characters, got %d", len(preview)) }
+}
+
+func TestChannelShadowModeCommandAndEndpoint(t *testing.T) {
+    session, responses := captureInteractionResponses(t)
+    t.Setenv("CORAL_API_KEY", "test-key")
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    commands := handlers.NewCommandHandler(services.NewMockMarketService(logger), subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger)
+
+    interaction := commandInteraction("channel_shadow_mode", &discordgo.ApplicationCommandInteractionDataOption{Name: "setting", Type: discordgo.ApplicationCommandOptionString, Value: "on"})
+    interaction.GuildID, interaction.ChannelID = "g1", "c1"
+    commands.HandleInteraction(session, interaction)
+    if reply := (*responses)[len(*responses)-1].Data.Content; !strings.Contains(reply, "logged") { t.Fatalf("unexpected reply %q", reply) }
+    if config, _ := subscriptionService.GetChannelConfig(ctx, "c1"); !config.ShadowMode || config.GuildID != "g1" { t.Fatalf("expected c1 in shadow mode, got %+v", config) }
+
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/shadow_mode", `{"enabled": true}`, "test-key"); rec.Code != http.StatusBadRequest { t.Fatalf("expected 400 without a channel, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/shadow_mode", `{"channel_id": "c1", "enabled": false}`, "test-key"); rec.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String()) }
+    if config, _ := subscriptionService.GetChannelConfig(ctx, "c1"); config.ShadowMode { t.Fatalf("expected shadow mode off, got %+v", config) }
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestSnoozeSuppressesNotifications(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+
+    // A disconnected gateway buffers the DMs, so they can be counted
+    session, _ := discordgo.New("Bot test")
+    h.SetDiscordSession(session)
+    gateway := services.NewGatewayMonitor(0, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+
+    subscriptionService.SubscribeToMarket(ctx, "u1", "m1")
+    subscriptionService.CreateWatchlist(ctx, "u1", "crypto")
+    subscriptionService.AddToWatchlist(ctx, "u1", "crypto", "m1")
+    subscriptionService.SubscribeToMarket(ctx, "u2", "m1")
+    update := func() {
+        payload, _ := json.Marshal(map[string]interface{}{"market_id": "m1", "title": "Update", "probability": 0.5})
+        if _, err := h.ProcessEventJSON(ctx, models.EventMarketUpdate, payload); err != nil { t.Fatalf("failed to process update: %v", err) }
+    }
+
+    until, err := subscriptionService.SnoozeNotifications(ctx, "u1", 2*time.Hour)
+    if err != nil || until.Before(time.Now().Add(time.Hour)) { t.Fatalf("expected a snooze of two hours, got %v, %v", until, err) }
+    update()
+    if buffered := gateway.Status().Buffered; buffered != 1 { t.Fatalf("expected only u2 to get a DM, got %d", buffered) }
+
+    // A snooze that has run out resumes notifications by itself
+    subscription, _ := repo.GetSubscription(ctx, "u1")
+    subscription.SnoozedUntil = time.Now().Add(-time.Minute)
+    repo.SaveSubscription(ctx, subscription)
+    update()
+    if buffered := gateway.Status().Buffered; buffered != 3 { t.Fatalf("expected both users to get a DM after the snooze, got %d more", buffered - 1) }
+
+    if _, err := subscriptionService.SnoozeNotifications(ctx, "u1", 31*24*time.Hour); !errors.Is(err, services.ErrInvalidSnooze) { t.Fatalf("expected a snooze over 30 days to be rejected, got %v", err) }
+    if resumed, _ := subscriptionService.ResumeNotifications(ctx, "u1"); resumed { t.Fatalf("expected an expired snooze not to count as snoozed") }
+}
+
+func TestSnoozeCommand(t *testing.T) {
+    ctx := context.Background()
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := handlers.NewCommandHandler(services.NewMockMarketService(logger), subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger)
+
+    snooze := func(duration string) string {
+        h.HandleInteraction(session, commandInteraction("snooze", &discordgo.ApplicationCommandInteractionDataOption{Name: "duration", Type: discordgo.ApplicationCommandOptionString, Value: duration}))
+        return (*responses)[len(*responses)-1].Data.Content
+    }
+
+    if reply := snooze("soon"); !strings.Contains(reply, "Invalid duration") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := snooze("45d"); !strings.Contains(reply, "up to 30 days") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := snooze("3d"); !strings.Contains(reply, "snoozed until <t:") { t.Fatalf("unexpected reply %q", reply) }
+    subscription, _ := subscriptionService.GetUserSubscriptions(ctx, "u1")
+    if remaining := time.Until(subscription.SnoozedUntil); remaining < 71*time.Hour || remaining > 72*time.Hour { t.Fatalf("expected a three day snooze, got %s", remaining) }
+    if reply := snooze("off"); !strings.Contains(reply, "back on") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := snooze("OFF"); !strings.Contains(reply, "not snoozed") { t.Fatalf("unexpected reply %q", reply) }
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "path/filepath"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestStorageMigrationToFile(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    subscriptionService.SubscribeToMarket(ctx, "u1", "m1")
+    subscriptionService.SubscribeToCreator(ctx, "u2", "alice")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "test")
+    registration, _ := subscriptionService.RegisterWebhook(ctx, &models.WebhookRegistration{ChannelID: "c1", WebhookURL: "https://discord.com/api/webhooks/1/x"}, "test")
+    subscriptionService.RegisterWebhook(ctx, &models.WebhookRegistration{ChannelID: "c2", WebhookURL: "https://discord.com/api/webhooks/2/y"}, "test")
+    subscriptionService.UnregisterWebhook(ctx, registration.ID, "test")
+
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    h.SetStorageMigrationService(services.NewStorageMigrationService(repo, logger))
+    path := filepath.Join(t.TempDir(), "data.json")
+    body, _ := json.Marshal(web.StorageMigrationRequest{Driver: "file", Path: path})
+
+    rec := serveWithKey(h, http.MethodPost, "/discord/admin/storage/migrate", string(body), "root-key")
+    if rec.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String()) }
+    var migration models.StorageMigration
+    json.Unmarshal(rec.Body.Bytes(), &migration)
+    if migration.Subscriptions != 2 || migration.ChannelConfigs != 1 || migration.WebhookRegistrations != 2 || migration.Path != path { t.Fatalf("unexpected migration %s", rec.Body.String()) }
+
+    migrated, err := repository.NewFileSubscriptionRepository(path, logger)
+    if err != nil { t.Fatalf("failed to open the migrated file: %v", err) }
+    if subscription, _ := migrated.GetSubscription(ctx, "u2"); len(subscription.SubscribedCreators) != 1 { t.Fatalf("expected the subscriptions in the file, got %+v", subscription) }
+    if removed, _ := migrated.GetWebhookRegistration(ctx, registration.ID); removed == nil || removed.DeletedAt == nil { t.Fatalf("expected the unregistered webhook to be kept as unregistered, got %+v", removed) }
+
+    if rec := serveWithKey(h, http.MethodPost, "/discord/admin/storage/migrate", string(body), "root-key"); rec.Code != http.StatusConflict { t.Fatalf("expected 409 for an existing file, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/admin/storage/migrate", `{"driver": "postgres", "path": "x"}`, "root-key"); rec.Code != http.StatusBadRequest { t.Fatalf("expected 400 for an unknown driver, got %d", rec.Code) }
+}
+
+func TestStorageMigrationRejectsMissingRecords(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    services.NewSubscriptionService(repo, logger).SubscribeToMarket(ctx, "u1", "m1")
+
+    // A target that drops subscriptions is caught when the migration reads them back
+    target := &droppingRepository{InMemorySubscriptionRepository: repository.NewInMemorySubscriptionRepository()}
+    if _, err := services.NewStorageMigrationService(repo, logger).Migrate(ctx, target); !errors.Is(err, services.ErrMigrationMismatch) { t.Fatalf("expected a mismatch, got %v", err) }
+}
+
+// droppingRepository loses every subscription saved to it
+type droppingRepository struct {
+    *repository.InMemorySubscriptionRepository
+}
+
+func (repo *droppingRepository) SaveSubscription(ctx context.Context, subscription *models.Subscription) error {
+    return nil
+}
+
+func (repo *droppingRepository) WithTx(ctx context.Context, fn func(tx repository.SubscriptionRepository) error) error {
+    return fn(repo)
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestSubscriptionLimits(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    subscriptionService.SetSubscriptionLimits(models.SubscriptionLimits{MaxMarkets: 2, MaxCreators: 1, MaxOutcomes: 1, MaxChannels: 1})
+
+    subscriptionService.SubscribeToMarket(ctx, "u1", "m1")
+    subscriptionService.SubscribeToMarket(ctx, "u1", "m2")
+    err := subscriptionService.SubscribeToMarket(ctx, "u1", "m3")
+    var limitErr *services.LimitExceededError
+    if !errors.As(err, &limitErr) || limitErr.Limit != 2 || !strings.Contains(err.Error(), "2 markets") { t.Fatalf("expected the market limit to be reached, got %v", err) }
+    if err := subscriptionService.SubscribeToMarket(ctx, "u1", "m1"); err != nil { t.Fatalf("expected a repeated subscription to pass, got %v", err) }
+    if err := subscriptionService.SubscribeToMarket(ctx, "u2", "m3"); err != nil { t.Fatalf("expected the limit to be per user, got %v", err) }
+
+    subscriptionService.SubscribeToCreator(ctx, "u1", "alice")
+    if err := subscriptionService.SubscribeToCreator(ctx, "u1", "bob"); !errors.As(err, &limitErr) { t.Fatalf("expected the creator limit to be reached, got %v", err) }
+    subscriptionService.SubscribeToOutcome(ctx, "u1", "m1", "Yes", 5)
+    if err := subscriptionService.SubscribeToOutcome(ctx, "u1", "m1", "Yes", 10); err != nil { t.Fatalf("expected a threshold change to pass, got %v", err) }
+    if err := subscriptionService.SubscribeToOutcome(ctx, "u1", "m2", "Yes", 5); !errors.As(err, &limitErr) { t.Fatalf("expected the outcome limit to be reached, got %v", err) }
+
+    three, unlimited := 3, 0
+    if _, err := subscriptionService.SetLimitOverride(ctx, &models.LimitOverride{Subject: models.LimitSubjectUser, SubjectID: "u1", MaxMarkets: &three, MaxCreators: &unlimited}, "test"); err != nil { t.Fatalf("failed to override limits: %v", err) }
+    if err := subscriptionService.SubscribeToMarket(ctx, "u1", "m3"); err != nil { t.Fatalf("expected the override to raise the limit, got %v", err) }
+    if err := subscriptionService.SubscribeToMarket(ctx, "u1", "m4"); !errors.As(err, &limitErr) || limitErr.Limit != 3 { t.Fatalf("expected the raised limit to be reached, got %v", err) }
+    if err := subscriptionService.SubscribeToCreator(ctx, "u1", "bob"); err != nil { t.Fatalf("expected a zero override to lift the limit, got %v", err) }
+    if limits, _ := subscriptionService.GetSubscriptionLimits(ctx, models.LimitSubjectUser, "u1"); limits.MaxOutcomes != 1 { t.Fatalf("expected limits left out of the override to keep the default, got %+v", limits) }
+
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1"}, "test")
+    if err := subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c2", GuildID: "g1"}, "test"); !errors.As(err, &limitErr) || limitErr.Kind != "channels" { t.Fatalf("expected the guild's channel limit to be reached, got %v", err) }
+    if err := subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "test"); err != nil { t.Fatalf("expected a configured channel to stay editable, got %v", err) }
+    if err := subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c3", GuildID: "g2"}, "test"); err != nil { t.Fatalf("expected the limit to be per guild, got %v", err) }
+
+    if _, err := subscriptionService.SetLimitOverride(ctx, &models.LimitOverride{Subject: "team", SubjectID: "x"}, "test"); !errors.Is(err, services.ErrInvalidLimitOverride) { t.Fatalf("expected an unknown subject to be rejected, got %v", err) }
+    negative := -1
+    if _, err := subscriptionService.SetLimitOverride(ctx, &models.LimitOverride{Subject: models.LimitSubjectGuild, SubjectID: "g1", MaxChannels: &negative}, "test"); !errors.Is(err, services.ErrInvalidLimitOverride) { t.Fatalf("expected a negative limit to be rejected, got %v", err) }
+}
+
+func TestAdminLimitOverrides(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    subscriptionService.SetSubscriptionLimits(models.SubscriptionLimits{MaxMarkets: 1})
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+
+    serveWithKey(h, http.MethodPost, "/discord/subscribe/market", `{"discord_user_id": "u1", "market_id": "m1"}`, "root-key")
+    rec := serveWithKey(h, http.MethodPost, "/discord/subscribe/market", `{"discord_user_id": "u1", "market_id": "m2"}`, "root-key")
+    if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "1 markets") { t.Fatalf("expected 409 with the limit, got %d: %s", rec.Code, rec.Body.String()) }
+
+    if rec := serveWithKey(h, http.MethodPut, "/discord/admin/limits/team/u1", `{"max_markets": 5}`, "root-key"); rec.Code != http.StatusBadRequest { t.Fatalf("expected 400 for an unknown subject, got %d", rec.Code) }
+    rec = serveWithKey(h, http.MethodPut, "/discord/admin/limits/user/u1", `{"max_markets": 5, "reason": "market maker"}`, "root-key")
+    if rec.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String()) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/subscribe/market", `{"discord_user_id": "u1", "market_id": "m2"}`, "root-key"); rec.Code != http.StatusOK { t.Fatalf("expected the override to allow the subscription, got %d", rec.Code) }
+
+    rec = serveWithKey(h, http.MethodGet, "/discord/admin/limits", "", "root-key")
+    var limits web.LimitsResponse
+    json.Unmarshal(rec.Body.Bytes(), &limits)
+    if limits.Defaults.MaxMarkets != 1 || len(limits.Overrides) != 1 || *limits.Overrides[0].MaxMarkets != 5 || limits.Overrides[0].UpdatedBy != "api" { t.Fatalf("unexpected limits %s", rec.Body.String()) }
+    entries, _ := subscriptionService.GetAuditLog(context.Background(), models.AuditFilter{})
+    if len(entries) != 1 || entries[0].ResourceType != models.AuditResourceLimitOverride || entries[0].ResourceID != "user:u1" { t.Fatalf("expected the override to be audited, got %+v", entries) }
+
+    if rec := serveWithKey(h, http.MethodDelete, "/discord/admin/limits/user/u1", "", "root-key"); rec.Code != http.StatusNoContent { t.Fatalf("expected 204, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodDelete, "/discord/admin/limits/user/u1", "", "root-key"); rec.Code != http.StatusNotFound { t.Fatalf("expected 404 without an override, got %d", rec.Code) }
+}
+
+func TestSubscribeCommandExplainsLimit(t *testing.T) {
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    subscriptionService.SetSubscriptionLimits(models.SubscriptionLimits{MaxMarkets: 1})
+    subscriptionService.SubscribeToMarket(context.Background(), "u1", "m1")
+    h := handlers.NewCommandHandler(services.NewMockMarketService(logger), subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger)
+
+    h.HandleInteraction(session, commandInteraction("subscribe_market", &discordgo.ApplicationCommandInteractionDataOption{Name: "market_id", Type: discordgo.ApplicationCommandOptionString, Value: "m2"}))
+    reply := (*responses)[0].Data
+    if reply.Flags != discordgo.MessageFlagsEphemeral || !strings.Contains(reply.Content, "already subscribed to 1 markets") || errorIDPattern.MatchString(reply.Content) { t.Fatalf("expected the limit to be explained privately without an error ID, got %+v", reply) }
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestLocalizeTimestamps(t *testing.T) {
+    closes := time.Date(2026, time.March, 6, 18, 0, 0, 0, time.UTC)
+    message := "⏰ Closes: " + services.DiscordTimestamp(closes, services.TimestampLongDateTime) + " (" + services.DiscordTimestamp(closes, services.TimestampRelative) + ")"
+    if !strings.Contains(message, "<t:1772820000:F>") { t.Fatalf("expected long date markup, got %q", message) }
+
+    if got := services.LocalizeTimestamps(message, nil); got != message { t.Fatalf("expected markup to be kept without a timezone, got %q", got) }
+
+    tokyo, err := services.LoadTimezone("Asia/Tokyo")
+    if err != nil { t.Fatalf("failed to load timezone: %v", err) }
+    localized := services.LocalizeTimestamps(message, tokyo)
+    if !strings.Contains(localized, "Saturday, March 7, 2026 03:00 JST") { t.Fatalf("expected the close time in Tokyo, got %q", localized) }
+    if !strings.Contains(localized, "<t:1772820000:R>") { t.Fatalf("expected the relative time to stay markup, got %q", localized) }
+}
+
+func TestLoadTimezoneRejectsUnknownZones(t *testing.T) {
+    if loc, err := services.LoadTimezone(""); loc != nil || err != nil { t.Fatalf("expected no timezone for an empty name, got %v %v", loc, err) }
+    for _, name := range []string{"Mars/Olympus", "Local"} {
+        if _, err := services.LoadTimezone(name); !errors.Is(err, services.ErrInvalidTimezone) { t.Fatalf("expected %q to be rejected, got %v", name, err) }
+    }
+}
+
+func TestSetTimezonePersistsPerUserAndChannel(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+
+    if err := subscriptionService.SetUserTimezone(ctx, "u1", "America/New_York"); err != nil { t.Fatalf("failed to set user timezone: %v", err) }
+    if sub, _ := subscriptionService.GetUserSubscriptions(ctx, "u1"); sub.Timezone != "America/New_York" { t.Fatalf("expected the user timezone to be saved, got %q", sub.Timezone) }
+    if err := subscriptionService.SetUserTimezone(ctx, "u1", "Nowhere/Land"); !errors.Is(err, services.ErrInvalidTimezone) { t.Fatalf("expected an invalid timezone error, got %v", err) }
+
+    if err := subscriptionService.SetChannelTimezone(ctx, "c1", "g1", "Europe/Berlin", "u1"); err != nil { t.Fatalf("failed to set channel timezone: %v", err) }
+    cfg, _ := subscriptionService.GetChannelConfig(ctx, "c1")
+    if cfg.Timezone != "Europe/Berlin" || cfg.GuildID != "g1" { t.Fatalf("expected the channel timezone to be saved, got %+v", cfg) }
+    if cfg.Settings().Timezone != "Europe/Berlin" { t.Fatalf("expected the timezone to be exported with the channel settings") }
+
+    if err := subscriptionService.SetChannelTimezone(ctx, "c1", "", "", "u1"); err != nil { t.Fatalf("failed to clear channel timezone: %v", err) }
+    if cfg, _ := subscriptionService.GetChannelConfig(ctx, "c1"); cfg.Timezone != "" { t.Fatalf("expected the channel timezone to be cleared, got %q", cfg.Timezone) }
+}
+
+func TestClosingSoonNoticeUsesChannelTimezone(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    marketService := services.NewMockMarketService(logger)
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    notifier := newRecordingNotifier()
+    closingSoonService := services.NewClosingSoonService(repo, marketService, notifier, logger)
+    now := time.Date(2026, time.March, 6, 12, 0, 0, 0, time.UTC)
+
+    marketService.SetMarket(&models.Market{ID: "soon", Title: "Closing Soon", Status: "active", EndTime: now.Add(6 * time.Hour)})
+    for _, config := range []*models.ChannelConfig{
+        {ChannelID: "tokyo", GuildID: "g1", FeedEnabled: true, ClosingSoonHours: 24, Timezone: "Asia/Tokyo"},
+        {ChannelID: "local", GuildID: "g1", FeedEnabled: true, ClosingSoonHours: 24},
+    } {
+        if err := subscriptionService.UpdateChannelConfig(ctx, config, "u1"); err != nil { t.Fatalf("failed to save config: %v", err) }
+    }
+
+    if sent := closingSoonService.ProcessClosingSoon(ctx, now); sent != 2 { t.Fatalf("expected 2 announcements, got %d", sent) }
+    if message := notifier.channelMessages["tokyo"][0]; !strings.Contains(message, "March 7, 2026 03:00 JST") { t.Fatalf("expected the close time in Tokyo, got %q", message) }
+    if message := notifier.channelMessages["local"][0]; !strings.Contains(message, "<t:1772820000:F>") { t.Fatalf("expected timestamp markup, got %q", message) }
+}
+
+func TestTimezoneEndpoints(t *testing.T) {
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+
+    if rec := serveWithKey(h, http.MethodPost, "/discord/timezone", `{"discord_user_id": "u1", "timezone": "Asia/Kolkata"}`, ""); rec.Code != http.StatusOK {
+        t.Fatalf("expected %d got %d", http.StatusOK, rec.Code)
+    }
+    if sub, _ := subscriptionService.GetUserSubscriptions(context.Background(), "u1"); sub.Timezone != "Asia/Kolkata" { t.Fatalf("expected Asia/Kolkata, got %q", sub.Timezone) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/timezone", `{"channel_id": "c1", "timezone": "Europe/Paris"}`, ""); rec.Code != http.StatusOK {
+        t.Fatalf("expected %d got %d", http.StatusOK, rec.Code)
+    }
+    if cfg, _ := subscriptionService.GetChannelConfig(context.Background(), "c1"); cfg.Timezone != "Europe/Paris" { t.Fatalf("expected Europe/Paris, got %q", cfg.Timezone) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/timezone", `{"channel_id": "c1", "timezone": "Europe/Atlantis"}`, ""); rec.Code != http.StatusBadRequest {
+        t.Fatalf("expected %d got %d", http.StatusBadRequest, rec.Code)
+    }
+}
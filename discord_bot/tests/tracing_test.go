@@ -0,0 +1,56 @@
+package tests
+
+import (
+    "bytes"
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/tracing"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "go.opentelemetry.io/otel"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    "go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWebhookDeliveryContinuesIncomingTrace(t *testing.T) {
+    recorder := tracetest.NewSpanRecorder()
+    provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+    previous := otel.GetTracerProvider()
+    otel.SetTracerProvider(provider)
+    defer otel.SetTracerProvider(previous)
+    if _, err := tracing.Setup(context.Background(), false); err != nil { t.Fatalf("setup failed: %v", err) }
+
+    logger := utils.NewLogger()
+    repo := repository.NewTracedSubscriptionRepository(repository.NewInMemorySubscriptionRepository())
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), services.NewSubscriptionService(repo, logger), logger)
+
+    body := []byte(`{"market_id":"m1","title":"Traced","outcomes":[{"id":"o1","name":"Yes"},{"id":"o2","name":"No"}]}`)
+    req := httptest.NewRequest(http.MethodPost, "/discord/events/new-market", bytes.NewBuffer(body))
+    req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+    rec := httptest.NewRecorder()
+    h.Handler().ServeHTTP(rec, req)
+    if rec.Code != http.StatusAccepted { t.Fatalf("expected 202, got %d", rec.Code) }
+
+    seen := map[string]bool{}
+    for _, span := range recorder.Ended() {
+        if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+            t.Fatalf("span %s is not part of the incoming trace", span.Name())
+        }
+        seen[span.Name()] = true
+    }
+    for _, name := range []string{
+        "POST /discord/events/new-market",
+        "decode.payload",
+        "render.MarketAnnouncement",
+        "dispatch.new_market",
+        "repository.GetMarketSnapshot",
+    } {
+        if !seen[name] { t.Fatalf("expected span %q, got %v", name, seen) }
+    }
+}
@@ -0,0 +1,176 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+// fakeOrderBackend is a backend order API that places each idempotency key once, and fails while
+// failures is above 0
+type fakeOrderBackend struct {
+    mutex    sync.Mutex
+    orders   map[string]models.Order
+    failures int
+    reject   string
+}
+
+func newFakeOrderBackend(t *testing.T) (*fakeOrderBackend, string) {
+    backend := &fakeOrderBackend{orders: make(map[string]models.Order)}
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        backend.mutex.Lock()
+        defer backend.mutex.Unlock()
+        if r.Method != http.MethodPost || r.URL.Path != "/accounts/acc-1/orders" || r.Header.Get("Authorization") != "Bearer trading-token" { w.WriteHeader(http.StatusNotFound); return }
+        if backend.failures > 0 { backend.failures--; w.WriteHeader(http.StatusBadGateway); return }
+        if backend.reject != "" { w.WriteHeader(http.StatusBadRequest); json.NewEncoder(w).Encode(map[string]string{"error": backend.reject}); return }
+        key := r.Header.Get("Idempotency-Key")
+        order, ok := backend.orders[key]
+        if !ok {
+            var request struct {
+                MarketID string  `json:"market_id"`
+                Outcome  string  `json:"outcome"`
+                Amount   float64 `json:"amount"`
+                Source   string  `json:"source"`
+            }
+            json.NewDecoder(r.Body).Decode(&request)
+            order = models.Order{ID: "order-" + key, MarketID: request.MarketID, Outcome: request.Outcome, Amount: request.Amount, Shares: request.Amount / 0.4, Price: 0.4, Status: models.OrderFilled}
+            backend.orders[key] = order
+        }
+        json.NewEncoder(w).Encode(order)
+    }))
+    t.Cleanup(server.Close)
+    return backend, server.URL
+}
+
+func (backend *fakeOrderBackend) count() int {
+    backend.mutex.Lock()
+    defer backend.mutex.Unlock()
+    return len(backend.orders)
+}
+
+func tradableMarket() *models.Market {
+    return &models.Market{ID: "m1", Title: "Will it rain?", Outcomes: []string{"Yes", "No"}, Percentages: []float64{40, 60}, Status: "active", EndTime: time.Now().Add(24 * time.Hour)}
+}
+
+func TestTradingServicePlacesOrdersOnce(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    accounts := services.NewAccountService(repo, logger)
+    backend, baseURL := newFakeOrderBackend(t)
+    clock := services.NewFakeClock(time.Now())
+    trading := services.NewTradingService(accounts, baseURL, "trading-token", logger)
+    trading.SetClock(clock)
+    trading.SetIDGenerator(&services.SequentialIDs{})
+    market := tradableMarket()
+
+    if _, err := trading.PrepareTrade(ctx, "u1", market, "Yes", 20); !errors.Is(err, services.ErrAccountNotLinked) { t.Fatalf("expected a linked account to be needed, got %v", err) }
+    code, _ := accounts.CreateLinkCode(ctx, "u1")
+    if _, err := accounts.LinkAccount(ctx, code, "acc-1"); err != nil { t.Fatalf("failed to link: %v", err) }
+    if _, err := trading.PrepareTrade(ctx, "u1", market, "Yes", 0); !errors.Is(err, services.ErrInvalidChannelSettings) { t.Fatalf("expected an amount of 0 to be refused, got %v", err) }
+    if _, err := trading.PrepareTrade(ctx, "u1", market, "Maybe", 20); !errors.Is(err, services.ErrInvalidChannelSettings) { t.Fatalf("expected an unknown outcome to be refused, got %v", err) }
+
+    trade, err := trading.PrepareTrade(ctx, "u1", market, "yes", 20.004)
+    if err != nil || trade.Outcome != "Yes" || trade.Amount != 20 || trade.Price != 0.4 { t.Fatalf("unexpected trade %+v, %v", trade, err) }
+    if _, err := trading.ConfirmTrade(ctx, "u2", trade.ID); !errors.Is(err, services.ErrTradeExpired) { t.Fatalf("expected another user not to confirm the trade, got %v", err) }
+
+    backend.failures = 1
+    if _, err := trading.ConfirmTrade(ctx, "u1", trade.ID); err == nil || errors.Is(err, services.ErrOrderRejected) { t.Fatalf("expected a retryable failure, got %v", err) }
+    confirmed, err := trading.ConfirmTrade(ctx, "u1", trade.ID)
+    if err != nil || confirmed.Order == nil || confirmed.Order.ID != "order-"+trade.ID || confirmed.Order.Shares != 50 { t.Fatalf("expected the retry to place the order, got %+v, %v", confirmed, err) }
+    if again, err := trading.ConfirmTrade(ctx, "u1", trade.ID); err != nil || again.Order.ID != confirmed.Order.ID || backend.count() != 1 { t.Fatalf("expected confirming twice to place one order, got %+v, %v, %d orders", again, err, backend.count()) }
+    if trading.CancelTrade("u1", trade.ID) { t.Fatalf("expected a placed trade not to be cancelled") }
+
+    backend.reject = "insufficient balance"
+    rejected, _ := trading.PrepareTrade(ctx, "u1", market, "No", 500)
+    if _, err := trading.ConfirmTrade(ctx, "u1", rejected.ID); !errors.Is(err, services.ErrOrderRejected) || !strings.Contains(err.Error(), "insufficient balance") { t.Fatalf("expected the backend's reason, got %v", err) }
+    if _, err := trading.ConfirmTrade(ctx, "u1", rejected.ID); !errors.Is(err, services.ErrTradeExpired) { t.Fatalf("expected a rejected trade to be dropped, got %v", err) }
+
+    backend.reject = ""
+    expired, _ := trading.PrepareTrade(ctx, "u1", market, "No", 5)
+    clock.Advance(services.TradeTTL + time.Second)
+    if _, err := trading.ConfirmTrade(ctx, "u1", expired.ID); !errors.Is(err, services.ErrTradeExpired) || backend.count() != 1 { t.Fatalf("expected an old trade to expire unplaced, got %v", err) }
+    cancelled, _ := trading.PrepareTrade(ctx, "u1", market, "No", 5)
+    if !trading.CancelTrade("u1", cancelled.ID) { t.Fatalf("expected the trade to be cancelled") }
+    if _, err := trading.ConfirmTrade(ctx, "u1", cancelled.ID); !errors.Is(err, services.ErrTradeExpired) { t.Fatalf("expected a cancelled trade to be gone, got %v", err) }
+
+    market.Status = "closed"
+    if services.Tradable(market, time.Now()) || services.TradeButtons(tradableMarket()) == nil { t.Fatalf("expected closed markets not to be tradable") }
+    if marketID, outcome, ok := services.ParseTradeButton("trade|m1|1"); !ok || marketID != "m1" || outcome != 1 { t.Fatalf("unexpected parse %q %d %v", marketID, outcome, ok) }
+}
+
+func TestTradingFromDiscord(t *testing.T) {
+    ctx := context.Background()
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    markets := services.NewMockMarketService(logger)
+    markets.SetMarket(tradableMarket())
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := handlers.NewCommandHandler(markets, subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger)
+    last := func() *discordgo.InteractionResponseData { return (*responses)[len(*responses)-1].Data }
+    click := func(customID string) *discordgo.InteractionResponse {
+        interaction := buttonClick(customID)
+        interaction.GuildID = "g1"
+        h.HandleInteraction(session, interaction)
+        return &(*responses)[len(*responses)-1]
+    }
+    setting := func(value string) *discordgo.InteractionCreate {
+        interaction := commandInteraction("trading_buttons", &discordgo.ApplicationCommandInteractionDataOption{Name: "setting", Type: discordgo.ApplicationCommandOptionString, Value: value})
+        interaction.GuildID = "g1"
+        interaction.Member = &discordgo.Member{User: &discordgo.User{ID: "u1"}}
+        return interaction
+    }
+
+    if reply := click("trade|m1|0").Data; !strings.Contains(reply.Content, "Trading is not enabled on this bot") { t.Fatalf("expected buy buttons to need trading, got %+v", reply) }
+    accounts := services.NewAccountService(repo, logger)
+    h.SetAccountService(accounts)
+    backend, baseURL := newFakeOrderBackend(t)
+    trading := services.NewTradingService(accounts, baseURL, "trading-token", logger)
+    trading.SetIDGenerator(&services.SequentialIDs{})
+    h.SetTradingService(trading)
+
+    if reply := click("trade|m1|0").Data; !strings.Contains(reply.Content, "not enabled in this server") { t.Fatalf("expected buy buttons to need the server to enable them, got %+v", reply) }
+    h.HandleInteraction(session, setting("on"))
+    if reply := last(); !strings.Contains(reply.Content, "Buy buttons are on") { t.Fatalf("unexpected reply %+v", reply) }
+    if config, _ := subscriptionService.GetGuildConfig(ctx, "g1"); config == nil || !config.TradingEnabled { t.Fatalf("expected trading to be enabled for g1, got %+v", config) }
+    if reply := click("trade|m1|0").Data; !strings.Contains(reply.Content, "/link_account") { t.Fatalf("expected buy buttons to need a linked account, got %+v", reply) }
+
+    code, _ := accounts.CreateLinkCode(ctx, "u1")
+    accounts.LinkAccount(ctx, code, "acc-1")
+    if response := click("trade|m1|0"); response.Type != discordgo.InteractionResponseModal || response.Data.CustomID != "trade|m1|0" || response.Data.Title != "Buy Yes" { t.Fatalf("expected an amount modal, got %+v", response) }
+
+    h.HandleInteraction(session, modalSubmission("trade|m1|0", map[string]string{"amount": "lots"}))
+    if reply := last(); !strings.Contains(reply.Content, "number of dollars") { t.Fatalf("expected an invalid amount to be refused, got %+v", reply) }
+    h.HandleInteraction(session, modalSubmission("trade|m1|0", map[string]string{"amount": "$20"}))
+    summary := last()
+    if summary.Flags&discordgo.MessageFlagsEphemeral == 0 || !strings.Contains(summary.Content, "Buy **$20.00** of **Yes** in **Will it rain?** at 40.0%, about 50.00 shares") || len(summary.Components) != 1 { t.Fatalf("expected a private summary to confirm, got %+v", summary) }
+    if backend.count() != 0 { t.Fatalf("expected nothing to be bought before confirming") }
+
+    backend.failures = 1
+    if response := click("trade_confirm|id1"); response.Type != discordgo.InteractionResponseUpdateMessage || !strings.Contains(response.Data.Content, "Confirm again") || len(response.Data.Components) != 1 { t.Fatalf("expected a retry after a failure, got %+v", response) }
+    if response := click("trade_confirm|id1"); !strings.Contains(response.Data.Content, "✅ Bought 50.00 shares of **Yes** for **$20.00** at 40.0% in **Will it rain?**") || len(response.Data.Components) != 0 { t.Fatalf("expected the order to be placed, got %+v", response.Data) }
+    click("trade_confirm|id1")
+    if backend.count() != 1 { t.Fatalf("expected one order, got %d", backend.count()) }
+
+    h.HandleInteraction(session, modalSubmission("trade|m1|1", map[string]string{"amount": "5"}))
+    if response := click("trade_cancel|id2"); !strings.Contains(response.Data.Content, "nothing was bought") { t.Fatalf("expected the trade to be cancelled, got %+v", response.Data) }
+    if response := click("trade_confirm|id2"); !strings.Contains(response.Data.Content, "expired") || backend.count() != 1 { t.Fatalf("expected a cancelled trade not to be placed, got %+v", response.Data) }
+
+    h.HandleInteraction(session, setting("off"))
+    if reply := click("trade|m1|0").Data; !strings.Contains(reply.Content, "not enabled in this server") { t.Fatalf("expected buy buttons to stop when the server turns them off, got %+v", reply) }
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+)
+
+func TestInMemoryTransactionsRunOneAtATime(t *testing.T) {
+    ctx := context.Background()
+    repo := repository.NewInMemorySubscriptionRepository()
+    release := make(chan struct{})
+    started := make(chan struct{})
+    first := make(chan error)
+    second := make(chan error)
+
+    go func() {
+        first <- repo.WithTx(ctx, func(tx repository.SubscriptionRepository) error {
+            close(started)
+            <-release
+            // A nested transaction joins instead of waiting for this one
+            return tx.WithTx(ctx, func(tx repository.SubscriptionRepository) error {
+                return tx.SaveChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1"})
+            })
+        })
+    }()
+    <-started
+    go func() {
+        second <- repo.WithTx(ctx, func(tx repository.SubscriptionRepository) error { return errors.New("second failed") })
+    }()
+
+    select {
+    case err := <-second:
+        t.Fatalf("expected the second transaction to wait for the first, got %v", err)
+    case <-time.After(50 * time.Millisecond):
+    }
+    close(release)
+    if err := <-first; err != nil { t.Fatalf("expected the first transaction to commit, got %v", err) }
+    if err := <-second; err == nil || err.Error() != "second failed" { t.Fatalf("expected the second transaction's error, got %v", err) }
+    if configs, _ := repo.GetAllChannelConfigs(ctx); len(configs) != 1 || configs[0].ChannelID != "c1" { t.Fatalf("expected the nested save to be kept, got %+v", configs) }
+}
+
+func TestRemoveGuildRunsInOneTransaction(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewTracedSubscriptionRepository(repository.NewInMemorySubscriptionRepository())
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", GuildID: "g1", FeedEnabled: true}, "test")
+    subscriptionService.UpdateGuildConfig(ctx, &models.GuildConfig{GuildID: "g1", DefaultChannelID: "c1"})
+    subscriptionService.SetCategoryRoute(ctx, "g1", "Sports", "c1", "test")
+    subscriptionService.RegisterWebhook(ctx, &models.WebhookRegistration{ChannelID: "c1", WebhookURL: "https://discord.com/api/webhooks/1/x"}, "test")
+
+    // Removing a channel within the guild's transaction would deadlock if it did not join it
+    done := make(chan error)
+    go func() { done <- subscriptionService.RemoveGuild(ctx, "g1", []string{"c2"}, "test") }()
+    select {
+    case err := <-done:
+        if err != nil { t.Fatalf("failed to remove guild: %v", err) }
+    case <-time.After(2 * time.Second):
+        t.Fatalf("expected the nested transactions to join the guild's")
+    }
+
+    if configs, _ := subscriptionService.GetAllChannelConfigs(ctx); len(configs) != 0 { t.Fatalf("expected the guild's channels to be removed, got %d", len(configs)) }
+    if routes, _ := subscriptionService.GetCategoryRoutes(ctx, "g1"); len(routes) != 0 { t.Fatalf("expected the guild's routes to be removed, got %+v", routes) }
+    if webhooks, _ := subscriptionService.ListWebhookRegistrationsByChannel(ctx, "c1"); len(webhooks) != 0 { t.Fatalf("expected the channel's webhooks to be unregistered, got %+v", webhooks) }
+}
+
+func TestInMemoryTransactionsRollBackAndIsolate(t *testing.T) {
+    ctx := context.Background()
+    repo := repository.NewInMemorySubscriptionRepository()
+    repo.SaveChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c0", FeedEnabled: true})
+    repo.SaveSubscription(ctx, &models.Subscription{DiscordUserID: "u1", SubscribedMarkets: []string{"m1"}})
+
+    started := make(chan struct{})
+    release := make(chan struct{})
+    result := make(chan error)
+    go func() {
+        result <- repo.WithTx(ctx, func(tx repository.SubscriptionRepository) error {
+            tx.SaveChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1"})
+            config, _ := tx.GetChannelConfig(ctx, "c0")
+            config.FeedEnabled = false
+            tx.SaveChannelConfig(ctx, config)
+            // Changed in place before it is saved, as the services do
+            subscription, _ := tx.GetSubscription(ctx, "u1")
+            subscription.SubscribedMarkets = append(subscription.SubscribedMarkets, "m2")
+            tx.SaveSubscription(ctx, subscription)
+            tx.SaveAnalyticsEvent(ctx, &models.AnalyticsEvent{Kind: models.AnalyticsCommand, Name: "help", Timestamp: time.Now()})
+            close(started)
+            <-release
+            return errors.New("failed after writing")
+        })
+    }()
+    <-started
+
+    // Writes and reads outside the transaction wait for it to end
+    outside := make(chan error)
+    go func() { outside <- repo.SaveChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c2"}) }()
+    select {
+    case err := <-outside:
+        t.Fatalf("expected the write to wait for the transaction, got %v", err)
+    case <-time.After(50 * time.Millisecond):
+    }
+    close(release)
+    if err := <-result; err == nil || err.Error() != "failed after writing" { t.Fatalf("expected the transaction's error, got %v", err) }
+    if err := <-outside; err != nil { t.Fatalf("failed to save outside the transaction: %v", err) }
+
+    configs, _ := repo.GetAllChannelConfigs(ctx)
+    ids := map[string]bool{}
+    for _, config := range configs {
+        ids[config.ChannelID] = true
+    }
+    if len(configs) != 2 || !ids["c0"] || !ids["c2"] { t.Fatalf("expected only c0 and the write made after the transaction, got %+v", configs) }
+    if config, _ := repo.GetChannelConfig(ctx, "c0"); !config.FeedEnabled { t.Fatalf("expected c0's change rolled back, got %+v", config) }
+    if subscription, _ := repo.GetSubscription(ctx, "u1"); len(subscription.SubscribedMarkets) != 1 { t.Fatalf("expected the subscription rolled back, got %+v", subscription) }
+    if events, _ := repo.GetAnalyticsEvents(ctx, time.Time{}, time.Now().Add(time.Hour)); len(events) != 0 { t.Fatalf("expected the analytics event rolled back, got %d", len(events)) }
+
+    // A committed transaction keeps every write, appended slices included
+    repo.WithTx(ctx, func(tx repository.SubscriptionRepository) error {
+        return tx.SaveAnalyticsEvent(ctx, &models.AnalyticsEvent{Kind: models.AnalyticsCommand, Name: "help", Timestamp: time.Now()})
+    })
+    if events, _ := repo.GetAnalyticsEvents(ctx, time.Time{}, time.Now().Add(time.Hour)); len(events) != 1 { t.Fatalf("expected the committed event kept, got %d", len(events)) }
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/services"
+)
+
+func TestUpdateBurstsAreCoalescedIntoTheLatest(t *testing.T) {
+    h := newHarness(t)
+    clock := services.NewFakeClock(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+    h.handler.SetClock(clock)
+    h.handler.SetUpdateCoalescing(time.Minute)
+    h.subscriptions.SubscribeToMarket(h.ctx, "u1", "m1")
+    h.subscriptions.SubscribeToMarket(h.ctx, "u1", "m2")
+
+    h.postEvent("market-update", marketUpdateEvent("m1", 60))
+    h.postEvent("market-update", marketUpdateEvent("m1", 65))
+    h.postEvent("market-update", marketUpdateEvent("m1", 70))
+    h.postEvent("market-update", marketUpdateEvent("m2", 40))
+    if messages := h.discord.directMessages("u1"); len(messages) != 2 { t.Fatalf("expected the first update of each market right away, got %+v", messages) }
+    if sent := h.handler.FlushDueCoalescedUpdates(clock.Now().Add(59 * time.Second)); sent != 0 { t.Fatalf("expected nothing sent within the window, sent %d", sent) }
+
+    clock.Advance(time.Minute)
+    if sent := h.handler.FlushDueCoalescedUpdates(clock.Now()); sent != 1 { t.Fatalf("expected one coalesced update after the window, sent %d", sent) }
+    messages := h.discord.directMessages("u1")
+    if len(messages) != 3 { t.Fatalf("expected one coalesced update after the window, got %+v", messages) }
+    if !strings.Contains(messages[2].Content, "70") || !strings.Contains(messages[2].Content, "1 earlier update") { t.Fatalf("expected the latest update noting the one it replaced, got %q", messages[2].Content) }
+
+    // Nothing was held back in the second window, so the next update goes out at once
+    clock.Advance(time.Minute)
+    if sent := h.handler.FlushDueCoalescedUpdates(clock.Now()); sent != 0 { t.Fatalf("expected nothing held in the second window, sent %d", sent) }
+    h.postEvent("market-update", marketUpdateEvent("m1", 75))
+    if messages := h.discord.directMessages("u1"); len(messages) != 4 || strings.Contains(messages[3].Content, "earlier") { t.Fatalf("expected the update after a quiet window right away, got %+v", messages) }
+
+    // An update after a window that was not flushed yet is sent at once, replacing the held one
+    h.postEvent("market-update", marketUpdateEvent("m1", 80))
+    clock.Advance(time.Minute)
+    h.postEvent("market-update", marketUpdateEvent("m1", 85))
+    if messages := h.discord.directMessages("u1"); len(messages) != 5 || !strings.Contains(messages[4].Content, "85") || !strings.Contains(messages[4].Content, "1 earlier update") { t.Fatalf("expected the latest update replacing the held one, got %+v", messages) }
+}
+
+func TestFlushSendsHeldUpdates(t *testing.T) {
+    h := newHarness(t)
+    h.handler.SetUpdateCoalescing(time.Hour)
+    h.subscriptions.SubscribeToMarket(h.ctx, "u1", "m1")
+
+    for _, yes := range []float64{60, 61, 62, 63} {
+        h.postEvent("market-update", marketUpdateEvent("m1", yes))
+    }
+    h.handler.FlushCoalescedUpdates()
+    messages := h.discord.directMessages("u1")
+    if len(messages) != 2 || !strings.Contains(messages[1].Content, "2 earlier updates") { t.Fatalf("expected the flush to send the latest update, got %+v", messages) }
+}
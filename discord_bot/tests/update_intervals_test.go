@@ -0,0 +1,108 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestChannelUpdatesFollowConfiguredIntervals(t *testing.T) {
+    clock := services.NewFakeClock(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+    marketService := services.NewMarketService("", utils.NewLogger())
+    marketService.SetClock(clock)
+    marketService.SetUpdateIntervals(models.UpdateIntervals{High: 10 * time.Minute, ClosingSoonWindow: time.Hour})
+    market := &models.Market{ID: "m1", Status: "active", EndTime: clock.Now().Add(48 * time.Hour)}
+    config := &models.ChannelConfig{ChannelID: "c1", FrequencyMode: "high", LastUpdateTimestamp: clock.Now().Add(-15 * time.Minute)}
+
+    if !marketService.ShouldSendChannelUpdate(market, config, nil) { t.Fatalf("expected the bot's 10 minute high interval to apply") }
+    if config.FrequencyMode = "medium"; marketService.ShouldSendChannelUpdate(market, config, nil) { t.Fatalf("expected the default medium interval to be kept") }
+
+    guild := &models.GuildConfig{GuildID: "g1", UpdateIntervals: &models.UpdateIntervals{Medium: 15 * time.Minute}}
+    if !marketService.ShouldSendChannelUpdate(market, config, guild) { t.Fatalf("expected the guild's medium interval to apply") }
+    if intervals := marketService.UpdateIntervals(guild); intervals.High != 10*time.Minute || intervals.Low != 3*time.Hour || intervals.ClosingSoonWindow != time.Hour { t.Fatalf("expected the guild's intervals over the bot's over the defaults, got %+v", intervals) }
+
+    config.FrequencyMode, config.CustomInterval = models.FrequencyCustom, 20*time.Minute
+    if marketService.ShouldSendChannelUpdate(market, config, guild) { t.Fatalf("expected the custom interval to win over the frequencies") }
+    clock.Advance(5 * time.Minute)
+    if !marketService.ShouldSendChannelUpdate(market, config, guild) { t.Fatalf("expected an update once the custom interval passed") }
+
+    // Within the bot's one-hour closing window the default 15 minute boost applies, but never slows a channel down
+    market.EndTime = clock.Now().Add(30 * time.Minute)
+    config.CustomInterval, config.LastUpdateTimestamp = 2*time.Hour, clock.Now().Add(-15*time.Minute)
+    if !marketService.ShouldSendChannelUpdate(market, config, nil) { t.Fatalf("expected a closing market to update every 15 minutes") }
+    config.CustomInterval, config.LastUpdateTimestamp = 5*time.Minute, clock.Now().Add(-6*time.Minute)
+    if !marketService.ShouldSendChannelUpdate(market, config, nil) { t.Fatalf("expected a shorter custom interval to be kept near closing") }
+}
+
+func TestChannelFeedFrequencyCustom(t *testing.T) {
+    ctx := context.Background()
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := handlers.NewCommandHandler(services.NewMockMarketService(logger), subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger)
+
+    frequency := func(options ...string) string {
+        var data []*discordgo.ApplicationCommandInteractionDataOption
+        for i, name := range []string{"level", "interval"}[:len(options)] {
+            data = append(data, &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionString, Value: options[i]})
+        }
+        interaction := commandInteraction("channel_feed_frequency", data...)
+        interaction.GuildID, interaction.ChannelID = "g1", "c1"
+        h.HandleInteraction(session, interaction)
+        return (*responses)[len(*responses)-1].Data.Content
+    }
+
+    if content := frequency("custom", "45m"); !strings.Contains(content, "custom (every 45m)") { t.Fatalf("expected the custom interval to be confirmed, got %q", content) }
+    config, _ := subscriptionService.GetChannelConfig(ctx, "c1")
+    if config.FrequencyMode != models.FrequencyCustom || config.CustomInterval != 45*time.Minute { t.Fatalf("expected a 45 minute custom frequency, got %+v", config) }
+
+    for _, interval := range []string{"", "1m", "soon"} {
+        if content := frequency("custom", interval); !strings.Contains(content, "needs an interval between 5m and 168h") { t.Fatalf("expected interval %q to be refused, got %q", interval, content) }
+    }
+    if content := frequency("low"); !strings.Contains(content, "set to: low") { t.Fatalf("expected the low frequency, got %q", content) }
+    if config, _ := subscriptionService.GetChannelConfig(ctx, "c1"); config.CustomInterval != 0 { t.Fatalf("expected a preset to clear the custom interval, got %v", config.CustomInterval) }
+
+    settings := &models.ChannelSettings{FrequencyMode: models.FrequencyCustom}
+    if err := services.ValidateChannelSettings(settings); err == nil { t.Fatalf("expected imported custom settings without an interval to be rejected") }
+}
+
+func TestGuildUpdateIntervalsEndpoints(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "test-key")
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    subscriptionService.UpdateGuildConfig(ctx, &models.GuildConfig{GuildID: "g1", DefaultChannelID: "c1"})
+
+    rec := serveWithKey(h, http.MethodPut, "/discord/guild/update_intervals/g1", `{"high": "10m", "closing_soon_interval": "5m"}`, "test-key")
+    if rec.Code != http.StatusOK { t.Fatalf("expected %d got %d: %s", http.StatusOK, rec.Code, rec.Body.String()) }
+    var intervals web.UpdateIntervalsResponse
+    json.Unmarshal(rec.Body.Bytes(), &intervals)
+    if !intervals.Overridden || intervals.High != "10m" || intervals.Medium != "1h" || intervals.ClosingSoonInterval != "5m" { t.Fatalf("expected the guild's intervals over the defaults, got %+v", intervals) }
+    if guild, _ := subscriptionService.GetGuildConfig(ctx, "g1"); guild.DefaultChannelID != "c1" { t.Fatalf("expected the guild's default channel to be kept, got %+v", guild) }
+
+    for _, body := range []string{`{"low": "tomorrow"}`, `{"low": "1m"}`, `{"medium": "400h"}`} {
+        if bad := serveWithKey(h, http.MethodPut, "/discord/guild/update_intervals/g1", body, "test-key"); bad.Code != http.StatusBadRequest { t.Fatalf("expected %d for %s, got %d", http.StatusBadRequest, body, bad.Code) }
+    }
+
+    rec = serveWithKey(h, http.MethodDelete, "/discord/guild/update_intervals/g1", "", "test-key")
+    json.Unmarshal(rec.Body.Bytes(), &intervals)
+    if rec.Code != http.StatusOK || intervals.Overridden || intervals.High != "30m" { t.Fatalf("expected the guild back on the defaults, got %d %+v", rec.Code, intervals) }
+
+    if bad := serveWithKey(h, http.MethodPost, "/discord/channel/feed/frequency", `{"channel_id": "c1", "frequency": "custom"}`, "test-key"); bad.Code != http.StatusBadRequest { t.Fatalf("expected a custom frequency without interval to be refused, got %d", bad.Code) }
+    if rec := serveWithKey(h, http.MethodPost, "/discord/channel/feed/frequency", `{"channel_id": "c1", "frequency": "custom", "interval": "90m"}`, "test-key"); rec.Code != http.StatusOK { t.Fatalf("expected %d got %d: %s", http.StatusOK, rec.Code, rec.Body.String()) }
+    if config, _ := subscriptionService.GetChannelConfig(ctx, "c1"); config.CustomInterval != 90*time.Minute { t.Fatalf("expected a 90 minute custom interval, got %+v", config) }
+}
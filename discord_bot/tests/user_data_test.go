@@ -0,0 +1,88 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "testing"
+    "time"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestUserDataIsExportedThenDeleted(t *testing.T) {
+    t.Setenv("CORAL_API_KEY", "root-key")
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    analyticsService := services.NewAnalyticsService(repo, logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+    h.SetUserDataService(services.NewUserDataService(repo, logger))
+
+    subscriptionService.SubscribeToMarket(ctx, "u1", "m1")
+    subscriptionService.SubscribeToCreator(ctx, "u1", "alice")
+    subscriptionService.SubscribeToMarket(ctx, "u2", "m1")
+    analyticsService.RecordCommand(ctx, "list_subscriptions", "u1")
+    analyticsService.RecordCommand(ctx, "list_subscriptions", "u2")
+    repo.SaveReminder(ctx, &models.Reminder{ID: "rem_1", MarketID: "m1", DiscordUserID: "u1", RemindAt: time.Now().Add(time.Hour)})
+
+    rec := serveWithKey(h, http.MethodGet, "/discord/users/u1/data", "", "root-key")
+    if rec.Code != http.StatusOK { t.Fatalf("expected 200 got %d: %s", rec.Code, rec.Body.String()) }
+    var export models.UserData
+    if err := json.Unmarshal(rec.Body.Bytes(), &export); err != nil { t.Fatalf("failed to decode export: %v", err) }
+    if len(export.Subscription.SubscribedMarkets) != 1 || len(export.Subscription.SubscribedCreators) != 1 { t.Fatalf("unexpected subscription %+v", export.Subscription) }
+    // two subscribe events and one command
+    if len(export.Reminders) != 1 || len(export.Analytics) != 3 { t.Fatalf("expected one reminder and three analytics events, got %d and %d", len(export.Reminders), len(export.Analytics)) }
+
+    rec = serveWithKey(h, http.MethodDelete, "/discord/users/u1/data", "", "root-key")
+    if rec.Code != http.StatusOK { t.Fatalf("expected 200 got %d: %s", rec.Code, rec.Body.String()) }
+    var deleted models.UserData
+    json.Unmarshal(rec.Body.Bytes(), &deleted)
+    if len(deleted.Subscription.SubscribedMarkets) != 1 || len(deleted.Reminders) != 1 { t.Fatalf("expected the deleted data in the response, got %+v", deleted) }
+
+    rec = serveWithKey(h, http.MethodGet, "/discord/users/u1/data", "", "root-key")
+    var after models.UserData
+    json.Unmarshal(rec.Body.Bytes(), &after)
+    if len(after.Subscription.SubscribedMarkets) != 0 || len(after.Subscription.SubscribedCreators) != 0 || len(after.Reminders) != 0 || len(after.Analytics) != 0 { t.Fatalf("expected nothing left, got %+v", after) }
+    if due, _ := repo.GetDueReminders(ctx, time.Now().Add(2*time.Hour)); len(due) != 0 { t.Fatalf("expected the reminder to be unscheduled, got %d due", len(due)) }
+
+    // Other users are untouched
+    other, _ := services.NewUserDataService(repo, logger).ExportUserData(ctx, "u2")
+    if len(other.Subscription.SubscribedMarkets) != 1 || len(other.Analytics) != 2 { t.Fatalf("expected u2's data to be kept, got %+v", other) }
+}
+
+func TestUserDataDeletionRequiresAdminWrite(t *testing.T) {
+    h, apiKeyService := setupAPIKeyHandler()
+    logger := utils.NewLogger()
+    h.SetUserDataService(services.NewUserDataService(repository.NewInMemorySubscriptionRepository(), logger))
+    _, secret, err := apiKeyService.CreateKey(context.Background(), "support", []string{models.ScopeAdminRead})
+    if err != nil { t.Fatalf("failed to create key: %v", err) }
+
+    if rec := serveWithKey(h, http.MethodGet, "/discord/users/u1/data", "", secret); rec.Code != http.StatusOK { t.Fatalf("expected admin:read to export, got %d", rec.Code) }
+    if rec := serveWithKey(h, http.MethodDelete, "/discord/users/u1/data", "", secret); rec.Code != http.StatusForbidden { t.Fatalf("expected admin:read to be refused deletion, got %d", rec.Code) }
+}
+
+func TestUserDataDeletionRedactsTheUserFromTheAuditLog(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", FeedEnabled: true}, "u1")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c1", FeedEnabled: false}, "u2")
+    subscriptionService.UpdateChannelConfig(ctx, &models.ChannelConfig{ChannelID: "c2", FeedEnabled: true}, "u1")
+    before, _ := repo.GetAuditEntries(ctx, models.AuditFilter{})
+
+    if _, err := services.NewUserDataService(repo, logger).DeleteUserData(ctx, "u1"); err != nil { t.Fatalf("failed to delete user data: %v", err) }
+    entries, _ := repo.GetAuditEntries(ctx, models.AuditFilter{})
+    actors := []string{}
+    for _, entry := range entries {
+        actors = append(actors, entry.Actor)
+    }
+    if len(actors) != 3 || actors[0] != models.AuditActorDeletedUser || actors[1] != "u2" || actors[2] != models.AuditActorDeletedUser { t.Fatalf("expected the entries kept with u1 redacted, got %v", actors) }
+    if entries[0].ChannelID != "c2" || entries[0].Action != before[0].Action { t.Fatalf("expected the rest of the entry kept, got %+v", entries[0]) }
+}
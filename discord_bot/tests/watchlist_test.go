@@ -0,0 +1,144 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/handlers"
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/repository"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/utils"
+    "coral-bot/discord_bot/internal/web"
+
+    "github.com/bwmarrin/discordgo"
+)
+
+func TestWatchlists(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    subscriptionService.SetSubscriptionLimits(models.SubscriptionLimits{MaxMarkets: 2})
+
+    watchlist, err := subscriptionService.CreateWatchlist(ctx, "u1", " Crypto ")
+    if err != nil || watchlist.Name != "crypto" || watchlist.Notify != models.WatchlistNotifyAll { t.Fatalf("expected a normalized watchlist notifying about everything, got %+v, %v", watchlist, err) }
+    if _, err := subscriptionService.CreateWatchlist(ctx, "u1", "CRYPTO"); !errors.Is(err, services.ErrWatchlistExists) { t.Fatalf("expected names to be unique per user, got %v", err) }
+    if _, err := subscriptionService.CreateWatchlist(ctx, "u2", "crypto"); err != nil { t.Fatalf("expected another user to use the name, got %v", err) }
+    if _, err := subscriptionService.CreateWatchlist(ctx, "u1", strings.Repeat("x", 33)); !errors.Is(err, services.ErrInvalidWatchlist) { t.Fatalf("expected a long name to be rejected, got %v", err) }
+
+    subscriptionService.AddToWatchlist(ctx, "u1", "crypto", "m1")
+    subscriptionService.AddToWatchlist(ctx, "u1", "Crypto", "m1")
+    subscriptionService.AddToWatchlist(ctx, "u1", "crypto", "m2")
+    var limitErr *services.LimitExceededError
+    if _, err := subscriptionService.AddToWatchlist(ctx, "u1", "crypto", "m3"); !errors.As(err, &limitErr) || !strings.Contains(err.Error(), "2 markets") { t.Fatalf("expected the market limit per watchlist, got %v", err) }
+    if _, err := subscriptionService.AddToWatchlist(ctx, "u1", "politics", "m1"); !errors.Is(err, services.ErrWatchlistNotFound) { t.Fatalf("expected an unknown watchlist to be reported, got %v", err) }
+    if removed, _ := subscriptionService.RemoveFromWatchlist(ctx, "u1", "crypto", "m2"); !removed { t.Fatalf("expected m2 to be removed") }
+
+    major, negative := models.WatchlistNotifyMajor, -1.0
+    if _, err := subscriptionService.UpdateWatchlistSettings(ctx, "u1", "crypto", services.WatchlistSettings{MinBuyAmount: &negative}); !errors.Is(err, services.ErrInvalidWatchlist) { t.Fatalf("expected a negative minimum buy to be rejected, got %v", err) }
+    watchlist, _ = subscriptionService.UpdateWatchlistSettings(ctx, "u1", "crypto", services.WatchlistSettings{Notify: &major})
+    if watchlist.Notify != major || len(watchlist.MarketIDs) != 1 || watchlist.MarketIDs[0] != "m1" { t.Fatalf("unexpected watchlist %+v", watchlist) }
+
+    data, _ := services.NewUserDataService(repo, logger).DeleteUserData(ctx, "u1")
+    if len(data.Watchlists) != 1 { t.Fatalf("expected the watchlist in the export, got %+v", data.Watchlists) }
+    if watchlists, _ := subscriptionService.GetWatchlists(ctx, "u1"); len(watchlists) != 0 { t.Fatalf("expected the watchlist to be deleted with the user's data, got %+v", watchlists) }
+}
+
+func TestWatchlistNotifications(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    h := web.NewWebhookHandler(services.NewMockMarketService(logger), subscriptionService, logger)
+
+    // A disconnected gateway buffers the DMs, so they can be counted
+    session, _ := discordgo.New("Bot test")
+    h.SetDiscordSession(session)
+    gateway := services.NewGatewayMonitor(0, logger)
+    gateway.HandleDisconnect(nil, &discordgo.Disconnect{})
+    h.SetGateway(gateway)
+
+    // u1 has m1 on two watchlists and a subscription, u2 only follows its resolution, u3 has muted it
+    subscriptionService.SubscribeToMarket(ctx, "u1", "m1")
+    for _, name := range []string{"crypto", "favourites"} {
+        subscriptionService.CreateWatchlist(ctx, "u1", name)
+        subscriptionService.AddToWatchlist(ctx, "u1", name, "m1")
+    }
+    major, off := models.WatchlistNotifyMajor, models.WatchlistNotifyOff
+    subscriptionService.CreateWatchlist(ctx, "u2", "crypto")
+    subscriptionService.AddToWatchlist(ctx, "u2", "crypto", "m1")
+    subscriptionService.UpdateWatchlistSettings(ctx, "u2", "crypto", services.WatchlistSettings{Notify: &major})
+    subscriptionService.CreateWatchlist(ctx, "u3", "crypto")
+    subscriptionService.AddToWatchlist(ctx, "u3", "crypto", "m1")
+    subscriptionService.UpdateWatchlistSettings(ctx, "u3", "crypto", services.WatchlistSettings{Notify: &off})
+
+    process := func(eventType string, payload map[string]interface{}) {
+        body, _ := json.Marshal(payload)
+        if _, err := h.ProcessEventJSON(ctx, eventType, body); err != nil { t.Fatalf("failed to process %s: %v", eventType, err) }
+    }
+    process(models.EventMarketUpdate, map[string]interface{}{"market_id": "m1", "title": "Update", "probability": 0.5})
+    if buffered := gateway.Status().Buffered; buffered != 1 { t.Fatalf("expected one DM to u1 for an update, got %d", buffered) }
+    process(models.EventMarketResolved, map[string]interface{}{"market_id": "m1", "title": "Resolved", "outcome": "Yes"})
+    if buffered := gateway.Status().Buffered; buffered != 3 { t.Fatalf("expected DMs to u1 and u2 for a resolution, got %d more", buffered - 1) }
+}
+
+func TestWatchlistCommand(t *testing.T) {
+    session, responses := captureInteractionResponses(t)
+    logger := utils.NewLogger()
+    repo := repository.NewInMemorySubscriptionRepository()
+    subscriptionService := services.NewSubscriptionService(repo, logger)
+    h := handlers.NewCommandHandler(services.NewMockMarketService(logger), subscriptionService, nil, services.NewAnalyticsService(repo, logger), logger)
+
+    watchlist := func(subcommand string, options ...*discordgo.ApplicationCommandInteractionDataOption) string {
+        h.HandleInteraction(session, commandInteraction("watchlist", &discordgo.ApplicationCommandInteractionDataOption{Name: subcommand, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options}))
+        return (*responses)[len(*responses)-1].Data.Content
+    }
+    name := &discordgo.ApplicationCommandInteractionDataOption{Name: "name", Type: discordgo.ApplicationCommandOptionString, Value: "crypto"}
+    market := &discordgo.ApplicationCommandInteractionDataOption{Name: "market_id", Type: discordgo.ApplicationCommandOptionString, Value: "m1"}
+
+    if reply := watchlist("add", name, market); !strings.Contains(reply, "no watchlist named `crypto`") { t.Fatalf("expected an unknown watchlist to be explained, got %q", reply) }
+    if reply := watchlist("create", name); !strings.Contains(reply, "Created watchlist `crypto`") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := watchlist("add", name, market); !strings.Contains(reply, "(1 market)") { t.Fatalf("unexpected reply %q", reply) }
+    watchlist("settings", name, &discordgo.ApplicationCommandInteractionDataOption{Name: "min_buy", Type: discordgo.ApplicationCommandOptionNumber, Value: 50.0})
+    if reply := watchlist("show", name); !strings.Contains(reply, "- `m1`") || !strings.Contains(reply, "all events") || !strings.Contains(reply, "$50.00") { t.Fatalf("expected the markets and settings, got %q", reply) }
+    if reply := watchlist("list"); !strings.Contains(reply, "`crypto` - 1 market") { t.Fatalf("unexpected reply %q", reply) }
+    if reply := watchlist("delete", name); !strings.Contains(reply, "Deleted watchlist") { t.Fatalf("unexpected reply %q", reply) }
+
+    for _, command := range h.Commands() {
+        if command.Name == "watchlist" && len(command.Options) != 9 { t.Fatalf("expected nine subcommands, got %d", len(command.Options)) }
+    }
+}
+
+func TestWatchlistShareCodes(t *testing.T) {
+    ctx := context.Background()
+    logger := utils.NewLogger()
+    subscriptionService := services.NewSubscriptionService(repository.NewInMemorySubscriptionRepository(), logger)
+    subscriptionService.CreateWatchlist(ctx, "u1", "crypto")
+    subscriptionService.AddToWatchlist(ctx, "u1", "crypto", "m1")
+    subscriptionService.AddToWatchlist(ctx, "u1", "crypto", "m2")
+
+    share, err := subscriptionService.ShareWatchlist(ctx, "u1", "crypto")
+    if err != nil || len(share.Code) != 8 { t.Fatalf("expected an eight character code, got %+v, %v", share, err) }
+    if again, _ := subscriptionService.ShareWatchlist(ctx, "u1", "Crypto"); again.Code != share.Code { t.Fatalf("expected sharing again to return the same code, got %s and %s", share.Code, again.Code) }
+    if _, err := subscriptionService.ShareWatchlist(ctx, "u2", "crypto"); !errors.Is(err, services.ErrWatchlistNotFound) { t.Fatalf("expected only the owner's watchlists to be shared, got %v", err) }
+
+    typed := strings.ToLower(services.FormatShareCode(share.Code))
+    imported, err := subscriptionService.ImportWatchlist(ctx, "u2", typed, "")
+    if err != nil || imported.Name != "crypto" || len(imported.MarketIDs) != 2 || imported.ImportedFrom != share.Code || imported.DiscordUserID != "u2" { t.Fatalf("expected a copy of the watchlist, got %+v, %v", imported, err) }
+    if _, err := subscriptionService.ImportWatchlist(ctx, "u2", share.Code, ""); !errors.Is(err, services.ErrWatchlistExists) { t.Fatalf("expected a second import under the same name to be refused, got %v", err) }
+
+    // The copy is independent of the original
+    subscriptionService.AddToWatchlist(ctx, "u1", "crypto", "m3")
+    if copied, _ := subscriptionService.GetWatchlist(ctx, "u2", "crypto"); len(copied.MarketIDs) != 2 { t.Fatalf("expected the copy not to follow the original, got %v", copied.MarketIDs) }
+
+    subscriptionService.SetSubscriptionLimits(models.SubscriptionLimits{MaxMarkets: 2})
+    var limitErr *services.LimitExceededError
+    if _, err := subscriptionService.ImportWatchlist(ctx, "u3", share.Code, ""); !errors.As(err, &limitErr) { t.Fatalf("expected the market limit to apply to imports, got %v", err) }
+    if watchlists, _ := subscriptionService.GetWatchlists(ctx, "u3"); len(watchlists) != 0 { t.Fatalf("expected nothing to be created, got %+v", watchlists) }
+
+    subscriptionService.DeleteWatchlist(ctx, "u1", "crypto")
+    if _, err := subscriptionService.ImportWatchlist(ctx, "u3", share.Code, "copy"); !errors.Is(err, services.ErrShareCodeNotFound) { t.Fatalf("expected the code to stop working with its watchlist, got %v", err) }
+}
@@ -17,7 +17,7 @@ import (
 func TestWebhookHandler_AuthOk(t *testing.T) {
     logger := utils.NewLogger()
     repo := repository.NewInMemorySubscriptionRepository()
-    marketService := services.NewMarketService("", logger)
+    marketService := services.NewMockMarketService(logger)
     subscriptionService := services.NewSubscriptionService(repo, logger)
     handler := web.NewWebhookHandler(marketService, subscriptionService, logger)
 
@@ -55,7 +55,7 @@ func TestWebhookHandler_AuthOk(t *testing.T) {
 func TestWebhookHandler_HandleNewMarket(t *testing.T) {
     logger := utils.NewLogger()
     repo := repository.NewInMemorySubscriptionRepository()
-    marketService := services.NewMarketService("", logger)
+    marketService := services.NewMockMarketService(logger)
     subscriptionService := services.NewSubscriptionService(repo, logger)
     handler := web.NewWebhookHandler(marketService, subscriptionService, logger)
 
@@ -69,7 +69,7 @@ func TestWebhookHandler_HandleNewMarket(t *testing.T) {
         Creator:     "Test Creator",
         Volume:      1000.0,
         Status:      "active",
-        Link:        "https://example.com/markets/test-market",
+        Link:        "https://coral.markets/markets/test-market",
     }
 
     payload := struct {
@@ -89,7 +89,7 @@ func TestWebhookHandler_HandleNewMarket(t *testing.T) {
     req.Header.Set("Content-Type", "application/json")
 
     rr := httptest.NewRecorder()
-    handler.HandleNewMarket(rr, req)
+    handler.Handler().ServeHTTP(rr, req)
 
     if status := rr.Code; status != http.StatusOK {
         t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusOK)
@@ -17,13 +17,13 @@ import (
 func TestRegisterUnregisterListWebhooks(t *testing.T) {
     logger := utils.NewLogger()
     repo := repository.NewInMemorySubscriptionRepository()
-    marketService := services.NewMarketService("", logger)
+    marketService := services.NewMockMarketService(logger)
     subscriptionService := services.NewSubscriptionService(repo, logger)
     handler := web.NewWebhookHandler(marketService, subscriptionService, logger)
 
     payload := map[string]interface{}{
         "channel_id":  "channel-1",
-        "webhook_url": "https://discordapp.test/webhook/1",
+        "webhook_url": "https://discord.com/api/webhooks/1/token",
         "events":      []string{"new_market"},
         "frequency":   "low",
     }
@@ -0,0 +1,82 @@
+package tests
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strings"
+    "testing"
+
+    "coral-bot/discord_bot/internal/models"
+    "coral-bot/discord_bot/internal/services"
+    "coral-bot/discord_bot/internal/web"
+)
+
+func TestParseDiscordWebhookURL(t *testing.T) {
+    id, token, err := services.ParseDiscordWebhookURL("https://discord.com/api/webhooks/123456789/abc-DEF_1")
+    if err != nil || id != "123456789" || token != "abc-DEF_1" { t.Fatalf("expected the ID and token, got %q %q %v", id, token, err) }
+    for _, raw := range []string{"https://discordapp.com/api/webhooks/1/x", "https://canary.discord.com/api/v10/webhooks/1/x", "https://ptb.discord.com/api/webhooks/1/x/"} {
+        if _, _, err := services.ParseDiscordWebhookURL(raw); err != nil { t.Fatalf("expected %s accepted, got %v", raw, err) }
+    }
+
+    for _, raw := range []string{
+        "",
+        "not a url",
+        "http://discord.com/api/webhooks/1/x",
+        "https://discord.com.evil.test/api/webhooks/1/x",
+        "https://discordapp.test/webhook/1",
+        "https://discord.com/api/webhooks/abc/x",
+        "https://discord.com/api/webhooks/1",
+        "https://discord.com/api/webhooks/1/x/slack",
+        "https://user@discord.com/api/webhooks/1/x",
+        "https://discord.com:8443/api/webhooks/1/x",
+    } {
+        if _, _, err := services.ParseDiscordWebhookURL(raw); !errors.Is(err, services.ErrInvalidWebhookURL) { t.Fatalf("expected %q rejected, got %v", raw, err) }
+    }
+}
+
+func TestRegisterWebhookRejectsInvalidURLs(t *testing.T) {
+    h := newHarness(t)
+
+    rec := serveWithKey(h.handler, http.MethodPost, "/discord/webhooks/register", `{"channel_id": "c1", "webhook_url": "https://example.com/hook"}`, harnessAPIKey)
+    var errResp web.ErrorResponse
+    json.Unmarshal(rec.Body.Bytes(), &errResp)
+    if rec.Code != http.StatusBadRequest || errResp.Code != web.ErrorCodeInvalidRequest || !strings.Contains(errResp.Error, "invalid webhook URL") { t.Fatalf("expected the URL rejected, got %d: %s", rec.Code, rec.Body.String()) }
+
+    _, err := h.subscriptions.RegisterWebhook(h.ctx, &models.WebhookRegistration{ChannelID: "c1", WebhookURL: "https://example.com/hook"}, "test")
+    if !errors.Is(err, services.ErrInvalidWebhookURL) { t.Fatalf("expected the service to reject the URL too, got %v", err) }
+    if registrations, _ := h.subscriptions.ListWebhookRegistrations(h.ctx); len(registrations) != 0 { t.Fatalf("expected nothing registered, got %d", len(registrations)) }
+}
+
+func TestRegisterWebhookTestPing(t *testing.T) {
+    h := newHarness(t)
+    h.handler.SetWebhookTestPing(true)
+    h.discord.addWebhook("111", "c1")
+
+    register := func(body string) (int, web.ErrorResponse) {
+        rec := serveWithKey(h.handler, http.MethodPost, "/discord/webhooks/register", body, harnessAPIKey)
+        var errResp web.ErrorResponse
+        json.Unmarshal(rec.Body.Bytes(), &errResp)
+        return rec.Code, errResp
+    }
+
+    // A webhook Discord does not know is refused with Discord's reason
+    code, errResp := register(`{"channel_id": "c1", "webhook_url": "https://discord.com/api/webhooks/222/gone"}`)
+    if code != http.StatusBadRequest || !strings.Contains(errResp.Error, "Unknown Webhook") || errResp.Details["discord_status"] != float64(http.StatusNotFound) { t.Fatalf("expected the unknown webhook refused, got %d %+v", code, errResp) }
+
+    // So is a webhook posting to another channel, before anything is posted
+    code, errResp = register(`{"channel_id": "c2", "webhook_url": "https://discord.com/api/webhooks/111/token"}`)
+    if code != http.StatusBadRequest || errResp.Details["webhook_channel_id"] != "c1" { t.Fatalf("expected the channel mismatch refused, got %d %+v", code, errResp) }
+    if registrations, _ := h.subscriptions.ListWebhookRegistrations(h.ctx); len(registrations) != 0 || h.discord.count() != 0 { t.Fatalf("expected nothing registered or posted, got %d registrations and %d messages", len(registrations), h.discord.count()) }
+
+    code, _ = register(`{"channel_id": "c1", "webhook_url": "https://discord.com/api/webhooks/111/token"}`)
+    if code != http.StatusCreated { t.Fatalf("expected the webhook registered, got %d", code) }
+    if messages := h.discord.channelMessages("c1"); len(messages) != 1 || messages[0].Content == "" { t.Fatalf("expected a test message in c1, got %+v", messages) }
+
+    // The ping can be skipped per registration, and without a Discord session it cannot be sent
+    code, _ = register(`{"channel_id": "c2", "webhook_url": "https://discord.com/api/webhooks/222/gone", "test_ping": false}`)
+    if code != http.StatusCreated || h.discord.count() != 1 { t.Fatalf("expected the webhook registered without a ping, got %d", code) }
+    h.handler.SetDiscordSession(nil)
+    code, _ = register(`{"channel_id": "c1", "webhook_url": "https://discord.com/api/webhooks/111/token"}`)
+    if code != http.StatusServiceUnavailable { t.Fatalf("expected 503 without a session, got %d", code) }
+}